@@ -2,22 +2,168 @@ package aws_helper
 
 import (
 	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
 	"github.com/aws/aws-sdk-go/aws/credentials/stscreds"
-	"github.com/aws/aws-sdk-go/aws/endpoints"
 	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/sts"
 	"github.com/gruntwork-io/terragrunt/errors"
+	"github.com/gruntwork-io/terragrunt/oidc"
 	"github.com/gruntwork-io/terragrunt/options"
+	"github.com/gruntwork-io/terragrunt/shell"
+	"github.com/gruntwork-io/terragrunt/util"
 )
 
+// The number of times a failed AWS API call is retried, with exponential backoff, e.g. when it's throttled. When the
+// remote state of many modules is checked or initialized at once, the calls can exceed the rate limits of the AWS APIs,
+// so this is higher than the default of the AWS SDK.
+const AWS_API_MAX_RETRIES = 10
+
+// Return the HTTP client of a new session. The AWS SDK sets the TLS settings of the env, e.g. the CA bundle of
+// AWS_CA_BUNDLE, on the HTTP client of the session, which is the default HTTP client of the process unless one is set,
+// so the sessions created concurrently, e.g. by the units of *-all commands, each get their own.
+func newSessionHttpClient() *http.Client {
+	return &http.Client{}
+}
+
+// The env vars the credentials of a session are read from, which are the ones of the env of the unit, as the units of
+// *-all commands can each have their own, e.g. the ones of their vault_credentials blocks. The sessions are shared for
+// the rest of the run, but the env can change in between, e.g. when commands are run by the daemon, so these are part
+// of the key they're shared by.
+var credentialsEnvVars = []string{"AWS_PROFILE", "AWS_DEFAULT_PROFILE", "AWS_ACCESS_KEY_ID", "AWS_SECRET_ACCESS_KEY", "AWS_SESSION_TOKEN", "AWS_REGION", "AWS_DEFAULT_REGION"}
+
+// Creating a session means finding the credentials, which can include assuming an IAM role, and during *-all commands
+// many modules create sessions with the same config, e.g. to check the remote state resources they share. Instead, the
+// sessions, and the caller identities looked up with them, are shared for the rest of the run. Only the sessions whose
+// credentials were found are recorded, so that a failure is retried by the next module. We use sync.Map to ensure
+// atomic updates during concurrent access.
+var sharedSessions = sync.Map{}
+var sharedCallerIdentities = sync.Map{}
+
+// The locks that make the modules creating a session with the same config wait for the first one, rather than all of
+// them finding the credentials at the same time
+var sharedSessionLocks = sync.Map{}
+
+// When hundreds of modules assume the same IAM role during *-all commands, assuming it once per module would call STS
+// over and over. Instead, the credentials of the assumed roles are shared for the rest of the run, by role, session
+// duration and credentials env vars, and refreshed once less than half of the session duration is left, so that each
+// module still gets credentials that stay valid for a good while. We use sync.Map to ensure atomic updates during
+// concurrent access.
+var assumedRoleCredentials = sync.Map{}
+
+// ClearSharedSessions forgets the sessions, caller identities and assumed role credentials shared so far, e.g. between
+// the commands run by the daemon, which may run with other credentials, profiles or IAM roles.
+func ClearSharedSessions() {
+	sharedSessions = sync.Map{}
+	sharedCallerIdentities = sync.Map{}
+	assumedRoleCredentials = sync.Map{}
+	usedMfaTokens = sync.Map{}
+}
+
+// An MFA token can only be used once, so the one set via --terragrunt-iam-assume-role-mfa-token is only used to assume
+// the IAM role the first time. The tokens used so far are recorded by MFA device.
+var usedMfaTokens = sync.Map{}
+
+// The locks that make the modules assuming the same role wait for the first one, rather than all of them calling STS
+// at the same time
+var assumedRoleLocks = sync.Map{}
+
+// The key the credentials of an assumed role are shared by
+type assumedRoleKey struct {
+	roleArn                string
+	sessionName            string
+	sessionDurationSeconds int64
+	externalId             string
+	mfaSerial              string
+	webIdentityToken       string
+	chain                  string
+	awsProfile             string
+	envCredsID             string
+}
+
+// The key the sessions created with the same config, IAM role and credentials env vars are shared by. The config
+// includes the session name and duration of the role it assumes, if any.
+type sharedSessionKey struct {
+	config              AwsSessionConfig
+	hasConfig           bool
+	iamRole             string
+	iamRoleDuration     int64
+	iamRoleExternalId   string
+	iamRoleMfaSerial    string
+	iamWebIdentityToken string
+	iamRoleChain        string
+	awsProfile          string
+	envCredsID          string
+}
+
+func newSharedSessionKey(config *AwsSessionConfig, terragruntOptions *options.TerragruntOptions) sharedSessionKey {
+	key := sharedSessionKey{
+		iamRole:             terragruntOptions.IamRole,
+		iamRoleDuration:     terragruntOptions.IamAssumeRoleDuration,
+		iamRoleExternalId:   terragruntOptions.IamAssumeRoleExternalId,
+		iamRoleMfaSerial:    terragruntOptions.IamAssumeRoleMfaSerial,
+		iamWebIdentityToken: terragruntOptions.IamWebIdentityToken,
+		iamRoleChain:        iamRoleChainID(terragruntOptions.IamRoleChain),
+		awsProfile:          terragruntOptions.AwsProfile,
+	}
+	if config != nil {
+		key.config = *config
+		key.hasConfig = true
+	}
+	key.envCredsID = envCredsID(terragruntOptions)
+	return key
+}
+
+// Return an ID of the given chain of IAM roles, as the keys the sessions and credentials are shared by can't hold slices
+func iamRoleChainID(chain []options.IamRoleHop) string {
+	if len(chain) == 0 {
+		return ""
+	}
+	return fmt.Sprintf("%v", chain)
+}
+
+// Return an ID of the values of the env vars the credentials of the unit of the given options are read from. See
+// credentialsEnv.
+func envCredsID(terragruntOptions *options.TerragruntOptions) string {
+	env := credentialsEnv(terragruntOptions)
+	id := ""
+	for _, envVar := range credentialsEnvVars {
+		id += envVar + "=" + env[envVar] + "\n"
+	}
+	return id
+}
+
+// Return the values of the env vars the credentials of the unit of the given options are read from: the ones of its
+// Env, or the ones it had before the credentials of its IAM role were set there, which the role is assumed with. The
+// options may be nil, which means the env vars of the process.
+func credentialsEnv(terragruntOptions *options.TerragruntOptions) map[string]string {
+	env := map[string]string{}
+	for _, envVar := range credentialsEnvVars {
+		switch {
+		case terragruntOptions == nil:
+			env[envVar] = os.Getenv(envVar)
+		case terragruntOptions.IamRoleSourceEnv != nil:
+			env[envVar] = terragruntOptions.IamRoleSourceEnv[envVar]
+		default:
+			env[envVar] = terragruntOptions.Env[envVar]
+		}
+	}
+	return env
+}
+
 // A representation of the configuration options for an AWS Session
 type AwsSessionConfig struct {
 	Region                  string
 	CustomS3Endpoint        string
 	CustomDynamoDBEndpoint  string
+	CustomSTSEndpoint       string
+	CustomIAMEndpoint       string
 	Profile                 string
 	RoleArn                 string
 	CredsFilename           string
@@ -25,44 +171,57 @@ type AwsSessionConfig struct {
 	DisableComputeChecksums bool
 	ExternalID              string
 	SessionName             string
+	AssumeRoleDuration      time.Duration
 }
 
 // Returns an AWS session object for the given config region (required), profile name (optional), and IAM role to assume
 // (optional), ensuring that the credentials are available.
 func CreateAwsSessionFromConfig(config *AwsSessionConfig, terragruntOptions *options.TerragruntOptions) (*session.Session, error) {
-	defaultResolver := endpoints.DefaultResolver()
-	s3CustResolverFn := func(service, region string, optFns ...func(*endpoints.Options)) (endpoints.ResolvedEndpoint, error) {
-		if service == "s3" && config.CustomS3Endpoint != "" {
-			return endpoints.ResolvedEndpoint{
-				URL:           config.CustomS3Endpoint,
-				SigningRegion: config.Region,
-			}, nil
-		} else if service == "dynamodb" && config.CustomDynamoDBEndpoint != "" {
-			return endpoints.ResolvedEndpoint{
-				URL:           config.CustomDynamoDBEndpoint,
-				SigningRegion: config.Region,
-			}, nil
-		}
-
-		return defaultResolver.EndpointFor(service, region, optFns...)
-	}
-
 	var awsConfig = aws.Config{
 		Region:                  aws.String(config.Region),
-		EndpointResolver:        endpoints.ResolverFunc(s3CustResolverFn),
 		S3ForcePathStyle:        aws.Bool(config.S3ForcePathStyle),
 		DisableComputeChecksums: aws.Bool(config.DisableComputeChecksums),
+		MaxRetries:              aws.Int(AWS_API_MAX_RETRIES),
+		HTTPClient:              newSessionHttpClient(),
 	}
-
-	var sessionOptions = session.Options{
-		Config:            awsConfig,
-		Profile:           config.Profile,
-		SharedConfigState: session.SharedConfigEnable,
-	}
-
+	applyEndpoints(&awsConfig, map[string]string{
+		"s3":       config.CustomS3Endpoint,
+		"dynamodb": config.CustomDynamoDBEndpoint,
+		"sts":      config.CustomSTSEndpoint,
+		"iam":      config.CustomIAMEndpoint,
+	}, terragruntOptions)
+
+	var sessionOptions session.Options
 	if len(config.CredsFilename) > 0 {
-		sessionOptions.SharedConfigFiles = []string{config.CredsFilename}
+		// The given credentials file replaces the default one, but the profiles of the config file still apply, as they
+		// do for the AWS CLI. Without a profile, the credentials of the env of the unit take precedence.
+		sessionOptions = session.Options{
+			Profile:                 config.Profile,
+			SharedConfigState:       session.SharedConfigEnable,
+			SharedConfigFiles:       []string{config.CredsFilename},
+			AssumeRoleTokenProvider: profileMfaTokenProvider(config.Profile, terragruntOptions),
+		}
+		if configFile, err := awsConfigFile(); err == nil {
+			sessionOptions.SharedConfigFiles = []string{configFile, config.CredsFilename}
+		}
+		if config.Profile == "" {
+			env := credentialsEnv(terragruntOptions)
+			sessionOptions.Config.Credentials = envStaticCredentials(env)
+			sessionOptions.Profile = envProfileOf(env)
+		}
+	} else {
+		// Without a profile of its own, the config uses the profile the module is pinned to, if any
+		profile := config.Profile
+		if profile == "" {
+			profile = terragruntOptions.AwsProfile
+		}
+		var err error
+		if sessionOptions, err = profileSessionOptions(profile, terragruntOptions); err != nil {
+			return nil, err
+		}
 	}
+	awsConfig.Credentials = sessionOptions.Config.Credentials
+	sessionOptions.Config = awsConfig
 
 	sess, err := session.NewSessionWithOptions(sessionOptions)
 	if err != nil {
@@ -76,12 +235,17 @@ func CreateAwsSessionFromConfig(config *AwsSessionConfig, terragruntOptions *opt
 		if config.SessionName != "" {
 			p.RoleSessionName = config.SessionName
 		}
+		if config.AssumeRoleDuration > 0 {
+			p.Duration = config.AssumeRoleDuration
+		}
 	}
 
+	// Only fall back to the IAM role of terragrunt when the config has no credentials of its own, as e.g. a profile
+	// pointing to another account shouldn't be used to assume a role meant for the account of the module
 	if config.RoleArn != "" {
 		sess.Config.Credentials = stscreds.NewCredentials(sess, config.RoleArn, credentialsOptFn)
-	} else if terragruntOptions.IamRole != "" {
-		sess.Config.Credentials = stscreds.NewCredentials(sess, terragruntOptions.IamRole, credentialsOptFn)
+	} else if terragruntOptions.IamRole != "" && config.Profile == "" && config.CredsFilename == "" {
+		sess.Config.Credentials = iamRoleCredentials(sess, terragruntOptions, config, credentialsOptFn)
 	}
 	return sess, nil
 }
@@ -91,18 +255,44 @@ func CreateAwsSessionFromConfig(config *AwsSessionConfig, terragruntOptions *opt
 //   assume (optional).
 // - The provided TerragruntOptions struct, which specifies any IAM role to assume (optional).
 // Note that if the AwsSessionConfig object is null, this will return default session credentials using the default
-// credentials chain of the AWS SDK.
+// credentials chain of the AWS SDK. The session is shared with the other callers passing the same config for the rest of
+// the run, so it must not be modified.
 func CreateAwsSession(config *AwsSessionConfig, terragruntOptions *options.TerragruntOptions) (*session.Session, error) {
+	key := newSharedSessionKey(config, terragruntOptions)
+
+	rawLock, _ := sharedSessionLocks.LoadOrStore(key, &sync.Mutex{})
+	lock := rawLock.(*sync.Mutex)
+	lock.Lock()
+	defer lock.Unlock()
+
+	if sess, isShared := sharedSessions.Load(key); isShared {
+		return sess.(*session.Session), nil
+	}
+
+	sess, err := createAwsSessionWithCredentials(config, terragruntOptions)
+	if err != nil {
+		return nil, err
+	}
+
+	sharedSessions.Store(key, sess)
+	return sess, nil
+}
+
+// Create a new AWS session as described by CreateAwsSession, and make sure its credentials can be found
+func createAwsSessionWithCredentials(config *AwsSessionConfig, terragruntOptions *options.TerragruntOptions) (*session.Session, error) {
 	var sess *session.Session
 	var err error
 	if config == nil {
-		sessionOptions := session.Options{SharedConfigState: session.SharedConfigEnable}
+		sessionOptions, err := profileSessionOptions(terragruntOptions.AwsProfile, terragruntOptions)
+		if err != nil {
+			return nil, err
+		}
 		sess, err = session.NewSessionWithOptions(sessionOptions)
 		if err != nil {
 			return nil, errors.WithStackTrace(err)
 		}
 		if terragruntOptions.IamRole != "" {
-			sess.Config.Credentials = stscreds.NewCredentials(sess, terragruntOptions.IamRole)
+			sess.Config.Credentials = iamRoleCredentials(sess, terragruntOptions, nil)
 		}
 	} else {
 		sess, err = CreateAwsSessionFromConfig(config, terragruntOptions)
@@ -112,6 +302,9 @@ func CreateAwsSession(config *AwsSessionConfig, terragruntOptions *options.Terra
 	}
 
 	if _, err = sess.Config.Credentials.Get(); err != nil {
+		if isSsoSessionExpired(err) {
+			return nil, err
+		}
 		msg := "Error finding AWS credentials (did you set the AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY environment variables?)"
 		if config != nil && len(config.CredsFilename) > 0 {
 			msg = fmt.Sprintf("Error finding AWS credentials in file '%s' (did you set the correct file name and/or profile?)", config.CredsFilename)
@@ -123,26 +316,149 @@ func CreateAwsSession(config *AwsSessionConfig, terragruntOptions *options.Terra
 	return sess, nil
 }
 
-// Make API calls to AWS to assume the IAM role specified and return the temporary AWS credentials to use that role
-func AssumeIamRole(iamRoleArn string, sessionDurationSeconds int64) (*sts.Credentials, error) {
-	sessionOptions := session.Options{SharedConfigState: session.SharedConfigEnable}
+// Return the credentials of the IAM role of terragrunt for the given session. Assuming a role that requires MFA takes a
+// token the user enters, and each token can only be used once, and neither a role assumed with a web identity token nor
+// the last role of a chain use the credentials of the session, so those credentials are shared with
+// AssumeIamRoleWithSharedCredentials rather than each session assuming the role on its own. The session name and
+// duration of the given config, if any, take precedence over the ones of the options.
+func iamRoleCredentials(sess *session.Session, terragruntOptions *options.TerragruntOptions, config *AwsSessionConfig, optFns ...func(*stscreds.AssumeRoleProvider)) *credentials.Credentials {
+	if terragruntOptions.IamAssumeRoleMfaSerial != "" || terragruntOptions.IamWebIdentityToken != "" || len(terragruntOptions.IamRoleChain) > 0 {
+		provider := &sharedIamRoleProvider{terragruntOptions: terragruntOptions}
+		if config != nil {
+			provider.sessionName = config.SessionName
+			provider.sessionDurationSeconds = int64(config.AssumeRoleDuration / time.Second)
+		}
+		return credentials.NewCredentials(provider)
+	}
+	optFns = append(optFns, iamRoleExternalIdOptFn(terragruntOptions))
+	return stscreds.NewCredentials(sess, terragruntOptions.IamRole, optFns...)
+}
+
+// A provider of the credentials of the IAM role of terragrunt that are shared for the rest of the run. See
+// assumedRoleCredentials.
+type sharedIamRoleProvider struct {
+	credentials.Expiry
+	terragruntOptions      *options.TerragruntOptions
+	sessionName            string
+	sessionDurationSeconds int64
+}
+
+func (provider *sharedIamRoleProvider) Retrieve() (credentials.Value, error) {
+	creds, err := assumeIamRoleOfOptionsWithSessionWithSharedCredentials(provider.terragruntOptions, provider.sessionName, provider.sessionDurationSeconds)
+	if err != nil {
+		return credentials.Value{ProviderName: stscreds.ProviderName}, err
+	}
+
+	provider.SetExpiration(aws.TimeValue(creds.Expiration), time.Minute)
+	return credentials.Value{
+		AccessKeyID:     aws.StringValue(creds.AccessKeyId),
+		SecretAccessKey: aws.StringValue(creds.SecretAccessKey),
+		SessionToken:    aws.StringValue(creds.SessionToken),
+		ProviderName:    stscreds.ProviderName,
+	}, nil
+}
+
+// Return an option of the provider of the credentials of the IAM role of terragrunt that sets the external ID of the
+// role, if any
+func iamRoleExternalIdOptFn(terragruntOptions *options.TerragruntOptions) func(p *stscreds.AssumeRoleProvider) {
+	return func(p *stscreds.AssumeRoleProvider) {
+		if terragruntOptions.IamAssumeRoleExternalId != "" {
+			p.ExternalID = aws.String(terragruntOptions.IamAssumeRoleExternalId)
+		}
+	}
+}
+
+// Return a function that returns the MFA token to assume the IAM role of terragrunt with: the one set via
+// --terragrunt-iam-assume-role-mfa-token, if it wasn't used yet, or else the one the user enters when prompted. The
+// token of the flag can only be used once, so when the role is assumed again, e.g. as its credentials expired during a
+// long run, the user is asked for a new one.
+func mfaTokenProvider(terragruntOptions *options.TerragruntOptions) func() (string, error) {
+	return func() (string, error) {
+		tokenUsed := false
+		if terragruntOptions.IamAssumeRoleMfaToken != "" {
+			key := terragruntOptions.IamAssumeRoleMfaSerial + "|" + terragruntOptions.IamAssumeRoleMfaToken
+			if _, tokenUsed = usedMfaTokens.LoadOrStore(key, true); !tokenUsed {
+				return terragruntOptions.IamAssumeRoleMfaToken, nil
+			}
+		}
+		if terragruntOptions.NonInteractive {
+			return "", errors.WithStackTrace(MissingMfaToken{MfaSerial: terragruntOptions.IamAssumeRoleMfaSerial, TokenUsed: tokenUsed})
+		}
+
+		prompt := fmt.Sprintf("Enter the MFA token of %s to assume the IAM role %s: ", terragruntOptions.IamAssumeRoleMfaSerial, terragruntOptions.IamRole)
+		token, err := shell.PromptUserForInput(prompt, terragruntOptions)
+		if err != nil {
+			return "", err
+		}
+		if token == "" {
+			return "", errors.WithStackTrace(MissingMfaToken{MfaSerial: terragruntOptions.IamAssumeRoleMfaSerial, TokenUsed: tokenUsed})
+		}
+		return token, nil
+	}
+}
+
+// Make API calls to AWS to assume the IAM role specified, with the given external ID if it's not empty, and return the
+// temporary AWS credentials to use that role. If the MFA serial is not empty, the role is assumed with it and the
+// token returned by the given function.
+func AssumeIamRole(iamRoleArn string, sessionDurationSeconds int64, externalId string, mfaSerial string, mfaTokenProvider func() (string, error)) (*sts.Credentials, error) {
+	sess, err := newSessionWithDefaultCredentials(nil)
+	if err != nil {
+		return nil, err
+	}
+	return assumeIamRoleWithSession(sess, iamRoleArn, "", sessionDurationSeconds, externalId, mfaSerial, mfaTokenProvider)
+}
+
+// Create a session with the credentials of the profile the module is pinned to, if any, or else the ones the AWS SDK
+// finds in the environment, making sure there are any. The given options, if any, allow starting a new SSO session when
+// the one of the profile expired, and asking for the MFA token of the profile. See profileSessionOptions.
+func newSessionWithDefaultCredentials(terragruntOptions *options.TerragruntOptions) (*session.Session, error) {
+	profile := ""
+	if terragruntOptions != nil {
+		profile = terragruntOptions.AwsProfile
+	}
+	sessionOptions, err := profileSessionOptions(profile, terragruntOptions)
+	if err != nil {
+		return nil, err
+	}
 	sess, err := session.NewSessionWithOptions(sessionOptions)
 	if err != nil {
 		return nil, errors.WithStackTrace(err)
 	}
 
 	_, err = sess.Config.Credentials.Get()
+	if isSsoSessionExpired(err) {
+		return nil, err
+	}
 	if err != nil {
 		return nil, errors.WithStackTraceAndPrefix(err, "Error finding AWS credentials (did you set the AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY environment variables?)")
 	}
+	return sess, nil
+}
 
+// Assume the given IAM role with the credentials of the given session, as described by AssumeIamRole. An empty session
+// name means a generated one.
+func assumeIamRoleWithSession(sess *session.Session, iamRoleArn string, sessionName string, sessionDurationSeconds int64, externalId string, mfaSerial string, mfaTokenProvider func() (string, error)) (*sts.Credentials, error) {
 	stsClient := sts.New(sess)
 
+	if sessionName == "" {
+		sessionName = fmt.Sprintf("terragrunt-%d", time.Now().UTC().UnixNano())
+	}
 	input := sts.AssumeRoleInput{
 		RoleArn:         aws.String(iamRoleArn),
-		RoleSessionName: aws.String(fmt.Sprintf("terragrunt-%d", time.Now().UTC().UnixNano())),
+		RoleSessionName: aws.String(sessionName),
 		DurationSeconds: aws.Int64(sessionDurationSeconds),
 	}
+	if externalId != "" {
+		input.ExternalId = aws.String(externalId)
+	}
+	if mfaSerial != "" {
+		token, err := mfaTokenProvider()
+		if err != nil {
+			return nil, err
+		}
+		input.SerialNumber = aws.String(mfaSerial)
+		input.TokenCode = aws.String(token)
+	}
 
 	output, err := stsClient.AssumeRole(&input)
 	if err != nil {
@@ -152,8 +468,248 @@ func AssumeIamRole(iamRoleArn string, sessionDurationSeconds int64) (*sts.Creden
 	return output.Credentials, nil
 }
 
-// Return the AWS caller identity associated with the current set of credentials
+// Return the temporary AWS credentials to use the given IAM role, assuming it only if the credentials from assuming it
+// earlier in the run are about to expire. See assumedRoleCredentials. As the credentials are shared, the MFA token of a
+// role that requires MFA is only asked for once per session, rather than by each module of a *-all command.
+func AssumeIamRoleWithSharedCredentials(iamRoleArn string, sessionDurationSeconds int64, externalId string, mfaSerial string, mfaTokenProvider func() (string, error)) (*sts.Credentials, error) {
+	key := assumedRoleKey{roleArn: iamRoleArn, sessionDurationSeconds: sessionDurationSeconds, externalId: externalId, mfaSerial: mfaSerial, envCredsID: envCredsID(nil)}
+	return shareAssumedRoleCredentials(key, nil, func() (*sts.Credentials, error) {
+		return AssumeIamRole(iamRoleArn, sessionDurationSeconds, externalId, mfaSerial, mfaTokenProvider)
+	})
+}
+
+// Make an API call to AWS to assume the IAM role specified with the given web identity token, e.g. the OIDC token of a
+// GitHub Actions job or the service account token of an EKS pod, and return the temporary AWS credentials to use that
+// role. The token is either the path of a file to read it from, which is read on each call as such tokens are rotated,
+// or the token itself.
+func AssumeIamRoleWithWebIdentity(iamRoleArn string, sessionDurationSeconds int64, webIdentityToken string) (*sts.Credentials, error) {
+	return assumeIamRoleWithWebIdentity(iamRoleArn, "", sessionDurationSeconds, webIdentityToken, nil)
+}
+
+// Assume the given IAM role with the given web identity token, as described by AssumeIamRoleWithWebIdentity, at the STS
+// endpoint of the region of the given options, if any, or of the one set via --terragrunt-aws-endpoint, unless the
+// options set the ones to assume roles with web identity tokens at. An empty session name means a generated one.
+func assumeIamRoleWithWebIdentity(iamRoleArn string, sessionName string, sessionDurationSeconds int64, webIdentityToken string, terragruntOptions *options.TerragruntOptions) (*sts.Credentials, error) {
+	token, err := readWebIdentityToken(webIdentityToken)
+	if err != nil {
+		return nil, err
+	}
+	role := oidc.AwsRole{RoleArn: iamRoleArn, SessionName: sessionName, DurationSeconds: sessionDurationSeconds, Region: envRegion(terragruntOptions)}
+	if terragruntOptions != nil {
+		role.Endpoint = terragruntOptions.AwsEndpoints["sts"]
+		role.RegionalEndpoint = terragruntOptions.AwsStsRegionalEndpoints
+		if terragruntOptions.IamWebIdentityRegion != "" {
+			role.Region = terragruntOptions.IamWebIdentityRegion
+		}
+		if terragruntOptions.IamWebIdentityStsEndpoint != "" {
+			role.Endpoint = terragruntOptions.IamWebIdentityStsEndpoint
+		}
+	}
+	return oidc.AwsCredentials(token, role)
+}
+
+// Return the given web identity token, or the contents of the file at the given path if it's the path of a file
+func readWebIdentityToken(webIdentityToken string) (string, error) {
+	if !util.FileExists(webIdentityToken) {
+		return webIdentityToken, nil
+	}
+	contents, err := util.ReadFileAsString(webIdentityToken)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(contents), nil
+}
+
+// Return the temporary AWS credentials to use the given IAM role with the given web identity token, assuming it only if
+// the credentials from assuming it earlier in the run are about to expire. See assumedRoleCredentials.
+func AssumeIamRoleWithWebIdentityWithSharedCredentials(iamRoleArn string, sessionDurationSeconds int64, webIdentityToken string) (*sts.Credentials, error) {
+	return assumeIamRoleWithWebIdentityWithSharedCredentials(iamRoleArn, "", sessionDurationSeconds, webIdentityToken, nil)
+}
+
+func assumeIamRoleWithWebIdentityWithSharedCredentials(iamRoleArn string, sessionName string, sessionDurationSeconds int64, webIdentityToken string, terragruntOptions *options.TerragruntOptions) (*sts.Credentials, error) {
+	key := assumedRoleKey{roleArn: iamRoleArn, sessionName: sessionName, sessionDurationSeconds: sessionDurationSeconds, webIdentityToken: webIdentityToken}
+	return shareAssumedRoleCredentials(key, nil, func() (*sts.Credentials, error) {
+		return assumeIamRoleWithWebIdentity(iamRoleArn, sessionName, sessionDurationSeconds, webIdentityToken, terragruntOptions)
+	})
+}
+
+// Return the credentials shared by the given key, if they're still valid for at least half of the session, or else the
+// ones the given function gets by assuming the role again. When the given options, if any, enable the keyring cache, the
+// credentials are also shared with the next runs via the keyring of the OS. See credentialsKeyring.
+func shareAssumedRoleCredentials(key assumedRoleKey, terragruntOptions *options.TerragruntOptions, assumeRole func() (*sts.Credentials, error)) (*sts.Credentials, error) {
+	rawLock, _ := assumedRoleLocks.LoadOrStore(key, &sync.Mutex{})
+	lock := rawLock.(*sync.Mutex)
+	lock.Lock()
+	defer lock.Unlock()
+
+	if creds, isShared := assumedRoleCredentials.Load(key); isShared {
+		if credentialsValidForHalfOfSession(creds.(*sts.Credentials), key.sessionDurationSeconds, time.Now()) {
+			return creds.(*sts.Credentials), nil
+		}
+	}
+
+	useKeyring := terragruntOptions != nil && terragruntOptions.AwsKeyringCache
+	if useKeyring {
+		if creds := loadKeyringCredentials(credentialsKeyring, key, terragruntOptions); creds != nil {
+			assumedRoleCredentials.Store(key, creds)
+			return creds, nil
+		}
+	}
+
+	creds, err := assumeRole()
+	if err != nil {
+		return nil, err
+	}
+
+	assumedRoleCredentials.Store(key, creds)
+	if useKeyring {
+		storeKeyringCredentials(credentialsKeyring, key, creds, terragruntOptions)
+	}
+	return creds, nil
+}
+
+// Assume the IAM role of terragrunt with the duration, and the web identity token or the external ID and MFA device, set
+// in the given options, sharing the credentials as described by AssumeIamRoleWithSharedCredentials
+func assumeIamRoleOfOptionsWithSharedCredentials(terragruntOptions *options.TerragruntOptions) (*sts.Credentials, error) {
+	return assumeIamRoleOfOptionsWithSessionWithSharedCredentials(terragruntOptions, "", 0)
+}
+
+// Assume the IAM role of terragrunt as described by assumeIamRoleOfOptionsWithSharedCredentials, with the given session
+// name and duration, if any, e.g. the ones of the config of a session. An empty session name means the one of the
+// options for web identity tokens, if any, or else a generated one, and a zero duration means the one of the options. The roles of an IAM role chain keep the session names and durations of
+// their own.
+func assumeIamRoleOfOptionsWithSessionWithSharedCredentials(terragruntOptions *options.TerragruntOptions, sessionName string, sessionDurationSeconds int64) (*sts.Credentials, error) {
+	if chain := terragruntOptions.IamRoleChain; len(chain) > 0 {
+		key := assumedRoleKey{
+			roleArn:                chain[len(chain)-1].RoleArn,
+			sessionDurationSeconds: iamRoleHopDurationSeconds(chain[len(chain)-1], terragruntOptions),
+			externalId:             terragruntOptions.IamAssumeRoleExternalId,
+			mfaSerial:              terragruntOptions.IamAssumeRoleMfaSerial,
+			webIdentityToken:       terragruntOptions.IamWebIdentityToken,
+			chain:                  iamRoleChainID(chain),
+			awsProfile:             terragruntOptions.AwsProfile,
+			envCredsID:             envCredsID(terragruntOptions),
+		}
+		return shareAssumedRoleCredentials(key, terragruntOptions, func() (*sts.Credentials, error) {
+			return assumeIamRoleChain(terragruntOptions)
+		})
+	}
+	if sessionDurationSeconds <= 0 {
+		sessionDurationSeconds = terragruntOptions.IamAssumeRoleDuration
+	}
+	if terragruntOptions.IamWebIdentityToken != "" {
+		if sessionName == "" {
+			sessionName = terragruntOptions.IamWebIdentitySessionName
+		}
+		return assumeIamRoleWithWebIdentityWithSharedCredentials(terragruntOptions.IamRole, sessionName, sessionDurationSeconds, terragruntOptions.IamWebIdentityToken, terragruntOptions)
+	}
+	key := assumedRoleKey{
+		roleArn:                terragruntOptions.IamRole,
+		sessionName:            sessionName,
+		sessionDurationSeconds: sessionDurationSeconds,
+		externalId:             terragruntOptions.IamAssumeRoleExternalId,
+		mfaSerial:              terragruntOptions.IamAssumeRoleMfaSerial,
+		awsProfile:             terragruntOptions.AwsProfile,
+		envCredsID:             envCredsID(terragruntOptions),
+	}
+	return shareAssumedRoleCredentials(key, terragruntOptions, func() (*sts.Credentials, error) {
+		sess, err := newSessionWithDefaultCredentials(terragruntOptions)
+		if err != nil {
+			return nil, err
+		}
+		return assumeIamRoleWithSession(
+			sess,
+			terragruntOptions.IamRole,
+			sessionName,
+			sessionDurationSeconds,
+			terragruntOptions.IamAssumeRoleExternalId,
+			terragruntOptions.IamAssumeRoleMfaSerial,
+			mfaTokenProvider(terragruntOptions),
+		)
+	})
+}
+
+// Assume the roles of the IAM role chain set in the given options in sequence, each with the credentials of the one
+// before it, and return the temporary AWS credentials of the last one. The first role is assumed with the web identity
+// token, if any, or else with the credentials found in the environment and the MFA device, if any. The external ID, if
+// any, is passed when assuming the last role.
+func assumeIamRoleChain(terragruntOptions *options.TerragruntOptions) (*sts.Credentials, error) {
+	chain := terragruntOptions.IamRoleChain
+
+	var creds *sts.Credentials
+	for i, hop := range chain {
+		durationSeconds := iamRoleHopDurationSeconds(hop, terragruntOptions)
+		externalId := ""
+		if i == len(chain)-1 {
+			externalId = terragruntOptions.IamAssumeRoleExternalId
+		}
+		terragruntOptions.Logger.Debugf("Assuming IAM role %s (%d of %d in the chain) with a session duration of %d seconds.", hop.RoleArn, i+1, len(chain), durationSeconds)
+
+		var err error
+		switch {
+		case i == 0 && terragruntOptions.IamWebIdentityToken != "":
+			creds, err = assumeIamRoleWithWebIdentity(hop.RoleArn, hop.SessionName, durationSeconds, terragruntOptions.IamWebIdentityToken, terragruntOptions)
+		case i == 0:
+			var sess *session.Session
+			if sess, err = newSessionWithDefaultCredentials(terragruntOptions); err == nil {
+				creds, err = assumeIamRoleWithSession(sess, hop.RoleArn, hop.SessionName, durationSeconds, externalId, terragruntOptions.IamAssumeRoleMfaSerial, mfaTokenProvider(terragruntOptions))
+			}
+		default:
+			var sess *session.Session
+			if sess, err = newSessionWithAssumedRoleCredentials(creds, terragruntOptions); err == nil {
+				creds, err = assumeIamRoleWithSession(sess, hop.RoleArn, hop.SessionName, durationSeconds, externalId, "", nil)
+			}
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return creds, nil
+}
+
+// Create a session with the given temporary credentials of an assumed role
+func newSessionWithAssumedRoleCredentials(creds *sts.Credentials, terragruntOptions *options.TerragruntOptions) (*session.Session, error) {
+	sessionOptions := session.Options{
+		Config:            aws.Config{Credentials: credentials.NewStaticCredentials(aws.StringValue(creds.AccessKeyId), aws.StringValue(creds.SecretAccessKey), aws.StringValue(creds.SessionToken)), HTTPClient: newSessionHttpClient()},
+		SharedConfigState: session.SharedConfigEnable,
+	}
+	applyEndpoints(&sessionOptions.Config, nil, terragruntOptions)
+	sess, err := session.NewSessionWithOptions(sessionOptions)
+	if err != nil {
+		return nil, errors.WithStackTrace(err)
+	}
+	return sess, nil
+}
+
+// Return the session duration, in seconds, of the given role of the IAM role chain set in the given options
+func iamRoleHopDurationSeconds(hop options.IamRoleHop, terragruntOptions *options.TerragruntOptions) int64 {
+	if hop.DurationSeconds > 0 {
+		return hop.DurationSeconds
+	}
+	return terragruntOptions.IamAssumeRoleDuration
+}
+
+// Returns true if the given credentials of an assumed role session with the given duration are still valid, at the
+// given time, for at least half of that duration
+func credentialsValidForHalfOfSession(creds *sts.Credentials, sessionDurationSeconds int64, now time.Time) bool {
+	if creds.Expiration == nil {
+		return false
+	}
+	if sessionDurationSeconds <= 0 {
+		sessionDurationSeconds = options.DEFAULT_IAM_ASSUME_ROLE_DURATION
+	}
+	halfOfSession := time.Duration(sessionDurationSeconds) * time.Second / 2
+	return creds.Expiration.After(now.Add(halfOfSession))
+}
+
+// Return the AWS caller identity associated with the current set of credentials. Like the session, the identity is
+// shared with the other callers passing the same config for the rest of the run.
 func GetAWSCallerIdentity(config *AwsSessionConfig, terragruntOptions *options.TerragruntOptions) (sts.GetCallerIdentityOutput, error) {
+	key := newSharedSessionKey(config, terragruntOptions)
+	if identity, isShared := sharedCallerIdentities.Load(key); isShared {
+		return identity.(sts.GetCallerIdentityOutput), nil
+	}
+
 	sess, err := CreateAwsSession(config, terragruntOptions)
 	if err != nil {
 		return sts.GetCallerIdentityOutput{}, errors.WithStackTrace(err)
@@ -164,6 +720,7 @@ func GetAWSCallerIdentity(config *AwsSessionConfig, terragruntOptions *options.T
 		return sts.GetCallerIdentityOutput{}, errors.WithStackTrace(err)
 	}
 
+	sharedCallerIdentities.Store(key, *identity)
 	return *identity, nil
 }
 
@@ -198,14 +755,18 @@ func GetAWSUserID(config *AwsSessionConfig, terragruntOptions *options.Terragrun
 }
 
 // Assume an IAM role, if one is specified, by making API calls to Amazon STS and setting the environment variables
-// we get back inside of terragruntOptions.Env
+// we get back inside of terragruntOptions.Env. The role is assumed with the credentials the Env had before, which are
+// kept in IamRoleSourceEnv, so that the sessions of the unit assume it with the same ones.
 func AssumeRoleAndUpdateEnvIfNecessary(terragruntOptions *options.TerragruntOptions) error {
 	if terragruntOptions.IamRole == "" {
 		return nil
 	}
+	if terragruntOptions.IamRoleSourceEnv == nil {
+		terragruntOptions.IamRoleSourceEnv = credentialsEnv(terragruntOptions)
+	}
 
 	terragruntOptions.Logger.Debugf("Assuming IAM role %s with a session duration of %d seconds.", terragruntOptions.IamRole, terragruntOptions.IamAssumeRoleDuration)
-	creds, err := AssumeIamRole(terragruntOptions.IamRole, terragruntOptions.IamAssumeRoleDuration)
+	creds, err := assumeIamRoleOfOptionsWithSharedCredentials(terragruntOptions)
 	if err != nil {
 		return err
 	}
@@ -217,3 +778,18 @@ func AssumeRoleAndUpdateEnvIfNecessary(terragruntOptions *options.TerragruntOpti
 
 	return nil
 }
+
+// Custom error types
+
+type MissingMfaToken struct {
+	MfaSerial string
+	// Whether the token set via --terragrunt-iam-assume-role-mfa-token was already used to assume the role
+	TokenUsed bool
+}
+
+func (err MissingMfaToken) Error() string {
+	if err.TokenUsed {
+		return fmt.Sprintf("Assuming the IAM role again requires a new token of the MFA device %s, as the one set with --terragrunt-iam-assume-role-mfa-token or the TERRAGRUNT_IAM_ASSUME_ROLE_MFA_TOKEN env var was already used, and each token can only be used once. Run terragrunt interactively to be asked for a new one, or increase --terragrunt-iam-assume-role-duration so that the credentials of the role last for the whole run.", err.MfaSerial)
+	}
+	return fmt.Sprintf("Assuming the IAM role requires a token of the MFA device %s. Set it with --terragrunt-iam-assume-role-mfa-token or the TERRAGRUNT_IAM_ASSUME_ROLE_MFA_TOKEN env var, or run terragrunt interactively to be asked for it.", err.MfaSerial)
+}
@@ -0,0 +1,215 @@
+package aws_helper
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/sts"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/gruntwork-io/terragrunt/errors"
+	"github.com/gruntwork-io/terragrunt/options"
+)
+
+func TestCredentialsValidForHalfOfSession(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2021, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	testCases := []struct {
+		expiresIn              time.Duration
+		sessionDurationSeconds int64
+		expected               bool
+	}{
+		{time.Hour, 3600, true},
+		{31 * time.Minute, 3600, true},
+		{29 * time.Minute, 3600, false},
+		{-time.Minute, 3600, false},
+		{10 * time.Minute, 900, true},
+		{5 * time.Minute, 900, false},
+		// Without a duration, the default one of an hour applies
+		{29 * time.Minute, 0, false},
+	}
+
+	for _, testCase := range testCases {
+		creds := &sts.Credentials{Expiration: aws.Time(now.Add(testCase.expiresIn))}
+		actual := credentialsValidForHalfOfSession(creds, testCase.sessionDurationSeconds, now)
+		assert.Equal(t, testCase.expected, actual, "Expiring in %v with a session of %d seconds", testCase.expiresIn, testCase.sessionDurationSeconds)
+	}
+
+	assert.False(t, credentialsValidForHalfOfSession(&sts.Credentials{}, 3600, now))
+}
+
+func TestAssumeIamRoleWithSharedCredentials(t *testing.T) {
+	t.Parallel()
+
+	roleArn := "arn:aws:iam::123456789012:role/test-shared-credentials"
+	creds := &sts.Credentials{
+		AccessKeyId:     aws.String("AKIAEXAMPLE"),
+		SecretAccessKey: aws.String("secret"),
+		SessionToken:    aws.String("token"),
+		Expiration:      aws.Time(time.Now().Add(time.Hour)),
+	}
+	assumedRoleCredentials.Store(assumedRoleKey{roleArn: roleArn, sessionDurationSeconds: 3600, externalId: "acme", envCredsID: envCredsID(nil)}, creds)
+
+	// The credentials are still valid, so the role is not assumed again
+	for i := 0; i < 3; i++ {
+		sharedCreds, err := AssumeIamRoleWithSharedCredentials(roleArn, 3600, "acme", "", nil)
+		require.NoError(t, err)
+		assert.True(t, creds == sharedCreds, "Expected the shared credentials to be returned")
+	}
+}
+
+func TestAssumeIamRoleWithSharedCredentialsAsksForMfaTokenOnce(t *testing.T) {
+	t.Parallel()
+
+	roleArn := "arn:aws:iam::123456789012:role/test-shared-mfa-credentials"
+	mfaSerial := "arn:aws:iam::123456789012:mfa/test"
+	creds := &sts.Credentials{
+		AccessKeyId:     aws.String("AKIAEXAMPLE"),
+		SecretAccessKey: aws.String("secret"),
+		SessionToken:    aws.String("token"),
+		Expiration:      aws.Time(time.Now().Add(time.Hour)),
+	}
+	assumedRoleCredentials.Store(assumedRoleKey{roleArn: roleArn, sessionDurationSeconds: 3600, mfaSerial: mfaSerial, envCredsID: envCredsID(nil)}, creds)
+
+	// The credentials are still valid, so the user is not asked for another token
+	tokenProvider := func() (string, error) {
+		t.Error("Unexpected request for an MFA token")
+		return "", nil
+	}
+	for i := 0; i < 3; i++ {
+		sharedCreds, err := AssumeIamRoleWithSharedCredentials(roleArn, 3600, "", mfaSerial, tokenProvider)
+		require.NoError(t, err)
+		assert.True(t, creds == sharedCreds, "Expected the shared credentials to be returned")
+	}
+}
+
+func TestMfaTokenProvider(t *testing.T) {
+	t.Parallel()
+
+	terragruntOptions, err := options.NewTerragruntOptionsForTest("terragrunt.hcl")
+	require.NoError(t, err)
+	terragruntOptions.IamAssumeRoleMfaSerial = "arn:aws:iam::123456789012:mfa/token-provider-test"
+	terragruntOptions.IamAssumeRoleMfaToken = "123456"
+	terragruntOptions.NonInteractive = true
+
+	token, err := mfaTokenProvider(terragruntOptions)()
+	require.NoError(t, err)
+	assert.Equal(t, "123456", token)
+
+	// The token of the flag was used already, and there's no one to ask for a new one when running non-interactively
+	_, err = mfaTokenProvider(terragruntOptions.Clone(terragruntOptions.TerragruntConfigPath))()
+	assert.True(t, errors.IsError(err, MissingMfaToken{MfaSerial: terragruntOptions.IamAssumeRoleMfaSerial, TokenUsed: true}), "Unexpected error: %v", err)
+
+	terragruntOptions.IamAssumeRoleMfaToken = ""
+	_, err = mfaTokenProvider(terragruntOptions)()
+	assert.True(t, errors.IsError(err, MissingMfaToken{MfaSerial: terragruntOptions.IamAssumeRoleMfaSerial}), "Unexpected error: %v", err)
+}
+
+func TestReadWebIdentityToken(t *testing.T) {
+	t.Parallel()
+
+	tmpDir, err := ioutil.TempDir("", "web-identity-token")
+	require.NoError(t, err)
+	defer os.RemoveAll(tmpDir)
+	tokenFile := filepath.Join(tmpDir, "token")
+	require.NoError(t, ioutil.WriteFile(tokenFile, []byte("token-from-file\n"), 0600))
+
+	token, err := readWebIdentityToken(tokenFile)
+	require.NoError(t, err)
+	assert.Equal(t, "token-from-file", token)
+
+	token, err = readWebIdentityToken("eyJhbGciOiJSUzI1NiJ9.payload.signature")
+	require.NoError(t, err)
+	assert.Equal(t, "eyJhbGciOiJSUzI1NiJ9.payload.signature", token)
+}
+
+func TestAssumeIamRoleChainWithSharedCredentials(t *testing.T) {
+	t.Parallel()
+
+	terragruntOptions, err := options.NewTerragruntOptionsForTest("terragrunt.hcl")
+	require.NoError(t, err)
+	terragruntOptions.IamRoleChain = []options.IamRoleHop{
+		{RoleArn: "arn:aws:iam::111111111111:role/test-chain-hub", SessionName: "hub"},
+		{RoleArn: "arn:aws:iam::222222222222:role/test-chain-deploy", DurationSeconds: 900},
+	}
+	terragruntOptions.IamRole = "arn:aws:iam::222222222222:role/test-chain-deploy"
+	creds := &sts.Credentials{
+		AccessKeyId:     aws.String("AKIAEXAMPLE"),
+		SecretAccessKey: aws.String("secret"),
+		SessionToken:    aws.String("token"),
+		Expiration:      aws.Time(time.Now().Add(15 * time.Minute)),
+	}
+	key := assumedRoleKey{
+		roleArn:                terragruntOptions.IamRole,
+		sessionDurationSeconds: 900,
+		chain:                  iamRoleChainID(terragruntOptions.IamRoleChain),
+		envCredsID:             envCredsID(terragruntOptions),
+	}
+	assumedRoleCredentials.Store(key, creds)
+
+	// The credentials of the last role are still valid for more than half of its session, so the chain is not assumed
+	// again
+	sharedCreds, err := assumeIamRoleOfOptionsWithSharedCredentials(terragruntOptions)
+	require.NoError(t, err)
+	assert.True(t, creds == sharedCreds, "Expected the shared credentials to be returned")
+}
+
+func TestSharedSessionKeyIncludesSessionNameAndDuration(t *testing.T) {
+	t.Parallel()
+
+	terragruntOptions, err := options.NewTerragruntOptionsForTest("terragrunt.hcl")
+	require.NoError(t, err)
+	terragruntOptions.IamRole = "arn:aws:iam::123456789012:role/test-session-key"
+
+	config := &AwsSessionConfig{Region: "us-east-1", RoleArn: "arn:aws:iam::123456789012:role/test-config-role"}
+	key := newSharedSessionKey(config, terragruntOptions)
+
+	withSessionName := *config
+	withSessionName.SessionName = "deploy"
+	assert.NotEqual(t, key, newSharedSessionKey(&withSessionName, terragruntOptions))
+
+	withDuration := *config
+	withDuration.AssumeRoleDuration = 15 * time.Minute
+	assert.NotEqual(t, key, newSharedSessionKey(&withDuration, terragruntOptions))
+
+	otherOptions := terragruntOptions.Clone(terragruntOptions.TerragruntConfigPath)
+	otherOptions.IamAssumeRoleDuration = 900
+	assert.NotEqual(t, newSharedSessionKey(nil, terragruntOptions), newSharedSessionKey(nil, otherOptions))
+}
+
+func TestAssumeIamRoleOfOptionsWithSessionWithSharedCredentials(t *testing.T) {
+	t.Parallel()
+
+	terragruntOptions, err := options.NewTerragruntOptionsForTest("terragrunt.hcl")
+	require.NoError(t, err)
+	terragruntOptions.IamRole = "arn:aws:iam::123456789012:role/test-shared-session-name"
+	terragruntOptions.IamAssumeRoleMfaSerial = "arn:aws:iam::123456789012:mfa/test"
+	creds := &sts.Credentials{
+		AccessKeyId:     aws.String("AKIAEXAMPLE"),
+		SecretAccessKey: aws.String("secret"),
+		SessionToken:    aws.String("token"),
+		Expiration:      aws.Time(time.Now().Add(15 * time.Minute)),
+	}
+	key := assumedRoleKey{
+		roleArn:                terragruntOptions.IamRole,
+		sessionName:            "deploy",
+		sessionDurationSeconds: 900,
+		mfaSerial:              terragruntOptions.IamAssumeRoleMfaSerial,
+		awsProfile:             terragruntOptions.AwsProfile,
+		envCredsID:             envCredsID(terragruntOptions),
+	}
+	assumedRoleCredentials.Store(key, creds)
+
+	// The credentials assumed with the session name and duration of a config are shared by those, not by the defaults
+	// of the options
+	sharedCreds, err := assumeIamRoleOfOptionsWithSessionWithSharedCredentials(terragruntOptions, "deploy", 900)
+	require.NoError(t, err)
+	assert.True(t, creds == sharedCreds, "Expected the shared credentials to be returned")
+}
@@ -0,0 +1,121 @@
+package aws_helper
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/endpoints"
+
+	"github.com/gruntwork-io/terragrunt/errors"
+	"github.com/gruntwork-io/terragrunt/options"
+	"github.com/gruntwork-io/terragrunt/util"
+)
+
+// The AWS services terragrunt itself calls, whose endpoints can be set via --terragrunt-aws-endpoint
+var customizableEndpointServices = []string{"dynamodb", "iam", "s3", "sts"}
+
+// ValidateCustomEndpoints returns an error if the given endpoints, by AWS service, as set via --terragrunt-aws-endpoint,
+// are for a service terragrunt doesn't call
+func ValidateCustomEndpoints(customEndpoints map[string]string) error {
+	for service := range customEndpoints {
+		if !util.ListContainsElement(customizableEndpointServices, service) {
+			return errors.WithStackTrace(UnsupportedCustomEndpointService(service))
+		}
+	}
+	return nil
+}
+
+// Set the endpoints of the given config of a session: the given custom endpoints by AWS service, if any, which take
+// precedence over the ones set via --terragrunt-aws-endpoint, and the regional STS endpoints if they're enabled via
+// --terragrunt-aws-sts-regional-endpoints. The other services use the default endpoints of the region, in the partition
+// of the region, e.g. sts.cn-north-1.amazonaws.com.cn for cn-north-1. The options may be nil.
+func applyEndpoints(awsConfig *aws.Config, customEndpoints map[string]string, terragruntOptions *options.TerragruntOptions) {
+	merged := map[string]string{}
+	if terragruntOptions != nil {
+		for service, endpoint := range terragruntOptions.AwsEndpoints {
+			merged[service] = endpoint
+		}
+		if terragruntOptions.AwsStsRegionalEndpoints {
+			awsConfig.STSRegionalEndpoint = endpoints.RegionalSTSEndpoint
+		}
+	}
+	for service, endpoint := range customEndpoints {
+		if endpoint != "" {
+			merged[service] = endpoint
+		}
+	}
+	if len(merged) > 0 {
+		awsConfig.EndpointResolver = customEndpointResolver(merged)
+	}
+}
+
+// Return a resolver of the endpoints of the AWS services that resolves the given services to the given endpoints, and
+// the others to their default endpoints. Requests to the custom endpoints are signed for the region of the session.
+func customEndpointResolver(customEndpoints map[string]string) endpoints.Resolver {
+	defaultResolver := endpoints.DefaultResolver()
+	return endpoints.ResolverFunc(func(service, region string, optFns ...func(*endpoints.Options)) (endpoints.ResolvedEndpoint, error) {
+		if endpoint, isCustom := customEndpoints[service]; isCustom {
+			return endpoints.ResolvedEndpoint{
+				URL:           endpoint,
+				SigningRegion: region,
+			}, nil
+		}
+		return defaultResolver.EndpointFor(service, region, optFns...)
+	})
+}
+
+// Return the region set in the env vars of the given options, if any, or else in the ones of the process, as the AWS CLI
+// reads it. The options may be nil.
+func envRegion(terragruntOptions *options.TerragruntOptions) string {
+	for _, envVar := range []string{"AWS_REGION", "AWS_DEFAULT_REGION"} {
+		if terragruntOptions != nil && terragruntOptions.Env[envVar] != "" {
+			return terragruntOptions.Env[envVar]
+		}
+		if region := os.Getenv(envVar); region != "" {
+			return region
+		}
+	}
+	return ""
+}
+
+// AWS regions consist of a geographic area, optionally a sovereign or government partition, a direction and a number,
+// e.g. us-east-1, us-gov-west-1 or eusc-de-east-1
+var awsRegionRegexp = regexp.MustCompile(`^[a-z]{2,}(-[a-z]+)+-[0-9]+$`)
+
+// IsRegion returns true if the given string is an AWS region, e.g. us-east-1. Regions that aren't known to the AWS SDK
+// yet count too, as long as they have the same format.
+func IsRegion(region string) bool {
+	if _, isKnown := endpoints.PartitionForRegion(endpoints.DefaultPartitions(), region); isKnown {
+		return true
+	}
+	return awsRegionRegexp.MatchString(region)
+}
+
+// PartitionOfRegion returns the AWS partition the given region is in, e.g. aws-us-gov for us-gov-west-1 and aws-cn for
+// cn-north-1, or aws for an empty region
+func PartitionOfRegion(region string) string {
+	if partition, isKnown := endpoints.PartitionForRegion(endpoints.DefaultPartitions(), region); isKnown {
+		return partition.ID()
+	}
+	return endpoints.AwsPartitionID
+}
+
+// DNSSuffixOfRegion returns the domain of the endpoints of the AWS services in the given region, e.g. amazonaws.com.cn
+// for cn-north-1
+func DNSSuffixOfRegion(region string) string {
+	if partition, isKnown := endpoints.PartitionForRegion(endpoints.DefaultPartitions(), region); isKnown {
+		return partition.DNSSuffix()
+	}
+	return "amazonaws.com"
+}
+
+// Custom error types
+
+type UnsupportedCustomEndpointService string
+
+func (service UnsupportedCustomEndpointService) Error() string {
+	return fmt.Sprintf("Can't set the endpoint of the AWS service %s, only the ones of %s", string(service), strings.Join(customizableEndpointServices, ", "))
+}
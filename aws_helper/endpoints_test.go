@@ -0,0 +1,108 @@
+package aws_helper
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/endpoints"
+	"github.com/gruntwork-io/terragrunt/errors"
+	"github.com/gruntwork-io/terragrunt/options"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateCustomEndpoints(t *testing.T) {
+	t.Parallel()
+
+	assert.NoError(t, ValidateCustomEndpoints(nil))
+	assert.NoError(t, ValidateCustomEndpoints(map[string]string{"sts": "http://localhost:4566", "s3": "http://localhost:4566"}))
+
+	err := ValidateCustomEndpoints(map[string]string{"ec2": "http://localhost:4566"})
+	require.Error(t, err)
+	assert.Equal(t, UnsupportedCustomEndpointService("ec2"), errors.Unwrap(err))
+}
+
+func TestIsRegion(t *testing.T) {
+	t.Parallel()
+
+	for _, region := range []string{"us-east-1", "us-gov-west-1", "cn-north-1", "eusc-de-east-1", "ap-southeast-12"} {
+		assert.True(t, IsRegion(region), region)
+	}
+	for _, region := range []string{"", "us-east", "US-EAST-1", "east-1", "us-east-1a"} {
+		assert.False(t, IsRegion(region), region)
+	}
+}
+
+func TestPartitionOfRegion(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		region            string
+		expectedPartition string
+		expectedDNSSuffix string
+	}{
+		{"", "aws", "amazonaws.com"},
+		{"us-east-1", "aws", "amazonaws.com"},
+		{"us-gov-west-1", "aws-us-gov", "amazonaws.com"},
+		{"cn-north-1", "aws-cn", "amazonaws.com.cn"},
+	}
+
+	for _, testCase := range testCases {
+		// Save the testCase in local scope so all the t.Run calls don't end up with the last item in the list
+		testCase := testCase
+
+		t.Run(testCase.region, func(t *testing.T) {
+			t.Parallel()
+
+			assert.Equal(t, testCase.expectedPartition, PartitionOfRegion(testCase.region))
+			assert.Equal(t, testCase.expectedDNSSuffix, DNSSuffixOfRegion(testCase.region))
+		})
+	}
+}
+
+func TestApplyEndpoints(t *testing.T) {
+	t.Parallel()
+
+	terragruntOptions, err := options.NewTerragruntOptionsForTest("endpoints_test")
+	require.NoError(t, err)
+	terragruntOptions.AwsEndpoints = map[string]string{"sts": "http://localhost:4566", "iam": "http://localhost:4566"}
+	terragruntOptions.AwsStsRegionalEndpoints = true
+
+	awsConfig := aws.NewConfig()
+	applyEndpoints(awsConfig, map[string]string{"iam": "http://iam.example.com", "s3": ""}, terragruntOptions)
+
+	assert.Equal(t, endpoints.RegionalSTSEndpoint, awsConfig.STSRegionalEndpoint)
+
+	sts, err := awsConfig.EndpointResolver.EndpointFor("sts", "eu-west-1")
+	require.NoError(t, err)
+	assert.Equal(t, "http://localhost:4566", sts.URL)
+	assert.Equal(t, "eu-west-1", sts.SigningRegion)
+
+	iam, err := awsConfig.EndpointResolver.EndpointFor("iam", "eu-west-1")
+	require.NoError(t, err)
+	assert.Equal(t, "http://iam.example.com", iam.URL)
+
+	s3, err := awsConfig.EndpointResolver.EndpointFor("s3", "cn-north-1")
+	require.NoError(t, err)
+	assert.Equal(t, "https://s3.cn-north-1.amazonaws.com.cn", s3.URL)
+}
+
+func TestApplyEndpointsWithoutCustomEndpoints(t *testing.T) {
+	t.Parallel()
+
+	awsConfig := aws.NewConfig()
+	applyEndpoints(awsConfig, map[string]string{"s3": ""}, nil)
+
+	assert.Nil(t, awsConfig.EndpointResolver)
+	assert.Equal(t, endpoints.UnsetSTSEndpoint, awsConfig.STSRegionalEndpoint)
+}
+
+func TestEnvRegion(t *testing.T) {
+	t.Parallel()
+
+	terragruntOptions, err := options.NewTerragruntOptionsForTest("endpoints_test")
+	require.NoError(t, err)
+	terragruntOptions.Env = map[string]string{"AWS_REGION": "us-gov-west-1"}
+
+	assert.Equal(t, "us-gov-west-1", envRegion(terragruntOptions))
+}
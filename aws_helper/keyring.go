@@ -0,0 +1,89 @@
+package aws_helper
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/sts"
+
+	"github.com/gruntwork-io/terragrunt/keyring"
+	"github.com/gruntwork-io/terragrunt/options"
+)
+
+// The prefix of the keys the credentials of assumed roles are cached under in the keyring
+const keyringCredentialsKeyPrefix = "aws-credentials-"
+
+// The keyring of the OS the credentials of assumed roles are cached in across runs, when enabled via
+// --terragrunt-aws-keyring-cache, so that repeated runs don't assume the role, and ask for the MFA token, each time
+var credentialsKeyring = keyring.New()
+
+// The credentials of an assumed role, as they're cached in the keyring
+type keyringCredentials struct {
+	AccessKeyId     string
+	SecretAccessKey string
+	SessionToken    string
+	Expiration      time.Time
+}
+
+// Return the key the credentials of an assumed role are cached under in the keyring. The key is a hash of the role and
+// session, i.e. the role, the settings it's assumed with and the credentials it's assumed with, as the latter include
+// the secrets of the env vars.
+func keyringCredentialsKey(key assumedRoleKey) string {
+	hash := sha256.Sum256([]byte(fmt.Sprintf("%+v", key)))
+	return keyringCredentialsKeyPrefix + hex.EncodeToString(hash[:])
+}
+
+// Return the credentials of the given role cached in the given keyring, if they're still valid for at least half of the
+// session, or nil otherwise. A keyring that's unavailable, e.g. on a server without a Secret Service, is not an error,
+// as the role is then assumed as usual.
+func loadKeyringCredentials(store keyring.Keyring, key assumedRoleKey, terragruntOptions *options.TerragruntOptions) *sts.Credentials {
+	secret, err := store.Get(keyringCredentialsKey(key))
+	if err != nil {
+		if !keyring.IsSecretNotFound(err) {
+			terragruntOptions.Logger.Debugf("Could not read the credentials of IAM role %s from the keyring: %v", key.roleArn, err)
+		}
+		return nil
+	}
+
+	var cached keyringCredentials
+	if err := json.Unmarshal([]byte(secret), &cached); err != nil {
+		terragruntOptions.Logger.Debugf("Ignoring the invalid credentials of IAM role %s in the keyring: %v", key.roleArn, err)
+		return nil
+	}
+	creds := &sts.Credentials{
+		AccessKeyId:     aws.String(cached.AccessKeyId),
+		SecretAccessKey: aws.String(cached.SecretAccessKey),
+		SessionToken:    aws.String(cached.SessionToken),
+		Expiration:      aws.Time(cached.Expiration),
+	}
+	if !credentialsValidForHalfOfSession(creds, key.sessionDurationSeconds, time.Now()) {
+		return nil
+	}
+
+	terragruntOptions.Logger.Debugf("Using the credentials of IAM role %s cached in the keyring, which expire at %s.", key.roleArn, cached.Expiration.Format(time.RFC3339))
+	return creds
+}
+
+// Cache the given credentials of the given role in the given keyring. Failing to is not an error, see
+// loadKeyringCredentials.
+func storeKeyringCredentials(store keyring.Keyring, key assumedRoleKey, creds *sts.Credentials, terragruntOptions *options.TerragruntOptions) {
+	secret, err := json.Marshal(keyringCredentials{
+		AccessKeyId:     aws.StringValue(creds.AccessKeyId),
+		SecretAccessKey: aws.StringValue(creds.SecretAccessKey),
+		SessionToken:    aws.StringValue(creds.SessionToken),
+		Expiration:      aws.TimeValue(creds.Expiration),
+	})
+	if err != nil {
+		terragruntOptions.Logger.Debugf("Could not cache the credentials of IAM role %s in the keyring: %v", key.roleArn, err)
+		return
+	}
+
+	label := fmt.Sprintf("Terragrunt: AWS credentials of %s", key.roleArn)
+	if err := store.Set(keyringCredentialsKey(key), label, string(secret)); err != nil {
+		terragruntOptions.Logger.Debugf("Could not cache the credentials of IAM role %s in the keyring: %v", key.roleArn, err)
+	}
+}
@@ -0,0 +1,74 @@
+package aws_helper
+
+import (
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/sts"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/gruntwork-io/terragrunt/keyring"
+	"github.com/gruntwork-io/terragrunt/options"
+)
+
+// A keyring that keeps the secrets in memory, rather than in the keyring of the OS
+type inMemoryKeyring map[string]string
+
+func (store inMemoryKeyring) Get(key string) (string, error) {
+	secret, isStored := store[key]
+	if !isStored {
+		return "", keyring.SecretNotFound(key)
+	}
+	return secret, nil
+}
+
+func (store inMemoryKeyring) Set(key string, label string, secret string) error {
+	store[key] = secret
+	return nil
+}
+
+func (store inMemoryKeyring) Delete(key string) error {
+	delete(store, key)
+	return nil
+}
+
+func TestKeyringCredentials(t *testing.T) {
+	t.Parallel()
+
+	terragruntOptions, err := options.NewTerragruntOptionsForTest("terragrunt.hcl")
+	require.NoError(t, err)
+
+	store := inMemoryKeyring{}
+	key := assumedRoleKey{roleArn: "arn:aws:iam::123456789012:role/test-keyring", sessionDurationSeconds: 3600, mfaSerial: "arn:aws:iam::123456789012:mfa/test"}
+	assert.Nil(t, loadKeyringCredentials(store, key, terragruntOptions))
+
+	creds := &sts.Credentials{
+		AccessKeyId:     aws.String("AKIAEXAMPLE"),
+		SecretAccessKey: aws.String("secret"),
+		SessionToken:    aws.String("token"),
+		Expiration:      aws.Time(time.Now().Add(time.Hour).Truncate(time.Second)),
+	}
+	storeKeyringCredentials(store, key, creds, terragruntOptions)
+	cached := loadKeyringCredentials(store, key, terragruntOptions)
+	require.NotNil(t, cached)
+	assert.Equal(t, "AKIAEXAMPLE", aws.StringValue(cached.AccessKeyId))
+	assert.Equal(t, "secret", aws.StringValue(cached.SecretAccessKey))
+	assert.Equal(t, "token", aws.StringValue(cached.SessionToken))
+	assert.True(t, creds.Expiration.Equal(aws.TimeValue(cached.Expiration)))
+
+	// The credentials are cached by role and session
+	otherKey := key
+	otherKey.envCredsID = "AWS_PROFILE=other\n"
+	assert.NotEqual(t, keyringCredentialsKey(key), keyringCredentialsKey(otherKey))
+	assert.Nil(t, loadKeyringCredentials(store, otherKey, terragruntOptions))
+
+	// Credentials valid for less than half of the session are assumed again
+	creds.Expiration = aws.Time(time.Now().Add(20 * time.Minute))
+	storeKeyringCredentials(store, key, creds, terragruntOptions)
+	assert.Nil(t, loadKeyringCredentials(store, key, terragruntOptions))
+
+	store[keyringCredentialsKey(key)] = "not json"
+	assert.Nil(t, loadKeyringCredentials(store, key, terragruntOptions))
+}
@@ -0,0 +1,257 @@
+package aws_helper
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/credentials/stscreds"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/sirupsen/logrus"
+
+	"github.com/gruntwork-io/terragrunt/errors"
+	"github.com/gruntwork-io/terragrunt/options"
+	"github.com/gruntwork-io/terragrunt/shell"
+	"github.com/gruntwork-io/terragrunt/util"
+)
+
+// The region of the STS endpoint the roles of profiles are assumed at when neither the profile nor the environment set
+// one
+const defaultStsRegion = "us-east-1"
+
+// The env vars of static credentials, which take precedence over the AWS_PROFILE env var in the AWS SDK and the AWS CLI
+var staticCredentialsEnvVars = []string{"AWS_ACCESS_KEY_ID", "AWS_SECRET_ACCESS_KEY", "AWS_SESSION_TOKEN", "AWS_SECURITY_TOKEN"}
+
+// The env vars terragrunt passes the AWS credentials of a unit to terraform with: the profile the unit is pinned to, or
+// the credentials of the IAM role it assumes
+var unitCredentialsEnvVars = append([]string{"AWS_PROFILE"}, staticCredentialsEnvVars...)
+
+// profileSessionOptions returns the options of a session with the credentials of the given profile, or of the env of the
+// unit of the given options if it's empty, found the same way the AWS CLI finds them: the static credentials of the env,
+// if any, or else the profile of the env, via the shared config and credentials files, including credential_process,
+// credential_source and source_profile chains, asking for the MFA token of the profiles that set an mfa_serial, and
+// with the profiles the AWS SDK doesn't support. See profileCredentials and credentialsEnv.
+func profileSessionOptions(profile string, terragruntOptions *options.TerragruntOptions) (session.Options, error) {
+	env := credentialsEnv(terragruntOptions)
+	if profile == "" {
+		profile = envProfileOf(env)
+	}
+	sessionOptions := session.Options{
+		Config:                  aws.Config{MaxRetries: aws.Int(AWS_API_MAX_RETRIES), HTTPClient: newSessionHttpClient()},
+		Profile:                 profile,
+		SharedConfigState:       session.SharedConfigEnable,
+		AssumeRoleTokenProvider: profileMfaTokenProvider(profile, terragruntOptions),
+	}
+	if region := envRegion(terragruntOptions); region != "" {
+		sessionOptions.Config.Region = aws.String(region)
+	}
+	applyEndpoints(&sessionOptions.Config, nil, terragruntOptions)
+	if sessionOptions.Profile == "" {
+		// The static credentials of the env take precedence over its profile, as they do in the AWS SDK
+		if creds := envStaticCredentials(env); creds != nil {
+			sessionOptions.Config.Credentials = creds
+			return sessionOptions, nil
+		}
+	}
+	creds, err := profileCredentials(profile, terragruntOptions)
+	if err != nil {
+		return sessionOptions, err
+	}
+	sessionOptions.Config.Credentials = creds
+	return sessionOptions, nil
+}
+
+// profileCredentials returns the credentials of the given profile of the AWS config file if the AWS SDK can't find them
+// the way the AWS CLI does, or nil otherwise. These are AWS IAM Identity Center (SSO) profiles, see ssoCredentials, and
+// profiles that assume a role, via source_profile, with the credentials of one of them. An empty profile means the
+// default one, unless the env of the unit sets the credentials themselves.
+func profileCredentials(profile string, terragruntOptions *options.TerragruntOptions) (*credentials.Credentials, error) {
+	if profile == "" {
+		if credentialsEnv(terragruntOptions)["AWS_ACCESS_KEY_ID"] != "" {
+			return nil, nil
+		}
+		profile = envProfile(terragruntOptions)
+	}
+
+	configFile, err := awsConfigFile()
+	if err != nil || !util.FileExists(configFile) {
+		return nil, nil
+	}
+	sections, err := parseAwsConfigFile(configFile)
+	if err != nil {
+		return nil, err
+	}
+	return profileCredentialsOfSections(sections, profile, terragruntOptions, map[string]bool{})
+}
+
+// profileCredentialsOfSections returns the credentials of the given profile of the given sections of an AWS config
+// file, as described by profileCredentials. The given profiles were already visited in the source_profile chain, so a
+// loop in the chain is left to the AWS SDK to report.
+func profileCredentialsOfSections(sections map[string]map[string]string, profile string, terragruntOptions *options.TerragruntOptions, visited map[string]bool) (*credentials.Credentials, error) {
+	ssoProfile, err := ssoProfileOfSections(sections, profile)
+	if err != nil {
+		return nil, err
+	}
+	if ssoProfile != nil {
+		return ssoCredentials(ssoProfile, terragruntOptions)
+	}
+
+	section := sections[profileSectionName(profile)]
+	roleArn := section["role_arn"]
+	sourceProfile := section["source_profile"]
+	if roleArn == "" || sourceProfile == "" || visited[profile] {
+		return nil, nil
+	}
+	visited[profile] = true
+
+	sourceCreds, err := profileCredentialsOfSections(sections, sourceProfile, terragruntOptions, visited)
+	if err != nil || sourceCreds == nil {
+		return nil, err
+	}
+
+	region := section["region"]
+	if region == "" {
+		region = envRegion(terragruntOptions)
+	}
+	if region == "" {
+		region = defaultStsRegion
+	}
+	awsConfig := aws.Config{Region: aws.String(region), Credentials: sourceCreds, MaxRetries: aws.Int(AWS_API_MAX_RETRIES), HTTPClient: newSessionHttpClient()}
+	applyEndpoints(&awsConfig, nil, terragruntOptions)
+	sess, err := session.NewSessionWithOptions(session.Options{
+		Config:            awsConfig,
+		SharedConfigState: session.SharedConfigDisable,
+	})
+	if err != nil {
+		return nil, errors.WithStackTrace(err)
+	}
+	return stscreds.NewCredentials(sess, roleArn, func(p *stscreds.AssumeRoleProvider) {
+		if externalId := section["external_id"]; externalId != "" {
+			p.ExternalID = aws.String(externalId)
+		}
+		if sessionName := section["role_session_name"]; sessionName != "" {
+			p.RoleSessionName = sessionName
+		}
+		if seconds, err := strconv.Atoi(section["duration_seconds"]); err == nil && seconds > 0 {
+			p.Duration = time.Duration(seconds) * time.Second
+		}
+		if mfaSerial := section["mfa_serial"]; mfaSerial != "" {
+			p.SerialNumber = aws.String(mfaSerial)
+			p.TokenProvider = profileMfaTokenProvider(profile, terragruntOptions)
+		}
+	}), nil
+}
+
+// Return a function that returns the MFA token to assume the role of the given profile with, when the profile sets an
+// mfa_serial: the one set via --terragrunt-iam-assume-role-mfa-token, if any, or else the one the user enters when
+// prompted
+func profileMfaTokenProvider(profile string, terragruntOptions *options.TerragruntOptions) func() (string, error) {
+	return func() (string, error) {
+		if profile == "" {
+			profile = envProfile(terragruntOptions)
+		}
+		if terragruntOptions != nil && terragruntOptions.IamAssumeRoleMfaToken != "" {
+			return terragruntOptions.IamAssumeRoleMfaToken, nil
+		}
+		if terragruntOptions == nil || terragruntOptions.NonInteractive {
+			return "", errors.WithStackTrace(MissingProfileMfaToken(profile))
+		}
+
+		prompt := fmt.Sprintf("Enter the MFA token to assume the IAM role of the AWS profile %s: ", profile)
+		token, err := shell.PromptUserForInput(prompt, terragruntOptions)
+		if err != nil {
+			return "", err
+		}
+		if token == "" {
+			return "", errors.WithStackTrace(MissingProfileMfaToken(profile))
+		}
+		return token, nil
+	}
+}
+
+// Return the static credentials set in the given env vars, if any, or else nil
+func envStaticCredentials(env map[string]string) *credentials.Credentials {
+	if env["AWS_ACCESS_KEY_ID"] == "" || env["AWS_SECRET_ACCESS_KEY"] == "" {
+		return nil
+	}
+	return credentials.NewStaticCredentials(env["AWS_ACCESS_KEY_ID"], env["AWS_SECRET_ACCESS_KEY"], env["AWS_SESSION_TOKEN"])
+}
+
+// UpdateEnvWithAwsProfile passes the AWS profile the unit is pinned to via aws_profile, if any, to terraform via the
+// AWS_PROFILE env var. The static credentials in the env would take precedence over the profile, so they're removed.
+func UpdateEnvWithAwsProfile(terragruntOptions *options.TerragruntOptions) {
+	if terragruntOptions.AwsProfile == "" {
+		return
+	}
+	terragruntOptions.Env["AWS_PROFILE"] = terragruntOptions.AwsProfile
+	for _, envVar := range staticCredentialsEnvVars {
+		delete(terragruntOptions.Env, envVar)
+	}
+}
+
+// ResetAwsCredentialsEnv sets the env vars of the AWS credentials in the given options, which were cloned from the
+// options of another unit, back to the ones of the process, so that the profile the other unit is pinned to, or the
+// credentials of the role it assumed, don't leak into this unit. The unit then sets its own, see
+// UpdateEnvWithAwsProfile and AssumeRoleAndUpdateEnvIfNecessary.
+func ResetAwsCredentialsEnv(terragruntOptions *options.TerragruntOptions) {
+	terragruntOptions.IamRoleSourceEnv = nil
+	for _, envVar := range unitCredentialsEnvVars {
+		if value, isSet := os.LookupEnv(envVar); isSet {
+			terragruntOptions.Env[envVar] = value
+		} else {
+			delete(terragruntOptions.Env, envVar)
+		}
+	}
+}
+
+// LogAwsIdentity logs, at the debug level, the AWS identity the unit of the given options runs terraform, and makes its
+// own AWS calls, with: the one of the profile it's pinned to, of the IAM role it assumes, or of the credentials of its
+// env, e.g. the ones of its vault_credentials block. During *-all commands with units of different accounts, this shows
+// which identity each unit ended up with. Looking up the identity is skipped at the other log levels, as it takes an
+// STS call.
+func LogAwsIdentity(terragruntOptions *options.TerragruntOptions) {
+	if !terragruntOptions.Logger.Logger.IsLevelEnabled(logrus.DebugLevel) {
+		return
+	}
+
+	source := []string{}
+	if terragruntOptions.AwsProfile != "" {
+		source = append(source, fmt.Sprintf("profile %s", terragruntOptions.AwsProfile))
+	}
+	if terragruntOptions.IamRole != "" {
+		source = append(source, fmt.Sprintf("IAM role %s", terragruntOptions.IamRole))
+	}
+	if len(source) == 0 {
+		source = append(source, awsCredentialsSourceOfEnv(credentialsEnv(terragruntOptions)))
+	}
+
+	identityArn, err := GetAWSIdentityArn(nil, terragruntOptions)
+	if err != nil {
+		terragruntOptions.Logger.Debugf("Could not look up the AWS identity of the unit (%s): %v", strings.Join(source, ", "), err)
+		return
+	}
+	terragruntOptions.Logger.Debugf("Using AWS identity %s (%s)", identityArn, strings.Join(source, ", "))
+}
+
+// Return a description of where the credentials of the given env vars come from, for LogAwsIdentity
+func awsCredentialsSourceOfEnv(env map[string]string) string {
+	if env["AWS_ACCESS_KEY_ID"] != "" {
+		return fmt.Sprintf("access key %s of the env", env["AWS_ACCESS_KEY_ID"])
+	}
+	if profile := envProfileOf(env); profile != "" {
+		return fmt.Sprintf("profile %s of the env", profile)
+	}
+	return "default credentials"
+}
+
+// Custom error types
+
+type MissingProfileMfaToken string
+
+func (profile MissingProfileMfaToken) Error() string {
+	return fmt.Sprintf("Assuming the IAM role of the AWS profile %s requires an MFA token. Set it with --terragrunt-iam-assume-role-mfa-token or the TERRAGRUNT_IAM_ASSUME_ROLE_MFA_TOKEN env var, or run terragrunt interactively to be asked for it.", string(profile))
+}
@@ -0,0 +1,295 @@
+package aws_helper
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/gruntwork-io/terragrunt/errors"
+	"github.com/gruntwork-io/terragrunt/options"
+	"github.com/gruntwork-io/terragrunt/util"
+)
+
+// The credential_process is filled in with the path of a script, as the AWS SDK can't parse JSON in the config file
+const testAwsProfilesConfig = `
+[profile sso]
+sso_session    = acme
+sso_account_id = 111111111111
+sso_role_name  = Admin
+
+[profile deploy]
+role_arn       = arn:aws:iam::222222222222:role/deploy
+source_profile = sso
+
+[profile deploy-prod]
+role_arn       = arn:aws:iam::333333333333:role/deploy
+source_profile = deploy
+
+[profile process]
+credential_process = %s
+
+[profile process-role]
+role_arn       = arn:aws:iam::222222222222:role/deploy
+source_profile = process
+mfa_serial     = arn:aws:iam::111111111111:mfa/user
+
+[profile loop-a]
+role_arn       = arn:aws:iam::222222222222:role/a
+source_profile = loop-b
+
+[profile loop-b]
+role_arn       = arn:aws:iam::222222222222:role/b
+source_profile = loop-a
+
+[sso-session acme]
+sso_start_url = https://acme.awsapps.com/start
+sso_region    = eu-central-1
+`
+
+func TestProfileCredentialsOfSections(t *testing.T) {
+	t.Parallel()
+
+	configFile := writeTestFile(t, "config", fmt.Sprintf(testAwsProfilesConfig, "credential-process"))
+	defer os.RemoveAll(filepath.Dir(configFile))
+	sections, err := parseAwsConfigFile(configFile)
+	require.NoError(t, err)
+
+	// SSO profiles, and the profiles assuming a role with the credentials of one, which the AWS SDK doesn't support
+	for _, profile := range []string{"sso", "deploy", "deploy-prod"} {
+		creds, err := profileCredentialsOfSections(sections, profile, nil, map[string]bool{})
+		require.NoError(t, err)
+		assert.NotNil(t, creds, "Profile %s", profile)
+	}
+
+	// The profiles the AWS SDK finds the credentials of the same way the AWS CLI does, and the loops it reports
+	for _, profile := range []string{"process", "process-role", "loop-a", "unknown"} {
+		creds, err := profileCredentialsOfSections(sections, profile, nil, map[string]bool{})
+		require.NoError(t, err)
+		assert.Nil(t, creds, "Profile %s", profile)
+	}
+}
+
+func TestProfileSessionOptions(t *testing.T) {
+	t.Parallel()
+
+	credentialProcess := writeTestFile(t, "credential-process", `#!/bin/sh
+echo '{"Version": 1, "AccessKeyId": "AKIAPROCESS", "SecretAccessKey": "secret"}'
+`)
+	defer os.RemoveAll(filepath.Dir(credentialProcess))
+	require.NoError(t, os.Chmod(credentialProcess, 0700))
+	configFile := writeTestFile(t, "config", fmt.Sprintf(testAwsProfilesConfig, credentialProcess))
+	defer os.RemoveAll(filepath.Dir(configFile))
+
+	terragruntOptions, err := options.NewTerragruntOptionsForTest("profile_test")
+	require.NoError(t, err)
+	terragruntOptions.NonInteractive = true
+
+	sessionOptions, err := profileSessionOptions("process", terragruntOptions)
+	require.NoError(t, err)
+	sessionOptions.SharedConfigFiles = []string{configFile}
+	sess, err := session.NewSessionWithOptions(sessionOptions)
+	require.NoError(t, err)
+	creds, err := sess.Config.Credentials.Get()
+	require.NoError(t, err)
+	assert.Equal(t, "AKIAPROCESS", creds.AccessKeyID)
+
+	// The MFA token of a profile is asked for rather than the AWS SDK failing, which can't be done non-interactively
+	sessionOptions, err = profileSessionOptions("process-role", terragruntOptions)
+	require.NoError(t, err)
+	sessionOptions.SharedConfigFiles = []string{configFile}
+	sess, err = session.NewSessionWithOptions(sessionOptions)
+	require.NoError(t, err)
+	_, err = sess.Config.Credentials.Get()
+	_, isMissing := errors.Unwrap(err).(MissingProfileMfaToken)
+	assert.True(t, isMissing, "Unexpected error: %v", err)
+
+	terragruntOptions.IamAssumeRoleMfaToken = "123456"
+	token, err := profileMfaTokenProvider("process-role", terragruntOptions)()
+	require.NoError(t, err)
+	assert.Equal(t, "123456", token)
+}
+
+func TestUpdateEnvWithAwsProfile(t *testing.T) {
+	t.Parallel()
+
+	terragruntOptions, err := options.NewTerragruntOptionsForTest("profile_test")
+	require.NoError(t, err)
+	terragruntOptions.Env = map[string]string{"AWS_ACCESS_KEY_ID": "AKIAENV", "AWS_SECRET_ACCESS_KEY": "secret", "AWS_REGION": "eu-west-1"}
+
+	UpdateEnvWithAwsProfile(terragruntOptions)
+	assert.Equal(t, map[string]string{"AWS_ACCESS_KEY_ID": "AKIAENV", "AWS_SECRET_ACCESS_KEY": "secret", "AWS_REGION": "eu-west-1"}, terragruntOptions.Env)
+
+	terragruntOptions.AwsProfile = "deploy"
+	UpdateEnvWithAwsProfile(terragruntOptions)
+	assert.Equal(t, map[string]string{"AWS_PROFILE": "deploy", "AWS_REGION": "eu-west-1"}, terragruntOptions.Env)
+}
+
+func TestAwsProfilesOfUnitsAreIsolated(t *testing.T) {
+	t.Parallel()
+
+	configContents := ""
+	for _, profile := range []string{"unit-a", "unit-b"} {
+		credentialProcess := writeTestFile(t, "credential-process", fmt.Sprintf(`#!/bin/sh
+echo '{"Version": 1, "AccessKeyId": "AKIA-%s", "SecretAccessKey": "secret"}'
+`, profile))
+		defer os.RemoveAll(filepath.Dir(credentialProcess))
+		require.NoError(t, os.Chmod(credentialProcess, 0700))
+		configContents += fmt.Sprintf("[profile %s]\ncredential_process = %s\n\n", profile, credentialProcess)
+	}
+	configFile := writeTestFile(t, "config", configContents)
+	defer os.RemoveAll(filepath.Dir(configFile))
+
+	terragruntOptions, err := options.NewTerragruntOptionsForTest("profile_test")
+	require.NoError(t, err)
+	terragruntOptions.Env = map[string]string{"AWS_ACCESS_KEY_ID": "AKIAENV", "AWS_SECRET_ACCESS_KEY": "secret"}
+
+	// The units of a *-all command run concurrently, each with a clone of the options
+	var waitGroup sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		profile := []string{"unit-a", "unit-b"}[i%2]
+		unitOptions := terragruntOptions.Clone(filepath.Join(profile, "terragrunt.hcl"))
+		unitOptions.AwsProfile = profile
+
+		waitGroup.Add(1)
+		go func() {
+			defer waitGroup.Done()
+
+			UpdateEnvWithAwsProfile(unitOptions)
+			assert.Equal(t, map[string]string{"AWS_PROFILE": profile}, unitOptions.Env)
+
+			sessionOptions, err := profileSessionOptions(unitOptions.AwsProfile, unitOptions)
+			require.NoError(t, err)
+			sessionOptions.SharedConfigFiles = []string{configFile}
+			sess, err := session.NewSessionWithOptions(sessionOptions)
+			require.NoError(t, err)
+			creds, err := sess.Config.Credentials.Get()
+			require.NoError(t, err)
+			assert.Equal(t, "AKIA-"+profile, creds.AccessKeyID)
+		}()
+	}
+	waitGroup.Wait()
+
+	assert.Equal(t, map[string]string{"AWS_ACCESS_KEY_ID": "AKIAENV", "AWS_SECRET_ACCESS_KEY": "secret"}, terragruntOptions.Env)
+}
+
+func TestResetAwsCredentialsEnv(t *testing.T) {
+	t.Parallel()
+
+	terragruntOptions, err := options.NewTerragruntOptionsForTest("profile_test")
+	require.NoError(t, err)
+	terragruntOptions.Env = map[string]string{"AWS_PROFILE": "other-unit", "AWS_SESSION_TOKEN": "token", "AWS_REGION": "eu-west-1"}
+
+	ResetAwsCredentialsEnv(terragruntOptions)
+	assert.Equal(t, "eu-west-1", terragruntOptions.Env["AWS_REGION"])
+	for _, envVar := range unitCredentialsEnvVars {
+		value, isSet := os.LookupEnv(envVar)
+		actual, isActualSet := terragruntOptions.Env[envVar]
+		assert.Equal(t, isSet, isActualSet, envVar)
+		assert.Equal(t, value, actual, envVar)
+	}
+}
+
+// A fake STS endpoint, where the identity of the access key of a request is the one of the IAM user AKIA-<name> or the
+// role ASIA-<name> stands for, and assuming a role returns the credentials ASIA-<name of the role>. The roles assumed
+// by each access key are recorded.
+func newFakeStsServer(t *testing.T) (*httptest.Server, *sync.Map) {
+	accessKeyRegexp := regexp.MustCompile(`Credential=([^/]+)/`)
+	assumedRoles := &sync.Map{}
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		require.NoError(t, request.ParseForm())
+		match := accessKeyRegexp.FindStringSubmatch(request.Header.Get("Authorization"))
+		require.NotNil(t, match, "Unsigned request")
+		accessKey := match[1]
+
+		switch request.Form.Get("Action") {
+		case "AssumeRole":
+			roleArn := request.Form.Get("RoleArn")
+			assumedRoles.Store(roleArn, accessKey)
+			fmt.Fprintf(writer, `<AssumeRoleResponse><AssumeRoleResult><Credentials><AccessKeyId>ASIA-%s</AccessKeyId><SecretAccessKey>secret</SecretAccessKey><SessionToken>token</SessionToken><Expiration>%s</Expiration></Credentials></AssumeRoleResult></AssumeRoleResponse>`, roleArn[strings.LastIndex(roleArn, "/")+1:], time.Now().Add(time.Hour).UTC().Format(time.RFC3339))
+		case "GetCallerIdentity":
+			arn := "arn:aws:iam::111111111111:user/" + strings.TrimPrefix(accessKey, "AKIA-")
+			if strings.HasPrefix(accessKey, "ASIA-") {
+				arn = "arn:aws:sts::222222222222:assumed-role/" + strings.TrimPrefix(accessKey, "ASIA-") + "/terragrunt"
+			}
+			fmt.Fprintf(writer, `<GetCallerIdentityResponse><GetCallerIdentityResult><Arn>%s</Arn><UserId>%s</UserId><Account>111111111111</Account></GetCallerIdentityResult></GetCallerIdentityResponse>`, arn, accessKey)
+		default:
+			writer.WriteHeader(http.StatusBadRequest)
+		}
+	}))
+	return server, assumedRoles
+}
+
+func TestAwsIdentitiesOfUnitsAreIsolated(t *testing.T) {
+	t.Parallel()
+
+	server, assumedRoles := newFakeStsServer(t)
+	defer server.Close()
+
+	terragruntOptions, err := options.NewTerragruntOptionsForTest("profile_test")
+	require.NoError(t, err)
+	terragruntOptions.Env = map[string]string{"AWS_ACCESS_KEY_ID": "AKIA-isolated-ci", "AWS_SECRET_ACCESS_KEY": "secret", "AWS_REGION": "us-east-1"}
+	terragruntOptions.AwsEndpoints = map[string]string{"sts": server.URL}
+
+	// The units of a run-all command run concurrently, each with a clone of the options: two assume roles of their own
+	// with the credentials of the env, one has credentials of its own in its env, e.g. the ones of a vault_credentials
+	// block, and one uses the credentials of the env
+	units := []struct {
+		name        string
+		iamRole     string
+		env         map[string]string
+		expectedArn string
+		expectedLog string
+	}{
+		{"unit-a", "arn:aws:iam::222222222222:role/isolated-unit-a", nil, "arn:aws:sts::222222222222:assumed-role/isolated-unit-a/terragrunt", "IAM role arn:aws:iam::222222222222:role/isolated-unit-a"},
+		{"unit-b", "arn:aws:iam::222222222222:role/isolated-unit-b", nil, "arn:aws:sts::222222222222:assumed-role/isolated-unit-b/terragrunt", "IAM role arn:aws:iam::222222222222:role/isolated-unit-b"},
+		{"unit-c", "", map[string]string{"AWS_ACCESS_KEY_ID": "AKIA-isolated-vault", "AWS_SECRET_ACCESS_KEY": "secret"}, "arn:aws:iam::111111111111:user/isolated-vault", "access key AKIA-isolated-vault of the env"},
+		{"unit-d", "", nil, "arn:aws:iam::111111111111:user/isolated-ci", "access key AKIA-isolated-ci of the env"},
+	}
+
+	var waitGroup sync.WaitGroup
+	for i := 0; i < 3*len(units); i++ {
+		unit := units[i%len(units)]
+		unitOptions := terragruntOptions.Clone(filepath.Join(unit.name, "terragrunt.hcl"))
+		unitOptions.IamRole = unit.iamRole
+		for key, value := range unit.env {
+			unitOptions.Env[key] = value
+		}
+		var logs bytes.Buffer
+		unitOptions.Logger = util.CreateLogEntryWithWriter(&logs, unit.name, logrus.DebugLevel)
+
+		waitGroup.Add(1)
+		go func() {
+			defer waitGroup.Done()
+
+			require.NoError(t, AssumeRoleAndUpdateEnvIfNecessary(unitOptions))
+			arn, err := GetAWSIdentityArn(nil, unitOptions)
+			require.NoError(t, err)
+			assert.Equal(t, unit.expectedArn, arn, unit.name)
+
+			// The identity of the credentials of the env is logged too
+			LogAwsIdentity(unitOptions)
+			assert.Contains(t, logs.String(), fmt.Sprintf("Using AWS identity %s (%s)", unit.expectedArn, unit.expectedLog))
+		}()
+	}
+	waitGroup.Wait()
+
+	for _, unit := range units[:2] {
+		accessKey, isAssumed := assumedRoles.Load(unit.iamRole)
+		assert.True(t, isAssumed, unit.name)
+		assert.Equal(t, "AKIA-isolated-ci", accessKey, "The role of %s must be assumed with the credentials of the env, not the ones of another role", unit.name)
+	}
+	assert.Equal(t, "AKIA-isolated-ci", terragruntOptions.Env["AWS_ACCESS_KEY_ID"])
+}
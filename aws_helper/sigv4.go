@@ -0,0 +1,114 @@
+package aws_helper
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	v4 "github.com/aws/aws-sdk-go/aws/signer/v4"
+
+	"github.com/gruntwork-io/terragrunt/errors"
+	"github.com/gruntwork-io/terragrunt/options"
+)
+
+// SigV4Transport is an http.RoundTripper that signs the requests it sends with AWS Signature Version 4, for the servers
+// that authenticate the requests with AWS credentials, such as an API Gateway with IAM authorization
+type SigV4Transport struct {
+	signer        *v4.Signer
+	service       string
+	region        string
+	defaultRegion string
+	base          http.RoundTripper
+}
+
+// NewSigV4Transport returns a transport that signs the requests for the given AWS service with the AWS credentials of the
+// module of the given options. The requests are signed for the given region if it's set, or else for the region in the
+// host name of the AWS endpoint the requests are sent to, e.g. abc123.execute-api.eu-west-1.amazonaws.com, or else for
+// the region of the module.
+func NewSigV4Transport(service string, region string, terragruntOptions *options.TerragruntOptions) (*SigV4Transport, error) {
+	sess, err := CreateAwsSession(nil, terragruntOptions)
+	if err != nil {
+		return nil, err
+	}
+	return newSigV4Transport(sess.Config.Credentials, service, region, aws.StringValue(sess.Config.Region)), nil
+}
+
+func newSigV4Transport(creds *credentials.Credentials, service string, region string, defaultRegion string) *SigV4Transport {
+	return &SigV4Transport{
+		signer:        v4.NewSigner(creds),
+		service:       service,
+		region:        region,
+		defaultRegion: defaultRegion,
+		base:          http.DefaultTransport,
+	}
+}
+
+// RoundTrip signs a copy of the given request and sends it
+func (transport *SigV4Transport) RoundTrip(request *http.Request) (*http.Response, error) {
+	region := transport.regionOfHost(request.URL.Hostname())
+	if region == "" {
+		return nil, errors.WithStackTrace(MissingSigV4Region{Host: request.URL.Hostname(), Service: transport.service})
+	}
+
+	// A RoundTripper must not modify the request it's given, and the signer sets the headers of the signature and
+	// replaces the body with the one it read
+	signedRequest := request.Clone(request.Context())
+	var body io.ReadSeeker
+	if request.Body != nil && request.Body != http.NoBody {
+		data, err := ioutil.ReadAll(request.Body)
+		request.Body.Close()
+		if err != nil {
+			return nil, errors.WithStackTrace(err)
+		}
+		body = bytes.NewReader(data)
+	}
+
+	if _, err := transport.signer.Sign(signedRequest, body, transport.service, region, time.Now()); err != nil {
+		return nil, errors.WithStackTrace(err)
+	}
+	return transport.base.RoundTrip(signedRequest)
+}
+
+// Return the region to sign the requests to the given host for
+func (transport *SigV4Transport) regionOfHost(host string) string {
+	if transport.region != "" {
+		return transport.region
+	}
+
+	if region := ServiceEndpointRegion(transport.service, host); region != "" {
+		return region
+	}
+	return transport.defaultRegion
+}
+
+// ServiceEndpointRegion returns the region of the given host name if it's an endpoint of the given AWS service, e.g.
+// eu-west-1 for abc123.execute-api.eu-west-1.amazonaws.com and execute-api, or an empty string otherwise
+func ServiceEndpointRegion(service string, host string) string {
+	if !strings.HasSuffix(host, ".amazonaws.com") && !strings.HasSuffix(host, ".amazonaws.com.cn") {
+		return ""
+	}
+	labels := strings.Split(host, ".")
+	for i := 0; i < len(labels)-1; i++ {
+		if labels[i] == service && IsRegion(labels[i+1]) {
+			return labels[i+1]
+		}
+	}
+	return ""
+}
+
+// Custom error types
+
+type MissingSigV4Region struct {
+	Host    string
+	Service string
+}
+
+func (err MissingSigV4Region) Error() string {
+	return fmt.Sprintf("Could not find out which AWS region to sign the %s requests to %s for. Set the region of the module, e.g. via the AWS_REGION environment variable.", err.Service, err.Host)
+}
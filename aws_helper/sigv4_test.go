@@ -0,0 +1,59 @@
+package aws_helper
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSigV4TransportSignsRequests(t *testing.T) {
+	t.Parallel()
+
+	var authorization, body string
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		data, _ := ioutil.ReadAll(request.Body)
+		authorization = request.Header.Get("Authorization")
+		body = string(data)
+	}))
+	defer server.Close()
+
+	transport := newSigV4Transport(credentials.NewStaticCredentials("AKIAEXAMPLE", "secret", ""), "execute-api", "", "eu-west-1")
+	client := &http.Client{Transport: transport}
+
+	request, err := http.NewRequest(http.MethodPost, server.URL+"/state", strings.NewReader(`{"version":4}`))
+	require.NoError(t, err)
+	response, err := client.Do(request)
+	require.NoError(t, err)
+	response.Body.Close()
+
+	assert.True(t, strings.HasPrefix(authorization, "AWS4-HMAC-SHA256 Credential=AKIAEXAMPLE/"), authorization)
+	assert.Contains(t, authorization, "/eu-west-1/execute-api/aws4_request")
+	assert.Equal(t, `{"version":4}`, body)
+	assert.Empty(t, request.Header.Get("Authorization"), "The request given to the transport must not be modified")
+}
+
+func TestSigV4TransportRegionOfHost(t *testing.T) {
+	t.Parallel()
+
+	transport := newSigV4Transport(credentials.AnonymousCredentials, "execute-api", "", "us-east-1")
+	assert.Equal(t, "eu-west-1", transport.regionOfHost("abc123.execute-api.eu-west-1.amazonaws.com"))
+	assert.Equal(t, "cn-north-1", transport.regionOfHost("abc123.execute-api.cn-north-1.amazonaws.com.cn"))
+	assert.Equal(t, "us-east-1", transport.regionOfHost("state.example.com"))
+
+	transport.region = "us-west-2"
+	assert.Equal(t, "us-west-2", transport.regionOfHost("abc123.execute-api.eu-west-1.amazonaws.com"))
+
+	assert.Equal(t, "eusc-de-east-1", ServiceEndpointRegion("execute-api", "abc123.execute-api.eusc-de-east-1.amazonaws.com"))
+	assert.Equal(t, "", ServiceEndpointRegion("execute-api", "abc123.execute-api.eu-west-1.example.com"))
+	assert.Equal(t, "", ServiceEndpointRegion("execute-api", "abc123.lambda-url.eu-west-1.amazonaws.com"))
+
+	transport = newSigV4Transport(credentials.AnonymousCredentials, "execute-api", "", "")
+	_, err := transport.RoundTrip(httptest.NewRequest(http.MethodGet, "https://state.example.com/state", nil))
+	assert.Error(t, err)
+}
@@ -0,0 +1,332 @@
+package aws_helper
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/sso"
+	"github.com/aws/aws-sdk-go/service/sso/ssoiface"
+
+	"github.com/gruntwork-io/terragrunt/errors"
+	"github.com/gruntwork-io/terragrunt/options"
+	"github.com/gruntwork-io/terragrunt/shell"
+)
+
+// The name of the credentials provider of AWS IAM Identity Center (SSO) profiles
+const ssoProviderName = "TerragruntSSOProvider"
+
+// The formats of the expiry of the tokens the AWS CLI caches. Older versions of the CLI don't write it as RFC 3339.
+var ssoTokenExpiryFormats = []string{time.RFC3339, "2006-01-02T15:04:05UTC"}
+
+// The locks that make the modules of a *-all command wait for the one that logs in to an SSO session, rather than all of
+// them starting a login of their own
+var ssoLoginLocks = sync.Map{}
+
+// ssoProfile is a profile of the AWS config file that gets its credentials from AWS IAM Identity Center, formerly AWS SSO,
+// either via an sso-session section or, in the legacy format, with the SSO settings in the profile itself
+type ssoProfile struct {
+	Name string
+	// The name of the sso-session section of the profile, if any
+	SessionName string
+	StartUrl    string
+	Region      string
+	AccountId   string
+	RoleName    string
+}
+
+// The name of the file, in the SSO cache of the AWS CLI, of the token of the profile, which is named after the session
+// for sso-session profiles, and after the start URL for legacy ones
+func (profile ssoProfile) cacheFileName() string {
+	key := profile.StartUrl
+	if profile.SessionName != "" {
+		key = profile.SessionName
+	}
+	hash := sha1.Sum([]byte(key))
+	return hex.EncodeToString(hash[:]) + ".json"
+}
+
+// ssoCredentials returns the credentials of the given AWS IAM Identity Center (SSO) profile. The AWS SDK only supports
+// the legacy format of these profiles, and fails with a generic error once the SSO session expires, so terragrunt finds
+// their credentials itself. See profileCredentials.
+func ssoCredentials(ssoProfile *ssoProfile, terragruntOptions *options.TerragruntOptions) (*credentials.Credentials, error) {
+	cacheDir, err := ssoCacheDir()
+	if err != nil {
+		return nil, err
+	}
+	sess, err := session.NewSessionWithOptions(session.Options{
+		Config:            aws.Config{Region: aws.String(ssoProfile.Region), Credentials: credentials.AnonymousCredentials, HTTPClient: newSessionHttpClient()},
+		SharedConfigState: session.SharedConfigDisable,
+	})
+	if err != nil {
+		return nil, errors.WithStackTrace(err)
+	}
+
+	return credentials.NewCredentials(&ssoCredentialsProvider{
+		profile:           *ssoProfile,
+		client:            sso.New(sess),
+		cacheDir:          cacheDir,
+		terragruntOptions: terragruntOptions,
+	}), nil
+}
+
+// Return the profile set in the env of the unit of the given options, or else the default one. See credentialsEnv.
+func envProfile(terragruntOptions *options.TerragruntOptions) string {
+	if profile := envProfileOf(credentialsEnv(terragruntOptions)); profile != "" {
+		return profile
+	}
+	return "default"
+}
+
+// Return the profile set in the given env vars, if any, or else an empty string
+func envProfileOf(env map[string]string) string {
+	for _, envVar := range []string{"AWS_PROFILE", "AWS_DEFAULT_PROFILE"} {
+		if profile := env[envVar]; profile != "" {
+			return profile
+		}
+	}
+	return ""
+}
+
+// Return the path of the AWS config file, which the AWS_CONFIG_FILE env var overrides like it does for the AWS CLI
+func awsConfigFile() (string, error) {
+	if configFile := os.Getenv("AWS_CONFIG_FILE"); configFile != "" {
+		return configFile, nil
+	}
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", errors.WithStackTrace(err)
+	}
+	return filepath.Join(homeDir, ".aws", "config"), nil
+}
+
+// Return the directory the AWS CLI caches the tokens of SSO sessions in
+func ssoCacheDir() (string, error) {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", errors.WithStackTrace(err)
+	}
+	return filepath.Join(homeDir, ".aws", "sso", "cache"), nil
+}
+
+// findSsoProfile returns the given profile of the given AWS config file if it's an SSO profile, or nil if it's not, or
+// if the file has no such profile
+func findSsoProfile(configFile string, profile string) (*ssoProfile, error) {
+	sections, err := parseAwsConfigFile(configFile)
+	if err != nil {
+		return nil, err
+	}
+	return ssoProfileOfSections(sections, profile)
+}
+
+// ssoProfileOfSections returns the given profile of the given sections of an AWS config file if it's an SSO profile, or
+// nil if it's not, or if there's no such profile
+func ssoProfileOfSections(sections map[string]map[string]string, profile string) (*ssoProfile, error) {
+	section, hasProfile := sections[profileSectionName(profile)]
+	if !hasProfile {
+		return nil, nil
+	}
+
+	result := &ssoProfile{Name: profile, AccountId: section["sso_account_id"], RoleName: section["sso_role_name"]}
+	if sessionName := section["sso_session"]; sessionName != "" {
+		ssoSession, hasSession := sections["sso-session "+sessionName]
+		if !hasSession {
+			return nil, errors.WithStackTrace(InvalidSsoProfile{Profile: profile, Reason: fmt.Sprintf("there's no sso-session section named %s", sessionName)})
+		}
+		result.SessionName = sessionName
+		result.StartUrl = ssoSession["sso_start_url"]
+		result.Region = ssoSession["sso_region"]
+	} else if section["sso_start_url"] != "" {
+		result.StartUrl = section["sso_start_url"]
+		result.Region = section["sso_region"]
+	} else {
+		return nil, nil
+	}
+
+	missing := []string{}
+	for _, setting := range [][2]string{{"sso_start_url", result.StartUrl}, {"sso_region", result.Region}, {"sso_account_id", result.AccountId}, {"sso_role_name", result.RoleName}} {
+		if setting[1] == "" {
+			missing = append(missing, setting[0])
+		}
+	}
+	if len(missing) > 0 {
+		return nil, errors.WithStackTrace(InvalidSsoProfile{Profile: profile, Reason: "missing " + strings.Join(missing, ", ")})
+	}
+	return result, nil
+}
+
+// Return the name of the section of the given profile in the AWS config file
+func profileSectionName(profile string) string {
+	if profile == "default" {
+		return "default"
+	}
+	return "profile " + profile
+}
+
+// parseAwsConfigFile parses the sections of the given AWS config file into maps of their settings. Nested settings,
+// e.g. the ones of the s3 section of a profile, are skipped, as none of them are needed.
+func parseAwsConfigFile(configFile string) (map[string]map[string]string, error) {
+	file, err := os.Open(configFile)
+	if err != nil {
+		return nil, errors.WithStackTrace(err)
+	}
+	defer file.Close()
+
+	sections := map[string]map[string]string{}
+	var section map[string]string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		switch {
+		case line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, ";"):
+		case strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]"):
+			name := strings.Join(strings.Fields(line[1:len(line)-1]), " ")
+			section = map[string]string{}
+			sections[name] = section
+		case section != nil && strings.Contains(line, "="):
+			parts := strings.SplitN(line, "=", 2)
+			section[strings.TrimSpace(parts[0])] = strings.TrimSpace(parts[1])
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, errors.WithStackTrace(err)
+	}
+	return sections, nil
+}
+
+// The token of an SSO session, as cached by the AWS CLI
+type ssoToken struct {
+	AccessToken string `json:"accessToken"`
+	ExpiresAt   string `json:"expiresAt"`
+}
+
+// readSsoAccessToken returns the access token of the SSO session of the given profile from the given cache dir, or an
+// SsoSessionExpired error if there's none that is valid at the given time
+func readSsoAccessToken(profile ssoProfile, cacheDir string, now time.Time) (string, error) {
+	contents, err := ioutil.ReadFile(filepath.Join(cacheDir, profile.cacheFileName()))
+	if os.IsNotExist(err) {
+		return "", errors.WithStackTrace(SsoSessionExpired(profile.Name))
+	}
+	if err != nil {
+		return "", errors.WithStackTrace(err)
+	}
+
+	var token ssoToken
+	if err := json.Unmarshal(contents, &token); err != nil {
+		return "", errors.WithStackTrace(err)
+	}
+	if token.AccessToken == "" {
+		return "", errors.WithStackTrace(SsoSessionExpired(profile.Name))
+	}
+	for _, format := range ssoTokenExpiryFormats {
+		if expiresAt, err := time.Parse(format, token.ExpiresAt); err == nil {
+			if !now.Before(expiresAt) {
+				return "", errors.WithStackTrace(SsoSessionExpired(profile.Name))
+			}
+			return token.AccessToken, nil
+		}
+	}
+	// Without a known expiry, let AWS tell whether the token is still valid
+	return token.AccessToken, nil
+}
+
+// A provider of the credentials of the role of an SSO profile, which it gets from AWS IAM Identity Center with the token
+// of the SSO session the AWS CLI cached on login
+type ssoCredentialsProvider struct {
+	credentials.Expiry
+	profile           ssoProfile
+	client            ssoiface.SSOAPI
+	cacheDir          string
+	terragruntOptions *options.TerragruntOptions
+}
+
+func (provider *ssoCredentialsProvider) Retrieve() (credentials.Value, error) {
+	accessToken, err := readSsoAccessToken(provider.profile, provider.cacheDir, time.Now())
+	if isSsoSessionExpired(err) && provider.canLogin() {
+		if err := loginToSso(provider.profile, provider.cacheDir, provider.terragruntOptions); err != nil {
+			return credentials.Value{ProviderName: ssoProviderName}, err
+		}
+		accessToken, err = readSsoAccessToken(provider.profile, provider.cacheDir, time.Now())
+	}
+	if err != nil {
+		return credentials.Value{ProviderName: ssoProviderName}, err
+	}
+
+	output, err := provider.client.GetRoleCredentials(&sso.GetRoleCredentialsInput{
+		AccessToken: aws.String(accessToken),
+		AccountId:   aws.String(provider.profile.AccountId),
+		RoleName:    aws.String(provider.profile.RoleName),
+	})
+	if awsErr, isAwsErr := err.(awserr.Error); isAwsErr && awsErr.Code() == sso.ErrCodeUnauthorizedException {
+		return credentials.Value{ProviderName: ssoProviderName}, errors.WithStackTrace(SsoSessionExpired(provider.profile.Name))
+	}
+	if err != nil {
+		return credentials.Value{ProviderName: ssoProviderName}, errors.WithStackTrace(err)
+	}
+
+	provider.SetExpiration(time.Unix(0, aws.Int64Value(output.RoleCredentials.Expiration)*int64(time.Millisecond)), time.Minute)
+	return credentials.Value{
+		AccessKeyID:     aws.StringValue(output.RoleCredentials.AccessKeyId),
+		SecretAccessKey: aws.StringValue(output.RoleCredentials.SecretAccessKey),
+		SessionToken:    aws.StringValue(output.RoleCredentials.SessionToken),
+		ProviderName:    ssoProviderName,
+	}, nil
+}
+
+// Returns true if the provider may start a new SSO session when the current one expired, which is only the case if the
+// user asked for it and is there to complete the login in the browser
+func (provider *ssoCredentialsProvider) canLogin() bool {
+	return provider.terragruntOptions != nil && provider.terragruntOptions.AwsSsoLogin && !provider.terragruntOptions.NonInteractive
+}
+
+// loginToSso starts a new SSO session of the given profile with the AWS CLI, which takes the user through the device
+// authorization flow in the browser. Only one module logs in to the session of a profile at a time, and the modules that
+// waited for it use the session it started.
+func loginToSso(profile ssoProfile, cacheDir string, terragruntOptions *options.TerragruntOptions) error {
+	rawLock, _ := ssoLoginLocks.LoadOrStore(profile.Name, &sync.Mutex{})
+	lock := rawLock.(*sync.Mutex)
+	lock.Lock()
+	defer lock.Unlock()
+
+	if _, err := readSsoAccessToken(profile, cacheDir, time.Now()); err == nil {
+		return nil
+	}
+
+	terragruntOptions.Logger.Infof("The SSO session of the AWS profile %s has expired. Running aws sso login to start a new one.", profile.Name)
+	return shell.RunShellCommand(terragruntOptions, "aws", "sso", "login", "--profile", profile.Name)
+}
+
+// Returns true if the given error is an SsoSessionExpired error
+func isSsoSessionExpired(err error) bool {
+	_, isExpired := errors.Unwrap(err).(SsoSessionExpired)
+	return isExpired
+}
+
+// Custom error types
+
+type SsoSessionExpired string
+
+func (profile SsoSessionExpired) Error() string {
+	return fmt.Sprintf("The AWS SSO session of the profile %s has expired or was never started. Run \"aws sso login --profile %s\" and try again, or pass --terragrunt-aws-sso-login to have Terragrunt run it.", string(profile), string(profile))
+}
+
+type InvalidSsoProfile struct {
+	Profile string
+	Reason  string
+}
+
+func (err InvalidSsoProfile) Error() string {
+	return fmt.Sprintf("The AWS SSO profile %s is invalid: %s", err.Profile, err.Reason)
+}
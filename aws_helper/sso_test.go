@@ -0,0 +1,146 @@
+package aws_helper
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/sso"
+	"github.com/aws/aws-sdk-go/service/sso/ssoiface"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/gruntwork-io/terragrunt/errors"
+)
+
+const testAwsConfig = `
+[default]
+region = us-east-1
+
+[profile dev]
+sso_session    = acme
+sso_account_id = 111111111111
+sso_role_name  = Admin
+region         = eu-west-1
+
+[profile legacy]
+sso_start_url  = https://acme.awsapps.com/start
+sso_region     = us-east-1
+sso_account_id = 222222222222
+sso_role_name  = ReadOnly
+
+[profile incomplete]
+sso_session = acme
+
+[profile orphan]
+sso_session    = missing
+sso_account_id = 111111111111
+sso_role_name  = Admin
+
+[sso-session acme]
+sso_start_url           = https://acme.awsapps.com/start
+sso_region              = eu-central-1
+sso_registration_scopes = sso:account:access
+`
+
+func TestFindSsoProfile(t *testing.T) {
+	t.Parallel()
+
+	configFile := writeTestFile(t, "config", testAwsConfig)
+	defer os.RemoveAll(filepath.Dir(configFile))
+
+	profile, err := findSsoProfile(configFile, "dev")
+	require.NoError(t, err)
+	assert.Equal(t, &ssoProfile{Name: "dev", SessionName: "acme", StartUrl: "https://acme.awsapps.com/start", Region: "eu-central-1", AccountId: "111111111111", RoleName: "Admin"}, profile)
+
+	profile, err = findSsoProfile(configFile, "legacy")
+	require.NoError(t, err)
+	assert.Equal(t, &ssoProfile{Name: "legacy", StartUrl: "https://acme.awsapps.com/start", Region: "us-east-1", AccountId: "222222222222", RoleName: "ReadOnly"}, profile)
+
+	for _, name := range []string{"default", "unknown"} {
+		profile, err = findSsoProfile(configFile, name)
+		require.NoError(t, err)
+		assert.Nil(t, profile, "Profile %s", name)
+	}
+
+	for _, name := range []string{"incomplete", "orphan"} {
+		_, err = findSsoProfile(configFile, name)
+		_, isInvalid := errors.Unwrap(err).(InvalidSsoProfile)
+		assert.True(t, isInvalid, "Unexpected error for profile %s: %v", name, err)
+	}
+}
+
+func TestReadSsoAccessToken(t *testing.T) {
+	t.Parallel()
+
+	sessionProfile := ssoProfile{Name: "dev", SessionName: "acme", StartUrl: "https://acme.awsapps.com/start"}
+	legacyProfile := ssoProfile{Name: "legacy", StartUrl: "https://acme.awsapps.com/start"}
+	cacheFile := writeTestFile(t, sessionProfile.cacheFileName(), `{"accessToken": "token", "expiresAt": "2030-01-01T00:00:00Z"}`)
+	cacheDir := filepath.Dir(cacheFile)
+	defer os.RemoveAll(cacheDir)
+	require.NoError(t, ioutil.WriteFile(filepath.Join(cacheDir, legacyProfile.cacheFileName()), []byte(`{"accessToken": "legacy-token", "expiresAt": "2030-01-01T00:00:00UTC"}`), 0600))
+
+	now := time.Date(2029, 12, 31, 0, 0, 0, 0, time.UTC)
+	token, err := readSsoAccessToken(sessionProfile, cacheDir, now)
+	require.NoError(t, err)
+	assert.Equal(t, "token", token)
+
+	token, err = readSsoAccessToken(legacyProfile, cacheDir, now)
+	require.NoError(t, err)
+	assert.Equal(t, "legacy-token", token)
+
+	_, err = readSsoAccessToken(sessionProfile, cacheDir, now.Add(48*time.Hour))
+	assert.True(t, isSsoSessionExpired(err), "Unexpected error: %v", err)
+
+	_, err = readSsoAccessToken(ssoProfile{Name: "other", SessionName: "other"}, cacheDir, now)
+	assert.True(t, isSsoSessionExpired(err), "Unexpected error: %v", err)
+}
+
+type mockSsoClient struct {
+	ssoiface.SSOAPI
+	err error
+}
+
+func (client *mockSsoClient) GetRoleCredentials(input *sso.GetRoleCredentialsInput) (*sso.GetRoleCredentialsOutput, error) {
+	if client.err != nil {
+		return nil, client.err
+	}
+	return &sso.GetRoleCredentialsOutput{RoleCredentials: &sso.RoleCredentials{
+		AccessKeyId:     aws.String("ASIA" + aws.StringValue(input.AccountId)),
+		SecretAccessKey: aws.String("secret"),
+		SessionToken:    aws.String(aws.StringValue(input.AccessToken) + "-session"),
+		Expiration:      aws.Int64(time.Now().Add(time.Hour).UnixNano() / int64(time.Millisecond)),
+	}}, nil
+}
+
+func TestSsoCredentialsProvider(t *testing.T) {
+	t.Parallel()
+
+	profile := ssoProfile{Name: "dev", SessionName: "acme-provider", AccountId: "111111111111", RoleName: "Admin"}
+	cacheFile := writeTestFile(t, profile.cacheFileName(), `{"accessToken": "token", "expiresAt": "`+time.Now().Add(time.Hour).UTC().Format(time.RFC3339)+`"}`)
+	defer os.RemoveAll(filepath.Dir(cacheFile))
+
+	provider := &ssoCredentialsProvider{profile: profile, client: &mockSsoClient{}, cacheDir: filepath.Dir(cacheFile)}
+	creds, err := provider.Retrieve()
+	require.NoError(t, err)
+	assert.Equal(t, "ASIA111111111111", creds.AccessKeyID)
+	assert.Equal(t, "token-session", creds.SessionToken)
+	assert.False(t, provider.IsExpired())
+
+	// A token that AWS no longer accepts, e.g. because the session was revoked, means the session expired too
+	provider = &ssoCredentialsProvider{profile: profile, client: &mockSsoClient{err: awserr.New(sso.ErrCodeUnauthorizedException, "Session token not found or invalid", nil)}, cacheDir: filepath.Dir(cacheFile)}
+	_, err = provider.Retrieve()
+	assert.True(t, isSsoSessionExpired(err), "Unexpected error: %v", err)
+}
+
+func writeTestFile(t *testing.T, name string, contents string) string {
+	tmpDir, err := ioutil.TempDir("", "aws-helper-test")
+	require.NoError(t, err)
+	path := filepath.Join(tmpDir, name)
+	require.NoError(t, ioutil.WriteFile(path, []byte(contents), 0600))
+	return path
+}
@@ -9,6 +9,7 @@ import (
 	"strconv"
 	"strings"
 
+	"github.com/gruntwork-io/terragrunt/aws_helper"
 	"github.com/gruntwork-io/terragrunt/config"
 	"github.com/gruntwork-io/terragrunt/errors"
 	"github.com/gruntwork-io/terragrunt/options"
@@ -52,10 +53,29 @@ func parseTerragruntOptionsFromArgs(terragruntVersion string, args []string, wri
 		return nil, err
 	}
 
+	// The user or system wide defaults file has the lowest precedence, so its values are only used as the fallback
+	// for settings that are not passed in via the CLI or environment. A broken defaults file shouldn't get in the way
+	// of showing the help or version, which don't use any of its settings.
+	defaults, err := config.ReadDefaultsFile()
+	if err != nil {
+		if defaultsFileRequired(args) {
+			return nil, err
+		}
+		util.GlobalFallbackLogEntry.Warnf("Ignoring the defaults file, as it could not be read: %v", err)
+		defaults = &config.DefaultsFile{}
+	}
+
 	downloadDirRaw, err := parseStringArg(args, OPT_DOWNLOAD_DIR, os.Getenv("TERRAGRUNT_DOWNLOAD"))
 	if err != nil {
 		return nil, err
 	}
+	// A template can only be rendered once the config of the module is known, so the default download dir is used
+	// until then
+	downloadDirTemplate := ""
+	if config.IsDownloadDirTemplate(downloadDirRaw) {
+		downloadDirTemplate = downloadDirRaw
+		downloadDirRaw = ""
+	}
 	if downloadDirRaw == "" {
 		downloadDirRaw = util.JoinPath(workingDir, options.TerragruntCacheDir)
 	}
@@ -82,11 +102,166 @@ func parseTerragruntOptionsFromArgs(terragruntVersion string, args []string, wri
 		return nil, err
 	}
 
+	providersLockMirrorDir, err := parseStringArg(args, OPT_TERRAGRUNT_PROVIDERS_LOCK_MIRROR_DIR, os.Getenv("TERRAGRUNT_PROVIDERS_LOCK_MIRROR_DIR"))
+	if err != nil {
+		return nil, err
+	}
+	// The mirror is shared by modules in different folders, so it must not be relative to any of them
+	if providersLockMirrorDir != "" {
+		providersLockMirrorDir, err = filepath.Abs(providersLockMirrorDir)
+		if err != nil {
+			return nil, errors.WithStackTrace(err)
+		}
+	}
+
+	destroyDependentsSearchDir, err := parseStringArg(args, OPT_TERRAGRUNT_DESTROY_DEPENDENTS_SEARCH_DIR, os.Getenv("TERRAGRUNT_DESTROY_DEPENDENTS_SEARCH_DIR"))
+	if err != nil {
+		return nil, err
+	}
+	if destroyDependentsSearchDir != "" {
+		destroyDependentsSearchDir, err = filepath.Abs(destroyDependentsSearchDir)
+		if err != nil {
+			return nil, errors.WithStackTrace(err)
+		}
+	}
+
+	providerCacheDir, err := parseStringArg(args, OPT_TERRAGRUNT_PROVIDER_CACHE_DIR, os.Getenv("TERRAGRUNT_PROVIDER_CACHE_DIR"))
+	if err != nil {
+		return nil, err
+	}
+	if providerCacheDir == "" && defaults.ProviderCacheDir != nil {
+		providerCacheDir = *defaults.ProviderCacheDir
+		if !filepath.IsAbs(providerCacheDir) {
+			providerCacheDir = util.JoinPath(filepath.Dir(defaults.Path), providerCacheDir)
+		}
+	}
+	if providerCacheDir != "" {
+		providerCacheDir, err = filepath.Abs(providerCacheDir)
+		if err != nil {
+			return nil, errors.WithStackTrace(err)
+		}
+	}
+
+	cpuProfile, err := parsePathArg(args, OPT_TERRAGRUNT_CPU_PROFILE, os.Getenv("TERRAGRUNT_CPU_PROFILE"))
+	if err != nil {
+		return nil, err
+	}
+	memProfile, err := parsePathArg(args, OPT_TERRAGRUNT_MEM_PROFILE, os.Getenv("TERRAGRUNT_MEM_PROFILE"))
+	if err != nil {
+		return nil, err
+	}
+	traceFile, err := parsePathArg(args, OPT_TERRAGRUNT_TRACE, os.Getenv("TERRAGRUNT_TRACE"))
+	if err != nil {
+		return nil, err
+	}
+	telemetryEndpoint, err := parseStringArg(args, OPT_TERRAGRUNT_TELEMETRY_ENDPOINT, os.Getenv("TERRAGRUNT_TELEMETRY_ENDPOINT"))
+	if err != nil {
+		return nil, err
+	}
+	if telemetryEndpoint == "" && defaults.TelemetryEndpoint != nil {
+		telemetryEndpoint = *defaults.TelemetryEndpoint
+	}
+	metricsEndpoint, err := parseStringArg(args, OPT_TERRAGRUNT_METRICS_ENDPOINT, os.Getenv("TERRAGRUNT_METRICS_ENDPOINT"))
+	if err != nil {
+		return nil, err
+	}
+	eventsEndpoint, err := parseStringArg(args, OPT_TERRAGRUNT_EVENTS_ENDPOINT, os.Getenv("TERRAGRUNT_EVENTS_ENDPOINT"))
+	if err != nil {
+		return nil, err
+	}
+	atlantisWorkflow, err := parseStringArg(args, OPT_TERRAGRUNT_ATLANTIS_WORKFLOW, os.Getenv("TERRAGRUNT_ATLANTIS_WORKFLOW"))
+	if err != nil {
+		return nil, err
+	}
+	junitReportPath, err := parsePathArg(args, OPT_TERRAGRUNT_REPORT_JUNIT, os.Getenv("TERRAGRUNT_REPORT_JUNIT"))
+	if err != nil {
+		return nil, err
+	}
+	sarifOutput, err := parsePathArg(args, OPT_TERRAGRUNT_SARIF_OUTPUT, os.Getenv("TERRAGRUNT_SARIF_OUTPUT"))
+	if err != nil {
+		return nil, err
+	}
+	infracostReportPath, err := parsePathArg(args, OPT_TERRAGRUNT_INFRACOST_REPORT, os.Getenv("TERRAGRUNT_INFRACOST_REPORT"))
+	if err != nil {
+		return nil, err
+	}
+	driftReportPath, err := parsePathArg(args, OPT_TERRAGRUNT_DRIFT_REPORT, os.Getenv("TERRAGRUNT_DRIFT_REPORT"))
+	if err != nil {
+		return nil, err
+	}
+	planArtifactUrl, err := parseStringArg(args, OPT_TERRAGRUNT_PLAN_ARTIFACT_URL, os.Getenv("TERRAGRUNT_PLAN_ARTIFACT_URL"))
+	if err != nil {
+		return nil, err
+	}
+	planArtifactRunId, err := parseStringArg(args, OPT_TERRAGRUNT_PLAN_ARTIFACT_RUN_ID, os.Getenv("TERRAGRUNT_PLAN_ARTIFACT_RUN_ID"))
+	if err != nil {
+		return nil, err
+	}
+	planMarkdownPath, err := parsePathArg(args, OPT_TERRAGRUNT_PLAN_MARKDOWN, os.Getenv("TERRAGRUNT_PLAN_MARKDOWN"))
+	if err != nil {
+		return nil, err
+	}
+	planRenderer, err := parseStringArg(args, OPT_TERRAGRUNT_PLAN_RENDERER, os.Getenv("TERRAGRUNT_PLAN_RENDERER"))
+	if err != nil {
+		return nil, err
+	}
+	docsInventoryPath, err := parsePathArg(args, OPT_TERRAGRUNT_DOCS_INVENTORY, os.Getenv("TERRAGRUNT_DOCS_INVENTORY"))
+	if err != nil {
+		return nil, err
+	}
+	gitCredentialHelper, err := parseStringArg(args, OPT_TERRAGRUNT_GIT_CREDENTIAL_HELPER, os.Getenv("TERRAGRUNT_GIT_CREDENTIAL_HELPER"))
+	if err != nil {
+		return nil, err
+	}
+	gitNetrcTemplate, err := parsePathArg(args, OPT_TERRAGRUNT_GIT_NETRC_TEMPLATE, os.Getenv("TERRAGRUNT_GIT_NETRC_TEMPLATE"))
+	if err != nil {
+		return nil, err
+	}
+	githubAppID, err := parseStringArg(args, OPT_TERRAGRUNT_GITHUB_APP_ID, os.Getenv("TERRAGRUNT_GITHUB_APP_ID"))
+	if err != nil {
+		return nil, err
+	}
+	githubAppInstallationID, err := parseStringArg(args, OPT_TERRAGRUNT_GITHUB_APP_INSTALLATION_ID, os.Getenv("TERRAGRUNT_GITHUB_APP_INSTALLATION_ID"))
+	if err != nil {
+		return nil, err
+	}
+	githubAppPrivateKey, err := parseStringArg(args, OPT_TERRAGRUNT_GITHUB_APP_PRIVATE_KEY, os.Getenv("TERRAGRUNT_GITHUB_APP_PRIVATE_KEY"))
+	if err != nil {
+		return nil, err
+	}
+	gitLabReportDir, err := parsePathArg(args, OPT_TERRAGRUNT_GITLAB_REPORT_DIR, os.Getenv("TERRAGRUNT_GITLAB_REPORT_DIR"))
+	if err != nil {
+		return nil, err
+	}
+
+	daemonSocket, err := parseStringArg(args, OPT_TERRAGRUNT_DAEMON_SOCKET, os.Getenv("TERRAGRUNT_DAEMON_SOCKET"))
+	if err != nil {
+		return nil, err
+	}
+	if daemonSocket != "" {
+		daemonSocket, err = filepath.Abs(daemonSocket)
+		if err != nil {
+			return nil, errors.WithStackTrace(err)
+		}
+	}
+
+	sourceCacheDir, err := parseStringArg(args, OPT_TERRAGRUNT_SOURCE_CACHE_DIR, os.Getenv("TERRAGRUNT_SOURCE_CACHE_DIR"))
+	if err != nil {
+		return nil, err
+	}
+	if sourceCacheDir != "" {
+		sourceCacheDir, err = filepath.Abs(sourceCacheDir)
+		if err != nil {
+			return nil, errors.WithStackTrace(err)
+		}
+	}
+
 	terraformPath, err := parseStringArg(args, OPT_TERRAGRUNT_TFPATH, os.Getenv("TERRAGRUNT_TFPATH"))
 	if err != nil {
 		return nil, err
 	}
-	if terraformPath == "" {
+	terraformPathExplicit := terraformPath != ""
+	if !terraformPathExplicit {
 		terraformPath = options.TERRAFORM_DEFAULT_PATH
 	}
 
@@ -99,26 +274,93 @@ func parseTerragruntOptionsFromArgs(terragruntVersion string, args []string, wri
 	if err != nil {
 		return nil, err
 	}
+	if terraformSourceMapEnvVar == nil {
+		terraformSourceMapEnvVar = defaults.SourceMap
+	}
 	terraformSourceMap, err := parseMutliStringKeyValueArg(args, OPT_TERRAGRUNT_SOURCE_MAP, terraformSourceMapEnvVar)
 	if err != nil {
 		return nil, err
 	}
 
+	sourceMirrorsEnvVar, err := parseMultiStringKeyValueEnvVar("TERRAGRUNT_SOURCE_MIRROR")
+	if err != nil {
+		return nil, err
+	}
+	if len(sourceMirrorsEnvVar) == 0 && defaults.SourceMirror != nil {
+		sourceMirrorsEnvVar = defaults.SourceMirror
+	}
+	sourceMirrors, err := parseMutliStringKeyValueArg(args, OPT_TERRAGRUNT_SOURCE_MIRROR, sourceMirrorsEnvVar)
+	if err != nil {
+		return nil, err
+	}
+	sourceMirrorHeadersEnvVar, err := parseMultiStringKeyValueEnvVar("TERRAGRUNT_SOURCE_MIRROR_HEADER")
+	if err != nil {
+		return nil, err
+	}
+	sourceMirrorHeaders, err := parseMutliStringKeyValueArg(args, OPT_TERRAGRUNT_SOURCE_MIRROR_HEADER, sourceMirrorHeadersEnvVar)
+	if err != nil {
+		return nil, err
+	}
+	awsEndpointsEnvVar, err := parseMultiStringKeyValueEnvVar("TERRAGRUNT_AWS_ENDPOINT")
+	if err != nil {
+		return nil, err
+	}
+	awsEndpoints, err := parseMutliStringKeyValueArg(args, OPT_TERRAGRUNT_AWS_ENDPOINT, awsEndpointsEnvVar)
+	if err != nil {
+		return nil, err
+	}
+	if err := aws_helper.ValidateCustomEndpoints(awsEndpoints); err != nil {
+		return nil, err
+	}
+	sourceSigV4Service, err := parseStringArg(args, OPT_TERRAGRUNT_SOURCE_SIGV4_SERVICE, os.Getenv("TERRAGRUNT_SOURCE_SIGV4_SERVICE"))
+	if err != nil {
+		return nil, err
+	}
+
 	sourceUpdate := parseBooleanArg(args, OPT_TERRAGRUNT_SOURCE_UPDATE, os.Getenv("TERRAGRUNT_SOURCE_UPDATE") == "true" || os.Getenv("TERRAGRUNT_SOURCE_UPDATE") == "1")
 
 	ignoreDependencyErrors := parseBooleanArg(args, OPT_TERRAGRUNT_IGNORE_DEPENDENCY_ERRORS, false)
 
 	ignoreDependencyOrder := parseBooleanArg(args, OPT_TERRAGRUNT_IGNORE_DEPENDENCY_ORDER, false)
 
-	ignoreExternalDependencies := parseBooleanArg(args, OPT_TERRAGRUNT_IGNORE_EXTERNAL_DEPENDENCIES, false)
+	ignoreExternalDependencies := parseBooleanArg(args, OPT_TERRAGRUNT_IGNORE_EXTERNAL_DEPENDENCIES, os.Getenv("TERRAGRUNT_IGNORE_EXTERNAL_DEPENDENCIES") == "true")
 
 	includeExternalDependencies := parseBooleanArg(args, OPT_TERRAGRUNT_INCLUDE_EXTERNAL_DEPENDENCIES, os.Getenv("TERRAGRUNT_INCLUDE_EXTERNAL_DEPENDENCIES") == "true")
 
+	if ignoreExternalDependencies && includeExternalDependencies {
+		return nil, errors.WithStackTrace(ConflictingArgs{OPT_TERRAGRUNT_IGNORE_EXTERNAL_DEPENDENCIES, OPT_TERRAGRUNT_INCLUDE_EXTERNAL_DEPENDENCIES})
+	}
+
 	iamRole, err := parseStringArg(args, OPT_TERRAGRUNT_IAM_ROLE, os.Getenv("TERRAGRUNT_IAM_ROLE"))
 	if err != nil {
 		return nil, err
 	}
 
+	iamAssumeRoleExternalId, err := parseStringArg(args, OPT_TERRAGRUNT_IAM_ASSUME_ROLE_EXTERNAL_ID, os.Getenv("TERRAGRUNT_IAM_ASSUME_ROLE_EXTERNAL_ID"))
+	if err != nil {
+		return nil, err
+	}
+
+	iamAssumeRoleMfaSerial, err := parseStringArg(args, OPT_TERRAGRUNT_IAM_ASSUME_ROLE_MFA_SERIAL, os.Getenv("TERRAGRUNT_IAM_ASSUME_ROLE_MFA_SERIAL"))
+	if err != nil {
+		return nil, err
+	}
+
+	iamAssumeRoleMfaToken, err := parseStringArg(args, OPT_TERRAGRUNT_IAM_ASSUME_ROLE_MFA_TOKEN, os.Getenv("TERRAGRUNT_IAM_ASSUME_ROLE_MFA_TOKEN"))
+	if err != nil {
+		return nil, err
+	}
+
+	iamWebIdentityToken, err := parseStringArg(args, OPT_TERRAGRUNT_IAM_WEB_IDENTITY_TOKEN, os.Getenv("TERRAGRUNT_IAM_WEB_IDENTITY_TOKEN"))
+	if err != nil {
+		return nil, err
+	}
+
+	googleImpersonateServiceAccount, err := parseStringArg(args, OPT_TERRAGRUNT_GOOGLE_IMPERSONATE_SERVICE_ACCOUNT, os.Getenv("TERRAGRUNT_GOOGLE_IMPERSONATE_SERVICE_ACCOUNT"))
+	if err != nil {
+		return nil, err
+	}
+
 	envValue, envProvided := os.LookupEnv("TERRAGRUNT_IAM_ASSUME_ROLE_DURATION")
 	IamAssumeRoleDuration, err := parseIntArg(args, OPT_TERRAGRUNT_IAM_ASSUME_ROLE_DURATION, envValue, envProvided, options.DEFAULT_IAM_ASSUME_ROLE_DURATION)
 	if err != nil {
@@ -135,10 +377,33 @@ func parseTerragruntOptionsFromArgs(terragruntVersion string, args []string, wri
 		return nil, err
 	}
 
-	strictInclude := parseBooleanArg(args, OPT_TERRAGRUNT_STRICT_INCLUDE, false)
+	defaultPolicies := []string{}
+	if envPolicies := os.Getenv("TERRAGRUNT_POLICY"); envPolicies != "" {
+		defaultPolicies = strings.Split(envPolicies, ",")
+	}
+	policies, err := parseMultiStringArg(args, OPT_TERRAGRUNT_POLICY, defaultPolicies)
+	if err != nil {
+		return nil, err
+	}
+
+	queueIncludeUnitsReading, err := parseMultiStringArg(args, OPT_TERRAGRUNT_QUEUE_INCLUDE_UNITS_READING, []string{})
+	if err != nil {
+		return nil, err
+	}
+
+	changedSince, err := parseStringArg(args, OPT_TERRAGRUNT_CHANGED_SINCE, os.Getenv("TERRAGRUNT_CHANGED_SINCE"))
+	if err != nil {
+		return nil, err
+	}
+
+	strictInclude := parseBooleanArg(args, OPT_TERRAGRUNT_STRICT_INCLUDE, os.Getenv("TERRAGRUNT_STRICT_INCLUDE") == "true")
 
 	// Those correspond to logrus levels
-	logLevel, err := parseStringArg(args, OPT_TERRAGRUNT_LOGLEVEL, util.DEFAULT_LOG_LEVEL.String())
+	defaultLogLevel := util.DEFAULT_LOG_LEVEL.String()
+	if defaults.LogLevel != nil {
+		defaultLogLevel = *defaults.LogLevel
+	}
+	logLevel, err := parseStringArg(args, OPT_TERRAGRUNT_LOGLEVEL, defaultLogLevel)
 	if err != nil {
 		return nil, err
 	}
@@ -162,13 +427,62 @@ func parseTerragruntOptionsFromArgs(terragruntVersion string, args []string, wri
 	}
 
 	envValue, envProvided = os.LookupEnv("TERRAGRUNT_PARALLELISM")
-	parallelism, err := parseIntArg(args, OPT_TERRAGRUNT_PARALLELISM, envValue, envProvided, options.DEFAULT_PARALLELISM)
+	defaultParallelism := options.DEFAULT_PARALLELISM
+	if defaults.Parallelism != nil {
+		defaultParallelism = *defaults.Parallelism
+	}
+	parallelism, err := parseIntArg(args, OPT_TERRAGRUNT_PARALLELISM, envValue, envProvided, defaultParallelism)
 	if err != nil {
 		return nil, err
 	}
+	// A parallelism of 0 would make the *-all commands wait forever, as no module would ever be allowed to run
+	if parallelism < 1 {
+		return nil, errors.WithStackTrace(InvalidParallelism(parallelism))
+	}
+
+	envValue, envProvided = os.LookupEnv("TERRAGRUNT_DEPENDENCY_FETCH_PARALLELISM")
+	dependencyFetchParallelism, err := parseIntArg(args, OPT_TERRAGRUNT_DEPENDENCY_FETCH_PARALLELISM, envValue, envProvided, options.DEFAULT_DEPENDENCY_FETCH_PARALLELISM)
+	if err != nil {
+		return nil, err
+	}
+	if dependencyFetchParallelism < 1 {
+		return nil, errors.WithStackTrace(InvalidDependencyFetchParallelism(dependencyFetchParallelism))
+	}
+
+	envValue, envProvided = os.LookupEnv("TERRAGRUNT_DEPENDENCY_OUTPUT_CACHE_TTL")
+	dependencyOutputCacheTTL, err := parseIntArg(args, OPT_TERRAGRUNT_DEPENDENCY_OUTPUT_CACHE_TTL, envValue, envProvided, 0)
+	if err != nil {
+		return nil, err
+	}
+	if dependencyOutputCacheTTL < 0 {
+		return nil, errors.WithStackTrace(InvalidDependencyOutputCacheTTL(dependencyOutputCacheTTL))
+	}
+
+	envValue, envProvided = os.LookupEnv("TERRAGRUNT_SOURCE_CACHE_MAX_AGE")
+	sourceCacheMaxAge, err := parseIntArg(args, OPT_TERRAGRUNT_SOURCE_CACHE_MAX_AGE, envValue, envProvided, options.DEFAULT_SOURCE_CACHE_MAX_AGE)
+	if err != nil {
+		return nil, err
+	}
+	if sourceCacheMaxAge < 0 {
+		return nil, errors.WithStackTrace(InvalidSourceCacheMaxAge(sourceCacheMaxAge))
+	}
+
+	defaultInputMode := options.INPUT_MODE_AUTO
+	if envInputMode := os.Getenv("TERRAGRUNT_INPUT_MODE"); envInputMode != "" {
+		defaultInputMode = envInputMode
+	}
+	inputMode, err := parseStringArg(args, OPT_TERRAGRUNT_INPUT_MODE, defaultInputMode)
+	if err != nil {
+		return nil, err
+	}
+	if !util.ListContainsElement(options.INPUT_MODES, inputMode) {
+		return nil, errors.WithStackTrace(InvalidInputMode(inputMode))
+	}
 
 	opts.TerraformPath = filepath.ToSlash(terraformPath)
+	opts.TerraformPathExplicit = terraformPathExplicit
 	opts.AutoInit = !parseBooleanArg(args, OPT_TERRAGRUNT_NO_AUTO_INIT, os.Getenv("TERRAGRUNT_AUTO_INIT") == "false")
+	opts.CopyLockFile = !parseBooleanArg(args, OPT_TERRAGRUNT_NO_COPY_LOCK_FILE, os.Getenv("TERRAGRUNT_COPY_LOCK_FILE") == "false")
 	opts.AutoRetry = !parseBooleanArg(args, OPT_TERRAGRUNT_NO_AUTO_RETRY, os.Getenv("TERRAGRUNT_AUTO_RETRY") == "false")
 	opts.NonInteractive = parseBooleanArg(args, OPT_NON_INTERACTIVE, os.Getenv("TF_INPUT") == "false" || os.Getenv("TF_INPUT") == "0")
 	opts.TerraformCliArgs = filterTerragruntArgs(args)
@@ -176,13 +490,21 @@ func parseTerragruntOptionsFromArgs(terragruntVersion string, args []string, wri
 	opts.TerraformCommand = util.FirstArg(opts.TerraformCliArgs)
 	opts.WorkingDir = filepath.ToSlash(workingDir)
 	opts.DownloadDir = filepath.ToSlash(downloadDir)
+	opts.DownloadDirTemplate = downloadDirTemplate
 	opts.LogLevel = loggingLevel
 	opts.Logger = util.CreateLogEntry("", loggingLevel)
 	opts.Logger.Logger.SetOutput(errWriter)
 	opts.RunTerragrunt = RunTerragrunt
 	opts.Source = terraformSource
 	opts.SourceMap = terraformSourceMap
+	opts.SourceMirrors = sourceMirrors
+	opts.SourceMirrorHeaders = sourceMirrorHeaders
+	opts.SourceSigV4Service = sourceSigV4Service
 	opts.SourceUpdate = sourceUpdate
+	opts.SymlinkLocalSource = parseBooleanArg(args, OPT_TERRAGRUNT_SYMLINK_LOCAL_SOURCE, os.Getenv("TERRAGRUNT_SYMLINK_LOCAL_SOURCE") == "true")
+	opts.SourceCache = parseBooleanArg(args, OPT_TERRAGRUNT_SOURCE_CACHE, os.Getenv("TERRAGRUNT_SOURCE_CACHE") == "true")
+	opts.SourceCacheDir = filepath.ToSlash(sourceCacheDir)
+	opts.SourceCacheMaxAge = sourceCacheMaxAge
 	opts.TerragruntVersion, err = version.NewVersion(terragruntVersion)
 	if err != nil {
 		// Malformed Terragrunt version; set the version to 0.0
@@ -200,14 +522,102 @@ func parseTerragruntOptionsFromArgs(terragruntVersion string, args []string, wri
 	opts.Env = parseEnvironmentVariables(os.Environ())
 	opts.IamRole = iamRole
 	opts.IamAssumeRoleDuration = int64(IamAssumeRoleDuration)
+	opts.IamAssumeRoleExternalId = iamAssumeRoleExternalId
+	opts.IamAssumeRoleMfaSerial = iamAssumeRoleMfaSerial
+	opts.IamAssumeRoleMfaToken = iamAssumeRoleMfaToken
+	opts.IamWebIdentityToken = iamWebIdentityToken
+	opts.GoogleImpersonateServiceAccount = googleImpersonateServiceAccount
 	opts.ExcludeDirs = excludeDirs
 	opts.IncludeDirs = includeDirs
 	opts.StrictInclude = strictInclude
+	opts.QueueIncludeUnitsReading = queueIncludeUnitsReading
+	opts.ChangedSince = changedSince
+	opts.IncludeChangedDependents = parseBooleanArg(args, OPT_TERRAGRUNT_INCLUDE_CHANGED_DEPENDENTS, os.Getenv("TERRAGRUNT_INCLUDE_CHANGED_DEPENDENTS") == "true")
 	opts.Parallelism = parallelism
+	opts.DependencyFetchParallelism = dependencyFetchParallelism
+	opts.DependencyOutputCacheTTL = dependencyOutputCacheTTL
+	opts.FetchDependencyOutputFromState = parseBooleanArg(args, OPT_TERRAGRUNT_FETCH_DEPENDENCY_OUTPUT_FROM_STATE, os.Getenv("TERRAGRUNT_FETCH_DEPENDENCY_OUTPUT_FROM_STATE") == "true")
+	opts.AwsSsoLogin = parseBooleanArg(args, OPT_TERRAGRUNT_AWS_SSO_LOGIN, os.Getenv("TERRAGRUNT_AWS_SSO_LOGIN") == "true")
+	opts.AwsEndpoints = awsEndpoints
+	opts.AwsStsRegionalEndpoints = parseBooleanArg(args, OPT_TERRAGRUNT_AWS_STS_REGIONAL_ENDPOINTS, os.Getenv("TERRAGRUNT_AWS_STS_REGIONAL_ENDPOINTS") == "true")
+	opts.AwsKeyringCache = parseBooleanArg(args, OPT_TERRAGRUNT_AWS_KEYRING_CACHE, os.Getenv("TERRAGRUNT_AWS_KEYRING_CACHE") == "true")
+	opts.PreflightChecks = parseBooleanArg(args, OPT_TERRAGRUNT_PREFLIGHT_CHECKS, os.Getenv("TERRAGRUNT_PREFLIGHT_CHECKS") == "true")
+	opts.InputMode = inputMode
+	opts.ProvidersLockMirrorDir = filepath.ToSlash(providersLockMirrorDir)
+	providerCache := defaults.ProviderCache != nil && *defaults.ProviderCache
+	if envValue, envProvided := os.LookupEnv("TERRAGRUNT_PROVIDER_CACHE"); envProvided {
+		providerCache = envValue == "true" || envValue == "1"
+	}
+	opts.ProviderCache = parseBooleanArg(args, OPT_TERRAGRUNT_PROVIDER_CACHE, providerCache)
+	opts.ProviderCacheDir = filepath.ToSlash(providerCacheDir)
+	opts.CPUProfile = cpuProfile
+	opts.MemProfile = memProfile
+	opts.TraceFile = traceFile
+	opts.TelemetryEndpoint = telemetryEndpoint
+	opts.MetricsEndpoint = metricsEndpoint
+	opts.EventsEndpoint = eventsEndpoint
+	opts.GitLabReportDir = gitLabReportDir
+	opts.JUnitReportPath = junitReportPath
+	opts.AtlantisWorkflow = atlantisWorkflow
+	opts.SarifOutput = sarifOutput
+	opts.Policies = policies
+	opts.Infracost = parseBooleanArg(args, OPT_TERRAGRUNT_INFRACOST, os.Getenv("TERRAGRUNT_INFRACOST") == "true") || infracostReportPath != ""
+	opts.InfracostReportPath = infracostReportPath
+	opts.DriftReportPath = driftReportPath
+	opts.PlanArtifactUrl = planArtifactUrl
+	opts.PlanArtifactRunId = planArtifactRunId
+	opts.PlanMarkdownPath = planMarkdownPath
+	opts.PlanRenderer = planRenderer
+	opts.DocsInventoryPath = docsInventoryPath
+	opts.TFCRun = parseBooleanArg(args, OPT_TERRAGRUNT_TFC_RUN, os.Getenv("TERRAGRUNT_TFC_RUN") == "true")
+	opts.ShallowClone = parseBooleanArg(args, OPT_TERRAGRUNT_SHALLOW_CLONE, os.Getenv("TERRAGRUNT_SHALLOW_CLONE") == "true")
+	opts.SparseCheckout = parseBooleanArg(args, OPT_TERRAGRUNT_SPARSE_CHECKOUT, os.Getenv("TERRAGRUNT_SPARSE_CHECKOUT") == "true")
+	opts.GitCredentialHelper = gitCredentialHelper
+	opts.GitNetrcTemplate = gitNetrcTemplate
+	opts.GitHubAppID = githubAppID
+	opts.GitHubAppInstallationID = githubAppInstallationID
+	opts.GitHubAppPrivateKey = githubAppPrivateKey
+	opts.GitHubActions = parseBooleanArg(args, OPT_TERRAGRUNT_GITHUB_ACTIONS, os.Getenv("TERRAGRUNT_GITHUB_ACTIONS") == "true")
+	opts.Daemon = parseBooleanArg(args, OPT_TERRAGRUNT_DAEMON, os.Getenv("TERRAGRUNT_DAEMON") == "true")
+	opts.DaemonSocket = daemonSocket
+	opts.PrefetchOnly = parseBooleanArg(args, OPT_TERRAGRUNT_PREFETCH_ONLY, os.Getenv("TERRAGRUNT_PREFETCH_ONLY") == "true")
+	if opts.PrefetchOnly && opts.TerraformCommand != CMD_INIT {
+		return nil, errors.WithStackTrace(PrefetchOnlyRequiresInit(opts.TerraformCommand))
+	}
+	if parseBooleanArg(args, OPT_TERRAGRUNT_NO_CONFIG_CACHE, os.Getenv("TERRAGRUNT_NO_CONFIG_CACHE") == "true") {
+		opts.ConfigCache = nil
+	}
+	opts.NoDestroyDependenciesCheck = parseBooleanArg(args, OPT_TERRAGRUNT_NO_DESTROY_DEPENDENCIES_CHECK, os.Getenv("TERRAGRUNT_NO_DESTROY_DEPENDENCIES_CHECK") == "true")
+	opts.DestroyDependentsSearchDir = filepath.ToSlash(destroyDependentsSearchDir)
+	opts.NoOutputPrefix = parseBooleanArg(args, OPT_TERRAGRUNT_NO_OUTPUT_PREFIX, os.Getenv("TERRAGRUNT_NO_OUTPUT_PREFIX") == "true")
 	opts.Check = parseBooleanArg(args, OPT_TERRAGRUNT_CHECK, os.Getenv("TERRAGRUNT_CHECK") == "true")
 	opts.HclFile = filepath.ToSlash(terragruntHclFilePath)
 	opts.AwsProviderPatchOverrides = awsProviderPatchOverrides
 
+	// The org-wide default download dir in the environment takes precedence over the one in the defaults file. Templates
+	// are rendered relative to the folder of each module, so they're kept as is.
+	if defaultDownloadDir := os.Getenv(config.DefaultDownloadDirEnvVar); defaultDownloadDir != "" {
+		if !config.IsDownloadDirTemplate(defaultDownloadDir) {
+			defaultDownloadDir, err = filepath.Abs(defaultDownloadDir)
+			if err != nil {
+				return nil, errors.WithStackTrace(err)
+			}
+		}
+		opts.DefaultsDownloadDir = filepath.ToSlash(defaultDownloadDir)
+	} else if defaults.DownloadDir != nil {
+		defaultsDownloadDir := *defaults.DownloadDir
+		if !filepath.IsAbs(defaultsDownloadDir) && !config.IsDownloadDirTemplate(defaultsDownloadDir) {
+			defaultsDownloadDir = util.JoinPath(filepath.Dir(defaults.Path), defaultsDownloadDir)
+		}
+		opts.DefaultsDownloadDir = filepath.ToSlash(defaultsDownloadDir)
+	}
+	if defaults.TerraformBinary != nil {
+		opts.DefaultsTerraformPath = *defaults.TerraformBinary
+	}
+	if defaults.Path != "" {
+		opts.Logger.Debugf("Loaded defaults from %s", defaults.Path)
+	}
+
 	return opts, nil
 }
 
@@ -286,6 +696,21 @@ func parseEnvironmentVariables(environment []string) map[string]string {
 }
 
 // Return a copy of the given args with all Terragrunt-specific args removed
+// Returns true if the command in the given args uses the settings of the defaults file, so that it must fail if the
+// defaults file can't be read. Showing the help or the version of terraform doesn't.
+func defaultsFileRequired(args []string) bool {
+	terraformArgs := filterTerragruntArgs(args)
+	if util.FirstArg(terraformArgs) == "version" {
+		return false
+	}
+	for _, helpFlag := range TERRAFORM_HELP_FLAGS {
+		if util.ListContainsElement(terraformArgs, helpFlag) {
+			return false
+		}
+	}
+	return true
+}
+
 func filterTerragruntArgs(args []string) []string {
 	out := []string{}
 	for i := 0; i < len(args); i++ {
@@ -356,6 +781,20 @@ func parseStringArg(args []string, argName string, defaultValue string) (string,
 	return defaultValue, nil
 }
 
+// Find a string argument (e.g. --foo "PATH") of the given name in the given list of arguments, like parseStringArg, and
+// return it as an absolute path, so that it's not affected by the working dir changing later on.
+func parsePathArg(args []string, argName string, defaultValue string) (string, error) {
+	path, err := parseStringArg(args, argName, defaultValue)
+	if err != nil || path == "" {
+		return path, err
+	}
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return "", errors.WithStackTrace(err)
+	}
+	return filepath.ToSlash(absPath), nil
+}
+
 // Find a int argument (e.g. --foo 1) of the given name in the given list of arguments. If it's present,
 // return its value. If it is present, but has no value, return an error. If it isn't present, return envValue if provided. If not provided, return defaultValue.
 func parseIntArg(args []string, argName string, envValue string, envProvided bool, defaultValue int) (int, error) {
@@ -473,3 +912,48 @@ type ArgMissingValue string
 func (err ArgMissingValue) Error() string {
 	return fmt.Sprintf("You must specify a value for the --%s option", string(err))
 }
+
+type InvalidParallelism int
+
+func (err InvalidParallelism) Error() string {
+	return fmt.Sprintf("The --%s option must be at least 1, but got %d", OPT_TERRAGRUNT_PARALLELISM, int(err))
+}
+
+type InvalidDependencyFetchParallelism int
+
+func (err InvalidDependencyFetchParallelism) Error() string {
+	return fmt.Sprintf("The --%s option must be at least 1, but got %d", OPT_TERRAGRUNT_DEPENDENCY_FETCH_PARALLELISM, int(err))
+}
+
+type InvalidDependencyOutputCacheTTL int
+
+func (err InvalidDependencyOutputCacheTTL) Error() string {
+	return fmt.Sprintf("The --%s option must not be negative, but got %d", OPT_TERRAGRUNT_DEPENDENCY_OUTPUT_CACHE_TTL, int(err))
+}
+
+type InvalidSourceCacheMaxAge int
+
+func (err InvalidSourceCacheMaxAge) Error() string {
+	return fmt.Sprintf("The --%s option must not be negative, but got %d", OPT_TERRAGRUNT_SOURCE_CACHE_MAX_AGE, int(err))
+}
+
+type InvalidInputMode string
+
+func (err InvalidInputMode) Error() string {
+	return fmt.Sprintf("The --%s option must be one of %s, but got '%s'", OPT_TERRAGRUNT_INPUT_MODE, strings.Join(options.INPUT_MODES, ", "), string(err))
+}
+
+type ConflictingArgs struct {
+	Arg            string
+	ConflictingArg string
+}
+
+func (err ConflictingArgs) Error() string {
+	return fmt.Sprintf("The --%s and --%s options can't be used together", err.Arg, err.ConflictingArg)
+}
+
+type PrefetchOnlyRequiresInit string
+
+func (command PrefetchOnlyRequiresInit) Error() string {
+	return fmt.Sprintf("The --%s option can only be used with the init command, but got '%s'", OPT_TERRAGRUNT_PREFETCH_ONLY, string(command))
+}
@@ -156,6 +156,36 @@ func TestParseTerragruntOptionsFromArgs(t *testing.T) {
 			nil,
 			ArgMissingValue("terragrunt-config"),
 		},
+		{
+			[]string{"--terragrunt-parallelism", "0"},
+			nil,
+			InvalidParallelism(0),
+		},
+		{
+			[]string{"--terragrunt-dependency-fetch-parallelism", "0"},
+			nil,
+			InvalidDependencyFetchParallelism(0),
+		},
+		{
+			[]string{"--terragrunt-dependency-output-cache-ttl", "-1"},
+			nil,
+			InvalidDependencyOutputCacheTTL(-1),
+		},
+		{
+			[]string{"--terragrunt-input-mode", "tty"},
+			nil,
+			InvalidInputMode("tty"),
+		},
+		{
+			[]string{"--terragrunt-ignore-external-dependencies", "--terragrunt-include-external-dependencies"},
+			nil,
+			ConflictingArgs{"terragrunt-ignore-external-dependencies", "terragrunt-include-external-dependencies"},
+		},
+		{
+			[]string{"plan", "--terragrunt-prefetch-only"},
+			nil,
+			PrefetchOnlyRequiresInit("plan"),
+		},
 		{
 			[]string{"--terragrunt-debug"},
 			mockOptions(t, util.JoinPath(workingDir, config.DefaultTerragruntConfigPath), workingDir, []string{}, false, "", false, false, defaultLogLevel, true),
@@ -174,6 +204,25 @@ func TestParseTerragruntOptionsFromArgs(t *testing.T) {
 	}
 }
 
+func TestParseDownloadDirTemplate(t *testing.T) {
+	t.Parallel()
+
+	workingDir, err := os.Getwd()
+	require.NoError(t, err)
+	template := `/dev/shm/terragrunt/${get_env("BRANCH", "main")}`
+
+	// The template is kept for each module to render, and the default download dir is used until then
+	opts, err := parseTerragruntOptionsFromArgs("0.0", []string{"plan", "--" + OPT_DOWNLOAD_DIR, template}, &bytes.Buffer{}, &bytes.Buffer{})
+	require.NoError(t, err)
+	assert.Equal(t, template, opts.DownloadDirTemplate)
+	assert.Equal(t, util.JoinPath(filepath.ToSlash(workingDir), options.TerragruntCacheDir), opts.DownloadDir)
+
+	opts, err = parseTerragruntOptionsFromArgs("0.0", []string{"plan", "--" + OPT_DOWNLOAD_DIR, "/dev/shm/terragrunt"}, &bytes.Buffer{}, &bytes.Buffer{})
+	require.NoError(t, err)
+	assert.Equal(t, "", opts.DownloadDirTemplate)
+	assert.Equal(t, "/dev/shm/terragrunt", opts.DownloadDir)
+}
+
 // We can't do a direct comparison between TerragruntOptions objects because we can't compare Logger or RunTerragrunt
 // instances. Therefore, we have to manually check everything else.
 func assertOptionsEqual(t *testing.T, expected options.TerragruntOptions, actual options.TerragruntOptions, msgAndArgs ...interface{}) {
@@ -589,6 +638,25 @@ func TestToTerraformEnvVars(t *testing.T) {
 	}
 }
 
+func TestDefaultsFileRequired(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		args     []string
+		expected bool
+	}{
+		{[]string{"plan"}, true},
+		{[]string{"run-all", "apply", "--terragrunt-non-interactive"}, true},
+		{[]string{"version"}, false},
+		{[]string{"plan", "--help"}, false},
+		{[]string{"apply", "-h", "--terragrunt-log-level", "debug"}, false},
+	}
+
+	for _, testCase := range testCases {
+		assert.Equal(t, testCase.expected, defaultsFileRequired(testCase.args), "args %v", testCase.args)
+	}
+}
+
 func createTempFile(t *testing.T) string {
 	tmpFile, err := ioutil.TempFile("", "")
 	if err != nil {
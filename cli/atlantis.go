@@ -0,0 +1,55 @@
+package cli
+
+import (
+	"io/ioutil"
+	"path/filepath"
+
+	"gopkg.in/yaml.v2"
+
+	"github.com/gruntwork-io/terragrunt/configstack"
+	"github.com/gruntwork-io/terragrunt/errors"
+	"github.com/gruntwork-io/terragrunt/options"
+	"github.com/gruntwork-io/terragrunt/util"
+)
+
+// The file Atlantis reads the config of the repo from
+const ATLANTIS_CONFIG_FILE = "atlantis.yaml"
+
+// The header of the generated atlantis.yaml, so that nobody edits it by hand
+const atlantisConfigHeader = "# Generated by terragrunt generate-atlantis-config. Do not edit.\n"
+
+func shouldGenerateAtlantisConfig(terragruntOptions *options.TerragruntOptions) bool {
+	return util.FirstArg(terragruntOptions.TerraformCliArgs) == CMD_GENERATE_ATLANTIS_CONFIG
+}
+
+// Write the repo config of Atlantis, with a project for each module in the working dir or its subfolders, to
+// atlantis.yaml in the working dir. See configstack.Stack.AtlantisConfig for which files are watched for changes.
+func generateAtlantisConfig(terragruntOptions *options.TerragruntOptions) error {
+	// Track the files read by the configs of the modules, so that they're watched too. The external dependencies are
+	// only needed to watch their files, so there's no need to ask whether to run them.
+	terragruntOptions.FilesRead = &options.FilesRead{}
+	terragruntOptions.IgnoreExternalDependencies = true
+
+	stack, err := configstack.FindStackInSubfolders(terragruntOptions)
+	if err != nil {
+		return err
+	}
+
+	atlantisConfig, err := stack.AtlantisConfig(terragruntOptions.WorkingDir, terragruntOptions.AtlantisWorkflow)
+	if err != nil {
+		return err
+	}
+
+	contents, err := yaml.Marshal(atlantisConfig)
+	if err != nil {
+		return errors.WithStackTrace(err)
+	}
+
+	configPath := filepath.Join(terragruntOptions.WorkingDir, ATLANTIS_CONFIG_FILE)
+	if err := ioutil.WriteFile(configPath, append([]byte(atlantisConfigHeader), contents...), 0644); err != nil {
+		return errors.WithStackTrace(err)
+	}
+
+	terragruntOptions.Logger.Infof("Wrote the Atlantis config of %d projects to %s", len(atlantisConfig.Projects), configPath)
+	return nil
+}
@@ -0,0 +1,76 @@
+package cli
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v2"
+
+	"github.com/gruntwork-io/terragrunt/config"
+	"github.com/gruntwork-io/terragrunt/configstack"
+	"github.com/gruntwork-io/terragrunt/options"
+	"github.com/gruntwork-io/terragrunt/util"
+)
+
+func TestGenerateAtlantisConfig(t *testing.T) {
+	t.Parallel()
+
+	rootPath, err := ioutil.TempDir("", "atlantis-config")
+	require.NoError(t, err)
+	defer os.RemoveAll(rootPath)
+
+	files := map[string]string{
+		"root.hcl":                   "locals {}\n",
+		"modules/vpc/main.tf":        "",
+		"modules/vpc/subnet/main.tf": "",
+		"live/vpc/terragrunt.hcl":    "include {\n  path = find_in_parent_folders(\"root.hcl\")\n}\nterraform {\n  source = \"../../modules//vpc\"\n}\n",
+		"live/app/main.tf":           "",
+		"live/app/terragrunt.hcl":    "include {\n  path = find_in_parent_folders(\"root.hcl\")\n}\ndependency \"vpc\" {\n  config_path = \"../vpc\"\n}\n",
+	}
+	for path, contents := range files {
+		filePath := filepath.Join(rootPath, path)
+		require.NoError(t, os.MkdirAll(filepath.Dir(filePath), os.ModePerm))
+		require.NoError(t, ioutil.WriteFile(filePath, []byte(contents), 0644))
+	}
+
+	terragruntOptions, err := options.NewTerragruntOptionsForTest(util.JoinPath(rootPath, config.DefaultTerragruntConfigPath))
+	require.NoError(t, err)
+	terragruntOptions.TerraformCliArgs = []string{CMD_GENERATE_ATLANTIS_CONFIG}
+	terragruntOptions.AtlantisWorkflow = "terragrunt"
+	require.True(t, shouldGenerateAtlantisConfig(terragruntOptions))
+	require.NoError(t, generateAtlantisConfig(terragruntOptions))
+
+	contents, err := ioutil.ReadFile(filepath.Join(rootPath, ATLANTIS_CONFIG_FILE))
+	require.NoError(t, err)
+	var atlantisConfig configstack.AtlantisConfig
+	require.NoError(t, yaml.Unmarshal(contents, &atlantisConfig))
+
+	expected := configstack.AtlantisConfig{
+		Version: 3,
+		Projects: []configstack.AtlantisProject{
+			{
+				Name:     "live_app",
+				Dir:      "live/app",
+				Workflow: "terragrunt",
+				Autoplan: configstack.AtlantisAutoplan{
+					Enabled:      true,
+					WhenModified: []string{"*.hcl", "*.tf*", "../../root.hcl", "../vpc/*.hcl", "../vpc/*.tf*"},
+				},
+			},
+			{
+				Name:     "live_vpc",
+				Dir:      "live/vpc",
+				Workflow: "terragrunt",
+				Autoplan: configstack.AtlantisAutoplan{
+					Enabled:      true,
+					WhenModified: []string{"*.hcl", "*.tf*", "../../modules/**/*.tf*", "../../root.hcl"},
+				},
+			},
+		},
+	}
+	assert.Equal(t, expected, atlantisConfig)
+}
@@ -0,0 +1,336 @@
+package cli
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/gruntwork-io/terragrunt/config"
+	"github.com/gruntwork-io/terragrunt/errors"
+	"github.com/gruntwork-io/terragrunt/options"
+	"github.com/gruntwork-io/terragrunt/remote"
+	"github.com/gruntwork-io/terragrunt/shell"
+	"github.com/gruntwork-io/terragrunt/util"
+)
+
+const (
+	CMD_BACKEND_BOOTSTRAP = "bootstrap"
+	CMD_BACKEND_DELETE    = "delete"
+	CMD_BACKEND_MIGRATE   = "migrate"
+	CMD_BACKEND_CHECK     = "check"
+	CMD_BACKEND_REPAIR    = "repair"
+)
+
+// The subcommands of the backend command
+var backendSubcommands = []string{CMD_BACKEND_BOOTSTRAP, CMD_BACKEND_DELETE, CMD_BACKEND_MIGRATE, CMD_BACKEND_CHECK, CMD_BACKEND_REPAIR}
+
+// The flag of the backend delete and backend migrate commands to skip the confirmation prompt and, for migrate, to
+// overwrite a destination state with a different lineage
+const backendForceFlag = "-force"
+
+func shouldRunBackendCommand(terragruntOptions *options.TerragruntOptions) bool {
+	return util.FirstArg(terragruntOptions.TerraformCliArgs) == CMD_BACKEND
+}
+
+// backendSubcommand returns the subcommand of the backend command (e.g. bootstrap) and the args that follow it
+func backendSubcommand(terragruntOptions *options.TerragruntOptions) (string, []string) {
+	args := terragruntOptions.TerraformCliArgs
+	if len(args) < 2 {
+		return "", nil
+	}
+	return args[1], args[2:]
+}
+
+// isBackendMigrate returns true if the backend migrate command is being run. Unlike the other backend commands, it
+// runs against the two modules given as its args, so it doesn't need the config of the current module.
+func isBackendMigrate(terragruntOptions *options.TerragruntOptions) bool {
+	subcommand, _ := backendSubcommand(terragruntOptions)
+	return shouldRunBackendCommand(terragruntOptions) && subcommand == CMD_BACKEND_MIGRATE
+}
+
+// runBackendCommand runs the backend bootstrap, delete, check and repair commands against the remote_state of the
+// given config, so that the resources storing the state can be managed separately from running terraform init.
+func runBackendCommand(terragruntOptions *options.TerragruntOptions, terragruntConfig *config.TerragruntConfig) error {
+	subcommand, args := backendSubcommand(terragruntOptions)
+	if !util.ListContainsElement(backendSubcommands, subcommand) || subcommand == CMD_BACKEND_MIGRATE {
+		return errors.WithStackTrace(UnknownBackendSubcommand(subcommand))
+	}
+
+	if terragruntConfig.RemoteState == nil {
+		return errors.WithStackTrace(BackendCommandWithoutRemoteState(terragruntOptions.TerragruntConfigPath))
+	}
+
+	switch subcommand {
+	case CMD_BACKEND_BOOTSTRAP:
+		return runBackendBootstrap(terragruntOptions, terragruntConfig)
+	case CMD_BACKEND_CHECK:
+		return runBackendCheck(terragruntOptions, terragruntConfig)
+	case CMD_BACKEND_REPAIR:
+		return runBackendRepair(terragruntOptions, terragruntConfig)
+	default:
+		return runBackendDelete(terragruntOptions, terragruntConfig, util.ListContainsElement(args, backendForceFlag))
+	}
+}
+
+// runBackendBootstrap creates the resources needed to store the state, e.g. the S3 bucket and DynamoDB lock table,
+// without running terraform init. As bootstrapping is explicitly asked for, there is no prompt before creating them.
+func runBackendBootstrap(terragruntOptions *options.TerragruntOptions, terragruntConfig *config.TerragruntConfig) error {
+	bootstrapOptions := terragruntOptions.Clone(terragruntOptions.TerragruntConfigPath)
+	bootstrapOptions.NonInteractive = true
+
+	terragruntOptions.Logger.Infof("Bootstrapping the %s remote state backend of %s", terragruntConfig.RemoteState.Backend, terragruntOptions.TerragruntConfigPath)
+	return terragruntConfig.RemoteState.Initialize(bootstrapOptions)
+}
+
+// runBackendDelete deletes the state of the module. As the state can't be recreated, the user has to confirm the
+// delete, and in non-interactive mode, the -force flag has to be passed.
+func runBackendDelete(terragruntOptions *options.TerragruntOptions, terragruntConfig *config.TerragruntConfig, force bool) error {
+	if !force {
+		if terragruntOptions.NonInteractive {
+			return errors.WithStackTrace(BackendDeleteNotConfirmed(terragruntOptions.TerragruntConfigPath))
+		}
+
+		prompt := fmt.Sprintf("WARNING: This will delete the %s remote state of %s, and terraform will lose track of all the resources in it. Are you sure you want to delete it?", terragruntConfig.RemoteState.Backend, terragruntOptions.TerragruntConfigPath)
+		shouldDelete, err := shell.PromptUserForYesNo(prompt, terragruntOptions)
+		if err != nil {
+			return err
+		}
+		if !shouldDelete {
+			return errors.WithStackTrace(BackendDeleteNotConfirmed(terragruntOptions.TerragruntConfigPath))
+		}
+	}
+
+	return terragruntConfig.RemoteState.DeleteState(terragruntOptions)
+}
+
+// runBackendCheck reports the settings of the resources storing the state, such as the versioning of the S3 bucket,
+// that differ from the settings Terragrunt creates them with. It fails if there is any drift, so that it can be used to
+// audit all the modules with run-all.
+func runBackendCheck(terragruntOptions *options.TerragruntOptions, terragruntConfig *config.TerragruntConfig) error {
+	drifts, err := terragruntConfig.RemoteState.CheckDrift(terragruntOptions)
+	if err != nil {
+		return err
+	}
+
+	if len(drifts) == 0 {
+		terragruntOptions.Logger.Infof("The %s remote state backend of %s matches the config", terragruntConfig.RemoteState.Backend, terragruntOptions.TerragruntConfigPath)
+		return nil
+	}
+
+	for _, drift := range drifts {
+		terragruntOptions.Logger.Warnf("%s", drift.String())
+	}
+	return errors.WithStackTrace(BackendDriftDetected{ConfigPath: terragruntOptions.TerragruntConfigPath, NumDrifts: len(drifts)})
+}
+
+// runBackendRepair updates the resources storing the state to the settings Terragrunt creates them with, after the user
+// has confirmed the changes. The drift Terragrunt can't repair, such as a missing bucket, is reported instead.
+func runBackendRepair(terragruntOptions *options.TerragruntOptions, terragruntConfig *config.TerragruntConfig) error {
+	drifts, err := terragruntConfig.RemoteState.CheckDrift(terragruntOptions)
+	if err != nil {
+		return err
+	}
+
+	var repairableDrifts []remote.RemoteStateDrift
+	var unrepairableDrifts []remote.RemoteStateDrift
+	for _, drift := range drifts {
+		if drift.CanRepair() {
+			repairableDrifts = append(repairableDrifts, drift)
+		} else {
+			unrepairableDrifts = append(unrepairableDrifts, drift)
+		}
+	}
+
+	if len(repairableDrifts) > 0 {
+		var descriptions []string
+		for _, drift := range repairableDrifts {
+			descriptions = append(descriptions, "  "+drift.String())
+		}
+		prompt := fmt.Sprintf("The %s remote state backend of %s has drifted from the config:\n%s\nWould you like Terragrunt to repair it?", terragruntConfig.RemoteState.Backend, terragruntOptions.TerragruntConfigPath, strings.Join(descriptions, "\n"))
+		shouldRepair, err := shell.PromptUserForYesNo(prompt, terragruntOptions)
+		if err != nil {
+			return err
+		}
+		if !shouldRepair {
+			return errors.WithStackTrace(BackendDriftDetected{ConfigPath: terragruntOptions.TerragruntConfigPath, NumDrifts: len(drifts)})
+		}
+
+		for _, drift := range repairableDrifts {
+			terragruntOptions.Logger.Infof("Repairing %s", drift.String())
+			if err := drift.Repair(); err != nil {
+				return err
+			}
+		}
+	}
+
+	if len(unrepairableDrifts) > 0 {
+		for _, drift := range unrepairableDrifts {
+			terragruntOptions.Logger.Warnf("%s. Run terragrunt backend bootstrap to create it.", drift.String())
+		}
+		return errors.WithStackTrace(BackendDriftDetected{ConfigPath: terragruntOptions.TerragruntConfigPath, NumDrifts: len(unrepairableDrifts)})
+	}
+
+	if len(repairableDrifts) == 0 {
+		terragruntOptions.Logger.Infof("The %s remote state backend of %s matches the config", terragruntConfig.RemoteState.Backend, terragruntOptions.TerragruntConfigPath)
+	}
+	return nil
+}
+
+// runBackendMigrate copies the state of the module given as the first arg to the module given as the second arg, by
+// running terraform state pull in the first one and terraform state push in the second one. Going through terraform
+// makes this work with any backend, and respects the state locks. The state of the source module is left in place, so
+// that it can be removed with backend delete once the migration has been checked.
+func runBackendMigrate(terragruntOptions *options.TerragruntOptions) error {
+	_, args := backendSubcommand(terragruntOptions)
+
+	force := false
+	var modulePaths []string
+	for _, arg := range args {
+		if arg == backendForceFlag {
+			force = true
+		} else {
+			modulePaths = append(modulePaths, arg)
+		}
+	}
+	if len(modulePaths) != 2 {
+		return errors.WithStackTrace(BackendMigrateWrongNumberOfArgs(len(modulePaths)))
+	}
+
+	srcOptions, err := backendModuleOptions(terragruntOptions, modulePaths[0])
+	if err != nil {
+		return err
+	}
+	dstOptions, err := backendModuleOptions(terragruntOptions, modulePaths[1])
+	if err != nil {
+		return err
+	}
+
+	terragruntOptions.Logger.Infof("Pulling the state of %s", srcOptions.TerragruntConfigPath)
+	var state bytes.Buffer
+	srcOptions.TerraformCommand = "state"
+	srcOptions.TerraformCliArgs = []string{"state", "pull"}
+	srcOptions.Writer = &state
+	if err := RunTerragrunt(srcOptions); err != nil {
+		return err
+	}
+
+	// Pushing an empty state would wipe out the destination, so stop if there is nothing to migrate
+	if len(bytes.TrimSpace(state.Bytes())) == 0 {
+		return errors.WithStackTrace(BackendMigrateEmptyState(srcOptions.TerragruntConfigPath))
+	}
+
+	stateFile, err := ioutil.TempFile("", "terragrunt-backend-migrate-*.tfstate")
+	if err != nil {
+		return errors.WithStackTrace(err)
+	}
+	defer os.Remove(stateFile.Name())
+
+	if _, err := stateFile.Write(state.Bytes()); err != nil {
+		stateFile.Close()
+		return errors.WithStackTrace(err)
+	}
+	if err := stateFile.Close(); err != nil {
+		return errors.WithStackTrace(err)
+	}
+
+	terragruntOptions.Logger.Infof("Pushing the state of %s to %s", srcOptions.TerragruntConfigPath, dstOptions.TerragruntConfigPath)
+	dstOptions.TerraformCommand = "state"
+	dstOptions.TerraformCliArgs = []string{"state", "push"}
+	if force {
+		dstOptions.TerraformCliArgs = append(dstOptions.TerraformCliArgs, "-force")
+	}
+	dstOptions.TerraformCliArgs = append(dstOptions.TerraformCliArgs, stateFile.Name())
+	if err := RunTerragrunt(dstOptions); err != nil {
+		return err
+	}
+
+	terragruntOptions.Logger.Infof("Migrated the state of %s to %s. Once you have checked it, run 'terragrunt backend delete' in %s to delete the old state.", srcOptions.TerragruntConfigPath, dstOptions.TerragruntConfigPath, filepath.Dir(srcOptions.TerragruntConfigPath))
+	return nil
+}
+
+// backendModuleOptions returns the options to run terragrunt in the module at the given path, which is relative to
+// the working dir.
+func backendModuleOptions(terragruntOptions *options.TerragruntOptions, modulePath string) (*options.TerragruntOptions, error) {
+	canonicalModulePath, err := util.CanonicalPath(modulePath, terragruntOptions.WorkingDir)
+	if err != nil {
+		return nil, err
+	}
+
+	terragruntConfigPath := config.GetDefaultConfigPath(canonicalModulePath)
+	if !util.FileExists(terragruntConfigPath) {
+		return nil, errors.WithStackTrace(BackendMigrateModuleNotFound(modulePath))
+	}
+
+	moduleOptions := terragruntOptions.Clone(terragruntConfigPath)
+	moduleOptions.OriginalTerragruntConfigPath = terragruntConfigPath
+	moduleOptions.Source = ""
+
+	_, defaultDownloadDir, err := options.DefaultWorkingAndDownloadDirs(terragruntOptions.TerragruntConfigPath)
+	if err != nil {
+		return nil, err
+	}
+
+	// Like run-all, use the download dir of the module, unless a custom one was given
+	if terragruntOptions.DownloadDir == defaultDownloadDir {
+		_, downloadDir, err := options.DefaultWorkingAndDownloadDirs(terragruntConfigPath)
+		if err != nil {
+			return nil, err
+		}
+		moduleOptions.DownloadDir = downloadDir
+	}
+
+	return moduleOptions, nil
+}
+
+// Custom error types
+
+type UnknownBackendSubcommand string
+
+func (subcommand UnknownBackendSubcommand) Error() string {
+	if subcommand == "" {
+		return fmt.Sprintf("Missing subcommand for terragrunt backend. Expected one of %s.", strings.Join(backendSubcommands, ", "))
+	}
+	return fmt.Sprintf("Unknown subcommand %s for terragrunt backend. Expected one of %s.", string(subcommand), strings.Join(backendSubcommands, ", "))
+}
+
+type BackendCommandWithoutRemoteState string
+
+func (configPath BackendCommandWithoutRemoteState) Error() string {
+	return fmt.Sprintf("There is no remote_state block in %s, so there is no backend to manage", string(configPath))
+}
+
+type BackendDeleteNotConfirmed string
+
+func (configPath BackendDeleteNotConfirmed) Error() string {
+	return fmt.Sprintf("Not deleting the remote state of %s. Pass %s to terragrunt backend delete to delete it without confirmation.", string(configPath), backendForceFlag)
+}
+
+type BackendMigrateWrongNumberOfArgs int
+
+func (numArgs BackendMigrateWrongNumberOfArgs) Error() string {
+	return fmt.Sprintf("terragrunt backend migrate expects the paths of the source and destination modules, but got %d args", int(numArgs))
+}
+
+type BackendMigrateModuleNotFound string
+
+func (modulePath BackendMigrateModuleNotFound) Error() string {
+	return fmt.Sprintf("Could not find a terragrunt config in %s", string(modulePath))
+}
+
+type BackendMigrateEmptyState string
+
+func (configPath BackendMigrateEmptyState) Error() string {
+	return fmt.Sprintf("The state of %s is empty, so there is nothing to migrate", string(configPath))
+}
+
+type BackendDriftDetected struct {
+	ConfigPath string
+	NumDrifts  int
+}
+
+func (err BackendDriftDetected) Error() string {
+	return fmt.Sprintf("The remote state backend of %s has drifted from the config in %d setting(s)", err.ConfigPath, err.NumDrifts)
+}
@@ -0,0 +1,118 @@
+package cli
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/gruntwork-io/terragrunt/config"
+	"github.com/gruntwork-io/terragrunt/errors"
+	"github.com/gruntwork-io/terragrunt/options"
+	"github.com/gruntwork-io/terragrunt/remote"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBackendSubcommand(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		args               []string
+		expectedSubcommand string
+		expectedArgs       []string
+		expectedIsBackend  bool
+		expectedIsMigrate  bool
+	}{
+		{[]string{"backend", "bootstrap"}, "bootstrap", []string{}, true, false},
+		{[]string{"backend", "delete", "-force"}, "delete", []string{"-force"}, true, false},
+		{[]string{"backend", "migrate", "../old", "../new"}, "migrate", []string{"../old", "../new"}, true, true},
+		{[]string{"backend"}, "", nil, true, false},
+		{[]string{"state", "rm", "module.backend"}, "rm", []string{"module.backend"}, false, false},
+		{[]string{"plan", "-backend=false"}, "-backend=false", []string{}, false, false},
+	}
+
+	for _, testCase := range testCases {
+		terragruntOptions, err := options.NewTerragruntOptionsForTest("backend_test")
+		require.NoError(t, err)
+		terragruntOptions.TerraformCliArgs = testCase.args
+
+		subcommand, args := backendSubcommand(terragruntOptions)
+		assert.Equal(t, testCase.expectedSubcommand, subcommand, "For args %v", testCase.args)
+		assert.Equal(t, testCase.expectedArgs, args, "For args %v", testCase.args)
+		assert.Equal(t, testCase.expectedIsBackend, shouldRunBackendCommand(terragruntOptions), "For args %v", testCase.args)
+		assert.Equal(t, testCase.expectedIsMigrate, isBackendMigrate(terragruntOptions), "For args %v", testCase.args)
+	}
+}
+
+func TestRunBackendCommandErrors(t *testing.T) {
+	t.Parallel()
+
+	remoteState := &remote.RemoteState{Backend: "s3", Config: map[string]interface{}{"bucket": "my-bucket", "key": "terraform.tfstate"}}
+
+	testCases := []struct {
+		name          string
+		args          []string
+		remoteState   *remote.RemoteState
+		expectedError error
+	}{
+		{"missing-subcommand", []string{"backend"}, remoteState, UnknownBackendSubcommand("")},
+		{"unknown-subcommand", []string{"backend", "destroy"}, remoteState, UnknownBackendSubcommand("destroy")},
+		{"no-remote-state", []string{"backend", "bootstrap"}, nil, BackendCommandWithoutRemoteState("backend_test")},
+		{"delete-non-interactive", []string{"backend", "delete"}, remoteState, BackendDeleteNotConfirmed("backend_test")},
+		{"check-unsupported-backend", []string{"backend", "check"}, &remote.RemoteState{Backend: "http"}, remote.DriftCheckNotSupported("http")},
+		{"repair-unsupported-backend", []string{"backend", "repair"}, &remote.RemoteState{Backend: "http"}, remote.DriftCheckNotSupported("http")},
+	}
+
+	for _, testCase := range testCases {
+		// Save the testCase in local scope so all the t.Run calls don't end up with the last item in the list
+		testCase := testCase
+
+		t.Run(testCase.name, func(t *testing.T) {
+			t.Parallel()
+
+			terragruntOptions, err := options.NewTerragruntOptionsForTest("backend_test")
+			require.NoError(t, err)
+			terragruntOptions.TerraformCliArgs = testCase.args
+			terragruntOptions.NonInteractive = true
+
+			err = runBackendCommand(terragruntOptions, &config.TerragruntConfig{RemoteState: testCase.remoteState})
+			require.Error(t, err)
+			assert.Equal(t, testCase.expectedError, errors.Unwrap(err))
+		})
+	}
+}
+
+func TestRunBackendMigrateErrors(t *testing.T) {
+	t.Parallel()
+
+	rootPath, err := ioutil.TempDir("", "backend-migrate")
+	require.NoError(t, err)
+	defer os.RemoveAll(rootPath)
+
+	require.NoError(t, os.MkdirAll(filepath.Join(rootPath, "old"), os.ModePerm))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(rootPath, "old", config.DefaultTerragruntConfigPath), []byte(""), 0644))
+
+	testCases := []struct {
+		name          string
+		args          []string
+		expectedError error
+	}{
+		{"no-args", []string{"backend", "migrate"}, BackendMigrateWrongNumberOfArgs(0)},
+		{"one-arg", []string{"backend", "migrate", "old", "-force"}, BackendMigrateWrongNumberOfArgs(1)},
+		{"missing-destination", []string{"backend", "migrate", "old", "new"}, BackendMigrateModuleNotFound("new")},
+	}
+
+	// The subtests aren't run in parallel, as the modules are deleted when this test returns
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			terragruntOptions, err := options.NewTerragruntOptionsForTest(filepath.Join(rootPath, config.DefaultTerragruntConfigPath))
+			require.NoError(t, err)
+			terragruntOptions.TerraformCliArgs = testCase.args
+
+			err = runBackendMigrate(terragruntOptions)
+			require.Error(t, err)
+			assert.Equal(t, testCase.expectedError, errors.Unwrap(err))
+		})
+	}
+}
@@ -0,0 +1,59 @@
+package cli
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+
+	"github.com/gruntwork-io/terragrunt/configstack"
+	"github.com/gruntwork-io/terragrunt/errors"
+	"github.com/gruntwork-io/terragrunt/options"
+	"github.com/gruntwork-io/terragrunt/util"
+)
+
+// The file Backstage reads the entities of a folder from
+const BACKSTAGE_CATALOG_FILE = "catalog-info.yaml"
+
+// The header of the generated catalog-info.yaml files, so that nobody edits them by hand
+const backstageCatalogHeader = "# Generated by terragrunt generate-catalog-info. Do not edit.\n"
+
+func shouldGenerateCatalogInfo(terragruntOptions *options.TerragruntOptions) bool {
+	return util.FirstArg(terragruntOptions.TerraformCliArgs) == CMD_GENERATE_CATALOG_INFO
+}
+
+// Write the Backstage entity of each module in the working dir or its subfolders to the catalog-info.yaml in the
+// folder of the module, so that the modules show up in the service catalog of Backstage. See
+// configstack.Stack.BackstageCatalog for how the entities are described.
+func generateCatalogInfo(terragruntOptions *options.TerragruntOptions) error {
+	// The external dependencies can't be in the catalog of the repo, so there's no need to ask whether to run them
+	terragruntOptions.IgnoreExternalDependencies = true
+
+	stack, err := configstack.FindStackInSubfolders(terragruntOptions)
+	if err != nil {
+		return err
+	}
+
+	catalogFiles, modulesWithoutOwner, err := stack.BackstageCatalog(terragruntOptions.WorkingDir)
+	if err != nil {
+		return err
+	}
+	if len(modulesWithoutOwner) > 0 {
+		terragruntOptions.Logger.Warnf("The owner of these modules is unknown, as their catalog block doesn't set it:\n  - %s", strings.Join(modulesWithoutOwner, "\n  - "))
+	}
+
+	for _, catalogFile := range catalogFiles {
+		contents, err := yaml.Marshal(catalogFile.Entity)
+		if err != nil {
+			return errors.WithStackTrace(err)
+		}
+		catalogPath := filepath.Join(catalogFile.ModulePath, BACKSTAGE_CATALOG_FILE)
+		if err := ioutil.WriteFile(catalogPath, append([]byte(backstageCatalogHeader), contents...), 0644); err != nil {
+			return errors.WithStackTrace(err)
+		}
+	}
+
+	terragruntOptions.Logger.Infof("Wrote the Backstage entities of %d modules to their %s", len(catalogFiles), BACKSTAGE_CATALOG_FILE)
+	return nil
+}
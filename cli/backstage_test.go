@@ -0,0 +1,71 @@
+package cli
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v2"
+
+	"github.com/gruntwork-io/terragrunt/config"
+	"github.com/gruntwork-io/terragrunt/configstack"
+	"github.com/gruntwork-io/terragrunt/options"
+	"github.com/gruntwork-io/terragrunt/util"
+)
+
+func TestGenerateCatalogInfo(t *testing.T) {
+	t.Parallel()
+
+	rootPath, err := ioutil.TempDir("", "generate-catalog-info")
+	require.NoError(t, err)
+	defer os.RemoveAll(rootPath)
+
+	files := map[string]string{
+		"live/vpc/main.tf":        "",
+		"live/vpc/terragrunt.hcl": "catalog {\n  owner  = \"team-network\"\n  system = \"platform\"\n}\n",
+		"live/app/main.tf":        "",
+		"live/app/terragrunt.hcl": "catalog {\n  kind = \"Component\"\n  type = \"service\"\n  tags = [\"web\"]\n}\n\ndependency \"vpc\" {\n  config_path = \"../vpc\"\n}\n",
+	}
+	for path, contents := range files {
+		filePath := filepath.Join(rootPath, path)
+		require.NoError(t, os.MkdirAll(filepath.Dir(filePath), os.ModePerm))
+		require.NoError(t, ioutil.WriteFile(filePath, []byte(contents), 0644))
+	}
+
+	terragruntOptions, err := options.NewTerragruntOptionsForTest(util.JoinPath(rootPath, config.DefaultTerragruntConfigPath))
+	require.NoError(t, err)
+	terragruntOptions.TerraformCliArgs = []string{CMD_GENERATE_CATALOG_INFO}
+	require.True(t, shouldGenerateCatalogInfo(terragruntOptions))
+	require.NoError(t, generateCatalogInfo(terragruntOptions))
+
+	readEntity := func(dir string) configstack.BackstageEntity {
+		contents, err := ioutil.ReadFile(filepath.Join(rootPath, dir, BACKSTAGE_CATALOG_FILE))
+		require.NoError(t, err)
+		assert.True(t, strings.HasPrefix(string(contents), backstageCatalogHeader))
+		var entity configstack.BackstageEntity
+		require.NoError(t, yaml.Unmarshal(contents, &entity))
+		return entity
+	}
+
+	assert.Equal(t, configstack.BackstageEntity{
+		ApiVersion: "backstage.io/v1alpha1",
+		Kind:       "Resource",
+		Metadata:   configstack.BackstageMetadata{Name: "live-vpc", Title: "live/vpc"},
+		Spec:       configstack.BackstageSpec{Type: "terraform", Owner: "team-network", System: "platform"},
+	}, readEntity("live/vpc"))
+	assert.Equal(t, configstack.BackstageEntity{
+		ApiVersion: "backstage.io/v1alpha1",
+		Kind:       "Component",
+		Metadata:   configstack.BackstageMetadata{Name: "live-app", Title: "live/app", Tags: []string{"web"}},
+		Spec: configstack.BackstageSpec{
+			Type:      "service",
+			Lifecycle: "unknown",
+			Owner:     "unknown",
+			DependsOn: []string{"resource:live-vpc"},
+		},
+	}, readEntity("live/app"))
+}
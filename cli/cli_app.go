@@ -4,6 +4,8 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"io/ioutil"
+	"path/filepath"
 	"regexp"
 	"strings"
 	"time"
@@ -29,14 +31,32 @@ const OPT_TERRAGRUNT_CONFIG = "terragrunt-config"
 const OPT_TERRAGRUNT_TFPATH = "terragrunt-tfpath"
 const OPT_TERRAGRUNT_NO_AUTO_INIT = "terragrunt-no-auto-init"
 const OPT_TERRAGRUNT_NO_AUTO_RETRY = "terragrunt-no-auto-retry"
+const OPT_TERRAGRUNT_NO_COPY_LOCK_FILE = "terragrunt-no-copy-lock-file"
 const OPT_NON_INTERACTIVE = "terragrunt-non-interactive"
 const OPT_WORKING_DIR = "terragrunt-working-dir"
 const OPT_DOWNLOAD_DIR = "terragrunt-download-dir"
 const OPT_TERRAGRUNT_SOURCE = "terragrunt-source"
 const OPT_TERRAGRUNT_SOURCE_MAP = "terragrunt-source-map"
+const OPT_TERRAGRUNT_SOURCE_MIRROR = "terragrunt-source-mirror"
+const OPT_TERRAGRUNT_SOURCE_MIRROR_HEADER = "terragrunt-source-mirror-header"
+const OPT_TERRAGRUNT_SOURCE_SIGV4_SERVICE = "terragrunt-source-sigv4-service"
 const OPT_TERRAGRUNT_SOURCE_UPDATE = "terragrunt-source-update"
 const OPT_TERRAGRUNT_IAM_ROLE = "terragrunt-iam-role"
 const OPT_TERRAGRUNT_IAM_ASSUME_ROLE_DURATION = "terragrunt-iam-assume-role-duration"
+const OPT_TERRAGRUNT_IAM_ASSUME_ROLE_EXTERNAL_ID = "terragrunt-iam-assume-role-external-id"
+const OPT_TERRAGRUNT_IAM_ASSUME_ROLE_MFA_SERIAL = "terragrunt-iam-assume-role-mfa-serial"
+const OPT_TERRAGRUNT_IAM_ASSUME_ROLE_MFA_TOKEN = "terragrunt-iam-assume-role-mfa-token"
+const OPT_TERRAGRUNT_IAM_WEB_IDENTITY_TOKEN = "terragrunt-iam-web-identity-token"
+const OPT_TERRAGRUNT_GOOGLE_IMPERSONATE_SERVICE_ACCOUNT = "terragrunt-google-impersonate-service-account"
+const OPT_TERRAGRUNT_AWS_SSO_LOGIN = "terragrunt-aws-sso-login"
+const OPT_TERRAGRUNT_AWS_KEYRING_CACHE = "terragrunt-aws-keyring-cache"
+const OPT_TERRAGRUNT_AWS_ENDPOINT = "terragrunt-aws-endpoint"
+const OPT_TERRAGRUNT_AWS_STS_REGIONAL_ENDPOINTS = "terragrunt-aws-sts-regional-endpoints"
+const OPT_TERRAGRUNT_PREFLIGHT_CHECKS = "terragrunt-preflight-checks"
+const OPT_TERRAGRUNT_SYMLINK_LOCAL_SOURCE = "terragrunt-symlink-local-source"
+const OPT_TERRAGRUNT_SOURCE_CACHE = "terragrunt-source-cache"
+const OPT_TERRAGRUNT_SOURCE_CACHE_DIR = "terragrunt-source-cache-dir"
+const OPT_TERRAGRUNT_SOURCE_CACHE_MAX_AGE = "terragrunt-source-cache-max-age"
 const OPT_TERRAGRUNT_IGNORE_DEPENDENCY_ERRORS = "terragrunt-ignore-dependency-errors"
 const OPT_TERRAGRUNT_IGNORE_DEPENDENCY_ORDER = "terragrunt-ignore-dependency-order"
 const OPT_TERRAGRUNT_IGNORE_EXTERNAL_DEPENDENCIES = "terragrunt-ignore-external-dependencies"
@@ -44,25 +64,90 @@ const OPT_TERRAGRUNT_INCLUDE_EXTERNAL_DEPENDENCIES = "terragrunt-include-externa
 const OPT_TERRAGRUNT_EXCLUDE_DIR = "terragrunt-exclude-dir"
 const OPT_TERRAGRUNT_INCLUDE_DIR = "terragrunt-include-dir"
 const OPT_TERRAGRUNT_STRICT_INCLUDE = "terragrunt-strict-include"
+const OPT_TERRAGRUNT_QUEUE_INCLUDE_UNITS_READING = "terragrunt-queue-include-units-reading"
+const OPT_TERRAGRUNT_CHANGED_SINCE = "terragrunt-changed-since"
+const OPT_TERRAGRUNT_INCLUDE_CHANGED_DEPENDENTS = "terragrunt-include-changed-dependents"
 const OPT_TERRAGRUNT_PARALLELISM = "terragrunt-parallelism"
+const OPT_TERRAGRUNT_DEPENDENCY_FETCH_PARALLELISM = "terragrunt-dependency-fetch-parallelism"
+const OPT_TERRAGRUNT_DEPENDENCY_OUTPUT_CACHE_TTL = "terragrunt-dependency-output-cache-ttl"
+const OPT_TERRAGRUNT_FETCH_DEPENDENCY_OUTPUT_FROM_STATE = "terragrunt-fetch-dependency-output-from-state"
+const OPT_TERRAGRUNT_INPUT_MODE = "terragrunt-input-mode"
+const OPT_TERRAGRUNT_NO_DESTROY_DEPENDENCIES_CHECK = "terragrunt-no-destroy-dependencies-check"
+const OPT_TERRAGRUNT_DESTROY_DEPENDENTS_SEARCH_DIR = "terragrunt-destroy-dependents-search-dir"
+const OPT_TERRAGRUNT_NO_OUTPUT_PREFIX = "terragrunt-no-output-prefix"
+const OPT_TERRAGRUNT_PROVIDERS_LOCK_MIRROR_DIR = "terragrunt-providers-lock-mirror-dir"
+const OPT_TERRAGRUNT_PROVIDER_CACHE = "terragrunt-provider-cache"
+const OPT_TERRAGRUNT_NO_CONFIG_CACHE = "terragrunt-no-config-cache"
+const OPT_TERRAGRUNT_PROVIDER_CACHE_DIR = "terragrunt-provider-cache-dir"
+const OPT_TERRAGRUNT_PREFETCH_ONLY = "terragrunt-prefetch-only"
+const OPT_TERRAGRUNT_DAEMON = "terragrunt-daemon"
+const OPT_TERRAGRUNT_DAEMON_SOCKET = "terragrunt-daemon-socket"
 const OPT_TERRAGRUNT_CHECK = "terragrunt-check"
 const OPT_TERRAGRUNT_HCLFMT_FILE = "terragrunt-hclfmt-file"
 const OPT_TERRAGRUNT_DEBUG = "terragrunt-debug"
 const OPT_TERRAGRUNT_OVERRIDE_ATTR = "terragrunt-override-attr"
 const OPT_TERRAGRUNT_LOGLEVEL = "terragrunt-log-level"
+const OPT_TERRAGRUNT_CPU_PROFILE = "terragrunt-cpu-profile"
+const OPT_TERRAGRUNT_MEM_PROFILE = "terragrunt-mem-profile"
+const OPT_TERRAGRUNT_TRACE = "terragrunt-trace"
+const OPT_TERRAGRUNT_TELEMETRY_ENDPOINT = "terragrunt-telemetry-endpoint"
+const OPT_TERRAGRUNT_METRICS_ENDPOINT = "terragrunt-metrics-endpoint"
+const OPT_TERRAGRUNT_EVENTS_ENDPOINT = "terragrunt-events-endpoint"
+const OPT_TERRAGRUNT_GITHUB_ACTIONS = "terragrunt-github-actions"
+const OPT_TERRAGRUNT_GITLAB_REPORT_DIR = "terragrunt-gitlab-report-dir"
+const OPT_TERRAGRUNT_ATLANTIS_WORKFLOW = "terragrunt-atlantis-workflow"
+const OPT_TERRAGRUNT_REPORT_JUNIT = "terragrunt-report-junit"
+const OPT_TERRAGRUNT_SARIF_OUTPUT = "terragrunt-sarif-output"
+const OPT_TERRAGRUNT_POLICY = "terragrunt-policy"
+const OPT_TERRAGRUNT_INFRACOST = "terragrunt-infracost"
+const OPT_TERRAGRUNT_INFRACOST_REPORT = "terragrunt-infracost-report"
+const OPT_TERRAGRUNT_DRIFT_REPORT = "terragrunt-drift-report"
+const OPT_TERRAGRUNT_PLAN_ARTIFACT_URL = "terragrunt-plan-artifact-url"
+const OPT_TERRAGRUNT_PLAN_ARTIFACT_RUN_ID = "terragrunt-plan-artifact-run-id"
+const OPT_TERRAGRUNT_PLAN_MARKDOWN = "terragrunt-plan-markdown"
+const OPT_TERRAGRUNT_PLAN_RENDERER = "terragrunt-plan-renderer"
+const OPT_TERRAGRUNT_DOCS_INVENTORY = "terragrunt-docs-inventory"
+const OPT_TERRAGRUNT_TFC_RUN = "terragrunt-tfc-run"
+const OPT_TERRAGRUNT_SHALLOW_CLONE = "terragrunt-shallow-clone"
+const OPT_TERRAGRUNT_SPARSE_CHECKOUT = "terragrunt-sparse-checkout"
+const OPT_TERRAGRUNT_GIT_CREDENTIAL_HELPER = "terragrunt-git-credential-helper"
+const OPT_TERRAGRUNT_GIT_NETRC_TEMPLATE = "terragrunt-git-netrc-template"
+const OPT_TERRAGRUNT_GITHUB_APP_ID = "terragrunt-github-app-id"
+const OPT_TERRAGRUNT_GITHUB_APP_INSTALLATION_ID = "terragrunt-github-app-installation-id"
+const OPT_TERRAGRUNT_GITHUB_APP_PRIVATE_KEY = "terragrunt-github-app-private-key"
 
 var ALL_TERRAGRUNT_BOOLEAN_OPTS = []string{
 	OPT_NON_INTERACTIVE,
 	OPT_TERRAGRUNT_SOURCE_UPDATE,
+	OPT_TERRAGRUNT_SYMLINK_LOCAL_SOURCE,
+	OPT_TERRAGRUNT_SOURCE_CACHE,
 	OPT_TERRAGRUNT_IGNORE_DEPENDENCY_ERRORS,
 	OPT_TERRAGRUNT_IGNORE_DEPENDENCY_ORDER,
 	OPT_TERRAGRUNT_IGNORE_EXTERNAL_DEPENDENCIES,
 	OPT_TERRAGRUNT_INCLUDE_EXTERNAL_DEPENDENCIES,
 	OPT_TERRAGRUNT_NO_AUTO_INIT,
 	OPT_TERRAGRUNT_NO_AUTO_RETRY,
+	OPT_TERRAGRUNT_NO_COPY_LOCK_FILE,
 	OPT_TERRAGRUNT_CHECK,
 	OPT_TERRAGRUNT_STRICT_INCLUDE,
 	OPT_TERRAGRUNT_DEBUG,
+	OPT_TERRAGRUNT_NO_DESTROY_DEPENDENCIES_CHECK,
+	OPT_TERRAGRUNT_NO_OUTPUT_PREFIX,
+	OPT_TERRAGRUNT_PROVIDER_CACHE,
+	OPT_TERRAGRUNT_PREFETCH_ONLY,
+	OPT_TERRAGRUNT_DAEMON,
+	OPT_TERRAGRUNT_NO_CONFIG_CACHE,
+	OPT_TERRAGRUNT_INCLUDE_CHANGED_DEPENDENTS,
+	OPT_TERRAGRUNT_FETCH_DEPENDENCY_OUTPUT_FROM_STATE,
+	OPT_TERRAGRUNT_AWS_SSO_LOGIN,
+	OPT_TERRAGRUNT_AWS_KEYRING_CACHE,
+	OPT_TERRAGRUNT_AWS_STS_REGIONAL_ENDPOINTS,
+	OPT_TERRAGRUNT_PREFLIGHT_CHECKS,
+	OPT_TERRAGRUNT_GITHUB_ACTIONS,
+	OPT_TERRAGRUNT_INFRACOST,
+	OPT_TERRAGRUNT_TFC_RUN,
+	OPT_TERRAGRUNT_SHALLOW_CLONE,
+	OPT_TERRAGRUNT_SPARSE_CHECKOUT,
 }
 var ALL_TERRAGRUNT_STRING_OPTS = []string{
 	OPT_TERRAGRUNT_CONFIG,
@@ -71,14 +156,57 @@ var ALL_TERRAGRUNT_STRING_OPTS = []string{
 	OPT_DOWNLOAD_DIR,
 	OPT_TERRAGRUNT_SOURCE,
 	OPT_TERRAGRUNT_SOURCE_MAP,
+	OPT_TERRAGRUNT_SOURCE_MIRROR,
+	OPT_TERRAGRUNT_SOURCE_MIRROR_HEADER,
+	OPT_TERRAGRUNT_SOURCE_SIGV4_SERVICE,
+	OPT_TERRAGRUNT_AWS_ENDPOINT,
+	OPT_TERRAGRUNT_SOURCE_CACHE_DIR,
+	OPT_TERRAGRUNT_SOURCE_CACHE_MAX_AGE,
 	OPT_TERRAGRUNT_IAM_ROLE,
 	OPT_TERRAGRUNT_IAM_ASSUME_ROLE_DURATION,
+	OPT_TERRAGRUNT_IAM_ASSUME_ROLE_EXTERNAL_ID,
+	OPT_TERRAGRUNT_IAM_ASSUME_ROLE_MFA_SERIAL,
+	OPT_TERRAGRUNT_IAM_ASSUME_ROLE_MFA_TOKEN,
+	OPT_TERRAGRUNT_IAM_WEB_IDENTITY_TOKEN,
+	OPT_TERRAGRUNT_GOOGLE_IMPERSONATE_SERVICE_ACCOUNT,
 	OPT_TERRAGRUNT_EXCLUDE_DIR,
 	OPT_TERRAGRUNT_INCLUDE_DIR,
+	OPT_TERRAGRUNT_QUEUE_INCLUDE_UNITS_READING,
+	OPT_TERRAGRUNT_CHANGED_SINCE,
 	OPT_TERRAGRUNT_PARALLELISM,
+	OPT_TERRAGRUNT_DEPENDENCY_FETCH_PARALLELISM,
+	OPT_TERRAGRUNT_DEPENDENCY_OUTPUT_CACHE_TTL,
+	OPT_TERRAGRUNT_INPUT_MODE,
+	OPT_TERRAGRUNT_PROVIDERS_LOCK_MIRROR_DIR,
+	OPT_TERRAGRUNT_DESTROY_DEPENDENTS_SEARCH_DIR,
+	OPT_TERRAGRUNT_PROVIDER_CACHE_DIR,
+	OPT_TERRAGRUNT_DAEMON_SOCKET,
 	OPT_TERRAGRUNT_HCLFMT_FILE,
 	OPT_TERRAGRUNT_OVERRIDE_ATTR,
 	OPT_TERRAGRUNT_LOGLEVEL,
+	OPT_TERRAGRUNT_CPU_PROFILE,
+	OPT_TERRAGRUNT_MEM_PROFILE,
+	OPT_TERRAGRUNT_TRACE,
+	OPT_TERRAGRUNT_TELEMETRY_ENDPOINT,
+	OPT_TERRAGRUNT_METRICS_ENDPOINT,
+	OPT_TERRAGRUNT_EVENTS_ENDPOINT,
+	OPT_TERRAGRUNT_GITLAB_REPORT_DIR,
+	OPT_TERRAGRUNT_ATLANTIS_WORKFLOW,
+	OPT_TERRAGRUNT_REPORT_JUNIT,
+	OPT_TERRAGRUNT_SARIF_OUTPUT,
+	OPT_TERRAGRUNT_POLICY,
+	OPT_TERRAGRUNT_INFRACOST_REPORT,
+	OPT_TERRAGRUNT_DRIFT_REPORT,
+	OPT_TERRAGRUNT_PLAN_ARTIFACT_URL,
+	OPT_TERRAGRUNT_PLAN_ARTIFACT_RUN_ID,
+	OPT_TERRAGRUNT_PLAN_MARKDOWN,
+	OPT_TERRAGRUNT_PLAN_RENDERER,
+	OPT_TERRAGRUNT_DOCS_INVENTORY,
+	OPT_TERRAGRUNT_GIT_CREDENTIAL_HELPER,
+	OPT_TERRAGRUNT_GIT_NETRC_TEMPLATE,
+	OPT_TERRAGRUNT_GITHUB_APP_ID,
+	OPT_TERRAGRUNT_GITHUB_APP_INSTALLATION_ID,
+	OPT_TERRAGRUNT_GITHUB_APP_PRIVATE_KEY,
 }
 
 const CMD_INIT = "init"
@@ -91,6 +219,14 @@ const CMD_TERRAGRUNT_GRAPH_DEPENDENCIES = "graph-dependencies"
 const CMD_TERRAGRUNT_READ_CONFIG = "terragrunt-read-config"
 const CMD_HCLFMT = "hclfmt"
 const CMD_AWS_PROVIDER_PATCH = "aws-provider-patch"
+const CMD_BACKEND = "backend"
+const CMD_DAEMON = "daemon"
+const CMD_GENERATE_ATLANTIS_CONFIG = "generate-atlantis-config"
+const CMD_TFLINT = "tflint"
+const CMD_GENERATE_DOCS = "generate-docs"
+const CMD_EXPORT_STACKS = "export-stacks"
+const CMD_GENERATE_CATALOG_INFO = "generate-catalog-info"
+const CMD_EXPORT_INVENTORY = "export-inventory"
 
 // START: Constants useful for multimodule command handling
 const CMD_RUN_ALL = "run-all"
@@ -170,10 +306,26 @@ var TERRAFORM_COMMANDS_THAT_USE_STATE = []string{
 	"state",
 }
 
+// The terraform commands after which the cached outputs of the module are outdated
+var TERRAFORM_COMMANDS_THAT_CHANGE_OUTPUTS = []string{
+	"apply",
+	"destroy",
+	"import",
+	"push",
+	"refresh",
+	"state",
+	"taint",
+	"untaint",
+}
+
 var TERRAFORM_COMMANDS_THAT_DO_NOT_NEED_INIT = []string{
 	"version",
 	"terragrunt-info",
 	"graph-dependencies",
+	"generate-atlantis-config",
+	"export-stacks",
+	"generate-catalog-info",
+	"export-inventory",
 }
 
 // DEPRECATED_ARGUMENTS is a map of deprecated arguments to the argument that replace them.
@@ -207,8 +359,17 @@ COMMANDS:
    terragrunt-info       Emits limited terragrunt state on stdout and exits
    validate-inputs       Checks if the terragrunt configured inputs align with the terraform defined variables.
    graph-dependencies    Prints the terragrunt dependency graph to stdout
+   generate-atlantis-config Write atlantis.yaml, with an Atlantis project for each module in the current directory or its subfolders.
+   export-stacks <PLATFORM> Write the Spacelift stacks or env0 templates of the modules in the current directory or its subfolders, with their dependencies.
+   generate-catalog-info Write the catalog-info.yaml of each module in the current directory or its subfolders, with its Backstage entity.
+   export-inventory [FORMAT] Write the inventory of the module sources and provider versions of the modules in the current directory or its subfolders, as json or a cyclonedx BOM.
+   tflint                Run tflint on the Terraform code of the module, with the terragrunt configured inputs passed in.
+   generate-docs         Write MODULE.md, with the terraform-docs documentation of the module and the terragrunt configured inputs.
    hclfmt                Recursively find hcl files and rewrite them into a canonical format.
+   completion <SHELL>    Print the completion script for the given shell (bash, zsh or fish).
    aws-provider-patch    Overwrite settings on nested AWS providers to work around a Terraform bug (issue #13018)
+   backend <SUBCOMMAND>  Manage the remote state backend: bootstrap, delete or migrate <SRC> <DST>.
+   daemon                Run the Terragrunt daemon, which runs the commands passed --terragrunt-daemon with warm caches.
    *                     Terragrunt forwards all other commands directly to Terraform
 
 GLOBAL OPTIONS:
@@ -216,25 +377,89 @@ GLOBAL OPTIONS:
    terragrunt-tfpath                            Path to the Terraform binary. Default is terraform (on PATH).
    terragrunt-no-auto-init                      Don't automatically run 'terraform init' during other terragrunt commands. You must run 'terragrunt init' manually.
    terragrunt-no-auto-retry                     Don't automatically re-run command in case of transient errors.
+   terragrunt-no-copy-lock-file                 Don't copy the .terraform.lock.hcl file between the working directory and the folder the Terraform code is downloaded into.
    terragrunt-non-interactive                   Assume "yes" for all prompts.
    terragrunt-working-dir                       The path to the Terraform templates. Default is current directory.
    terragrunt-download-dir                      The path where to download Terraform code. Default is .terragrunt-cache in the working directory.
    terragrunt-source                            Download Terraform configurations from the specified source into a temporary folder, and run Terraform in that temporary folder.
    terragrunt-source-update                     Delete the contents of the temporary folder to clear out any old, cached source code before downloading new source code into it.
+   terragrunt-symlink-local-source              Symlink the files of local Terraform sources into the temporary folder rather than copying them. Can also be set via the TERRAGRUNT_SYMLINK_LOCAL_SOURCE environment variable.
+   terragrunt-source-cache                      Download each remote Terraform source once into a cache shared by all modules, and copy it from there. Can also be set via the TERRAGRUNT_SOURCE_CACHE environment variable.
+   terragrunt-source-cache-dir                  The directory in which the remote Terraform sources are cached. Can also be set via the TERRAGRUNT_SOURCE_CACHE_DIR environment variable.
+   terragrunt-source-cache-max-age <SEC>        Remove the cached Terraform sources that were not used for SEC seconds. Default is 30 days. Can also be set via the TERRAGRUNT_SOURCE_CACHE_MAX_AGE environment variable.
    terragrunt-iam-role                          Assume the specified IAM role before executing Terraform. Can also be set via the TERRAGRUNT_IAM_ROLE environment variable.
    terragrunt-iam-assume-role-duration          Session duration for IAM Assume Role session. Can also be set via the TERRAGRUNT_IAM_ASSUME_ROLE_DURATION environment variable.
+   terragrunt-iam-assume-role-external-id       External ID to pass when assuming the IAM role. Can also be set via the TERRAGRUNT_IAM_ASSUME_ROLE_EXTERNAL_ID environment variable.
+   terragrunt-iam-assume-role-mfa-serial        Serial number or ARN of the MFA device to assume the IAM role with. Can also be set via the TERRAGRUNT_IAM_ASSUME_ROLE_MFA_SERIAL environment variable.
+   terragrunt-iam-assume-role-mfa-token         Token of the MFA device to assume the IAM role with. Prompted for if not set. Can also be set via the TERRAGRUNT_IAM_ASSUME_ROLE_MFA_TOKEN environment variable.
+   terragrunt-iam-web-identity-token            Web identity token, or path of a file containing one, to assume the IAM role with. Can also be set via the TERRAGRUNT_IAM_WEB_IDENTITY_TOKEN environment variable.
+   terragrunt-google-impersonate-service-accountEmail of a GCP service account to impersonate when bootstrapping GCS backends and running terraform. Can also be set via the TERRAGRUNT_GOOGLE_IMPERSONATE_SERVICE_ACCOUNT environment variable.
+   terragrunt-aws-sso-login                     Run aws sso login when the SSO session of the AWS profile has expired, rather than failing. Can also be set via the TERRAGRUNT_AWS_SSO_LOGIN environment variable.
+   terragrunt-aws-keyring-cache                 Cache the credentials of the assumed IAM roles in the keyring of the OS, and reuse them in the next runs. Can also be set via the TERRAGRUNT_AWS_KEYRING_CACHE environment variable.
+   terragrunt-aws-endpoint                      The endpoint of an AWS service terragrunt calls, i.e. s3, dynamodb, sts or iam, e.g. sts=http://localhost:4566. Can be supplied multiple times.
+   terragrunt-aws-sts-regional-endpoints        Call the STS endpoint of the region, rather than the global one. Can also be set via the TERRAGRUNT_AWS_STS_REGIONAL_ENDPOINTS environment variable.
+   terragrunt-preflight-checks                  Check that the credentials of the remote state have the permissions to use its backend before running terraform. Can also be set via the TERRAGRUNT_PREFLIGHT_CHECKS environment variable.
    terragrunt-ignore-dependency-errors          *-all commands continue processing components even if a dependency fails.
    terragrunt-ignore-dependency-order           *-all commands will be run disregarding the dependencies
-   terragrunt-ignore-external-dependencies      *-all commands will not attempt to include external dependencies
-   terragrunt-include-external-dependencies     *-all commands will include external dependencies
+   terragrunt-ignore-external-dependencies      *-all commands will not attempt to include external dependencies. Can also be set via the TERRAGRUNT_IGNORE_EXTERNAL_DEPENDENCIES environment variable.
+   terragrunt-include-external-dependencies     *-all commands will include external dependencies. Can also be set via the TERRAGRUNT_INCLUDE_EXTERNAL_DEPENDENCIES environment variable.
    terragrunt-parallelism <N>                   *-all commands parallelism set to at most N modules
+   terragrunt-dependency-fetch-parallelism <N>  Fetch the outputs of at most N dependency blocks of a module concurrently (default 10). Can also be set via the TERRAGRUNT_DEPENDENCY_FETCH_PARALLELISM environment variable.
+   terragrunt-dependency-output-cache-ttl <SEC> Cache the outputs of dependencies on disk for SEC seconds, so that they're reused by later runs. Can also be set via the TERRAGRUNT_DEPENDENCY_OUTPUT_CACHE_TTL environment variable.
+   terragrunt-fetch-dependency-output-from-state Read the outputs of dependencies straight from their S3 or GCS state objects, rather than running terraform. Can also be set via the TERRAGRUNT_FETCH_DEPENDENCY_OUTPUT_FROM_STATE environment variable.
+   terragrunt-input-mode                        How stdin is connected to terraform and hooks: auto (default), stdin, pty or none. Can also be set via the TERRAGRUNT_INPUT_MODE environment variable.
    terragrunt-exclude-dir                       Unix-style glob of directories to exclude when running *-all commands
    terragrunt-include-dir                       Unix-style glob of directories to include when running *-all commands
+   terragrunt-queue-include-units-reading       Include the modules that read the given file (e.g. via include or read_terragrunt_config) when running *-all commands
+   terragrunt-changed-since                     *-all commands will only run the modules affected by the files changed since the given git ref (e.g. origin/main). Can also be set via the TERRAGRUNT_CHANGED_SINCE environment variable.
+   terragrunt-include-changed-dependents        *-all commands will also run the modules that depend on the modules affected by the changed files.
+   terragrunt-strict-include                    *-all commands will only run the modules under the included directories. Dependencies outside of them are assumed to be already applied.
+   terragrunt-no-destroy-dependencies-check     Don't check for other modules that depend on a module before destroying it. Can also be set via the TERRAGRUNT_NO_DESTROY_DEPENDENCIES_CHECK environment variable.
+   terragrunt-destroy-dependents-search-dir     The folder in which to look for the modules that depend on a module before destroying it (default: the parent folder of the module). Can also be set via the TERRAGRUNT_DESTROY_DEPENDENTS_SEARCH_DIR environment variable.
+   terragrunt-no-output-prefix                  *-all commands will not prefix each line of the output of the modules with the module path. Can also be set via the TERRAGRUNT_NO_OUTPUT_PREFIX environment variable.
+   terragrunt-providers-lock-mirror-dir         Populate a provider mirror shared by all modules and use it when running 'providers lock'. Can also be set via the TERRAGRUNT_PROVIDERS_LOCK_MIRROR_DIR environment variable.
+   terragrunt-provider-cache                    Install the providers of all modules through a local provider cache server, which downloads each provider once. Can also be set via the TERRAGRUNT_PROVIDER_CACHE environment variable.
+   terragrunt-provider-cache-dir                The directory in which the provider cache server caches the providers. Can also be set via the TERRAGRUNT_PROVIDER_CACHE_DIR environment variable.
+   terragrunt-prefetch-only                     Only download the Terraform sources, modules and providers when running init, without configuring the backends. Can also be set via the TERRAGRUNT_PREFETCH_ONLY environment variable.
+   terragrunt-daemon                            Run the command through the Terragrunt daemon, if it's running, which keeps the parsed configs and the provider cache warm between commands. Can also be set via the TERRAGRUNT_DAEMON environment variable.
+   terragrunt-daemon-socket                     The path of the unix socket the Terragrunt daemon listens on. Can also be set via the TERRAGRUNT_DAEMON_SOCKET environment variable.
+   terragrunt-no-config-cache                   Parse the Terragrunt configs and run helpers such as run_cmd every time they are read, rather than once per run. Can also be set via the TERRAGRUNT_NO_CONFIG_CACHE environment variable.
    terragrunt-check                             Enable check mode in the hclfmt command.
    terragrunt-hclfmt-file                       The path to a single hcl file that the hclfmt command should run on.
    terragrunt-override-attr                     A key=value attribute to override in a provider block as part of the aws-provider-patch command. May be specified multiple times.
    terragrunt-debug                             Write terragrunt-debug.tfvars to working folder to help root-cause issues.
    terragrunt-log-level                         Sets the logging level for Terragrunt. Supported levels: panic, fatal, error, warn (default), info, debug, trace.
+   terragrunt-cpu-profile <FILE>                Write a CPU profile of Terragrunt itself to the given file, for go tool pprof. Can also be set via the TERRAGRUNT_CPU_PROFILE environment variable.
+   terragrunt-mem-profile <FILE>                Write a heap profile of Terragrunt itself to the given file once the command finishes, for go tool pprof. Can also be set via the TERRAGRUNT_MEM_PROFILE environment variable.
+   terragrunt-trace <FILE>                      Write an execution trace of Terragrunt itself to the given file, for go tool trace. Can also be set via the TERRAGRUNT_TRACE environment variable.
+   terragrunt-telemetry-endpoint <URL>          Export OpenTelemetry traces of Terragrunt to the given OTLP/HTTP endpoint. Can also be set via the TERRAGRUNT_TELEMETRY_ENDPOINT environment variable, or the standard OTEL_EXPORTER_OTLP_ENDPOINT.
+   terragrunt-metrics-endpoint <URL>            Send metrics about each unit to a StatsD server (statsd://host:port) or a Prometheus Pushgateway (http://host:port). Can also be set via the TERRAGRUNT_METRICS_ENDPOINT environment variable.
+   terragrunt-events-endpoint <URL>             Send the start and end of applies and destroys, and the outcome of each unit, as events to Datadog (datadog://<site>) or Amazon EventBridge (eventbridge://<event bus>). Can also be set via the TERRAGRUNT_EVENTS_ENDPOINT environment variable.
+   terragrunt-github-actions                    Print errors as GitHub Actions annotations and write a summary of the units to the job summary. Can also be set via the TERRAGRUNT_GITHUB_ACTIONS environment variable.
+   terragrunt-gitlab-report-dir <DIR>           Write the resource changes of the plan of each unit to DIR as a GitLab terraform report. Can also be set via the TERRAGRUNT_GITLAB_REPORT_DIR environment variable.
+   terragrunt-report-junit <FILE>               Write a JUnit XML report to FILE, with a test case for each unit that was run. Can also be set via the TERRAGRUNT_REPORT_JUNIT environment variable.
+   terragrunt-sarif-output <FILE>               Write the findings of validate-inputs to FILE as a SARIF log, for code scanning tools. Can also be set via the TERRAGRUNT_SARIF_OUTPUT environment variable.
+   terragrunt-policy <PATH>                     Evaluate the config and saved plans of the units against the Rego policies at PATH, a file, dir or go-getter URL of a bundle, before running plan, apply or destroy, and fail if they deny it. May be specified multiple times. Can also be set via the TERRAGRUNT_POLICY environment variable, as a comma-separated list.
+   terragrunt-infracost                         Estimate the change in the monthly cost of the plans of the units with Infracost, and print the total. Can also be set via the TERRAGRUNT_INFRACOST environment variable.
+   terragrunt-infracost-report <FILE>           Estimate the cost of the plans of the units with Infracost, and write the costs of the units and their total to FILE as JSON. Can also be set via the TERRAGRUNT_INFRACOST_REPORT environment variable.
+   terragrunt-drift-report <FILE>               Classify each unit a plan runs in as no-drift, drift or error, write the result to FILE as JSON, and exit with 2 if any unit drifted. Can also be set via the TERRAGRUNT_DRIFT_REPORT environment variable.
+   terragrunt-plan-artifact-url <URL>           Upload the plan of each unit plan runs in, and its JSON, to URL, an s3://, gs:// or file:// key template with {run_id} and {unit}, and make apply apply the uploaded plan. Can also be set via the TERRAGRUNT_PLAN_ARTIFACT_URL environment variable.
+   terragrunt-plan-artifact-run-id <ID>         The ID of the run the plan artifacts are uploaded under. Defaults to the GITHUB_RUN_ID or CI_PIPELINE_ID environment variables. Can also be set via the TERRAGRUNT_PLAN_ARTIFACT_RUN_ID environment variable.
+   terragrunt-plan-markdown <FILE>              Write the plans of the units plan runs in to FILE as a Markdown document, with a collapsible section per unit that changes, to post as a pull request comment. Can also be set via the TERRAGRUNT_PLAN_MARKDOWN environment variable.
+   terragrunt-plan-renderer <COMMAND>           Pipe the output of plan through COMMAND, e.g. a diff highlighter, before displaying it, per unit. Can also be set via the TERRAGRUNT_PLAN_RENDERER environment variable.
+   terragrunt-docs-inventory <FILE>             Write the documentation generate-docs generates of all the modules to FILE, instead of a MODULE.md per module. Can also be set via the TERRAGRUNT_DOCS_INVENTORY environment variable.
+   terragrunt-tfc-run                           Run plan, apply and destroy as runs of the Terraform Cloud workspace of the remote_state block of each unit, instead of running terraform locally. Can also be set via the TERRAGRUNT_TFC_RUN environment variable.
+   terragrunt-shallow-clone                     Fetch git sources with a depth of 1, rather than with their whole history. Can also be set via the TERRAGRUNT_SHALLOW_CLONE environment variable.
+   terragrunt-sparse-checkout                   Check out only the folder of the module of git sources, i.e., the part of the source after the //. Can also be set via the TERRAGRUNT_SPARSE_CHECKOUT environment variable.
+   terragrunt-git-credential-helper             The git credential helper to fetch private git sources with, e.g. cache, or an absolute path. Can also be set via the TERRAGRUNT_GIT_CREDENTIAL_HELPER environment variable.
+   terragrunt-git-netrc-template                The path of a netrc file, with ${VAR} references to environment variables, to fetch private git sources with. Can also be set via the TERRAGRUNT_GIT_NETRC_TEMPLATE environment variable.
+   terragrunt-github-app-id                     The ID of the GitHub App to fetch private git sources from GitHub with. Can also be set via the TERRAGRUNT_GITHUB_APP_ID environment variable.
+   terragrunt-github-app-installation-id        The ID of the installation of the GitHub App. Can also be set via the TERRAGRUNT_GITHUB_APP_INSTALLATION_ID environment variable.
+   terragrunt-github-app-private-key            The path of the private key of the GitHub App, or its contents. Can also be set via the TERRAGRUNT_GITHUB_APP_PRIVATE_KEY environment variable.
+   terragrunt-source-mirror                     Replace the sources that start with the given prefix with their archive in the given S3, GCS or HTTP mirror, e.g. github.com/acme=s3::https://s3.amazonaws.com/acme-modules. Can be supplied multiple times.
+   terragrunt-source-mirror-header              A header to send along with the requests to the HTTP source mirrors, e.g. Authorization=Bearer <token>. Can be supplied multiple times.
+   terragrunt-source-sigv4-service <SERVICE>    Sign the requests of the HTTP downloads of the sources from the source mirrors and from the endpoints of the given AWS service, e.g. execute-api, with AWS SigV4, using the AWS credentials of the module. Can also be set via the TERRAGRUNT_SOURCE_SIGV4_SERVICE environment variable.
+   terragrunt-atlantis-workflow <NAME>          The Atlantis workflow the projects written by generate-atlantis-config run. Can also be set via the TERRAGRUNT_ATLANTIS_WORKFLOW environment variable.
 
 VERSION:
    {{.Version}}{{if len .Authors}}
@@ -276,6 +501,13 @@ func CreateTerragruntCli(version string, writer io.Writer, errwriter io.Writer)
 	app.Name = "terragrunt"
 	app.Author = "Gruntwork <www.gruntwork.io>"
 	app.Version = version
+	// The version flag is handled in runApp rather than by the cli library, so that it can print the terraform version
+	// too and support --format
+	app.HideVersion = true
+	app.Flags = []cli.Flag{
+		cli.BoolFlag{Name: OPT_VERSION + ", v", Usage: "print the version"},
+		cli.StringFlag{Name: OPT_FORMAT, Usage: "the format of the --version output: text or json", Hidden: true},
+	}
 	app.Action = runApp
 	app.Usage = "terragrunt <COMMAND> [GLOBAL OPTIONS]"
 	app.Writer = writer
@@ -290,19 +522,97 @@ func CreateTerragruntCli(version string, writer io.Writer, errwriter io.Writer)
 func runApp(cliContext *cli.Context) (finalErr error) {
 	defer errors.Recover(func(cause error) { finalErr = cause })
 
+	if cliContext.Bool(OPT_VERSION) {
+		return runVersionCommand(cliContext)
+	}
+
 	// If someone calls us with no args at all, show the help text and exit
 	if !cliContext.Args().Present() {
 		return cli.ShowAppHelp(cliContext)
 	}
 
+	if isCompletionCommand(cliContext.Args().First()) {
+		return runCompletionCommand(cliContext.Args(), cliContext.App.Writer)
+	}
+
 	terragruntOptions, err := ParseTerragruntOptions(cliContext)
 	if err != nil {
 		return err
 	}
 
+	givenCommand := cliContext.Args().First()
+	if state, inDaemon := cliContext.App.Metadata[METADATA_DAEMON].(*daemonState); inDaemon {
+		state.applyTo(terragruntOptions)
+	} else if terragruntOptions.Daemon && givenCommand != CMD_DAEMON {
+		ranThroughDaemon, err := runThroughDaemon(cliContext, terragruntOptions)
+		if ranThroughDaemon || err != nil {
+			return err
+		}
+	}
+
+	// Profile wherever the command runs, which is in the daemon if it was sent there
+	stopProfiling, err := startProfiling(terragruntOptions)
+	if err != nil {
+		return err
+	}
+	defer stopProfiling()
+
+	if givenCommand == CMD_DAEMON {
+		return runDaemon(cliContext, terragruntOptions)
+	}
+
+	// Like profiling, the spans are of wherever the command runs
+	stopTelemetry, err := startTelemetry(givenCommand, terragruntOptions)
+	if err != nil {
+		return err
+	}
+	defer func() { stopTelemetry(finalErr) }()
+
+	stopMetrics, err := startMetrics(terragruntOptions)
+	if err != nil {
+		return err
+	}
+	defer stopMetrics()
+
+	stopSarif := startSarif(terragruntOptions)
+	defer stopSarif()
+
+	stopInfracost := startInfracost(terragruntOptions)
+	defer stopInfracost()
+
+	stopDriftReport := startDriftReport(terragruntOptions)
+	defer func() { finalErr = stopDriftReport(finalErr) }()
+
+	stopPlanMarkdown := startPlanMarkdown(terragruntOptions)
+	defer func() { stopPlanMarkdown(finalErr) }()
+
+	if err := startPlanArtifacts(terragruntOptions); err != nil {
+		return err
+	}
+
+	stopTflint := startTflint(terragruntOptions)
+	defer stopTflint()
+
+	stopDocsInventory := startDocsInventory(terragruntOptions)
+	defer stopDocsInventory()
+
+	stopGitCredentials, err := startGitCredentials(terragruntOptions)
+	if err != nil {
+		return err
+	}
+	defer stopGitCredentials()
+	defer removePolicyBundles()
+
+	if terragruntOptions.GitHubActions {
+		defer func() {
+			if err := printGitHubAnnotations(cliContext.App.Writer, finalErr, terragruntOptions); err != nil {
+				terragruntOptions.Logger.Warnf("Could not print the GitHub Actions annotations: %v", err)
+			}
+		}()
+	}
+
 	shell.PrepareConsole(terragruntOptions)
 
-	givenCommand := cliContext.Args().First()
 	newOptions, command := checkDeprecated(givenCommand, terragruntOptions)
 	return runCommand(command, newOptions)
 }
@@ -327,15 +637,46 @@ func checkDeprecated(command string, terragruntOptions *options.TerragruntOption
 // runCommand runs one or many terraform commands based on the type of
 // terragrunt command
 func runCommand(command string, terragruntOptions *options.TerragruntOptions) (finalEff error) {
+	if terragruntOptions.ProviderCache {
+		stopProviderCacheServer, err := startProviderCacheServer(terragruntOptions)
+		if err != nil {
+			return err
+		}
+		defer stopProviderCacheServer()
+	}
+
 	if command == CMD_RUN_ALL {
 		return runAll(terragruntOptions)
 	}
+	// run-all destroy takes care of the dependents itself, by destroying them first, so this is only checked when
+	// destroying a single module
+	if err := checkDestroyDependents(terragruntOptions); err != nil {
+		return err
+	}
 	return RunTerragrunt(terragruntOptions)
 }
 
+// isDependencyOutputsRun returns true if the unit of the given options is only run to fetch its outputs for the
+// dependency blocks of another unit, rather than as part of the command the user ran
+func isDependencyOutputsRun(terragruntOptions *options.TerragruntOptions) bool {
+	return terragruntOptions.TerraformCommand != terragruntOptions.OriginalTerraformCommand
+}
+
+// isMainPlanRun returns true if the unit of the given options runs a plan the user asked for, rather than one run to
+// fetch its outputs for the dependency blocks of another unit
+func isMainPlanRun(terragruntOptions *options.TerragruntOptions) bool {
+	return util.FirstArg(terragruntOptions.TerraformCliArgs) == "plan" && !isDependencyOutputsRun(terragruntOptions)
+}
+
 // Downloads terraform source if necessary, then runs terraform with the given options and CLI args.
 // This will forward all the args and extra_arguments directly to Terraform.
-func RunTerragrunt(terragruntOptions *options.TerragruntOptions) error {
+func RunTerragrunt(terragruntOptions *options.TerragruntOptions) (finalErr error) {
+	if unitMetrics := startUnitMetrics(terragruntOptions); unitMetrics != nil {
+		defer func() { finishUnitMetrics(unitMetrics, finalErr, terragruntOptions) }()
+	}
+	defer func() { recordDriftError(terragruntOptions, finalErr) }()
+	defer func() { recordPlanMarkdownError(terragruntOptions, finalErr) }()
+
 	if shouldPrintTerraformHelp(terragruntOptions) {
 		return shell.RunTerraformCommand(terragruntOptions, terragruntOptions.TerraformCliArgs...)
 	}
@@ -348,6 +689,26 @@ func RunTerragrunt(terragruntOptions *options.TerragruntOptions) error {
 		return runGraphDependencies(terragruntOptions)
 	}
 
+	if shouldGenerateAtlantisConfig(terragruntOptions) {
+		return generateAtlantisConfig(terragruntOptions)
+	}
+
+	if shouldExportStacks(terragruntOptions) {
+		return exportStacks(terragruntOptions)
+	}
+
+	if shouldGenerateCatalogInfo(terragruntOptions) {
+		return generateCatalogInfo(terragruntOptions)
+	}
+
+	if shouldExportInventory(terragruntOptions) {
+		return exportInventory(terragruntOptions)
+	}
+
+	if isBackendMigrate(terragruntOptions) {
+		return runBackendMigrate(terragruntOptions)
+	}
+
 	if err := checkVersionConstraints(terragruntOptions); err != nil {
 		return err
 	}
@@ -372,8 +733,19 @@ func RunTerragrunt(terragruntOptions *options.TerragruntOptions) error {
 		return nil
 	}
 
+	finishNotifications, err := startUnitNotifications(terragruntOptions, terragruntConfig)
+	if err != nil {
+		return err
+	}
+	defer func() { finishNotifications(finalErr) }()
+
+	if terragruntConfig.AwsProfile != "" {
+		terragruntOptions.AwsProfile = terragruntConfig.AwsProfile
+	}
+	aws_helper.UpdateEnvWithAwsProfile(terragruntOptions)
+
 	if terragruntOptions.IamRole == "" {
-		terragruntOptions.IamRole = terragruntConfig.IamRole
+		terragruntOptions.IamRole, terragruntOptions.IamRoleChain = terragruntConfig.IamRoleForCommand(terragruntOptions.TerraformCommand)
 	}
 
 	// replace default sts duration if set in config
@@ -381,9 +753,51 @@ func RunTerragrunt(terragruntOptions *options.TerragruntOptions) error {
 		terragruntOptions.IamAssumeRoleDuration = *terragruntConfig.IamAssumeRoleDuration
 	}
 
-	if err := aws_helper.AssumeRoleAndUpdateEnvIfNecessary(terragruntOptions); err != nil {
+	if terragruntOptions.IamAssumeRoleExternalId == "" {
+		terragruntOptions.IamAssumeRoleExternalId = terragruntConfig.IamAssumeRoleExternalId
+	}
+
+	if terragruntOptions.IamWebIdentityToken == "" {
+		terragruntOptions.IamWebIdentityToken = terragruntConfig.IamWebIdentityToken
+	}
+
+	if terragruntOptions.GoogleImpersonateServiceAccount == "" {
+		terragruntOptions.GoogleImpersonateServiceAccount = terragruntConfig.GoogleImpersonateServiceAccount
+	}
+	remote.UpdateEnvWithGoogleImpersonateServiceAccount(terragruntOptions)
+	config.UpdateEnvWithAzureAuth(terragruntOptions, terragruntConfig.AzureAuth)
+
+	removeOidcCredentials, err := startOidcCredentials(terragruntOptions, terragruntConfig)
+	if err != nil {
+		return err
+	}
+	defer removeOidcCredentials()
+
+	stopVaultCredentials, err := startVaultCredentialsAndAssumeRole(terragruntOptions, terragruntConfig)
+	if err != nil {
+		return err
+	}
+	defer stopVaultCredentials()
+
+	stopHTTPBackendSigningProxy, err := remote.StartHTTPBackendSigningProxy(terragruntConfig.RemoteState, terragruntOptions)
+	if err != nil {
+		return err
+	}
+	defer stopHTTPBackendSigningProxy()
+
+	if err := runPreflightChecks(terragruntOptions, terragruntConfig); err != nil {
+		return err
+	}
+
+	if shouldRunBackendCommand(terragruntOptions) {
+		return runBackendCommand(terragruntOptions, terragruntConfig)
+	}
+
+	removeRegistryCredentials, err := prepareRegistryCredentials(terragruntOptions, terragruntConfig)
+	if err != nil {
 		return err
 	}
+	defer removeRegistryCredentials()
 
 	// get the default download dir
 	_, defaultDownloadDir, err := options.DefaultWorkingAndDownloadDirs(terragruntOptions.TerragruntConfigPath)
@@ -391,10 +805,26 @@ func RunTerragrunt(terragruntOptions *options.TerragruntOptions) error {
 		return err
 	}
 
-	// if the download dir hasn't been changed from default, and is set in the config,
-	// then use it
-	if terragruntOptions.DownloadDir == defaultDownloadDir && terragruntConfig.DownloadDir != "" {
+	// if the download dir passed in via the CLI or environment is a template, render it for this module. Otherwise, if
+	// the download dir hasn't been changed from default, and is set in the config, then use it. Otherwise, fall back to
+	// the org-wide default or the one from the defaults file, if any.
+	if terragruntOptions.DownloadDirTemplate != "" {
+		downloadDir, err := config.RenderDownloadDir(terragruntOptions.DownloadDirTemplate, terragruntOptions)
+		if err != nil {
+			return err
+		}
+		terragruntOptions.DownloadDir = downloadDir
+	} else if terragruntOptions.DownloadDir == defaultDownloadDir && terragruntConfig.DownloadDir != "" {
 		terragruntOptions.DownloadDir = terragruntConfig.DownloadDir
+	} else if terragruntOptions.DownloadDir == defaultDownloadDir && terragruntOptions.DefaultsDownloadDir != "" {
+		downloadDir := terragruntOptions.DefaultsDownloadDir
+		if config.IsDownloadDirTemplate(downloadDir) {
+			downloadDir, err = config.RenderDownloadDir(downloadDir, terragruntOptions)
+			if err != nil {
+				return err
+			}
+		}
+		terragruntOptions.DownloadDir = downloadDir
 	}
 
 	// Override the default value of retryable errors using the value set in the config file
@@ -477,6 +907,14 @@ func RunTerragrunt(terragruntOptions *options.TerragruntOptions) error {
 		return validateTerragruntInputs(updatedTerragruntOptions, terragruntConfig)
 	}
 
+	if shouldRunTflint(updatedTerragruntOptions) {
+		return runTflint(updatedTerragruntOptions, terragruntConfig)
+	}
+
+	if shouldGenerateDocs(updatedTerragruntOptions) {
+		return generateDocs(updatedTerragruntOptions, terragruntConfig)
+	}
+
 	// We do the debug file generation here, after all the terragrunt generated terraform files are created so that we
 	// can ensure the tfvars json file only includes the vars that are defined in the module.
 	if updatedTerragruntOptions.Debug {
@@ -489,10 +927,28 @@ func RunTerragrunt(terragruntOptions *options.TerragruntOptions) error {
 	return runTerragruntWithConfig(terragruntOptions, updatedTerragruntOptions, terragruntConfig, false)
 }
 
+// Request the credentials of the vault_credentials blocks of the given config, if any, and then assume the IAM role of
+// the unit of the given options, if any, with them, so that terraform and the AWS calls of terragrunt for the unit use
+// the credentials of the role, or else the ones of Vault. Returns a function that stops renewing the leases of the
+// credentials of Vault and revokes them, which should be called once the unit finishes.
+func startVaultCredentialsAndAssumeRole(terragruntOptions *options.TerragruntOptions, terragruntConfig *config.TerragruntConfig) (func(), error) {
+	stopVaultCredentials, err := startVaultCredentials(terragruntOptions, terragruntConfig)
+	if err != nil {
+		return nil, err
+	}
+	if err := aws_helper.AssumeRoleAndUpdateEnvIfNecessary(terragruntOptions); err != nil {
+		stopVaultCredentials()
+		return nil, err
+	}
+	aws_helper.LogAwsIdentity(terragruntOptions)
+	return stopVaultCredentials, nil
+}
+
 // Check the version constraints of both terragrunt and terraform. Note that as a side effect this will set the
 // following settings on terragruntOptions:
 // - TerraformPath
 // - TerraformVersion
+// - TerraformImplementation
 // TODO: Look into a way to refactor this function to avoid the side effect.
 func checkVersionConstraints(terragruntOptions *options.TerragruntOptions) error {
 	partialTerragruntConfig, err := config.PartialParseConfigFile(
@@ -507,8 +963,13 @@ func checkVersionConstraints(terragruntOptions *options.TerragruntOptions) error
 
 	// Change the terraform binary path before checking the version
 	// if the path is not changed from default and set in the config.
+	// If it's not set anywhere, prefer OpenTofu when it's installed.
 	if terragruntOptions.TerraformPath == options.TERRAFORM_DEFAULT_PATH && partialTerragruntConfig.TerraformBinary != "" {
 		terragruntOptions.TerraformPath = partialTerragruntConfig.TerraformBinary
+	} else if terragruntOptions.TerraformPath == options.TERRAFORM_DEFAULT_PATH && terragruntOptions.DefaultsTerraformPath != "" {
+		terragruntOptions.TerraformPath = terragruntOptions.DefaultsTerraformPath
+	} else if terragruntOptions.TerraformPath == options.TERRAFORM_DEFAULT_PATH && !terragruntOptions.TerraformPathExplicit {
+		terragruntOptions.TerraformPath = detectTerraformPath()
 	}
 	if err := PopulateTerraformVersion(terragruntOptions); err != nil {
 		return err
@@ -641,6 +1102,19 @@ func runTerragruntWithConfig(originalTerragruntOptions *options.TerragruntOption
 		return err
 	}
 
+	// Terraform Cloud runs init itself, and the plans it runs can't be evaluated locally, so only the hooks run here
+	if shouldRunInTFC(terragruntOptions) {
+		if err := checkProtectedModule(terragruntOptions, terragruntConfig); err != nil {
+			return err
+		}
+		return runActionWithHooks("terraform", terragruntOptions, terragruntConfig, func() error {
+			if util.ListContainsElement(TERRAFORM_COMMANDS_THAT_CHANGE_OUTPUTS, util.FirstArg(terragruntOptions.TerraformCliArgs)) {
+				defer config.InvalidateOutputCache(originalTerragruntOptions.TerragruntConfigPath, terragruntOptions)
+			}
+			return runInTFC(terragruntOptions, terragruntConfig)
+		})
+	}
+
 	if util.FirstArg(terragruntOptions.TerraformCliArgs) == CMD_INIT {
 		if err := prepareInitCommand(terragruntOptions, terragruntConfig, allowSourceDownload); err != nil {
 			return err
@@ -651,6 +1125,12 @@ func runTerragruntWithConfig(originalTerragruntOptions *options.TerragruntOption
 		}
 	}
 
+	if shouldSelectWorkspace(terragruntOptions, terragruntConfig) {
+		if err := selectTerraformWorkspace(terragruntOptions, terragruntConfig); err != nil {
+			return err
+		}
+	}
+
 	// Now that we've run 'init' and have all the source code locally, we can finally run the patch command
 	if shouldApplyAwsProviderPatch(terragruntOptions) {
 		return applyAwsProviderPatch(terragruntOptions)
@@ -660,11 +1140,63 @@ func runTerragruntWithConfig(originalTerragruntOptions *options.TerragruntOption
 		return err
 	}
 
-	return runActionWithHooks("terraform", terragruntOptions, terragruntConfig, func() error {
-		runTerraformError := runTerraformWithRetry(terragruntOptions)
+	removeCostPlan, err := prepareCostEstimate(terragruntOptions)
+	if err != nil {
+		return err
+	}
+	defer removeCostPlan()
+
+	removeDriftPlan, err := prepareDriftDetection(terragruntOptions)
+	if err != nil {
+		return err
+	}
+	defer removeDriftPlan()
+
+	removeMarkdownPlan, err := preparePlanMarkdown(terragruntOptions)
+	if err != nil {
+		return err
+	}
+	defer removeMarkdownPlan()
+
+	removeArtifactPlan, err := preparePlanArtifacts(terragruntOptions)
+	if err != nil {
+		return err
+	}
+	defer removeArtifactPlan()
+
+	removeReviewedPlan, err := fetchReviewedPlan(terragruntOptions)
+	if err != nil {
+		return err
+	}
+	defer removeReviewedPlan()
+
+	if err := checkPolicies(terragruntOptions, terragruntConfig); err != nil {
+		return err
+	}
+
+	if err := prepareProvidersLockMirror(terragruntOptions); err != nil {
+		return err
+	}
+
+	if terragruntOptions.Debug {
+		logTerraformCommandForDebug(terragruntOptions, terragruntConfig)
+	}
+
+	actionErr := runActionWithHooks("terraform", terragruntOptions, terragruntConfig, func() error {
+		runTerraformError := renderPlanOutput(terragruntOptions, func() error { return runTerraformWithRetry(terragruntOptions) })
+
+		if runTerraformError == nil && shouldWriteInitFingerprint(terragruntOptions) {
+			if err := writeInitFingerprint(terragruntOptions, terragruntConfig); err != nil {
+				terragruntOptions.Logger.Warnf("Could not record the init fingerprint of %s: %v", terragruntOptions.WorkingDir, err)
+			}
+		}
+
+		if util.ListContainsElement(TERRAFORM_COMMANDS_THAT_CHANGE_OUTPUTS, util.FirstArg(terragruntOptions.TerraformCliArgs)) {
+			config.InvalidateOutputCache(originalTerragruntOptions.TerragruntConfigPath, terragruntOptions)
+		}
 
 		var lockFileError error
-		if shouldCopyLockFile(terragruntOptions.TerraformCliArgs) {
+		if terragruntOptions.CopyLockFile && shouldCopyLockFile(terragruntOptions.TerraformCliArgs) {
 			// Copy the lock file from the Terragrunt working dir (e.g., .terragrunt-cache/xxx/<some-module>) to the
 			// user's working dir (e.g., /live/stage/vpc). That way, the lock file will end up right next to the user's
 			// terragrunt.hcl and can be checked into version control. Note that in the past, Terragrunt allowed the
@@ -677,6 +1209,22 @@ func runTerragruntWithConfig(originalTerragruntOptions *options.TerragruntOption
 
 		return multierror.Append(runTerraformError, lockFileError).ErrorOrNil()
 	})
+	if actionErr != nil {
+		return actionErr
+	}
+
+	if err := checkSavedPlanPolicies(terragruntOptions, terragruntConfig); err != nil {
+		return err
+	}
+
+	if err := uploadPlanArtifacts(terragruntOptions); err != nil {
+		return err
+	}
+
+	estimateCost(terragruntOptions)
+	detectDrift(terragruntOptions)
+	addPlanToMarkdown(terragruntOptions)
+	return nil
 }
 
 // Terraform 0.14 now manages a lock file for providers. This can be updated
@@ -698,24 +1246,39 @@ func shouldCopyLockFile(args []string) bool {
 		return true
 	}
 
-	if util.FirstArg(args) == CMD_PROVIDERS && util.SecondArg(args) == CMD_LOCK {
+	if isProvidersLockCommand(args) {
 		return true
 	}
 	return false
 }
 
 // Terraform 0.14 now generates a lock file when you run `terraform init`.
-// If any such file exists, this function will copy the lock file to the destination folder
+// If any such file exists, this function will copy the lock file to the destination folder. Nothing is copied if the
+// folders are the same, which is the case when the module has no source, or if the lock file hasn't changed.
 func copyLockFile(sourceFolder string, destinationFolder string, logger *logrus.Entry) error {
 	sourceLockFilePath := util.JoinPath(sourceFolder, util.TerraformLockFile)
 	destinationLockFilePath := util.JoinPath(destinationFolder, util.TerraformLockFile)
 
-	if util.FileExists(sourceLockFilePath) {
-		logger.Debugf("Copying lock file from %s to %s", sourceLockFilePath, destinationFolder)
-		return util.CopyFile(sourceLockFilePath, destinationLockFilePath)
+	if !util.FileExists(sourceLockFilePath) || filepath.Clean(sourceFolder) == filepath.Clean(destinationFolder) {
+		return nil
 	}
 
-	return nil
+	if util.FileExists(destinationLockFilePath) {
+		sourceContents, err := util.ReadFileAsString(sourceLockFilePath)
+		if err != nil {
+			return err
+		}
+		destinationContents, err := util.ReadFileAsString(destinationLockFilePath)
+		if err != nil {
+			return err
+		}
+		if sourceContents == destinationContents {
+			return nil
+		}
+	}
+
+	logger.Debugf("Copying lock file from %s to %s", sourceLockFilePath, destinationFolder)
+	return util.CopyFile(sourceLockFilePath, destinationLockFilePath)
 }
 
 // Run the given action function surrounded by hooks. That is, run the before hooks first, then, if there were no
@@ -763,11 +1326,25 @@ func setTerragruntInputsAsEnvVars(terragruntOptions *options.TerragruntOptions,
 func runTerraformWithRetry(terragruntOptions *options.TerragruntOptions) error {
 	// Retry the command configurable time with sleep in between
 	for i := 0; i < terragruntOptions.RetryMaxAttempts; i++ {
-		if out, tferr := shell.RunTerraformCommandWithOutput(terragruntOptions, terragruntOptions.TerraformCliArgs...); tferr != nil {
-			if out != nil && isRetryable(out.Stderr, tferr, terragruntOptions) {
+		// The stdout of a plan or apply can be large, so it's scanned for the resource changes rather than read into memory
+		var stderr string
+		var started bool
+		tferr := shell.RunTerraformCommandWithOutputReaders(terragruntOptions, func(stdout io.Reader, stderrReader io.Reader) {
+			started = true
+			terragruntOptions.UnitMetrics.ScanResourceChanges(stdout)
+			if stderrBytes, err := ioutil.ReadAll(stderrReader); err == nil {
+				stderr = string(stderrBytes)
+			}
+		}, terragruntOptions.TerraformCliArgs...)
+		if tferr != nil {
+			if started && isRetryable(stderr, tferr, terragruntOptions) {
 				terragruntOptions.Logger.Infof("Encountered an error eligible for retrying. Sleeping %v before retrying.\n", terragruntOptions.RetrySleepIntervalSec)
+				terragruntOptions.UnitMetrics.AddRetry()
 				time.Sleep(terragruntOptions.RetrySleepIntervalSec)
 			} else {
+				if started {
+					terragruntOptions.UnitMetrics.SetErrorOutput(stderr)
+				}
 				return tferr
 			}
 		} else {
@@ -781,7 +1358,21 @@ func runTerraformWithRetry(terragruntOptions *options.TerragruntOptions) error {
 // Prepare for running 'terraform init' by initializing remote state storage and adding backend configuration arguments
 // to the TerraformCliArgs
 func prepareInitCommand(terragruntOptions *options.TerragruntOptions, terragruntConfig *config.TerragruntConfig, allowSourceDownload bool) error {
+	if terragruntOptions.PrefetchOnly {
+		// Only download the modules and providers, leaving the remote state storage and the backend alone
+		terragruntOptions.Logger.Debugf("Not configuring the backend of %s, as only prefetching", terragruntOptions.TerragruntConfigPath)
+		if !util.ListContainsElement(terragruntOptions.TerraformCliArgs, "-backend=false") {
+			terragruntOptions.InsertTerraformCliArgs("-backend=false")
+		}
+		return nil
+	}
+
 	if terragruntConfig.RemoteState != nil {
+		// Catch mistakes in the config, such as a missing bucket, before terraform reports them as a backend error
+		if err := terragruntConfig.RemoteState.ValidateConfig(terragruntOptions); err != nil {
+			return err
+		}
+
 		// Initialize the remote state if necessary  (e.g. create S3 bucket and DynamoDB table)
 		remoteStateNeedsInit, err := remoteStateNeedsInit(terragruntConfig.RemoteState, terragruntOptions)
 		if err != nil {
@@ -791,6 +1382,20 @@ func prepareInitCommand(terragruntOptions *options.TerragruntOptions, terragrunt
 			if err := terragruntConfig.RemoteState.Initialize(terragruntOptions); err != nil {
 				return err
 			}
+
+			workspace, err := stateTerraformWorkspace(terragruntOptions, terragruntConfig)
+			if err != nil {
+				return err
+			}
+			stateCopied, err := terragruntConfig.RemoteState.CopyMovedState(workspace, terragruntOptions)
+			if err != nil {
+				return err
+			}
+			// The state is already at its new location, so terraform doesn't need to migrate it when switching to the
+			// new backend config
+			if stateCopied && !util.ListContainsElement(terragruntOptions.TerraformCliArgs, "-reconfigure") && !util.ListContainsElement(terragruntOptions.TerraformCliArgs, "-migrate-state") {
+				terragruntOptions.InsertTerraformCliArgs("-reconfigure")
+			}
 		}
 
 		// Add backend config arguments to the command
@@ -878,6 +1483,17 @@ func needsInit(terragruntOptions *options.TerragruntOptions, terragruntConfig *c
 		return true, nil
 	}
 
+	// If nothing init depends on changed since the last init, there's no need for the checks below, some of which, such
+	// as checking the remote state storage exists, take a while
+	fingerprintMatches, err := initFingerprintMatches(terragruntOptions, terragruntConfig)
+	if err != nil {
+		return false, err
+	}
+	if fingerprintMatches {
+		terragruntOptions.Logger.Debugf("Nothing changed since the last init of %s, so not running init again", terragruntOptions.WorkingDir)
+		return false, nil
+	}
+
 	modulesNeedsInit, err := modulesNeedInit(terragruntOptions)
 	if err != nil {
 		return false, err
@@ -886,6 +1502,8 @@ func needsInit(terragruntOptions *options.TerragruntOptions, terragruntConfig *c
 		return true, nil
 	}
 
+	// The fingerprint is only recorded once an init runs, as the checks above can be skipped, e.g. for the backend with
+	// --terragrunt-disable-bucket-update or disable_init, so they don't show the working dir is fully initialized
 	return remoteStateNeedsInit(terragruntConfig.RemoteState, terragruntOptions)
 }
 
@@ -1000,6 +1618,12 @@ func runAll(terragruntOptions *options.TerragruntOptions) error {
 		}
 	}
 
+	stopNotifications, err := startRunAllNotifications(terragruntOptions, stack)
+	if err != nil {
+		return err
+	}
+	defer stopNotifications()
+
 	return stack.Run(terragruntOptions)
 }
 
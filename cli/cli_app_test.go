@@ -5,6 +5,7 @@ import (
 
 	"github.com/gruntwork-io/terragrunt/config"
 	"github.com/gruntwork-io/terragrunt/options"
+	"github.com/gruntwork-io/terragrunt/remote"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -135,3 +136,19 @@ func TestTerragruntHandlesCatastrophicTerraformFailure(t *testing.T) {
 	err = runTerraformWithRetry(tgOptions)
 	require.Error(t, err)
 }
+
+func TestPrepareInitCommandWhenPrefetching(t *testing.T) {
+	t.Parallel()
+
+	tgOptions, err := options.NewTerragruntOptionsForTest("mock-path-for-test.hcl")
+	require.NoError(t, err)
+	tgOptions.TerraformCliArgs = []string{"init"}
+	tgOptions.PrefetchOnly = true
+
+	// The bucket is never checked or created, and no backend config is passed to terraform
+	terragruntConfig := &config.TerragruntConfig{
+		RemoteState: &remote.RemoteState{Backend: "s3", Config: map[string]interface{}{"bucket": "my-bucket", "key": "terraform.tfstate"}},
+	}
+	require.NoError(t, prepareInitCommand(tgOptions, terragruntConfig, false))
+	assert.Equal(t, []string{"init", "-backend=false"}, tgOptions.TerraformCliArgs)
+}
@@ -0,0 +1,224 @@
+package cli
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"sort"
+	"strings"
+
+	"github.com/gruntwork-io/terragrunt/errors"
+	"github.com/gruntwork-io/terragrunt/options"
+	"github.com/gruntwork-io/terragrunt/util"
+)
+
+// The command that prints the shell completion script for the given shell
+const CMD_TERRAGRUNT_COMPLETION = "completion"
+
+// The hidden command the completion scripts call to get the candidates for the word being completed
+const CMD_TERRAGRUNT_COMPLETE = "__complete"
+
+// The commands that are implemented by terragrunt itself, rather than passed through to terraform
+var TERRAGRUNT_COMMANDS = []string{
+	CMD_RUN_ALL,
+	CMD_TERRAGRUNT_INFO,
+	CMD_TERRAGRUNT_VALIDATE_INPUTS,
+	CMD_TERRAGRUNT_GRAPH_DEPENDENCIES,
+	CMD_GENERATE_ATLANTIS_CONFIG,
+	CMD_EXPORT_STACKS,
+	CMD_GENERATE_CATALOG_INFO,
+	CMD_EXPORT_INVENTORY,
+	CMD_TFLINT,
+	CMD_GENERATE_DOCS,
+	CMD_HCLFMT,
+	CMD_AWS_PROVIDER_PATCH,
+	CMD_BACKEND,
+	CMD_DAEMON,
+	CMD_TERRAGRUNT_COMPLETION,
+}
+
+// The terraform commands offered when completing the command name. The flags and arguments of these commands are
+// completed by terraform itself.
+var TERRAFORM_COMMANDS_FOR_COMPLETION = []string{
+	"apply",
+	"console",
+	"destroy",
+	"fmt",
+	"force-unlock",
+	"get",
+	"graph",
+	"import",
+	"init",
+	"login",
+	"logout",
+	"output",
+	"plan",
+	"providers",
+	"refresh",
+	"show",
+	"state",
+	"taint",
+	"untaint",
+	"validate",
+	"version",
+	"workspace",
+}
+
+var completionScripts = map[string]string{
+	"bash": `# terragrunt bash completion. To enable it, add the following to your ~/.bashrc:
+#
+#   source <(terragrunt completion bash)
+_terragrunt_completion() {
+  local IFS=$'\n'
+  COMPREPLY=($(terragrunt __complete "${COMP_WORDS[@]:1:COMP_CWORD}" 2>/dev/null))
+}
+complete -o default -F _terragrunt_completion terragrunt
+`,
+	"zsh": `#compdef terragrunt
+# terragrunt zsh completion. To enable it, add the following to your ~/.zshrc:
+#
+#   source <(terragrunt completion zsh)
+_terragrunt() {
+  local -a completions
+  completions=("${(@f)$(terragrunt __complete "${words[@]:1:$((CURRENT-1))}" 2>/dev/null)}")
+  if [[ -n "${completions[1]}" ]]; then
+    compadd -- "${completions[@]}"
+  else
+    _files
+  fi
+}
+compdef _terragrunt terragrunt
+`,
+	"fish": `# terragrunt fish completion. To enable it, run:
+#
+#   terragrunt completion fish > ~/.config/fish/completions/terragrunt.fish
+function __terragrunt_complete
+    set -l tokens (commandline -opc)
+    set -e tokens[1]
+    terragrunt __complete $tokens (commandline -ct) 2>/dev/null
+end
+complete -c terragrunt -a '(__terragrunt_complete)'
+`,
+}
+
+func isCompletionCommand(command string) bool {
+	return command == CMD_TERRAGRUNT_COMPLETION || command == CMD_TERRAGRUNT_COMPLETE
+}
+
+// runCompletionCommand handles the completion commands. These are handled before the terragrunt options are parsed,
+// as the words being completed are often not valid terragrunt arguments yet (e.g., a string flag missing its value).
+func runCompletionCommand(args []string, writer io.Writer) error {
+	if util.FirstArg(args) == CMD_TERRAGRUNT_COMPLETE {
+		terraformPath := os.Getenv("TERRAGRUNT_TFPATH")
+		if terraformPath == "" {
+			terraformPath = options.TERRAFORM_DEFAULT_PATH
+		}
+		for _, candidate := range completeWords(args[1:], terraformPath) {
+			fmt.Fprintln(writer, candidate)
+		}
+		return nil
+	}
+
+	shell := util.SecondArg(args)
+	script, supported := completionScripts[shell]
+	if !supported {
+		return errors.WithStackTrace(UnsupportedCompletionShell(shell))
+	}
+	_, err := fmt.Fprint(writer, script)
+	return errors.WithStackTrace(err)
+}
+
+// completeWords returns the completion candidates for the last of the given words, which are the words typed after
+// "terragrunt" so far. Terragrunt's own commands and flags are completed here, everything else is passed through to
+// terraform's completion.
+func completeWords(words []string, terraformPath string) []string {
+	if len(words) == 0 {
+		words = []string{""}
+	}
+	current := words[len(words)-1]
+	previous := words[:len(words)-1]
+
+	terraformWords := []string{}
+	isRunAll := false
+	for i := 0; i < len(previous); i++ {
+		word := previous[i]
+		flag := strings.TrimPrefix(word, "--")
+		switch {
+		case util.ListContainsElement(MULTI_MODULE_COMMANDS, word):
+			isRunAll = true
+		case util.ListContainsElement(ALL_TERRAGRUNT_STRING_OPTS, flag):
+			// The value of a string flag is being completed, which is best left to the shell's file completion
+			if i == len(previous)-1 {
+				return []string{}
+			}
+			i++
+		case util.ListContainsElement(ALL_TERRAGRUNT_BOOLEAN_OPTS, flag):
+		default:
+			terraformWords = append(terraformWords, word)
+		}
+	}
+
+	candidates := []string{}
+	if strings.HasPrefix(current, "-") {
+		for _, flag := range append(util.CloneStringList(ALL_TERRAGRUNT_BOOLEAN_OPTS), ALL_TERRAGRUNT_STRING_OPTS...) {
+			candidates = append(candidates, "--"+flag)
+		}
+	} else if len(terraformWords) == 0 {
+		for _, command := range TERRAFORM_COMMANDS_FOR_COMPLETION {
+			if _, disabled := runAllDisabledCommands[command]; !(isRunAll && disabled) {
+				candidates = append(candidates, command)
+			}
+		}
+		if !isRunAll {
+			candidates = append(candidates, TERRAGRUNT_COMMANDS...)
+		}
+	}
+
+	matches := []string{}
+	for _, candidate := range candidates {
+		if strings.HasPrefix(candidate, current) {
+			matches = append(matches, candidate)
+		}
+	}
+
+	if len(terraformWords) > 0 && !util.ListContainsElement(TERRAGRUNT_COMMANDS, terraformWords[0]) {
+		matches = append(matches, completeTerraformWords(append(terraformWords, current), terraformPath)...)
+	}
+
+	sort.Strings(matches)
+	return util.RemoveDuplicatesFromList(matches)
+}
+
+// completeTerraformWords asks terraform for the completion candidates of the given words. Terraform completes the
+// command line in the COMP_LINE env var when it's set, which is how its own -install-autocomplete works. Any errors
+// are ignored, as there is nothing useful to do with them while completing.
+func completeTerraformWords(words []string, terraformPath string) []string {
+	compLine := "terraform " + strings.Join(words, " ")
+
+	cmd := exec.Command(terraformPath)
+	cmd.Env = append(os.Environ(), "COMP_LINE="+compLine, fmt.Sprintf("COMP_POINT=%d", len(compLine)))
+	var stdout bytes.Buffer
+	cmd.Stdout = &stdout
+
+	if err := cmd.Run(); err != nil {
+		return []string{}
+	}
+
+	candidates := []string{}
+	for _, line := range strings.Split(stdout.String(), "\n") {
+		if candidate := strings.TrimSpace(line); candidate != "" {
+			candidates = append(candidates, candidate)
+		}
+	}
+	return candidates
+}
+
+// Custom error types
+
+type UnsupportedCompletionShell string
+
+func (shell UnsupportedCompletionShell) Error() string {
+	return fmt.Sprintf("Unsupported shell '%s' for the %s command. Supported shells are: bash, zsh, fish.", string(shell), CMD_TERRAGRUNT_COMPLETION)
+}
@@ -0,0 +1,77 @@
+package cli
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/gruntwork-io/terragrunt/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCompleteWords(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name     string
+		words    []string
+		expected []string
+	}{
+		{
+			"command names",
+			[]string{"va"},
+			[]string{"validate", "validate-inputs"},
+		},
+		{
+			"run-all excludes terragrunt commands and disabled terraform commands",
+			[]string{"run-all", "t"},
+			[]string{},
+		},
+		{
+			"run-all command names",
+			[]string{"run-all", "pl"},
+			[]string{"plan"},
+		},
+		{
+			"terragrunt flags",
+			[]string{"--terragrunt-no-"},
+			[]string{"--terragrunt-no-auto-init", "--terragrunt-no-auto-retry", "--terragrunt-no-config-cache", "--terragrunt-no-copy-lock-file", "--terragrunt-no-destroy-dependencies-check", "--terragrunt-no-output-prefix"},
+		},
+		{
+			"value of a string flag",
+			[]string{"--terragrunt-working-dir", ""},
+			[]string{},
+		},
+		{
+			"flags with values are skipped when finding the command",
+			[]string{"--terragrunt-working-dir", "/live", "--terragrunt-non-interactive", "hcl"},
+			[]string{"hclfmt"},
+		},
+	}
+
+	for _, testCase := range testCases {
+		// Capture range variable so that it is brought into the scope within the for loop, so that it is stable even
+		// when subtests are run in parallel.
+		testCase := testCase
+
+		t.Run(testCase.name, func(t *testing.T) {
+			t.Parallel()
+
+			actual := completeWords(testCase.words, "terraform-binary-that-does-not-exist")
+			assert.Equal(t, testCase.expected, actual)
+		})
+	}
+}
+
+func TestRunCompletionCommand(t *testing.T) {
+	t.Parallel()
+
+	for _, shell := range []string{"bash", "zsh", "fish"} {
+		var out bytes.Buffer
+		require.NoError(t, runCompletionCommand([]string{CMD_TERRAGRUNT_COMPLETION, shell}, &out))
+		assert.Contains(t, out.String(), "terragrunt __complete")
+	}
+
+	err := runCompletionCommand([]string{CMD_TERRAGRUNT_COMPLETION, "powershell"}, &bytes.Buffer{})
+	assert.True(t, errors.IsError(err, UnsupportedCompletionShell("powershell")))
+}
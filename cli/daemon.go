@@ -0,0 +1,197 @@
+package cli
+
+import (
+	"io"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"sync"
+	"syscall"
+
+	"github.com/urfave/cli"
+
+	"github.com/gruntwork-io/terragrunt/aws_helper"
+	"github.com/gruntwork-io/terragrunt/config"
+	"github.com/gruntwork-io/terragrunt/daemon"
+	"github.com/gruntwork-io/terragrunt/errors"
+	"github.com/gruntwork-io/terragrunt/options"
+	"github.com/gruntwork-io/terragrunt/remote"
+	"github.com/gruntwork-io/terragrunt/shell"
+	"github.com/gruntwork-io/terragrunt/util"
+)
+
+// The key in the app metadata under which the state of the daemon is stored, for the commands the daemon runs
+const METADATA_DAEMON = "daemon"
+
+// daemonState is what the daemon keeps warm between the commands it runs
+type daemonState struct {
+	// The parsed config files, which stay valid for as long as the files are unchanged
+	configFileCache *options.ConfigCache
+	// The env vars that point terraform at the provider cache server of the daemon, if it runs one
+	providerCacheEnv map[string]string
+}
+
+// Apply the state of the daemon to the options of a command it runs
+func (state *daemonState) applyTo(terragruntOptions *options.TerragruntOptions) {
+	terragruntOptions.PersistentConfigCache = state.configFileCache
+
+	// The commands use the provider cache server of the daemon, rather than starting their own
+	if state.providerCacheEnv != nil {
+		terragruntOptions.ProviderCache = false
+		delete(terragruntOptions.Env, terraformPluginCacheDirEnvVar)
+		for key, value := range state.providerCacheEnv {
+			terragruntOptions.Env[key] = value
+		}
+	}
+
+	// The stdin of the daemon is not the one of the CLI, so the commands it runs can't read from stdin
+	terragruntOptions.InputMode = options.INPUT_MODE_NONE
+}
+
+// Return the path of the unix socket of the daemon: the one set in the options, or else terragrunt/daemon.sock in the
+// user cache dir
+func daemonSocketPath(terragruntOptions *options.TerragruntOptions) (string, error) {
+	if terragruntOptions.DaemonSocket != "" {
+		return terragruntOptions.DaemonSocket, nil
+	}
+	userCacheDir, err := os.UserCacheDir()
+	if err != nil {
+		return "", errors.WithStackTrace(err)
+	}
+	return filepath.Join(userCacheDir, "terragrunt", "daemon.sock"), nil
+}
+
+// Run the Terragrunt daemon in the foreground until it's interrupted, running the commands the CLI sends it
+func runDaemon(cliContext *cli.Context, terragruntOptions *options.TerragruntOptions) error {
+	socketPath, err := daemonSocketPath(terragruntOptions)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(socketPath), 0700); err != nil {
+		return errors.WithStackTrace(err)
+	}
+
+	state := &daemonState{configFileCache: options.NewConfigCache()}
+	if terragruntOptions.ProviderCache {
+		stopProviderCacheServer, err := startProviderCacheServer(terragruntOptions)
+		if err != nil {
+			return err
+		}
+		defer stopProviderCacheServer()
+		state.providerCacheEnv = map[string]string{terraformCLIConfigFileEnvVar: terragruntOptions.Env[terraformCLIConfigFileEnvVar]}
+	}
+
+	// Prompts read straight from stdin, so make them fail rather than wait for input that never comes
+	devNull, err := os.Open(os.DevNull)
+	if err != nil {
+		return errors.WithStackTrace(err)
+	}
+	defer devNull.Close()
+	os.Stdin = devNull
+
+	run := func(request daemon.Request, stdout io.Writer, stderr io.Writer) (int, error) {
+		return runDaemonRequest(cliContext.App, state, request, stdout, stderr)
+	}
+	server := daemon.NewServer(socketPath, run, terragruntOptions.Logger)
+	if err := server.Start(); err != nil {
+		return err
+	}
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(signals)
+	<-signals
+
+	terragruntOptions.Logger.Infof("Shutting down the Terragrunt daemon once the running command, if any, finishes")
+	return server.Close()
+}
+
+// Run a command sent by the CLI the way the CLI would, but with the state of the daemon. The command runs in the
+// working dir and with the env vars of the CLI, which are set on the process for the duration of the command, which is
+// why the daemon runs one command at a time.
+func runDaemonRequest(parentApp *cli.App, state *daemonState, request daemon.Request, stdout io.Writer, stderr io.Writer) (int, error) {
+	originalWorkingDir, err := os.Getwd()
+	if err != nil {
+		return 1, errors.WithStackTrace(err)
+	}
+	originalEnv := os.Environ()
+	defer func() {
+		os.Chdir(originalWorkingDir)
+		setProcessEnv(originalEnv)
+	}()
+
+	if err := os.Chdir(request.WorkingDir); err != nil {
+		return 1, errors.WithStackTrace(err)
+	}
+	setProcessEnv(request.Env)
+
+	// The logs that aren't tied to the options of the command, e.g. about its args, belong to the CLI that sent it too
+	originalLogOutput := util.GlobalFallbackLogEntry.Logger.Out
+	util.GlobalFallbackLogEntry.Logger.SetOutput(stderr)
+	defer util.GlobalFallbackLogEntry.Logger.SetOutput(originalLogOutput)
+
+	clearRunCaches()
+
+	app := CreateTerragruntCli(parentApp.Version, stdout, stderr)
+	app.Metadata = map[string]interface{}{METADATA_DAEMON: state}
+	for key, value := range parentApp.Metadata {
+		app.Metadata[key] = value
+	}
+
+	err = app.Run(append([]string{app.Name}, request.Args...))
+	if err == nil {
+		return 0, nil
+	}
+	exitCode, exitCodeErr := shell.GetExitCode(err)
+	if exitCodeErr != nil || exitCode == 0 {
+		exitCode = 1
+	}
+	return exitCode, err
+}
+
+// Clear what's cached for the rest of a run, which the commands run by the daemon must not share, as each is a run of
+// its own, with its own env vars, credentials and flags, e.g. --terragrunt-source-update. Only the parsed config files,
+// which stay valid for as long as the files are unchanged, are kept between the commands.
+func clearRunCaches() {
+	config.ClearOutputCache()
+	config.ClearSopsCache()
+	config.ClearConfigWarnings()
+	remote.ClearRemoteStateChecks()
+	aws_helper.ClearSharedSessions()
+	updatedSourceCacheEntries = sync.Map{}
+	pruneSourceCacheOnce = sync.Once{}
+	removePolicyBundles()
+}
+
+// Replace the env vars of the process with the given ones, in the form key=value
+func setProcessEnv(env []string) {
+	os.Clearenv()
+	for _, keyValue := range env {
+		keyAndValue := strings.SplitN(keyValue, "=", 2)
+		if len(keyAndValue) == 2 {
+			os.Setenv(keyAndValue[0], keyAndValue[1])
+		}
+	}
+}
+
+// Send the command to the daemon, rather than running it here. Returns false if the daemon isn't running, in which
+// case the command should be run here instead.
+func runThroughDaemon(cliContext *cli.Context, terragruntOptions *options.TerragruntOptions) (bool, error) {
+	socketPath, err := daemonSocketPath(terragruntOptions)
+	if err != nil {
+		return false, err
+	}
+	workingDir, err := os.Getwd()
+	if err != nil {
+		return false, errors.WithStackTrace(err)
+	}
+
+	request := daemon.Request{Args: cliContext.Args(), WorkingDir: workingDir, Env: os.Environ()}
+	err = daemon.Run(socketPath, request, cliContext.App.Writer, cliContext.App.ErrWriter)
+	if _, notRunning := errors.Unwrap(err).(daemon.DaemonNotRunning); notRunning {
+		terragruntOptions.Logger.Warnf("%v. Running the command without the daemon.", err)
+		return false, nil
+	}
+	return true, err
+}
@@ -0,0 +1,38 @@
+package cli
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/gruntwork-io/terragrunt/daemon"
+	"github.com/gruntwork-io/terragrunt/options"
+)
+
+// Not parallel, as the daemon changes the working dir, the env vars and the caches of the whole process
+func TestRunDaemonRequestLogsToRequestStderrAndClearsRunCaches(t *testing.T) {
+	updatedSourceCacheEntries.Store("example", true)
+	policyBundles.Store("example", &policyBundle{})
+
+	workingDir, err := ioutil.TempDir("", "daemon-request")
+	require.NoError(t, err)
+	defer os.RemoveAll(workingDir)
+
+	var stdout, stderr bytes.Buffer
+	state := &daemonState{configFileCache: options.NewConfigCache()}
+	request := daemon.Request{Args: []string{"plan", "--terragrunt-log-level", "bogus"}, WorkingDir: workingDir, Env: os.Environ()}
+	exitCode, err := runDaemonRequest(CreateTerragruntCli("test", ioutil.Discard, ioutil.Discard), state, request, &stdout, &stderr)
+
+	assert.Error(t, err)
+	assert.Equal(t, 1, exitCode)
+	assert.Contains(t, stderr.String(), "not a valid logrus Level")
+
+	_, isUpdated := updatedSourceCacheEntries.Load("example")
+	assert.False(t, isUpdated)
+	_, isDownloaded := policyBundles.Load("example")
+	assert.False(t, isDownloaded)
+}
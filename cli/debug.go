@@ -6,6 +6,8 @@ import (
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"regexp"
+	"sort"
 	"strings"
 
 	"github.com/hashicorp/terraform-config-inspect/tfconfig"
@@ -48,16 +50,83 @@ func writeTerragruntDebugFile(terragruntOptions *options.TerragruntOptions, terr
 	}
 
 	terragruntOptions.Logger.Debugf("Variables passed to terraform are located in \"%s\"", fileName)
-	terragruntOptions.Logger.Debugf("Run this command to replicate how terraform was invoked:")
-	terragruntOptions.Logger.Debugf(
-		"\tterraform %s -var-file=\"%s\" \"%s\"",
-		strings.Join(terragruntOptions.TerraformCliArgs, " "),
-		fileName,
-		terragruntOptions.WorkingDir,
-	)
 	return nil
 }
 
+// logTerraformCommandForDebug logs the exact terraform command, including the working dir and the env vars set by
+// terragrunt, that can be used to replicate how terragrunt is about to invoke terraform, using the debug tfvars file
+// instead of the TF_VAR_xxx env vars for the inputs. This should be called right before running terraform, once all
+// the extra_arguments have been added.
+func logTerraformCommandForDebug(terragruntOptions *options.TerragruntOptions, terragruntConfig *config.TerragruntConfig) {
+	terragruntOptions.Logger.Debugf("Run this command to replicate how terraform was invoked:")
+	terragruntOptions.Logger.Debugf("\t%s", terraformCommandForDebug(terragruntOptions, terragruntConfig, os.Environ()))
+}
+
+// terraformCommandForDebug returns a shell command that replicates the terraform invocation. Only the env vars that
+// differ from the given environment of the terragrunt process are included, as the rest will already be set when the
+// user runs the command from the same shell. The values of env vars that look like secrets are redacted.
+func terraformCommandForDebug(terragruntOptions *options.TerragruntOptions, terragruntConfig *config.TerragruntConfig, processEnv []string) string {
+	parts := []string{"cd", shellQuote(terragruntOptions.WorkingDir), "&&"}
+
+	originalEnv := parseEnvironmentVariables(processEnv)
+	envVarNames := []string{}
+	for name, value := range terragruntOptions.Env {
+		if originalValue, isSet := originalEnv[name]; isSet && originalValue == value {
+			continue
+		}
+		// The inputs are passed in via the debug tfvars file instead
+		if strings.HasPrefix(name, TFVarPrefix+"_") {
+			if _, isInput := terragruntConfig.Inputs[strings.TrimPrefix(name, TFVarPrefix+"_")]; isInput {
+				continue
+			}
+		}
+		envVarNames = append(envVarNames, name)
+	}
+	sort.Strings(envVarNames)
+	for _, name := range envVarNames {
+		value := terragruntOptions.Env[name]
+		if isSensitiveEnvVar(name) {
+			value = "<REDACTED>"
+		}
+		parts = append(parts, fmt.Sprintf("%s=%s", name, shellQuote(value)))
+	}
+
+	args := util.CloneStringList(terragruntOptions.TerraformCliArgs)
+	if len(terragruntConfig.Inputs) > 0 && util.ListContainsElement(config.TERRAFORM_COMMANDS_NEED_VARS, util.FirstArg(args)) {
+		varFile := filepath.Join(filepath.Dir(terragruntOptions.TerragruntConfigPath), TerragruntTFVarsFile)
+		args = append([]string{args[0], fmt.Sprintf("-var-file=%s", varFile)}, args[1:]...)
+	}
+
+	parts = append(parts, shellQuote(terragruntOptions.TerraformPath))
+	for _, arg := range args {
+		parts = append(parts, shellQuote(arg))
+	}
+	return strings.Join(parts, " ")
+}
+
+var sensitiveEnvVarMarkers = []string{"SECRET", "TOKEN", "PASSWORD", "CREDENTIALS"}
+
+// isSensitiveEnvVar returns true if the env var with the given name is likely to contain a secret
+func isSensitiveEnvVar(name string) bool {
+	upperName := strings.ToUpper(name)
+	for _, marker := range sensitiveEnvVarMarkers {
+		if strings.Contains(upperName, marker) {
+			return true
+		}
+	}
+	return false
+}
+
+// shellQuote quotes the given string for use in a POSIX shell, if necessary
+func shellQuote(value string) string {
+	if value != "" && shellSafeRegexp.MatchString(value) {
+		return value
+	}
+	return "'" + strings.Replace(value, "'", `'"'"'`, -1) + "'"
+}
+
+var shellSafeRegexp = regexp.MustCompile(`^[A-Za-z0-9_@%+=:,./-]+$`)
+
 // terragruntDebugFileContents will return a tfvars file in json format of all the terragrunt rendered variables values
 // that should be set to invoke the terraform module in the same way as terragrunt. Note that this will only include the
 // values of variables that are actually defined in the module.
@@ -0,0 +1,44 @@
+package cli
+
+import (
+	"testing"
+
+	"github.com/gruntwork-io/terragrunt/config"
+	"github.com/gruntwork-io/terragrunt/options"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTerraformCommandForDebug(t *testing.T) {
+	t.Parallel()
+
+	terragruntOptions, err := options.NewTerragruntOptionsForTest("/live/app/terragrunt.hcl")
+	require.NoError(t, err)
+	terragruntOptions.WorkingDir = "/live/app/.terragrunt-cache/abc/app"
+	terragruntOptions.TerraformCliArgs = []string{"plan", "-lock-timeout=20m", "-out", "my plan"}
+	terragruntOptions.Env = map[string]string{
+		"HOME":                  "/home/me",
+		"TF_VAR_name":           "app",
+		"TF_VAR_extra":          "from env",
+		"AWS_REGION":            "us-east-1",
+		"AWS_SECRET_ACCESS_KEY": "super-secret",
+	}
+	terragruntConfig := &config.TerragruntConfig{Inputs: map[string]interface{}{"name": "app"}}
+
+	actual := terraformCommandForDebug(terragruntOptions, terragruntConfig, []string{"HOME=/home/me", "TF_VAR_extra=from env"})
+	expected := "cd /live/app/.terragrunt-cache/abc/app && AWS_REGION=us-east-1 AWS_SECRET_ACCESS_KEY='<REDACTED>' terraform plan -var-file=/live/app/terragrunt-debug.tfvars.json -lock-timeout=20m -out 'my plan'"
+	assert.Equal(t, expected, actual)
+}
+
+func TestTerraformCommandForDebugNoVarFileForCommandsWithoutVars(t *testing.T) {
+	t.Parallel()
+
+	terragruntOptions, err := options.NewTerragruntOptionsForTest("/live/app/terragrunt.hcl")
+	require.NoError(t, err)
+	terragruntOptions.WorkingDir = "/live/app"
+	terragruntOptions.TerraformCliArgs = []string{"output", "-json"}
+	terragruntConfig := &config.TerragruntConfig{Inputs: map[string]interface{}{"name": "app"}}
+
+	actual := terraformCommandForDebug(terragruntOptions, terragruntConfig, []string{})
+	assert.Equal(t, "cd /live/app && terraform output -json", actual)
+}
@@ -0,0 +1,125 @@
+package cli
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/gruntwork-io/terragrunt/config"
+	"github.com/gruntwork-io/terragrunt/errors"
+	"github.com/gruntwork-io/terragrunt/options"
+	"github.com/gruntwork-io/terragrunt/shell"
+	"github.com/gruntwork-io/terragrunt/util"
+)
+
+// checkDestroyDependents looks for other modules whose dependency or dependencies blocks point to the module about to
+// be destroyed, as destroying it would leave them orphaned. If there are any, the user has to confirm the destroy,
+// and in non-interactive mode, it is refused outright.
+func checkDestroyDependents(terragruntOptions *options.TerragruntOptions) error {
+	if util.FirstArg(terragruntOptions.TerraformCliArgs) != "destroy" || terragruntOptions.NoDestroyDependenciesCheck {
+		return nil
+	}
+
+	modulePath := filepath.Dir(terragruntOptions.TerragruntConfigPath)
+	searchPath := dependentsSearchPath(terragruntOptions, modulePath)
+	terragruntOptions.Logger.Debugf("Looking for modules in %s that depend on %s before destroying it", searchPath, modulePath)
+
+	dependents, err := findDependentModules(terragruntOptions, modulePath, searchPath)
+	if err != nil {
+		return err
+	}
+	if len(dependents) == 0 {
+		return nil
+	}
+
+	if terragruntOptions.NonInteractive {
+		return errors.WithStackTrace(ModuleHasDependents{ModulePath: modulePath, Dependents: dependents})
+	}
+
+	prompt := fmt.Sprintf("WARNING: The following modules depend on %s and will be left orphaned if it's destroyed:\n  %s\nAre you sure you want to run `terragrunt destroy`?", modulePath, strings.Join(dependents, "\n  "))
+	shouldDestroy, err := shell.PromptUserForYesNo(prompt, terragruntOptions)
+	if err != nil {
+		return err
+	}
+	if !shouldDestroy {
+		return errors.WithStackTrace(ModuleHasDependents{ModulePath: modulePath, Dependents: dependents})
+	}
+	return nil
+}
+
+// dependentsSearchPath returns the folder in which to look for the dependents of the module at the given path: the
+// folder passed in with --terragrunt-destroy-dependents-search-dir, or the parent folder of the module. Every
+// configuration in that folder is partially parsed, so it defaults to the siblings of the module, which are where the
+// dependents usually are, rather than to the whole repo.
+func dependentsSearchPath(terragruntOptions *options.TerragruntOptions, modulePath string) string {
+	if terragruntOptions.DestroyDependentsSearchDir != "" {
+		return terragruntOptions.DestroyDependentsSearchDir
+	}
+	return filepath.ToSlash(filepath.Dir(modulePath))
+}
+
+// findDependentModules returns the paths of the modules under searchPath whose dependency or dependencies blocks point
+// to the module at modulePath. Modules whose configuration can't be parsed are skipped, as they should not prevent
+// destroying an unrelated module.
+func findDependentModules(terragruntOptions *options.TerragruntOptions, modulePath string, searchPath string) ([]string, error) {
+	canonicalModulePath, err := util.CanonicalPath(modulePath, "")
+	if err != nil {
+		return nil, err
+	}
+
+	terragruntConfigPaths, err := config.FindConfigFilesInPath(searchPath, terragruntOptions)
+	if err != nil {
+		return nil, err
+	}
+
+	dependents := []string{}
+	for _, terragruntConfigPath := range terragruntConfigPaths {
+		otherModulePath, err := util.CanonicalPath(filepath.Dir(terragruntConfigPath), "")
+		if err != nil {
+			return nil, err
+		}
+		if otherModulePath == canonicalModulePath {
+			continue
+		}
+
+		opts := terragruntOptions.Clone(terragruntConfigPath)
+		opts.OriginalTerragruntConfigPath = terragruntConfigPath
+		terragruntConfig, err := config.PartialParseConfigFile(
+			terragruntConfigPath,
+			opts,
+			nil,
+			[]config.PartialDecodeSectionType{config.DependenciesBlock, config.DependencyBlock},
+		)
+		if err != nil {
+			terragruntOptions.Logger.Debugf("Skipping %s while looking for dependents, as its configuration could not be parsed: %v", terragruntConfigPath, err)
+			continue
+		}
+		if terragruntConfig.Dependencies == nil {
+			continue
+		}
+
+		for _, dependencyPath := range terragruntConfig.Dependencies.Paths {
+			canonicalDependencyPath, err := util.CanonicalPath(dependencyPath, otherModulePath)
+			if err != nil {
+				return nil, err
+			}
+			if canonicalDependencyPath == canonicalModulePath {
+				dependents = append(dependents, otherModulePath)
+				break
+			}
+		}
+	}
+
+	return dependents, nil
+}
+
+// Custom error types
+
+type ModuleHasDependents struct {
+	ModulePath string
+	Dependents []string
+}
+
+func (err ModuleHasDependents) Error() string {
+	return fmt.Sprintf("Not destroying %s, as the following modules depend on it: %s. Destroy them first, or pass --%s to skip this check.", err.ModulePath, strings.Join(err.Dependents, ", "), OPT_TERRAGRUNT_NO_DESTROY_DEPENDENCIES_CHECK)
+}
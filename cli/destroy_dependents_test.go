@@ -0,0 +1,92 @@
+package cli
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/gruntwork-io/terragrunt/config"
+	"github.com/gruntwork-io/terragrunt/errors"
+	"github.com/gruntwork-io/terragrunt/options"
+	"github.com/gruntwork-io/terragrunt/util"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func createModulesForDestroyDependentsTest(t *testing.T) string {
+	rootPath, err := ioutil.TempDir("", "destroy-dependents")
+	require.NoError(t, err)
+	rootPath, err = util.CanonicalPath(rootPath, "")
+	require.NoError(t, err)
+
+	modules := map[string]string{
+		"vpc":      "",
+		"app":      "dependency \"vpc\" {\n  config_path = \"../vpc\"\n}\n",
+		"database": "dependencies {\n  paths = [\"../vpc\"]\n}\n",
+		"other":    "",
+	}
+	for name, contents := range modules {
+		modulePath := filepath.Join(rootPath, name)
+		require.NoError(t, os.MkdirAll(modulePath, os.ModePerm))
+		require.NoError(t, ioutil.WriteFile(filepath.Join(modulePath, config.DefaultTerragruntConfigPath), []byte(contents), 0644))
+	}
+	return rootPath
+}
+
+func TestFindDependentModules(t *testing.T) {
+	t.Parallel()
+
+	rootPath := createModulesForDestroyDependentsTest(t)
+	defer os.RemoveAll(rootPath)
+
+	terragruntOptions, err := options.NewTerragruntOptionsForTest(util.JoinPath(rootPath, "vpc", config.DefaultTerragruntConfigPath))
+	require.NoError(t, err)
+
+	dependents, err := findDependentModules(terragruntOptions, util.JoinPath(rootPath, "vpc"), rootPath)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{util.JoinPath(rootPath, "app"), util.JoinPath(rootPath, "database")}, dependents)
+
+	dependents, err = findDependentModules(terragruntOptions, util.JoinPath(rootPath, "app"), rootPath)
+	require.NoError(t, err)
+	assert.Empty(t, dependents)
+}
+
+func TestCheckDestroyDependents(t *testing.T) {
+	t.Parallel()
+
+	rootPath := createModulesForDestroyDependentsTest(t)
+	defer os.RemoveAll(rootPath)
+
+	terragruntOptions, err := options.NewTerragruntOptionsForTest(util.JoinPath(rootPath, "vpc", config.DefaultTerragruntConfigPath))
+	require.NoError(t, err)
+	terragruntOptions.TerraformCliArgs = []string{"destroy"}
+
+	// NewTerragruntOptionsForTest runs in non-interactive mode, so the destroy is refused without prompting
+	err = checkDestroyDependents(terragruntOptions)
+	_, isModuleHasDependents := errors.Unwrap(err).(ModuleHasDependents)
+	assert.True(t, isModuleHasDependents, "Unexpected error: %v", err)
+
+	terragruntOptions.NoDestroyDependenciesCheck = true
+	assert.NoError(t, checkDestroyDependents(terragruntOptions))
+
+	terragruntOptions.NoDestroyDependenciesCheck = false
+	terragruntOptions.TerraformCliArgs = []string{"plan"}
+	assert.NoError(t, checkDestroyDependents(terragruntOptions))
+}
+
+func TestCheckDestroyDependentsOnlySearchesTheSearchDir(t *testing.T) {
+	t.Parallel()
+
+	rootPath := createModulesForDestroyDependentsTest(t)
+	defer os.RemoveAll(rootPath)
+
+	terragruntOptions, err := options.NewTerragruntOptionsForTest(util.JoinPath(rootPath, "vpc", config.DefaultTerragruntConfigPath))
+	require.NoError(t, err)
+	terragruntOptions.TerraformCliArgs = []string{"destroy"}
+	assert.Equal(t, rootPath, dependentsSearchPath(terragruntOptions, util.JoinPath(rootPath, "vpc")))
+
+	// The dependents of vpc are outside of the search dir, so they aren't found
+	terragruntOptions.DestroyDependentsSearchDir = util.JoinPath(rootPath, "other")
+	assert.NoError(t, checkDestroyDependents(terragruntOptions))
+}
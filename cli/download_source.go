@@ -2,15 +2,22 @@ package cli
 
 import (
 	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
 	"os"
 	"path/filepath"
+	"regexp"
 
 	"github.com/hashicorp/go-getter"
 
+	"github.com/gruntwork-io/terragrunt/aws_helper"
 	"github.com/gruntwork-io/terragrunt/cli/tfsource"
 	"github.com/gruntwork-io/terragrunt/config"
 	"github.com/gruntwork-io/terragrunt/errors"
+	"github.com/gruntwork-io/terragrunt/oci"
 	"github.com/gruntwork-io/terragrunt/options"
+	"github.com/gruntwork-io/terragrunt/tfr"
 	"github.com/gruntwork-io/terragrunt/util"
 )
 
@@ -34,7 +41,9 @@ func downloadTerraformSource(source string, terragruntOptions *options.Terragrun
 	}
 
 	terragruntOptions.Logger.Debugf("Copying files from %s into %s", terragruntOptions.WorkingDir, terraformSource.WorkingDir)
-	if err := util.CopyFolderContents(terragruntOptions.WorkingDir, terraformSource.WorkingDir, MODULE_MANIFEST_NAME); err != nil {
+	if err := util.CopyFolderContentsWithFilter(terragruntOptions.WorkingDir, terraformSource.WorkingDir, MODULE_MANIFEST_NAME, func(path string) bool {
+		return shouldCopyIntoWorkingDir(path, terragruntOptions)
+	}); err != nil {
 		return nil, err
 	}
 
@@ -42,10 +51,21 @@ func downloadTerraformSource(source string, terragruntOptions *options.Terragrun
 
 	terragruntOptions.Logger.Debugf("Setting working directory to %s", terraformSource.WorkingDir)
 	updatedTerragruntOptions.WorkingDir = terraformSource.WorkingDir
+	updatedTerragruntOptions.SourceDownloadDir = terraformSource.DownloadDir
 
 	return updatedTerragruntOptions, nil
 }
 
+// Returns true if the given file in the working dir of the user should be copied into the folder the Terraform code is
+// downloaded into. The lock file is copied along with the other files, so that terraform uses the providers it locks,
+// unless copying the lock file is disabled, in which case terraform manages the one in the download folder on its own.
+func shouldCopyIntoWorkingDir(path string, terragruntOptions *options.TerragruntOptions) bool {
+	if filepath.Base(path) == util.TerraformLockFile && !terragruntOptions.CopyLockFile {
+		return false
+	}
+	return !util.TerragruntExcludes(path)
+}
+
 // Download the specified TerraformSource if the latest code hasn't already been downloaded.
 func downloadTerraformSourceIfNecessary(terraformSource *tfsource.TerraformSource, terragruntOptions *options.TerragruntOptions, terragruntConfig *config.TerragruntConfig) error {
 	if terragruntOptions.SourceUpdate {
@@ -55,6 +75,20 @@ func downloadTerraformSourceIfNecessary(terraformSource *tfsource.TerraformSourc
 		}
 	}
 
+	// When downloading source, we need to process any hooks waiting on `init-from-module`. Therefore, we clone the
+	// options struct, set the command to the value the hooks are expecting, and run the download action surrounded by
+	// before and after hooks (if any).
+	terragruntOptionsForDownload := terragruntOptions.Clone(terragruntOptions.TerragruntConfigPath)
+	terragruntOptionsForDownload.TerraformCommand = CMD_INIT_FROM_MODULE
+
+	// Symlinks always point to the latest code, so there is no version to compare, and they're cheap to create, so
+	// they're simply created again on every run.
+	if terragruntOptions.SymlinkLocalSource && tfsource.IsLocalSource(terraformSource.CanonicalSourceURL) {
+		return runActionWithHooks("link source", terragruntOptionsForDownload, terragruntConfig, func() error {
+			return linkSource(terraformSource, terragruntOptions)
+		})
+	}
+
 	alreadyLatest, err := alreadyHaveLatestCode(terraformSource, terragruntOptions)
 	if err != nil {
 		return err
@@ -65,11 +99,6 @@ func downloadTerraformSourceIfNecessary(terraformSource *tfsource.TerraformSourc
 		return nil
 	}
 
-	// When downloading source, we need to process any hooks waiting on `init-from-module`. Therefore, we clone the
-	// options struct, set the command to the value the hooks are expecting, and run the download action surrounded by
-	// before and after hooks (if any).
-	terragruntOptionsForDownload := terragruntOptions.Clone(terragruntOptions.TerragruntConfigPath)
-	terragruntOptionsForDownload.TerraformCommand = CMD_INIT_FROM_MODULE
 	downloadErr := runActionWithHooks("download source", terragruntOptionsForDownload, terragruntConfig, func() error {
 		return downloadSource(terraformSource, terragruntOptions, terragruntConfig)
 	})
@@ -87,11 +116,11 @@ func downloadTerraformSourceIfNecessary(terraformSource *tfsource.TerraformSourc
 
 // Returns true if the specified TerraformSource, of the exact same version, has already been downloaded into the
 // DownloadFolder. This helps avoid downloading the same code multiple times. Note that if the TerraformSource points
-// to a local file path, we assume the user is doing local development and always return false to ensure the latest
-// code is downloaded (or rather, copied) every single time. See the ProcessTerraformSource method for more info.
+// to a local file path, the version is a hash of the contents of the folder, so the code is copied again whenever it
+// changes, e.g. during local development, but otherwise the copy, and the .terraform folder in it, is reused. See the
+// ProcessTerraformSource method for more info.
 func alreadyHaveLatestCode(terraformSource *tfsource.TerraformSource, terragruntOptions *options.TerragruntOptions) (bool, error) {
-	if tfsource.IsLocalSource(terraformSource.CanonicalSourceURL) ||
-		!util.FileExists(terraformSource.DownloadDir) ||
+	if !util.FileExists(terraformSource.DownloadDir) ||
 		!util.FileExists(terraformSource.WorkingDir) ||
 		!util.FileExists(terraformSource.VersionFile) {
 
@@ -108,7 +137,10 @@ func alreadyHaveLatestCode(terraformSource *tfsource.TerraformSource, terragrunt
 		return false, nil
 	}
 
-	currentVersion := terraformSource.EncodeSourceVersion()
+	currentVersion, err := terraformSource.EncodeSourceVersion()
+	if err != nil {
+		return false, err
+	}
 	previousVersion, err := readVersionFile(terraformSource)
 
 	if err != nil {
@@ -125,32 +157,190 @@ func readVersionFile(terraformSource *tfsource.TerraformSource) (string, error)
 	return util.ReadFileAsString(terraformSource.VersionFile)
 }
 
-// We use this code to force go-getter to copy files instead of creating symlinks.
-var copyFiles = func(client *getter.Client) error {
-
-	// We copy all the default getters from the go-getter library, but replace the "file" getter. We shallow clone the
-	// getter map here rather than using getter.Getters directly because (a) we shouldn't change the original,
-	// globally-shared getter.Getters map and (b) Terragrunt may run this code from many goroutines concurrently during
-	// xxx-all calls, so creating a new map each time ensures we don't a "concurrent map writes" error.
-	client.Getters = map[string]getter.Getter{}
-	for getterName, getterValue := range getter.Getters {
-		if getterName == "file" {
-			client.Getters[getterName] = &FileCopyGetter{}
-		} else {
-			client.Getters[getterName] = getterValue
+// We use this code to force go-getter to copy files instead of creating symlinks. The tfr:// getter looks up the tokens of
+// the registries in the env of the given options, e.g. in the CLI config of the registry_credentials blocks.
+func copyFiles(terragruntOptions *options.TerragruntOptions) getter.ClientOption {
+	return func(client *getter.Client) error {
+		// We copy all the default getters from the go-getter library, but replace the "file" getter. We shallow clone the
+		// getter map here rather than using getter.Getters directly because (a) we shouldn't change the original,
+		// globally-shared getter.Getters map and (b) Terragrunt may run this code from many goroutines concurrently during
+		// xxx-all calls, so creating a new map each time ensures we don't a "concurrent map writes" error.
+		client.Getters = map[string]getter.Getter{}
+		for getterName, getterValue := range getter.Getters {
+			if getterName == "file" {
+				client.Getters[getterName] = &FileCopyGetter{}
+			} else if getterName == "git" {
+				client.Getters[getterName] = &GitGetter{}
+			} else {
+				client.Getters[getterName] = getterValue
+			}
+		}
+		// go-getter doesn't support OCI artifacts, so modules can also be pulled from container registries
+		client.Getters[oci.SCHEME] = &oci.Getter{}
+		// nor the module registry protocol, so modules can also be downloaded from public and private registries
+		client.Getters[tfr.SCHEME] = &tfr.Getter{Env: terragruntOptions.Env}
+
+		return nil
+	}
+}
+
+// Return the go-getter option that sends the --terragrunt-source-mirror-header headers along with the requests of the
+// download of the given source URL, if it points at one of the --terragrunt-source-mirror mirrors, so that the headers,
+// which usually hold a token of the mirror, are never sent to other servers
+func withSourceMirrorHeaders(sourceURL string, terragruntOptions *options.TerragruntOptions) getter.ClientOption {
+	return func(client *getter.Client) error {
+		if len(terragruntOptions.SourceMirrorHeaders) == 0 || !config.IsSourceMirrorUrl(terragruntOptions.SourceMirrors, sourceURL) {
+			return nil
+		}
+		header := http.Header{}
+		for name, value := range terragruntOptions.SourceMirrorHeaders {
+			header.Set(name, value)
 		}
+		httpGetter := &getter.HttpGetter{Netrc: true, Header: header}
+		client.Getters["http"] = httpGetter
+		client.Getters["https"] = httpGetter
+		return nil
 	}
+}
 
-	return nil
+// Return the go-getter option that signs the requests of the HTTP download of the given source URL with AWS SigV4 for
+// the --terragrunt-source-sigv4-service service, using the AWS credentials of the module, for sources and mirrors behind
+// an API Gateway with IAM authorization and the like. Only the downloads from the --terragrunt-source-mirror mirrors and
+// from the endpoints of the service are signed, so that the signatures are never sent to other servers.
+func withSourceSigV4Signing(sourceURL string, terragruntOptions *options.TerragruntOptions) getter.ClientOption {
+	return func(client *getter.Client) error {
+		if terragruntOptions.SourceSigV4Service == "" {
+			return nil
+		}
+		if !config.IsSourceMirrorUrl(terragruntOptions.SourceMirrors, sourceURL) && aws_helper.ServiceEndpointRegion(terragruntOptions.SourceSigV4Service, sourceHost(sourceURL)) == "" {
+			return nil
+		}
+		transport, err := aws_helper.NewSigV4Transport(terragruntOptions.SourceSigV4Service, "", terragruntOptions)
+		if err != nil {
+			return err
+		}
+		httpGetter := &getter.HttpGetter{Netrc: true, Client: &http.Client{Transport: transport}}
+		// Keep the headers of the source mirror, if any
+		if mirrorGetter, isHttpGetter := client.Getters["https"].(*getter.HttpGetter); isHttpGetter {
+			httpGetter.Header = mirrorGetter.Header
+		}
+		client.Getters["http"] = httpGetter
+		client.Getters["https"] = httpGetter
+		return nil
+	}
+}
+
+// Return the host of the given source URL, without its forced getter, e.g. mirror.acme.internal for
+// http::https://mirror.acme.internal/vpc.zip, or an empty string if it's not a URL
+func sourceHost(sourceURL string) string {
+	parsedURL, err := url.Parse(forcedGetterPrefixRegexp.ReplaceAllString(sourceURL, ""))
+	if err != nil {
+		return ""
+	}
+	return parsedURL.Hostname()
 }
 
-// Download the code from the Canonical Source URL into the Download Folder using the go-getter library
+var forcedGetterPrefixRegexp = regexp.MustCompile(`^([A-Za-z0-9]+::)+`)
+
+// Download the code from the Canonical Source URL into the Download Folder using the go-getter library, through the
+// source cache if the terragrunt-source-cache flag is set and the source is not a local folder
 func downloadSource(terraformSource *tfsource.TerraformSource, terragruntOptions *options.TerragruntOptions, terragruntConfig *config.TerragruntConfig) error {
-	terragruntOptions.Logger.Debugf("Downloading Terraform configurations from %s into %s", terraformSource.CanonicalSourceURL, terraformSource.DownloadDir)
+	if terragruntOptions.SourceCache && !tfsource.IsLocalSource(terraformSource.CanonicalSourceURL) {
+		return downloadSourceThroughCache(terraformSource, terragruntOptions)
+	}
 
-	if err := getter.GetAny(terraformSource.DownloadDir, terraformSource.CanonicalSourceURL.String(), copyFiles); err != nil {
+	sourceURL, err := sourceDownloadUrl(terraformSource, terragruntOptions)
+	if err != nil {
+		return err
+	}
+	terragruntOptions.Logger.Debugf("Downloading Terraform configurations from %s into %s", sourceURL, terraformSource.DownloadDir)
+
+	if err := getter.GetAny(terraformSource.DownloadDir, sourceURL, copyFiles(terragruntOptions), withSourceMirrorHeaders(sourceURL, terragruntOptions), withSourceSigV4Signing(sourceURL, terragruntOptions)); err != nil {
 		return errors.WithStackTrace(err)
 	}
 
 	return nil
 }
+
+// Create symlinks in the Download Folder to the files and folders of the local folder the Canonical Source URL points
+// to, rather than copying them. Only the folders leading to the Working Dir are created in the Download Folder, as
+// Terragrunt copies the files of the Terragrunt configuration folder into the Working Dir and Terraform creates the
+// .terraform folder in it, neither of which should end up in the local source.
+func linkSource(terraformSource *tfsource.TerraformSource, terragruntOptions *options.TerragruntOptions) error {
+	sourceFolder := terraformSource.CanonicalSourceURL.Path
+	terragruntOptions.Logger.Debugf("Linking Terraform configurations from %s into %s", sourceFolder, terraformSource.DownloadDir)
+
+	// The version file of a previous copy of the source no longer describes the contents of the Download Folder
+	if err := os.Remove(terraformSource.VersionFile); err != nil && !os.IsNotExist(err) {
+		return errors.WithStackTrace(err)
+	}
+
+	// The files of the Terragrunt configuration folder are copied into the Working Dir afterwards, so the files and
+	// folders of the module with the same names are not linked, as otherwise the copy would write through the symlinks
+	// into the local source.
+	terragruntFiles, err := filepath.Glob(fmt.Sprintf("%s/*", terragruntOptions.WorkingDir))
+	if err != nil {
+		return errors.WithStackTrace(err)
+	}
+	skipInWorkingDir := map[string]bool{}
+	for _, file := range terragruntFiles {
+		skipInWorkingDir[filepath.Base(file)] = true
+	}
+
+	return linkFolderContents(sourceFolder, terraformSource.DownloadDir, terraformSource.WorkingDir, skipInWorkingDir)
+}
+
+// Create a symlink in destFolder to each file and folder in sourceFolder, except for the folders leading to workingDir,
+// which are created as folders, with their contents linked in the same way. Like util.CopyFolderContents, this skips
+// hidden files and folders (see util.TerragruntExcludes).
+func linkFolderContents(sourceFolder string, destFolder string, workingDir string, skipInWorkingDir map[string]bool) error {
+	if err := os.MkdirAll(destFolder, 0700); err != nil {
+		return errors.WithStackTrace(err)
+	}
+
+	// Remove the symlinks created by a previous run, as the files and folders they point to may have been removed or
+	// renamed since.
+	existingFiles, err := ioutil.ReadDir(destFolder)
+	if err != nil {
+		return errors.WithStackTrace(err)
+	}
+	for _, existingFile := range existingFiles {
+		if existingFile.Mode()&os.ModeSymlink != 0 {
+			if err := os.Remove(filepath.Join(destFolder, existingFile.Name())); err != nil {
+				return errors.WithStackTrace(err)
+			}
+		}
+	}
+
+	files, err := filepath.Glob(fmt.Sprintf("%s/*", sourceFolder))
+	if err != nil {
+		return errors.WithStackTrace(err)
+	}
+
+	isWorkingDir := util.CleanPath(destFolder) == util.CleanPath(workingDir)
+
+	for _, file := range files {
+		name := filepath.Base(file)
+		if util.TerragruntExcludes(name) || (isWorkingDir && skipInWorkingDir[name]) {
+			continue
+		}
+
+		destPath := filepath.Join(destFolder, name)
+		if util.IsDir(file) && util.HasPathPrefix(workingDir, destPath) {
+			if err := linkFolderContents(file, destPath, workingDir, skipInWorkingDir); err != nil {
+				return err
+			}
+			continue
+		}
+
+		// Anything left at this path was copied there by a run without symlinks
+		if err := os.RemoveAll(destPath); err != nil {
+			return errors.WithStackTrace(err)
+		}
+		if err := os.Symlink(file, destPath); err != nil {
+			return errors.WithStackTrace(err)
+		}
+	}
+
+	return nil
+}
@@ -1,8 +1,13 @@
 package cli
 
 import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
 	"fmt"
 	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
 	"net/url"
 	"os"
 	"path"
@@ -10,12 +15,14 @@ import (
 	"strings"
 	"testing"
 
+	"github.com/hashicorp/go-getter"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
 	"github.com/gruntwork-io/terragrunt/cli/tfsource"
 	"github.com/gruntwork-io/terragrunt/config"
 	"github.com/gruntwork-io/terragrunt/options"
+	"github.com/gruntwork-io/terragrunt/tfr"
 	"github.com/gruntwork-io/terragrunt/util"
 )
 
@@ -28,6 +35,37 @@ func TestAlreadyHaveLatestCodeLocalFilePath(t *testing.T) {
 	testAlreadyHaveLatestCode(t, canonicalUrl, downloadDir, false)
 }
 
+func TestAlreadyHaveLatestCodeLocalFilePathUnchanged(t *testing.T) {
+	t.Parallel()
+
+	sourceDir := tmpDir(t)
+	defer os.RemoveAll(sourceDir)
+	downloadDir := tmpDir(t)
+	defer os.RemoveAll(downloadDir)
+
+	mainTf := filepath.Join(sourceDir, "main.tf")
+	require.NoError(t, ioutil.WriteFile(mainTf, []byte(`output "hello" { value = "Hello, World" }`), 0644))
+	copyFolder(t, sourceDir, downloadDir)
+
+	terraformSource := &tfsource.TerraformSource{
+		CanonicalSourceURL: parseUrl(t, fmt.Sprintf("file://%s", sourceDir)),
+		DownloadDir:        downloadDir,
+		WorkingDir:         downloadDir,
+		VersionFile:        util.JoinPath(downloadDir, "version-file.txt"),
+	}
+	require.NoError(t, terraformSource.WriteVersionFile())
+
+	testAlreadyHaveLatestCode(t, terraformSource.CanonicalSourceURL.String(), downloadDir, true)
+
+	// Hidden files, such as the .terraform folder, are not copied, so they don't change the version
+	require.NoError(t, os.MkdirAll(filepath.Join(sourceDir, ".terraform"), 0755))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(sourceDir, ".terraform", "state"), []byte("{}"), 0644))
+	testAlreadyHaveLatestCode(t, terraformSource.CanonicalSourceURL.String(), downloadDir, true)
+
+	require.NoError(t, ioutil.WriteFile(mainTf, []byte(`output "hello" { value = "Hello, Terragrunt" }`), 0644))
+	testAlreadyHaveLatestCode(t, terraformSource.CanonicalSourceURL.String(), downloadDir, false)
+}
+
 func TestAlreadyHaveLatestCodeRemoteFilePathDownloadDirDoesNotExist(t *testing.T) {
 	t.Parallel()
 
@@ -225,6 +263,60 @@ func TestDownloadTerraformSourceFromLocalFolderWithManifest(t *testing.T) {
 
 }
 
+func TestDownloadTerraformSourceSymlinkLocalSource(t *testing.T) {
+	t.Parallel()
+
+	sourceDir := tmpDir(t)
+	defer os.RemoveAll(sourceDir)
+	terragruntDir := tmpDir(t)
+	defer os.RemoveAll(terragruntDir)
+	downloadDir := tmpDir(t)
+	defer os.RemoveAll(downloadDir)
+
+	writeFile := func(path string, contents string) {
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+		require.NoError(t, ioutil.WriteFile(path, []byte(contents), 0644))
+	}
+	writeFile(filepath.Join(sourceDir, "modules", "vpc", "main.tf"), "# vpc")
+	writeFile(filepath.Join(sourceDir, "modules", "vpc", "outputs.tf"), "# outputs")
+	writeFile(filepath.Join(sourceDir, "modules", "common", "variables.tf"), "# common")
+	writeFile(filepath.Join(sourceDir, ".git", "HEAD"), "ref: refs/heads/main")
+	writeFile(filepath.Join(terragruntDir, config.DefaultTerragruntConfigPath), "")
+	writeFile(filepath.Join(terragruntDir, "outputs.tf"), "# overridden outputs")
+
+	terragruntOptions, err := options.NewTerragruntOptionsForTest(filepath.Join(terragruntDir, config.DefaultTerragruntConfigPath))
+	require.NoError(t, err)
+	terragruntOptions.WorkingDir = terragruntDir
+	terragruntOptions.DownloadDir = downloadDir
+	terragruntOptions.SymlinkLocalSource = true
+
+	updatedTerragruntOptions, err := downloadTerraformSource(sourceDir+"//modules/vpc", terragruntOptions, &config.TerragruntConfig{})
+	require.NoError(t, err)
+	workingDir := updatedTerragruntOptions.WorkingDir
+
+	// The folders leading to the working dir are created, while everything else is linked
+	assert.False(t, util.IsSymLink(workingDir))
+	assert.False(t, util.IsSymLink(filepath.Dir(workingDir)))
+	assert.True(t, util.IsSymLink(filepath.Join(workingDir, "main.tf")))
+	assert.True(t, util.IsSymLink(filepath.Join(filepath.Dir(workingDir), "common")))
+	assert.False(t, util.FileExists(filepath.Join(filepath.Dir(filepath.Dir(workingDir)), ".git")))
+
+	// The files of the Terragrunt configuration folder are copied, without writing through the links into the source
+	assert.False(t, util.IsSymLink(filepath.Join(workingDir, "outputs.tf")))
+	assert.Equal(t, "# overridden outputs", readFile(t, filepath.Join(workingDir, "outputs.tf")))
+	assert.Equal(t, "# outputs", readFile(t, filepath.Join(sourceDir, "modules", "vpc", "outputs.tf")))
+
+	// Changes to the source are picked up instantly, and links to removed files are cleaned up on the next run
+	writeFile(filepath.Join(sourceDir, "modules", "vpc", "main.tf"), "# updated vpc")
+	assert.Equal(t, "# updated vpc", readFile(t, filepath.Join(workingDir, "main.tf")))
+
+	require.NoError(t, os.Rename(filepath.Join(sourceDir, "modules", "vpc", "main.tf"), filepath.Join(sourceDir, "modules", "vpc", "vpc.tf")))
+	_, err = downloadTerraformSource(sourceDir+"//modules/vpc", terragruntOptions, &config.TerragruntConfig{})
+	require.NoError(t, err)
+	assert.False(t, util.IsSymLink(filepath.Join(workingDir, "main.tf")))
+	assert.True(t, util.IsSymLink(filepath.Join(workingDir, "vpc.tf")))
+}
+
 func testDownloadTerraformSourceIfNecessary(t *testing.T, canonicalUrl string, downloadDir string, sourceUpdate bool, expectedFileContents string) {
 	terraformSource := &tfsource.TerraformSource{
 		CanonicalSourceURL: parseUrl(t, canonicalUrl),
@@ -314,3 +406,139 @@ func copyFolder(t *testing.T, src string, dest string) {
 	err := util.CopyFolderContents(filepath.FromSlash(src), filepath.FromSlash(dest), ".terragrunt-test")
 	require.Nil(t, err)
 }
+
+func TestShouldCopyIntoWorkingDir(t *testing.T) {
+	t.Parallel()
+
+	terragruntOptions, err := options.NewTerragruntOptionsForTest("mock-path-for-test.hcl")
+	require.NoError(t, err)
+
+	assert.True(t, shouldCopyIntoWorkingDir("/live/vpc/main.tf", terragruntOptions))
+	assert.True(t, shouldCopyIntoWorkingDir("/live/vpc/"+util.TerraformLockFile, terragruntOptions))
+	assert.False(t, shouldCopyIntoWorkingDir("/live/vpc/.terraform/terraform.tfstate", terragruntOptions))
+
+	// Without copying the lock file, terraform manages the one in the download folder on its own
+	terragruntOptions.CopyLockFile = false
+	assert.True(t, shouldCopyIntoWorkingDir("/live/vpc/main.tf", terragruntOptions))
+	assert.False(t, shouldCopyIntoWorkingDir("/live/vpc/"+util.TerraformLockFile, terragruntOptions))
+}
+
+func TestCopyLockFile(t *testing.T) {
+	t.Parallel()
+
+	downloadDir := tmpDir(t)
+	defer os.RemoveAll(downloadDir)
+	workingDir := tmpDir(t)
+	defer os.RemoveAll(workingDir)
+	logger := util.CreateLogEntry("", util.DEFAULT_LOG_LEVEL)
+
+	// Nothing to copy before terraform creates the lock file
+	require.NoError(t, copyLockFile(downloadDir, workingDir, logger))
+	assert.False(t, util.FileExists(filepath.Join(workingDir, util.TerraformLockFile)))
+
+	lockFile := `provider "registry.terraform.io/hashicorp/aws" {}`
+	require.NoError(t, ioutil.WriteFile(filepath.Join(downloadDir, util.TerraformLockFile), []byte(lockFile), 0644))
+	require.NoError(t, copyLockFile(downloadDir, workingDir, logger))
+	assert.Equal(t, lockFile, readFile(t, filepath.Join(workingDir, util.TerraformLockFile)))
+
+	// Copying a lock file onto itself, when the module has no source, leaves it alone
+	require.NoError(t, copyLockFile(workingDir, workingDir, logger))
+	assert.Equal(t, lockFile, readFile(t, filepath.Join(workingDir, util.TerraformLockFile)))
+
+	updatedLockFile := `provider "registry.terraform.io/hashicorp/aws" { version = "3.0.0" }`
+	require.NoError(t, ioutil.WriteFile(filepath.Join(downloadDir, util.TerraformLockFile), []byte(updatedLockFile), 0644))
+	require.NoError(t, copyLockFile(downloadDir, workingDir, logger))
+	assert.Equal(t, updatedLockFile, readFile(t, filepath.Join(workingDir, util.TerraformLockFile)))
+}
+
+func TestDownloadSourceFromMirrorWithHeaders(t *testing.T) {
+	t.Parallel()
+
+	// An HTTP mirror that only serves the archive of the modules repo to the requests with its token
+	var archive bytes.Buffer
+	gzipWriter := gzip.NewWriter(&archive)
+	tarWriter := tar.NewWriter(gzipWriter)
+	contents := `variable "cidr" {}`
+	require.NoError(t, tarWriter.WriteHeader(&tar.Header{Name: "vpc/main.tf", Mode: 0644, Size: int64(len(contents)), Typeflag: tar.TypeReg}))
+	_, err := tarWriter.Write([]byte(contents))
+	require.NoError(t, err)
+	require.NoError(t, tarWriter.Close())
+	require.NoError(t, gzipWriter.Close())
+
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		if request.Header.Get("Authorization") != "Bearer mirror-token" {
+			writer.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if request.URL.Path != "/acme/modules/v1.2.0.tar.gz" {
+			writer.WriteHeader(http.StatusNotFound)
+			return
+		}
+		writer.Write(archive.Bytes())
+	}))
+	defer server.Close()
+
+	terragruntOptions, err := options.NewTerragruntOptionsForTest("./should-not-be-used")
+	require.NoError(t, err)
+	terragruntOptions.SourceMirrors = map[string]string{"github.com/acme": server.URL + "/acme"}
+
+	source := "git::https://github.com/acme/modules.git//vpc?ref=v1.2.0"
+	terragruntConfig := &config.TerragruntConfig{Terraform: &config.TerraformConfig{Source: &source}}
+	sourceUrl, err := config.GetTerraformSourceUrl(terragruntOptions, terragruntConfig)
+	require.NoError(t, err)
+	assert.Equal(t, server.URL+"/acme/modules/v1.2.0.tar.gz//vpc", sourceUrl)
+
+	downloadDir := tmpDir(t)
+	defer os.RemoveAll(downloadDir)
+	terraformSource, err := tfsource.NewTerraformSource(sourceUrl, downloadDir, terragruntOptions.WorkingDir, terragruntOptions.Logger)
+	require.NoError(t, err)
+
+	// Without the header, the mirror refuses the download
+	require.Error(t, downloadSource(terraformSource, terragruntOptions, terragruntConfig))
+
+	terragruntOptions.SourceMirrorHeaders = map[string]string{"Authorization": "Bearer mirror-token"}
+	require.NoError(t, downloadSource(terraformSource, terragruntOptions, terragruntConfig))
+	assert.Equal(t, contents, readFile(t, filepath.Join(terraformSource.WorkingDir, "main.tf")))
+}
+
+func TestWithSourceSigV4SigningOnlySignsMirrorsAndServiceEndpoints(t *testing.T) {
+	t.Parallel()
+
+	terragruntOptions, err := options.NewTerragruntOptionsForTest("terragrunt.hcl")
+	require.NoError(t, err)
+	terragruntOptions.Env = map[string]string{"AWS_ACCESS_KEY_ID": "AKIAEXAMPLE", "AWS_SECRET_ACCESS_KEY": "secret", "AWS_REGION": "eu-west-1"}
+	terragruntOptions.SourceSigV4Service = "execute-api"
+	terragruntOptions.SourceMirrors = map[string]string{"github.com/acme": "https://mirror.acme.internal/acme"}
+
+	testCases := []struct {
+		sourceURL      string
+		expectedSigned bool
+	}{
+		{"https://mirror.acme.internal/acme/modules/v1.2.0.tar.gz//vpc", true},
+		{"http::https://abc123.execute-api.eu-west-1.amazonaws.com/modules/vpc.zip", true},
+		{"https://releases.example.com/modules/vpc.zip", false},
+		{"https://mirror.acme.internal.example.com/acme/modules/v1.2.0.tar.gz", false},
+	}
+
+	for _, testCase := range testCases {
+		client := &getter.Client{Getters: map[string]getter.Getter{}}
+		require.NoError(t, withSourceSigV4Signing(testCase.sourceURL, terragruntOptions)(client))
+		_, isSigned := client.Getters["https"]
+		assert.Equal(t, testCase.expectedSigned, isSigned, testCase.sourceURL)
+	}
+}
+
+func TestCopyFilesPassesEnvToRegistryGetter(t *testing.T) {
+	t.Parallel()
+
+	terragruntOptions, err := options.NewTerragruntOptionsForTest("terragrunt.hcl")
+	require.NoError(t, err)
+	terragruntOptions.Env = map[string]string{"TF_CLI_CONFIG_FILE": "/tmp/registry-credentials.tfrc"}
+
+	// The tokens of registry_credentials are looked up in the env of the unit, not in the one of the process
+	client := &getter.Client{}
+	require.NoError(t, copyFiles(terragruntOptions)(client))
+	registryGetter, isRegistryGetter := client.Getters[tfr.SCHEME].(*tfr.Getter)
+	require.True(t, isRegistryGetter)
+	assert.Equal(t, terragruntOptions.Env, registryGetter.Env)
+}
@@ -0,0 +1,118 @@
+package cli
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/hashicorp/go-multierror"
+
+	"github.com/gruntwork-io/terragrunt/configstack"
+	"github.com/gruntwork-io/terragrunt/drift"
+	"github.com/gruntwork-io/terragrunt/errors"
+	"github.com/gruntwork-io/terragrunt/options"
+	"github.com/gruntwork-io/terragrunt/shell"
+	"github.com/gruntwork-io/terragrunt/util"
+)
+
+// Start detecting the drift of the units the plan command runs in, if a file to write the report to is set via
+// --terragrunt-drift-report. Returns a function that writes the report, and returns the error of the whole run given
+// the error the command finished with, which should be called once the command finishes: the error of the command if it
+// failed, a DriftDetected error, which exits with code 2, if any unit drifted, or nil otherwise.
+func startDriftReport(terragruntOptions *options.TerragruntOptions) func(error) error {
+	if terragruntOptions.DriftReportPath == "" || terragruntOptions.TerraformCommand != "plan" {
+		return func(runErr error) error { return runErr }
+	}
+
+	// The report sets the exit code of the run itself, and a unit whose plan exits with 2 would count as failed
+	if util.ListContainsElement(terragruntOptions.TerraformCliArgs, configstack.TERRAFORM_DETAILED_EXITCODE_FLAG) {
+		terragruntOptions.Logger.Debugf("Ignoring %s, as the drift report sets the exit code of the run", configstack.TERRAFORM_DETAILED_EXITCODE_FLAG)
+		terragruntOptions.TerraformCliArgs = util.RemoveElementFromList(terragruntOptions.TerraformCliArgs, configstack.TERRAFORM_DETAILED_EXITCODE_FLAG)
+	}
+
+	report := drift.NewReport(unitsRootDir(terragruntOptions))
+	terragruntOptions.DriftReport = report
+
+	return func(runErr error) error {
+		forEachSkippedUnit(runErr, report.AddError)
+
+		result := report.Result()
+		terragruntOptions.Logger.Infof("Drift of %d units: %d without drift, %d drifted, %d failed", result.Summary.Units, result.Summary.NoDrift, result.Summary.Drift, result.Summary.Error)
+		if err := result.Write(terragruntOptions.DriftReportPath); err != nil {
+			terragruntOptions.Logger.Warnf("Could not write the drift report to %s: %v", terragruntOptions.DriftReportPath, err)
+		}
+
+		if runErr != nil {
+			return runErr
+		}
+		if result.Status == drift.StatusError {
+			return errors.WithStackTrace(DriftDetectionFailed(result.Summary.Error))
+		}
+		if driftedUnits := result.DriftedUnits(); len(driftedUnits) > 0 {
+			return errors.WithStackTrace(drift.DriftDetected(driftedUnits))
+		}
+		return nil
+	}
+}
+
+// The units that weren't planned because one of their dependencies failed never run, so the reports get them from the
+// errors of the run: call the given function with the dir of each of them and the error it was skipped with
+func forEachSkippedUnit(runErr error, addError func(unitDir string, err error)) {
+	switch err := errors.Unwrap(runErr).(type) {
+	case *multierror.Error:
+		for _, moduleErr := range err.Errors {
+			forEachSkippedUnit(moduleErr, addError)
+		}
+	case configstack.DependencyFinishedWithError:
+		addError(err.Module.Path, err)
+	}
+}
+
+// Make the plan command of the unit of the given options save its plan, so that its drift can be detected once it
+// finishes. Returns a function that removes the plan, which should be called once the drift is detected.
+func prepareDriftDetection(terragruntOptions *options.TerragruntOptions) (func(), error) {
+	if !shouldDetectDrift(terragruntOptions) {
+		return func() {}, nil
+	}
+	return savePlanToTempFile(terragruntOptions, "terragrunt-drift-*.tfplan")
+}
+
+// Add the changes of the plan the unit of the given options just saved to the drift report of the run. A plan whose
+// changes can't be read makes the unit fail in the report, as its drift is unknown.
+func detectDrift(terragruntOptions *options.TerragruntOptions) {
+	planFile := planOutFile(terragruntOptions.TerraformCliArgs)
+	if !shouldDetectDrift(terragruntOptions) || planFile == "" {
+		return
+	}
+
+	unitDir := filepath.Dir(terragruntOptions.TerragruntConfigPath)
+	out, err := shell.RunShellCommandWithOutput(terragruntOptions, "", true, false, terragruntOptions.TerraformPath, "show", "-json", planFile)
+	if err != nil {
+		terragruntOptions.DriftReport.AddError(unitDir, err)
+		return
+	}
+	changes, err := drift.ParsePlan([]byte(out.Stdout))
+	if err != nil {
+		terragruntOptions.DriftReport.AddError(unitDir, err)
+		return
+	}
+	terragruntOptions.DriftReport.Add(unitDir, changes)
+}
+
+// Add the error the unit of the given options failed with, if any, to the drift report of the run
+func recordDriftError(terragruntOptions *options.TerragruntOptions, err error) {
+	if err != nil && shouldDetectDrift(terragruntOptions) {
+		terragruntOptions.DriftReport.AddError(filepath.Dir(terragruntOptions.TerragruntConfigPath), err)
+	}
+}
+
+func shouldDetectDrift(terragruntOptions *options.TerragruntOptions) bool {
+	return terragruntOptions.DriftReport != nil && isMainPlanRun(terragruntOptions)
+}
+
+// Custom error types
+
+type DriftDetectionFailed int
+
+func (count DriftDetectionFailed) Error() string {
+	return fmt.Sprintf("Could not detect the drift of %d units. See the drift report for the errors.", int(count))
+}
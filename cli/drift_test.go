@@ -0,0 +1,103 @@
+package cli
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/hashicorp/go-multierror"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/gruntwork-io/terragrunt/configstack"
+	"github.com/gruntwork-io/terragrunt/drift"
+	"github.com/gruntwork-io/terragrunt/errors"
+	"github.com/gruntwork-io/terragrunt/options"
+	"github.com/gruntwork-io/terragrunt/shell"
+)
+
+func newDriftTestOptions(t *testing.T, dir string) *options.TerragruntOptions {
+	terragruntOptions, err := options.NewTerragruntOptionsForTest(filepath.Join(dir, "terragrunt.hcl"))
+	require.NoError(t, err)
+	terragruntOptions.WorkingDir = dir
+	terragruntOptions.TerraformCommand = "plan"
+	terragruntOptions.OriginalTerraformCommand = "plan"
+	terragruntOptions.TerraformCliArgs = []string{"plan", configstack.TERRAFORM_DETAILED_EXITCODE_FLAG}
+	terragruntOptions.DriftReportPath = filepath.Join(dir, "drift.json")
+	return terragruntOptions
+}
+
+func readDriftReport(t *testing.T, path string) drift.Result {
+	contents, err := ioutil.ReadFile(path)
+	require.NoError(t, err)
+	var result drift.Result
+	require.NoError(t, json.Unmarshal(contents, &result))
+	return result
+}
+
+func TestDriftReportExitsWithTwoOnDrift(t *testing.T) {
+	t.Parallel()
+
+	dir, err := ioutil.TempDir("", "drift-report")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	terragruntOptions := newDriftTestOptions(t, dir)
+	stopDriftReport := startDriftReport(terragruntOptions)
+	assert.Equal(t, []string{"plan"}, terragruntOptions.TerraformCliArgs)
+
+	unitOptions := terragruntOptions.Clone(filepath.Join(dir, "live", "app", "terragrunt.hcl"))
+	require.True(t, shouldDetectDrift(unitOptions))
+	unitOptions.DriftReport.Add(filepath.Join(dir, "live", "app"), &drift.Changes{Resources: []drift.ResourceChange{{Address: "aws_instance.app", Actions: []string{"update"}}}})
+	unitOptions.DriftReport.Add(filepath.Join(dir, "live", "vpc"), &drift.Changes{})
+
+	err = stopDriftReport(nil)
+	exitCode, exitCodeErr := shell.GetExitCode(err)
+	require.NoError(t, exitCodeErr)
+	assert.Equal(t, drift.EXIT_CODE_DRIFT, exitCode)
+
+	result := readDriftReport(t, terragruntOptions.DriftReportPath)
+	assert.Equal(t, drift.StatusDrift, result.Status)
+	assert.Equal(t, drift.Summary{Units: 2, NoDrift: 1, Drift: 1}, result.Summary)
+}
+
+func TestDriftReportRecordsFailedAndSkippedUnits(t *testing.T) {
+	t.Parallel()
+
+	dir, err := ioutil.TempDir("", "drift-report")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	terragruntOptions := newDriftTestOptions(t, dir)
+	stopDriftReport := startDriftReport(terragruntOptions)
+
+	vpcOptions := terragruntOptions.Clone(filepath.Join(dir, "live", "vpc", "terragrunt.hcl"))
+	vpcErr := errors.WithStackTrace(drift.InvalidPlan("unexpected EOF"))
+	recordDriftError(vpcOptions, vpcErr)
+
+	vpc := &configstack.TerraformModule{Path: filepath.Join(dir, "live", "vpc")}
+	app := &configstack.TerraformModule{Path: filepath.Join(dir, "live", "app")}
+	runErr := multierror.Append(vpcErr, configstack.DependencyFinishedWithError{Module: app, Dependency: vpc, Err: vpcErr})
+	assert.Equal(t, runErr, stopDriftReport(runErr))
+
+	result := readDriftReport(t, terragruntOptions.DriftReportPath)
+	assert.Equal(t, drift.StatusError, result.Status)
+	assert.Equal(t, drift.Summary{Units: 2, Error: 2}, result.Summary)
+	assert.Equal(t, "live/app", result.Units[0].Unit)
+	assert.Contains(t, result.Units[0].Error, "one of its dependencies")
+}
+
+func TestDriftReportDisabled(t *testing.T) {
+	t.Parallel()
+
+	terragruntOptions, err := options.NewTerragruntOptionsForTest("mock-path-for-test.hcl")
+	require.NoError(t, err)
+	terragruntOptions.TerraformCommand = "apply"
+	terragruntOptions.DriftReportPath = "drift.json"
+
+	runErr := errors.WithStackTrace(drift.InvalidPlan(""))
+	assert.Equal(t, runErr, startDriftReport(terragruntOptions)(runErr))
+	assert.Nil(t, terragruntOptions.DriftReport)
+}
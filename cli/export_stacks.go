@@ -0,0 +1,104 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+
+	"github.com/gruntwork-io/terragrunt/configstack"
+	"github.com/gruntwork-io/terragrunt/errors"
+	"github.com/gruntwork-io/terragrunt/options"
+	"github.com/gruntwork-io/terragrunt/util"
+)
+
+// The file the env0 workflow of the stack is written to
+const ENV0_WORKFLOW_FILE = "env0.workflow.yaml"
+
+// The header of the generated env0 workflow, so that nobody edits it by hand
+const env0WorkflowHeader = "# Generated by terragrunt export-stacks env0. Do not edit.\n"
+
+func shouldExportStacks(terragruntOptions *options.TerragruntOptions) bool {
+	return util.FirstArg(terragruntOptions.TerraformCliArgs) == CMD_EXPORT_STACKS
+}
+
+// Export the modules in the working dir or its subfolders, and their dependencies, to the platform given as the
+// argument of the command: Spacelift stacks and stack dependencies, written to spacelift.tf.json, or env0 templates,
+// written to env0.tf.json, and the env0 workflow that deploys them in order, written to env0.workflow.yaml. The files
+// are written to the working dir, which should be the root of the repo.
+func exportStacks(terragruntOptions *options.TerragruntOptions) error {
+	args := terragruntOptions.TerraformCliArgs
+	if len(args) < 2 || !util.ListContainsElement(configstack.ExportPlatforms, args[1]) {
+		return errors.WithStackTrace(UnsupportedExportPlatform(strings.Join(args[1:], " ")))
+	}
+	platform := args[1]
+
+	// The external dependencies can't be exported, as they're outside of the repo, so there's no need to ask whether
+	// to run them
+	terragruntOptions.IgnoreExternalDependencies = true
+
+	stack, err := configstack.FindStackInSubfolders(terragruntOptions)
+	if err != nil {
+		return err
+	}
+
+	var terraformConfig *configstack.TerraformJsonConfig
+	switch platform {
+	case configstack.ExportPlatformSpacelift:
+		terraformConfig, err = stack.SpaceliftConfig(terragruntOptions.WorkingDir)
+		if err != nil {
+			return err
+		}
+	case configstack.ExportPlatformEnv0:
+		terragruntVersion := ""
+		if terragruntOptions.TerragruntVersion != nil {
+			terragruntVersion = terragruntOptions.TerragruntVersion.String()
+		}
+		var workflow *configstack.Env0Workflow
+		terraformConfig, workflow, err = stack.Env0Config(terragruntOptions.WorkingDir, terragruntVersion)
+		if err != nil {
+			return err
+		}
+		if err := writeEnv0Workflow(workflow, terragruntOptions); err != nil {
+			return err
+		}
+	}
+
+	contents, err := json.MarshalIndent(terraformConfig, "", "  ")
+	if err != nil {
+		return errors.WithStackTrace(err)
+	}
+	configPath := filepath.Join(terragruntOptions.WorkingDir, platform+".tf.json")
+	if err := ioutil.WriteFile(configPath, append(contents, '\n'), 0644); err != nil {
+		return errors.WithStackTrace(err)
+	}
+
+	terragruntOptions.Logger.Infof("Wrote the %s config of the modules to %s", platform, configPath)
+	return nil
+}
+
+func writeEnv0Workflow(workflow *configstack.Env0Workflow, terragruntOptions *options.TerragruntOptions) error {
+	contents, err := yaml.Marshal(workflow)
+	if err != nil {
+		return errors.WithStackTrace(err)
+	}
+
+	workflowPath := filepath.Join(terragruntOptions.WorkingDir, ENV0_WORKFLOW_FILE)
+	if err := ioutil.WriteFile(workflowPath, append([]byte(env0WorkflowHeader), contents...), 0644); err != nil {
+		return errors.WithStackTrace(err)
+	}
+
+	terragruntOptions.Logger.Infof("Wrote the env0 workflow of %d environments to %s", len(workflow.Environments), workflowPath)
+	return nil
+}
+
+// Custom error types
+
+type UnsupportedExportPlatform string
+
+func (platform UnsupportedExportPlatform) Error() string {
+	return fmt.Sprintf("Expected the platform to export to, one of %s, but got '%s'", strings.Join(configstack.ExportPlatforms, ", "), string(platform))
+}
@@ -0,0 +1,129 @@
+package cli
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v2"
+
+	"github.com/gruntwork-io/terragrunt/config"
+	"github.com/gruntwork-io/terragrunt/configstack"
+	"github.com/gruntwork-io/terragrunt/errors"
+	"github.com/gruntwork-io/terragrunt/options"
+	"github.com/gruntwork-io/terragrunt/util"
+)
+
+// Create a repo with a vpc module, and an app module that depends on it, and return its root
+func createExportStacksRepo(t *testing.T) string {
+	rootPath, err := ioutil.TempDir("", "export-stacks")
+	require.NoError(t, err)
+
+	files := map[string]string{
+		"live/vpc/main.tf":        "",
+		"live/vpc/terragrunt.hcl": "",
+		"live/app/main.tf":        "",
+		"live/app/terragrunt.hcl": "dependency \"vpc\" {\n  config_path = \"../vpc\"\n}\n",
+	}
+	for path, contents := range files {
+		filePath := filepath.Join(rootPath, path)
+		require.NoError(t, os.MkdirAll(filepath.Dir(filePath), os.ModePerm))
+		require.NoError(t, ioutil.WriteFile(filePath, []byte(contents), 0644))
+	}
+	return rootPath
+}
+
+func TestExportStacksSpacelift(t *testing.T) {
+	t.Parallel()
+
+	rootPath := createExportStacksRepo(t)
+	defer os.RemoveAll(rootPath)
+
+	terragruntOptions, err := options.NewTerragruntOptionsForTest(util.JoinPath(rootPath, config.DefaultTerragruntConfigPath))
+	require.NoError(t, err)
+	terragruntOptions.TerraformCliArgs = []string{CMD_EXPORT_STACKS, configstack.ExportPlatformSpacelift}
+	require.True(t, shouldExportStacks(terragruntOptions))
+	require.NoError(t, exportStacks(terragruntOptions))
+
+	contents, err := ioutil.ReadFile(filepath.Join(rootPath, "spacelift.tf.json"))
+	require.NoError(t, err)
+	var spaceliftConfig map[string]interface{}
+	require.NoError(t, json.Unmarshal(contents, &spaceliftConfig))
+
+	resources := spaceliftConfig["resource"].(map[string]interface{})
+	assert.Equal(t, map[string]interface{}{
+		"live_app": map[string]interface{}{
+			"name":         "live/app",
+			"repository":   "${var.repository}",
+			"branch":       "${var.branch}",
+			"project_root": "live/app",
+			"terragrunt":   map[string]interface{}{"use_run_all": false},
+		},
+		"live_vpc": map[string]interface{}{
+			"name":         "live/vpc",
+			"repository":   "${var.repository}",
+			"branch":       "${var.branch}",
+			"project_root": "live/vpc",
+			"terragrunt":   map[string]interface{}{"use_run_all": false},
+		},
+	}, resources["spacelift_stack"])
+	assert.Equal(t, map[string]interface{}{
+		"live_app_live_vpc": map[string]interface{}{
+			"stack_id":            "${spacelift_stack.live_app.id}",
+			"depends_on_stack_id": "${spacelift_stack.live_vpc.id}",
+		},
+	}, resources["spacelift_stack_dependency"])
+	assert.Contains(t, spaceliftConfig["variable"], "repository")
+}
+
+func TestExportStacksEnv0(t *testing.T) {
+	t.Parallel()
+
+	rootPath := createExportStacksRepo(t)
+	defer os.RemoveAll(rootPath)
+
+	terragruntOptions, err := options.NewTerragruntOptionsForTest(util.JoinPath(rootPath, config.DefaultTerragruntConfigPath))
+	require.NoError(t, err)
+	terragruntOptions.TerraformCliArgs = []string{CMD_EXPORT_STACKS, configstack.ExportPlatformEnv0}
+	require.NoError(t, exportStacks(terragruntOptions))
+
+	contents, err := ioutil.ReadFile(filepath.Join(rootPath, ENV0_WORKFLOW_FILE))
+	require.NoError(t, err)
+	var workflow configstack.Env0Workflow
+	require.NoError(t, yaml.Unmarshal(contents, &workflow))
+	assert.Equal(t, configstack.Env0Workflow{Environments: map[string]configstack.Env0WorkflowEnvironment{
+		"live_app": {Name: "live/app", TemplateName: "live/app", Needs: []string{"live_vpc"}},
+		"live_vpc": {Name: "live/vpc", TemplateName: "live/vpc"},
+	}}, workflow)
+
+	contents, err = ioutil.ReadFile(filepath.Join(rootPath, "env0.tf.json"))
+	require.NoError(t, err)
+	var env0Config configstack.TerraformJsonConfig
+	require.NoError(t, json.Unmarshal(contents, &env0Config))
+	assert.Equal(t, map[string]interface{}{
+		"name":               "live/app",
+		"type":               "terragrunt",
+		"repository":         "${var.repository}",
+		"revision":           "${var.revision}",
+		"path":               "live/app",
+		"terragrunt_version": "${var.terragrunt_version}",
+	}, env0Config.Resource["env0_template"]["live_app"])
+}
+
+func TestExportStacksUnsupportedPlatform(t *testing.T) {
+	t.Parallel()
+
+	terragruntOptions, err := options.NewTerragruntOptionsForTest(config.DefaultTerragruntConfigPath)
+	require.NoError(t, err)
+
+	for _, args := range [][]string{{CMD_EXPORT_STACKS}, {CMD_EXPORT_STACKS, "terraform-cloud"}} {
+		terragruntOptions.TerraformCliArgs = args
+		err := exportStacks(terragruntOptions)
+		_, isUnsupportedPlatform := errors.Unwrap(err).(UnsupportedExportPlatform)
+		assert.True(t, isUnsupportedPlatform, "Unexpected error: %v", err)
+	}
+}
@@ -0,0 +1,78 @@
+package cli
+
+import (
+	"io/ioutil"
+	"path/filepath"
+
+	"github.com/gruntwork-io/terragrunt/config"
+	"github.com/gruntwork-io/terragrunt/errors"
+	"github.com/gruntwork-io/terragrunt/moduledocs"
+	"github.com/gruntwork-io/terragrunt/options"
+	"github.com/gruntwork-io/terragrunt/shell"
+	"github.com/gruntwork-io/terragrunt/util"
+)
+
+// The binary of terraform-docs, which the documentation of the Terraform modules is generated with
+const TERRAFORM_DOCS_BINARY = "terraform-docs"
+
+// The file the documentation of each unit is written to, next to its terragrunt.hcl
+const MODULE_DOCS_FILE = "MODULE.md"
+
+func shouldGenerateDocs(terragruntOptions *options.TerragruntOptions) bool {
+	return util.FirstArg(terragruntOptions.TerraformCliArgs) == CMD_GENERATE_DOCS
+}
+
+// Start collecting the documentation of the units into a single inventory, if the command is generate-docs and a file
+// to write the inventory to is set via --terragrunt-docs-inventory. Returns a function that writes the inventory, which
+// should be called once the command finishes.
+func startDocsInventory(terragruntOptions *options.TerragruntOptions) func() {
+	if !shouldGenerateDocs(terragruntOptions) || terragruntOptions.DocsInventoryPath == "" {
+		return func() {}
+	}
+
+	inventory := moduledocs.NewInventory()
+	terragruntOptions.DocsInventory = inventory
+
+	return func() {
+		if err := inventory.Write(terragruntOptions.DocsInventoryPath); err != nil {
+			terragruntOptions.Logger.Warnf("Could not write the inventory of the units to %s: %v", terragruntOptions.DocsInventoryPath, err)
+		}
+	}
+}
+
+// Generate the documentation of the unit of the given options, once its Terraform code is downloaded and generated:
+// the documentation terraform-docs generates of its module, preceded by the inputs Terragrunt passes to the module. The
+// documentation is added to the inventory if there is one, or else written to the MODULE.md of the unit.
+func generateDocs(terragruntOptions *options.TerragruntOptions, terragruntConfig *config.TerragruntConfig) error {
+	unitDir := filepath.Dir(terragruntOptions.TerragruntConfigPath)
+
+	source, err := config.GetTerraformSourceUrl(terragruntOptions, terragruntConfig)
+	if err != nil {
+		return err
+	}
+
+	// terraform-docs picks up the .terraform-docs.yml of the module, if it has one
+	out, err := shell.RunShellCommandWithOutput(terragruntOptions, "", true, false, TERRAFORM_DOCS_BINARY, "markdown", "table", ".")
+	if err != nil {
+		return err
+	}
+
+	unit := moduledocs.Unit{
+		Path:       filepath.Base(unitDir),
+		Source:     source,
+		Inputs:     terragruntConfig.Inputs,
+		ModuleDocs: out.Stdout,
+	}
+	if relPath, err := filepath.Rel(unitsRootDir(terragruntOptions), unitDir); err == nil {
+		unit.Path = filepath.ToSlash(relPath)
+	}
+
+	if terragruntOptions.DocsInventory != nil {
+		terragruntOptions.DocsInventory.Add(unit)
+		return nil
+	}
+
+	docsPath := filepath.Join(unitDir, MODULE_DOCS_FILE)
+	terragruntOptions.Logger.Infof("Writing the documentation of %s to %s", unit.Path, docsPath)
+	return errors.WithStackTrace(ioutil.WriteFile(docsPath, []byte(unit.Markdown(1)), 0644))
+}
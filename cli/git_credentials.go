@@ -0,0 +1,125 @@
+package cli
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/gruntwork-io/terragrunt/errors"
+	"github.com/gruntwork-io/terragrunt/gitcreds"
+	"github.com/gruntwork-io/terragrunt/options"
+)
+
+// Hand the git credentials of --terragrunt-git-credential-helper, --terragrunt-git-netrc-template and the
+// --terragrunt-github-app-* options over to the git commands that fetch the sources, i.e. the ones Terragrunt runs, and
+// the ones terraform init runs for the module sources, and return a function that takes them back. The credentials are
+// written to a credential store in a temp dir, which is removed once the command is done.
+func startGitCredentials(terragruntOptions *options.TerragruntOptions) (func(), error) {
+	noop := func() {}
+	hasGitHubApp := terragruntOptions.GitHubAppID != "" || terragruntOptions.GitHubAppInstallationID != "" || terragruntOptions.GitHubAppPrivateKey != ""
+	if terragruntOptions.GitCredentialHelper == "" && terragruntOptions.GitNetrcTemplate == "" && !hasGitHubApp {
+		return noop, nil
+	}
+
+	helpers := []string{}
+	if terragruntOptions.GitCredentialHelper != "" {
+		helpers = append(helpers, terragruntOptions.GitCredentialHelper)
+	}
+
+	credentials := []gitcreds.Credential{}
+	if terragruntOptions.GitNetrcTemplate != "" {
+		netrcCredentials, err := gitcreds.ReadNetrcTemplate(terragruntOptions.GitNetrcTemplate, terragruntOptions.Env)
+		if err != nil {
+			return nil, err
+		}
+		credentials = append(credentials, netrcCredentials...)
+	}
+	if hasGitHubApp {
+		credential, err := githubAppCredential(terragruntOptions)
+		if err != nil {
+			return nil, err
+		}
+		credentials = append(credentials, *credential)
+	}
+
+	storeDir := ""
+	if len(credentials) > 0 {
+		var err error
+		storeDir, err = ioutil.TempDir("", "terragrunt-git-credentials")
+		if err != nil {
+			return nil, errors.WithStackTrace(err)
+		}
+		storePath := filepath.Join(storeDir, "credentials")
+		if err := gitcreds.WriteStore(storePath, credentials); err != nil {
+			os.RemoveAll(storeDir)
+			return nil, err
+		}
+		helpers = append(helpers, gitcreds.StoreHelper(storePath))
+		terragruntOptions.Logger.Debugf("Wrote the git credentials of %d hosts to %s", len(credentials), storePath)
+	}
+
+	configEnv, err := gitcreds.ConfigEnv(terragruntOptions.Env, helpers)
+	if err != nil {
+		os.RemoveAll(storeDir)
+		return nil, err
+	}
+
+	// go-getter runs git with the env of the process, and terraform with the env of the options
+	previousEnv := map[string]*string{}
+	for key, value := range configEnv {
+		if previousValue, isSet := os.LookupEnv(key); isSet {
+			previousEnv[key] = &previousValue
+		} else {
+			previousEnv[key] = nil
+		}
+		os.Setenv(key, value)
+		terragruntOptions.Env[key] = value
+	}
+
+	return func() {
+		for key, previousValue := range previousEnv {
+			if previousValue == nil {
+				os.Unsetenv(key)
+			} else {
+				os.Setenv(key, *previousValue)
+			}
+		}
+		if storeDir != "" {
+			if err := os.RemoveAll(storeDir); err != nil {
+				terragruntOptions.Logger.Warnf("Could not remove the git credentials in %s: %v", storeDir, err)
+			}
+		}
+	}, nil
+}
+
+// Create an installation token of the GitHub App of the --terragrunt-github-app-* options. The private key is either
+// the path of the PEM file, or its contents, as CI secrets often hold.
+func githubAppCredential(terragruntOptions *options.TerragruntOptions) (*gitcreds.Credential, error) {
+	if terragruntOptions.GitHubAppID == "" || terragruntOptions.GitHubAppInstallationID == "" || terragruntOptions.GitHubAppPrivateKey == "" {
+		return nil, errors.WithStackTrace(IncompleteGitHubAppOptions{})
+	}
+
+	privateKey := []byte(terragruntOptions.GitHubAppPrivateKey)
+	if !strings.HasPrefix(strings.TrimSpace(terragruntOptions.GitHubAppPrivateKey), "-----BEGIN") {
+		var err error
+		if privateKey, err = ioutil.ReadFile(terragruntOptions.GitHubAppPrivateKey); err != nil {
+			return nil, errors.WithStackTrace(err)
+		}
+	}
+
+	apiUrl := terragruntOptions.Env["GITHUB_API_URL"]
+	if apiUrl == "" {
+		apiUrl = gitcreds.DEFAULT_GITHUB_API_URL
+	}
+	app := &gitcreds.GitHubApp{ApiUrl: apiUrl, AppID: terragruntOptions.GitHubAppID, InstallationID: terragruntOptions.GitHubAppInstallationID, PrivateKey: privateKey}
+	return app.InstallationCredential()
+}
+
+// Custom error types
+
+type IncompleteGitHubAppOptions struct{}
+
+func (err IncompleteGitHubAppOptions) Error() string {
+	return "To fetch sources with a GitHub App, all of --terragrunt-github-app-id, --terragrunt-github-app-installation-id and --terragrunt-github-app-private-key must be set."
+}
@@ -0,0 +1,49 @@
+package cli
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/gruntwork-io/terragrunt/options"
+)
+
+// Not parallel, as it sets the env vars of the process
+func TestStartGitCredentialsNetrcTemplate(t *testing.T) {
+	dir, err := ioutil.TempDir("", "git-credentials")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	netrcPath := filepath.Join(dir, "netrc")
+	require.NoError(t, ioutil.WriteFile(netrcPath, []byte("machine gitlab.example.com login oauth2 password ${GITLAB_TOKEN}\n"), 0600))
+
+	terragruntOptions, err := options.NewTerragruntOptionsForTest("mock-path-for-test.hcl")
+	require.NoError(t, err)
+	terragruntOptions.GitNetrcTemplate = netrcPath
+	terragruntOptions.Env = map[string]string{"GITLAB_TOKEN": "glpat-123"}
+
+	stopGitCredentials, err := startGitCredentials(terragruntOptions)
+	require.NoError(t, err)
+	assert.Equal(t, os.Getenv("GIT_CONFIG_COUNT"), terragruntOptions.Env["GIT_CONFIG_COUNT"])
+
+	// Ask git for the credentials of the host, as it does when fetching a source from it
+	cmd := exec.Command("git", "credential", "fill")
+	cmd.Dir = dir
+	cmd.Stdin = strings.NewReader("protocol=https\nhost=gitlab.example.com\n\n")
+	cmd.Env = append(os.Environ(), "GIT_TERMINAL_PROMPT=0")
+	output, err := cmd.Output()
+	require.NoError(t, err)
+	assert.Contains(t, string(output), "username=oauth2\n")
+	assert.Contains(t, string(output), "password=glpat-123\n")
+
+	stopGitCredentials()
+	_, isSet := os.LookupEnv("GIT_CONFIG_COUNT")
+	assert.False(t, isSet)
+	storeHelper := terragruntOptions.Env["GIT_CONFIG_VALUE_0"]
+	assert.NoFileExists(t, strings.Trim(strings.TrimPrefix(storeHelper, "store --file="), `"`))
+}
@@ -0,0 +1,181 @@
+package cli
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/hashicorp/go-getter"
+
+	"github.com/gruntwork-io/terragrunt/cli/tfsource"
+	"github.com/gruntwork-io/terragrunt/errors"
+	"github.com/gruntwork-io/terragrunt/options"
+)
+
+// The query param of git sources with the folder of the repo to check out, rather than the whole repo, as set via
+// --terragrunt-sparse-checkout
+const gitSparseQueryParam = "sparse"
+
+// The refs that are commit SHAs, possibly abbreviated, rather than branches or tags
+var gitCommitShaRegexp = regexp.MustCompile(`^[0-9a-fA-F]{7,64}$`)
+
+// A custom getter.Getter implementation for git sources that fetches only the commit of the ref, rather than cloning the
+// repo and checking out the ref, when a depth is set, and checks out only the given folder with sparse checkout when
+// the sparse query param is set, so that modules in large monorepos download quickly. The go-getter GitGetter clones
+// the default branch with the depth, so refs that aren't on it can't be checked out. The other sources are handed over
+// to it.
+type GitGetter struct {
+	getter.GitGetter
+}
+
+func (g *GitGetter) Get(dst string, u *url.URL) error {
+	query := u.Query()
+	depth := query.Get("depth")
+	sparsePath := query.Get(gitSparseQueryParam)
+	query.Del(gitSparseQueryParam)
+
+	// The go-getter GitGetter takes care of the SSH keys
+	if (depth == "" && sparsePath == "") || query.Get("sshkey") != "" {
+		urlWithoutSparse := *u
+		urlWithoutSparse.RawQuery = query.Encode()
+		return g.GitGetter.Get(dst, &urlWithoutSparse)
+	}
+
+	ref := query.Get("ref")
+	if ref == "" {
+		ref = "HEAD"
+	}
+	query.Del("ref")
+	query.Del("depth")
+	remoteUrl := *u
+	remoteUrl.RawQuery = query.Encode()
+
+	if _, err := os.Stat(filepath.Join(dst, ".git")); os.IsNotExist(err) {
+		if err := os.MkdirAll(dst, 0755); err != nil {
+			return errors.WithStackTrace(err)
+		}
+		if err := runGit(dst, "init", "--quiet"); err != nil {
+			return err
+		}
+		if err := runGit(dst, "remote", "add", "origin", remoteUrl.String()); err != nil {
+			return err
+		}
+	}
+
+	fetchArgs := []string{"fetch", "--quiet"}
+	if depth != "" {
+		if _, err := strconv.Atoi(depth); err != nil {
+			return errors.WithStackTrace(InvalidGitDepth(depth))
+		}
+		fetchArgs = append(fetchArgs, "--depth", depth)
+	}
+	if sparsePath != "" {
+		if err := configureSparseCheckout(dst, sparsePath); err != nil {
+			return err
+		}
+		// Only the blobs of the folder are downloaded, when the checkout needs them. The servers that don't support
+		// partial clones ignore the filter, and send all the blobs.
+		fetchArgs = append(fetchArgs, "--filter=blob:none")
+	}
+	fetchArgs = append(fetchArgs, "origin", ref)
+	if err := runGit(dst, fetchArgs...); err != nil {
+		return err
+	}
+	if err := runGit(dst, "checkout", "--quiet", "--force", "FETCH_HEAD"); err != nil {
+		return err
+	}
+
+	submoduleArgs := []string{"submodule", "update", "--init", "--recursive"}
+	if depth != "" {
+		submoduleArgs = append(submoduleArgs, "--depth", depth)
+	}
+	return runGit(dst, submoduleArgs...)
+}
+
+// Configure the repo in the given dir to check out only the given folder, and to fetch the blobs of its files lazily
+func configureSparseCheckout(dir string, sparsePath string) error {
+	configs := [][]string{
+		{"core.sparseCheckout", "true"},
+		{"remote.origin.promisor", "true"},
+		{"remote.origin.partialclonefilter", "blob:none"},
+	}
+	for _, config := range configs {
+		if err := runGit(dir, "config", config[0], config[1]); err != nil {
+			return err
+		}
+	}
+
+	pattern := "/" + strings.Trim(filepath.ToSlash(sparsePath), "/") + "/\n"
+	return errors.WithStackTrace(ioutil.WriteFile(filepath.Join(dir, ".git", "info", "sparse-checkout"), []byte(pattern), 0644))
+}
+
+func runGit(dir string, args ...string) error {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	var output bytes.Buffer
+	cmd.Stdout = &output
+	cmd.Stderr = &output
+	if err := cmd.Run(); err != nil {
+		return errors.WithStackTrace(GitCommandFailed{Args: args, Err: err, Output: strings.TrimSpace(output.String())})
+	}
+	return nil
+}
+
+// Return the URL to download the given source from: with --terragrunt-shallow-clone, the git sources are fetched with a
+// depth of 1, unless they set a depth, or are cloned by the go-getter GitGetter with an SSH key and have a commit SHA as
+// their ref, as it clones the default branch with the depth, which may not include the commit. With
+// --terragrunt-sparse-checkout, only the folder of the module, i.e., the
+// part of the source after the //, is checked out. The other sources are downloaded from the Canonical Source URL.
+func sourceDownloadUrl(terraformSource *tfsource.TerraformSource, terragruntOptions *options.TerragruntOptions) (string, error) {
+	sourceUrl := *terraformSource.CanonicalSourceURL
+	if !isGitSource(&sourceUrl) || (!terragruntOptions.ShallowClone && !terragruntOptions.SparseCheckout) {
+		return sourceUrl.String(), nil
+	}
+
+	query := sourceUrl.Query()
+	isShaClonedWithSshKey := query.Get("sshkey") != "" && gitCommitShaRegexp.MatchString(query.Get("ref"))
+	if terragruntOptions.ShallowClone && query.Get("depth") == "" && !isShaClonedWithSshKey {
+		query.Set("depth", "1")
+	}
+	if terragruntOptions.SparseCheckout {
+		modulePath, err := filepath.Rel(terraformSource.DownloadDir, terraformSource.WorkingDir)
+		if err != nil {
+			return "", errors.WithStackTrace(err)
+		}
+		if modulePath != "." {
+			query.Set(gitSparseQueryParam, filepath.ToSlash(modulePath))
+		}
+	}
+	sourceUrl.RawQuery = query.Encode()
+	return sourceUrl.String(), nil
+}
+
+// Returns true if the given Canonical Source URL is downloaded with git, e.g. git::https://github.com/foo/modules.git
+func isGitSource(sourceUrl *url.URL) bool {
+	return sourceUrl.Scheme == "git" || strings.HasPrefix(sourceUrl.Scheme, "git::")
+}
+
+// Custom error types
+
+type GitCommandFailed struct {
+	Args   []string
+	Err    error
+	Output string
+}
+
+func (err GitCommandFailed) Error() string {
+	return fmt.Sprintf("git %s failed: %v\n%s", strings.Join(err.Args, " "), err.Err, err.Output)
+}
+
+type InvalidGitDepth string
+
+func (depth InvalidGitDepth) Error() string {
+	return fmt.Sprintf("The depth of a git source must be a number, but got %s", string(depth))
+}
@@ -0,0 +1,115 @@
+package cli
+
+import (
+	"io/ioutil"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/gruntwork-io/terragrunt/cli/tfsource"
+	"github.com/gruntwork-io/terragrunt/options"
+)
+
+// Create a git repo with the modules/vpc and modules/app folders, tagged v1, and a later commit
+func createModulesRepo(t *testing.T) string {
+	repoDir, err := ioutil.TempDir("", "modules-repo")
+	require.NoError(t, err)
+
+	runGitCommand := func(args ...string) {
+		cmd := exec.Command("git", args...)
+		cmd.Dir = repoDir
+		output, err := cmd.CombinedOutput()
+		require.NoError(t, err, string(output))
+	}
+	writeFile := func(path string, contents string) {
+		require.NoError(t, os.MkdirAll(filepath.Dir(filepath.Join(repoDir, path)), 0755))
+		require.NoError(t, ioutil.WriteFile(filepath.Join(repoDir, path), []byte(contents), 0644))
+	}
+
+	runGitCommand("init", "--quiet")
+	runGitCommand("config", "user.email", "test@example.com")
+	runGitCommand("config", "user.name", "test")
+	runGitCommand("config", "uploadpack.allowFilter", "true")
+	writeFile("modules/vpc/main.tf", `variable "cidr" {}`)
+	writeFile("modules/app/main.tf", `variable "image" {}`)
+	runGitCommand("add", "-A")
+	runGitCommand("commit", "--quiet", "-m", "Add the modules")
+	runGitCommand("tag", "v1")
+	writeFile("modules/vpc/main.tf", `variable "cidr_block" {}`)
+	runGitCommand("commit", "--quiet", "-am", "Rename the cidr variable")
+	return repoDir
+}
+
+func TestGitGetterShallowSparseCheckout(t *testing.T) {
+	t.Parallel()
+
+	repoDir := createModulesRepo(t)
+	defer os.RemoveAll(repoDir)
+	dst, err := ioutil.TempDir("", "modules-clone")
+	require.NoError(t, err)
+	defer os.RemoveAll(dst)
+
+	sourceUrl, err := url.Parse("file://" + filepath.ToSlash(repoDir) + "?ref=v1&depth=1&sparse=modules/vpc")
+	require.NoError(t, err)
+	require.NoError(t, (&GitGetter{}).Get(dst, sourceUrl))
+
+	contents, err := ioutil.ReadFile(filepath.Join(dst, "modules", "vpc", "main.tf"))
+	require.NoError(t, err)
+	assert.Equal(t, `variable "cidr" {}`, string(contents))
+	assert.NoFileExists(t, filepath.Join(dst, "modules", "app", "main.tf"))
+
+	cmd := exec.Command("git", "rev-list", "--count", "HEAD")
+	cmd.Dir = dst
+	output, err := cmd.Output()
+	require.NoError(t, err)
+	assert.Equal(t, "1", strings.TrimSpace(string(output)))
+
+	// Getting the source again updates the existing checkout
+	sourceUrl, err = url.Parse("file://" + filepath.ToSlash(repoDir) + "?depth=1&sparse=modules/vpc")
+	require.NoError(t, err)
+	require.NoError(t, (&GitGetter{}).Get(dst, sourceUrl))
+	contents, err = ioutil.ReadFile(filepath.Join(dst, "modules", "vpc", "main.tf"))
+	require.NoError(t, err)
+	assert.Equal(t, `variable "cidr_block" {}`, string(contents))
+}
+
+func TestSourceDownloadUrl(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		source         string
+		shallowClone   bool
+		sparseCheckout bool
+		expected       string
+	}{
+		{"git::https://github.com/acme/modules.git//modules/vpc?ref=v1", false, false, "git::https://github.com/acme/modules.git?ref=v1"},
+		{"git::https://github.com/acme/modules.git//modules/vpc?ref=v1", true, false, "git::https://github.com/acme/modules.git?depth=1&ref=v1"},
+		{"git::https://github.com/acme/modules.git//modules/vpc?ref=v1&depth=5", true, false, "git::https://github.com/acme/modules.git?depth=5&ref=v1"},
+		{"git::https://github.com/acme/modules.git//modules/vpc?ref=v1", true, true, "git::https://github.com/acme/modules.git?depth=1&ref=v1&sparse=modules%2Fvpc"},
+		{"git::https://github.com/acme/modules.git?ref=v1", false, true, "git::https://github.com/acme/modules.git?ref=v1"},
+		{"github.com/acme/modules//vpc", true, true, "git::https://github.com/acme/modules.git?depth=1&sparse=vpc"},
+		{"https://example.com/modules.zip//vpc", true, true, "https://example.com/modules.zip"},
+		{"git::ssh://git@github.com/acme/modules.git//vpc?ref=v1&sshkey=a2V5", true, false, "git::ssh://git@github.com/acme/modules.git?depth=1&ref=v1&sshkey=a2V5"},
+		{"git::ssh://git@github.com/acme/modules.git//vpc?ref=3f5b2a1c9d8e7f6a5b4c3d2e1f0a9b8c7d6e5f4a&sshkey=a2V5", true, false, "git::ssh://git@github.com/acme/modules.git?ref=3f5b2a1c9d8e7f6a5b4c3d2e1f0a9b8c7d6e5f4a&sshkey=a2V5"},
+		{"git::https://github.com/acme/modules.git//vpc?ref=3f5b2a1", true, false, "git::https://github.com/acme/modules.git?depth=1&ref=3f5b2a1"},
+	}
+
+	for _, testCase := range testCases {
+		terragruntOptions, err := options.NewTerragruntOptionsForTest("mock-path-for-test.hcl")
+		require.NoError(t, err)
+		terragruntOptions.ShallowClone = testCase.shallowClone
+		terragruntOptions.SparseCheckout = testCase.sparseCheckout
+
+		terraformSource, err := tfsource.NewTerraformSource(testCase.source, "/tmp/download", "/tmp/working", terragruntOptions.Logger)
+		require.NoError(t, err, testCase.source)
+		actual, err := sourceDownloadUrl(terraformSource, terragruntOptions)
+		require.NoError(t, err, testCase.source)
+		assert.Equal(t, testCase.expected, actual, testCase.source)
+	}
+}
@@ -0,0 +1,169 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/hashicorp/go-multierror"
+	"github.com/hashicorp/hcl/v2"
+
+	"github.com/gruntwork-io/terragrunt/config"
+	"github.com/gruntwork-io/terragrunt/configstack"
+	"github.com/gruntwork-io/terragrunt/errors"
+	"github.com/gruntwork-io/terragrunt/options"
+)
+
+// The env vars GitHub Actions sets in the jobs it runs. See
+// https://docs.github.com/en/actions/learn-github-actions/variables#default-environment-variables
+const (
+	GITHUB_STEP_SUMMARY_ENV_VAR = "GITHUB_STEP_SUMMARY"
+	GITHUB_WORKSPACE_ENV_VAR    = "GITHUB_WORKSPACE"
+)
+
+// The title of the annotations of the errors that aren't about a specific place in the Terragrunt configuration
+const gitHubAnnotationTitle = "Terragrunt"
+
+// Return the file GitHub Actions shows as the summary of the job, if the GitHub Actions output mode is enabled via
+// --terragrunt-github-actions and Terragrunt runs in a GitHub Actions job. Returns an empty string otherwise.
+func gitHubStepSummaryPath(terragruntOptions *options.TerragruntOptions) string {
+	if !terragruntOptions.GitHubActions {
+		return ""
+	}
+	return os.Getenv(GITHUB_STEP_SUMMARY_ENV_VAR)
+}
+
+// Print the given error of the command, if any, and the warnings about the Terragrunt configuration as GitHub Actions
+// workflow commands, which show up as annotations in the job and on the files of pull requests. The diagnostics of the
+// Terragrunt configuration, such as a syntax error or a reference to a local that doesn't exist, are annotated with the
+// file and lines they're about. See
+// https://docs.github.com/en/actions/using-workflows/workflow-commands-for-github-actions#setting-an-error-message
+func printGitHubAnnotations(writer io.Writer, commandErr error, terragruntOptions *options.TerragruntOptions) error {
+	rootDir := os.Getenv(GITHUB_WORKSPACE_ENV_VAR)
+	if rootDir == "" {
+		rootDir = unitsRootDir(terragruntOptions)
+	}
+
+	diagnostics, otherErrs := collectDiagnostics(commandErr)
+	diagnostics = append(diagnostics, config.ConfigWarnings()...)
+	annotations := []string{}
+	for _, diagnostic := range diagnostics {
+		annotations = append(annotations, diagnosticAnnotation(diagnostic, rootDir))
+	}
+	for _, err := range otherErrs {
+		annotations = append(annotations, gitHubAnnotation("error", map[string]string{"title": gitHubAnnotationTitle}, err.Error()))
+	}
+
+	// The error of a unit shows up again in the errors of the units depending on it, so each annotation is only printed
+	// once
+	printed := map[string]bool{}
+	for _, annotation := range annotations {
+		if printed[annotation] {
+			continue
+		}
+		printed[annotation] = true
+		if _, err := fmt.Fprintln(writer, annotation); err != nil {
+			return errors.WithStackTrace(err)
+		}
+	}
+	return nil
+}
+
+// Return the HCL diagnostics in the given error, which may combine the errors of many units, along with the errors
+// that aren't diagnostics. The errors of the units of *-all commands, and of the units whose dependencies failed, are
+// unwrapped to find the diagnostics they wrap.
+func collectDiagnostics(err error) (hcl.Diagnostics, []error) {
+	switch underlyingErr := errors.Unwrap(err).(type) {
+	case nil:
+		return nil, nil
+	case hcl.Diagnostics:
+		return underlyingErr, nil
+	case *hcl.Diagnostic:
+		return hcl.Diagnostics{underlyingErr}, nil
+	case configstack.ErrorProcessingModule:
+		return collectWrappedDiagnostics(err, underlyingErr.UnderlyingError)
+	case configstack.DependencyFinishedWithError:
+		return collectWrappedDiagnostics(err, underlyingErr.Err)
+	case *multierror.Error:
+		diagnostics := hcl.Diagnostics{}
+		otherErrs := []error{}
+		for _, wrappedErr := range underlyingErr.Errors {
+			wrappedDiagnostics, wrappedOtherErrs := collectDiagnostics(wrappedErr)
+			diagnostics = append(diagnostics, wrappedDiagnostics...)
+			otherErrs = append(otherErrs, wrappedOtherErrs...)
+		}
+		return diagnostics, otherErrs
+	default:
+		return nil, []error{err}
+	}
+}
+
+// Return the HCL diagnostics in the given error wrapped by the given error, or else the given error itself, which says
+// which unit the error is about
+func collectWrappedDiagnostics(err error, wrappedErr error) (hcl.Diagnostics, []error) {
+	diagnostics, otherErrs := collectDiagnostics(wrappedErr)
+	if len(diagnostics) == 0 {
+		return nil, []error{err}
+	}
+	return diagnostics, otherErrs
+}
+
+// Render the given diagnostic as a GitHub Actions annotation, with its file relative to the given root dir
+func diagnosticAnnotation(diagnostic *hcl.Diagnostic, rootDir string) string {
+	command := "error"
+	if diagnostic.Severity == hcl.DiagWarning {
+		command = "warning"
+	}
+
+	parameters := map[string]string{"title": diagnostic.Summary}
+	if subject := diagnostic.Subject; subject != nil && subject.Filename != "" {
+		parameters["file"] = annotationFilePath(subject.Filename, rootDir)
+		parameters["line"] = fmt.Sprint(subject.Start.Line)
+		parameters["col"] = fmt.Sprint(subject.Start.Column)
+		parameters["endLine"] = fmt.Sprint(subject.End.Line)
+		parameters["endColumn"] = fmt.Sprint(subject.End.Column)
+	}
+
+	message := diagnostic.Detail
+	if message == "" {
+		message = diagnostic.Summary
+	}
+	return gitHubAnnotation(command, parameters, message)
+}
+
+// GitHub only matches annotations to the files of the repo if their paths are relative to the workspace
+func annotationFilePath(path string, rootDir string) string {
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return filepath.ToSlash(path)
+	}
+	relPath, err := filepath.Rel(rootDir, absPath)
+	if err != nil || strings.HasPrefix(relPath, "..") {
+		return filepath.ToSlash(path)
+	}
+	return filepath.ToSlash(relPath)
+}
+
+// Render a GitHub Actions workflow command, e.g. ::error file=app.hcl,line=1,title=Oops::Something went wrong, with the
+// given parameters in a stable order
+func gitHubAnnotation(command string, parameters map[string]string, message string) string {
+	renderedParameters := []string{}
+	for _, name := range []string{"file", "line", "col", "endLine", "endColumn", "title"} {
+		if value, hasValue := parameters[name]; hasValue {
+			renderedParameters = append(renderedParameters, fmt.Sprintf("%s=%s", name, escapeGitHubProperty(value)))
+		}
+	}
+	return fmt.Sprintf("::%s %s::%s", command, strings.Join(renderedParameters, ","), escapeGitHubData(message))
+}
+
+// Escape the message of a workflow command, which can't contain line breaks
+func escapeGitHubData(value string) string {
+	return strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A").Replace(value)
+}
+
+// Escape the value of a parameter of a workflow command, which can't contain the separators of the parameters either
+func escapeGitHubProperty(value string) string {
+	return strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A", ":", "%3A", ",", "%2C").Replace(value)
+}
@@ -0,0 +1,104 @@
+package cli
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/go-multierror"
+	"github.com/hashicorp/hcl/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/gruntwork-io/terragrunt/configstack"
+	"github.com/gruntwork-io/terragrunt/errors"
+	"github.com/gruntwork-io/terragrunt/options"
+)
+
+func TestPrintGitHubAnnotations(t *testing.T) {
+	t.Parallel()
+
+	workingDir, err := os.Getwd()
+	require.NoError(t, err)
+	terragruntOptions, err := options.NewTerragruntOptionsForTest(filepath.Join(workingDir, "terragrunt.hcl"))
+	require.NoError(t, err)
+	// The paths are relative to the workspace when the tests themselves run in GitHub Actions
+	rootDir := os.Getenv(GITHUB_WORKSPACE_ENV_VAR)
+	if rootDir == "" {
+		rootDir = unitsRootDir(terragruntOptions)
+	}
+	configPath := filepath.Join(rootDir, "live", "app", "terragrunt.hcl")
+
+	syntaxErr := hcl.Diagnostics{
+		{
+			Severity: hcl.DiagError,
+			Summary:  "Unsupported attribute",
+			Detail:   "This object does not have an attribute named \"vpc_id\".\nDid you mean \"vpc\"?",
+			Subject: &hcl.Range{
+				Filename: configPath,
+				Start:    hcl.Pos{Line: 12, Column: 3},
+				End:      hcl.Pos{Line: 12, Column: 30},
+			},
+		},
+		{
+			Severity: hcl.DiagWarning,
+			Summary:  "Deprecated: use inputs",
+			Subject: &hcl.Range{
+				Filename: configPath,
+				Start:    hcl.Pos{Line: 2, Column: 1},
+				End:      hcl.Pos{Line: 4, Column: 2},
+			},
+		},
+	}
+	var commandErr *multierror.Error
+	commandErr = multierror.Append(commandErr, errors.WithStackTrace(syntaxErr))
+	commandErr = multierror.Append(commandErr, errors.WithStackTrace(fmt.Errorf("exit status 1, 50%% done")))
+
+	var output bytes.Buffer
+	require.NoError(t, printGitHubAnnotations(&output, errors.WithStackTrace(commandErr), terragruntOptions))
+
+	lines := strings.Split(strings.TrimSpace(output.String()), "\n")
+	require.Len(t, lines, 3)
+	assert.Equal(t, "::error file=live/app/terragrunt.hcl,line=12,col=3,endLine=12,endColumn=30,title=Unsupported attribute::This object does not have an attribute named \"vpc_id\".%0ADid you mean \"vpc\"?", lines[0])
+	assert.Equal(t, "::warning file=live/app/terragrunt.hcl,line=2,col=1,endLine=4,endColumn=2,title=Deprecated%3A use inputs::Deprecated: use inputs", lines[1])
+	assert.Equal(t, "::error title=Terragrunt::exit status 1, 50%25 done", lines[2])
+}
+
+func TestCollectDiagnosticsUnwrapsModuleErrors(t *testing.T) {
+	t.Parallel()
+
+	diagnostic := &hcl.Diagnostic{Severity: hcl.DiagError, Summary: "Unsupported attribute"}
+	moduleErr := errors.WithStackTrace(configstack.ErrorProcessingModule{
+		UnderlyingError:       errors.WithStackTrace(hcl.Diagnostics{diagnostic}),
+		ModulePath:            "/repo/live/app/terragrunt.hcl",
+		HowThisModuleWasFound: "Terragrunt config file found in a subdirectory of /repo/live",
+	})
+	dependencyErr := configstack.DependencyFinishedWithError{Err: errors.WithStackTrace(fmt.Errorf("exit status 1"))}
+
+	var commandErr *multierror.Error
+	commandErr = multierror.Append(commandErr, moduleErr)
+	commandErr = multierror.Append(commandErr, dependencyErr)
+
+	diagnostics, otherErrs := collectDiagnostics(errors.WithStackTrace(commandErr))
+	assert.Equal(t, hcl.Diagnostics{diagnostic}, diagnostics)
+	// Without diagnostics to annotate, the error of the unit whose dependency failed is kept as is
+	assert.Equal(t, []error{dependencyErr}, otherErrs)
+}
+
+func TestAnnotationFilePath(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, "live/vpc/terragrunt.hcl", annotationFilePath("/repo/live/vpc/terragrunt.hcl", "/repo"))
+	assert.Equal(t, "/other/terragrunt.hcl", annotationFilePath("/other/terragrunt.hcl", "/repo"))
+}
+
+func TestGitHubStepSummaryPathRequiresGitHubActionsMode(t *testing.T) {
+	t.Parallel()
+
+	terragruntOptions, err := options.NewTerragruntOptionsForTest("/repo/terragrunt.hcl")
+	require.NoError(t, err)
+	assert.Empty(t, gitHubStepSummaryPath(terragruntOptions))
+}
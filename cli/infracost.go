@@ -0,0 +1,139 @@
+package cli
+
+import (
+	"io/ioutil"
+	"os"
+
+	"github.com/gruntwork-io/terragrunt/errors"
+	"github.com/gruntwork-io/terragrunt/infracost"
+	"github.com/gruntwork-io/terragrunt/options"
+	"github.com/gruntwork-io/terragrunt/shell"
+)
+
+// The binary of Infracost, which the cost of the plans is estimated with
+const INFRACOST_BINARY = "infracost"
+
+// Start estimating the cost of the plans of the units the command runs, if Infracost is enabled via
+// --terragrunt-infracost or --terragrunt-infracost-report. Returns a function that prints the total change in the
+// monthly cost of the units, and writes it to the report file and the job summary of GitHub Actions if they're set,
+// which should be called once the command finishes.
+func startInfracost(terragruntOptions *options.TerragruntOptions) func() {
+	if !terragruntOptions.Infracost {
+		return func() {}
+	}
+
+	report := infracost.NewReport(unitsRootDir(terragruntOptions))
+	terragruntOptions.CostReport = report
+
+	return func() {
+		totals, err := report.Totals()
+		if err != nil {
+			terragruntOptions.Logger.Warnf("Could not add up the costs of the units: %v", err)
+			return
+		}
+		if len(totals.Units) == 0 {
+			return
+		}
+
+		terragruntOptions.Logger.Infof("Monthly cost change of %d units, as estimated by Infracost: %s", len(totals.Units), totals.Summary())
+
+		if terragruntOptions.InfracostReportPath != "" {
+			if err := totals.Write(terragruntOptions.InfracostReportPath); err != nil {
+				terragruntOptions.Logger.Warnf("Could not write the Infracost report to %s: %v", terragruntOptions.InfracostReportPath, err)
+			}
+		}
+		if stepSummaryPath := gitHubStepSummaryPath(terragruntOptions); stepSummaryPath != "" {
+			if err := appendToFile(stepSummaryPath, totals.Markdown()); err != nil {
+				terragruntOptions.Logger.Warnf("Could not write the costs of the units to the job summary: %v", err)
+			}
+		}
+	}
+}
+
+// Make the plan command of the unit of the given options save its plan, so that its cost can be estimated once it
+// finishes, by adding -out with a temp file to it, unless it already saves the plan. Returns a function that removes
+// the temp file, which should be called once the cost is estimated.
+func prepareCostEstimate(terragruntOptions *options.TerragruntOptions) (func(), error) {
+	if !shouldEstimateCost(terragruntOptions) {
+		return func() {}, nil
+	}
+	return savePlanToTempFile(terragruntOptions, "terragrunt-infracost-*.tfplan")
+}
+
+// Make the plan command of the unit of the given options save its plan to a temp file matching the given pattern, by
+// adding -out with it, unless it already saves the plan. Returns a function that removes the temp file, if any.
+func savePlanToTempFile(terragruntOptions *options.TerragruntOptions, pattern string) (func(), error) {
+	if planOutFile(terragruntOptions.TerraformCliArgs) != "" {
+		return func() {}, nil
+	}
+
+	planFile, err := ioutil.TempFile("", pattern)
+	if err != nil {
+		return nil, errors.WithStackTrace(err)
+	}
+	if err := planFile.Close(); err != nil {
+		return nil, errors.WithStackTrace(err)
+	}
+	terragruntOptions.AppendTerraformCliArgs("-out=" + planFile.Name())
+	return func() { os.Remove(planFile.Name()) }, nil
+}
+
+// Estimate the cost of the plan the unit of the given options just saved, and add it to the cost report of the run. A
+// unit whose cost can't be estimated, e.g. because Infracost isn't installed, doesn't fail the run, so it's only logged.
+func estimateCost(terragruntOptions *options.TerragruntOptions) {
+	planFile := planOutFile(terragruntOptions.TerraformCliArgs)
+	if !shouldEstimateCost(terragruntOptions) || planFile == "" {
+		return
+	}
+
+	breakdown, err := infracostBreakdown(terragruntOptions, planFile)
+	if err != nil {
+		terragruntOptions.Logger.Warnf("Could not estimate the cost of the plan of %s with Infracost: %v", terragruntOptions.WorkingDir, err)
+		return
+	}
+	terragruntOptions.CostReport.Add(terragruntOptions.TerragruntConfigPath, breakdown)
+}
+
+func shouldEstimateCost(terragruntOptions *options.TerragruntOptions) bool {
+	return terragruntOptions.CostReport != nil && isMainPlanRun(terragruntOptions)
+}
+
+// Run infracost breakdown on the JSON of the given plan file, as printed by terraform show -json
+func infracostBreakdown(terragruntOptions *options.TerragruntOptions, planFile string) (*infracost.Breakdown, error) {
+	out, err := shell.RunShellCommandWithOutput(terragruntOptions, "", true, false, terragruntOptions.TerraformPath, "show", "-json", planFile)
+	if err != nil {
+		return nil, err
+	}
+
+	planJsonFile, err := ioutil.TempFile("", "terragrunt-infracost-*.json")
+	if err != nil {
+		return nil, errors.WithStackTrace(err)
+	}
+	defer os.Remove(planJsonFile.Name())
+	if _, err := planJsonFile.WriteString(out.Stdout); err != nil {
+		planJsonFile.Close()
+		return nil, errors.WithStackTrace(err)
+	}
+	if err := planJsonFile.Close(); err != nil {
+		return nil, errors.WithStackTrace(err)
+	}
+
+	out, err = shell.RunShellCommandWithOutput(terragruntOptions, "", true, false, INFRACOST_BINARY, "breakdown", "--path", planJsonFile.Name(), "--format", "json", "--no-color")
+	if err != nil {
+		return nil, err
+	}
+	return infracost.ParseBreakdown([]byte(out.Stdout))
+}
+
+// Append the given contents to the given file, creating it if it doesn't exist
+func appendToFile(path string, contents string) error {
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return errors.WithStackTrace(err)
+	}
+	if _, err := file.WriteString(contents); err != nil {
+		file.Close()
+		return errors.WithStackTrace(err)
+	}
+	return errors.WithStackTrace(file.Close())
+}
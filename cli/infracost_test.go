@@ -0,0 +1,47 @@
+package cli
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/gruntwork-io/terragrunt/config"
+	"github.com/gruntwork-io/terragrunt/infracost"
+	"github.com/gruntwork-io/terragrunt/options"
+	"github.com/gruntwork-io/terragrunt/util"
+)
+
+func TestPrepareCostEstimate(t *testing.T) {
+	t.Parallel()
+
+	terragruntOptions, err := options.NewTerragruntOptionsForTest(util.JoinPath("/repo/app", config.DefaultTerragruntConfigPath))
+	require.NoError(t, err)
+	terragruntOptions.CostReport = infracost.NewReport("/repo")
+	terragruntOptions.TerraformCommand = "plan"
+	terragruntOptions.OriginalTerraformCommand = "plan"
+
+	terragruntOptions.TerraformCliArgs = []string{"plan", "-input=false"}
+	removeCostPlan, err := prepareCostEstimate(terragruntOptions)
+	require.NoError(t, err)
+	planFile := planOutFile(terragruntOptions.TerraformCliArgs)
+	assert.True(t, util.FileExists(planFile))
+	removeCostPlan()
+	assert.False(t, util.FileExists(planFile))
+
+	terragruntOptions.TerraformCliArgs = []string{"plan", "-out=tfplan"}
+	_, err = prepareCostEstimate(terragruntOptions)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"plan", "-out=tfplan"}, terragruntOptions.TerraformCliArgs)
+
+	terragruntOptions.TerraformCliArgs = []string{"apply"}
+	_, err = prepareCostEstimate(terragruntOptions)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"apply"}, terragruntOptions.TerraformCliArgs)
+
+	terragruntOptions.CostReport = nil
+	terragruntOptions.TerraformCliArgs = []string{"plan"}
+	_, err = prepareCostEstimate(terragruntOptions)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"plan"}, terragruntOptions.TerraformCliArgs)
+}
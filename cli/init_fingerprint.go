@@ -0,0 +1,182 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+
+	"github.com/gruntwork-io/terragrunt/config"
+	"github.com/gruntwork-io/terragrunt/errors"
+	"github.com/gruntwork-io/terragrunt/options"
+	"github.com/gruntwork-io/terragrunt/remote"
+	"github.com/gruntwork-io/terragrunt/util"
+)
+
+// The name of the file, in the Terraform data dir (.terraform by default), where the fingerprint of the last init is
+// stored. As it's in the data dir, removing that dir also removes the fingerprint.
+const INIT_FINGERPRINT_FILE = "terragrunt-init-fingerprint.json"
+
+// initFingerprint captures what running 'terraform init' in a working dir depends on. If none of it changed since the
+// last init, and the modules and providers it installed are still there, Auto-Init can skip checking whether init
+// needs to run again, some of which, such as checking the remote state storage exists, takes a while.
+type initFingerprint struct {
+	// The hash of the remote state config, and of the extra args and env vars passed to init
+	Backend string `json:"backend"`
+	// The hash of the backend config init stored in the data dir, which changes when init is run outside of
+	// Terragrunt, e.g. terraform init -reconfigure
+	BackendState string `json:"backend_state"`
+	// The hash of the Terraform files in the working dir, which declare the module sources and the provider constraints
+	Modules string `json:"modules"`
+	// The hash of the lock file of the providers and of the terraform binary used to install them
+	Providers string `json:"providers"`
+}
+
+// Return true if the fingerprint of the last init in the working dir matches the current one
+func initFingerprintMatches(terragruntOptions *options.TerragruntOptions, terragruntConfig *config.TerragruntConfig) (bool, error) {
+	previous, err := readInitFingerprint(terragruntOptions)
+	if err != nil || previous == nil {
+		return false, err
+	}
+
+	current, err := computeInitFingerprint(terragruntOptions, terragruntConfig)
+	if err != nil {
+		return false, err
+	}
+
+	if *previous != *current {
+		terragruntOptions.Logger.Debugf("The init fingerprint of %s changed since the last init", terragruntOptions.WorkingDir)
+		return false, nil
+	}
+	return true, nil
+}
+
+// Returns true if the given command is an init that fully initializes the working dir, including the backend, after
+// which its fingerprint is recorded
+func shouldWriteInitFingerprint(terragruntOptions *options.TerragruntOptions) bool {
+	return util.FirstArg(terragruntOptions.TerraformCliArgs) == CMD_INIT &&
+		!terragruntOptions.PrefetchOnly &&
+		!util.ListContainsElement(terragruntOptions.TerraformCliArgs, "-backend=false")
+}
+
+// Record the fingerprint of the working dir, once it's initialized, so that the next commands can tell whether
+// anything init depends on changed since
+func writeInitFingerprint(terragruntOptions *options.TerragruntOptions, terragruntConfig *config.TerragruntConfig) error {
+	fingerprint, err := computeInitFingerprint(terragruntOptions, terragruntConfig)
+	if err != nil {
+		return err
+	}
+
+	contents, err := json.Marshal(fingerprint)
+	if err != nil {
+		return errors.WithStackTrace(err)
+	}
+
+	if err := util.EnsureDirectory(terragruntOptions.DataDir()); err != nil {
+		return err
+	}
+	return errors.WithStackTrace(ioutil.WriteFile(initFingerprintPath(terragruntOptions), contents, 0644))
+}
+
+// Read the fingerprint of the last init in the working dir. Returns nil if there is none, or if it can't be parsed,
+// e.g. because it was written by another version of Terragrunt.
+func readInitFingerprint(terragruntOptions *options.TerragruntOptions) (*initFingerprint, error) {
+	path := initFingerprintPath(terragruntOptions)
+	if !util.FileExists(path) {
+		return nil, nil
+	}
+
+	contents, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, errors.WithStackTrace(err)
+	}
+
+	var fingerprint initFingerprint
+	if err := json.Unmarshal(contents, &fingerprint); err != nil {
+		terragruntOptions.Logger.Debugf("Ignoring the init fingerprint in %s, as it can't be parsed: %v", path, err)
+		return nil, nil
+	}
+	return &fingerprint, nil
+}
+
+func computeInitFingerprint(terragruntOptions *options.TerragruntOptions, terragruntConfig *config.TerragruntConfig) (*initFingerprint, error) {
+	backend, err := json.Marshal(struct {
+		RemoteState *remote.RemoteState
+		InitArgs    []config.TerraformExtraArguments
+	}{terragruntConfig.RemoteState, initExtraArgs(terragruntConfig)})
+	if err != nil {
+		return nil, errors.WithStackTrace(err)
+	}
+
+	modules, err := hashTerraformFiles(terragruntOptions.WorkingDir)
+	if err != nil {
+		return nil, err
+	}
+
+	lockFile, err := readFileIfExists(util.JoinPath(terragruntOptions.WorkingDir, util.TerraformLockFile))
+	if err != nil {
+		return nil, err
+	}
+
+	backendState, err := readFileIfExists(util.JoinPath(terragruntOptions.DataDir(), "terraform.tfstate"))
+	if err != nil {
+		return nil, err
+	}
+
+	return &initFingerprint{
+		Backend:      util.EncodeBase64Sha1(string(backend)),
+		BackendState: util.EncodeBase64Sha1(backendState),
+		Modules:      modules,
+		Providers:    util.EncodeBase64Sha1(terragruntOptions.TerraformPath + "\n" + lockFile),
+	}, nil
+}
+
+// Return the contents of the file at the given path, or an empty string if it doesn't exist
+func readFileIfExists(path string) (string, error) {
+	if !util.FileExists(path) {
+		return "", nil
+	}
+	return util.ReadFileAsString(path)
+}
+
+// Return the extra_arguments blocks of the config that apply to init
+func initExtraArgs(terragruntConfig *config.TerragruntConfig) []config.TerraformExtraArguments {
+	initArgs := []config.TerraformExtraArguments{}
+	if terragruntConfig.Terraform == nil {
+		return initArgs
+	}
+	for _, extraArgs := range terragruntConfig.Terraform.ExtraArgs {
+		if util.ListContainsElement(extraArgs.Commands, CMD_INIT) {
+			initArgs = append(initArgs, extraArgs)
+		}
+	}
+	return initArgs
+}
+
+// Return a hash of the names and contents of the Terraform files in the given dir
+func hashTerraformFiles(dir string) (string, error) {
+	files := []string{}
+	for _, glob := range []string{TERRAFORM_EXTENSION_GLOB, TERRAFORM_EXTENSION_GLOB + ".json"} {
+		matches, err := filepath.Glob(util.JoinPath(dir, glob))
+		if err != nil {
+			return "", errors.WithStackTrace(err)
+		}
+		files = append(files, matches...)
+	}
+	sort.Strings(files)
+
+	contents := ""
+	for _, file := range files {
+		fileContents, err := util.ReadFileAsString(file)
+		if err != nil {
+			return "", err
+		}
+		contents += fmt.Sprintf("%s\n%s\n", filepath.Base(file), util.EncodeBase64Sha1(fileContents))
+	}
+	return util.EncodeBase64Sha1(contents), nil
+}
+
+func initFingerprintPath(terragruntOptions *options.TerragruntOptions) string {
+	return util.JoinPath(terragruntOptions.DataDir(), INIT_FINGERPRINT_FILE)
+}
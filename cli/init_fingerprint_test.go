@@ -0,0 +1,137 @@
+package cli
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/gruntwork-io/terragrunt/config"
+	"github.com/gruntwork-io/terragrunt/options"
+	"github.com/gruntwork-io/terragrunt/remote"
+	"github.com/gruntwork-io/terragrunt/util"
+)
+
+func TestInitFingerprintMatches(t *testing.T) {
+	t.Parallel()
+
+	workingDir, err := ioutil.TempDir("", "init-fingerprint")
+	require.NoError(t, err)
+	defer os.RemoveAll(workingDir)
+	require.NoError(t, ioutil.WriteFile(util.JoinPath(workingDir, "main.tf"), []byte(`module "vpc" { source = "./vpc" }`), 0644))
+
+	terragruntOptions := initFingerprintOptionsForTest(t, workingDir)
+	terragruntConfig := &config.TerragruntConfig{
+		RemoteState: &remote.RemoteState{Backend: "s3", Config: map[string]interface{}{"bucket": "my-bucket", "key": "terraform.tfstate"}},
+	}
+
+	// Without a previous init, there's nothing to compare to
+	matches, err := initFingerprintMatches(terragruntOptions, terragruntConfig)
+	require.NoError(t, err)
+	assert.False(t, matches)
+
+	require.NoError(t, writeInitFingerprint(terragruntOptions, terragruntConfig))
+	matches, err = initFingerprintMatches(terragruntOptions, terragruntConfig)
+	require.NoError(t, err)
+	assert.True(t, matches)
+
+	// Changing the backend config changes the fingerprint
+	changedConfig := &config.TerragruntConfig{
+		RemoteState: &remote.RemoteState{Backend: "s3", Config: map[string]interface{}{"bucket": "other-bucket", "key": "terraform.tfstate"}},
+	}
+	matches, err = initFingerprintMatches(terragruntOptions, changedConfig)
+	require.NoError(t, err)
+	assert.False(t, matches)
+
+	// So does changing the Terraform files
+	require.NoError(t, ioutil.WriteFile(util.JoinPath(workingDir, "providers.tf"), []byte(`provider "aws" {}`), 0644))
+	matches, err = initFingerprintMatches(terragruntOptions, terragruntConfig)
+	require.NoError(t, err)
+	assert.False(t, matches)
+
+	// And running init outside of Terragrunt, which changes the backend config in the data dir
+	require.NoError(t, writeInitFingerprint(terragruntOptions, terragruntConfig))
+	require.NoError(t, os.MkdirAll(terragruntOptions.DataDir(), 0755))
+	require.NoError(t, ioutil.WriteFile(util.JoinPath(terragruntOptions.DataDir(), "terraform.tfstate"), []byte(`{"backend": {"type": "local"}}`), 0644))
+	matches, err = initFingerprintMatches(terragruntOptions, terragruntConfig)
+	require.NoError(t, err)
+	assert.False(t, matches)
+
+	// And using another terraform binary
+	require.NoError(t, writeInitFingerprint(terragruntOptions, terragruntConfig))
+	terragruntOptions.TerraformPath = "/opt/terraform/bin/terraform"
+	matches, err = initFingerprintMatches(terragruntOptions, terragruntConfig)
+	require.NoError(t, err)
+	assert.False(t, matches)
+}
+
+func TestNeedsInitWithMatchingInitFingerprint(t *testing.T) {
+	t.Parallel()
+
+	workingDir, err := ioutil.TempDir("", "init-fingerprint")
+	require.NoError(t, err)
+	defer os.RemoveAll(workingDir)
+	require.NoError(t, ioutil.WriteFile(util.JoinPath(workingDir, "main.tf"), []byte(`module "vpc" { source = "./vpc" }`), 0644))
+
+	terragruntOptions := initFingerprintOptionsForTest(t, workingDir)
+	terragruntOptions.TerraformCliArgs = []string{"plan"}
+	terragruntConfig := &config.TerragruntConfig{}
+	require.NoError(t, os.MkdirAll(util.JoinPath(terragruntOptions.DataDir(), "providers"), 0755))
+
+	// The modules haven't been downloaded, so init is needed
+	initNeeded, err := needsInit(terragruntOptions, terragruntConfig)
+	require.NoError(t, err)
+	assert.True(t, initNeeded)
+
+	// Only running init records the fingerprint, not finding that init isn't needed
+	require.NoError(t, os.MkdirAll(util.JoinPath(terragruntOptions.DataDir(), "modules"), 0755))
+	require.NoError(t, ioutil.WriteFile(util.JoinPath(terragruntOptions.DataDir(), "modules", "modules.json"), []byte(`{"Modules": [{"Key": "vpc", "Source": "./vpc"}]}`), 0644))
+	_, err = needsInit(terragruntOptions, terragruntConfig)
+	require.NoError(t, err)
+	assert.False(t, util.FileExists(initFingerprintPath(terragruntOptions)))
+
+	// Once init ran, nothing changed since, so it's not needed again
+	require.NoError(t, writeInitFingerprint(terragruntOptions, terragruntConfig))
+	initNeeded, err = needsInit(terragruntOptions, terragruntConfig)
+	require.NoError(t, err)
+	assert.False(t, initNeeded)
+
+	// Unless the providers are gone
+	require.NoError(t, os.RemoveAll(util.JoinPath(terragruntOptions.DataDir(), "providers")))
+	initNeeded, err = needsInit(terragruntOptions, terragruntConfig)
+	require.NoError(t, err)
+	assert.True(t, initNeeded)
+}
+
+func TestShouldWriteInitFingerprint(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		args         []string
+		prefetchOnly bool
+		expected     bool
+	}{
+		{[]string{"init"}, false, true},
+		{[]string{"init", "-upgrade"}, false, true},
+		{[]string{"init", "-backend=false"}, false, false},
+		{[]string{"init"}, true, false},
+		{[]string{"plan"}, false, false},
+	}
+
+	for _, testCase := range testCases {
+		terragruntOptions, err := options.NewTerragruntOptionsForTest("mock-path-for-test.hcl")
+		require.NoError(t, err)
+		terragruntOptions.TerraformCliArgs = testCase.args
+		terragruntOptions.PrefetchOnly = testCase.prefetchOnly
+		assert.Equal(t, testCase.expected, shouldWriteInitFingerprint(terragruntOptions), "For args %v", testCase.args)
+	}
+}
+
+func initFingerprintOptionsForTest(t *testing.T, workingDir string) *options.TerragruntOptions {
+	terragruntOptions, err := options.NewTerragruntOptionsForTest(util.JoinPath(workingDir, config.DefaultTerragruntConfigPath))
+	require.NoError(t, err)
+	terragruntOptions.WorkingDir = workingDir
+	return terragruntOptions
+}
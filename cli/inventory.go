@@ -0,0 +1,101 @@
+package cli
+
+import (
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/gruntwork-io/terragrunt/configstack"
+	"github.com/gruntwork-io/terragrunt/errors"
+	"github.com/gruntwork-io/terragrunt/options"
+	"github.com/gruntwork-io/terragrunt/util"
+)
+
+// The files the inventory of the stack is written to, per format
+var INVENTORY_FILES = map[string]string{
+	configstack.InventoryFormatJson:      "terragrunt-inventory.json",
+	configstack.InventoryFormatCycloneDX: "terragrunt-inventory.cdx.json",
+}
+
+func shouldExportInventory(terragruntOptions *options.TerragruntOptions) bool {
+	return util.FirstArg(terragruntOptions.TerraformCliArgs) == CMD_EXPORT_INVENTORY
+}
+
+// Write the inventory of the modules and providers the modules in the working dir or its subfolders use, in the format
+// given as the argument of the command, json by default, or cyclonedx for a CycloneDX BOM, to a file in the working dir,
+// which should be the root of the repo. See configstack.Stack.Inventory for what the inventory contains.
+func exportInventory(terragruntOptions *options.TerragruntOptions) error {
+	format := configstack.InventoryFormatJson
+	if args := terragruntOptions.TerraformCliArgs; len(args) > 1 {
+		format = args[1]
+	}
+	if !util.ListContainsElement(configstack.InventoryFormats, format) {
+		return errors.WithStackTrace(UnsupportedInventoryFormat(format))
+	}
+
+	// The external dependencies are outside of the repo, so there's no need to ask whether to run them
+	terragruntOptions.IgnoreExternalDependencies = true
+
+	stack, err := configstack.FindStackInSubfolders(terragruntOptions)
+	if err != nil {
+		return err
+	}
+
+	inventory, unitsWithoutLockFile, err := stack.Inventory(terragruntOptions.WorkingDir)
+	if err != nil {
+		return err
+	}
+	if len(unitsWithoutLockFile) > 0 {
+		terragruntOptions.Logger.Warnf("The providers of these modules are unknown, as they have no %s. Run terragrunt run-all init to create it:\n  - %s", util.TerraformLockFile, strings.Join(unitsWithoutLockFile, "\n  - "))
+	}
+
+	var document interface{} = inventory
+	if format == configstack.InventoryFormatCycloneDX {
+		terragruntVersion := ""
+		if terragruntOptions.TerragruntVersion != nil {
+			terragruntVersion = terragruntOptions.TerragruntVersion.String()
+		}
+		bom := inventory.CycloneDX(terragruntOptions.WorkingDir, terragruntVersion, time.Now())
+		bom.SerialNumber, err = newSerialNumber()
+		if err != nil {
+			return err
+		}
+		document = bom
+	}
+
+	contents, err := json.MarshalIndent(document, "", "  ")
+	if err != nil {
+		return errors.WithStackTrace(err)
+	}
+	inventoryPath := filepath.Join(terragruntOptions.WorkingDir, INVENTORY_FILES[format])
+	if err := ioutil.WriteFile(inventoryPath, append(contents, '\n'), 0644); err != nil {
+		return errors.WithStackTrace(err)
+	}
+
+	terragruntOptions.Logger.Infof("Wrote the inventory of %d modules and %d providers of %d units to %s", len(inventory.Modules), len(inventory.Providers), len(inventory.Units), inventoryPath)
+	return nil
+}
+
+// Return a random urn:uuid serial number for a CycloneDX BOM, as the spec expects a new one for every BOM
+func newSerialNumber() (string, error) {
+	uuid := make([]byte, 16)
+	if _, err := rand.Read(uuid); err != nil {
+		return "", errors.WithStackTrace(err)
+	}
+	// Set the version 4 and the RFC 4122 variant bits
+	uuid[6] = (uuid[6] & 0x0f) | 0x40
+	uuid[8] = (uuid[8] & 0x3f) | 0x80
+	return fmt.Sprintf("urn:uuid:%x-%x-%x-%x-%x", uuid[0:4], uuid[4:6], uuid[6:8], uuid[8:10], uuid[10:]), nil
+}
+
+// Custom error types
+
+type UnsupportedInventoryFormat string
+
+func (format UnsupportedInventoryFormat) Error() string {
+	return fmt.Sprintf("Expected the format of the inventory, one of %s, but got '%s'", strings.Join(configstack.InventoryFormats, ", "), string(format))
+}
@@ -0,0 +1,124 @@
+package cli
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/gruntwork-io/terragrunt/config"
+	"github.com/gruntwork-io/terragrunt/configstack"
+	"github.com/gruntwork-io/terragrunt/errors"
+	"github.com/gruntwork-io/terragrunt/options"
+	"github.com/gruntwork-io/terragrunt/util"
+)
+
+const testInventoryLockFile = `provider "registry.terraform.io/hashicorp/aws" {
+  version     = "5.1.0"
+  constraints = "~> 5.0"
+  hashes = [
+    "h1:abc=",
+    "zh:0123456789abcdef",
+  ]
+}
+`
+
+func createInventoryTestStack(t *testing.T) string {
+	rootPath, err := ioutil.TempDir("", "export-inventory")
+	require.NoError(t, err)
+
+	files := map[string]string{
+		"live/vpc/terragrunt.hcl":            "terraform {\n  source = \"git::https://github.com/acme/modules.git//vpc?ref=v1.2.0\"\n}\n",
+		"live/vpc/" + util.TerraformLockFile: testInventoryLockFile,
+		"live/app/terragrunt.hcl":            "terraform {\n  source = \"tfr:///terraform-aws-modules/ecs/aws?version=4.0.0\"\n}\n\ndependency \"vpc\" {\n  config_path = \"../vpc\"\n}\n",
+		"live/app/" + util.TerraformLockFile: testInventoryLockFile,
+		"live/legacy/main.tf":                "",
+		"live/legacy/terragrunt.hcl":         "",
+	}
+	for path, contents := range files {
+		filePath := filepath.Join(rootPath, path)
+		require.NoError(t, os.MkdirAll(filepath.Dir(filePath), os.ModePerm))
+		require.NoError(t, ioutil.WriteFile(filePath, []byte(contents), 0644))
+	}
+	return rootPath
+}
+
+func TestExportInventoryJson(t *testing.T) {
+	t.Parallel()
+
+	rootPath := createInventoryTestStack(t)
+	defer os.RemoveAll(rootPath)
+
+	terragruntOptions, err := options.NewTerragruntOptionsForTest(util.JoinPath(rootPath, config.DefaultTerragruntConfigPath))
+	require.NoError(t, err)
+	terragruntOptions.TerraformCliArgs = []string{CMD_EXPORT_INVENTORY}
+	require.True(t, shouldExportInventory(terragruntOptions))
+	require.NoError(t, exportInventory(terragruntOptions))
+
+	contents, err := ioutil.ReadFile(filepath.Join(rootPath, INVENTORY_FILES[configstack.InventoryFormatJson]))
+	require.NoError(t, err)
+	var inventory configstack.Inventory
+	require.NoError(t, json.Unmarshal(contents, &inventory))
+
+	awsProvider := configstack.InventoryUnitProvider{Address: "registry.terraform.io/hashicorp/aws", Version: "5.1.0", Constraints: "~> 5.0"}
+	assert.Equal(t, []configstack.InventoryUnit{
+		{Path: "live/app", Module: "tfr:///terraform-aws-modules/ecs/aws", ModuleVersion: "4.0.0", Providers: []configstack.InventoryUnitProvider{awsProvider}, Dependencies: []string{"live/vpc"}},
+		{Path: "live/legacy", Providers: []configstack.InventoryUnitProvider{}, Dependencies: []string{}},
+		{Path: "live/vpc", Module: "git::https://github.com/acme/modules.git//vpc", ModuleVersion: "v1.2.0", Providers: []configstack.InventoryUnitProvider{awsProvider}, Dependencies: []string{}},
+	}, inventory.Units)
+	assert.Equal(t, []configstack.InventoryModule{
+		{Source: "git::https://github.com/acme/modules.git//vpc", Version: "v1.2.0", Units: []string{"live/vpc"}},
+		{Source: "tfr:///terraform-aws-modules/ecs/aws", Version: "4.0.0", Units: []string{"live/app"}},
+	}, inventory.Modules)
+	assert.Equal(t, []configstack.InventoryProvider{
+		{Address: "registry.terraform.io/hashicorp/aws", Version: "5.1.0", Hashes: []string{"h1:abc=", "zh:0123456789abcdef"}, Units: []string{"live/app", "live/vpc"}},
+	}, inventory.Providers)
+}
+
+func TestExportInventoryCycloneDX(t *testing.T) {
+	t.Parallel()
+
+	rootPath := createInventoryTestStack(t)
+	defer os.RemoveAll(rootPath)
+
+	terragruntOptions, err := options.NewTerragruntOptionsForTest(util.JoinPath(rootPath, config.DefaultTerragruntConfigPath))
+	require.NoError(t, err)
+	terragruntOptions.TerraformCliArgs = []string{CMD_EXPORT_INVENTORY, configstack.InventoryFormatCycloneDX}
+	require.NoError(t, exportInventory(terragruntOptions))
+
+	contents, err := ioutil.ReadFile(filepath.Join(rootPath, INVENTORY_FILES[configstack.InventoryFormatCycloneDX]))
+	require.NoError(t, err)
+	var bom configstack.CycloneDXBom
+	require.NoError(t, json.Unmarshal(contents, &bom))
+
+	assert.Equal(t, "CycloneDX", bom.BomFormat)
+	assert.Regexp(t, `^urn:uuid:[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`, bom.SerialNumber)
+	assert.Len(t, bom.Components, 6)
+	assert.Contains(t, bom.Components, configstack.CycloneDXComponent{
+		Type:       "library",
+		BomRef:     "provider:registry.terraform.io/hashicorp/aws@5.1.0",
+		Group:      "registry.terraform.io/hashicorp",
+		Name:       "aws",
+		Version:    "5.1.0",
+		Hashes:     []configstack.CycloneDXHash{{Alg: "SHA-256", Content: "0123456789abcdef"}},
+		Properties: []configstack.CycloneDXProperty{{Name: "terraform:provider:address", Value: "registry.terraform.io/hashicorp/aws"}},
+	})
+	assert.Contains(t, bom.Dependencies, configstack.CycloneDXDependency{
+		Ref:       "unit:live/app",
+		DependsOn: []string{"module:tfr:///terraform-aws-modules/ecs/aws@4.0.0", "provider:registry.terraform.io/hashicorp/aws@5.1.0", "unit:live/vpc"},
+	})
+	assert.Equal(t, configstack.CycloneDXDependency{Ref: "stack", DependsOn: []string{"unit:live/app", "unit:live/legacy", "unit:live/vpc"}}, bom.Dependencies[0])
+}
+
+func TestExportInventoryUnsupportedFormat(t *testing.T) {
+	t.Parallel()
+
+	terragruntOptions, err := options.NewTerragruntOptionsForTest("mock-path-for-test.hcl")
+	require.NoError(t, err)
+	terragruntOptions.TerraformCliArgs = []string{CMD_EXPORT_INVENTORY, "spdx"}
+	assert.Equal(t, UnsupportedInventoryFormat("spdx"), errors.Unwrap(exportInventory(terragruntOptions)))
+}
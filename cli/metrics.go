@@ -0,0 +1,111 @@
+package cli
+
+import (
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/gruntwork-io/terragrunt/metrics"
+	"github.com/gruntwork-io/terragrunt/options"
+	"github.com/gruntwork-io/terragrunt/shell"
+	"github.com/gruntwork-io/terragrunt/util"
+)
+
+// Start recording metrics about the units the command runs, if an endpoint is set via --terragrunt-metrics-endpoint,
+// if the job summary of GitHub Actions should be written, if a dir for GitLab terraform reports or a file for a JUnit
+// report is set via --terragrunt-gitlab-report-dir or --terragrunt-report-junit, or if an events endpoint is set via
+// --terragrunt-events-endpoint. The units are identified by their path
+// relative to the root of the git repo they're in, or else to the working dir, so that the same unit has the same name
+// on every machine. Returns a function that sends the metrics not sent yet, which should be called once the command
+// finishes.
+func startMetrics(terragruntOptions *options.TerragruntOptions) (func(), error) {
+	stepSummaryPath := gitHubStepSummaryPath(terragruntOptions)
+	if terragruntOptions.MetricsEndpoint == "" && stepSummaryPath == "" && terragruntOptions.GitLabReportDir == "" && terragruntOptions.JUnitReportPath == "" && terragruntOptions.EventsEndpoint == "" {
+		return func() {}, nil
+	}
+
+	recorder := metrics.NewRecorder(unitsRootDir(terragruntOptions))
+	if terragruntOptions.MetricsEndpoint != "" {
+		if err := recorder.AddEndpoint(terragruntOptions.MetricsEndpoint); err != nil {
+			return nil, err
+		}
+	}
+	if stepSummaryPath != "" {
+		recorder.AddStepSummary(stepSummaryPath)
+	}
+	if terragruntOptions.GitLabReportDir != "" {
+		recorder.AddGitLabReport(terragruntOptions.GitLabReportDir)
+	}
+	if terragruntOptions.JUnitReportPath != "" {
+		recorder.AddJUnitReport(terragruntOptions.JUnitReportPath)
+	}
+	if terragruntOptions.EventsEndpoint != "" {
+		if err := recorder.AddEventsEndpoint(terragruntOptions.EventsEndpoint, terragruntOptions.Env); err != nil {
+			return nil, err
+		}
+	}
+	terragruntOptions.Metrics = recorder
+
+	// The command of run-all is the one it runs in each unit
+	command := terragruntOptions.TerraformCommand
+	if command == CMD_RUN_ALL {
+		command = util.SecondArg(terragruntOptions.TerraformCliArgs)
+	}
+	if err := recorder.RunStarted(command); err != nil {
+		terragruntOptions.Logger.Warnf("Could not record that the run started: %v", err)
+	}
+
+	return func() {
+		if err := recorder.Close(); err != nil {
+			terragruntOptions.Logger.Warnf("Could not send the metrics of the units: %v", err)
+		}
+	}, nil
+}
+
+// Return the dir the paths of the units are relative to in metrics and GitHub Actions annotations: the root of the git
+// repo of the working dir, or the working dir itself if it's not in a git repo
+func unitsRootDir(terragruntOptions *options.TerragruntOptions) string {
+	if rootDir := gitRepoRoot(terragruntOptions.WorkingDir); rootDir != "" {
+		return rootDir
+	}
+	return terragruntOptions.WorkingDir
+}
+
+// gitRepoRoot returns the root of the git repo the given folder is in, or an empty string if it's not in a git repo
+func gitRepoRoot(path string) string {
+	cmd := exec.Command("git", "rev-parse", "--show-toplevel")
+	cmd.Dir = path
+	output, err := cmd.Output()
+	if err != nil {
+		return ""
+	}
+	return filepath.ToSlash(strings.TrimSpace(string(output)))
+}
+
+// Start measuring the run of the unit of the given options, and set it as the unit whose metrics the code below
+// records. Returns nil if metrics are disabled, or if the unit is only run to fetch its outputs for a dependency, which
+// would count the outputs of some units many times.
+func startUnitMetrics(terragruntOptions *options.TerragruntOptions) *metrics.Unit {
+	if terragruntOptions.Metrics == nil || terragruntOptions.TerraformCommand != terragruntOptions.OriginalTerraformCommand {
+		return nil
+	}
+
+	unitMetrics := terragruntOptions.Metrics.StartUnit(terragruntOptions.TerragruntConfigPath, terragruntOptions.TerraformCommand)
+	terragruntOptions.UnitMetrics = unitMetrics
+	return unitMetrics
+}
+
+// Record that the run of a unit finished with the given error, and send its metrics
+func finishUnitMetrics(unitMetrics *metrics.Unit, runErr error, terragruntOptions *options.TerragruntOptions) {
+	exitCode := 0
+	if runErr != nil {
+		exitCode = 1
+		if code, err := shell.GetExitCode(runErr); err == nil && code != 0 {
+			exitCode = code
+		}
+	}
+
+	if err := unitMetrics.Finish(exitCode, runErr); err != nil {
+		terragruntOptions.Logger.Warnf("Could not send the metrics of %s: %v", terragruntOptions.TerragruntConfigPath, err)
+	}
+}
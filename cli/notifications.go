@@ -0,0 +1,109 @@
+package cli
+
+import (
+	"sort"
+
+	"github.com/gruntwork-io/terragrunt/config"
+	"github.com/gruntwork-io/terragrunt/configstack"
+	"github.com/gruntwork-io/terragrunt/notify"
+	"github.com/gruntwork-io/terragrunt/options"
+)
+
+// Start notifying the webhooks of the notification blocks of the modules of the given stack that the run-all command
+// started. The modules share a notifier, so that each webhook is notified of the start and completion of the run only
+// once, no matter how many modules configure it, e.g. via an include. If modules configure different notification blocks
+// with the same name, the one of the first module wins. Returns a function that notifies the webhooks that the run
+// completed, which should be called once the modules are done running.
+func startRunAllNotifications(terragruntOptions *options.TerragruntOptions, stack *configstack.Stack) (func(), error) {
+	notifications := map[string]config.NotificationConfig{}
+	modulesToRun := []*configstack.TerraformModule{}
+	for _, module := range stack.Modules {
+		if module.FlagExcluded || module.AssumeAlreadyApplied {
+			continue
+		}
+		modulesToRun = append(modulesToRun, module)
+		for name, notification := range module.Config.Notifications {
+			if _, alreadyConfigured := notifications[name]; !alreadyConfigured {
+				notifications[name] = notification
+			}
+		}
+	}
+
+	notifier, err := newNotifier(notifications, terragruntOptions)
+	if notifier == nil || err != nil {
+		return func() {}, err
+	}
+	for _, module := range modulesToRun {
+		module.TerragruntOptions.Notifier = notifier
+	}
+
+	if err := notifier.RunStarted(terragruntOptions.TerraformCommand, len(modulesToRun)); err != nil {
+		terragruntOptions.Logger.Warnf("Could not notify that the run started: %v", err)
+	}
+	return func() {
+		if err := notifier.RunFinished(); err != nil {
+			terragruntOptions.Logger.Warnf("Could not notify that the run completed: %v", err)
+		}
+	}, nil
+}
+
+// Start notifying the webhooks of the run of the unit of the given options. When the unit is run by run-all, it uses
+// the notifier of the whole run, so only its failure is notified. Otherwise, the unit is the whole run, so its
+// notification blocks are notified of its start and completion too. Returns a function that records that the unit
+// finished with the given error, which should be called once it does.
+func startUnitNotifications(terragruntOptions *options.TerragruntOptions, terragruntConfig *config.TerragruntConfig) (func(error), error) {
+	// The units only run to fetch the outputs of their dependencies are not part of the run
+	if isDependencyOutputsRun(terragruntOptions) {
+		return func(error) {}, nil
+	}
+
+	notifier := terragruntOptions.Notifier
+	ownsRun := notifier == nil
+	if ownsRun {
+		var err error
+		notifier, err = newNotifier(terragruntConfig.Notifications, terragruntOptions)
+		if notifier == nil || err != nil {
+			return func(error) {}, err
+		}
+		terragruntOptions.Notifier = notifier
+
+		if err := notifier.RunStarted(terragruntOptions.TerraformCommand, 1); err != nil {
+			terragruntOptions.Logger.Warnf("Could not notify that the run started: %v", err)
+		}
+	}
+
+	return func(runErr error) {
+		if err := notifier.UnitFinished(terragruntOptions.TerragruntConfigPath, runErr); err != nil {
+			terragruntOptions.Logger.Warnf("Could not notify that %s failed: %v", terragruntOptions.TerragruntConfigPath, err)
+		}
+		if !ownsRun {
+			return
+		}
+		if err := notifier.RunFinished(); err != nil {
+			terragruntOptions.Logger.Warnf("Could not notify that the run completed: %v", err)
+		}
+	}, nil
+}
+
+// Create a notifier of the webhooks of the given notification blocks, sorted by name. Returns nil if there are none.
+func newNotifier(notifications map[string]config.NotificationConfig, terragruntOptions *options.TerragruntOptions) (*notify.Notifier, error) {
+	if len(notifications) == 0 {
+		return nil, nil
+	}
+
+	names := []string{}
+	for name := range notifications {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	webhooks := []*notify.Webhook{}
+	for _, name := range names {
+		webhook, err := notifications[name].Webhook()
+		if err != nil {
+			return nil, err
+		}
+		webhooks = append(webhooks, webhook)
+	}
+	return notify.NewNotifier(webhooks, unitsRootDir(terragruntOptions)), nil
+}
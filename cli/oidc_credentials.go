@@ -0,0 +1,168 @@
+package cli
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/aws/aws-sdk-go/aws"
+
+	"github.com/gruntwork-io/terragrunt/config"
+	"github.com/gruntwork-io/terragrunt/errors"
+	"github.com/gruntwork-io/terragrunt/oidc"
+	"github.com/gruntwork-io/terragrunt/options"
+)
+
+// The env var Application Default Credentials, which the Google provider and the GCS backend use, are read from
+const googleApplicationCredentialsEnvVar = "GOOGLE_APPLICATION_CREDENTIALS"
+
+// Exchange the OIDC token of the CI job Terragrunt runs in for the credentials of the clouds of the oidc_credentials
+// blocks of the given config, and pass them to the terraform processes of the unit of the given options via the env
+// vars of their cloud. The token of aws is assumed as the IAM role of the unit instead, see injectOidcAwsCredentials.
+// Outside of CI, or in a job that can't request a token, the blocks are skipped, which leaves the unit to the
+// credentials the user already has. Returns a function that removes the files the credentials of GCP are
+// configured with, which should be called once the unit finishes.
+func startOidcCredentials(terragruntOptions *options.TerragruntOptions, terragruntConfig *config.TerragruntConfig) (func(), error) {
+	cleanups := []func(){}
+	cleanupAll := func() {
+		for _, cleanup := range cleanups {
+			cleanup()
+		}
+	}
+
+	if _, hasAwsCredentials := terragruntConfig.OidcCredentials[config.OidcCloudAws]; hasAwsCredentials && terragruntOptions.IamRole != "" {
+		return nil, errors.WithStackTrace(OidcCredentialsWithIamRole(terragruntOptions.IamRole))
+	}
+
+	for _, cloud := range []string{config.OidcCloudAws, config.OidcCloudGcp, config.OidcCloudAzure} {
+		credentials, hasCredentials := terragruntConfig.OidcCredentials[cloud]
+		if !hasCredentials {
+			continue
+		}
+
+		source := oidc.FindTokenSource(aws.StringValue(credentials.TokenEnvVar), terragruntOptions.Env)
+		if source == nil {
+			terragruntOptions.Logger.Debugf("Ignoring the oidc_credentials block of %s, as there is no OIDC token of a CI job", cloud)
+			continue
+		}
+
+		var err error
+		switch cloud {
+		case config.OidcCloudAws:
+			err = injectOidcAwsCredentials(terragruntOptions, source, credentials)
+		case config.OidcCloudGcp:
+			var cleanup func()
+			cleanup, err = injectOidcGcpCredentials(terragruntOptions, source, credentials)
+			if cleanup != nil {
+				cleanups = append(cleanups, cleanup)
+			}
+		case config.OidcCloudAzure:
+			err = injectOidcAzureCredentials(terragruntOptions, source, credentials)
+		}
+		if err != nil {
+			cleanupAll()
+			return nil, err
+		}
+	}
+	return cleanupAll, nil
+}
+
+// Make the IAM role of the given block the one of the unit, assumed with a token of the given source as its web identity
+// token, so that terragrunt exchanges the token for the credentials of the role, which terraform and the AWS calls of
+// terragrunt for the unit use, the same way as with iam_web_identity_token. See
+// aws_helper.AssumeRoleAndUpdateEnvIfNecessary.
+func injectOidcAwsCredentials(terragruntOptions *options.TerragruntOptions, source *oidc.TokenSource, credentials config.OidcCredentialsConfig) error {
+	audience := oidc.DefaultAwsAudience
+	if credentials.Audience != nil {
+		audience = *credentials.Audience
+	}
+	token, err := source.Token(audience)
+	if err != nil {
+		return err
+	}
+	terragruntOptions.Logger.Debugf("Assuming %s with the OIDC token of %s", *credentials.RoleArn, source)
+
+	terragruntOptions.IamRole = *credentials.RoleArn
+	terragruntOptions.IamWebIdentityToken = token
+	terragruntOptions.IamWebIdentitySessionName = aws.StringValue(credentials.SessionName)
+	terragruntOptions.IamWebIdentityRegion = aws.StringValue(credentials.Region)
+	terragruntOptions.IamWebIdentityStsEndpoint = aws.StringValue(credentials.StsEndpoint)
+	if credentials.SessionDuration != nil {
+		terragruntOptions.IamAssumeRoleDuration = *credentials.SessionDuration
+	}
+	return nil
+}
+
+// Write the credentials config of the workload identity federation of the given block, and point the Application
+// Default Credentials of the unit at it. The token of an env var is written next to it, for the config to read. Returns
+// a function that removes the files.
+func injectOidcGcpCredentials(terragruntOptions *options.TerragruntOptions, source *oidc.TokenSource, credentials config.OidcCredentialsConfig) (func(), error) {
+	provider := *credentials.WorkloadIdentityProvider
+	audience := oidc.DefaultGcpAudience(provider)
+	if credentials.Audience != nil {
+		audience = *credentials.Audience
+	}
+
+	// The files hold tokens, which TempDir already makes readable by the current user only
+	dir, err := ioutil.TempDir("", "terragrunt-oidc-gcp-")
+	if err != nil {
+		return nil, errors.WithStackTrace(err)
+	}
+	cleanup := func() { os.RemoveAll(dir) }
+
+	tokenFile := filepath.Join(dir, "token")
+	if source.RequestUrl == "" {
+		token, err := source.Token(audience)
+		if err != nil {
+			cleanup()
+			return nil, err
+		}
+		if err := ioutil.WriteFile(tokenFile, []byte(token), 0600); err != nil {
+			cleanup()
+			return nil, errors.WithStackTrace(err)
+		}
+	}
+
+	contents, err := oidc.GcpCredentialsConfig(source, audience, tokenFile, provider, aws.StringValue(credentials.ServiceAccount))
+	if err != nil {
+		cleanup()
+		return nil, err
+	}
+	credentialsFile := filepath.Join(dir, "credentials.json")
+	if err := ioutil.WriteFile(credentialsFile, contents, 0600); err != nil {
+		cleanup()
+		return nil, errors.WithStackTrace(err)
+	}
+	terragruntOptions.Logger.Debugf("Configured the workload identity federation of %s with the OIDC token of %s", provider, source)
+
+	terragruntOptions.Env[googleApplicationCredentialsEnvVar] = credentialsFile
+	return cleanup, nil
+}
+
+// Set the env vars that make the azurerm provider and backend exchange a token for credentials of the app registration
+// or managed identity of the given block
+func injectOidcAzureCredentials(terragruntOptions *options.TerragruntOptions, source *oidc.TokenSource, credentials config.OidcCredentialsConfig) error {
+	audience := oidc.DefaultAzureAudience
+	if credentials.Audience != nil {
+		audience = *credentials.Audience
+	}
+	env, err := oidc.AzureEnv(source, audience, *credentials.ClientId, *credentials.TenantId, aws.StringValue(credentials.SubscriptionId))
+	if err != nil {
+		return err
+	}
+	terragruntOptions.Logger.Debugf("Passing the OIDC tokens of %s to the azurerm provider, for client %s", source, *credentials.ClientId)
+
+	for key, value := range env {
+		terragruntOptions.Env[key] = value
+	}
+	return nil
+}
+
+// Custom error types
+
+type OidcCredentialsWithIamRole string
+
+func (iamRole OidcCredentialsWithIamRole) Error() string {
+	return fmt.Sprintf("The oidc_credentials block of aws assumes an IAM role of its own, so it can't be combined with the IAM role %s set via iam_role or --terragrunt-iam-role. Remove one of them.", string(iamRole))
+}
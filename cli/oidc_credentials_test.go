@@ -0,0 +1,108 @@
+package cli
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/gruntwork-io/terragrunt/aws_helper"
+	"github.com/gruntwork-io/terragrunt/config"
+	"github.com/gruntwork-io/terragrunt/errors"
+	"github.com/gruntwork-io/terragrunt/oidc"
+	"github.com/gruntwork-io/terragrunt/options"
+	"github.com/gruntwork-io/terragrunt/util"
+)
+
+func TestStartOidcCredentials(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		// The token endpoint of GitHub Actions is called with GET, and STS with POST
+		switch request.Method {
+		case http.MethodGet:
+			writer.Write([]byte(`{"value": "token-for-` + request.URL.Query().Get("audience") + `"}`))
+		case http.MethodPost:
+			require.NoError(t, request.ParseForm())
+			assert.Equal(t, "token-for-sts.amazonaws.com", request.PostForm.Get("WebIdentityToken"))
+			assert.Equal(t, "ci-job", request.PostForm.Get("RoleSessionName"))
+			writer.Write([]byte(`<AssumeRoleWithWebIdentityResponse><AssumeRoleWithWebIdentityResult><Credentials>
+<AccessKeyId>ASIA123</AccessKeyId><SecretAccessKey>secret</SecretAccessKey><SessionToken>session</SessionToken>
+</Credentials></AssumeRoleWithWebIdentityResult></AssumeRoleWithWebIdentityResponse>`))
+		}
+	}))
+	defer server.Close()
+
+	terragruntOptions, err := options.NewTerragruntOptionsForTest("terragrunt.hcl")
+	require.NoError(t, err)
+	terragruntOptions.Env = map[string]string{oidc.GitHubRequestUrlEnvVar: server.URL + "/?api-version=2.0", oidc.GitHubRequestTokenEnvVar: "request-token"}
+	roleArn, sessionName, stsEndpoint := "arn:aws:iam::123456789012:role/ci", "ci-job", server.URL
+	provider := "projects/123/locations/global/workloadIdentityPools/ci/providers/github"
+	clientId, tenantId := "client", "tenant"
+	terragruntConfig := &config.TerragruntConfig{OidcCredentials: map[string]config.OidcCredentialsConfig{
+		config.OidcCloudAws:   {Cloud: config.OidcCloudAws, RoleArn: &roleArn, SessionName: &sessionName, StsEndpoint: &stsEndpoint},
+		config.OidcCloudGcp:   {Cloud: config.OidcCloudGcp, WorkloadIdentityProvider: &provider},
+		config.OidcCloudAzure: {Cloud: config.OidcCloudAzure, ClientId: &clientId, TenantId: &tenantId},
+	}}
+
+	removeOidcCredentials, err := startOidcCredentials(terragruntOptions, terragruntConfig)
+	require.NoError(t, err)
+	assert.Equal(t, roleArn, terragruntOptions.IamRole)
+	assert.Equal(t, "token-for-sts.amazonaws.com", terragruntOptions.IamWebIdentityToken)
+
+	// The role of the block is the one of the unit, which terragrunt assumes with the token
+	require.NoError(t, aws_helper.AssumeRoleAndUpdateEnvIfNecessary(terragruntOptions))
+	assert.Equal(t, "ASIA123", terragruntOptions.Env["AWS_ACCESS_KEY_ID"])
+	assert.Equal(t, "session", terragruntOptions.Env["AWS_SESSION_TOKEN"])
+	assert.Equal(t, server.URL+"/?api-version=2.0&audience=api%3A%2F%2FAzureADTokenExchange", terragruntOptions.Env["ARM_OIDC_REQUEST_URL"])
+	assert.Equal(t, "request-token", terragruntOptions.Env["ARM_OIDC_REQUEST_TOKEN"])
+	assert.Equal(t, "client", terragruntOptions.Env["ARM_CLIENT_ID"])
+
+	credentialsFile := terragruntOptions.Env[googleApplicationCredentialsEnvVar]
+	contents, err := util.ReadFileAsString(credentialsFile)
+	require.NoError(t, err)
+	var credentialsConfig map[string]interface{}
+	require.NoError(t, json.Unmarshal([]byte(contents), &credentialsConfig))
+	assert.Equal(t, "//iam.googleapis.com/"+provider, credentialsConfig["audience"])
+
+	removeOidcCredentials()
+	assert.False(t, util.FileExists(credentialsFile))
+}
+
+func TestStartOidcCredentialsOutsideOfCI(t *testing.T) {
+	t.Parallel()
+
+	terragruntOptions, err := options.NewTerragruntOptionsForTest("terragrunt.hcl")
+	require.NoError(t, err)
+	terragruntOptions.Env = map[string]string{}
+	clientId, tenantId, tokenEnvVar := "client", "tenant", "AZURE_ID_TOKEN"
+	terragruntConfig := &config.TerragruntConfig{OidcCredentials: map[string]config.OidcCredentialsConfig{
+		config.OidcCloudAzure: {Cloud: config.OidcCloudAzure, ClientId: &clientId, TenantId: &tenantId, TokenEnvVar: &tokenEnvVar},
+	}}
+
+	removeOidcCredentials, err := startOidcCredentials(terragruntOptions, terragruntConfig)
+	require.NoError(t, err)
+	removeOidcCredentials()
+	assert.Empty(t, terragruntOptions.Env)
+}
+
+func TestStartOidcCredentialsWithIamRole(t *testing.T) {
+	t.Parallel()
+
+	terragruntOptions, err := options.NewTerragruntOptionsForTest("terragrunt.hcl")
+	require.NoError(t, err)
+	terragruntOptions.Env = map[string]string{}
+	terragruntOptions.IamRole = "arn:aws:iam::123456789012:role/deploy"
+	roleArn := "arn:aws:iam::123456789012:role/ci"
+	terragruntConfig := &config.TerragruntConfig{OidcCredentials: map[string]config.OidcCredentialsConfig{
+		config.OidcCloudAws: {Cloud: config.OidcCloudAws, RoleArn: &roleArn},
+	}}
+
+	// The combination is rejected even outside of CI, where the block would be skipped
+	_, err = startOidcCredentials(terragruntOptions, terragruntConfig)
+	_, isWithIamRole := errors.Unwrap(err).(OidcCredentialsWithIamRole)
+	assert.True(t, isWithIamRole, "Unexpected error: %v", err)
+}
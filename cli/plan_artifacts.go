@@ -0,0 +1,178 @@
+package cli
+
+import (
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/gruntwork-io/terragrunt/errors"
+	"github.com/gruntwork-io/terragrunt/options"
+	"github.com/gruntwork-io/terragrunt/planartifact"
+	"github.com/gruntwork-io/terragrunt/shell"
+	"github.com/gruntwork-io/terragrunt/util"
+)
+
+// The env vars of the CI systems whose value identifies the run of a pipeline, which both its plan and apply stages see
+var planArtifactRunIdEnvVars = []string{"GITHUB_RUN_ID", "CI_PIPELINE_ID"}
+
+// Check the location of the plan artifacts set via --terragrunt-plan-artifact-url, if any, before any unit runs, and
+// resolve the ID of the run and the dir the paths of the units are relative to, which are the same for all the units
+func startPlanArtifacts(terragruntOptions *options.TerragruntOptions) error {
+	if terragruntOptions.PlanArtifactUrl == "" {
+		return nil
+	}
+	if _, err := planartifact.ParseLocation(terragruntOptions.PlanArtifactUrl); err != nil {
+		return err
+	}
+
+	for _, envVar := range planArtifactRunIdEnvVars {
+		if terragruntOptions.PlanArtifactRunId != "" {
+			break
+		}
+		terragruntOptions.PlanArtifactRunId = terragruntOptions.Env[envVar]
+	}
+	terragruntOptions.PlanArtifactRootDir = unitsRootDir(terragruntOptions)
+	return nil
+}
+
+// Make the plan command of the unit of the given options save its plan, so that it can be uploaded once it finishes.
+// Returns a function that removes the plan, which should be called once it's uploaded.
+func preparePlanArtifacts(terragruntOptions *options.TerragruntOptions) (func(), error) {
+	if !shouldUploadPlanArtifacts(terragruntOptions) {
+		return func() {}, nil
+	}
+	return savePlanToTempFile(terragruntOptions, "terragrunt-plan-*.tfplan")
+}
+
+// Upload the plan the unit of the given options just saved, and its JSON, as printed by terraform show -json, under the
+// key of the unit. A plan that can't be uploaded fails the unit, as it couldn't be applied.
+func uploadPlanArtifacts(terragruntOptions *options.TerragruntOptions) error {
+	planFile := planOutFile(terragruntOptions.TerraformCliArgs)
+	if !shouldUploadPlanArtifacts(terragruntOptions) || planFile == "" {
+		return nil
+	}
+	if !filepath.IsAbs(planFile) {
+		planFile = filepath.Join(terragruntOptions.WorkingDir, planFile)
+	}
+
+	store, location, key, err := planArtifactStore(terragruntOptions)
+	if err != nil {
+		return err
+	}
+
+	out, err := shell.RunShellCommandWithOutput(terragruntOptions, "", true, false, terragruntOptions.TerraformPath, "show", "-json", planFile)
+	if err != nil {
+		return err
+	}
+	planJsonFile, err := ioutil.TempFile("", "terragrunt-plan-*.json")
+	if err != nil {
+		return errors.WithStackTrace(err)
+	}
+	defer os.Remove(planJsonFile.Name())
+	if _, err := planJsonFile.WriteString(out.Stdout); err != nil {
+		planJsonFile.Close()
+		return errors.WithStackTrace(err)
+	}
+	if err := planJsonFile.Close(); err != nil {
+		return errors.WithStackTrace(err)
+	}
+
+	if err := store.Upload(planFile, path.Join(key, planartifact.PLAN_FILE)); err != nil {
+		return err
+	}
+	if err := store.Upload(planJsonFile.Name(), path.Join(key, planartifact.PLAN_JSON_FILE)); err != nil {
+		return err
+	}
+	terragruntOptions.Logger.Infof("Uploaded the plan of %s to %s://%s", terragruntOptions.WorkingDir, location.Scheme, path.Join(location.Bucket, key))
+	return nil
+}
+
+// Make the apply command of the unit of the given options apply the plan uploaded under the key of the unit, by
+// downloading it to a temp file and passing it to apply, unless apply is already given a plan. The variables of the plan
+// can't be set when applying it, so the -var and -var-file args, e.g. those of the extra_arguments of the unit, which
+// were added before the plan was known, are removed. Returns a function that removes the temp file, which should be
+// called once the plan is applied.
+func fetchReviewedPlan(terragruntOptions *options.TerragruntOptions) (func(), error) {
+	if !shouldFetchReviewedPlan(terragruntOptions) {
+		return func() {}, nil
+	}
+
+	store, location, key, err := planArtifactStore(terragruntOptions)
+	if err != nil {
+		return nil, err
+	}
+
+	planFile, err := ioutil.TempFile("", "terragrunt-reviewed-*.tfplan")
+	if err != nil {
+		return nil, errors.WithStackTrace(err)
+	}
+	if err := planFile.Close(); err != nil {
+		os.Remove(planFile.Name())
+		return nil, errors.WithStackTrace(err)
+	}
+	if err := store.Download(path.Join(key, planartifact.PLAN_FILE), planFile.Name()); err != nil {
+		os.Remove(planFile.Name())
+		return nil, err
+	}
+
+	terragruntOptions.Logger.Infof("Applying the reviewed plan %s://%s", location.Scheme, path.Join(location.Bucket, key, planartifact.PLAN_FILE))
+	terragruntOptions.TerraformCliArgs = removeTerraformVarArgs(terragruntOptions.TerraformCliArgs)
+	terragruntOptions.AppendTerraformCliArgs(planFile.Name())
+	return func() { os.Remove(planFile.Name()) }, nil
+}
+
+// Return the given terraform args without the -var and -var-file args, whether their values are part of them, e.g.
+// -var-file=prod.tfvars, or the next args
+func removeTerraformVarArgs(args []string) []string {
+	filtered := []string{}
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		flag := strings.SplitN(strings.TrimPrefix(strings.TrimPrefix(arg, "-"), "-"), "=", 2)[0]
+		if !strings.HasPrefix(arg, "-") || (flag != "var" && flag != "var-file") {
+			filtered = append(filtered, arg)
+			continue
+		}
+		if !strings.Contains(arg, "=") {
+			i++
+		}
+	}
+	return filtered
+}
+
+// Return the store of the plan artifacts, its location, and the key of the unit of the given options in it. The unit is
+// identified by the path of its folder relative to the root dir of the plan artifacts.
+func planArtifactStore(terragruntOptions *options.TerragruntOptions) (planartifact.Store, *planartifact.Location, string, error) {
+	location, err := planartifact.ParseLocation(terragruntOptions.PlanArtifactUrl)
+	if err != nil {
+		return nil, nil, "", err
+	}
+
+	unitDir := filepath.Dir(terragruntOptions.TerragruntConfigPath)
+	unit, err := util.GetPathRelativeTo(unitDir, terragruntOptions.PlanArtifactRootDir)
+	if err != nil {
+		return nil, nil, "", err
+	}
+	key, err := location.Key(terragruntOptions.PlanArtifactRunId, unit)
+	if err != nil {
+		return nil, nil, "", err
+	}
+
+	store, err := planartifact.NewStore(location, terragruntOptions)
+	if err != nil {
+		return nil, nil, "", err
+	}
+	return store, location, key, nil
+}
+
+func shouldUploadPlanArtifacts(terragruntOptions *options.TerragruntOptions) bool {
+	return terragruntOptions.PlanArtifactUrl != "" && isMainPlanRun(terragruntOptions)
+}
+
+func shouldFetchReviewedPlan(terragruntOptions *options.TerragruntOptions) bool {
+	return terragruntOptions.PlanArtifactUrl != "" &&
+		util.FirstArg(terragruntOptions.TerraformCliArgs) == "apply" &&
+		!isDependencyOutputsRun(terragruntOptions) &&
+		savedPlanFile(terragruntOptions) == ""
+}
@@ -0,0 +1,78 @@
+package cli
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/gruntwork-io/terragrunt/errors"
+	"github.com/gruntwork-io/terragrunt/options"
+	"github.com/gruntwork-io/terragrunt/planartifact"
+	"github.com/gruntwork-io/terragrunt/util"
+)
+
+func TestFetchReviewedPlan(t *testing.T) {
+	t.Parallel()
+
+	dir, err := ioutil.TempDir("", "plan-artifacts")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	storeDir := filepath.Join(dir, "store")
+	reviewedPlanPath := filepath.Join(storeDir, "1234", "live", "app", planartifact.PLAN_FILE)
+	require.NoError(t, os.MkdirAll(filepath.Dir(reviewedPlanPath), os.ModePerm))
+	require.NoError(t, ioutil.WriteFile(reviewedPlanPath, []byte("reviewed plan"), 0600))
+
+	terragruntOptions, err := options.NewTerragruntOptionsForTest(filepath.Join(dir, "terragrunt.hcl"))
+	require.NoError(t, err)
+	terragruntOptions.WorkingDir = dir
+	terragruntOptions.Env = map[string]string{"GITHUB_RUN_ID": "1234"}
+	terragruntOptions.PlanArtifactUrl = "file://" + filepath.ToSlash(storeDir)
+	require.NoError(t, startPlanArtifacts(terragruntOptions))
+	assert.Equal(t, "1234", terragruntOptions.PlanArtifactRunId)
+
+	unitOptions := terragruntOptions.Clone(filepath.Join(dir, "live", "app", "terragrunt.hcl"))
+	unitOptions.TerraformCommand = "apply"
+	unitOptions.OriginalTerraformCommand = "apply"
+	// The var files of the extra_arguments of the unit are inserted before the plan is fetched, but can't be set when
+	// applying it
+	unitOptions.TerraformCliArgs = []string{"apply", "-var-file=/live/common.tfvars", "-var", "cidr=10.0.0.0/16", "-auto-approve"}
+	require.True(t, shouldFetchReviewedPlan(unitOptions))
+	assert.False(t, shouldUploadPlanArtifacts(unitOptions))
+
+	removeReviewedPlan, err := fetchReviewedPlan(unitOptions)
+	require.NoError(t, err)
+	require.Len(t, unitOptions.TerraformCliArgs, 3)
+	assert.Equal(t, []string{"apply", "-auto-approve"}, unitOptions.TerraformCliArgs[:2])
+	planFile := unitOptions.TerraformCliArgs[2]
+	contents, err := ioutil.ReadFile(planFile)
+	require.NoError(t, err)
+	assert.Equal(t, "reviewed plan", string(contents))
+
+	// Once apply is given the plan, it isn't fetched again
+	assert.False(t, shouldFetchReviewedPlan(unitOptions))
+	removeReviewedPlan()
+	assert.False(t, util.FileExists(planFile))
+
+	// A unit without a reviewed plan isn't applied
+	otherUnitOptions := terragruntOptions.Clone(filepath.Join(dir, "live", "db", "terragrunt.hcl"))
+	otherUnitOptions.TerraformCommand = "apply"
+	otherUnitOptions.OriginalTerraformCommand = "apply"
+	otherUnitOptions.TerraformCliArgs = []string{"apply"}
+	_, err = fetchReviewedPlan(otherUnitOptions)
+	assert.IsType(t, planartifact.ArtifactNotFound(""), errors.Unwrap(err))
+	assert.Equal(t, []string{"apply"}, otherUnitOptions.TerraformCliArgs)
+}
+
+func TestStartPlanArtifactsInvalidUrl(t *testing.T) {
+	t.Parallel()
+
+	terragruntOptions, err := options.NewTerragruntOptionsForTest("mock-path-for-test.hcl")
+	require.NoError(t, err)
+	terragruntOptions.PlanArtifactUrl = "https://example.com/plans"
+	assert.IsType(t, planartifact.InvalidLocation(""), errors.Unwrap(startPlanArtifacts(terragruntOptions)))
+}
@@ -0,0 +1,79 @@
+package cli
+
+import (
+	"path/filepath"
+
+	"github.com/gruntwork-io/terragrunt/drift"
+	"github.com/gruntwork-io/terragrunt/options"
+	"github.com/gruntwork-io/terragrunt/planmarkdown"
+	"github.com/gruntwork-io/terragrunt/shell"
+)
+
+// Start collecting the plans of the units the plan command runs in, if a file to write them to as Markdown is set via
+// --terragrunt-plan-markdown. Returns a function that writes the document given the error the command finished with,
+// which should be called once the command finishes.
+func startPlanMarkdown(terragruntOptions *options.TerragruntOptions) func(error) {
+	if terragruntOptions.PlanMarkdownPath == "" || terragruntOptions.TerraformCommand != "plan" {
+		return func(error) {}
+	}
+
+	report := planmarkdown.NewReport(unitsRootDir(terragruntOptions))
+	terragruntOptions.PlanMarkdown = report
+
+	return func(runErr error) {
+		forEachSkippedUnit(runErr, report.AddError)
+		if err := report.Write(terragruntOptions.PlanMarkdownPath); err != nil {
+			terragruntOptions.Logger.Warnf("Could not write the plans to %s: %v", terragruntOptions.PlanMarkdownPath, err)
+			return
+		}
+		terragruntOptions.Logger.Infof("Wrote the plans of the units to %s", terragruntOptions.PlanMarkdownPath)
+	}
+}
+
+// Make the plan command of the unit of the given options save its plan, so that it can be added to the Markdown document
+// once it finishes. Returns a function that removes the plan, which should be called once it's added.
+func preparePlanMarkdown(terragruntOptions *options.TerragruntOptions) (func(), error) {
+	if !shouldAddPlanToMarkdown(terragruntOptions) {
+		return func() {}, nil
+	}
+	return savePlanToTempFile(terragruntOptions, "terragrunt-markdown-*.tfplan")
+}
+
+// Add the plan the unit of the given options just saved to the Markdown document of the run, with its changes, as
+// printed by terraform show -json, and its text, as printed by terraform show. A plan that can't be shown makes the unit
+// fail in the document.
+func addPlanToMarkdown(terragruntOptions *options.TerragruntOptions) {
+	planFile := planOutFile(terragruntOptions.TerraformCliArgs)
+	if !shouldAddPlanToMarkdown(terragruntOptions) || planFile == "" {
+		return
+	}
+
+	unitDir := filepath.Dir(terragruntOptions.TerragruntConfigPath)
+	jsonOut, err := shell.RunShellCommandWithOutput(terragruntOptions, "", true, false, terragruntOptions.TerraformPath, "show", "-json", planFile)
+	if err != nil {
+		terragruntOptions.PlanMarkdown.AddError(unitDir, err)
+		return
+	}
+	changes, err := drift.ParsePlan([]byte(jsonOut.Stdout))
+	if err != nil {
+		terragruntOptions.PlanMarkdown.AddError(unitDir, err)
+		return
+	}
+	textOut, err := shell.RunShellCommandWithOutput(terragruntOptions, "", true, false, terragruntOptions.TerraformPath, "show", "-no-color", planFile)
+	if err != nil {
+		terragruntOptions.PlanMarkdown.AddError(unitDir, err)
+		return
+	}
+	terragruntOptions.PlanMarkdown.Add(unitDir, changes, textOut.Stdout)
+}
+
+// Add the error the unit of the given options failed with, if any, to the Markdown document of the run
+func recordPlanMarkdownError(terragruntOptions *options.TerragruntOptions, err error) {
+	if err != nil && shouldAddPlanToMarkdown(terragruntOptions) {
+		terragruntOptions.PlanMarkdown.AddError(filepath.Dir(terragruntOptions.TerragruntConfigPath), err)
+	}
+}
+
+func shouldAddPlanToMarkdown(terragruntOptions *options.TerragruntOptions) bool {
+	return terragruntOptions.PlanMarkdown != nil && isMainPlanRun(terragruntOptions)
+}
@@ -0,0 +1,63 @@
+package cli
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/hashicorp/go-multierror"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/gruntwork-io/terragrunt/configstack"
+	"github.com/gruntwork-io/terragrunt/drift"
+	"github.com/gruntwork-io/terragrunt/errors"
+	"github.com/gruntwork-io/terragrunt/options"
+)
+
+func TestPlanMarkdownWritesDocument(t *testing.T) {
+	t.Parallel()
+
+	dir, err := ioutil.TempDir("", "plan-markdown")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	terragruntOptions, err := options.NewTerragruntOptionsForTest(filepath.Join(dir, "terragrunt.hcl"))
+	require.NoError(t, err)
+	terragruntOptions.WorkingDir = dir
+	terragruntOptions.TerraformCommand = "plan"
+	terragruntOptions.OriginalTerraformCommand = "plan"
+	terragruntOptions.TerraformCliArgs = []string{"plan"}
+	terragruntOptions.PlanMarkdownPath = filepath.Join(dir, "comments", "plan.md")
+
+	stopPlanMarkdown := startPlanMarkdown(terragruntOptions)
+	require.NotNil(t, terragruntOptions.PlanMarkdown)
+
+	unitOptions := terragruntOptions.Clone(filepath.Join(dir, "app", "terragrunt.hcl"))
+	require.True(t, shouldAddPlanToMarkdown(unitOptions))
+	unitOptions.PlanMarkdown.Add(filepath.Join(dir, "app"), &drift.Changes{Resources: []drift.ResourceChange{{Address: "null_resource.this", Actions: []string{"create"}}}}, "  + resource \"null_resource\" \"this\" {}")
+	recordPlanMarkdownError(unitOptions, nil)
+
+	dependencyErr := configstack.DependencyFinishedWithError{Module: &configstack.TerraformModule{Path: filepath.Join(dir, "db")}, Dependency: &configstack.TerraformModule{Path: filepath.Join(dir, "vpc")}, Err: errors.WithStackTrace(os.ErrNotExist)}
+	stopPlanMarkdown(multierror.Append(nil, errors.WithStackTrace(dependencyErr)))
+
+	contents, err := ioutil.ReadFile(terragruntOptions.PlanMarkdownPath)
+	require.NoError(t, err)
+	assert.Contains(t, string(contents), "2 units planned: 1 with changes, 0 without changes, 1 failed.")
+	assert.Contains(t, string(contents), "```diff\n+   resource \"null_resource\" \"this\" {}\n```")
+	assert.Contains(t, string(contents), "<code>db</code>: failed")
+}
+
+func TestPlanMarkdownOnlyForPlan(t *testing.T) {
+	t.Parallel()
+
+	terragruntOptions, err := options.NewTerragruntOptionsForTest("terragrunt.hcl")
+	require.NoError(t, err)
+	terragruntOptions.TerraformCommand = "apply"
+	terragruntOptions.PlanMarkdownPath = "plan.md"
+
+	startPlanMarkdown(terragruntOptions)(nil)
+	assert.Nil(t, terragruntOptions.PlanMarkdown)
+	assert.False(t, shouldAddPlanToMarkdown(terragruntOptions))
+}
@@ -0,0 +1,119 @@
+package cli
+
+import (
+	"bytes"
+	"fmt"
+	"os/exec"
+	"strings"
+
+	"github.com/google/shlex"
+
+	"github.com/gruntwork-io/terragrunt/errors"
+	"github.com/gruntwork-io/terragrunt/options"
+	"github.com/gruntwork-io/terragrunt/shell"
+	"github.com/gruntwork-io/terragrunt/util"
+)
+
+// Run the given function, which runs the terraform command of the unit of the given options, and, if a renderer is set
+// via --terragrunt-plan-renderer, pipe the output the plan command writes to stdout through it before it's displayed.
+// The error of the function is returned as is, so that the exit code of the plan, e.g. 2 with -detailed-exitcode, is
+// kept. If the renderer fails, the output is displayed as terraform wrote it.
+func renderPlanOutput(terragruntOptions *options.TerragruntOptions, runTerraform func() error) error {
+	if !shouldRenderPlan(terragruntOptions) {
+		return runTerraform()
+	}
+
+	writer := terragruntOptions.Writer
+	var planOutput bytes.Buffer
+	terragruntOptions.Writer = &planOutput
+	runErr := runTerraform()
+	terragruntOptions.Writer = writer
+
+	if planOutput.Len() == 0 {
+		return runErr
+	}
+
+	rendered, err := runPlanRenderer(terragruntOptions, planOutput.Bytes())
+	if err != nil {
+		terragruntOptions.Logger.Warnf("Could not render the plan of %s with %s, so displaying it as is: %v", terragruntOptions.WorkingDir, terragruntOptions.PlanRenderer, err)
+		rendered = planOutput.Bytes()
+	}
+	if _, err := writer.Write(rendered); err != nil {
+		return errors.WithStackTrace(err)
+	}
+	return runErr
+}
+
+// Run the renderer of the given options in the working dir of the unit, with the given plan output as its stdin, and
+// return what it writes to stdout. What it writes to stderr is displayed as is. The renderer is split into the command
+// and its args with shell quoting rules, so that e.g. an arg with spaces can be quoted.
+func runPlanRenderer(terragruntOptions *options.TerragruntOptions, planOutput []byte) ([]byte, error) {
+	commandAndArgs, err := shlex.Split(terragruntOptions.PlanRenderer)
+	if err != nil {
+		return nil, errors.WithStackTrace(err)
+	}
+	if len(commandAndArgs) == 0 || commandAndArgs[0] == "" {
+		return nil, errors.WithStackTrace(InvalidPlanRenderer(terragruntOptions.PlanRenderer))
+	}
+
+	var stdout bytes.Buffer
+	cmd := exec.Command(commandAndArgs[0], commandAndArgs[1:]...)
+	cmd.Dir = terragruntOptions.WorkingDir
+	for key, value := range terragruntOptions.Env {
+		cmd.Env = append(cmd.Env, fmt.Sprintf("%s=%s", key, value))
+	}
+	cmd.Stdin = bytes.NewReader(planOutput)
+	cmd.Stdout = &stdout
+	cmd.Stderr = terragruntOptions.ErrWriter
+
+	if err := cmd.Run(); err != nil {
+		if exitCode, exitCodeErr := shell.GetExitCode(err); exitCodeErr == nil {
+			return nil, errors.WithStackTrace(PlanRendererFailed{Renderer: commandAndArgs[0], ExitCode: exitCode})
+		}
+		return nil, errors.WithStackTrace(err)
+	}
+	return stdout.Bytes(), nil
+}
+
+// Only the output of the plans the user runs is rendered, not the one of the plans run to fetch the outputs of the
+// dependencies, nor the JSON of plan -json, which is meant for tools rather than people. As the output is only displayed
+// once the plan finishes, the plans that may ask for the values of variables aren't rendered, so that the prompts show
+// up.
+func shouldRenderPlan(terragruntOptions *options.TerragruntOptions) bool {
+	return strings.TrimSpace(terragruntOptions.PlanRenderer) != "" &&
+		isMainPlanRun(terragruntOptions) &&
+		!util.ListContainsElement(terragruntOptions.TerraformCliArgs, "-json") &&
+		isTerraformInputDisabled(terragruntOptions)
+}
+
+// Returns true if terraform can't ask for input in the unit of the given options: with --terragrunt-non-interactive,
+// --terragrunt-input-mode none, -input=false, or the TF_INPUT env var set to false or 0
+func isTerraformInputDisabled(terragruntOptions *options.TerragruntOptions) bool {
+	if terragruntOptions.NonInteractive || terragruntOptions.InputMode == options.INPUT_MODE_NONE {
+		return true
+	}
+	for _, arg := range terragruntOptions.TerraformCliArgs {
+		if arg == "-input=false" || arg == "--input=false" {
+			return true
+		}
+	}
+	tfInput := terragruntOptions.Env["TF_INPUT"]
+	return tfInput == "false" || tfInput == "0"
+}
+
+// Custom error types
+
+type PlanRendererFailed struct {
+	Renderer string
+	ExitCode int
+}
+
+func (err PlanRendererFailed) Error() string {
+	return fmt.Sprintf("The plan renderer %s exited with code %d", err.Renderer, err.ExitCode)
+}
+
+type InvalidPlanRenderer string
+
+func (renderer InvalidPlanRenderer) Error() string {
+	return fmt.Sprintf("The plan renderer %s has no command", string(renderer))
+}
@@ -0,0 +1,118 @@
+// +build linux darwin
+
+package cli
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/gruntwork-io/terragrunt/errors"
+	"github.com/gruntwork-io/terragrunt/options"
+	"github.com/gruntwork-io/terragrunt/shell"
+)
+
+func newPlanRendererTestOptions(t *testing.T, renderer string) (*options.TerragruntOptions, *bytes.Buffer) {
+	terragruntOptions, err := options.NewTerragruntOptionsForTest(filepath.Join("live", "app", "terragrunt.hcl"))
+	require.NoError(t, err)
+	terragruntOptions.WorkingDir = os.TempDir()
+	var stdout bytes.Buffer
+	terragruntOptions.Writer = &stdout
+	terragruntOptions.ErrWriter = ioutil.Discard
+	terragruntOptions.TerraformCommand = "plan"
+	terragruntOptions.OriginalTerraformCommand = "plan"
+	terragruntOptions.TerraformCliArgs = []string{"plan", "-detailed-exitcode"}
+	terragruntOptions.PlanRenderer = renderer
+	return terragruntOptions, &stdout
+}
+
+// A terraform plan that writes the given output, and exits with the given code with -detailed-exitcode
+func fakePlan(terragruntOptions *options.TerragruntOptions, output string, exitCode int) func() error {
+	return func() error {
+		fmt.Fprint(terragruntOptions.Writer, output)
+		if exitCode == 0 {
+			return nil
+		}
+		return errors.WithStackTrace(testExitCodeError(exitCode))
+	}
+}
+
+type testExitCodeError int
+
+func (err testExitCodeError) Error() string {
+	return fmt.Sprintf("exit status %d", int(err))
+}
+
+func (err testExitCodeError) ExitStatus() (int, error) {
+	return int(err), nil
+}
+
+func TestRenderPlanOutputPipesThroughRenderer(t *testing.T) {
+	t.Parallel()
+
+	terragruntOptions, stdout := newPlanRendererTestOptions(t, "tr a-z A-Z")
+	err := renderPlanOutput(terragruntOptions, fakePlan(terragruntOptions, "  + resource \"null_resource\" \"this\" {}\n", 2))
+
+	exitCode, exitCodeErr := shell.GetExitCode(err)
+	require.NoError(t, exitCodeErr)
+	assert.Equal(t, 2, exitCode)
+	assert.Equal(t, "  + RESOURCE \"NULL_RESOURCE\" \"THIS\" {}\n", stdout.String())
+	assert.Equal(t, stdout, terragruntOptions.Writer)
+}
+
+func TestRenderPlanOutputSplitsRendererWithShellQuoting(t *testing.T) {
+	t.Parallel()
+
+	terragruntOptions, stdout := newPlanRendererTestOptions(t, `sed 's/No changes/Nothing to do/'`)
+	require.NoError(t, renderPlanOutput(terragruntOptions, fakePlan(terragruntOptions, "No changes.\n", 0)))
+	assert.Equal(t, "Nothing to do.\n", stdout.String())
+}
+
+func TestRenderPlanOutputSkipsPlansThatMayAskForInput(t *testing.T) {
+	t.Parallel()
+
+	terragruntOptions, stdout := newPlanRendererTestOptions(t, "tr a-z A-Z")
+	terragruntOptions.NonInteractive = false
+	assert.False(t, shouldRenderPlan(terragruntOptions))
+	require.NoError(t, renderPlanOutput(terragruntOptions, fakePlan(terragruntOptions, "var.cidr\n  Enter a value: ", 0)))
+	assert.Equal(t, "var.cidr\n  Enter a value: ", stdout.String())
+
+	terragruntOptions.TerraformCliArgs = []string{"plan", "-input=false"}
+	assert.True(t, shouldRenderPlan(terragruntOptions))
+	terragruntOptions.TerraformCliArgs = []string{"plan"}
+	terragruntOptions.Env = map[string]string{"TF_INPUT": "0"}
+	assert.True(t, shouldRenderPlan(terragruntOptions))
+}
+
+func TestRenderPlanOutputFallsBackWhenRendererFails(t *testing.T) {
+	t.Parallel()
+
+	terragruntOptions, stdout := newPlanRendererTestOptions(t, "false")
+	err := renderPlanOutput(terragruntOptions, fakePlan(terragruntOptions, "No changes.\n", 0))
+
+	assert.NoError(t, err)
+	assert.Equal(t, "No changes.\n", stdout.String())
+}
+
+func TestRenderPlanOutputSkipsJsonPlans(t *testing.T) {
+	t.Parallel()
+
+	terragruntOptions, stdout := newPlanRendererTestOptions(t, "tr a-z A-Z")
+	terragruntOptions.TerraformCliArgs = []string{"plan", "-json"}
+	require.NoError(t, renderPlanOutput(terragruntOptions, fakePlan(terragruntOptions, "{\"type\":\"version\"}\n", 0)))
+	assert.Equal(t, "{\"type\":\"version\"}\n", stdout.String())
+}
+
+func TestRunPlanRendererReportsExitCode(t *testing.T) {
+	t.Parallel()
+
+	terragruntOptions, _ := newPlanRendererTestOptions(t, "false")
+	_, err := runPlanRenderer(terragruntOptions, []byte("plan"))
+	assert.Equal(t, PlanRendererFailed{Renderer: "false", ExitCode: 1}, errors.Unwrap(err))
+}
@@ -0,0 +1,314 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/hashicorp/go-getter"
+
+	"github.com/gruntwork-io/terragrunt/config"
+	"github.com/gruntwork-io/terragrunt/errors"
+	"github.com/gruntwork-io/terragrunt/options"
+	"github.com/gruntwork-io/terragrunt/shell"
+	"github.com/gruntwork-io/terragrunt/util"
+)
+
+// The binary of the Open Policy Agent the policies are evaluated with
+const OPA_BINARY = "opa"
+
+// The rule of the policies that denies units. Like the deny rules of conftest, it's a set of messages saying why the
+// unit is denied, so the unit is allowed if it's empty or undefined.
+const policyDenyQuery = "data.terragrunt.deny"
+
+// The commands the policies are evaluated before
+var policyCheckedCommands = []string{"plan", "apply", "destroy"}
+
+// The dir the remote policy bundles are downloaded into
+var policyBundlesDir = filepath.Join(os.TempDir(), "terragrunt-policies")
+
+// The remote policy bundles downloaded during this run, by source, so that each is only downloaded once per run rather
+// than once per unit. The units of *-all commands check their policies concurrently, so the first unit needing a bundle
+// downloads it while the others wait for it.
+var policyBundles = sync.Map{}
+
+type policyBundle struct {
+	once sync.Once
+	dir  string
+	err  error
+}
+
+// Remove the policy bundles downloaded during this run and forget them, e.g. once the run finishes or between the
+// commands run by the daemon
+func removePolicyBundles() {
+	policyBundles.Range(func(source, rawBundle interface{}) bool {
+		if bundle := rawBundle.(*policyBundle); bundle.dir != "" {
+			os.RemoveAll(filepath.Dir(bundle.dir))
+		}
+		return true
+	})
+	policyBundles = sync.Map{}
+}
+
+// The input document the policies are evaluated against
+type policyInput struct {
+	// The path of the unit relative to the root of the git repo, or else to the working dir
+	Unit string `json:"unit"`
+	// The terraform command that is about to run, e.g. apply
+	Command string `json:"command"`
+	// The config of the unit, in the same shape as read_terragrunt_config returns it
+	Config json.RawMessage `json:"config"`
+	// The plan that is about to be applied, or was just saved, as printed by terraform show -json. Only set when the
+	// command has a saved plan file.
+	Plan json.RawMessage `json:"plan,omitempty"`
+}
+
+// The output of opa eval --format json
+type opaEvalOutput struct {
+	Result []struct {
+		Expressions []struct {
+			Value json.RawMessage `json:"value"`
+		} `json:"expressions"`
+	} `json:"result"`
+}
+
+func shouldCheckPolicies(terragruntOptions *options.TerragruntOptions) bool {
+	return len(terragruntOptions.Policies) > 0 && util.ListContainsElement(policyCheckedCommands, util.FirstArg(terragruntOptions.TerraformCliArgs))
+}
+
+// Evaluate the policies set via --terragrunt-policy against the config of the unit before terraform runs, and fail if
+// any of them denies it. When applying a saved plan, the plan is evaluated too. When saving a plan with plan -out, the
+// policies are evaluated once the plan is saved instead, by checkSavedPlanPolicies, so that they can check the plan.
+func checkPolicies(terragruntOptions *options.TerragruntOptions, terragruntConfig *config.TerragruntConfig) error {
+	if !shouldCheckPolicies(terragruntOptions) || planOutFile(terragruntOptions.TerraformCliArgs) != "" {
+		return nil
+	}
+	return evaluatePolicies(terragruntOptions, terragruntConfig, savedPlanFile(terragruntOptions))
+}
+
+// Evaluate the policies against the config of the unit and the plan it just saved with plan -out, if it did
+func checkSavedPlanPolicies(terragruntOptions *options.TerragruntOptions, terragruntConfig *config.TerragruntConfig) error {
+	planFile := planOutFile(terragruntOptions.TerraformCliArgs)
+	if !shouldCheckPolicies(terragruntOptions) || planFile == "" {
+		return nil
+	}
+	return evaluatePolicies(terragruntOptions, terragruntConfig, planFile)
+}
+
+// Evaluate the policies against the config of the unit and, if planFile is set, the plan in it, and return a
+// PolicyDenied error with the messages of the deny rule if it's not empty
+func evaluatePolicies(terragruntOptions *options.TerragruntOptions, terragruntConfig *config.TerragruntConfig, planFile string) error {
+	configJson, err := config.TerragruntConfigAsJson(terragruntConfig)
+	if err != nil {
+		return err
+	}
+
+	unit := filepath.Dir(terragruntOptions.TerragruntConfigPath)
+	if relPath, err := filepath.Rel(unitsRootDir(terragruntOptions), unit); err == nil {
+		unit = filepath.ToSlash(relPath)
+	}
+	input := policyInput{Unit: unit, Command: util.FirstArg(terragruntOptions.TerraformCliArgs), Config: configJson}
+
+	if planFile != "" {
+		out, err := shell.RunShellCommandWithOutput(terragruntOptions, "", true, false, terragruntOptions.TerraformPath, "show", "-json", planFile)
+		if err != nil {
+			return err
+		}
+		input.Plan = json.RawMessage(out.Stdout)
+	}
+
+	inputFile, err := ioutil.TempFile("", "terragrunt-policy-input-*.json")
+	if err != nil {
+		return errors.WithStackTrace(err)
+	}
+	defer os.Remove(inputFile.Name())
+	if err := json.NewEncoder(inputFile).Encode(input); err != nil {
+		inputFile.Close()
+		return errors.WithStackTrace(err)
+	}
+	if err := inputFile.Close(); err != nil {
+		return errors.WithStackTrace(err)
+	}
+
+	args := []string{"eval", "--format", "json", "--input", inputFile.Name()}
+	policyArgs, err := policySourceArgs(terragruntOptions)
+	if err != nil {
+		return err
+	}
+	args = append(append(args, policyArgs...), policyDenyQuery)
+
+	terragruntOptions.Logger.Debugf("Evaluating the policies against %s", unit)
+	out, err := shell.RunShellCommandWithOutput(terragruntOptions, "", true, false, OPA_BINARY, args...)
+	if err != nil {
+		return err
+	}
+
+	denials, err := parsePolicyDenials([]byte(out.Stdout))
+	if err != nil {
+		return err
+	}
+	if len(denials) > 0 {
+		return errors.WithStackTrace(PolicyDenied{Unit: unit, Denials: denials})
+	}
+	return nil
+}
+
+// Return the args of opa eval that load the policies: the local files and dirs are loaded as data, and the others are
+// downloaded with go-getter, e.g. from a git repo or an HTTP URL of a bundle tarball, and loaded as bundles
+func policySourceArgs(terragruntOptions *options.TerragruntOptions) ([]string, error) {
+	args := []string{}
+	for _, source := range terragruntOptions.Policies {
+		if util.FileExists(source) {
+			absPath, err := filepath.Abs(source)
+			if err != nil {
+				return nil, errors.WithStackTrace(err)
+			}
+			args = append(args, "--data", absPath)
+			continue
+		}
+
+		bundleDir, err := downloadPolicyBundle(source, terragruntOptions)
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, "--bundle", bundleDir)
+	}
+	return args, nil
+}
+
+// Download the policy bundle at the given source, unless it was already downloaded during this run. Each run downloads
+// the bundle into a dir of its own, so that the runs in parallel, e.g. of other jobs on the same machine, don't replace
+// the bundle while it's evaluated.
+func downloadPolicyBundle(source string, terragruntOptions *options.TerragruntOptions) (string, error) {
+	rawBundle, _ := policyBundles.LoadOrStore(source, &policyBundle{})
+	bundle := rawBundle.(*policyBundle)
+	bundle.once.Do(func() {
+		if err := os.MkdirAll(policyBundlesDir, os.ModePerm); err != nil {
+			bundle.err = errors.WithStackTrace(err)
+			return
+		}
+		dir, err := ioutil.TempDir(policyBundlesDir, util.EncodeBase64Sha1(source)+"-")
+		if err != nil {
+			bundle.err = errors.WithStackTrace(err)
+			return
+		}
+		// The getters of some sources, e.g. git, update the dir they download into if it exists, so the bundle goes into
+		// a dir that doesn't exist yet
+		bundle.dir = filepath.Join(dir, "bundle")
+		terragruntOptions.Logger.Infof("Downloading the policies at %s into %s", source, bundle.dir)
+		if err := getter.GetAny(bundle.dir, source); err != nil {
+			bundle.err = errors.WithStackTrace(PolicyDownloadFailed{Source: source, Err: err})
+		}
+	})
+	return bundle.dir, bundle.err
+}
+
+// Return the messages of the deny rule in the given output of opa eval. The messages that aren't strings, e.g. objects
+// with details, are returned as JSON.
+func parsePolicyDenials(output []byte) ([]string, error) {
+	var evalOutput opaEvalOutput
+	if err := json.Unmarshal(output, &evalOutput); err != nil {
+		return nil, errors.WithStackTrace(err)
+	}
+
+	denials := []string{}
+	for _, result := range evalOutput.Result {
+		for _, expression := range result.Expressions {
+			var values []json.RawMessage
+			if err := json.Unmarshal(expression.Value, &values); err != nil {
+				return nil, errors.WithStackTrace(InvalidPolicyDenyRule(string(expression.Value)))
+			}
+			for _, value := range values {
+				var message string
+				if err := json.Unmarshal(value, &message); err != nil {
+					message = string(value)
+				}
+				denials = append(denials, message)
+			}
+		}
+	}
+	return denials, nil
+}
+
+// Return the plan file the apply command applies, i.e. its last argument, if it's a file in the working dir
+func savedPlanFile(terragruntOptions *options.TerragruntOptions) string {
+	args := terragruntOptions.TerraformCliArgs
+	if util.FirstArg(args) != "apply" || len(args) < 2 {
+		return ""
+	}
+	lastArg := args[len(args)-1]
+	if strings.HasPrefix(lastArg, "-") || isTerraformFlagWithValue(args[len(args)-2]) {
+		return ""
+	}
+	planFile := lastArg
+	if !filepath.IsAbs(planFile) {
+		planFile = filepath.Join(terragruntOptions.WorkingDir, planFile)
+	}
+	if !util.IsFile(planFile) {
+		return ""
+	}
+	return lastArg
+}
+
+// The flags of terraform plan and apply that take a value, which can also be given as the next argument, e.g.
+// -var-file foo.tfvars
+var terraformFlagsWithValue = []string{"backup", "lock-timeout", "out", "parallelism", "replace", "state", "state-out", "target", "var", "var-file"}
+
+// Returns true if the given argument is a flag of terraform plan or apply whose value is the next argument
+func isTerraformFlagWithValue(arg string) bool {
+	if !strings.HasPrefix(arg, "-") {
+		return false
+	}
+	return util.ListContainsElement(terraformFlagsWithValue, strings.TrimPrefix(strings.TrimPrefix(arg, "-"), "-"))
+}
+
+// Return the file the plan command saves the plan to with -out, if any. Like the other flags of terraform, -out can
+// also be given as --out, and its value as the next argument.
+func planOutFile(args []string) string {
+	if util.FirstArg(args) != "plan" {
+		return ""
+	}
+	for i, arg := range args {
+		if !strings.HasPrefix(arg, "-") {
+			continue
+		}
+		flag := strings.TrimPrefix(strings.TrimPrefix(arg, "-"), "-")
+		switch {
+		case strings.HasPrefix(flag, "out="):
+			return strings.TrimPrefix(flag, "out=")
+		case flag == "out" && i+1 < len(args):
+			return args[i+1]
+		}
+	}
+	return ""
+}
+
+// Custom error types
+
+type PolicyDenied struct {
+	Unit    string
+	Denials []string
+}
+
+func (err PolicyDenied) Error() string {
+	return fmt.Sprintf("The policies deny %s:\n  - %s", err.Unit, strings.Join(err.Denials, "\n  - "))
+}
+
+type PolicyDownloadFailed struct {
+	Source string
+	Err    error
+}
+
+func (err PolicyDownloadFailed) Error() string {
+	return fmt.Sprintf("Could not download the policies at %s: %v", err.Source, err.Err)
+}
+
+type InvalidPolicyDenyRule string
+
+func (err InvalidPolicyDenyRule) Error() string {
+	return fmt.Sprintf("Expected %s to be a set of messages, but got %s", policyDenyQuery, string(err))
+}
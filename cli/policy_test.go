@@ -0,0 +1,144 @@
+package cli
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/gruntwork-io/terragrunt/config"
+	"github.com/gruntwork-io/terragrunt/options"
+	"github.com/gruntwork-io/terragrunt/util"
+)
+
+func TestParsePolicyDenials(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name     string
+		output   string
+		expected []string
+	}{
+		{"undefined", `{}`, []string{}},
+		{"empty", `{"result": [{"expressions": [{"value": [], "text": "data.terragrunt.deny"}]}]}`, []string{}},
+		{
+			"messages",
+			`{"result": [{"expressions": [{"value": ["prod must set prevent_destroy", {"rule": "tags"}], "text": "data.terragrunt.deny"}]}]}`,
+			[]string{"prod must set prevent_destroy", `{"rule": "tags"}`},
+		},
+	}
+
+	for _, testCase := range testCases {
+		// Capture range variable so that it is brought into the scope within the for loop, so that it is stable even
+		// when subtests are run in parallel.
+		testCase := testCase
+
+		t.Run(testCase.name, func(t *testing.T) {
+			t.Parallel()
+
+			denials, err := parsePolicyDenials([]byte(testCase.output))
+			require.NoError(t, err)
+			assert.Equal(t, testCase.expected, denials)
+		})
+	}
+
+	_, err := parsePolicyDenials([]byte(`{"result": [{"expressions": [{"value": true}]}]}`))
+	assert.Error(t, err)
+}
+
+func TestPolicyPlanFiles(t *testing.T) {
+	t.Parallel()
+
+	workingDir, err := ioutil.TempDir("", "policy-plan")
+	require.NoError(t, err)
+	defer os.RemoveAll(workingDir)
+	require.NoError(t, ioutil.WriteFile(filepath.Join(workingDir, "tfplan"), []byte{}, 0644))
+
+	terragruntOptions, err := options.NewTerragruntOptionsForTest(util.JoinPath(workingDir, config.DefaultTerragruntConfigPath))
+	require.NoError(t, err)
+	terragruntOptions.Policies = []string{"policies"}
+
+	terragruntOptions.TerraformCliArgs = []string{"apply", "-input=false", "tfplan"}
+	assert.True(t, shouldCheckPolicies(terragruntOptions))
+	assert.Equal(t, "tfplan", savedPlanFile(terragruntOptions))
+
+	terragruntOptions.TerraformCliArgs = []string{"apply", "-input=false", "-auto-approve"}
+	assert.Equal(t, "", savedPlanFile(terragruntOptions))
+
+	terragruntOptions.TerraformCliArgs = []string{"apply", "-var", "name=missing"}
+	assert.Equal(t, "", savedPlanFile(terragruntOptions))
+
+	// The value of a flag is not a plan, even if it's a file
+	terragruntOptions.TerraformCliArgs = []string{"apply", "-var-file", "tfplan"}
+	assert.Equal(t, "", savedPlanFile(terragruntOptions))
+
+	assert.Equal(t, "tfplan", planOutFile([]string{"plan", "-out=tfplan", "-input=false"}))
+	assert.Equal(t, "tfplan", planOutFile([]string{"plan", "-out", "tfplan", "-input=false"}))
+	assert.Equal(t, "tfplan", planOutFile([]string{"plan", "--out=tfplan"}))
+	assert.Equal(t, "tfplan", planOutFile([]string{"plan", "--out", "tfplan"}))
+	assert.Equal(t, "", planOutFile([]string{"plan", "-out"}))
+	assert.Equal(t, "", planOutFile([]string{"plan", "out", "tfplan"}))
+	assert.Equal(t, "", planOutFile([]string{"plan", "-input=false"}))
+	assert.Equal(t, "", planOutFile([]string{"apply", "-out=tfplan"}))
+
+	terragruntOptions.TerraformCliArgs = []string{"output"}
+	assert.False(t, shouldCheckPolicies(terragruntOptions))
+}
+
+func TestPolicyInputConfig(t *testing.T) {
+	t.Parallel()
+
+	preventDestroy := true
+	configJson, err := config.TerragruntConfigAsJson(&config.TerragruntConfig{
+		PreventDestroy: &preventDestroy,
+		Inputs:         map[string]interface{}{"env": "prod"},
+	})
+	require.NoError(t, err)
+
+	var renderedConfig map[string]interface{}
+	require.NoError(t, json.Unmarshal(configJson, &renderedConfig))
+	assert.Equal(t, true, renderedConfig["prevent_destroy"])
+	assert.Equal(t, map[string]interface{}{"env": "prod"}, renderedConfig["inputs"])
+}
+
+// Not parallel, as removing the policy bundles forgets the ones downloaded by the whole process
+func TestDownloadPolicyBundleOncePerRun(t *testing.T) {
+	sourceDir, err := ioutil.TempDir("", "policy-bundle")
+	require.NoError(t, err)
+	defer os.RemoveAll(sourceDir)
+	require.NoError(t, ioutil.WriteFile(filepath.Join(sourceDir, "policy.rego"), []byte("package terragrunt\n"), 0644))
+
+	terragruntOptions, err := options.NewTerragruntOptionsForTest("terragrunt.hcl")
+	require.NoError(t, err)
+
+	// The units of *-all commands check their policies concurrently, and all of them get the one download
+	dirs := make(chan string, 10)
+	var wg sync.WaitGroup
+	for i := 0; i < cap(dirs); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			dir, err := downloadPolicyBundle(sourceDir, terragruntOptions)
+			assert.NoError(t, err)
+			dirs <- dir
+		}()
+	}
+	wg.Wait()
+	close(dirs)
+
+	bundleDir := <-dirs
+	for dir := range dirs {
+		assert.Equal(t, bundleDir, dir)
+	}
+	assert.True(t, util.FileExists(filepath.Join(bundleDir, "policy.rego")))
+
+	removePolicyBundles()
+	assert.False(t, util.FileExists(filepath.Dir(bundleDir)))
+	_, isDownloaded := policyBundles.Load(sourceDir)
+	assert.False(t, isDownloaded)
+}
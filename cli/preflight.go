@@ -0,0 +1,16 @@
+package cli
+
+import (
+	"github.com/gruntwork-io/terragrunt/config"
+	"github.com/gruntwork-io/terragrunt/options"
+)
+
+// Check, when enabled via --terragrunt-preflight-checks, that the credentials of the remote state of the given config
+// have the permissions to use its backend, so that missing permissions fail the unit right away, with the list of the
+// permissions, rather than terraform failing after a long init.
+func runPreflightChecks(terragruntOptions *options.TerragruntOptions, terragruntConfig *config.TerragruntConfig) error {
+	if !terragruntOptions.PreflightChecks || terragruntConfig.RemoteState == nil {
+		return nil
+	}
+	return terragruntConfig.RemoteState.CheckPermissions(terragruntOptions)
+}
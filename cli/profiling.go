@@ -0,0 +1,89 @@
+package cli
+
+import (
+	"os"
+	"runtime"
+	"runtime/pprof"
+	"runtime/trace"
+
+	"github.com/gruntwork-io/terragrunt/errors"
+	"github.com/gruntwork-io/terragrunt/options"
+)
+
+// Start profiling Terragrunt itself, as set by the --terragrunt-cpu-profile, --terragrunt-mem-profile and
+// --terragrunt-trace options, so that slow runs can be investigated with go tool pprof and go tool trace. Returns a
+// function that stops profiling and writes out the profiles, which should be called once the command finishes.
+func startProfiling(terragruntOptions *options.TerragruntOptions) (func(), error) {
+	stops := []func(){}
+	stop := func() {
+		// Stop in reverse order, so that the heap profile is written last, once the other profiles are no longer
+		// being recorded
+		for i := len(stops) - 1; i >= 0; i-- {
+			stops[i]()
+		}
+	}
+
+	if terragruntOptions.MemProfile != "" {
+		stops = append(stops, func() { writeMemProfile(terragruntOptions) })
+	}
+
+	if terragruntOptions.CPUProfile != "" {
+		cpuProfileFile, err := os.Create(terragruntOptions.CPUProfile)
+		if err != nil {
+			return nil, errors.WithStackTrace(err)
+		}
+		if err := pprof.StartCPUProfile(cpuProfileFile); err != nil {
+			cpuProfileFile.Close()
+			return nil, errors.WithStackTrace(err)
+		}
+		stops = append(stops, func() {
+			pprof.StopCPUProfile()
+			closeProfileFile(cpuProfileFile, "CPU profile", terragruntOptions)
+		})
+	}
+
+	if terragruntOptions.TraceFile != "" {
+		traceFile, err := os.Create(terragruntOptions.TraceFile)
+		if err != nil {
+			stop()
+			return nil, errors.WithStackTrace(err)
+		}
+		if err := trace.Start(traceFile); err != nil {
+			traceFile.Close()
+			stop()
+			return nil, errors.WithStackTrace(err)
+		}
+		stops = append(stops, func() {
+			trace.Stop()
+			closeProfileFile(traceFile, "execution trace", terragruntOptions)
+		})
+	}
+
+	return stop, nil
+}
+
+// Write the heap profile, after a garbage collection, so that it reflects the memory still in use at the end of the
+// command, along with all the allocations made during it
+func writeMemProfile(terragruntOptions *options.TerragruntOptions) {
+	memProfileFile, err := os.Create(terragruntOptions.MemProfile)
+	if err != nil {
+		terragruntOptions.Logger.Errorf("Could not write the heap profile to %s: %v", terragruntOptions.MemProfile, err)
+		return
+	}
+	runtime.GC()
+	if err := pprof.WriteHeapProfile(memProfileFile); err != nil {
+		memProfileFile.Close()
+		terragruntOptions.Logger.Errorf("Could not write the heap profile to %s: %v", terragruntOptions.MemProfile, err)
+		return
+	}
+	closeProfileFile(memProfileFile, "heap profile", terragruntOptions)
+}
+
+// Close the given file a profile was written to, and tell the user where to find it
+func closeProfileFile(file *os.File, description string, terragruntOptions *options.TerragruntOptions) {
+	if err := file.Close(); err != nil {
+		terragruntOptions.Logger.Errorf("Could not write the %s to %s: %v", description, file.Name(), err)
+		return
+	}
+	terragruntOptions.Logger.Infof("Wrote the %s of Terragrunt to %s", description, file.Name())
+}
@@ -0,0 +1,48 @@
+package cli
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/gruntwork-io/terragrunt/options"
+)
+
+func TestStartProfiling(t *testing.T) {
+	t.Parallel()
+
+	profileDir, err := ioutil.TempDir("", "terragrunt-profiling")
+	require.NoError(t, err)
+	defer os.RemoveAll(profileDir)
+
+	terragruntOptions, err := options.NewTerragruntOptionsForTest("mock-path-for-test.hcl")
+	require.NoError(t, err)
+	terragruntOptions.CPUProfile = filepath.Join(profileDir, "cpu.pprof")
+	terragruntOptions.MemProfile = filepath.Join(profileDir, "mem.pprof")
+	terragruntOptions.TraceFile = filepath.Join(profileDir, "trace.out")
+
+	stopProfiling, err := startProfiling(terragruntOptions)
+	require.NoError(t, err)
+	stopProfiling()
+
+	for _, profile := range []string{terragruntOptions.CPUProfile, terragruntOptions.MemProfile, terragruntOptions.TraceFile} {
+		profileInfo, err := os.Stat(profile)
+		require.NoError(t, err)
+		assert.True(t, profileInfo.Size() > 0, "Expected %s not to be empty", profile)
+	}
+}
+
+func TestStartProfilingIsNoopWithoutProfiles(t *testing.T) {
+	t.Parallel()
+
+	terragruntOptions, err := options.NewTerragruntOptionsForTest("mock-path-for-test.hcl")
+	require.NoError(t, err)
+
+	stopProfiling, err := startProfiling(terragruntOptions)
+	require.NoError(t, err)
+	stopProfiling()
+}
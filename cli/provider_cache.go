@@ -0,0 +1,91 @@
+package cli
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/gruntwork-io/terragrunt/errors"
+	"github.com/gruntwork-io/terragrunt/options"
+	"github.com/gruntwork-io/terragrunt/provider_cache"
+	"github.com/gruntwork-io/terragrunt/util"
+)
+
+// The env vars terraform reads the path of its CLI config file and of its plugin cache dir from
+const (
+	terraformCLIConfigFileEnvVar  = "TF_CLI_CONFIG_FILE"
+	terraformPluginCacheDirEnvVar = "TF_PLUGIN_CACHE_DIR"
+)
+
+// Start the provider cache server and point all the terraform processes Terragrunt runs at it, by generating a CLI
+// config file that routes the provider registries through the server. The plugin cache dir is no longer needed, and as
+// terraform does not support concurrent writes to it, it's turned off. Returns a function that stops the server and
+// removes the CLI config file.
+func startProviderCacheServer(terragruntOptions *options.TerragruntOptions) (func(), error) {
+	cacheDir := terragruntOptions.ProviderCacheDir
+	if cacheDir == "" {
+		userCacheDir, err := os.UserCacheDir()
+		if err != nil {
+			return nil, errors.WithStackTrace(err)
+		}
+		cacheDir = filepath.Join(userCacheDir, "terragrunt", "providers")
+	}
+
+	server := provider_cache.NewServer(cacheDir, nil, terragruntOptions.Logger)
+	if err := server.Start(); err != nil {
+		return nil, err
+	}
+
+	existingCLIConfig, err := readTerraformCLIConfig(terragruntOptions)
+	if err != nil {
+		server.Close()
+		return nil, err
+	}
+
+	cliConfigFile, err := ioutil.TempFile(cacheDir, "terragrunt-provider-cache-*.tfrc")
+	if err != nil {
+		server.Close()
+		return nil, errors.WithStackTrace(err)
+	}
+	stop := func() {
+		server.Close()
+		os.Remove(cliConfigFile.Name())
+	}
+
+	_, err = cliConfigFile.WriteString(server.CLIConfig(existingCLIConfig))
+	if closeErr := cliConfigFile.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		stop()
+		return nil, errors.WithStackTrace(err)
+	}
+
+	terragruntOptions.Env[terraformCLIConfigFileEnvVar] = cliConfigFile.Name()
+	if _, hasPluginCacheDir := terragruntOptions.Env[terraformPluginCacheDirEnvVar]; hasPluginCacheDir {
+		terragruntOptions.Logger.Infof("Ignoring %s, as the providers are installed through the provider cache server", terraformPluginCacheDirEnvVar)
+		delete(terragruntOptions.Env, terraformPluginCacheDirEnvVar)
+	}
+
+	terragruntOptions.Logger.Infof("Installing providers through the provider cache server at %s, which caches them in %s", server.URL(), cacheDir)
+	return stop, nil
+}
+
+// Return the contents of the CLI config file terraform would read, so that the settings in it are kept in the CLI
+// config file of the provider cache server. This is the file set in TF_CLI_CONFIG_FILE, or else .terraformrc in the
+// home dir.
+func readTerraformCLIConfig(terragruntOptions *options.TerragruntOptions) (string, error) {
+	cliConfigPath := terragruntOptions.Env[terraformCLIConfigFileEnvVar]
+	if cliConfigPath == "" {
+		homeDir, err := os.UserHomeDir()
+		if err != nil {
+			return "", nil
+		}
+		cliConfigPath = filepath.Join(homeDir, ".terraformrc")
+	}
+
+	if !util.FileExists(cliConfigPath) {
+		return "", nil
+	}
+	return util.ReadFileAsString(cliConfigPath)
+}
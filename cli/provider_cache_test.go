@@ -0,0 +1,45 @@
+package cli
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/gruntwork-io/terragrunt/options"
+	"github.com/gruntwork-io/terragrunt/util"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStartProviderCacheServer(t *testing.T) {
+	t.Parallel()
+
+	cacheDir, err := ioutil.TempDir("", "provider-cache")
+	require.NoError(t, err)
+	defer os.RemoveAll(cacheDir)
+
+	existingCLIConfig := filepath.Join(cacheDir, "existing.tfrc")
+	require.NoError(t, ioutil.WriteFile(existingCLIConfig, []byte("disable_checkpoint = true\n"), 0644))
+
+	terragruntOptions, err := options.NewTerragruntOptionsForTest("provider_cache_test")
+	require.NoError(t, err)
+	terragruntOptions.ProviderCacheDir = cacheDir
+	terragruntOptions.Env["TF_CLI_CONFIG_FILE"] = existingCLIConfig
+	terragruntOptions.Env["TF_PLUGIN_CACHE_DIR"] = filepath.Join(cacheDir, "plugins")
+
+	stop, err := startProviderCacheServer(terragruntOptions)
+	require.NoError(t, err)
+
+	cliConfigFile := terragruntOptions.Env["TF_CLI_CONFIG_FILE"]
+	assert.NotEqual(t, existingCLIConfig, cliConfigFile)
+	assert.NotContains(t, terragruntOptions.Env, "TF_PLUGIN_CACHE_DIR")
+
+	cliConfig, err := util.ReadFileAsString(cliConfigFile)
+	require.NoError(t, err)
+	assert.Contains(t, cliConfig, "disable_checkpoint = true")
+	assert.Contains(t, cliConfig, `host "registry.terraform.io"`)
+
+	stop()
+	assert.False(t, util.FileExists(cliConfigFile))
+}
@@ -0,0 +1,61 @@
+package cli
+
+import (
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/gruntwork-io/terragrunt/options"
+	"github.com/gruntwork-io/terragrunt/shell"
+	"github.com/gruntwork-io/terragrunt/util"
+)
+
+// Makes sure only one module at a time populates the providers lock mirror, as terraform providers mirror does not
+// expect other processes to write to the same directory concurrently
+var providersLockMirrorMutex sync.Mutex
+
+// Return true if the given args are for the terraform providers lock command
+func isProvidersLockCommand(args []string) bool {
+	return util.FirstArg(args) == CMD_PROVIDERS && util.SecondArg(args) == CMD_LOCK
+}
+
+// Return the -platform args in the given terraform args, in both the -platform=X and the -platform X forms
+func providersLockPlatformArgs(args []string) []string {
+	platformArgs := []string{}
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		switch {
+		case strings.HasPrefix(arg, "-platform="):
+			platformArgs = append(platformArgs, arg)
+		case arg == "-platform" && i+1 < len(args):
+			platformArgs = append(platformArgs, "-platform="+args[i+1])
+			i++
+		}
+	}
+	return platformArgs
+}
+
+// When running providers lock with --terragrunt-providers-lock-mirror-dir, first run terraform providers mirror to
+// populate the mirror shared by all modules with the providers of this module, for the requested platforms, and then
+// point providers lock at that mirror via -fs-mirror. That way, providers lock computes the checksums from the
+// packages in the mirror instead of fetching every provider from the registry in every module.
+func prepareProvidersLockMirror(terragruntOptions *options.TerragruntOptions) error {
+	if !isProvidersLockCommand(terragruntOptions.TerraformCliArgs) || terragruntOptions.ProvidersLockMirrorDir == "" {
+		return nil
+	}
+
+	mirrorDir := filepath.ToSlash(terragruntOptions.ProvidersLockMirrorDir)
+	mirrorArgs := append([]string{CMD_PROVIDERS, "mirror"}, providersLockPlatformArgs(terragruntOptions.TerraformCliArgs)...)
+	mirrorArgs = append(mirrorArgs, mirrorDir)
+
+	terragruntOptions.Logger.Debugf("Populating the providers lock mirror %s for module %s", mirrorDir, terragruntOptions.WorkingDir)
+	providersLockMirrorMutex.Lock()
+	err := shell.RunTerraformCommand(terragruntOptions, mirrorArgs...)
+	providersLockMirrorMutex.Unlock()
+	if err != nil {
+		return err
+	}
+
+	terragruntOptions.InsertTerraformCliArgs("-fs-mirror=" + mirrorDir)
+	return nil
+}
@@ -0,0 +1,62 @@
+package cli
+
+import (
+	"testing"
+
+	"github.com/gruntwork-io/terragrunt/options"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProvidersLockPlatformArgs(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		args     []string
+		expected []string
+	}{
+		{[]string{"providers", "lock"}, []string{}},
+		{[]string{"providers", "lock", "-platform=linux_amd64", "-platform=darwin_arm64"}, []string{"-platform=linux_amd64", "-platform=darwin_arm64"}},
+		{[]string{"providers", "lock", "-platform", "linux_arm64", "hashicorp/aws"}, []string{"-platform=linux_arm64"}},
+	}
+
+	for _, testCase := range testCases {
+		assert.Equal(t, testCase.expected, providersLockPlatformArgs(testCase.args))
+	}
+}
+
+func TestExtraArgsAreInsertedAfterProvidersSubcommand(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		args     []string
+		insert   []string
+		expected []string
+	}{
+		{[]string{"providers", "lock", "-platform=linux_amd64"}, []string{"-platform=darwin_arm64"}, []string{"providers", "lock", "-platform=darwin_arm64", "-platform=linux_amd64"}},
+		{[]string{"providers", "lock"}, []string{"-fs-mirror=/mirror"}, []string{"providers", "lock", "-fs-mirror=/mirror"}},
+		{[]string{"providers", "mirror", "/mirror"}, []string{"-platform=linux_amd64"}, []string{"providers", "mirror", "-platform=linux_amd64", "/mirror"}},
+		{[]string{"providers", "schema"}, []string{"-json"}, []string{"providers", "schema", "-json"}},
+		{[]string{"providers"}, []string{"-no-color"}, []string{"providers", "-no-color"}},
+	}
+
+	for _, testCase := range testCases {
+		terragruntOptions, err := options.NewTerragruntOptionsForTest("")
+		require.NoError(t, err)
+
+		terragruntOptions.TerraformCliArgs = testCase.args
+		terragruntOptions.InsertTerraformCliArgs(testCase.insert...)
+		assert.Equal(t, testCase.expected, terragruntOptions.TerraformCliArgs, "%v", testCase.args)
+	}
+}
+
+func TestPrepareProvidersLockMirrorDoesNothingWithoutMirrorDir(t *testing.T) {
+	t.Parallel()
+
+	terragruntOptions, err := options.NewTerragruntOptionsForTest("")
+	require.NoError(t, err)
+
+	terragruntOptions.TerraformCliArgs = []string{"providers", "lock"}
+	require.NoError(t, prepareProvidersLockMirror(terragruntOptions))
+	assert.Equal(t, []string{"providers", "lock"}, terragruntOptions.TerraformCliArgs)
+}
@@ -0,0 +1,89 @@
+package cli
+
+import (
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"github.com/gruntwork-io/terragrunt/config"
+	"github.com/gruntwork-io/terragrunt/errors"
+	"github.com/gruntwork-io/terragrunt/options"
+	"github.com/gruntwork-io/terragrunt/tfc"
+)
+
+// Point the terraform processes of the unit of the given options, and the registry sources Terragrunt downloads itself,
+// at a CLI config file with the registry_credentials blocks and the registry_credentials_helper block of the given
+// config, on top of the CLI config terraform would read otherwise, so that they authenticate with the private registries
+// the modules and providers are installed from. Returns a function that removes the file, which should be called once the
+// unit finishes.
+func prepareRegistryCredentials(terragruntOptions *options.TerragruntOptions, terragruntConfig *config.TerragruntConfig) (func(), error) {
+	tokens := map[string]string{}
+	for host, credentials := range terragruntConfig.RegistryCredentials {
+		// A token taken from an env var that isn't set in e.g. local runs leaves the host to the user's own credentials
+		if credentials.Token == "" {
+			terragruntOptions.Logger.Debugf("Ignoring the registry_credentials block of %s, as its token is empty", host)
+			continue
+		}
+		// The credentials the user already has, e.g. from terraform login or a TF_TOKEN_<host> env var, take precedence
+		existingToken, err := tfc.CLIConfigToken(host, terragruntOptions.Env)
+		if err != nil {
+			return nil, err
+		}
+		if existingToken != "" {
+			terragruntOptions.Logger.Debugf("Keeping the credentials of %s the terraform CLI is already configured with", host)
+			continue
+		}
+		tokens[host] = credentials.Token
+	}
+	var helper *tfc.CredentialsHelper
+	if terragruntConfig.RegistryCredentialsHelper != nil {
+		helper = &tfc.CredentialsHelper{Name: terragruntConfig.RegistryCredentialsHelper.Name}
+		if terragruntConfig.RegistryCredentialsHelper.Args != nil {
+			helper.Args = *terragruntConfig.RegistryCredentialsHelper.Args
+		}
+	}
+	if len(tokens) == 0 && helper == nil {
+		return func() {}, nil
+	}
+
+	existingCLIConfig, err := readTerraformCLIConfig(terragruntOptions)
+	if err != nil {
+		return nil, err
+	}
+	existingCLIConfigPath := terragruntOptions.Env[terraformCLIConfigFileEnvVar]
+	if existingCLIConfigPath == "" {
+		existingCLIConfigPath = "~/.terraformrc"
+	}
+	cliConfig, skipped, err := tfc.AddCredentialsToCLIConfig(existingCLIConfig, existingCLIConfigPath, tokens, helper)
+	if err != nil {
+		return nil, err
+	}
+	if len(skipped) > 0 {
+		terragruntOptions.Logger.Debugf("Keeping the credentials of %s already set in the terraform CLI config", strings.Join(skipped, ", "))
+	}
+
+	// The file holds tokens, which TempFile already makes readable by the current user only
+	cliConfigFile, err := ioutil.TempFile("", "terragrunt-registry-credentials-*.tfrc")
+	if err != nil {
+		return nil, errors.WithStackTrace(err)
+	}
+	_, err = cliConfigFile.WriteString(cliConfig)
+	if closeErr := cliConfigFile.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(cliConfigFile.Name())
+		return nil, errors.WithStackTrace(err)
+	}
+
+	previousCLIConfigFile, hadCLIConfigFile := terragruntOptions.Env[terraformCLIConfigFileEnvVar]
+	terragruntOptions.Env[terraformCLIConfigFileEnvVar] = cliConfigFile.Name()
+	return func() {
+		os.Remove(cliConfigFile.Name())
+		if hadCLIConfigFile {
+			terragruntOptions.Env[terraformCLIConfigFileEnvVar] = previousCLIConfigFile
+		} else {
+			delete(terragruntOptions.Env, terraformCLIConfigFileEnvVar)
+		}
+	}, nil
+}
@@ -0,0 +1,71 @@
+package cli
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/gruntwork-io/terragrunt/config"
+	"github.com/gruntwork-io/terragrunt/options"
+	"github.com/gruntwork-io/terragrunt/tfc"
+	"github.com/gruntwork-io/terragrunt/util"
+)
+
+func TestPrepareRegistryCredentials(t *testing.T) {
+	t.Parallel()
+
+	dir, err := ioutil.TempDir("", "registry-credentials")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	userCLIConfig := filepath.Join(dir, "user.tfrc")
+	require.NoError(t, ioutil.WriteFile(userCLIConfig, []byte(`credentials "registry.acme.com" {
+  token = "user-token"
+}
+`), 0600))
+
+	terragruntOptions, err := options.NewTerragruntOptionsForTest(filepath.Join(dir, "terragrunt.hcl"))
+	require.NoError(t, err)
+	terragruntOptions.Env = map[string]string{terraformCLIConfigFileEnvVar: userCLIConfig, "TF_CLI_CONFIG_DIR": dir, "TF_TOKEN_env_acme_com": "env-token"}
+	terragruntConfig := &config.TerragruntConfig{RegistryCredentials: map[string]config.RegistryCredentialsConfig{
+		"registry.acme.com": {Host: "registry.acme.com", Token: "config-token"},
+		"env.acme.com":      {Host: "env.acme.com", Token: "config-token"},
+		"app.terraform.io":  {Host: "app.terraform.io", Token: "tfc-token"},
+		"tfe.acme.com":      {Host: "tfe.acme.com", Token: ""},
+	}}
+
+	removeRegistryCredentials, err := prepareRegistryCredentials(terragruntOptions, terragruntConfig)
+	require.NoError(t, err)
+	generatedCLIConfig := terragruntOptions.Env[terraformCLIConfigFileEnvVar]
+	assert.NotEqual(t, userCLIConfig, generatedCLIConfig)
+
+	for host, expectedToken := range map[string]string{"registry.acme.com": "user-token", "env.acme.com": "env-token", "app.terraform.io": "tfc-token", "tfe.acme.com": ""} {
+		token, err := tfc.CLIConfigToken(host, terragruntOptions.Env)
+		require.NoError(t, err)
+		assert.Equal(t, expectedToken, token, host)
+	}
+
+	contents, err := util.ReadFileAsString(generatedCLIConfig)
+	require.NoError(t, err)
+	assert.NotContains(t, contents, "config-token")
+
+	removeRegistryCredentials()
+	assert.Equal(t, userCLIConfig, terragruntOptions.Env[terraformCLIConfigFileEnvVar])
+	assert.False(t, util.FileExists(generatedCLIConfig))
+}
+
+func TestPrepareRegistryCredentialsWithoutConfig(t *testing.T) {
+	t.Parallel()
+
+	terragruntOptions, err := options.NewTerragruntOptionsForTest("terragrunt.hcl")
+	require.NoError(t, err)
+
+	removeRegistryCredentials, err := prepareRegistryCredentials(terragruntOptions, &config.TerragruntConfig{})
+	require.NoError(t, err)
+	removeRegistryCredentials()
+	_, hasCLIConfigFile := terragruntOptions.Env[terraformCLIConfigFileEnvVar]
+	assert.False(t, hasCLIConfigFile)
+}
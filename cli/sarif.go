@@ -0,0 +1,127 @@
+package cli
+
+import (
+	"fmt"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclparse"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/zclconf/go-cty/cty"
+
+	"github.com/gruntwork-io/terragrunt/options"
+	"github.com/gruntwork-io/terragrunt/sarif"
+)
+
+// The rules of the findings of validate-inputs
+var (
+	sarifUnusedInputRule = sarif.Rule{
+		ID:          "terragrunt/unused-input",
+		Description: "An input passed in by Terragrunt is not a variable of the Terraform module",
+		HelpUri:     "https://terragrunt.gruntwork.io/docs/reference/cli-options/#validate-inputs",
+	}
+	sarifMissingInputRule = sarif.Rule{
+		ID:          "terragrunt/missing-required-input",
+		Description: "A required variable of the Terraform module is not passed in by Terragrunt",
+		HelpUri:     "https://terragrunt.gruntwork.io/docs/reference/cli-options/#validate-inputs",
+	}
+)
+
+// Start collecting the findings of validate-inputs, if a file to write them to is set via --terragrunt-sarif-output.
+// The files are identified by their path relative to the root of the git repo they're in, or else to the working dir.
+// Returns a function that writes the findings, which should be called once the command finishes.
+func startSarif(terragruntOptions *options.TerragruntOptions) func() {
+	if terragruntOptions.SarifOutput == "" {
+		return func() {}
+	}
+
+	toolVersion := ""
+	if terragruntOptions.TerragruntVersion != nil {
+		toolVersion = terragruntOptions.TerragruntVersion.String()
+	}
+	report := sarif.NewReport(unitsRootDir(terragruntOptions), toolVersion)
+	terragruntOptions.Sarif = report
+
+	return func() {
+		if err := report.Write(terragruntOptions.SarifOutput); err != nil {
+			terragruntOptions.Logger.Warnf("Could not write the SARIF log to %s: %v", terragruntOptions.SarifOutput, err)
+		}
+	}
+}
+
+// Add the unused and missing inputs of the unit of the given options to its SARIF report. The unused inputs set in the
+// inputs attribute of the config of the unit point at their key, and the other findings at the inputs attribute, or
+// at the config itself if the inputs aren't set there, e.g. because they're inherited from an included config.
+func addInputsToSarif(terragruntOptions *options.TerragruntOptions, unusedVars []string, missingVars []string) {
+	if terragruntOptions.Sarif == nil {
+		return
+	}
+
+	configPath := terragruntOptions.TerragruntConfigPath
+	inputsRange, inputRanges := findInputRanges(configPath)
+
+	for _, varName := range unusedVars {
+		location, hasLocation := inputRanges[varName]
+		if !hasLocation {
+			location = inputsRange
+		}
+		terragruntOptions.Sarif.Add(sarifResultAt(location, sarif.Result{
+			Rule:    sarifUnusedInputRule,
+			Level:   sarif.LevelWarning,
+			Message: fmt.Sprintf("The input %s passed in by Terragrunt is not a variable of the Terraform module, so it's unused.", varName),
+			File:    configPath,
+		}))
+	}
+
+	for _, varName := range missingVars {
+		terragruntOptions.Sarif.Add(sarifResultAt(inputsRange, sarif.Result{
+			Rule:    sarifMissingInputRule,
+			Level:   sarif.LevelError,
+			Message: fmt.Sprintf("The required variable %s of the Terraform module is not passed in by Terragrunt.", varName),
+			File:    configPath,
+		}))
+	}
+}
+
+// Return the range of the inputs attribute of the given config, and the ranges of the keys of the inputs set in it.
+// The ranges are nil if the config can't be parsed, or doesn't set the inputs attribute.
+func findInputRanges(configPath string) (*hcl.Range, map[string]*hcl.Range) {
+	inputRanges := map[string]*hcl.Range{}
+
+	file, diags := hclparse.NewParser().ParseHCLFile(configPath)
+	if diags.HasErrors() {
+		return nil, inputRanges
+	}
+	body, isSyntaxBody := file.Body.(*hclsyntax.Body)
+	if !isSyntaxBody {
+		return nil, inputRanges
+	}
+	inputs, hasInputs := body.Attributes["inputs"]
+	if !hasInputs {
+		return nil, inputRanges
+	}
+
+	if object, isObject := inputs.Expr.(*hclsyntax.ObjectConsExpr); isObject {
+		for _, item := range object.Items {
+			key, diags := item.KeyExpr.Value(nil)
+			if diags.HasErrors() || !key.IsKnown() || key.IsNull() || key.Type() != cty.String {
+				continue
+			}
+			keyRange := item.KeyExpr.Range()
+			inputRanges[key.AsString()] = &keyRange
+		}
+	}
+
+	inputsRange := inputs.SrcRange
+	return &inputsRange, inputRanges
+}
+
+// Set the region of the given result to the given range, if any
+func sarifResultAt(location *hcl.Range, result sarif.Result) sarif.Result {
+	if location != nil {
+		result.StartLine = location.Start.Line
+		result.StartColumn = location.Start.Column
+		result.EndLine = location.End.Line
+		result.EndColumn = location.End.Column
+	}
+	return result
+}
@@ -0,0 +1,90 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/gruntwork-io/terragrunt/config"
+	"github.com/gruntwork-io/terragrunt/options"
+	"github.com/gruntwork-io/terragrunt/util"
+)
+
+func TestAddInputsToSarif(t *testing.T) {
+	t.Parallel()
+
+	tmpDir, err := ioutil.TempDir("", "sarif-inputs")
+	require.NoError(t, err)
+	defer os.RemoveAll(tmpDir)
+
+	configPath := util.JoinPath(tmpDir, config.DefaultTerragruntConfigPath)
+	contents := []byte("inputs = {\n  used   = 1\n  unused = 2\n}\n")
+	require.NoError(t, ioutil.WriteFile(configPath, contents, 0644))
+
+	terragruntOptions, err := options.NewTerragruntOptionsForTest(configPath)
+	require.NoError(t, err)
+	terragruntOptions.SarifOutput = filepath.Join(tmpDir, "terragrunt.sarif")
+	stopSarif := startSarif(terragruntOptions)
+	require.NotNil(t, terragruntOptions.Sarif)
+
+	addInputsToSarif(terragruntOptions, []string{"unused", "inherited"}, []string{"required"})
+	stopSarif()
+
+	contents, err = ioutil.ReadFile(terragruntOptions.SarifOutput)
+	require.NoError(t, err)
+	var log struct {
+		Runs []struct {
+			Results []struct {
+				RuleID    string `json:"ruleId"`
+				Locations []struct {
+					PhysicalLocation struct {
+						ArtifactLocation struct {
+							Uri string `json:"uri"`
+						} `json:"artifactLocation"`
+						Region struct {
+							StartLine   int `json:"startLine"`
+							StartColumn int `json:"startColumn"`
+							EndLine     int `json:"endLine"`
+						} `json:"region"`
+					} `json:"physicalLocation"`
+				} `json:"locations"`
+			} `json:"results"`
+		} `json:"runs"`
+	}
+	require.NoError(t, json.Unmarshal(contents, &log))
+	require.Len(t, log.Runs, 1)
+
+	// The unused input set in the config points at its key, and the other findings at the inputs attribute
+	findings := []string{}
+	for _, result := range log.Runs[0].Results {
+		location := result.Locations[0].PhysicalLocation
+		assert.Equal(t, config.DefaultTerragruntConfigPath, location.ArtifactLocation.Uri)
+		findings = append(findings, fmt.Sprintf("%s %d:%d-%d", result.RuleID, location.Region.StartLine, location.Region.StartColumn, location.Region.EndLine))
+	}
+	assert.Equal(t, []string{
+		"terragrunt/unused-input 1:1-4",
+		"terragrunt/missing-required-input 1:1-4",
+		"terragrunt/unused-input 3:3-3",
+	}, findings)
+}
+
+func TestAddInputsToSarifWithoutInputsAttribute(t *testing.T) {
+	t.Parallel()
+
+	tmpDir, err := ioutil.TempDir("", "sarif-inputs")
+	require.NoError(t, err)
+	defer os.RemoveAll(tmpDir)
+
+	configPath := util.JoinPath(tmpDir, config.DefaultTerragruntConfigPath)
+	require.NoError(t, ioutil.WriteFile(configPath, []byte("locals {}\n"), 0644))
+
+	inputsRange, inputRanges := findInputRanges(configPath)
+	assert.Nil(t, inputsRange)
+	assert.Empty(t, inputRanges)
+}
@@ -0,0 +1,160 @@
+package cli
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/go-getter"
+
+	"github.com/gruntwork-io/terragrunt/cli/tfsource"
+	"github.com/gruntwork-io/terragrunt/errors"
+	"github.com/gruntwork-io/terragrunt/options"
+	"github.com/gruntwork-io/terragrunt/util"
+)
+
+// manifest for files copied from the source cache into the Download Folder, so that the files removed from a newer
+// version of the source are removed from the Download Folder as well
+const SOURCE_CACHE_MANIFEST_NAME = ".terragrunt-source-cache-manifest"
+
+// The prefix of the temporary folders sources are downloaded into before they're moved into the source cache
+const sourceCacheDownloadPrefix = ".download-"
+
+// The locks that make the modules that use the same source wait for the first one to download it into the source cache,
+// rather than all of them downloading it at the same time
+var sourceCacheLocks = sync.Map{}
+
+// The entries of the source cache that were updated during this run because the terragrunt-source-update flag is set,
+// so that they're only downloaded again once per run, rather than once per module
+var updatedSourceCacheEntries = sync.Map{}
+
+// Ensures the source cache is only pruned once per run
+var pruneSourceCacheOnce sync.Once
+
+// Download the code from the Canonical Source URL into the source cache, unless it's already there, and copy it from
+// the cache into the Download Folder. The entries of the cache are keyed by the hash of the Canonical Source URL, which
+// includes the ref, so the modules that use the same source at the same ref share one download, within a run and across
+// runs.
+func downloadSourceThroughCache(terraformSource *tfsource.TerraformSource, terragruntOptions *options.TerragruntOptions) error {
+	cacheDir, err := sourceCacheDir(terragruntOptions)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(cacheDir, 0700); err != nil {
+		return errors.WithStackTrace(err)
+	}
+
+	pruneSourceCacheOnce.Do(func() {
+		pruneSourceCache(cacheDir, time.Duration(terragruntOptions.SourceCacheMaxAge)*time.Second, terragruntOptions)
+	})
+
+	sourceURL, err := sourceDownloadUrl(terraformSource, terragruntOptions)
+	if err != nil {
+		return err
+	}
+	entryDir := filepath.Join(cacheDir, util.EncodeBase64Sha1(sourceURL))
+
+	rawLock, _ := sourceCacheLocks.LoadOrStore(entryDir, &sync.Mutex{})
+	lock := rawLock.(*sync.Mutex)
+	lock.Lock()
+	defer lock.Unlock()
+
+	if terragruntOptions.SourceUpdate {
+		if _, alreadyUpdated := updatedSourceCacheEntries.LoadOrStore(entryDir, true); !alreadyUpdated {
+			terragruntOptions.Logger.Debugf("The --%s flag is set, so deleting the cached source %s before downloading it again.", OPT_TERRAGRUNT_SOURCE_UPDATE, entryDir)
+			if err := os.RemoveAll(entryDir); err != nil {
+				return errors.WithStackTrace(err)
+			}
+		}
+	}
+
+	if util.IsDir(entryDir) {
+		terragruntOptions.Logger.Debugf("Using the cached download of %s in %s", sourceURL, entryDir)
+	} else if err := downloadSourceIntoCache(sourceURL, cacheDir, entryDir, terragruntOptions); err != nil {
+		return err
+	}
+
+	// The modification time of the entry records when it was last used, which is what pruning is based on
+	now := time.Now()
+	if err := os.Chtimes(entryDir, now, now); err != nil {
+		return errors.WithStackTrace(err)
+	}
+
+	terragruntOptions.Logger.Debugf("Copying Terraform configurations from %s into %s", entryDir, terraformSource.DownloadDir)
+	return util.CopyFolderContentsWithFilter(entryDir, terraformSource.DownloadDir, SOURCE_CACHE_MANIFEST_NAME, func(path string) bool {
+		return !util.ListContainsElement(util.SplitPath(path), ".git")
+	})
+}
+
+// Download the given source URL into a temporary folder in the source cache, and then move it to the given entry of the
+// cache. Moving a folder is atomic, so other Terragrunt processes sharing the cache never see a partial download.
+func downloadSourceIntoCache(sourceURL string, cacheDir string, entryDir string, terragruntOptions *options.TerragruntOptions) error {
+	tmpDir, err := ioutil.TempDir(cacheDir, sourceCacheDownloadPrefix)
+	if err != nil {
+		return errors.WithStackTrace(err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	terragruntOptions.Logger.Debugf("Downloading Terraform configurations from %s into the source cache %s", sourceURL, entryDir)
+	downloadDir := filepath.Join(tmpDir, "source")
+	if err := getter.GetAny(downloadDir, sourceURL, copyFiles(terragruntOptions), withSourceMirrorHeaders(sourceURL, terragruntOptions), withSourceSigV4Signing(sourceURL, terragruntOptions)); err != nil {
+		return errors.WithStackTrace(err)
+	}
+
+	if err := os.Rename(downloadDir, entryDir); err != nil {
+		// Another Terragrunt process sharing the cache downloaded the same source in the meantime
+		if util.IsDir(entryDir) {
+			return nil
+		}
+		return errors.WithStackTrace(err)
+	}
+	return nil
+}
+
+// Remove the entries of the source cache that were last used longer ago than the given max age, along with the
+// temporary folders left behind by interrupted downloads. Failing to prune the cache doesn't prevent using it, so errors
+// are only logged.
+func pruneSourceCache(cacheDir string, maxAge time.Duration, terragruntOptions *options.TerragruntOptions) {
+	if maxAge <= 0 {
+		return
+	}
+
+	entries, err := ioutil.ReadDir(cacheDir)
+	if err != nil {
+		terragruntOptions.Logger.Warnf("Could not list the entries of the source cache %s to prune them: %v", cacheDir, err)
+		return
+	}
+
+	for _, entry := range entries {
+		if !entry.IsDir() || time.Since(entry.ModTime()) <= maxAge {
+			continue
+		}
+
+		entryDir := filepath.Join(cacheDir, entry.Name())
+		if strings.HasPrefix(entry.Name(), sourceCacheDownloadPrefix) {
+			terragruntOptions.Logger.Debugf("Removing the interrupted download %s from the source cache", entryDir)
+		} else {
+			terragruntOptions.Logger.Debugf("Removing the cached source %s, which was last used %s", entryDir, entry.ModTime())
+		}
+		if err := os.RemoveAll(entryDir); err != nil {
+			terragruntOptions.Logger.Warnf("Could not remove %s from the source cache: %v", entryDir, err)
+		}
+	}
+}
+
+// Return the directory of the source cache: the one set with the terragrunt-source-cache-dir flag, or else
+// terragrunt/sources in the user cache dir.
+func sourceCacheDir(terragruntOptions *options.TerragruntOptions) (string, error) {
+	if terragruntOptions.SourceCacheDir != "" {
+		return terragruntOptions.SourceCacheDir, nil
+	}
+
+	userCacheDir, err := os.UserCacheDir()
+	if err != nil {
+		return "", errors.WithStackTrace(err)
+	}
+	return filepath.Join(userCacheDir, "terragrunt", "sources"), nil
+}
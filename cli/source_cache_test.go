@@ -0,0 +1,88 @@
+package cli
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/gruntwork-io/terragrunt/cli/tfsource"
+	"github.com/gruntwork-io/terragrunt/options"
+	"github.com/gruntwork-io/terragrunt/util"
+)
+
+func TestDownloadSourceThroughCacheUsesCachedEntry(t *testing.T) {
+	t.Parallel()
+
+	cacheDir := tmpDir(t)
+	defer os.RemoveAll(cacheDir)
+	downloadDir := tmpDir(t)
+	defer os.RemoveAll(downloadDir)
+
+	writeFile := func(path string, contents string) {
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+		require.NoError(t, ioutil.WriteFile(path, []byte(contents), 0644))
+	}
+
+	// The source is never downloaded, as it's already in the cache
+	sourceURL := "git::https://example.com/acme/modules.git?ref=v1.0.0"
+	entryDir := filepath.Join(cacheDir, util.EncodeBase64Sha1(parseUrl(t, sourceURL).String()))
+	writeFile(filepath.Join(entryDir, "vpc", "main.tf"), "# vpc")
+	writeFile(filepath.Join(entryDir, ".git", "HEAD"), "ref: refs/heads/main")
+	lastUsed := time.Now().Add(-time.Hour)
+	require.NoError(t, os.Chtimes(entryDir, lastUsed, lastUsed))
+
+	terragruntOptions, err := options.NewTerragruntOptionsForTest("./should-not-be-used")
+	require.NoError(t, err)
+	terragruntOptions.SourceCache = true
+	terragruntOptions.SourceCacheDir = cacheDir
+
+	terraformSource := &tfsource.TerraformSource{
+		CanonicalSourceURL: parseUrl(t, sourceURL),
+		DownloadDir:        downloadDir,
+		WorkingDir:         filepath.Join(downloadDir, "vpc"),
+		VersionFile:        util.JoinPath(downloadDir, "version-file.txt"),
+	}
+	require.NoError(t, downloadSource(terraformSource, terragruntOptions, nil))
+
+	assert.Equal(t, "# vpc", readFile(t, filepath.Join(downloadDir, "vpc", "main.tf")))
+	assert.False(t, util.FileExists(filepath.Join(downloadDir, ".git")))
+
+	// Using the entry marks it as recently used, so it's not pruned
+	entryInfo, err := os.Stat(entryDir)
+	require.NoError(t, err)
+	assert.True(t, entryInfo.ModTime().After(lastUsed))
+}
+
+func TestPruneSourceCache(t *testing.T) {
+	t.Parallel()
+
+	cacheDir := tmpDir(t)
+	defer os.RemoveAll(cacheDir)
+
+	terragruntOptions, err := options.NewTerragruntOptionsForTest("./should-not-be-used")
+	require.NoError(t, err)
+
+	createEntry := func(name string, lastUsed time.Time) string {
+		entryDir := filepath.Join(cacheDir, name)
+		require.NoError(t, os.MkdirAll(entryDir, 0755))
+		require.NoError(t, os.Chtimes(entryDir, lastUsed, lastUsed))
+		return entryDir
+	}
+	recentEntry := createEntry("recent", time.Now().Add(-time.Hour))
+	oldEntry := createEntry("old", time.Now().Add(-48*time.Hour))
+	interruptedDownload := createEntry(sourceCacheDownloadPrefix+"123", time.Now().Add(-48*time.Hour))
+
+	// A max age of zero disables pruning
+	pruneSourceCache(cacheDir, 0, terragruntOptions)
+	assert.True(t, util.IsDir(oldEntry))
+
+	pruneSourceCache(cacheDir, 24*time.Hour, terragruntOptions)
+	assert.True(t, util.IsDir(recentEntry))
+	assert.False(t, util.FileExists(oldEntry))
+	assert.False(t, util.FileExists(interruptedDownload))
+}
@@ -0,0 +1,42 @@
+package cli
+
+import (
+	"os"
+	"strings"
+
+	"github.com/gruntwork-io/terragrunt/options"
+	"github.com/gruntwork-io/terragrunt/telemetry"
+)
+
+// Start tracing the given Terragrunt command with OpenTelemetry, if an OTLP endpoint is set via
+// --terragrunt-telemetry-endpoint or the standard OTEL_EXPORTER_OTLP_* env vars. The root span of the command is set
+// on terragruntOptions, so that the spans of the operations it runs are its children. Returns a function that ends the
+// root span with the error of the command and exports the spans that are left, which should be called once the
+// command finishes.
+func startTelemetry(command string, terragruntOptions *options.TerragruntOptions) (func(error), error) {
+	endpoint := telemetry.TracesEndpoint(terragruntOptions.TelemetryEndpoint)
+	if endpoint == "" {
+		return func(error) {}, nil
+	}
+
+	headers, err := telemetry.ParseHeaders(os.Getenv(telemetry.HeadersEnvVar))
+	if err != nil {
+		return nil, err
+	}
+
+	tracer := telemetry.NewTracer(endpoint, headers, os.Getenv(telemetry.ServiceNameEnvVar))
+	rootSpan := tracer.StartRootSpan("terragrunt "+command, os.Getenv(telemetry.TraceParentEnvVar), map[string]string{
+		"terragrunt.command":     command,
+		"terragrunt.args":        strings.Join(terragruntOptions.TerraformCliArgs, " "),
+		"terragrunt.working_dir": terragruntOptions.WorkingDir,
+	})
+	terragruntOptions.TelemetrySpan = rootSpan
+	terragruntOptions.Logger.Debugf("Exporting the traces of Terragrunt to %s", endpoint)
+
+	return func(commandErr error) {
+		rootSpan.End(commandErr)
+		if err := tracer.Shutdown(); err != nil {
+			terragruntOptions.Logger.Warnf("Could not export the traces of Terragrunt: %v", err)
+		}
+	}, nil
+}
@@ -0,0 +1,264 @@
+package cli
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+
+	"github.com/gruntwork-io/terragrunt/config"
+	"github.com/gruntwork-io/terragrunt/errors"
+	"github.com/gruntwork-io/terragrunt/options"
+	"github.com/gruntwork-io/terragrunt/remote"
+	"github.com/gruntwork-io/terragrunt/shell"
+	"github.com/gruntwork-io/terragrunt/tfc"
+	"github.com/gruntwork-io/terragrunt/util"
+)
+
+// The commands that run in Terraform Cloud with --terragrunt-tfc-run. The other commands, e.g. output, run locally.
+var TFC_RUN_COMMANDS = []string{"plan", "apply", "destroy"}
+
+// The var file the inputs of the unit are uploaded as, which Terraform Cloud loads like any other *.auto.tfvars.json
+// file, as the TF_VAR_ env vars terragrunt passes the inputs with locally don't reach it
+const TFC_INPUTS_FILE = "terragrunt.auto.tfvars.json"
+
+// The var files passed to the command with -var-file, including the required_var_files and optional_var_files of the
+// unit, are uploaded as *.auto.tfvars files named after this format and their position. Terraform Cloud loads them after
+// the inputs, as the names sort after TFC_INPUTS_FILE, and in the order they were passed in, so that they take
+// precedence over the inputs, and each over the ones before it, like they do locally.
+const TFC_VAR_FILE_FORMAT = "terragrunt_var_file_%03d.auto.tfvars"
+
+func shouldRunInTFC(terragruntOptions *options.TerragruntOptions) bool {
+	return terragruntOptions.TFCRun && util.ListContainsElement(TFC_RUN_COMMANDS, util.FirstArg(terragruntOptions.TerraformCliArgs))
+}
+
+// Run the terraform command as a run of the Terraform Cloud workspace of the remote_state block of the unit, rather
+// than locally: upload the working dir, with the inputs and var files as *.auto.tfvars files, as a configuration
+// version of the workspace, along with the rest of the source it was downloaded from, e.g. the modules the code refers
+// to with relative paths, in which case the working directory of the workspace is set to the folder of the unit,
+// create a run of it with the options of the command, and print its logs until it ends. When the run waits for a
+// confirmation, the user is asked whether to apply it, like terraform apply does. The exit code follows the status the
+// run ends in.
+func runInTFC(terragruntOptions *options.TerragruntOptions, terragruntConfig *config.TerragruntConfig) error {
+	if planFile := savedPlanFile(terragruntOptions); planFile != "" {
+		return errors.WithStackTrace(TFCSavedPlanNotSupported(planFile))
+	}
+
+	tfcConfig, err := remote.ParseTFCWorkspace(terragruntConfig.RemoteState)
+	if err != nil {
+		return err
+	}
+	token := tfcConfig.Token
+	if token == "" {
+		token, err = tfc.FindToken(tfcConfig.Hostname, terragruntOptions.Env)
+		if err != nil {
+			return err
+		}
+	}
+	client := tfc.NewClient("https://"+tfcConfig.Hostname, token)
+
+	commandArgs, err := parseTFCCommandArgs(terragruntOptions.TerraformCliArgs)
+	if err != nil {
+		return err
+	}
+	extraFiles, err := tfcVarFiles(commandArgs.VarFiles, terragruntOptions.WorkingDir)
+	if err != nil {
+		return err
+	}
+
+	workspace, err := client.Workspace(tfcConfig.Organization, tfcConfig.Workspaces.Name)
+	if err != nil {
+		return err
+	}
+
+	configurationVersion, err := client.CreateConfigurationVersion(workspace.ID, commandArgs.RunOptions.PlanOnly)
+	if err != nil {
+		return err
+	}
+
+	required, optional, err := terraformModuleVariables(terragruntOptions)
+	if err != nil {
+		return err
+	}
+	inputs, err := terragruntDebugFileContents(terragruntOptions, terragruntConfig, append(required, optional...))
+	if err != nil {
+		return err
+	}
+	extraFiles[TFC_INPUTS_FILE] = inputs
+
+	uploadDir, workingDirectory, err := tfcUploadDirs(terragruntOptions)
+	if err != nil {
+		return err
+	}
+	if err := client.SetWorkingDirectory(workspace, workingDirectory); err != nil {
+		return err
+	}
+	// The extra files are loaded from the working directory of the workspace
+	if workingDirectory != "" {
+		filesInWorkingDirectory := map[string][]byte{}
+		for name, contents := range extraFiles {
+			filesInWorkingDirectory[workingDirectory+"/"+name] = contents
+		}
+		extraFiles = filesInWorkingDirectory
+	}
+	terragruntOptions.Logger.Debugf("Uploading %s to the workspace %s, with the working directory %q", uploadDir, workspace.Name, workingDirectory)
+	if err := client.UploadConfiguration(configurationVersion, uploadDir, extraFiles); err != nil {
+		return err
+	}
+
+	run, err := client.CreateRun(workspace.ID, configurationVersion.ID, commandArgs.RunOptions)
+	if err != nil {
+		return err
+	}
+	runUrl := fmt.Sprintf("https://%s/app/%s/workspaces/%s/runs/%s", tfcConfig.Hostname, workspace.Organization, workspace.Name, run.ID)
+	terragruntOptions.Logger.Infof("Created the run %s of the workspace %s: %s", run.ID, workspace.Name, runUrl)
+
+	run, err = client.WatchRun(run.ID, terragruntOptions.Writer, func(run *tfc.Run) (bool, error) {
+		return shell.PromptUserForYesNo(fmt.Sprintf("Apply the plan of the run %s?", run.ID), terragruntOptions)
+	})
+	if err != nil {
+		return err
+	}
+
+	if exitCode := tfc.ExitCode(run, commandArgs.DetailedExitCode); exitCode != 0 {
+		return errors.WithStackTrace(TFCRunExitCode{RunUrl: runUrl, Status: run.Status, AwaitsDecision: tfc.RunAwaitsDecision(run), ExitCode: exitCode})
+	}
+	return nil
+}
+
+// The arguments of the terraform command a run of Terraform Cloud replaces
+type tfcCommandArgs struct {
+	// The options of the run
+	RunOptions tfc.RunOptions
+	// Whether the command asks for a detailed exit code
+	DetailedExitCode bool
+	// The var files passed to the command, in order
+	VarFiles []string
+}
+
+// Parse the given args of the terraform command a run of Terraform Cloud replaces. Like the other flags of terraform,
+// they can start with - or --, and their values can also be the next argument. The values of -var can't be passed on
+// to the run, as Terraform Cloud only takes variables from var files and the variables of the workspace, so they're
+// rejected rather than left out.
+func parseTFCCommandArgs(args []string) (*tfcCommandArgs, error) {
+	command := util.FirstArg(args)
+	commandArgs := &tfcCommandArgs{
+		RunOptions: tfc.RunOptions{
+			Message:   fmt.Sprintf("Triggered by terragrunt %s", command),
+			IsDestroy: command == "destroy",
+			PlanOnly:  command == "plan",
+		},
+	}
+
+	for i := 1; i < len(args); i++ {
+		arg := args[i]
+		if !strings.HasPrefix(arg, "-") {
+			continue
+		}
+		flag := strings.TrimPrefix(strings.TrimPrefix(arg, "-"), "-")
+		value := ""
+		if nameAndValue := strings.SplitN(flag, "=", 2); len(nameAndValue) == 2 {
+			flag, value = nameAndValue[0], nameAndValue[1]
+		} else if isTerraformFlagWithValue(arg) && i+1 < len(args) {
+			i++
+			value = args[i]
+		}
+
+		switch flag {
+		case "destroy":
+			commandArgs.RunOptions.IsDestroy = true
+		case "auto-approve":
+			commandArgs.RunOptions.AutoApply = true
+		case "refresh-only":
+			commandArgs.RunOptions.RefreshOnly = true
+		case "detailed-exitcode":
+			commandArgs.DetailedExitCode = true
+		case "target":
+			commandArgs.RunOptions.TargetAddrs = append(commandArgs.RunOptions.TargetAddrs, value)
+		case "replace":
+			commandArgs.RunOptions.ReplaceAddrs = append(commandArgs.RunOptions.ReplaceAddrs, value)
+		case "var-file":
+			commandArgs.VarFiles = append(commandArgs.VarFiles, value)
+		case "var":
+			return nil, errors.WithStackTrace(TFCVarNotSupported(value))
+		}
+	}
+	return commandArgs, nil
+}
+
+// Return the dir to upload to Terraform Cloud, and the working directory of the workspace relative to it, in the form
+// Terraform Cloud expects it, e.g. modules/vpc: the root of the downloaded source and the folder of the unit in it, so
+// that the relative paths of the code, e.g. to the modules in the same repo, still work, or else the working dir itself
+func tfcUploadDirs(terragruntOptions *options.TerragruntOptions) (string, string, error) {
+	if terragruntOptions.SourceDownloadDir == "" {
+		return terragruntOptions.WorkingDir, "", nil
+	}
+	workingDirectory, err := filepath.Rel(terragruntOptions.SourceDownloadDir, terragruntOptions.WorkingDir)
+	if err != nil {
+		return "", "", errors.WithStackTrace(err)
+	}
+	workingDirectory = filepath.ToSlash(workingDirectory)
+	if workingDirectory == ".." || strings.HasPrefix(workingDirectory, "../") {
+		return terragruntOptions.WorkingDir, "", nil
+	}
+	if workingDirectory == "." {
+		workingDirectory = ""
+	}
+	return terragruntOptions.SourceDownloadDir, workingDirectory, nil
+}
+
+// Return the contents of the given var files, relative to the given working dir, keyed by the names they're uploaded
+// as. See TFC_VAR_FILE_FORMAT.
+func tfcVarFiles(varFiles []string, workingDir string) (map[string][]byte, error) {
+	files := map[string][]byte{}
+	for i, varFile := range varFiles {
+		if !filepath.IsAbs(varFile) {
+			varFile = filepath.Join(workingDir, varFile)
+		}
+		contents, err := ioutil.ReadFile(varFile)
+		if err != nil {
+			return nil, errors.WithStackTrace(err)
+		}
+		name := fmt.Sprintf(TFC_VAR_FILE_FORMAT, i+1)
+		if strings.HasSuffix(varFile, ".json") {
+			name += ".json"
+		}
+		files[name] = contents
+	}
+	return files, nil
+}
+
+// Custom error types
+
+type TFCRunExitCode struct {
+	RunUrl         string
+	Status         string
+	AwaitsDecision string
+	ExitCode       int
+}
+
+func (err TFCRunExitCode) Error() string {
+	if err.ExitCode == 2 {
+		return fmt.Sprintf("The plan of the run %s has changes", err.RunUrl)
+	}
+	if err.AwaitsDecision != "" {
+		return fmt.Sprintf("The run %s waits for %s in Terraform Cloud", err.RunUrl, err.AwaitsDecision)
+	}
+	return fmt.Sprintf("The run %s ended with the status %s", err.RunUrl, err.Status)
+}
+
+func (err TFCRunExitCode) ExitStatus() (int, error) {
+	return err.ExitCode, nil
+}
+
+type TFCSavedPlanNotSupported string
+
+func (planFile TFCSavedPlanNotSupported) Error() string {
+	return fmt.Sprintf("Can't apply the saved plan %s in Terraform Cloud, which applies the plans of its own runs. Run apply without a plan file instead.", string(planFile))
+}
+
+type TFCVarNotSupported string
+
+func (value TFCVarNotSupported) Error() string {
+	return fmt.Sprintf("Can't pass -var %s to the run in Terraform Cloud, which only takes variables from var files and the variables of the workspace. Set it in the inputs of the unit or in a var file instead.", string(value))
+}
@@ -0,0 +1,127 @@
+package cli
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/gruntwork-io/terragrunt/errors"
+	"github.com/gruntwork-io/terragrunt/options"
+	"github.com/gruntwork-io/terragrunt/tfc"
+)
+
+func TestParseTFCCommandArgs(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		args                     []string
+		expectedOptions          tfc.RunOptions
+		expectedDetailedExitCode bool
+		expectedVarFiles         []string
+	}{
+		{
+			[]string{"plan", "-input=false", "-detailed-exitcode"},
+			tfc.RunOptions{Message: "Triggered by terragrunt plan", PlanOnly: true},
+			true,
+			nil,
+		},
+		{
+			[]string{"plan", "-destroy", "-target=aws_vpc.main", "-target=aws_subnet.private"},
+			tfc.RunOptions{Message: "Triggered by terragrunt plan", PlanOnly: true, IsDestroy: true, TargetAddrs: []string{"aws_vpc.main", "aws_subnet.private"}},
+			false,
+			nil,
+		},
+		{
+			[]string{"plan", "-target", "aws_vpc.main", "--replace", "aws_instance.web", "-var-file=/live/common.tfvars", "-var-file", "prod.tfvars"},
+			tfc.RunOptions{Message: "Triggered by terragrunt plan", PlanOnly: true, TargetAddrs: []string{"aws_vpc.main"}, ReplaceAddrs: []string{"aws_instance.web"}},
+			false,
+			[]string{"/live/common.tfvars", "prod.tfvars"},
+		},
+		{
+			[]string{"apply", "-auto-approve", "-replace=aws_instance.web"},
+			tfc.RunOptions{Message: "Triggered by terragrunt apply", AutoApply: true, ReplaceAddrs: []string{"aws_instance.web"}},
+			false,
+			nil,
+		},
+		{
+			[]string{"destroy"},
+			tfc.RunOptions{Message: "Triggered by terragrunt destroy", IsDestroy: true},
+			false,
+			nil,
+		},
+	}
+
+	for _, testCase := range testCases {
+		commandArgs, err := parseTFCCommandArgs(testCase.args)
+		require.NoError(t, err, "%v", testCase.args)
+		assert.Equal(t, testCase.expectedOptions, commandArgs.RunOptions, "%v", testCase.args)
+		assert.Equal(t, testCase.expectedDetailedExitCode, commandArgs.DetailedExitCode, "%v", testCase.args)
+		assert.Equal(t, testCase.expectedVarFiles, commandArgs.VarFiles, "%v", testCase.args)
+	}
+
+	_, err := parseTFCCommandArgs([]string{"apply", "-var", "cidr=10.0.0.0/16"})
+	assert.Equal(t, TFCVarNotSupported("cidr=10.0.0.0/16"), errors.Unwrap(err))
+}
+
+func TestTFCVarFiles(t *testing.T) {
+	t.Parallel()
+
+	workingDir, err := ioutil.TempDir("", "tfc-var-files")
+	require.NoError(t, err)
+	defer os.RemoveAll(workingDir)
+	require.NoError(t, ioutil.WriteFile(filepath.Join(workingDir, "common.tfvars.json"), []byte(`{"cidr": "10.0.0.0/16"}`), 0644))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(workingDir, "prod.tfvars"), []byte(`cidr = "10.1.0.0/16"`), 0644))
+
+	files, err := tfcVarFiles([]string{filepath.Join(workingDir, "common.tfvars.json"), "prod.tfvars"}, workingDir)
+	require.NoError(t, err)
+	assert.Equal(t, map[string][]byte{
+		"terragrunt_var_file_001.auto.tfvars.json": []byte(`{"cidr": "10.0.0.0/16"}`),
+		"terragrunt_var_file_002.auto.tfvars":      []byte(`cidr = "10.1.0.0/16"`),
+	}, files)
+	// The var files are loaded after the inputs, so that they take precedence
+	assert.True(t, TFC_INPUTS_FILE < "terragrunt_var_file_001.auto.tfvars")
+}
+
+func TestTFCUploadDirs(t *testing.T) {
+	t.Parallel()
+
+	terragruntOptions, err := options.NewTerragruntOptionsForTest("terragrunt.hcl")
+	require.NoError(t, err)
+
+	// Without a source, the working dir is the whole configuration
+	terragruntOptions.WorkingDir = "/live/prod/vpc"
+	uploadDir, workingDirectory, err := tfcUploadDirs(terragruntOptions)
+	require.NoError(t, err)
+	assert.Equal(t, "/live/prod/vpc", uploadDir)
+	assert.Equal(t, "", workingDirectory)
+
+	// With a source, the whole source is uploaded, so that e.g. the modules the unit refers to with ../ are there too
+	terragruntOptions.SourceDownloadDir = "/live/prod/vpc/.terragrunt-cache/abc/def"
+	terragruntOptions.WorkingDir = "/live/prod/vpc/.terragrunt-cache/abc/def/modules/vpc"
+	uploadDir, workingDirectory, err = tfcUploadDirs(terragruntOptions)
+	require.NoError(t, err)
+	assert.Equal(t, "/live/prod/vpc/.terragrunt-cache/abc/def", uploadDir)
+	assert.Equal(t, "modules/vpc", workingDirectory)
+
+	terragruntOptions.WorkingDir = terragruntOptions.SourceDownloadDir
+	uploadDir, workingDirectory, err = tfcUploadDirs(terragruntOptions)
+	require.NoError(t, err)
+	assert.Equal(t, "/live/prod/vpc/.terragrunt-cache/abc/def", uploadDir)
+	assert.Equal(t, "", workingDirectory)
+}
+
+func TestShouldRunInTFC(t *testing.T) {
+	t.Parallel()
+
+	for _, args := range [][]string{{"plan"}, {"apply", "-auto-approve"}, {"destroy"}, {"output"}} {
+		terragruntOptions := &options.TerragruntOptions{TerraformCliArgs: args}
+		assert.False(t, shouldRunInTFC(terragruntOptions))
+
+		terragruntOptions.TFCRun = true
+		assert.Equal(t, args[0] != "output", shouldRunInTFC(terragruntOptions), "%v", args)
+	}
+}
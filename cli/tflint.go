@@ -0,0 +1,175 @@
+package cli
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/gruntwork-io/terragrunt/config"
+	"github.com/gruntwork-io/terragrunt/errors"
+	"github.com/gruntwork-io/terragrunt/options"
+	"github.com/gruntwork-io/terragrunt/shell"
+	"github.com/gruntwork-io/terragrunt/tflint"
+	"github.com/gruntwork-io/terragrunt/util"
+)
+
+// The binary of tflint, which the tflint command runs
+const TFLINT_BINARY = "tflint"
+
+// The config file of tflint, which it looks for in the dir it runs in
+const TFLINT_CONFIG_FILE = ".tflint.hcl"
+
+// The exit code of tflint when it found issues, as opposed to failing to lint the code
+const tflintIssuesExitCode = 2
+
+func shouldRunTflint(terragruntOptions *options.TerragruntOptions) bool {
+	return util.FirstArg(terragruntOptions.TerraformCliArgs) == CMD_TFLINT
+}
+
+// Start adding up the issues tflint finds in the units, if the command is tflint, e.g. run via run-all tflint. Returns
+// a function that prints how many issues were found in how many units, which should be called once the command
+// finishes.
+func startTflint(terragruntOptions *options.TerragruntOptions) func() {
+	if !shouldRunTflint(terragruntOptions) {
+		return func() {}
+	}
+
+	results := tflint.NewResults()
+	terragruntOptions.TflintResults = results
+
+	return func() {
+		if summary := results.Summary(); summary != "" {
+			terragruntOptions.Logger.Infof("%s", summary)
+		}
+	}
+}
+
+// Run tflint in the working dir of the unit of the given options, once its Terraform code is downloaded and generated,
+// so that tflint sees the same code, providers and inputs terraform would. The inputs of the unit that are variables of
+// the module are passed to tflint in a var file, the .tflint.hcl of the working dir, or else the closest one in the
+// folder of the unit or its parents, configures tflint, and the args after tflint are passed on to it, e.g.
+// --minimum-failure-severity=error. Returns an error if tflint found issues or failed to lint the code.
+func runTflint(terragruntOptions *options.TerragruntOptions, terragruntConfig *config.TerragruntConfig) error {
+	unit := filepath.Dir(terragruntOptions.TerragruntConfigPath)
+	if relPath, err := filepath.Rel(unitsRootDir(terragruntOptions), unit); err == nil {
+		unit = filepath.ToSlash(relPath)
+	}
+
+	configArgs := []string{}
+	if configPath := findTflintConfig(terragruntOptions); configPath != "" {
+		configArgs = append(configArgs, "--config", configPath)
+		// Install the plugins the config enables, such as the ruleset of the cloud provider, unless they already are
+		initArgs := append([]string{"--init"}, configArgs...)
+		if _, err := shell.RunShellCommandWithOutput(terragruntOptions, "", true, false, TFLINT_BINARY, initArgs...); err != nil {
+			return err
+		}
+	}
+
+	varFile, err := writeTflintVarFile(terragruntOptions, terragruntConfig)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(varFile)
+
+	args := append([]string{"--format", "json", "--var-file", varFile}, configArgs...)
+	args = append(args, terragruntOptions.TerraformCliArgs[1:]...)
+	out, tflintErr := shell.RunShellCommandWithOutput(terragruntOptions, "", true, false, TFLINT_BINARY, args...)
+	if out == nil {
+		return tflintErr
+	}
+
+	output, err := tflint.ParseOutput([]byte(out.Stdout))
+	if err != nil {
+		if tflintErr != nil {
+			return tflintErr
+		}
+		return err
+	}
+
+	terragruntOptions.TflintResults.Add(output.Issues)
+	for _, issue := range output.Issues {
+		fmt.Fprintf(terragruntOptions.Writer, "%s: %s\n", unit, issue)
+	}
+
+	if len(output.Errors) > 0 {
+		return errors.WithStackTrace(TflintFailed{Unit: unit, Errors: output.Errors})
+	}
+	if tflintErr != nil {
+		if exitCode, err := shell.GetExitCode(tflintErr); err == nil && exitCode == tflintIssuesExitCode {
+			return errors.WithStackTrace(TflintIssuesFound{Unit: unit, Issues: len(output.Issues)})
+		}
+		return tflintErr
+	}
+	return nil
+}
+
+// Return the config file tflint should use: the .tflint.hcl of the working dir, e.g. from the Terraform module, or
+// else the closest one in the folder of the unit or its parents, e.g. at the root of the repo. Returns an empty
+// string if there is none, in which case tflint uses its defaults.
+func findTflintConfig(terragruntOptions *options.TerragruntOptions) string {
+	if configPath := filepath.Join(terragruntOptions.WorkingDir, TFLINT_CONFIG_FILE); util.FileExists(configPath) {
+		return configPath
+	}
+
+	dir := filepath.Dir(terragruntOptions.TerragruntConfigPath)
+	for {
+		if configPath := filepath.Join(dir, TFLINT_CONFIG_FILE); util.FileExists(configPath) {
+			return configPath
+		}
+		parentDir := filepath.Dir(dir)
+		if parentDir == dir {
+			return ""
+		}
+		dir = parentDir
+	}
+}
+
+// Write the inputs of the unit that are variables of its module to a temp var file, like the debug file, and return
+// its path
+func writeTflintVarFile(terragruntOptions *options.TerragruntOptions, terragruntConfig *config.TerragruntConfig) (string, error) {
+	required, optional, err := terraformModuleVariables(terragruntOptions)
+	if err != nil {
+		return "", err
+	}
+	contents, err := terragruntDebugFileContents(terragruntOptions, terragruntConfig, append(required, optional...))
+	if err != nil {
+		return "", err
+	}
+
+	varFile, err := ioutil.TempFile("", "terragrunt-tflint-*.tfvars.json")
+	if err != nil {
+		return "", errors.WithStackTrace(err)
+	}
+	if _, err := varFile.Write(contents); err != nil {
+		varFile.Close()
+		os.Remove(varFile.Name())
+		return "", errors.WithStackTrace(err)
+	}
+	if err := varFile.Close(); err != nil {
+		os.Remove(varFile.Name())
+		return "", errors.WithStackTrace(err)
+	}
+	return varFile.Name(), nil
+}
+
+// Custom error types
+
+type TflintIssuesFound struct {
+	Unit   string
+	Issues int
+}
+
+func (err TflintIssuesFound) Error() string {
+	return fmt.Sprintf("tflint found %d issues in %s", err.Issues, err.Unit)
+}
+
+type TflintFailed struct {
+	Unit   string
+	Errors []string
+}
+
+func (err TflintFailed) Error() string {
+	return fmt.Sprintf("tflint failed to lint %s:\n  - %s", err.Unit, strings.Join(err.Errors, "\n  - "))
+}
@@ -0,0 +1,64 @@
+package cli
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/gruntwork-io/terragrunt/config"
+	"github.com/gruntwork-io/terragrunt/options"
+	"github.com/gruntwork-io/terragrunt/util"
+)
+
+func TestFindTflintConfig(t *testing.T) {
+	t.Parallel()
+
+	rootDir, err := ioutil.TempDir("", "tflint-config")
+	require.NoError(t, err)
+	defer os.RemoveAll(rootDir)
+
+	unitDir := filepath.Join(rootDir, "live", "app")
+	workingDir := filepath.Join(unitDir, ".terragrunt-cache", "abc", "module")
+	require.NoError(t, os.MkdirAll(workingDir, os.ModePerm))
+
+	terragruntOptions, err := options.NewTerragruntOptionsForTest(util.JoinPath(unitDir, config.DefaultTerragruntConfigPath))
+	require.NoError(t, err)
+	terragruntOptions.WorkingDir = workingDir
+
+	assert.Equal(t, "", findTflintConfig(terragruntOptions))
+
+	rootConfig := filepath.Join(rootDir, TFLINT_CONFIG_FILE)
+	require.NoError(t, ioutil.WriteFile(rootConfig, []byte{}, 0644))
+	assert.Equal(t, rootConfig, findTflintConfig(terragruntOptions))
+
+	moduleConfig := filepath.Join(workingDir, TFLINT_CONFIG_FILE)
+	require.NoError(t, ioutil.WriteFile(moduleConfig, []byte{}, 0644))
+	assert.Equal(t, moduleConfig, findTflintConfig(terragruntOptions))
+}
+
+func TestWriteTflintVarFile(t *testing.T) {
+	t.Parallel()
+
+	workingDir, err := ioutil.TempDir("", "tflint-var-file")
+	require.NoError(t, err)
+	defer os.RemoveAll(workingDir)
+	require.NoError(t, ioutil.WriteFile(filepath.Join(workingDir, "variables.tf"), []byte(`variable "name" {}`), 0644))
+
+	terragruntOptions, err := options.NewTerragruntOptionsForTest(util.JoinPath(workingDir, config.DefaultTerragruntConfigPath))
+	require.NoError(t, err)
+	terragruntOptions.WorkingDir = workingDir
+
+	varFile, err := writeTflintVarFile(terragruntOptions, &config.TerragruntConfig{
+		Inputs: map[string]interface{}{"name": "app", "unused": true},
+	})
+	require.NoError(t, err)
+	defer os.Remove(varFile)
+
+	contents, err := ioutil.ReadFile(varFile)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"name": "app"}`, string(contents))
+}
@@ -1,9 +1,14 @@
 package tfsource
 
 import (
+	"crypto/sha1"
+	"encoding/base64"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"net/url"
+	"os"
+	"path/filepath"
 	"regexp"
 	"strings"
 
@@ -40,19 +45,86 @@ func (src *TerraformSource) String() string {
 // string of the source URL, calculate its sha1, and base 64 encode it. For remote URLs (e.g. Git URLs), this is
 // based on the assumption that the scheme/host/path of the URL (e.g. git::github.com/foo/bar) identifies the module
 // name and the query string (e.g. ?ref=v0.0.3) identifies the version. For local file paths, there is no query string,
-// so the same file path (/foo/bar) is always considered the same version. See also the encodeSourceName and
-// ProcessTerraformSource methods.
-func (terraformSource TerraformSource) EncodeSourceVersion() string {
-	return util.EncodeBase64Sha1(terraformSource.CanonicalSourceURL.Query().Encode())
+// so the version is calculated from the contents of the folder instead, which means the code is only copied again when
+// it has changed. See also the encodeSourceName and ProcessTerraformSource methods.
+func (terraformSource TerraformSource) EncodeSourceVersion() (string, error) {
+	if IsLocalSource(terraformSource.CanonicalSourceURL) {
+		return encodeFolderContents(terraformSource.CanonicalSourceURL.Path)
+	}
+	return util.EncodeBase64Sha1(terraformSource.CanonicalSourceURL.Query().Encode()), nil
 }
 
 // Write a file into the DownloadDir that contains the version number of this source code. The version number is
 // calculated using the EncodeSourceVersion method.
 func (terraformSource TerraformSource) WriteVersionFile() error {
-	version := terraformSource.EncodeSourceVersion()
+	version, err := terraformSource.EncodeSourceVersion()
+	if err != nil {
+		return err
+	}
 	return errors.WithStackTrace(ioutil.WriteFile(terraformSource.VersionFile, []byte(version), 0640))
 }
 
+// Return the base 64 encoded sha1 hash of the paths and contents of all the files in the given folder that Terragrunt
+// copies into the download dir, so that any change to the files changes the hash. Like the copy, this follows symlinks
+// and skips hidden files and folders (see util.TerragruntExcludes).
+func encodeFolderContents(folder string) (string, error) {
+	hash := sha1.New()
+	if err := hashFolderContents(hash, folder, folder); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(hash.Sum(nil)), nil
+}
+
+func hashFolderContents(hash io.Writer, rootFolder string, folder string) error {
+	// Use filepath.Glob for the same reason as util.CopyFolderContentsWithFilter: it follows symlinks. It also returns
+	// the files in sorted order, which keeps the hash stable.
+	files, err := filepath.Glob(fmt.Sprintf("%s/*", folder))
+	if err != nil {
+		return errors.WithStackTrace(err)
+	}
+
+	for _, file := range files {
+		fileRelativePath, err := util.GetPathRelativeTo(file, rootFolder)
+		if err != nil {
+			return err
+		}
+		if util.TerragruntExcludes(fileRelativePath) {
+			continue
+		}
+
+		if util.IsDir(file) {
+			if err := hashFolderContents(hash, rootFolder, file); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if err := hashFile(hash, fileRelativePath, file); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func hashFile(hash io.Writer, relativePath string, path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return errors.WithStackTrace(err)
+	}
+	defer file.Close()
+
+	fileInfo, err := file.Stat()
+	if err != nil {
+		return errors.WithStackTrace(err)
+	}
+
+	// Include the path, mode and size, so that renaming a file, making it executable, or moving content from one file
+	// to the next all change the hash
+	fmt.Fprintf(hash, "%s\x00%o\x00%d\x00", relativePath, fileInfo.Mode().Perm(), fileInfo.Size())
+	_, err = io.Copy(hash, file)
+	return errors.WithStackTrace(err)
+}
+
 // Take the given source path and create a TerraformSource struct from it, including the folder where the source should
 // be downloaded to. Our goal is to reuse the download folder for the same source URL between Terragrunt runs.
 // Otherwise, for every Terragrunt command, you'd have to wait for Terragrunt to download your Terraform code, download
@@ -77,7 +149,8 @@ func (terraformSource TerraformSource) WriteVersionFile() error {
 // The downloadTerraformSourceIfNecessary decides when we should download the Terraform code and when not to. It uses
 // the following rules:
 //
-// 1. Always download source URLs pointing to local file paths.
+// 1. Only copy source URLs pointing to local file paths if /T/W/H doesn't already exist or, if it does exist, if the
+//    hash of the contents of the local folder in /T/W/H/.terragrunt-source-version doesn't match the current hash.
 // 2. Only download source URLs pointing to remote paths if /T/W/H doesn't already exist or, if it does exist, if the
 //    version number in /T/W/H/.terragrunt-source-version doesn't match the current version.
 func NewTerraformSource(source string, downloadDir string, workingDir string, logger *logrus.Entry) (*TerraformSource, error) {
@@ -36,6 +36,10 @@ func TestSplitSourceUrl(t *testing.T) {
 		{"parent-url-multiple-children-no-double-slash", "ssh://git@github.com/foo/modules.git/foo/bar/baz/blah", "ssh://git@github.com/foo/modules.git/foo/bar/baz/blah", ""},
 		{"parent-url-one-child-with-double-slash", "ssh://git@github.com/foo/modules.git//foo", "ssh://git@github.com/foo/modules.git", "foo"},
 		{"parent-url-multiple-children-with-double-slash", "ssh://git@github.com/foo/modules.git//foo/bar/baz/blah", "ssh://git@github.com/foo/modules.git", "foo/bar/baz/blah"},
+		{"oci-url-no-double-slash", "oci://registry.example.com/modules/vpc:1.2.0", "oci://registry.example.com/modules/vpc:1.2.0", ""},
+		{"oci-url-with-double-slash", "oci://registry.example.com/modules/network:1.2.0//vpc", "oci://registry.example.com/modules/network:1.2.0", "vpc"},
+		{"tfr-url-default-registry", "tfr:///terraform-aws-modules/vpc/aws?version=3.3.0", "tfr:///terraform-aws-modules/vpc/aws?version=3.3.0", ""},
+		{"tfr-url-with-double-slash", "tfr://artifactory.example.com/acme/network/aws//modules/vpc?version=1.0.0", "tfr://artifactory.example.com/acme/network/aws?version=1.0.0", "modules/vpc"},
 	}
 
 	for _, testCase := range testCases {
@@ -45,6 +45,8 @@ func validateTerragruntInputs(terragruntOptions *options.TerragruntOptions, work
 		}
 	}
 
+	addInputsToSarif(terragruntOptions, unusedVars, missingVars)
+
 	// Now print out all the information
 	if len(unusedVars) > 0 {
 		terragruntOptions.Logger.Warn("The following inputs passed in by terragrunt are unused:\n")
@@ -0,0 +1,126 @@
+package cli
+
+import (
+	"time"
+
+	"github.com/gruntwork-io/terragrunt/config"
+	"github.com/gruntwork-io/terragrunt/options"
+	"github.com/gruntwork-io/terragrunt/vault"
+)
+
+// The env var the Google provider reads an OAuth2 access token from
+const googleAccessTokenEnvVar = "GOOGLE_OAUTH_ACCESS_TOKEN"
+
+// Request the short-lived credentials of the vault_credentials blocks of the given config from Vault, and pass them to
+// the terraform processes of the unit of the given options via the env vars of their cloud. The leases of the
+// credentials are renewed while the unit runs, so that a long apply isn't cut short. Returns a function that stops
+// renewing the leases and revokes them, which should be called once the unit finishes.
+func startVaultCredentials(terragruntOptions *options.TerragruntOptions, terragruntConfig *config.TerragruntConfig) (func(), error) {
+	stops := []func(){}
+	stopAll := func() {
+		for _, stop := range stops {
+			stop()
+		}
+	}
+
+	for _, engine := range []string{config.VaultEngineAws, config.VaultEngineGcp} {
+		credentials, hasCredentials := terragruntConfig.VaultCredentials[engine]
+		if !hasCredentials {
+			continue
+		}
+
+		address := ""
+		if credentials.Address != nil {
+			address = *credentials.Address
+		}
+		client, err := vault.NewClient(address, terragruntOptions.Env)
+		if err != nil {
+			stopAll()
+			return nil, err
+		}
+		mount := engine
+		if credentials.Mount != nil {
+			mount = *credentials.Mount
+		}
+
+		var stop func()
+		if engine == config.VaultEngineAws {
+			stop, err = injectVaultAwsCredentials(terragruntOptions, client, mount, credentials)
+		} else {
+			err = injectVaultGcpToken(terragruntOptions, client, mount, credentials)
+		}
+		if err != nil {
+			stopAll()
+			return nil, err
+		}
+		if stop != nil {
+			stops = append(stops, stop)
+		}
+	}
+	return stopAll, nil
+}
+
+// Request credentials of the given role of the AWS secrets engine, and set the AWS env vars of the unit to them. Returns
+// a function that stops renewing their lease and revokes it.
+func injectVaultAwsCredentials(terragruntOptions *options.TerragruntOptions, client *vault.Client, mount string, credentials config.VaultCredentialsConfig) (func(), error) {
+	roleArn := ""
+	if credentials.RoleArn != nil {
+		roleArn = *credentials.RoleArn
+	}
+	ttl := ""
+	if credentials.Ttl != nil {
+		ttl = *credentials.Ttl
+	}
+
+	awsCredentials, err := client.AwsCredentials(mount, credentials.Role, roleArn, ttl)
+	if err != nil {
+		return nil, err
+	}
+	terragruntOptions.Logger.Debugf("Requested the AWS credentials of %s/creds/%s from Vault, with a lease of %s", mount, credentials.Role, awsCredentials.Lease.Duration)
+
+	terragruntOptions.Env["AWS_ACCESS_KEY_ID"] = awsCredentials.AccessKeyId
+	terragruntOptions.Env["AWS_SECRET_ACCESS_KEY"] = awsCredentials.SecretAccessKey
+	if awsCredentials.SessionToken != "" {
+		terragruntOptions.Env["AWS_SESSION_TOKEN"] = awsCredentials.SessionToken
+		terragruntOptions.Env["AWS_SECURITY_TOKEN"] = awsCredentials.SessionToken
+	} else {
+		// The session token of other credentials would make AWS reject the keys of an IAM user
+		delete(terragruntOptions.Env, "AWS_SESSION_TOKEN")
+		delete(terragruntOptions.Env, "AWS_SECURITY_TOKEN")
+	}
+
+	stopRenewing := client.KeepRenewed(awsCredentials.Lease, awsCredentials.Lease.Duration, func(lease vault.Lease, err error) {
+		if err != nil {
+			terragruntOptions.Logger.Warnf("Could not renew the lease of the AWS credentials from Vault: %v", err)
+			return
+		}
+		terragruntOptions.Logger.Debugf("Renewed the lease of the AWS credentials from Vault for %s", lease.Duration)
+	})
+	return func() {
+		stopRenewing()
+		if awsCredentials.Lease.ID == "" {
+			return
+		}
+		if err := client.RevokeLease(awsCredentials.Lease); err != nil {
+			terragruntOptions.Logger.Warnf("Could not revoke the lease of the AWS credentials from Vault: %v", err)
+		}
+	}, nil
+}
+
+// Request an OAuth2 access token of the given account of the GCP secrets engine, and set the env var of the Google
+// provider to it. Access tokens can't be renewed, and expire after an hour at most.
+func injectVaultGcpToken(terragruntOptions *options.TerragruntOptions, client *vault.Client, mount string, credentials config.VaultCredentialsConfig) error {
+	accountType := vault.GcpRoleset
+	if credentials.AccountType != nil {
+		accountType = *credentials.AccountType
+	}
+
+	token, err := client.GcpAccessToken(mount, accountType, credentials.Role)
+	if err != nil {
+		return err
+	}
+	terragruntOptions.Logger.Debugf("Requested a GCP access token of %s/%s/%s from Vault, which expires in %s", mount, accountType, credentials.Role, time.Until(token.ExpiresAt).Round(time.Second))
+
+	terragruntOptions.Env[googleAccessTokenEnvVar] = token.Token
+	return nil
+}
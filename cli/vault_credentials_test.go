@@ -0,0 +1,120 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/gruntwork-io/terragrunt/config"
+	"github.com/gruntwork-io/terragrunt/options"
+)
+
+func TestStartVaultCredentials(t *testing.T) {
+	t.Parallel()
+
+	revoked := []string{}
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		switch request.URL.Path {
+		case "/v1/aws/creds/deploy":
+			json.NewEncoder(writer).Encode(map[string]interface{}{
+				"lease_id":       "aws/creds/deploy/abc",
+				"lease_duration": 3600,
+				"renewable":      true,
+				"data":           map[string]interface{}{"access_key": "AKIA123", "secret_key": "secret", "security_token": nil},
+			})
+		case "/v1/gcp-prod/roleset/terraform/token":
+			json.NewEncoder(writer).Encode(map[string]interface{}{"data": map[string]interface{}{"token": "ya29.token", "expires_at_seconds": 1700000000}})
+		case "/v1/sys/leases/revoke":
+			var body map[string]string
+			require.NoError(t, json.NewDecoder(request.Body).Decode(&body))
+			revoked = append(revoked, body["lease_id"])
+			writer.WriteHeader(http.StatusNoContent)
+		default:
+			writer.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	terragruntOptions, err := options.NewTerragruntOptionsForTest("terragrunt.hcl")
+	require.NoError(t, err)
+	terragruntOptions.Env = map[string]string{"VAULT_ADDR": server.URL, "VAULT_TOKEN": "s.test", "AWS_SESSION_TOKEN": "stale"}
+	gcpMount := "gcp-prod"
+	terragruntConfig := &config.TerragruntConfig{VaultCredentials: map[string]config.VaultCredentialsConfig{
+		config.VaultEngineAws: {Engine: config.VaultEngineAws, Role: "deploy"},
+		config.VaultEngineGcp: {Engine: config.VaultEngineGcp, Mount: &gcpMount, Role: "terraform"},
+	}}
+
+	stopVaultCredentials, err := startVaultCredentials(terragruntOptions, terragruntConfig)
+	require.NoError(t, err)
+	assert.Equal(t, "AKIA123", terragruntOptions.Env["AWS_ACCESS_KEY_ID"])
+	assert.Equal(t, "secret", terragruntOptions.Env["AWS_SECRET_ACCESS_KEY"])
+	_, hasSessionToken := terragruntOptions.Env["AWS_SESSION_TOKEN"]
+	assert.False(t, hasSessionToken)
+	assert.Equal(t, "ya29.token", terragruntOptions.Env[googleAccessTokenEnvVar])
+
+	stopVaultCredentials()
+	assert.Equal(t, []string{"aws/creds/deploy/abc"}, revoked)
+}
+
+// A fake STS endpoint, where assuming a role returns the credentials ASIA-<name of the role>. The access key each role
+// is assumed with is sent on the returned channel.
+func newFakeAssumeRoleServer(t *testing.T) (*httptest.Server, chan string) {
+	accessKeyRegexp := regexp.MustCompile(`Credential=([^/]+)/`)
+	assumedWith := make(chan string, 10)
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		require.NoError(t, request.ParseForm())
+		if request.Form.Get("Action") != "AssumeRole" {
+			writer.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		match := accessKeyRegexp.FindStringSubmatch(request.Header.Get("Authorization"))
+		require.NotNil(t, match, "Unsigned request")
+		assumedWith <- match[1]
+		fmt.Fprintf(writer, `<AssumeRoleResponse><AssumeRoleResult><Credentials><AccessKeyId>ASIA-%s</AccessKeyId><SecretAccessKey>secret</SecretAccessKey><SessionToken>token</SessionToken><Expiration>%s</Expiration></Credentials></AssumeRoleResult></AssumeRoleResponse>`, path.Base(request.Form.Get("RoleArn")), time.Now().Add(time.Hour).UTC().Format(time.RFC3339))
+	}))
+	return server, assumedWith
+}
+
+func TestStartVaultCredentialsAndAssumeRole(t *testing.T) {
+	t.Parallel()
+
+	vaultServer := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		if request.URL.Path != "/v1/aws/creds/deploy" {
+			writer.WriteHeader(http.StatusNoContent)
+			return
+		}
+		json.NewEncoder(writer).Encode(map[string]interface{}{
+			"lease_id":       "aws/creds/deploy/vault-and-role",
+			"lease_duration": 3600,
+			"data":           map[string]interface{}{"access_key": "AKIA-vault-and-role", "secret_key": "secret", "security_token": nil},
+		})
+	}))
+	defer vaultServer.Close()
+	stsServer, assumedWith := newFakeAssumeRoleServer(t)
+	defer stsServer.Close()
+
+	terragruntOptions, err := options.NewTerragruntOptionsForTest("terragrunt.hcl")
+	require.NoError(t, err)
+	terragruntOptions.Env = map[string]string{"VAULT_ADDR": vaultServer.URL, "VAULT_TOKEN": "s.test", "AWS_ACCESS_KEY_ID": "AKIA-env", "AWS_SECRET_ACCESS_KEY": "secret", "AWS_REGION": "us-east-1"}
+	terragruntOptions.AwsEndpoints = map[string]string{"sts": stsServer.URL}
+	terragruntOptions.IamRole = "arn:aws:iam::222222222222:role/vault-and-role"
+	terragruntConfig := &config.TerragruntConfig{VaultCredentials: map[string]config.VaultCredentialsConfig{
+		config.VaultEngineAws: {Engine: config.VaultEngineAws, Role: "deploy"},
+	}}
+
+	// The IAM role is assumed with the credentials of Vault, and its credentials are the ones terraform gets
+	stopVaultCredentials, err := startVaultCredentialsAndAssumeRole(terragruntOptions, terragruntConfig)
+	require.NoError(t, err)
+	defer stopVaultCredentials()
+	assert.Equal(t, "AKIA-vault-and-role", <-assumedWith)
+	assert.Equal(t, "ASIA-vault-and-role", terragruntOptions.Env["AWS_ACCESS_KEY_ID"])
+	assert.Equal(t, "AKIA-vault-and-role", terragruntOptions.IamRoleSourceEnv["AWS_ACCESS_KEY_ID"])
+}
@@ -0,0 +1,148 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"regexp"
+	"runtime"
+	"strings"
+
+	"github.com/urfave/cli"
+
+	"github.com/gruntwork-io/terragrunt/errors"
+)
+
+// The key in the app metadata under which the commit terragrunt was built from is stored
+const METADATA_COMMIT = "commit"
+
+const OPT_VERSION = "version"
+const OPT_FORMAT = "format"
+
+const VERSION_FORMAT_TEXT = "text"
+const VERSION_FORMAT_JSON = "json"
+
+// Matches the first lines of the output of terraform -version, e.g. "Terraform v0.14.7" followed by
+// "on linux_amd64". OpenTofu prints "OpenTofu" instead of "Terraform".
+var terraformVersionOutputRegex = regexp.MustCompile(`(?m)^(Terraform|OpenTofu) v?(\S+)(?:\s+on (\S+))?`)
+
+// VersionInfo is the information printed by terragrunt --version
+type VersionInfo struct {
+	TerragruntVersion string                `json:"terragrunt_version"`
+	Commit            string                `json:"commit,omitempty"`
+	Platform          string                `json:"platform"`
+	GoVersion         string                `json:"go_version"`
+	Terraform         *TerraformVersionInfo `json:"terraform"`
+}
+
+// TerraformVersionInfo is the information about the terraform binary printed by terragrunt --version. If the binary
+// could not be run, Error is set instead of the version.
+type TerraformVersionInfo struct {
+	Path     string `json:"path"`
+	Binary   string `json:"binary,omitempty"`
+	Version  string `json:"version,omitempty"`
+	Platform string `json:"platform,omitempty"`
+	Error    string `json:"error,omitempty"`
+}
+
+// Print the versions of terragrunt and of the terraform binary it runs, in the format requested via --format
+func runVersionCommand(cliContext *cli.Context) error {
+	format := cliContext.String(OPT_FORMAT)
+	if format == "" {
+		format = VERSION_FORMAT_TEXT
+	}
+	if format != VERSION_FORMAT_TEXT && format != VERSION_FORMAT_JSON {
+		return errors.WithStackTrace(UnsupportedVersionFormat(format))
+	}
+
+	commit, _ := cliContext.App.Metadata[METADATA_COMMIT].(string)
+	terraformPath := os.Getenv("TERRAGRUNT_TFPATH")
+	if terraformPath == "" {
+		terraformPath = detectTerraformPath()
+	}
+
+	versionInfo := VersionInfo{
+		TerragruntVersion: cliContext.App.Version,
+		Commit:            commit,
+		Platform:          fmt.Sprintf("%s_%s", runtime.GOOS, runtime.GOARCH),
+		GoVersion:         runtime.Version(),
+		Terraform:         getTerraformVersionInfo(terraformPath),
+	}
+
+	return printVersionInfo(cliContext.App.Writer, versionInfo, format)
+}
+
+func printVersionInfo(writer io.Writer, versionInfo VersionInfo, format string) error {
+	if format == VERSION_FORMAT_JSON {
+		versionJson, err := json.MarshalIndent(versionInfo, "", "  ")
+		if err != nil {
+			return errors.WithStackTrace(err)
+		}
+		_, err = fmt.Fprintf(writer, "%s\n", versionJson)
+		return errors.WithStackTrace(err)
+	}
+
+	// The first line is the same as what terragrunt --version has always printed, so that existing scripts parsing it
+	// keep working
+	lines := []string{fmt.Sprintf("terragrunt version %s", versionInfo.TerragruntVersion)}
+	if versionInfo.Commit != "" {
+		lines = append(lines, fmt.Sprintf("Commit: %s", versionInfo.Commit))
+	}
+	lines = append(lines, fmt.Sprintf("Platform: %s", versionInfo.Platform), fmt.Sprintf("Go: %s", versionInfo.GoVersion))
+
+	terraform := versionInfo.Terraform
+	if terraform.Error != "" {
+		lines = append(lines, fmt.Sprintf("Terraform: unknown (%s: %s)", terraform.Path, terraform.Error))
+	} else {
+		lines = append(lines, fmt.Sprintf("%s: v%s on %s (%s)", terraform.Binary, terraform.Version, terraform.Platform, terraform.Path))
+	}
+
+	_, err := fmt.Fprintln(writer, strings.Join(lines, "\n"))
+	return errors.WithStackTrace(err)
+}
+
+// Run the terraform binary at the given path to find out its version and platform. This works for both Terraform and
+// OpenTofu.
+func getTerraformVersionInfo(terraformPath string) *TerraformVersionInfo {
+	versionInfo := &TerraformVersionInfo{Path: terraformPath}
+
+	cmd := exec.Command(terraformPath, "-version")
+	// Make sure no extra args from the env break the -version call. See PopulateTerraformVersion.
+	for _, envVar := range os.Environ() {
+		if !strings.HasPrefix(envVar, "TF_CLI_ARGS") {
+			cmd.Env = append(cmd.Env, envVar)
+		}
+	}
+	output, err := cmd.Output()
+	if err != nil {
+		versionInfo.Error = err.Error()
+		return versionInfo
+	}
+
+	return parseTerraformVersionOutput(terraformPath, string(output))
+}
+
+func parseTerraformVersionOutput(terraformPath string, output string) *TerraformVersionInfo {
+	versionInfo := &TerraformVersionInfo{Path: terraformPath}
+
+	matches := terraformVersionOutputRegex.FindStringSubmatch(output)
+	if len(matches) == 0 {
+		versionInfo.Error = InvalidTerraformVersionSyntax(output).Error()
+		return versionInfo
+	}
+
+	versionInfo.Binary = matches[1]
+	versionInfo.Version = matches[2]
+	versionInfo.Platform = matches[3]
+	return versionInfo
+}
+
+// Custom error types
+
+type UnsupportedVersionFormat string
+
+func (format UnsupportedVersionFormat) Error() string {
+	return fmt.Sprintf("Unsupported --%s '%s'. Supported formats are: %s, %s.", OPT_FORMAT, string(format), VERSION_FORMAT_TEXT, VERSION_FORMAT_JSON)
+}
@@ -3,6 +3,7 @@ package cli
 import (
 	"fmt"
 	"io/ioutil"
+	"os/exec"
 	"regexp"
 	"strings"
 
@@ -16,8 +17,18 @@ import (
 // - Terraform v0.9.5-dev (cad024a5fe131a546936674ef85445215bbc4226+CHANGES)
 // - Terraform v0.13.0-beta2
 // - Terraform v0.12.27
+// - OpenTofu v1.6.0
 // We only make sure the "v#.#.#" part is present in the output.
-var TerraformVersionRegex = regexp.MustCompile(`Terraform (v?\d+\.\d+\.\d+).*`)
+var TerraformVersionRegex = regexp.MustCompile(`(Terraform|OpenTofu) (v?\d+\.\d+\.\d+).*`)
+
+// Return the terraform binary to run when none was set explicitly: tofu, if OpenTofu is installed, and terraform
+// otherwise
+func detectTerraformPath() string {
+	if _, err := exec.LookPath(options.TOFU_DEFAULT_PATH); err == nil {
+		return options.TOFU_DEFAULT_PATH
+	}
+	return options.TERRAFORM_DEFAULT_PATH
+}
 
 // Populate the currently installed version of Terraform into the given terragruntOptions
 func PopulateTerraformVersion(terragruntOptions *options.TerragruntOptions) error {
@@ -47,14 +58,20 @@ func PopulateTerraformVersion(terragruntOptions *options.TerragruntOptions) erro
 	}
 
 	terragruntOptions.TerraformVersion = terraformVersion
-	terragruntOptions.Logger.Debugf("Terraform version: %s", terraformVersion)
+	terragruntOptions.TerraformImplementation = parseTerraformImplementation(output.Stdout)
+	terragruntOptions.Logger.Debugf("%s version: %s", terraformImplementationName(terragruntOptions.TerraformImplementation), terraformVersion)
 	return nil
 }
 
 // Check that the currently installed Terraform version works meets the specified version constraint and return an error
 // if it doesn't
 func CheckTerraformVersion(constraint string, terragruntOptions *options.TerragruntOptions) error {
-	return checkTerraformVersionMeetsConstraint(terragruntOptions.TerraformVersion, constraint)
+	err := checkTerraformVersionMeetsConstraint(terragruntOptions.TerraformVersion, constraint)
+	if invalidVersion, isInvalidVersion := errors.Unwrap(err).(InvalidTerraformVersion); isInvalidVersion {
+		invalidVersion.Implementation = terragruntOptions.TerraformImplementation
+		return errors.WithStackTrace(invalidVersion)
+	}
+	return err
 }
 
 // Check that the currently running Terragrunt version meets the specified version constraint and return an error
@@ -95,11 +112,31 @@ func checkTerraformVersionMeetsConstraint(currentVersion *version.Version, const
 func parseTerraformVersion(versionCommandOutput string) (*version.Version, error) {
 	matches := TerraformVersionRegex.FindStringSubmatch(versionCommandOutput)
 
-	if len(matches) != 2 {
+	if len(matches) != 3 {
 		return nil, errors.WithStackTrace(InvalidTerraformVersionSyntax(versionCommandOutput))
 	}
 
-	return version.NewVersion(matches[1])
+	return version.NewVersion(matches[2])
+}
+
+// Parse whether the output of the terraform --version command comes from Terraform or OpenTofu
+func parseTerraformImplementation(versionCommandOutput string) options.TerraformImplementationType {
+	matches := TerraformVersionRegex.FindStringSubmatch(versionCommandOutput)
+	if len(matches) != 3 {
+		return options.UnknownImpl
+	}
+	if matches[1] == "OpenTofu" {
+		return options.OpenTofuImpl
+	}
+	return options.TerraformImpl
+}
+
+// Return the name of the given implementation of terraform for use in messages to the user
+func terraformImplementationName(implementation options.TerraformImplementationType) string {
+	if implementation == options.OpenTofuImpl {
+		return "OpenTofu"
+	}
+	return "Terraform"
 }
 
 // Custom error types
@@ -113,6 +150,7 @@ func (err InvalidTerraformVersionSyntax) Error() string {
 type InvalidTerraformVersion struct {
 	CurrentVersion     *version.Version
 	VersionConstraints version.Constraints
+	Implementation     options.TerraformImplementationType
 }
 
 type InvalidTerragruntVersion struct {
@@ -121,7 +159,7 @@ type InvalidTerragruntVersion struct {
 }
 
 func (err InvalidTerraformVersion) Error() string {
-	return fmt.Sprintf("The currently installed version of Terraform (%s) is not compatible with the version Terragrunt requires (%s).", err.CurrentVersion.String(), err.VersionConstraints.String())
+	return fmt.Sprintf("The currently installed version of %s (%s) is not compatible with the version Terragrunt requires (%s).", terraformImplementationName(err.Implementation), err.CurrentVersion.String(), err.VersionConstraints.String())
 }
 
 func (err InvalidTerragruntVersion) Error() string {
@@ -4,6 +4,7 @@ import (
 	"testing"
 
 	"github.com/gruntwork-io/terragrunt/errors"
+	"github.com/gruntwork-io/terragrunt/options"
 	"github.com/hashicorp/go-version"
 	"github.com/stretchr/testify/assert"
 )
@@ -69,6 +70,39 @@ func TestParseTerraformVersionWithUnexpectedName(t *testing.T) {
 	testParseTerraformVersion(t, "Terraform v0.15.0-rc1", "v0.15.0", nil)
 }
 
+func TestParseTerraformVersionOpenTofu(t *testing.T) {
+	t.Parallel()
+	testParseTerraformVersion(t, "OpenTofu v1.6.0\non linux_amd64", "v1.6.0", nil)
+}
+
+func TestParseTerraformVersionOpenTofuWithBeta(t *testing.T) {
+	t.Parallel()
+	testParseTerraformVersion(t, "OpenTofu v1.7.0-beta1", "v1.7.0", nil)
+}
+
+func TestParseTerraformImplementation(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, options.TerraformImpl, parseTerraformImplementation("Terraform v0.14.7\non linux_amd64"))
+	assert.Equal(t, options.OpenTofuImpl, parseTerraformImplementation("OpenTofu v1.6.0\non linux_amd64"))
+	assert.Equal(t, options.UnknownImpl, parseTerraformImplementation("invalid-syntax"))
+}
+
+func TestCheckTerraformVersionNamesOpenTofu(t *testing.T) {
+	t.Parallel()
+
+	terragruntOptions, err := options.NewTerragruntOptionsForTest("mock-path-for-test.hcl")
+	assert.NoError(t, err)
+	terragruntOptions.TerraformVersion = version.Must(version.NewVersion("v1.6.0"))
+	terragruntOptions.TerraformImplementation = options.OpenTofuImpl
+
+	assert.NoError(t, CheckTerraformVersion(">= v0.12.0", terragruntOptions))
+
+	err = CheckTerraformVersion(">= v1.7.0", terragruntOptions)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "version of OpenTofu (1.6.0)")
+}
+
 func TestParseTerraformVersionInvalidSyntax(t *testing.T) {
 	t.Parallel()
 	testParseTerraformVersion(t, "invalid-syntax", "", InvalidTerraformVersionSyntax("invalid-syntax"))
@@ -0,0 +1,62 @@
+package cli
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseTerraformVersionOutput(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		output   string
+		expected TerraformVersionInfo
+	}{
+		{
+			"Terraform v0.14.7\non linux_amd64\n",
+			TerraformVersionInfo{Path: "terraform", Binary: "Terraform", Version: "0.14.7", Platform: "linux_amd64"},
+		},
+		{
+			"OpenTofu v1.6.0\non darwin_arm64\n+ provider registry.opentofu.org/hashicorp/aws v5.31.0\n",
+			TerraformVersionInfo{Path: "terraform", Binary: "OpenTofu", Version: "1.6.0", Platform: "darwin_arm64"},
+		},
+		{
+			"Terraform v0.11.14\n",
+			TerraformVersionInfo{Path: "terraform", Binary: "Terraform", Version: "0.11.14"},
+		},
+	}
+
+	for _, testCase := range testCases {
+		actual := parseTerraformVersionOutput("terraform", testCase.output)
+		assert.Equal(t, testCase.expected, *actual)
+	}
+
+	actual := parseTerraformVersionOutput("terraform", "not a version")
+	assert.NotEmpty(t, actual.Error)
+}
+
+func TestPrintVersionInfo(t *testing.T) {
+	t.Parallel()
+
+	versionInfo := VersionInfo{
+		TerragruntVersion: "v0.28.0",
+		Commit:            "abc123",
+		Platform:          "linux_amd64",
+		GoVersion:         "go1.15.8",
+		Terraform:         &TerraformVersionInfo{Path: "terraform", Binary: "Terraform", Version: "0.14.7", Platform: "linux_amd64"},
+	}
+
+	var text bytes.Buffer
+	require.NoError(t, printVersionInfo(&text, versionInfo, VERSION_FORMAT_TEXT))
+	assert.Equal(t, "terragrunt version v0.28.0\nCommit: abc123\nPlatform: linux_amd64\nGo: go1.15.8\nTerraform: v0.14.7 on linux_amd64 (terraform)\n", text.String())
+
+	var jsonOutput bytes.Buffer
+	require.NoError(t, printVersionInfo(&jsonOutput, versionInfo, VERSION_FORMAT_JSON))
+	var parsed VersionInfo
+	require.NoError(t, json.Unmarshal(jsonOutput.Bytes(), &parsed))
+	assert.Equal(t, versionInfo, parsed)
+}
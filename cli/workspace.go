@@ -0,0 +1,123 @@
+package cli
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+
+	"github.com/gruntwork-io/terragrunt/config"
+	"github.com/gruntwork-io/terragrunt/errors"
+	"github.com/gruntwork-io/terragrunt/options"
+	"github.com/gruntwork-io/terragrunt/shell"
+	"github.com/gruntwork-io/terragrunt/util"
+	"github.com/hashicorp/go-version"
+)
+
+// The workspace terraform uses when no other workspace has been selected
+const defaultTerraformWorkspace = "default"
+
+// The file in the terraform data dir in which terraform records the selected workspace
+const terraformWorkspaceFile = "environment"
+
+// The env var terraform reads the workspace to use from, overriding the selected workspace
+const terraformWorkspaceEnvVar = "TF_WORKSPACE"
+
+// terraform workspace select supports -or-create, which creates the workspace if it doesn't exist, from terraform 1.4
+var workspaceSelectOrCreateMinVersion = version.Must(version.NewVersion("1.4.0"))
+
+// Return true if the workspace set in the config needs to be selected before running the given terraform command. This
+// is the case for the commands that use the state, other than init, which sets up the backend the workspaces are
+// stored in, and the deprecated env command, which manages the workspaces itself.
+func shouldSelectWorkspace(terragruntOptions *options.TerragruntOptions, terragruntConfig *config.TerragruntConfig) bool {
+	command := util.FirstArg(terragruntOptions.TerraformCliArgs)
+	return terragruntConfig.Workspace != "" &&
+		util.ListContainsElement(TERRAFORM_COMMANDS_THAT_USE_STATE, command) &&
+		command != CMD_INIT &&
+		command != "env"
+}
+
+// Select the terraform workspace set in the workspace attribute of the config, creating it if it doesn't exist yet, so
+// that the command runs against the state of that workspace.
+func selectTerraformWorkspace(terragruntOptions *options.TerragruntOptions, terragruntConfig *config.TerragruntConfig) error {
+	workspace := terragruntConfig.Workspace
+
+	// terraform refuses to select a workspace while TF_WORKSPACE is set, and the command would run in the workspace of
+	// TF_WORKSPACE rather than the one in the config
+	if envWorkspace, hasEnvWorkspace := terragruntOptions.Env[terraformWorkspaceEnvVar]; hasEnvWorkspace && envWorkspace != "" {
+		if envWorkspace != workspace {
+			return errors.WithStackTrace(WorkspaceConflictsWithEnvVar{ConfigWorkspace: workspace, EnvWorkspace: envWorkspace})
+		}
+		return nil
+	}
+
+	currentWorkspace, err := currentTerraformWorkspace(terragruntOptions)
+	if err != nil {
+		return err
+	}
+	if currentWorkspace == workspace {
+		terragruntOptions.Logger.Debugf("Terraform workspace %s is already selected", workspace)
+		return nil
+	}
+
+	terragruntOptions.Logger.Infof("Selecting terraform workspace %s (was %s)", workspace, currentWorkspace)
+
+	// Don't pollute stdout with the output of the workspace commands, as with Auto-Init
+	workspaceOptions := terragruntOptions.Clone(terragruntOptions.TerragruntConfigPath)
+	workspaceOptions.Writer = workspaceOptions.ErrWriter
+
+	if terragruntOptions.TerraformVersion != nil && terragruntOptions.TerraformVersion.GreaterThanOrEqual(workspaceSelectOrCreateMinVersion) {
+		return shell.RunTerraformCommand(workspaceOptions, "workspace", "select", "-or-create", workspace)
+	}
+
+	// Older versions of terraform can only select existing workspaces, so fall back to creating the workspace, which
+	// also selects it
+	workspaceOptions.ErrWriter = ioutil.Discard
+	if err := shell.RunTerraformCommand(workspaceOptions, "workspace", "select", workspace); err == nil {
+		return nil
+	}
+	terragruntOptions.Logger.Infof("Terraform workspace %s does not exist. Creating it.", workspace)
+	workspaceOptions.ErrWriter = terragruntOptions.ErrWriter
+	return shell.RunTerraformCommand(workspaceOptions, "workspace", "new", workspace)
+}
+
+// Return the terraform workspace that is currently selected in the working dir, which terraform records in the
+// environment file of its data dir
+func currentTerraformWorkspace(terragruntOptions *options.TerragruntOptions) (string, error) {
+	workspaceFile := filepath.Join(terragruntOptions.DataDir(), terraformWorkspaceFile)
+	if !util.FileExists(workspaceFile) {
+		return defaultTerraformWorkspace, nil
+	}
+
+	contents, err := util.ReadFileAsString(workspaceFile)
+	if err != nil {
+		return "", err
+	}
+	if workspace := strings.TrimSpace(contents); workspace != "" {
+		return workspace, nil
+	}
+	return defaultTerraformWorkspace, nil
+}
+
+// Return the terraform workspace whose state the command runs against: the one of the workspace attribute of the config,
+// if any, or else the one of TF_WORKSPACE, or else the one currently selected in the working dir
+func stateTerraformWorkspace(terragruntOptions *options.TerragruntOptions, terragruntConfig *config.TerragruntConfig) (string, error) {
+	if terragruntConfig.Workspace != "" {
+		return terragruntConfig.Workspace, nil
+	}
+	if envWorkspace := terragruntOptions.Env[terraformWorkspaceEnvVar]; envWorkspace != "" {
+		return envWorkspace, nil
+	}
+	return currentTerraformWorkspace(terragruntOptions)
+}
+
+// Custom error types
+
+type WorkspaceConflictsWithEnvVar struct {
+	ConfigWorkspace string
+	EnvWorkspace    string
+}
+
+func (err WorkspaceConflictsWithEnvVar) Error() string {
+	return fmt.Sprintf("The workspace attribute selects the terraform workspace %s, but the %s env var selects %s. Unset %s or change the workspace attribute.", err.ConfigWorkspace, terraformWorkspaceEnvVar, err.EnvWorkspace, terraformWorkspaceEnvVar)
+}
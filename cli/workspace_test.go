@@ -0,0 +1,108 @@
+package cli
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/gruntwork-io/terragrunt/config"
+	"github.com/gruntwork-io/terragrunt/errors"
+	"github.com/gruntwork-io/terragrunt/options"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestShouldSelectWorkspace(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		args      []string
+		workspace string
+		expected  bool
+	}{
+		{[]string{"plan"}, "prod", true},
+		{[]string{"apply", "-auto-approve"}, "prod", true},
+		{[]string{"state", "list"}, "prod", true},
+		{[]string{"plan"}, "", false},
+		{[]string{"init"}, "prod", false},
+		{[]string{"env", "list"}, "prod", false},
+		{[]string{"workspace", "list"}, "prod", false},
+		{[]string{"version"}, "prod", false},
+	}
+
+	for _, testCase := range testCases {
+		terragruntOptions, err := options.NewTerragruntOptionsForTest("workspace_test")
+		require.NoError(t, err)
+		terragruntOptions.TerraformCliArgs = testCase.args
+
+		actual := shouldSelectWorkspace(terragruntOptions, &config.TerragruntConfig{Workspace: testCase.workspace})
+		assert.Equal(t, testCase.expected, actual, "For args %v and workspace %q", testCase.args, testCase.workspace)
+	}
+}
+
+func TestCurrentTerraformWorkspace(t *testing.T) {
+	t.Parallel()
+
+	workingDir, err := ioutil.TempDir("", "workspace-test")
+	require.NoError(t, err)
+	defer os.RemoveAll(workingDir)
+
+	terragruntOptions, err := options.NewTerragruntOptionsForTest(filepath.Join(workingDir, config.DefaultTerragruntConfigPath))
+	require.NoError(t, err)
+	terragruntOptions.WorkingDir = workingDir
+
+	workspace, err := currentTerraformWorkspace(terragruntOptions)
+	require.NoError(t, err)
+	assert.Equal(t, "default", workspace)
+
+	require.NoError(t, os.MkdirAll(terragruntOptions.DataDir(), 0755))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(terragruntOptions.DataDir(), "environment"), []byte("prod"), 0644))
+
+	workspace, err = currentTerraformWorkspace(terragruntOptions)
+	require.NoError(t, err)
+	assert.Equal(t, "prod", workspace)
+}
+
+func TestStateTerraformWorkspace(t *testing.T) {
+	t.Parallel()
+
+	workingDir, err := ioutil.TempDir("", "workspace-test")
+	require.NoError(t, err)
+	defer os.RemoveAll(workingDir)
+
+	terragruntOptions, err := options.NewTerragruntOptionsForTest(filepath.Join(workingDir, config.DefaultTerragruntConfigPath))
+	require.NoError(t, err)
+	terragruntOptions.WorkingDir = workingDir
+	require.NoError(t, os.MkdirAll(terragruntOptions.DataDir(), 0755))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(terragruntOptions.DataDir(), "environment"), []byte("prod"), 0644))
+
+	workspace, err := stateTerraformWorkspace(terragruntOptions, &config.TerragruntConfig{})
+	require.NoError(t, err)
+	assert.Equal(t, "prod", workspace)
+
+	terragruntOptions.Env["TF_WORKSPACE"] = "stage"
+	workspace, err = stateTerraformWorkspace(terragruntOptions, &config.TerragruntConfig{})
+	require.NoError(t, err)
+	assert.Equal(t, "stage", workspace)
+
+	workspace, err = stateTerraformWorkspace(terragruntOptions, &config.TerragruntConfig{Workspace: "blue"})
+	require.NoError(t, err)
+	assert.Equal(t, "blue", workspace)
+}
+
+func TestSelectTerraformWorkspaceConflictsWithEnvVar(t *testing.T) {
+	t.Parallel()
+
+	terragruntOptions, err := options.NewTerragruntOptionsForTest("workspace_test")
+	require.NoError(t, err)
+	terragruntOptions.Env["TF_WORKSPACE"] = "stage"
+
+	err = selectTerraformWorkspace(terragruntOptions, &config.TerragruntConfig{Workspace: "prod"})
+	require.Error(t, err)
+	assert.Equal(t, WorkspaceConflictsWithEnvVar{ConfigWorkspace: "prod", EnvWorkspace: "stage"}, errors.Unwrap(err))
+
+	// When TF_WORKSPACE already selects the workspace of the config, there is nothing to do
+	terragruntOptions.Env["TF_WORKSPACE"] = "prod"
+	assert.NoError(t, selectTerraformWorkspace(terragruntOptions, &config.TerragruntConfig{Workspace: "prod"}))
+}
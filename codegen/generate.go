@@ -10,7 +10,9 @@ import (
 	"sort"
 	"strings"
 
+	"github.com/hashicorp/hcl/v2"
 	"github.com/hashicorp/hcl/v2/hclwrite"
+	"github.com/zclconf/go-cty/cty"
 	ctyjson "github.com/zclconf/go-cty/cty/json"
 
 	"github.com/gruntwork-io/terragrunt/errors"
@@ -77,6 +79,13 @@ func WriteToFile(terragruntOptions *options.TerragruntOptions, basePath string,
 		}
 	}
 
+	// With --terragrunt-symlink-local-source, the files and folders of the module in the working dir are symlinks to the
+	// local source, so writing through them would change the user's code. A linked file is replaced with a regular one,
+	// and a file in a linked folder is refused.
+	if err := replaceSymlink(basePath, targetPath); err != nil {
+		return err
+	}
+
 	// Add the signature as a prefix to the file, unless it is disabled.
 	prefix := ""
 	if !config.DisableSignature {
@@ -91,6 +100,26 @@ func WriteToFile(terragruntOptions *options.TerragruntOptions, basePath string,
 	return nil
 }
 
+// Remove the target path if it's a symlink, so that a regular file is written in its place rather than through it, and
+// return an error if one of the folders between the base path and the target path is a symlink.
+func replaceSymlink(basePath string, targetPath string) error {
+	relPath, err := filepath.Rel(basePath, filepath.Dir(targetPath))
+	if err == nil && relPath != ".." && !strings.HasPrefix(relPath, ".."+string(filepath.Separator)) {
+		for dir := filepath.Dir(targetPath); relPath != "."; dir, relPath = filepath.Dir(dir), filepath.Dir(relPath) {
+			if info, err := os.Lstat(dir); err == nil && info.Mode()&os.ModeSymlink != 0 {
+				return errors.WithStackTrace(GenerateThroughSymlinkError{path: targetPath, symlink: dir})
+			}
+		}
+	}
+
+	if info, err := os.Lstat(targetPath); err == nil && info.Mode()&os.ModeSymlink != 0 {
+		if err := os.Remove(targetPath); err != nil {
+			return errors.WithStackTrace(err)
+		}
+	}
+	return nil
+}
+
 // Whether or not file generation should continue if the file path already exists. The answer depends on the
 // ifExists configuration.
 func shouldContinueWithFileExists(terragruntOptions *options.TerragruntOptions, path string, ifExists GenerateConfigExists) (bool, error) {
@@ -144,33 +173,148 @@ func fileWasGeneratedByTerragrunt(path string) (bool, error) {
 	return strings.HasSuffix(strings.TrimSpace(firstLine), TerragruntGeneratedSignature), nil
 }
 
-// Convert the arbitrary map that represents a remote state config into HCL code to configure that remote state.
-func RemoteStateConfigToTerraformCode(backend string, config map[string]interface{}) ([]byte, error) {
+// The OpenTofu state encryption settings to render in the terraform block next to the backend. The keys of the given
+// key provider are used by a single encryption method, which encrypts both the state and the plan files.
+type StateEncryption struct {
+	// The type of the key provider, e.g. pbkdf2 or aws_kms
+	KeyProvider string
+	// The config of the key provider, e.g. the passphrase for pbkdf2 or the kms_key_id for aws_kms
+	KeyProviderConfig map[string]interface{}
+	// The encryption method, e.g. aes_gcm
+	Method string
+	// When true, OpenTofu refuses to read or write unencrypted state and plan files
+	Enforced bool
+}
+
+// The backend type of the cloud block of Terraform Cloud
+const TerraformCloudBackend = "cloud"
+
+// The settings of the backends that terraform expects as nested blocks rather than as attributes
+var backendNestedBlocks = map[string][]string{
+	"remote":              {"workspaces"},
+	TerraformCloudBackend: {"workspaces"},
+}
+
+// The name of the key provider and method blocks rendered for the state encryption
+const stateEncryptionBlockName = "default"
+
+// Convert the arbitrary map that represents a remote state config into HCL code to configure that remote state. If
+// encryption is not nil, an OpenTofu encryption block is added to the terraform block as well.
+func RemoteStateConfigToTerraformCode(backend string, config map[string]interface{}, encryption *StateEncryption) ([]byte, error) {
 	f := hclwrite.NewEmptyFile()
-	backendBlock := f.Body().AppendNewBlock("terraform", nil).Body().AppendNewBlock("backend", []string{backend})
-	backendBlockBody := backendBlock.Body()
-	var backendKeys []string
+	terraformBlockBody := f.Body().AppendNewBlock("terraform", nil).Body()
+
+	// The cloud block of Terraform Cloud is configured with its own block, rather than with a backend block
+	var backendBlock *hclwrite.Block
+	if backend == TerraformCloudBackend {
+		backendBlock = terraformBlockBody.AppendNewBlock(TerraformCloudBackend, nil)
+	} else {
+		backendBlock = terraformBlockBody.AppendNewBlock("backend", []string{backend})
+	}
+
+	// Render the settings that terraform expects as nested blocks, such as the workspaces of Terraform Cloud, as blocks
+	// after the attributes
+	attributes := map[string]interface{}{}
+	nestedBlocks := map[string]map[string]interface{}{}
+	for key, value := range config {
+		if blockValue, isMap := value.(map[string]interface{}); isMap && util.ListContainsElement(backendNestedBlocks[backend], key) {
+			nestedBlocks[key] = blockValue
+		} else {
+			attributes[key] = value
+		}
+	}
+
+	if err := setAttributeValues(backendBlock.Body(), attributes); err != nil {
+		return nil, err
+	}
+	for _, key := range backendNestedBlocks[backend] {
+		blockValue, hasBlock := nestedBlocks[key]
+		if !hasBlock {
+			continue
+		}
+		if err := setAttributeValues(backendBlock.Body().AppendNewBlock(key, nil).Body(), blockValue); err != nil {
+			return nil, err
+		}
+	}
 
-	for key := range config {
-		backendKeys = append(backendKeys, key)
+	if encryption != nil {
+		if err := appendStateEncryptionBlock(terraformBlockBody, encryption); err != nil {
+			return nil, err
+		}
+	}
+
+	return f.Bytes(), nil
+}
+
+// appendStateEncryptionBlock renders the given state encryption settings as an OpenTofu encryption block:
+//
+//	encryption {
+//	  key_provider "<key provider>" "default" { ... }
+//	  method "<method>" "default" {
+//	    keys = key_provider.<key provider>.default
+//	  }
+//	  state {
+//	    method = method.<method>.default
+//	  }
+//	  plan {
+//	    method = method.<method>.default
+//	  }
+//	}
+func appendStateEncryptionBlock(body *hclwrite.Body, encryption *StateEncryption) error {
+	encryptionBlockBody := body.AppendNewBlock("encryption", nil).Body()
+
+	keyProviderBlock := encryptionBlockBody.AppendNewBlock("key_provider", []string{encryption.KeyProvider, stateEncryptionBlockName})
+	if err := setAttributeValues(keyProviderBlock.Body(), encryption.KeyProviderConfig); err != nil {
+		return err
+	}
+
+	methodBlock := encryptionBlockBody.AppendNewBlock("method", []string{encryption.Method, stateEncryptionBlockName})
+	methodBlock.Body().SetAttributeTraversal("keys", hcl.Traversal{
+		hcl.TraverseRoot{Name: "key_provider"},
+		hcl.TraverseAttr{Name: encryption.KeyProvider},
+		hcl.TraverseAttr{Name: stateEncryptionBlockName},
+	})
+
+	for _, target := range []string{"state", "plan"} {
+		targetBlockBody := encryptionBlockBody.AppendNewBlock(target, nil).Body()
+		targetBlockBody.SetAttributeTraversal("method", hcl.Traversal{
+			hcl.TraverseRoot{Name: "method"},
+			hcl.TraverseAttr{Name: encryption.Method},
+			hcl.TraverseAttr{Name: stateEncryptionBlockName},
+		})
+		if encryption.Enforced {
+			targetBlockBody.SetAttributeValue("enforced", cty.True)
+		}
 	}
-	sort.Strings(backendKeys)
-	for _, key := range backendKeys {
+
+	return nil
+}
+
+// setAttributeValues sets the values of the given map as attributes of the given body, sorted by key so that the
+// generated code is stable.
+func setAttributeValues(body *hclwrite.Body, values map[string]interface{}) error {
+	var keys []string
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
 		// Since we don't have the cty type information for the config and since config can be arbitrary, we cheat by using
 		// json as an intermediate representation.
-		jsonBytes, err := json.Marshal(config[key])
+		jsonBytes, err := json.Marshal(values[key])
 		if err != nil {
-			return nil, errors.WithStackTrace(err)
+			return errors.WithStackTrace(err)
 		}
 		var ctyVal ctyjson.SimpleJSONValue
 		if err := ctyVal.UnmarshalJSON(jsonBytes); err != nil {
-			return nil, errors.WithStackTrace(err)
+			return errors.WithStackTrace(err)
 		}
 
-		backendBlockBody.SetAttributeValue(key, ctyVal.Value)
+		body.SetAttributeValue(key, ctyVal.Value)
 	}
 
-	return f.Bytes(), nil
+	return nil
 }
 
 // GenerateConfigExistsFromString converst a string representation of if_exists into the enum, returning an error if it
@@ -209,3 +353,12 @@ type GenerateFileExistsError struct {
 func (err GenerateFileExistsError) Error() string {
 	return fmt.Sprintf("Can not generate terraform file: %s already exists", err.path)
 }
+
+type GenerateThroughSymlinkError struct {
+	path    string
+	symlink string
+}
+
+func (err GenerateThroughSymlinkError) Error() string {
+	return fmt.Sprintf("Can not generate terraform file: %s is in %s, which is a symlink, so the file would be written into the folder it points to", err.path, err.symlink)
+}
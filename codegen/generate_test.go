@@ -2,8 +2,15 @@ package codegen
 
 import (
 	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
 	"testing"
 
+	"github.com/gruntwork-io/terragrunt/errors"
+	"github.com/gruntwork-io/terragrunt/options"
+	"github.com/gruntwork-io/terragrunt/util"
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
 
@@ -48,7 +55,7 @@ func TestRemoteStateConfigToTerraformCode(t *testing.T) {
 
 	for _, testCase := range testCases {
 		t.Run(testCase.name, func(t *testing.T) {
-			output, err := RemoteStateConfigToTerraformCode(testCase.backend, testCase.config)
+			output, err := RemoteStateConfigToTerraformCode(testCase.backend, testCase.config, nil)
 			// validates the first output.
 			require.True(t, bytes.Contains(output, []byte(testCase.backend)))
 			require.Equal(t, testCase.expected, output)
@@ -57,9 +64,144 @@ func TestRemoteStateConfigToTerraformCode(t *testing.T) {
 			// runs the function a few of times again. All the outputs must be
 			// equal to the first output.
 			for i := 0; i < 20; i++ {
-				actual, _ := RemoteStateConfigToTerraformCode(testCase.backend, testCase.config)
+				actual, _ := RemoteStateConfigToTerraformCode(testCase.backend, testCase.config, nil)
 				require.Equal(t, output, actual)
 			}
 		})
 	}
 }
+
+func TestRemoteStateConfigToTerraformCodeWithEncryption(t *testing.T) {
+	t.Parallel()
+
+	expected := []byte(`terraform {
+  backend "s3" {
+    bucket = "my-bucket"
+  }
+  encryption {
+    key_provider "pbkdf2" "default" {
+      key_length = 32
+      passphrase = "correct-horse-battery-staple"
+    }
+    method "aes_gcm" "default" {
+      keys = key_provider.pbkdf2.default
+    }
+    state {
+      method   = method.aes_gcm.default
+      enforced = true
+    }
+    plan {
+      method   = method.aes_gcm.default
+      enforced = true
+    }
+  }
+}
+`)
+
+	encryption := &StateEncryption{
+		KeyProvider:       "pbkdf2",
+		KeyProviderConfig: map[string]interface{}{"passphrase": "correct-horse-battery-staple", "key_length": 32},
+		Method:            "aes_gcm",
+		Enforced:          true,
+	}
+
+	output, err := RemoteStateConfigToTerraformCode("s3", map[string]interface{}{"bucket": "my-bucket"}, encryption)
+	require.NoError(t, err)
+	require.Equal(t, string(expected), string(output))
+}
+
+func TestRemoteStateConfigToTerraformCodeTerraformCloud(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name     string
+		backend  string
+		config   map[string]interface{}
+		expected string
+	}{
+		{
+			"cloud",
+			"cloud",
+			map[string]interface{}{
+				"organization": "my-org",
+				"workspaces":   map[string]interface{}{"name": "prod-vpc", "project": "networking"},
+			},
+			`terraform {
+  cloud {
+    organization = "my-org"
+    workspaces {
+      name    = "prod-vpc"
+      project = "networking"
+    }
+  }
+}
+`,
+		},
+		{
+			"remote",
+			"remote",
+			map[string]interface{}{
+				"hostname":     "tfe.example.com",
+				"organization": "my-org",
+				"workspaces":   map[string]interface{}{"prefix": "vpc-"},
+			},
+			`terraform {
+  backend "remote" {
+    hostname     = "tfe.example.com"
+    organization = "my-org"
+    workspaces {
+      prefix = "vpc-"
+    }
+  }
+}
+`,
+		},
+	}
+
+	for _, testCase := range testCases {
+		// Save the testCase in local scope so all the t.Run calls don't end up with the last item in the list
+		testCase := testCase
+
+		t.Run(testCase.name, func(t *testing.T) {
+			t.Parallel()
+
+			output, err := RemoteStateConfigToTerraformCode(testCase.backend, testCase.config, nil)
+			require.NoError(t, err)
+			require.Equal(t, testCase.expected, string(output))
+		})
+	}
+}
+
+func TestWriteToFileReplacesSymlink(t *testing.T) {
+	t.Parallel()
+
+	sourceDir, err := ioutil.TempDir("", "codegen-source")
+	require.NoError(t, err)
+	defer os.RemoveAll(sourceDir)
+	workingDir, err := ioutil.TempDir("", "codegen-working-dir")
+	require.NoError(t, err)
+	defer os.RemoveAll(workingDir)
+
+	sourceFile := filepath.Join(sourceDir, "provider.tf")
+	require.NoError(t, ioutil.WriteFile(sourceFile, []byte("# module code\n"), 0644))
+	require.NoError(t, os.Symlink(sourceFile, filepath.Join(workingDir, "provider.tf")))
+	require.NoError(t, os.Symlink(sourceDir, filepath.Join(workingDir, "modules")))
+
+	terragruntOptions, err := options.NewTerragruntOptionsForTest("codegen_test")
+	require.NoError(t, err)
+
+	config := GenerateConfig{Path: "provider.tf", IfExists: ExistsOverwrite, CommentPrefix: DefaultCommentPrefix, Contents: "# generated\n"}
+	require.NoError(t, WriteToFile(terragruntOptions, workingDir, config))
+
+	info, err := os.Lstat(filepath.Join(workingDir, "provider.tf"))
+	require.NoError(t, err)
+	assert.Zero(t, info.Mode()&os.ModeSymlink)
+	sourceContents, err := ioutil.ReadFile(sourceFile)
+	require.NoError(t, err)
+	assert.Equal(t, "# module code\n", string(sourceContents))
+
+	config.Path = filepath.Join("modules", "backend.tf")
+	err = WriteToFile(terragruntOptions, workingDir, config)
+	assert.IsType(t, GenerateThroughSymlinkError{}, errors.Unwrap(err))
+	assert.False(t, util.FileExists(filepath.Join(sourceDir, "backend.tf")))
+}
@@ -0,0 +1,122 @@
+package config
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/gruntwork-io/terragrunt/errors"
+	"github.com/gruntwork-io/terragrunt/options"
+)
+
+// AzureAuthConfig represents how terragrunt and terraform authenticate to Azure: as the service principal, or user
+// assigned managed identity, of the given client ID, in the given tenant and subscription, with a managed identity if
+// use_msi is set, or with a token of the CI job via the federated credentials of the client if use_oidc is set.
+// Terragrunt uses it to bootstrap azurerm backends, and passes it to terraform as the ARM_* env vars the azurerm
+// provider and backend read.
+type AzureAuthConfig struct {
+	ClientId       *string `hcl:"client_id,attr" cty:"client_id"`
+	TenantId       *string `hcl:"tenant_id,attr" cty:"tenant_id"`
+	SubscriptionId *string `hcl:"subscription_id,attr" cty:"subscription_id"`
+	UseMsi         *bool   `hcl:"use_msi,attr" cty:"use_msi"`
+	UseOidc        *bool   `hcl:"use_oidc,attr" cty:"use_oidc"`
+	// The path of a file containing the token to exchange for credentials of the client, e.g. the one AKS workload
+	// identity mounts in pods. Defaults to the ARM_OIDC_TOKEN or ARM_OIDC_REQUEST_URL env vars, which the azure
+	// oidc_credentials block sets.
+	OidcTokenFilePath *string `hcl:"oidc_token_file_path,attr" cty:"oidc_token_file_path"`
+}
+
+// Validate returns an error if the block sets both use_msi and use_oidc, or the settings of OIDC without use_oidc
+func (auth *AzureAuthConfig) Validate() error {
+	if auth == nil {
+		return nil
+	}
+	useMsi := auth.UseMsi != nil && *auth.UseMsi
+	useOidc := auth.UseOidc != nil && *auth.UseOidc
+	if useMsi && useOidc {
+		return errors.WithStackTrace(InvalidAzureAuthConfig("use_msi and use_oidc can't both be set"))
+	}
+	if auth.OidcTokenFilePath != nil && !useOidc {
+		return errors.WithStackTrace(InvalidAzureAuthConfig("oidc_token_file_path requires use_oidc"))
+	}
+	return nil
+}
+
+// Env returns the ARM_* env vars of the attributes set in the block
+func (auth *AzureAuthConfig) Env() map[string]string {
+	env := map[string]string{}
+	if auth == nil {
+		return env
+	}
+	if auth.ClientId != nil {
+		env["ARM_CLIENT_ID"] = *auth.ClientId
+	}
+	if auth.TenantId != nil {
+		env["ARM_TENANT_ID"] = *auth.TenantId
+	}
+	if auth.SubscriptionId != nil {
+		env["ARM_SUBSCRIPTION_ID"] = *auth.SubscriptionId
+	}
+	if auth.UseMsi != nil {
+		env["ARM_USE_MSI"] = strconv.FormatBool(*auth.UseMsi)
+	}
+	if auth.UseOidc != nil {
+		env["ARM_USE_OIDC"] = strconv.FormatBool(*auth.UseOidc)
+	}
+	if auth.OidcTokenFilePath != nil {
+		env["ARM_OIDC_TOKEN_FILE_PATH"] = *auth.OidcTokenFilePath
+	}
+	return env
+}
+
+// UpdateEnvWithAzureAuth passes the given azure_auth block, if any, to the azurerm backend bootstrap and to terraform
+// via the ARM_* env vars. The env vars set in the environment take precedence over the block, the same way the
+// TERRAGRUNT_* ones take precedence over the other attributes.
+func UpdateEnvWithAzureAuth(terragruntOptions *options.TerragruntOptions, auth *AzureAuthConfig) {
+	for key, value := range auth.Env() {
+		if _, isSet := terragruntOptions.Env[key]; isSet {
+			terragruntOptions.Logger.Debugf("Not setting %s from the azure_auth block, as it's already set in the environment", key)
+			continue
+		}
+		terragruntOptions.Env[key] = value
+	}
+}
+
+// Merge the attributes set in the given child azure_auth block into the given parent one, returning a new block, so
+// that e.g. the tenant can be set once in the included config, and the subscription in each child
+func mergeAzureAuth(parent *AzureAuthConfig, child *AzureAuthConfig) *AzureAuthConfig {
+	if parent == nil {
+		return child
+	}
+	if child == nil {
+		return parent
+	}
+
+	merged := *parent
+	if child.ClientId != nil {
+		merged.ClientId = child.ClientId
+	}
+	if child.TenantId != nil {
+		merged.TenantId = child.TenantId
+	}
+	if child.SubscriptionId != nil {
+		merged.SubscriptionId = child.SubscriptionId
+	}
+	if child.UseMsi != nil {
+		merged.UseMsi = child.UseMsi
+	}
+	if child.UseOidc != nil {
+		merged.UseOidc = child.UseOidc
+	}
+	if child.OidcTokenFilePath != nil {
+		merged.OidcTokenFilePath = child.OidcTokenFilePath
+	}
+	return &merged
+}
+
+// Custom error types
+
+type InvalidAzureAuthConfig string
+
+func (reason InvalidAzureAuthConfig) Error() string {
+	return fmt.Sprintf("Invalid azure_auth block: %s", string(reason))
+}
@@ -2,11 +2,14 @@ package config
 
 import (
 	"fmt"
+	"io/ioutil"
 	"net/url"
 	"os"
 	"path/filepath"
 	"reflect"
+	"regexp"
 	"strings"
+	"sync"
 
 	"github.com/hashicorp/go-getter"
 	"github.com/hashicorp/hcl/v2"
@@ -17,9 +20,11 @@ import (
 
 	"github.com/gruntwork-io/terragrunt/codegen"
 	"github.com/gruntwork-io/terragrunt/errors"
+	"github.com/gruntwork-io/terragrunt/notify"
 	"github.com/gruntwork-io/terragrunt/options"
 	"github.com/gruntwork-io/terragrunt/remote"
 	"github.com/gruntwork-io/terragrunt/util"
+	"github.com/gruntwork-io/terragrunt/vault"
 )
 
 const DefaultTerragruntConfigPath = "terragrunt.hcl"
@@ -28,24 +33,39 @@ const DefaultTerragruntJsonConfigPath = "terragrunt.hcl.json"
 // TerragruntConfig represents a parsed and expanded configuration
 // NOTE: if any attributes are added, make sure to update terragruntConfigAsCty in config_as_cty.go
 type TerragruntConfig struct {
-	Terraform                   *TerraformConfig
-	TerraformBinary             string
-	TerraformVersionConstraint  string
-	TerragruntVersionConstraint string
-	RemoteState                 *remote.RemoteState
-	Dependencies                *ModuleDependencies
-	DownloadDir                 string
-	PreventDestroy              *bool
-	Skip                        bool
-	IamRole                     string
-	IamAssumeRoleDuration       *int64
-	Inputs                      map[string]interface{}
-	Locals                      map[string]interface{}
-	TerragruntDependencies      []Dependency
-	GenerateConfigs             map[string]codegen.GenerateConfig
-	RetryableErrors             []string
-	RetryMaxAttempts            *int
-	RetrySleepIntervalSec       *int
+	Terraform                       *TerraformConfig
+	TerraformBinary                 string
+	TerraformVersionConstraint      string
+	TerragruntVersionConstraint     string
+	RemoteState                     *remote.RemoteState
+	Dependencies                    *ModuleDependencies
+	DownloadDir                     string
+	PreventDestroy                  *bool
+	Skip                            bool
+	IamRole                         string
+	IamRoleChain                    []options.IamRoleHop
+	IamCommandRoles                 map[string]IamCommandRole
+	IamAssumeRoleDuration           *int64
+	IamAssumeRoleExternalId         string
+	IamWebIdentityToken             string
+	GoogleImpersonateServiceAccount string
+	AwsProfile                      string
+	AzureAuth                       *AzureAuthConfig
+	Inputs                          map[string]interface{}
+	Locals                          map[string]interface{}
+	TerragruntDependencies          []Dependency
+	GenerateConfigs                 map[string]codegen.GenerateConfig
+	RetryableErrors                 []string
+	RetryMaxAttempts                *int
+	RetrySleepIntervalSec           *int
+	Workspace                       string
+	Parallelism                     *ParallelismConfig
+	Notifications                   map[string]NotificationConfig
+	Catalog                         *CatalogConfig
+	RegistryCredentials             map[string]RegistryCredentialsConfig
+	RegistryCredentialsHelper       *RegistryCredentialsHelperConfig
+	VaultCredentials                map[string]VaultCredentialsConfig
+	OidcCredentials                 map[string]OidcCredentialsConfig
 
 	// Indicates whether or not this is the result of a partial evaluation
 	IsPartial bool
@@ -81,13 +101,17 @@ type terragruntConfigFile struct {
 	RemoteState     *remoteStateConfigFile `hcl:"remote_state,block"`
 	RemoteStateAttr *cty.Value             `hcl:"remote_state,optional"`
 
-	Dependencies           *ModuleDependencies `hcl:"dependencies,block"`
-	DownloadDir            *string             `hcl:"download_dir,attr"`
-	PreventDestroy         *bool               `hcl:"prevent_destroy,attr"`
-	Skip                   *bool               `hcl:"skip,attr"`
-	IamRole                *string             `hcl:"iam_role,attr"`
-	IamAssumeRoleDuration  *int64              `hcl:"iam_assume_role_duration,attr"`
-	TerragruntDependencies []Dependency        `hcl:"dependency,block"`
+	Dependencies                    *ModuleDependencies `hcl:"dependencies,block"`
+	DownloadDir                     *string             `hcl:"download_dir,attr"`
+	PreventDestroy                  *bool               `hcl:"prevent_destroy,attr"`
+	Skip                            *bool               `hcl:"skip,attr"`
+	IamRole                         *cty.Value          `hcl:"iam_role,attr"`
+	IamAssumeRoleDuration           *int64              `hcl:"iam_assume_role_duration,attr"`
+	IamAssumeRoleExternalId         *string             `hcl:"iam_assume_role_external_id,attr"`
+	IamWebIdentityToken             *string             `hcl:"iam_web_identity_token,attr"`
+	GoogleImpersonateServiceAccount *string             `hcl:"google_impersonate_service_account,attr"`
+	AwsProfile                      *string             `hcl:"aws_profile,attr"`
+	TerragruntDependencies          []Dependency        `hcl:"dependency,block"`
 
 	// We allow users to configure code generation via blocks:
 	//
@@ -111,6 +135,25 @@ type terragruntConfigFile struct {
 	RetryMaxAttempts      *int     `hcl:"retry_max_attempts,optional"`
 	RetrySleepIntervalSec *int     `hcl:"retry_sleep_interval_sec,optional"`
 
+	Workspace *string `hcl:"workspace,attr"`
+
+	Parallelism *ParallelismConfig `hcl:"parallelism,block"`
+
+	Notifications []NotificationConfig `hcl:"notification,block"`
+
+	Catalog *CatalogConfig `hcl:"catalog,block"`
+
+	RegistryCredentials       []RegistryCredentialsConfig      `hcl:"registry_credentials,block"`
+	RegistryCredentialsHelper *RegistryCredentialsHelperConfig `hcl:"registry_credentials_helper,block"`
+
+	VaultCredentials []VaultCredentialsConfig `hcl:"vault_credentials,block"`
+
+	OidcCredentials []OidcCredentialsConfig `hcl:"oidc_credentials,block"`
+
+	AzureAuth *AzureAuthConfig `hcl:"azure_auth,block"`
+
+	IamCommandRoles []iamCommandRoleBlock `hcl:"iam_command_role,block"`
+
 	// This struct is used for validating and parsing the entire terragrunt config. Since locals are evaluated in a
 	// completely separate cycle, it should not be evaluated here. Otherwise, we can't support self referencing other
 	// elements in the same block.
@@ -130,6 +173,7 @@ type remoteStateConfigFile struct {
 	DisableDependencyOptimization *bool                      `hcl:"disable_dependency_optimization,attr"`
 	Generate                      *remoteStateConfigGenerate `hcl:"generate,attr"`
 	Config                        cty.Value                  `hcl:"config,attr"`
+	Encryption                    *cty.Value                 `hcl:"encryption,attr"`
 }
 
 func (remoteState *remoteStateConfigFile) String() string {
@@ -160,6 +204,13 @@ func (remoteState *remoteStateConfigFile) toConfig() (*remote.RemoteState, error
 	if remoteState.DisableDependencyOptimization != nil {
 		config.DisableDependencyOptimization = *remoteState.DisableDependencyOptimization
 	}
+	if remoteState.Encryption != nil {
+		encryption, err := parseCtyValueToMap(*remoteState.Encryption)
+		if err != nil {
+			return nil, err
+		}
+		config.Encryption = encryption
+	}
 
 	config.FillDefaults()
 	if err := config.Validate(); err != nil {
@@ -218,6 +269,332 @@ func (deps *ModuleDependencies) String() string {
 	return fmt.Sprintf("ModuleDependencies{Paths = %v}", deps.Paths)
 }
 
+// ParallelismConfig represents how the module is scheduled when *-all commands run many modules concurrently
+type ParallelismConfig struct {
+	// The number of the --terragrunt-parallelism slots the module takes up while it runs. Defaults to 1.
+	Weight *int `hcl:"weight,attr" cty:"weight"`
+	// If true, no other module runs while the module runs
+	Exclusive *bool `hcl:"exclusive,attr" cty:"exclusive"`
+	// The name of the group of modules the module belongs to, which share the group limit
+	Group *string `hcl:"group,attr" cty:"group"`
+	// The maximum number of modules of the group that run concurrently
+	GroupLimit *int `hcl:"group_limit,attr" cty:"group_limit"`
+}
+
+// Validate returns an error if the weight or group limit are out of range, or if a group limit is set without a group.
+func (parallelism *ParallelismConfig) Validate() error {
+	if parallelism == nil {
+		return nil
+	}
+	if parallelism.Weight != nil && *parallelism.Weight < 1 {
+		return errors.WithStackTrace(InvalidParallelismConfig(fmt.Sprintf("weight must be at least 1, but got %d", *parallelism.Weight)))
+	}
+	if parallelism.GroupLimit != nil {
+		if parallelism.Group == nil || *parallelism.Group == "" {
+			return errors.WithStackTrace(InvalidParallelismConfig("group_limit can only be set along with group"))
+		}
+		if *parallelism.GroupLimit < 1 {
+			return errors.WithStackTrace(InvalidParallelismConfig(fmt.Sprintf("group_limit must be at least 1, but got %d", *parallelism.GroupLimit)))
+		}
+	}
+	return nil
+}
+
+func (parallelism *ParallelismConfig) String() string {
+	return fmt.Sprintf("ParallelismConfig{Weight = %v, Exclusive = %v, Group = %v, GroupLimit = %v}", parallelism.Weight, parallelism.Exclusive, parallelism.Group, parallelism.GroupLimit)
+}
+
+// NotificationConfig represents a webhook, e.g. of Slack or Microsoft Teams, that is posted when a run starts, when a
+// unit fails and when the run completes
+type NotificationConfig struct {
+	Name string `hcl:"name,label" cty:"name"`
+	Url  string `hcl:"url,attr" cty:"url"`
+	// The events to post, out of run_start, unit_failure and run_complete. Defaults to all of them.
+	Events *[]string `hcl:"events,attr" cty:"events"`
+	// The Go template the message of the events is rendered with
+	Template *string `hcl:"template,attr" cty:"template"`
+}
+
+// Webhook returns the webhook the notification posts to, or an error if its url, events or template are invalid
+func (notification NotificationConfig) Webhook() (*notify.Webhook, error) {
+	events := []string{}
+	if notification.Events != nil {
+		events = *notification.Events
+	}
+	template := ""
+	if notification.Template != nil {
+		template = *notification.Template
+	}
+	return notify.NewWebhook(notification.Name, notification.Url, events, template)
+}
+
+// Validate and index the given notification blocks by name
+func notificationConfigsByName(notifications []NotificationConfig) (map[string]NotificationConfig, error) {
+	if len(notifications) == 0 {
+		return nil, nil
+	}
+	configs := map[string]NotificationConfig{}
+	for _, notification := range notifications {
+		if _, err := notification.Webhook(); err != nil {
+			return nil, err
+		}
+		configs[notification.Name] = notification
+	}
+	return configs, nil
+}
+
+// RegistryCredentialsConfig represents the token terraform authenticates with a private module or provider registry
+// with, which is written to a credentials block of the CLI config of the terraform processes Terragrunt runs
+type RegistryCredentialsConfig struct {
+	Host  string `hcl:"host,label" cty:"host"`
+	Token string `hcl:"token,attr" cty:"token"`
+}
+
+// RegistryCredentialsHelperConfig represents the credentials helper terraform gets the tokens of the private registries
+// from, i.e. the terraform-credentials-<name> plugin, which is written to the credentials_helper block of the CLI config
+// of the terraform processes Terragrunt runs
+type RegistryCredentialsHelperConfig struct {
+	Name string    `hcl:"name,label" cty:"name"`
+	Args *[]string `hcl:"args,attr" cty:"args"`
+}
+
+// Validate and index the given registry_credentials blocks by host
+func registryCredentialsByHost(credentials []RegistryCredentialsConfig) (map[string]RegistryCredentialsConfig, error) {
+	if len(credentials) == 0 {
+		return nil, nil
+	}
+	configs := map[string]RegistryCredentialsConfig{}
+	for _, hostCredentials := range credentials {
+		if hostCredentials.Host == "" || strings.ContainsAny(hostCredentials.Host, " /") {
+			return nil, errors.WithStackTrace(InvalidRegistryCredentialsConfig(fmt.Sprintf("%q is not a valid host", hostCredentials.Host)))
+		}
+		if _, isDuplicate := configs[hostCredentials.Host]; isDuplicate {
+			return nil, errors.WithStackTrace(InvalidRegistryCredentialsConfig(fmt.Sprintf("found more than one block for %s", hostCredentials.Host)))
+		}
+		configs[hostCredentials.Host] = hostCredentials
+	}
+	return configs, nil
+}
+
+// The secrets engines of Vault the vault_credentials blocks can request credentials from
+const (
+	VaultEngineAws = "aws"
+	VaultEngineGcp = "gcp"
+)
+
+var vaultEngines = []string{VaultEngineAws, VaultEngineGcp}
+
+// VaultCredentialsConfig represents the short-lived credentials of a cloud that are requested from a secrets engine of
+// Vault at the start of the run of a unit, and passed to its terraform processes
+type VaultCredentialsConfig struct {
+	// The secrets engine, aws or gcp
+	Engine string `hcl:"engine,label" cty:"engine"`
+	// The address of Vault. Defaults to the VAULT_ADDR env var.
+	Address *string `hcl:"address,attr" cty:"address"`
+	// The path the secrets engine is mounted at. Defaults to the name of the engine.
+	Mount *string `hcl:"mount,attr" cty:"mount"`
+	// The role of the aws engine, or the name of the roleset or account of the gcp engine
+	Role string `hcl:"role,attr" cty:"role"`
+	// The ARN of the IAM role to assume, for the aws roles that can assume more than one
+	RoleArn *string `hcl:"role_arn,attr" cty:"role_arn"`
+	// The TTL of the STS credentials of the aws engine, e.g. 1h
+	Ttl *string `hcl:"ttl,attr" cty:"ttl"`
+	// The type of the account of the gcp engine, out of roleset, static-account and impersonated-account. Defaults to
+	// roleset.
+	AccountType *string `hcl:"account_type,attr" cty:"account_type"`
+}
+
+// Validate and index the given vault_credentials blocks by engine
+func vaultCredentialsByEngine(credentials []VaultCredentialsConfig) (map[string]VaultCredentialsConfig, error) {
+	if len(credentials) == 0 {
+		return nil, nil
+	}
+	configs := map[string]VaultCredentialsConfig{}
+	for _, engineCredentials := range credentials {
+		if !util.ListContainsElement(vaultEngines, engineCredentials.Engine) {
+			return nil, errors.WithStackTrace(InvalidVaultCredentialsConfig(fmt.Sprintf("the engine must be one of %s, but got %s", strings.Join(vaultEngines, ", "), engineCredentials.Engine)))
+		}
+		if _, isDuplicate := configs[engineCredentials.Engine]; isDuplicate {
+			return nil, errors.WithStackTrace(InvalidVaultCredentialsConfig(fmt.Sprintf("found more than one block for %s", engineCredentials.Engine)))
+		}
+		if engineCredentials.Role == "" {
+			return nil, errors.WithStackTrace(InvalidVaultCredentialsConfig(fmt.Sprintf("the role of %s must be set", engineCredentials.Engine)))
+		}
+		if engineCredentials.Engine == VaultEngineGcp && engineCredentials.AccountType != nil && !util.ListContainsElement(vault.GcpAccountTypes, *engineCredentials.AccountType) {
+			return nil, errors.WithStackTrace(InvalidVaultCredentialsConfig(fmt.Sprintf("the account_type of gcp must be one of %s, but got %s", strings.Join(vault.GcpAccountTypes, ", "), *engineCredentials.AccountType)))
+		}
+		if engineCredentials.Engine == VaultEngineGcp && (engineCredentials.RoleArn != nil || engineCredentials.Ttl != nil) {
+			return nil, errors.WithStackTrace(InvalidVaultCredentialsConfig("role_arn and ttl only apply to aws"))
+		}
+		if engineCredentials.Engine == VaultEngineAws && engineCredentials.AccountType != nil {
+			return nil, errors.WithStackTrace(InvalidVaultCredentialsConfig("account_type only applies to gcp"))
+		}
+		configs[engineCredentials.Engine] = engineCredentials
+	}
+	return configs, nil
+}
+
+// The clouds the oidc_credentials blocks can exchange the OIDC token of the CI job for credentials of
+const (
+	OidcCloudAws   = "aws"
+	OidcCloudGcp   = "gcp"
+	OidcCloudAzure = "azure"
+)
+
+var oidcClouds = []string{OidcCloudAws, OidcCloudGcp, OidcCloudAzure}
+
+// OidcCredentialsConfig represents the credentials of a cloud the OIDC token of the CI job, e.g. of GitHub Actions or
+// GitLab CI, is exchanged for at the start of the run of a unit, and passed to its terraform processes
+type OidcCredentialsConfig struct {
+	// The cloud, aws, gcp or azure
+	Cloud string `hcl:"cloud,label" cty:"cloud"`
+	// The audience the token is requested for. Defaults to the one the cloud expects.
+	Audience *string `hcl:"audience,attr" cty:"audience"`
+	// The env var the token is in, e.g. one of the id_tokens of a GitLab CI job. Defaults to requesting one from GitHub
+	// Actions, or else to the GITLAB_OIDC_TOKEN env var in GitLab CI.
+	TokenEnvVar *string `hcl:"token_env_var,attr" cty:"token_env_var"`
+
+	// The IAM role to assume with the token, the name and duration in seconds of its session, and the region or endpoint
+	// of STS
+	RoleArn         *string `hcl:"role_arn,attr" cty:"role_arn"`
+	SessionName     *string `hcl:"session_name,attr" cty:"session_name"`
+	SessionDuration *int64  `hcl:"session_duration,attr" cty:"session_duration"`
+	Region          *string `hcl:"region,attr" cty:"region"`
+	StsEndpoint     *string `hcl:"sts_endpoint,attr" cty:"sts_endpoint"`
+
+	// The provider of the workload identity pool of GCP, e.g.
+	// projects/123/locations/global/workloadIdentityPools/ci/providers/github, and the service account to impersonate, if
+	// any
+	WorkloadIdentityProvider *string `hcl:"workload_identity_provider,attr" cty:"workload_identity_provider"`
+	ServiceAccount           *string `hcl:"service_account,attr" cty:"service_account"`
+
+	// The client ID of the app registration or managed identity with the federated credentials of Azure, its tenant, and
+	// the subscription to use, if not the one of the azurerm provider blocks
+	ClientId       *string `hcl:"client_id,attr" cty:"client_id"`
+	TenantId       *string `hcl:"tenant_id,attr" cty:"tenant_id"`
+	SubscriptionId *string `hcl:"subscription_id,attr" cty:"subscription_id"`
+}
+
+// Validate and index the given oidc_credentials blocks by cloud
+func oidcCredentialsByCloud(credentials []OidcCredentialsConfig) (map[string]OidcCredentialsConfig, error) {
+	if len(credentials) == 0 {
+		return nil, nil
+	}
+	configs := map[string]OidcCredentialsConfig{}
+	for _, cloudCredentials := range credentials {
+		if !util.ListContainsElement(oidcClouds, cloudCredentials.Cloud) {
+			return nil, errors.WithStackTrace(InvalidOidcCredentialsConfig(fmt.Sprintf("the cloud must be one of %s, but got %s", strings.Join(oidcClouds, ", "), cloudCredentials.Cloud)))
+		}
+		if _, isDuplicate := configs[cloudCredentials.Cloud]; isDuplicate {
+			return nil, errors.WithStackTrace(InvalidOidcCredentialsConfig(fmt.Sprintf("found more than one block for %s", cloudCredentials.Cloud)))
+		}
+
+		// The attributes of each cloud, and whether they're required
+		attributes := map[string]map[string]bool{
+			OidcCloudAws: {
+				"role_arn":         cloudCredentials.RoleArn != nil,
+				"session_name":     cloudCredentials.SessionName != nil,
+				"session_duration": cloudCredentials.SessionDuration != nil,
+				"region":           cloudCredentials.Region != nil,
+				"sts_endpoint":     cloudCredentials.StsEndpoint != nil,
+			},
+			OidcCloudGcp: {
+				"workload_identity_provider": cloudCredentials.WorkloadIdentityProvider != nil,
+				"service_account":            cloudCredentials.ServiceAccount != nil,
+			},
+			OidcCloudAzure: {
+				"client_id":       cloudCredentials.ClientId != nil,
+				"tenant_id":       cloudCredentials.TenantId != nil,
+				"subscription_id": cloudCredentials.SubscriptionId != nil,
+			},
+		}
+		required := map[string][]string{
+			OidcCloudAws:   {"role_arn"},
+			OidcCloudGcp:   {"workload_identity_provider"},
+			OidcCloudAzure: {"client_id", "tenant_id"},
+		}
+		for _, name := range required[cloudCredentials.Cloud] {
+			if !attributes[cloudCredentials.Cloud][name] {
+				return nil, errors.WithStackTrace(InvalidOidcCredentialsConfig(fmt.Sprintf("the %s of %s must be set", name, cloudCredentials.Cloud)))
+			}
+		}
+		for _, otherCloud := range oidcClouds {
+			if otherCloud == cloudCredentials.Cloud {
+				continue
+			}
+			for name, isSet := range attributes[otherCloud] {
+				if isSet {
+					return nil, errors.WithStackTrace(InvalidOidcCredentialsConfig(fmt.Sprintf("%s only applies to %s", name, otherCloud)))
+				}
+			}
+		}
+		configs[cloudCredentials.Cloud] = cloudCredentials
+	}
+	return configs, nil
+}
+
+// The kinds of entities a module can be in a service catalog
+var catalogKinds = []string{"Resource", "Component"}
+
+// CatalogConfig represents how the module is described in a service catalog, such as Backstage
+type CatalogConfig struct {
+	// The team or user that owns the module
+	Owner *string `hcl:"owner,attr" cty:"owner"`
+	// The system the module is part of
+	System *string `hcl:"system,attr" cty:"system"`
+	// The lifecycle stage of the module, e.g. production
+	Lifecycle   *string `hcl:"lifecycle,attr" cty:"lifecycle"`
+	Description *string `hcl:"description,attr" cty:"description"`
+	// The kind of entity the module is, Resource or Component. Defaults to Resource.
+	Kind *string `hcl:"kind,attr" cty:"kind"`
+	// The type of the entity, e.g. database. Defaults to terraform.
+	Type *string   `hcl:"type,attr" cty:"type"`
+	Tags *[]string `hcl:"tags,attr" cty:"tags"`
+}
+
+// Validate returns an error if the kind isn't one of the supported kinds
+func (catalog *CatalogConfig) Validate() error {
+	if catalog == nil || catalog.Kind == nil || util.ListContainsElement(catalogKinds, *catalog.Kind) {
+		return nil
+	}
+	return errors.WithStackTrace(InvalidCatalogConfig(fmt.Sprintf("kind must be one of %s, but got %s", strings.Join(catalogKinds, ", "), *catalog.Kind)))
+}
+
+// Merge the attributes set in the given child catalog block into the given parent one, returning a new block, so that
+// e.g. the owner can be set once in the included config, and the description in each child
+func mergeCatalog(parent *CatalogConfig, child *CatalogConfig) *CatalogConfig {
+	if parent == nil {
+		return child
+	}
+	if child == nil {
+		return parent
+	}
+
+	merged := *parent
+	if child.Owner != nil {
+		merged.Owner = child.Owner
+	}
+	if child.System != nil {
+		merged.System = child.System
+	}
+	if child.Lifecycle != nil {
+		merged.Lifecycle = child.Lifecycle
+	}
+	if child.Description != nil {
+		merged.Description = child.Description
+	}
+	if child.Kind != nil {
+		merged.Kind = child.Kind
+	}
+	if child.Type != nil {
+		merged.Type = child.Type
+	}
+	if child.Tags != nil {
+		merged.Tags = child.Tags
+	}
+	return &merged
+}
+
 // Hook specifies terraform commands (apply/plan) and array of os commands to execute
 type Hook struct {
 	Name       string   `hcl:"name,label" cty:"name"`
@@ -325,7 +702,11 @@ func GetTerraformSourceUrl(terragruntOptions *options.TerragruntOptions, terragr
 	if terragruntOptions.Source != "" {
 		return terragruntOptions.Source, nil
 	} else if terragruntConfig.Terraform != nil && terragruntConfig.Terraform.Source != nil {
-		return adjustSourceWithMap(terragruntOptions.SourceMap, *terragruntConfig.Terraform.Source, terragruntOptions.OriginalTerragruntConfigPath)
+		source, err := adjustSourceWithMap(terragruntOptions.SourceMap, *terragruntConfig.Terraform.Source, terragruntOptions.OriginalTerragruntConfigPath)
+		if err != nil {
+			return "", err
+		}
+		return adjustSourceWithMirrors(terragruntOptions.SourceMirrors, source), nil
 	} else {
 		return "", nil
 	}
@@ -333,7 +714,9 @@ func GetTerraformSourceUrl(terragruntOptions *options.TerragruntOptions, terragr
 
 // adjustSourceWithMap implements the --terragrunt-source-map feature. This function will check if the URL portion of a
 // terraform source matches any entry in the provided source map and if it does, replace it with the configured source
-// in the map. Note that this only performs literal matches with the URL portion.
+// in the map. A literal match on the URL portion always wins. Otherwise, the URL and the map keys are normalized (see
+// normalizeSourceUrlForMap), and the longest key that is a path prefix of the URL is used, with the rest of the URL
+// path appended to the configured source.
 //
 // Example:
 // Suppose terragrunt is called with:
@@ -375,11 +758,18 @@ func adjustSourceWithMap(sourceMap map[string]string, source string, modulePath
 	moduleUrlParsed.RawQuery = ""
 	moduleUrlQuery := moduleUrlParsed.String()
 
-	// Check if there is an entry to replace the URL portion in the map. Return the source as is if there is no entry in
-	// the map.
+	// Check if there is an entry to replace the URL portion in the map. If there is no literal entry, fall back to
+	// prefix matching. Return the source as is if nothing matches.
 	sourcePath, hasKey := sourceMap[moduleUrlQuery]
 	if hasKey == false {
-		return source, nil
+		prefixSourcePath, matched := adjustSourceWithMapPrefix(sourceMap, moduleUrlQuery)
+		if !matched {
+			return source, nil
+		}
+		if moduleSubdir == "" {
+			return prefixSourcePath, nil
+		}
+		return util.JoinTerraformModulePath(prefixSourcePath, moduleSubdir), nil
 	}
 
 	// Since there is a source mapping, replace the module URL portion with the entry in the map, and join with the
@@ -396,6 +786,62 @@ func adjustSourceWithMap(sourceMap map[string]string, source string, modulePath
 
 }
 
+// adjustSourceWithMapPrefix looks for the longest key in the source map that, once normalized, is a path prefix of the
+// given module URL. If there is one, this returns the mapped path with the remainder of the URL path appended to it.
+//
+// Example: with a source map of github.com/org=/home/me/modules, the module URL
+// git::ssh://git@github.com/org/vpc.git maps to /home/me/modules/vpc.
+func adjustSourceWithMapPrefix(sourceMap map[string]string, moduleUrl string) (string, bool) {
+	longestKey, remainder := longestSourceUrlPrefix(sourceMap, moduleUrl)
+	if longestKey == "" {
+		return "", false
+	}
+	if remainder == "" {
+		return sourceMap[longestKey], true
+	}
+	return util.JoinPath(sourceMap[longestKey], remainder), true
+}
+
+// longestSourceUrlPrefix returns the longest key of the given map that, once normalized, is a path prefix of the given
+// module URL, along with the rest of the normalized URL path. It returns an empty key if none of them is.
+func longestSourceUrlPrefix(sourceMap map[string]string, moduleUrl string) (string, string) {
+	normalizedModuleUrl := normalizeSourceUrlForMap(moduleUrl)
+
+	longestKey := ""
+	longestNormalizedKey := ""
+	remainder := ""
+	for key := range sourceMap {
+		normalizedKey := normalizeSourceUrlForMap(key)
+		if normalizedKey == "" || len(normalizedKey) <= len(longestNormalizedKey) {
+			continue
+		}
+		if normalizedModuleUrl == normalizedKey {
+			longestKey, longestNormalizedKey, remainder = key, normalizedKey, ""
+		} else if strings.HasPrefix(normalizedModuleUrl, normalizedKey+"/") {
+			longestKey, longestNormalizedKey, remainder = key, normalizedKey, strings.TrimPrefix(normalizedModuleUrl, normalizedKey+"/")
+		}
+	}
+	return longestKey, remainder
+}
+
+// normalizeSourceUrlForMap strips the parts of a source URL that don't identify the code being downloaded, so that
+// git::ssh://git@github.com/org/modules.git, https://github.com/org/modules and github.com/org/modules all normalize to
+// github.com/org/modules.
+func normalizeSourceUrlForMap(sourceUrl string) string {
+	normalized := forcedGetterRegexp.ReplaceAllString(sourceUrl, "")
+	normalized = schemeRegexp.ReplaceAllString(normalized, "")
+	normalized = userInfoRegexp.ReplaceAllString(normalized, "")
+	// scp-like git URLs use a colon to separate the host from the path, e.g. github.com:org/modules.git
+	normalized = scpLikePathRegexp.ReplaceAllString(normalized, "$1/")
+	normalized = strings.TrimRight(normalized, "/")
+	return strings.TrimSuffix(normalized, ".git")
+}
+
+var forcedGetterRegexp = regexp.MustCompile(`^[A-Za-z0-9]+::`)
+var schemeRegexp = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9+.-]*://`)
+var userInfoRegexp = regexp.MustCompile(`^[^@/]+@`)
+var scpLikePathRegexp = regexp.MustCompile(`^([^/:]+):`)
+
 // Return the default hcl path to use for the Terragrunt configuration file in the given directory
 func DefaultConfigPath(workingDir string) string {
 	return util.JoinPath(workingDir, DefaultTerragruntConfigPath)
@@ -416,33 +862,84 @@ func GetDefaultConfigPath(workingDir string) string {
 }
 
 // Returns a list of all Terragrunt config files in the given path or any subfolder of the path. A file is a Terragrunt
-// config file if it has a name as returned by the DefaultConfigPath method
+// config file if it has a name as returned by the DefaultConfigPath method. The subfolders are scanned concurrently,
+// but the files are returned in the same order as filepath.Walk would find them.
 func FindConfigFilesInPath(rootPath string, terragruntOptions *options.TerragruntOptions) ([]string, error) {
-	configFiles := []string{}
+	info, err := os.Lstat(rootPath)
+	if err != nil {
+		return []string{}, errors.WithStackTrace(err)
+	}
 
-	err := filepath.Walk(rootPath, func(path string, info os.FileInfo, err error) error {
-		if err != nil {
-			return err
-		}
+	finder := configFilesFinder{
+		terragruntOptions: terragruntOptions,
+		slots:             make(chan struct{}, terragruntOptions.DiscoveryParallelism()),
+	}
+	return finder.find(rootPath, info)
+}
 
-		// Skip the Terragrunt cache dir entirely
-		if info.IsDir() && info.Name() == options.TerragruntCacheDir {
-			return filepath.SkipDir
-		}
+// configFilesFinder finds the Terragrunt config files in a folder tree, scanning the subfolders of each folder
+// concurrently. The number of folders scanned at the same time is limited by the size of the slots channel.
+type configFilesFinder struct {
+	terragruntOptions *options.TerragruntOptions
+	slots             chan struct{}
+}
 
-		isTerragruntModule, err := containsTerragruntModule(path, info, terragruntOptions)
-		if err != nil {
-			return err
+// Return the config files in the given path and its subfolders, in the order filepath.Walk would visit them
+func (finder configFilesFinder) find(path string, info os.FileInfo) ([]string, error) {
+	// Skip the Terragrunt cache dir entirely
+	if !info.IsDir() || info.Name() == options.TerragruntCacheDir {
+		return []string{}, nil
+	}
+
+	finder.slots <- struct{}{}
+	configFiles, entries, err := finder.scan(path, info)
+	<-finder.slots
+	if err != nil {
+		return nil, err
+	}
+
+	// Like filepath.Walk, entries is sorted by name and uses lstat, so symlinks to folders are not followed
+	results := make([][]string, len(entries))
+	errs := make([]error, len(entries))
+	var wg sync.WaitGroup
+	for i, entry := range entries {
+		if !entry.IsDir() {
+			continue
 		}
+		wg.Add(1)
+		go func(i int, entry os.FileInfo) {
+			defer wg.Done()
+			results[i], errs[i] = finder.find(filepath.Join(path, entry.Name()), entry)
+		}(i, entry)
+	}
+	wg.Wait()
 
-		if isTerragruntModule {
-			configFiles = append(configFiles, GetDefaultConfigPath(path))
+	for i := range entries {
+		if errs[i] != nil {
+			return nil, errs[i]
 		}
+		configFiles = append(configFiles, results[i]...)
+	}
+	return configFiles, nil
+}
 
-		return nil
-	})
+// Return the config file in the given folder, if it contains a Terragrunt module, along with the entries of the folder
+func (finder configFilesFinder) scan(path string, info os.FileInfo) ([]string, []os.FileInfo, error) {
+	configFiles := []string{}
+
+	isTerragruntModule, err := containsTerragruntModule(path, info, finder.terragruntOptions)
+	if err != nil {
+		return nil, nil, err
+	}
+	if isTerragruntModule {
+		configFiles = append(configFiles, GetDefaultConfigPath(path))
+	}
 
-	return configFiles, err
+	entries, err := ioutil.ReadDir(path)
+	if err != nil {
+		return nil, nil, errors.WithStackTrace(err)
+	}
+	return configFiles, entries, nil
 }
 
 // Returns true if the given path with the given FileInfo contains a Terragrunt module and false otherwise. A path
@@ -496,13 +993,16 @@ func ReadTerragruntConfig(terragruntOptions *options.TerragruntOptions) (*Terrag
 
 // Parse the Terragrunt config file at the given path. If the include parameter is not nil, then treat this as a config
 // included in some other config file when resolving relative paths.
-func ParseConfigFile(filename string, terragruntOptions *options.TerragruntOptions, include *IncludeConfig) (*TerragruntConfig, error) {
-	configString, err := util.ReadFileAsString(filename)
+func ParseConfigFile(filename string, terragruntOptions *options.TerragruntOptions, include *IncludeConfig) (config *TerragruntConfig, finalErr error) {
+	span := terragruntOptions.TelemetrySpan.StartChild("parse_config", map[string]string{"terragrunt.config_path": filename})
+	defer func() { span.End(finalErr) }()
+
+	parser, file, err := readAndParseHclFile(filename, terragruntOptions)
 	if err != nil {
 		return nil, err
 	}
 
-	config, err := ParseConfigString(configString, terragruntOptions, include, filename)
+	config, err = parseConfig(parser, file, terragruntOptions, include, filename)
 	if err != nil {
 		return nil, err
 	}
@@ -542,6 +1042,18 @@ func ParseConfigString(configString string, terragruntOptions *options.Terragrun
 		return nil, err
 	}
 
+	return parseConfig(parser, file, terragruntOptions, includeFromChild, filename)
+}
+
+// Decode the Terragrunt config in the given HCL file and merge it with the given include config (if any). See
+// ParseConfigString for the parsing order.
+func parseConfig(
+	parser *hclparse.Parser,
+	file *hcl.File,
+	terragruntOptions *options.TerragruntOptions,
+	includeFromChild *IncludeConfig,
+	filename string,
+) (*TerragruntConfig, error) {
 	// Decode just the Base blocks. See the function docs for DecodeBaseBlocks for more info on what base blocks are.
 	localsAsCty, terragruntInclude, includeForDecode, err := DecodeBaseBlocks(terragruntOptions, parser, file, filename, includeFromChild)
 	if err != nil {
@@ -554,27 +1066,22 @@ func ParseConfigString(configString string, terragruntOptions *options.Terragrun
 		Include: includeForDecode,
 	}
 
-	// Decode just the `dependency` blocks, retrieving the outputs from the target terragrunt config in the
-	// process.
-	retrievedOutputs, err := decodeAndRetrieveOutputs(file, filename, terragruntOptions, contextExtensions)
-	if err != nil {
-		return nil, err
-	}
-	contextExtensions.DecodedDependencies = retrievedOutputs
-
-	// Decode the rest of the config, passing in this config's `include` block or the child's `include` block, whichever
-	// is appropriate
-	terragruntConfigFile, err := decodeAsTerragruntConfigFile(file, filename, terragruntOptions, contextExtensions)
-	if err != nil {
-		return nil, err
-	}
-	if terragruntConfigFile == nil {
-		return nil, errors.WithStackTrace(CouldNotResolveTerragruntConfigInFile(filename))
-	}
+	// Commands that don't need the outputs of the dependencies, such as validate, first try to decode the config
+	// without retrieving them.
+	config := decodeWithUnknownDependencyOutputs(file, filename, terragruntOptions, contextExtensions)
+	if config == nil {
+		// Decode just the `dependency` blocks, retrieving the outputs from the target terragrunt config in the
+		// process.
+		retrievedOutputs, err := decodeAndRetrieveOutputs(file, filename, terragruntOptions, contextExtensions)
+		if err != nil {
+			return nil, err
+		}
+		contextExtensions.DecodedDependencies = retrievedOutputs
 
-	config, err := convertToTerragruntConfig(terragruntConfigFile, filename, terragruntOptions, contextExtensions)
-	if err != nil {
-		return nil, err
+		config, err = decodeAndConvertTerragruntConfig(file, filename, terragruntOptions, contextExtensions)
+		if err != nil {
+			return nil, err
+		}
 	}
 
 	// If this file includes another, parse and merge it.  Otherwise just return this config.
@@ -589,6 +1096,25 @@ func ParseConfigString(configString string, terragruntOptions *options.Terragrun
 	}
 }
 
+// Decode the rest of the config, passing in this config's `include` block or the child's `include` block, whichever is
+// appropriate, and convert it to a TerragruntConfig.
+func decodeAndConvertTerragruntConfig(
+	file *hcl.File,
+	filename string,
+	terragruntOptions *options.TerragruntOptions,
+	extensions EvalContextExtensions,
+) (*TerragruntConfig, error) {
+	terragruntConfigFile, err := decodeAsTerragruntConfigFile(file, filename, terragruntOptions, extensions)
+	if err != nil {
+		return nil, err
+	}
+	if terragruntConfigFile == nil {
+		return nil, errors.WithStackTrace(CouldNotResolveTerragruntConfigInFile(filename))
+	}
+
+	return convertToTerragruntConfig(terragruntConfigFile, filename, terragruntOptions, extensions)
+}
+
 func getIncludedConfigForDecode(
 	parsedTerragruntInclude *terragruntInclude,
 	terragruntOptions *options.TerragruntOptions,
@@ -660,12 +1186,39 @@ func mergeConfigWithIncludedConfig(config *TerragruntConfig, includedConfig *Ter
 
 	if config.IamRole != "" {
 		includedConfig.IamRole = config.IamRole
+		includedConfig.IamRoleChain = config.IamRoleChain
+	}
+
+	// An iam_command_role block of the child overrides the one of the parent with the same name
+	if len(config.IamCommandRoles) > 0 && includedConfig.IamCommandRoles == nil {
+		includedConfig.IamCommandRoles = map[string]IamCommandRole{}
+	}
+	for name, role := range config.IamCommandRoles {
+		includedConfig.IamCommandRoles[name] = role
 	}
 
 	if config.IamAssumeRoleDuration != nil {
 		includedConfig.IamAssumeRoleDuration = config.IamAssumeRoleDuration
 	}
 
+	if config.IamAssumeRoleExternalId != "" {
+		includedConfig.IamAssumeRoleExternalId = config.IamAssumeRoleExternalId
+	}
+
+	if config.IamWebIdentityToken != "" {
+		includedConfig.IamWebIdentityToken = config.IamWebIdentityToken
+	}
+
+	if config.GoogleImpersonateServiceAccount != "" {
+		includedConfig.GoogleImpersonateServiceAccount = config.GoogleImpersonateServiceAccount
+	}
+
+	if config.AwsProfile != "" {
+		includedConfig.AwsProfile = config.AwsProfile
+	}
+
+	includedConfig.AzureAuth = mergeAzureAuth(includedConfig.AzureAuth, config.AzureAuth)
+
 	if config.TerraformVersionConstraint != "" {
 		includedConfig.TerraformVersionConstraint = config.TerraformVersionConstraint
 	}
@@ -690,6 +1243,52 @@ func mergeConfigWithIncludedConfig(config *TerragruntConfig, includedConfig *Ter
 		includedConfig.TerragruntVersionConstraint = config.TerragruntVersionConstraint
 	}
 
+	if config.Workspace != "" {
+		includedConfig.Workspace = config.Workspace
+	}
+
+	if config.Parallelism != nil {
+		includedConfig.Parallelism = config.Parallelism
+	}
+
+	includedConfig.Catalog = mergeCatalog(includedConfig.Catalog, config.Catalog)
+
+	// Like the generate configs, a notification block of the child overrides the one of the parent with the same name
+	if len(config.Notifications) > 0 && includedConfig.Notifications == nil {
+		includedConfig.Notifications = map[string]NotificationConfig{}
+	}
+	for name, notification := range config.Notifications {
+		includedConfig.Notifications[name] = notification
+	}
+
+	// Like the notification blocks, a registry_credentials block of the child overrides the one of the parent with the
+	// same host, and terraform only supports one credentials helper
+	if len(config.RegistryCredentials) > 0 && includedConfig.RegistryCredentials == nil {
+		includedConfig.RegistryCredentials = map[string]RegistryCredentialsConfig{}
+	}
+	for host, credentials := range config.RegistryCredentials {
+		includedConfig.RegistryCredentials[host] = credentials
+	}
+	if config.RegistryCredentialsHelper != nil {
+		includedConfig.RegistryCredentialsHelper = config.RegistryCredentialsHelper
+	}
+
+	// A vault_credentials block of the child overrides the one of the parent for the same engine
+	if len(config.VaultCredentials) > 0 && includedConfig.VaultCredentials == nil {
+		includedConfig.VaultCredentials = map[string]VaultCredentialsConfig{}
+	}
+	for engine, credentials := range config.VaultCredentials {
+		includedConfig.VaultCredentials[engine] = credentials
+	}
+
+	// An oidc_credentials block of the child overrides the one of the parent for the same cloud
+	if len(config.OidcCredentials) > 0 && includedConfig.OidcCredentials == nil {
+		includedConfig.OidcCredentials = map[string]OidcCredentialsConfig{}
+	}
+	for cloud, credentials := range config.OidcCredentials {
+		includedConfig.OidcCredentials[cloud] = credentials
+	}
+
 	// Merge the generate configs. This is a shallow merge. Meaning, if the child has the same name generate block, then the
 	// child's generate block will override the parent's block.
 	for key, val := range config.GenerateConfigs {
@@ -791,6 +1390,8 @@ func parseIncludedConfig(includedConfig *IncludeConfig, terragruntOptions *optio
 		includePath = util.JoinPath(filepath.Dir(terragruntOptions.TerragruntConfigPath), includePath)
 	}
 
+	terragruntOptions.MarkFileAsRead(includePath)
+
 	return ParseConfigFile(includePath, terragruntOptions, includedConfig)
 }
 
@@ -808,6 +1409,25 @@ func mergeInputs(childInputs map[string]interface{}, parentInputs map[string]int
 	return out
 }
 
+// Derive the Terraform Cloud workspace name of the unit, if the remote state config asks for it, from the path of the
+// unit relative to the included config, as path_relative_to_include() would return it. For a config with no include,
+// that path is ".", so the name of the unit's folder is used instead.
+func deriveTFCWorkspaceName(remoteState *remote.RemoteState, terragruntOptions *options.TerragruntOptions, include *IncludeConfig) error {
+	if remoteState == nil {
+		return nil
+	}
+
+	unitPath, err := pathRelativeToInclude(include, terragruntOptions)
+	if err != nil {
+		return err
+	}
+	if unitPath == "." {
+		unitPath = filepath.Base(filepath.Dir(terragruntOptions.TerragruntConfigPath))
+	}
+
+	return remote.DeriveTFCWorkspaceName(remoteState, unitPath)
+}
+
 // Convert the contents of a fully resolved Terragrunt configuration to a TerragruntConfig object
 func convertToTerragruntConfig(
 	terragruntConfigFromFile *terragruntConfigFile,
@@ -851,6 +1471,10 @@ func convertToTerragruntConfig(
 		terragruntConfig.RemoteState = remoteState
 	}
 
+	if err := deriveTFCWorkspaceName(terragruntConfig.RemoteState, terragruntOptions, contextExtensions.Include); err != nil {
+		return nil, err
+	}
+
 	if err := terragruntConfigFromFile.Terraform.ValidateHooks(); err != nil {
 		return nil, err
 	}
@@ -896,13 +1520,84 @@ func convertToTerragruntConfig(
 	}
 
 	if terragruntConfigFromFile.IamRole != nil {
-		terragruntConfig.IamRole = *terragruntConfigFromFile.IamRole
+		iamRole, iamRoleChain, err := parseIamRole(*terragruntConfigFromFile.IamRole)
+		if err != nil {
+			return nil, err
+		}
+		terragruntConfig.IamRole = iamRole
+		terragruntConfig.IamRoleChain = iamRoleChain
+	}
+
+	iamCommandRoles, err := iamCommandRolesByName(terragruntConfigFromFile.IamCommandRoles)
+	if err != nil {
+		return nil, err
 	}
+	terragruntConfig.IamCommandRoles = iamCommandRoles
 
 	if terragruntConfigFromFile.IamAssumeRoleDuration != nil {
 		terragruntConfig.IamAssumeRoleDuration = terragruntConfigFromFile.IamAssumeRoleDuration
 	}
 
+	if terragruntConfigFromFile.IamAssumeRoleExternalId != nil {
+		terragruntConfig.IamAssumeRoleExternalId = *terragruntConfigFromFile.IamAssumeRoleExternalId
+	}
+
+	if terragruntConfigFromFile.IamWebIdentityToken != nil {
+		terragruntConfig.IamWebIdentityToken = *terragruntConfigFromFile.IamWebIdentityToken
+	}
+
+	if terragruntConfigFromFile.GoogleImpersonateServiceAccount != nil {
+		terragruntConfig.GoogleImpersonateServiceAccount = *terragruntConfigFromFile.GoogleImpersonateServiceAccount
+	}
+
+	if terragruntConfigFromFile.AwsProfile != nil {
+		terragruntConfig.AwsProfile = *terragruntConfigFromFile.AwsProfile
+	}
+
+	if err := terragruntConfigFromFile.AzureAuth.Validate(); err != nil {
+		return nil, err
+	}
+	terragruntConfig.AzureAuth = terragruntConfigFromFile.AzureAuth
+
+	if terragruntConfigFromFile.Workspace != nil {
+		terragruntConfig.Workspace = *terragruntConfigFromFile.Workspace
+	}
+
+	if err := terragruntConfigFromFile.Parallelism.Validate(); err != nil {
+		return nil, err
+	}
+	terragruntConfig.Parallelism = terragruntConfigFromFile.Parallelism
+
+	notifications, err := notificationConfigsByName(terragruntConfigFromFile.Notifications)
+	if err != nil {
+		return nil, err
+	}
+	terragruntConfig.Notifications = notifications
+
+	if err := terragruntConfigFromFile.Catalog.Validate(); err != nil {
+		return nil, err
+	}
+	terragruntConfig.Catalog = terragruntConfigFromFile.Catalog
+
+	registryCredentials, err := registryCredentialsByHost(terragruntConfigFromFile.RegistryCredentials)
+	if err != nil {
+		return nil, err
+	}
+	terragruntConfig.RegistryCredentials = registryCredentials
+	terragruntConfig.RegistryCredentialsHelper = terragruntConfigFromFile.RegistryCredentialsHelper
+
+	vaultCredentials, err := vaultCredentialsByEngine(terragruntConfigFromFile.VaultCredentials)
+	if err != nil {
+		return nil, err
+	}
+	terragruntConfig.VaultCredentials = vaultCredentials
+
+	oidcCredentials, err := oidcCredentialsByCloud(terragruntConfigFromFile.OidcCredentials)
+	if err != nil {
+		return nil, err
+	}
+	terragruntConfig.OidcCredentials = oidcCredentials
+
 	generateBlocks := []terragruntGenerateBlock{}
 	generateBlocks = append(generateBlocks, terragruntConfigFromFile.GenerateBlocks...)
 
@@ -947,7 +1642,13 @@ func convertToTerragruntConfig(
 	}
 
 	if terragruntConfigFromFile.Inputs != nil {
-		inputs, err := parseCtyValueToMap(*terragruntConfigFromFile.Inputs)
+		// The inputs can only be partially known when the outputs of the dependencies were not retrieved, as they're not
+		// needed by the command. See decodeWithUnknownDependencyOutputs.
+		knownInputs, err := replaceUnknownValuesWithNull(*terragruntConfigFromFile.Inputs)
+		if err != nil {
+			return nil, err
+		}
+		inputs, err := parseCtyValueToMap(knownInputs)
 		if err != nil {
 			return nil, err
 		}
@@ -1014,6 +1715,36 @@ func (err PanicWhileParsingConfig) Error() string {
 	return fmt.Sprintf("Recovering panic while parsing '%s'. Got error of type '%v': %v", err.ConfigFile, reflect.TypeOf(err.RecoveredValue), err.RecoveredValue)
 }
 
+type InvalidParallelismConfig string
+
+func (err InvalidParallelismConfig) Error() string {
+	return fmt.Sprintf("Invalid parallelism block: %s", string(err))
+}
+
+type InvalidCatalogConfig string
+
+func (err InvalidCatalogConfig) Error() string {
+	return fmt.Sprintf("Invalid catalog block: %s", string(err))
+}
+
+type InvalidRegistryCredentialsConfig string
+
+func (err InvalidRegistryCredentialsConfig) Error() string {
+	return fmt.Sprintf("Invalid registry_credentials block: %s", string(err))
+}
+
+type InvalidVaultCredentialsConfig string
+
+func (err InvalidVaultCredentialsConfig) Error() string {
+	return fmt.Sprintf("Invalid vault_credentials block: %s", string(err))
+}
+
+type InvalidOidcCredentialsConfig string
+
+func (err InvalidOidcCredentialsConfig) Error() string {
+	return fmt.Sprintf("Invalid oidc_credentials block: %s", string(err))
+}
+
 type InvalidBackendConfigType struct {
 	ExpectedType string
 	ActualType   string
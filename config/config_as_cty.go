@@ -24,7 +24,12 @@ func terragruntConfigAsCty(config *TerragruntConfig) (cty.Value, error) {
 	output["terragrunt_version_constraint"] = gostringToCty(config.TerragruntVersionConstraint)
 	output["download_dir"] = gostringToCty(config.DownloadDir)
 	output["iam_role"] = gostringToCty(config.IamRole)
+	output["iam_assume_role_external_id"] = gostringToCty(config.IamAssumeRoleExternalId)
+	output["iam_web_identity_token"] = gostringToCty(config.IamWebIdentityToken)
+	output["google_impersonate_service_account"] = gostringToCty(config.GoogleImpersonateServiceAccount)
+	output["aws_profile"] = gostringToCty(config.AwsProfile)
 	output["skip"] = goboolToCty(config.Skip)
+	output["workspace"] = gostringToCty(config.Workspace)
 
 	terraformConfigCty, err := terraformConfigAsCty(config.Terraform)
 	if err != nil {
@@ -103,6 +108,78 @@ func terragruntConfigAsCty(config *TerragruntConfig) (cty.Value, error) {
 		output["retry_sleep_interval_sec"] = retrySleepIntervalSecCty
 	}
 
+	parallelismCty, err := goTypeToCty(config.Parallelism)
+	if err != nil {
+		return cty.NilVal, err
+	}
+	if parallelismCty != cty.NilVal {
+		output["parallelism"] = parallelismCty
+	}
+
+	notificationCty, err := goTypeToCty(config.Notifications)
+	if err != nil {
+		return cty.NilVal, err
+	}
+	if notificationCty != cty.NilVal {
+		output["notification"] = notificationCty
+	}
+
+	catalogCty, err := goTypeToCty(config.Catalog)
+	if err != nil {
+		return cty.NilVal, err
+	}
+	if catalogCty != cty.NilVal {
+		output["catalog"] = catalogCty
+	}
+
+	registryCredentialsCty, err := goTypeToCty(config.RegistryCredentials)
+	if err != nil {
+		return cty.NilVal, err
+	}
+	if registryCredentialsCty != cty.NilVal {
+		output["registry_credentials"] = registryCredentialsCty
+	}
+
+	registryCredentialsHelperCty, err := goTypeToCty(config.RegistryCredentialsHelper)
+	if err != nil {
+		return cty.NilVal, err
+	}
+	if registryCredentialsHelperCty != cty.NilVal {
+		output["registry_credentials_helper"] = registryCredentialsHelperCty
+	}
+
+	vaultCredentialsCty, err := goTypeToCty(config.VaultCredentials)
+	if err != nil {
+		return cty.NilVal, err
+	}
+	if vaultCredentialsCty != cty.NilVal {
+		output["vault_credentials"] = vaultCredentialsCty
+	}
+
+	oidcCredentialsCty, err := goTypeToCty(config.OidcCredentials)
+	if err != nil {
+		return cty.NilVal, err
+	}
+	if oidcCredentialsCty != cty.NilVal {
+		output["oidc_credentials"] = oidcCredentialsCty
+	}
+
+	azureAuthCty, err := goTypeToCty(config.AzureAuth)
+	if err != nil {
+		return cty.NilVal, err
+	}
+	if azureAuthCty != cty.NilVal {
+		output["azure_auth"] = azureAuthCty
+	}
+
+	iamCommandRolesCty, err := goTypeToCty(config.IamCommandRoles)
+	if err != nil {
+		return cty.NilVal, err
+	}
+	if iamCommandRolesCty != cty.NilVal {
+		output["iam_command_role"] = iamCommandRolesCty
+	}
+
 	inputsCty, err := convertToCtyWithJson(config.Inputs)
 	if err != nil {
 		return cty.NilVal, err
@@ -122,6 +199,19 @@ func terragruntConfigAsCty(config *TerragruntConfig) (cty.Value, error) {
 	return convertValuesMapToCtyVal(output)
 }
 
+// TerragruntConfigAsJson renders the given config as JSON, in the same shape as read_terragrunt_config returns it
+func TerragruntConfigAsJson(config *TerragruntConfig) ([]byte, error) {
+	configCty, err := terragruntConfigAsCty(config)
+	if err != nil {
+		return nil, err
+	}
+	configJson, err := ctyjson.Marshal(configCty, configCty.Type())
+	if err != nil {
+		return nil, errors.WithStackTrace(err)
+	}
+	return configJson, nil
+}
+
 // ctyTerraformConfig is an alternate representation of TerraformConfig that converts internal blocks into a map that
 // maps the name to the underlying struct, as opposed to a list representation.
 type ctyTerraformConfig struct {
@@ -181,6 +271,12 @@ func remoteStateAsCty(remoteState *remote.RemoteState) (cty.Value, error) {
 	}
 	output["config"] = ctyJsonVal
 
+	encryptionCty, err := convertToCtyWithJson(remoteState.Encryption)
+	if err != nil {
+		return cty.NilVal, err
+	}
+	output["encryption"] = encryptionCty
+
 	return convertValuesMapToCtyVal(output)
 }
 
@@ -62,6 +62,7 @@ func TestTerragruntConfigAsCtyDrift(t *testing.T) {
 		PreventDestroy: &testTrue,
 		Skip:           true,
 		IamRole:        "terragruntRole",
+		Workspace:      "prod",
 		Inputs: map[string]interface{}{
 			"aws_region": "us-east-1",
 		},
@@ -189,6 +190,16 @@ func terragruntConfigStructFieldToMapKey(t *testing.T, fieldName string) (string
 		return "iam_role", true
 	case "IamAssumeRoleDuration":
 		return "iam_assume_role_duration", true
+	case "IamAssumeRoleExternalId":
+		return "iam_assume_role_external_id", true
+	case "IamWebIdentityToken":
+		return "iam_web_identity_token", true
+	case "GoogleImpersonateServiceAccount":
+		return "google_impersonate_service_account", true
+	case "AwsProfile":
+		return "aws_profile", true
+	case "AzureAuth":
+		return "azure_auth", true
 	case "Inputs":
 		return "inputs", true
 	case "Locals":
@@ -199,12 +210,33 @@ func terragruntConfigStructFieldToMapKey(t *testing.T, fieldName string) (string
 		return "generate", true
 	case "IsPartial":
 		return "", false
+	case "IamRoleChain":
+		// The chain is converted as the iam_role it ends with
+		return "", false
 	case "RetryableErrors":
 		return "retryable_errors", true
 	case "RetryMaxAttempts":
 		return "retry_max_attempts", true
 	case "RetrySleepIntervalSec":
 		return "retry_sleep_interval_sec", true
+	case "Workspace":
+		return "workspace", true
+	case "Parallelism":
+		return "parallelism", true
+	case "Notifications":
+		return "notification", true
+	case "Catalog":
+		return "catalog", true
+	case "RegistryCredentials":
+		return "registry_credentials", true
+	case "RegistryCredentialsHelper":
+		return "registry_credentials_helper", true
+	case "VaultCredentials":
+		return "vault_credentials", true
+	case "OidcCredentials":
+		return "oidc_credentials", true
+	case "IamCommandRoles":
+		return "iam_command_role", true
 	default:
 		t.Fatalf("Unknown struct property: %s", fieldName)
 		// This should not execute
@@ -224,6 +256,8 @@ func remoteStateStructFieldToMapKey(t *testing.T, fieldName string) (string, boo
 		return "generate", true
 	case "Config":
 		return "config", true
+	case "Encryption":
+		return "encryption", true
 	default:
 		t.Fatalf("Unknown struct property: %s", fieldName)
 		// This should not execute
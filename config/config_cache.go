@@ -0,0 +1,137 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclparse"
+
+	"github.com/gruntwork-io/terragrunt/options"
+	"github.com/gruntwork-io/terragrunt/util"
+)
+
+// cachedHclFile is a config file parsed into an AST, along with the parser that parsed it, which is needed to render
+// the diagnostics of errors in the file.
+type cachedHclFile struct {
+	modTime time.Time
+	size    int64
+	parser  *hclparse.Parser
+	file    *hcl.File
+}
+
+// cachedPartialConfig is the result of partially parsing a config file.
+type cachedPartialConfig struct {
+	modTime time.Time
+	size    int64
+	config  *TerragruntConfig
+}
+
+// readAndParseHclFile reads the given config file and parses it into an AST. During *-all commands, the same config
+// files, such as the parent configs included by every module, are read over and over, so the AST is stored in the
+// config cache of the given options and reused for as long as the modification time and size of the file are
+// unchanged. Note that only the parsing is cached: the AST is still evaluated every time, as the result depends on the
+// module the config is read for. As that makes the AST valid beyond the run, the daemon keeps it between commands.
+func readAndParseHclFile(filename string, terragruntOptions *options.TerragruntOptions) (*hclparse.Parser, *hcl.File, error) {
+	cache := terragruntOptions.ConfigCache
+	if cache != nil && terragruntOptions.PersistentConfigCache != nil {
+		cache = terragruntOptions.PersistentConfigCache
+	}
+	cacheKey := "hcl:" + filename
+
+	fileInfo, statErr := os.Stat(filename)
+	if cache != nil && statErr == nil {
+		if cached, hasCached := cache.Load(cacheKey); hasCached {
+			cachedFile := cached.(*cachedHclFile)
+			if cachedFile.modTime.Equal(fileInfo.ModTime()) && cachedFile.size == fileInfo.Size() {
+				return cachedFile.parser, cachedFile.file, nil
+			}
+		}
+	}
+
+	configString, err := util.ReadFileAsString(filename)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	parser := hclparse.NewParser()
+	file, err := parseHcl(parser, configString, filename)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if cache != nil && statErr == nil {
+		cache.Store(cacheKey, &cachedHclFile{modTime: fileInfo.ModTime(), size: fileInfo.Size(), parser: parser, file: file})
+	}
+	return parser, file, nil
+}
+
+// partialParseConfigFileWithCache partially parses the given config file, reusing the result of a previous partial
+// parse of the same blocks of the file for the same module. This is what makes the configs of dependencies, which are
+// partially parsed by each of the modules that depend on them, only be evaluated once during *-all commands.
+//
+// The result is only cached for configs that are not included in another config, as those are evaluated in the context
+// of the child config, and when the files read while parsing are not being tracked, as a cached result doesn't record
+// them.
+func partialParseConfigFileWithCache(
+	filename string,
+	terragruntOptions *options.TerragruntOptions,
+	include *IncludeConfig,
+	decodeList []PartialDecodeSectionType,
+	parse func() (*TerragruntConfig, error),
+) (*TerragruntConfig, error) {
+	cache := terragruntOptions.ConfigCache
+	if cache == nil || include != nil || terragruntOptions.FilesRead != nil {
+		return parse()
+	}
+
+	fileInfo, err := os.Stat(filename)
+	if err != nil {
+		return parse()
+	}
+
+	cacheKey := fmt.Sprintf("partial:%s:%s:%v", filename, terragruntOptions.TerragruntConfigPath, decodeList)
+	if cached, hasCached := cache.Load(cacheKey); hasCached {
+		cachedConfig := cached.(*cachedPartialConfig)
+		if cachedConfig.modTime.Equal(fileInfo.ModTime()) && cachedConfig.size == fileInfo.Size() {
+			terragruntOptions.Logger.Debugf("Using the cached partial parse of %s", filename)
+			configCopy := *cachedConfig.config
+			return &configCopy, nil
+		}
+	}
+
+	config, err := parse()
+	if err != nil {
+		return nil, err
+	}
+
+	configCopy := *config
+	cache.Store(cacheKey, &cachedPartialConfig{modTime: fileInfo.ModTime(), size: fileInfo.Size(), config: &configCopy})
+	return config, nil
+}
+
+// memoizedResult is the result of a helper function memoized in the config cache. The result is computed exactly once,
+// even when many modules call the function concurrently.
+type memoizedResult struct {
+	once  sync.Once
+	value string
+	err   error
+}
+
+// memoize returns the result of computing the value under the given key earlier in the run, or computes it now. This
+// is used for the helper functions that are expensive and return the same result for every module, such as run_cmd, so
+// that a command in a config included by every module only runs once during *-all commands. Errors are memoized as well, so that the modules all see the same result.
+func memoize(key string, terragruntOptions *options.TerragruntOptions, compute func() (string, error)) (string, error) {
+	cache := terragruntOptions.ConfigCache
+	if cache == nil {
+		return compute()
+	}
+
+	result := cache.LoadOrStore("memoized:"+key, &memoizedResult{}).(*memoizedResult)
+	result.once.Do(func() {
+		result.value, result.err = compute()
+	})
+	return result.value, result.err
+}
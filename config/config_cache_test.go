@@ -0,0 +1,106 @@
+package config
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/gruntwork-io/terragrunt/options"
+)
+
+func TestReadAndParseHclFileUsesCache(t *testing.T) {
+	t.Parallel()
+
+	tmpDir, err := ioutil.TempDir("", "config-cache")
+	require.NoError(t, err)
+	defer os.RemoveAll(tmpDir)
+
+	configPath := filepath.Join(tmpDir, DefaultTerragruntConfigPath)
+	require.NoError(t, ioutil.WriteFile(configPath, []byte(`skip = true`), 0644))
+
+	terragruntOptions := mockOptionsForTestWithConfigPath(t, configPath)
+
+	_, file, err := readAndParseHclFile(configPath, terragruntOptions)
+	require.NoError(t, err)
+	_, cachedFile, err := readAndParseHclFile(configPath, terragruntOptions)
+	require.NoError(t, err)
+	assert.True(t, file == cachedFile, "Expected the parsed file to be reused")
+
+	// Modifying the file invalidates the cache
+	require.NoError(t, ioutil.WriteFile(configPath, []byte(`skip = false`+"\n"), 0644))
+	_, modifiedFile, err := readAndParseHclFile(configPath, terragruntOptions)
+	require.NoError(t, err)
+	assert.False(t, file == modifiedFile, "Expected the modified file to be parsed again")
+
+	// Without a cache, the file is parsed every time
+	terragruntOptions.ConfigCache = nil
+	_, uncachedFile, err := readAndParseHclFile(configPath, terragruntOptions)
+	require.NoError(t, err)
+	assert.False(t, modifiedFile == uncachedFile, "Expected the file to be parsed again without a cache")
+}
+
+func TestReadAndParseHclFileUsesPersistentCache(t *testing.T) {
+	t.Parallel()
+
+	tmpDir, err := ioutil.TempDir("", "config-cache")
+	require.NoError(t, err)
+	defer os.RemoveAll(tmpDir)
+
+	configPath := filepath.Join(tmpDir, DefaultTerragruntConfigPath)
+	require.NoError(t, ioutil.WriteFile(configPath, []byte(`skip = true`), 0644))
+
+	// The parsed file outlives the config cache of the run, as the daemon keeps it between commands
+	persistentCache := options.NewConfigCache()
+	terragruntOptions := mockOptionsForTestWithConfigPath(t, configPath)
+	terragruntOptions.PersistentConfigCache = persistentCache
+	_, file, err := readAndParseHclFile(configPath, terragruntOptions)
+	require.NoError(t, err)
+
+	nextTerragruntOptions := mockOptionsForTestWithConfigPath(t, configPath)
+	nextTerragruntOptions.PersistentConfigCache = persistentCache
+	_, cachedFile, err := readAndParseHclFile(configPath, nextTerragruntOptions)
+	require.NoError(t, err)
+	assert.True(t, file == cachedFile, "Expected the parsed file to be reused by the next run")
+}
+
+func TestPartialParseConfigFileUsesCache(t *testing.T) {
+	t.Parallel()
+
+	tmpDir, err := ioutil.TempDir("", "config-cache")
+	require.NoError(t, err)
+	defer os.RemoveAll(tmpDir)
+
+	configPath := filepath.Join(tmpDir, DefaultTerragruntConfigPath)
+	require.NoError(t, ioutil.WriteFile(configPath, []byte(`dependencies { paths = ["../vpc"] }`), 0644))
+
+	terragruntOptions := mockOptionsForTestWithConfigPath(t, configPath)
+	decodeList := []PartialDecodeSectionType{DependenciesBlock}
+
+	terragruntConfig, err := PartialParseConfigFile(configPath, terragruntOptions, nil, decodeList)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"../vpc"}, terragruntConfig.Dependencies.Paths)
+
+	// Changes the callers make to the returned config don't end up in the cache
+	terragruntConfig.Skip = true
+
+	cachedConfig, err := PartialParseConfigFile(configPath, terragruntOptions.Clone(configPath), nil, decodeList)
+	require.NoError(t, err)
+	assert.True(t, terragruntConfig.Dependencies == cachedConfig.Dependencies, "Expected the cached config to be returned")
+	assert.False(t, cachedConfig.Skip)
+
+	// The cache is per module, as the config can evaluate differently depending on the module it's read for
+	otherOptions := terragruntOptions.Clone(filepath.Join(tmpDir, "other", DefaultTerragruntConfigPath))
+	otherConfig, err := PartialParseConfigFile(configPath, otherOptions, nil, decodeList)
+	require.NoError(t, err)
+	assert.False(t, terragruntConfig.Dependencies == otherConfig.Dependencies, "Expected the config to be parsed again for another module")
+
+	// The cache is not used when tracking the files read, as a cached config doesn't record them
+	terragruntOptions.FilesRead = &options.FilesRead{}
+	trackedConfig, err := PartialParseConfigFile(configPath, terragruntOptions, nil, decodeList)
+	require.NoError(t, err)
+	assert.False(t, terragruntConfig.Dependencies == trackedConfig.Dependencies, "Expected the config to be parsed again when tracking the files read")
+}
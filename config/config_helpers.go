@@ -7,6 +7,7 @@ import (
 	"regexp"
 	"runtime"
 	"strings"
+	"sync"
 	"unicode/utf8"
 
 	"github.com/hashicorp/go-getter"
@@ -116,6 +117,7 @@ func CreateTerragruntEvalContext(
 		"get_terraform_commands_that_need_parallelism": wrapStaticValueToStringSliceAsFuncImpl(TERRAFORM_COMMANDS_NEED_PARALLELISM),
 		"sops_decrypt_file":                            wrapStringSliceToStringAsFuncImpl(sopsDecryptFile, extensions.Include, terragruntOptions),
 		"get_terragrunt_source_cli_flag":               wrapVoidToStringAsFuncImpl(getTerragruntSourceCliFlag, extensions.Include, terragruntOptions),
+		"mark_as_read":                                 wrapStringSliceToStringAsFuncImpl(markAsRead, extensions.Include, terragruntOptions),
 	}
 
 	functions := map[string]function.Function{}
@@ -204,20 +206,41 @@ func parseGetEnvParameters(parameters []string) (EnvVar, error) {
 }
 
 // runCommand is a helper function that runs a command and returns the stdout as the interporation
-// result
+// result. The result is memoized for the rest of the run, by the folder the command runs in and its args, or only by
+// its args if --terragrunt-global-cache is passed.
 func runCommand(args []string, include *IncludeConfig, terragruntOptions *options.TerragruntOptions) (string, error) {
 	if len(args) == 0 {
 		return "", errors.WithStackTrace(EmptyStringNotAllowed("parameter to the run_cmd function"))
 	}
 
 	suppressOutput := false
-	if args[0] == "--terragrunt-quiet" {
-		suppressOutput = true
-		args = append(args[:0], args[1:]...)
+	globalCache := false
+	for len(args) > 0 && (args[0] == "--terragrunt-quiet" || args[0] == "--terragrunt-global-cache") {
+		if args[0] == "--terragrunt-quiet" {
+			suppressOutput = true
+		} else {
+			globalCache = true
+		}
+		args = args[1:]
+	}
+	if len(args) == 0 {
+		return "", errors.WithStackTrace(EmptyStringNotAllowed("command to the run_cmd function"))
 	}
 
 	currentPath := filepath.Dir(terragruntOptions.TerragruntConfigPath)
 
+	cacheKey := fmt.Sprintf("run_cmd:%s:%q", currentPath, args)
+	if globalCache {
+		cacheKey = fmt.Sprintf("run_cmd:%q", args)
+	}
+
+	return memoize(cacheKey, terragruntOptions, func() (string, error) {
+		return runCommandUncached(args, currentPath, suppressOutput, terragruntOptions)
+	})
+}
+
+// runCommandUncached runs the command of run_cmd in the given folder and returns its stdout
+func runCommandUncached(args []string, currentPath string, suppressOutput bool, terragruntOptions *options.TerragruntOptions) (string, error) {
 	cmdOutput, err := shell.RunShellCommandWithOutput(terragruntOptions, currentPath, suppressOutput, false, args[0], args[1:]...)
 	if err != nil {
 		return "", errors.WithStackTrace(err)
@@ -353,29 +376,17 @@ func getTerraformCliArgs(include *IncludeConfig, terragruntOptions *options.Terr
 
 // Return the AWS account id associated to the current set of credentials
 func getAWSAccountID(include *IncludeConfig, terragruntOptions *options.TerragruntOptions) (string, error) {
-	accountID, err := aws_helper.GetAWSAccountID(nil, terragruntOptions)
-	if err == nil {
-		return accountID, nil
-	}
-	return "", err
+	return aws_helper.GetAWSAccountID(nil, terragruntOptions)
 }
 
 // Return the ARN of the AWS identity associated with the current set of credentials
 func getAWSCallerIdentityARN(include *IncludeConfig, terragruntOptions *options.TerragruntOptions) (string, error) {
-	identityARN, err := aws_helper.GetAWSIdentityArn(nil, terragruntOptions)
-	if err == nil {
-		return identityARN, nil
-	}
-	return "", err
+	return aws_helper.GetAWSIdentityArn(nil, terragruntOptions)
 }
 
 // Return the UserID of the AWS identity associated with the current set of credentials
 func getAWSCallerIdentityUserID(include *IncludeConfig, terragruntOptions *options.TerragruntOptions) (string, error) {
-	userID, err := aws_helper.GetAWSUserID(nil, terragruntOptions)
-	if err == nil {
-		return userID, nil
-	}
-	return "", err
+	return aws_helper.GetAWSUserID(nil, terragruntOptions)
 }
 
 // Parse the terragrunt config and return a representation that can be used as a reference. If given a default value,
@@ -392,6 +403,8 @@ func readTerragruntConfig(configPath string, defaultVal *cty.Value, terragruntOp
 		return *defaultVal, nil
 	}
 
+	terragruntOptions.MarkFileAsRead(targetConfig)
+
 	// We update the context of terragruntOptions to the config being read in.
 	targetOptions := terragruntOptions.Clone(targetConfig)
 	config, err := ParseConfigFile(targetConfig, targetOptions, nil)
@@ -527,6 +540,18 @@ func getModulePathFromSourceUrl(sourceUrl string) (string, error) {
 //
 var sopsCache = make(map[string]string)
 
+// sopsCacheLock protects sopsCache, as configs are parsed concurrently, e.g. while discovering the modules of *-all
+// commands.
+var sopsCacheLock sync.Mutex
+
+// ClearSopsCache clears the decrypted sops files, e.g. between the commands run by the daemon, as the files and the keys
+// that decrypt them may have changed since.
+func ClearSopsCache() {
+	sopsCacheLock.Lock()
+	defer sopsCacheLock.Unlock()
+	sopsCache = make(map[string]string)
+}
+
 // decrypts and returns sops encrypted utf-8 yaml or json data as a string
 func sopsDecryptFile(params []string, include *IncludeConfig, terragruntOptions *options.TerragruntOptions) (string, error) {
 	numParams := len(params)
@@ -555,7 +580,12 @@ func sopsDecryptFile(params []string, include *IncludeConfig, terragruntOptions
 		return "", errors.WithStackTrace(err)
 	}
 
-	if val, ok := sopsCache[canonicalSourceFile]; ok {
+	terragruntOptions.MarkFileAsRead(canonicalSourceFile)
+
+	sopsCacheLock.Lock()
+	val, ok := sopsCache[canonicalSourceFile]
+	sopsCacheLock.Unlock()
+	if ok {
 		return val, nil
 	}
 
@@ -566,7 +596,9 @@ func sopsDecryptFile(params []string, include *IncludeConfig, terragruntOptions
 
 	if utf8.Valid(rawData) {
 		value := string(rawData)
+		sopsCacheLock.Lock()
 		sopsCache[canonicalSourceFile] = value
+		sopsCacheLock.Unlock()
 		return value, nil
 	}
 
@@ -578,6 +610,24 @@ func getTerragruntSourceCliFlag(include *IncludeConfig, terragruntOptions *optio
 	return terragruntOptions.Source, nil
 }
 
+// Record that the given file is read by the current configuration and return its path unchanged. This is useful for
+// files that Terragrunt can't tell are being read, e.g. files read via Terraform's file() function, so that the
+// configuration is still picked up by --terragrunt-queue-include-units-reading.
+func markAsRead(params []string, include *IncludeConfig, terragruntOptions *options.TerragruntOptions) (string, error) {
+	numParams := len(params)
+	if numParams != 1 {
+		return "", errors.WithStackTrace(WrongNumberOfParams{Func: "mark_as_read", Expected: "1", Actual: numParams})
+	}
+
+	path := params[0]
+	if !filepath.IsAbs(path) {
+		path = util.JoinPath(filepath.Dir(terragruntOptions.TerragruntConfigPath), path)
+	}
+	terragruntOptions.MarkFileAsRead(path)
+
+	return params[0], nil
+}
+
 // Custom error types
 type WrongNumberOfParams struct {
 	Func     string
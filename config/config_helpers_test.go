@@ -2,6 +2,7 @@ package config
 
 import (
 	"fmt"
+	"io/ioutil"
 	"os"
 	"path/filepath"
 	"testing"
@@ -9,6 +10,7 @@ import (
 	"github.com/gruntwork-io/terragrunt/errors"
 	"github.com/gruntwork-io/terragrunt/options"
 	"github.com/gruntwork-io/terragrunt/test/helpers"
+	"github.com/gruntwork-io/terragrunt/util"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"github.com/zclconf/go-cty/cty"
@@ -180,6 +182,55 @@ func absPath(t *testing.T, path string) string {
 	return out
 }
 
+func TestRunCommandIsMemoized(t *testing.T) {
+	t.Parallel()
+
+	tmpDir, err := ioutil.TempDir("", "run-cmd-memoized")
+	require.NoError(t, err)
+	defer os.RemoveAll(tmpDir)
+
+	require.NoError(t, os.Mkdir(filepath.Join(tmpDir, "vpc"), 0755))
+	require.NoError(t, os.Mkdir(filepath.Join(tmpDir, "app"), 0755))
+
+	counterFile := filepath.Join(tmpDir, "counter")
+	countRuns := func() int {
+		contents, err := ioutil.ReadFile(counterFile)
+		if os.IsNotExist(err) {
+			return 0
+		}
+		require.NoError(t, err)
+		return len(contents)
+	}
+	command := []string{"/bin/bash", "-c", fmt.Sprintf("echo -n x >> %s && echo -n foo", counterFile)}
+
+	terragruntOptions := terragruntOptionsForTest(t, filepath.Join(tmpDir, "vpc", DefaultTerragruntConfigPath))
+	for i := 0; i < 2; i++ {
+		output, err := runCommand(command, nil, terragruntOptions.Clone(terragruntOptions.TerragruntConfigPath))
+		require.NoError(t, err)
+		assert.Equal(t, "foo", output)
+	}
+	assert.Equal(t, 1, countRuns())
+
+	// The command runs again for a module in another folder, unless it is cached globally
+	otherOptions := terragruntOptions.Clone(filepath.Join(tmpDir, "app", DefaultTerragruntConfigPath))
+	_, err = runCommand(command, nil, otherOptions)
+	require.NoError(t, err)
+	assert.Equal(t, 2, countRuns())
+
+	globalCommand := append([]string{"--terragrunt-global-cache", "--terragrunt-quiet"}, command...)
+	_, err = runCommand(globalCommand, nil, terragruntOptions)
+	require.NoError(t, err)
+	_, err = runCommand(globalCommand, nil, otherOptions)
+	require.NoError(t, err)
+	assert.Equal(t, 3, countRuns())
+
+	// Without a cache, the command runs every time
+	terragruntOptions.ConfigCache = nil
+	_, err = runCommand(command, nil, terragruntOptions)
+	require.NoError(t, err)
+	assert.Equal(t, 4, countRuns())
+}
+
 func TestFindInParentFolders(t *testing.T) {
 	t.Parallel()
 
@@ -877,6 +928,34 @@ func TestReadTerragruntConfigLocals(t *testing.T) {
 	assert.Equal(t, localsMap["number_expression"].(float64), float64(42))
 }
 
+func TestReadTerragruntConfigMarksFileAsRead(t *testing.T) {
+	t.Parallel()
+
+	terragruntOptions := terragruntOptionsForTest(t, DefaultTerragruntConfigPath)
+	terragruntOptions.FilesRead = &options.FilesRead{}
+	_, err := readTerragruntConfig("../test/fixture-locals/canonical/terragrunt.hcl", nil, terragruntOptions)
+	require.NoError(t, err)
+
+	expectedPath, err := util.CanonicalPath("../test/fixture-locals/canonical/terragrunt.hcl", ".")
+	require.NoError(t, err)
+	assert.True(t, terragruntOptions.FilesRead.Contains(expectedPath))
+}
+
+func TestMarkAsRead(t *testing.T) {
+	t.Parallel()
+
+	terragruntOptions := terragruntOptionsForTest(t, "/root/child/"+DefaultTerragruntConfigPath)
+	terragruntOptions.FilesRead = &options.FilesRead{}
+
+	actual, err := markAsRead([]string{"../common.yaml"}, nil, terragruntOptions)
+	require.NoError(t, err)
+	assert.Equal(t, "../common.yaml", actual)
+	assert.Equal(t, []string{"/root/common.yaml"}, terragruntOptions.FilesRead.Paths())
+
+	_, err = markAsRead([]string{}, nil, terragruntOptions)
+	assert.True(t, errors.IsError(err, WrongNumberOfParams{Func: "mark_as_read", Expected: "1", Actual: 0}))
+}
+
 func TestGetTerragruntSourceForModuleHappyPath(t *testing.T) {
 	t.Parallel()
 
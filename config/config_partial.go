@@ -25,6 +25,9 @@ const (
 	TerragruntFlags
 	TerragruntVersionConstraints
 	RemoteStateBlock
+	ParallelismBlock
+	NotificationBlock
+	CatalogBlock
 )
 
 // terragruntInclude is a struct that can be used to only decode the include block.
@@ -58,12 +61,21 @@ type terraformConfigSourceOnly struct {
 	Remain hcl.Body `hcl:",remain"`
 }
 
-// terragruntFlags is a struct that can be used to only decode the flag attributes (skip and prevent_destroy)
+// terragruntFlags is a struct that can be used to only decode the flag attributes (skip and prevent_destroy), along
+// with the iam_role, iam_command_role blocks, iam_assume_role_external_id, iam_web_identity_token,
+// google_impersonate_service_account, aws_profile, azure_auth block and workspace needed to read the outputs of a module
 type terragruntFlags struct {
-	IamRole        *string  `hcl:"iam_role,attr"`
-	PreventDestroy *bool    `hcl:"prevent_destroy,attr"`
-	Skip           *bool    `hcl:"skip,attr"`
-	Remain         hcl.Body `hcl:",remain"`
+	IamRole                         *cty.Value            `hcl:"iam_role,attr"`
+	IamCommandRoles                 []iamCommandRoleBlock `hcl:"iam_command_role,block"`
+	IamAssumeRoleExternalId         *string               `hcl:"iam_assume_role_external_id,attr"`
+	IamWebIdentityToken             *string               `hcl:"iam_web_identity_token,attr"`
+	GoogleImpersonateServiceAccount *string               `hcl:"google_impersonate_service_account,attr"`
+	AwsProfile                      *string               `hcl:"aws_profile,attr"`
+	AzureAuth                       *AzureAuthConfig      `hcl:"azure_auth,block"`
+	PreventDestroy                  *bool                 `hcl:"prevent_destroy,attr"`
+	Skip                            *bool                 `hcl:"skip,attr"`
+	Workspace                       *string               `hcl:"workspace,attr"`
+	Remain                          hcl.Body              `hcl:",remain"`
 }
 
 // terragruntVersionConstraints is a struct that can be used to only decode the attributes related to constraining the
@@ -87,6 +99,24 @@ type terragruntRemoteState struct {
 	Remain      hcl.Body               `hcl:",remain"`
 }
 
+// terragruntParallelism is a struct that can be used to only decode the parallelism block in the terragrunt config
+type terragruntParallelism struct {
+	Parallelism *ParallelismConfig `hcl:"parallelism,block"`
+	Remain      hcl.Body           `hcl:",remain"`
+}
+
+// terragruntCatalog is a struct that can be used to only decode the catalog block in the terragrunt config
+type terragruntCatalog struct {
+	Catalog *CatalogConfig `hcl:"catalog,block"`
+	Remain  hcl.Body       `hcl:",remain"`
+}
+
+// terragruntNotifications is a struct that can be used to only decode the notification blocks in the terragrunt config
+type terragruntNotifications struct {
+	Notifications []NotificationConfig `hcl:"notification,block"`
+	Remain        hcl.Body             `hcl:",remain"`
+}
+
 // DecodeBaseBlocks takes in a parsed HCL2 file and decodes the base blocks. Base blocks are blocks that should always
 // be decoded even in partial decoding, because they provide bindings that are necessary for parsing any block in the
 // file. Currently base blocks are:
@@ -134,17 +164,14 @@ func PartialParseConfigFile(
 	include *IncludeConfig,
 	decodeList []PartialDecodeSectionType,
 ) (*TerragruntConfig, error) {
-	configString, err := util.ReadFileAsString(filename)
-	if err != nil {
-		return nil, err
-	}
-
-	config, err := PartialParseConfigString(configString, terragruntOptions, include, filename, decodeList)
-	if err != nil {
-		return nil, err
-	}
+	return partialParseConfigFileWithCache(filename, terragruntOptions, include, decodeList, func() (*TerragruntConfig, error) {
+		parser, file, err := readAndParseHclFile(filename, terragruntOptions)
+		if err != nil {
+			return nil, err
+		}
 
-	return config, nil
+		return partialParseConfig(parser, file, terragruntOptions, include, filename, decodeList)
+	})
 }
 
 // ParitalParseConfigString partially parses and decodes the provided string. Which blocks/attributes to decode is
@@ -157,6 +184,9 @@ func PartialParseConfigFile(
 // - TerragruntVersionConstraints: Parses the attributes related to constraining terragrunt and terraform versions in
 //                                 the config.
 // - RemoteStateBlock: Parses the `remote_state` block in the config
+// - ParallelismBlock: Parses the `parallelism` block in the config
+// - NotificationBlock: Parses the `notification` blocks in the config
+// - CatalogBlock: Parses the `catalog` block in the config
 // Note that the following blocks are always decoded:
 // - locals
 // - include
@@ -176,6 +206,19 @@ func PartialParseConfigString(
 		return nil, err
 	}
 
+	return partialParseConfig(parser, file, terragruntOptions, includeFromChild, filename, decodeList)
+}
+
+// Decode the blocks/attributes in the given decode list from the Terragrunt config in the given HCL file. See
+// PartialParseConfigString for the valid values.
+func partialParseConfig(
+	parser *hclparse.Parser,
+	file *hcl.File,
+	terragruntOptions *options.TerragruntOptions,
+	includeFromChild *IncludeConfig,
+	filename string,
+	decodeList []PartialDecodeSectionType,
+) (*TerragruntConfig, error) {
 	// Decode just the Base blocks. See the function docs for DecodeBaseBlocks for more info on what base blocks are.
 	localsAsCty, terragruntInclude, includeForDecode, err := DecodeBaseBlocks(terragruntOptions, parser, file, filename, includeFromChild)
 	if err != nil {
@@ -256,7 +299,36 @@ func PartialParseConfigString(
 				output.Skip = *decoded.Skip
 			}
 			if decoded.IamRole != nil {
-				output.IamRole = *decoded.IamRole
+				iamRole, iamRoleChain, err := parseIamRole(*decoded.IamRole)
+				if err != nil {
+					return nil, err
+				}
+				output.IamRole = iamRole
+				output.IamRoleChain = iamRoleChain
+			}
+			iamCommandRoles, err := iamCommandRolesByName(decoded.IamCommandRoles)
+			if err != nil {
+				return nil, err
+			}
+			output.IamCommandRoles = iamCommandRoles
+			if decoded.IamAssumeRoleExternalId != nil {
+				output.IamAssumeRoleExternalId = *decoded.IamAssumeRoleExternalId
+			}
+			if decoded.IamWebIdentityToken != nil {
+				output.IamWebIdentityToken = *decoded.IamWebIdentityToken
+			}
+			if decoded.GoogleImpersonateServiceAccount != nil {
+				output.GoogleImpersonateServiceAccount = *decoded.GoogleImpersonateServiceAccount
+			}
+			if decoded.AwsProfile != nil {
+				output.AwsProfile = *decoded.AwsProfile
+			}
+			if err := decoded.AzureAuth.Validate(); err != nil {
+				return nil, err
+			}
+			output.AzureAuth = decoded.AzureAuth
+			if decoded.Workspace != nil {
+				output.Workspace = *decoded.Workspace
 			}
 
 		case TerragruntVersionConstraints:
@@ -276,8 +348,19 @@ func PartialParseConfigString(
 			}
 
 		case RemoteStateBlock:
+			// The remote_state block can use the outputs of dependencies, e.g. the KMS key to encrypt the state with,
+			// so retrieve them first, but only when they are used, as this can mean running terragrunt output.
+			remoteStateExtensions := contextExtensions
+			if remoteStateReferencesDependencies(file) {
+				retrievedOutputs, err := decodeAndRetrieveOutputs(file, filename, terragruntOptions, contextExtensions)
+				if err != nil {
+					return nil, err
+				}
+				remoteStateExtensions.DecodedDependencies = retrievedOutputs
+			}
+
 			decoded := terragruntRemoteState{}
-			err := decodeHcl(file, filename, &decoded, terragruntOptions, contextExtensions)
+			err := decodeHcl(file, filename, &decoded, terragruntOptions, remoteStateExtensions)
 			if err != nil {
 				return nil, err
 			}
@@ -289,6 +372,40 @@ func PartialParseConfigString(
 				output.RemoteState = remoteState
 			}
 
+		case ParallelismBlock:
+			decoded := terragruntParallelism{}
+			err := decodeHcl(file, filename, &decoded, terragruntOptions, contextExtensions)
+			if err != nil {
+				return nil, err
+			}
+			if err := decoded.Parallelism.Validate(); err != nil {
+				return nil, err
+			}
+			output.Parallelism = decoded.Parallelism
+
+		case NotificationBlock:
+			decoded := terragruntNotifications{}
+			err := decodeHcl(file, filename, &decoded, terragruntOptions, contextExtensions)
+			if err != nil {
+				return nil, err
+			}
+			notifications, err := notificationConfigsByName(decoded.Notifications)
+			if err != nil {
+				return nil, err
+			}
+			output.Notifications = notifications
+
+		case CatalogBlock:
+			decoded := terragruntCatalog{}
+			err := decodeHcl(file, filename, &decoded, terragruntOptions, contextExtensions)
+			if err != nil {
+				return nil, err
+			}
+			if err := decoded.Catalog.Validate(); err != nil {
+				return nil, err
+			}
+			output.Catalog = decoded.Catalog
+
 		default:
 			return nil, InvalidPartialBlockName{decode}
 		}
@@ -305,6 +422,27 @@ func PartialParseConfigString(
 	return &output, nil
 }
 
+// remoteStateReferencesDependencies returns true if any attribute of the remote_state block in the given file
+// references a dependency block, e.g. dependency.kms.outputs.key_arn.
+func remoteStateReferencesDependencies(file *hcl.File) bool {
+	content, _, _ := file.Body.PartialContent(&hcl.BodySchema{Blocks: []hcl.BlockHeaderSchema{{Type: "remote_state"}}})
+	if content == nil {
+		return false
+	}
+
+	for _, block := range content.Blocks {
+		attributes, _ := block.Body.JustAttributes()
+		for _, attribute := range attributes {
+			for _, traversal := range attribute.Expr.Variables() {
+				if traversal.RootName() == "dependency" {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
 func partialParseIncludedConfig(includedConfig *IncludeConfig, terragruntOptions *options.TerragruntOptions, decodeList []PartialDecodeSectionType) (*TerragruntConfig, error) {
 	if includedConfig.Path == "" {
 		return nil, errors.WithStackTrace(IncludedConfigMissingPath(terragruntOptions.TerragruntConfigPath))
@@ -316,6 +454,8 @@ func partialParseIncludedConfig(includedConfig *IncludeConfig, terragruntOptions
 		includePath = util.JoinPath(filepath.Dir(terragruntOptions.TerragruntConfigPath), includePath)
 	}
 
+	terragruntOptions.MarkFileAsRead(includePath)
+
 	return PartialParseConfigFile(
 		includePath,
 		terragruntOptions,
@@ -1,10 +1,17 @@
 package config
 
 import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
 	"testing"
 
+	"github.com/hashicorp/hcl/v2/hclparse"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+
+	"github.com/gruntwork-io/terragrunt/errors"
+	"github.com/gruntwork-io/terragrunt/notify"
 )
 
 func TestPartialParseResolvesLocals(t *testing.T) {
@@ -315,3 +322,238 @@ terraform {
 	require.NotNil(t, terragruntConfig.Terraform.Source)
 	assert.Equal(t, *terragruntConfig.Terraform.Source, "../../modules/app")
 }
+
+func TestPartialParseRemoteStateResolvesDependencyOutputs(t *testing.T) {
+	t.Parallel()
+
+	rootPath, err := ioutil.TempDir("", "partial-parse-remote-state")
+	require.NoError(t, err)
+	defer os.RemoveAll(rootPath)
+
+	require.NoError(t, os.MkdirAll(filepath.Join(rootPath, "kms"), os.ModePerm))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(rootPath, "kms", DefaultTerragruntConfigPath), []byte(""), 0644))
+
+	config := `
+dependency "kms" {
+  config_path  = "../kms"
+  skip_outputs = true
+  mock_outputs = {
+    key_arn = "arn:aws:kms:us-east-1:123456789012:key/foo"
+  }
+}
+
+remote_state {
+  backend = "s3"
+  config = {
+    bucket     = "my-bucket"
+    key        = "app/terraform.tfstate"
+    region     = "us-east-1"
+    kms_key_id = dependency.kms.outputs.key_arn
+  }
+}
+`
+
+	configPath := filepath.Join(rootPath, "app", DefaultTerragruntConfigPath)
+	terragruntConfig, err := PartialParseConfigString(config, mockOptionsForTestWithConfigPath(t, configPath), nil, configPath, []PartialDecodeSectionType{RemoteStateBlock})
+	require.NoError(t, err)
+
+	require.NotNil(t, terragruntConfig.RemoteState)
+	assert.Equal(t, "arn:aws:kms:us-east-1:123456789012:key/foo", terragruntConfig.RemoteState.Config["kms_key_id"])
+}
+
+func TestRemoteStateReferencesDependencies(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name     string
+		config   string
+		expected bool
+	}{
+		{
+			"no-remote-state",
+			`inputs = { vpc_id = dependency.vpc.outputs.vpc_id }`,
+			false,
+		},
+		{
+			"remote-state-without-dependencies",
+			`
+locals {
+  region = "us-east-1"
+}
+
+remote_state {
+  backend = "s3"
+  config = {
+    bucket = "my-bucket"
+    region = local.region
+  }
+}
+
+inputs = { vpc_id = dependency.vpc.outputs.vpc_id }
+`,
+			false,
+		},
+		{
+			"remote-state-with-dependencies",
+			`
+remote_state {
+  backend = "s3"
+  config = {
+    bucket     = "my-bucket"
+    kms_key_id = dependency.kms.outputs.key_arn
+  }
+}
+`,
+			true,
+		},
+	}
+
+	for _, testCase := range testCases {
+		// Save the testCase in local scope so all the t.Run calls don't end up with the last item in the list
+		testCase := testCase
+
+		t.Run(testCase.name, func(t *testing.T) {
+			t.Parallel()
+
+			file, err := parseHcl(hclparse.NewParser(), testCase.config, DefaultTerragruntConfigPath)
+			require.NoError(t, err)
+			assert.Equal(t, testCase.expected, remoteStateReferencesDependencies(file))
+		})
+	}
+}
+
+func TestPartialParseParallelismBlock(t *testing.T) {
+	t.Parallel()
+
+	config := `
+parallelism {
+  weight      = 2
+  group       = "databases"
+  group_limit = 1
+}
+`
+
+	terragruntConfig, err := PartialParseConfigString(config, mockOptionsForTest(t), nil, DefaultTerragruntConfigPath, []PartialDecodeSectionType{ParallelismBlock})
+	require.NoError(t, err)
+
+	require.NotNil(t, terragruntConfig.Parallelism)
+	assert.Equal(t, 2, *terragruntConfig.Parallelism.Weight)
+	assert.Nil(t, terragruntConfig.Parallelism.Exclusive)
+	assert.Equal(t, "databases", *terragruntConfig.Parallelism.Group)
+	assert.Equal(t, 1, *terragruntConfig.Parallelism.GroupLimit)
+}
+
+func TestPartialParseInvalidParallelismBlock(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name   string
+		config string
+	}{
+		{"zero weight", `parallelism { weight = 0 }`},
+		{"group limit without group", `parallelism { group_limit = 1 }`},
+		{"zero group limit", `parallelism {
+  group       = "databases"
+  group_limit = 0
+}`},
+	}
+
+	for _, testCase := range testCases {
+		// Save the testCase in local scope so all the t.Run calls don't end up with the last item in the list
+		testCase := testCase
+
+		t.Run(testCase.name, func(t *testing.T) {
+			t.Parallel()
+
+			_, err := PartialParseConfigString(testCase.config, mockOptionsForTest(t), nil, DefaultTerragruntConfigPath, []PartialDecodeSectionType{ParallelismBlock})
+			require.Error(t, err)
+			_, isInvalidParallelismConfig := errors.Unwrap(err).(InvalidParallelismConfig)
+			assert.True(t, isInvalidParallelismConfig, "Unexpected error: %v", err)
+		})
+	}
+}
+
+func TestPartialParseNotificationBlocks(t *testing.T) {
+	t.Parallel()
+
+	config := `
+notification "slack" {
+  url    = "https://hooks.slack.com/services/T0/B0/secret"
+  events = ["unit_failure", "run_complete"]
+}
+
+notification "ops" {
+  url      = "https://ops.example.com/hooks/terragrunt"
+  template = "{{ .Command }}: {{ .Event }}"
+}
+`
+
+	terragruntConfig, err := PartialParseConfigString(config, mockOptionsForTest(t), nil, DefaultTerragruntConfigPath, []PartialDecodeSectionType{NotificationBlock})
+	require.NoError(t, err)
+
+	require.Len(t, terragruntConfig.Notifications, 2)
+	slack := terragruntConfig.Notifications["slack"]
+	assert.Equal(t, "https://hooks.slack.com/services/T0/B0/secret", slack.Url)
+	assert.Equal(t, []string{"unit_failure", "run_complete"}, *slack.Events)
+	assert.Nil(t, slack.Template)
+	ops := terragruntConfig.Notifications["ops"]
+	assert.Nil(t, ops.Events)
+	assert.Equal(t, "{{ .Command }}: {{ .Event }}", *ops.Template)
+}
+
+func TestPartialParseInvalidNotificationBlock(t *testing.T) {
+	t.Parallel()
+
+	config := `
+notification "slack" {
+  url    = "https://hooks.slack.com/services/T0/B0/secret"
+  events = ["unit_success"]
+}
+`
+
+	_, err := PartialParseConfigString(config, mockOptionsForTest(t), nil, DefaultTerragruntConfigPath, []PartialDecodeSectionType{NotificationBlock})
+	require.Error(t, err)
+	_, isInvalidWebhook := errors.Unwrap(err).(notify.InvalidWebhook)
+	assert.True(t, isInvalidWebhook, "Unexpected error: %v", err)
+}
+
+func TestPartialParseCatalogBlock(t *testing.T) {
+	t.Parallel()
+
+	config := `
+catalog {
+  owner       = "group:platform"
+  description = "The VPC of prod"
+  tags        = ["network"]
+}
+`
+
+	terragruntConfig, err := PartialParseConfigString(config, mockOptionsForTest(t), nil, DefaultTerragruntConfigPath, []PartialDecodeSectionType{CatalogBlock})
+	require.NoError(t, err)
+
+	require.NotNil(t, terragruntConfig.Catalog)
+	assert.Equal(t, "group:platform", *terragruntConfig.Catalog.Owner)
+	assert.Equal(t, "The VPC of prod", *terragruntConfig.Catalog.Description)
+	assert.Equal(t, []string{"network"}, *terragruntConfig.Catalog.Tags)
+	assert.Nil(t, terragruntConfig.Catalog.Kind)
+
+	_, err = PartialParseConfigString(`catalog { kind = "System" }`, mockOptionsForTest(t), nil, DefaultTerragruntConfigPath, []PartialDecodeSectionType{CatalogBlock})
+	require.Error(t, err)
+	_, isInvalidCatalogConfig := errors.Unwrap(err).(InvalidCatalogConfig)
+	assert.True(t, isInvalidCatalogConfig, "Unexpected error: %v", err)
+}
+
+func TestMergeCatalog(t *testing.T) {
+	t.Parallel()
+
+	owner := "group:platform"
+	system := "network"
+	description := "The VPC of prod"
+	parent := &CatalogConfig{Owner: &owner, System: &system}
+	child := &CatalogConfig{Description: &description}
+
+	assert.Equal(t, &CatalogConfig{Owner: &owner, System: &system, Description: &description}, mergeCatalog(parent, child))
+	assert.Equal(t, &CatalogConfig{Owner: &owner, System: &system}, parent)
+	assert.Equal(t, parent, mergeCatalog(parent, nil))
+	assert.Equal(t, child, mergeCatalog(nil, child))
+}
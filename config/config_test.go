@@ -2,6 +2,7 @@ package config
 
 import (
 	"fmt"
+	"io/ioutil"
 	"os"
 	"path/filepath"
 	"testing"
@@ -237,6 +238,22 @@ func TestParseIamRole(t *testing.T) {
 	assert.Equal(t, "terragrunt-iam-role", terragruntConfig.IamRole)
 }
 
+func TestParseWorkspace(t *testing.T) {
+	t.Parallel()
+
+	config := `workspace = "prod"`
+
+	terragruntConfig, err := ParseConfigString(config, mockOptionsForTest(t), nil, DefaultTerragruntConfigPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Nil(t, terragruntConfig.RemoteState)
+	assert.Nil(t, terragruntConfig.Terraform)
+
+	assert.Equal(t, "prod", terragruntConfig.Workspace)
+}
+
 func TestParseIamAssumeRoleDuration(t *testing.T) {
 	t.Parallel()
 
@@ -255,6 +272,225 @@ func TestParseIamAssumeRoleDuration(t *testing.T) {
 	assert.Equal(t, int64(36000), *terragruntConfig.IamAssumeRoleDuration)
 }
 
+func TestParseIamAssumeRoleExternalId(t *testing.T) {
+	t.Parallel()
+
+	config := `
+iam_role                    = "arn:aws:iam::123456789012:role/terragrunt"
+iam_assume_role_external_id = "acme"
+`
+
+	terragruntConfig, err := ParseConfigString(config, mockOptionsForTest(t), nil, DefaultTerragruntConfigPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, "arn:aws:iam::123456789012:role/terragrunt", terragruntConfig.IamRole)
+	assert.Equal(t, "acme", terragruntConfig.IamAssumeRoleExternalId)
+}
+
+func TestParseIamRoleChain(t *testing.T) {
+	t.Parallel()
+
+	config := `
+iam_role = [
+  {
+    role_arn     = "arn:aws:iam::111111111111:role/hub"
+    session_name = "terragrunt-hub"
+    duration     = 900
+  },
+  "arn:aws:iam::222222222222:role/deploy",
+]
+`
+
+	terragruntConfig, err := ParseConfigString(config, mockOptionsForTest(t), nil, DefaultTerragruntConfigPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, "arn:aws:iam::222222222222:role/deploy", terragruntConfig.IamRole)
+	assert.Equal(t, []options.IamRoleHop{
+		{RoleArn: "arn:aws:iam::111111111111:role/hub", SessionName: "terragrunt-hub", DurationSeconds: 900},
+		{RoleArn: "arn:aws:iam::222222222222:role/deploy"},
+	}, terragruntConfig.IamRoleChain)
+}
+
+func TestParseIamRoleChainInvalid(t *testing.T) {
+	t.Parallel()
+
+	for _, config := range []string{
+		`iam_role = []`,
+		`iam_role = [{ session_name = "terragrunt" }]`,
+		`iam_role = [{ role_arn = "arn:aws:iam::111111111111:role/hub", name = "hub" }]`,
+		`iam_role = { role_arn = "arn:aws:iam::111111111111:role/hub" }`,
+	} {
+		_, err := ParseConfigString(config, mockOptionsForTest(t), nil, DefaultTerragruntConfigPath)
+		_, isInvalidIamRole := errors.Unwrap(err).(InvalidIamRole)
+		assert.True(t, isInvalidIamRole, "Unexpected error for %s: %v", config, err)
+	}
+}
+
+func TestParseIamCommandRole(t *testing.T) {
+	t.Parallel()
+
+	config := `
+iam_role = "arn:aws:iam::123456789012:role/deploy"
+
+iam_command_role "read_only" {
+  commands = ["plan", "validate"]
+  iam_role = "arn:aws:iam::123456789012:role/read-only"
+}
+
+iam_command_role "output" {
+  commands = ["output"]
+  iam_role = ["arn:aws:iam::111111111111:role/hub", "arn:aws:iam::123456789012:role/outputs"]
+}
+`
+
+	terragruntConfig, err := ParseConfigString(config, mockOptionsForTest(t), nil, DefaultTerragruntConfigPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	iamRole, iamRoleChain := terragruntConfig.IamRoleForCommand("plan")
+	assert.Equal(t, "arn:aws:iam::123456789012:role/read-only", iamRole)
+	assert.Nil(t, iamRoleChain)
+
+	iamRole, iamRoleChain = terragruntConfig.IamRoleForCommand("output")
+	assert.Equal(t, "arn:aws:iam::123456789012:role/outputs", iamRole)
+	assert.Len(t, iamRoleChain, 2)
+
+	iamRole, _ = terragruntConfig.IamRoleForCommand("apply")
+	assert.Equal(t, "arn:aws:iam::123456789012:role/deploy", iamRole)
+}
+
+func TestParseIamCommandRoleInvalid(t *testing.T) {
+	t.Parallel()
+
+	for _, config := range []string{
+		`
+iam_command_role "read_only" {
+  commands = []
+  iam_role = "arn:aws:iam::123456789012:role/read-only"
+}
+`,
+		`
+iam_command_role "read_only" {
+  commands = ["plan"]
+  iam_role = "arn:aws:iam::123456789012:role/read-only"
+}
+
+iam_command_role "plan" {
+  commands = ["plan"]
+  iam_role = "arn:aws:iam::123456789012:role/plan"
+}
+`,
+	} {
+		_, err := ParseConfigString(config, mockOptionsForTest(t), nil, DefaultTerragruntConfigPath)
+		_, isInvalid := errors.Unwrap(err).(InvalidIamCommandRole)
+		assert.True(t, isInvalid, "Unexpected error for %s: %v", config, err)
+	}
+}
+
+func TestParseIamWebIdentityToken(t *testing.T) {
+	t.Parallel()
+
+	config := `
+iam_role               = "arn:aws:iam::123456789012:role/terragrunt"
+iam_web_identity_token = "/var/run/secrets/eks.amazonaws.com/serviceaccount/token"
+`
+
+	terragruntConfig, err := ParseConfigString(config, mockOptionsForTest(t), nil, DefaultTerragruntConfigPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, "arn:aws:iam::123456789012:role/terragrunt", terragruntConfig.IamRole)
+	assert.Equal(t, "/var/run/secrets/eks.amazonaws.com/serviceaccount/token", terragruntConfig.IamWebIdentityToken)
+}
+
+func TestParseGoogleImpersonateServiceAccount(t *testing.T) {
+	t.Parallel()
+
+	config := `google_impersonate_service_account = "terragrunt@acme.iam.gserviceaccount.com"`
+
+	terragruntConfig, err := ParseConfigString(config, mockOptionsForTest(t), nil, DefaultTerragruntConfigPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, "terragrunt@acme.iam.gserviceaccount.com", terragruntConfig.GoogleImpersonateServiceAccount)
+}
+
+func TestParseAwsProfile(t *testing.T) {
+	t.Parallel()
+
+	config := `aws_profile = "deploy-prod"`
+
+	terragruntConfig, err := ParseConfigString(config, mockOptionsForTest(t), nil, DefaultTerragruntConfigPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, "deploy-prod", terragruntConfig.AwsProfile)
+}
+
+func TestParseAzureAuth(t *testing.T) {
+	t.Parallel()
+
+	config := `
+azure_auth {
+  client_id            = "00000000-0000-0000-0000-000000000001"
+  tenant_id            = "00000000-0000-0000-0000-000000000002"
+  use_oidc             = true
+  oidc_token_file_path = "/var/run/secrets/azure/tokens/azure-identity-token"
+}
+`
+
+	terragruntConfig, err := ParseConfigString(config, mockOptionsForTest(t), nil, DefaultTerragruntConfigPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, map[string]string{
+		"ARM_CLIENT_ID":            "00000000-0000-0000-0000-000000000001",
+		"ARM_TENANT_ID":            "00000000-0000-0000-0000-000000000002",
+		"ARM_USE_OIDC":             "true",
+		"ARM_OIDC_TOKEN_FILE_PATH": "/var/run/secrets/azure/tokens/azure-identity-token",
+	}, terragruntConfig.AzureAuth.Env())
+
+	// The env vars set in the environment take precedence over the block
+	terragruntOptions := mockOptionsForTest(t)
+	terragruntOptions.Env = map[string]string{"ARM_CLIENT_ID": "00000000-0000-0000-0000-000000000003"}
+	UpdateEnvWithAzureAuth(terragruntOptions, terragruntConfig.AzureAuth)
+	assert.Equal(t, "00000000-0000-0000-0000-000000000003", terragruntOptions.Env["ARM_CLIENT_ID"])
+	assert.Equal(t, "00000000-0000-0000-0000-000000000002", terragruntOptions.Env["ARM_TENANT_ID"])
+}
+
+func TestParseAzureAuthInvalid(t *testing.T) {
+	t.Parallel()
+
+	configs := []string{
+		`
+azure_auth {
+  use_msi  = true
+  use_oidc = true
+}
+`,
+		`
+azure_auth {
+  oidc_token_file_path = "/var/run/secrets/azure/tokens/azure-identity-token"
+}
+`,
+	}
+
+	for _, config := range configs {
+		_, err := ParseConfigString(config, mockOptionsForTest(t), nil, DefaultTerragruntConfigPath)
+		_, isInvalid := errors.Unwrap(err).(InvalidAzureAuthConfig)
+		assert.True(t, isInvalid, "Unexpected error for config %s: %v", config, err)
+	}
+}
+
 func TestParseTerragruntConfigDependenciesOnePath(t *testing.T) {
 	t.Parallel()
 
@@ -432,6 +668,25 @@ include {
 
 }
 
+func TestParseTerragruntConfigIncludeDerivesTFCWorkspaceName(t *testing.T) {
+	t.Parallel()
+
+	config := `
+include {
+	path = find_in_parent_folders()
+}
+`
+
+	opts := mockOptionsForTestWithConfigPath(t, "../test/fixture-tfc-derive-workspace-name/prod/vpc/"+DefaultTerragruntConfigPath)
+
+	terragruntConfig, err := ParseConfigString(config, opts, nil, opts.TerragruntConfigPath)
+	require.NoError(t, err)
+
+	if assert.NotNil(t, terragruntConfig.RemoteState) {
+		assert.Equal(t, map[string]interface{}{"name": "prod-vpc"}, terragruntConfig.RemoteState.Config["workspaces"])
+	}
+}
+
 func TestParseTerragruntConfigIncludeWithFindInParentFolders(t *testing.T) {
 	t.Parallel()
 
@@ -788,6 +1043,23 @@ func TestMergeConfigIntoIncludedConfig(t *testing.T) {
 			&TerragruntConfig{IamRole: "role1"},
 			&TerragruntConfig{IamRole: "role2"},
 		},
+		{
+			&TerragruntConfig{RegistryCredentials: map[string]RegistryCredentialsConfig{"registry.acme.com": {Host: "registry.acme.com", Token: "child"}}},
+			&TerragruntConfig{
+				RegistryCredentials: map[string]RegistryCredentialsConfig{
+					"registry.acme.com": {Host: "registry.acme.com", Token: "parent"},
+					"app.terraform.io":  {Host: "app.terraform.io", Token: "parent"},
+				},
+				RegistryCredentialsHelper: &RegistryCredentialsHelperConfig{Name: "credstore"},
+			},
+			&TerragruntConfig{
+				RegistryCredentials: map[string]RegistryCredentialsConfig{
+					"registry.acme.com": {Host: "registry.acme.com", Token: "child"},
+					"app.terraform.io":  {Host: "app.terraform.io", Token: "parent"},
+				},
+				RegistryCredentialsHelper: &RegistryCredentialsHelperConfig{Name: "credstore"},
+			},
+		},
 	}
 
 	for _, testCase := range testCases {
@@ -798,6 +1070,165 @@ func TestMergeConfigIntoIncludedConfig(t *testing.T) {
 	}
 }
 
+func TestParseTerragruntConfigRegistryCredentials(t *testing.T) {
+	t.Parallel()
+
+	config := `
+registry_credentials "registry.acme.com" {
+  token = "acme-token"
+}
+
+registry_credentials "app.terraform.io" {
+  token = "tfc-token"
+}
+
+registry_credentials_helper "credstore" {
+  args = ["--host=credstore.acme.com"]
+}
+`
+
+	terragruntConfig, err := ParseConfigString(config, mockOptionsForTest(t), nil, DefaultTerragruntConfigPath)
+	require.NoError(t, err)
+
+	assert.Equal(t, map[string]RegistryCredentialsConfig{
+		"registry.acme.com": {Host: "registry.acme.com", Token: "acme-token"},
+		"app.terraform.io":  {Host: "app.terraform.io", Token: "tfc-token"},
+	}, terragruntConfig.RegistryCredentials)
+	require.NotNil(t, terragruntConfig.RegistryCredentialsHelper)
+	assert.Equal(t, "credstore", terragruntConfig.RegistryCredentialsHelper.Name)
+	assert.Equal(t, []string{"--host=credstore.acme.com"}, *terragruntConfig.RegistryCredentialsHelper.Args)
+}
+
+func TestParseTerragruntConfigDuplicateRegistryCredentials(t *testing.T) {
+	t.Parallel()
+
+	config := `
+registry_credentials "registry.acme.com" {
+  token = "first"
+}
+
+registry_credentials "registry.acme.com" {
+  token = "second"
+}
+`
+
+	_, err := ParseConfigString(config, mockOptionsForTest(t), nil, DefaultTerragruntConfigPath)
+	require.Error(t, err)
+	_, isInvalidRegistryCredentials := errors.Unwrap(err).(InvalidRegistryCredentialsConfig)
+	assert.True(t, isInvalidRegistryCredentials, "Unexpected error: %v", err)
+}
+
+func TestParseTerragruntConfigVaultCredentials(t *testing.T) {
+	t.Parallel()
+
+	config := `
+vault_credentials "aws" {
+  role = "deploy"
+  ttl  = "1h"
+}
+
+vault_credentials "gcp" {
+  address      = "https://vault.acme.com"
+  mount        = "gcp-prod"
+  role         = "terraform"
+  account_type = "impersonated-account"
+}
+`
+
+	terragruntConfig, err := ParseConfigString(config, mockOptionsForTest(t), nil, DefaultTerragruntConfigPath)
+	require.NoError(t, err)
+
+	require.Len(t, terragruntConfig.VaultCredentials, 2)
+	aws := terragruntConfig.VaultCredentials[VaultEngineAws]
+	assert.Equal(t, "deploy", aws.Role)
+	assert.Equal(t, "1h", *aws.Ttl)
+	assert.Nil(t, aws.Mount)
+	gcp := terragruntConfig.VaultCredentials[VaultEngineGcp]
+	assert.Equal(t, "https://vault.acme.com", *gcp.Address)
+	assert.Equal(t, "gcp-prod", *gcp.Mount)
+	assert.Equal(t, "impersonated-account", *gcp.AccountType)
+}
+
+func TestParseTerragruntConfigInvalidVaultCredentials(t *testing.T) {
+	t.Parallel()
+
+	testCases := []string{
+		`vault_credentials "azure" { role = "deploy" }`,
+		`vault_credentials "aws" { role = "" }`,
+		`vault_credentials "gcp" {
+  role         = "terraform"
+  account_type = "service-account"
+}`,
+		`vault_credentials "gcp" {
+  role = "terraform"
+  ttl  = "1h"
+}`,
+	}
+
+	for _, testCase := range testCases {
+		_, err := ParseConfigString(testCase, mockOptionsForTest(t), nil, DefaultTerragruntConfigPath)
+		require.Error(t, err, testCase)
+		_, isInvalidVaultCredentials := errors.Unwrap(err).(InvalidVaultCredentialsConfig)
+		assert.True(t, isInvalidVaultCredentials, "Unexpected error for %s: %v", testCase, err)
+	}
+}
+
+func TestParseTerragruntConfigOidcCredentials(t *testing.T) {
+	t.Parallel()
+
+	config := `
+oidc_credentials "aws" {
+  role_arn         = "arn:aws:iam::123456789012:role/ci"
+  session_duration = 7200
+}
+
+oidc_credentials "gcp" {
+  workload_identity_provider = "projects/123/locations/global/workloadIdentityPools/ci/providers/github"
+  service_account            = "terraform@acme.iam.gserviceaccount.com"
+}
+
+oidc_credentials "azure" {
+  client_id     = "00000000-0000-0000-0000-000000000001"
+  tenant_id     = "00000000-0000-0000-0000-000000000002"
+  token_env_var = "AZURE_OIDC_TOKEN"
+}
+`
+
+	terragruntConfig, err := ParseConfigString(config, mockOptionsForTest(t), nil, DefaultTerragruntConfigPath)
+	require.NoError(t, err)
+
+	require.Len(t, terragruntConfig.OidcCredentials, 3)
+	aws := terragruntConfig.OidcCredentials[OidcCloudAws]
+	assert.Equal(t, "arn:aws:iam::123456789012:role/ci", *aws.RoleArn)
+	assert.Equal(t, int64(7200), *aws.SessionDuration)
+	assert.Nil(t, aws.Audience)
+	gcp := terragruntConfig.OidcCredentials[OidcCloudGcp]
+	assert.Equal(t, "terraform@acme.iam.gserviceaccount.com", *gcp.ServiceAccount)
+	azure := terragruntConfig.OidcCredentials[OidcCloudAzure]
+	assert.Equal(t, "AZURE_OIDC_TOKEN", *azure.TokenEnvVar)
+}
+
+func TestParseTerragruntConfigInvalidOidcCredentials(t *testing.T) {
+	t.Parallel()
+
+	testCases := []string{
+		`oidc_credentials "oci" { audience = "oci" }`,
+		`oidc_credentials "aws" { region = "eu-west-1" }`,
+		`oidc_credentials "azure" { client_id = "00000000-0000-0000-0000-000000000001" }`,
+		`oidc_credentials "gcp" {
+  workload_identity_provider = "projects/123/locations/global/workloadIdentityPools/ci/providers/github"
+  role_arn                   = "arn:aws:iam::123456789012:role/ci"
+}`,
+	}
+
+	for _, testCase := range testCases {
+		_, err := ParseConfigString(testCase, mockOptionsForTest(t), nil, DefaultTerragruntConfigPath)
+		require.Error(t, err, testCase)
+		_, isInvalidOidcCredentials := errors.Unwrap(err).(InvalidOidcCredentialsConfig)
+		assert.True(t, isInvalidOidcCredentials, "Unexpected error for %s: %v", testCase, err)
+	}
+}
+
 func TestParseTerragruntConfigTerraformNoSource(t *testing.T) {
 	t.Parallel()
 
@@ -1194,6 +1625,35 @@ func TestFindConfigFilesIgnoresDownloadDir(t *testing.T) {
 	assert.Equal(t, expected, actual)
 }
 
+func TestFindConfigFilesInPathKeepsWalkOrder(t *testing.T) {
+	t.Parallel()
+
+	rootDir, err := ioutil.TempDir("", "find-config-files")
+	require.NoError(t, err)
+	defer os.RemoveAll(rootDir)
+
+	// "a-c" sorts before "a/b" as a string, but filepath.Walk visits everything in "a" first
+	for _, dir := range []string{"a", "a/b", "a/b/c", "a-c", "b", "b/.terragrunt-cache/x", "c/d"} {
+		require.NoError(t, os.MkdirAll(filepath.Join(rootDir, dir), 0755))
+		require.NoError(t, ioutil.WriteFile(filepath.Join(rootDir, dir, DefaultTerragruntConfigPath), []byte(""), 0644))
+	}
+
+	expected := []string{}
+	for _, dir := range []string{"a", "a/b", "a/b/c", "a-c", "b", "c/d"} {
+		expected = append(expected, filepath.Join(rootDir, dir, DefaultTerragruntConfigPath))
+	}
+
+	for _, parallelism := range []int{1, options.DEFAULT_PARALLELISM} {
+		terragruntOptions, err := options.NewTerragruntOptionsForTest(filepath.Join(rootDir, DefaultTerragruntConfigPath))
+		require.NoError(t, err)
+		terragruntOptions.Parallelism = parallelism
+
+		actual, err := FindConfigFilesInPath(rootDir, terragruntOptions)
+		require.NoError(t, err)
+		assert.Equal(t, expected, actual, "With parallelism %d", parallelism)
+	}
+}
+
 func mockOptionsForTestWithConfigPath(t *testing.T, configPath string) *options.TerragruntOptions {
 	opts, err := options.NewTerragruntOptionsForTest(configPath)
 	if err != nil {
@@ -1377,3 +1837,77 @@ func TestModuleDependenciesMerge(t *testing.T) {
 func ptr(str string) *string {
 	return &str
 }
+
+func TestAdjustSourceWithMap(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name      string
+		sourceMap map[string]string
+		source    string
+		expected  string
+	}{
+		{
+			"no map",
+			map[string]string{},
+			"git::ssh://git@github.com/org/modules.git//vpc?ref=v1.0.0",
+			"git::ssh://git@github.com/org/modules.git//vpc?ref=v1.0.0",
+		},
+		{
+			"literal match",
+			map[string]string{"git::ssh://git@github.com/org/modules.git": "/home/me/modules"},
+			"git::ssh://git@github.com/org/modules.git//vpc?ref=v1.0.0",
+			"/home/me/modules//vpc",
+		},
+		{
+			"normalized match",
+			map[string]string{"github.com/org/modules": "/home/me/modules"},
+			"git::ssh://git@github.com/org/modules.git//vpc?ref=v1.0.0",
+			"/home/me/modules//vpc",
+		},
+		{
+			"prefix match",
+			map[string]string{"github.com/org": "/home/me/repos"},
+			"git::https://github.com/org/modules.git//vpc?ref=v1.0.0",
+			"/home/me/repos/modules//vpc",
+		},
+		{
+			"prefix match without subdir",
+			map[string]string{"github.com/org": "/home/me/repos"},
+			"git::https://github.com/org/vpc.git?ref=v1.0.0",
+			"/home/me/repos/vpc",
+		},
+		{
+			"longest prefix wins",
+			map[string]string{"github.com/org": "/home/me/repos", "github.com/org/modules": "/home/me/modules"},
+			"git::https://github.com/org/modules.git//vpc?ref=v1.0.0",
+			"/home/me/modules//vpc",
+		},
+		{
+			"prefix must end at a path boundary",
+			map[string]string{"github.com/org/mod": "/home/me/mod"},
+			"git::https://github.com/org/modules.git//vpc?ref=v1.0.0",
+			"git::https://github.com/org/modules.git//vpc?ref=v1.0.0",
+		},
+		{
+			"no match",
+			map[string]string{"github.com/other": "/home/me/other"},
+			"git::https://github.com/org/modules.git//vpc?ref=v1.0.0",
+			"git::https://github.com/org/modules.git//vpc?ref=v1.0.0",
+		},
+	}
+
+	for _, testCase := range testCases {
+		// Capture range variable so that it is brought into the scope within the for loop, so that it is stable even
+		// when subtests are run in parallel.
+		testCase := testCase
+
+		t.Run(testCase.name, func(t *testing.T) {
+			t.Parallel()
+
+			actual, err := adjustSourceWithMap(testCase.sourceMap, testCase.source, "/some/module")
+			require.NoError(t, err)
+			assert.Equal(t, testCase.expected, actual)
+		})
+	}
+}
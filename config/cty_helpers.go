@@ -113,6 +113,22 @@ func parseCtyValueToMap(value cty.Value) (map[string]interface{}, error) {
 	return ctyJsonOutput.Value, nil
 }
 
+// replaceUnknownValuesWithNull replaces all the unknown values nested in the given value with null values of the same
+// type, so that it can be converted to JSON.
+func replaceUnknownValuesWithNull(value cty.Value) (cty.Value, error) {
+	if value.IsWhollyKnown() {
+		return value, nil
+	}
+
+	knownValue, err := cty.Transform(value, func(path cty.Path, nestedValue cty.Value) (cty.Value, error) {
+		if !nestedValue.IsKnown() {
+			return cty.NullVal(nestedValue.Type()), nil
+		}
+		return nestedValue, nil
+	})
+	return knownValue, errors.WithStackTrace(err)
+}
+
 // When you convert a cty value to JSON, if any of that types are not yet known (i.e., are labeled as
 // DynamicPseudoType), cty's Marshall method will write the type information to a type field and the actual value to
 // a value field. This struct is used to capture that information so when we parse the JSON back into a Go struct, we
@@ -0,0 +1,146 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/gruntwork-io/terragrunt/errors"
+	"github.com/gruntwork-io/terragrunt/util"
+	"github.com/hashicorp/hcl/v2/gohcl"
+	"github.com/hashicorp/hcl/v2/hclparse"
+)
+
+// The environment variable that can be used to point Terragrunt at a specific defaults file. Setting it to an empty
+// string is not the same as leaving it unset: it disables the defaults file lookup entirely.
+const DefaultsFileEnvVar = "TERRAGRUNT_DEFAULTS_FILE"
+
+// The name of the user level defaults file, relative to the home directory of the current user.
+const UserDefaultsFileName = ".terragrunt.hcl"
+
+// The path of the system wide defaults file, shared by all users on the machine.
+const SystemDefaultsFilePath = "/etc/terragrunt/config.hcl"
+
+// DefaultsFile represents the settings that can be configured in the user or system wide defaults file. These settings
+// apply across all repos and have the lowest precedence: anything set in the Terragrunt configuration of a module,
+// the environment or the CLI will override them.
+type DefaultsFile struct {
+	DownloadDir       *string           `hcl:"download_dir,attr"`
+	TerraformBinary   *string           `hcl:"terraform_binary,attr"`
+	LogLevel          *string           `hcl:"log_level,attr"`
+	Parallelism       *int              `hcl:"parallelism,attr"`
+	SourceMap         map[string]string `hcl:"source_map,optional"`
+	SourceMirror      map[string]string `hcl:"source_mirror,optional"`
+	ProviderCache     *bool             `hcl:"provider_cache,attr"`
+	ProviderCacheDir  *string           `hcl:"provider_cache_dir,attr"`
+	TelemetryEndpoint *string           `hcl:"telemetry_endpoint,attr"`
+
+	// The path the defaults were read from, for logging purposes
+	Path string
+}
+
+func (defaults *DefaultsFile) String() string {
+	return fmt.Sprintf("DefaultsFile{Path = %s}", defaults.Path)
+}
+
+// FindDefaultsFile returns the path of the defaults file that should be used, or an empty string if there is none. The
+// lookup order is:
+//
+// 1. The path in the TERRAGRUNT_DEFAULTS_FILE environment variable, if set.
+// 2. ~/.terragrunt.hcl
+// 3. /etc/terragrunt/config.hcl
+func FindDefaultsFile() (string, error) {
+	if path, isSet := os.LookupEnv(DefaultsFileEnvVar); isSet {
+		if path == "" {
+			return "", nil
+		}
+		if !util.FileExists(path) {
+			return "", errors.WithStackTrace(DefaultsFileNotFound(path))
+		}
+		return path, nil
+	}
+
+	homeDir, err := os.UserHomeDir()
+	if err == nil {
+		userDefaultsFile := filepath.Join(homeDir, UserDefaultsFileName)
+		if util.FileExists(userDefaultsFile) && !util.IsDir(userDefaultsFile) {
+			return userDefaultsFile, nil
+		}
+	}
+
+	if util.FileExists(SystemDefaultsFilePath) {
+		return SystemDefaultsFilePath, nil
+	}
+
+	return "", nil
+}
+
+// ReadDefaultsFile finds and parses the defaults file. If there is no defaults file, this returns an empty
+// DefaultsFile, so callers don't need to nil check the result.
+func ReadDefaultsFile() (*DefaultsFile, error) {
+	path, err := FindDefaultsFile()
+	if err != nil {
+		return nil, err
+	}
+	if path == "" {
+		return &DefaultsFile{}, nil
+	}
+	return ParseDefaultsFile(path)
+}
+
+// ParseDefaultsFile parses the defaults file at the given path. The defaults file is loaded before any module
+// configuration, so only literal values are supported: functions and variable references are not available.
+func ParseDefaultsFile(path string) (*DefaultsFile, error) {
+	contents, err := util.ReadFileAsString(path)
+	if err != nil {
+		return nil, err
+	}
+	return ParseDefaultsString(contents, path)
+}
+
+// ParseDefaultsString parses the given string as the contents of a defaults file located at filename.
+func ParseDefaultsString(contents string, filename string) (defaults *DefaultsFile, err error) {
+	// The HCL2 parser and especially cty conversions will panic in many types of errors, so we have to recover from
+	// those panics here and convert them to normal errors
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			err = errors.WithStackTrace(PanicWhileParsingConfig{RecoveredValue: recovered, ConfigFile: filename})
+		}
+	}()
+
+	file, err := parseHcl(hclparse.NewParser(), contents, filename)
+	if err != nil {
+		return nil, errors.WithStackTrace(err)
+	}
+
+	defaults = &DefaultsFile{}
+	decodeDiagnostics := gohcl.DecodeBody(file.Body, nil, defaults)
+	if decodeDiagnostics != nil && decodeDiagnostics.HasErrors() {
+		return nil, errors.WithStackTrace(decodeDiagnostics)
+	}
+
+	if defaults.Parallelism != nil && *defaults.Parallelism < 1 {
+		return nil, errors.WithStackTrace(InvalidDefaultsFileValue{Path: filename, Name: "parallelism", Value: fmt.Sprintf("%d", *defaults.Parallelism)})
+	}
+
+	defaults.Path = filename
+	return defaults, nil
+}
+
+// Custom error types
+
+type DefaultsFileNotFound string
+
+func (path DefaultsFileNotFound) Error() string {
+	return fmt.Sprintf("The defaults file %s set via %s does not exist.", string(path), DefaultsFileEnvVar)
+}
+
+type InvalidDefaultsFileValue struct {
+	Path  string
+	Name  string
+	Value string
+}
+
+func (err InvalidDefaultsFileValue) Error() string {
+	return fmt.Sprintf("Invalid value %s for %s in defaults file %s", err.Value, err.Name, err.Path)
+}
@@ -0,0 +1,95 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/gruntwork-io/terragrunt/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseDefaultsString(t *testing.T) {
+	t.Parallel()
+
+	contents := `
+download_dir     = "/var/cache/terragrunt"
+terraform_binary = "/usr/local/bin/terraform"
+log_level        = "debug"
+parallelism      = 4
+source_map = {
+  "git::ssh://git@github.com/org/modules.git" = "/opt/modules"
+}
+source_mirror = {
+  "github.com/org" = "s3::https://s3.amazonaws.com/org-modules"
+}
+provider_cache     = true
+provider_cache_dir = "/var/cache/terragrunt-providers"
+telemetry_endpoint = "http://localhost:4318"
+`
+
+	defaults, err := ParseDefaultsString(contents, "/etc/terragrunt/config.hcl")
+	require.NoError(t, err)
+
+	require.NotNil(t, defaults.DownloadDir)
+	assert.Equal(t, "/var/cache/terragrunt", *defaults.DownloadDir)
+	require.NotNil(t, defaults.TerraformBinary)
+	assert.Equal(t, "/usr/local/bin/terraform", *defaults.TerraformBinary)
+	require.NotNil(t, defaults.LogLevel)
+	assert.Equal(t, "debug", *defaults.LogLevel)
+	require.NotNil(t, defaults.Parallelism)
+	assert.Equal(t, 4, *defaults.Parallelism)
+	assert.Equal(t, map[string]string{"git::ssh://git@github.com/org/modules.git": "/opt/modules"}, defaults.SourceMap)
+	assert.Equal(t, map[string]string{"github.com/org": "s3::https://s3.amazonaws.com/org-modules"}, defaults.SourceMirror)
+	require.NotNil(t, defaults.ProviderCache)
+	assert.True(t, *defaults.ProviderCache)
+	require.NotNil(t, defaults.ProviderCacheDir)
+	assert.Equal(t, "/var/cache/terragrunt-providers", *defaults.ProviderCacheDir)
+	require.NotNil(t, defaults.TelemetryEndpoint)
+	assert.Equal(t, "http://localhost:4318", *defaults.TelemetryEndpoint)
+	assert.Equal(t, "/etc/terragrunt/config.hcl", defaults.Path)
+}
+
+func TestParseDefaultsStringEmpty(t *testing.T) {
+	t.Parallel()
+
+	defaults, err := ParseDefaultsString("", "/etc/terragrunt/config.hcl")
+	require.NoError(t, err)
+
+	assert.Nil(t, defaults.DownloadDir)
+	assert.Nil(t, defaults.TerraformBinary)
+	assert.Nil(t, defaults.LogLevel)
+	assert.Nil(t, defaults.Parallelism)
+	assert.Nil(t, defaults.SourceMap)
+	assert.Nil(t, defaults.SourceMirror)
+	assert.Nil(t, defaults.ProviderCache)
+	assert.Nil(t, defaults.ProviderCacheDir)
+	assert.Nil(t, defaults.TelemetryEndpoint)
+}
+
+func TestParseDefaultsStringDownloadDirTemplate(t *testing.T) {
+	t.Parallel()
+
+	// The template is escaped, so that it's kept as is, and rendered for each module
+	defaults, err := ParseDefaultsString(`download_dir = "/dev/shm/$${get_env(\"BRANCH\", \"main\")}"`, "/etc/terragrunt/config.hcl")
+	require.NoError(t, err)
+
+	require.NotNil(t, defaults.DownloadDir)
+	assert.Equal(t, `/dev/shm/${get_env("BRANCH", "main")}`, *defaults.DownloadDir)
+	assert.True(t, IsDownloadDirTemplate(*defaults.DownloadDir))
+}
+
+func TestParseDefaultsStringUnknownAttribute(t *testing.T) {
+	t.Parallel()
+
+	_, err := ParseDefaultsString(`remote_state = {}`, "/etc/terragrunt/config.hcl")
+	assert.Error(t, err)
+}
+
+func TestParseDefaultsStringInvalidParallelism(t *testing.T) {
+	t.Parallel()
+
+	_, err := ParseDefaultsString(`parallelism = 0`, "/etc/terragrunt/config.hcl")
+	require.Error(t, err)
+	_, isInvalidValue := errors.Unwrap(err).(InvalidDefaultsFileValue)
+	assert.True(t, isInvalidValue)
+}
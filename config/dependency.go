@@ -11,6 +11,7 @@ import (
 	"path/filepath"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/hashicorp/go-getter"
 	"github.com/hashicorp/hcl/v2"
@@ -64,6 +65,10 @@ var jsonOutputCache = sync.Map{}
 // output running for a given dependent config. We use sync.Map to ensure atomic updates during concurrent access.
 var outputLocks = sync.Map{}
 
+// The name of the file, in the download dir of a module, that its outputs are cached in when
+// --terragrunt-dependency-output-cache-ttl is set
+const dependencyOutputCacheFile = "dependency-outputs.json"
+
 // Decode the dependency blocks from the file, and then retrieve all the outputs from the remote state. Then encode the
 // resulting map as a cty.Value object.
 // TODO: In the future, consider allowing importing dependency blocks from included config
@@ -85,6 +90,55 @@ func decodeAndRetrieveOutputs(
 	return dependencyBlocksToCtyValue(decodedDependency.Dependencies, terragruntOptions)
 }
 
+// TERRAFORM_COMMANDS_THAT_DO_NOT_NEED_OUTPUTS are the commands that never use the values of the inputs, so the outputs of
+// the dependencies are not needed to run them.
+var TERRAFORM_COMMANDS_THAT_DO_NOT_NEED_OUTPUTS = []string{
+	"fmt",
+	"graph-dependencies",
+	"providers",
+	"validate",
+}
+
+// Retrieving the outputs of the dependencies runs terraform in each of them, which is what makes parsing a config with
+// many dependencies slow. When running one of the commands that don't need the outputs, this decodes the config with the
+// outputs of all the dependencies set to unknown values instead, which are nulled out in the inputs. The same goes for
+// prefetching, which only downloads the sources, modules and providers. Returns nil if the command needs the outputs, or
+// if the config can't be decoded without them, e.g. because they're used in the remote_state or generate blocks, in
+// which case the outputs should be retrieved as usual.
+func decodeWithUnknownDependencyOutputs(
+	file *hcl.File,
+	filename string,
+	terragruntOptions *options.TerragruntOptions,
+	extensions EvalContextExtensions,
+) *TerragruntConfig {
+	if !terragruntOptions.PrefetchOnly && !util.ListContainsElement(TERRAFORM_COMMANDS_THAT_DO_NOT_NEED_OUTPUTS, terragruntOptions.OriginalTerraformCommand) {
+		return nil
+	}
+
+	decodedDependency := terragruntDependency{}
+	if err := decodeHcl(file, filename, &decodedDependency, terragruntOptions, extensions); err != nil {
+		return nil
+	}
+	if len(decodedDependency.Dependencies) == 0 {
+		return nil
+	}
+
+	dependencyMap := map[string]cty.Value{}
+	for _, dependencyConfig := range decodedDependency.Dependencies {
+		dependencyMap[dependencyConfig.Name] = cty.ObjectVal(map[string]cty.Value{"outputs": cty.DynamicVal})
+	}
+	unknownOutputs := cty.ObjectVal(dependencyMap)
+	extensions.DecodedDependencies = &unknownOutputs
+
+	config, err := decodeAndConvertTerragruntConfig(file, filename, terragruntOptions, extensions)
+	if err != nil {
+		terragruntOptions.Logger.Debugf("Could not decode %s without the outputs of its dependencies, so retrieving them: %v", filename, err)
+		return nil
+	}
+	terragruntOptions.Logger.Debugf("Not retrieving the outputs of the dependencies of %s, as they're not needed by %s", filename, terragruntOptions.OriginalTerraformCommand)
+	return config
+}
+
 // Convert the list of parsed Dependency blocks into a list of module dependencies. Each output block should
 // become a dependency of the current config, since that module has to be applied before we can read the output.
 func dependencyBlocksToModuleDependencies(decodedDependencyBlocks []Dependency) *ModuleDependencies {
@@ -186,6 +240,14 @@ func dependencyBlocksToCtyValue(dependencyConfigs []Dependency, terragruntOption
 	lock := sync.Mutex{}
 	dependencyErrGroup, _ := errgroup.WithContext(context.Background())
 
+	// Fetching the outputs runs terraform for each dependency, so limit how many run at once. The limit is per module,
+	// rather than global, as fetching the outputs of a dependency can in turn fetch the outputs of its own dependencies.
+	parallelism := terragruntOptions.DependencyFetchParallelism
+	if parallelism < 1 {
+		parallelism = options.DEFAULT_DEPENDENCY_FETCH_PARALLELISM
+	}
+	fetchSlots := make(chan struct{}, parallelism)
+
 	for _, dependencyConfig := range dependencyConfigs {
 		dependencyConfig := dependencyConfig // https://golang.org/doc/faq#closures_and_goroutines
 		dependencyErrGroup.Go(func() error {
@@ -194,11 +256,16 @@ func dependencyBlocksToCtyValue(dependencyConfigs []Dependency, terragruntOption
 			dependencyEncodingMap := map[string]cty.Value{}
 
 			// Encode the outputs and nest under `outputs` attribute if we should get the outputs or the `mock_outputs`
-			if err := dependencyConfig.setRenderedOutputs(terragruntOptions); err != nil {
+			fetchSlots <- struct{}{}
+			err := dependencyConfig.setRenderedOutputs(terragruntOptions)
+			<-fetchSlots
+			if err != nil {
 				return err
 			}
 			if dependencyConfig.RenderedOutputs != nil {
+				lock.Lock()
 				paths = append(paths, dependencyConfig.ConfigPath)
+				lock.Unlock()
 				dependencyEncodingMap["outputs"] = *dependencyConfig.RenderedOutputs
 			}
 
@@ -330,15 +397,79 @@ func getOutputJsonWithCaching(targetConfig string, terragruntOptions *options.Te
 		return rawJsonBytes.([]byte), nil
 	}
 
+	// Look up if a previous run has cached the output on disk, and it hasn't expired yet
+	if cachedJsonBytes, hasCached := readDependencyOutputCache(targetConfig, terragruntOptions); hasCached {
+		terragruntOptions.Logger.Debugf("Using the output of %s cached on disk.", targetConfig)
+		jsonOutputCache.Store(targetConfig, cachedJsonBytes)
+		return cachedJsonBytes, nil
+	}
+
 	// Cache miss, so look up the output and store in cache
 	newJsonBytes, err := getTerragruntOutputJson(terragruntOptions, targetConfig)
 	if err != nil {
 		return nil, err
 	}
 	jsonOutputCache.Store(targetConfig, newJsonBytes)
+	writeDependencyOutputCache(targetConfig, newJsonBytes, terragruntOptions)
 	return newJsonBytes, nil
 }
 
+// Return the path of the file the outputs of the given config are cached in on disk
+func dependencyOutputCachePath(targetConfig string) (string, error) {
+	_, downloadDir, err := options.DefaultWorkingAndDownloadDirs(targetConfig)
+	if err != nil {
+		return "", err
+	}
+	return util.JoinPath(downloadDir, dependencyOutputCacheFile), nil
+}
+
+// Read the outputs of the given config cached on disk, if caching on disk is enabled and the cached outputs are younger
+// than the TTL.
+func readDependencyOutputCache(targetConfig string, terragruntOptions *options.TerragruntOptions) ([]byte, bool) {
+	if terragruntOptions.DependencyOutputCacheTTL <= 0 {
+		return nil, false
+	}
+
+	cachePath, err := dependencyOutputCachePath(targetConfig)
+	if err != nil {
+		return nil, false
+	}
+	cacheInfo, err := os.Stat(cachePath)
+	if err != nil {
+		return nil, false
+	}
+	if time.Since(cacheInfo.ModTime()) > time.Duration(terragruntOptions.DependencyOutputCacheTTL)*time.Second {
+		terragruntOptions.Logger.Debugf("The output of %s cached on disk has expired.", targetConfig)
+		return nil, false
+	}
+
+	jsonBytes, err := ioutil.ReadFile(cachePath)
+	if err != nil {
+		return nil, false
+	}
+	return jsonBytes, true
+}
+
+// Cache the outputs of the given config on disk, if caching on disk is enabled. The outputs can contain sensitive
+// values, so the file is only readable by the current user. Failing to cache the outputs is not fatal, as they can
+// always be retrieved again.
+func writeDependencyOutputCache(targetConfig string, jsonBytes []byte, terragruntOptions *options.TerragruntOptions) {
+	if terragruntOptions.DependencyOutputCacheTTL <= 0 {
+		return
+	}
+
+	cachePath, err := dependencyOutputCachePath(targetConfig)
+	if err == nil {
+		err = util.EnsureDirectory(filepath.Dir(cachePath))
+	}
+	if err == nil {
+		err = ioutil.WriteFile(cachePath, jsonBytes, 0600)
+	}
+	if err != nil {
+		terragruntOptions.Logger.Warnf("Could not cache the output of %s on disk: %v", targetConfig, err)
+	}
+}
+
 // Whenever executing a dependency module, we clone the original options, and reset:
 //
 // - The config path to the dependency module's config
@@ -402,20 +533,27 @@ func cloneTerragruntOptionsForDependencyOutput(terragruntOptions *options.Terrag
 // Retrieve the outputs from the terraform state in the target configuration. This attempts to optimize the output
 // retrieval if the following conditions are true:
 // - State backends are managed with a `remote_state` block.
-// - The `remote_state` block does not depend on any `dependency` outputs.
+// - Dependency optimization is not disabled on the `remote_state` block.
 // If these conditions are met, terragrunt can optimize the retrieval to avoid recursively retrieving dependency outputs
-// by directly pulling down the state file. Otherwise, terragrunt will fallback to running `terragrunt output` on the
-// target module.
-func getTerragruntOutputJson(terragruntOptions *options.TerragruntOptions, targetConfig string) ([]byte, error) {
+// by directly pulling down the state file. Only the outputs of the dependencies the `remote_state` block itself uses,
+// if any, are retrieved. Otherwise, terragrunt will fallback to running `terragrunt output` on the target module.
+func getTerragruntOutputJson(terragruntOptions *options.TerragruntOptions, targetConfig string) (outputJson []byte, finalErr error) {
+	span := terragruntOptions.TelemetrySpan.StartChild("dependency_outputs", map[string]string{
+		"terragrunt.config_path":     terragruntOptions.TerragruntConfigPath,
+		"terragrunt.dependency_path": targetConfig,
+	})
+	defer func() { span.End(finalErr) }()
+
 	// Make a copy of the terragruntOptions so that we can reuse the same execution environment, but in the context of
 	// the target config.
 	targetTGOptions, err := cloneTerragruntOptionsForDependencyOutput(terragruntOptions, targetConfig)
 	if err != nil {
 		return nil, err
 	}
+	targetTGOptions.TelemetrySpan = span
 
-	// First attempt to parse the `remote_state` blocks without parsing/getting dependency outputs. If this is possible,
-	// proceed to routine that fetches remote state directly. Otherwise, fallback to calling `terragrunt output`
+	// First attempt to parse the `remote_state` blocks, getting only the dependency outputs they use. If this is
+	// possible, proceed to routine that fetches remote state directly. Otherwise, fallback to calling `terragrunt output`
 	// directly.
 	remoteStateTGConfig, err := PartialParseConfigFile(targetConfig, targetTGOptions, nil, []PartialDecodeSectionType{RemoteStateBlock, TerragruntFlags})
 	if err != nil || !canGetRemoteState(remoteStateTGConfig.RemoteState) {
@@ -424,6 +562,16 @@ func getTerragruntOutputJson(terragruntOptions *options.TerragruntOptions, targe
 		return runTerragruntOutputJson(targetTGOptions, targetConfig)
 	}
 
+	// If requested, read the outputs straight from the state object in the backend, skipping terraform altogether.
+	// This is only possible for some backends, so fall back to running terraform for the others.
+	if terragruntOptions.FetchDependencyOutputFromState && remoteStateTGConfig.RemoteState.Encryption == nil {
+		jsonBytes, err := getTerragruntOutputJsonFromStateObject(targetTGOptions, targetConfig, remoteStateTGConfig.RemoteState, remoteStateTGConfig)
+		if _, isNotSupported := errors.Unwrap(err).(remote.ReadStateNotSupported); !isNotSupported {
+			return jsonBytes, err
+		}
+		terragruntOptions.Logger.Debugf("Could not read the state of %s directly: %v. Falling back to terraform output.", targetConfig, err)
+	}
+
 	// In optimization mode, see if there is already an init-ed folder that terragrunt can use, and if so, run
 	// `terraform output` in the working directory.
 	isInit, workingDir, err := terragruntAlreadyInit(targetTGOptions, targetConfig)
@@ -431,9 +579,9 @@ func getTerragruntOutputJson(terragruntOptions *options.TerragruntOptions, targe
 		return nil, err
 	}
 	if isInit {
-		return getTerragruntOutputJsonFromInitFolder(targetTGOptions, workingDir, remoteStateTGConfig.IamRole)
+		return getTerragruntOutputJsonFromInitFolder(targetTGOptions, workingDir, remoteStateTGConfig)
 	}
-	return getTerragruntOutputJsonFromRemoteState(targetTGOptions, targetConfig, remoteStateTGConfig.RemoteState, remoteStateTGConfig.IamRole)
+	return getTerragruntOutputJsonFromRemoteState(targetTGOptions, targetConfig, remoteStateTGConfig.RemoteState, remoteStateTGConfig)
 }
 
 // canGetRemoteState returns true if the remote state block is not nil and dependency optimization is not disabled
@@ -481,12 +629,12 @@ func terragruntAlreadyInit(terragruntOptions *options.TerragruntOptions, configP
 
 // getTerragruntOutputJsonFromInitFolder will retrieve the outputs directly from the module's working directory without
 // running init.
-func getTerragruntOutputJsonFromInitFolder(terragruntOptions *options.TerragruntOptions, terraformWorkingDir string, iamRole string) ([]byte, error) {
+func getTerragruntOutputJsonFromInitFolder(terragruntOptions *options.TerragruntOptions, terraformWorkingDir string, remoteStateTGConfig *TerragruntConfig) ([]byte, error) {
 	targetConfig := terragruntOptions.TerragruntConfigPath
 
 	terragruntOptions.Logger.Debugf("Detected module %s is already init-ed. Retrieving outputs directly from working directory.", targetConfig)
 
-	targetTGOptions, err := setupTerragruntOptionsForBareTerraform(terragruntOptions, terraformWorkingDir, targetConfig, iamRole)
+	targetTGOptions, err := setupTerragruntOptionsForBareTerraform(terragruntOptions, terraformWorkingDir, targetConfig, remoteStateTGConfig)
 	if err != nil {
 		return nil, err
 	}
@@ -513,7 +661,7 @@ func getTerragruntOutputJsonFromRemoteState(
 	terragruntOptions *options.TerragruntOptions,
 	targetConfig string,
 	remoteState *remote.RemoteState,
-	iamRole string,
+	remoteStateTGConfig *TerragruntConfig,
 ) ([]byte, error) {
 	terragruntOptions.Logger.Debugf("Detected remote state block with generate config. Resolving dependency by pulling remote state.")
 
@@ -530,10 +678,16 @@ func getTerragruntOutputJsonFromRemoteState(
 	defer os.RemoveAll(tempWorkDir)
 	terragruntOptions.Logger.Debugf("Setting dependency working directory to %s", tempWorkDir)
 
-	targetTGOptions, err := setupTerragruntOptionsForBareTerraform(terragruntOptions, tempWorkDir, targetConfig, iamRole)
+	targetTGOptions, err := setupTerragruntOptionsForBareTerraform(terragruntOptions, tempWorkDir, targetConfig, remoteStateTGConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	stopHTTPBackendSigningProxy, err := remote.StartHTTPBackendSigningProxy(remoteState, targetTGOptions)
 	if err != nil {
 		return nil, err
 	}
+	defer stopHTTPBackendSigningProxy()
 
 	// Generate the backend configuration in the working dir. If no generate config is set on the remote state block,
 	// set a temporary generate config so we can generate the backend code.
@@ -564,9 +718,46 @@ func getTerragruntOutputJsonFromRemoteState(
 	return jsonBytes, nil
 }
 
+// getTerragruntOutputJsonFromStateObject will retrieve the outputs by reading the state object straight from the
+// backend configured in the remote state block and parsing the outputs out of the state JSON, without running terraform.
+// Returns a remote.ReadStateNotSupported error if the backend doesn't support reading the state directly.
+// NOTE: terragruntOptions should be in the context of the targetConfig already.
+func getTerragruntOutputJsonFromStateObject(
+	terragruntOptions *options.TerragruntOptions,
+	targetConfig string,
+	remoteState *remote.RemoteState,
+	remoteStateTGConfig *TerragruntConfig,
+) ([]byte, error) {
+	terragruntOptions.Logger.Debugf("Reading the outputs of %s directly from the remote state.", targetConfig)
+
+	targetTGOptions, err := setupTerragruntOptionsForBareTerraform(terragruntOptions, filepath.Dir(targetConfig), targetConfig, remoteStateTGConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	// Like terraform, use the workspace selected via the environment if the target config doesn't select one
+	workspace := remoteStateTGConfig.Workspace
+	if workspace == "" {
+		workspace = targetTGOptions.Env["TF_WORKSPACE"]
+	}
+
+	stateData, err := remoteState.ReadState(workspace, targetTGOptions)
+	if err != nil {
+		return nil, err
+	}
+
+	jsonBytes, err := remote.TerraformStateOutputsJson(stateData)
+	if err != nil {
+		return nil, err
+	}
+	terragruntOptions.Logger.Debugf("Retrieved output from %s as json: %s", targetConfig, jsonBytes)
+	return jsonBytes, nil
+}
+
 // setupTerragruntOptionsForBareTerraform sets up a new TerragruntOptions struct that can be used to run terraform
 // without going through the full RunTerragrunt operation.
-func setupTerragruntOptionsForBareTerraform(originalOptions *options.TerragruntOptions, workingDir string, configPath string, iamRole string) (*options.TerragruntOptions, error) {
+// The IAM role and the workspace are taken from the given partially parsed config of the target.
+func setupTerragruntOptionsForBareTerraform(originalOptions *options.TerragruntOptions, workingDir string, configPath string, remoteStateTGConfig *TerragruntConfig) (*options.TerragruntOptions, error) {
 	// Here we clone the terragrunt options again since we need to make further modifications to it to allow running
 	// terraform directly.
 	// Set the terraform working dir to the tempdir, and set stdout writer to ioutil.Discard so that output content is
@@ -577,8 +768,32 @@ func setupTerragruntOptionsForBareTerraform(originalOptions *options.TerragruntO
 
 	// If the target config has an IAM role directive and it was not set on the command line, set it to
 	// the one we retrieved from the config.
-	if iamRole != "" && targetTGOptions.IamRole == "" {
+	// The outputs are read with the role of the output command, if the target config sets one.
+	if iamRole, iamRoleChain := remoteStateTGConfig.IamRoleForCommand("output"); iamRole != "" && targetTGOptions.IamRole == "" {
 		targetTGOptions.IamRole = iamRole
+		targetTGOptions.IamRoleChain = iamRoleChain
+	}
+	if remoteStateTGConfig.IamAssumeRoleExternalId != "" && targetTGOptions.IamAssumeRoleExternalId == "" {
+		targetTGOptions.IamAssumeRoleExternalId = remoteStateTGConfig.IamAssumeRoleExternalId
+	}
+	if remoteStateTGConfig.IamWebIdentityToken != "" && targetTGOptions.IamWebIdentityToken == "" {
+		targetTGOptions.IamWebIdentityToken = remoteStateTGConfig.IamWebIdentityToken
+	}
+	if remoteStateTGConfig.GoogleImpersonateServiceAccount != "" && targetTGOptions.GoogleImpersonateServiceAccount == "" {
+		targetTGOptions.GoogleImpersonateServiceAccount = remoteStateTGConfig.GoogleImpersonateServiceAccount
+	}
+	remote.UpdateEnvWithGoogleImpersonateServiceAccount(targetTGOptions)
+	// The outputs are read with the profile the target config is pinned to, if any, and otherwise with the credentials of
+	// the environment, rather than with the ones of the unit reading them
+	aws_helper.ResetAwsCredentialsEnv(targetTGOptions)
+	targetTGOptions.AwsProfile = remoteStateTGConfig.AwsProfile
+	aws_helper.UpdateEnvWithAwsProfile(targetTGOptions)
+	UpdateEnvWithAzureAuth(targetTGOptions, remoteStateTGConfig.AzureAuth)
+
+	// If the target config selects a workspace, read the outputs from the state of that workspace rather than the
+	// workspace that happens to be selected in the working dir
+	if remoteStateTGConfig.Workspace != "" {
+		targetTGOptions.Env["TF_WORKSPACE"] = remoteStateTGConfig.Workspace
 	}
 
 	// Make sure to assume any roles set by TERRAGRUNT_IAM_ROLE
@@ -639,11 +854,31 @@ func terraformOutputJsonToCtyValueMap(targetConfig string, jsonBytes []byte) (ma
 	return flattenedOutput, nil
 }
 
-// ClearOutputCache clears the output cache. Useful during testing.
+// ClearOutputCache clears the output cache. Useful during testing, and between the commands run by the daemon.
 func ClearOutputCache() {
 	jsonOutputCache = sync.Map{}
 }
 
+// InvalidateOutputCache removes the cached outputs of the given config, both in memory and on disk. This should be
+// called after running a command that can change the outputs of the config, e.g. apply, so that the modules that depend
+// on it don't use outdated outputs.
+func InvalidateOutputCache(configPath string, terragruntOptions *options.TerragruntOptions) {
+	absConfigPath, err := filepath.Abs(configPath)
+	if err != nil {
+		absConfigPath = configPath
+	}
+	targetConfig := util.CleanPath(absConfigPath)
+	jsonOutputCache.Delete(targetConfig)
+
+	cachePath, err := dependencyOutputCachePath(targetConfig)
+	if err != nil || !util.FileExists(cachePath) {
+		return
+	}
+	if err := os.Remove(cachePath); err != nil {
+		terragruntOptions.Logger.Warnf("Could not remove the output of %s cached on disk: %v", targetConfig, err)
+	}
+}
+
 // runTerraformInitForDependencyOutput will run terraform init in a mode that doesn't pull down plugins or modules. Note
 // that this will cause the command to fail for most modules as terraform init does a validation check to make sure the
 // plugins are available, even though we don't need it for our purposes (terraform output does not depend on any of the
@@ -1,12 +1,18 @@
 package config
 
 import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/hashicorp/hcl/v2/hclparse"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"github.com/zclconf/go-cty/cty/gocty"
+
+	"github.com/gruntwork-io/terragrunt/util"
 )
 
 func TestDecodeDependencyBlockMultiple(t *testing.T) {
@@ -109,3 +115,133 @@ dependency "hitchhiker" {
 	require.NotNil(t, defaultAllowedCommands)
 	assert.Equal(t, *defaultAllowedCommands, []string{"validate", "apply"})
 }
+
+func TestDependencyOutputCacheOnDisk(t *testing.T) {
+	t.Parallel()
+
+	tmpDir, err := ioutil.TempDir("", "dependency-output-cache")
+	require.NoError(t, err)
+	defer os.RemoveAll(tmpDir)
+
+	targetConfig := filepath.Join(tmpDir, DefaultTerragruntConfigPath)
+	terragruntOptions := mockOptionsForTest(t)
+	outputs := []byte(`{"vpc_id": {"sensitive": false, "type": "string", "value": "vpc-123"}}`)
+
+	// Without a TTL, the outputs are not cached on disk
+	writeDependencyOutputCache(targetConfig, outputs, terragruntOptions)
+	_, hasCached := readDependencyOutputCache(targetConfig, terragruntOptions)
+	assert.False(t, hasCached)
+
+	terragruntOptions.DependencyOutputCacheTTL = 60
+	writeDependencyOutputCache(targetConfig, outputs, terragruntOptions)
+	cached, hasCached := readDependencyOutputCache(targetConfig, terragruntOptions)
+	require.True(t, hasCached)
+	assert.Equal(t, outputs, cached)
+
+	cachePath, err := dependencyOutputCachePath(targetConfig)
+	require.NoError(t, err)
+	cacheInfo, err := os.Stat(cachePath)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0600), cacheInfo.Mode().Perm())
+
+	// Expired outputs are ignored
+	expired := time.Now().Add(-2 * time.Minute)
+	require.NoError(t, os.Chtimes(cachePath, expired, expired))
+	_, hasCached = readDependencyOutputCache(targetConfig, terragruntOptions)
+	assert.False(t, hasCached)
+
+	// Invalidating the outputs removes them from disk
+	writeDependencyOutputCache(targetConfig, outputs, terragruntOptions)
+	InvalidateOutputCache(targetConfig, terragruntOptions)
+	assert.False(t, util.FileExists(cachePath))
+}
+
+func TestParseConfigWithoutDependencyOutputs(t *testing.T) {
+	t.Parallel()
+
+	config := `
+dependency "vpc" {
+  config_path = "../does-not-exist"
+}
+
+inputs = {
+  vpc_id = dependency.vpc.outputs.vpc_id
+  name   = "app"
+}
+`
+
+	terragruntOptions := mockOptionsForTest(t)
+	terragruntOptions.OriginalTerraformCommand = "validate"
+
+	terragruntConfig, err := ParseConfigString(config, terragruntOptions, nil, DefaultTerragruntConfigPath)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{"vpc_id": nil, "name": "app"}, terragruntConfig.Inputs)
+
+	// Commands that use the inputs need the outputs, which can't be retrieved here
+	terragruntOptions.OriginalTerraformCommand = "plan"
+	_, err = ParseConfigString(config, terragruntOptions, nil, DefaultTerragruntConfigPath)
+	assert.Error(t, err)
+
+	// Prefetching doesn't need the outputs either
+	terragruntOptions.OriginalTerraformCommand = "init"
+	terragruntOptions.PrefetchOnly = true
+	terragruntConfig, err = ParseConfigString(config, terragruntOptions, nil, DefaultTerragruntConfigPath)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{"vpc_id": nil, "name": "app"}, terragruntConfig.Inputs)
+}
+
+func TestParseConfigWithoutDependencyOutputsFallsBackWhenOutputsAreRequired(t *testing.T) {
+	t.Parallel()
+
+	config := `
+dependency "vpc" {
+  config_path = "../does-not-exist"
+}
+
+generate "vpc" {
+  path      = "vpc.tf"
+  if_exists = "overwrite"
+  contents  = dependency.vpc.outputs.vpc_id
+}
+`
+
+	terragruntOptions := mockOptionsForTest(t)
+	terragruntOptions.OriginalTerraformCommand = "validate"
+
+	// The generate block can't be decoded with unknown outputs, so they're retrieved, which fails here as the
+	// dependency doesn't exist
+	_, err := ParseConfigString(config, terragruntOptions, nil, DefaultTerragruntConfigPath)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "does-not-exist")
+}
+
+func TestSetupTerragruntOptionsForBareTerraformIsolatesAwsCredentials(t *testing.T) {
+	t.Parallel()
+
+	// The options of a unit pinned to a profile, with the credentials of a role it assumed
+	dependentOptions := mockOptionsForTest(t)
+	dependentOptions.AwsProfile = "dependent"
+	dependentOptions.Env = map[string]string{"AWS_PROFILE": "dependent", "AWS_ACCESS_KEY_ID": "AKIADEPENDENT", "AWS_REGION": "eu-west-1"}
+
+	// A dependency pinned to a profile of its own reads its outputs with that profile
+	targetOptions, err := setupTerragruntOptionsForBareTerraform(dependentOptions, "../vpc", "../vpc/terragrunt.hcl", &TerragruntConfig{AwsProfile: "vpc"})
+	require.NoError(t, err)
+	assert.Equal(t, "vpc", targetOptions.AwsProfile)
+	assert.Equal(t, "vpc", targetOptions.Env["AWS_PROFILE"])
+	assert.NotContains(t, targetOptions.Env, "AWS_ACCESS_KEY_ID")
+	assert.Equal(t, "eu-west-1", targetOptions.Env["AWS_REGION"])
+
+	// A dependency that's not pinned to a profile reads its outputs with the credentials of the environment
+	targetOptions, err = setupTerragruntOptionsForBareTerraform(dependentOptions, "../vpc", "../vpc/terragrunt.hcl", &TerragruntConfig{})
+	require.NoError(t, err)
+	assert.Equal(t, "", targetOptions.AwsProfile)
+	for _, envVar := range []string{"AWS_PROFILE", "AWS_ACCESS_KEY_ID"} {
+		value, isSet := os.LookupEnv(envVar)
+		actual, isActualSet := targetOptions.Env[envVar]
+		assert.Equal(t, isSet, isActualSet, envVar)
+		assert.Equal(t, value, actual, envVar)
+	}
+
+	// The options of the dependent unit are left as they were
+	assert.Equal(t, map[string]string{"AWS_PROFILE": "dependent", "AWS_ACCESS_KEY_ID": "AKIADEPENDENT", "AWS_REGION": "eu-west-1"}, dependentOptions.Env)
+}
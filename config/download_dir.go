@@ -0,0 +1,68 @@
+package config
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/zclconf/go-cty/cty"
+	"github.com/zclconf/go-cty/cty/convert"
+
+	"github.com/gruntwork-io/terragrunt/errors"
+	"github.com/gruntwork-io/terragrunt/options"
+	"github.com/gruntwork-io/terragrunt/util"
+)
+
+// The environment variable for the org-wide default download dir. Unlike TERRAGRUNT_DOWNLOAD, it doesn't override the
+// download_dir set in the Terragrunt configuration, but it does override the one in the defaults file.
+const DefaultDownloadDirEnvVar = "TERRAGRUNT_DEFAULT_DOWNLOAD_DIR"
+
+// IsDownloadDirTemplate returns true if the given download dir, as passed in via the CLI, the environment or the
+// defaults file, is a template with interpolations (${...}) or directives (%{...}) that need to be rendered for each
+// module.
+func IsDownloadDirTemplate(downloadDir string) bool {
+	return strings.Contains(downloadDir, "${") || strings.Contains(downloadDir, "%{")
+}
+
+// RenderDownloadDir renders the given download dir template for the module of the given options. The template can use
+// the same functions as the Terragrunt configuration, e.g.:
+//
+// /dev/shm/terragrunt/${get_env("BRANCH", "main")}/${path_relative_to_include()}
+//
+// If the rendered download dir is a relative path, it's relative to the folder of the module.
+func RenderDownloadDir(template string, terragruntOptions *options.TerragruntOptions) (string, error) {
+	expression, diags := hclsyntax.ParseTemplate([]byte(template), "download_dir", hcl.Pos{Line: 1, Column: 1, Byte: 0})
+	if diags.HasErrors() {
+		return "", errors.WithStackTrace(InvalidDownloadDirTemplate{Template: template, Err: diags})
+	}
+
+	evalContext := CreateTerragruntEvalContext(terragruntOptions.TerragruntConfigPath, terragruntOptions, EvalContextExtensions{})
+	value, diags := expression.Value(evalContext)
+	if diags.HasErrors() {
+		return "", errors.WithStackTrace(InvalidDownloadDirTemplate{Template: template, Err: diags})
+	}
+
+	value, err := convert.Convert(value, cty.String)
+	if err != nil || value.IsNull() || !value.IsKnown() || value.AsString() == "" {
+		return "", errors.WithStackTrace(InvalidDownloadDirTemplate{Template: template, Err: fmt.Errorf("it must render to a non-empty string")})
+	}
+
+	downloadDir := value.AsString()
+	if !filepath.IsAbs(downloadDir) {
+		downloadDir = util.JoinPath(filepath.Dir(terragruntOptions.TerragruntConfigPath), downloadDir)
+	}
+	return filepath.ToSlash(filepath.Clean(downloadDir)), nil
+}
+
+// Custom error types
+
+type InvalidDownloadDirTemplate struct {
+	Template string
+	Err      error
+}
+
+func (err InvalidDownloadDirTemplate) Error() string {
+	return fmt.Sprintf("Could not render the download dir template %s: %v", err.Template, err.Err)
+}
@@ -0,0 +1,56 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/gruntwork-io/terragrunt/errors"
+)
+
+func TestIsDownloadDirTemplate(t *testing.T) {
+	t.Parallel()
+
+	assert.True(t, IsDownloadDirTemplate(`/tmp/${get_env("BRANCH", "main")}`))
+	assert.True(t, IsDownloadDirTemplate(`/tmp/%{if get_platform() == "linux"}shm%{endif}`))
+	assert.False(t, IsDownloadDirTemplate("/tmp/terragrunt"))
+	assert.False(t, IsDownloadDirTemplate("/tmp/$HOME"))
+}
+
+func TestRenderDownloadDir(t *testing.T) {
+	t.Parallel()
+
+	terragruntOptions := mockOptionsForTestWithConfigPath(t, "/live/prod/vpc/"+DefaultTerragruntConfigPath)
+	terragruntOptions.Env = map[string]string{"BRANCH": "feature"}
+
+	testCases := []struct {
+		template string
+		expected string
+	}{
+		{`/dev/shm/terragrunt/${get_env("BRANCH", "main")}`, "/dev/shm/terragrunt/feature"},
+		{`/dev/shm/terragrunt/${get_env("MISSING_BRANCH", "main")}`, "/dev/shm/terragrunt/main"},
+		// Relative paths are relative to the folder of the module
+		{`.terragrunt-cache/${get_env("BRANCH", "main")}`, "/live/prod/vpc/.terragrunt-cache/feature"},
+		{`/cache/%{if get_env("BRANCH", "main") == "main"}main%{else}branches/${get_env("BRANCH", "")}%{endif}`, "/cache/branches/feature"},
+	}
+
+	for _, testCase := range testCases {
+		actual, err := RenderDownloadDir(testCase.template, terragruntOptions)
+		require.NoError(t, err, testCase.template)
+		assert.Equal(t, testCase.expected, actual, testCase.template)
+	}
+}
+
+func TestRenderInvalidDownloadDir(t *testing.T) {
+	t.Parallel()
+
+	terragruntOptions := mockOptionsForTestWithConfigPath(t, "/live/prod/vpc/"+DefaultTerragruntConfigPath)
+	terragruntOptions.Env = map[string]string{}
+
+	for _, template := range []string{`/tmp/${get_env(`, `/tmp/${no_such_function()}`, `${get_env("EMPTY", "")}`} {
+		_, err := RenderDownloadDir(template, terragruntOptions)
+		_, isInvalidTemplate := errors.Unwrap(err).(InvalidDownloadDirTemplate)
+		assert.True(t, isInvalidTemplate, "Expected an InvalidDownloadDirTemplate error for %s but got %v", template, err)
+	}
+}
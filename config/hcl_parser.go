@@ -1,7 +1,9 @@
 package config
 
 import (
+	"fmt"
 	"path/filepath"
+	"sync"
 
 	"github.com/gruntwork-io/terragrunt/errors"
 	"github.com/gruntwork-io/terragrunt/options"
@@ -12,6 +14,48 @@ import (
 	"github.com/zclconf/go-cty/cty/gocty"
 )
 
+// The warnings of the HCL parser about the configs parsed during the run, e.g. to annotate them in GitHub Actions even
+// when the command succeeds. The same config is parsed many times, e.g. once per module including it, so each warning
+// is only recorded once.
+var configWarnings = hcl.Diagnostics{}
+var recordedConfigWarnings = map[string]bool{}
+
+// configWarningsLock protects configWarnings, as configs are parsed concurrently, e.g. while discovering the modules of
+// *-all commands.
+var configWarningsLock sync.Mutex
+
+// ConfigWarnings returns the warnings of the HCL parser about the configs parsed so far.
+func ConfigWarnings() hcl.Diagnostics {
+	configWarningsLock.Lock()
+	defer configWarningsLock.Unlock()
+	return append(hcl.Diagnostics{}, configWarnings...)
+}
+
+// ClearConfigWarnings forgets the warnings recorded so far, e.g. between the commands run by the daemon.
+func ClearConfigWarnings() {
+	configWarningsLock.Lock()
+	defer configWarningsLock.Unlock()
+	configWarnings = hcl.Diagnostics{}
+	recordedConfigWarnings = map[string]bool{}
+}
+
+// Record the warnings among the given diagnostics. See configWarnings.
+func recordConfigWarnings(diagnostics hcl.Diagnostics) {
+	configWarningsLock.Lock()
+	defer configWarningsLock.Unlock()
+	for _, diagnostic := range diagnostics {
+		if diagnostic.Severity != hcl.DiagWarning {
+			continue
+		}
+		key := fmt.Sprintf("%s\n%s\n%v", diagnostic.Summary, diagnostic.Detail, diagnostic.Subject)
+		if recordedConfigWarnings[key] {
+			continue
+		}
+		recordedConfigWarnings[key] = true
+		configWarnings = append(configWarnings, diagnostic)
+	}
+}
+
 // parseHcl uses the HCL2 parser to parse the given string into an HCL file body.
 func parseHcl(parser *hclparse.Parser, hcl string, filename string) (file *hcl.File, err error) {
 	// The HCL2 parser and especially cty conversions will panic in many types of errors, so we have to recover from
@@ -24,6 +68,7 @@ func parseHcl(parser *hclparse.Parser, hcl string, filename string) (file *hcl.F
 
 	if filepath.Ext(filename) == ".json" {
 		file, parseDiagnostics := parser.ParseJSON([]byte(hcl), filename)
+		recordConfigWarnings(parseDiagnostics)
 		if parseDiagnostics != nil && parseDiagnostics.HasErrors() {
 			return nil, parseDiagnostics
 		}
@@ -32,6 +77,7 @@ func parseHcl(parser *hclparse.Parser, hcl string, filename string) (file *hcl.F
 	}
 
 	file, parseDiagnostics := parser.ParseHCL([]byte(hcl), filename)
+	recordConfigWarnings(parseDiagnostics)
 	if parseDiagnostics != nil && parseDiagnostics.HasErrors() {
 		return nil, parseDiagnostics
 	}
@@ -58,6 +104,7 @@ func decodeHcl(
 	evalContext := CreateTerragruntEvalContext(filename, terragruntOptions, extensions)
 
 	decodeDiagnostics := gohcl.DecodeBody(file.Body, evalContext, out)
+	recordConfigWarnings(decodeDiagnostics)
 	if decodeDiagnostics != nil && decodeDiagnostics.HasErrors() {
 		return decodeDiagnostics
 	}
@@ -0,0 +1,32 @@
+package config
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRecordConfigWarnings(t *testing.T) {
+	t.Parallel()
+
+	warning := &hcl.Diagnostic{
+		Severity: hcl.DiagWarning,
+		Summary:  "Test warning of TestRecordConfigWarnings",
+		Subject:  &hcl.Range{Filename: "terragrunt.hcl", Start: hcl.Pos{Line: 1, Column: 1}, End: hcl.Pos{Line: 1, Column: 5}},
+	}
+	err := &hcl.Diagnostic{Severity: hcl.DiagError, Summary: "Test error of TestRecordConfigWarnings"}
+
+	// The same config is parsed once per module including it, but its warnings are only recorded once
+	recordConfigWarnings(hcl.Diagnostics{warning, err})
+	recordConfigWarnings(hcl.Diagnostics{warning})
+
+	recorded := []string{}
+	for _, diagnostic := range ConfigWarnings() {
+		if strings.HasSuffix(diagnostic.Summary, "of TestRecordConfigWarnings") {
+			recorded = append(recorded, diagnostic.Summary)
+		}
+	}
+	assert.Equal(t, []string{warning.Summary}, recorded)
+}
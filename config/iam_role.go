@@ -0,0 +1,168 @@
+package config
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/mitchellh/mapstructure"
+	"github.com/zclconf/go-cty/cty"
+
+	"github.com/gruntwork-io/terragrunt/errors"
+	"github.com/gruntwork-io/terragrunt/options"
+)
+
+// iamRoleHopAttrs are the attributes of a role in a list of roles set in iam_role
+type iamRoleHopAttrs struct {
+	RoleArn     string `mapstructure:"role_arn"`
+	SessionName string `mapstructure:"session_name"`
+	Duration    int64  `mapstructure:"duration"`
+}
+
+// parseIamRole parses the value of the iam_role attribute, which is either the ARN of the role to assume, or a list of
+// roles to assume in sequence, e.g. a role in a hub account and then one in the account of the module. Each role of a
+// list is either its ARN or an object with the role_arn, and optionally the session_name and the duration of its
+// session. Returns the ARN of the role terragrunt ends up with, and the chain of roles to get there if it's a list.
+func parseIamRole(value cty.Value) (string, []options.IamRoleHop, error) {
+	if value.IsNull() {
+		return "", nil, nil
+	}
+	if value.Type() == cty.String {
+		return value.AsString(), nil, nil
+	}
+	if !value.Type().IsListType() && !value.Type().IsTupleType() {
+		return "", nil, errors.WithStackTrace(InvalidIamRole("expected the ARN of a role or a list of roles"))
+	}
+	if value.LengthInt() == 0 {
+		return "", nil, errors.WithStackTrace(InvalidIamRole("the list of roles is empty"))
+	}
+
+	chain := []options.IamRoleHop{}
+	for it := value.ElementIterator(); it.Next(); {
+		_, element := it.Element()
+		hop, err := parseIamRoleHop(element)
+		if err != nil {
+			return "", nil, err
+		}
+		chain = append(chain, hop)
+	}
+	return chain[len(chain)-1].RoleArn, chain, nil
+}
+
+// parseIamRoleHop parses a role of a list of roles set in iam_role
+func parseIamRoleHop(value cty.Value) (options.IamRoleHop, error) {
+	if value.Type() == cty.String && !value.IsNull() {
+		return options.IamRoleHop{RoleArn: value.AsString()}, nil
+	}
+	if !value.Type().IsObjectType() && !value.Type().IsMapType() {
+		return options.IamRoleHop{}, errors.WithStackTrace(InvalidIamRole("expected each role of the list to be an ARN or an object with a role_arn"))
+	}
+
+	attrsMap, err := parseCtyValueToMap(value)
+	if err != nil {
+		return options.IamRoleHop{}, err
+	}
+	var attrs iamRoleHopAttrs
+	decoder, err := mapstructure.NewDecoder(&mapstructure.DecoderConfig{Result: &attrs, ErrorUnused: true})
+	if err != nil {
+		return options.IamRoleHop{}, errors.WithStackTrace(err)
+	}
+	if err := decoder.Decode(attrsMap); err != nil {
+		return options.IamRoleHop{}, errors.WithStackTrace(InvalidIamRole(err.Error()))
+	}
+	if attrs.RoleArn == "" {
+		return options.IamRoleHop{}, errors.WithStackTrace(InvalidIamRole("each role of the list must set role_arn"))
+	}
+	if attrs.Duration < 0 {
+		return options.IamRoleHop{}, errors.WithStackTrace(InvalidIamRole(fmt.Sprintf("the duration of %s must not be negative", attrs.RoleArn)))
+	}
+
+	return options.IamRoleHop{RoleArn: attrs.RoleArn, SessionName: attrs.SessionName, DurationSeconds: attrs.Duration}, nil
+}
+
+// iamCommandRoleBlock is an iam_command_role block, which sets an IAM role to assume instead of the one of iam_role
+// when running any of the given terraform commands, e.g. a read-only role for plan
+type iamCommandRoleBlock struct {
+	Name     string    `hcl:"name,label"`
+	Commands []string  `hcl:"commands,attr"`
+	IamRole  cty.Value `hcl:"iam_role,attr"`
+}
+
+// IamCommandRole is the IAM role to assume when running any of the given terraform commands, as set by an
+// iam_command_role block
+type IamCommandRole struct {
+	Name     string   `cty:"name"`
+	Commands []string `cty:"commands"`
+	// The ARN of the role, and the chain of roles to get there if the block sets a list of roles. See parseIamRole.
+	IamRole      string `cty:"iam_role"`
+	IamRoleChain []options.IamRoleHop
+}
+
+// Validate and index the given iam_command_role blocks by name
+func iamCommandRolesByName(blocks []iamCommandRoleBlock) (map[string]IamCommandRole, error) {
+	if len(blocks) == 0 {
+		return nil, nil
+	}
+
+	roles := map[string]IamCommandRole{}
+	blockOfCommand := map[string]string{}
+	for _, block := range blocks {
+		if _, isDuplicate := roles[block.Name]; isDuplicate {
+			return nil, errors.WithStackTrace(InvalidIamCommandRole(fmt.Sprintf("found more than one block named %s", block.Name)))
+		}
+		if len(block.Commands) == 0 {
+			return nil, errors.WithStackTrace(InvalidIamCommandRole(fmt.Sprintf("the commands of %s must not be empty", block.Name)))
+		}
+		for _, command := range block.Commands {
+			if otherBlock, isDuplicate := blockOfCommand[command]; isDuplicate {
+				return nil, errors.WithStackTrace(InvalidIamCommandRole(fmt.Sprintf("the command %s is in both %s and %s", command, otherBlock, block.Name)))
+			}
+			blockOfCommand[command] = block.Name
+		}
+
+		iamRole, iamRoleChain, err := parseIamRole(block.IamRole)
+		if err != nil {
+			return nil, err
+		}
+		if iamRole == "" {
+			return nil, errors.WithStackTrace(InvalidIamCommandRole(fmt.Sprintf("the iam_role of %s must be set", block.Name)))
+		}
+		roles[block.Name] = IamCommandRole{Name: block.Name, Commands: block.Commands, IamRole: iamRole, IamRoleChain: iamRoleChain}
+	}
+	return roles, nil
+}
+
+// IamRoleForCommand returns the IAM role to assume when running the given terraform command, and the chain of roles to
+// get there, if any: the role of the iam_command_role block of the command, if there is one, or else the iam_role.
+// Included configs may set the same command in blocks of different names, in which case the block whose name comes
+// first wins.
+func (conf *TerragruntConfig) IamRoleForCommand(command string) (string, []options.IamRoleHop) {
+	names := []string{}
+	for name := range conf.IamCommandRoles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		role := conf.IamCommandRoles[name]
+		for _, roleCommand := range role.Commands {
+			if roleCommand == command {
+				return role.IamRole, role.IamRoleChain
+			}
+		}
+	}
+	return conf.IamRole, conf.IamRoleChain
+}
+
+// Custom error types
+
+type InvalidIamRole string
+
+func (reason InvalidIamRole) Error() string {
+	return fmt.Sprintf("Invalid iam_role: %s", string(reason))
+}
+
+type InvalidIamCommandRole string
+
+func (reason InvalidIamCommandRole) Error() string {
+	return fmt.Sprintf("Invalid iam_command_role block: %s", string(reason))
+}
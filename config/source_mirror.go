@@ -0,0 +1,120 @@
+package config
+
+import (
+	"net/url"
+	"strings"
+
+	"github.com/hashicorp/go-getter"
+)
+
+// The placeholders of the URLs of the --terragrunt-source-mirror mirrors: the path of the source after the key of the
+// mirror, and the ref of the source
+const (
+	SourceMirrorPathPlaceholder = "{path}"
+	SourceMirrorRefPlaceholder  = "{ref}"
+)
+
+// The ref the sources without one, i.e. the ones that point at the default branch, have in the mirrors
+const SourceMirrorDefaultRef = "HEAD"
+
+// adjustSourceWithMirrors implements the --terragrunt-source-mirror feature. This function will check if the URL portion
+// of a terraform source matches any key of the provided mirrors, the same way as the prefix matching of
+// --terragrunt-source-map, and if it does, replace the source with the archive of its code in the artifact store the
+// longest matching key maps to, e.g. an S3 or GCS bucket, or an internal HTTP server. The mirror URL is a template, where
+// {path} is the rest of the path of the source URL after the key, and {ref} is the ref of the source, or HEAD if it has
+// none. If the mirror URL has neither, /{path}/{ref}.tar.gz is appended to it. The subdir of the source is kept.
+//
+// Example:
+// Suppose terragrunt is called with:
+//
+//   --terragrunt-source-mirror github.com/acme=s3::https://s3.amazonaws.com/acme-modules
+//
+// and the terraform source is:
+//
+//   git::https://github.com/acme/modules.git//vpc?ref=v1.2.0
+//
+// This function will take that source and transform it to:
+//
+//   s3::https://s3.amazonaws.com/acme-modules/modules/v1.2.0.tar.gz//vpc
+//
+func adjustSourceWithMirrors(mirrors map[string]string, source string) string {
+	if len(mirrors) == 0 {
+		return source
+	}
+
+	moduleUrl, moduleSubdir := getter.SourceDirSubdir(source)
+	if moduleUrl == "" {
+		return source
+	}
+	moduleUrl, rawQuery := splitSourceQuery(moduleUrl)
+
+	key, path := longestSourceUrlPrefix(mirrors, moduleUrl)
+	if key == "" {
+		return source
+	}
+
+	ref := SourceMirrorDefaultRef
+	if query, err := url.ParseQuery(rawQuery); err == nil && query.Get("ref") != "" {
+		ref = query.Get("ref")
+	}
+
+	mirrorUrl, mirrorQuery := splitSourceQuery(mirrors[key])
+	if !strings.Contains(mirrorUrl, SourceMirrorPathPlaceholder) && !strings.Contains(mirrorUrl, SourceMirrorRefPlaceholder) {
+		mirrorUrl = strings.TrimRight(mirrorUrl, "/")
+		if path != "" {
+			mirrorUrl += "/" + SourceMirrorPathPlaceholder
+		}
+		mirrorUrl += "/" + SourceMirrorRefPlaceholder + ".tar.gz"
+	}
+	mirrorUrl = strings.Replace(mirrorUrl, SourceMirrorPathPlaceholder, path, -1)
+	mirrorUrl = strings.Replace(mirrorUrl, SourceMirrorRefPlaceholder, ref, -1)
+
+	// go-getter expects the subdir before the query
+	if moduleSubdir != "" {
+		mirrorUrl += "//" + moduleSubdir
+	}
+	if mirrorQuery != "" {
+		mirrorUrl += "?" + mirrorQuery
+	}
+	return mirrorUrl
+}
+
+// IsSourceMirrorUrl returns true if the given source URL points at one of the given --terragrunt-source-mirror mirrors
+func IsSourceMirrorUrl(mirrors map[string]string, sourceUrl string) bool {
+	for _, mirrorUrl := range mirrors {
+		mirrorUrl, _ = splitSourceQuery(mirrorUrl)
+		if placeholderIndex := strings.Index(mirrorUrl, "{"); placeholderIndex >= 0 {
+			mirrorUrl = mirrorUrl[:placeholderIndex]
+		}
+		if mirrorUrl != "" && hasMirrorUrlPrefix(sourceUrl, mirrorUrl) {
+			return true
+		}
+	}
+	return false
+}
+
+// hasMirrorUrlPrefix returns true if the given source URL starts with the given prefix of a mirror URL. When the prefix
+// ends within the host of the mirror, e.g. https://mirror.acme.internal, the host of the source URL must end there too,
+// so that e.g. https://mirror.acme.internal.example.com doesn't count as the mirror.
+func hasMirrorUrlPrefix(sourceUrl string, prefix string) bool {
+	if !strings.HasPrefix(sourceUrl, prefix) {
+		return false
+	}
+	host := prefix
+	if schemeIndex := strings.Index(host, "://"); schemeIndex >= 0 {
+		host = host[schemeIndex+len("://"):]
+	}
+	if strings.ContainsAny(host, "/:") {
+		return true
+	}
+	rest := sourceUrl[len(prefix):]
+	return rest == "" || rest[0] == '/' || rest[0] == ':'
+}
+
+// splitSourceQuery splits the given source URL into the part before the query and the query
+func splitSourceQuery(sourceUrl string) (string, string) {
+	if queryIndex := strings.Index(sourceUrl, "?"); queryIndex >= 0 {
+		return sourceUrl[:queryIndex], sourceUrl[queryIndex+1:]
+	}
+	return sourceUrl, ""
+}
@@ -0,0 +1,82 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAdjustSourceWithMirrors(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name     string
+		mirrors  map[string]string
+		source   string
+		expected string
+	}{
+		{
+			"no mirrors",
+			map[string]string{},
+			"git::https://github.com/acme/modules.git//vpc?ref=v1.2.0",
+			"git::https://github.com/acme/modules.git//vpc?ref=v1.2.0",
+		},
+		{
+			"no match",
+			map[string]string{"github.com/other": "s3::https://s3.amazonaws.com/acme-modules"},
+			"git::https://github.com/acme/modules.git//vpc?ref=v1.2.0",
+			"git::https://github.com/acme/modules.git//vpc?ref=v1.2.0",
+		},
+		{
+			"default layout",
+			map[string]string{"github.com/acme": "s3::https://s3.amazonaws.com/acme-modules/"},
+			"git::ssh://git@github.com/acme/modules.git//vpc?ref=v1.2.0",
+			"s3::https://s3.amazonaws.com/acme-modules/modules/v1.2.0.tar.gz//vpc",
+		},
+		{
+			"exact match without ref",
+			map[string]string{"github.com/acme/modules": "gcs::https://www.googleapis.com/storage/v1/acme-modules/modules"},
+			"github.com/acme/modules//vpc",
+			"gcs::https://www.googleapis.com/storage/v1/acme-modules/modules/HEAD.tar.gz//vpc",
+		},
+		{
+			"template with query",
+			map[string]string{"github.com/acme": "https://mirror.acme.internal/{path}-{ref}.zip?archive=zip"},
+			"git::https://github.com/acme/modules.git//vpc/subnets?ref=v1.2.0",
+			"https://mirror.acme.internal/modules-v1.2.0.zip//vpc/subnets?archive=zip",
+		},
+		{
+			"longest prefix wins",
+			map[string]string{"github.com/acme": "https://mirror.acme.internal/github", "github.com/acme/modules": "https://modules.acme.internal"},
+			"git::https://github.com/acme/modules.git?ref=v1.2.0",
+			"https://modules.acme.internal/v1.2.0.tar.gz",
+		},
+	}
+
+	for _, testCase := range testCases {
+		// Save the test case in local scope so all the t.Run calls don't end up with the last item in the list
+		testCase := testCase
+		t.Run(testCase.name, func(t *testing.T) {
+			t.Parallel()
+			assert.Equal(t, testCase.expected, adjustSourceWithMirrors(testCase.mirrors, testCase.source))
+		})
+	}
+}
+
+func TestIsSourceMirrorUrl(t *testing.T) {
+	t.Parallel()
+
+	mirrors := map[string]string{"github.com/acme": "https://mirror.acme.internal/{path}/{ref}.tar.gz"}
+	assert.True(t, IsSourceMirrorUrl(mirrors, "https://mirror.acme.internal/modules/v1.2.0.tar.gz//vpc"))
+	assert.False(t, IsSourceMirrorUrl(mirrors, "https://github.com/acme/modules/archive/v1.2.0.tar.gz"))
+	assert.False(t, IsSourceMirrorUrl(map[string]string{}, "https://mirror.acme.internal/modules/v1.2.0.tar.gz"))
+
+	// The host of the source must be the one of the mirror, not just start with it
+	mirrors = map[string]string{"github.com/acme": "https://mirror.acme.internal{path}/{ref}.tar.gz"}
+	assert.True(t, IsSourceMirrorUrl(mirrors, "https://mirror.acme.internal/modules/v1.2.0.tar.gz"))
+	assert.False(t, IsSourceMirrorUrl(mirrors, "https://mirror.acme.internal.evil/modules/v1.2.0.tar.gz"))
+	mirrors = map[string]string{"github.com/acme": "git::ssh://git@mirror.acme.internal"}
+	assert.True(t, IsSourceMirrorUrl(mirrors, "git::ssh://git@mirror.acme.internal:2222/modules.git"))
+	assert.True(t, IsSourceMirrorUrl(mirrors, "git::ssh://git@mirror.acme.internal"))
+	assert.False(t, IsSourceMirrorUrl(mirrors, "git::ssh://git@mirror.acme.internal-evil.com/modules.git"))
+}
@@ -0,0 +1,138 @@
+package configstack
+
+import (
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/gruntwork-io/terragrunt/util"
+)
+
+// The version of the repo config format of Atlantis the config is generated in
+const atlantisConfigVersion = 3
+
+// AtlantisConfig is the repo config of Atlantis, atlantis.yaml, with a project for each module of the stack. See
+// https://www.runatlantis.io/docs/repo-level-atlantis-yaml.html
+type AtlantisConfig struct {
+	Version  int               `yaml:"version"`
+	Projects []AtlantisProject `yaml:"projects"`
+}
+
+// AtlantisProject is the Atlantis project of a module. Atlantis plans it automatically when any of the files matched
+// by the patterns in when_modified, which are relative to the dir of the project, is changed by a pull request.
+type AtlantisProject struct {
+	Name     string           `yaml:"name"`
+	Dir      string           `yaml:"dir"`
+	Workflow string           `yaml:"workflow,omitempty"`
+	Autoplan AtlantisAutoplan `yaml:"autoplan"`
+}
+
+type AtlantisAutoplan struct {
+	Enabled      bool     `yaml:"enabled"`
+	WhenModified []string `yaml:"when_modified"`
+}
+
+// The files of a folder that make up a module, or the Terraform code of a dependency
+var atlantisModuleFilePatterns = []string{"*.hcl", "*.tf*"}
+
+// AtlantisConfig returns the repo config of Atlantis for the modules of the stack, with the given root dir being the
+// dir of atlantis.yaml, usually the root of the repo. Each module is planned whenever a file in its folder, a file its
+// configuration reads (e.g. an included config or a file read with read_terragrunt_config), the files of its
+// dependencies, or its Terraform source, when that's a local folder, are changed. The files read by the modules are
+// only known if the stack was created with options that track them, i.e., with FilesRead set. The modules that are
+// excluded, or that are outside of the root dir, are left out. If workflow is set, the projects run the Atlantis
+// workflow of that name, which is how Atlantis is told to run terragrunt instead of terraform.
+func (stack *Stack) AtlantisConfig(rootDir string, workflow string) (*AtlantisConfig, error) {
+	canonicalRootDir, err := util.CanonicalPath(rootDir, ".")
+	if err != nil {
+		return nil, err
+	}
+
+	atlantisConfig := &AtlantisConfig{Version: atlantisConfigVersion, Projects: []AtlantisProject{}}
+	for _, module := range stack.Modules {
+		if module.FlagExcluded || module.AssumeAlreadyApplied || !util.HasPathPrefix(module.Path, canonicalRootDir) {
+			continue
+		}
+
+		whenModified, err := atlantisWhenModified(module)
+		if err != nil {
+			return nil, err
+		}
+
+		dir, err := util.GetPathRelativeTo(module.Path, canonicalRootDir)
+		if err != nil {
+			return nil, err
+		}
+		atlantisConfig.Projects = append(atlantisConfig.Projects, AtlantisProject{
+			Name:     atlantisProjectName(dir, canonicalRootDir),
+			Dir:      dir,
+			Workflow: workflow,
+			Autoplan: AtlantisAutoplan{Enabled: true, WhenModified: whenModified},
+		})
+	}
+
+	sort.Slice(atlantisConfig.Projects, func(i, j int) bool {
+		return atlantisConfig.Projects[i].Dir < atlantisConfig.Projects[j].Dir
+	})
+	return atlantisConfig, nil
+}
+
+// Return the sorted patterns of the files that affect the given module, relative to its folder
+func atlantisWhenModified(module *TerraformModule) ([]string, error) {
+	patterns := map[string]bool{}
+	for _, pattern := range atlantisModuleFilePatterns {
+		patterns[pattern] = true
+	}
+
+	if module.TerragruntOptions != nil && module.TerragruntOptions.FilesRead != nil {
+		for _, file := range module.TerragruntOptions.FilesRead.Paths() {
+			if filepath.Dir(file) == module.Path {
+				continue
+			}
+			relPath, err := util.GetPathRelativeTo(file, module.Path)
+			if err != nil {
+				return nil, err
+			}
+			patterns[relPath] = true
+		}
+	}
+
+	for _, dependency := range module.Dependencies {
+		dependencyDir, err := util.GetPathRelativeTo(dependency.Path, module.Path)
+		if err != nil {
+			return nil, err
+		}
+		for _, pattern := range atlantisModuleFilePatterns {
+			patterns[dependencyDir+"/"+pattern] = true
+		}
+	}
+
+	localSourceDir, err := getLocalTerraformSourceDir(module)
+	if err != nil {
+		return nil, err
+	}
+	// The whole folder is copied, so the Terraform code of the modules it calls from its subfolders counts too
+	if localSourceDir != "" && localSourceDir != module.Path {
+		sourceDir, err := util.GetPathRelativeTo(localSourceDir, module.Path)
+		if err != nil {
+			return nil, err
+		}
+		patterns[sourceDir+"/**/*.tf*"] = true
+	}
+
+	whenModified := []string{}
+	for pattern := range patterns {
+		whenModified = append(whenModified, pattern)
+	}
+	sort.Strings(whenModified)
+	return whenModified, nil
+}
+
+// Atlantis requires the names of the projects to be unique, so they're derived from their dir, with the module at the
+// root named after the root dir
+func atlantisProjectName(dir string, rootDir string) string {
+	if dir == "." {
+		return filepath.Base(rootDir)
+	}
+	return strings.ReplaceAll(dir, "/", "_")
+}
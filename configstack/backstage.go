@@ -0,0 +1,160 @@
+package configstack
+
+import (
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/gruntwork-io/terragrunt/util"
+)
+
+// The defaults of the catalog block of the modules
+const (
+	backstageDefaultKind  = "Resource"
+	backstageDefaultType  = "terraform"
+	backstageUnknownValue = "unknown"
+)
+
+// The maximum length of the name of a Backstage entity
+const backstageMaxNameLength = 63
+
+// The runs of chars that can't be part of the name of a Backstage entity, which are replaced by a hyphen
+var invalidBackstageNameChars = regexp.MustCompile(`[^a-zA-Z0-9_.-]+`)
+
+// BackstageEntity is the Backstage entity of a module, a Resource or a Component, as written to its catalog-info.yaml.
+// See https://backstage.io/docs/features/software-catalog/descriptor-format
+type BackstageEntity struct {
+	ApiVersion string            `yaml:"apiVersion"`
+	Kind       string            `yaml:"kind"`
+	Metadata   BackstageMetadata `yaml:"metadata"`
+	Spec       BackstageSpec     `yaml:"spec"`
+}
+
+type BackstageMetadata struct {
+	Name        string   `yaml:"name"`
+	Title       string   `yaml:"title"`
+	Description string   `yaml:"description,omitempty"`
+	Tags        []string `yaml:"tags,omitempty"`
+}
+
+type BackstageSpec struct {
+	Type      string   `yaml:"type"`
+	Lifecycle string   `yaml:"lifecycle,omitempty"`
+	Owner     string   `yaml:"owner"`
+	System    string   `yaml:"system,omitempty"`
+	DependsOn []string `yaml:"dependsOn,omitempty"`
+}
+
+// BackstageCatalogFile is the entity of a module, along with the folder of the module, which its catalog-info.yaml is
+// written to
+type BackstageCatalogFile struct {
+	ModulePath string
+	Entity     BackstageEntity
+}
+
+// BackstageCatalog returns the Backstage entity of each module of the stack, sorted by path, with the given root dir,
+// usually the root of the repo, being the dir the names of the entities are derived from. The entities are described
+// by the catalog blocks of the modules, and depend on the entities of the dependencies of the modules. The modules that
+// are excluded, or that are outside of the root dir, are left out, like their dependencies. Also returns the paths of
+// the modules whose catalog block doesn't set an owner, which Backstage requires, so their owner is unknown.
+func (stack *Stack) BackstageCatalog(rootDir string) ([]BackstageCatalogFile, []string, error) {
+	canonicalRootDir, err := util.CanonicalPath(rootDir, ".")
+	if err != nil {
+		return nil, nil, err
+	}
+
+	files := map[string]*BackstageCatalogFile{}
+	modulesToExport := []*TerraformModule{}
+	for _, module := range stack.Modules {
+		if module.FlagExcluded || module.AssumeAlreadyApplied || !util.HasPathPrefix(module.Path, canonicalRootDir) {
+			continue
+		}
+		dir, err := util.GetPathRelativeTo(module.Path, canonicalRootDir)
+		if err != nil {
+			return nil, nil, err
+		}
+		files[module.Path] = &BackstageCatalogFile{ModulePath: module.Path, Entity: backstageEntity(module, dir, canonicalRootDir)}
+		modulesToExport = append(modulesToExport, module)
+	}
+
+	catalogFiles := []BackstageCatalogFile{}
+	modulesWithoutOwner := []string{}
+	for _, module := range modulesToExport {
+		file := files[module.Path]
+		for _, dependency := range module.Dependencies {
+			if dependencyFile, isExported := files[dependency.Path]; isExported {
+				file.Entity.Spec.DependsOn = append(file.Entity.Spec.DependsOn, backstageEntityRef(dependencyFile.Entity))
+			}
+		}
+		sort.Strings(file.Entity.Spec.DependsOn)
+
+		if file.Entity.Spec.Owner == backstageUnknownValue {
+			modulesWithoutOwner = append(modulesWithoutOwner, module.Path)
+		}
+		catalogFiles = append(catalogFiles, *file)
+	}
+
+	sort.Slice(catalogFiles, func(i, j int) bool { return catalogFiles[i].ModulePath < catalogFiles[j].ModulePath })
+	sort.Strings(modulesWithoutOwner)
+	return catalogFiles, modulesWithoutOwner, nil
+}
+
+// Return the entity of the given module, with the given dir relative to the root dir, without its dependencies
+func backstageEntity(module *TerraformModule, dir string, rootDir string) BackstageEntity {
+	entity := BackstageEntity{
+		ApiVersion: "backstage.io/v1alpha1",
+		Kind:       backstageDefaultKind,
+		Metadata:   BackstageMetadata{Name: backstageEntityName(dir, rootDir), Title: dir},
+		Spec:       BackstageSpec{Type: backstageDefaultType, Owner: backstageUnknownValue},
+	}
+
+	catalog := module.Config.Catalog
+	if catalog != nil {
+		if catalog.Kind != nil {
+			entity.Kind = *catalog.Kind
+		}
+		if catalog.Type != nil {
+			entity.Spec.Type = *catalog.Type
+		}
+		if catalog.Owner != nil {
+			entity.Spec.Owner = *catalog.Owner
+		}
+		if catalog.System != nil {
+			entity.Spec.System = *catalog.System
+		}
+		if catalog.Lifecycle != nil {
+			entity.Spec.Lifecycle = *catalog.Lifecycle
+		}
+		if catalog.Description != nil {
+			entity.Metadata.Description = *catalog.Description
+		}
+		if catalog.Tags != nil {
+			entity.Metadata.Tags = *catalog.Tags
+		}
+	}
+
+	// Backstage requires the lifecycle of components, but not of resources
+	if entity.Kind == "Component" && entity.Spec.Lifecycle == "" {
+		entity.Spec.Lifecycle = backstageUnknownValue
+	}
+	return entity
+}
+
+// Return the reference other entities use to point at the given entity, e.g. resource:live-vpc
+func backstageEntityRef(entity BackstageEntity) string {
+	return strings.ToLower(entity.Kind) + ":" + entity.Metadata.Name
+}
+
+// The entities are named after the dir of their module, with the chars Backstage doesn't allow in names replaced by
+// hyphens, and the module at the root named after the root dir
+func backstageEntityName(dir string, rootDir string) string {
+	if dir == "." {
+		dir = filepath.Base(rootDir)
+	}
+	name := strings.Trim(invalidBackstageNameChars.ReplaceAllString(dir, "-"), "-_.")
+	if len(name) > backstageMaxNameLength {
+		name = strings.TrimRight(name[:backstageMaxNameLength], "-_.")
+	}
+	return name
+}
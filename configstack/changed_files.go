@@ -0,0 +1,183 @@
+package configstack
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/gruntwork-io/terragrunt/cli/tfsource"
+	"github.com/gruntwork-io/terragrunt/config"
+	"github.com/gruntwork-io/terragrunt/errors"
+	"github.com/gruntwork-io/terragrunt/options"
+	"github.com/gruntwork-io/terragrunt/shell"
+	"github.com/gruntwork-io/terragrunt/util"
+)
+
+// flagUnchangedModules flags all the modules that are not affected by the files changed since the git ref specified
+// via the terragrunt-changed-since CLI flag as excluded. A module is affected if a changed file is in its folder, was
+// read by its configuration (e.g. an included config or a file read with read_terragrunt_config), or is in its
+// Terraform source, when that's a local folder. If the terragrunt-include-changed-dependents CLI flag is set, the
+// modules that depend on an affected module, directly or not, are affected as well.
+func flagUnchangedModules(modules []*TerraformModule, terragruntOptions *options.TerragruntOptions) ([]*TerraformModule, error) {
+	if terragruntOptions.ChangedSince == "" {
+		return modules, nil
+	}
+
+	changedFiles, err := getChangedFiles(terragruntOptions.ChangedSince, terragruntOptions)
+	if err != nil {
+		return nil, err
+	}
+	terragruntOptions.Logger.Debugf("Files changed since %s: %v", terragruntOptions.ChangedSince, changedFiles)
+
+	return flagModulesNotAffectedByFiles(modules, changedFiles, terragruntOptions.IncludeChangedDependents)
+}
+
+// flagModulesNotAffectedByFiles flags all the modules that are not affected by any of the given canonical file paths as
+// excluded. If includeDependents is true, the modules that depend on an affected module are not excluded either.
+func flagModulesNotAffectedByFiles(modules []*TerraformModule, canonicalFiles []string, includeDependents bool) ([]*TerraformModule, error) {
+	affectedModules := map[string]bool{}
+	toVisit := []*TerraformModule{}
+	for _, module := range modules {
+		isAffected, err := moduleIsAffectedByFiles(module, canonicalFiles)
+		if err != nil {
+			return nil, err
+		}
+		if isAffected {
+			affectedModules[module.Path] = true
+			toVisit = append(toVisit, module)
+		}
+	}
+
+	if includeDependents {
+		// Walk from the affected modules to their dependents, so that the dependents of dependents are included too
+		dependents := dependentsOfModules(modules)
+		for len(toVisit) > 0 {
+			module := toVisit[0]
+			toVisit = toVisit[1:]
+			for _, dependent := range dependents[module.Path] {
+				if !affectedModules[dependent.Path] {
+					affectedModules[dependent.Path] = true
+					toVisit = append(toVisit, dependent)
+				}
+			}
+		}
+	}
+
+	for _, module := range modules {
+		if !affectedModules[module.Path] {
+			module.FlagExcluded = true
+		}
+	}
+
+	return modules, nil
+}
+
+// Returns true if any of the given canonical file paths is in the folder of the module, was read by its configuration,
+// or is in its Terraform source, if that's a local folder.
+func moduleIsAffectedByFiles(module *TerraformModule, canonicalFiles []string) (bool, error) {
+	if moduleReadsAnyFile(module, canonicalFiles) {
+		return true, nil
+	}
+
+	affectedDirs := []string{module.Path}
+
+	localSourceDir, err := getLocalTerraformSourceDir(module)
+	if err != nil {
+		return false, err
+	}
+	if localSourceDir != "" {
+		affectedDirs = append(affectedDirs, localSourceDir)
+	}
+
+	for _, file := range canonicalFiles {
+		for _, dir := range affectedDirs {
+			if util.HasPathPrefix(file, dir) {
+				return true, nil
+			}
+		}
+	}
+	return false, nil
+}
+
+// Return the canonical path of the folder Terragrunt copies the Terraform code of the module from, if its source is a
+// local folder. That's the part of the source before the double-slash (//), if any, as the whole folder is copied.
+// Returns an empty string if the module doesn't have a source, or if it's not a local folder.
+func getLocalTerraformSourceDir(module *TerraformModule) (string, error) {
+	if module.TerragruntOptions == nil {
+		return "", nil
+	}
+
+	source, err := config.GetTerraformSourceUrl(module.TerragruntOptions, &module.Config)
+	if err != nil || source == "" {
+		return "", err
+	}
+
+	terraformSource, err := tfsource.NewTerraformSource(source, module.TerragruntOptions.DownloadDir, module.Path, module.TerragruntOptions.Logger)
+	if err != nil {
+		return "", err
+	}
+	if !tfsource.IsLocalSource(terraformSource.CanonicalSourceURL) {
+		return "", nil
+	}
+	return terraformSource.CanonicalSourceURL.Path, nil
+}
+
+// Return the canonical paths of the files that changed since the given git ref in the git repo of the working dir.
+// These are the files changed by the commits since the current branch diverged from the ref, the uncommitted changes,
+// and the untracked files, so that changes that have not been committed yet can be checked locally.
+func getChangedFiles(ref string, terragruntOptions *options.TerragruntOptions) ([]string, error) {
+	// The path from the working dir to the root of the repo. Unlike the absolute path of the root, this doesn't resolve
+	// symlinks, so that the changed files can be compared to the paths of the modules.
+	pathToRepoRoot, err := runGitCommand(terragruntOptions.WorkingDir, ref, terragruntOptions, "rev-parse", "--show-cdup")
+	if err != nil {
+		return nil, err
+	}
+	repoRoot, err := util.CanonicalPath(strings.TrimSpace(pathToRepoRoot), terragruntOptions.WorkingDir)
+	if err != nil {
+		return nil, err
+	}
+
+	mergeBase, err := runGitCommand(repoRoot, ref, terragruntOptions, "merge-base", ref, "HEAD")
+	if err != nil {
+		return nil, err
+	}
+
+	// Both commands list the files relative to the root of the repo they're run in
+	changedFiles, err := runGitCommand(repoRoot, ref, terragruntOptions, "diff", "--name-only", strings.TrimSpace(mergeBase))
+	if err != nil {
+		return nil, err
+	}
+	untrackedFiles, err := runGitCommand(repoRoot, ref, terragruntOptions, "ls-files", "--others", "--exclude-standard")
+	if err != nil {
+		return nil, err
+	}
+
+	files := []string{}
+	for _, file := range strings.Split(changedFiles+"\n"+untrackedFiles, "\n") {
+		file = strings.TrimSpace(file)
+		if file == "" {
+			continue
+		}
+		files = append(files, util.JoinPath(repoRoot, file))
+	}
+	return util.RemoveDuplicatesFromList(files), nil
+}
+
+// Run the given git command in the given dir and return its stdout
+func runGitCommand(dir string, ref string, terragruntOptions *options.TerragruntOptions, args ...string) (string, error) {
+	output, err := shell.RunShellCommandWithOutput(terragruntOptions, dir, true, false, "git", args...)
+	if err != nil {
+		return "", errors.WithStackTrace(CouldNotGetChangedFiles{Ref: ref, Err: err})
+	}
+	return output.Stdout, nil
+}
+
+// Custom error types
+
+type CouldNotGetChangedFiles struct {
+	Ref string
+	Err error
+}
+
+func (err CouldNotGetChangedFiles) Error() string {
+	return fmt.Sprintf("Could not get the files changed since %s, which requires the working dir to be in a git repo and %s to exist: %v", err.Ref, err.Ref, err.Err)
+}
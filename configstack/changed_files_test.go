@@ -0,0 +1,122 @@
+package configstack
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/gruntwork-io/terragrunt/config"
+	"github.com/gruntwork-io/terragrunt/options"
+	"github.com/gruntwork-io/terragrunt/util"
+)
+
+func TestFlagModulesNotAffectedByFiles(t *testing.T) {
+	t.Parallel()
+
+	newModule := func(path string, source string, filesRead []string, dependencies ...*TerraformModule) *TerraformModule {
+		opts := mockOptions.Clone(path + "/" + config.DefaultTerragruntConfigPath)
+		opts.FilesRead = &options.FilesRead{}
+		for _, file := range filesRead {
+			opts.FilesRead.Add(file)
+		}
+		return &TerraformModule{
+			Path:              path,
+			Dependencies:      dependencies,
+			Config:            config.TerragruntConfig{Terraform: &config.TerraformConfig{Source: ptr(source)}},
+			TerragruntOptions: opts,
+		}
+	}
+
+	testCases := []struct {
+		name              string
+		changedFiles      []string
+		includeDependents bool
+		expectedIncluded  []string
+	}{
+		{"file in module folder", []string{"/repo/live/vpc/terragrunt.hcl"}, false, []string{"/repo/live/vpc"}},
+		{"file in module folder with dependents", []string{"/repo/live/vpc/terragrunt.hcl"}, true, []string{"/repo/live/vpc", "/repo/live/app", "/repo/live/web"}},
+		{"file in local source", []string{"/repo/modules/vpc/main.tf"}, false, []string{"/repo/live/vpc"}},
+		{"file read by config", []string{"/repo/live/common.hcl"}, false, []string{"/repo/live/app"}},
+		{"file in remote source module", []string{"/repo/modules/app/main.tf"}, false, []string{}},
+		{"unrelated file", []string{"/repo/README.md"}, true, []string{}},
+	}
+
+	for _, testCase := range testCases {
+		// Save the testCase in local scope so all the t.Run calls don't end up with the last item in the list
+		testCase := testCase
+
+		t.Run(testCase.name, func(t *testing.T) {
+			t.Parallel()
+
+			vpc := newModule("/repo/live/vpc", "../../modules/vpc", nil)
+			app := newModule("/repo/live/app", "git::git@github.com:acme/modules.git//app?ref=v1.0.0", []string{"/repo/live/common.hcl"}, vpc)
+			web := newModule("/repo/live/web", "", nil, app)
+
+			modules, err := flagModulesNotAffectedByFiles([]*TerraformModule{vpc, app, web}, testCase.changedFiles, testCase.includeDependents)
+			require.NoError(t, err)
+
+			included := []string{}
+			for _, module := range modules {
+				if !module.FlagExcluded {
+					included = append(included, module.Path)
+				}
+			}
+			assert.Equal(t, testCase.expectedIncluded, included)
+		})
+	}
+}
+
+func TestGetChangedFiles(t *testing.T) {
+	t.Parallel()
+
+	repoDir, err := ioutil.TempDir("", "changed-files")
+	require.NoError(t, err)
+	defer os.RemoveAll(repoDir)
+
+	runGit := func(args ...string) {
+		cmd := exec.Command("git", append([]string{"-c", "user.name=test", "-c", "user.email=test@example.com"}, args...)...)
+		cmd.Dir = repoDir
+		output, err := cmd.CombinedOutput()
+		require.NoError(t, err, string(output))
+	}
+	writeFile := func(path string, contents string) {
+		require.NoError(t, os.MkdirAll(filepath.Dir(filepath.Join(repoDir, path)), 0755))
+		require.NoError(t, ioutil.WriteFile(filepath.Join(repoDir, path), []byte(contents), 0644))
+	}
+
+	runGit("init", "--quiet")
+	writeFile("live/vpc/terragrunt.hcl", "")
+	writeFile("live/app/terragrunt.hcl", "")
+	runGit("add", "--all")
+	runGit("commit", "--quiet", "--message", "initial")
+	runGit("tag", "base")
+
+	// A committed change, an uncommitted change, and an untracked file
+	writeFile("live/app/terragrunt.hcl", "skip = true")
+	runGit("commit", "--quiet", "--all", "--message", "change")
+	writeFile("live/vpc/terragrunt.hcl", "skip = true")
+	writeFile("live/db/terragrunt.hcl", "")
+
+	terragruntOptions, err := options.NewTerragruntOptionsForTest(filepath.Join(repoDir, "live", config.DefaultTerragruntConfigPath))
+	require.NoError(t, err)
+	terragruntOptions.WorkingDir = filepath.Join(repoDir, "live")
+
+	changedFiles, err := getChangedFiles("base", terragruntOptions)
+	require.NoError(t, err)
+
+	canonicalRepoDir, err := util.CanonicalPath(repoDir, "")
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{
+		canonicalRepoDir + "/live/app/terragrunt.hcl",
+		canonicalRepoDir + "/live/vpc/terragrunt.hcl",
+		canonicalRepoDir + "/live/db/terragrunt.hcl",
+	}, changedFiles)
+
+	_, err = getChangedFiles("does-not-exist", terragruntOptions)
+	assert.Error(t, err)
+}
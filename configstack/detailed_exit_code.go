@@ -0,0 +1,127 @@
+package configstack
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/gruntwork-io/terragrunt/options"
+	"github.com/gruntwork-io/terragrunt/shell"
+	"github.com/gruntwork-io/terragrunt/util"
+)
+
+// The flag that makes terraform plan exit with 2 when there are changes
+const TERRAFORM_DETAILED_EXITCODE_FLAG = "-detailed-exitcode"
+
+// The exit code terraform plan uses with -detailed-exitcode to signal that the plan succeeded and has changes
+const DETAILED_EXIT_CODE_CHANGES = 2
+
+// DetailedExitCodes records the exit code of each module when running plan -detailed-exitcode across a stack, so
+// that they can be aggregated into a single exit code for the whole run.
+type DetailedExitCodes struct {
+	exitCodes map[string]int
+	mutex     sync.Mutex
+}
+
+func newDetailedExitCodes() *DetailedExitCodes {
+	return &DetailedExitCodes{exitCodes: map[string]int{}}
+}
+
+// Return true if the given terraform args are for a plan with detailed exit codes
+func usesDetailedExitCode(terraformCliArgs []string) bool {
+	return util.FirstArg(terraformCliArgs) == "plan" && util.ListContainsElement(terraformCliArgs, TERRAFORM_DETAILED_EXITCODE_FLAG)
+}
+
+// Set the exit code for the module at the given path
+func (detailedExitCodes *DetailedExitCodes) Set(path string, exitCode int) {
+	detailedExitCodes.mutex.Lock()
+	defer detailedExitCodes.mutex.Unlock()
+	detailedExitCodes.exitCodes[path] = exitCode
+}
+
+// Get returns the exit code recorded for the module at the given path, and whether one was recorded at all
+func (detailedExitCodes *DetailedExitCodes) Get(path string) (int, bool) {
+	detailedExitCodes.mutex.Lock()
+	defer detailedExitCodes.mutex.Unlock()
+	exitCode, recorded := detailedExitCodes.exitCodes[path]
+	return exitCode, recorded
+}
+
+// ModulesWithChanges returns the sorted paths of the modules whose plan has changes
+func (detailedExitCodes *DetailedExitCodes) ModulesWithChanges() []string {
+	detailedExitCodes.mutex.Lock()
+	defer detailedExitCodes.mutex.Unlock()
+
+	paths := []string{}
+	for path, exitCode := range detailedExitCodes.exitCodes {
+		if exitCode == DETAILED_EXIT_CODE_CHANGES {
+			paths = append(paths, path)
+		}
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+// wrapRunTerragrunt wraps the RunTerragrunt function of each module so that the exit code of its plan is recorded.
+// An exit code of 2 means the plan succeeded with changes, so it is not treated as an error: that way, the modules
+// that depend on it still get planned.
+func (detailedExitCodes *DetailedExitCodes) wrapRunTerragrunt(modules []*TerraformModule) {
+	for _, module := range modules {
+		module := module
+		runTerragrunt := module.TerragruntOptions.RunTerragrunt
+		module.TerragruntOptions.RunTerragrunt = func(terragruntOptions *options.TerragruntOptions) error {
+			err := runTerragrunt(terragruntOptions)
+			if err == nil {
+				detailedExitCodes.Set(module.Path, 0)
+				return nil
+			}
+
+			exitCode, exitCodeErr := shell.GetExitCode(err)
+			if exitCodeErr != nil {
+				exitCode = 1
+			}
+			detailedExitCodes.Set(module.Path, exitCode)
+
+			if exitCode == DETAILED_EXIT_CODE_CHANGES {
+				return nil
+			}
+			return err
+		}
+	}
+}
+
+// Log the exit code of each module that was planned and return the error for the whole run: the given error if any
+// module failed, a PlanHasChanges error, which exits with code 2, if any module has changes, or nil otherwise.
+func (detailedExitCodes *DetailedExitCodes) aggregate(terragruntOptions *options.TerragruntOptions, modules []*TerraformModule, runErr error) error {
+	lines := []string{}
+	for _, module := range modules {
+		if exitCode, recorded := detailedExitCodes.Get(module.Path); recorded {
+			lines = append(lines, fmt.Sprintf("  %s: %d", module.Path, exitCode))
+		}
+	}
+	sort.Strings(lines)
+	terragruntOptions.Logger.Infof("Exit codes of plan %s per module:\n%s", TERRAFORM_DETAILED_EXITCODE_FLAG, strings.Join(lines, "\n"))
+
+	if runErr != nil {
+		return runErr
+	}
+
+	if modulesWithChanges := detailedExitCodes.ModulesWithChanges(); len(modulesWithChanges) > 0 {
+		return PlanHasChanges(modulesWithChanges)
+	}
+
+	return nil
+}
+
+// Custom error types
+
+type PlanHasChanges []string
+
+func (err PlanHasChanges) Error() string {
+	return fmt.Sprintf("The plan has changes in the following modules: %s", strings.Join([]string(err), ", "))
+}
+
+func (err PlanHasChanges) ExitStatus() (int, error) {
+	return DETAILED_EXIT_CODE_CHANGES, nil
+}
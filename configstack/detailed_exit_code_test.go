@@ -0,0 +1,100 @@
+package configstack
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/gruntwork-io/terragrunt/config"
+	"github.com/gruntwork-io/terragrunt/options"
+	"github.com/gruntwork-io/terragrunt/shell"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type mockExitCodeError int
+
+func (err mockExitCodeError) Error() string {
+	return fmt.Sprintf("exit status %d", int(err))
+}
+
+func (err mockExitCodeError) ExitStatus() (int, error) {
+	return int(err), nil
+}
+
+func moduleWithExitCode(t *testing.T, path string, exitCode int, dependencies ...*TerraformModule) *TerraformModule {
+	opts, err := options.NewTerragruntOptionsForTest(path)
+	require.NoError(t, err)
+	opts.RunTerragrunt = func(terragruntOptions *options.TerragruntOptions) error {
+		if exitCode == 0 {
+			return nil
+		}
+		return mockExitCodeError(exitCode)
+	}
+	return &TerraformModule{
+		Path:              path,
+		Dependencies:      dependencies,
+		Config:            config.TerragruntConfig{},
+		TerragruntOptions: opts,
+	}
+}
+
+func runModulesWithDetailedExitCodes(t *testing.T, modules []*TerraformModule) (*DetailedExitCodes, error) {
+	terragruntOptions, err := options.NewTerragruntOptionsForTest("stack")
+	require.NoError(t, err)
+
+	detailedExitCodes := newDetailedExitCodes()
+	detailedExitCodes.wrapRunTerragrunt(modules)
+	err = RunModules(modules, options.DEFAULT_PARALLELISM)
+	return detailedExitCodes, detailedExitCodes.aggregate(terragruntOptions, modules, err)
+}
+
+func TestDetailedExitCodesNoChanges(t *testing.T) {
+	t.Parallel()
+
+	moduleA := moduleWithExitCode(t, "a", 0)
+	moduleB := moduleWithExitCode(t, "b", 0, moduleA)
+
+	_, err := runModulesWithDetailedExitCodes(t, []*TerraformModule{moduleA, moduleB})
+	assert.NoError(t, err)
+}
+
+func TestDetailedExitCodesChangesDoNotBlockDependents(t *testing.T) {
+	t.Parallel()
+
+	moduleA := moduleWithExitCode(t, "a", 2)
+	moduleB := moduleWithExitCode(t, "b", 0, moduleA)
+
+	detailedExitCodes, err := runModulesWithDetailedExitCodes(t, []*TerraformModule{moduleA, moduleB})
+	assert.Equal(t, PlanHasChanges{"a"}, err)
+
+	exitCode, err := shell.GetExitCode(err)
+	require.NoError(t, err)
+	assert.Equal(t, 2, exitCode)
+
+	exitCode, recorded := detailedExitCodes.Get("b")
+	assert.True(t, recorded)
+	assert.Equal(t, 0, exitCode)
+}
+
+func TestDetailedExitCodesErrorsTakePrecedenceOverChanges(t *testing.T) {
+	t.Parallel()
+
+	moduleA := moduleWithExitCode(t, "a", 2)
+	moduleB := moduleWithExitCode(t, "b", 1)
+
+	detailedExitCodes, err := runModulesWithDetailedExitCodes(t, []*TerraformModule{moduleA, moduleB})
+	require.Error(t, err)
+
+	exitCode, err := shell.GetExitCode(err)
+	require.NoError(t, err)
+	assert.Equal(t, 1, exitCode)
+	assert.Equal(t, []string{"a"}, detailedExitCodes.ModulesWithChanges())
+}
+
+func TestUsesDetailedExitCode(t *testing.T) {
+	t.Parallel()
+
+	assert.True(t, usesDetailedExitCode([]string{"plan", "-input=false", "-detailed-exitcode"}))
+	assert.False(t, usesDetailedExitCode([]string{"plan"}))
+	assert.False(t, usesDetailedExitCode([]string{"apply", "-detailed-exitcode"}))
+}
@@ -0,0 +1,260 @@
+package configstack
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+
+	"github.com/gruntwork-io/terragrunt/util"
+)
+
+// The platforms the stack can be exported to
+const (
+	ExportPlatformSpacelift = "spacelift"
+	ExportPlatformEnv0      = "env0"
+)
+
+var ExportPlatforms = []string{ExportPlatformSpacelift, ExportPlatformEnv0}
+
+// TerraformJsonConfig is a Terraform configuration in the JSON syntax, with the variables and resources that manage the
+// modules of the stack on a platform. See https://developer.hashicorp.com/terraform/language/syntax/json
+type TerraformJsonConfig struct {
+	Variable map[string]TerraformJsonVariable  `json:"variable"`
+	Resource map[string]map[string]interface{} `json:"resource"`
+}
+
+type TerraformJsonVariable struct {
+	Type        string `json:"type"`
+	Description string `json:"description"`
+	Default     string `json:"default,omitempty"`
+}
+
+// SpaceliftStack is a spacelift_stack resource, which runs terragrunt in the folder of a module. See
+// https://registry.terraform.io/providers/spacelift-io/spacelift/latest/docs/resources/stack
+type SpaceliftStack struct {
+	Name        string              `json:"name"`
+	Repository  string              `json:"repository"`
+	Branch      string              `json:"branch"`
+	ProjectRoot string              `json:"project_root"`
+	Terragrunt  SpaceliftTerragrunt `json:"terragrunt"`
+}
+
+type SpaceliftTerragrunt struct {
+	// Each stack runs its own module, and the stack dependencies run them in order, so run-all isn't needed
+	UseRunAll bool `json:"use_run_all"`
+}
+
+// SpaceliftStackDependency is a spacelift_stack_dependency resource, which makes a stack run after the stack it depends
+// on. See https://registry.terraform.io/providers/spacelift-io/spacelift/latest/docs/resources/stack_dependency
+type SpaceliftStackDependency struct {
+	StackId          string `json:"stack_id"`
+	DependsOnStackId string `json:"depends_on_stack_id"`
+}
+
+// Env0Template is an env0_template resource of type terragrunt, which environments of a module are deployed from. See
+// https://registry.terraform.io/providers/env0/env0/latest/docs/resources/template
+type Env0Template struct {
+	Name              string `json:"name"`
+	Type              string `json:"type"`
+	Repository        string `json:"repository"`
+	Revision          string `json:"revision"`
+	Path              string `json:"path"`
+	TerragruntVersion string `json:"terragrunt_version"`
+}
+
+// Env0Workflow is an env0 workflow file, env0.workflow.yaml, which deploys an environment of each module, after the
+// environments of its dependencies. See https://docs.env0.com/docs/workflows
+type Env0Workflow struct {
+	Environments map[string]Env0WorkflowEnvironment `yaml:"environments"`
+}
+
+type Env0WorkflowEnvironment struct {
+	Name         string   `yaml:"name"`
+	TemplateName string   `yaml:"templateName"`
+	Needs        []string `yaml:"needs,omitempty"`
+}
+
+// A module of the stack, as exported to a platform
+type exportedModule struct {
+	// The name of the resources of the module, which is unique among the modules
+	Name string
+	// The folder of the module, relative to the root dir
+	Dir string
+	// The names of the exported modules the module depends on
+	Dependencies []string
+}
+
+// The chars that can't be part of the name of a Terraform resource, and the ones it can start with
+var (
+	invalidResourceNameChars = regexp.MustCompile(`[^a-zA-Z0-9_-]`)
+	validResourceNameStart   = regexp.MustCompile(`^[a-zA-Z_]`)
+)
+
+// SpaceliftConfig returns the Terraform configuration of a Spacelift stack for each module of the stack, and of a stack
+// dependency for each of their dependencies, with the given root dir, usually the root of the repo, being the dir the
+// project roots of the stacks are relative to. The repository and branch of the stacks are variables of the
+// configuration. The modules that are excluded, or that are outside of the root dir, are left out, like their
+// dependencies.
+func (stack *Stack) SpaceliftConfig(rootDir string) (*TerraformJsonConfig, error) {
+	modules, err := stack.exportedModules(rootDir)
+	if err != nil {
+		return nil, err
+	}
+
+	stacks := map[string]interface{}{}
+	dependencies := map[string]interface{}{}
+	for _, module := range modules {
+		stacks[module.Name] = SpaceliftStack{
+			Name:        module.Dir,
+			Repository:  "${var.repository}",
+			Branch:      "${var.branch}",
+			ProjectRoot: module.Dir,
+		}
+		for _, dependency := range module.Dependencies {
+			dependencies[module.Name+"_"+dependency] = SpaceliftStackDependency{
+				StackId:          fmt.Sprintf("${spacelift_stack.%s.id}", module.Name),
+				DependsOnStackId: fmt.Sprintf("${spacelift_stack.%s.id}", dependency),
+			}
+		}
+	}
+
+	config := &TerraformJsonConfig{
+		Variable: map[string]TerraformJsonVariable{
+			"repository": {Type: "string", Description: "The name of the repository of the stacks, without the owner part"},
+			"branch":     {Type: "string", Description: "The branch the stacks track", Default: "main"},
+		},
+		Resource: map[string]map[string]interface{}{"spacelift_stack": stacks},
+	}
+	if len(dependencies) > 0 {
+		config.Resource["spacelift_stack_dependency"] = dependencies
+	}
+	return config, nil
+}
+
+// Env0Config returns the Terraform configuration of an env0 template of type terragrunt for each module of the stack,
+// and the env0 workflow that deploys them in the order of their dependencies, with the given root dir, usually the root
+// of the repo, being the dir the paths of the templates are relative to. The repository and revision of the templates,
+// and the version of terragrunt they run, are variables of the configuration, with the version defaulting to the
+// given one. The modules that are excluded, or that are outside of the root dir, are left out, like their dependencies.
+func (stack *Stack) Env0Config(rootDir string, terragruntVersion string) (*TerraformJsonConfig, *Env0Workflow, error) {
+	modules, err := stack.exportedModules(rootDir)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	templates := map[string]interface{}{}
+	workflow := &Env0Workflow{Environments: map[string]Env0WorkflowEnvironment{}}
+	for _, module := range modules {
+		templates[module.Name] = Env0Template{
+			Name:              module.Dir,
+			Type:              "terragrunt",
+			Repository:        "${var.repository}",
+			Revision:          "${var.revision}",
+			Path:              module.Dir,
+			TerragruntVersion: "${var.terragrunt_version}",
+		}
+		workflow.Environments[module.Name] = Env0WorkflowEnvironment{
+			Name:         module.Dir,
+			TemplateName: module.Dir,
+			Needs:        module.Dependencies,
+		}
+	}
+
+	config := &TerraformJsonConfig{
+		Variable: map[string]TerraformJsonVariable{
+			"repository":         {Type: "string", Description: "The URL of the repository of the templates"},
+			"revision":           {Type: "string", Description: "The branch or tag the templates deploy", Default: "main"},
+			"terragrunt_version": {Type: "string", Description: "The version of terragrunt the templates run", Default: terragruntVersion},
+		},
+		Resource: map[string]map[string]interface{}{"env0_template": templates},
+	}
+	return config, workflow, nil
+}
+
+// Return the modules of the stack that are exported, sorted by dir: those that aren't excluded, and are in the given
+// root dir, with only the dependencies that are exported too
+func (stack *Stack) exportedModules(rootDir string) ([]exportedModule, error) {
+	canonicalRootDir, err := util.CanonicalPath(rootDir, ".")
+	if err != nil {
+		return nil, err
+	}
+
+	dirsByPath := map[string]string{}
+	modulesToExport := []*TerraformModule{}
+	for _, module := range stack.Modules {
+		if module.FlagExcluded || module.AssumeAlreadyApplied || !util.HasPathPrefix(module.Path, canonicalRootDir) {
+			continue
+		}
+		dir, err := util.GetPathRelativeTo(module.Path, canonicalRootDir)
+		if err != nil {
+			return nil, err
+		}
+		dirsByPath[module.Path] = dir
+		modulesToExport = append(modulesToExport, module)
+	}
+	sort.Slice(modulesToExport, func(i, j int) bool {
+		return dirsByPath[modulesToExport[i].Path] < dirsByPath[modulesToExport[j].Path]
+	})
+
+	dirs := []string{}
+	for _, module := range modulesToExport {
+		dirs = append(dirs, dirsByPath[module.Path])
+	}
+	names := exportedModuleNames(dirs, canonicalRootDir)
+	namesByPath := map[string]string{}
+	for i, module := range modulesToExport {
+		namesByPath[module.Path] = names[i]
+	}
+
+	modules := []exportedModule{}
+	for _, module := range modulesToExport {
+		exported := exportedModule{Name: namesByPath[module.Path], Dir: dirsByPath[module.Path]}
+		for _, dependency := range module.Dependencies {
+			if dependencyName, isExported := namesByPath[dependency.Path]; isExported {
+				exported.Dependencies = append(exported.Dependencies, dependencyName)
+			}
+		}
+		sort.Strings(exported.Dependencies)
+		modules = append(modules, exported)
+	}
+	return modules, nil
+}
+
+// Return the names of the resources of the modules in the given dirs, sorted, as described by exportedModuleName.
+// Different dirs can end up with the same name, e.g. a.b/c and a_b/c, so all but the first of them get a numbered
+// suffix, e.g. a_b_c_2, that isn't the name of another module.
+func exportedModuleNames(dirs []string, rootDir string) []string {
+	names := []string{}
+	isTaken := map[string]bool{}
+	for _, dir := range dirs {
+		name := exportedModuleName(dir, rootDir)
+		names = append(names, name)
+		isTaken[name] = true
+	}
+
+	isUsed := map[string]bool{}
+	for i, name := range names {
+		if isUsed[name] {
+			for suffix := 2; ; suffix++ {
+				candidate := fmt.Sprintf("%s_%d", name, suffix)
+				if !isTaken[candidate] {
+					names[i] = candidate
+					isTaken[candidate] = true
+					break
+				}
+			}
+		}
+		isUsed[names[i]] = true
+	}
+	return names
+}
+
+// The resources of the modules are named after their dir, like the Atlantis projects, but without the chars Terraform
+// doesn't allow in names, and starting with a letter
+func exportedModuleName(dir string, rootDir string) string {
+	name := invalidResourceNameChars.ReplaceAllString(atlantisProjectName(dir, rootDir), "_")
+	if !validResourceNameStart.MatchString(name) {
+		name = "_" + name
+	}
+	return name
+}
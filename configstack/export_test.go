@@ -0,0 +1,32 @@
+package configstack
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExportedModuleName(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		dir      string
+		expected string
+	}{
+		{"live/app", "live_app"},
+		{"live/us-east-1/app.v2", "live_us-east-1_app_v2"},
+		{"1-networking/vpc", "_1-networking_vpc"},
+		{".", "infra"},
+	}
+
+	for _, testCase := range testCases {
+		assert.Equal(t, testCase.expected, exportedModuleName(testCase.dir, "/repos/infra"), testCase.dir)
+	}
+}
+
+func TestExportedModuleNamesWithCollisions(t *testing.T) {
+	t.Parallel()
+
+	dirs := []string{"a.b/c", "a_b/c", "a_b/c_2", "live/app", "live_app"}
+	assert.Equal(t, []string{"a_b_c", "a_b_c_3", "a_b_c_2", "live_app", "live_app_2"}, exportedModuleNames(dirs, "/repos/infra"))
+}
@@ -2,16 +2,14 @@ package configstack
 
 import (
 	"github.com/gruntwork-io/terragrunt/errors"
-	"github.com/gruntwork-io/terragrunt/util"
 )
 
 // Check for dependency cycles in the given list of modules and return an error if one is found
 func CheckForCycles(modules []*TerraformModule) error {
-	visitedPaths := []string{}
-	currentTraversalPaths := []string{}
+	search := &cycleSearch{visitedPaths: map[string]bool{}, currentTraversalPaths: map[string]bool{}}
 
 	for _, module := range modules {
-		err := checkForCyclesUsingDepthFirstSearch(module, &visitedPaths, &currentTraversalPaths)
+		err := search.checkForCyclesUsingDepthFirstSearch(module)
 		if err != nil {
 			return err
 		}
@@ -20,32 +18,52 @@ func CheckForCycles(modules []*TerraformModule) error {
 	return nil
 }
 
+// cycleSearch is the state of a depth-first-search for dependency cycles. The paths of the modules on the current
+// traversal are tracked both in a list, to show the proper order of the paths in a cycle, and in a map, so that checking
+// whether a module is on the current traversal doesn't take longer the deeper the traversal gets. This keeps the search
+// linear in the number of modules and dependencies, even for stacks with thousands of modules.
+type cycleSearch struct {
+	visitedPaths          map[string]bool
+	currentTraversalPaths map[string]bool
+	currentTraversal      []string
+}
+
 // Check for cycles using a depth-first-search as described here:
 // https://en.wikipedia.org/wiki/Topological_sorting#Depth-first_search
-//
-// Note that this method uses two lists, visitedPaths, and currentTraversalPaths, to track what nodes have already been
-// seen. We need to use lists to maintain ordering so we can show the proper order of paths in a cycle. Of course, a
-// list doesn't perform well with repeated contains() and remove() checks, so ideally we'd use an ordered Map (e.g.
-// Java's LinkedHashMap), but since Go doesn't have such a data structure built-in, and our lists are going to be very
-// small (at most, a few dozen paths), there is no point in worrying about performance.
-func checkForCyclesUsingDepthFirstSearch(module *TerraformModule, visitedPaths *[]string, currentTraversalPaths *[]string) error {
-	if util.ListContainsElement(*visitedPaths, module.Path) {
+func (search *cycleSearch) checkForCyclesUsingDepthFirstSearch(module *TerraformModule) error {
+	if search.visitedPaths[module.Path] {
 		return nil
 	}
 
-	if util.ListContainsElement(*currentTraversalPaths, module.Path) {
-		return errors.WithStackTrace(DependencyCycle(append(*currentTraversalPaths, module.Path)))
+	if search.currentTraversalPaths[module.Path] {
+		cycle := append([]string{}, search.currentTraversal...)
+		return errors.WithStackTrace(DependencyCycle(append(cycle, module.Path)))
 	}
 
-	*currentTraversalPaths = append(*currentTraversalPaths, module.Path)
+	search.currentTraversalPaths[module.Path] = true
+	search.currentTraversal = append(search.currentTraversal, module.Path)
 	for _, dependency := range module.Dependencies {
-		if err := checkForCyclesUsingDepthFirstSearch(dependency, visitedPaths, currentTraversalPaths); err != nil {
+		if err := search.checkForCyclesUsingDepthFirstSearch(dependency); err != nil {
 			return err
 		}
 	}
 
-	*visitedPaths = append(*visitedPaths, module.Path)
-	*currentTraversalPaths = util.RemoveElementFromList(*currentTraversalPaths, module.Path)
+	search.visitedPaths[module.Path] = true
+	delete(search.currentTraversalPaths, module.Path)
+	search.currentTraversal = search.currentTraversal[:len(search.currentTraversal)-1]
 
 	return nil
 }
+
+// Return the modules that depend on each module, by the path of the module. This is the reverse of the Dependencies of
+// the modules, which makes it possible to walk the graph from a module to its dependents without going through all the
+// modules every time.
+func dependentsOfModules(modules []*TerraformModule) map[string][]*TerraformModule {
+	dependents := map[string][]*TerraformModule{}
+	for _, module := range modules {
+		for _, dependency := range module.Dependencies {
+			dependents[dependency.Path] = append(dependents[dependency.Path], module)
+		}
+	}
+	return dependents
+}
@@ -0,0 +1,104 @@
+package configstack
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/gruntwork-io/terragrunt/config"
+)
+
+// The number of modules in the stacks the benchmarks below build the dependency graph of
+const benchmarkStackSize = 5000
+
+func BenchmarkCheckForCyclesInChain(b *testing.B) {
+	modules := linkedModules(chainOfModules(benchmarkStackSize))
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err := CheckForCycles(modules); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkCheckForCyclesInLayers(b *testing.B) {
+	modules := linkedModules(layersOfModules(benchmarkStackSize))
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err := CheckForCycles(modules); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkCrosslinkDependencies(b *testing.B) {
+	moduleMap := layersOfModules(benchmarkStackSize)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := crosslinkDependencies(moduleMap, nil); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkFlagModulesNotAffectedByFilesWithDependents(b *testing.B) {
+	modules := linkedModules(chainOfModules(benchmarkStackSize))
+	// The first module of the chain changed, which affects all the others
+	changedFiles := []string{modules[0].Path + "/terragrunt.hcl"}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := flagModulesNotAffectedByFiles(modules, changedFiles, true); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// Return the given number of modules, where each module depends on the one before it
+func chainOfModules(size int) map[string]*TerraformModule {
+	moduleMap := map[string]*TerraformModule{}
+	for i := 0; i < size; i++ {
+		module := &TerraformModule{Path: benchmarkModulePath(i), Config: config.TerragruntConfig{}}
+		if i > 0 {
+			module.Config.Dependencies = &config.ModuleDependencies{Paths: []string{benchmarkModulePath(i - 1)}}
+		}
+		moduleMap[module.Path] = module
+	}
+	return moduleMap
+}
+
+// Return the given number of modules in layers of 100, where each module depends on all the modules of the layer
+// before it, which makes for a lot more dependencies than modules
+func layersOfModules(size int) map[string]*TerraformModule {
+	const layerSize = 100
+
+	moduleMap := map[string]*TerraformModule{}
+	for i := 0; i < size; i++ {
+		module := &TerraformModule{Path: benchmarkModulePath(i), Config: config.TerragruntConfig{}}
+		layerStart := i - i%layerSize
+		if layerStart > 0 {
+			paths := []string{}
+			for j := layerStart - layerSize; j < layerStart; j++ {
+				paths = append(paths, benchmarkModulePath(j))
+			}
+			module.Config.Dependencies = &config.ModuleDependencies{Paths: paths}
+		}
+		moduleMap[module.Path] = module
+	}
+	return moduleMap
+}
+
+// Cross-link the dependencies of the given modules, in the order of their paths
+func linkedModules(moduleMap map[string]*TerraformModule) []*TerraformModule {
+	modules, err := crosslinkDependencies(moduleMap, nil)
+	if err != nil {
+		panic(err)
+	}
+	return modules
+}
+
+func benchmarkModulePath(i int) string {
+	return fmt.Sprintf("/stack/module-%05d", i)
+}
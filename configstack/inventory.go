@@ -0,0 +1,421 @@
+package configstack
+
+import (
+	"fmt"
+	"net/url"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/go-getter"
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/gohcl"
+	"github.com/hashicorp/hcl/v2/hclparse"
+
+	"github.com/gruntwork-io/terragrunt/errors"
+	"github.com/gruntwork-io/terragrunt/oci"
+	"github.com/gruntwork-io/terragrunt/util"
+)
+
+// The formats the inventory of the stack can be exported in
+const (
+	InventoryFormatJson      = "json"
+	InventoryFormatCycloneDX = "cyclonedx"
+)
+
+var InventoryFormats = []string{InventoryFormatJson, InventoryFormatCycloneDX}
+
+// The version of the CycloneDX spec of the exported BOMs
+const cycloneDXSpecVersion = "1.4"
+
+// The forced getter of a source, e.g. git:: in git::https://github.com/acme/modules.git
+var forcedGetterRegexp = regexp.MustCompile(`^([A-Za-z0-9]+)::(.+)$`)
+
+// The prefix of the hashes of the lock files that are the SHA-256 hex digests of the zip packages of the providers
+const providerZipHashPrefix = "zh:"
+
+// Inventory is the inventory of the modules and providers the units of a stack use, for supply-chain and audit tooling
+type Inventory struct {
+	Units     []InventoryUnit     `json:"units"`
+	Modules   []InventoryModule   `json:"modules"`
+	Providers []InventoryProvider `json:"providers"`
+}
+
+// InventoryUnit is a unit of the stack, i.e. a folder with a terragrunt.hcl, with the module its terraform source points
+// at, if any, and the providers its lock file pins
+type InventoryUnit struct {
+	// The folder of the unit, relative to the root dir
+	Path          string                  `json:"path"`
+	Module        string                  `json:"module,omitempty"`
+	ModuleVersion string                  `json:"module_version,omitempty"`
+	Providers     []InventoryUnitProvider `json:"providers"`
+	// The paths of the units the unit depends on
+	Dependencies []string `json:"dependencies"`
+}
+
+type InventoryUnitProvider struct {
+	Address     string `json:"address"`
+	Version     string `json:"version"`
+	Constraints string `json:"constraints,omitempty"`
+}
+
+// InventoryModule is a version of a module, along with the paths of the units that use it
+type InventoryModule struct {
+	Source  string   `json:"source"`
+	Version string   `json:"version,omitempty"`
+	Units   []string `json:"units"`
+}
+
+// InventoryProvider is a version of a provider, with the hashes of its packages, along with the paths of the units that
+// use it
+type InventoryProvider struct {
+	Address string   `json:"address"`
+	Version string   `json:"version"`
+	Hashes  []string `json:"hashes"`
+	Units   []string `json:"units"`
+}
+
+// The provider blocks of a .terraform.lock.hcl file. See
+// https://developer.hashicorp.com/terraform/language/files/dependency-lock
+type providerLockFile struct {
+	Providers []providerLock `hcl:"provider,block"`
+	Remain    hcl.Body       `hcl:",remain"`
+}
+
+type providerLock struct {
+	Address     string   `hcl:"address,label"`
+	Version     string   `hcl:"version,attr"`
+	Constraints *string  `hcl:"constraints,attr"`
+	Hashes      []string `hcl:"hashes,optional"`
+	Remain      hcl.Body `hcl:",remain"`
+}
+
+// Inventory returns the inventory of the stack, with the given root dir, usually the root of the repo, being the dir the
+// paths of the units are relative to. The modules are those the terraform sources of the units point at, with the ref of
+// the git sources, the version of the registry sources, or the tag of the OCI sources as their version, and the
+// providers are those the .terraform.lock.hcl files of the units pin. The modules that are excluded, or that are outside
+// of the root dir, are left out, like their dependencies. Also returns the paths of the units without a lock file, e.g.
+// because they were never initialized, whose providers are unknown.
+func (stack *Stack) Inventory(rootDir string) (*Inventory, []string, error) {
+	canonicalRootDir, err := util.CanonicalPath(rootDir, ".")
+	if err != nil {
+		return nil, nil, err
+	}
+
+	pathsByModulePath := map[string]string{}
+	modulesToExport := []*TerraformModule{}
+	for _, module := range stack.Modules {
+		if module.FlagExcluded || module.AssumeAlreadyApplied || !util.HasPathPrefix(module.Path, canonicalRootDir) {
+			continue
+		}
+		path, err := util.GetPathRelativeTo(module.Path, canonicalRootDir)
+		if err != nil {
+			return nil, nil, err
+		}
+		pathsByModulePath[module.Path] = path
+		modulesToExport = append(modulesToExport, module)
+	}
+
+	inventory := &Inventory{Units: []InventoryUnit{}, Modules: []InventoryModule{}, Providers: []InventoryProvider{}}
+	modules := map[string]*InventoryModule{}
+	providers := map[string]*InventoryProvider{}
+	unitsWithoutLockFile := []string{}
+	for _, module := range modulesToExport {
+		unit := InventoryUnit{Path: pathsByModulePath[module.Path], Providers: []InventoryUnitProvider{}, Dependencies: []string{}}
+
+		if module.Config.Terraform != nil && module.Config.Terraform.Source != nil && *module.Config.Terraform.Source != "" {
+			unit.Module, unit.ModuleVersion, err = inventoryModuleSource(*module.Config.Terraform.Source, module.Path, canonicalRootDir)
+			if err != nil {
+				return nil, nil, err
+			}
+			key := unit.Module + "@" + unit.ModuleVersion
+			if _, exists := modules[key]; !exists {
+				modules[key] = &InventoryModule{Source: unit.Module, Version: unit.ModuleVersion}
+			}
+			modules[key].Units = append(modules[key].Units, unit.Path)
+		}
+
+		lockFilePath := filepath.Join(module.Path, util.TerraformLockFile)
+		if !util.FileExists(lockFilePath) {
+			unitsWithoutLockFile = append(unitsWithoutLockFile, unit.Path)
+		} else {
+			locks, err := readProviderLocks(lockFilePath)
+			if err != nil {
+				return nil, nil, err
+			}
+			for _, lock := range locks {
+				unitProvider := InventoryUnitProvider{Address: lock.Address, Version: lock.Version}
+				if lock.Constraints != nil {
+					unitProvider.Constraints = *lock.Constraints
+				}
+				unit.Providers = append(unit.Providers, unitProvider)
+
+				key := lock.Address + "@" + lock.Version
+				if _, exists := providers[key]; !exists {
+					providers[key] = &InventoryProvider{Address: lock.Address, Version: lock.Version, Hashes: []string{}}
+				}
+				providers[key].Hashes = util.RemoveDuplicatesFromList(append(providers[key].Hashes, lock.Hashes...))
+				providers[key].Units = append(providers[key].Units, unit.Path)
+			}
+			sort.Slice(unit.Providers, func(i, j int) bool { return unit.Providers[i].Address < unit.Providers[j].Address })
+		}
+
+		for _, dependency := range module.Dependencies {
+			if dependencyPath, isExported := pathsByModulePath[dependency.Path]; isExported {
+				unit.Dependencies = append(unit.Dependencies, dependencyPath)
+			}
+		}
+		sort.Strings(unit.Dependencies)
+		inventory.Units = append(inventory.Units, unit)
+	}
+
+	for _, module := range modules {
+		sort.Strings(module.Units)
+		inventory.Modules = append(inventory.Modules, *module)
+	}
+	for _, provider := range providers {
+		sort.Strings(provider.Hashes)
+		sort.Strings(provider.Units)
+		inventory.Providers = append(inventory.Providers, *provider)
+	}
+
+	sort.Slice(inventory.Units, func(i, j int) bool { return inventory.Units[i].Path < inventory.Units[j].Path })
+	sort.Slice(inventory.Modules, func(i, j int) bool {
+		if inventory.Modules[i].Source != inventory.Modules[j].Source {
+			return inventory.Modules[i].Source < inventory.Modules[j].Source
+		}
+		return inventory.Modules[i].Version < inventory.Modules[j].Version
+	})
+	sort.Slice(inventory.Providers, func(i, j int) bool {
+		if inventory.Providers[i].Address != inventory.Providers[j].Address {
+			return inventory.Providers[i].Address < inventory.Providers[j].Address
+		}
+		return inventory.Providers[i].Version < inventory.Providers[j].Version
+	})
+	sort.Strings(unitsWithoutLockFile)
+	return inventory, unitsWithoutLockFile, nil
+}
+
+// Return the module the given terraform source of the unit in the given folder points at, and its version. The module is
+// the source without its query, and without the credentials of its URL, if any, so that they don't end up in the
+// inventory. The local sources are relative to the root dir, so that the units that share a local module agree on it.
+func inventoryModuleSource(source string, modulePath string, rootDir string) (string, string, error) {
+	if strings.HasPrefix(source, "./") || strings.HasPrefix(source, "../") || filepath.IsAbs(source) {
+		sourcePath := source
+		if !filepath.IsAbs(sourcePath) {
+			sourcePath = filepath.Join(modulePath, source)
+		}
+		relativePath, err := util.GetPathRelativeTo(sourcePath, rootDir)
+		return relativePath, "", err
+	}
+
+	sourceUrl, subdir := getter.SourceDirSubdir(source)
+	forcedGetter := ""
+	if matches := forcedGetterRegexp.FindStringSubmatch(sourceUrl); matches != nil {
+		forcedGetter = matches[1] + "::"
+		sourceUrl = matches[2]
+	}
+
+	moduleVersion := ""
+	if queryIndex := strings.Index(sourceUrl, "?"); queryIndex >= 0 {
+		if query, err := url.ParseQuery(sourceUrl[queryIndex+1:]); err == nil {
+			moduleVersion = query.Get("ref")
+			if moduleVersion == "" {
+				moduleVersion = query.Get("version")
+			}
+		}
+		sourceUrl = sourceUrl[:queryIndex]
+	}
+
+	if parsedUrl, err := url.Parse(sourceUrl); err == nil && parsedUrl.Scheme != "" {
+		switch {
+		case parsedUrl.Scheme == oci.SCHEME && forcedGetter == "":
+			reference, err := oci.ParseReference(parsedUrl)
+			if err != nil {
+				return "", "", err
+			}
+			sourceUrl = oci.SCHEME + "://" + reference.Registry + "/" + reference.Repository
+			moduleVersion = reference.Reference
+		case parsedUrl.User != nil && parsedUrl.Scheme != "ssh":
+			parsedUrl.User = nil
+			sourceUrl = parsedUrl.String()
+		}
+	}
+
+	if subdir != "" {
+		sourceUrl += "//" + subdir
+	}
+	return forcedGetter + sourceUrl, moduleVersion, nil
+}
+
+// Read the provider blocks of the given .terraform.lock.hcl file
+func readProviderLocks(lockFilePath string) ([]providerLock, error) {
+	file, diags := hclparse.NewParser().ParseHCLFile(lockFilePath)
+	if diags.HasErrors() {
+		return nil, errors.WithStackTrace(InvalidLockFile{Path: lockFilePath, Diags: diags})
+	}
+	var lockFile providerLockFile
+	if diags := gohcl.DecodeBody(file.Body, nil, &lockFile); diags.HasErrors() {
+		return nil, errors.WithStackTrace(InvalidLockFile{Path: lockFilePath, Diags: diags})
+	}
+	return lockFile.Providers, nil
+}
+
+// CycloneDXBom is a CycloneDX BOM in the JSON format. See https://cyclonedx.org/docs/1.4/json/
+type CycloneDXBom struct {
+	BomFormat    string                `json:"bomFormat"`
+	SpecVersion  string                `json:"specVersion"`
+	SerialNumber string                `json:"serialNumber,omitempty"`
+	Version      int                   `json:"version"`
+	Metadata     CycloneDXMetadata     `json:"metadata"`
+	Components   []CycloneDXComponent  `json:"components"`
+	Dependencies []CycloneDXDependency `json:"dependencies"`
+}
+
+type CycloneDXMetadata struct {
+	Timestamp string             `json:"timestamp"`
+	Tools     []CycloneDXTool    `json:"tools"`
+	Component CycloneDXComponent `json:"component"`
+}
+
+type CycloneDXTool struct {
+	Vendor  string `json:"vendor"`
+	Name    string `json:"name"`
+	Version string `json:"version,omitempty"`
+}
+
+type CycloneDXComponent struct {
+	Type               string                       `json:"type"`
+	BomRef             string                       `json:"bom-ref"`
+	Group              string                       `json:"group,omitempty"`
+	Name               string                       `json:"name"`
+	Version            string                       `json:"version,omitempty"`
+	Hashes             []CycloneDXHash              `json:"hashes,omitempty"`
+	ExternalReferences []CycloneDXExternalReference `json:"externalReferences,omitempty"`
+	Properties         []CycloneDXProperty          `json:"properties,omitempty"`
+}
+
+type CycloneDXHash struct {
+	Alg     string `json:"alg"`
+	Content string `json:"content"`
+}
+
+type CycloneDXExternalReference struct {
+	Type string `json:"type"`
+	Url  string `json:"url"`
+}
+
+type CycloneDXProperty struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+type CycloneDXDependency struct {
+	Ref       string   `json:"ref"`
+	DependsOn []string `json:"dependsOn"`
+}
+
+// CycloneDX returns the inventory as a CycloneDX BOM generated at the given time by the given version of terragrunt, with
+// the stack in the given root dir as the component the BOM describes. The units are application components, which
+// depend on the library components of their module and providers, and on the units they depend on. The SHA-256 hashes
+// of the packages of the providers are the zh: hashes of the lock files.
+func (inventory *Inventory) CycloneDX(rootDir string, terragruntVersion string, timestamp time.Time) *CycloneDXBom {
+	stackRef := "stack"
+	bom := &CycloneDXBom{
+		BomFormat:   "CycloneDX",
+		SpecVersion: cycloneDXSpecVersion,
+		Version:     1,
+		Metadata: CycloneDXMetadata{
+			Timestamp: timestamp.UTC().Format(time.RFC3339),
+			Tools:     []CycloneDXTool{{Vendor: "Gruntwork", Name: "terragrunt", Version: terragruntVersion}},
+			Component: CycloneDXComponent{Type: "application", BomRef: stackRef, Name: filepath.Base(rootDir)},
+		},
+		Components:   []CycloneDXComponent{},
+		Dependencies: []CycloneDXDependency{},
+	}
+
+	stackDependency := CycloneDXDependency{Ref: stackRef, DependsOn: []string{}}
+	for _, unit := range inventory.Units {
+		ref := cycloneDXUnitRef(unit.Path)
+		bom.Components = append(bom.Components, CycloneDXComponent{Type: "application", BomRef: ref, Name: unit.Path})
+		stackDependency.DependsOn = append(stackDependency.DependsOn, ref)
+
+		unitDependency := CycloneDXDependency{Ref: ref, DependsOn: []string{}}
+		if unit.Module != "" {
+			unitDependency.DependsOn = append(unitDependency.DependsOn, cycloneDXModuleRef(unit.Module, unit.ModuleVersion))
+		}
+		for _, provider := range unit.Providers {
+			unitDependency.DependsOn = append(unitDependency.DependsOn, cycloneDXProviderRef(provider.Address, provider.Version))
+		}
+		for _, dependency := range unit.Dependencies {
+			unitDependency.DependsOn = append(unitDependency.DependsOn, cycloneDXUnitRef(dependency))
+		}
+		bom.Dependencies = append(bom.Dependencies, unitDependency)
+	}
+	bom.Dependencies = append([]CycloneDXDependency{stackDependency}, bom.Dependencies...)
+
+	for _, module := range inventory.Modules {
+		component := CycloneDXComponent{
+			Type:    "library",
+			BomRef:  cycloneDXModuleRef(module.Source, module.Version),
+			Name:    module.Source,
+			Version: module.Version,
+		}
+		referenceType := "distribution"
+		if strings.HasPrefix(module.Source, "git::") || strings.HasPrefix(module.Source, "github.com/") || strings.HasPrefix(module.Source, "bitbucket.org/") {
+			referenceType = "vcs"
+		}
+		component.ExternalReferences = []CycloneDXExternalReference{{Type: referenceType, Url: module.Source}}
+		bom.Components = append(bom.Components, component)
+		bom.Dependencies = append(bom.Dependencies, CycloneDXDependency{Ref: component.BomRef, DependsOn: []string{}})
+	}
+
+	for _, provider := range inventory.Providers {
+		component := CycloneDXComponent{
+			Type:       "library",
+			BomRef:     cycloneDXProviderRef(provider.Address, provider.Version),
+			Name:       provider.Address,
+			Version:    provider.Version,
+			Properties: []CycloneDXProperty{{Name: "terraform:provider:address", Value: provider.Address}},
+		}
+		// The addresses of the providers are <host>/<namespace>/<type>
+		if addressParts := strings.Split(provider.Address, "/"); len(addressParts) == 3 {
+			component.Group = addressParts[0] + "/" + addressParts[1]
+			component.Name = addressParts[2]
+		}
+		for _, hash := range provider.Hashes {
+			if strings.HasPrefix(hash, providerZipHashPrefix) {
+				component.Hashes = append(component.Hashes, CycloneDXHash{Alg: "SHA-256", Content: strings.TrimPrefix(hash, providerZipHashPrefix)})
+			}
+		}
+		bom.Components = append(bom.Components, component)
+		bom.Dependencies = append(bom.Dependencies, CycloneDXDependency{Ref: component.BomRef, DependsOn: []string{}})
+	}
+
+	return bom
+}
+
+func cycloneDXUnitRef(path string) string {
+	return "unit:" + path
+}
+
+func cycloneDXModuleRef(source string, moduleVersion string) string {
+	return "module:" + source + "@" + moduleVersion
+}
+
+func cycloneDXProviderRef(address string, providerVersion string) string {
+	return "provider:" + address + "@" + providerVersion
+}
+
+// Custom error types
+
+type InvalidLockFile struct {
+	Path  string
+	Diags hcl.Diagnostics
+}
+
+func (err InvalidLockFile) Error() string {
+	return fmt.Sprintf("Could not read the providers of the lock file %s: %s", err.Path, err.Diags.Error())
+}
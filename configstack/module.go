@@ -5,6 +5,7 @@ import (
 	"path/filepath"
 	"sort"
 	"strings"
+	"sync"
 
 	"github.com/gruntwork-io/terragrunt/config"
 	"github.com/gruntwork-io/terragrunt/errors"
@@ -25,6 +26,10 @@ type TerraformModule struct {
 	TerragruntOptions    *options.TerragruntOptions
 	AssumeAlreadyApplied bool
 	FlagExcluded         bool
+
+	// The canonical paths of the dependencies in the config, which are looked up more than once while building the
+	// dependency graph. Use canonicalDependencyPaths to read them.
+	dependencyPaths []string
 }
 
 // Render this module as a human-readable string
@@ -64,7 +69,12 @@ func ResolveTerraformModules(terragruntConfigPaths []string, terragruntOptions *
 		return []*TerraformModule{}, err
 	}
 
-	finalModules, err := flagExcludedDirs(includedModules, terragruntOptions)
+	changedModules, err := flagUnchangedModules(includedModules, terragruntOptions)
+	if err != nil {
+		return []*TerraformModule{}, err
+	}
+
+	finalModules, err := flagExcludedDirs(changedModules, terragruntOptions)
 	if err != nil {
 		return []*TerraformModule{}, err
 	}
@@ -108,13 +118,13 @@ func flagExcludedDirs(modules []*TerraformModule, terragruntOptions *options.Ter
 	}
 
 	// Make sure all paths are canonical
-	canonicalExcludeDirs := []string{}
+	canonicalExcludeDirs := map[string]bool{}
 	for _, module := range excludeGlobMatches {
 		canonicalPath, err := util.CanonicalPath(module, terragruntOptions.WorkingDir)
 		if err != nil {
 			return nil, err
 		}
-		canonicalExcludeDirs = append(canonicalExcludeDirs, canonicalPath)
+		canonicalExcludeDirs[canonicalPath] = true
 	}
 
 	for _, module := range modules {
@@ -135,10 +145,11 @@ func flagExcludedDirs(modules []*TerraformModule, terragruntOptions *options.Ter
 }
 
 //flagIncludedDirs iterates over a module slice and flags all entries not in the list specified via the terragrunt-include-dir CLI flag  as excluded.
+//Modules that read one of the files specified via the terragrunt-queue-include-units-reading CLI flag are included as well.
 func flagIncludedDirs(modules []*TerraformModule, terragruntOptions *options.TerragruntOptions) ([]*TerraformModule, error) {
 
 	// If no IncludeDirs is specified return the modules list instantly
-	if len(terragruntOptions.IncludeDirs) == 0 {
+	if len(terragruntOptions.IncludeDirs) == 0 && len(terragruntOptions.QueueIncludeUnitsReading) == 0 {
 		// If we aren't given any include directories, but are given the strict include flag,
 		// return no modules.
 		if terragruntOptions.StrictInclude {
@@ -173,18 +184,28 @@ func flagIncludedDirs(modules []*TerraformModule, terragruntOptions *options.Ter
 	}
 
 	// Make sure all paths are canonical
-	canonicalIncludeDirs := []string{}
+	canonicalIncludeDirs := map[string]bool{}
 	for _, module := range includeGlobMatches {
 		canonicalPath, err := util.CanonicalPath(module, terragruntOptions.WorkingDir)
 		if err != nil {
 			return nil, err
 		}
-		canonicalIncludeDirs = append(canonicalIncludeDirs, canonicalPath)
+		canonicalIncludeDirs[canonicalPath] = true
+	}
+
+	canonicalFilesToInclude, err := util.CanonicalPaths(terragruntOptions.QueueIncludeUnitsReading, canonicalWorkingDir)
+	if err != nil {
+		return nil, err
 	}
 
 	for _, module := range modules {
-		if findModuleinPath(module, canonicalIncludeDirs) {
+		if findModuleinPath(module, canonicalIncludeDirs) || moduleReadsAnyFile(module, canonicalFilesToInclude) {
 			module.FlagExcluded = false
+			// In strict include mode, external dependencies are assumed to be applied, unless they were explicitly
+			// included, in which case the user wants them to run.
+			if terragruntOptions.StrictInclude {
+				module.AssumeAlreadyApplied = false
+			}
 		} else {
 			module.FlagExcluded = true
 		}
@@ -204,10 +225,18 @@ func flagIncludedDirs(modules []*TerraformModule, terragruntOptions *options.Ter
 	return modules, nil
 }
 
-// Returns true if a module is located under one of the target directories
-func findModuleinPath(module *TerraformModule, targetDirs []string) bool {
-	for _, targetDir := range targetDirs {
-		if module.Path == targetDir {
+// Returns true if a module is located under one of the target directories, given as a set of canonical paths
+func findModuleinPath(module *TerraformModule, targetDirs map[string]bool) bool {
+	return targetDirs[module.Path]
+}
+
+// Returns true if the configuration of the module read any of the given files while it was parsed
+func moduleReadsAnyFile(module *TerraformModule, canonicalFiles []string) bool {
+	if module.TerragruntOptions == nil || module.TerragruntOptions.FilesRead == nil {
+		return false
+	}
+	for _, file := range canonicalFiles {
+		if module.TerragruntOptions.FilesRead.Contains(file) {
 			return true
 		}
 	}
@@ -217,13 +246,34 @@ func findModuleinPath(module *TerraformModule, targetDirs []string) bool {
 // Go through each of the given Terragrunt configuration files and resolve the module that configuration file represents
 // into a TerraformModule struct. Note that this method will NOT fill in the Dependencies field of the TerraformModule
 // struct (see the crosslinkDependencies method for that). Return a map from module path to TerraformModule struct.
+//
+// The configuration files are parsed concurrently by a pool of workers, but the errors are returned in the order of the
+// given paths, so that the same error is reported no matter which worker finishes first.
 func resolveModules(canonicalTerragruntConfigPaths []string, terragruntOptions *options.TerragruntOptions, howTheseModulesWereFound string) (map[string]*TerraformModule, error) {
-	moduleMap := map[string]*TerraformModule{}
+	modules := make([]*TerraformModule, len(canonicalTerragruntConfigPaths))
+	errs := make([]error, len(canonicalTerragruntConfigPaths))
+
+	indexes := make(chan int)
+	var wg sync.WaitGroup
+	for worker := 0; worker < util.Min(terragruntOptions.DiscoveryParallelism(), len(canonicalTerragruntConfigPaths)); worker++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				modules[i], errs[i] = resolveTerraformModule(canonicalTerragruntConfigPaths[i], terragruntOptions, howTheseModulesWereFound)
+			}
+		}()
+	}
+	for i := range canonicalTerragruntConfigPaths {
+		indexes <- i
+	}
+	close(indexes)
+	wg.Wait()
 
-	for _, terragruntConfigPath := range canonicalTerragruntConfigPaths {
-		module, err := resolveTerraformModule(terragruntConfigPath, terragruntOptions, howTheseModulesWereFound)
-		if err != nil {
-			return moduleMap, err
+	moduleMap := map[string]*TerraformModule{}
+	for i, module := range modules {
+		if errs[i] != nil {
+			return moduleMap, errs[i]
 		}
 		if module != nil {
 			moduleMap[module.Path] = module
@@ -250,6 +300,13 @@ func resolveTerraformModule(terragruntConfigPath string, terragruntOptions *opti
 	// from, which is not what any of the modules will want.
 	opts.OriginalTerragruntConfigPath = terragruntConfigPath
 
+	// Keep track of the files read by each module so we can tell which ones to include for
+	// --terragrunt-queue-include-units-reading and --terragrunt-changed-since, or if the caller tracks them, e.g. to
+	// generate the Atlantis config.
+	if len(terragruntOptions.QueueIncludeUnitsReading) > 0 || terragruntOptions.ChangedSince != "" || terragruntOptions.FilesRead != nil {
+		opts.FilesRead = &options.FilesRead{}
+	}
+
 	// We only partially parse the config, only using the pieces that we need in this section. This config will be fully
 	// parsed at a later stage right before the action is run. This is to delay interpolation of functions until right
 	// before we call out to terraform.
@@ -264,6 +321,15 @@ func resolveTerraformModule(terragruntConfigPath string, terragruntOptions *opti
 			// Need for parsing out the dependencies
 			config.DependenciesBlock,
 			config.DependencyBlock,
+
+			// Need for scheduling the modules
+			config.ParallelismBlock,
+
+			// Need for notifying the webhooks of the run
+			config.NotificationBlock,
+
+			// Need for exporting the modules to service catalogs
+			config.CatalogBlock,
 		},
 	)
 	if err != nil {
@@ -334,7 +400,9 @@ func resolveExternalDependenciesForModules(moduleMap map[string]*TerraformModule
 			}
 
 			shouldApply := false
-			if !terragruntOptions.IgnoreExternalDependencies {
+			if terragruntOptions.IgnoreExternalDependencies {
+				terragruntOptions.Logger.Debugf("The --terragrunt-ignore-external-dependencies flag is set, so assuming module %s, which is a dependency of module %s, is already applied.", externalDependency.Path, module.Path)
+			} else {
 				shouldApply, err = confirmShouldApplyExternalDependency(module, externalDependency, terragruntOptions)
 				if err != nil {
 					return externalDependencies, err
@@ -363,17 +431,13 @@ func resolveExternalDependenciesForModules(moduleMap map[string]*TerraformModule
 // user is trying to apply-all or destroy-all. Note that this method will NOT fill in the Dependencies field of the
 // TerraformModule struct (see the crosslinkDependencies method for that).
 func resolveExternalDependenciesForModule(module *TerraformModule, moduleMap map[string]*TerraformModule, terragruntOptions *options.TerragruntOptions) (map[string]*TerraformModule, error) {
-	if module.Config.Dependencies == nil || len(module.Config.Dependencies.Paths) == 0 {
-		return map[string]*TerraformModule{}, nil
+	dependencyPaths, err := module.canonicalDependencyPaths()
+	if err != nil || len(dependencyPaths) == 0 {
+		return map[string]*TerraformModule{}, err
 	}
 
 	externalTerragruntConfigPaths := []string{}
-	for _, dependency := range module.Config.Dependencies.Paths {
-		dependencyPath, err := util.CanonicalPath(dependency, module.Path)
-		if err != nil {
-			return map[string]*TerraformModule{}, err
-		}
-
+	for _, dependencyPath := range dependencyPaths {
 		terragruntConfigPath := config.GetDefaultConfigPath(dependencyPath)
 		if _, alreadyContainsModule := moduleMap[dependencyPath]; !alreadyContainsModule {
 			externalTerragruntConfigPaths = append(externalTerragruntConfigPaths, terragruntConfigPath)
@@ -387,6 +451,11 @@ func resolveExternalDependenciesForModule(module *TerraformModule, moduleMap map
 // Confirm with the user whether they want Terragrunt to assume the given dependency of the given module is already
 // applied. If the user selects "yes", then Terragrunt will apply that module as well.
 func confirmShouldApplyExternalDependency(module *TerraformModule, dependency *TerraformModule, terragruntOptions *options.TerragruntOptions) (bool, error) {
+	if terragruntOptions.StrictInclude {
+		terragruntOptions.Logger.Debugf("The --terragrunt-strict-include flag is set, so assuming module %s, which is an external dependency of module %s, is already applied. It will only be run if it's in one of the included directories.", dependency.Path, module.Path)
+		return false, nil
+	}
+
 	if terragruntOptions.IncludeExternalDependencies {
 		terragruntOptions.Logger.Debugf("The --terragrunt-include-external-dependencies flag is set, so automatically including all external dependencies, and will run this command against module %s, which is a dependency of module %s.", dependency.Path, module.Path)
 		return true, nil
@@ -441,21 +510,17 @@ func crosslinkDependencies(moduleMap map[string]*TerraformModule, canonicalTerra
 func getDependenciesForModule(module *TerraformModule, moduleMap map[string]*TerraformModule, terragruntConfigPaths []string) ([]*TerraformModule, error) {
 	dependencies := []*TerraformModule{}
 
-	if module.Config.Dependencies == nil || len(module.Config.Dependencies.Paths) == 0 {
+	dependencyModulePaths, err := module.canonicalDependencyPaths()
+	if err != nil {
 		return dependencies, nil
 	}
 
-	for _, dependencyPath := range module.Config.Dependencies.Paths {
-		dependencyModulePath, err := util.CanonicalPath(dependencyPath, module.Path)
-		if err != nil {
-			return dependencies, nil
-		}
-
+	for i, dependencyModulePath := range dependencyModulePaths {
 		dependencyModule, foundModule := moduleMap[dependencyModulePath]
 		if !foundModule {
 			err := UnrecognizedDependency{
 				ModulePath:            module.Path,
-				DependencyPath:        dependencyPath,
+				DependencyPath:        module.Config.Dependencies.Paths[i],
 				TerragruntConfigPaths: terragruntConfigPaths,
 			}
 			return dependencies, errors.WithStackTrace(err)
@@ -466,6 +531,25 @@ func getDependenciesForModule(module *TerraformModule, moduleMap map[string]*Ter
 	return dependencies, nil
 }
 
+// Return the canonical paths of the dependencies in the config of the module. They're worked out the first time this is
+// called, and reused afterwards, as resolving the same relative paths over and over adds up for large stacks.
+func (module *TerraformModule) canonicalDependencyPaths() ([]string, error) {
+	if module.dependencyPaths != nil || module.Config.Dependencies == nil {
+		return module.dependencyPaths, nil
+	}
+
+	dependencyPaths := make([]string, 0, len(module.Config.Dependencies.Paths))
+	for _, dependencyPath := range module.Config.Dependencies.Paths {
+		canonicalPath, err := util.CanonicalPath(dependencyPath, module.Path)
+		if err != nil {
+			return nil, err
+		}
+		dependencyPaths = append(dependencyPaths, canonicalPath)
+	}
+	module.dependencyPaths = dependencyPaths
+	return dependencyPaths, nil
+}
+
 // Return the keys for the given map in sorted order. This is used to ensure we always iterate over maps of modules
 // in a consistent order (Go does not guarantee iteration order for maps, and usually makes it random)
 func getSortedKeys(modules map[string]*TerraformModule) []string {
@@ -692,6 +692,100 @@ func TestResolveTerraformModulesMultipleModulesWithExternalDependencies(t *testi
 	assertModuleListsEqual(t, expected, actualModules)
 }
 
+func TestResolveTerraformModulesMultipleModulesWithExternalDependenciesStrictInclude(t *testing.T) {
+	t.Parallel()
+
+	opts, _ := options.NewTerragruntOptionsForTest("running_module_test")
+	opts.IncludeDirs = []string{canonical(t, "../test/fixture-modules/module-g")}
+	opts.StrictInclude = true
+	opts.IncludeExternalDependencies = true
+
+	moduleF := &TerraformModule{
+		Path:                 canonical(t, "../test/fixture-modules/module-f"),
+		Dependencies:         []*TerraformModule{},
+		Config:               config.TerragruntConfig{IsPartial: true},
+		TerragruntOptions:    opts.Clone(canonical(t, "../test/fixture-modules/module-f/"+config.DefaultTerragruntConfigPath)),
+		AssumeAlreadyApplied: true,
+		FlagExcluded:         true,
+	}
+
+	moduleG := &TerraformModule{
+		Path:         canonical(t, "../test/fixture-modules/module-g"),
+		Dependencies: []*TerraformModule{moduleF},
+		Config: config.TerragruntConfig{
+			Dependencies: &config.ModuleDependencies{Paths: []string{"../module-f"}},
+			Terraform:    &config.TerraformConfig{Source: ptr("test")},
+			IsPartial:    true,
+		},
+		TerragruntOptions: opts.Clone(canonical(t, "../test/fixture-modules/module-g/"+config.DefaultTerragruntConfigPath)),
+	}
+
+	configPaths := []string{"../test/fixture-modules/module-g/" + config.DefaultTerragruntConfigPath}
+	expected := []*TerraformModule{moduleF, moduleG}
+
+	actualModules, actualErr := ResolveTerraformModules(configPaths, opts, mockHowThesePathsWereFound)
+	assert.Nil(t, actualErr, "Unexpected error: %v", actualErr)
+	assertModuleListsEqual(t, expected, actualModules)
+}
+
+func TestResolveTerraformModulesMultipleModulesWithExternalDependenciesStrictIncludeExplicitlyIncluded(t *testing.T) {
+	t.Parallel()
+
+	opts, _ := options.NewTerragruntOptionsForTest("running_module_test")
+	opts.IncludeDirs = []string{canonical(t, "../test/fixture-modules/module-g"), canonical(t, "../test/fixture-modules/module-f")}
+	opts.StrictInclude = true
+
+	moduleF := &TerraformModule{
+		Path:              canonical(t, "../test/fixture-modules/module-f"),
+		Dependencies:      []*TerraformModule{},
+		Config:            config.TerragruntConfig{IsPartial: true},
+		TerragruntOptions: opts.Clone(canonical(t, "../test/fixture-modules/module-f/"+config.DefaultTerragruntConfigPath)),
+	}
+
+	moduleG := &TerraformModule{
+		Path:         canonical(t, "../test/fixture-modules/module-g"),
+		Dependencies: []*TerraformModule{moduleF},
+		Config: config.TerragruntConfig{
+			Dependencies: &config.ModuleDependencies{Paths: []string{"../module-f"}},
+			Terraform:    &config.TerraformConfig{Source: ptr("test")},
+			IsPartial:    true,
+		},
+		TerragruntOptions: opts.Clone(canonical(t, "../test/fixture-modules/module-g/"+config.DefaultTerragruntConfigPath)),
+	}
+
+	configPaths := []string{"../test/fixture-modules/module-g/" + config.DefaultTerragruntConfigPath}
+	expected := []*TerraformModule{moduleF, moduleG}
+
+	actualModules, actualErr := ResolveTerraformModules(configPaths, opts, mockHowThesePathsWereFound)
+	assert.Nil(t, actualErr, "Unexpected error: %v", actualErr)
+	assertModuleListsEqual(t, expected, actualModules)
+}
+
+func TestResolveTerraformModulesQueueIncludeUnitsReading(t *testing.T) {
+	t.Parallel()
+
+	opts, _ := options.NewTerragruntOptionsForTest("running_module_test")
+	opts.QueueIncludeUnitsReading = []string{canonical(t, "../test/fixture-modules/common.hcl")}
+
+	configPaths := []string{
+		"../test/fixture-modules/module-reads-common/" + config.DefaultTerragruntConfigPath,
+		"../test/fixture-modules/module-reads-nothing/" + config.DefaultTerragruntConfigPath,
+	}
+
+	actualModules, actualErr := ResolveTerraformModules(configPaths, opts, mockHowThesePathsWereFound)
+	require.NoError(t, actualErr)
+	require.Len(t, actualModules, 2)
+
+	excludedByPath := map[string]bool{}
+	for _, module := range actualModules {
+		excludedByPath[module.Path] = module.FlagExcluded
+	}
+	assert.Equal(t, map[string]bool{
+		canonical(t, "../test/fixture-modules/module-reads-common"):  false,
+		canonical(t, "../test/fixture-modules/module-reads-nothing"): true,
+	}, excludedByPath)
+}
+
 func TestResolveTerraformModulesMultipleModulesWithNestedExternalDependencies(t *testing.T) {
 	t.Parallel()
 
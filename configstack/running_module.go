@@ -163,13 +163,13 @@ func removeFlagExcluded(modules map[string]*runningModule) map[string]*runningMo
 // as much concurrency as possible.
 func runModules(modules map[string]*runningModule, parallelism int) error {
 	var waitGroup sync.WaitGroup
-	var semaphore = make(chan struct{}, parallelism) // Make a semaphore from a buffered channel
+	scheduler := newModuleScheduler(modules, parallelism)
 
 	for _, module := range modules {
 		waitGroup.Add(1)
 		go func(module *runningModule) {
 			defer waitGroup.Done()
-			module.runModuleWhenReady(semaphore)
+			module.runModuleWhenReady(scheduler)
 		}(module)
 	}
 
@@ -191,19 +191,36 @@ func collectErrors(modules map[string]*runningModule) error {
 	return result.ErrorOrNil()
 }
 
-// Run a module once all of its dependencies have finished executing.
-func (module *runningModule) runModuleWhenReady(semaphore chan struct{}) {
+// Run a module once all of its dependencies have finished executing, and the scheduler has room for it.
+func (module *runningModule) runModuleWhenReady(scheduler *moduleScheduler) {
 	err := module.waitForDependencies()
-	semaphore <- struct{}{} // Add one to the buffered channel. Will block if parallelism limit is met
-	defer func() {
-		<-semaphore // Remove one from the buffered channel
-	}()
+	scheduled := scheduler.start(module) // Will block if the parallelism limits are met
+	defer scheduler.finish(scheduled)
 	if err == nil {
 		err = module.runNow()
+	} else {
+		module.recordSkipped(err)
 	}
 	module.moduleFinished(err)
 }
 
+// Record in the metrics of the units that this module wasn't run because of the given error of one of its dependencies
+func (module *runningModule) recordSkipped(dependencyErr error) {
+	if module.Module.AssumeAlreadyApplied {
+		return
+	}
+
+	reason := dependencyErr.Error()
+	if finishedWithError, isFinishedWithError := dependencyErr.(DependencyFinishedWithError); isFinishedWithError {
+		reason = fmt.Sprintf("Dependency %s finished with an error", finishedWithError.Dependency.Path)
+	}
+
+	terragruntOptions := module.Module.TerragruntOptions
+	if err := terragruntOptions.Metrics.SkipUnit(terragruntOptions.TerragruntConfigPath, terragruntOptions.TerraformCommand, reason); err != nil {
+		terragruntOptions.Logger.Warnf("Could not send the metrics of %s: %v", terragruntOptions.TerragruntConfigPath, err)
+	}
+}
+
 // Wait for all of this modules dependencies to finish executing. Return an error if any of those dependencies complete
 // with an error. Return immediately if this module has no dependencies.
 func (module *runningModule) waitForDependencies() error {
@@ -236,7 +253,11 @@ func (module *runningModule) runNow() error {
 		return nil
 	} else {
 		module.Module.TerragruntOptions.Logger.Debugf("Running module %s now", module.Module.Path)
-		return module.Module.TerragruntOptions.RunTerragrunt(module.Module.TerragruntOptions)
+		span := module.Module.TerragruntOptions.TelemetrySpan.StartChild("run_module", map[string]string{"terragrunt.module_path": module.Module.Path})
+		module.Module.TerragruntOptions.TelemetrySpan = span
+		err := module.Module.TerragruntOptions.RunTerragrunt(module.Module.TerragruntOptions)
+		span.End(err)
+		return err
 	}
 }
 
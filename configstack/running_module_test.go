@@ -2,11 +2,14 @@ package configstack
 
 import (
 	"fmt"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/gruntwork-io/terragrunt/config"
 	"github.com/gruntwork-io/terragrunt/options"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 var mockOptions, _ = options.NewTerragruntOptionsForTest("running_module_test")
@@ -686,6 +689,183 @@ func TestRunModulesMultipleModulesNoDependenciesSuccessNoParallelism(t *testing.
 	assert.True(t, cRan)
 }
 
+func TestRunModulesRespectsParallelismLimit(t *testing.T) {
+	t.Parallel()
+
+	var running int32
+	var maxRunning int32
+	runTerragrunt := func(terragruntOptions *options.TerragruntOptions) error {
+		current := atomic.AddInt32(&running, 1)
+		defer atomic.AddInt32(&running, -1)
+		for {
+			previousMax := atomic.LoadInt32(&maxRunning)
+			if current <= previousMax || atomic.CompareAndSwapInt32(&maxRunning, previousMax, current) {
+				break
+			}
+		}
+		time.Sleep(50 * time.Millisecond)
+		return nil
+	}
+
+	modules := []*TerraformModule{}
+	for _, path := range []string{"a", "b", "c", "d", "e"} {
+		opts, err := options.NewTerragruntOptionsForTest(path)
+		require.NoError(t, err)
+		opts.RunTerragrunt = runTerragrunt
+		modules = append(modules, &TerraformModule{
+			Path:              path,
+			Dependencies:      []*TerraformModule{},
+			Config:            config.TerragruntConfig{},
+			TerragruntOptions: opts,
+		})
+	}
+
+	err := RunModules(modules, 2)
+	assert.Nil(t, err, "Unexpected error: %v", err)
+	// How many modules overlap depends on the scheduling, so only the limit is checked
+	assert.LessOrEqual(t, atomic.LoadInt32(&maxRunning), int32(2))
+	assert.GreaterOrEqual(t, atomic.LoadInt32(&maxRunning), int32(1))
+}
+
+func TestRunModulesRespectsParallelismWeightsAndGroups(t *testing.T) {
+	t.Parallel()
+
+	weight := 2
+	exclusive := true
+	dbGroup := "db"
+	groupLimit := 1
+
+	testCases := []struct {
+		name        string
+		parallelism int
+		configs     map[string]*config.ParallelismConfig
+		tracked     []string
+		expectedMax int32
+	}{
+		{
+			"weights",
+			4,
+			map[string]*config.ParallelismConfig{
+				"a": {Weight: &weight}, "b": {Weight: &weight}, "c": {Weight: &weight}, "d": {Weight: &weight}, "e": {Weight: &weight},
+			},
+			[]string{"a", "b", "c", "d", "e"},
+			2,
+		},
+		{
+			"weight over the parallelism limit runs alone",
+			1,
+			map[string]*config.ParallelismConfig{"a": {Weight: &weight}},
+			[]string{"a", "b", "c", "d", "e"},
+			1,
+		},
+		{
+			"group limit",
+			options.DEFAULT_PARALLELISM,
+			map[string]*config.ParallelismConfig{
+				"a": {Group: &dbGroup, GroupLimit: &groupLimit}, "b": {Group: &dbGroup}, "c": {Group: &dbGroup},
+			},
+			[]string{"a", "b", "c"},
+			1,
+		},
+	}
+
+	for _, testCase := range testCases {
+		// Save the testCase in local scope so all the t.Run calls don't end up with the last item in the list
+		testCase := testCase
+
+		t.Run(testCase.name, func(t *testing.T) {
+			t.Parallel()
+
+			var running int32
+			var maxRunning int32
+			runTerragrunt := func(terragruntOptions *options.TerragruntOptions) error {
+				current := atomic.AddInt32(&running, 1)
+				defer atomic.AddInt32(&running, -1)
+				for {
+					previousMax := atomic.LoadInt32(&maxRunning)
+					if current <= previousMax || atomic.CompareAndSwapInt32(&maxRunning, previousMax, current) {
+						break
+					}
+				}
+				time.Sleep(50 * time.Millisecond)
+				return nil
+			}
+
+			modules := []*TerraformModule{}
+			for _, path := range []string{"a", "b", "c", "d", "e"} {
+				opts, err := options.NewTerragruntOptionsForTest(path)
+				require.NoError(t, err)
+				opts.RunTerragrunt = func(terragruntOptions *options.TerragruntOptions) error {
+					time.Sleep(50 * time.Millisecond)
+					return nil
+				}
+				for _, trackedPath := range testCase.tracked {
+					if trackedPath == path {
+						opts.RunTerragrunt = runTerragrunt
+					}
+				}
+				modules = append(modules, &TerraformModule{
+					Path:              path,
+					Dependencies:      []*TerraformModule{},
+					Config:            config.TerragruntConfig{Parallelism: testCase.configs[path]},
+					TerragruntOptions: opts,
+				})
+			}
+
+			err := RunModules(modules, testCase.parallelism)
+			assert.Nil(t, err, "Unexpected error: %v", err)
+			assert.Equal(t, testCase.expectedMax, atomic.LoadInt32(&maxRunning))
+		})
+	}
+
+	t.Run("exclusive", func(t *testing.T) {
+		t.Parallel()
+
+		var running int32
+		var exclusiveRunning int32
+		var violations int32
+		modules := []*TerraformModule{}
+		for _, path := range []string{"a", "b", "c", "d", "e"} {
+			path := path
+			opts, err := options.NewTerragruntOptionsForTest(path)
+			require.NoError(t, err)
+			opts.RunTerragrunt = func(terragruntOptions *options.TerragruntOptions) error {
+				current := atomic.AddInt32(&running, 1)
+				defer atomic.AddInt32(&running, -1)
+				if path == "c" {
+					atomic.StoreInt32(&exclusiveRunning, 1)
+					defer atomic.StoreInt32(&exclusiveRunning, 0)
+					if current != 1 {
+						atomic.AddInt32(&violations, 1)
+					}
+				} else if atomic.LoadInt32(&exclusiveRunning) == 1 {
+					atomic.AddInt32(&violations, 1)
+				}
+				time.Sleep(50 * time.Millisecond)
+				if path == "c" && atomic.LoadInt32(&running) != 1 {
+					atomic.AddInt32(&violations, 1)
+				}
+				return nil
+			}
+
+			parallelismConfig := (*config.ParallelismConfig)(nil)
+			if path == "c" {
+				parallelismConfig = &config.ParallelismConfig{Exclusive: &exclusive}
+			}
+			modules = append(modules, &TerraformModule{
+				Path:              path,
+				Dependencies:      []*TerraformModule{},
+				Config:            config.TerragruntConfig{Parallelism: parallelismConfig},
+				TerragruntOptions: opts,
+			})
+		}
+
+		err := RunModules(modules, options.DEFAULT_PARALLELISM)
+		assert.Nil(t, err, "Unexpected error: %v", err)
+		assert.Equal(t, int32(0), atomic.LoadInt32(&violations))
+	})
+}
+
 func TestRunModulesReverseOrderMultipleModulesNoDependenciesSuccess(t *testing.T) {
 	t.Parallel()
 
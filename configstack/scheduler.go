@@ -0,0 +1,161 @@
+package configstack
+
+import (
+	"sync"
+)
+
+// moduleScheduler decides when the modules that are ready to run, i.e. whose dependencies have finished, can start
+// running, based on the --terragrunt-parallelism limit and the parallelism block of each module:
+//
+// * Each module takes up a number of the parallelism slots while it runs, set by its weight, which defaults to 1.
+// * An exclusive module only runs when no other module is running, and no other module starts while it runs.
+// * The modules of a group with a group limit run at most that many at a time.
+//
+// The modules start in the order they become ready. When a module doesn't fit in the slots that are left, the modules
+// that became ready after it wait for it, rather than taking up the slots as they free up, so that a heavyweight or
+// exclusive module is never starved by a steady stream of lightweight ones. Modules waiting for their group limit don't
+// hold up the others, as the slots they'd take are not the ones that are short.
+type moduleScheduler struct {
+	capacity         int
+	used             int
+	running          int
+	exclusiveRunning bool
+	groupRunning     map[string]int
+	groupLimits      map[string]int
+	queue            []*scheduledModule
+	mutex            sync.Mutex
+	cond             *sync.Cond
+}
+
+// scheduledModule is a module that is waiting to run or running under a moduleScheduler
+type scheduledModule struct {
+	weight    int
+	exclusive bool
+	group     string
+	started   bool
+}
+
+// Create a scheduler for the given modules, which runs at most parallelism of them at a time. If the modules of a group
+// set different group limits, the smallest one is used.
+func newModuleScheduler(modules map[string]*runningModule, parallelism int) *moduleScheduler {
+	scheduler := &moduleScheduler{
+		capacity:     parallelism,
+		groupRunning: map[string]int{},
+		groupLimits:  map[string]int{},
+	}
+	scheduler.cond = sync.NewCond(&scheduler.mutex)
+
+	for _, module := range modules {
+		parallelismConfig := module.Module.Config.Parallelism
+		if parallelismConfig == nil || parallelismConfig.Group == nil || parallelismConfig.GroupLimit == nil {
+			continue
+		}
+		group := *parallelismConfig.Group
+		if limit, hasLimit := scheduler.groupLimits[group]; !hasLimit || *parallelismConfig.GroupLimit < limit {
+			scheduler.groupLimits[group] = *parallelismConfig.GroupLimit
+		}
+	}
+
+	return scheduler
+}
+
+// Block until the given module can start running, and take up the slots it needs. Call finish with the returned value
+// once the module is done running, to free up the slots.
+func (scheduler *moduleScheduler) start(module *runningModule) *scheduledModule {
+	scheduled := &scheduledModule{weight: 1}
+	if parallelismConfig := module.Module.Config.Parallelism; parallelismConfig != nil {
+		if parallelismConfig.Weight != nil {
+			scheduled.weight = *parallelismConfig.Weight
+		}
+		if parallelismConfig.Exclusive != nil {
+			scheduled.exclusive = *parallelismConfig.Exclusive
+		}
+		if parallelismConfig.Group != nil {
+			scheduled.group = *parallelismConfig.Group
+		}
+	}
+	// A module heavier than the parallelism limit would never fit, so it takes up all the slots instead, which means
+	// it runs alone
+	if scheduled.weight > scheduler.capacity {
+		scheduled.weight = scheduler.capacity
+	}
+
+	scheduler.mutex.Lock()
+	defer scheduler.mutex.Unlock()
+
+	scheduler.queue = append(scheduler.queue, scheduled)
+	scheduler.startWaitingModules()
+	if !scheduled.started {
+		module.Module.TerragruntOptions.Logger.Debugf("Module %s is waiting for a parallelism slot", module.Module.Path)
+	}
+	for !scheduled.started {
+		scheduler.cond.Wait()
+	}
+	return scheduled
+}
+
+// Free up the slots taken up by the given module, and start the waiting modules that fit in them
+func (scheduler *moduleScheduler) finish(scheduled *scheduledModule) {
+	scheduler.mutex.Lock()
+	defer scheduler.mutex.Unlock()
+
+	scheduler.used -= scheduled.weight
+	scheduler.running--
+	if scheduled.exclusive {
+		scheduler.exclusiveRunning = false
+	}
+	if scheduled.group != "" {
+		scheduler.groupRunning[scheduled.group]--
+	}
+	scheduler.startWaitingModules()
+}
+
+// Start the waiting modules that can run now, in the order they became ready. Must be called with the mutex held.
+func (scheduler *moduleScheduler) startWaitingModules() {
+	stillWaiting := []*scheduledModule{}
+	slotsReserved := false
+	for _, scheduled := range scheduler.queue {
+		if !scheduler.groupHasRoom(scheduled) {
+			stillWaiting = append(stillWaiting, scheduled)
+			continue
+		}
+		if slotsReserved || !scheduler.fits(scheduled) {
+			// Keep the slots that free up for this module, rather than giving them to the modules after it
+			slotsReserved = true
+			stillWaiting = append(stillWaiting, scheduled)
+			continue
+		}
+
+		scheduler.used += scheduled.weight
+		scheduler.running++
+		if scheduled.exclusive {
+			scheduler.exclusiveRunning = true
+		}
+		if scheduled.group != "" {
+			scheduler.groupRunning[scheduled.group]++
+		}
+		scheduled.started = true
+	}
+
+	if len(stillWaiting) < len(scheduler.queue) {
+		scheduler.queue = stillWaiting
+		scheduler.cond.Broadcast()
+	}
+}
+
+// Return true if the given module fits in the parallelism slots that are left
+func (scheduler *moduleScheduler) fits(scheduled *scheduledModule) bool {
+	if scheduler.exclusiveRunning {
+		return false
+	}
+	if scheduled.exclusive {
+		return scheduler.running == 0
+	}
+	return scheduler.used+scheduled.weight <= scheduler.capacity
+}
+
+// Return true if the group of the given module, if any, is below its group limit
+func (scheduler *moduleScheduler) groupHasRoom(scheduled *scheduledModule) bool {
+	limit, hasLimit := scheduler.groupLimits[scheduled.group]
+	return !hasLimit || scheduler.groupRunning[scheduled.group] < limit
+}
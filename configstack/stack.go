@@ -1,8 +1,9 @@
 package configstack
 
 import (
-	"bytes"
+	"bufio"
 	"fmt"
+	"io"
 	"sort"
 	"strings"
 
@@ -12,6 +13,10 @@ import (
 	"github.com/gruntwork-io/terragrunt/util"
 )
 
+// The number of bytes of the error output of each module kept in memory to summarize the errors of plan-all. Anything
+// past that is spilled into a temp file.
+const maxPlanErrorOutputInMemory = 64 * 1024
+
 // Represents a stack of Terraform modules (i.e. folders with Terraform templates) that you can "spin up" or
 // "spin down" in a single command
 type Stack struct {
@@ -38,8 +43,9 @@ func (stack *Stack) Run(terragruntOptions *options.TerragruntOptions) error {
 	stackCmd := terragruntOptions.TerraformCommand
 
 	// For any command that needs input, run in non-interactive mode to avoid cominglint stdin across multiple
-	// concurrent runs.
-	if util.ListContainsElement(config.TERRAFORM_COMMANDS_NEED_INPUT, stackCmd) {
+	// concurrent runs. When only a single module is run, its stdin isn't shared with any other run, so it can
+	// prompt for input like a regular terragrunt run.
+	if util.ListContainsElement(config.TERRAFORM_COMMANDS_NEED_INPUT, stackCmd) && !stack.runsSingleModule() {
 		// to support potential positional args in the args list, we append the input=false arg after the first element,
 		// which is the target command.
 		terragruntOptions.TerraformCliArgs = util.StringListInsert(terragruntOptions.TerraformCliArgs, "-input=false", 1)
@@ -58,17 +64,101 @@ func (stack *Stack) Run(terragruntOptions *options.TerragruntOptions) error {
 	}
 
 	if stackCmd == "plan" {
-		// We capture the out stream for each module
-		errorStreams := make([]bytes.Buffer, len(stack.Modules))
+		// We capture a copy of the error stream of each module, while still streaming it
+		errorStreams := make([]*util.SpillBuffer, len(stack.Modules))
 		for n, module := range stack.Modules {
-			module.TerragruntOptions.ErrWriter = &errorStreams[n]
+			errorStreams[n] = util.NewSpillBuffer(maxPlanErrorOutputInMemory)
+			module.TerragruntOptions.ErrWriter = io.MultiWriter(module.TerragruntOptions.ErrWriter, errorStreams[n])
 		}
 		defer stack.summarizePlanAllErrors(terragruntOptions, errorStreams)
 	}
 
+	// The output of the modules is streamed as they run, so when several of them run concurrently, each line is framed
+	// with the path of the module it comes from
+	if !stack.runsSingleModule() && !terragruntOptions.NoOutputPrefix {
+		prefixWriters := stack.prefixModuleOutput()
+		defer flushPrefixWriters(prefixWriters, terragruntOptions)
+	}
+
+	// With plan -detailed-exitcode, a module with changes exits with 2, which should neither fail the run nor its
+	// dependents. Instead, the exit codes of all modules are aggregated into the exit code of the whole run.
+	if usesDetailedExitCode(terragruntOptions.TerraformCliArgs) {
+		detailedExitCodes := newDetailedExitCodes()
+		detailedExitCodes.wrapRunTerragrunt(stack.Modules)
+		err := stack.runModules(terragruntOptions)
+		return detailedExitCodes.aggregate(terragruntOptions, stack.Modules, err)
+	}
+
+	return stack.runModules(terragruntOptions)
+}
+
+// Return true if only one of the modules in this stack is actually run, i.e. all the others are excluded or assumed to
+// be already applied
+func (stack *Stack) runsSingleModule() bool {
+	modulesToRun := 0
+	for _, module := range stack.Modules {
+		if !module.FlagExcluded && !module.AssumeAlreadyApplied {
+			modulesToRun++
+		}
+	}
+	return modulesToRun == 1
+}
+
+// Scan the given error output of a plan line by line, streaming it from the temp file it was spilled into, if any,
+// rather than reading it into memory. Returns whether the plan failed, and whether it failed on a remote state that
+// doesn't exist.
+func scanPlanErrors(errorStream *util.SpillBuffer) (bool, bool, error) {
+	reader, err := errorStream.Reader()
+	if err != nil {
+		return false, false, err
+	}
+	defer reader.Close()
+
+	planFailed := false
+	refersToRemoteState := false
+	scanner := bufio.NewScanner(reader)
+	scanner.Buffer(nil, maxPlanErrorOutputInMemory)
+	for scanner.Scan() {
+		line := scanner.Text()
+		planFailed = planFailed || strings.Contains(line, "Error running plan:")
+		refersToRemoteState = refersToRemoteState || strings.Contains(line, ": Resource 'data.terraform_remote_state.")
+	}
+	return planFailed, refersToRemoteState, errors.WithStackTrace(scanner.Err())
+}
+
+// Wrap the stdout and stderr writers of each module with a writer that prefixes each line with the path of the module,
+// like the log messages of the module. Returns the wrappers, which must be flushed once the modules are done running.
+// The stdout of commands with JSON output, e.g. output -json, is left as is, as it's meant to be parsed.
+func (stack *Stack) prefixModuleOutput() []*util.PrefixWriter {
+	prefixWriters := []*util.PrefixWriter{}
+	for _, module := range stack.Modules {
+		prefix := fmt.Sprintf("[%s] ", module.Path)
+		if !util.ListContainsElement(module.TerragruntOptions.TerraformCliArgs, "-json") {
+			writer := util.NewPrefixWriter(module.TerragruntOptions.Writer, prefix)
+			module.TerragruntOptions.Writer = writer
+			prefixWriters = append(prefixWriters, writer)
+		}
+		errWriter := util.NewPrefixWriter(module.TerragruntOptions.ErrWriter, prefix)
+		module.TerragruntOptions.ErrWriter = errWriter
+		prefixWriters = append(prefixWriters, errWriter)
+	}
+	return prefixWriters
+}
+
+// Write out the last, unterminated, line of output held on to by each of the given writers
+func flushPrefixWriters(prefixWriters []*util.PrefixWriter, terragruntOptions *options.TerragruntOptions) {
+	for _, prefixWriter := range prefixWriters {
+		if err := prefixWriter.Flush(); err != nil {
+			terragruntOptions.Logger.Warnf("Could not write the output of a module: %v", err)
+		}
+	}
+}
+
+// Run the modules of this stack in the order required by the command being run
+func (stack *Stack) runModules(terragruntOptions *options.TerragruntOptions) error {
 	if terragruntOptions.IgnoreDependencyOrder {
 		return RunModulesIgnoreOrder(stack.Modules, terragruntOptions.Parallelism)
-	} else if stackCmd == "destroy" {
+	} else if terragruntOptions.TerraformCommand == "destroy" {
 		return RunModulesReverseOrder(stack.Modules, terragruntOptions.Parallelism)
 	} else {
 		return RunModules(stack.Modules, terragruntOptions.Parallelism)
@@ -77,19 +167,21 @@ func (stack *Stack) Run(terragruntOptions *options.TerragruntOptions) error {
 
 // We inspect the error streams to give an explicit message if the plan failed because there were references to
 // remote states. `terraform plan` will fail if it tries to access remote state from dependencies and the plan
-// has never been applied on the dependency.
-func (stack *Stack) summarizePlanAllErrors(terragruntOptions *options.TerragruntOptions, errorStreams []bytes.Buffer) {
+// has never been applied on the dependency. The error streams were already streamed as the modules ran, so they're
+// not logged again.
+func (stack *Stack) summarizePlanAllErrors(terragruntOptions *options.TerragruntOptions, errorStreams []*util.SpillBuffer) {
 	for i, errorStream := range errorStreams {
-		output := errorStream.String()
-
-		if len(output) == 0 {
-			// We get empty buffer if stack execution completed without errors, so skip that to avoid logging too much
+		planFailed, refersToRemoteState, err := scanPlanErrors(errorStream)
+		if closeErr := errorStream.Close(); closeErr != nil {
+			terragruntOptions.Logger.Debugf("Could not remove the error output of %s: %v", stack.Modules[i].Path, closeErr)
+		}
+		if err != nil {
+			terragruntOptions.Logger.Debugf("Could not read the error output of %s: %v", stack.Modules[i].Path, err)
 			continue
 		}
 
-		terragruntOptions.Logger.Infoln(output)
-		if strings.Contains(output, "Error running plan:") {
-			if strings.Contains(output, ": Resource 'data.terraform_remote_state.") {
+		if planFailed {
+			if refersToRemoteState {
 				var dependenciesMsg string
 				if len(stack.Modules[i].Dependencies) > 0 {
 					dependenciesMsg = fmt.Sprintf(" contains dependencies to %v and", stack.Modules[i].Config.Dependencies.Paths)
@@ -111,7 +203,10 @@ func (stack *Stack) CheckForCycles() error {
 
 // Find all the Terraform modules in the subfolders of the working directory of the given TerragruntOptions and
 // assemble them into a Stack object that can be applied or destroyed in a single command
-func FindStackInSubfolders(terragruntOptions *options.TerragruntOptions) (*Stack, error) {
+func FindStackInSubfolders(terragruntOptions *options.TerragruntOptions) (stack *Stack, finalErr error) {
+	span := terragruntOptions.TelemetrySpan.StartChild("resolve_dependencies", map[string]string{"terragrunt.working_dir": terragruntOptions.WorkingDir})
+	defer func() { span.End(finalErr) }()
+
 	terragruntConfigFiles, err := config.FindConfigFilesInPath(terragruntOptions.WorkingDir, terragruntOptions)
 	if err != nil {
 		return nil, err
@@ -0,0 +1,86 @@
+package daemon
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+
+	"github.com/gruntwork-io/terragrunt/errors"
+)
+
+// Run sends the given request to the daemon listening on the given unix socket, and writes the output of the command
+// to stdout and stderr as it's streamed back. If the command fails, this returns a CommandFailed error with the exit
+// code of the command. If the daemon can't be reached, this returns a DaemonNotRunning error without running anything,
+// so that the caller can run the command itself instead.
+func Run(socketPath string, request Request, stdout io.Writer, stderr io.Writer) error {
+	connection, err := net.Dial("unix", socketPath)
+	if err != nil {
+		return errors.WithStackTrace(DaemonNotRunning{SocketPath: socketPath, Err: err})
+	}
+	defer connection.Close()
+
+	if err := json.NewEncoder(connection).Encode(request); err != nil {
+		return errors.WithStackTrace(DaemonNotRunning{SocketPath: socketPath, Err: err})
+	}
+
+	decoder := json.NewDecoder(connection)
+	for {
+		var msg message
+		if err := decoder.Decode(&msg); err != nil {
+			if err == io.EOF {
+				return errors.WithStackTrace(DaemonConnectionLost(socketPath))
+			}
+			return errors.WithStackTrace(err)
+		}
+
+		if len(msg.Stdout) > 0 {
+			if _, err := stdout.Write(msg.Stdout); err != nil {
+				return errors.WithStackTrace(err)
+			}
+		}
+		if len(msg.Stderr) > 0 {
+			if _, err := stderr.Write(msg.Stderr); err != nil {
+				return errors.WithStackTrace(err)
+			}
+		}
+
+		if msg.Done {
+			if msg.Error != "" {
+				return errors.WithStackTrace(CommandFailed{Message: msg.Error, ExitCode: msg.ExitCode})
+			}
+			return nil
+		}
+	}
+}
+
+// Custom error types
+
+type DaemonNotRunning struct {
+	SocketPath string
+	Err        error
+}
+
+func (err DaemonNotRunning) Error() string {
+	return fmt.Sprintf("Could not connect to the Terragrunt daemon at %s: %v", err.SocketPath, err.Err)
+}
+
+type DaemonConnectionLost string
+
+func (socketPath DaemonConnectionLost) Error() string {
+	return fmt.Sprintf("The connection to the Terragrunt daemon at %s was closed before the command finished", string(socketPath))
+}
+
+// CommandFailed is the error of a command the daemon ran, which makes the CLI exit with the exit code of the command
+type CommandFailed struct {
+	Message  string
+	ExitCode int
+}
+
+func (err CommandFailed) Error() string {
+	return err.Message
+}
+
+func (err CommandFailed) ExitStatus() (int, error) {
+	return err.ExitCode, nil
+}
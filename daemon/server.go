@@ -0,0 +1,213 @@
+// Package daemon implements the Terragrunt daemon, a long running process that runs the commands of the Terragrunt CLI
+// on its behalf, so that what Terragrunt keeps in memory, such as the parsed configs and the provider cache server,
+// stays warm between invocations. The CLI talks to the daemon over a unix socket: it sends its args, working dir and
+// env vars as a Request, and the daemon streams back the output of the command, followed by its result.
+//
+// The daemon runs one command at a time, as the commands it runs change the working dir and the env vars of the
+// process.
+package daemon
+
+import (
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/gruntwork-io/terragrunt/errors"
+	"github.com/gruntwork-io/terragrunt/util"
+)
+
+// Request is a command the CLI asks the daemon to run
+type Request struct {
+	// The args of the command, not including the name of the binary
+	Args []string `json:"args"`
+	// The working dir of the CLI, which the command runs in
+	WorkingDir string `json:"working_dir"`
+	// The env vars of the CLI, in the form key=value, which the command runs with
+	Env []string `json:"env"`
+}
+
+// message is what the daemon sends back to the CLI: either a chunk of the stdout or stderr of the command, or, in the
+// last message, the result of the command
+type message struct {
+	Stdout   []byte `json:"stdout,omitempty"`
+	Stderr   []byte `json:"stderr,omitempty"`
+	Done     bool   `json:"done,omitempty"`
+	ExitCode int    `json:"exit_code,omitempty"`
+	Error    string `json:"error,omitempty"`
+}
+
+// RunFunc runs the given request, writing the output of the command to stdout and stderr. If the command fails, it
+// returns the error along with the exit code the CLI should exit with.
+type RunFunc func(request Request, stdout io.Writer, stderr io.Writer) (int, error)
+
+// Server is the daemon server. Create it with NewServer, and start it with Start.
+type Server struct {
+	socketPath string
+	run        RunFunc
+	logger     *logrus.Entry
+
+	listener net.Listener
+	// Held while running a command, as the commands can't run concurrently
+	runMutex sync.Mutex
+	// Tracks the connections being served, so that Close can wait for them
+	connections sync.WaitGroup
+}
+
+// NewServer creates a daemon server that listens on the given unix socket and runs the requests it receives with the
+// given function
+func NewServer(socketPath string, run RunFunc, logger *logrus.Entry) *Server {
+	return &Server{socketPath: socketPath, run: run, logger: logger}
+}
+
+// Start listens on the unix socket and serves the requests in the background, until Close is called. If the socket
+// is left over from a daemon that didn't shut down cleanly, it's replaced, but if another daemon is listening on it,
+// this returns an error.
+func (server *Server) Start() error {
+	if util.FileExists(server.socketPath) {
+		if connection, err := net.Dial("unix", server.socketPath); err == nil {
+			connection.Close()
+			return errors.WithStackTrace(DaemonAlreadyRunning(server.socketPath))
+		}
+		if err := os.Remove(server.socketPath); err != nil {
+			return errors.WithStackTrace(err)
+		}
+	}
+
+	listener, err := listenPrivately(server.socketPath)
+	if err != nil {
+		return err
+	}
+	server.listener = listener
+
+	go server.serve()
+
+	server.logger.Infof("Terragrunt daemon listening on %s", server.socketPath)
+	return nil
+}
+
+// Close stops listening on the unix socket, removes it, and waits for the commands being run to finish
+func (server *Server) Close() error {
+	err := server.listener.Close()
+	if removeErr := os.Remove(server.socketPath); removeErr != nil && !os.IsNotExist(removeErr) && err == nil {
+		err = removeErr
+	}
+	server.connections.Wait()
+	return errors.WithStackTrace(err)
+}
+
+// Listen on a unix socket at the given path that only the current user may connect to, as whoever connects to it can
+// run commands as that user. The socket is created inside a new folder only the current user can access, and its mode
+// is set there, before it's moved to the given path, so that no other user can connect to it in the meantime, whatever
+// the umask and the permissions of the folder of the path are.
+func listenPrivately(socketPath string) (net.Listener, error) {
+	privateDir, err := ioutil.TempDir(filepath.Dir(socketPath), ".terragrunt-daemon")
+	if err != nil {
+		return nil, errors.WithStackTrace(err)
+	}
+	defer os.RemoveAll(privateDir)
+
+	privateSocketPath := filepath.Join(privateDir, "sock")
+	listener, err := net.Listen("unix", privateSocketPath)
+	if err != nil {
+		return nil, errors.WithStackTrace(err)
+	}
+	// The socket is moved, so closing the listener must not remove the socket at its original path, which Close does
+	// at the given path instead
+	listener.(*net.UnixListener).SetUnlinkOnClose(false)
+
+	if err := os.Chmod(privateSocketPath, 0600); err != nil {
+		listener.Close()
+		return nil, errors.WithStackTrace(err)
+	}
+	if err := os.Rename(privateSocketPath, socketPath); err != nil {
+		listener.Close()
+		return nil, errors.WithStackTrace(err)
+	}
+	return listener, nil
+}
+
+// Accept the connections of the CLI until the listener is closed
+func (server *Server) serve() {
+	for {
+		connection, err := server.listener.Accept()
+		if err != nil {
+			return
+		}
+		server.connections.Add(1)
+		go func() {
+			defer server.connections.Done()
+			defer connection.Close()
+			server.handleConnection(connection)
+		}()
+	}
+}
+
+// Run the request sent over the given connection, streaming back the output and the result of the command
+func (server *Server) handleConnection(connection net.Conn) {
+	var request Request
+	if err := json.NewDecoder(connection).Decode(&request); err != nil {
+		server.logger.Errorf("Could not read the request sent to the daemon: %v", err)
+		return
+	}
+
+	encoder := &messageEncoder{encoder: json.NewEncoder(connection)}
+
+	server.runMutex.Lock()
+	server.logger.Debugf("Running %v in %s", request.Args, request.WorkingDir)
+	exitCode, err := server.run(request, &streamWriter{encoder: encoder, stderr: false}, &streamWriter{encoder: encoder, stderr: true})
+	server.runMutex.Unlock()
+
+	result := message{Done: true, ExitCode: exitCode}
+	if err != nil {
+		result.Error = err.Error()
+		if result.ExitCode == 0 {
+			result.ExitCode = 1
+		}
+	}
+	if err := encoder.encode(result); err != nil {
+		server.logger.Warnf("Could not send the result of %v to the CLI: %v", request.Args, err)
+	}
+}
+
+// messageEncoder serializes the messages sent over a connection, as stdout and stderr are written concurrently
+type messageEncoder struct {
+	encoder *json.Encoder
+	mutex   sync.Mutex
+}
+
+func (messageEncoder *messageEncoder) encode(msg message) error {
+	messageEncoder.mutex.Lock()
+	defer messageEncoder.mutex.Unlock()
+	return messageEncoder.encoder.Encode(msg)
+}
+
+// streamWriter is a writer that sends what's written to it to the CLI, as the stdout or stderr of the command
+type streamWriter struct {
+	encoder *messageEncoder
+	stderr  bool
+}
+
+func (writer *streamWriter) Write(p []byte) (int, error) {
+	msg := message{Stdout: p}
+	if writer.stderr {
+		msg = message{Stderr: p}
+	}
+	if err := writer.encoder.encode(msg); err != nil {
+		return 0, errors.WithStackTrace(err)
+	}
+	return len(p), nil
+}
+
+// Custom error types
+
+type DaemonAlreadyRunning string
+
+func (socketPath DaemonAlreadyRunning) Error() string {
+	return "A Terragrunt daemon is already listening on " + string(socketPath)
+}
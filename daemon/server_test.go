@@ -0,0 +1,99 @@
+package daemon
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/gruntwork-io/terragrunt/errors"
+	"github.com/gruntwork-io/terragrunt/util"
+)
+
+func TestRunThroughDaemon(t *testing.T) {
+	t.Parallel()
+
+	socketPath := tmpSocketPath(t)
+	defer os.RemoveAll(filepath.Dir(socketPath))
+
+	run := func(request Request, stdout io.Writer, stderr io.Writer) (int, error) {
+		fmt.Fprintf(stdout, "running %v in %s\n", request.Args, request.WorkingDir)
+		fmt.Fprintf(stderr, "env %v\n", request.Env)
+		if request.Args[0] == "fail" {
+			return 3, fmt.Errorf("command failed")
+		}
+		return 0, nil
+	}
+	server := NewServer(socketPath, run, util.CreateLogEntry("", util.DEFAULT_LOG_LEVEL))
+	require.NoError(t, server.Start())
+	defer server.Close()
+
+	var stdout, stderr bytes.Buffer
+	err := Run(socketPath, Request{Args: []string{"plan"}, WorkingDir: "/live/vpc", Env: []string{"FOO=bar"}}, &stdout, &stderr)
+	require.NoError(t, err)
+	assert.Equal(t, "running [plan] in /live/vpc\n", stdout.String())
+	assert.Equal(t, "env [FOO=bar]\n", stderr.String())
+
+	// The error of the command makes the CLI exit with the exit code of the command
+	err = Run(socketPath, Request{Args: []string{"fail"}}, &bytes.Buffer{}, &bytes.Buffer{})
+	require.Error(t, err)
+	assert.Equal(t, "command failed", err.Error())
+	exitCode, exitCodeErr := errors.Unwrap(err).(CommandFailed).ExitStatus()
+	require.NoError(t, exitCodeErr)
+	assert.Equal(t, 3, exitCode)
+}
+
+func TestRunThroughDaemonThatIsNotRunning(t *testing.T) {
+	t.Parallel()
+
+	socketPath := tmpSocketPath(t)
+	defer os.RemoveAll(filepath.Dir(socketPath))
+
+	err := Run(socketPath, Request{Args: []string{"plan"}}, &bytes.Buffer{}, &bytes.Buffer{})
+	require.Error(t, err)
+	_, notRunning := errors.Unwrap(err).(DaemonNotRunning)
+	assert.True(t, notRunning, "Expected a DaemonNotRunning error but got %v", err)
+}
+
+func TestStartDaemonTwice(t *testing.T) {
+	t.Parallel()
+
+	socketPath := tmpSocketPath(t)
+	defer os.RemoveAll(filepath.Dir(socketPath))
+	logger := util.CreateLogEntry("", util.DEFAULT_LOG_LEVEL)
+	run := func(request Request, stdout io.Writer, stderr io.Writer) (int, error) {
+		return 0, nil
+	}
+
+	server := NewServer(socketPath, run, logger)
+	require.NoError(t, server.Start())
+
+	err := NewServer(socketPath, run, logger).Start()
+	assert.True(t, errors.IsError(err, DaemonAlreadyRunning(socketPath)), "Expected a DaemonAlreadyRunning error but got %v", err)
+
+	// Only the user running the daemon may connect to it
+	socketInfo, err := os.Stat(socketPath)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0600), socketInfo.Mode().Perm())
+
+	// Closing the daemon removes the socket, so another one can start
+	require.NoError(t, server.Close())
+	assert.False(t, util.FileExists(socketPath))
+	server = NewServer(socketPath, run, logger)
+	require.NoError(t, server.Start())
+	require.NoError(t, server.Close())
+}
+
+// Return the path of a unix socket in a new temp dir. Unix socket paths are limited to about 100 chars, so this uses a
+// short name.
+func tmpSocketPath(t *testing.T) string {
+	dir, err := ioutil.TempDir("", "tg-daemon")
+	require.NoError(t, err)
+	return filepath.Join(dir, "d.sock")
+}
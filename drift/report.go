@@ -0,0 +1,266 @@
+// Package drift classifies the units a run-all plan plans by whether their infrastructure drifted from their code, from
+// the plans terraform show -json prints, and collects them into a report, so that nightly drift detection jobs can act
+// on the units that drifted or failed to plan.
+package drift
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+
+	"github.com/gruntwork-io/terragrunt/errors"
+)
+
+// The statuses of the units, and of the whole report, which is the worst status of its units
+const (
+	StatusNoDrift = "no-drift"
+	StatusDrift   = "drift"
+	StatusError   = "error"
+)
+
+// The exit code of a run whose units planned successfully, but some of which drifted, like with plan -detailed-exitcode
+const EXIT_CODE_DRIFT = 2
+
+// Changes are the changes of the plan of a unit
+type Changes struct {
+	// The resources the plan changes
+	Resources []ResourceChange
+	// The names of the outputs the plan changes
+	Outputs []string
+	// The addresses of the resources terraform found to be changed outside of terraform when it refreshed them
+	ChangedOutside []string
+}
+
+// ResourceChange is a resource the plan of a unit changes, with the actions the plan takes on it, e.g. update, or delete
+// and create for a replacement
+type ResourceChange struct {
+	Address string   `json:"address"`
+	Actions []string `json:"actions"`
+}
+
+// The parts of the output of terraform show -json <plan file> that are used. See
+// https://developer.hashicorp.com/terraform/internals/json-format#plan-representation
+type planJson struct {
+	ResourceChanges []resourceChangeJson         `json:"resource_changes"`
+	ResourceDrift   []resourceChangeJson         `json:"resource_drift"`
+	OutputChanges   map[string]changeActionsJson `json:"output_changes"`
+}
+
+type resourceChangeJson struct {
+	Address string            `json:"address"`
+	Change  changeActionsJson `json:"change"`
+}
+
+type changeActionsJson struct {
+	Actions []string `json:"actions"`
+}
+
+// ParsePlan parses the output of terraform show -json <plan file> into the changes of the plan. The resources and outputs
+// whose actions are only no-op, or read for the data sources, don't change.
+func ParsePlan(output []byte) (*Changes, error) {
+	var plan planJson
+	if err := json.Unmarshal(output, &plan); err != nil {
+		return nil, errors.WithStackTrace(InvalidPlan(err.Error()))
+	}
+
+	changes := &Changes{Resources: []ResourceChange{}, Outputs: []string{}, ChangedOutside: []string{}}
+	for _, resourceChange := range plan.ResourceChanges {
+		if isChange(resourceChange.Change.Actions) {
+			changes.Resources = append(changes.Resources, ResourceChange{Address: resourceChange.Address, Actions: resourceChange.Change.Actions})
+		}
+	}
+	for name, outputChange := range plan.OutputChanges {
+		if isChange(outputChange.Actions) {
+			changes.Outputs = append(changes.Outputs, name)
+		}
+	}
+	for _, resourceDrift := range plan.ResourceDrift {
+		if isChange(resourceDrift.Change.Actions) {
+			changes.ChangedOutside = append(changes.ChangedOutside, resourceDrift.Address)
+		}
+	}
+
+	sort.Slice(changes.Resources, func(i, j int) bool { return changes.Resources[i].Address < changes.Resources[j].Address })
+	sort.Strings(changes.Outputs)
+	sort.Strings(changes.ChangedOutside)
+	return changes, nil
+}
+
+// Return true if the given actions of a resource or output change it
+func isChange(actions []string) bool {
+	for _, action := range actions {
+		if action != "no-op" && action != "read" {
+			return true
+		}
+	}
+	return false
+}
+
+// HasDrift returns true if the plan changes any resource or output, i.e. if the infrastructure doesn't match the code
+func (changes *Changes) HasDrift() bool {
+	return len(changes.Resources) > 0 || len(changes.Outputs) > 0
+}
+
+// Unit is the status of a unit, as written to the report file, along with the changes of its plan if it drifted, or the
+// error it failed with
+type Unit struct {
+	Unit           string           `json:"unit"`
+	Status         string           `json:"status"`
+	Resources      []ResourceChange `json:"resources,omitempty"`
+	Outputs        []string         `json:"outputs,omitempty"`
+	ChangedOutside []string         `json:"changed_outside,omitempty"`
+	Error          string           `json:"error,omitempty"`
+}
+
+// Summary is the number of units of each status
+type Summary struct {
+	Units   int `json:"units"`
+	NoDrift int `json:"no_drift"`
+	Drift   int `json:"drift"`
+	Error   int `json:"error"`
+}
+
+// Result is the status of all the units of the run, as written to the report file
+type Result struct {
+	Status  string  `json:"status"`
+	Summary Summary `json:"summary"`
+	Units   []Unit  `json:"units"`
+}
+
+// Report collects the statuses of the units of a run. All the methods of Report can be called on a nil report, which is
+// what is used when drift detection is disabled.
+type Report struct {
+	rootDir string
+
+	mutex sync.Mutex
+	units map[string]Unit
+}
+
+// Create a report whose units are identified by their path relative to the given root dir
+func NewReport(rootDir string) *Report {
+	return &Report{rootDir: rootDir, units: map[string]Unit{}}
+}
+
+// Add the changes of the plan of the unit in the given dir to the report
+func (report *Report) Add(unitDir string, changes *Changes) {
+	if report == nil {
+		return
+	}
+
+	unit := Unit{Unit: report.unitPath(unitDir), Status: StatusNoDrift}
+	if changes.HasDrift() {
+		unit.Status = StatusDrift
+		unit.Resources = changes.Resources
+		unit.Outputs = changes.Outputs
+	}
+	if len(changes.ChangedOutside) > 0 {
+		unit.ChangedOutside = changes.ChangedOutside
+	}
+	report.set(unit)
+}
+
+// Add the error the unit in the given dir failed with to the report. An error overrides the changes of the unit, if any,
+// as its plan didn't complete.
+func (report *Report) AddError(unitDir string, err error) {
+	if report == nil {
+		return
+	}
+	report.set(Unit{Unit: report.unitPath(unitDir), Status: StatusError, Error: err.Error()})
+}
+
+func (report *Report) set(unit Unit) {
+	report.mutex.Lock()
+	defer report.mutex.Unlock()
+	if existing, exists := report.units[unit.Unit]; exists && existing.Status == StatusError {
+		return
+	}
+	report.units[unit.Unit] = unit
+}
+
+// Return the path of the given unit dir relative to the root dir of the report, with forward slashes
+func (report *Report) unitPath(unitDir string) string {
+	if relPath, err := filepath.Rel(report.rootDir, unitDir); err == nil {
+		unitDir = relPath
+	}
+	return filepath.ToSlash(unitDir)
+}
+
+// Result returns the statuses of the units added so far, sorted by unit, and the status of the whole run: error if any
+// unit failed, else drift if any unit drifted, else no-drift
+func (report *Report) Result() *Result {
+	if report == nil {
+		return nil
+	}
+
+	report.mutex.Lock()
+	defer report.mutex.Unlock()
+
+	result := &Result{Status: StatusNoDrift, Units: []Unit{}}
+	for _, unit := range report.units {
+		result.Units = append(result.Units, unit)
+		switch unit.Status {
+		case StatusNoDrift:
+			result.Summary.NoDrift++
+		case StatusDrift:
+			result.Summary.Drift++
+		case StatusError:
+			result.Summary.Error++
+		}
+	}
+	result.Summary.Units = len(result.Units)
+	sort.Slice(result.Units, func(i, j int) bool { return result.Units[i].Unit < result.Units[j].Unit })
+
+	if result.Summary.Error > 0 {
+		result.Status = StatusError
+	} else if result.Summary.Drift > 0 {
+		result.Status = StatusDrift
+	}
+	return result
+}
+
+// Write the result as JSON to the given file
+func (result *Result) Write(path string) error {
+	contents, err := json.MarshalIndent(result, "", "  ")
+	if err != nil {
+		return errors.WithStackTrace(err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+		return errors.WithStackTrace(err)
+	}
+	return errors.WithStackTrace(ioutil.WriteFile(path, append(contents, '\n'), 0644))
+}
+
+// DriftedUnits returns the paths of the units that drifted
+func (result *Result) DriftedUnits() []string {
+	units := []string{}
+	for _, unit := range result.Units {
+		if unit.Status == StatusDrift {
+			units = append(units, unit.Unit)
+		}
+	}
+	return units
+}
+
+// Custom error types
+
+type InvalidPlan string
+
+func (err InvalidPlan) Error() string {
+	return fmt.Sprintf("Could not parse the JSON of the plan: %s", string(err))
+}
+
+// DriftDetected is the error of a run whose units planned successfully, but some of which drifted, so that the run
+// exits with EXIT_CODE_DRIFT
+type DriftDetected []string
+
+func (err DriftDetected) Error() string {
+	return fmt.Sprintf("The infrastructure of %d units drifted from their code: %v", len(err), []string(err))
+}
+
+func (err DriftDetected) ExitStatus() (int, error) {
+	return EXIT_CODE_DRIFT, nil
+}
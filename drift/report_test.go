@@ -0,0 +1,99 @@
+package drift
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/gruntwork-io/terragrunt/errors"
+)
+
+const testPlanJson = `{
+  "format_version": "1.2",
+  "resource_drift": [
+    {"address": "aws_security_group.app", "change": {"actions": ["update"]}}
+  ],
+  "resource_changes": [
+    {"address": "aws_security_group.app", "change": {"actions": ["update"]}},
+    {"address": "aws_instance.app", "change": {"actions": ["delete", "create"]}},
+    {"address": "aws_s3_bucket.logs", "change": {"actions": ["no-op"]}},
+    {"address": "data.aws_ami.ubuntu", "change": {"actions": ["read"]}}
+  ],
+  "output_changes": {
+    "instance_id": {"actions": ["update"]},
+    "bucket": {"actions": ["no-op"]}
+  }
+}`
+
+func TestParsePlan(t *testing.T) {
+	t.Parallel()
+
+	changes, err := ParsePlan([]byte(testPlanJson))
+	require.NoError(t, err)
+	assert.Equal(t, &Changes{
+		Resources: []ResourceChange{
+			{Address: "aws_instance.app", Actions: []string{"delete", "create"}},
+			{Address: "aws_security_group.app", Actions: []string{"update"}},
+		},
+		Outputs:        []string{"instance_id"},
+		ChangedOutside: []string{"aws_security_group.app"},
+	}, changes)
+	assert.True(t, changes.HasDrift())
+
+	changes, err = ParsePlan([]byte(`{"format_version": "1.2", "resource_changes": [{"address": "aws_s3_bucket.logs", "change": {"actions": ["no-op"]}}]}`))
+	require.NoError(t, err)
+	assert.False(t, changes.HasDrift())
+
+	_, err = ParsePlan([]byte("Error: no plan"))
+	assert.IsType(t, InvalidPlan(""), errors.Unwrap(err))
+}
+
+func TestReportResult(t *testing.T) {
+	t.Parallel()
+
+	drifted, err := ParsePlan([]byte(testPlanJson))
+	require.NoError(t, err)
+
+	report := NewReport("/repos/infra")
+	report.Add("/repos/infra/live/vpc", &Changes{})
+	report.Add("/repos/infra/live/app", drifted)
+	result := report.Result()
+	assert.Equal(t, StatusDrift, result.Status)
+	assert.Equal(t, Summary{Units: 2, NoDrift: 1, Drift: 1}, result.Summary)
+	assert.Equal(t, []string{"live/app"}, result.DriftedUnits())
+	assert.Equal(t, Unit{Unit: "live/vpc", Status: StatusNoDrift}, result.Units[1])
+
+	// An error overrides the changes of a unit, whichever is added first
+	report.AddError("/repos/infra/live/db", errors.WithStackTrace(InvalidPlan("unexpected EOF")))
+	report.Add("/repos/infra/live/db", &Changes{})
+	result = report.Result()
+	assert.Equal(t, StatusError, result.Status)
+	assert.Equal(t, Summary{Units: 3, NoDrift: 1, Drift: 1, Error: 1}, result.Summary)
+	assert.Equal(t, Unit{Unit: "live/db", Status: StatusError, Error: "Could not parse the JSON of the plan: unexpected EOF"}, result.Units[1])
+
+	dir, err := ioutil.TempDir("", "drift-report")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	reportPath := filepath.Join(dir, "reports", "drift.json")
+	require.NoError(t, result.Write(reportPath))
+
+	contents, err := ioutil.ReadFile(reportPath)
+	require.NoError(t, err)
+	var written Result
+	require.NoError(t, json.Unmarshal(contents, &written))
+	assert.Equal(t, *result, written)
+}
+
+func TestNilReport(t *testing.T) {
+	t.Parallel()
+
+	var report *Report
+	report.Add("/repos/infra/live/app", &Changes{})
+	report.AddError("/repos/infra/live/app", InvalidPlan(""))
+	assert.Nil(t, report.Result())
+}
@@ -25,6 +25,19 @@ const MAX_RETRIES_WAITING_FOR_TABLE_TO_BE_ACTIVE = 30
 const SLEEP_BETWEEN_TABLE_STATUS_CHECKS = 10 * time.Second
 
 const DYNAMODB_PAY_PER_REQUEST_BILLING_MODE = "PAY_PER_REQUEST"
+const DYNAMODB_PROVISIONED_BILLING_MODE = "PROVISIONED"
+
+// The settings of the lock table when Terragrunt creates it. The zero value creates an untagged PAY_PER_REQUEST table
+// with the default encryption of DynamoDB.
+type LockTableSettings struct {
+	Tags                      map[string]string
+	BillingMode               string
+	ReadCapacity              int64
+	WriteCapacity             int64
+	EnableSSEncryption        bool
+	SSEKMSKeyID               string
+	EnablePointInTimeRecovery bool
+}
 
 // Create an authenticated client for DynamoDB
 func CreateDynamoDbClient(config *aws_helper.AwsSessionConfig, terragruntOptions *options.TerragruntOptions) (*dynamodb.DynamoDB, error) {
@@ -36,8 +49,9 @@ func CreateDynamoDbClient(config *aws_helper.AwsSessionConfig, terragruntOptions
 	return dynamodb.New(session), nil
 }
 
-// Create the lock table in DynamoDB if it doesn't already exist
-func CreateLockTableIfNecessary(tableName string, tags map[string]string, client *dynamodb.DynamoDB, terragruntOptions *options.TerragruntOptions) error {
+// Create the lock table in DynamoDB if it doesn't already exist, and enable point-in-time recovery on it if the settings
+// say so
+func CreateLockTableIfNecessary(tableName string, settings LockTableSettings, client *dynamodb.DynamoDB, terragruntOptions *options.TerragruntOptions) error {
 	tableExists, err := LockTableExistsAndIsActive(tableName, client)
 	if err != nil {
 		return err
@@ -45,7 +59,15 @@ func CreateLockTableIfNecessary(tableName string, tags map[string]string, client
 
 	if !tableExists {
 		terragruntOptions.Logger.Debugf("Lock table %s does not exist in DynamoDB. Will need to create it just this first time.", tableName)
-		return CreateLockTable(tableName, tags, client, terragruntOptions)
+		if err := CreateLockTable(tableName, settings, client, terragruntOptions); err != nil {
+			return err
+		}
+	}
+
+	// The table may have been created before point-in-time recovery was configured, so this is checked for existing
+	// tables too
+	if settings.EnablePointInTimeRecovery {
+		return EnablePointInTimeRecoveryIfNecessary(tableName, client, terragruntOptions)
 	}
 
 	return nil
@@ -77,26 +99,13 @@ func LockTableCheckSSEncryptionIsOn(tableName string, client *dynamodb.DynamoDB)
 
 // Create a lock table in DynamoDB and wait until it is in "active" state. If the table already exists, merely wait
 // until it is in "active" state.
-func CreateLockTable(tableName string, tags map[string]string, client *dynamodb.DynamoDB, terragruntOptions *options.TerragruntOptions) error {
+func CreateLockTable(tableName string, settings LockTableSettings, client *dynamodb.DynamoDB, terragruntOptions *options.TerragruntOptions) error {
 	tableCreateDeleteSemaphore.Acquire()
 	defer tableCreateDeleteSemaphore.Release()
 
 	terragruntOptions.Logger.Debugf("Creating table %s in DynamoDB", tableName)
 
-	attributeDefinitions := []*dynamodb.AttributeDefinition{
-		{AttributeName: aws.String(ATTR_LOCK_ID), AttributeType: aws.String(dynamodb.ScalarAttributeTypeS)},
-	}
-
-	keySchema := []*dynamodb.KeySchemaElement{
-		{AttributeName: aws.String(ATTR_LOCK_ID), KeyType: aws.String(dynamodb.KeyTypeHash)},
-	}
-
-	createTableOutput, err := client.CreateTable(&dynamodb.CreateTableInput{
-		TableName:            aws.String(tableName),
-		BillingMode:          aws.String(DYNAMODB_PAY_PER_REQUEST_BILLING_MODE),
-		AttributeDefinitions: attributeDefinitions,
-		KeySchema:            keySchema,
-	})
+	createTableOutput, err := client.CreateTable(lockTableCreateTableInput(tableName, settings))
 
 	if err != nil {
 		if isTableAlreadyBeingCreatedOrUpdatedError(err) {
@@ -115,7 +124,7 @@ func CreateLockTable(tableName string, tags map[string]string, client *dynamodb.
 	if createTableOutput != nil && createTableOutput.TableDescription != nil && createTableOutput.TableDescription.TableArn != nil {
 		// Do not tag in case somebody else had created the table
 
-		err = tagTableIfTagsGiven(tags, createTableOutput.TableDescription.TableArn, client, terragruntOptions)
+		err = tagTableIfTagsGiven(settings.Tags, createTableOutput.TableDescription.TableArn, client, terragruntOptions)
 
 		if err != nil {
 			return errors.WithStackTrace(err)
@@ -125,6 +134,83 @@ func CreateLockTable(tableName string, tags map[string]string, client *dynamodb.
 	return nil
 }
 
+// Return the input to create the lock table with the given name and settings
+func lockTableCreateTableInput(tableName string, settings LockTableSettings) *dynamodb.CreateTableInput {
+	attributeDefinitions := []*dynamodb.AttributeDefinition{
+		{AttributeName: aws.String(ATTR_LOCK_ID), AttributeType: aws.String(dynamodb.ScalarAttributeTypeS)},
+	}
+
+	keySchema := []*dynamodb.KeySchemaElement{
+		{AttributeName: aws.String(ATTR_LOCK_ID), KeyType: aws.String(dynamodb.KeyTypeHash)},
+	}
+
+	input := &dynamodb.CreateTableInput{
+		TableName:            aws.String(tableName),
+		BillingMode:          aws.String(DYNAMODB_PAY_PER_REQUEST_BILLING_MODE),
+		AttributeDefinitions: attributeDefinitions,
+		KeySchema:            keySchema,
+	}
+
+	if settings.BillingMode == DYNAMODB_PROVISIONED_BILLING_MODE {
+		input.BillingMode = aws.String(DYNAMODB_PROVISIONED_BILLING_MODE)
+		input.ProvisionedThroughput = &dynamodb.ProvisionedThroughput{
+			ReadCapacityUnits:  aws.Int64(settings.ReadCapacity),
+			WriteCapacityUnits: aws.Int64(settings.WriteCapacity),
+		}
+	}
+
+	if settings.EnableSSEncryption || settings.SSEKMSKeyID != "" {
+		input.SSESpecification = lockTableSSESpecification(settings.SSEKMSKeyID)
+	}
+
+	return input
+}
+
+// Return the specification to encrypt the lock table with the given KMS key, or with the AWS managed key if it's empty
+func lockTableSSESpecification(kmsKeyID string) *dynamodb.SSESpecification {
+	sseSpecification := &dynamodb.SSESpecification{
+		Enabled: aws.Bool(true),
+		SSEType: aws.String(dynamodb.SSETypeKms),
+	}
+
+	if kmsKeyID != "" {
+		sseSpecification.KMSMasterKeyId = aws.String(kmsKeyID)
+	}
+
+	return sseSpecification
+}
+
+// Return true if point-in-time recovery is enabled for the lock table
+func LockTableCheckPointInTimeRecoveryIsOn(tableName string, client *dynamodb.DynamoDB) (bool, error) {
+	output, err := client.DescribeContinuousBackups(&dynamodb.DescribeContinuousBackupsInput{TableName: aws.String(tableName)})
+	if err != nil {
+		return false, errors.WithStackTrace(err)
+	}
+
+	description := output.ContinuousBackupsDescription
+	return description != nil && description.PointInTimeRecoveryDescription != nil && aws.StringValue(description.PointInTimeRecoveryDescription.PointInTimeRecoveryStatus) == dynamodb.PointInTimeRecoveryStatusEnabled, nil
+}
+
+// Enable point-in-time recovery for the lock table, if it isn't enabled yet
+func EnablePointInTimeRecoveryIfNecessary(tableName string, client *dynamodb.DynamoDB, terragruntOptions *options.TerragruntOptions) error {
+	isEnabled, err := LockTableCheckPointInTimeRecoveryIsOn(tableName, client)
+	if err != nil {
+		return err
+	}
+	if isEnabled {
+		terragruntOptions.Logger.Debugf("Table %s already has point-in-time recovery enabled", tableName)
+		return nil
+	}
+
+	terragruntOptions.Logger.Debugf("Enabling point-in-time recovery on table %s in AWS DynamoDB", tableName)
+
+	_, err = client.UpdateContinuousBackups(&dynamodb.UpdateContinuousBackupsInput{
+		TableName:                        aws.String(tableName),
+		PointInTimeRecoverySpecification: &dynamodb.PointInTimeRecoverySpecification{PointInTimeRecoveryEnabled: aws.Bool(true)},
+	})
+	return errors.WithStackTrace(err)
+}
+
 func tagTableIfTagsGiven(tags map[string]string, tableArn *string, client *dynamodb.DynamoDB, terragruntOptions *options.TerragruntOptions) error {
 
 	if tags == nil || len(tags) == 0 {
@@ -159,6 +245,17 @@ func DeleteTable(tableName string, client *dynamodb.DynamoDB) error {
 	return err
 }
 
+// Delete the item with the given lock ID, where terraform stores the digest of a state, from the given lock table
+func DeleteStateDigest(tableName string, lockID string, client *dynamodb.DynamoDB) error {
+	_, err := client.DeleteItem(&dynamodb.DeleteItemInput{
+		TableName: aws.String(tableName),
+		Key: map[string]*dynamodb.AttributeValue{
+			ATTR_LOCK_ID: {S: aws.String(lockID)},
+		},
+	})
+	return errors.WithStackTrace(err)
+}
+
 // Return true if the given error is the error message returned by AWS when the resource already exists and is being
 // updated by someone else
 func isTableAlreadyBeingCreatedOrUpdatedError(err error) bool {
@@ -195,8 +292,9 @@ func waitForTableToBeActiveWithRandomSleep(tableName string, client *dynamodb.Dy
 	return errors.WithStackTrace(TableActiveRetriesExceeded{TableName: tableName, Retries: maxRetries})
 }
 
-// Encrypt the TFState Lock table - If Necessary
-func UpdateLockTableSetSSEncryptionOnIfNecessary(tableName string, client *dynamodb.DynamoDB, terragruntOptions *options.TerragruntOptions) error {
+// Encrypt the TFState Lock table - If Necessary. The table is encrypted with the given KMS key, or with the AWS managed
+// key if it's empty.
+func UpdateLockTableSetSSEncryptionOnIfNecessary(tableName string, kmsKeyID string, client *dynamodb.DynamoDB, terragruntOptions *options.TerragruntOptions) error {
 	tableSSEncrypted, err := LockTableCheckSSEncryptionIsOn(tableName, client)
 	if err != nil {
 		return errors.WithStackTrace(err)
@@ -213,11 +311,8 @@ func UpdateLockTableSetSSEncryptionOnIfNecessary(tableName string, client *dynam
 	terragruntOptions.Logger.Debugf("Enabling server-side encryption on table %s in AWS DynamoDB", tableName)
 
 	input := &dynamodb.UpdateTableInput{
-		SSESpecification: &dynamodb.SSESpecification{
-			Enabled: aws.Bool(true),
-			SSEType: aws.String("KMS"),
-		},
-		TableName: aws.String(tableName),
+		SSESpecification: lockTableSSESpecification(kmsKeyID),
+		TableName:        aws.String(tableName),
 	}
 
 	if _, err := client.UpdateTable(input); err != nil {
@@ -44,7 +44,7 @@ func TestCreateLockTableConcurrency(t *testing.T) {
 		waitGroup.Add(1)
 		go func() {
 			defer waitGroup.Done()
-			err := CreateLockTableIfNecessary(tableName, nil, client, mockOptions)
+			err := CreateLockTableIfNecessary(tableName, LockTableSettings{}, client, mockOptions)
 			assert.Nil(t, err, "Unexpected error: %v", err)
 		}()
 	}
@@ -82,7 +82,7 @@ func TestCreateLockTableIfNecessaryTableAlreadyExists(t *testing.T) {
 		assertCanWriteToTable(t, tableName, client)
 
 		// Try to create the table the second time and make sure you get no errors
-		err = CreateLockTableIfNecessary(tableName, nil, client, mockOptions)
+		err = CreateLockTableIfNecessary(tableName, LockTableSettings{}, client, mockOptions)
 		assert.Nil(t, err, "Unexpected error: %v", err)
 	})
 }
@@ -104,7 +104,7 @@ func TestTableTagging(t *testing.T) {
 		assertTags(tags, tableName, client, t)
 
 		// Try to create the table the second time and make sure you get no errors
-		err = CreateLockTableIfNecessary(tableName, nil, client, mockOptions)
+		err = CreateLockTableIfNecessary(tableName, LockTableSettings{}, client, mockOptions)
 		assert.Nil(t, err, "Unexpected error: %v", err)
 	})
 }
@@ -130,3 +130,26 @@ func assertTags(expectedTags map[string]string, tableName string, client *dynamo
 
 	assert.Equal(t, expectedTags, actualTags, "Did not find expected tags on dynamo table.")
 }
+
+func TestLockTableCreateTableInput(t *testing.T) {
+	t.Parallel()
+
+	input := lockTableCreateTableInput("foo", LockTableSettings{})
+	assert.Equal(t, "foo", aws.StringValue(input.TableName))
+	assert.Equal(t, DYNAMODB_PAY_PER_REQUEST_BILLING_MODE, aws.StringValue(input.BillingMode))
+	assert.Nil(t, input.ProvisionedThroughput)
+	assert.Nil(t, input.SSESpecification)
+
+	input = lockTableCreateTableInput("foo", LockTableSettings{
+		BillingMode:   DYNAMODB_PROVISIONED_BILLING_MODE,
+		ReadCapacity:  5,
+		WriteCapacity: 2,
+		SSEKMSKeyID:   "arn:aws:kms:us-east-1:123456789012:key/foo",
+	})
+	assert.Equal(t, DYNAMODB_PROVISIONED_BILLING_MODE, aws.StringValue(input.BillingMode))
+	assert.Equal(t, &dynamodb.ProvisionedThroughput{ReadCapacityUnits: aws.Int64(5), WriteCapacityUnits: aws.Int64(2)}, input.ProvisionedThroughput)
+	assert.Equal(t, &dynamodb.SSESpecification{Enabled: aws.Bool(true), SSEType: aws.String("KMS"), KMSMasterKeyId: aws.String("arn:aws:kms:us-east-1:123456789012:key/foo")}, input.SSESpecification)
+
+	input = lockTableCreateTableInput("foo", LockTableSettings{EnableSSEncryption: true})
+	assert.Equal(t, &dynamodb.SSESpecification{Enabled: aws.Bool(true), SSEType: aws.String("KMS")}, input.SSESpecification)
+}
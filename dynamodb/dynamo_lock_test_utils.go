@@ -65,7 +65,7 @@ func withLockTableTagged(t *testing.T, tags map[string]string, action func(table
 		t.Fatal(err)
 	}
 
-	err = CreateLockTableIfNecessary(tableName, tags, client, mockOptions)
+	err = CreateLockTableIfNecessary(tableName, LockTableSettings{Tags: tags}, client, mockOptions)
 	assert.Nil(t, err, "Unexpected error: %v", err)
 	defer cleanupTableForTest(t, tableName, client)
 
@@ -0,0 +1,68 @@
+// Package gitcreds provides the credentials git uses to fetch private sources over HTTPS: from a netrc file templated
+// with env vars, or an installation token of a GitHub App, which are written to a git credential store, and handed to
+// git, along with any other credential helpers, through the GIT_CONFIG_* env vars, so that no token has to be baked into
+// the source URLs.
+package gitcreds
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/gruntwork-io/terragrunt/errors"
+)
+
+// Credential is the username and password, or token, git logs in to a host with
+type Credential struct {
+	Host     string
+	Username string
+	Password string
+}
+
+// Write the given credentials to the given file in the format of git credential-store, i.e. one
+// https://<username>:<password>@<host> URL per line, readable only by the current user
+func WriteStore(path string, credentials []Credential) error {
+	lines := []string{}
+	for _, credential := range credentials {
+		credentialUrl := url.URL{Scheme: "https", User: url.UserPassword(credential.Username, credential.Password), Host: credential.Host}
+		lines = append(lines, credentialUrl.String())
+	}
+	return errors.WithStackTrace(ioutil.WriteFile(path, []byte(strings.Join(lines, "\n")+"\n"), 0600))
+}
+
+// Return the git credential helper that reads the credentials of the given git credential-store file
+func StoreHelper(path string) string {
+	return fmt.Sprintf("store --file=%q", path)
+}
+
+// Return the env vars that add the given credential helpers to the config of git, on top of the ones set by the
+// GIT_CONFIG_* env vars of the given env, if any. git tries the helpers in order, until one of them has credentials for
+// the host.
+func ConfigEnv(env map[string]string, helpers []string) (map[string]string, error) {
+	count := 0
+	if existingCount := env["GIT_CONFIG_COUNT"]; existingCount != "" {
+		var err error
+		if count, err = strconv.Atoi(existingCount); err != nil {
+			return nil, errors.WithStackTrace(InvalidGitConfigCount(existingCount))
+		}
+	}
+
+	configEnv := map[string]string{}
+	for _, helper := range helpers {
+		configEnv[fmt.Sprintf("GIT_CONFIG_KEY_%d", count)] = "credential.helper"
+		configEnv[fmt.Sprintf("GIT_CONFIG_VALUE_%d", count)] = helper
+		count++
+	}
+	configEnv["GIT_CONFIG_COUNT"] = strconv.Itoa(count)
+	return configEnv, nil
+}
+
+// Custom error types
+
+type InvalidGitConfigCount string
+
+func (count InvalidGitConfigCount) Error() string {
+	return fmt.Sprintf("The GIT_CONFIG_COUNT env var must be a number, but got %s", string(count))
+}
@@ -0,0 +1,165 @@
+package gitcreds
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/gruntwork-io/terragrunt/errors"
+)
+
+// The URL of the API of github.com. GitHub Actions sets the GITHUB_API_URL env var to the one of the GitHub Enterprise
+// Server the workflow runs on.
+const DEFAULT_GITHUB_API_URL = "https://api.github.com"
+
+// The username the installation tokens of GitHub Apps are used with
+const githubAppUsername = "x-access-token"
+
+// How long the JWT the app authenticates with is valid, and how far back its issue time is set, to allow for clock
+// drift. GitHub allows 10 minutes at most.
+const (
+	githubAppJWTLifetime = 9 * time.Minute
+	githubAppClockDrift  = time.Minute
+)
+
+// GitHubApp is a GitHub App installation, whose tokens can fetch the repos the app is installed on
+type GitHubApp struct {
+	// The URL of the GitHub API, e.g. https://api.github.com, or https://ghe.example.com/api/v3 for GitHub Enterprise
+	// Server
+	ApiUrl         string
+	AppID          string
+	InstallationID string
+	// The PEM encoded private key of the app
+	PrivateKey []byte
+}
+
+// Create an installation token of the app, and return it as the credential of the git host of its API. The tokens
+// expire after an hour.
+func (app *GitHubApp) InstallationCredential() (*Credential, error) {
+	apiUrl, err := url.Parse(strings.TrimSuffix(app.ApiUrl, "/"))
+	if err != nil {
+		return nil, errors.WithStackTrace(err)
+	}
+	host := apiUrl.Host
+	if host == "api.github.com" {
+		host = "github.com"
+	}
+
+	jwt, err := app.jwt(time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	tokenUrl := fmt.Sprintf("%s/app/installations/%s/access_tokens", apiUrl.String(), url.PathEscape(app.InstallationID))
+	request, err := http.NewRequest(http.MethodPost, tokenUrl, nil)
+	if err != nil {
+		return nil, errors.WithStackTrace(err)
+	}
+	request.Header.Set("Authorization", "Bearer "+jwt)
+	request.Header.Set("Accept", "application/vnd.github+json")
+
+	response, err := (&http.Client{Timeout: time.Minute}).Do(request)
+	if err != nil {
+		return nil, errors.WithStackTrace(err)
+	}
+	defer response.Body.Close()
+	body, err := ioutil.ReadAll(response.Body)
+	if err != nil {
+		return nil, errors.WithStackTrace(err)
+	}
+	if response.StatusCode != http.StatusCreated {
+		return nil, errors.WithStackTrace(GitHubAppTokenFailed{AppID: app.AppID, InstallationID: app.InstallationID, StatusCode: response.StatusCode, Message: strings.TrimSpace(string(body))})
+	}
+
+	var token struct {
+		Token string `json:"token"`
+	}
+	if err := json.Unmarshal(body, &token); err != nil {
+		return nil, errors.WithStackTrace(err)
+	}
+	return &Credential{Host: host, Username: githubAppUsername, Password: token.Token}, nil
+}
+
+// Return the JWT, signed with the private key of the app, the app authenticates with to create installation tokens
+func (app *GitHubApp) jwt(now time.Time) (string, error) {
+	privateKey, err := parseRSAPrivateKey(app.PrivateKey)
+	if err != nil {
+		return "", err
+	}
+
+	header, err := json.Marshal(map[string]string{"alg": "RS256", "typ": "JWT"})
+	if err != nil {
+		return "", errors.WithStackTrace(err)
+	}
+	claims, err := json.Marshal(map[string]interface{}{
+		"iat": now.Add(-githubAppClockDrift).Unix(),
+		"exp": now.Add(githubAppJWTLifetime).Unix(),
+		"iss": app.AppID,
+	})
+	if err != nil {
+		return "", errors.WithStackTrace(err)
+	}
+
+	unsigned := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(claims)
+	hash := sha256.Sum256([]byte(unsigned))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, privateKey, crypto.SHA256, hash[:])
+	if err != nil {
+		return "", errors.WithStackTrace(err)
+	}
+	return unsigned + "." + base64.RawURLEncoding.EncodeToString(signature), nil
+}
+
+// Parse the given PEM encoded RSA private key, in the PKCS #1 format GitHub generates, or in the PKCS #8 format
+func parseRSAPrivateKey(contents []byte) (*rsa.PrivateKey, error) {
+	block, _ := pem.Decode(contents)
+	if block == nil {
+		return nil, errors.WithStackTrace(InvalidGitHubAppPrivateKey("no PEM block found"))
+	}
+	if block.Type == "RSA PRIVATE KEY" {
+		privateKey, err := x509.ParsePKCS1PrivateKey(block.Bytes)
+		if err != nil {
+			return nil, errors.WithStackTrace(InvalidGitHubAppPrivateKey(err.Error()))
+		}
+		return privateKey, nil
+	}
+
+	privateKey, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, errors.WithStackTrace(InvalidGitHubAppPrivateKey(err.Error()))
+	}
+	rsaPrivateKey, isRSA := privateKey.(*rsa.PrivateKey)
+	if !isRSA {
+		return nil, errors.WithStackTrace(InvalidGitHubAppPrivateKey("not an RSA key"))
+	}
+	return rsaPrivateKey, nil
+}
+
+// Custom error types
+
+type GitHubAppTokenFailed struct {
+	AppID          string
+	InstallationID string
+	StatusCode     int
+	Message        string
+}
+
+func (err GitHubAppTokenFailed) Error() string {
+	return fmt.Sprintf("Could not create a token of the installation %s of the GitHub App %s (status %d): %s", err.InstallationID, err.AppID, err.StatusCode, err.Message)
+}
+
+type InvalidGitHubAppPrivateKey string
+
+func (reason InvalidGitHubAppPrivateKey) Error() string {
+	return fmt.Sprintf("The private key of the GitHub App is not a valid PEM encoded RSA key: %s", string(reason))
+}
@@ -0,0 +1,103 @@
+package gitcreds
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"github.com/gruntwork-io/terragrunt/errors"
+)
+
+// Read the credentials of the netrc file at the given path, after replacing the references to env vars in it, e.g.
+// ${GITLAB_TOKEN}, with their value in the given env, so that the file can be committed while the tokens stay in the
+// secrets of the CI. $$ stands for a literal $. Only the machine entries are read, as a credential store can't hold the
+// credentials of the default entry.
+func ReadNetrcTemplate(path string, env map[string]string) ([]Credential, error) {
+	contents, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, errors.WithStackTrace(err)
+	}
+
+	missingVars := []string{}
+	rendered := os.Expand(string(contents), func(name string) string {
+		if name == "$" {
+			return "$"
+		}
+		value, hasValue := env[name]
+		if !hasValue {
+			missingVars = append(missingVars, name)
+		}
+		return value
+	})
+	if len(missingVars) > 0 {
+		return nil, errors.WithStackTrace(MissingNetrcEnvVars{Path: path, Names: missingVars})
+	}
+	return parseNetrc(rendered), nil
+}
+
+// Parse the machine entries of the given netrc contents, e.g. machine gitlab.com login oauth2 password s3cr3t, into
+// credentials. The entries without a login or a password are skipped, as are the macros.
+func parseNetrc(contents string) []Credential {
+	credentials := []Credential{}
+	var current *Credential
+	addCurrent := func() {
+		if current != nil && current.Host != "" && current.Username != "" && current.Password != "" {
+			credentials = append(credentials, *current)
+		}
+		current = nil
+	}
+
+	lines := strings.Split(contents, "\n")
+	for lineIndex := 0; lineIndex < len(lines); lineIndex++ {
+		tokens := strings.Fields(lines[lineIndex])
+		for tokenIndex := 0; tokenIndex < len(tokens); tokenIndex++ {
+			token := tokens[tokenIndex]
+			if strings.HasPrefix(token, "#") {
+				break
+			}
+			value := ""
+			if tokenIndex+1 < len(tokens) {
+				value = tokens[tokenIndex+1]
+			}
+
+			switch token {
+			case "machine":
+				addCurrent()
+				current = &Credential{Host: value}
+				tokenIndex++
+			case "default":
+				addCurrent()
+				current = &Credential{}
+			case "login", "password", "account":
+				if current != nil && token == "login" {
+					current.Username = value
+				}
+				if current != nil && token == "password" {
+					current.Password = value
+				}
+				tokenIndex++
+			case "macdef":
+				// A macro runs until the next blank line
+				addCurrent()
+				for lineIndex+1 < len(lines) && strings.TrimSpace(lines[lineIndex+1]) != "" {
+					lineIndex++
+				}
+				tokenIndex = len(tokens)
+			}
+		}
+	}
+	addCurrent()
+	return credentials
+}
+
+// Custom error types
+
+type MissingNetrcEnvVars struct {
+	Path  string
+	Names []string
+}
+
+func (err MissingNetrcEnvVars) Error() string {
+	return fmt.Sprintf("The netrc template %s references the env vars %s, which aren't set", err.Path, strings.Join(err.Names, ", "))
+}
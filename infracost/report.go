@@ -0,0 +1,225 @@
+// Package infracost collects the cost estimates Infracost makes of the plans of the units a Terragrunt command runs,
+// and adds them up, so that the change in the monthly cost of the whole stack a run-all plan would make is known before
+// it's applied. See https://www.infracost.io/docs/
+package infracost
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/gruntwork-io/terragrunt/errors"
+)
+
+// The currency of the costs when Infracost doesn't say
+const defaultCurrency = "USD"
+
+// Breakdown is the estimate Infracost made of the monthly cost of a unit before and after its plan is applied
+type Breakdown struct {
+	Currency        string
+	MonthlyCost     float64
+	PastMonthlyCost float64
+	DiffMonthlyCost float64
+}
+
+// The parts of the output of infracost breakdown --format json that are used. The costs are decimal strings, and are
+// null when Infracost couldn't estimate them, e.g. for a plan without any resources it knows the price of.
+type infracostOutput struct {
+	Currency             string  `json:"currency"`
+	TotalMonthlyCost     *string `json:"totalMonthlyCost"`
+	PastTotalMonthlyCost *string `json:"pastTotalMonthlyCost"`
+	DiffTotalMonthlyCost *string `json:"diffTotalMonthlyCost"`
+}
+
+// ParseBreakdown parses the output of infracost breakdown --format json. The costs Infracost couldn't estimate are 0.
+func ParseBreakdown(output []byte) (*Breakdown, error) {
+	var parsed infracostOutput
+	if err := json.Unmarshal(output, &parsed); err != nil {
+		return nil, errors.WithStackTrace(err)
+	}
+
+	breakdown := &Breakdown{Currency: parsed.Currency}
+	if breakdown.Currency == "" {
+		breakdown.Currency = defaultCurrency
+	}
+	costs := []struct {
+		value *string
+		cost  *float64
+	}{
+		{parsed.TotalMonthlyCost, &breakdown.MonthlyCost},
+		{parsed.PastTotalMonthlyCost, &breakdown.PastMonthlyCost},
+		{parsed.DiffTotalMonthlyCost, &breakdown.DiffMonthlyCost},
+	}
+	for _, cost := range costs {
+		if cost.value == nil || *cost.value == "" {
+			continue
+		}
+		parsedCost, err := strconv.ParseFloat(*cost.value, 64)
+		if err != nil {
+			return nil, errors.WithStackTrace(InvalidCost(*cost.value))
+		}
+		*cost.cost = parsedCost
+	}
+	return breakdown, nil
+}
+
+// UnitCost is the estimate of the monthly cost of a unit, as written to the report file
+type UnitCost struct {
+	Unit            string  `json:"unit"`
+	MonthlyCost     float64 `json:"monthly_cost"`
+	PastMonthlyCost float64 `json:"past_monthly_cost"`
+	DiffMonthlyCost float64 `json:"diff_monthly_cost"`
+}
+
+// Totals is the estimate of the monthly cost of all the units of the run, as written to the report file
+type Totals struct {
+	Currency        string     `json:"currency"`
+	MonthlyCost     float64    `json:"total_monthly_cost"`
+	PastMonthlyCost float64    `json:"past_total_monthly_cost"`
+	DiffMonthlyCost float64    `json:"diff_total_monthly_cost"`
+	Units           []UnitCost `json:"units"`
+}
+
+// Report adds up the estimates of the units of a run. All the methods of Report can be called on a nil report, which
+// is what is used when Infracost is disabled.
+type Report struct {
+	rootDir string
+
+	mutex      sync.Mutex
+	currencies map[string]bool
+	units      []UnitCost
+}
+
+// Create a report whose units are identified by their path relative to the given root dir
+func NewReport(rootDir string) *Report {
+	return &Report{rootDir: rootDir, currencies: map[string]bool{}}
+}
+
+// Add the estimate of the unit with the given Terragrunt config to the report
+func (report *Report) Add(terragruntConfigPath string, breakdown *Breakdown) {
+	if report == nil {
+		return
+	}
+
+	unitPath := filepath.Dir(terragruntConfigPath)
+	if relPath, err := filepath.Rel(report.rootDir, unitPath); err == nil {
+		unitPath = relPath
+	}
+
+	report.mutex.Lock()
+	defer report.mutex.Unlock()
+	report.currencies[breakdown.Currency] = true
+	report.units = append(report.units, UnitCost{
+		Unit:            filepath.ToSlash(unitPath),
+		MonthlyCost:     breakdown.MonthlyCost,
+		PastMonthlyCost: breakdown.PastMonthlyCost,
+		DiffMonthlyCost: breakdown.DiffMonthlyCost,
+	})
+}
+
+// Totals returns the estimates added so far, sorted by unit, and their totals. Returns an error if the units were
+// estimated in different currencies, which can't be added up.
+func (report *Report) Totals() (*Totals, error) {
+	if report == nil {
+		return nil, nil
+	}
+
+	report.mutex.Lock()
+	defer report.mutex.Unlock()
+
+	totals := &Totals{Currency: defaultCurrency, Units: append([]UnitCost{}, report.units...)}
+	if len(report.currencies) > 1 {
+		currencies := []string{}
+		for currency := range report.currencies {
+			currencies = append(currencies, currency)
+		}
+		sort.Strings(currencies)
+		return nil, errors.WithStackTrace(MixedCurrencies(currencies))
+	}
+	for currency := range report.currencies {
+		totals.Currency = currency
+	}
+
+	sort.SliceStable(totals.Units, func(i, j int) bool { return totals.Units[i].Unit < totals.Units[j].Unit })
+	for _, unit := range totals.Units {
+		totals.MonthlyCost += unit.MonthlyCost
+		totals.PastMonthlyCost += unit.PastMonthlyCost
+		totals.DiffMonthlyCost += unit.DiffMonthlyCost
+	}
+	return totals, nil
+}
+
+// Write the totals as JSON to the given file
+func (totals *Totals) Write(path string) error {
+	contents, err := json.MarshalIndent(totals, "", "  ")
+	if err != nil {
+		return errors.WithStackTrace(err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+		return errors.WithStackTrace(err)
+	}
+	return errors.WithStackTrace(ioutil.WriteFile(path, append(contents, '\n'), 0644))
+}
+
+// Summary returns a one line summary of the change in the monthly cost, e.g. "+12.50 USD (100.00 USD → 112.50 USD)"
+func (totals *Totals) Summary() string {
+	return fmt.Sprintf(
+		"%s (%s → %s)",
+		formatCostDiff(totals.DiffMonthlyCost, totals.Currency),
+		formatCost(totals.PastMonthlyCost, totals.Currency),
+		formatCost(totals.MonthlyCost, totals.Currency),
+	)
+}
+
+// Markdown renders the totals as a Markdown table of the units whose cost changes, for the step summary of a GitHub
+// Actions job
+func (totals *Totals) Markdown() string {
+	lines := []string{
+		"### Infracost",
+		"",
+		fmt.Sprintf("Monthly cost change of %d units: %s", len(totals.Units), totals.Summary()),
+		"",
+		"| Unit | Monthly cost | Change |",
+		"| --- | ---: | ---: |",
+	}
+	for _, unit := range totals.Units {
+		if unit.DiffMonthlyCost == 0 {
+			continue
+		}
+		lines = append(lines, fmt.Sprintf(
+			"| %s | %s | %s |",
+			unit.Unit,
+			formatCost(unit.MonthlyCost, totals.Currency),
+			formatCostDiff(unit.DiffMonthlyCost, totals.Currency),
+		))
+	}
+	return strings.Join(lines, "\n") + "\n\n"
+}
+
+func formatCost(cost float64, currency string) string {
+	return fmt.Sprintf("%.2f %s", cost, currency)
+}
+
+func formatCostDiff(cost float64, currency string) string {
+	return fmt.Sprintf("%+.2f %s", cost, currency)
+}
+
+// Custom error types
+
+type InvalidCost string
+
+func (err InvalidCost) Error() string {
+	return fmt.Sprintf("Infracost returned a cost that is not a number: %s", string(err))
+}
+
+type MixedCurrencies []string
+
+func (err MixedCurrencies) Error() string {
+	return fmt.Sprintf("Can't add up the costs of the units, as they're in different currencies: %s", strings.Join(err, ", "))
+}
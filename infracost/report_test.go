@@ -0,0 +1,124 @@
+package infracost
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/gruntwork-io/terragrunt/errors"
+)
+
+func TestParseBreakdown(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name     string
+		output   string
+		expected Breakdown
+	}{
+		{
+			"costs",
+			`{"version": "0.2", "currency": "EUR", "totalMonthlyCost": "112.5", "pastTotalMonthlyCost": "100", "diffTotalMonthlyCost": "12.5", "projects": []}`,
+			Breakdown{Currency: "EUR", MonthlyCost: 112.5, PastMonthlyCost: 100, DiffMonthlyCost: 12.5},
+		},
+		{
+			"no estimate",
+			`{"totalMonthlyCost": null, "pastTotalMonthlyCost": null, "diffTotalMonthlyCost": null}`,
+			Breakdown{Currency: "USD"},
+		},
+	}
+
+	for _, testCase := range testCases {
+		// Capture range variable so that it is brought into the scope within the for loop, so that it is stable even
+		// when subtests are run in parallel.
+		testCase := testCase
+
+		t.Run(testCase.name, func(t *testing.T) {
+			t.Parallel()
+
+			breakdown, err := ParseBreakdown([]byte(testCase.output))
+			require.NoError(t, err)
+			assert.Equal(t, testCase.expected, *breakdown)
+		})
+	}
+
+	_, err := ParseBreakdown([]byte(`{"totalMonthlyCost": "lots"}`))
+	require.Error(t, err)
+	_, isInvalidCost := errors.Unwrap(err).(InvalidCost)
+	assert.True(t, isInvalidCost, "Unexpected error: %v", err)
+}
+
+func TestReportTotals(t *testing.T) {
+	t.Parallel()
+
+	report := NewReport("/repo")
+	report.Add("/repo/live/vpc/terragrunt.hcl", &Breakdown{Currency: "USD", MonthlyCost: 30, PastMonthlyCost: 30})
+	report.Add("/repo/live/app/terragrunt.hcl", &Breakdown{Currency: "USD", MonthlyCost: 82.5, PastMonthlyCost: 70, DiffMonthlyCost: 12.5})
+
+	totals, err := report.Totals()
+	require.NoError(t, err)
+	assert.Equal(t, &Totals{
+		Currency:        "USD",
+		MonthlyCost:     112.5,
+		PastMonthlyCost: 100,
+		DiffMonthlyCost: 12.5,
+		Units: []UnitCost{
+			{Unit: "live/app", MonthlyCost: 82.5, PastMonthlyCost: 70, DiffMonthlyCost: 12.5},
+			{Unit: "live/vpc", MonthlyCost: 30, PastMonthlyCost: 30},
+		},
+	}, totals)
+	assert.Equal(t, "+12.50 USD (100.00 USD → 112.50 USD)", totals.Summary())
+
+	markdown := totals.Markdown()
+	assert.Contains(t, markdown, "| live/app | 82.50 USD | +12.50 USD |")
+	assert.NotContains(t, markdown, "live/vpc")
+
+	report.Add("/repo/live/db/terragrunt.hcl", &Breakdown{Currency: "EUR"})
+	_, err = report.Totals()
+	require.Error(t, err)
+	_, isMixedCurrencies := errors.Unwrap(err).(MixedCurrencies)
+	assert.True(t, isMixedCurrencies, "Unexpected error: %v", err)
+}
+
+func TestTotalsWrite(t *testing.T) {
+	t.Parallel()
+
+	reportDir, err := ioutil.TempDir("", "infracost-report")
+	require.NoError(t, err)
+	defer os.RemoveAll(reportDir)
+
+	report := NewReport("/repo")
+	report.Add("/repo/app/terragrunt.hcl", &Breakdown{Currency: "USD", MonthlyCost: 10, DiffMonthlyCost: 10})
+	totals, err := report.Totals()
+	require.NoError(t, err)
+
+	reportPath := filepath.Join(reportDir, "reports", "infracost.json")
+	require.NoError(t, totals.Write(reportPath))
+
+	contents, err := ioutil.ReadFile(reportPath)
+	require.NoError(t, err)
+	var written map[string]interface{}
+	require.NoError(t, json.Unmarshal(contents, &written))
+	assert.Equal(t, float64(10), written["diff_total_monthly_cost"])
+	assert.Equal(t, []interface{}{map[string]interface{}{
+		"unit":              "app",
+		"monthly_cost":      float64(10),
+		"past_monthly_cost": float64(0),
+		"diff_monthly_cost": float64(10),
+	}}, written["units"])
+}
+
+func TestNilReport(t *testing.T) {
+	t.Parallel()
+
+	var report *Report
+	report.Add("/repo/terragrunt.hcl", &Breakdown{Currency: "USD"})
+	totals, err := report.Totals()
+	assert.NoError(t, err)
+	assert.Nil(t, totals)
+}
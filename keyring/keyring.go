@@ -0,0 +1,52 @@
+// Package keyring stores secrets in the keyring of the OS: the login keychain on macOS, the Secret Service (e.g. GNOME
+// Keyring or KWallet) via secret-tool on Linux, and the Credential Manager on Windows.
+package keyring
+
+import (
+	"fmt"
+
+	"github.com/gruntwork-io/terragrunt/errors"
+)
+
+// The service the secrets of terragrunt are stored under, so that they're easy to find and remove in the keyring
+const service = "terragrunt"
+
+// Keyring is a store of secrets by key. The label of a secret is what the user sees when listing the keyring.
+type Keyring interface {
+	// Get returns the secret stored under the given key, or a SecretNotFound error if there's none
+	Get(key string) (string, error)
+	// Set stores the given secret under the given key, replacing the one stored under it, if any
+	Set(key string, label string, secret string) error
+	// Delete removes the secret stored under the given key, if any
+	Delete(key string) error
+}
+
+// New returns the keyring of the OS
+func New() Keyring {
+	return osKeyring{}
+}
+
+// The keyring of the OS, implemented in the file of each OS
+type osKeyring struct{}
+
+// Returns true if the given error means there's no secret stored under the key
+func IsSecretNotFound(err error) bool {
+	_, isNotFound := errors.Unwrap(err).(SecretNotFound)
+	return isNotFound
+}
+
+// Custom error types
+
+type SecretNotFound string
+
+func (key SecretNotFound) Error() string {
+	return fmt.Sprintf("No secret %s found in the keyring", string(key))
+}
+
+type KeyringUnavailable struct {
+	Reason string
+}
+
+func (err KeyringUnavailable) Error() string {
+	return fmt.Sprintf("The keyring of the OS is unavailable: %s", err.Reason)
+}
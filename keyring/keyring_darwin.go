@@ -0,0 +1,82 @@
+package keyring
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"os/exec"
+	"strings"
+
+	"github.com/gruntwork-io/terragrunt/errors"
+)
+
+// The exit code of the security command when the keychain has no such item
+const securityItemNotFoundExitCode = 44
+
+// Get returns the secret stored under the given key in the login keychain, via the security command
+func (osKeyring) Get(key string) (string, error) {
+	cmd := exec.Command("security", "find-generic-password", "-s", service, "-a", key, "-w")
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if exitErr, isExitErr := err.(*exec.ExitError); isExitErr && exitErr.ExitCode() == securityItemNotFoundExitCode {
+			return "", errors.WithStackTrace(SecretNotFound(key))
+		}
+		return "", errors.WithStackTrace(securityError(err, stderr.String()))
+	}
+
+	// The secrets are stored base64 encoded, as the security command prints secrets with e.g. newlines in hex
+	secret, err := base64.StdEncoding.DecodeString(strings.TrimSpace(stdout.String()))
+	if err != nil {
+		return "", errors.WithStackTrace(err)
+	}
+	return string(secret), nil
+}
+
+// Set stores the given secret under the given key in the login keychain, via the interactive mode of the security
+// command, so that the secret isn't in the arguments of the process for other users to see
+func (osKeyring) Set(key string, label string, secret string) error {
+	command := fmt.Sprintf(
+		"add-generic-password -U -s %s -a %s -l %s -w %s\n",
+		securityQuote(service),
+		securityQuote(key),
+		securityQuote(label),
+		securityQuote(base64.StdEncoding.EncodeToString([]byte(secret))),
+	)
+	cmd := exec.Command("security", "-i")
+	cmd.Stdin = strings.NewReader(command)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return errors.WithStackTrace(securityError(err, stderr.String()))
+	}
+	return nil
+}
+
+// Delete removes the secret stored under the given key from the login keychain, if any
+func (osKeyring) Delete(key string) error {
+	cmd := exec.Command("security", "delete-generic-password", "-s", service, "-a", key)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if exitErr, isExitErr := err.(*exec.ExitError); isExitErr && exitErr.ExitCode() == securityItemNotFoundExitCode {
+			return nil
+		}
+		return errors.WithStackTrace(securityError(err, stderr.String()))
+	}
+	return nil
+}
+
+// Quote the given argument of a command of the interactive mode of the security command
+func securityQuote(arg string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(arg) + `"`
+}
+
+// Return the error of the given failed security command
+func securityError(err error, stderr string) error {
+	if _, isExitErr := err.(*exec.ExitError); !isExitErr {
+		return KeyringUnavailable{Reason: fmt.Sprintf("the security command could not be run: %v", err)}
+	}
+	return fmt.Errorf("security command failed: %v: %s", err, strings.TrimSpace(stderr))
+}
@@ -0,0 +1,62 @@
+package keyring
+
+import (
+	"bytes"
+	"fmt"
+	"os/exec"
+	"strings"
+
+	"github.com/gruntwork-io/terragrunt/errors"
+)
+
+// Get returns the secret stored under the given key in the Secret Service, via the secret-tool command of libsecret
+func (osKeyring) Get(key string) (string, error) {
+	cmd := exec.Command("secret-tool", "lookup", "service", service, "key", key)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		// secret-tool exits with 1 and prints nothing when there's no such secret
+		if exitErr, isExitErr := err.(*exec.ExitError); isExitErr && exitErr.ExitCode() == 1 && stderr.Len() == 0 {
+			return "", errors.WithStackTrace(SecretNotFound(key))
+		}
+		return "", errors.WithStackTrace(secretToolError(err, stderr.String()))
+	}
+	return stdout.String(), nil
+}
+
+// Set stores the given secret under the given key in the Secret Service. The secret is passed on stdin, so that it isn't
+// in the arguments of the process for other users to see.
+func (osKeyring) Set(key string, label string, secret string) error {
+	cmd := exec.Command("secret-tool", "store", "--label="+label, "service", service, "key", key)
+	cmd.Stdin = strings.NewReader(secret)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return errors.WithStackTrace(secretToolError(err, stderr.String()))
+	}
+	return nil
+}
+
+// Delete removes the secret stored under the given key from the Secret Service, if any
+func (osKeyring) Delete(key string) error {
+	cmd := exec.Command("secret-tool", "clear", "service", service, "key", key)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if exitErr, isExitErr := err.(*exec.ExitError); isExitErr && exitErr.ExitCode() == 1 && stderr.Len() == 0 {
+			return nil
+		}
+		return errors.WithStackTrace(secretToolError(err, stderr.String()))
+	}
+	return nil
+}
+
+// Return the error of the given failed secret-tool command. Without secret-tool, or a Secret Service to talk to, e.g.
+// on servers and in containers, the keyring is unavailable.
+func secretToolError(err error, stderr string) error {
+	if _, isExitErr := err.(*exec.ExitError); !isExitErr {
+		return KeyringUnavailable{Reason: fmt.Sprintf("secret-tool, of libsecret, could not be run: %v", err)}
+	}
+	return KeyringUnavailable{Reason: fmt.Sprintf("secret-tool failed: %v: %s", err, strings.TrimSpace(stderr))}
+}
@@ -0,0 +1,28 @@
+// +build !darwin,!linux,!windows
+
+package keyring
+
+import (
+	"runtime"
+
+	"github.com/gruntwork-io/terragrunt/errors"
+)
+
+// Get fails, as terragrunt doesn't support the keyring of this OS
+func (osKeyring) Get(key string) (string, error) {
+	return "", errors.WithStackTrace(unsupportedOS())
+}
+
+// Set fails, as terragrunt doesn't support the keyring of this OS
+func (osKeyring) Set(key string, label string, secret string) error {
+	return errors.WithStackTrace(unsupportedOS())
+}
+
+// Delete fails, as terragrunt doesn't support the keyring of this OS
+func (osKeyring) Delete(key string) error {
+	return errors.WithStackTrace(unsupportedOS())
+}
+
+func unsupportedOS() error {
+	return KeyringUnavailable{Reason: "keyrings are not supported on " + runtime.GOOS}
+}
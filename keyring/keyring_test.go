@@ -0,0 +1,19 @@
+package keyring
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/gruntwork-io/terragrunt/errors"
+)
+
+func TestIsSecretNotFound(t *testing.T) {
+	t.Parallel()
+
+	assert.True(t, IsSecretNotFound(SecretNotFound("key")))
+	assert.True(t, IsSecretNotFound(errors.WithStackTrace(SecretNotFound("key"))))
+	assert.False(t, IsSecretNotFound(errors.WithStackTrace(KeyringUnavailable{Reason: "no secret-tool"})))
+	assert.False(t, IsSecretNotFound(fmt.Errorf("other error")))
+}
@@ -0,0 +1,121 @@
+// +build windows
+
+package keyring
+
+import (
+	"syscall"
+	"unsafe"
+
+	"github.com/gruntwork-io/terragrunt/errors"
+)
+
+// The generic credentials of the Windows Credential Manager, see
+// https://docs.microsoft.com/en-us/windows/win32/api/wincred/
+const (
+	credTypeGeneric           = 1
+	credPersistLocalMachine   = 2
+	credMaxCredentialBlobSize = 5 * 512
+	errorNotFound             = syscall.Errno(1168)
+)
+
+var (
+	advapi32       = syscall.NewLazyDLL("advapi32.dll")
+	procCredReadW  = advapi32.NewProc("CredReadW")
+	procCredWriteW = advapi32.NewProc("CredWriteW")
+	procCredDelete = advapi32.NewProc("CredDeleteW")
+	procCredFree   = advapi32.NewProc("CredFree")
+)
+
+// The CREDENTIALW struct of the Windows API
+type credential struct {
+	Flags              uint32
+	Type               uint32
+	TargetName         *uint16
+	Comment            *uint16
+	LastWritten        syscall.Filetime
+	CredentialBlobSize uint32
+	CredentialBlob     *byte
+	Persist            uint32
+	AttributeCount     uint32
+	Attributes         uintptr
+	TargetAlias        *uint16
+	UserName           *uint16
+}
+
+// The name of the credential of the Credential Manager the secret of the given key is stored in
+func targetName(key string) string {
+	return service + ":" + key
+}
+
+// Get returns the secret stored under the given key in the Credential Manager
+func (osKeyring) Get(key string) (string, error) {
+	target, err := syscall.UTF16PtrFromString(targetName(key))
+	if err != nil {
+		return "", errors.WithStackTrace(err)
+	}
+
+	var cred *credential
+	ret, _, err := procCredReadW.Call(uintptr(unsafe.Pointer(target)), credTypeGeneric, 0, uintptr(unsafe.Pointer(&cred)))
+	if ret == 0 {
+		if err == errorNotFound {
+			return "", errors.WithStackTrace(SecretNotFound(key))
+		}
+		return "", errors.WithStackTrace(KeyringUnavailable{Reason: err.Error()})
+	}
+	defer procCredFree.Call(uintptr(unsafe.Pointer(cred)))
+
+	blob := make([]byte, cred.CredentialBlobSize)
+	if cred.CredentialBlobSize > 0 {
+		copy(blob, (*[credMaxCredentialBlobSize]byte)(unsafe.Pointer(cred.CredentialBlob))[:cred.CredentialBlobSize:cred.CredentialBlobSize])
+	}
+	return string(blob), nil
+}
+
+// Set stores the given secret under the given key in the Credential Manager, for the current user on this machine
+func (osKeyring) Set(key string, label string, secret string) error {
+	target, err := syscall.UTF16PtrFromString(targetName(key))
+	if err != nil {
+		return errors.WithStackTrace(err)
+	}
+	comment, err := syscall.UTF16PtrFromString(label)
+	if err != nil {
+		return errors.WithStackTrace(err)
+	}
+	userName, err := syscall.UTF16PtrFromString(service)
+	if err != nil {
+		return errors.WithStackTrace(err)
+	}
+
+	blob := []byte(secret)
+	cred := credential{
+		Type:               credTypeGeneric,
+		TargetName:         target,
+		Comment:            comment,
+		CredentialBlobSize: uint32(len(blob)),
+		Persist:            credPersistLocalMachine,
+		UserName:           userName,
+	}
+	if len(blob) > 0 {
+		cred.CredentialBlob = &blob[0]
+	}
+
+	ret, _, err := procCredWriteW.Call(uintptr(unsafe.Pointer(&cred)), 0)
+	if ret == 0 {
+		return errors.WithStackTrace(KeyringUnavailable{Reason: err.Error()})
+	}
+	return nil
+}
+
+// Delete removes the secret stored under the given key from the Credential Manager, if any
+func (osKeyring) Delete(key string) error {
+	target, err := syscall.UTF16PtrFromString(targetName(key))
+	if err != nil {
+		return errors.WithStackTrace(err)
+	}
+
+	ret, _, err := procCredDelete.Call(uintptr(unsafe.Pointer(target)), credTypeGeneric, 0)
+	if ret == 0 && err != errorNotFound {
+		return errors.WithStackTrace(KeyringUnavailable{Reason: err.Error()})
+	}
+	return nil
+}
@@ -13,6 +13,9 @@ import (
 // http://stackoverflow.com/a/11355611/483528
 var VERSION string
 
+// The commit Terragrunt was built from. Like VERSION, this is set at build time using -ldflags parameters.
+var COMMIT string
+
 // The main entrypoint for Terragrunt
 func main() {
 	// Log the terragrunt version in debug mode. This helps with debugging issues and ensuring a specific version of
@@ -22,6 +25,7 @@ func main() {
 	defer errors.Recover(checkForErrorsAndExit)
 
 	app := cli.CreateTerragruntCli(VERSION, os.Stdout, os.Stderr)
+	app.Metadata = map[string]interface{}{cli.METADATA_COMMIT: COMMIT}
 	err := app.Run(os.Args)
 
 	checkForErrorsAndExit(err)
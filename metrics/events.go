@@ -0,0 +1,359 @@
+package metrics
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/eventbridge"
+
+	"github.com/gruntwork-io/terragrunt/errors"
+)
+
+// The URL schemes of the supported events endpoints
+const (
+	datadogScheme     = "datadog"
+	eventBridgeScheme = "eventbridge"
+)
+
+// The env var the API key of Datadog is read from, like the Datadog agent does
+const DatadogApiKeyEnvVar = "DD_API_KEY"
+
+// The commands whose runs are sent as events, i.e., the ones that change the infrastructure, which is what the events
+// are overlaid on dashboards for
+var eventCommands = []string{"apply", "destroy"}
+
+// The kinds of events
+const (
+	eventRunStart   = "run_start"
+	eventUnitFinish = "unit_finish"
+	eventRunFinish  = "run_finish"
+)
+
+// The source and the detail types of the events sent to EventBridge, which rules match the events on
+const eventBridgeSource = "terragrunt"
+
+var eventBridgeDetailTypes = map[string]string{
+	eventRunStart:   "Terragrunt Run Started",
+	eventUnitFinish: "Terragrunt Unit Finished",
+	eventRunFinish:  "Terragrunt Run Finished",
+}
+
+// The maximum length of the text of a Datadog event
+const maxDatadogTextLength = 4000
+
+// runEvent is an event of a run, which is the detail of the EventBridge events, and from which the title, text and tags
+// of the Datadog events are rendered. The fields that don't apply to the kind of the event are left empty.
+type runEvent struct {
+	Type string `json:"type"`
+	// Identifies the run the event is part of, so that the events of the same run can be grouped
+	RunId   string    `json:"run_id"`
+	Command string    `json:"command"`
+	Time    time.Time `json:"time"`
+
+	// The unit that finished, how it went and what it changed. Only set for unit_finish.
+	Unit      string           `json:"unit,omitempty"`
+	ExitCode  *int             `json:"exit_code,omitempty"`
+	Error     string           `json:"error,omitempty"`
+	Resources *resourceChanges `json:"resources,omitempty"`
+
+	// Whether the unit or the run succeeded, and how long it took. Not set for run_start.
+	Status          string  `json:"status,omitempty"`
+	DurationSeconds float64 `json:"duration_seconds,omitempty"`
+
+	// The outcome of the units of the run. Only set for run_finish.
+	Units *unitCounts `json:"units,omitempty"`
+}
+
+type resourceChanges struct {
+	Added     int `json:"added"`
+	Changed   int `json:"changed"`
+	Destroyed int `json:"destroyed"`
+}
+
+type unitCounts struct {
+	Succeeded int `json:"succeeded"`
+	Failed    int `json:"failed"`
+	Skipped   int `json:"skipped"`
+}
+
+// Where the events are published to
+type eventPublisher interface {
+	publish(event runEvent) error
+}
+
+// Sends the start and the end of a run of a command that changes the infrastructure, and the outcome of each unit it
+// applies or destroys, as events, so that they can be overlaid on the dashboards of the infrastructure
+type eventsSink struct {
+	publisher eventPublisher
+
+	mutex     sync.Mutex
+	runId     string
+	command   string
+	start     time.Time
+	succeeded int
+	failed    int
+	skipped   int
+}
+
+func newEventsSink(publisher eventPublisher) *eventsSink {
+	return &eventsSink{publisher: publisher}
+}
+
+func (sink *eventsSink) runStarted(command string) error {
+	if !isEventCommand(command) {
+		return nil
+	}
+	sink.mutex.Lock()
+	sink.start = time.Now()
+	sink.runId = fmt.Sprintf("terragrunt-%d", sink.start.UnixNano())
+	sink.command = command
+	sink.mutex.Unlock()
+
+	return sink.publisher.publish(runEvent{Type: eventRunStart, RunId: sink.runId, Command: command, Time: sink.start})
+}
+
+func (sink *eventsSink) record(run UnitRun) error {
+	if !isEventCommand(run.Command) {
+		return nil
+	}
+
+	sink.mutex.Lock()
+	runId := sink.runId
+	switch {
+	case run.Skipped:
+		sink.skipped++
+	case run.ExitCode == 0:
+		sink.succeeded++
+	default:
+		sink.failed++
+	}
+	sink.mutex.Unlock()
+
+	// The units that didn't run didn't change anything
+	if run.Skipped {
+		return nil
+	}
+	if runId == "" {
+		runId = fmt.Sprintf("terragrunt-%d", run.Start.UnixNano())
+	}
+
+	event := runEvent{
+		Type:            eventUnitFinish,
+		RunId:           runId,
+		Command:         run.Command,
+		Time:            run.Start.Add(run.Duration),
+		Unit:            run.Unit,
+		ExitCode:        &run.ExitCode,
+		Error:           run.Error,
+		Status:          eventStatus(run.ExitCode != 0),
+		DurationSeconds: run.Duration.Seconds(),
+	}
+	if run.HasResourceChanges {
+		event.Resources = &resourceChanges{Added: run.ResourcesAdded, Changed: run.ResourcesChanged, Destroyed: run.ResourcesDestroyed}
+	}
+	return sink.publisher.publish(event)
+}
+
+func (sink *eventsSink) close() error {
+	sink.mutex.Lock()
+	if sink.runId == "" {
+		sink.mutex.Unlock()
+		return nil
+	}
+	event := runEvent{
+		Type:            eventRunFinish,
+		RunId:           sink.runId,
+		Command:         sink.command,
+		Time:            time.Now(),
+		Status:          eventStatus(sink.failed > 0),
+		DurationSeconds: time.Since(sink.start).Seconds(),
+		Units:           &unitCounts{Succeeded: sink.succeeded, Failed: sink.failed, Skipped: sink.skipped},
+	}
+	sink.mutex.Unlock()
+
+	return sink.publisher.publish(event)
+}
+
+func isEventCommand(command string) bool {
+	for _, eventCommand := range eventCommands {
+		if command == eventCommand {
+			return true
+		}
+	}
+	return false
+}
+
+func eventStatus(failed bool) string {
+	if failed {
+		return "failure"
+	}
+	return "success"
+}
+
+// Posts the events to the events API of Datadog. See https://docs.datadoghq.com/api/latest/events/#post-an-event
+type datadogPublisher struct {
+	url    string
+	apiKey string
+	client *http.Client
+}
+
+// Create a publisher to the Datadog site of the given host, e.g. datadoghq.com or datadoghq.eu
+func newDatadogPublisher(site string, apiKey string) *datadogPublisher {
+	return &datadogPublisher{url: "https://api." + site + "/api/v1/events", apiKey: apiKey, client: &http.Client{Timeout: pushTimeout}}
+}
+
+func (publisher *datadogPublisher) publish(event runEvent) error {
+	alertType := "info"
+	if event.Status != "" {
+		alertType = map[string]string{"success": "success", "failure": "error"}[event.Status]
+	}
+	tags := []string{"source:terragrunt", "command:" + event.Command, "run_id:" + event.RunId}
+	if event.Unit != "" {
+		tags = append(tags, "unit:"+event.Unit)
+	}
+	if event.Status != "" {
+		tags = append(tags, "status:"+event.Status)
+	}
+
+	body, err := json.Marshal(map[string]interface{}{
+		"title":           event.title(),
+		"text":            truncateText(event.text(), maxDatadogTextLength),
+		"tags":            tags,
+		"alert_type":      alertType,
+		"aggregation_key": event.RunId,
+		"date_happened":   event.Time.Unix(),
+	})
+	if err != nil {
+		return errors.WithStackTrace(err)
+	}
+
+	request, err := http.NewRequest(http.MethodPost, publisher.url, bytes.NewReader(body))
+	if err != nil {
+		return errors.WithStackTrace(err)
+	}
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set("DD-API-KEY", publisher.apiKey)
+
+	response, err := publisher.client.Do(request)
+	if err != nil {
+		return errors.WithStackTrace(err)
+	}
+	defer response.Body.Close()
+	responseBody, _ := ioutil.ReadAll(io.LimitReader(response.Body, 4096))
+
+	if response.StatusCode < 200 || response.StatusCode >= 300 {
+		return errors.WithStackTrace(PublishEventFailed{Destination: publisher.url, Message: fmt.Sprintf("status %d: %s", response.StatusCode, string(responseBody))})
+	}
+	return nil
+}
+
+// Puts the events on an event bus of Amazon EventBridge, i.e., CloudWatch Events
+type eventBridgePublisher struct {
+	eventBus string
+	client   *eventbridge.EventBridge
+}
+
+// Create a publisher to the event bus with the given name or ARN, with the credentials and the region of the default
+// AWS session
+func newEventBridgePublisher(eventBus string) (*eventBridgePublisher, error) {
+	sess, err := session.NewSessionWithOptions(session.Options{SharedConfigState: session.SharedConfigEnable})
+	if err != nil {
+		return nil, errors.WithStackTrace(err)
+	}
+	return &eventBridgePublisher{eventBus: eventBus, client: eventbridge.New(sess)}, nil
+}
+
+func (publisher *eventBridgePublisher) publish(event runEvent) error {
+	detail, err := json.Marshal(event)
+	if err != nil {
+		return errors.WithStackTrace(err)
+	}
+
+	output, err := publisher.client.PutEvents(&eventbridge.PutEventsInput{
+		Entries: []*eventbridge.PutEventsRequestEntry{{
+			EventBusName: aws.String(publisher.eventBus),
+			Source:       aws.String(eventBridgeSource),
+			DetailType:   aws.String(eventBridgeDetailTypes[event.Type]),
+			Detail:       aws.String(string(detail)),
+			Time:         aws.Time(event.Time),
+		}},
+	})
+	if err != nil {
+		return errors.WithStackTrace(err)
+	}
+	if aws.Int64Value(output.FailedEntryCount) > 0 && len(output.Entries) > 0 {
+		return errors.WithStackTrace(PublishEventFailed{Destination: publisher.eventBus, Message: aws.StringValue(output.Entries[0].ErrorMessage)})
+	}
+	return nil
+}
+
+// The title of the event in Datadog
+func (event runEvent) title() string {
+	switch event.Type {
+	case eventRunStart:
+		return fmt.Sprintf("terragrunt %s started", event.Command)
+	case eventUnitFinish:
+		return fmt.Sprintf("terragrunt %s %s in %s", event.Command, map[string]string{"success": "succeeded", "failure": "failed"}[event.Status], event.Unit)
+	case eventRunFinish:
+		return fmt.Sprintf("terragrunt %s %s", event.Command, map[string]string{"success": "completed", "failure": "failed"}[event.Status])
+	}
+	return event.Type
+}
+
+// The text of the event in Datadog
+func (event runEvent) text() string {
+	lines := []string{}
+	if event.Resources != nil {
+		lines = append(lines, fmt.Sprintf("Resources: %d added, %d changed, %d destroyed.", event.Resources.Added, event.Resources.Changed, event.Resources.Destroyed))
+	}
+	if event.Units != nil {
+		lines = append(lines, fmt.Sprintf("Units: %d succeeded, %d failed, %d skipped.", event.Units.Succeeded, event.Units.Failed, event.Units.Skipped))
+	}
+	if event.Type != eventRunStart {
+		lines = append(lines, fmt.Sprintf("Duration: %s.", time.Duration(event.DurationSeconds*float64(time.Second)).Round(time.Second)))
+	}
+	if event.Error != "" {
+		lines = append(lines, "", event.Error)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// Keep the start of the given text, up to the given length
+func truncateText(text string, maxLength int) string {
+	if len(text) <= maxLength {
+		return text
+	}
+	return text[:maxLength-3] + "..."
+}
+
+// Custom error types
+
+type PublishEventFailed struct {
+	Destination string
+	Message     string
+}
+
+func (err PublishEventFailed) Error() string {
+	return fmt.Sprintf("Publishing the Terragrunt event to %s failed: %s", err.Destination, err.Message)
+}
+
+type InvalidEventsEndpoint string
+
+func (endpoint InvalidEventsEndpoint) Error() string {
+	return fmt.Sprintf("Invalid events endpoint %q: expected datadog://<site>, e.g. datadog://datadoghq.com, or eventbridge://<event bus name or ARN>", string(endpoint))
+}
+
+type MissingDatadogApiKey string
+
+func (site MissingDatadogApiKey) Error() string {
+	return fmt.Sprintf("Found no API key for the Datadog site %s. Set the %s env var.", string(site), DatadogApiKeyEnvVar)
+}
@@ -0,0 +1,127 @@
+package metrics
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/eventbridge"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/gruntwork-io/terragrunt/errors"
+)
+
+func TestAddEventsEndpointInvalid(t *testing.T) {
+	t.Parallel()
+
+	for _, endpoint := range []string{"datadoghq.com", "datadog://", "statsd://localhost:8125", "eventbridge:/default"} {
+		err := NewRecorder("/repo").AddEventsEndpoint(endpoint, map[string]string{DatadogApiKeyEnvVar: "key"})
+		assert.True(t, errors.IsError(err, InvalidEventsEndpoint(endpoint)), "For endpoint %s: %v", endpoint, err)
+	}
+
+	err := NewRecorder("/repo").AddEventsEndpoint("datadog://datadoghq.eu", map[string]string{})
+	assert.True(t, errors.IsError(err, MissingDatadogApiKey("datadoghq.eu")), "Unexpected error: %v", err)
+}
+
+func TestRecorderPostsDatadogEvents(t *testing.T) {
+	t.Parallel()
+
+	events := []map[string]interface{}{}
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		assert.Equal(t, "key", request.Header.Get("DD-API-KEY"))
+		event := map[string]interface{}{}
+		require.NoError(t, json.NewDecoder(request.Body).Decode(&event))
+		events = append(events, event)
+		writer.WriteHeader(http.StatusAccepted)
+	}))
+	defer server.Close()
+
+	recorder := NewRecorder("/repo")
+	recorder.sinks = append(recorder.sinks, newEventsSink(&datadogPublisher{url: server.URL, apiKey: "key", client: server.Client()}))
+
+	require.NoError(t, recorder.RunStarted("apply"))
+	vpc := recorder.StartUnit("/repo/live/prod/vpc/terragrunt.hcl", "apply")
+	vpc.SetResourceChanges("Apply complete! Resources: 2 added, 0 changed, 1 destroyed.")
+	require.NoError(t, vpc.Finish(0, nil))
+	require.NoError(t, recorder.StartUnit("/repo/live/prod/app/terragrunt.hcl", "apply").Finish(1, errors.WithStackTrace(InvalidEventsEndpoint("boom"))))
+	require.NoError(t, recorder.SkipUnit("/repo/live/prod/dns/terragrunt.hcl", "apply", "a dependency failed"))
+	require.NoError(t, recorder.Close())
+
+	require.Len(t, events, 4)
+	assert.Equal(t, "terragrunt apply started", events[0]["title"])
+	assert.Equal(t, "info", events[0]["alert_type"])
+	runId := events[0]["aggregation_key"]
+
+	assert.Equal(t, "terragrunt apply succeeded in live/prod/vpc", events[1]["title"])
+	assert.Equal(t, "success", events[1]["alert_type"])
+	assert.Contains(t, events[1]["text"], "Resources: 2 added, 0 changed, 1 destroyed.")
+	assert.Contains(t, events[1]["tags"], "unit:live/prod/vpc")
+	assert.Equal(t, runId, events[1]["aggregation_key"])
+
+	assert.Equal(t, "terragrunt apply failed in live/prod/app", events[2]["title"])
+	assert.Equal(t, "error", events[2]["alert_type"])
+
+	assert.Equal(t, "terragrunt apply failed", events[3]["title"])
+	assert.Contains(t, events[3]["text"], "Units: 1 succeeded, 1 failed, 1 skipped.")
+	assert.Equal(t, runId, events[3]["aggregation_key"])
+}
+
+func TestRecorderSendsNoEventsForPlan(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		t.Errorf("Unexpected event: %s %s", request.Method, request.URL)
+	}))
+	defer server.Close()
+
+	recorder := NewRecorder("/repo")
+	recorder.sinks = append(recorder.sinks, newEventsSink(&datadogPublisher{url: server.URL, apiKey: "key", client: server.Client()}))
+
+	require.NoError(t, recorder.RunStarted("plan"))
+	require.NoError(t, recorder.StartUnit("/repo/vpc/terragrunt.hcl", "plan").Finish(0, nil))
+	require.NoError(t, recorder.Close())
+}
+
+func TestRecorderPutsEventBridgeEvents(t *testing.T) {
+	t.Parallel()
+
+	entries := []map[string]interface{}{}
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		assert.Equal(t, "AWSEvents.PutEvents", request.Header.Get("X-Amz-Target"))
+		var input struct {
+			Entries []map[string]interface{}
+		}
+		require.NoError(t, json.NewDecoder(request.Body).Decode(&input))
+		entries = append(entries, input.Entries...)
+		writer.Write([]byte(`{"FailedEntryCount": 0, "Entries": [{"EventId": "1"}]}`))
+	}))
+	defer server.Close()
+
+	sess, err := session.NewSession(aws.NewConfig().WithRegion("us-east-1").WithEndpoint(server.URL).WithCredentials(credentials.NewStaticCredentials("id", "secret", "")))
+	require.NoError(t, err)
+	recorder := NewRecorder("/repo")
+	recorder.sinks = append(recorder.sinks, newEventsSink(&eventBridgePublisher{eventBus: "infra", client: eventbridge.New(sess)}))
+
+	require.NoError(t, recorder.RunStarted("destroy"))
+	require.NoError(t, recorder.StartUnit("/repo/vpc/terragrunt.hcl", "destroy").Finish(0, nil))
+	require.NoError(t, recorder.Close())
+
+	require.Len(t, entries, 3)
+	for i, detailType := range []string{"Terragrunt Run Started", "Terragrunt Unit Finished", "Terragrunt Run Finished"} {
+		assert.Equal(t, "infra", entries[i]["EventBusName"])
+		assert.Equal(t, "terragrunt", entries[i]["Source"])
+		assert.Equal(t, detailType, entries[i]["DetailType"])
+	}
+
+	detail := map[string]interface{}{}
+	require.NoError(t, json.Unmarshal([]byte(entries[1]["Detail"].(string)), &detail))
+	assert.Equal(t, "unit_finish", detail["type"])
+	assert.Equal(t, "vpc", detail["unit"])
+	assert.Equal(t, "success", detail["status"])
+	assert.Equal(t, float64(0), detail["exit_code"])
+}
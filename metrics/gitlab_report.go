@@ -0,0 +1,58 @@
+package metrics
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/gruntwork-io/terragrunt/errors"
+)
+
+// The name of the report of each unit, in the folder of the unit under the report dir
+const GitLabReportFileName = "plan.json"
+
+// The terraform command whose resource changes GitLab shows on merge requests
+const gitLabReportCommand = "plan"
+
+// Writes the resource changes of the plan of each unit, as soon as it finishes, to a JSON file in the format of the
+// terraform reports of GitLab CI, so that merge requests show a widget with the changes of each unit once the files are
+// uploaded as artifacts:reports:terraform. See
+// https://docs.gitlab.com/ee/ci/yaml/artifacts_reports.html#artifactsreportsterraform
+type gitLabReportSink struct {
+	dir string
+}
+
+// The format of the terraform reports of GitLab CI, as written by the gitlab-terraform script. Resources that are
+// replaced are counted as both created and deleted, as in the summary of terraform plan.
+type gitLabReport struct {
+	Create int `json:"create"`
+	Update int `json:"update"`
+	Delete int `json:"delete"`
+}
+
+func newGitLabReportSink(dir string) *gitLabReportSink {
+	return &gitLabReportSink{dir: dir}
+}
+
+func (sink *gitLabReportSink) record(run UnitRun) error {
+	if run.Command != gitLabReportCommand || !run.HasResourceChanges {
+		return nil
+	}
+
+	// Units outside of the root dir, whose path starts with .., are written under the report dir all the same
+	reportPath := filepath.Join(sink.dir, filepath.Clean("/"+run.Unit), GitLabReportFileName)
+	if err := os.MkdirAll(filepath.Dir(reportPath), os.ModePerm); err != nil {
+		return errors.WithStackTrace(err)
+	}
+
+	report, err := json.Marshal(gitLabReport{Create: run.ResourcesAdded, Update: run.ResourcesChanged, Delete: run.ResourcesDestroyed})
+	if err != nil {
+		return errors.WithStackTrace(err)
+	}
+	return errors.WithStackTrace(ioutil.WriteFile(reportPath, report, 0644))
+}
+
+func (sink *gitLabReportSink) close() error {
+	return nil
+}
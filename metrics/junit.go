@@ -0,0 +1,164 @@
+package metrics
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gruntwork-io/terragrunt/errors"
+)
+
+// The name of the test suite the units are the test cases of
+const junitSuiteName = "terragrunt"
+
+// Collects the runs of the units, and writes them as a JUnit XML report once the Terragrunt command finishes, with a
+// test case for each unit, so that CI systems show which units passed and failed the same way as the results of tests.
+// The test cases are named after the units, and their class name is the command that was run.
+type junitSink struct {
+	path string
+
+	mutex sync.Mutex
+	runs  []UnitRun
+}
+
+// The elements of a JUnit XML report, as understood by Jenkins, GitLab, CircleCI, Azure Pipelines and most other CI
+// systems. See https://github.com/testmoapp/junitxml
+type junitTestSuites struct {
+	XMLName  xml.Name         `xml:"testsuites"`
+	Name     string           `xml:"name,attr"`
+	Tests    int              `xml:"tests,attr"`
+	Failures int              `xml:"failures,attr"`
+	Skipped  int              `xml:"skipped,attr"`
+	Time     string           `xml:"time,attr"`
+	Suites   []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name      string          `xml:"name,attr"`
+	Tests     int             `xml:"tests,attr"`
+	Failures  int             `xml:"failures,attr"`
+	Skipped   int             `xml:"skipped,attr"`
+	Time      string          `xml:"time,attr"`
+	Timestamp string          `xml:"timestamp,attr,omitempty"`
+	TestCases []junitTestCase `xml:"testcase"`
+}
+
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Time      string        `xml:"time,attr"`
+	Failure   *junitFailure `xml:"failure,omitempty"`
+	Skipped   *junitSkipped `xml:"skipped,omitempty"`
+}
+
+type junitFailure struct {
+	Message  string `xml:"message,attr"`
+	Type     string `xml:"type,attr"`
+	Contents string `xml:",chardata"`
+}
+
+type junitSkipped struct {
+	Message string `xml:"message,attr"`
+}
+
+func newJUnitSink(path string) *junitSink {
+	return &junitSink{path: path}
+}
+
+func (sink *junitSink) record(run UnitRun) error {
+	sink.mutex.Lock()
+	defer sink.mutex.Unlock()
+	sink.runs = append(sink.runs, run)
+	return nil
+}
+
+func (sink *junitSink) close() error {
+	sink.mutex.Lock()
+	runs := append([]UnitRun{}, sink.runs...)
+	sink.mutex.Unlock()
+
+	report, err := xml.MarshalIndent(junitReport(runs), "", "  ")
+	if err != nil {
+		return errors.WithStackTrace(err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(sink.path), os.ModePerm); err != nil {
+		return errors.WithStackTrace(err)
+	}
+	return errors.WithStackTrace(ioutil.WriteFile(sink.path, append([]byte(xml.Header), append(report, '\n')...), 0644))
+}
+
+// Convert the given runs to a JUnit report with a single test suite, with the test cases sorted by unit. The time of
+// the suite is the time from the start of the first unit to the end of the last one, as units run concurrently.
+func junitReport(runs []UnitRun) junitTestSuites {
+	sort.SliceStable(runs, func(i, j int) bool { return runs[i].Unit < runs[j].Unit })
+
+	suite := junitTestSuite{Name: junitSuiteName, TestCases: []junitTestCase{}}
+	var start, end time.Time
+	for _, run := range runs {
+		testCase := junitTestCase{Name: run.Unit, ClassName: run.Command, Time: junitSeconds(run.Duration)}
+		switch {
+		case run.Skipped:
+			suite.Skipped++
+			testCase.Skipped = &junitSkipped{Message: run.Error}
+		case run.ExitCode != 0:
+			suite.Failures++
+			testCase.Failure = junitFailureOf(run)
+		}
+		suite.TestCases = append(suite.TestCases, testCase)
+		suite.Tests++
+
+		if run.Skipped {
+			continue
+		}
+		if start.IsZero() || run.Start.Before(start) {
+			start = run.Start
+		}
+		if runEnd := run.Start.Add(run.Duration); runEnd.After(end) {
+			end = runEnd
+		}
+	}
+
+	suite.Time = junitSeconds(end.Sub(start))
+	if !start.IsZero() {
+		suite.Timestamp = start.UTC().Format("2006-01-02T15:04:05")
+	}
+
+	return junitTestSuites{
+		Name:     junitSuiteName,
+		Tests:    suite.Tests,
+		Failures: suite.Failures,
+		Skipped:  suite.Skipped,
+		Time:     suite.Time,
+		Suites:   []junitTestSuite{suite},
+	}
+}
+
+// The failure of a run has the first line of its error as the message, and the stderr of terraform, if any, or else
+// the whole error, as the details
+func junitFailureOf(run UnitRun) *junitFailure {
+	message := strings.TrimSpace(run.Error)
+	if index := strings.Index(message, "\n"); index >= 0 {
+		message = message[:index]
+	}
+	if message == "" {
+		message = fmt.Sprintf("%s failed with exit code %d", run.Command, run.ExitCode)
+	}
+
+	details := run.ErrorOutput
+	if strings.TrimSpace(details) == "" {
+		details = run.Error
+	}
+
+	return &junitFailure{Message: message, Type: fmt.Sprintf("exit code %d", run.ExitCode), Contents: details}
+}
+
+func junitSeconds(duration time.Duration) string {
+	return fmt.Sprintf("%.3f", duration.Seconds())
+}
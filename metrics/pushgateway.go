@@ -0,0 +1,167 @@
+package metrics
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gruntwork-io/terragrunt/errors"
+)
+
+// The job the metrics are grouped under in the Pushgateway, unless the endpoint already names a group
+const pushgatewayJob = "terragrunt"
+
+// How long to wait for the Pushgateway to accept the metrics
+const pushTimeout = 10 * time.Second
+
+// Collects the metrics of the units, and pushes them all to a Prometheus Pushgateway once the Terragrunt command
+// finishes. They are pushed together because each push replaces the metrics of the same name in the group, which
+// would leave only the metrics of the last unit if each unit pushed its own.
+type pushgatewaySink struct {
+	url    string
+	client *http.Client
+
+	mutex sync.Mutex
+	// The runs to push, by unit and command, so that each series is only pushed once
+	runs map[string]UnitRun
+}
+
+func newPushgatewaySink(endpointUrl *url.URL) *pushgatewaySink {
+	pushUrl := *endpointUrl
+	if !strings.Contains(pushUrl.Path, "/metrics/job/") {
+		pushUrl.Path = strings.TrimSuffix(pushUrl.Path, "/") + "/metrics/job/" + pushgatewayJob
+	}
+	return &pushgatewaySink{
+		url:    pushUrl.String(),
+		client: &http.Client{Timeout: pushTimeout},
+		runs:   map[string]UnitRun{},
+	}
+}
+
+func (sink *pushgatewaySink) record(run UnitRun) error {
+	// Only the units that ran have metrics
+	if run.Skipped {
+		return nil
+	}
+	sink.mutex.Lock()
+	defer sink.mutex.Unlock()
+	sink.runs[run.Unit+"\n"+run.Command] = run
+	return nil
+}
+
+func (sink *pushgatewaySink) close() error {
+	sink.mutex.Lock()
+	runs := []UnitRun{}
+	for _, run := range sink.runs {
+		runs = append(runs, run)
+	}
+	sink.mutex.Unlock()
+
+	if len(runs) == 0 {
+		return nil
+	}
+
+	// POST replaces the metrics of the same names in the group, but leaves the other metrics in it
+	response, err := sink.client.Post(sink.url, "text/plain; version=0.0.4", bytes.NewReader(prometheusText(runs)))
+	if err != nil {
+		return errors.WithStackTrace(err)
+	}
+	defer response.Body.Close()
+	responseBody, _ := ioutil.ReadAll(io.LimitReader(response.Body, 4096))
+
+	if response.StatusCode < 200 || response.StatusCode >= 300 {
+		return errors.WithStackTrace(PushFailed{Url: sink.url, StatusCode: response.StatusCode, Body: string(responseBody)})
+	}
+	return nil
+}
+
+// A metric in the Prometheus text format, along with how to get its value from a run
+type prometheusMetric struct {
+	name  string
+	help  string
+	value func(run UnitRun) float64
+}
+
+var prometheusMetrics = []prometheusMetric{
+	{
+		name:  "terragrunt_unit_duration_seconds",
+		help:  "How long the last run of the command in the unit took.",
+		value: func(run UnitRun) float64 { return run.Duration.Seconds() },
+	},
+	{
+		name:  "terragrunt_unit_exit_code",
+		help:  "The exit code of the last run of the command in the unit.",
+		value: func(run UnitRun) float64 { return float64(run.ExitCode) },
+	},
+	{
+		name:  "terragrunt_unit_retries",
+		help:  "The number of times the last run of the command in the unit was retried after a transient error.",
+		value: func(run UnitRun) float64 { return float64(run.Retries) },
+	},
+	{
+		name:  "terragrunt_unit_last_run_timestamp_seconds",
+		help:  "When the last run of the command in the unit started.",
+		value: func(run UnitRun) float64 { return float64(run.Start.UnixNano()) / float64(time.Second) },
+	},
+}
+
+// Render the metrics of the given runs in the Prometheus text format, sorted by unit and command
+func prometheusText(runs []UnitRun) []byte {
+	sort.Slice(runs, func(i, j int) bool {
+		if runs[i].Unit != runs[j].Unit {
+			return runs[i].Unit < runs[j].Unit
+		}
+		return runs[i].Command < runs[j].Command
+	})
+
+	var text bytes.Buffer
+	for _, metric := range prometheusMetrics {
+		fmt.Fprintf(&text, "# HELP %s %s\n# TYPE %s gauge\n", metric.name, metric.help, metric.name)
+		for _, run := range runs {
+			fmt.Fprintf(&text, "%s{%s} %v\n", metric.name, prometheusLabels("unit", run.Unit, "command", run.Command), metric.value(run))
+		}
+	}
+
+	resourcesName := "terragrunt_unit_resources"
+	fmt.Fprintf(&text, "# HELP %s The number of resources the last run of the command in the unit added, changed or destroyed.\n# TYPE %s gauge\n", resourcesName, resourcesName)
+	for _, run := range runs {
+		if !run.HasResourceChanges {
+			continue
+		}
+		for _, action := range resourceActions(run) {
+			fmt.Fprintf(&text, "%s{%s} %d\n", resourcesName, prometheusLabels("unit", run.Unit, "command", run.Command, "action", action.name), action.count)
+		}
+	}
+
+	return text.Bytes()
+}
+
+// Render the given labels, as alternating names and values, in the Prometheus text format, e.g.
+// unit="vpc",command="apply"
+func prometheusLabels(namesAndValues ...string) string {
+	replacer := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+	labels := []string{}
+	for i := 0; i+1 < len(namesAndValues); i += 2 {
+		labels = append(labels, fmt.Sprintf(`%s="%s"`, namesAndValues[i], replacer.Replace(namesAndValues[i+1])))
+	}
+	return strings.Join(labels, ",")
+}
+
+// Custom error types
+
+type PushFailed struct {
+	Url        string
+	StatusCode int
+	Body       string
+}
+
+func (err PushFailed) Error() string {
+	return fmt.Sprintf("Pushing the Terragrunt metrics to %s failed with status %d: %s", err.Url, err.StatusCode, err.Body)
+}
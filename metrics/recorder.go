@@ -0,0 +1,363 @@
+// Package metrics emits metrics about the units Terragrunt runs, such as how long each took, how many times it was
+// retried, its exit code and how many resources it changed, to a StatsD server or a Prometheus Pushgateway, so that
+// dashboards of the health of the infrastructure code can be built without parsing the logs. The same metrics can be
+// written as a table to the step summary of a GitHub Actions job or as a JUnit XML report, and the resource changes of
+// plans as GitLab CI terraform reports. The applies and destroys can be sent as events to Datadog or Amazon
+// EventBridge, to track the changes to the infrastructure on dashboards.
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/url"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/go-multierror"
+
+	"github.com/gruntwork-io/terragrunt/errors"
+)
+
+// The URL schemes of the supported metrics endpoints
+const (
+	statsdScheme = "statsd"
+	httpScheme   = "http"
+	httpsScheme  = "https"
+)
+
+// UnitRun is the outcome of running a Terragrunt command in a unit, i.e., a folder with a Terragrunt configuration
+type UnitRun struct {
+	// The path of the unit, relative to the root dir of the recorder
+	Unit string
+	// The terraform command that was run, e.g. plan or apply
+	Command  string
+	Start    time.Time
+	Duration time.Duration
+	// The number of times the command was retried after a transient error
+	Retries  int
+	ExitCode int
+	// The error the run failed with, or why the unit was skipped, if it was
+	Error string
+	// The tail of the stderr of the terraform command that failed, if any
+	ErrorOutput string
+	// True if the unit wasn't run because one of its dependencies failed. Only Unit, Command and Error are set then.
+	Skipped bool
+
+	// The number of resources added, changed and destroyed, as reported by terraform plan, apply or destroy. Only set
+	// if HasResourceChanges is true.
+	HasResourceChanges bool
+	ResourcesAdded     int
+	ResourcesChanged   int
+	ResourcesDestroyed int
+}
+
+// Recorder sends the metrics of the units a Terragrunt command runs to a StatsD server, a Prometheus Pushgateway, the
+// step summary of a GitHub Actions job, GitLab CI terraform reports, a JUnit XML report and/or an events endpoint
+type Recorder struct {
+	sinks   []sink
+	rootDir string
+}
+
+// Unit collects the metrics of a single run of a unit until it finishes. All the methods of Unit can be called on a nil
+// unit, which is what is used when metrics are disabled, so that the code being measured doesn't need to check
+// whether they are.
+type Unit struct {
+	recorder *Recorder
+
+	mutex sync.Mutex
+	run   UnitRun
+}
+
+// The destinations of the metrics
+type sink interface {
+	// Record the metrics of the given run of a unit
+	record(run UnitRun) error
+	// Send any metrics not sent yet and release the resources of the sink
+	close() error
+}
+
+// The sinks that record the start of the run too
+type runStartSink interface {
+	runStarted(command string) error
+}
+
+// The maximum number of bytes of the stderr of a failed terraform command that are kept, from the end, which is where
+// terraform prints the errors
+const maxErrorOutputBytes = 64 * 1024
+
+// The escape sequences terraform colors its output with
+var colorCodesRegex = regexp.MustCompile(`\x1b\[[0-9;]*m`)
+
+// The summaries of the resource changes printed by terraform, which may be wrapped in color codes
+var (
+	planSummaryRegex    = regexp.MustCompile(`Plan: (\d+) to add, (\d+) to change, (\d+) to destroy`)
+	applySummaryRegex   = regexp.MustCompile(`Apply complete! Resources: (\d+) added, (\d+) changed, (\d+) destroyed`)
+	destroySummaryRegex = regexp.MustCompile(`Destroy complete! Resources: (\d+) destroyed`)
+	noChangesRegex      = regexp.MustCompile(`No changes\.`)
+)
+
+// Create a recorder that doesn't send the metrics anywhere until AddEndpoint, AddStepSummary, AddGitLabReport,
+// AddJUnitReport or AddEventsEndpoint is called. The units are identified by their path relative to the given root dir.
+func NewRecorder(rootDir string) *Recorder {
+	return &Recorder{rootDir: rootDir}
+}
+
+// Send the metrics to the given endpoint, which is either a StatsD server, given as statsd://host:port, or a
+// Prometheus Pushgateway, given as an http:// or https:// URL
+func (recorder *Recorder) AddEndpoint(endpoint string) error {
+	endpointUrl, err := url.Parse(endpoint)
+	if err != nil || endpointUrl.Host == "" {
+		return errors.WithStackTrace(InvalidEndpoint(endpoint))
+	}
+
+	switch endpointUrl.Scheme {
+	case statsdScheme:
+		statsd, err := newStatsdSink(endpointUrl.Host)
+		if err != nil {
+			return err
+		}
+		recorder.sinks = append(recorder.sinks, statsd)
+	case httpScheme, httpsScheme:
+		recorder.sinks = append(recorder.sinks, newPushgatewaySink(endpointUrl))
+	default:
+		return errors.WithStackTrace(InvalidEndpoint(endpoint))
+	}
+	return nil
+}
+
+// Write the metrics as a Markdown table to the given file once the command finishes. This is meant for the file in
+// the GITHUB_STEP_SUMMARY env var, which GitHub Actions shows on the summary page of the job.
+func (recorder *Recorder) AddStepSummary(path string) {
+	recorder.sinks = append(recorder.sinks, newStepSummarySink(path))
+}
+
+// Write the resource changes of the plan of each unit to <dir>/<unit>/plan.json, in the format of the terraform reports
+// of GitLab CI
+func (recorder *Recorder) AddGitLabReport(dir string) {
+	recorder.sinks = append(recorder.sinks, newGitLabReportSink(dir))
+}
+
+// Write the runs of the units as a JUnit XML report to the given file once the command finishes, with a test case for
+// each unit
+func (recorder *Recorder) AddJUnitReport(path string) {
+	recorder.sinks = append(recorder.sinks, newJUnitSink(path))
+}
+
+// Send the start and the end of the runs of apply and destroy, and the outcome of each unit they run, as events to the
+// given endpoint, which is either Datadog, given as datadog://<site>, e.g. datadog://datadoghq.com, with the API key of
+// the DD_API_KEY env var of the given env, or an event bus of Amazon EventBridge, given as eventbridge://<name or ARN>
+func (recorder *Recorder) AddEventsEndpoint(endpoint string, env map[string]string) error {
+	scheme, destination := "", ""
+	if parts := strings.SplitN(endpoint, "://", 2); len(parts) == 2 {
+		scheme, destination = parts[0], strings.TrimSuffix(parts[1], "/")
+	}
+	if destination == "" {
+		return errors.WithStackTrace(InvalidEventsEndpoint(endpoint))
+	}
+
+	switch scheme {
+	case datadogScheme:
+		apiKey := env[DatadogApiKeyEnvVar]
+		if apiKey == "" {
+			return errors.WithStackTrace(MissingDatadogApiKey(destination))
+		}
+		recorder.sinks = append(recorder.sinks, newEventsSink(newDatadogPublisher(destination, apiKey)))
+	case eventBridgeScheme:
+		publisher, err := newEventBridgePublisher(destination)
+		if err != nil {
+			return err
+		}
+		recorder.sinks = append(recorder.sinks, newEventsSink(publisher))
+	default:
+		return errors.WithStackTrace(InvalidEventsEndpoint(endpoint))
+	}
+	return nil
+}
+
+// Record that the run of the given command started. This is a no-op if the recorder is nil.
+func (recorder *Recorder) RunStarted(command string) error {
+	if recorder == nil {
+		return nil
+	}
+	var result *multierror.Error
+	for _, metricsSink := range recorder.sinks {
+		if startSink, recordsStart := metricsSink.(runStartSink); recordsStart {
+			result = multierror.Append(result, startSink.runStarted(command))
+		}
+	}
+	return result.ErrorOrNil()
+}
+
+// Start measuring a run of the given command in the unit with the given Terragrunt config. Returns nil if the recorder
+// is nil, i.e., metrics are disabled.
+func (recorder *Recorder) StartUnit(terragruntConfigPath string, command string) *Unit {
+	if recorder == nil {
+		return nil
+	}
+
+	return &Unit{
+		recorder: recorder,
+		run:      UnitRun{Unit: recorder.unitPath(terragruntConfigPath), Command: command, Start: time.Now()},
+	}
+}
+
+// Record that the given command wasn't run in the unit with the given Terragrunt config for the given reason, e.g.
+// because one of its dependencies failed. This is a no-op if the recorder is nil.
+func (recorder *Recorder) SkipUnit(terragruntConfigPath string, command string, reason string) error {
+	if recorder == nil {
+		return nil
+	}
+	return recorder.record(UnitRun{Unit: recorder.unitPath(terragruntConfigPath), Command: command, Error: reason, Skipped: true})
+}
+
+// Send the metrics that are not sent yet. This should be called once the Terragrunt command finishes.
+func (recorder *Recorder) Close() error {
+	if recorder == nil {
+		return nil
+	}
+	var result *multierror.Error
+	for _, metricsSink := range recorder.sinks {
+		result = multierror.Append(result, metricsSink.close())
+	}
+	return result.ErrorOrNil()
+}
+
+// Return the path of the unit with the given Terragrunt config relative to the root dir, with forward slashes
+func (recorder *Recorder) unitPath(terragruntConfigPath string) string {
+	unitPath := filepath.Dir(terragruntConfigPath)
+	if relPath, err := filepath.Rel(recorder.rootDir, unitPath); err == nil {
+		unitPath = relPath
+	}
+	return filepath.ToSlash(unitPath)
+}
+
+// Send the given run to all the sinks
+func (recorder *Recorder) record(run UnitRun) error {
+	var result *multierror.Error
+	for _, metricsSink := range recorder.sinks {
+		result = multierror.Append(result, metricsSink.record(run))
+	}
+	return result.ErrorOrNil()
+}
+
+// Record that the command of the unit is retried
+func (unit *Unit) AddRetry() {
+	if unit == nil {
+		return
+	}
+	unit.mutex.Lock()
+	defer unit.mutex.Unlock()
+	unit.run.Retries++
+}
+
+// Record the resource changes reported in the given output of terraform plan, apply or destroy, if any
+func (unit *Unit) SetResourceChanges(terraformOutput string) {
+	if unit == nil {
+		return
+	}
+	added, changed, destroyed, found := parseResourceChanges(terraformOutput)
+	if !found {
+		return
+	}
+
+	unit.mutex.Lock()
+	defer unit.mutex.Unlock()
+	unit.run.HasResourceChanges = true
+	unit.run.ResourcesAdded = added
+	unit.run.ResourcesChanged = changed
+	unit.run.ResourcesDestroyed = destroyed
+}
+
+// ScanResourceChanges records the resource changes of the unit like SetResourceChanges, reading the given output of
+// terraform line by line, so that the output of a large plan doesn't have to be held in memory
+func (unit *Unit) ScanResourceChanges(terraformOutput io.Reader) {
+	if unit == nil {
+		return
+	}
+	reader := bufio.NewReader(terraformOutput)
+	for {
+		line, isPrefix, err := reader.ReadLine()
+		if err != nil {
+			return
+		}
+		// The summary lines are short, so the lines that don't fit in the buffer, e.g. of a large JSON policy, are skipped
+		if isPrefix {
+			for isPrefix && err == nil {
+				_, isPrefix, err = reader.ReadLine()
+			}
+			continue
+		}
+		unit.SetResourceChanges(string(line))
+	}
+}
+
+// Record the stderr of a terraform command of the unit that failed, without the color codes, so that it can be reported
+// along with the error the run fails with
+func (unit *Unit) SetErrorOutput(stderr string) {
+	if unit == nil {
+		return
+	}
+	errorOutput := colorCodesRegex.ReplaceAllString(stderr, "")
+	if len(errorOutput) > maxErrorOutputBytes {
+		errorOutput = errorOutput[len(errorOutput)-maxErrorOutputBytes:]
+	}
+
+	unit.mutex.Lock()
+	defer unit.mutex.Unlock()
+	unit.run.ErrorOutput = errorOutput
+}
+
+// Record that the run of the unit finished with the given exit code and error, if any, and send its metrics
+func (unit *Unit) Finish(exitCode int, runErr error) error {
+	if unit == nil {
+		return nil
+	}
+
+	unit.mutex.Lock()
+	unit.run.Duration = time.Since(unit.run.Start)
+	unit.run.ExitCode = exitCode
+	if runErr != nil {
+		unit.run.Error = runErr.Error()
+	}
+	run := unit.run
+	unit.mutex.Unlock()
+
+	return unit.recorder.record(run)
+}
+
+// Parse the number of resources added, changed and destroyed from the given terraform output. Returns false if the
+// output doesn't report any resource changes, e.g. because it's not the output of plan, apply or destroy.
+func parseResourceChanges(terraformOutput string) (int, int, int, bool) {
+	if matches := applySummaryRegex.FindStringSubmatch(terraformOutput); matches != nil {
+		return atoi(matches[1]), atoi(matches[2]), atoi(matches[3]), true
+	}
+	if matches := planSummaryRegex.FindStringSubmatch(terraformOutput); matches != nil {
+		return atoi(matches[1]), atoi(matches[2]), atoi(matches[3]), true
+	}
+	if matches := destroySummaryRegex.FindStringSubmatch(terraformOutput); matches != nil {
+		return 0, 0, atoi(matches[1]), true
+	}
+	if noChangesRegex.MatchString(terraformOutput) {
+		return 0, 0, 0, true
+	}
+	return 0, 0, 0, false
+}
+
+// The regexes above only match digits, so this only fails on overflow, where 0 is as good a count as any
+func atoi(value string) int {
+	number, _ := strconv.Atoi(value)
+	return number
+}
+
+// Custom error types
+
+type InvalidEndpoint string
+
+func (endpoint InvalidEndpoint) Error() string {
+	return fmt.Sprintf("Invalid metrics endpoint %q: expected statsd://host:port for StatsD, or an http:// or https:// URL for a Prometheus Pushgateway", string(endpoint))
+}
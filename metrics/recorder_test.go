@@ -0,0 +1,300 @@
+package metrics
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/go-multierror"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/gruntwork-io/terragrunt/errors"
+)
+
+func TestParseResourceChanges(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		output    string
+		added     int
+		changed   int
+		destroyed int
+		found     bool
+	}{
+		{"Plan: 3 to add, 1 to change, 0 to destroy.", 3, 1, 0, true},
+		{"Plan: 1 to add, 0 to change, 2 to destroy, 1 to import.", 1, 0, 2, true},
+		{"\x1b[0m\x1b[1m\x1b[32mApply complete! Resources: 2 added, 5 changed, 1 destroyed.\x1b[0m", 2, 5, 1, true},
+		{"Destroy complete! Resources: 7 destroyed.", 0, 0, 7, true},
+		{"No changes. Your infrastructure matches the configuration.", 0, 0, 0, true},
+		{"Success! The configuration is valid.", 0, 0, 0, false},
+	}
+
+	for _, testCase := range testCases {
+		added, changed, destroyed, found := parseResourceChanges(testCase.output)
+		assert.Equal(t, testCase.found, found, "For output %q", testCase.output)
+		assert.Equal(t, []int{testCase.added, testCase.changed, testCase.destroyed}, []int{added, changed, destroyed}, "For output %q", testCase.output)
+	}
+}
+
+func TestNewRecorderInvalidEndpoint(t *testing.T) {
+	t.Parallel()
+
+	for _, endpoint := range []string{"localhost:8125", "udp://localhost:8125", "statsd://", "::"} {
+		err := NewRecorder("/repo").AddEndpoint(endpoint)
+		assert.True(t, errors.IsError(err, InvalidEndpoint(endpoint)), "For endpoint %s: %v", endpoint, err)
+	}
+}
+
+func TestRecorderSendsStatsdMetrics(t *testing.T) {
+	t.Parallel()
+
+	server, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer server.Close()
+
+	recorder := NewRecorder("/repo")
+	require.NoError(t, recorder.AddEndpoint("statsd://"+server.LocalAddr().String()))
+	defer recorder.Close()
+
+	unit := recorder.StartUnit("/repo/live/prod/vpc/terragrunt.hcl", "apply")
+	unit.AddRetry()
+	unit.SetResourceChanges("Apply complete! Resources: 2 added, 0 changed, 1 destroyed.")
+	require.NoError(t, unit.Finish(0, nil))
+
+	buffer := make([]byte, 4096)
+	require.NoError(t, server.SetReadDeadline(time.Now().Add(5*time.Second)))
+	n, _, err := server.ReadFrom(buffer)
+	require.NoError(t, err)
+
+	lines := strings.Split(string(buffer[:n]), "\n")
+	require.Len(t, lines, 6)
+	assert.Equal(t, "terragrunt.unit.runs:1|c|#unit:live/prod/vpc,command:apply,status:success,exit_code:0", lines[0])
+	assert.Regexp(t, `^terragrunt\.unit\.duration:\d+\|ms\|#unit:live/prod/vpc,command:apply$`, lines[1])
+	assert.Equal(t, "terragrunt.unit.retries:1|c|#unit:live/prod/vpc,command:apply", lines[2])
+	assert.Equal(t, "terragrunt.unit.resources:2|c|#unit:live/prod/vpc,command:apply,action:add", lines[3])
+	assert.Equal(t, "terragrunt.unit.resources:0|c|#unit:live/prod/vpc,command:apply,action:change", lines[4])
+	assert.Equal(t, "terragrunt.unit.resources:1|c|#unit:live/prod/vpc,command:apply,action:destroy", lines[5])
+}
+
+func TestRecorderPushesPrometheusMetrics(t *testing.T) {
+	t.Parallel()
+
+	pushes := []*http.Request{}
+	bodies := []string{}
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		body, _ := ioutil.ReadAll(request.Body)
+		pushes = append(pushes, request)
+		bodies = append(bodies, string(body))
+	}))
+	defer server.Close()
+
+	recorder := NewRecorder("/repo")
+	require.NoError(t, recorder.AddEndpoint(server.URL))
+
+	vpc := recorder.StartUnit("/repo/vpc/terragrunt.hcl", "plan")
+	vpc.SetResourceChanges("Plan: 3 to add, 0 to change, 0 to destroy.")
+	require.NoError(t, vpc.Finish(0, nil))
+	app := recorder.StartUnit(`/repo/app"s/terragrunt.hcl`, "plan")
+	require.NoError(t, app.Finish(1, nil))
+
+	// Nothing is pushed until the command finishes
+	assert.Empty(t, pushes)
+	require.NoError(t, recorder.Close())
+
+	require.Len(t, pushes, 1)
+	assert.Equal(t, http.MethodPost, pushes[0].Method)
+	assert.Equal(t, "/metrics/job/terragrunt", pushes[0].URL.Path)
+
+	body := bodies[0]
+	assert.Contains(t, body, "# TYPE terragrunt_unit_duration_seconds gauge\n")
+	assert.Contains(t, body, "terragrunt_unit_exit_code{unit=\"app\\\"s\",command=\"plan\"} 1\n")
+	assert.Contains(t, body, "terragrunt_unit_exit_code{unit=\"vpc\",command=\"plan\"} 0\n")
+	assert.Contains(t, body, "terragrunt_unit_retries{unit=\"vpc\",command=\"plan\"} 0\n")
+	assert.Contains(t, body, "terragrunt_unit_resources{unit=\"vpc\",command=\"plan\",action=\"add\"} 3\n")
+	assert.NotContains(t, body, "terragrunt_unit_resources{unit=\"app")
+}
+
+func TestRecorderReportsPushErrors(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		http.Error(writer, "bad metrics", http.StatusBadRequest)
+	}))
+	defer server.Close()
+
+	recorder := NewRecorder("/repo")
+	require.NoError(t, recorder.AddEndpoint(server.URL+"/metrics/job/ci/branch/main"))
+	require.NoError(t, recorder.StartUnit("/repo/vpc/terragrunt.hcl", "apply").Finish(0, nil))
+
+	err := recorder.Close()
+	multiErr, isMultiErr := err.(*multierror.Error)
+	require.True(t, isMultiErr, "Unexpected error: %v", err)
+	require.Len(t, multiErr.Errors, 1)
+	pushErr, isPushErr := errors.Unwrap(multiErr.Errors[0]).(PushFailed)
+	require.True(t, isPushErr, "Unexpected error: %v", err)
+	assert.Equal(t, http.StatusBadRequest, pushErr.StatusCode)
+	assert.True(t, strings.HasSuffix(pushErr.Url, "/metrics/job/ci/branch/main"))
+}
+
+func TestNilRecorder(t *testing.T) {
+	t.Parallel()
+
+	var recorder *Recorder
+	unit := recorder.StartUnit("/repo/vpc/terragrunt.hcl", "apply")
+	assert.Nil(t, unit)
+	unit.AddRetry()
+	unit.SetResourceChanges("Plan: 1 to add, 0 to change, 0 to destroy.")
+	assert.NoError(t, unit.Finish(0, nil))
+	assert.NoError(t, recorder.Close())
+}
+
+func TestRecorderWritesStepSummary(t *testing.T) {
+	t.Parallel()
+
+	summaryFile, err := ioutil.TempFile("", "step-summary")
+	require.NoError(t, err)
+	defer os.Remove(summaryFile.Name())
+	_, err = summaryFile.WriteString("## Previous step\n\n")
+	require.NoError(t, err)
+	require.NoError(t, summaryFile.Close())
+
+	recorder := NewRecorder("/repo")
+	recorder.AddStepSummary(summaryFile.Name())
+
+	vpc := recorder.StartUnit("/repo/live/vpc/terragrunt.hcl", "plan")
+	vpc.SetResourceChanges("Plan: 3 to add, 1 to change, 0 to destroy.")
+	require.NoError(t, vpc.Finish(0, nil))
+	app := recorder.StartUnit("/repo/live/app|web/terragrunt.hcl", "plan")
+	app.AddRetry()
+	require.NoError(t, app.Finish(2, nil))
+	require.NoError(t, recorder.SkipUnit("/repo/live/dns/terragrunt.hcl", "plan", "Dependency /repo/live/app|web finished with an error"))
+	require.NoError(t, recorder.Close())
+
+	summary, err := ioutil.ReadFile(summaryFile.Name())
+	require.NoError(t, err)
+	lines := strings.Split(string(summary), "\n")
+	assert.Equal(t, "## Previous step", lines[0])
+	assert.Equal(t, "### Terragrunt", lines[2])
+	assert.Equal(t, "2 units ran, 1 failed, 1 skipped because a dependency failed.", lines[4])
+	assert.Equal(t, "| `live/app\\|web` | plan | :x: Failed (exit code 2) | - | - | - | 1 | 0s |", lines[8])
+	assert.Equal(t, "| `live/dns` | plan | :fast_forward: Skipped | - | - | - | - | - |", lines[9])
+	assert.Equal(t, "| `live/vpc` | plan | :white_check_mark: Succeeded | 3 | 1 | 0 | 0 | 0s |", lines[10])
+}
+
+func TestRecorderWritesGitLabReports(t *testing.T) {
+	t.Parallel()
+
+	reportDir, err := ioutil.TempDir("", "gitlab-reports")
+	require.NoError(t, err)
+	defer os.RemoveAll(reportDir)
+
+	recorder := NewRecorder("/repo")
+	recorder.AddGitLabReport(reportDir)
+
+	vpc := recorder.StartUnit("/repo/live/vpc/terragrunt.hcl", "plan")
+	vpc.SetResourceChanges("Plan: 3 to add, 1 to change, 2 to destroy.")
+	require.NoError(t, vpc.Finish(0, nil))
+	app := recorder.StartUnit("/repo/live/app/terragrunt.hcl", "plan")
+	app.SetResourceChanges("No changes. Your infrastructure matches the configuration.")
+	require.NoError(t, app.Finish(0, nil))
+	external := recorder.StartUnit("/modules/db/terragrunt.hcl", "plan")
+	external.SetResourceChanges("Plan: 1 to add, 0 to change, 0 to destroy.")
+	require.NoError(t, external.Finish(0, nil))
+	// Only plans are reported, and only if their output could be parsed
+	apply := recorder.StartUnit("/repo/live/dns/terragrunt.hcl", "apply")
+	apply.SetResourceChanges("Apply complete! Resources: 1 added, 0 changed, 0 destroyed.")
+	require.NoError(t, apply.Finish(0, nil))
+	require.NoError(t, recorder.StartUnit("/repo/live/iam/terragrunt.hcl", "plan").Finish(1, nil))
+	require.NoError(t, recorder.Close())
+
+	report, err := ioutil.ReadFile(filepath.Join(reportDir, "live", "vpc", GitLabReportFileName))
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"create": 3, "update": 1, "delete": 2}`, string(report))
+
+	report, err = ioutil.ReadFile(filepath.Join(reportDir, "live", "app", GitLabReportFileName))
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"create": 0, "update": 0, "delete": 0}`, string(report))
+
+	report, err = ioutil.ReadFile(filepath.Join(reportDir, "modules", "db", GitLabReportFileName))
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"create": 1, "update": 0, "delete": 0}`, string(report))
+
+	assert.NoFileExists(t, filepath.Join(reportDir, "live", "dns", GitLabReportFileName))
+	assert.NoFileExists(t, filepath.Join(reportDir, "live", "iam", GitLabReportFileName))
+}
+
+func TestRecorderWritesJUnitReport(t *testing.T) {
+	t.Parallel()
+
+	reportDir, err := ioutil.TempDir("", "junit-report")
+	require.NoError(t, err)
+	defer os.RemoveAll(reportDir)
+	reportPath := filepath.Join(reportDir, "reports", "terragrunt.xml")
+
+	recorder := NewRecorder("/repo")
+	recorder.AddJUnitReport(reportPath)
+
+	vpc := recorder.StartUnit("/repo/live/vpc/terragrunt.hcl", "apply")
+	require.NoError(t, vpc.Finish(0, nil))
+	app := recorder.StartUnit("/repo/live/app/terragrunt.hcl", "apply")
+	app.SetErrorOutput("\x1b[31m╷\x1b[0m\n\x1b[31m│\x1b[0m \x1b[1m\x1b[31mError: \x1b[0m\x1b[0m\x1b[1mInvalid <reference>\x1b[0m\n")
+	require.NoError(t, app.Finish(1, fmt.Errorf("exit status 1")))
+	require.NoError(t, recorder.SkipUnit("/repo/live/dns/terragrunt.hcl", "apply", "Dependency /repo/live/app finished with an error"))
+	require.NoError(t, recorder.Close())
+
+	contents, err := ioutil.ReadFile(reportPath)
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(string(contents), xml.Header), "Missing XML header: %s", contents)
+
+	var report junitTestSuites
+	require.NoError(t, xml.Unmarshal(contents, &report))
+	assert.Equal(t, 3, report.Tests)
+	assert.Equal(t, 1, report.Failures)
+	assert.Equal(t, 1, report.Skipped)
+	require.Len(t, report.Suites, 1)
+
+	testCases := report.Suites[0].TestCases
+	require.Len(t, testCases, 3)
+	assert.Equal(t, "live/app", testCases[0].Name)
+	assert.Equal(t, "apply", testCases[0].ClassName)
+	require.NotNil(t, testCases[0].Failure)
+	assert.Equal(t, "exit status 1", testCases[0].Failure.Message)
+	assert.Equal(t, "exit code 1", testCases[0].Failure.Type)
+	assert.Equal(t, "╷\n│ Error: Invalid <reference>\n", testCases[0].Failure.Contents)
+	assert.Nil(t, testCases[0].Skipped)
+
+	assert.Equal(t, "live/dns", testCases[1].Name)
+	require.NotNil(t, testCases[1].Skipped)
+	assert.Equal(t, "Dependency /repo/live/app finished with an error", testCases[1].Skipped.Message)
+	assert.Nil(t, testCases[1].Failure)
+
+	assert.Equal(t, "live/vpc", testCases[2].Name)
+	assert.Nil(t, testCases[2].Failure)
+	assert.Nil(t, testCases[2].Skipped)
+}
+
+func TestScanResourceChanges(t *testing.T) {
+	t.Parallel()
+
+	recorder := NewRecorder("/repo")
+	defer recorder.Close()
+
+	unit := recorder.StartUnit("/repo/vpc/terragrunt.hcl", "plan")
+	// The lines too long for the buffer, e.g. of a large JSON policy, are skipped without losing the summary after them
+	output := "Terraform will perform the following actions:\n" + strings.Repeat("x", 10000) + "\nPlan: 3 to add, 1 to change, 2 to destroy.\n"
+	unit.ScanResourceChanges(strings.NewReader(output))
+
+	assert.True(t, unit.run.HasResourceChanges)
+	assert.Equal(t, 3, unit.run.ResourcesAdded)
+	assert.Equal(t, 1, unit.run.ResourcesChanged)
+	assert.Equal(t, 2, unit.run.ResourcesDestroyed)
+}
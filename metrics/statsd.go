@@ -0,0 +1,88 @@
+package metrics
+
+import (
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/gruntwork-io/terragrunt/errors"
+)
+
+// The prefix of the names of the StatsD metrics
+const statsdPrefix = "terragrunt.unit."
+
+// Sends the metrics of each unit to a StatsD server over UDP as soon as the unit finishes. The metrics are tagged with
+// the unit and the command using the DogStatsD tag extension, which is understood by most StatsD servers, including
+// the Datadog agent, Telegraf and the Prometheus statsd_exporter.
+type statsdSink struct {
+	conn net.Conn
+}
+
+func newStatsdSink(address string) (*statsdSink, error) {
+	conn, err := net.Dial("udp", address)
+	if err != nil {
+		return nil, errors.WithStackTrace(err)
+	}
+	return &statsdSink{conn: conn}, nil
+}
+
+func (sink *statsdSink) record(run UnitRun) error {
+	// Only the units that ran have metrics
+	if run.Skipped {
+		return nil
+	}
+	_, err := sink.conn.Write([]byte(strings.Join(statsdLines(run), "\n")))
+	return errors.WithStackTrace(err)
+}
+
+func (sink *statsdSink) close() error {
+	return errors.WithStackTrace(sink.conn.Close())
+}
+
+// Return the StatsD lines of the metrics of the given run, e.g.:
+//
+// terragrunt.unit.duration:1234|ms|#unit:vpc,command:apply
+func statsdLines(run UnitRun) []string {
+	status := "success"
+	if run.ExitCode != 0 {
+		status = "failure"
+	}
+	tags := statsdTags("unit", run.Unit, "command", run.Command)
+
+	lines := []string{
+		fmt.Sprintf("%sruns:1|c|%s", statsdPrefix, statsdTags("unit", run.Unit, "command", run.Command, "status", status, "exit_code", fmt.Sprint(run.ExitCode))),
+		fmt.Sprintf("%sduration:%d|ms|%s", statsdPrefix, run.Duration.Milliseconds(), tags),
+		fmt.Sprintf("%sretries:%d|c|%s", statsdPrefix, run.Retries, tags),
+	}
+	if run.HasResourceChanges {
+		for _, action := range resourceActions(run) {
+			lines = append(lines, fmt.Sprintf("%sresources:%d|c|%s", statsdPrefix, action.count, statsdTags("unit", run.Unit, "command", run.Command, "action", action.name)))
+		}
+	}
+	return lines
+}
+
+// Render the given tags, as alternating keys and values, in the DogStatsD format, e.g. #unit:vpc,command:apply. The
+// characters that separate the parts of a line are replaced in the values.
+func statsdTags(keysAndValues ...string) string {
+	replacer := strings.NewReplacer(",", "_", "|", "_", "#", "_", "\n", "_")
+	tags := []string{}
+	for i := 0; i+1 < len(keysAndValues); i += 2 {
+		tags = append(tags, fmt.Sprintf("%s:%s", keysAndValues[i], replacer.Replace(keysAndValues[i+1])))
+	}
+	return "#" + strings.Join(tags, ",")
+}
+
+// A count of resources that a run changed in the given way
+type resourceAction struct {
+	name  string
+	count int
+}
+
+func resourceActions(run UnitRun) []resourceAction {
+	return []resourceAction{
+		{"add", run.ResourcesAdded},
+		{"change", run.ResourcesChanged},
+		{"destroy", run.ResourcesDestroyed},
+	}
+}
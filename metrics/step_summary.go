@@ -0,0 +1,109 @@
+package metrics
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gruntwork-io/terragrunt/errors"
+)
+
+// Collects the runs of the units, and appends them as a Markdown table to the step summary of a GitHub Actions job
+// once the Terragrunt command finishes. See
+// https://docs.github.com/en/actions/using-workflows/workflow-commands-for-github-actions#adding-a-job-summary
+type stepSummarySink struct {
+	path string
+
+	mutex sync.Mutex
+	runs  []UnitRun
+}
+
+func newStepSummarySink(path string) *stepSummarySink {
+	return &stepSummarySink{path: path}
+}
+
+func (sink *stepSummarySink) record(run UnitRun) error {
+	sink.mutex.Lock()
+	defer sink.mutex.Unlock()
+	sink.runs = append(sink.runs, run)
+	return nil
+}
+
+func (sink *stepSummarySink) close() error {
+	sink.mutex.Lock()
+	runs := append([]UnitRun{}, sink.runs...)
+	sink.mutex.Unlock()
+
+	if len(runs) == 0 {
+		return nil
+	}
+
+	// Other steps of the job may have written to the summary too, so append to it
+	summaryFile, err := os.OpenFile(sink.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return errors.WithStackTrace(err)
+	}
+	if _, err := summaryFile.WriteString(stepSummaryMarkdown(runs)); err != nil {
+		summaryFile.Close()
+		return errors.WithStackTrace(err)
+	}
+	return errors.WithStackTrace(summaryFile.Close())
+}
+
+// Render the given runs as a Markdown table, sorted by unit, preceded by the number of units that failed and that were
+// skipped
+func stepSummaryMarkdown(runs []UnitRun) string {
+	sort.SliceStable(runs, func(i, j int) bool { return runs[i].Unit < runs[j].Unit })
+
+	failed, skipped := 0, 0
+	for _, run := range runs {
+		if run.Skipped {
+			skipped++
+		} else if run.ExitCode != 0 {
+			failed++
+		}
+	}
+
+	outcome := fmt.Sprintf("%d units ran, %d failed.", len(runs)-skipped, failed)
+	if skipped > 0 {
+		outcome = fmt.Sprintf("%d units ran, %d failed, %d skipped because a dependency failed.", len(runs)-skipped, failed, skipped)
+	}
+
+	lines := []string{
+		"### Terragrunt",
+		"",
+		outcome,
+		"",
+		"| Unit | Command | Result | Add | Change | Destroy | Retries | Duration |",
+		"| --- | --- | --- | ---: | ---: | ---: | ---: | ---: |",
+	}
+	for _, run := range runs {
+		if run.Skipped {
+			lines = append(lines, fmt.Sprintf("| `%s` | %s | :fast_forward: Skipped | - | - | - | - | - |", markdownCell(run.Unit), markdownCell(run.Command)))
+			continue
+		}
+
+		result := ":white_check_mark: Succeeded"
+		if run.ExitCode != 0 {
+			result = fmt.Sprintf(":x: Failed (exit code %d)", run.ExitCode)
+		}
+		add, change, destroy := "-", "-", "-"
+		if run.HasResourceChanges {
+			add, change, destroy = fmt.Sprint(run.ResourcesAdded), fmt.Sprint(run.ResourcesChanged), fmt.Sprint(run.ResourcesDestroyed)
+		}
+		lines = append(lines, fmt.Sprintf(
+			"| `%s` | %s | %s | %s | %s | %s | %d | %s |",
+			markdownCell(run.Unit), markdownCell(run.Command), result, add, change, destroy, run.Retries, run.Duration.Round(time.Second),
+		))
+	}
+
+	return strings.Join(lines, "\n") + "\n\n"
+}
+
+// Escape the characters that would break the table
+func markdownCell(value string) string {
+	return strings.NewReplacer("|", `\|`, "\n", " ", "`", "'").Replace(value)
+}
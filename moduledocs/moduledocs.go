@@ -0,0 +1,165 @@
+// Package moduledocs renders the documentation of the units of a stack: the Terraform module each unit deploys, as
+// documented by terraform-docs, and the inputs Terragrunt passes to it. See https://terraform-docs.io/
+package moduledocs
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/gruntwork-io/terragrunt/errors"
+)
+
+// Unit is the documentation of a unit
+type Unit struct {
+	// The path of the unit, relative to the root of the repo
+	Path string
+	// The source of the Terraform module of the unit, if it's not the folder of the unit itself
+	Source string
+	// The inputs Terragrunt passes to the module, as resolved for the unit
+	Inputs map[string]interface{}
+	// The documentation of the module, as printed by terraform-docs markdown
+	ModuleDocs string
+}
+
+// Markdown renders the documentation of the unit, with the title at the given heading level, and the sections of the
+// documentation one level below it
+func (unit Unit) Markdown(headingLevel int) string {
+	heading := strings.Repeat("#", headingLevel)
+
+	lines := []string{fmt.Sprintf("%s %s", heading, unit.Path), ""}
+	if unit.Source != "" {
+		lines = append(lines, fmt.Sprintf("Source: `%s`", unit.Source), "")
+	}
+
+	if len(unit.Inputs) > 0 {
+		lines = append(lines, fmt.Sprintf("%s# Terragrunt inputs", heading), "", "| Name | Value |", "|------|-------|")
+		names := []string{}
+		for name := range unit.Inputs {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			lines = append(lines, fmt.Sprintf("| %s | %s |", name, inputValue(unit.Inputs[name])))
+		}
+		lines = append(lines, "")
+	}
+
+	moduleDocs := strings.TrimSpace(demoteHeadings(unit.ModuleDocs, headingLevel-1))
+	if moduleDocs != "" {
+		lines = append(lines, moduleDocs, "")
+	}
+	return strings.Join(lines, "\n")
+}
+
+// Render the given input value as inline code in a Markdown table cell
+func inputValue(value interface{}) string {
+	rendered, err := json.Marshal(value)
+	if err != nil {
+		rendered = []byte(fmt.Sprintf("%v", value))
+	}
+	return fmt.Sprintf("`%s`", strings.ReplaceAll(string(rendered), "|", `\|`))
+}
+
+// Move the headings of the given Markdown down by the given number of levels, leaving the lines of code blocks alone
+func demoteHeadings(markdown string, levels int) string {
+	if levels <= 0 {
+		return markdown
+	}
+
+	lines := strings.Split(markdown, "\n")
+	inCodeBlock := false
+	for i, line := range lines {
+		if strings.HasPrefix(line, "```") {
+			inCodeBlock = !inCodeBlock
+		}
+		if !inCodeBlock && strings.HasPrefix(line, "#") {
+			lines[i] = strings.Repeat("#", levels) + line
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// Inventory collects the documentation of the units of a run into a single file. All the methods of Inventory can be
+// called on a nil inventory, which is what is used when each unit gets its own file instead.
+type Inventory struct {
+	mutex sync.Mutex
+	units []Unit
+}
+
+// Create an inventory without any units
+func NewInventory() *Inventory {
+	return &Inventory{}
+}
+
+// Add the documentation of a unit to the inventory
+func (inventory *Inventory) Add(unit Unit) {
+	if inventory == nil {
+		return
+	}
+
+	inventory.mutex.Lock()
+	defer inventory.mutex.Unlock()
+	inventory.units = append(inventory.units, unit)
+}
+
+// Markdown renders the inventory: a list of the units, sorted by path, followed by the documentation of each
+func (inventory *Inventory) Markdown() string {
+	if inventory == nil {
+		return ""
+	}
+
+	inventory.mutex.Lock()
+	units := append([]Unit{}, inventory.units...)
+	inventory.mutex.Unlock()
+	sort.SliceStable(units, func(i, j int) bool { return units[i].Path < units[j].Path })
+
+	sections := []string{"# Units\n"}
+	unitList := []string{}
+	for _, unit := range units {
+		unitList = append(unitList, fmt.Sprintf("- [%s](#%s)", unit.Path, headingAnchor(unit.Path)))
+	}
+	sections = append(sections, strings.Join(unitList, "\n")+"\n")
+	for _, unit := range units {
+		sections = append(sections, unit.Markdown(2))
+	}
+	return strings.Join(sections, "\n")
+}
+
+// Write the inventory to the given file. Nothing is written if there are no units.
+func (inventory *Inventory) Write(path string) error {
+	if inventory == nil {
+		return nil
+	}
+	inventory.mutex.Lock()
+	isEmpty := len(inventory.units) == 0
+	inventory.mutex.Unlock()
+	if isEmpty {
+		return nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+		return errors.WithStackTrace(err)
+	}
+	return errors.WithStackTrace(ioutil.WriteFile(path, []byte(inventory.Markdown()), 0644))
+}
+
+// Return the anchor GitHub and GitLab give the heading with the given text: lower case, without punctuation other
+// than hyphens and underscores, and with spaces replaced by hyphens
+func headingAnchor(heading string) string {
+	anchor := strings.Builder{}
+	for _, char := range strings.ToLower(heading) {
+		switch {
+		case char == ' ':
+			anchor.WriteRune('-')
+		case char == '-' || char == '_' || (char >= 'a' && char <= 'z') || (char >= '0' && char <= '9'):
+			anchor.WriteRune(char)
+		}
+	}
+	return anchor.String()
+}
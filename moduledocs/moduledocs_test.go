@@ -0,0 +1,74 @@
+package moduledocs
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// The output of terraform-docs markdown table for a module with a single variable
+const moduleDocs = "## Requirements\n\nNo requirements.\n\n## Inputs\n\n| Name | Description | Type | Default | Required |\n|------|-------------|------|---------|:--------:|\n| <a name=\"input_name\"></a> [name](#input\\_name) | The name | `string` | n/a | yes |\n"
+
+func TestUnitMarkdown(t *testing.T) {
+	t.Parallel()
+
+	unit := Unit{
+		Path:       "live/app",
+		Source:     "git::https://example.com/modules.git//app?ref=v1.0.0",
+		Inputs:     map[string]interface{}{"name": "app", "tags": map[string]interface{}{"env": "prod|dev"}},
+		ModuleDocs: moduleDocs,
+	}
+
+	expected := "# live/app\n\n" +
+		"Source: `git::https://example.com/modules.git//app?ref=v1.0.0`\n\n" +
+		"## Terragrunt inputs\n\n" +
+		"| Name | Value |\n|------|-------|\n" +
+		"| name | `\"app\"` |\n" +
+		"| tags | `{\"env\":\"prod\\|dev\"}` |\n\n" +
+		moduleDocs
+	assert.Equal(t, expected, unit.Markdown(1))
+
+	demoted := unit.Markdown(2)
+	assert.Contains(t, demoted, "## live/app\n")
+	assert.Contains(t, demoted, "### Terragrunt inputs\n")
+	assert.Contains(t, demoted, "### Requirements\n")
+}
+
+func TestDemoteHeadingsSkipsCodeBlocks(t *testing.T) {
+	t.Parallel()
+
+	markdown := "## Usage\n\n```sh\n# not a heading\n```\n"
+	assert.Equal(t, "### Usage\n\n```sh\n# not a heading\n```\n", demoteHeadings(markdown, 1))
+}
+
+func TestInventoryWrite(t *testing.T) {
+	t.Parallel()
+
+	inventoryDir, err := ioutil.TempDir("", "docs-inventory")
+	require.NoError(t, err)
+	defer os.RemoveAll(inventoryDir)
+	inventoryPath := filepath.Join(inventoryDir, "docs", "UNITS.md")
+
+	inventory := NewInventory()
+	require.NoError(t, inventory.Write(inventoryPath))
+	assert.NoFileExists(t, inventoryPath)
+
+	inventory.Add(Unit{Path: "live/vpc"})
+	inventory.Add(Unit{Path: "live/app_v2", ModuleDocs: "## Inputs\n"})
+	require.NoError(t, inventory.Write(inventoryPath))
+
+	contents, err := ioutil.ReadFile(inventoryPath)
+	require.NoError(t, err)
+	assert.Equal(t, "# Units\n\n"+
+		"- [live/app_v2](#liveapp_v2)\n- [live/vpc](#livevpc)\n\n"+
+		"## live/app_v2\n\n### Inputs\n\n"+
+		"## live/vpc\n", string(contents))
+
+	var nilInventory *Inventory
+	nilInventory.Add(Unit{Path: "live/db"})
+	assert.NoError(t, nilInventory.Write(inventoryPath))
+}
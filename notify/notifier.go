@@ -0,0 +1,206 @@
+// Package notify posts the lifecycle of a Terragrunt run, i.e., when it starts, when a unit fails and when it completes,
+// to webhooks such as the incoming webhooks of Slack and Microsoft Teams, so that nobody has to wrap long-running
+// applies in scripts to find out how they went.
+package notify
+
+import (
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/go-multierror"
+)
+
+// Event is a point in the lifecycle of a run that the webhooks can be notified of
+type Event string
+
+const (
+	EventRunStart    Event = "run_start"
+	EventUnitFailure Event = "unit_failure"
+	EventRunComplete Event = "run_complete"
+)
+
+// All the events, which is what the webhooks that don't filter the events are notified of
+var AllEvents = []Event{EventRunStart, EventUnitFailure, EventRunComplete}
+
+// How long to wait for a webhook to accept a notification
+const postTimeout = 10 * time.Second
+
+// Message is what the template of a webhook is rendered with. The fields that don't apply to the event are left empty.
+type Message struct {
+	Event Event
+	// The terraform command of the run, e.g. plan or apply
+	Command string
+	// The number of units in the run
+	Units int
+
+	// The path of the unit that failed, relative to the root dir of the notifier, and the error it failed with. Only
+	// set for unit_failure.
+	Unit  string
+	Error string
+
+	// The outcome of the units, and how long the run took. Only set for run_complete. The skipped units are those that
+	// didn't run, e.g. because one of their dependencies failed.
+	Succeeded int
+	Failed    int
+	Skipped   int
+	Duration  time.Duration
+}
+
+// Notifier posts the events of a run to webhooks. All the methods of Notifier can be called on a nil notifier, which
+// is what is used when no notifications are configured, so that the code running the units doesn't need to check
+// whether they are.
+type Notifier struct {
+	webhooks []*Webhook
+	rootDir  string
+	client   *http.Client
+
+	mutex     sync.Mutex
+	command   string
+	units     int
+	start     time.Time
+	succeeded int
+	failed    int
+}
+
+// Create a notifier that posts to the given webhooks, with the units identified by their path relative to the given
+// root dir
+func NewNotifier(webhooks []*Webhook, rootDir string) *Notifier {
+	return &Notifier{webhooks: webhooks, rootDir: rootDir, client: &http.Client{Timeout: postTimeout}}
+}
+
+// Notify that the given command started running in the given number of units
+func (notifier *Notifier) RunStarted(command string, units int) error {
+	if notifier == nil {
+		return nil
+	}
+
+	notifier.mutex.Lock()
+	notifier.command = command
+	notifier.units = units
+	notifier.start = time.Now()
+	notifier.mutex.Unlock()
+
+	return notifier.post(Message{Event: EventRunStart, Command: command, Units: units})
+}
+
+// Record that the unit with the given Terragrunt config finished running, and notify that it failed if runErr is set
+func (notifier *Notifier) UnitFinished(terragruntConfigPath string, runErr error) error {
+	if notifier == nil {
+		return nil
+	}
+
+	notifier.mutex.Lock()
+	if runErr == nil {
+		notifier.succeeded++
+	} else {
+		notifier.failed++
+	}
+	command, units := notifier.command, notifier.units
+	notifier.mutex.Unlock()
+
+	if runErr == nil {
+		return nil
+	}
+	return notifier.post(Message{
+		Event:   EventUnitFailure,
+		Command: command,
+		Units:   units,
+		Unit:    notifier.unitPath(terragruntConfigPath),
+		Error:   runErr.Error(),
+	})
+}
+
+// Notify that the run completed, with a summary of how the units went
+func (notifier *Notifier) RunFinished() error {
+	if notifier == nil {
+		return nil
+	}
+
+	notifier.mutex.Lock()
+	message := Message{
+		Event:     EventRunComplete,
+		Command:   notifier.command,
+		Units:     notifier.units,
+		Succeeded: notifier.succeeded,
+		Failed:    notifier.failed,
+		Skipped:   notifier.units - notifier.succeeded - notifier.failed,
+		Duration:  time.Since(notifier.start),
+	}
+	notifier.mutex.Unlock()
+
+	if message.Skipped < 0 {
+		message.Skipped = 0
+	}
+	return notifier.post(message)
+}
+
+// Post the given message to all the webhooks
+func (notifier *Notifier) post(message Message) error {
+	var result *multierror.Error
+	for _, webhook := range notifier.webhooks {
+		result = multierror.Append(result, webhook.post(notifier.client, message))
+	}
+	return result.ErrorOrNil()
+}
+
+// Return the path of the unit with the given Terragrunt config relative to the root dir, with forward slashes
+func (notifier *Notifier) unitPath(terragruntConfigPath string) string {
+	unitPath := filepath.Dir(terragruntConfigPath)
+	if relPath, err := filepath.Rel(notifier.rootDir, unitPath); err == nil {
+		unitPath = relPath
+	}
+	return filepath.ToSlash(unitPath)
+}
+
+// The message of the event when the webhook has no template
+func (message Message) defaultText() string {
+	switch message.Event {
+	case EventRunStart:
+		return fmt.Sprintf("terragrunt %s started in %s", message.Command, pluralize(message.Units, "unit"))
+	case EventUnitFailure:
+		return fmt.Sprintf("terragrunt %s failed in %s: %s", message.Command, message.Unit, firstLine(message.Error))
+	case EventRunComplete:
+		status := "completed"
+		if message.Failed > 0 {
+			status = "failed"
+		}
+		return fmt.Sprintf(
+			"terragrunt %s %s in %s: %d succeeded, %d failed, %d skipped",
+			message.Command,
+			status,
+			message.Duration.Round(time.Second),
+			message.Succeeded,
+			message.Failed,
+			message.Skipped,
+		)
+	}
+	return string(message.Event)
+}
+
+func isEvent(event Event) bool {
+	for _, knownEvent := range AllEvents {
+		if event == knownEvent {
+			return true
+		}
+	}
+	return false
+}
+
+func pluralize(count int, noun string) string {
+	if count == 1 {
+		return fmt.Sprintf("%d %s", count, noun)
+	}
+	return fmt.Sprintf("%d %ss", count, noun)
+}
+
+func firstLine(text string) string {
+	text = strings.TrimSpace(text)
+	if index := strings.Index(text, "\n"); index >= 0 {
+		return text[:index]
+	}
+	return text
+}
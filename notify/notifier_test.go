@@ -0,0 +1,166 @@
+package notify
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/gruntwork-io/terragrunt/errors"
+)
+
+// A webhook server that records the bodies posted to it
+type webhookServer struct {
+	*httptest.Server
+
+	mutex  sync.Mutex
+	bodies []map[string]interface{}
+}
+
+func newWebhookServer(t *testing.T) *webhookServer {
+	server := &webhookServer{}
+	server.Server = httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		contents, err := ioutil.ReadAll(request.Body)
+		require.NoError(t, err)
+		body := map[string]interface{}{}
+		require.NoError(t, json.Unmarshal(contents, &body))
+
+		server.mutex.Lock()
+		defer server.mutex.Unlock()
+		server.bodies = append(server.bodies, body)
+	}))
+	return server
+}
+
+func TestNotifierPostsRunLifecycle(t *testing.T) {
+	t.Parallel()
+
+	server := newWebhookServer(t)
+	defer server.Close()
+
+	webhook, err := NewWebhook("ops", server.URL, nil, "")
+	require.NoError(t, err)
+	notifier := NewNotifier([]*Webhook{webhook}, "/repo")
+
+	require.NoError(t, notifier.RunStarted("apply", 3))
+	require.NoError(t, notifier.UnitFinished("/repo/live/vpc/terragrunt.hcl", nil))
+	require.NoError(t, notifier.UnitFinished("/repo/live/app/terragrunt.hcl", fmt.Errorf("exit status 1\ndetails")))
+	require.NoError(t, notifier.RunFinished())
+
+	require.Len(t, server.bodies, 3)
+
+	assert.Equal(t, "run_start", server.bodies[0]["event"])
+	assert.Equal(t, "terragrunt apply started in 3 units", server.bodies[0]["text"])
+
+	assert.Equal(t, "unit_failure", server.bodies[1]["event"])
+	assert.Equal(t, "live/app", server.bodies[1]["unit"])
+	assert.Equal(t, "terragrunt apply failed in live/app: exit status 1", server.bodies[1]["text"])
+
+	assert.Equal(t, "run_complete", server.bodies[2]["event"])
+	assert.Equal(t, float64(1), server.bodies[2]["succeeded"])
+	assert.Equal(t, float64(1), server.bodies[2]["failed"])
+	assert.Equal(t, float64(1), server.bodies[2]["skipped"])
+	assert.Regexp(t, `^terragrunt apply failed in \d+s: 1 succeeded, 1 failed, 1 skipped$`, server.bodies[2]["text"])
+}
+
+func TestWebhookFiltersEventsAndRendersTemplate(t *testing.T) {
+	t.Parallel()
+
+	server := newWebhookServer(t)
+	defer server.Close()
+
+	webhook, err := NewWebhook("ops", server.URL, []string{"run_complete"}, "{{ .Command }} done, {{ .Failed }} of {{ .Units }} failed")
+	require.NoError(t, err)
+	notifier := NewNotifier([]*Webhook{webhook}, "/repo")
+
+	require.NoError(t, notifier.RunStarted("plan", 2))
+	require.NoError(t, notifier.UnitFinished("/repo/a/terragrunt.hcl", fmt.Errorf("boom")))
+	require.NoError(t, notifier.UnitFinished("/repo/b/terragrunt.hcl", nil))
+	require.NoError(t, notifier.RunFinished())
+
+	require.Len(t, server.bodies, 1)
+	assert.Equal(t, "plan done, 1 of 2 failed", server.bodies[0]["text"])
+}
+
+func TestChatWebhooksArePostedTheMessage(t *testing.T) {
+	t.Parallel()
+
+	server := newWebhookServer(t)
+	defer server.Close()
+
+	for _, webhookUrl := range []string{"https://hooks.slack.com/services/T0/B0/secret", "https://acme.webhook.office.com/webhookb2/secret"} {
+		webhook, err := NewWebhook("chat", webhookUrl, nil, "")
+		require.NoError(t, err)
+		assert.Equal(t, chatFormat, webhook.format)
+
+		webhook.Url = server.URL
+		require.NoError(t, NewNotifier([]*Webhook{webhook}, "/repo").RunStarted("apply", 1))
+	}
+
+	expected := map[string]interface{}{"text": "terragrunt apply started in 1 unit"}
+	assert.Equal(t, []map[string]interface{}{expected, expected}, server.bodies)
+}
+
+func TestWebhookErrorsLeaveOutTheUrl(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		writer.WriteHeader(http.StatusNotFound)
+	}))
+	defer server.Close()
+
+	webhook, err := NewWebhook("ops", server.URL+"/secret", nil, "")
+	require.NoError(t, err)
+	err = NewNotifier([]*Webhook{webhook}, "/repo").RunStarted("apply", 1)
+	require.Error(t, err)
+	assert.NotContains(t, err.Error(), "secret")
+
+	server.Close()
+	err = NewNotifier([]*Webhook{webhook}, "/repo").RunStarted("apply", 1)
+	require.Error(t, err)
+	assert.NotContains(t, err.Error(), "secret")
+}
+
+func TestNewWebhookInvalid(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name     string
+		url      string
+		events   []string
+		template string
+	}{
+		{"not a URL", "hooks.slack.com/services/secret", nil, ""},
+		{"unknown event", "https://example.com/hook", []string{"unit_success"}, ""},
+		{"invalid template", "https://example.com/hook", nil, "{{ .Command "},
+	}
+
+	for _, testCase := range testCases {
+		// Save the testCase in local scope so all the t.Run calls don't end up with the last item in the list
+		testCase := testCase
+
+		t.Run(testCase.name, func(t *testing.T) {
+			t.Parallel()
+
+			_, err := NewWebhook("ops", testCase.url, testCase.events, testCase.template)
+			require.Error(t, err)
+			_, isInvalidWebhook := errors.Unwrap(err).(InvalidWebhook)
+			assert.True(t, isInvalidWebhook, "Unexpected error: %v", err)
+		})
+	}
+}
+
+func TestNilNotifier(t *testing.T) {
+	t.Parallel()
+
+	var notifier *Notifier
+	assert.NoError(t, notifier.RunStarted("apply", 1))
+	assert.NoError(t, notifier.UnitFinished("/repo/terragrunt.hcl", fmt.Errorf("boom")))
+	assert.NoError(t, notifier.RunFinished())
+}
@@ -0,0 +1,177 @@
+package notify
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"text/template"
+
+	"github.com/gruntwork-io/terragrunt/errors"
+)
+
+// The formats of the bodies posted to the webhooks
+type webhookFormat int
+
+const (
+	// The JSON object of the event, see webhookPayload
+	jsonFormat webhookFormat = iota
+	// A JSON object with the message in its text property, which is what the incoming webhooks of Slack and Microsoft
+	// Teams accept
+	chatFormat
+)
+
+// The hosts of the incoming webhooks of Slack and Microsoft Teams, which are sent the message rather than the event
+var chatWebhookHostSuffixes = []string{"hooks.slack.com", "webhook.office.com", "outlook.office.com"}
+
+// Webhook is a URL the events of a run are posted to
+type Webhook struct {
+	Name string
+	Url  string
+
+	events   map[Event]bool
+	template *template.Template
+	format   webhookFormat
+}
+
+// The body posted to the webhooks that are neither Slack nor Microsoft Teams
+type webhookPayload struct {
+	Event           Event   `json:"event"`
+	Command         string  `json:"command"`
+	Text            string  `json:"text"`
+	Unit            string  `json:"unit,omitempty"`
+	Error           string  `json:"error,omitempty"`
+	Units           int     `json:"units"`
+	Succeeded       int     `json:"succeeded"`
+	Failed          int     `json:"failed"`
+	Skipped         int     `json:"skipped"`
+	DurationSeconds float64 `json:"duration_seconds"`
+}
+
+// Create a webhook with the given name and URL, which is posted the given events, or all of them if none are given.
+// If tmpl is set, it's the Go template the message of the events is rendered with, which is given the Message of the
+// event. The webhooks of Slack and Microsoft Teams are sent the message, and the other webhooks a JSON object of the
+// event along with the message.
+func NewWebhook(name string, webhookUrl string, events []string, tmpl string) (*Webhook, error) {
+	parsedUrl, err := url.Parse(webhookUrl)
+	if err != nil || (parsedUrl.Scheme != "http" && parsedUrl.Scheme != "https") || parsedUrl.Host == "" {
+		return nil, errors.WithStackTrace(InvalidWebhook{Name: name, Reason: "url must be an http:// or https:// URL"})
+	}
+
+	webhook := &Webhook{Name: name, Url: webhookUrl, events: map[Event]bool{}, format: jsonFormat}
+	for _, suffix := range chatWebhookHostSuffixes {
+		if strings.HasSuffix(parsedUrl.Hostname(), suffix) {
+			webhook.format = chatFormat
+		}
+	}
+
+	if len(events) == 0 {
+		for _, event := range AllEvents {
+			webhook.events[event] = true
+		}
+	}
+	for _, event := range events {
+		if !isEvent(Event(event)) {
+			return nil, errors.WithStackTrace(InvalidWebhook{Name: name, Reason: fmt.Sprintf("unknown event '%s', expected one of %v", event, AllEvents)})
+		}
+		webhook.events[Event(event)] = true
+	}
+
+	if tmpl != "" {
+		webhook.template, err = template.New(name).Option("missingkey=error").Parse(tmpl)
+		if err != nil {
+			return nil, errors.WithStackTrace(InvalidWebhook{Name: name, Reason: fmt.Sprintf("invalid template: %v", err)})
+		}
+	}
+
+	return webhook, nil
+}
+
+// Post the given message to the webhook, if it's interested in its event
+func (webhook *Webhook) post(client *http.Client, message Message) error {
+	if !webhook.events[message.Event] {
+		return nil
+	}
+
+	text := message.defaultText()
+	if webhook.template != nil {
+		var rendered bytes.Buffer
+		if err := webhook.template.Execute(&rendered, message); err != nil {
+			return errors.WithStackTrace(err)
+		}
+		text = rendered.String()
+	}
+
+	var payload interface{}
+	switch webhook.format {
+	case chatFormat:
+		payload = map[string]string{"text": text}
+	default:
+		payload = webhookPayload{
+			Event:           message.Event,
+			Command:         message.Command,
+			Text:            text,
+			Unit:            message.Unit,
+			Error:           message.Error,
+			Units:           message.Units,
+			Succeeded:       message.Succeeded,
+			Failed:          message.Failed,
+			Skipped:         message.Skipped,
+			DurationSeconds: message.Duration.Seconds(),
+		}
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return errors.WithStackTrace(err)
+	}
+
+	response, err := client.Post(webhook.Url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		// The URLs of webhooks are usually secrets, so they're left out of the errors that are logged
+		if urlErr, isUrlErr := err.(*url.Error); isUrlErr {
+			err = urlErr.Err
+		}
+		return errors.WithStackTrace(WebhookUnreachable{Name: webhook.Name, Err: err})
+	}
+	defer response.Body.Close()
+	responseBody, _ := ioutil.ReadAll(io.LimitReader(response.Body, 4096))
+
+	if response.StatusCode < 200 || response.StatusCode >= 300 {
+		return errors.WithStackTrace(PostFailed{Name: webhook.Name, StatusCode: response.StatusCode, Body: string(responseBody)})
+	}
+	return nil
+}
+
+// Custom error types
+
+type InvalidWebhook struct {
+	Name   string
+	Reason string
+}
+
+func (err InvalidWebhook) Error() string {
+	return fmt.Sprintf("Invalid notification '%s': %s", err.Name, err.Reason)
+}
+
+type PostFailed struct {
+	Name       string
+	StatusCode int
+	Body       string
+}
+
+func (err PostFailed) Error() string {
+	return fmt.Sprintf("Notification '%s' responded with status %d: %s", err.Name, err.StatusCode, err.Body)
+}
+
+type WebhookUnreachable struct {
+	Name string
+	Err  error
+}
+
+func (err WebhookUnreachable) Error() string {
+	return fmt.Sprintf("Could not post notification '%s': %v", err.Name, err.Err)
+}
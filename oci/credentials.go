@@ -0,0 +1,145 @@
+package oci
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/gruntwork-io/terragrunt/errors"
+)
+
+// The key of the credentials of Docker Hub in the docker config, which predates the registry-1.docker.io host
+const dockerHubConfigKey = "https://index.docker.io/v1/"
+
+// Credentials are the username and password, or token, a registry is logged in to with
+type Credentials struct {
+	Username string
+	Secret   string
+}
+
+// The parts of the docker config, i.e. ~/.docker/config.json, that hold the credentials of the registries
+type dockerConfig struct {
+	Auths map[string]struct {
+		Auth string `json:"auth"`
+	} `json:"auths"`
+	CredHelpers map[string]string `json:"credHelpers"`
+	CredsStore  string            `json:"credsStore"`
+}
+
+// Return the credentials of the given registry host, like docker does: from the credential helper of the host, e.g.
+// ecr-login for ECR or gcloud for GCR and Artifact Registry, or else the credentials store, or else the credentials
+// docker login stored in the docker config in the given dir. Returns nil when there are none, so that the registry is
+// accessed anonymously.
+func findCredentials(configDir string, host string) (*Credentials, error) {
+	contents, err := ioutil.ReadFile(filepath.Join(configDir, "config.json"))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errors.WithStackTrace(err)
+	}
+	var config dockerConfig
+	if err := json.Unmarshal(contents, &config); err != nil {
+		return nil, errors.WithStackTrace(InvalidDockerConfig{Path: filepath.Join(configDir, "config.json"), Err: err})
+	}
+
+	configKey := host
+	if host == dockerHubRegistry {
+		configKey = dockerHubConfigKey
+	}
+
+	if helper, hasHelper := config.CredHelpers[configKey]; hasHelper {
+		return credentialsFromHelper(helper, configKey)
+	}
+	if config.CredsStore != "" {
+		credentials, err := credentialsFromHelper(config.CredsStore, configKey)
+		if err != nil || credentials != nil {
+			return credentials, err
+		}
+	}
+
+	auth, hasAuth := config.Auths[configKey]
+	if !hasAuth || auth.Auth == "" {
+		return nil, nil
+	}
+	decoded, err := base64.StdEncoding.DecodeString(auth.Auth)
+	if err != nil {
+		return nil, errors.WithStackTrace(InvalidDockerConfig{Path: filepath.Join(configDir, "config.json"), Err: err})
+	}
+	parts := strings.SplitN(string(decoded), ":", 2)
+	if len(parts) != 2 {
+		return nil, errors.WithStackTrace(InvalidDockerConfig{Path: filepath.Join(configDir, "config.json"), Err: fmt.Errorf("the auth of %s isn't a username:password pair", configKey)})
+	}
+	return &Credentials{Username: parts[0], Secret: parts[1]}, nil
+}
+
+// Return the credentials of the given registry host from the given docker credential helper, e.g. ecr-login, which is
+// run as docker-credential-ecr-login. Returns nil when the helper has no credentials for the host.
+func credentialsFromHelper(helper string, host string) (*Credentials, error) {
+	cmd := exec.Command("docker-credential-"+helper, "get")
+	cmd.Stdin = strings.NewReader(host)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		// The helpers print this message, and exit with an error, when they have no credentials for the host
+		if strings.Contains(stdout.String()+stderr.String(), "credentials not found") {
+			return nil, nil
+		}
+		return nil, errors.WithStackTrace(CredentialHelperFailed{Helper: helper, Host: host, Err: err, Output: strings.TrimSpace(stdout.String() + stderr.String())})
+	}
+
+	var credentials struct {
+		Username string `json:"Username"`
+		Secret   string `json:"Secret"`
+	}
+	if err := json.Unmarshal(stdout.Bytes(), &credentials); err != nil {
+		return nil, errors.WithStackTrace(CredentialHelperFailed{Helper: helper, Host: host, Err: err})
+	}
+	return &Credentials{Username: credentials.Username, Secret: credentials.Secret}, nil
+}
+
+// Return the dir of the docker config: the DOCKER_CONFIG env var, or else ~/.docker
+func defaultDockerConfigDir() string {
+	if configDir := os.Getenv("DOCKER_CONFIG"); configDir != "" {
+		return configDir
+	}
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(homeDir, ".docker")
+}
+
+// Custom error types
+
+type InvalidDockerConfig struct {
+	Path string
+	Err  error
+}
+
+func (err InvalidDockerConfig) Error() string {
+	return fmt.Sprintf("Could not read the registry credentials of the docker config %s: %v", err.Path, err.Err)
+}
+
+type CredentialHelperFailed struct {
+	Helper string
+	Host   string
+	Err    error
+	Output string
+}
+
+func (err CredentialHelperFailed) Error() string {
+	message := fmt.Sprintf("The docker credential helper docker-credential-%s failed to get the credentials of %s: %v", err.Helper, err.Host, err.Err)
+	if err.Output != "" {
+		message = fmt.Sprintf("%s: %s", message, err.Output)
+	}
+	return message
+}
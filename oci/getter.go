@@ -0,0 +1,169 @@
+// Package oci pulls Terraform modules packaged as OCI artifacts, e.g. with oras push, from container registries, so that
+// terraform.source can point at them with the oci:// scheme. The registries are logged in to with the credentials of
+// the docker config, including its credential helpers, such as the ones of ECR and GCR.
+package oci
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/go-getter"
+
+	"github.com/gruntwork-io/terragrunt/errors"
+)
+
+// The scheme of the sources that point at OCI artifacts
+const SCHEME = "oci"
+
+// The annotation oras sets to the file name of the layers it pushes, and the one it sets on the folders it pushes
+const (
+	titleAnnotation      = "org.opencontainers.image.title"
+	orasUnpackAnnotation = "io.deis.oras.content.unpack"
+)
+
+// How long to wait for a response of the registry, or for a layer to download
+const requestTimeout = 10 * time.Minute
+
+// Getter is a go-getter getter that pulls the artifact the oci:// URL refers to, and unpacks its layers into the
+// destination folder: the tarballs and zip archives are extracted, and the other layers, such as the files oras pushes
+// on their own, are written to the file their title annotation names.
+type Getter struct {
+	// The dir of the docker config the credentials of the registries are read from. Defaults to the DOCKER_CONFIG env
+	// var, or ~/.docker.
+	DockerConfigDir string
+}
+
+func (ociGetter *Getter) ClientMode(sourceUrl *url.URL) (getter.ClientMode, error) {
+	return getter.ClientModeDir, nil
+}
+
+func (ociGetter *Getter) SetClient(client *getter.Client) {}
+
+// Pull the artifact the given URL refers to, and unpack its layers into the given dir
+func (ociGetter *Getter) Get(dst string, sourceUrl *url.URL) error {
+	reference, err := ParseReference(sourceUrl)
+	if err != nil {
+		return err
+	}
+
+	configDir := ociGetter.DockerConfigDir
+	if configDir == "" {
+		configDir = defaultDockerConfigDir()
+	}
+	credentials, err := findCredentials(configDir, reference.Registry)
+	if err != nil {
+		return err
+	}
+
+	client := &registryClient{reference: reference, credentials: credentials, httpClient: &http.Client{Timeout: requestTimeout}}
+	artifactManifest, err := client.manifest()
+	if err != nil {
+		return err
+	}
+	if len(artifactManifest.Layers) == 0 {
+		return errors.WithStackTrace(EmptyArtifact(reference.String()))
+	}
+
+	if err := os.MkdirAll(dst, 0755); err != nil {
+		return errors.WithStackTrace(err)
+	}
+	tempDir, err := ioutil.TempDir("", "terragrunt-oci")
+	if err != nil {
+		return errors.WithStackTrace(err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	for index, layer := range artifactManifest.Layers {
+		blobPath := filepath.Join(tempDir, fmt.Sprintf("layer-%d", index))
+		if err := client.downloadBlob(layer.Digest, blobPath); err != nil {
+			return err
+		}
+		if err := unpackLayer(layer, blobPath, dst, reference); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Modules are folders, so pulling a single file isn't supported
+func (ociGetter *Getter) GetFile(dst string, sourceUrl *url.URL) error {
+	return errors.WithStackTrace(SingleFileNotSupported(sourceUrl.String()))
+}
+
+// Unpack the given layer, downloaded to the given file, into the given dir
+func unpackLayer(layer descriptor, blobPath string, dst string, reference *Reference) error {
+	title := layer.Annotations[titleAnnotation]
+	if decompressor := layerDecompressor(layer); decompressor != nil {
+		return errors.WithStackTrace(decompressor.Decompress(dst, blobPath, true))
+	}
+
+	if title == "" {
+		return errors.WithStackTrace(UnsupportedLayer{Reference: reference.String(), Digest: layer.Digest, MediaType: layer.MediaType})
+	}
+	// The title is a path relative to the module, which mustn't escape it
+	filePath := filepath.Join(dst, filepath.FromSlash(title))
+	if !strings.HasPrefix(filePath, filepath.Clean(dst)+string(filepath.Separator)) {
+		return errors.WithStackTrace(UnsupportedLayer{Reference: reference.String(), Digest: layer.Digest, MediaType: layer.MediaType})
+	}
+	if err := os.MkdirAll(filepath.Dir(filePath), 0755); err != nil {
+		return errors.WithStackTrace(err)
+	}
+	contents, err := ioutil.ReadFile(blobPath)
+	if err != nil {
+		return errors.WithStackTrace(err)
+	}
+	return errors.WithStackTrace(ioutil.WriteFile(filePath, contents, 0644))
+}
+
+// Return the decompressor of the archive format of the given layer, or nil if the layer isn't an archive. oras gives
+// the files it pushes the media type of a tarball, so the layers with a title are told apart by its extension, or the
+// annotation oras sets on the folders it pushes as tarballs.
+func layerDecompressor(layer descriptor) getter.Decompressor {
+	name := layer.MediaType
+	if title, hasTitle := layer.Annotations[titleAnnotation]; hasTitle {
+		name = title
+		if layer.Annotations[orasUnpackAnnotation] == "true" {
+			name = ".tar.gz"
+		}
+	}
+
+	switch {
+	case strings.HasSuffix(name, "tar+gzip"), strings.HasSuffix(name, ".tar.gz"), strings.HasSuffix(name, ".tgz"):
+		return getter.Decompressors["tar.gz"]
+	case strings.HasSuffix(name, ".tar"):
+		return getter.Decompressors["tar"]
+	case strings.HasSuffix(name, "+zip"), strings.HasSuffix(name, ".zip"):
+		return getter.Decompressors["zip"]
+	}
+	return nil
+}
+
+// Custom error types
+
+type EmptyArtifact string
+
+func (reference EmptyArtifact) Error() string {
+	return fmt.Sprintf("The artifact %s has no layers to unpack the module from", string(reference))
+}
+
+type UnsupportedLayer struct {
+	Reference string
+	Digest    string
+	MediaType string
+}
+
+func (err UnsupportedLayer) Error() string {
+	return fmt.Sprintf("Could not unpack the layer %s of %s, of type %s: it's neither a tarball nor a zip archive, and has no valid %s annotation to name the file it's written to", err.Digest, err.Reference, err.MediaType, titleAnnotation)
+}
+
+type SingleFileNotSupported string
+
+func (source SingleFileNotSupported) Error() string {
+	return fmt.Sprintf("Could not pull %s: OCI artifacts can only be pulled as a module folder", string(source))
+}
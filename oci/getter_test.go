@@ -0,0 +1,173 @@
+package oci
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/gruntwork-io/terragrunt/errors"
+)
+
+func TestParseReference(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		source   string
+		expected Reference
+	}{
+		{"oci://registry.example.com/modules/vpc:1.2.0", Reference{Registry: "registry.example.com", Repository: "modules/vpc", Reference: "1.2.0"}},
+		{"oci://registry.example.com:5000/modules/vpc", Reference{Registry: "registry.example.com:5000", Repository: "modules/vpc", Reference: "latest"}},
+		{"oci://registry.example.com/modules/vpc@sha256:abc", Reference{Registry: "registry.example.com", Repository: "modules/vpc", Reference: "sha256:abc"}},
+		{"oci://docker.io/vpc:1.0", Reference{Registry: dockerHubRegistry, Repository: "library/vpc", Reference: "1.0"}},
+	}
+
+	for _, testCase := range testCases {
+		sourceUrl, err := url.Parse(testCase.source)
+		require.NoError(t, err)
+		reference, err := ParseReference(sourceUrl)
+		require.NoError(t, err, testCase.source)
+		assert.Equal(t, testCase.expected, *reference, testCase.source)
+	}
+
+	sourceUrl, err := url.Parse("oci://registry.example.com")
+	require.NoError(t, err)
+	_, err = ParseReference(sourceUrl)
+	assert.IsType(t, InvalidReference(""), errors.Unwrap(err))
+}
+
+// A fake registry with a single artifact, modules/vpc:1.0.0, which only the user with the password hunter2 can pull,
+// with a token its token endpoint exchanges the credentials for
+type fakeRegistry struct {
+	server *httptest.Server
+	blobs  map[string][]byte
+}
+
+func newFakeRegistry(t *testing.T, layers []descriptor, blobs [][]byte) *fakeRegistry {
+	registry := &fakeRegistry{blobs: map[string][]byte{}}
+	for index, blob := range blobs {
+		hash := sha256.Sum256(blob)
+		layers[index].Digest = "sha256:" + hex.EncodeToString(hash[:])
+		registry.blobs[layers[index].Digest] = blob
+	}
+	manifestJson, err := json.Marshal(manifest{MediaType: "application/vnd.oci.image.manifest.v1+json", Layers: layers})
+	require.NoError(t, err)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/token", func(writer http.ResponseWriter, request *http.Request) {
+		username, password, hasAuth := request.BasicAuth()
+		if !hasAuth || username != "deployer" || password != "hunter2" || request.URL.Query().Get("scope") != "repository:modules/vpc:pull" {
+			writer.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		fmt.Fprint(writer, `{"token": "pull-token"}`)
+	})
+	mux.HandleFunc("/v2/modules/vpc/", func(writer http.ResponseWriter, request *http.Request) {
+		if request.Header.Get("Authorization") != "Bearer pull-token" {
+			writer.Header().Set("WWW-Authenticate", fmt.Sprintf(`Bearer realm="%s/token",service="fake-registry"`, registry.server.URL))
+			writer.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		switch path := request.URL.Path; {
+		case path == "/v2/modules/vpc/manifests/1.0.0":
+			writer.Header().Set("Content-Type", "application/vnd.oci.image.manifest.v1+json")
+			writer.Write(manifestJson)
+		case registry.blobs[filepath.Base(path)] != nil:
+			writer.Write(registry.blobs[filepath.Base(path)])
+		default:
+			writer.WriteHeader(http.StatusNotFound)
+		}
+	})
+	registry.server = httptest.NewServer(mux)
+	return registry
+}
+
+// Return the docker config dir with the credentials of the given registry
+func createDockerConfig(t *testing.T, registry string, username string, password string) string {
+	configDir, err := ioutil.TempDir("", "docker-config")
+	require.NoError(t, err)
+	auth := base64.StdEncoding.EncodeToString([]byte(username + ":" + password))
+	config := fmt.Sprintf(`{"auths": {"%s": {"auth": "%s"}}}`, registry, auth)
+	require.NoError(t, ioutil.WriteFile(filepath.Join(configDir, "config.json"), []byte(config), 0600))
+	return configDir
+}
+
+func createTarball(t *testing.T, files map[string]string) []byte {
+	var buffer bytes.Buffer
+	gzipWriter := gzip.NewWriter(&buffer)
+	tarWriter := tar.NewWriter(gzipWriter)
+	for path, contents := range files {
+		require.NoError(t, tarWriter.WriteHeader(&tar.Header{Name: path, Mode: 0644, Size: int64(len(contents)), Typeflag: tar.TypeReg}))
+		_, err := tarWriter.Write([]byte(contents))
+		require.NoError(t, err)
+	}
+	require.NoError(t, tarWriter.Close())
+	require.NoError(t, gzipWriter.Close())
+	return buffer.Bytes()
+}
+
+func TestGetterPullsModule(t *testing.T) {
+	t.Parallel()
+
+	layers := []descriptor{
+		{MediaType: "application/vnd.oci.image.layer.v1.tar+gzip", Annotations: map[string]string{titleAnnotation: "vpc", orasUnpackAnnotation: "true"}},
+		{MediaType: "application/vnd.oci.image.layer.v1.tar", Annotations: map[string]string{titleAnnotation: "README.md"}},
+	}
+	blobs := [][]byte{
+		createTarball(t, map[string]string{"main.tf": `resource "null_resource" "vpc" {}`, "modules/subnet/main.tf": `variable "cidr" {}`}),
+		[]byte("# VPC"),
+	}
+	registry := newFakeRegistry(t, layers, blobs)
+	defer registry.server.Close()
+	registryHost := registry.server.Listener.Addr().String()
+
+	configDir := createDockerConfig(t, registryHost, "deployer", "hunter2")
+	defer os.RemoveAll(configDir)
+	dst, err := ioutil.TempDir("", "oci-module")
+	require.NoError(t, err)
+	defer os.RemoveAll(dst)
+
+	sourceUrl, err := url.Parse(fmt.Sprintf("oci://%s/modules/vpc:1.0.0", registryHost))
+	require.NoError(t, err)
+	ociGetter := &Getter{DockerConfigDir: configDir}
+	require.NoError(t, ociGetter.Get(dst, sourceUrl))
+
+	for path, expected := range map[string]string{"main.tf": `resource "null_resource" "vpc" {}`, "modules/subnet/main.tf": `variable "cidr" {}`, "README.md": "# VPC"} {
+		contents, err := ioutil.ReadFile(filepath.Join(dst, path))
+		require.NoError(t, err, path)
+		assert.Equal(t, expected, string(contents), path)
+	}
+}
+
+func TestGetterWrongCredentials(t *testing.T) {
+	t.Parallel()
+
+	registry := newFakeRegistry(t, []descriptor{{MediaType: "application/vnd.oci.image.layer.v1.tar+gzip"}}, [][]byte{createTarball(t, map[string]string{"main.tf": ""})})
+	defer registry.server.Close()
+	registryHost := registry.server.Listener.Addr().String()
+
+	configDir := createDockerConfig(t, registryHost, "deployer", "wrong")
+	defer os.RemoveAll(configDir)
+	dst, err := ioutil.TempDir("", "oci-module")
+	require.NoError(t, err)
+	defer os.RemoveAll(dst)
+
+	sourceUrl, err := url.Parse(fmt.Sprintf("oci://%s/modules/vpc:1.0.0", registryHost))
+	require.NoError(t, err)
+	err = (&Getter{DockerConfigDir: configDir}).Get(dst, sourceUrl)
+	assert.IsType(t, AuthenticationFailed{}, errors.Unwrap(err))
+}
@@ -0,0 +1,320 @@
+package oci
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/gruntwork-io/terragrunt/errors"
+)
+
+// The host of the registry of Docker Hub, which docker.io refers to
+const dockerHubRegistry = "registry-1.docker.io"
+
+// The media types of the manifests of artifacts, and of the indexes that list the manifests of several platforms
+var manifestMediaTypes = []string{
+	"application/vnd.oci.image.manifest.v1+json",
+	"application/vnd.docker.distribution.manifest.v2+json",
+	"application/vnd.oci.image.index.v1+json",
+	"application/vnd.docker.distribution.manifest.list.v2+json",
+}
+
+// The parameters of a WWW-Authenticate challenge, e.g. realm="https://auth.docker.io/token",service="registry.docker.io"
+var challengeParamRegexp = regexp.MustCompile(`(\w+)="([^"]*)"`)
+
+// Reference is a reference to an artifact in a registry, e.g. registry.example.com/modules/vpc:1.2.0
+type Reference struct {
+	Registry   string
+	Repository string
+	// The tag or the digest of the artifact
+	Reference string
+}
+
+// Parse the given oci:// URL, e.g. oci://registry.example.com/modules/vpc:1.2.0 or
+// oci://registry.example.com/modules/vpc@sha256:..., into a reference. The tag defaults to latest.
+func ParseReference(sourceUrl *url.URL) (*Reference, error) {
+	repository := strings.Trim(sourceUrl.Path, "/")
+	if sourceUrl.Host == "" || repository == "" {
+		return nil, errors.WithStackTrace(InvalidReference(sourceUrl.String()))
+	}
+
+	reference := &Reference{Registry: sourceUrl.Host, Reference: "latest"}
+	if digestIndex := strings.Index(repository, "@"); digestIndex >= 0 {
+		reference.Reference = repository[digestIndex+1:]
+		repository = repository[:digestIndex]
+	} else if tagIndex := strings.LastIndex(repository, ":"); tagIndex > strings.LastIndex(repository, "/") {
+		reference.Reference = repository[tagIndex+1:]
+		repository = repository[:tagIndex]
+	}
+	if repository == "" || reference.Reference == "" {
+		return nil, errors.WithStackTrace(InvalidReference(sourceUrl.String()))
+	}
+
+	// Like docker, docker.io refers to the registry of Docker Hub, where the official images are in the library
+	if reference.Registry == "docker.io" {
+		reference.Registry = dockerHubRegistry
+		if !strings.Contains(repository, "/") {
+			repository = "library/" + repository
+		}
+	}
+	reference.Repository = repository
+	return reference, nil
+}
+
+func (reference *Reference) String() string {
+	separator := ":"
+	if strings.Contains(reference.Reference, ":") {
+		separator = "@"
+	}
+	return reference.Registry + "/" + reference.Repository + separator + reference.Reference
+}
+
+// The manifest of an artifact, or the index that lists the manifests of several platforms
+type manifest struct {
+	MediaType string       `json:"mediaType"`
+	Layers    []descriptor `json:"layers"`
+	Manifests []descriptor `json:"manifests"`
+}
+
+// A descriptor of a blob, i.e., a layer of an artifact, or a manifest an index lists
+type descriptor struct {
+	MediaType   string            `json:"mediaType"`
+	Digest      string            `json:"digest"`
+	Annotations map[string]string `json:"annotations"`
+}
+
+// A client of the distribution API of a registry, which pulls the artifacts of a repository
+type registryClient struct {
+	reference     *Reference
+	credentials   *Credentials
+	httpClient    *http.Client
+	authorization string
+}
+
+// The base URL of the API of the registry. Like docker, the registries on localhost are accessed over plain HTTP.
+func (client *registryClient) baseUrl() string {
+	scheme := "https"
+	hostname := strings.Split(client.reference.Registry, ":")[0]
+	if hostname == "localhost" || hostname == "127.0.0.1" {
+		scheme = "http"
+	}
+	return fmt.Sprintf("%s://%s/v2/%s", scheme, client.reference.Registry, client.reference.Repository)
+}
+
+// Return the manifest of the artifact of the reference. When the reference points at an index, the manifest of its
+// first platform is returned, as modules are the same on all platforms.
+func (client *registryClient) manifest() (*manifest, error) {
+	artifactManifest, err := client.getManifest(client.reference.Reference)
+	if err != nil {
+		return nil, err
+	}
+	if len(artifactManifest.Manifests) > 0 && len(artifactManifest.Layers) == 0 {
+		return client.getManifest(artifactManifest.Manifests[0].Digest)
+	}
+	return artifactManifest, nil
+}
+
+func (client *registryClient) getManifest(reference string) (*manifest, error) {
+	response, err := client.get("/manifests/"+reference, strings.Join(manifestMediaTypes, ", "))
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+
+	var artifactManifest manifest
+	if err := json.NewDecoder(response.Body).Decode(&artifactManifest); err != nil {
+		return nil, errors.WithStackTrace(err)
+	}
+	return &artifactManifest, nil
+}
+
+// Download the blob with the given digest to the given file, checking it has that digest
+func (client *registryClient) downloadBlob(digest string, path string) error {
+	response, err := client.get("/blobs/"+digest, "")
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+
+	file, err := os.Create(path)
+	if err != nil {
+		return errors.WithStackTrace(err)
+	}
+	defer file.Close()
+
+	hash := sha256.New()
+	if _, err := io.Copy(io.MultiWriter(file, hash), response.Body); err != nil {
+		return errors.WithStackTrace(err)
+	}
+	if actualDigest := "sha256:" + hex.EncodeToString(hash.Sum(nil)); strings.HasPrefix(digest, "sha256:") && actualDigest != digest {
+		return errors.WithStackTrace(DigestMismatch{Reference: client.reference.String(), Expected: digest, Actual: actualDigest})
+	}
+	return nil
+}
+
+// Send a GET request to the given path of the API of the repository. When the registry asks for authentication, the
+// credentials are used to log in, as its challenge says, and the request is sent again.
+func (client *registryClient) get(path string, accept string) (*http.Response, error) {
+	response, err := client.send(path, accept)
+	if err != nil {
+		return nil, err
+	}
+
+	if response.StatusCode == http.StatusUnauthorized && client.authorization == "" {
+		challenge := response.Header.Get("WWW-Authenticate")
+		response.Body.Close()
+		if err := client.authenticate(challenge); err != nil {
+			return nil, err
+		}
+		response, err = client.send(path, accept)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if response.StatusCode != http.StatusOK {
+		response.Body.Close()
+		return nil, errors.WithStackTrace(RegistryError{Reference: client.reference.String(), Path: path, StatusCode: response.StatusCode})
+	}
+	return response, nil
+}
+
+func (client *registryClient) send(path string, accept string) (*http.Response, error) {
+	request, err := http.NewRequest(http.MethodGet, client.baseUrl()+path, nil)
+	if err != nil {
+		return nil, errors.WithStackTrace(err)
+	}
+	if accept != "" {
+		request.Header.Set("Accept", accept)
+	}
+	if client.authorization != "" {
+		request.Header.Set("Authorization", client.authorization)
+	}
+
+	response, err := client.httpClient.Do(request)
+	if err != nil {
+		return nil, errors.WithStackTrace(err)
+	}
+	return response, nil
+}
+
+// Log in to the registry as the given WWW-Authenticate challenge says: with the credentials themselves, for the Basic
+// scheme, or with a token the realm of the challenge exchanges them for, which allows anonymous pulls, for the Bearer
+// scheme
+func (client *registryClient) authenticate(challenge string) error {
+	params := map[string]string{}
+	for _, match := range challengeParamRegexp.FindAllStringSubmatch(challenge, -1) {
+		params[match[1]] = match[2]
+	}
+
+	switch {
+	case strings.HasPrefix(strings.ToLower(challenge), "basic"):
+		if client.credentials == nil {
+			return errors.WithStackTrace(MissingCredentials(client.reference.Registry))
+		}
+		client.authorization = "Basic " + base64.StdEncoding.EncodeToString([]byte(client.credentials.Username+":"+client.credentials.Secret))
+		return nil
+
+	case strings.HasPrefix(strings.ToLower(challenge), "bearer") && params["realm"] != "":
+		tokenUrl, err := url.Parse(params["realm"])
+		if err != nil {
+			return errors.WithStackTrace(err)
+		}
+		query := tokenUrl.Query()
+		if params["service"] != "" {
+			query.Set("service", params["service"])
+		}
+		scope := params["scope"]
+		if scope == "" {
+			scope = fmt.Sprintf("repository:%s:pull", client.reference.Repository)
+		}
+		query.Set("scope", scope)
+		tokenUrl.RawQuery = query.Encode()
+
+		request, err := http.NewRequest(http.MethodGet, tokenUrl.String(), nil)
+		if err != nil {
+			return errors.WithStackTrace(err)
+		}
+		if client.credentials != nil {
+			request.SetBasicAuth(client.credentials.Username, client.credentials.Secret)
+		}
+		response, err := client.httpClient.Do(request)
+		if err != nil {
+			return errors.WithStackTrace(err)
+		}
+		defer response.Body.Close()
+		if response.StatusCode != http.StatusOK {
+			body, _ := ioutil.ReadAll(response.Body)
+			return errors.WithStackTrace(AuthenticationFailed{Registry: client.reference.Registry, StatusCode: response.StatusCode, Message: strings.TrimSpace(string(body))})
+		}
+
+		var token struct {
+			Token       string `json:"token"`
+			AccessToken string `json:"access_token"`
+		}
+		if err := json.NewDecoder(response.Body).Decode(&token); err != nil {
+			return errors.WithStackTrace(err)
+		}
+		if token.Token == "" {
+			token.Token = token.AccessToken
+		}
+		client.authorization = "Bearer " + token.Token
+		return nil
+
+	default:
+		return errors.WithStackTrace(AuthenticationFailed{Registry: client.reference.Registry, StatusCode: http.StatusUnauthorized, Message: fmt.Sprintf("unsupported challenge %q", challenge)})
+	}
+}
+
+// Custom error types
+
+type InvalidReference string
+
+func (source InvalidReference) Error() string {
+	return fmt.Sprintf("%s is not a valid OCI artifact reference. Expected oci://<registry>/<repository>[:<tag>|@<digest>], e.g. oci://registry.example.com/modules/vpc:1.2.0.", string(source))
+}
+
+type RegistryError struct {
+	Reference  string
+	Path       string
+	StatusCode int
+}
+
+func (err RegistryError) Error() string {
+	return fmt.Sprintf("Could not pull %s: GET %s failed with status %d", err.Reference, err.Path, err.StatusCode)
+}
+
+type MissingCredentials string
+
+func (registry MissingCredentials) Error() string {
+	return fmt.Sprintf("The registry %s requires credentials, but there are none in the docker config. Log in with docker login %s, or configure a credential helper for it.", string(registry), string(registry))
+}
+
+type AuthenticationFailed struct {
+	Registry   string
+	StatusCode int
+	Message    string
+}
+
+func (err AuthenticationFailed) Error() string {
+	return fmt.Sprintf("Could not authenticate with the registry %s (status %d): %s", err.Registry, err.StatusCode, err.Message)
+}
+
+type DigestMismatch struct {
+	Reference string
+	Expected  string
+	Actual    string
+}
+
+func (err DigestMismatch) Error() string {
+	return fmt.Sprintf("A layer of %s has the digest %s rather than %s", err.Reference, err.Actual, err.Expected)
+}
@@ -0,0 +1,146 @@
+package oidc
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/endpoints"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/sts"
+
+	"github.com/gruntwork-io/terragrunt/errors"
+)
+
+// The audiences the tokens are requested for by default, which are the ones the clouds expect unless their identity
+// providers are configured otherwise
+const (
+	DefaultAwsAudience   = "sts.amazonaws.com"
+	DefaultAzureAudience = "api://AzureADTokenExchange"
+)
+
+// The region of the STS endpoint the token is exchanged at by default
+const defaultAwsRegion = "us-east-1"
+
+// AwsRole is the IAM role a token is exchanged for credentials of, via AssumeRoleWithWebIdentity
+type AwsRole struct {
+	RoleArn     string
+	SessionName string
+	// The duration of the session, in seconds. Zero means the default of STS, one hour.
+	DurationSeconds int64
+	// The region of the STS endpoint, and an endpoint that replaces the one of the region, e.g. a VPC endpoint
+	Region   string
+	Endpoint string
+	// Use the STS endpoint of the region even for the regions the AWS SDK uses the global endpoint for by default
+	RegionalEndpoint bool
+}
+
+// AwsCredentials exchanges the given token for temporary credentials of the given role. The call is not signed, so it
+// doesn't need any other credentials.
+func AwsCredentials(token string, role AwsRole) (*sts.Credentials, error) {
+	// The session gets an HTTP client of its own, as the AWS SDK sets the CA bundle of AWS_CA_BUNDLE, if any, on it, and
+	// tokens are exchanged concurrently during *-all commands
+	awsConfig := aws.NewConfig().WithRegion(defaultAwsRegion).WithCredentials(credentials.AnonymousCredentials).WithHTTPClient(&http.Client{})
+	if role.Region != "" {
+		awsConfig.WithRegion(role.Region)
+	}
+	if role.Endpoint != "" {
+		awsConfig.WithEndpoint(role.Endpoint)
+	}
+	if role.RegionalEndpoint {
+		awsConfig.STSRegionalEndpoint = endpoints.RegionalSTSEndpoint
+	}
+	sess, err := session.NewSession(awsConfig)
+	if err != nil {
+		return nil, errors.WithStackTrace(err)
+	}
+
+	input := &sts.AssumeRoleWithWebIdentityInput{
+		RoleArn:          aws.String(role.RoleArn),
+		RoleSessionName:  aws.String(role.SessionName),
+		WebIdentityToken: aws.String(token),
+	}
+	if input.RoleSessionName == nil || *input.RoleSessionName == "" {
+		input.RoleSessionName = aws.String(fmt.Sprintf("terragrunt-%d", time.Now().UTC().UnixNano()))
+	}
+	if role.DurationSeconds > 0 {
+		input.DurationSeconds = aws.Int64(role.DurationSeconds)
+	}
+	output, err := sts.New(sess).AssumeRoleWithWebIdentity(input)
+	if err != nil {
+		return nil, errors.WithStackTraceAndPrefix(err, "Error exchanging the OIDC token for credentials of %s", role.RoleArn)
+	}
+	return output.Credentials, nil
+}
+
+// The URLs of the STS of Google, and of the IAM credentials API service accounts are impersonated with
+const (
+	gcpTokenUrl                     = "https://sts.googleapis.com/v1/token"
+	gcpServiceAccountImpersonateUrl = "https://iamcredentials.googleapis.com/v1/projects/-/serviceAccounts/%s:generateAccessToken"
+	gcpJwtTokenType                 = "urn:ietf:params:oauth:token-type:jwt"
+)
+
+// DefaultGcpAudience returns the audience tokens are requested for by default for the given provider of a workload
+// identity pool, e.g. projects/123/locations/global/workloadIdentityPools/ci/providers/github, which is the default
+// audience of the providers
+func DefaultGcpAudience(workloadIdentityProvider string) string {
+	return "https://iam.googleapis.com/" + workloadIdentityProvider
+}
+
+// GcpCredentialsConfig returns the external_account credentials config of workload identity federation with the given
+// provider, which the Google provider and the GCS backend read via GOOGLE_APPLICATION_CREDENTIALS, and which makes them
+// exchange the token for credentials of the provider, or of the given service account if any, whenever they need one.
+// GitHub Actions is asked for a new token for the given audience on each exchange. The token of an env var is read from
+// the given file, which the caller writes it to.
+func GcpCredentialsConfig(source *TokenSource, audience string, tokenFile string, workloadIdentityProvider string, serviceAccount string) ([]byte, error) {
+	credentialSource := map[string]interface{}{"file": tokenFile}
+	if source.RequestUrl != "" {
+		credentialSource = map[string]interface{}{
+			"url":     source.AudienceUrl(audience),
+			"headers": map[string]string{"Authorization": "Bearer " + source.RequestToken},
+			"format":  map[string]string{"type": "json", "subject_token_field_name": "value"},
+		}
+	}
+
+	credentialsConfig := map[string]interface{}{
+		"type":               "external_account",
+		"audience":           "//iam.googleapis.com/" + workloadIdentityProvider,
+		"subject_token_type": gcpJwtTokenType,
+		"token_url":          gcpTokenUrl,
+		"credential_source":  credentialSource,
+	}
+	if serviceAccount != "" {
+		credentialsConfig["service_account_impersonation_url"] = fmt.Sprintf(gcpServiceAccountImpersonateUrl, serviceAccount)
+	}
+	contents, err := json.MarshalIndent(credentialsConfig, "", "  ")
+	return contents, errors.WithStackTrace(err)
+}
+
+// AzureEnv returns the env vars that make the azurerm provider and backend authenticate as the given app registration
+// or managed identity with the tokens of the given source for the given audience, via its federated credentials. Like
+// with GcpCredentialsConfig, they request a new token from GitHub Actions whenever they need one, so the credentials
+// don't expire during long runs, while the token of an env var is passed as is.
+func AzureEnv(source *TokenSource, audience string, clientId string, tenantId string, subscriptionId string) (map[string]string, error) {
+	env := map[string]string{
+		"ARM_USE_OIDC":  "true",
+		"ARM_CLIENT_ID": clientId,
+		"ARM_TENANT_ID": tenantId,
+	}
+	if source.RequestUrl != "" {
+		env["ARM_OIDC_REQUEST_URL"] = source.AudienceUrl(audience)
+		env["ARM_OIDC_REQUEST_TOKEN"] = source.RequestToken
+	} else {
+		token, err := source.Token(audience)
+		if err != nil {
+			return nil, err
+		}
+		env["ARM_OIDC_TOKEN"] = token
+	}
+	if subscriptionId != "" {
+		env["ARM_SUBSCRIPTION_ID"] = subscriptionId
+	}
+	return env, nil
+}
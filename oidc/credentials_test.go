@@ -0,0 +1,110 @@
+package oidc
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// An STS endpoint that responds to AssumeRoleWithWebIdentity with credentials, and records the form it's called with
+func newTestStsServer(t *testing.T, forms *[]map[string]string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		require.NoError(t, request.ParseForm())
+		form := map[string]string{}
+		for key := range request.PostForm {
+			form[key] = request.PostForm.Get(key)
+		}
+		*forms = append(*forms, form)
+
+		writer.Header().Set("Content-Type", "text/xml")
+		writer.Write([]byte(`<AssumeRoleWithWebIdentityResponse xmlns="https://sts.amazonaws.com/doc/2011-06-15/">
+  <AssumeRoleWithWebIdentityResult>
+    <Credentials>
+      <AccessKeyId>ASIA123</AccessKeyId>
+      <SecretAccessKey>secret</SecretAccessKey>
+      <SessionToken>session</SessionToken>
+      <Expiration>2030-01-01T00:00:00Z</Expiration>
+    </Credentials>
+  </AssumeRoleWithWebIdentityResult>
+</AssumeRoleWithWebIdentityResponse>`))
+	}))
+}
+
+func TestAwsCredentials(t *testing.T) {
+	t.Parallel()
+
+	forms := []map[string]string{}
+	server := newTestStsServer(t, &forms)
+	defer server.Close()
+
+	creds, err := AwsCredentials("ci-token", AwsRole{RoleArn: "arn:aws:iam::123456789012:role/ci", DurationSeconds: 900, Endpoint: server.URL})
+	require.NoError(t, err)
+	assert.Equal(t, "ASIA123", aws.StringValue(creds.AccessKeyId))
+	assert.Equal(t, "session", aws.StringValue(creds.SessionToken))
+
+	require.Len(t, forms, 1)
+	assert.Equal(t, "AssumeRoleWithWebIdentity", forms[0]["Action"])
+	assert.Equal(t, "ci-token", forms[0]["WebIdentityToken"])
+	assert.Equal(t, "arn:aws:iam::123456789012:role/ci", forms[0]["RoleArn"])
+	assert.Equal(t, "900", forms[0]["DurationSeconds"])
+	assert.Regexp(t, `^terragrunt-\d+$`, forms[0]["RoleSessionName"])
+}
+
+func TestGcpCredentialsConfig(t *testing.T) {
+	t.Parallel()
+
+	provider := "projects/123/locations/global/workloadIdentityPools/ci/providers/github"
+	gitHub := &TokenSource{RequestUrl: "https://token.actions.githubusercontent.com/?api-version=2.0", RequestToken: "request-token"}
+	contents, err := GcpCredentialsConfig(gitHub, DefaultGcpAudience(provider), "/tmp/token", provider, "terraform@acme.iam.gserviceaccount.com")
+	require.NoError(t, err)
+
+	var credentialsConfig map[string]interface{}
+	require.NoError(t, json.Unmarshal(contents, &credentialsConfig))
+	assert.Equal(t, "external_account", credentialsConfig["type"])
+	assert.Equal(t, "//iam.googleapis.com/"+provider, credentialsConfig["audience"])
+	assert.Equal(t, "https://iamcredentials.googleapis.com/v1/projects/-/serviceAccounts/terraform@acme.iam.gserviceaccount.com:generateAccessToken", credentialsConfig["service_account_impersonation_url"])
+	assert.Equal(t, map[string]interface{}{
+		"url":     "https://token.actions.githubusercontent.com/?api-version=2.0&audience=https%3A%2F%2Fiam.googleapis.com%2Fprojects%2F123%2Flocations%2Fglobal%2FworkloadIdentityPools%2Fci%2Fproviders%2Fgithub",
+		"headers": map[string]interface{}{"Authorization": "Bearer request-token"},
+		"format":  map[string]interface{}{"type": "json", "subject_token_field_name": "value"},
+	}, credentialsConfig["credential_source"])
+
+	gitLab := &TokenSource{EnvVar: DefaultGitLabTokenEnvVar, value: "gitlab-token"}
+	contents, err = GcpCredentialsConfig(gitLab, DefaultGcpAudience(provider), "/tmp/token", provider, "")
+	require.NoError(t, err)
+	credentialsConfig = map[string]interface{}{}
+	require.NoError(t, json.Unmarshal(contents, &credentialsConfig))
+	assert.Equal(t, map[string]interface{}{"file": "/tmp/token"}, credentialsConfig["credential_source"])
+	assert.NotContains(t, credentialsConfig, "service_account_impersonation_url")
+}
+
+func TestAzureEnv(t *testing.T) {
+	t.Parallel()
+
+	gitHub := &TokenSource{RequestUrl: "https://token.actions.githubusercontent.com/?api-version=2.0", RequestToken: "request-token"}
+	env, err := AzureEnv(gitHub, DefaultAzureAudience, "client", "tenant", "")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{
+		"ARM_USE_OIDC":           "true",
+		"ARM_OIDC_REQUEST_URL":   "https://token.actions.githubusercontent.com/?api-version=2.0&audience=api%3A%2F%2FAzureADTokenExchange",
+		"ARM_OIDC_REQUEST_TOKEN": "request-token",
+		"ARM_CLIENT_ID":          "client",
+		"ARM_TENANT_ID":          "tenant",
+	}, env)
+
+	gitLab := &TokenSource{EnvVar: DefaultGitLabTokenEnvVar, value: "gitlab-token"}
+	env, err = AzureEnv(gitLab, DefaultAzureAudience, "client", "tenant", "subscription")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{
+		"ARM_USE_OIDC":        "true",
+		"ARM_OIDC_TOKEN":      "gitlab-token",
+		"ARM_CLIENT_ID":       "client",
+		"ARM_TENANT_ID":       "tenant",
+		"ARM_SUBSCRIPTION_ID": "subscription",
+	}, env)
+}
@@ -0,0 +1,140 @@
+// Package oidc exchanges the OIDC tokens CI systems such as GitHub Actions and GitLab CI issue to their jobs for the
+// short-lived credentials of AWS, GCP and Azure, so that the terraform processes of a unit authenticate as the identity
+// of the CI job, without long-lived secrets or a script that bootstraps the credentials in every repo.
+package oidc
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/gruntwork-io/terragrunt/errors"
+)
+
+// The env vars GitHub Actions sets in the jobs with the id-token: write permission, to request OIDC tokens with. See
+// https://docs.github.com/en/actions/deployment/security-hardening-your-deployments/about-security-hardening-with-openid-connect
+const (
+	GitHubRequestUrlEnvVar   = "ACTIONS_ID_TOKEN_REQUEST_URL"
+	GitHubRequestTokenEnvVar = "ACTIONS_ID_TOKEN_REQUEST_TOKEN"
+)
+
+// GitLab CI only issues the OIDC tokens declared in the id_tokens of a job, in the env vars they're declared with, and
+// sets GITLAB_CI in all its jobs. The token is looked up in the env var the GitLab docs use, unless another one is
+// configured.
+const (
+	GitLabCIEnvVar           = "GITLAB_CI"
+	DefaultGitLabTokenEnvVar = "GITLAB_OIDC_TOKEN"
+)
+
+// TokenSource is where the OIDC token of the CI job is taken from: either an env var the token is already in, or the
+// endpoint of GitHub Actions that issues tokens for any audience
+type TokenSource struct {
+	// The env var the token is in, and its value
+	EnvVar string
+	value  string
+	// The URL to request tokens from, and the bearer token to request them with
+	RequestUrl   string
+	RequestToken string
+
+	httpClient *http.Client
+}
+
+// Find the source of the OIDC token of the CI job with the given env: the given env var, if any, or else the token
+// GitHub Actions issues, or else the token of the default env var of GitLab CI. Returns nil if there is no token, e.g.
+// when Terragrunt runs on the machine of a developer, or in a job without the permission to request one.
+func FindTokenSource(tokenEnvVar string, env map[string]string) *TokenSource {
+	if tokenEnvVar != "" {
+		if env[tokenEnvVar] == "" {
+			return nil
+		}
+		return &TokenSource{EnvVar: tokenEnvVar, value: env[tokenEnvVar]}
+	}
+	if env[GitHubRequestUrlEnvVar] != "" && env[GitHubRequestTokenEnvVar] != "" {
+		return NewRequestTokenSource(env[GitHubRequestUrlEnvVar], env[GitHubRequestTokenEnvVar])
+	}
+	if env[GitLabCIEnvVar] != "" && env[DefaultGitLabTokenEnvVar] != "" {
+		return &TokenSource{EnvVar: DefaultGitLabTokenEnvVar, value: env[DefaultGitLabTokenEnvVar]}
+	}
+	return nil
+}
+
+// NewRequestTokenSource returns the source of the tokens requested from the given URL of GitHub Actions, with the given
+// bearer token
+func NewRequestTokenSource(requestUrl string, requestToken string) *TokenSource {
+	return &TokenSource{RequestUrl: requestUrl, RequestToken: requestToken, httpClient: &http.Client{Timeout: time.Minute}}
+}
+
+// Token returns an OIDC token for the given audience. The audience of the tokens of an env var is fixed by the CI config
+// that put them there, so it only applies to the tokens requested from GitHub Actions.
+func (source *TokenSource) Token(audience string) (string, error) {
+	if source.RequestUrl == "" {
+		return source.value, nil
+	}
+
+	request, err := http.NewRequest(http.MethodGet, source.AudienceUrl(audience), nil)
+	if err != nil {
+		return "", errors.WithStackTrace(err)
+	}
+	request.Header.Set("Authorization", "Bearer "+source.RequestToken)
+	request.Header.Set("Accept", "application/json")
+
+	response, err := source.httpClient.Do(request)
+	if err != nil {
+		return "", errors.WithStackTrace(err)
+	}
+	defer response.Body.Close()
+	contents, err := ioutil.ReadAll(response.Body)
+	if err != nil {
+		return "", errors.WithStackTrace(err)
+	}
+	if response.StatusCode != http.StatusOK {
+		return "", errors.WithStackTrace(TokenRequestFailed{StatusCode: response.StatusCode, Message: strings.TrimSpace(string(contents))})
+	}
+
+	var token struct {
+		Value string `json:"value"`
+	}
+	if err := json.Unmarshal(contents, &token); err != nil {
+		return "", errors.WithStackTrace(err)
+	}
+	if token.Value == "" {
+		return "", errors.WithStackTrace(TokenRequestFailed{StatusCode: response.StatusCode, Message: "the response has no token"})
+	}
+	return token.Value, nil
+}
+
+// AudienceUrl returns the URL to request a token for the given audience from GitHub Actions. Its request URL already
+// has a query, e.g. ?api-version=2.0.
+func (source *TokenSource) AudienceUrl(audience string) string {
+	if audience == "" {
+		return source.RequestUrl
+	}
+	separator := "&"
+	if !strings.Contains(source.RequestUrl, "?") {
+		separator = "?"
+	}
+	return source.RequestUrl + separator + "audience=" + url.QueryEscape(audience)
+}
+
+// Describe where the tokens are taken from, for logging
+func (source *TokenSource) String() string {
+	if source.RequestUrl != "" {
+		return "GitHub Actions"
+	}
+	return fmt.Sprintf("the %s env var", source.EnvVar)
+}
+
+// Custom error types
+
+type TokenRequestFailed struct {
+	StatusCode int
+	Message    string
+}
+
+func (err TokenRequestFailed) Error() string {
+	return fmt.Sprintf("GitHub Actions responded to the request of an OIDC token with status %d: %s. Does the job have the id-token: write permission?", err.StatusCode, err.Message)
+}
@@ -0,0 +1,70 @@
+package oidc
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/gruntwork-io/terragrunt/errors"
+)
+
+// A GitHub Actions token endpoint that issues a token named after the audience it's requested for
+func newTestGitHubServer(t *testing.T) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		if request.Header.Get("Authorization") != "Bearer request-token" {
+			writer.WriteHeader(http.StatusForbidden)
+			writer.Write([]byte(`{"message": "Unable to get ACTIONS_ID_TOKEN_REQUEST_URL env variable"}`))
+			return
+		}
+		assert.Equal(t, "2.0", request.URL.Query().Get("api-version"))
+		writer.Write([]byte(`{"value": "token-for-` + request.URL.Query().Get("audience") + `"}`))
+	}))
+}
+
+func TestFindTokenSource(t *testing.T) {
+	t.Parallel()
+
+	gitHubEnv := map[string]string{GitHubRequestUrlEnvVar: "https://token.actions.githubusercontent.com/?api-version=2.0", GitHubRequestTokenEnvVar: "request-token"}
+	gitLabEnv := map[string]string{GitLabCIEnvVar: "true", DefaultGitLabTokenEnvVar: "gitlab-token", "VAULT_ID_TOKEN": "vault-token"}
+
+	assert.Nil(t, FindTokenSource("", map[string]string{}))
+	assert.Nil(t, FindTokenSource("", map[string]string{DefaultGitLabTokenEnvVar: "gitlab-token"}))
+	assert.Nil(t, FindTokenSource("AZURE_ID_TOKEN", gitHubEnv))
+
+	source := FindTokenSource("", gitHubEnv)
+	require.NotNil(t, source)
+	assert.Equal(t, "GitHub Actions", source.String())
+	assert.Equal(t, "https://token.actions.githubusercontent.com/?api-version=2.0&audience=sts.amazonaws.com", source.AudienceUrl("sts.amazonaws.com"))
+
+	source = FindTokenSource("", gitLabEnv)
+	require.NotNil(t, source)
+	token, err := source.Token("ignored")
+	require.NoError(t, err)
+	assert.Equal(t, "gitlab-token", token)
+
+	source = FindTokenSource("VAULT_ID_TOKEN", gitLabEnv)
+	require.NotNil(t, source)
+	assert.Equal(t, "the VAULT_ID_TOKEN env var", source.String())
+}
+
+func TestTokenFromGitHubActions(t *testing.T) {
+	t.Parallel()
+
+	server := newTestGitHubServer(t)
+	defer server.Close()
+
+	source := FindTokenSource("", map[string]string{GitHubRequestUrlEnvVar: server.URL + "/?api-version=2.0", GitHubRequestTokenEnvVar: "request-token"})
+	token, err := source.Token("api://AzureADTokenExchange")
+	require.NoError(t, err)
+	assert.Equal(t, "token-for-api://AzureADTokenExchange", token)
+
+	source.RequestToken = "expired"
+	_, err = source.Token("sts.amazonaws.com")
+	require.Error(t, err)
+	requestFailed, isRequestFailed := errors.Unwrap(err).(TokenRequestFailed)
+	require.True(t, isRequestFailed, "Unexpected error: %v", err)
+	assert.Equal(t, http.StatusForbidden, requestFailed.StatusCode)
+}
@@ -6,17 +6,32 @@ import (
 	"math"
 	"os"
 	"path/filepath"
+	"runtime"
+	"sort"
+	"sync"
 	"time"
 
+	"github.com/gruntwork-io/terragrunt/drift"
 	"github.com/gruntwork-io/terragrunt/errors"
+	"github.com/gruntwork-io/terragrunt/infracost"
+	"github.com/gruntwork-io/terragrunt/metrics"
+	"github.com/gruntwork-io/terragrunt/moduledocs"
+	"github.com/gruntwork-io/terragrunt/notify"
+	"github.com/gruntwork-io/terragrunt/planmarkdown"
+	"github.com/gruntwork-io/terragrunt/sarif"
+	"github.com/gruntwork-io/terragrunt/telemetry"
+	"github.com/gruntwork-io/terragrunt/tflint"
 	"github.com/gruntwork-io/terragrunt/util"
 	"github.com/hashicorp/go-version"
 	"github.com/sirupsen/logrus"
 )
 
+// The terraform commands whose subcommand comes before the args Terragrunt inserts, e.g. providers lock -platform=X, as
+// terraform only parses the flags of the providers lock and providers mirror subcommands after the subcommand
 var TERRAFORM_COMMANDS_WITH_SUBCOMMAND = []string{
 	"debug",
 	"force-unlock",
+	"providers",
 	"state",
 }
 
@@ -25,9 +40,29 @@ const DEFAULT_MAX_FOLDERS_TO_CHECK = 100
 // no limits on parallelism by default (limited by GOPROCS)
 const DEFAULT_PARALLELISM = math.MaxInt32
 
+// The number of dependency blocks of a module whose outputs are fetched concurrently by default
+const DEFAULT_DEPENDENCY_FETCH_PARALLELISM = 10
+
+// The maximum number of folders scanned and configs parsed concurrently while discovering the modules of *-all commands.
+// This is mostly IO bound, so it uses more workers than there are CPUs.
+var DEFAULT_DISCOVERY_PARALLELISM = 4 * runtime.NumCPU()
+
 // TERRAFORM_DEFAULT_PATH just takes terraform from the path
 const TERRAFORM_DEFAULT_PATH = "terraform"
 
+// TOFU_DEFAULT_PATH takes OpenTofu from the path. It's preferred over TERRAFORM_DEFAULT_PATH when it's installed and no
+// binary was set explicitly.
+const TOFU_DEFAULT_PATH = "tofu"
+
+// The implementations of terraform Terragrunt can run, as detected from the output of the version command
+type TerraformImplementationType string
+
+const (
+	TerraformImpl TerraformImplementationType = "terraform"
+	OpenTofuImpl  TerraformImplementationType = "tofu"
+	UnknownImpl   TerraformImplementationType = "unknown"
+)
+
 // DEFAULT_LOG_LEVEL defines default log level for Terragrunt
 const DEFAULT_LOG_LEVEL = util.DEFAULT_LOG_LEVEL
 
@@ -37,6 +72,25 @@ const DefaultTFDataDir = ".terraform"
 
 const DEFAULT_IAM_ASSUME_ROLE_DURATION = 3600
 
+// The number of seconds after which the cached Terraform sources that haven't been used are removed by default (30 days)
+const DEFAULT_SOURCE_CACHE_MAX_AGE = 30 * 24 * 60 * 60
+
+// The ways in which terragrunt can connect its stdin to the terraform commands it runs, so that terraform can prompt
+// for input
+const (
+	// Attach stdin to terraform, allocating a pseudo-tty for the commands that need one (e.g., console) when stdin is
+	// a terminal
+	INPUT_MODE_AUTO = "auto"
+	// Attach stdin to terraform, never allocating a pseudo-tty
+	INPUT_MODE_STDIN = "stdin"
+	// Allocate a pseudo-tty for the terraform command being run when stdin is a terminal
+	INPUT_MODE_PTY = "pty"
+	// Don't attach stdin to terraform or hooks, so that any prompt fails instead of waiting for input
+	INPUT_MODE_NONE = "none"
+)
+
+var INPUT_MODES = []string{INPUT_MODE_AUTO, INPUT_MODE_STDIN, INPUT_MODE_PTY, INPUT_MODE_NONE}
+
 // TerragruntOptions represents options that configure the behavior of the Terragrunt program
 type TerragruntOptions struct {
 	// Location of the Terragrunt config file
@@ -53,6 +107,10 @@ type TerragruntOptions struct {
 	// Location of the terraform binary
 	TerraformPath string
 
+	// True if the terraform binary was set via --terragrunt-tfpath or TERRAGRUNT_TFPATH, in which case Terragrunt
+	// doesn't look for OpenTofu on the PATH
+	TerraformPathExplicit bool
+
 	// Current Terraform command being executed by Terragrunt
 	TerraformCommand string
 
@@ -68,12 +126,19 @@ type TerragruntOptions struct {
 	// Version of terraform (obtained by running 'terraform version')
 	TerraformVersion *version.Version
 
+	// Whether the terraform binary is Terraform or OpenTofu (obtained by running 'terraform version')
+	TerraformImplementation TerraformImplementationType
+
 	// Whether we should prompt the user for confirmation or always assume "yes"
 	NonInteractive bool
 
 	// Whether we should automatically run terraform init if necessary when executing other commands
 	AutoInit bool
 
+	// Whether the .terraform.lock.hcl file should be copied back from the folder the Terraform code is downloaded into
+	// to the working dir after init, and forward again on the next run, so that it can be checked into version control
+	CopyLockFile bool
+
 	// CLI args that are intended for Terraform (i.e. all the CLI args except the --terragrunt ones)
 	TerraformCliArgs []string
 
@@ -97,18 +162,107 @@ type TerragruntOptions struct {
 	// value.
 	SourceMap map[string]string
 
+	// Map of the prefixes of source URLs to the URLs of the artifact stores that mirror them, as set via
+	// --terragrunt-source-mirror
+	SourceMirrors map[string]string
+
+	// The headers to send along with the requests to the HTTP source mirrors, as set via --terragrunt-source-mirror-header
+	SourceMirrorHeaders map[string]string
+
+	// The AWS service to sign the requests of the HTTP downloads of the sources with SigV4 for, as set via
+	// --terragrunt-source-sigv4-service. Empty if the requests aren't signed.
+	SourceSigV4Service string
+
 	// If set to true, delete the contents of the temporary folder before downloading Terraform source code into it
 	SourceUpdate bool
 
+	// If set to true, symlink the files and folders of local Terraform sources into the temporary folder rather than
+	// copying them
+	SymlinkLocalSource bool
+
+	// If set to true, download remote Terraform sources into a cache shared by all modules, and copy them from there
+	SourceCache bool
+
+	// The directory in which the remote Terraform sources are cached
+	SourceCacheDir string
+
+	// The number of seconds after which the cached Terraform sources that haven't been used are removed. Zero disables
+	// removing them.
+	SourceCacheMaxAge int
+
 	// Download Terraform configurations specified in the Source parameter into this folder
 	DownloadDir string
 
+	// The folder the Terraform configurations of the Source parameter of the module were downloaded into, e.g. the
+	// root of the repo of a git source, which WorkingDir is in. Empty if the module has no source.
+	SourceDownloadDir string
+
+	// The download dir passed in via the CLI or environment, if it's a template, such as
+	// /dev/shm/${get_env("BRANCH", "main")}. It's rendered for each module, once its configuration is read, and takes
+	// precedence over the download dir set there.
+	DownloadDirTemplate string
+
 	// The ARN of an IAM Role to assume before running Terraform
 	IamRole string
 
+	// The roles to assume in sequence, each with the credentials of the one before it, to get to IamRole, which is the
+	// last of them. Empty unless the config sets iam_role to a list of roles.
+	IamRoleChain []IamRoleHop
+
 	// Duration of the STS Session
 	IamAssumeRoleDuration int64
 
+	// The external ID to pass when assuming the IAM Role, which the trust policies of third-party and cross-account
+	// roles often require
+	IamAssumeRoleExternalId string
+
+	// The serial number or ARN of the MFA device to assume the IAM Role with, if the role requires MFA, and the token of
+	// that device. When the token is not set, the user is prompted for it.
+	IamAssumeRoleMfaSerial string
+	IamAssumeRoleMfaToken  string
+
+	// A web identity token, or the path of a file containing one, to assume the IAM Role with via
+	// AssumeRoleWithWebIdentity rather than with the credentials found in the environment
+	IamWebIdentityToken string
+
+	// The session name, and the region and endpoint of STS, to assume the IAM Role with via AssumeRoleWithWebIdentity,
+	// e.g. the ones of the oidc_credentials block of aws. Empty means a generated session name, and the region of the env
+	// and the STS endpoint set via --terragrunt-aws-endpoint.
+	IamWebIdentitySessionName string
+	IamWebIdentityRegion      string
+	IamWebIdentityStsEndpoint string
+
+	// The env vars of the AWS credentials of the unit from before the credentials of IamRole were set in Env, which
+	// IamRole is assumed with. Nil until the role is assumed. See aws_helper.AssumeRoleAndUpdateEnvIfNecessary.
+	IamRoleSourceEnv map[string]string
+
+	// The email of a GCP service account to impersonate, with the credentials found in the environment, when
+	// bootstrapping GCS backends and running terraform
+	GoogleImpersonateServiceAccount string
+
+	// The AWS profile the unit is pinned to via aws_profile, which terragrunt finds its own credentials with, and which
+	// is passed to terraform via AWS_PROFILE
+	AwsProfile string
+
+	// If set to true, start a new SSO session with aws sso login when the one of the AWS SSO profile in use expired,
+	// rather than failing
+	AwsSsoLogin bool
+
+	// If set to true, cache the credentials of the IAM roles terragrunt assumes in the keyring of the OS, so that the
+	// next runs reuse them rather than assuming the roles, and asking for MFA tokens, again
+	AwsKeyringCache bool
+
+	// The endpoints of the AWS services terragrunt calls, by service, as set via --terragrunt-aws-endpoint, e.g. the ones
+	// of localstack or of VPC endpoints
+	AwsEndpoints map[string]string
+
+	// If set to true, call the STS endpoint of the region even for the regions the AWS SDK calls the global endpoint for
+	AwsStsRegionalEndpoints bool
+
+	// If set to true, check that the credentials of the remote state have the permissions to use its backend before
+	// running terraform, so that missing permissions fail fast rather than after a long init
+	PreflightChecks bool
+
 	// If set to true, continue running *-all commands even if a dependency has errors. This is mostly useful for 'output-all <some_variable>'. See https://github.com/gruntwork-io/terragrunt/issues/193
 	IgnoreDependencyErrors bool
 
@@ -155,6 +309,52 @@ type TerragruntOptions struct {
 	// Parallelism limits the number of commands to run concurrently during *-all commands
 	Parallelism int
 
+	// DependencyFetchParallelism limits the number of dependency blocks of a module whose outputs are fetched
+	// concurrently
+	DependencyFetchParallelism int
+
+	// The number of seconds the outputs of dependencies are cached on disk for, so that they're reused by later runs. If
+	// 0, the outputs are only cached in memory for the duration of the run.
+	DependencyOutputCacheTTL int
+
+	// If set to true, read the outputs of dependencies straight from the state object in the remote state backend, when
+	// the backend supports it, rather than running terraform init and output
+	FetchDependencyOutputFromState bool
+
+	// If set to true, don't check for other modules depending on a module before destroying it
+	NoDestroyDependenciesCheck bool
+
+	// The folder in which to look for the modules depending on a module before destroying it. Defaults to the parent
+	// folder of the module.
+	DestroyDependentsSearchDir string
+
+	// If set to true, don't prefix each line of the output of the modules run by *-all commands with the module path
+	NoOutputPrefix bool
+
+	// If set, the directory of the provider mirror to populate and use when running providers lock, so that the
+	// providers are shared by all modules
+	ProvidersLockMirrorDir string
+
+	// If set to true, run a provider cache server that all the terraform processes install their providers through, so
+	// that each provider is downloaded only once
+	ProviderCache bool
+
+	// The directory in which the provider cache server caches the providers
+	ProviderCacheDir string
+
+	// If set to true, init only downloads the Terraform sources, modules and providers, without configuring the
+	// backends or reading the outputs of the dependencies, e.g. to warm up the caches ahead of time
+	PrefetchOnly bool
+
+	// If set to true, run the command through the Terragrunt daemon listening on DaemonSocket, if there's one
+	Daemon bool
+
+	// The path of the unix socket the Terragrunt daemon listens on
+	DaemonSocket string
+
+	// How stdin is connected to terraform and hooks. One of INPUT_MODES.
+	InputMode string
+
 	// Enable check mode, by default it's disabled.
 	Check bool
 
@@ -172,9 +372,168 @@ type TerragruntOptions struct {
 	// root-cause issues.
 	Debug bool
 
+	// If set, the files to write a CPU profile, a heap profile and an execution trace of Terragrunt itself to, in the
+	// formats read by go tool pprof and go tool trace
+	CPUProfile string
+	MemProfile string
+	TraceFile  string
+
+	// The OTLP/HTTP endpoint to export OpenTelemetry traces of Terragrunt to, if set via
+	// --terragrunt-telemetry-endpoint. Otherwise, the standard OTEL_EXPORTER_OTLP_* env vars are used.
+	TelemetryEndpoint string
+
+	// The span of the operation currently being run, which the spans of the operations it runs are children of. This is
+	// nil when tracing is disabled.
+	TelemetrySpan *telemetry.Span
+
+	// The StatsD server or Prometheus Pushgateway to send metrics about the units to, as set via
+	// --terragrunt-metrics-endpoint
+	MetricsEndpoint string
+
+	// The Datadog site or EventBridge event bus to send the applies and destroys to as events, as set via
+	// --terragrunt-events-endpoint
+	EventsEndpoint string
+
+	// The recorder of the metrics of the units the command runs, and the metrics of the unit currently being run. These
+	// are nil when metrics are disabled.
+	Metrics     *metrics.Recorder
+	UnitMetrics *metrics.Unit
+
+	// If set via --terragrunt-github-actions, print errors as GitHub Actions annotations and write a summary of the units
+	// to the job summary
+	GitHubActions bool
+
+	// The dir to write the GitLab terraform reports of the plans of the units to, as set via
+	// --terragrunt-gitlab-report-dir
+	GitLabReportDir string
+
+	// The file to write a JUnit XML report of the units to, as set via --terragrunt-report-junit
+	JUnitReportPath string
+
+	// The Atlantis workflow the projects of the config written by generate-atlantis-config run, as set via
+	// --terragrunt-atlantis-workflow
+	AtlantisWorkflow string
+
+	// The file to write the findings of validate-inputs to as a SARIF log, as set via --terragrunt-sarif-output
+	SarifOutput string
+
+	// The report the findings of validate-inputs are added to. This is nil when SARIF output is disabled.
+	Sarif *sarif.Report
+
+	// The notifier of the webhooks of the notification blocks of the units, which is set for the whole run, so that the
+	// units of run-all share it. This is nil when no notifications are configured, or the run hasn't started yet.
+	Notifier *notify.Notifier
+
+	// The Rego policies the config and plans of the units are evaluated against before terraform runs, as set via
+	// --terragrunt-policy. Each is a local file or dir, or a go-getter URL of a bundle.
+	Policies []string
+
+	// Whether to estimate the cost of the plans of the units with Infracost, as set via --terragrunt-infracost, or
+	// implied by --terragrunt-infracost-report
+	Infracost bool
+
+	// The file to write the costs of the units and their total to, as set via --terragrunt-infracost-report
+	InfracostReportPath string
+
+	// The report the cost estimates of the units are added to. This is nil when Infracost is disabled.
+	CostReport *infracost.Report
+
+	// The file to write the drift status of the units to, as set via --terragrunt-drift-report
+	DriftReportPath string
+
+	// The report the drift status of the units is added to. This is nil when drift detection is disabled.
+	DriftReport *drift.Report
+
+	// The key template of the location the plans of the units, and their JSON, are uploaded to, and the reviewed plans
+	// are applied from, as set via --terragrunt-plan-artifact-url
+	PlanArtifactUrl string
+
+	// The ID of the run the plan artifacts are uploaded under, as set via --terragrunt-plan-artifact-run-id, or else
+	// taken from the env vars of the CI system
+	PlanArtifactRunId string
+
+	// The dir the paths of the units in the keys of the plan artifacts are relative to, usually the root of the repo
+	PlanArtifactRootDir string
+
+	// The file to write the plans of the units to as a Markdown document, as set via --terragrunt-plan-markdown
+	PlanMarkdownPath string
+
+	// The Markdown document the plans of the units are added to. This is nil when the document is disabled.
+	PlanMarkdown *planmarkdown.Report
+
+	// The command, with its args, the output of plan is piped through before it's displayed, as set via
+	// --terragrunt-plan-renderer
+	PlanRenderer string
+
+	// The results the issues tflint finds in the units are added to. This is nil when the command isn't tflint.
+	TflintResults *tflint.Results
+
+	// The file to write the documentation generate-docs generates of all the units to, as set via
+	// --terragrunt-docs-inventory
+	DocsInventoryPath string
+
+	// The inventory the documentation of the units is added to. This is nil when each unit gets its own MODULE.md.
+	DocsInventory *moduledocs.Inventory
+
+	// Whether to run plan, apply and destroy as runs of the Terraform Cloud workspace of the remote_state block of the
+	// units, as set via --terragrunt-tfc-run
+	TFCRun bool
+
+	// Whether to fetch git sources with a depth of 1, rather than with their whole history, as set via
+	// --terragrunt-shallow-clone
+	ShallowClone bool
+
+	// Whether to check out only the folder of the module of git sources, i.e., the part of the source after the //, as
+	// set via --terragrunt-sparse-checkout
+	SparseCheckout bool
+
+	// The git credential helper to fetch private git sources with, as set via --terragrunt-git-credential-helper
+	GitCredentialHelper string
+
+	// The path of the netrc file, with references to env vars, to fetch private git sources with, as set via
+	// --terragrunt-git-netrc-template
+	GitNetrcTemplate string
+
+	// The GitHub App whose installation token private git sources are fetched with, as set via
+	// --terragrunt-github-app-id, --terragrunt-github-app-installation-id and --terragrunt-github-app-private-key. The
+	// private key is the path of the PEM file, or its contents.
+	GitHubAppID             string
+	GitHubAppInstallationID string
+	GitHubAppPrivateKey     string
+
 	// Attributes to override in AWS provider nested within modules as part of the aws-provider-patch command. See that
 	// command for more info.
 	AwsProviderPatchOverrides map[string]string
+
+	// The download dir and terraform binary set in the user or system wide defaults file. Unlike the other settings
+	// in that file, these can also be set in the Terragrunt configuration, which takes precedence, so they are only
+	// applied once the configuration has been read. The download dir can also be set via the
+	// TERRAGRUNT_DEFAULT_DOWNLOAD_DIR env var, and may be a template.
+	DefaultsDownloadDir   string
+	DefaultsTerraformPath string
+
+	// Files whose readers should be included when running *-all commands. A module is included if its configuration
+	// reads any of these files, e.g. via include, read_terragrunt_config or mark_as_read.
+	QueueIncludeUnitsReading []string
+
+	// If set, *-all commands only run the modules affected by the files changed since this git ref
+	ChangedSince string
+
+	// If true, *-all commands also run the modules that depend on the modules affected by the changed files
+	IncludeChangedDependents bool
+
+	// If set, records every file read while parsing the Terragrunt configuration. This is a pointer so that it's
+	// shared with the clones made while parsing included and read configurations.
+	FilesRead *FilesRead
+
+	// Caches the parsed Terragrunt configs, so that the configs read by many modules, such as the included parent
+	// configs and the configs of dependencies, are only parsed once per run. This is a pointer so that it's shared
+	// with all the clones. If nil, configs are parsed every time they're read.
+	ConfigCache *ConfigCache
+
+	// Caches the parsed Terragrunt config files beyond the run, as they're only reused while the files are unchanged.
+	// Only set when running in the daemon, which keeps it between the commands it runs. Not used if ConfigCache is nil.
+	PersistentConfigCache *ConfigCache
 }
 
 // Create a new TerragruntOptions object with reasonable defaults for real usage
@@ -189,9 +548,11 @@ func NewTerragruntOptions(terragruntConfigPath string) (*TerragruntOptions, erro
 	return &TerragruntOptions{
 		TerragruntConfigPath:        terragruntConfigPath,
 		TerraformPath:               TERRAFORM_DEFAULT_PATH,
+		TerraformImplementation:     UnknownImpl,
 		OriginalTerraformCommand:    "",
 		TerraformCommand:            "",
 		AutoInit:                    true,
+		CopyLockFile:                true,
 		NonInteractive:              false,
 		TerraformCliArgs:            []string{},
 		WorkingDir:                  workingDir,
@@ -200,7 +561,12 @@ func NewTerragruntOptions(terragruntConfigPath string) (*TerragruntOptions, erro
 		Env:                         map[string]string{},
 		Source:                      "",
 		SourceMap:                   map[string]string{},
+		SourceMirrors:               map[string]string{},
+		SourceMirrorHeaders:         map[string]string{},
+		AwsEndpoints:                map[string]string{},
 		SourceUpdate:                false,
+		SymlinkLocalSource:          false,
+		SourceCacheMaxAge:           DEFAULT_SOURCE_CACHE_MAX_AGE,
 		DownloadDir:                 downloadDir,
 		IamAssumeRoleDuration:       DEFAULT_IAM_ASSUME_ROLE_DURATION,
 		IgnoreDependencyErrors:      false,
@@ -217,8 +583,12 @@ func NewTerragruntOptions(terragruntConfigPath string) (*TerragruntOptions, erro
 		ExcludeDirs:                 []string{},
 		IncludeDirs:                 []string{},
 		StrictInclude:               false,
+		QueueIncludeUnitsReading:    []string{},
 		Parallelism:                 DEFAULT_PARALLELISM,
+		DependencyFetchParallelism:  DEFAULT_DEPENDENCY_FETCH_PARALLELISM,
+		InputMode:                   INPUT_MODE_AUTO,
 		Check:                       false,
+		ConfigCache:                 NewConfigCache(),
 		RunTerragrunt: func(terragruntOptions *TerragruntOptions) error {
 			return errors.WithStackTrace(RunTerragruntCommandNotSet)
 		},
@@ -261,47 +631,146 @@ func (terragruntOptions *TerragruntOptions) Clone(terragruntConfigPath string) *
 	// during xxx-all commands (e.g., apply-all, plan-all). See https://github.com/gruntwork-io/terragrunt/issues/367
 	// for more info.
 	return &TerragruntOptions{
-		TerragruntConfigPath:         terragruntConfigPath,
-		OriginalTerragruntConfigPath: terragruntOptions.OriginalTerragruntConfigPath,
-		TerraformPath:                terragruntOptions.TerraformPath,
-		OriginalTerraformCommand:     terragruntOptions.OriginalTerraformCommand,
-		TerraformCommand:             terragruntOptions.TerraformCommand,
-		TerraformVersion:             terragruntOptions.TerraformVersion,
-		TerragruntVersion:            terragruntOptions.TerragruntVersion,
-		AutoInit:                     terragruntOptions.AutoInit,
-		NonInteractive:               terragruntOptions.NonInteractive,
-		TerraformCliArgs:             util.CloneStringList(terragruntOptions.TerraformCliArgs),
-		WorkingDir:                   workingDir,
-		Logger:                       util.CreateLogEntryWithWriter(terragruntOptions.ErrWriter, workingDir, terragruntOptions.LogLevel),
-		LogLevel:                     terragruntOptions.LogLevel,
-		Env:                          util.CloneStringMap(terragruntOptions.Env),
-		Source:                       terragruntOptions.Source,
-		SourceMap:                    terragruntOptions.SourceMap,
-		SourceUpdate:                 terragruntOptions.SourceUpdate,
-		DownloadDir:                  terragruntOptions.DownloadDir,
-		Debug:                        terragruntOptions.Debug,
-		IamRole:                      terragruntOptions.IamRole,
-		IamAssumeRoleDuration:        terragruntOptions.IamAssumeRoleDuration,
-		IgnoreDependencyErrors:       terragruntOptions.IgnoreDependencyErrors,
-		IgnoreDependencyOrder:        terragruntOptions.IgnoreDependencyOrder,
-		IgnoreExternalDependencies:   terragruntOptions.IgnoreExternalDependencies,
-		IncludeExternalDependencies:  terragruntOptions.IncludeExternalDependencies,
-		Writer:                       terragruntOptions.Writer,
-		ErrWriter:                    terragruntOptions.ErrWriter,
-		MaxFoldersToCheck:            terragruntOptions.MaxFoldersToCheck,
-		AutoRetry:                    terragruntOptions.AutoRetry,
-		RetryMaxAttempts:             terragruntOptions.RetryMaxAttempts,
-		RetrySleepIntervalSec:        terragruntOptions.RetrySleepIntervalSec,
-		RetryableErrors:              util.CloneStringList(terragruntOptions.RetryableErrors),
-		ExcludeDirs:                  terragruntOptions.ExcludeDirs,
-		IncludeDirs:                  terragruntOptions.IncludeDirs,
-		Parallelism:                  terragruntOptions.Parallelism,
-		StrictInclude:                terragruntOptions.StrictInclude,
-		RunTerragrunt:                terragruntOptions.RunTerragrunt,
-		AwsProviderPatchOverrides:    terragruntOptions.AwsProviderPatchOverrides,
+		TerragruntConfigPath:            terragruntConfigPath,
+		OriginalTerragruntConfigPath:    terragruntOptions.OriginalTerragruntConfigPath,
+		TerraformPath:                   terragruntOptions.TerraformPath,
+		TerraformPathExplicit:           terragruntOptions.TerraformPathExplicit,
+		OriginalTerraformCommand:        terragruntOptions.OriginalTerraformCommand,
+		TerraformCommand:                terragruntOptions.TerraformCommand,
+		TerraformVersion:                terragruntOptions.TerraformVersion,
+		TerraformImplementation:         terragruntOptions.TerraformImplementation,
+		TerragruntVersion:               terragruntOptions.TerragruntVersion,
+		AutoInit:                        terragruntOptions.AutoInit,
+		CopyLockFile:                    terragruntOptions.CopyLockFile,
+		NonInteractive:                  terragruntOptions.NonInteractive,
+		TerraformCliArgs:                util.CloneStringList(terragruntOptions.TerraformCliArgs),
+		WorkingDir:                      workingDir,
+		Logger:                          util.CreateLogEntryWithWriter(terragruntOptions.ErrWriter, workingDir, terragruntOptions.LogLevel),
+		LogLevel:                        terragruntOptions.LogLevel,
+		Env:                             util.CloneStringMap(terragruntOptions.Env),
+		Source:                          terragruntOptions.Source,
+		SourceMap:                       terragruntOptions.SourceMap,
+		SourceMirrors:                   terragruntOptions.SourceMirrors,
+		SourceMirrorHeaders:             terragruntOptions.SourceMirrorHeaders,
+		SourceSigV4Service:              terragruntOptions.SourceSigV4Service,
+		SourceUpdate:                    terragruntOptions.SourceUpdate,
+		SymlinkLocalSource:              terragruntOptions.SymlinkLocalSource,
+		SourceCache:                     terragruntOptions.SourceCache,
+		SourceCacheDir:                  terragruntOptions.SourceCacheDir,
+		SourceCacheMaxAge:               terragruntOptions.SourceCacheMaxAge,
+		DownloadDir:                     terragruntOptions.DownloadDir,
+		SourceDownloadDir:               terragruntOptions.SourceDownloadDir,
+		DownloadDirTemplate:             terragruntOptions.DownloadDirTemplate,
+		Debug:                           terragruntOptions.Debug,
+		CPUProfile:                      terragruntOptions.CPUProfile,
+		MemProfile:                      terragruntOptions.MemProfile,
+		TraceFile:                       terragruntOptions.TraceFile,
+		TelemetryEndpoint:               terragruntOptions.TelemetryEndpoint,
+		TelemetrySpan:                   terragruntOptions.TelemetrySpan,
+		MetricsEndpoint:                 terragruntOptions.MetricsEndpoint,
+		EventsEndpoint:                  terragruntOptions.EventsEndpoint,
+		Metrics:                         terragruntOptions.Metrics,
+		UnitMetrics:                     terragruntOptions.UnitMetrics,
+		GitHubActions:                   terragruntOptions.GitHubActions,
+		GitLabReportDir:                 terragruntOptions.GitLabReportDir,
+		JUnitReportPath:                 terragruntOptions.JUnitReportPath,
+		AtlantisWorkflow:                terragruntOptions.AtlantisWorkflow,
+		SarifOutput:                     terragruntOptions.SarifOutput,
+		Sarif:                           terragruntOptions.Sarif,
+		Notifier:                        terragruntOptions.Notifier,
+		Policies:                        terragruntOptions.Policies,
+		Infracost:                       terragruntOptions.Infracost,
+		InfracostReportPath:             terragruntOptions.InfracostReportPath,
+		CostReport:                      terragruntOptions.CostReport,
+		DriftReportPath:                 terragruntOptions.DriftReportPath,
+		DriftReport:                     terragruntOptions.DriftReport,
+		PlanArtifactUrl:                 terragruntOptions.PlanArtifactUrl,
+		PlanArtifactRunId:               terragruntOptions.PlanArtifactRunId,
+		PlanArtifactRootDir:             terragruntOptions.PlanArtifactRootDir,
+		PlanMarkdownPath:                terragruntOptions.PlanMarkdownPath,
+		PlanMarkdown:                    terragruntOptions.PlanMarkdown,
+		PlanRenderer:                    terragruntOptions.PlanRenderer,
+		TflintResults:                   terragruntOptions.TflintResults,
+		DocsInventoryPath:               terragruntOptions.DocsInventoryPath,
+		DocsInventory:                   terragruntOptions.DocsInventory,
+		TFCRun:                          terragruntOptions.TFCRun,
+		ShallowClone:                    terragruntOptions.ShallowClone,
+		SparseCheckout:                  terragruntOptions.SparseCheckout,
+		GitCredentialHelper:             terragruntOptions.GitCredentialHelper,
+		GitNetrcTemplate:                terragruntOptions.GitNetrcTemplate,
+		GitHubAppID:                     terragruntOptions.GitHubAppID,
+		GitHubAppInstallationID:         terragruntOptions.GitHubAppInstallationID,
+		GitHubAppPrivateKey:             terragruntOptions.GitHubAppPrivateKey,
+		IamRole:                         terragruntOptions.IamRole,
+		IamRoleChain:                    terragruntOptions.IamRoleChain,
+		IamAssumeRoleDuration:           terragruntOptions.IamAssumeRoleDuration,
+		IamAssumeRoleExternalId:         terragruntOptions.IamAssumeRoleExternalId,
+		IamAssumeRoleMfaSerial:          terragruntOptions.IamAssumeRoleMfaSerial,
+		IamAssumeRoleMfaToken:           terragruntOptions.IamAssumeRoleMfaToken,
+		IamWebIdentityToken:             terragruntOptions.IamWebIdentityToken,
+		IamWebIdentitySessionName:       terragruntOptions.IamWebIdentitySessionName,
+		IamWebIdentityRegion:            terragruntOptions.IamWebIdentityRegion,
+		IamWebIdentityStsEndpoint:       terragruntOptions.IamWebIdentityStsEndpoint,
+		IamRoleSourceEnv:                terragruntOptions.IamRoleSourceEnv,
+		AwsProfile:                      terragruntOptions.AwsProfile,
+		AwsSsoLogin:                     terragruntOptions.AwsSsoLogin,
+		AwsKeyringCache:                 terragruntOptions.AwsKeyringCache,
+		AwsEndpoints:                    terragruntOptions.AwsEndpoints,
+		AwsStsRegionalEndpoints:         terragruntOptions.AwsStsRegionalEndpoints,
+		PreflightChecks:                 terragruntOptions.PreflightChecks,
+		GoogleImpersonateServiceAccount: terragruntOptions.GoogleImpersonateServiceAccount,
+		IgnoreDependencyErrors:          terragruntOptions.IgnoreDependencyErrors,
+		IgnoreDependencyOrder:           terragruntOptions.IgnoreDependencyOrder,
+		IgnoreExternalDependencies:      terragruntOptions.IgnoreExternalDependencies,
+		IncludeExternalDependencies:     terragruntOptions.IncludeExternalDependencies,
+		Writer:                          terragruntOptions.Writer,
+		ErrWriter:                       terragruntOptions.ErrWriter,
+		MaxFoldersToCheck:               terragruntOptions.MaxFoldersToCheck,
+		AutoRetry:                       terragruntOptions.AutoRetry,
+		RetryMaxAttempts:                terragruntOptions.RetryMaxAttempts,
+		RetrySleepIntervalSec:           terragruntOptions.RetrySleepIntervalSec,
+		RetryableErrors:                 util.CloneStringList(terragruntOptions.RetryableErrors),
+		ExcludeDirs:                     terragruntOptions.ExcludeDirs,
+		IncludeDirs:                     terragruntOptions.IncludeDirs,
+		Parallelism:                     terragruntOptions.Parallelism,
+		DependencyFetchParallelism:      terragruntOptions.DependencyFetchParallelism,
+		DependencyOutputCacheTTL:        terragruntOptions.DependencyOutputCacheTTL,
+		FetchDependencyOutputFromState:  terragruntOptions.FetchDependencyOutputFromState,
+		StrictInclude:                   terragruntOptions.StrictInclude,
+		InputMode:                       terragruntOptions.InputMode,
+		NoDestroyDependenciesCheck:      terragruntOptions.NoDestroyDependenciesCheck,
+		DestroyDependentsSearchDir:      terragruntOptions.DestroyDependentsSearchDir,
+		NoOutputPrefix:                  terragruntOptions.NoOutputPrefix,
+		ProvidersLockMirrorDir:          terragruntOptions.ProvidersLockMirrorDir,
+		ProviderCache:                   terragruntOptions.ProviderCache,
+		ProviderCacheDir:                terragruntOptions.ProviderCacheDir,
+		PrefetchOnly:                    terragruntOptions.PrefetchOnly,
+		Daemon:                          terragruntOptions.Daemon,
+		DaemonSocket:                    terragruntOptions.DaemonSocket,
+		RunTerragrunt:                   terragruntOptions.RunTerragrunt,
+		AwsProviderPatchOverrides:       terragruntOptions.AwsProviderPatchOverrides,
+		DefaultsDownloadDir:             terragruntOptions.DefaultsDownloadDir,
+		DefaultsTerraformPath:           terragruntOptions.DefaultsTerraformPath,
+		QueueIncludeUnitsReading:        terragruntOptions.QueueIncludeUnitsReading,
+		ChangedSince:                    terragruntOptions.ChangedSince,
+		IncludeChangedDependents:        terragruntOptions.IncludeChangedDependents,
+		FilesRead:                       terragruntOptions.FilesRead,
+		ConfigCache:                     terragruntOptions.ConfigCache,
+		PersistentConfigCache:           terragruntOptions.PersistentConfigCache,
 	}
 }
 
+// DiscoveryParallelism returns the number of folders to scan and configs to parse concurrently while discovering the
+// modules of *-all commands. This is never more than the parallelism of the commands, so that setting
+// --terragrunt-parallelism to 1 makes Terragrunt do everything one module at a time.
+func (terragruntOptions *TerragruntOptions) DiscoveryParallelism() int {
+	parallelism := util.Min(terragruntOptions.Parallelism, DEFAULT_DISCOVERY_PARALLELISM)
+	if parallelism < 1 {
+		return 1
+	}
+	return parallelism
+}
+
 // Inserts the given argsToInsert after the terraform command argument, but before the remaining args
 func (terragruntOptions *TerragruntOptions) InsertTerraformCliArgs(argsToInsert ...string) {
 
@@ -344,6 +813,95 @@ func (terragruntOptions *TerragruntOptions) DataDir() string {
 	return util.JoinPath(terragruntOptions.WorkingDir, tfDataDir)
 }
 
+// MarkFileAsRead records that the given file was read while parsing the Terragrunt configuration. This is a no-op
+// unless FilesRead has been set.
+func (terragruntOptions *TerragruntOptions) MarkFileAsRead(path string) {
+	if terragruntOptions.FilesRead == nil {
+		return
+	}
+	terragruntOptions.FilesRead.Add(path)
+}
+
+// IamRoleHop is one of the roles of IamRoleChain, with the name and the duration, in seconds, of its session. An empty
+// session name means a generated one, and a zero duration means IamAssumeRoleDuration.
+type IamRoleHop struct {
+	RoleArn         string
+	SessionName     string
+	DurationSeconds int64
+}
+
+// FilesRead is a concurrency safe set of the (canonical) paths of the files read while parsing a Terragrunt
+// configuration.
+type FilesRead struct {
+	lock  sync.Mutex
+	paths map[string]bool
+}
+
+// Add the given path to the set. The path is converted to its canonical form, so that it can be compared to other
+// paths regardless of how they were referenced in the configuration.
+func (filesRead *FilesRead) Add(path string) {
+	canonicalPath, err := util.CanonicalPath(path, ".")
+	if err != nil {
+		canonicalPath = path
+	}
+
+	filesRead.lock.Lock()
+	defer filesRead.lock.Unlock()
+
+	if filesRead.paths == nil {
+		filesRead.paths = map[string]bool{}
+	}
+	filesRead.paths[canonicalPath] = true
+}
+
+// Contains returns true if the given canonical path has been read
+func (filesRead *FilesRead) Contains(canonicalPath string) bool {
+	filesRead.lock.Lock()
+	defer filesRead.lock.Unlock()
+
+	return filesRead.paths[canonicalPath]
+}
+
+// Paths returns the sorted list of files that have been read
+func (filesRead *FilesRead) Paths() []string {
+	filesRead.lock.Lock()
+	defer filesRead.lock.Unlock()
+
+	paths := []string{}
+	for path := range filesRead.paths {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+// ConfigCache is a concurrency safe cache of the results of parsing Terragrunt configs. The values are opaque to this
+// package, so that the config package can store whatever it needs to avoid re-parsing a config.
+type ConfigCache struct {
+	entries sync.Map
+}
+
+// NewConfigCache creates an empty ConfigCache
+func NewConfigCache() *ConfigCache {
+	return &ConfigCache{}
+}
+
+// Load returns the value stored under the given key, if any
+func (cache *ConfigCache) Load(key string) (interface{}, bool) {
+	return cache.entries.Load(key)
+}
+
+// Store stores the given value under the given key, replacing any existing value
+func (cache *ConfigCache) Store(key string, value interface{}) {
+	cache.entries.Store(key, value)
+}
+
+// LoadOrStore returns the value stored under the given key, if any. Otherwise, it stores and returns the given value.
+func (cache *ConfigCache) LoadOrStore(key string, value interface{}) interface{} {
+	actual, _ := cache.entries.LoadOrStore(key, value)
+	return actual
+}
+
 // Custom error types
 
 var RunTerragruntCommandNotSet = fmt.Errorf("The RunTerragrunt option has not been set on this TerragruntOptions object")
@@ -0,0 +1,260 @@
+// Package planartifact uploads the plans the plan command saves, and their JSON, to object storage, i.e. S3, GCS or a
+// shared folder, under a key per run and unit, so that the apply stage of a pipeline, which usually runs on a different
+// runner, can download and apply exactly the plans that were reviewed.
+package planartifact
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"cloud.google.com/go/storage"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/s3"
+
+	"github.com/gruntwork-io/terragrunt/aws_helper"
+	"github.com/gruntwork-io/terragrunt/errors"
+	"github.com/gruntwork-io/terragrunt/options"
+	"github.com/gruntwork-io/terragrunt/remote"
+)
+
+// The schemes of the locations the artifacts can be uploaded to
+const (
+	SchemeS3   = "s3"
+	SchemeGCS  = "gs"
+	SchemeFile = "file"
+)
+
+// The placeholders of the key template of a location: the ID of the run, and the path of the unit
+const (
+	RunIdPlaceholder = "{run_id}"
+	UnitPlaceholder  = "{unit}"
+)
+
+// The names of the artifacts of a unit under its key: the plan, as saved by plan -out, and its JSON, as printed by
+// terraform show -json
+const (
+	PLAN_FILE      = "tfplan"
+	PLAN_JSON_FILE = "tfplan.json"
+)
+
+// Location is where the artifacts of the units are uploaded to, e.g. s3://acme-plans/{run_id}/{unit}
+type Location struct {
+	Scheme string
+	// The bucket of S3 and GCS locations. Empty for file locations.
+	Bucket string
+	// The template of the key the artifacts of a unit are uploaded under, which is a path for file locations
+	KeyTemplate string
+	// The region of the bucket of S3 locations, as set via the region query param. Defaults to the region of the AWS
+	// env vars or profile.
+	Region string
+}
+
+// ParseLocation parses the given URL, e.g. s3://acme-plans/{run_id}/{unit}?region=eu-west-1, gs://acme-plans/plans or
+// file:///mnt/plans, into a location. When the path of the URL has neither placeholder, /{run_id}/{unit} is appended to
+// it.
+func ParseLocation(rawUrl string) (*Location, error) {
+	// The placeholders aren't valid in URLs, so they're swapped out while parsing
+	escaped := strings.NewReplacer(RunIdPlaceholder, "__run_id__", UnitPlaceholder, "__unit__").Replace(rawUrl)
+	locationUrl, err := url.Parse(escaped)
+	if err != nil {
+		return nil, errors.WithStackTrace(InvalidLocation(rawUrl))
+	}
+
+	location := &Location{Scheme: locationUrl.Scheme, Bucket: locationUrl.Host, Region: locationUrl.Query().Get("region")}
+	keyTemplate := strings.NewReplacer("__run_id__", RunIdPlaceholder, "__unit__", UnitPlaceholder).Replace(locationUrl.Path)
+	switch location.Scheme {
+	case SchemeS3, SchemeGCS:
+		if location.Bucket == "" {
+			return nil, errors.WithStackTrace(InvalidLocation(rawUrl))
+		}
+		keyTemplate = strings.Trim(keyTemplate, "/")
+	case SchemeFile:
+		if location.Bucket != "" || keyTemplate == "" {
+			return nil, errors.WithStackTrace(InvalidLocation(rawUrl))
+		}
+		keyTemplate = strings.TrimRight(keyTemplate, "/")
+	default:
+		return nil, errors.WithStackTrace(InvalidLocation(rawUrl))
+	}
+
+	if !strings.Contains(keyTemplate, RunIdPlaceholder) && !strings.Contains(keyTemplate, UnitPlaceholder) {
+		keyTemplate = strings.TrimLeft(keyTemplate+"/"+RunIdPlaceholder+"/"+UnitPlaceholder, "/")
+		if location.Scheme == SchemeFile && !strings.HasPrefix(keyTemplate, "/") {
+			keyTemplate = "/" + keyTemplate
+		}
+	}
+	location.KeyTemplate = keyTemplate
+	return location, nil
+}
+
+// Key returns the key the artifacts of the unit with the given path, relative to the root of the repo, are uploaded
+// under in the run with the given ID
+func (location *Location) Key(runId string, unit string) (string, error) {
+	if runId == "" && strings.Contains(location.KeyTemplate, RunIdPlaceholder) {
+		return "", errors.WithStackTrace(MissingRunId(location.String()))
+	}
+	unit = strings.Trim(filepath.ToSlash(unit), "/")
+	if unit == "" || unit == "." {
+		unit = "_root"
+	}
+	return strings.NewReplacer(RunIdPlaceholder, runId, UnitPlaceholder, unit).Replace(location.KeyTemplate), nil
+}
+
+func (location *Location) String() string {
+	if location.Scheme == SchemeFile {
+		return SchemeFile + "://" + location.KeyTemplate
+	}
+	return location.Scheme + "://" + location.Bucket + "/" + location.KeyTemplate
+}
+
+// Store uploads artifacts to, and downloads them from, a location
+type Store interface {
+	// Upload the given local file to the given key
+	Upload(localPath string, key string) error
+	// Download the given key to the given local file. Returns an ArtifactNotFound error if there's nothing at the key.
+	Download(key string, localPath string) error
+}
+
+// NewStore returns the store of the given location, which authenticates like the remote state backends do: with the
+// AWS env vars or profile for S3, and with the Google env vars or application default credentials for GCS
+func NewStore(location *Location, terragruntOptions *options.TerragruntOptions) (Store, error) {
+	switch location.Scheme {
+	case SchemeS3:
+		client, err := remote.CreateS3Client(&aws_helper.AwsSessionConfig{Region: location.Region}, terragruntOptions)
+		if err != nil {
+			return nil, err
+		}
+		return &s3Store{client: client, bucket: location.Bucket}, nil
+	case SchemeGCS:
+		client, err := remote.CreateGCSClient(remote.RemoteStateConfigGCS{ImpersonateServiceAccount: terragruntOptions.GoogleImpersonateServiceAccount})
+		if err != nil {
+			return nil, err
+		}
+		return &gcsStore{client: client, bucket: location.Bucket}, nil
+	default:
+		return fileStore{}, nil
+	}
+}
+
+type s3Store struct {
+	client *s3.S3
+	bucket string
+}
+
+func (store *s3Store) Upload(localPath string, key string) error {
+	file, err := os.Open(localPath)
+	if err != nil {
+		return errors.WithStackTrace(err)
+	}
+	defer file.Close()
+
+	_, err = store.client.PutObject(&s3.PutObjectInput{Bucket: aws.String(store.bucket), Key: aws.String(key), Body: file})
+	return errors.WithStackTrace(err)
+}
+
+func (store *s3Store) Download(key string, localPath string) error {
+	output, err := store.client.GetObject(&s3.GetObjectInput{Bucket: aws.String(store.bucket), Key: aws.String(key)})
+	if awsErr, isAwsErr := err.(awserr.Error); isAwsErr && awsErr.Code() == s3.ErrCodeNoSuchKey {
+		return errors.WithStackTrace(ArtifactNotFound(fmt.Sprintf("s3://%s/%s", store.bucket, key)))
+	}
+	if err != nil {
+		return errors.WithStackTrace(err)
+	}
+	defer output.Body.Close()
+	return writeFile(localPath, output.Body)
+}
+
+type gcsStore struct {
+	client *storage.Client
+	bucket string
+}
+
+func (store *gcsStore) Upload(localPath string, key string) error {
+	file, err := os.Open(localPath)
+	if err != nil {
+		return errors.WithStackTrace(err)
+	}
+	defer file.Close()
+
+	writer := store.client.Bucket(store.bucket).Object(key).NewWriter(context.Background())
+	if _, err := io.Copy(writer, file); err != nil {
+		writer.Close()
+		return errors.WithStackTrace(err)
+	}
+	return errors.WithStackTrace(writer.Close())
+}
+
+func (store *gcsStore) Download(key string, localPath string) error {
+	reader, err := store.client.Bucket(store.bucket).Object(key).NewReader(context.Background())
+	if err == storage.ErrObjectNotExist {
+		return errors.WithStackTrace(ArtifactNotFound(fmt.Sprintf("gs://%s/%s", store.bucket, key)))
+	}
+	if err != nil {
+		return errors.WithStackTrace(err)
+	}
+	defer reader.Close()
+	return writeFile(localPath, reader)
+}
+
+// A store in a folder, e.g. a volume shared by the runners, where the keys are paths
+type fileStore struct{}
+
+func (store fileStore) Upload(localPath string, key string) error {
+	file, err := os.Open(localPath)
+	if err != nil {
+		return errors.WithStackTrace(err)
+	}
+	defer file.Close()
+	if err := os.MkdirAll(filepath.Dir(key), os.ModePerm); err != nil {
+		return errors.WithStackTrace(err)
+	}
+	return writeFile(key, file)
+}
+
+func (store fileStore) Download(key string, localPath string) error {
+	file, err := os.Open(key)
+	if os.IsNotExist(err) {
+		return errors.WithStackTrace(ArtifactNotFound(SchemeFile + "://" + key))
+	}
+	if err != nil {
+		return errors.WithStackTrace(err)
+	}
+	defer file.Close()
+	return writeFile(localPath, file)
+}
+
+// Write the contents of the given reader to the given file, replacing it if it exists
+func writeFile(path string, reader io.Reader) error {
+	contents, err := ioutil.ReadAll(reader)
+	if err != nil {
+		return errors.WithStackTrace(err)
+	}
+	return errors.WithStackTrace(ioutil.WriteFile(path, contents, 0600))
+}
+
+// Custom error types
+
+type InvalidLocation string
+
+func (location InvalidLocation) Error() string {
+	return fmt.Sprintf("%s is not a valid location for plan artifacts. Expected s3://<bucket>/<key template>, gs://<bucket>/<key template> or file://<path template>, where the templates may contain %s and %s.", string(location), RunIdPlaceholder, UnitPlaceholder)
+}
+
+type MissingRunId string
+
+func (location MissingRunId) Error() string {
+	return fmt.Sprintf("The key of the plan artifacts at %s depends on the ID of the run, but it is unknown. Set it via --terragrunt-plan-artifact-run-id.", string(location))
+}
+
+type ArtifactNotFound string
+
+func (artifactUrl ArtifactNotFound) Error() string {
+	return fmt.Sprintf("Found no plan artifact at %s. Run plan with the same run ID first.", string(artifactUrl))
+}
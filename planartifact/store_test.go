@@ -0,0 +1,93 @@
+package planartifact
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/gruntwork-io/terragrunt/errors"
+)
+
+func TestParseLocation(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		url      string
+		expected Location
+	}{
+		{"s3://acme-plans/{run_id}/{unit}", Location{Scheme: SchemeS3, Bucket: "acme-plans", KeyTemplate: "{run_id}/{unit}"}},
+		{"s3://acme-plans/plans/{unit}/{run_id}?region=eu-west-1", Location{Scheme: SchemeS3, Bucket: "acme-plans", KeyTemplate: "plans/{unit}/{run_id}", Region: "eu-west-1"}},
+		{"gs://acme-plans/plans/", Location{Scheme: SchemeGCS, Bucket: "acme-plans", KeyTemplate: "plans/{run_id}/{unit}"}},
+		{"gs://acme-plans", Location{Scheme: SchemeGCS, Bucket: "acme-plans", KeyTemplate: "{run_id}/{unit}"}},
+		{"file:///mnt/plans", Location{Scheme: SchemeFile, KeyTemplate: "/mnt/plans/{run_id}/{unit}"}},
+	}
+
+	for _, testCase := range testCases {
+		location, err := ParseLocation(testCase.url)
+		require.NoError(t, err, testCase.url)
+		assert.Equal(t, testCase.expected, *location, testCase.url)
+	}
+
+	for _, invalidUrl := range []string{"acme-plans/{unit}", "s3:///plans", "file://plans", "https://example.com/plans"} {
+		_, err := ParseLocation(invalidUrl)
+		assert.IsType(t, InvalidLocation(""), errors.Unwrap(err), invalidUrl)
+	}
+}
+
+func TestLocationKey(t *testing.T) {
+	t.Parallel()
+
+	location, err := ParseLocation("s3://acme-plans/plans/{run_id}/{unit}")
+	require.NoError(t, err)
+
+	key, err := location.Key("1234", "live/prod/app")
+	require.NoError(t, err)
+	assert.Equal(t, "plans/1234/live/prod/app", key)
+
+	key, err = location.Key("1234", ".")
+	require.NoError(t, err)
+	assert.Equal(t, "plans/1234/_root", key)
+
+	_, err = location.Key("", "live/prod/app")
+	assert.Equal(t, MissingRunId("s3://acme-plans/plans/{run_id}/{unit}"), errors.Unwrap(err))
+
+	// Without the run ID in the key, the run ID isn't needed
+	location, err = ParseLocation("gs://acme-plans/latest/{unit}")
+	require.NoError(t, err)
+	key, err = location.Key("", "live/prod/app")
+	require.NoError(t, err)
+	assert.Equal(t, "latest/live/prod/app", key)
+}
+
+func TestFileStore(t *testing.T) {
+	t.Parallel()
+
+	dir, err := ioutil.TempDir("", "plan-artifacts")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	location, err := ParseLocation("file://" + filepath.ToSlash(filepath.Join(dir, "store")))
+	require.NoError(t, err)
+	store, err := NewStore(location, nil)
+	require.NoError(t, err)
+	key, err := location.Key("1234", "live/app")
+	require.NoError(t, err)
+
+	planPath := filepath.Join(dir, "tfplan")
+	require.NoError(t, ioutil.WriteFile(planPath, []byte("plan"), 0600))
+	require.NoError(t, store.Upload(planPath, key+"/"+PLAN_FILE))
+	assert.FileExists(t, filepath.Join(dir, "store", "1234", "live", "app", PLAN_FILE))
+
+	downloadPath := filepath.Join(dir, "downloaded.tfplan")
+	require.NoError(t, store.Download(key+"/"+PLAN_FILE, downloadPath))
+	contents, err := ioutil.ReadFile(downloadPath)
+	require.NoError(t, err)
+	assert.Equal(t, "plan", string(contents))
+
+	err = store.Download(key+"/"+PLAN_JSON_FILE, downloadPath)
+	assert.IsType(t, ArtifactNotFound(""), errors.Unwrap(err))
+}
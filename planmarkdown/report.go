@@ -0,0 +1,298 @@
+// Package planmarkdown renders the plans of the units a run-all plan plans as a Markdown document, with a collapsible
+// section per unit that changes, whose resource changes are in a diff code block, so that CI can post it as a comment on
+// the pull request that is planned.
+package planmarkdown
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/gruntwork-io/terragrunt/drift"
+	"github.com/gruntwork-io/terragrunt/errors"
+)
+
+// The maximum length of a comment on a GitHub pull request. The plans of the units are truncated to keep the document
+// under it.
+const MaxCommentLength = 65536
+
+// The line of the output of terraform show the resource changes of a plan start after
+const planChangesHeader = "Terraform will perform the following actions:"
+
+// The markers of the changes at the start of the lines of a plan, after their indentation, e.g. "  ~ resource", and the
+// ones diff code blocks highlight instead
+var (
+	changeMarkerRegexp = regexp.MustCompile(`^(\s*)(-/\+|\+/-|[-+~<=])(\s)`)
+	diffMarkers        = map[string]string{"+": "+", "-": "-", "~": "!", "-/+": "!", "+/-": "!", "<=": "#", "=": "#"}
+)
+
+// The note that replaces the end of a truncated plan
+const truncatedNote = "\n... (truncated, see the full plan in the logs of the run)\n"
+
+// Summary is the number of resources the plan of a unit adds, changes and destroys, like the Plan: line of terraform
+type Summary struct {
+	Add     int
+	Change  int
+	Destroy int
+}
+
+// Summarize the given changes of a plan. A replacement adds and destroys a resource.
+func Summarize(changes *drift.Changes) Summary {
+	summary := Summary{}
+	for _, resource := range changes.Resources {
+		for _, action := range resource.Actions {
+			switch action {
+			case "create":
+				summary.Add++
+			case "update":
+				summary.Change++
+			case "delete":
+				summary.Destroy++
+			}
+		}
+	}
+	return summary
+}
+
+func (summary Summary) String() string {
+	return fmt.Sprintf("%d to add, %d to change, %d to destroy", summary.Add, summary.Change, summary.Destroy)
+}
+
+// A unit of the document
+type unit struct {
+	Path       string
+	HasChanges bool
+	Summary    Summary
+	// The resource changes of the plan of the unit, as printed by terraform show, or the error the unit failed with
+	Plan  string
+	Error string
+}
+
+// Report collects the plans of the units of a run. All the methods of Report can be called on a nil report, which is
+// what is used when the Markdown document is disabled.
+type Report struct {
+	rootDir string
+
+	mutex sync.Mutex
+	units map[string]unit
+}
+
+// Create a report whose units are identified by their path relative to the given root dir
+func NewReport(rootDir string) *Report {
+	return &Report{rootDir: rootDir, units: map[string]unit{}}
+}
+
+// Add the plan of the unit in the given dir, with the given changes and text, as printed by terraform show, to the
+// report
+func (report *Report) Add(unitDir string, changes *drift.Changes, planText string) {
+	if report == nil {
+		return
+	}
+	report.set(unit{
+		Path:       report.unitPath(unitDir),
+		HasChanges: changes.HasDrift(),
+		Summary:    Summarize(changes),
+		Plan:       planChanges(planText),
+	})
+}
+
+// Add the error the unit in the given dir failed with to the report. An error overrides the plan of the unit, if any.
+func (report *Report) AddError(unitDir string, err error) {
+	if report == nil {
+		return
+	}
+	report.set(unit{Path: report.unitPath(unitDir), Error: err.Error()})
+}
+
+func (report *Report) set(newUnit unit) {
+	report.mutex.Lock()
+	defer report.mutex.Unlock()
+	if existing, exists := report.units[newUnit.Path]; exists && existing.Error != "" {
+		return
+	}
+	report.units[newUnit.Path] = newUnit
+}
+
+// Return the path of the given unit dir relative to the root dir of the report, with forward slashes
+func (report *Report) unitPath(unitDir string) string {
+	if relPath, err := filepath.Rel(report.rootDir, unitDir); err == nil {
+		unitDir = relPath
+	}
+	return filepath.ToSlash(unitDir)
+}
+
+// Markdown renders the plans added so far as a Markdown document: a summary table of the units that change, a
+// collapsible section with the diff of the plan of each of them, a collapsible section with the error of each unit that
+// failed, and the list of the units without changes. The document fits in the given length, e.g. MaxCommentLength: the
+// table takes at most half of it, and the list a quarter of what's left, with a note of the units left out of them, and
+// the plans are truncated to share the rest, with the sections that don't fit at all left out too.
+func (report *Report) Markdown(maxLength int) string {
+	if report == nil {
+		return ""
+	}
+
+	report.mutex.Lock()
+	changed, failed, unchanged := []unit{}, []unit{}, []string{}
+	for _, planned := range report.units {
+		switch {
+		case planned.Error != "":
+			failed = append(failed, planned)
+		case planned.HasChanges:
+			changed = append(changed, planned)
+		default:
+			unchanged = append(unchanged, planned.Path)
+		}
+	}
+	report.mutex.Unlock()
+	sort.Slice(changed, func(i, j int) bool { return changed[i].Path < changed[j].Path })
+	sort.Slice(failed, func(i, j int) bool { return failed[i].Path < failed[j].Path })
+	sort.Strings(unchanged)
+
+	header := fmt.Sprintf("## Terragrunt plan\n\n%d units planned: %d with changes, %d without changes, %d failed.\n\n", len(changed)+len(failed)+len(unchanged), len(changed), len(unchanged), len(failed))
+
+	table := ""
+	if len(changed) > 0 {
+		rows := []string{}
+		for _, planned := range changed {
+			rows = append(rows, fmt.Sprintf("| `%s` | %d | %d | %d |\n", planned.Path, planned.Summary.Add, planned.Summary.Change, planned.Summary.Destroy))
+		}
+		tableStart := "| Unit | Add | Change | Destroy |\n| --- | ---: | ---: | ---: |\n"
+		tableEnd := "\n"
+		table = tableStart + joinLimited(rows, maxLength/2-len(header)-len(tableStart)-len(tableEnd), "| ... and %d more units with changes | | | |\n") + tableEnd
+	}
+
+	footer := ""
+	if len(unchanged) > 0 {
+		items := []string{}
+		for _, path := range unchanged {
+			items = append(items, fmt.Sprintf("- `%s`\n", path))
+		}
+		footerStart := "<details><summary>Units without changes</summary>\n\n"
+		footerEnd := "\n</details>\n"
+		footer = footerStart + joinLimited(items, (maxLength-len(header)-len(table))/4-len(footerStart)-len(footerEnd), "- ... and %d more units without changes\n") + footerEnd
+	}
+
+	units := append(append([]unit{}, changed...), failed...)
+	sections := []string{}
+	for _, planned := range units {
+		sections = append(sections, unitSection(planned, -1))
+	}
+
+	// Each section gets an equal share of the length left, and the sections that need less leave the rest to the others.
+	// The length of the note of the sections left out, if any, is set aside.
+	budget := maxLength - len(header) - len(table) - len(footer) - len(fmt.Sprintf(omittedSectionsNote, len(sections)))
+	document := header + table
+	for i, planned := range units {
+		share := budget / (len(sections) - i)
+		text := sections[i]
+		if len(text) > share {
+			text = unitSection(planned, share)
+		}
+		if len(text) > budget {
+			document += fmt.Sprintf(omittedSectionsNote, len(sections)-i)
+			break
+		}
+		budget -= len(text)
+		document += text
+	}
+	return document + footer
+}
+
+// The note that replaces the sections of the units that don't fit in the document
+const omittedSectionsNote = "_... and %d more units, see their plans in the logs of the run_\n\n"
+
+// Return the collapsible section of the given unit, with its plan or error truncated so that the section fits in the
+// given length, if it's not negative. The section can still be longer if even its truncated note doesn't fit.
+func unitSection(planned unit, maxLength int) string {
+	summary, language, text := fmt.Sprintf("<code>%s</code>: %s", planned.Path, planned.Summary), "diff", toDiff(planned.Plan)
+	if planned.Error != "" {
+		summary, language, text = fmt.Sprintf("<code>%s</code>: failed", planned.Path), "", planned.Error
+	}
+	full := section(summary, language, text)
+	if maxLength < 0 || len(full) <= maxLength {
+		return full
+	}
+	return section(summary, language, truncate(text, maxLength-len(full)+len(text)))
+}
+
+// Join the given lines, leaving out the ones that don't fit in the given length along with a note of how many were left
+// out, as formatted by the given format
+func joinLimited(lines []string, maxLength int, moreFormat string) string {
+	joined := strings.Join(lines, "")
+	if len(joined) <= maxLength {
+		return joined
+	}
+	joined = ""
+	for i, line := range lines {
+		if len(joined)+len(line)+len(fmt.Sprintf(moreFormat, len(lines)-i)) > maxLength {
+			return joined + fmt.Sprintf(moreFormat, len(lines)-i)
+		}
+		joined += line
+	}
+	return joined
+}
+
+// Write the document, truncated to MaxCommentLength, to the given file
+func (report *Report) Write(path string) error {
+	if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+		return errors.WithStackTrace(err)
+	}
+	return errors.WithStackTrace(ioutil.WriteFile(path, []byte(report.Markdown(MaxCommentLength)), 0644))
+}
+
+// Return a collapsible section with the given summary, and the given text in a code block of the given language
+func section(summary string, language string, text string) string {
+	fence := codeFence(text)
+	return fmt.Sprintf("<details><summary>%s</summary>\n\n%s%s\n%s\n%s\n\n</details>\n\n", summary, fence, language, strings.TrimRight(text, "\n"), fence)
+}
+
+// Return a code fence longer than any run of backticks in the given text, so that the text can't close it
+func codeFence(text string) string {
+	fence := "```"
+	for strings.Contains(text, fence) {
+		fence += "`"
+	}
+	return fence
+}
+
+// Return the resource changes of the given output of terraform show, without the legend of the markers before them
+func planChanges(planText string) string {
+	if headerIndex := strings.Index(planText, planChangesHeader); headerIndex >= 0 {
+		planText = planText[headerIndex+len(planChangesHeader):]
+	}
+	return strings.Trim(planText, "\n")
+}
+
+// Move the markers of the changes of the given plan to the start of their line, as diff code blocks only highlight the
+// lines that start with them, and replace the markers diff doesn't know, e.g. ~ for an update, with ones it highlights.
+// The other lines are kept as they are, so that the attributes stay aligned.
+func toDiff(plan string) string {
+	lines := strings.Split(plan, "\n")
+	for i, line := range lines {
+		if matches := changeMarkerRegexp.FindStringSubmatch(line); matches != nil {
+			lines[i] = diffMarkers[matches[2]] + matches[1] + strings.Repeat(" ", len(matches[2])-1) + matches[3] + line[len(matches[0]):]
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// Truncate the given text to at most the given length, at the end of a line, with a note that it's truncated
+func truncate(text string, maxLength int) string {
+	if len(text) <= maxLength {
+		return text
+	}
+	maxLength -= len(truncatedNote)
+	if maxLength <= 0 {
+		return strings.TrimPrefix(truncatedNote, "\n")
+	}
+	text = text[:maxLength]
+	if lineEnd := strings.LastIndex(text, "\n"); lineEnd >= 0 {
+		text = text[:lineEnd]
+	}
+	return text + truncatedNote
+}
@@ -0,0 +1,131 @@
+package planmarkdown
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/gruntwork-io/terragrunt/drift"
+)
+
+const testPlanText = `
+Terraform used the selected providers to generate the following execution
+plan. Resource actions are indicated with the following symbols:
+  + create
+  ~ update in-place
+
+Terraform will perform the following actions:
+
+  # aws_instance.app will be updated in-place
+  ~ resource "aws_instance" "app" {
+      ~ instance_type = "t3.micro" -> "t3.small"
+        id            = "i-0123456789"
+    }
+
+  # aws_s3_bucket.logs must be replaced
+-/+ resource "aws_s3_bucket" "logs" {
+      - tags = {} -> null
+      + arn  = (known after apply)
+    }
+
+Plan: 1 to add, 1 to change, 1 to destroy.
+`
+
+var testChanges = &drift.Changes{Resources: []drift.ResourceChange{
+	{Address: "aws_instance.app", Actions: []string{"update"}},
+	{Address: "aws_s3_bucket.logs", Actions: []string{"delete", "create"}},
+}}
+
+func TestSummarize(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, Summary{Add: 1, Change: 1, Destroy: 1}, Summarize(testChanges))
+	assert.Equal(t, "1 to add, 1 to change, 1 to destroy", Summarize(testChanges).String())
+	assert.Equal(t, Summary{}, Summarize(&drift.Changes{}))
+}
+
+func TestToDiff(t *testing.T) {
+	t.Parallel()
+
+	diff := toDiff(planChanges(testPlanText))
+	assert.NotContains(t, diff, "Resource actions are indicated")
+	assert.Contains(t, diff, "\n!   resource \"aws_instance\" \"app\" {\n")
+	assert.Contains(t, diff, "\n!       instance_type = \"t3.micro\" -> \"t3.small\"\n")
+	assert.Contains(t, diff, "\n        id            = \"i-0123456789\"\n")
+	assert.Contains(t, diff, "\n!   resource \"aws_s3_bucket\" \"logs\" {\n")
+	assert.Contains(t, diff, "\n-       tags = {} -> null\n")
+	assert.Contains(t, diff, "\n+       arn  = (known after apply)\n")
+}
+
+func TestMarkdown(t *testing.T) {
+	t.Parallel()
+
+	rootDir := filepath.Join("repo")
+	report := NewReport(rootDir)
+	report.Add(filepath.Join(rootDir, "live", "vpc"), &drift.Changes{}, "No changes.")
+	report.Add(filepath.Join(rootDir, "live", "app"), testChanges, testPlanText)
+	report.AddError(filepath.Join(rootDir, "live", "db"), fmt.Errorf("Error: invalid credentials"))
+
+	document := report.Markdown(MaxCommentLength)
+	assert.True(t, strings.HasPrefix(document, "## Terragrunt plan\n\n3 units planned: 1 with changes, 1 without changes, 1 failed.\n"))
+	assert.Contains(t, document, "| `live/app` | 1 | 1 | 1 |\n")
+	assert.NotContains(t, document, "| `live/vpc`")
+	assert.Contains(t, document, "<details><summary><code>live/app</code>: 1 to add, 1 to change, 1 to destroy</summary>\n\n```diff\n")
+	assert.Contains(t, document, "<details><summary><code>live/db</code>: failed</summary>\n\n```\nError: invalid credentials\n```\n")
+	assert.Contains(t, document, "<details><summary>Units without changes</summary>\n\n- `live/vpc`\n")
+}
+
+func TestMarkdownTruncatesPlans(t *testing.T) {
+	t.Parallel()
+
+	report := NewReport("repo")
+	longPlan := planChangesHeader + "\n" + strings.Repeat("  + resource \"null_resource\" \"this\" {}\n", 1000)
+	for i := 0; i < 5; i++ {
+		report.Add(filepath.Join("repo", fmt.Sprintf("unit-%d", i)), &drift.Changes{Resources: []drift.ResourceChange{{Address: "null_resource.this", Actions: []string{"create"}}}}, longPlan)
+	}
+
+	document := report.Markdown(10000)
+	assert.True(t, len(document) <= 10000, "document is %d long", len(document))
+	assert.Equal(t, 5, strings.Count(document, "truncated, see the full plan"))
+	assert.Equal(t, 5, strings.Count(document, "</details>"))
+}
+
+func TestMarkdownTruncatesTableAndUnitsWithoutChanges(t *testing.T) {
+	t.Parallel()
+
+	report := NewReport("repo")
+	changes := &drift.Changes{Resources: []drift.ResourceChange{{Address: "null_resource.this", Actions: []string{"create"}}}}
+	for i := 0; i < 2000; i++ {
+		report.Add(filepath.Join("repo", fmt.Sprintf("changed-unit-%04d", i)), changes, testPlanText)
+		report.Add(filepath.Join("repo", fmt.Sprintf("unchanged-unit-%04d", i)), &drift.Changes{}, "No changes.")
+	}
+
+	document := report.Markdown(10000)
+	assert.True(t, len(document) <= 10000, "document is %d long", len(document))
+	assert.Contains(t, document, "| `changed-unit-0000` | 1 | 0 | 0 |\n")
+	assert.Regexp(t, `\| \.\.\. and \d+ more units with changes \| \| \| \|\n`, document)
+	assert.Contains(t, document, "- `unchanged-unit-0000`\n")
+	assert.Regexp(t, `- \.\.\. and \d+ more units without changes\n`, document)
+	assert.Regexp(t, `_\.\.\. and \d+ more units, see their plans in the logs of the run_`, document)
+	assert.True(t, strings.HasSuffix(document, "</details>\n"))
+}
+
+func TestMarkdownFencesBackticks(t *testing.T) {
+	t.Parallel()
+
+	report := NewReport("repo")
+	report.AddError(filepath.Join("repo", "app"), fmt.Errorf("Error in ```heredoc```"))
+	assert.Contains(t, report.Markdown(MaxCommentLength), "````\nError in ```heredoc```\n````")
+}
+
+func TestNilReport(t *testing.T) {
+	t.Parallel()
+
+	var report *Report
+	report.Add("app", testChanges, testPlanText)
+	report.AddError("app", fmt.Errorf("error"))
+	assert.Equal(t, "", report.Markdown(MaxCommentLength))
+}
@@ -0,0 +1,400 @@
+// Package provider_cache implements a local provider cache server that the terraform processes spawned by Terragrunt
+// use as the registry of their providers. The server proxies the provider registry protocol to the real registries,
+// but serves the provider packages from a local cache dir, downloading each package only once, no matter how many
+// modules request it concurrently. Unlike a plugin cache dir shared by all modules, which terraform does not expect
+// to be written to concurrently, only the server writes to the cache dir.
+package provider_cache
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/gruntwork-io/terragrunt/errors"
+	"github.com/gruntwork-io/terragrunt/util"
+)
+
+// The registries whose providers are served through the cache when no registries are given
+var DefaultRegistryHosts = []string{"registry.terraform.io", "registry.opentofu.org"}
+
+// The path prefixes of the endpoints of the server
+const (
+	providersPathPrefix = "/v1/providers/"
+	downloadsPathPrefix = "/downloads/"
+)
+
+// The path of the service discovery document of a registry
+const serviceDiscoveryPath = "/.well-known/terraform.json"
+
+// How long to wait for a registry to respond, other than for downloading provider packages
+const registryRequestTimeout = 30 * time.Second
+
+// Server is the provider cache server. Create it with NewServer, and start it with Start.
+type Server struct {
+	cacheDir      string
+	registryHosts []string
+	logger        *logrus.Entry
+
+	// The scheme of the URLs of the registries, which is only changed by the tests
+	registryScheme string
+
+	listener   net.Listener
+	httpServer *http.Server
+	// The client for the requests to the registries, and the one for downloading the provider packages, which can take
+	// much longer
+	registryClient *http.Client
+	downloadClient *http.Client
+
+	mutex sync.Mutex
+	// The providers.v1 service URL of each registry, from its service discovery document
+	providerServiceURLs map[string]*url.URL
+	// The URL each provider package in the cache dir is downloaded from, by its path relative to the cache dir
+	packageURLs map[string]string
+	// The downloads of provider packages in progress or done, by their path relative to the cache dir
+	downloads map[string]*packageDownload
+}
+
+// A download of a provider package into the cache dir. done is closed once the download has finished.
+type packageDownload struct {
+	done chan struct{}
+	err  error
+}
+
+// NewServer creates a provider cache server that caches the provider packages of the given registries in the given
+// dir
+func NewServer(cacheDir string, registryHosts []string, logger *logrus.Entry) *Server {
+	if len(registryHosts) == 0 {
+		registryHosts = DefaultRegistryHosts
+	}
+
+	return &Server{
+		cacheDir:            cacheDir,
+		registryHosts:       registryHosts,
+		logger:              logger,
+		registryScheme:      "https",
+		registryClient:      &http.Client{Timeout: registryRequestTimeout},
+		downloadClient:      &http.Client{},
+		providerServiceURLs: map[string]*url.URL{},
+		packageURLs:         map[string]string{},
+		downloads:           map[string]*packageDownload{},
+	}
+}
+
+// Start listens on a random port of the loopback interface and serves the provider registry protocol in the
+// background, until Close is called
+func (server *Server) Start() error {
+	if err := util.EnsureDirectory(server.cacheDir); err != nil {
+		return err
+	}
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return errors.WithStackTrace(err)
+	}
+	server.listener = listener
+
+	mux := http.NewServeMux()
+	mux.HandleFunc(providersPathPrefix, server.handleProviders)
+	mux.HandleFunc(downloadsPathPrefix, server.handleDownload)
+	server.httpServer = &http.Server{Handler: mux}
+
+	go func() {
+		if err := server.httpServer.Serve(listener); err != nil && err != http.ErrServerClosed {
+			server.logger.Errorf("Provider cache server stopped: %v", err)
+		}
+	}()
+
+	server.logger.Debugf("Provider cache server listening on %s, caching providers in %s", server.URL(), server.cacheDir)
+	return nil
+}
+
+// Close stops the server
+func (server *Server) Close() error {
+	if server.httpServer == nil {
+		return nil
+	}
+	return errors.WithStackTrace(server.httpServer.Close())
+}
+
+// URL returns the base URL of the running server
+func (server *Server) URL() string {
+	return "http://" + server.listener.Addr().String()
+}
+
+// CLIConfig returns the terraform CLI config that makes terraform install the providers of the registries through
+// this server, appended to the given existing CLI config
+func (server *Server) CLIConfig(existingConfig string) string {
+	var builder strings.Builder
+	if existingConfig != "" {
+		builder.WriteString(strings.TrimRight(existingConfig, "\n"))
+		builder.WriteString("\n\n")
+	}
+	builder.WriteString("# Added by Terragrunt to install the providers through its provider cache server\n")
+	for _, host := range server.registryHosts {
+		fmt.Fprintf(&builder, "host %q {\n  services = {\n    \"providers.v1\" = %q\n  }\n}\n", host, server.URL()+providersPathPrefix+host+"/")
+	}
+	return builder.String()
+}
+
+// handleProviders proxies the requests of the provider registry protocol to the registry in the first segment of the
+// path, e.g. /v1/providers/registry.terraform.io/hashicorp/aws/versions. The download_url of the packages is replaced
+// with the URL of the package in the cache.
+func (server *Server) handleProviders(writer http.ResponseWriter, request *http.Request) {
+	parts := strings.SplitN(strings.TrimPrefix(request.URL.Path, providersPathPrefix), "/", 2)
+	if len(parts) != 2 || !util.ListContainsElement(server.registryHosts, parts[0]) {
+		http.NotFound(writer, request)
+		return
+	}
+	host, providerPath := parts[0], parts[1]
+
+	serviceURL, err := server.providerServiceURL(host)
+	if err != nil {
+		server.respondWithError(writer, err)
+		return
+	}
+	upstreamURL, err := serviceURL.Parse(providerPath)
+	if err != nil {
+		server.respondWithError(writer, errors.WithStackTrace(err))
+		return
+	}
+
+	statusCode, body, err := server.get(upstreamURL.String())
+	if err != nil {
+		server.respondWithError(writer, err)
+		return
+	}
+
+	// Only the responses of the download endpoint, {namespace}/{type}/{version}/download/{os}/{arch}, point at packages
+	pathSegments := strings.Split(providerPath, "/")
+	if statusCode == http.StatusOK && len(pathSegments) == 6 && pathSegments[3] == "download" {
+		body, err = server.rewriteDownloadURL(host, pathSegments, upstreamURL, body)
+		if err != nil {
+			server.respondWithError(writer, err)
+			return
+		}
+	}
+
+	writer.Header().Set("Content-Type", "application/json")
+	writer.WriteHeader(statusCode)
+	writer.Write(body)
+}
+
+// rewriteDownloadURL replaces the download_url in the given response of the download endpoint of the registry with
+// the URL of the package in the cache, and records where to download the package from
+func (server *Server) rewriteDownloadURL(host string, pathSegments []string, upstreamURL *url.URL, body []byte) ([]byte, error) {
+	var download map[string]interface{}
+	if err := json.Unmarshal(body, &download); err != nil {
+		return nil, errors.WithStackTrace(err)
+	}
+
+	downloadURL, _ := download["download_url"].(string)
+	filename, _ := download["filename"].(string)
+	if downloadURL == "" || filename == "" || filepath.Base(filename) != filename {
+		return nil, errors.WithStackTrace(InvalidRegistryResponse{URL: upstreamURL.String(), Reason: "missing download_url or filename"})
+	}
+
+	// The download_url may be relative to the URL of the download endpoint
+	resolvedURL, err := upstreamURL.Parse(downloadURL)
+	if err != nil {
+		return nil, errors.WithStackTrace(err)
+	}
+
+	namespace, providerType, version := pathSegments[0], pathSegments[1], pathSegments[2]
+	packagePath := strings.Join([]string{host, namespace, providerType, version, filename}, "/")
+
+	server.mutex.Lock()
+	server.packageURLs[packagePath] = resolvedURL.String()
+	server.mutex.Unlock()
+
+	download["download_url"] = server.URL() + downloadsPathPrefix + packagePath
+	return json.Marshal(download)
+}
+
+// handleDownload serves the provider package at the given path from the cache dir, downloading it first if it isn't
+// cached yet
+func (server *Server) handleDownload(writer http.ResponseWriter, request *http.Request) {
+	packagePath := strings.TrimPrefix(request.URL.Path, downloadsPathPrefix)
+	if strings.Contains(packagePath, "..") {
+		http.NotFound(writer, request)
+		return
+	}
+
+	if err := server.downloadPackageOnce(packagePath); err != nil {
+		server.respondWithError(writer, err)
+		return
+	}
+
+	http.ServeFile(writer, request, filepath.Join(server.cacheDir, filepath.FromSlash(packagePath)))
+}
+
+// downloadPackageOnce downloads the provider package at the given path into the cache dir, unless it's already
+// cached. Concurrent requests for the same package wait for a single download.
+func (server *Server) downloadPackageOnce(packagePath string) error {
+	server.mutex.Lock()
+	download, isDownloading := server.downloads[packagePath]
+	if !isDownloading {
+		download = &packageDownload{done: make(chan struct{})}
+		server.downloads[packagePath] = download
+	}
+	packageURL, hasPackageURL := server.packageURLs[packagePath]
+	server.mutex.Unlock()
+
+	if isDownloading {
+		<-download.done
+		return download.err
+	}
+
+	defer close(download.done)
+
+	cachedPath := filepath.Join(server.cacheDir, filepath.FromSlash(packagePath))
+	if util.FileExists(cachedPath) {
+		server.logger.Debugf("Serving provider package %s from the cache", packagePath)
+		return nil
+	}
+
+	if !hasPackageURL {
+		download.err = errors.WithStackTrace(UnknownProviderPackage(packagePath))
+	} else {
+		download.err = server.downloadPackage(packageURL, cachedPath)
+	}
+
+	// Let a later request retry a failed download
+	if download.err != nil {
+		server.mutex.Lock()
+		delete(server.downloads, packagePath)
+		server.mutex.Unlock()
+	}
+	return download.err
+}
+
+// downloadPackage downloads the provider package at the given URL to the given path. The package is written to a
+// temp file first and then renamed, so that an interrupted download never leaves a partial package in the cache.
+func (server *Server) downloadPackage(packageURL string, destination string) error {
+	server.logger.Infof("Downloading provider package %s into the provider cache", packageURL)
+
+	if err := util.EnsureDirectory(filepath.Dir(destination)); err != nil {
+		return err
+	}
+
+	response, err := server.downloadClient.Get(packageURL)
+	if err != nil {
+		return errors.WithStackTrace(err)
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		return errors.WithStackTrace(InvalidRegistryResponse{URL: packageURL, Reason: response.Status})
+	}
+
+	tempFile, err := ioutil.TempFile(filepath.Dir(destination), filepath.Base(destination)+".*.tmp")
+	if err != nil {
+		return errors.WithStackTrace(err)
+	}
+	defer os.Remove(tempFile.Name())
+
+	_, copyErr := io.Copy(tempFile, response.Body)
+	closeErr := tempFile.Close()
+	if copyErr != nil {
+		return errors.WithStackTrace(copyErr)
+	}
+	if closeErr != nil {
+		return errors.WithStackTrace(closeErr)
+	}
+
+	return errors.WithStackTrace(os.Rename(tempFile.Name(), destination))
+}
+
+// providerServiceURL returns the base URL of the providers.v1 service of the given registry, from its service
+// discovery document
+func (server *Server) providerServiceURL(host string) (*url.URL, error) {
+	server.mutex.Lock()
+	serviceURL, isDiscovered := server.providerServiceURLs[host]
+	server.mutex.Unlock()
+	if isDiscovered {
+		return serviceURL, nil
+	}
+
+	registryURL := &url.URL{Scheme: server.registryScheme, Host: host, Path: "/"}
+	discoveryURL := registryURL.ResolveReference(&url.URL{Path: serviceDiscoveryPath}).String()
+
+	statusCode, body, err := server.get(discoveryURL)
+	if err != nil {
+		return nil, err
+	}
+	if statusCode != http.StatusOK {
+		return nil, errors.WithStackTrace(InvalidRegistryResponse{URL: discoveryURL, Reason: http.StatusText(statusCode)})
+	}
+
+	var services map[string]interface{}
+	if err := json.Unmarshal(body, &services); err != nil {
+		return nil, errors.WithStackTrace(err)
+	}
+	servicePath, _ := services["providers.v1"].(string)
+	if servicePath == "" {
+		return nil, errors.WithStackTrace(InvalidRegistryResponse{URL: discoveryURL, Reason: "no providers.v1 service"})
+	}
+	// Make sure the provider paths are resolved relative to the service, rather than replacing its last segment
+	if !strings.HasSuffix(servicePath, "/") {
+		servicePath += "/"
+	}
+
+	serviceURL, err = registryURL.Parse(servicePath)
+	if err != nil {
+		return nil, errors.WithStackTrace(err)
+	}
+
+	server.mutex.Lock()
+	server.providerServiceURLs[host] = serviceURL
+	server.mutex.Unlock()
+	return serviceURL, nil
+}
+
+// get sends a GET request to the given URL of a registry and returns the status code and body of the response
+func (server *Server) get(requestURL string) (int, []byte, error) {
+	response, err := server.registryClient.Get(requestURL)
+	if err != nil {
+		return 0, nil, errors.WithStackTrace(err)
+	}
+	defer response.Body.Close()
+
+	body, err := ioutil.ReadAll(response.Body)
+	if err != nil {
+		return 0, nil, errors.WithStackTrace(err)
+	}
+	return response.StatusCode, body, nil
+}
+
+// respondWithError logs the given error and responds with a 502, as the server failed to get a valid response from
+// the registry
+func (server *Server) respondWithError(writer http.ResponseWriter, err error) {
+	server.logger.Errorf("Provider cache server: %v", err)
+	http.Error(writer, err.Error(), http.StatusBadGateway)
+}
+
+// Custom error types
+
+type InvalidRegistryResponse struct {
+	URL    string
+	Reason string
+}
+
+func (err InvalidRegistryResponse) Error() string {
+	return fmt.Sprintf("Invalid response from the provider registry at %s: %s", err.URL, err.Reason)
+}
+
+type UnknownProviderPackage string
+
+func (packagePath UnknownProviderPackage) Error() string {
+	return fmt.Sprintf("The provider package %s is not cached, and terraform has not requested its download URL from the provider cache server", string(packagePath))
+}
@@ -0,0 +1,142 @@
+package provider_cache
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/gruntwork-io/terragrunt/util"
+)
+
+const testPackageContents = "not really a zip file"
+
+// Start a fake registry serving the hashicorp/null provider, which counts the downloads of the package
+func startFakeRegistry(t *testing.T, packageDownloads *int32) *httptest.Server {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/terraform.json", func(writer http.ResponseWriter, request *http.Request) {
+		fmt.Fprint(writer, `{"providers.v1": "/v1/providers"}`)
+	})
+	mux.HandleFunc("/v1/providers/hashicorp/null/versions", func(writer http.ResponseWriter, request *http.Request) {
+		fmt.Fprint(writer, `{"versions": [{"version": "3.2.1", "protocols": ["5.0"]}]}`)
+	})
+	mux.HandleFunc("/v1/providers/hashicorp/null/3.2.1/download/linux/amd64", func(writer http.ResponseWriter, request *http.Request) {
+		fmt.Fprint(writer, `{"os": "linux", "arch": "amd64", "filename": "terraform-provider-null_3.2.1_linux_amd64.zip", "download_url": "/packages/terraform-provider-null_3.2.1_linux_amd64.zip", "shasum": "abc"}`)
+	})
+	mux.HandleFunc("/packages/terraform-provider-null_3.2.1_linux_amd64.zip", func(writer http.ResponseWriter, request *http.Request) {
+		atomic.AddInt32(packageDownloads, 1)
+		fmt.Fprint(writer, testPackageContents)
+	})
+	return httptest.NewServer(mux)
+}
+
+func startTestServer(t *testing.T, registry *httptest.Server) (*Server, string) {
+	cacheDir, err := ioutil.TempDir("", "provider-cache")
+	require.NoError(t, err)
+
+	registryURL, err := url.Parse(registry.URL)
+	require.NoError(t, err)
+
+	server := NewServer(cacheDir, []string{registryURL.Host}, util.CreateLogEntry("", util.DEFAULT_LOG_LEVEL))
+	server.registryScheme = "http"
+	require.NoError(t, server.Start())
+	return server, registryURL.Host
+}
+
+func TestProviderCacheServerProxiesVersions(t *testing.T) {
+	t.Parallel()
+
+	var packageDownloads int32
+	registry := startFakeRegistry(t, &packageDownloads)
+	defer registry.Close()
+
+	server, host := startTestServer(t, registry)
+	defer os.RemoveAll(server.cacheDir)
+	defer server.Close()
+
+	response, err := http.Get(fmt.Sprintf("%s/v1/providers/%s/hashicorp/null/versions", server.URL(), host))
+	require.NoError(t, err)
+	defer response.Body.Close()
+	body, err := ioutil.ReadAll(response.Body)
+	require.NoError(t, err)
+
+	assert.Equal(t, http.StatusOK, response.StatusCode)
+	assert.JSONEq(t, `{"versions": [{"version": "3.2.1", "protocols": ["5.0"]}]}`, string(body))
+
+	response, err = http.Get(server.URL() + "/v1/providers/registry.example.com/hashicorp/null/versions")
+	require.NoError(t, err)
+	response.Body.Close()
+	assert.Equal(t, http.StatusNotFound, response.StatusCode)
+}
+
+func TestProviderCacheServerDownloadsEachPackageOnce(t *testing.T) {
+	t.Parallel()
+
+	var packageDownloads int32
+	registry := startFakeRegistry(t, &packageDownloads)
+	defer registry.Close()
+
+	server, host := startTestServer(t, registry)
+	defer os.RemoveAll(server.cacheDir)
+	defer server.Close()
+
+	response, err := http.Get(fmt.Sprintf("%s/v1/providers/%s/hashicorp/null/3.2.1/download/linux/amd64", server.URL(), host))
+	require.NoError(t, err)
+	defer response.Body.Close()
+
+	var download map[string]interface{}
+	require.NoError(t, json.NewDecoder(response.Body).Decode(&download))
+	packageURL := fmt.Sprintf("%s/downloads/%s/hashicorp/null/3.2.1/terraform-provider-null_3.2.1_linux_amd64.zip", server.URL(), host)
+	assert.Equal(t, packageURL, download["download_url"])
+	assert.Equal(t, "abc", download["shasum"])
+
+	// Simulate the modules of a run-all downloading the same provider concurrently
+	var waitGroup sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		waitGroup.Add(1)
+		go func() {
+			defer waitGroup.Done()
+
+			response, err := http.Get(packageURL)
+			if !assert.NoError(t, err) {
+				return
+			}
+			defer response.Body.Close()
+			body, err := ioutil.ReadAll(response.Body)
+			assert.NoError(t, err)
+			assert.Equal(t, testPackageContents, string(body))
+		}()
+	}
+	waitGroup.Wait()
+
+	assert.Equal(t, int32(1), atomic.LoadInt32(&packageDownloads))
+	assert.FileExists(t, filepath.Join(server.cacheDir, host, "hashicorp", "null", "3.2.1", "terraform-provider-null_3.2.1_linux_amd64.zip"))
+}
+
+func TestProviderCacheServerCLIConfig(t *testing.T) {
+	t.Parallel()
+
+	cacheDir, err := ioutil.TempDir("", "provider-cache")
+	require.NoError(t, err)
+	defer os.RemoveAll(cacheDir)
+
+	server := NewServer(cacheDir, nil, util.CreateLogEntry("", util.DEFAULT_LOG_LEVEL))
+	require.NoError(t, server.Start())
+	defer server.Close()
+
+	cliConfig := server.CLIConfig("plugin_cache_may_break_dependency_lock_file = true\n")
+	assert.Contains(t, cliConfig, "plugin_cache_may_break_dependency_lock_file = true\n\n")
+	for _, host := range DefaultRegistryHosts {
+		assert.Contains(t, cliConfig, fmt.Sprintf("host %q {\n  services = {\n    \"providers.v1\" = \"%s/v1/providers/%s/\"\n  }\n}\n", host, server.URL(), host))
+	}
+}
@@ -3,10 +3,13 @@ package remote
 import (
 	"fmt"
 	"reflect"
+	"sort"
+	"strings"
 
 	"github.com/gruntwork-io/terragrunt/codegen"
 	"github.com/gruntwork-io/terragrunt/errors"
 	"github.com/gruntwork-io/terragrunt/options"
+	"github.com/mitchellh/mapstructure"
 )
 
 // Configuration for Terraform remote state
@@ -17,10 +20,11 @@ type RemoteState struct {
 	DisableDependencyOptimization bool
 	Generate                      *RemoteStateGenerate
 	Config                        map[string]interface{}
+	Encryption                    map[string]interface{}
 }
 
 func (remoteState *RemoteState) String() string {
-	return fmt.Sprintf("RemoteState{Backend = %v, DisableInit = %v, DisableDependencyOptimization = %v, Generate = %v, Config = %v}", remoteState.Backend, remoteState.DisableInit, remoteState.DisableDependencyOptimization, remoteState.Generate, remoteState.Config)
+	return fmt.Sprintf("RemoteState{Backend = %v, DisableInit = %v, DisableDependencyOptimization = %v, Generate = %v, Config = %v, Encryption = %v}", remoteState.Backend, remoteState.DisableInit, remoteState.DisableDependencyOptimization, remoteState.Generate, remoteState.Config, remoteState.Encryption)
 }
 
 // Code gen configuration for Terraform remote state
@@ -41,10 +45,88 @@ type RemoteStateInitializer interface {
 	GetTerraformInitArgs(config map[string]interface{}) map[string]interface{}
 }
 
+// Implemented by the initializers of the backends whose config Terragrunt can validate without calling the backend, so
+// that mistakes such as a missing bucket name are reported before running terraform init, rather than as an error
+// from terraform late in the run
+type RemoteStateConfigValidator interface {
+	// Return an error if the config of the given remote state is missing required settings or has invalid values
+	ValidateConfig(remoteState *RemoteState, terragruntOptions *options.TerragruntOptions) error
+}
+
+// Implemented by the initializers of the backends whose state objects Terragrunt can delete directly
+type RemoteStateDeleter interface {
+	// Delete the state object of the given remote state, but not the resources that store it
+	DeleteState(remoteState *RemoteState, terragruntOptions *options.TerragruntOptions) error
+}
+
+// Implemented by the initializers of the backends that can copy the state to its new location when it has moved, e.g.
+// when the key of the S3 backend has changed after the module was moved to another folder
+type RemoteStateMover interface {
+	// Copy the state of the given workspace from its location in the given existing backend to its location in the
+	// given remote state, if there is state at the former and none at the latter. Returns true if the state was copied.
+	CopyMovedState(remoteState *RemoteState, existingBackend *TerraformBackend, workspace string, terragruntOptions *options.TerragruntOptions) (bool, error)
+}
+
+// Implemented by the initializers of the backends whose resources Terragrunt can check for drift from the settings it
+// creates them with
+type RemoteStateDriftChecker interface {
+	// Return the settings of the resources storing the given remote state that differ from the config
+	CheckDrift(remoteState *RemoteState, terragruntOptions *options.TerragruntOptions) ([]RemoteStateDrift, error)
+}
+
+// Implemented by the initializers of the backends whose state objects Terragrunt can read directly, without running
+// terraform init
+type RemoteStateReader interface {
+	// Return the contents of the state object of the given remote state in the given workspace, or nil if there is no
+	// state. An empty workspace is the default workspace.
+	ReadState(remoteState *RemoteState, workspace string, terragruntOptions *options.TerragruntOptions) ([]byte, error)
+}
+
+// Implemented by the initializers of the backends whose permissions Terragrunt can check before running terraform
+type RemoteStatePermissionsChecker interface {
+	// Return an error listing the permissions the credentials of the given remote state lack to use its backend
+	CheckPermissions(remoteState *RemoteState, terragruntOptions *options.TerragruntOptions) error
+}
+
+// A setting of a resource storing the remote state, e.g. the versioning of the S3 bucket, that differs from the setting
+// Terragrunt creates the resource with
+type RemoteStateDrift struct {
+	Resource string
+	Setting  string
+	Expected string
+	Actual   string
+
+	// Updates the resource to the expected setting, or nil if Terragrunt can't repair the drift
+	repair func() error
+}
+
+func (drift RemoteStateDrift) String() string {
+	return fmt.Sprintf("%s: %s is %s, expected %s", drift.Resource, drift.Setting, drift.Actual, drift.Expected)
+}
+
+// Returns true if Terragrunt can update the resource to the expected setting
+func (drift RemoteStateDrift) CanRepair() bool {
+	return drift.repair != nil
+}
+
+// Update the resource to the expected setting
+func (drift RemoteStateDrift) Repair() error {
+	if drift.repair == nil {
+		return errors.WithStackTrace(DriftNotRepairable(drift.String()))
+	}
+	return drift.repair()
+}
+
 // TODO: initialization actions for other remote state backends can be added here
 var remoteStateInitializers = map[string]RemoteStateInitializer{
-	"s3":  S3Initializer{},
-	"gcs": GCSInitializer{},
+	"s3":      S3Initializer{},
+	"gcs":     GCSInitializer{},
+	"azurerm": AzureRMInitializer{},
+	"http":    HTTPInitializer{},
+	"pg":      PostgresInitializer{},
+
+	TFC_REMOTE_BACKEND: TFCInitializer{backend: TFC_REMOTE_BACKEND},
+	TFC_CLOUD_BACKEND:  TFCInitializer{backend: TFC_CLOUD_BACKEND},
 }
 
 // Fill in any default configuration for remote state
@@ -58,21 +140,193 @@ func (remoteState *RemoteState) Validate() error {
 		return errors.WithStackTrace(RemoteBackendMissing)
 	}
 
+	// The cloud block doesn't support -backend-config, so it can only be configured in the generated backend file. The
+	// remote backend does, but its workspaces block can't be passed with it, so it has to be in the terraform code then.
+	if remoteState.Backend == TFC_CLOUD_BACKEND && remoteState.Generate == nil {
+		return errors.WithStackTrace(TFCBackendWithoutGenerate(remoteState.Backend))
+	}
+	if remoteState.Backend == TFC_REMOTE_BACKEND && remoteState.Generate == nil {
+		for _, key := range []string{"workspaces", "derive_workspace_name"} {
+			if _, isSet := remoteState.Config[key]; isSet {
+				return errors.WithStackTrace(TFCWorkspacesWithoutGenerate(key))
+			}
+		}
+	}
+
+	if remoteState.Encryption != nil {
+		// The encryption settings can only be passed to OpenTofu in the generated backend file
+		if remoteState.Generate == nil {
+			return errors.WithStackTrace(EncryptionWithoutGenerate)
+		}
+		if _, err := parseStateEncryption(remoteState.Encryption); err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
 
+// The settings of the encryption attribute that are used by Terragrunt to render the encryption block, rather than
+// being passed on to the key provider
+const (
+	encryptionKeyProviderKey = "key_provider"
+	encryptionMethodKey      = "method"
+	encryptionEnforcedKey    = "enforced"
+)
+
+// The encryption method used when the method setting is not given
+const DEFAULT_STATE_ENCRYPTION_METHOD = "aes_gcm"
+
+// parseStateEncryption parses the encryption attribute of the remote_state block. All the settings other than
+// key_provider, method and enforced are the config of the key provider.
+func parseStateEncryption(encryption map[string]interface{}) (*codegen.StateEncryption, error) {
+	stateEncryption := &codegen.StateEncryption{
+		Method:            DEFAULT_STATE_ENCRYPTION_METHOD,
+		KeyProviderConfig: map[string]interface{}{},
+	}
+
+	for key, value := range encryption {
+		switch key {
+		case encryptionKeyProviderKey:
+			keyProvider, isString := value.(string)
+			if !isString || keyProvider == "" {
+				return nil, errors.WithStackTrace(InvalidStateEncryptionSetting{Name: key, Expected: "a non-empty string"})
+			}
+			stateEncryption.KeyProvider = keyProvider
+		case encryptionMethodKey:
+			method, isString := value.(string)
+			if !isString || method == "" {
+				return nil, errors.WithStackTrace(InvalidStateEncryptionSetting{Name: key, Expected: "a non-empty string"})
+			}
+			stateEncryption.Method = method
+		case encryptionEnforcedKey:
+			enforced, isBool := value.(bool)
+			if !isBool {
+				return nil, errors.WithStackTrace(InvalidStateEncryptionSetting{Name: key, Expected: "a bool"})
+			}
+			stateEncryption.Enforced = enforced
+		default:
+			stateEncryption.KeyProviderConfig[key] = value
+		}
+	}
+
+	if stateEncryption.KeyProvider == "" {
+		return nil, errors.WithStackTrace(InvalidStateEncryptionSetting{Name: encryptionKeyProviderKey, Expected: "a non-empty string"})
+	}
+
+	return stateEncryption, nil
+}
+
+// Validate the config of this remote state for its backend, e.g. that the S3 backend has a bucket, key and valid region,
+// without making any calls to the backend. Backends Terragrunt doesn't know about, and remote state with disable_init
+// set, are left to terraform to validate.
+func (remoteState *RemoteState) ValidateConfig(terragruntOptions *options.TerragruntOptions) error {
+	validator, isValidator := remoteStateInitializers[remoteState.Backend].(RemoteStateConfigValidator)
+	if !isValidator || remoteState.DisableInit {
+		return nil
+	}
+
+	terragruntOptions.Logger.Debugf("Validating the remote state config of the %s backend", remoteState.Backend)
+	err := validator.ValidateConfig(remoteState, terragruntOptions)
+	// The errors of mapstructure don't say which backend the settings belong to
+	if decodeErr, isDecodeErr := errors.Unwrap(err).(*mapstructure.Error); isDecodeErr {
+		return errors.WithStackTrace(InvalidRemoteStateConfigType{Backend: remoteState.Backend, Errors: decodeErr.Errors})
+	}
+	return err
+}
+
 // Perform any actions necessary to initialize the remote state before it's used for storage. For example, if you're
 // using S3 or GCS for remote state storage, this may create the bucket if it doesn't exist already.
 func (remoteState *RemoteState) Initialize(terragruntOptions *options.TerragruntOptions) error {
 	terragruntOptions.Logger.Debugf("Initializing remote state for the %s backend", remoteState.Backend)
 	initializer, hasInitializer := remoteStateInitializers[remoteState.Backend]
 	if hasInitializer {
-		return initializer.Initialize(remoteState, terragruntOptions)
+		return initializeRemoteStateOnce(remoteState, terragruntOptions, func() error {
+			span := terragruntOptions.TelemetrySpan.StartChild("remote_state_init", map[string]string{"terragrunt.backend": remoteState.Backend})
+			err := initializer.Initialize(remoteState, terragruntOptions)
+			span.End(err)
+			return err
+		})
 	}
 
 	return nil
 }
 
+// Delete the state object of this remote state, e.g. the S3 object or GCS object holding the state. The bucket, lock
+// table and any other resources created when initializing the remote state are left untouched.
+func (remoteState *RemoteState) DeleteState(terragruntOptions *options.TerragruntOptions) error {
+	deleter, isDeleter := remoteStateInitializers[remoteState.Backend].(RemoteStateDeleter)
+	if !isDeleter {
+		return errors.WithStackTrace(DeleteStateNotSupported(remoteState.Backend))
+	}
+
+	terragruntOptions.Logger.Debugf("Deleting the remote state of the %s backend", remoteState.Backend)
+	return deleter.DeleteState(remoteState, terragruntOptions)
+}
+
+// If the location of the state has changed since terraform init was last run, e.g. because the key of the S3 backend is
+// derived from the path of the module and the module was moved, and there is no state at the new location, offer to
+// copy the state of the given workspace from the old location, which is left in place as a backup. Without this,
+// terraform would start from an empty state. An empty workspace is the default workspace. Returns true if the state was
+// copied.
+//
+// The old location is the one of the backend recorded in the terraform data dir by the last terraform init, so the move
+// is only detected in a working dir that was initialized before, and not e.g. in a fresh checkout in CI, where
+// terragrunt backend migrate copies the state instead.
+func (remoteState *RemoteState) CopyMovedState(workspace string, terragruntOptions *options.TerragruntOptions) (bool, error) {
+	mover, isMover := remoteStateInitializers[remoteState.Backend].(RemoteStateMover)
+	if !isMover || remoteState.DisableInit {
+		return false, nil
+	}
+
+	state, err := ParseTerraformStateFileFromLocation(remoteState.Backend, remoteState.Config, terragruntOptions.WorkingDir, terragruntOptions.DataDir())
+	if err != nil {
+		return false, err
+	}
+	if state == nil || !state.IsRemote() {
+		return false, nil
+	}
+
+	return mover.CopyMovedState(remoteState, state.Backend, workspace, terragruntOptions)
+}
+
+// Read the state object of this remote state in the given workspace directly from the backend, e.g. the S3 object or GCS
+// object holding the state. Returns nil if there is no state yet. An empty workspace is the default workspace.
+func (remoteState *RemoteState) ReadState(workspace string, terragruntOptions *options.TerragruntOptions) ([]byte, error) {
+	reader, isReader := remoteStateInitializers[remoteState.Backend].(RemoteStateReader)
+	if !isReader {
+		return nil, errors.WithStackTrace(ReadStateNotSupported(remoteState.Backend))
+	}
+
+	terragruntOptions.Logger.Debugf("Reading the remote state of the %s backend", remoteState.Backend)
+	return reader.ReadState(remoteState, workspace, terragruntOptions)
+}
+
+// Return the settings of the resources storing this remote state, such as the S3 bucket and DynamoDB lock table, that
+// differ from the settings Terragrunt creates them with
+func (remoteState *RemoteState) CheckDrift(terragruntOptions *options.TerragruntOptions) ([]RemoteStateDrift, error) {
+	checker, isChecker := remoteStateInitializers[remoteState.Backend].(RemoteStateDriftChecker)
+	if !isChecker {
+		return nil, errors.WithStackTrace(DriftCheckNotSupported(remoteState.Backend))
+	}
+
+	terragruntOptions.Logger.Debugf("Checking the resources of the %s backend for drift", remoteState.Backend)
+	return checker.CheckDrift(remoteState, terragruntOptions)
+}
+
+// Check that the credentials of this remote state have the permissions to use its backend. Backends that don't support
+// the check are skipped, so that the check can be enabled for all the modules of a stack.
+func (remoteState *RemoteState) CheckPermissions(terragruntOptions *options.TerragruntOptions) error {
+	checker, isChecker := remoteStateInitializers[remoteState.Backend].(RemoteStatePermissionsChecker)
+	if !isChecker {
+		terragruntOptions.Logger.Debugf("Skipping the preflight check of the %s backend, which doesn't support it", remoteState.Backend)
+		return nil
+	}
+
+	terragruntOptions.Logger.Debugf("Checking the permissions to use the %s backend", remoteState.Backend)
+	return checker.CheckPermissions(remoteState, terragruntOptions)
+}
+
 // Returns true if remote state needs to be configured. This will be the case when:
 //
 // 1. Remote state has not already been configured
@@ -132,10 +386,18 @@ func terraformStateConfigEqual(existingConfig map[string]interface{}, newConfig
 
 	existingConfigNonNil := map[string]interface{}{}
 	for existingKey, existingValue := range existingConfig {
-		_, newValueIsSet := newConfig[existingKey]
+		newValue, newValueIsSet := newConfig[existingKey]
 		if existingValue == nil && !newValueIsSet {
 			continue
 		}
+
+		// Nested objects, such as the assume_role block of the s3 backend, are stored with all their keys as well
+		existingMap, existingIsMap := existingValue.(map[string]interface{})
+		newMap, newIsMap := newValue.(map[string]interface{})
+		if existingIsMap && newIsMap && terraformStateConfigEqual(existingMap, newMap) {
+			existingValue = newMap
+		}
+
 		existingConfigNonNil[existingKey] = existingValue
 	}
 
@@ -163,13 +425,38 @@ func (remoteState RemoteState) ToTerraformInitArgs() []string {
 	var backendConfigArgs []string = nil
 
 	for key, value := range config {
-		arg := fmt.Sprintf("-backend-config=%s=%v", key, value)
+		arg := fmt.Sprintf("-backend-config=%s=%s", key, backendConfigArgValue(value))
 		backendConfigArgs = append(backendConfigArgs, arg)
 	}
 
 	return backendConfigArgs
 }
 
+// Format the given value of the backend config for the -backend-config arg. Terraform parses the values of object
+// attributes, such as the assume_role block of the s3 backend, as HCL, so maps are formatted as HCL objects.
+func backendConfigArgValue(value interface{}) string {
+	mapValue, isMap := value.(map[string]interface{})
+	if !isMap {
+		return fmt.Sprintf("%v", value)
+	}
+
+	keys := make([]string, 0, len(mapValue))
+	for key := range mapValue {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var attributes []string
+	for _, key := range keys {
+		if stringValue, isString := mapValue[key].(string); isString {
+			attributes = append(attributes, fmt.Sprintf("%s=%q", key, stringValue))
+		} else {
+			attributes = append(attributes, fmt.Sprintf("%s=%s", key, backendConfigArgValue(mapValue[key])))
+		}
+	}
+	return fmt.Sprintf("{%s}", strings.Join(attributes, ","))
+}
+
 // Generate the terraform code for configuring remote state backend.
 func (remoteState *RemoteState) GenerateTerraformCode(terragruntOptions *options.TerragruntOptions) error {
 	if remoteState.Generate == nil {
@@ -189,7 +476,20 @@ func (remoteState *RemoteState) GenerateTerraformCode(terragruntOptions *options
 		return err
 	}
 
-	configBytes, err := codegen.RemoteStateConfigToTerraformCode(remoteState.Backend, config)
+	var encryption *codegen.StateEncryption
+	if remoteState.Encryption != nil {
+		// Only OpenTofu supports state encryption. If the binary couldn't be detected, e.g. when fetching the outputs of
+		// a dependency, leave it to the binary to report any error.
+		if terragruntOptions.TerraformImplementation == options.TerraformImpl {
+			return errors.WithStackTrace(StateEncryptionNotSupported(terragruntOptions.TerraformPath))
+		}
+		encryption, err = parseStateEncryption(remoteState.Encryption)
+		if err != nil {
+			return err
+		}
+	}
+
+	configBytes, err := codegen.RemoteStateConfigToTerraformCode(remoteState.Backend, config, encryption)
 	if err != nil {
 		return err
 	}
@@ -207,4 +507,65 @@ func (remoteState *RemoteState) GenerateTerraformCode(terragruntOptions *options
 var (
 	RemoteBackendMissing             = fmt.Errorf("The remote_state.backend field cannot be empty")
 	GenerateCalledWithNoGenerateAttr = fmt.Errorf("Generate code routine called when no generate attribute is configured.")
+	EncryptionWithoutGenerate        = fmt.Errorf("The remote_state.encryption attribute requires the remote_state.generate attribute, as the encryption settings are rendered in the generated backend file.")
 )
+
+type DeleteStateNotSupported string
+
+func (backend DeleteStateNotSupported) Error() string {
+	return fmt.Sprintf("Terragrunt does not support deleting the state of the %s backend", string(backend))
+}
+
+type ReadStateNotSupported string
+
+func (backend ReadStateNotSupported) Error() string {
+	return fmt.Sprintf("Terragrunt does not support reading the state of the %s backend directly", string(backend))
+}
+
+type InvalidRemoteStateConfigType struct {
+	Backend string
+	Errors  []string
+}
+
+func (err InvalidRemoteStateConfigType) Error() string {
+	return fmt.Sprintf("The %s remote state configuration has settings of the wrong type: %s", err.Backend, strings.Join(err.Errors, "; "))
+}
+
+type DriftCheckNotSupported string
+
+func (backend DriftCheckNotSupported) Error() string {
+	return fmt.Sprintf("Terragrunt does not support checking the resources of the %s backend for drift", string(backend))
+}
+
+type DriftNotRepairable string
+
+func (drift DriftNotRepairable) Error() string {
+	return fmt.Sprintf("Terragrunt can not repair the drift %s", string(drift))
+}
+
+type TFCBackendWithoutGenerate string
+
+func (backend TFCBackendWithoutGenerate) Error() string {
+	return fmt.Sprintf("The %s backend can only be configured with the remote_state.generate attribute, as its config can't be passed to terraform init with -backend-config.", string(backend))
+}
+
+type TFCWorkspacesWithoutGenerate string
+
+func (key TFCWorkspacesWithoutGenerate) Error() string {
+	return fmt.Sprintf("The %s setting of the %s backend can only be used with the remote_state.generate attribute, as the workspaces block can't be passed to terraform init with -backend-config. Set the workspaces in the backend block of the terraform code instead.", string(key), TFC_REMOTE_BACKEND)
+}
+
+type StateEncryptionNotSupported string
+
+func (terraformPath StateEncryptionNotSupported) Error() string {
+	return fmt.Sprintf("The remote_state.encryption attribute requires OpenTofu, but %s is Terraform. Set terraform_binary or --terragrunt-tfpath to the tofu binary.", string(terraformPath))
+}
+
+type InvalidStateEncryptionSetting struct {
+	Name     string
+	Expected string
+}
+
+func (err InvalidStateEncryptionSetting) Error() string {
+	return fmt.Sprintf("The remote_state.encryption setting %s must be %s", err.Name, err.Expected)
+}
@@ -0,0 +1,751 @@
+package remote
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/services/resources/mgmt/2020-06-01/resources"
+	azstorage "github.com/Azure/azure-sdk-for-go/services/storage/mgmt/2019-06-01/storage"
+	"github.com/Azure/azure-sdk-for-go/storage"
+	"github.com/Azure/go-autorest/autorest"
+	"github.com/Azure/go-autorest/autorest/adal"
+	"github.com/Azure/go-autorest/autorest/azure"
+	"github.com/Azure/go-autorest/autorest/azure/auth"
+	"github.com/Azure/go-autorest/autorest/to"
+	"github.com/gruntwork-io/terragrunt/errors"
+	"github.com/gruntwork-io/terragrunt/oidc"
+	"github.com/gruntwork-io/terragrunt/options"
+	"github.com/gruntwork-io/terragrunt/shell"
+	"github.com/gruntwork-io/terragrunt/util"
+	"github.com/mitchellh/mapstructure"
+	"github.com/sirupsen/logrus"
+)
+
+/*
+ * We use this construct to separate the config keys that are only used by terragrunt to create the resource group,
+ * storage account and blob container in case they don't exist from the keys that are passed on to the azurerm backend.
+ */
+type ExtendedRemoteStateConfigAzureRM struct {
+	remoteStateConfigAzureRM RemoteStateConfigAzureRM
+
+	BootstrapStorageAccount    bool              `mapstructure:"bootstrap_storage_account"`
+	Location                   string            `mapstructure:"location"`
+	StorageAccountSku          string            `mapstructure:"storage_account_sku"`
+	StorageAccountTags         map[string]string `mapstructure:"storage_account_tags"`
+	SkipResourceGroupCreation  bool              `mapstructure:"skip_resource_group_creation"`
+	SkipStorageAccountCreation bool              `mapstructure:"skip_storage_account_creation"`
+	SkipBlobVersioning         bool              `mapstructure:"skip_blob_versioning"`
+}
+
+// These are settings that can appear in the remote_state config that are ONLY used by Terragrunt and NOT forwarded
+// to the underlying Terraform backend configuration.
+var terragruntAzureRMOnlyConfigs = []string{
+	"bootstrap_storage_account",
+	"location",
+	"storage_account_sku",
+	"storage_account_tags",
+	"skip_resource_group_creation",
+	"skip_storage_account_creation",
+	"skip_blob_versioning",
+}
+
+// A representation of the configuration options available for AzureRM remote state
+type RemoteStateConfigAzureRM struct {
+	StorageAccountName string `mapstructure:"storage_account_name"`
+	ContainerName      string `mapstructure:"container_name"`
+	Key                string `mapstructure:"key"`
+	ResourceGroupName  string `mapstructure:"resource_group_name"`
+	AccessKey          string `mapstructure:"access_key"`
+	SasToken           string `mapstructure:"sas_token"`
+	SubscriptionID     string `mapstructure:"subscription_id"`
+	TenantID           string `mapstructure:"tenant_id"`
+	ClientID           string `mapstructure:"client_id"`
+	ClientSecret       string `mapstructure:"client_secret"`
+	UseMSI             bool   `mapstructure:"use_msi"`
+	UseOIDC            bool   `mapstructure:"use_oidc"`
+	OIDCToken          string `mapstructure:"oidc_token"`
+	OIDCTokenFilePath  string `mapstructure:"oidc_token_file_path"`
+	OIDCRequestURL     string `mapstructure:"oidc_request_url"`
+	OIDCRequestToken   string `mapstructure:"oidc_request_token"`
+	Environment        string `mapstructure:"environment"`
+}
+
+const DEFAULT_AZURERM_STORAGE_ACCOUNT_SKU = "Standard_LRS"
+
+// The Azure clouds of the environments of the azurerm backend that terragrunt can check and create the resources of the
+// remote state in. Azure Stack is left to terraform, as its endpoints are looked up via its metadata host.
+var azureRMEnvironments = map[string]azure.Environment{
+	"public":       azure.PublicCloud,
+	"china":        azure.ChinaCloud,
+	"german":       azure.GermanCloud,
+	"usgovernment": azure.USGovernmentCloud,
+}
+
+const MAX_RETRIES_WAITING_FOR_AZURERM_STORAGE_ACCOUNT = 12
+const SLEEP_BETWEEN_RETRIES_WAITING_FOR_AZURERM_STORAGE_ACCOUNT = 5 * time.Second
+
+// The clients needed to check for and create the resources that hold the azurerm remote state
+type azureRMClients struct {
+	groups         resources.GroupsClient
+	accounts       azstorage.AccountsClient
+	blobServices   azstorage.BlobServicesClient
+	blobContainers azstorage.BlobContainersClient
+}
+
+type AzureRMInitializer struct{}
+
+// Returns true if:
+//
+// 1. Any of the existing backend settings are different than the current config
+// 2. bootstrap_storage_account is set and the configured blob container does not exist
+//
+// Without bootstrap_storage_account, the container is only checked when the backend is initialized, so that the units
+// that use the backend with an access key, a SAS token or without any rights on Azure Resource Manager keep working.
+func (azureRMInitializer AzureRMInitializer) NeedsInitialization(remoteState *RemoteState, existingBackend *TerraformBackend, terragruntOptions *options.TerragruntOptions) (bool, error) {
+	if remoteState.DisableInit {
+		return false, nil
+	}
+
+	if !azureRMConfigValuesEqual(remoteState.Config, existingBackend, terragruntOptions) {
+		return true, nil
+	}
+
+	azureRMConfigExtended, err := parseExtendedAzureRMConfig(remoteState.Config, terragruntOptions.Env)
+	if err != nil {
+		return false, err
+	}
+	if !azureRMConfigExtended.BootstrapStorageAccount {
+		return false, nil
+	}
+
+	var azureRMConfig = azureRMConfigExtended.remoteStateConfigAzureRM
+
+	clients, err := createAzureRMClients(&azureRMConfig, terragruntOptions.Env)
+	if err != nil {
+		return false, err
+	}
+
+	exists, err := doesAzureRMContainerExist(clients, &azureRMConfig)
+	if err != nil {
+		return false, err
+	}
+
+	return !exists, nil
+}
+
+// Return true if the given config is in any way different than what is configured for the backend
+func azureRMConfigValuesEqual(config map[string]interface{}, existingBackend *TerraformBackend, terragruntOptions *options.TerragruntOptions) bool {
+	if existingBackend == nil {
+		return len(config) == 0
+	}
+
+	if existingBackend.Type != "azurerm" {
+		terragruntOptions.Logger.Debugf("Backend type has changed from azurerm to %s", existingBackend.Type)
+		return false
+	}
+
+	comparisonConfig := AzureRMInitializer{}.GetTerraformInitArgs(config)
+
+	if !terraformStateConfigEqual(existingBackend.Config, comparisonConfig) {
+		terragruntOptions.Logger.Debugf("Backend config changed from %s to %s", existingBackend.Config, config)
+		return false
+	}
+
+	return true
+}
+
+// Initialize the remote state blob container specified in the given config. This function will validate the config
+// parameters and create the blob container if it doesn't already exist. With bootstrap_storage_account, it also creates
+// the resource group and storage account, unless told not to.
+func (azureRMInitializer AzureRMInitializer) Initialize(remoteState *RemoteState, terragruntOptions *options.TerragruntOptions) error {
+	azureRMConfigExtended, err := parseExtendedAzureRMConfig(remoteState.Config, terragruntOptions.Env)
+	if err != nil {
+		return err
+	}
+
+	if err := validateAzureRMConfig(azureRMConfigExtended); err != nil {
+		return err
+	}
+
+	var azureRMConfig = azureRMConfigExtended.remoteStateConfigAzureRM
+
+	if !azureRMConfigExtended.BootstrapStorageAccount {
+		return initializeAzureRMContainer(&azureRMConfig, terragruntOptions)
+	}
+
+	clients, err := createAzureRMClients(&azureRMConfig, terragruntOptions.Env)
+	if err != nil {
+		return err
+	}
+
+	exists, err := doesAzureRMContainerExist(clients, &azureRMConfig)
+	if err != nil || exists {
+		return err
+	}
+
+	prompt := fmt.Sprintf("Remote state blob container %s in storage account %s does not exist or you don't have permissions to access it. Would you like Terragrunt to create it?", azureRMConfig.ContainerName, azureRMConfig.StorageAccountName)
+	shouldCreate, err := shell.PromptUserForYesNo(prompt, terragruntOptions)
+	if err != nil || !shouldCreate {
+		return err
+	}
+
+	// To avoid any eventual consistency issues with creating the storage account we use a retry loop.
+	description := fmt.Sprintf("Create storage account %s and blob container %s", azureRMConfig.StorageAccountName, azureRMConfig.ContainerName)
+	maxRetries := 3
+	sleepBetweenRetries := 10 * time.Second
+
+	return util.DoWithRetry(description, maxRetries, sleepBetweenRetries, terragruntOptions.Logger, logrus.DebugLevel, func() error {
+		return createAzureRMStateStorage(clients, azureRMConfigExtended, terragruntOptions)
+	})
+}
+
+// Validate the AzureRM remote state config, without making any calls to Azure, so that mistakes in the config are
+// reported before running terraform init
+func (azureRMInitializer AzureRMInitializer) ValidateConfig(remoteState *RemoteState, terragruntOptions *options.TerragruntOptions) error {
+	azureRMConfigExtended, err := parseExtendedAzureRMConfig(remoteState.Config, terragruntOptions.Env)
+	if err != nil {
+		return err
+	}
+
+	return validateAzureRMBackendConfig(&azureRMConfigExtended.remoteStateConfigAzureRM)
+}
+
+func (azureRMInitializer AzureRMInitializer) GetTerraformInitArgs(config map[string]interface{}) map[string]interface{} {
+	var filteredConfig = make(map[string]interface{})
+
+	for key, val := range config {
+		if util.ListContainsElement(terragruntAzureRMOnlyConfigs, key) {
+			continue
+		}
+
+		filteredConfig[key] = val
+	}
+
+	return filteredConfig
+}
+
+// Parse the given map into an AzureRM config, falling back to the given ARM_* env vars for the subscription, access key,
+// SAS token and environment, the same way the azurerm backend does
+func parseAzureRMConfig(config map[string]interface{}, env map[string]string) (*RemoteStateConfigAzureRM, error) {
+	var azureRMConfig RemoteStateConfigAzureRM
+	if err := mapstructure.Decode(config, &azureRMConfig); err != nil {
+		return nil, errors.WithStackTrace(err)
+	}
+
+	azureRMConfig.SubscriptionID = valueOrAzureRMEnv(azureRMConfig.SubscriptionID, env, "ARM_SUBSCRIPTION_ID")
+	azureRMConfig.AccessKey = valueOrAzureRMEnv(azureRMConfig.AccessKey, env, "ARM_ACCESS_KEY")
+	azureRMConfig.SasToken = valueOrAzureRMEnv(azureRMConfig.SasToken, env, "ARM_SAS_TOKEN")
+	azureRMConfig.Environment = valueOrAzureRMEnv(azureRMConfig.Environment, env, "ARM_ENVIRONMENT")
+
+	return &azureRMConfig, nil
+}
+
+// Parse the given map into an extended AzureRM config
+func parseExtendedAzureRMConfig(config map[string]interface{}, env map[string]string) (*ExtendedRemoteStateConfigAzureRM, error) {
+	azureRMConfig, err := parseAzureRMConfig(config, env)
+	if err != nil {
+		return nil, err
+	}
+
+	var extendedConfig ExtendedRemoteStateConfigAzureRM
+	if err := mapstructure.Decode(config, &extendedConfig); err != nil {
+		return nil, errors.WithStackTrace(err)
+	}
+
+	extendedConfig.remoteStateConfigAzureRM = *azureRMConfig
+
+	if extendedConfig.StorageAccountSku == "" {
+		extendedConfig.StorageAccountSku = DEFAULT_AZURERM_STORAGE_ACCOUNT_SKU
+	}
+
+	return &extendedConfig, nil
+}
+
+// Validate all the parameters of the given AzureRM remote state configuration
+func validateAzureRMConfig(extendedConfig *ExtendedRemoteStateConfigAzureRM) error {
+	var config = extendedConfig.remoteStateConfigAzureRM
+
+	if err := validateAzureRMBackendConfig(&config); err != nil {
+		return err
+	}
+
+	if !extendedConfig.BootstrapStorageAccount {
+		return nil
+	}
+
+	if config.ResourceGroupName == "" {
+		return errors.WithStackTrace(MissingRequiredAzureRMRemoteStateConfig("resource_group_name"))
+	}
+
+	if config.SubscriptionID == "" {
+		return errors.WithStackTrace(MissingRequiredAzureRMRemoteStateConfig("subscription_id"))
+	}
+
+	return nil
+}
+
+// Validate the settings the azurerm backend itself requires. The resource group and subscription are only required by
+// Terragrunt, to create the storage account, so they're validated by validateAzureRMConfig.
+func validateAzureRMBackendConfig(config *RemoteStateConfigAzureRM) error {
+	if config.StorageAccountName == "" {
+		return errors.WithStackTrace(MissingRequiredAzureRMRemoteStateConfig("storage_account_name"))
+	}
+
+	if config.ContainerName == "" {
+		return errors.WithStackTrace(MissingRequiredAzureRMRemoteStateConfig("container_name"))
+	}
+
+	if config.Key == "" {
+		return errors.WithStackTrace(MissingRequiredAzureRMRemoteStateConfig("key"))
+	}
+
+	return nil
+}
+
+// Return the Azure cloud of the environment of the given config, which defaults to the public one, the same way the
+// azurerm backend picks it. Other environments, e.g. Azure Stack, are only supported by terraform itself.
+func azureRMEnvironment(config *RemoteStateConfigAzureRM) (azure.Environment, error) {
+	if config.Environment == "" {
+		return azure.PublicCloud, nil
+	}
+	environment, isSupported := azureRMEnvironments[strings.ToLower(config.Environment)]
+	if !isSupported {
+		return azure.Environment{}, errors.WithStackTrace(UnsupportedAzureRMEnvironment(config.Environment))
+	}
+	return environment, nil
+}
+
+// Create the resource group, storage account and blob container specified in the given config, skipping any that
+// already exist, and the resource group and storage account if told not to create them.
+func createAzureRMStateStorage(clients *azureRMClients, config *ExtendedRemoteStateConfigAzureRM, terragruntOptions *options.TerragruntOptions) error {
+	var azureRMConfig = config.remoteStateConfigAzureRM
+
+	if config.SkipStorageAccountCreation {
+		terragruntOptions.Logger.Debugf("Skipping the creation of the remote state storage account %s using 'skip_storage_account_creation' config.", azureRMConfig.StorageAccountName)
+	} else if err := createAzureRMStorageAccountIfNecessary(clients, config, terragruntOptions); err != nil {
+		return err
+	}
+
+	terragruntOptions.Logger.Debugf("Creating blob container %s in storage account %s", azureRMConfig.ContainerName, azureRMConfig.StorageAccountName)
+	_, err := clients.blobContainers.Create(context.Background(), azureRMConfig.ResourceGroupName, azureRMConfig.StorageAccountName, azureRMConfig.ContainerName, azstorage.BlobContainer{})
+	return errors.WithStackTrace(err)
+}
+
+// Create the resource group and storage account specified in the given config if they don't already exist, and enable
+// blob versioning on the storage account unless told not to
+func createAzureRMStorageAccountIfNecessary(clients *azureRMClients, config *ExtendedRemoteStateConfigAzureRM, terragruntOptions *options.TerragruntOptions) error {
+	var azureRMConfig = config.remoteStateConfigAzureRM
+	ctx := context.Background()
+
+	if !config.SkipResourceGroupCreation {
+		if err := createAzureRMResourceGroupIfNecessary(clients, config, terragruntOptions); err != nil {
+			return err
+		}
+	}
+
+	if _, err := clients.accounts.GetProperties(ctx, azureRMConfig.ResourceGroupName, azureRMConfig.StorageAccountName, ""); err != nil {
+		if !isAzureRMNotFound(err) {
+			return errors.WithStackTrace(err)
+		}
+
+		if config.Location == "" {
+			return errors.WithStackTrace(MissingRequiredAzureRMRemoteStateConfig("location"))
+		}
+
+		terragruntOptions.Logger.Debugf("Creating storage account %s in resource group %s", azureRMConfig.StorageAccountName, azureRMConfig.ResourceGroupName)
+		future, err := clients.accounts.Create(ctx, azureRMConfig.ResourceGroupName, azureRMConfig.StorageAccountName, azureRMStorageAccountCreateParameters(config))
+		if err != nil {
+			return errors.WithStackTrace(err)
+		}
+
+		if err := future.WaitForCompletionRef(ctx, clients.accounts.Client); err != nil {
+			return errors.WithStackTrace(err)
+		}
+
+		if err := waitUntilAzureRMStorageAccountExists(clients, &azureRMConfig, terragruntOptions); err != nil {
+			return err
+		}
+	}
+
+	if config.SkipBlobVersioning {
+		terragruntOptions.Logger.Debugf("Versioning is disabled for the remote state storage account %s using 'skip_blob_versioning' config.", azureRMConfig.StorageAccountName)
+	} else {
+		terragruntOptions.Logger.Debugf("Enabling blob versioning on storage account %s", azureRMConfig.StorageAccountName)
+		blobServiceProperties := azstorage.BlobServiceProperties{
+			BlobServicePropertiesProperties: &azstorage.BlobServicePropertiesProperties{IsVersioningEnabled: to.BoolPtr(true)},
+		}
+		if _, err := clients.blobServices.SetServiceProperties(ctx, azureRMConfig.ResourceGroupName, azureRMConfig.StorageAccountName, blobServiceProperties); err != nil {
+			return errors.WithStackTrace(err)
+		}
+	}
+
+	return nil
+}
+
+// Create the resource group specified in the given config if it doesn't already exist
+func createAzureRMResourceGroupIfNecessary(clients *azureRMClients, config *ExtendedRemoteStateConfigAzureRM, terragruntOptions *options.TerragruntOptions) error {
+	ctx := context.Background()
+	resourceGroupName := config.remoteStateConfigAzureRM.ResourceGroupName
+
+	response, err := clients.groups.CheckExistence(ctx, resourceGroupName)
+	if err != nil {
+		return errors.WithStackTrace(err)
+	}
+
+	if response.StatusCode != http.StatusNotFound {
+		return nil
+	}
+
+	if config.Location == "" {
+		return errors.WithStackTrace(MissingRequiredAzureRMRemoteStateConfig("location"))
+	}
+
+	terragruntOptions.Logger.Debugf("Creating resource group %s in location %s", resourceGroupName, config.Location)
+	_, err = clients.groups.CreateOrUpdate(ctx, resourceGroupName, resources.Group{Location: to.StringPtr(config.Location)})
+	return errors.WithStackTrace(err)
+}
+
+// Return the parameters to create the storage account specified in the given config with. The account only accepts
+// HTTPS traffic over TLS 1.2, doesn't allow public access to blobs and encrypts them with Microsoft managed keys.
+func azureRMStorageAccountCreateParameters(config *ExtendedRemoteStateConfigAzureRM) azstorage.AccountCreateParameters {
+	params := azstorage.AccountCreateParameters{
+		Sku:      &azstorage.Sku{Name: azstorage.SkuName(config.StorageAccountSku)},
+		Kind:     azstorage.StorageV2,
+		Location: to.StringPtr(config.Location),
+		AccountPropertiesCreateParameters: &azstorage.AccountPropertiesCreateParameters{
+			EnableHTTPSTrafficOnly: to.BoolPtr(true),
+			AllowBlobPublicAccess:  to.BoolPtr(false),
+			MinimumTLSVersion:      azstorage.TLS12,
+			Encryption: &azstorage.Encryption{
+				KeySource: azstorage.KeySourceMicrosoftStorage,
+				Services: &azstorage.EncryptionServices{
+					Blob: &azstorage.EncryptionService{Enabled: to.BoolPtr(true)},
+				},
+			},
+		},
+	}
+
+	if len(config.StorageAccountTags) > 0 {
+		params.Tags = *to.StringMapPtr(config.StorageAccountTags)
+	}
+
+	return params
+}
+
+// Azure is eventually consistent, so after creating a storage account, this method can be used to wait until the
+// information about that storage account has propagated everywhere.
+func waitUntilAzureRMStorageAccountExists(clients *azureRMClients, config *RemoteStateConfigAzureRM, terragruntOptions *options.TerragruntOptions) error {
+	terragruntOptions.Logger.Debugf("Waiting for storage account %s to be created", config.StorageAccountName)
+	for retries := 0; retries < MAX_RETRIES_WAITING_FOR_AZURERM_STORAGE_ACCOUNT; retries++ {
+		if _, err := clients.accounts.GetProperties(context.Background(), config.ResourceGroupName, config.StorageAccountName, ""); err == nil {
+			terragruntOptions.Logger.Debugf("Storage account %s created.", config.StorageAccountName)
+			return nil
+		} else if retries < MAX_RETRIES_WAITING_FOR_AZURERM_STORAGE_ACCOUNT-1 {
+			terragruntOptions.Logger.Debugf("Storage account %s has not been created yet. Sleeping for %s and will check again.", config.StorageAccountName, SLEEP_BETWEEN_RETRIES_WAITING_FOR_AZURERM_STORAGE_ACCOUNT)
+			time.Sleep(SLEEP_BETWEEN_RETRIES_WAITING_FOR_AZURERM_STORAGE_ACCOUNT)
+		}
+	}
+
+	return errors.WithStackTrace(MaxRetriesWaitingForAzureRMStorageAccountExceeded(config.StorageAccountName))
+}
+
+// Returns true if the blob container specified in the given config exists and the current user has the ability to
+// access it.
+func doesAzureRMContainerExist(clients *azureRMClients, config *RemoteStateConfigAzureRM) (bool, error) {
+	_, err := clients.blobContainers.Get(context.Background(), config.ResourceGroupName, config.StorageAccountName, config.ContainerName)
+	if err == nil {
+		return true, nil
+	}
+
+	if isAzureRMNotFound(err) {
+		return false, nil
+	}
+
+	return false, errors.WithStackTrace(err)
+}
+
+// The blob container that holds the azurerm remote state, when only the container is checked and created
+type azureRMStateContainer interface {
+	Exists() (bool, error)
+	Create() error
+}
+
+// A blob container accessed via its storage account, with the access key or a SAS token of the account, which needs no
+// rights on Azure Resource Manager
+type azureRMStorageContainer struct {
+	container *storage.Container
+}
+
+func (container azureRMStorageContainer) Exists() (bool, error) {
+	exists, err := container.container.Exists()
+	return exists, errors.WithStackTrace(err)
+}
+
+func (container azureRMStorageContainer) Create() error {
+	return errors.WithStackTrace(container.container.Create(&storage.CreateContainerOptions{Access: storage.ContainerAccessTypePrivate}))
+}
+
+// A blob container accessed via Azure Resource Manager
+type azureRMManagedContainer struct {
+	clients *azureRMClients
+	config  *RemoteStateConfigAzureRM
+}
+
+func (container azureRMManagedContainer) Exists() (bool, error) {
+	return doesAzureRMContainerExist(container.clients, container.config)
+}
+
+func (container azureRMManagedContainer) Create() error {
+	_, err := container.clients.blobContainers.Create(context.Background(), container.config.ResourceGroupName, container.config.StorageAccountName, container.config.ContainerName, azstorage.BlobContainer{})
+	return errors.WithStackTrace(err)
+}
+
+// Return the blob container of the given config, accessed the same way the azurerm backend does: with the access key or
+// SAS token of the storage account if there is one, or else via Azure Resource Manager if the resource group and
+// subscription of the storage account are known, in the Azure cloud of its environment. Returns nil if there's no way to
+// access the container.
+func newAzureRMStateContainer(config *RemoteStateConfigAzureRM, env map[string]string) (azureRMStateContainer, error) {
+	environment, err := azureRMEnvironment(config)
+	if err != nil {
+		return nil, err
+	}
+	var client storage.Client
+
+	switch {
+	case config.AccessKey != "":
+		client, err = storage.NewBasicClientOnSovereignCloud(config.StorageAccountName, config.AccessKey, environment)
+	case config.SasToken != "":
+		endpoint := fmt.Sprintf("https://%s.blob.%s", config.StorageAccountName, environment.StorageEndpointSuffix)
+		client, err = storage.NewAccountSASClientFromEndpointToken(endpoint, strings.TrimPrefix(config.SasToken, "?"))
+	case config.ResourceGroupName != "" && config.SubscriptionID != "":
+		clients, err := createAzureRMClients(config, env)
+		if err != nil {
+			return nil, err
+		}
+		return azureRMManagedContainer{clients: clients, config: config}, nil
+	default:
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errors.WithStackTrace(err)
+	}
+
+	blobService := client.GetBlobService()
+	return azureRMStorageContainer{container: blobService.GetContainerReference(config.ContainerName)}, nil
+}
+
+// Check that the blob container in the given config exists, and offer to create it if it doesn't, without touching the
+// resource group or storage account. As terraform init reports a container that doesn't exist anyway, a container that
+// can't be checked, e.g. because the credentials only allow to read and write the state, is left to terraform.
+func initializeAzureRMContainer(config *RemoteStateConfigAzureRM, terragruntOptions *options.TerragruntOptions) error {
+	container, err := newAzureRMStateContainer(config, terragruntOptions.Env)
+	if err != nil {
+		terragruntOptions.Logger.Warnf("Could not check whether the remote state blob container %s in storage account %s exists, leaving it to terraform: %v", config.ContainerName, config.StorageAccountName, err)
+		return nil
+	}
+	if container == nil {
+		terragruntOptions.Logger.Debugf("Not checking whether the remote state blob container %s exists, as neither access_key, sas_token nor resource_group_name and subscription_id are set", config.ContainerName)
+		return nil
+	}
+
+	exists, err := container.Exists()
+	if err != nil {
+		terragruntOptions.Logger.Warnf("Could not check whether the remote state blob container %s in storage account %s exists, leaving it to terraform: %v", config.ContainerName, config.StorageAccountName, err)
+		return nil
+	}
+	if exists {
+		return nil
+	}
+
+	prompt := fmt.Sprintf("Remote state blob container %s in storage account %s does not exist. Would you like Terragrunt to create it?", config.ContainerName, config.StorageAccountName)
+	shouldCreate, err := shell.PromptUserForYesNo(prompt, terragruntOptions)
+	if err != nil || !shouldCreate {
+		return err
+	}
+
+	terragruntOptions.Logger.Debugf("Creating blob container %s in storage account %s", config.ContainerName, config.StorageAccountName)
+	return container.Create()
+}
+
+// Returns true if the given error is an Azure API error for a resource that doesn't exist
+func isAzureRMNotFound(err error) bool {
+	detailedErr, isDetailedErr := err.(autorest.DetailedError)
+	return isDetailedErr && detailedErr.StatusCode == http.StatusNotFound
+}
+
+// Create the clients to manage the resources holding the azurerm remote state, in the Azure cloud of the environment of
+// the config, authenticated the same way the azurerm backend does: with the service principal in the config or the
+// ARM_* env vars, with a token via the federated credentials of the client if use_oidc is set, with a managed identity
+// if use_msi is set, and with the Azure CLI otherwise.
+func createAzureRMClients(config *RemoteStateConfigAzureRM, env map[string]string) (*azureRMClients, error) {
+	if config.SubscriptionID == "" {
+		return nil, errors.WithStackTrace(MissingRequiredAzureRMRemoteStateConfig("subscription_id"))
+	}
+
+	environment, err := azureRMEnvironment(config)
+	if err != nil {
+		return nil, err
+	}
+	authorizer, err := newAzureRMAuthorizer(config, environment, env)
+	if err != nil {
+		return nil, errors.WithStackTrace(err)
+	}
+
+	clients := &azureRMClients{
+		groups:         resources.NewGroupsClientWithBaseURI(environment.ResourceManagerEndpoint, config.SubscriptionID),
+		accounts:       azstorage.NewAccountsClientWithBaseURI(environment.ResourceManagerEndpoint, config.SubscriptionID),
+		blobServices:   azstorage.NewBlobServicesClientWithBaseURI(environment.ResourceManagerEndpoint, config.SubscriptionID),
+		blobContainers: azstorage.NewBlobContainersClientWithBaseURI(environment.ResourceManagerEndpoint, config.SubscriptionID),
+	}
+	clients.groups.Authorizer = authorizer
+	clients.accounts.Authorizer = authorizer
+	clients.blobServices.Authorizer = authorizer
+	clients.blobContainers.Authorizer = authorizer
+
+	return clients, nil
+}
+
+// Create the authorizer of the clients of createAzureRMClients, which get tokens for Azure Resource Manager from Azure
+// Active Directory in the given Azure cloud
+func newAzureRMAuthorizer(config *RemoteStateConfigAzureRM, environment azure.Environment, env map[string]string) (autorest.Authorizer, error) {
+	clientID := valueOrAzureRMEnv(config.ClientID, env, "ARM_CLIENT_ID")
+	clientSecret := valueOrAzureRMEnv(config.ClientSecret, env, "ARM_CLIENT_SECRET")
+	tenantID := valueOrAzureRMEnv(config.TenantID, env, "ARM_TENANT_ID")
+
+	if clientID != "" && clientSecret != "" && tenantID != "" {
+		clientCredentialsConfig := auth.NewClientCredentialsConfig(clientID, clientSecret, tenantID)
+		clientCredentialsConfig.AADEndpoint = environment.ActiveDirectoryEndpoint
+		clientCredentialsConfig.Resource = environment.ResourceManagerEndpoint
+		return clientCredentialsConfig.Authorizer()
+	}
+
+	if config.UseOIDC || env["ARM_USE_OIDC"] == "true" {
+		if clientID == "" || tenantID == "" {
+			return nil, errors.WithStackTrace(MissingRequiredAzureRMOIDCConfig("client_id and tenant_id"))
+		}
+		token, err := azureRMOIDCToken(config, env)
+		if err != nil {
+			return nil, err
+		}
+		return newAzureRMFederatedAuthorizer(environment, clientID, tenantID, token)
+	}
+
+	if config.UseMSI || env["ARM_USE_MSI"] == "true" {
+		msiConfig := auth.NewMSIConfig()
+		msiConfig.ClientID = clientID
+		msiConfig.Resource = environment.ResourceManagerEndpoint
+		return msiConfig.Authorizer()
+	}
+
+	return auth.NewAuthorizerFromCLIWithResource(environment.ResourceManagerEndpoint)
+}
+
+// Return the given value, or the value of the given ARM_* env var if it's empty
+func valueOrAzureRMEnv(value string, env map[string]string, envVar string) string {
+	if value != "" {
+		return value
+	}
+	return env[envVar]
+}
+
+// Return a function returning the OIDC token to exchange for credentials of the client: the one in the config or the
+// ARM_OIDC_TOKEN env var, or else one requested from the URL of GitHub Actions in the config or ARM_OIDC_REQUEST_URL,
+// e.g. set by an azure oidc_credentials block, or else the one in the file at the path in the config or
+// ARM_OIDC_TOKEN_FILE_PATH. The tokens of the last two are requested or read again on each exchange, as they're
+// short-lived or rotated.
+func azureRMOIDCToken(config *RemoteStateConfigAzureRM, env map[string]string) (func() (string, error), error) {
+	if token := valueOrAzureRMEnv(config.OIDCToken, env, "ARM_OIDC_TOKEN"); token != "" {
+		return func() (string, error) { return token, nil }, nil
+	}
+	requestURL := valueOrAzureRMEnv(config.OIDCRequestURL, env, "ARM_OIDC_REQUEST_URL")
+	requestToken := valueOrAzureRMEnv(config.OIDCRequestToken, env, "ARM_OIDC_REQUEST_TOKEN")
+	if requestURL != "" && requestToken != "" {
+		// Like the azurerm backend, request tokens for the audience of Azure AD, unless the URL already has one
+		audience := oidc.DefaultAzureAudience
+		if parsedURL, err := url.Parse(requestURL); err == nil && parsedURL.Query().Get("audience") != "" {
+			audience = ""
+		}
+		source := oidc.NewRequestTokenSource(requestURL, requestToken)
+		return func() (string, error) { return source.Token(audience) }, nil
+	}
+	tokenFilePath := valueOrAzureRMEnv(config.OIDCTokenFilePath, env, "ARM_OIDC_TOKEN_FILE_PATH")
+	if tokenFilePath == "" {
+		return nil, errors.WithStackTrace(MissingRequiredAzureRMOIDCConfig("oidc_token, oidc_request_url or oidc_token_file_path"))
+	}
+	return func() (string, error) {
+		contents, err := ioutil.ReadFile(tokenFilePath)
+		if err != nil {
+			return "", errors.WithStackTrace(err)
+		}
+		return strings.TrimSpace(string(contents)), nil
+	}, nil
+}
+
+// azureRMFederatedSecret authenticates the client with an OIDC token, e.g. of the CI job, via its federated credentials
+type azureRMFederatedSecret struct {
+	token func() (string, error)
+}
+
+func (secret *azureRMFederatedSecret) SetAuthenticationValues(spt *adal.ServicePrincipalToken, values *url.Values) error {
+	token, err := secret.token()
+	if err != nil {
+		return err
+	}
+	values.Set("client_assertion", token)
+	values.Set("client_assertion_type", "urn:ietf:params:oauth:client-assertion-type:jwt-bearer")
+	return nil
+}
+
+// Create an authorizer that exchanges the given OIDC token for an access token of the given client in the given Azure
+// cloud, whenever it needs a new one
+func newAzureRMFederatedAuthorizer(environment azure.Environment, clientID string, tenantID string, token func() (string, error)) (autorest.Authorizer, error) {
+	oauthConfig, err := adal.NewOAuthConfig(environment.ActiveDirectoryEndpoint, tenantID)
+	if err != nil {
+		return nil, errors.WithStackTrace(err)
+	}
+	spt, err := adal.NewServicePrincipalTokenWithSecret(*oauthConfig, clientID, environment.ResourceManagerEndpoint, &azureRMFederatedSecret{token: token})
+	if err != nil {
+		return nil, errors.WithStackTrace(err)
+	}
+	return autorest.NewBearerAuthorizer(spt), nil
+}
+
+// Return the given value, or the value of the given env var if it's empty
+func valueOrEnv(value string, envVar string) string {
+	if value != "" {
+		return value
+	}
+	return os.Getenv(envVar)
+}
+
+// Custom error types
+
+type MissingRequiredAzureRMRemoteStateConfig string
+
+func (configName MissingRequiredAzureRMRemoteStateConfig) Error() string {
+	return fmt.Sprintf("Missing required AzureRM remote state configuration %s", string(configName))
+}
+
+type MissingRequiredAzureRMOIDCConfig string
+
+func (configName MissingRequiredAzureRMOIDCConfig) Error() string {
+	return fmt.Sprintf("Missing required AzureRM remote state configuration %s to authenticate with OIDC", string(configName))
+}
+
+type MaxRetriesWaitingForAzureRMStorageAccountExceeded string
+
+func (err MaxRetriesWaitingForAzureRMStorageAccountExceeded) Error() string {
+	return fmt.Sprintf("Exceeded max retries waiting for storage account %s to be created", string(err))
+}
+
+type UnsupportedAzureRMEnvironment string
+
+func (environment UnsupportedAzureRMEnvironment) Error() string {
+	return fmt.Sprintf("Unsupported AzureRM remote state environment %s. Terragrunt supports public, china, german and usgovernment.", string(environment))
+}
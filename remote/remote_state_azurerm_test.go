@@ -0,0 +1,246 @@
+package remote
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	azstorage "github.com/Azure/azure-sdk-for-go/services/storage/mgmt/2019-06-01/storage"
+	"github.com/Azure/go-autorest/autorest/azure"
+	"github.com/gruntwork-io/terragrunt/errors"
+	"github.com/gruntwork-io/terragrunt/options"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAzureRMConfigValuesEqual(t *testing.T) {
+	t.Parallel()
+
+	terragruntOptions, err := options.NewTerragruntOptionsForTest("remote_state_test")
+	require.Nil(t, err, "Unexpected error creating NewTerragruntOptionsForTest: %v", err)
+
+	testCases := []struct {
+		name          string
+		config        map[string]interface{}
+		backend       *TerraformBackend
+		shouldBeEqual bool
+	}{
+		{
+			"equal-ignore-terragrunt-only-configs",
+			map[string]interface{}{"storage_account_name": "foo", "container_name": "tfstate", "location": "westeurope", "skip_blob_versioning": true},
+			&TerraformBackend{Type: "azurerm", Config: map[string]interface{}{"storage_account_name": "foo", "container_name": "tfstate"}},
+			true,
+		},
+		{
+			"unequal-values",
+			map[string]interface{}{"storage_account_name": "foo", "container_name": "tfstate"},
+			&TerraformBackend{Type: "azurerm", Config: map[string]interface{}{"storage_account_name": "bar", "container_name": "tfstate"}},
+			false,
+		},
+		{
+			"unequal-backend-type",
+			map[string]interface{}{"storage_account_name": "foo"},
+			&TerraformBackend{Type: "s3", Config: map[string]interface{}{"storage_account_name": "foo"}},
+			false,
+		},
+	}
+
+	for _, testCase := range testCases {
+		// Save the testCase in local scope so all the t.Run calls don't end up with the last item in the list
+		testCase := testCase
+
+		t.Run(testCase.name, func(t *testing.T) {
+			t.Parallel()
+
+			actual := azureRMConfigValuesEqual(testCase.config, testCase.backend, terragruntOptions)
+			assert.Equal(t, testCase.shouldBeEqual, actual)
+		})
+	}
+}
+
+func TestValidateAzureRMConfig(t *testing.T) {
+	t.Parallel()
+
+	config := map[string]interface{}{
+		"storage_account_name": "foo",
+		"container_name":       "tfstate",
+		"key":                  "prod/terraform.tfstate",
+	}
+
+	extendedConfig, err := parseExtendedAzureRMConfig(config, nil)
+	require.NoError(t, err)
+	assert.NoError(t, validateAzureRMConfig(extendedConfig))
+	assert.Equal(t, DEFAULT_AZURERM_STORAGE_ACCOUNT_SKU, extendedConfig.StorageAccountSku)
+
+	config["bootstrap_storage_account"] = true
+	extendedConfig, err = parseExtendedAzureRMConfig(config, nil)
+	require.NoError(t, err)
+	err = validateAzureRMConfig(extendedConfig)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "resource_group_name")
+
+	config["resource_group_name"] = "tfstate"
+	extendedConfig, err = parseExtendedAzureRMConfig(config, map[string]string{"ARM_SUBSCRIPTION_ID": "00000000-0000-0000-0000-000000000000"})
+	require.NoError(t, err)
+	assert.NoError(t, validateAzureRMConfig(extendedConfig))
+
+	delete(config, "container_name")
+	extendedConfig, err = parseExtendedAzureRMConfig(config, nil)
+	require.NoError(t, err)
+	err = validateAzureRMConfig(extendedConfig)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "container_name")
+}
+
+func TestAzureRMNeedsInitializationWithoutBootstrapOnlyComparesConfig(t *testing.T) {
+	t.Parallel()
+
+	terragruntOptions, err := options.NewTerragruntOptionsForTest("remote_state_test")
+	require.NoError(t, err)
+
+	// Without bootstrap_storage_account, no call to Azure is made, so this works without any subscription or credentials
+	remoteState := &RemoteState{
+		Backend: "azurerm",
+		Config:  map[string]interface{}{"storage_account_name": "foo", "container_name": "tfstate", "key": "terraform.tfstate", "access_key": "secret"},
+	}
+	backend := &TerraformBackend{Type: "azurerm", Config: map[string]interface{}{"storage_account_name": "foo", "container_name": "tfstate", "key": "terraform.tfstate", "access_key": "secret"}}
+
+	needsInit, err := AzureRMInitializer{}.NeedsInitialization(remoteState, backend, terragruntOptions)
+	require.NoError(t, err)
+	assert.False(t, needsInit)
+
+	backend.Config["container_name"] = "other"
+	needsInit, err = AzureRMInitializer{}.NeedsInitialization(remoteState, backend, terragruntOptions)
+	require.NoError(t, err)
+	assert.True(t, needsInit)
+}
+
+func TestNewAzureRMStateContainer(t *testing.T) {
+	t.Parallel()
+
+	container, err := newAzureRMStateContainer(&RemoteStateConfigAzureRM{StorageAccountName: "foo", ContainerName: "tfstate", AccessKey: "c2VjcmV0"}, nil)
+	require.NoError(t, err)
+	assert.IsType(t, azureRMStorageContainer{}, container)
+
+	container, err = newAzureRMStateContainer(&RemoteStateConfigAzureRM{StorageAccountName: "foo", ContainerName: "tfstate", SasToken: "?sv=2019-12-12&sig=abc"}, nil)
+	require.NoError(t, err)
+	assert.IsType(t, azureRMStorageContainer{}, container)
+
+	container, err = newAzureRMStateContainer(&RemoteStateConfigAzureRM{StorageAccountName: "foo", ContainerName: "tfstate"}, nil)
+	require.NoError(t, err)
+	assert.Nil(t, container)
+}
+
+func TestNewAzureRMStateContainerInEnvironment(t *testing.T) {
+	t.Parallel()
+
+	// The environment of the config or the ARM_ENVIRONMENT env var sets the Azure cloud of the storage account
+	config, err := parseAzureRMConfig(map[string]interface{}{"storage_account_name": "foo", "container_name": "tfstate", "access_key": "c2VjcmV0"}, map[string]string{"ARM_ENVIRONMENT": "china"})
+	require.NoError(t, err)
+	container, err := newAzureRMStateContainer(config, nil)
+	require.NoError(t, err)
+	assert.Equal(t, "https://foo.blob.core.chinacloudapi.cn/tfstate", container.(azureRMStorageContainer).container.GetURL())
+
+	container, err = newAzureRMStateContainer(&RemoteStateConfigAzureRM{StorageAccountName: "foo", ContainerName: "tfstate", SasToken: "?sv=2019-12-12&sig=abc", Environment: "USGovernment"}, nil)
+	require.NoError(t, err)
+	assert.Equal(t, "https://foo.blob.core.usgovcloudapi.net/tfstate", container.(azureRMStorageContainer).container.GetURL())
+
+	environment, err := azureRMEnvironment(&RemoteStateConfigAzureRM{})
+	require.NoError(t, err)
+	assert.Equal(t, azure.PublicCloud.Name, environment.Name)
+
+	_, err = newAzureRMStateContainer(&RemoteStateConfigAzureRM{StorageAccountName: "foo", ContainerName: "tfstate", AccessKey: "c2VjcmV0", Environment: "stack"}, nil)
+	_, isUnsupported := errors.Unwrap(err).(UnsupportedAzureRMEnvironment)
+	assert.True(t, isUnsupported, "Unexpected error: %v", err)
+}
+
+func TestAzureRMStorageAccountCreateParameters(t *testing.T) {
+	t.Parallel()
+
+	extendedConfig, err := parseExtendedAzureRMConfig(map[string]interface{}{
+		"storage_account_name": "foo",
+		"location":             "westeurope",
+		"storage_account_tags": map[string]string{"team": "platform"},
+	}, nil)
+	require.NoError(t, err)
+
+	params := azureRMStorageAccountCreateParameters(extendedConfig)
+
+	assert.Equal(t, "westeurope", *params.Location)
+	assert.Equal(t, azstorage.StandardLRS, params.Sku.Name)
+	assert.Equal(t, azstorage.StorageV2, params.Kind)
+	assert.Equal(t, "platform", *params.Tags["team"])
+	assert.True(t, *params.EnableHTTPSTrafficOnly)
+	assert.False(t, *params.AllowBlobPublicAccess)
+	assert.Equal(t, azstorage.TLS12, params.MinimumTLSVersion)
+	assert.Equal(t, azstorage.KeySourceMicrosoftStorage, params.Encryption.KeySource)
+	assert.True(t, *params.Encryption.Services.Blob.Enabled)
+}
+
+func TestAzureRMGetTerraformInitArgsFiltersTerragruntOnlyConfigs(t *testing.T) {
+	t.Parallel()
+
+	config := map[string]interface{}{
+		"storage_account_name":          "foo",
+		"container_name":                "tfstate",
+		"location":                      "westeurope",
+		"storage_account_sku":           "Standard_GRS",
+		"storage_account_tags":          map[string]string{"team": "platform"},
+		"skip_resource_group_creation":  true,
+		"skip_storage_account_creation": true,
+		"skip_blob_versioning":          true,
+		"bootstrap_storage_account":     true,
+	}
+
+	args := AzureRMInitializer{}.GetTerraformInitArgs(config)
+	assert.Equal(t, map[string]interface{}{"storage_account_name": "foo", "container_name": "tfstate"}, args)
+}
+
+func TestAzureRMOIDCToken(t *testing.T) {
+	t.Parallel()
+
+	token, err := azureRMOIDCToken(&RemoteStateConfigAzureRM{}, map[string]string{"ARM_OIDC_TOKEN": "token"})
+	require.NoError(t, err)
+	value, err := token()
+	require.NoError(t, err)
+	assert.Equal(t, "token", value)
+
+	tokenFile, err := ioutil.TempFile("", "azurerm-oidc-token")
+	require.NoError(t, err)
+	defer os.Remove(tokenFile.Name())
+	_, err = tokenFile.WriteString("file-token\n")
+	require.NoError(t, err)
+	require.NoError(t, tokenFile.Close())
+
+	token, err = azureRMOIDCToken(&RemoteStateConfigAzureRM{OIDCTokenFilePath: tokenFile.Name()}, nil)
+	require.NoError(t, err)
+	value, err = token()
+	require.NoError(t, err)
+	assert.Equal(t, "file-token", value)
+
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		assert.Equal(t, "Bearer request-token", request.Header.Get("Authorization"))
+		writer.Write([]byte(`{"value": "token-for-` + request.URL.Query().Get("audience") + `"}`))
+	}))
+	defer server.Close()
+
+	env := map[string]string{"ARM_OIDC_REQUEST_URL": server.URL + "/?api-version=2.0", "ARM_OIDC_REQUEST_TOKEN": "request-token"}
+	token, err = azureRMOIDCToken(&RemoteStateConfigAzureRM{}, env)
+	require.NoError(t, err)
+	value, err = token()
+	require.NoError(t, err)
+	assert.Equal(t, "token-for-api://AzureADTokenExchange", value)
+
+	env["ARM_OIDC_REQUEST_URL"] = server.URL + "/?api-version=2.0&audience=custom"
+	token, err = azureRMOIDCToken(&RemoteStateConfigAzureRM{}, env)
+	require.NoError(t, err)
+	value, err = token()
+	require.NoError(t, err)
+	assert.Equal(t, "token-for-custom", value)
+
+	_, err = azureRMOIDCToken(&RemoteStateConfigAzureRM{}, nil)
+	_, isMissing := errors.Unwrap(err).(MissingRequiredAzureRMOIDCConfig)
+	assert.True(t, isMissing, "Unexpected error: %v", err)
+}
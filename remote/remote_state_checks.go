@@ -0,0 +1,140 @@
+package remote
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+
+	"github.com/hashicorp/go-multierror"
+
+	"github.com/gruntwork-io/terragrunt/options"
+)
+
+// The settings of the remote state configs that select the location of the state of a single module within the
+// resources that store the state of many, e.g. the key of the state in the S3 bucket or the prefix in the GCS bucket.
+// These are ignored when comparing configs to tell if they use the same resources.
+var stateLocationConfigKeys = []string{"key", "prefix"}
+
+// The resources storing remote state, such as S3 buckets and DynamoDB lock tables, are usually shared by many modules,
+// so during *-all commands Terragrunt would check the same resources over and over. Instead, the resources that have
+// been found to exist, and the remote state configs whose resources have been initialized, are recorded here for the
+// rest of the run. Only successful checks are recorded, so that a failed check is retried by the next module. We use
+// sync.Map to ensure atomic updates during concurrent access.
+var existingRemoteStateResources = sync.Map{}
+var initializedRemoteStates = sync.Map{}
+
+// ClearRemoteStateChecks forgets the remote state resources found to exist and the remote states initialized so far,
+// e.g. between the commands run by the daemon, as the resources may have been deleted since.
+func ClearRemoteStateChecks() {
+	existingRemoteStateResources = sync.Map{}
+	initializedRemoteStates = sync.Map{}
+}
+
+// The locks that make the modules with the same remote state config wait for the first one to initialize its resources,
+// rather than all of them checking, and possibly creating, the resources at the same time.
+var remoteStateInitLocks = sync.Map{}
+
+// Return true if the resource with the given key was found to exist earlier in the run
+func remoteStateResourceExists(resourceKey string) bool {
+	_, exists := existingRemoteStateResources.Load(resourceKey)
+	return exists
+}
+
+// Record that the resource with the given key exists, so that it's not checked again for the rest of the run
+func markRemoteStateResourceExists(resourceKey string) {
+	existingRemoteStateResources.Store(resourceKey, true)
+}
+
+// Run the given function to initialize the resources of the given remote state, unless the resources of a remote state
+// with the same config, apart from the location of the state within the resources, were already initialized earlier in
+// the run.
+func initializeRemoteStateOnce(remoteState *RemoteState, terragruntOptions *options.TerragruntOptions, initialize func() error) error {
+	initKey := remoteStateInitKey(remoteState, terragruntOptions)
+
+	rawLock, _ := remoteStateInitLocks.LoadOrStore(initKey, &sync.Mutex{})
+	lock := rawLock.(*sync.Mutex)
+	lock.Lock()
+	defer lock.Unlock()
+
+	if _, isInitialized := initializedRemoteStates.Load(initKey); isInitialized {
+		terragruntOptions.Logger.Debugf("The resources of the %s backend were already initialized during this run", remoteState.Backend)
+		return nil
+	}
+
+	if err := initialize(); err != nil {
+		return err
+	}
+
+	initializedRemoteStates.Store(initKey, true)
+	return nil
+}
+
+// Return the key identifying the resources initialized for the given remote state: the backend, the config without the
+// location of the state, and the IAM role, as the role may have access to different resources.
+func remoteStateInitKey(remoteState *RemoteState, terragruntOptions *options.TerragruntOptions) string {
+	sharedConfig := map[string]interface{}{}
+	for key, value := range remoteState.Config {
+		sharedConfig[key] = value
+	}
+	for _, key := range stateLocationConfigKeys {
+		delete(sharedConfig, key)
+	}
+
+	// json.Marshal sorts the keys of maps, so equal configs always result in the same key
+	configJson, err := json.Marshal(sharedConfig)
+	if err != nil {
+		configJson = []byte(fmt.Sprintf("%v", sharedConfig))
+	}
+	return fmt.Sprintf("%s:%s:%s", remoteState.Backend, terragruntOptions.IamRole, configJson)
+}
+
+// Run the given checks of remote state resources concurrently, as they don't depend on each other, and usually call
+// different APIs, and return true if any of them does. If any of the checks fail, their errors are returned instead.
+func anyCheckTrue(checks ...func() (bool, error)) (bool, error) {
+	results := make([]bool, len(checks))
+	err := runConcurrently(len(checks), func(i int) error {
+		result, err := checks[i]()
+		results[i] = result
+		return err
+	})
+	if err != nil {
+		return false, err
+	}
+
+	for _, result := range results {
+		if result {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// Run the given actions on remote state resources concurrently, as they don't depend on each other, and wait for all of
+// them to finish. Returns the errors of all the actions that failed.
+func runAllConcurrently(actions ...func() error) error {
+	return runConcurrently(len(actions), func(i int) error {
+		return actions[i]()
+	})
+}
+
+// Call the given function with each index from 0 to count, concurrently, and return the errors it returned, if any
+func runConcurrently(count int, run func(i int) error) error {
+	errs := make([]error, count)
+	var waitGroup sync.WaitGroup
+	for i := 0; i < count; i++ {
+		waitGroup.Add(1)
+		go func(i int) {
+			defer waitGroup.Done()
+			errs[i] = run(i)
+		}(i)
+	}
+	waitGroup.Wait()
+
+	var allErrors *multierror.Error
+	for _, err := range errs {
+		if err != nil {
+			allErrors = multierror.Append(allErrors, err)
+		}
+	}
+	return allErrors.ErrorOrNil()
+}
@@ -0,0 +1,113 @@
+package remote
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/gruntwork-io/terragrunt/options"
+)
+
+func TestRemoteStateInitKey(t *testing.T) {
+	t.Parallel()
+
+	terragruntOptions, err := options.NewTerragruntOptionsForTest("remote_state_checks_test")
+	require.NoError(t, err)
+
+	vpcState := &RemoteState{Backend: "s3", Config: map[string]interface{}{"bucket": "my-bucket", "region": "us-east-1", "key": "vpc/terraform.tfstate"}}
+	appState := &RemoteState{Backend: "s3", Config: map[string]interface{}{"region": "us-east-1", "bucket": "my-bucket", "key": "app/terraform.tfstate"}}
+	otherBucketState := &RemoteState{Backend: "s3", Config: map[string]interface{}{"bucket": "other-bucket", "region": "us-east-1", "key": "vpc/terraform.tfstate"}}
+	gcsState := &RemoteState{Backend: "gcs", Config: map[string]interface{}{"bucket": "my-bucket", "prefix": "vpc"}}
+
+	// The location of the state within the bucket doesn't matter
+	assert.Equal(t, remoteStateInitKey(vpcState, terragruntOptions), remoteStateInitKey(appState, terragruntOptions))
+	assert.NotEqual(t, remoteStateInitKey(vpcState, terragruntOptions), remoteStateInitKey(otherBucketState, terragruntOptions))
+	assert.NotEqual(t, remoteStateInitKey(vpcState, terragruntOptions), remoteStateInitKey(gcsState, terragruntOptions))
+
+	// Neither does computing the key modify the config
+	assert.Equal(t, "vpc/terraform.tfstate", vpcState.Config["key"])
+
+	roleOptions := terragruntOptions.Clone(terragruntOptions.TerragruntConfigPath)
+	roleOptions.IamRole = "arn:aws:iam::123456789012:role/terragrunt"
+	assert.NotEqual(t, remoteStateInitKey(vpcState, terragruntOptions), remoteStateInitKey(vpcState, roleOptions))
+}
+
+func TestInitializeRemoteStateOnce(t *testing.T) {
+	t.Parallel()
+
+	terragruntOptions, err := options.NewTerragruntOptionsForTest("remote_state_checks_test")
+	require.NoError(t, err)
+
+	newRemoteState := func(key string) *RemoteState {
+		return &RemoteState{Backend: "s3", Config: map[string]interface{}{"bucket": "initialize-once-test", "key": key}}
+	}
+
+	// The first initialization fails, so it's retried by the next module
+	calls := 0
+	err = initializeRemoteStateOnce(newRemoteState("vpc"), terragruntOptions, func() error {
+		calls++
+		return fmt.Errorf("bucket could not be created")
+	})
+	assert.Error(t, err)
+
+	for _, key := range []string{"vpc", "app", "web"} {
+		err := initializeRemoteStateOnce(newRemoteState(key), terragruntOptions, func() error {
+			calls++
+			return nil
+		})
+		assert.NoError(t, err)
+	}
+	assert.Equal(t, 2, calls)
+}
+
+func TestAnyCheckTrue(t *testing.T) {
+	t.Parallel()
+
+	no := func() (bool, error) { return false, nil }
+	yes := func() (bool, error) { return true, nil }
+	fail := func() (bool, error) { return false, fmt.Errorf("check failed") }
+
+	result, err := anyCheckTrue(no, no)
+	require.NoError(t, err)
+	assert.False(t, result)
+
+	result, err = anyCheckTrue(no, yes)
+	require.NoError(t, err)
+	assert.True(t, result)
+
+	_, err = anyCheckTrue(yes, fail)
+	assert.Error(t, err)
+}
+
+func TestRunAllConcurrently(t *testing.T) {
+	t.Parallel()
+
+	// The actions run at the same time: each of them waits for the other to start
+	firstStarted := make(chan struct{})
+	secondStarted := make(chan struct{})
+	err := runAllConcurrently(
+		func() error {
+			close(firstStarted)
+			<-secondStarted
+			return nil
+		},
+		func() error {
+			close(secondStarted)
+			<-firstStarted
+			return nil
+		},
+	)
+	require.NoError(t, err)
+
+	// The errors of all the actions are returned
+	err = runAllConcurrently(
+		func() error { return fmt.Errorf("first failed") },
+		func() error { return nil },
+		func() error { return fmt.Errorf("third failed") },
+	)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "first failed")
+	assert.Contains(t, err.Error(), "third failed")
+}
@@ -2,9 +2,12 @@ package remote
 
 import (
 	"context"
+	"encoding/base64"
 	"encoding/json"
 	"fmt"
+	"io/ioutil"
 	"os"
+	"path"
 	"reflect"
 	"strconv"
 	"time"
@@ -33,6 +36,7 @@ type ExtendedRemoteStateConfigGCS struct {
 	Project                string            `mapstructure:"project"`
 	Location               string            `mapstructure:"location"`
 	GCSBucketLabels        map[string]string `mapstructure:"gcs_bucket_labels"`
+	GCSBucketKMSKeyName    string            `mapstructure:"gcs_bucket_kms_key_name"`
 	SkipBucketVersioning   bool              `mapstructure:"skip_bucket_versioning"`
 	SkipBucketCreation     bool              `mapstructure:"skip_bucket_creation"`
 	EnableBucketPolicyOnly bool              `mapstructure:"enable_bucket_policy_only"`
@@ -44,6 +48,7 @@ var terragruntGCSOnlyConfigs = []string{
 	"project",
 	"location",
 	"gcs_bucket_labels",
+	"gcs_bucket_kms_key_name",
 	"skip_bucket_versioning",
 	"skip_bucket_creation",
 	"enable_bucket_policy_only",
@@ -96,13 +101,18 @@ func (gcsInitializer GCSInitializer) NeedsInitialization(remoteState *RemoteStat
 		return false, err
 	}
 
-	gcsClient, err := CreateGCSClient(*gcsConfig)
-	if err != nil {
-		return false, err
-	}
+	// The bucket is usually shared by many modules, so it's only checked once per run
+	bucketCheckKey := "gcs-bucket:" + gcsConfig.Bucket
+	if !remoteStateResourceExists(bucketCheckKey) {
+		gcsClient, err := CreateGCSClient(gcsConfigWithImpersonatedServiceAccount(*gcsConfig, terragruntOptions))
+		if err != nil {
+			return false, err
+		}
 
-	if !DoesGCSBucketExist(gcsClient, gcsConfig) {
-		return true, nil
+		if !DoesGCSBucketExist(gcsClient, gcsConfig) {
+			return true, nil
+		}
+		markRemoteStateResourceExists(bucketCheckKey)
 	}
 	if project != nil {
 		delete(remoteState.Config, "project")
@@ -167,7 +177,7 @@ func (gcsInitializer GCSInitializer) Initialize(remoteState *RemoteState, terrag
 
 	var gcsConfig = gcsConfigExtended.remoteStateConfigGCS
 
-	gcsClient, err := CreateGCSClient(gcsConfig)
+	gcsClient, err := CreateGCSClient(gcsConfigWithImpersonatedServiceAccount(gcsConfig, terragruntOptions))
 	if err != nil {
 		return err
 	}
@@ -242,6 +252,14 @@ func validateGCSConfig(extendedConfig *ExtendedRemoteStateConfigGCS, terragruntO
 	return nil
 }
 
+// Validate the types of the settings of the GCS remote state config, without making any calls to GCP, so that mistakes
+// in the config are reported before running terraform init. The GCS backend has no settings that have to be in the
+// remote_state config, as the bucket may be set in the backend block of the terraform code, so none are required.
+func (gcsInitializer GCSInitializer) ValidateConfig(remoteState *RemoteState, terragruntOptions *options.TerragruntOptions) error {
+	_, err := parseExtendedGCSConfig(remoteState.Config)
+	return err
+}
+
 // If the bucket specified in the given config doesn't already exist, prompt the user to create it, and if the user
 // confirms, create the bucket and enable versioning for it.
 func createGCSBucketIfNecessary(gcsClient *storage.Client, config *ExtendedRemoteStateConfigGCS, terragruntOptions *options.TerragruntOptions) error {
@@ -354,7 +372,14 @@ func CreateGCSBucket(gcsClient *storage.Client, config *ExtendedRemoteStateConfi
 
 	ctx := context.Background()
 	bucket := gcsClient.Bucket(config.remoteStateConfigGCS.Bucket)
+	bucketAttrs := gcsBucketAttrs(config, terragruntOptions)
 
+	err := bucket.Create(ctx, projectID, bucketAttrs)
+	return errors.WithStackTrace(err)
+}
+
+// Return the attributes to create the GCS bucket specified in the given config with
+func gcsBucketAttrs(config *ExtendedRemoteStateConfigGCS, terragruntOptions *options.TerragruntOptions) *storage.BucketAttrs {
 	bucketAttrs := &storage.BucketAttrs{}
 
 	if config.Location != "" {
@@ -374,8 +399,17 @@ func CreateGCSBucket(gcsClient *storage.Client, config *ExtendedRemoteStateConfi
 		bucketAttrs.BucketPolicyOnly = storage.BucketPolicyOnly{Enabled: true}
 	}
 
-	err := bucket.Create(ctx, projectID, bucketAttrs)
-	return errors.WithStackTrace(err)
+	// Set the labels when creating the bucket, so that it's never left unlabeled, e.g. if the process is interrupted
+	if len(config.GCSBucketLabels) > 0 {
+		bucketAttrs.Labels = config.GCSBucketLabels
+	}
+
+	if config.GCSBucketKMSKeyName != "" {
+		terragruntOptions.Logger.Debugf("Encrypting the objects in GCS bucket %s with the KMS key %s by default", config.remoteStateConfigGCS.Bucket, config.GCSBucketKMSKeyName)
+		bucketAttrs.Encryption = &storage.BucketEncryption{DefaultKMSKeyName: config.GCSBucketKMSKeyName}
+	}
+
+	return bucketAttrs
 }
 
 // GCP is eventually consistent, so after creating a GCS bucket, this method can be used to wait until the information
@@ -420,6 +454,110 @@ func DoesGCSBucketExist(gcsClient *storage.Client, config *RemoteStateConfigGCS)
 	return true
 }
 
+// DeleteState deletes the object holding the state of the default workspace under the prefix in the GCS bucket. If
+// versioning is enabled on the bucket, the previous versions of the state are kept, so the state can still be recovered.
+func (gcsInitializer GCSInitializer) DeleteState(remoteState *RemoteState, terragruntOptions *options.TerragruntOptions) error {
+	gcsConfig, err := parseGCSConfig(remoteState.Config)
+	if err != nil {
+		return err
+	}
+
+	if gcsConfig.Bucket == "" {
+		return errors.WithStackTrace(MissingRequiredGCSRemoteStateConfig("bucket"))
+	}
+
+	gcsClient, err := CreateGCSClient(gcsConfigWithImpersonatedServiceAccount(*gcsConfig, terragruntOptions))
+	if err != nil {
+		return err
+	}
+	defer gcsClient.Close()
+
+	objectName := gcsStateObjectName(gcsConfig)
+	terragruntOptions.Logger.Debugf("Deleting the state object %s in the GCS bucket %s", objectName, gcsConfig.Bucket)
+
+	err = gcsClient.Bucket(gcsConfig.Bucket).Object(objectName).Delete(context.Background())
+	if err == storage.ErrObjectNotExist {
+		terragruntOptions.Logger.Debugf("State object %s does not exist in the GCS bucket %s", objectName, gcsConfig.Bucket)
+		return nil
+	}
+	return errors.WithStackTrace(err)
+}
+
+// ReadState reads the object holding the state of the given workspace under the prefix in the GCS bucket. If the
+// state is encrypted with a customer-supplied encryption_key, the key is used to read it.
+func (gcsInitializer GCSInitializer) ReadState(remoteState *RemoteState, workspace string, terragruntOptions *options.TerragruntOptions) ([]byte, error) {
+	gcsConfig, err := parseGCSConfig(remoteState.Config)
+	if err != nil {
+		return nil, err
+	}
+
+	if gcsConfig.Bucket == "" {
+		return nil, errors.WithStackTrace(MissingRequiredGCSRemoteStateConfig("bucket"))
+	}
+
+	gcsClient, err := CreateGCSClient(gcsConfigWithImpersonatedServiceAccount(*gcsConfig, terragruntOptions))
+	if err != nil {
+		return nil, err
+	}
+	defer gcsClient.Close()
+
+	objectName := gcsStateObjectNameForWorkspace(gcsConfig, workspace)
+	terragruntOptions.Logger.Debugf("Reading the state object %s in the GCS bucket %s", objectName, gcsConfig.Bucket)
+
+	object := gcsClient.Bucket(gcsConfig.Bucket).Object(objectName)
+	if gcsConfig.EncryptionKey != "" {
+		encryptionKey, err := base64.StdEncoding.DecodeString(gcsConfig.EncryptionKey)
+		if err != nil {
+			return nil, errors.WithStackTrace(err)
+		}
+		object = object.Key(encryptionKey)
+	}
+
+	reader, err := object.NewReader(context.Background())
+	if err == storage.ErrObjectNotExist {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errors.WithStackTrace(err)
+	}
+	defer reader.Close()
+
+	stateData, err := ioutil.ReadAll(reader)
+	return stateData, errors.WithStackTrace(err)
+}
+
+// gcsStateObjectName returns the name of the object in which the gcs backend stores the state of the default workspace
+func gcsStateObjectName(config *RemoteStateConfigGCS) string {
+	return gcsStateObjectNameForWorkspace(config, "")
+}
+
+// gcsStateObjectNameForWorkspace returns the name of the object in which the gcs backend stores the state of the given
+// workspace. An empty workspace is the default workspace.
+func gcsStateObjectNameForWorkspace(config *RemoteStateConfigGCS, workspace string) string {
+	if workspace == "" {
+		workspace = "default"
+	}
+	return path.Join(config.Prefix, workspace+".tfstate")
+}
+
+// gcsConfigWithImpersonatedServiceAccount returns the given GCS config, impersonating the service account set via
+// google_impersonate_service_account if the config doesn't set one of its own
+func gcsConfigWithImpersonatedServiceAccount(gcsConfig RemoteStateConfigGCS, terragruntOptions *options.TerragruntOptions) RemoteStateConfigGCS {
+	if gcsConfig.ImpersonateServiceAccount == "" {
+		gcsConfig.ImpersonateServiceAccount = terragruntOptions.GoogleImpersonateServiceAccount
+	}
+	return gcsConfig
+}
+
+// UpdateEnvWithGoogleImpersonateServiceAccount exports the service account to impersonate, if any, via
+// GOOGLE_IMPERSONATE_SERVICE_ACCOUNT, so that the google provider and the gcs backend of terraform impersonate it too
+func UpdateEnvWithGoogleImpersonateServiceAccount(terragruntOptions *options.TerragruntOptions) {
+	if terragruntOptions.GoogleImpersonateServiceAccount == "" {
+		return
+	}
+	terragruntOptions.Env["GOOGLE_IMPERSONATE_SERVICE_ACCOUNT"] = terragruntOptions.GoogleImpersonateServiceAccount
+}
+
 // CreateGCSClient creates an authenticated client for GCS
 func CreateGCSClient(gcsConfigRemote RemoteStateConfigGCS) (*storage.Client, error) {
 	ctx := context.Background()
@@ -3,6 +3,7 @@ package remote
 import (
 	"testing"
 
+	"cloud.google.com/go/storage"
 	"github.com/gruntwork-io/terragrunt/options"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -127,3 +128,81 @@ func TestGCSConfigValuesEqual(t *testing.T) {
 		})
 	}
 }
+
+func TestGCSBucketAttrs(t *testing.T) {
+	t.Parallel()
+
+	terragruntOptions, err := options.NewTerragruntOptionsForTest("remote_state_test")
+	require.Nil(t, err, "Unexpected error creating NewTerragruntOptionsForTest: %v", err)
+
+	config, err := parseExtendedGCSConfig(map[string]interface{}{
+		"bucket":                    "my-bucket",
+		"prefix":                    "terraform.tfstate",
+		"project":                   "my-project",
+		"location":                  "eu",
+		"enable_bucket_policy_only": true,
+		"gcs_bucket_labels":         map[string]string{"team": "infra"},
+		"gcs_bucket_kms_key_name":   "projects/my-project/locations/eu/keyRings/tf/cryptoKeys/state",
+	})
+	require.NoError(t, err)
+
+	bucketAttrs := gcsBucketAttrs(config, terragruntOptions)
+	assert.Equal(t, "eu", bucketAttrs.Location)
+	assert.True(t, bucketAttrs.VersioningEnabled)
+	assert.True(t, bucketAttrs.BucketPolicyOnly.Enabled)
+	assert.Equal(t, map[string]string{"team": "infra"}, bucketAttrs.Labels)
+	assert.Equal(t, &storage.BucketEncryption{DefaultKMSKeyName: "projects/my-project/locations/eu/keyRings/tf/cryptoKeys/state"}, bucketAttrs.Encryption)
+
+	config.SkipBucketVersioning = true
+	config.GCSBucketKMSKeyName = ""
+	bucketAttrs = gcsBucketAttrs(config, terragruntOptions)
+	assert.False(t, bucketAttrs.VersioningEnabled)
+	assert.Nil(t, bucketAttrs.Encryption)
+}
+
+func TestGCSGetTerraformInitArgsFiltersTerragruntOnlyConfigs(t *testing.T) {
+	t.Parallel()
+
+	initArgs := GCSInitializer{}.GetTerraformInitArgs(map[string]interface{}{
+		"bucket":                  "my-bucket",
+		"prefix":                  "terraform.tfstate",
+		"project":                 "my-project",
+		"gcs_bucket_kms_key_name": "projects/my-project/locations/eu/keyRings/tf/cryptoKeys/state",
+	})
+	assert.Equal(t, map[string]interface{}{"bucket": "my-bucket", "prefix": "terraform.tfstate"}, initArgs)
+}
+
+func TestGCSStateObjectName(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, "prod/vpc/default.tfstate", gcsStateObjectName(&RemoteStateConfigGCS{Prefix: "prod/vpc"}))
+	assert.Equal(t, "prod/vpc/default.tfstate", gcsStateObjectName(&RemoteStateConfigGCS{Prefix: "prod/vpc/"}))
+	assert.Equal(t, "default.tfstate", gcsStateObjectName(&RemoteStateConfigGCS{}))
+}
+
+func TestGCSStateObjectNameForWorkspace(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, "prod/vpc/default.tfstate", gcsStateObjectNameForWorkspace(&RemoteStateConfigGCS{Prefix: "prod/vpc"}, ""))
+	assert.Equal(t, "prod/vpc/default.tfstate", gcsStateObjectNameForWorkspace(&RemoteStateConfigGCS{Prefix: "prod/vpc"}, "default"))
+	assert.Equal(t, "prod/vpc/blue.tfstate", gcsStateObjectNameForWorkspace(&RemoteStateConfigGCS{Prefix: "prod/vpc"}, "blue"))
+}
+
+func TestGCSConfigWithImpersonatedServiceAccount(t *testing.T) {
+	t.Parallel()
+
+	terragruntOptions, err := options.NewTerragruntOptionsForTest("remote_state_test")
+	require.NoError(t, err)
+
+	// Without google_impersonate_service_account, the config is left as is
+	assert.Equal(t, RemoteStateConfigGCS{Bucket: "state"}, gcsConfigWithImpersonatedServiceAccount(RemoteStateConfigGCS{Bucket: "state"}, terragruntOptions))
+
+	terragruntOptions.GoogleImpersonateServiceAccount = "terragrunt@acme.iam.gserviceaccount.com"
+	assert.Equal(t, "terragrunt@acme.iam.gserviceaccount.com", gcsConfigWithImpersonatedServiceAccount(RemoteStateConfigGCS{Bucket: "state"}, terragruntOptions).ImpersonateServiceAccount)
+
+	// The service account set in the backend config takes precedence
+	assert.Equal(t, "backend@acme.iam.gserviceaccount.com", gcsConfigWithImpersonatedServiceAccount(RemoteStateConfigGCS{ImpersonateServiceAccount: "backend@acme.iam.gserviceaccount.com"}, terragruntOptions).ImpersonateServiceAccount)
+
+	UpdateEnvWithGoogleImpersonateServiceAccount(terragruntOptions)
+	assert.Equal(t, "terragrunt@acme.iam.gserviceaccount.com", terragruntOptions.Env["GOOGLE_IMPERSONATE_SERVICE_ACCOUNT"])
+}
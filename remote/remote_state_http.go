@@ -0,0 +1,232 @@
+package remote
+
+import (
+	"fmt"
+	"net/url"
+	"reflect"
+	"strconv"
+
+	"github.com/gruntwork-io/terragrunt/errors"
+	"github.com/gruntwork-io/terragrunt/options"
+	"github.com/gruntwork-io/terragrunt/util"
+	"github.com/mitchellh/mapstructure"
+)
+
+// A representation of the configuration options available for HTTP remote state
+type RemoteStateConfigHTTP struct {
+	Address              string `mapstructure:"address"`
+	UpdateMethod         string `mapstructure:"update_method"`
+	LockAddress          string `mapstructure:"lock_address"`
+	LockMethod           string `mapstructure:"lock_method"`
+	UnlockAddress        string `mapstructure:"unlock_address"`
+	UnlockMethod         string `mapstructure:"unlock_method"`
+	Username             string `mapstructure:"username"`
+	Password             string `mapstructure:"password"`
+	SkipCertVerification bool   `mapstructure:"skip_cert_verification"`
+	RetryMax             int    `mapstructure:"retry_max"`
+	RetryWaitMin         int    `mapstructure:"retry_wait_min"`
+	RetryWaitMax         int    `mapstructure:"retry_wait_max"`
+	SigV4Service         string `mapstructure:"sigv4_service"`
+	SigV4Region          string `mapstructure:"sigv4_region"`
+}
+
+// These are settings that can appear in the remote_state config that are ONLY used by Terragrunt and NOT forwarded
+// to the underlying Terraform backend configuration.
+var terragruntHTTPOnlyConfigs = []string{
+	"sigv4_service",
+	"sigv4_region",
+}
+
+// The addresses of the HTTP backend, which, when the requests are signed with SigV4, are passed to terraform via the
+// env vars instead, as they point at the signing proxy, see StartHTTPBackendSigningProxy
+var httpAddressConfigs = []string{
+	"address",
+	"lock_address",
+	"unlock_address",
+}
+
+// The HTTP backend stores the state behind a REST endpoint that Terragrunt has no way to create, so there is nothing to
+// bootstrap: this initializer only validates the config and detects when it has changed.
+type HTTPInitializer struct{}
+
+// Returns true if any of the existing backend settings are different than the current config
+func (httpInitializer HTTPInitializer) NeedsInitialization(remoteState *RemoteState, existingBackend *TerraformBackend, terragruntOptions *options.TerragruntOptions) (bool, error) {
+	if remoteState.DisableInit {
+		return false, nil
+	}
+
+	return !httpConfigValuesEqual(httpInitializer.GetTerraformInitArgs(remoteState.Config), existingBackend, terragruntOptions), nil
+}
+
+// Return true if the given config is in any way different than what is configured for the backend
+func httpConfigValuesEqual(config map[string]interface{}, existingBackend *TerraformBackend, terragruntOptions *options.TerragruntOptions) bool {
+	if existingBackend == nil {
+		return len(config) == 0
+	}
+
+	if existingBackend.Type != "http" {
+		terragruntOptions.Logger.Debugf("Backend type has changed from http to %s", existingBackend.Type)
+		return false
+	}
+
+	// Values passed with -backend-config end up as strings in the backend config, while the same values read from
+	// the remote_state block keep their types, so convert them before comparing.
+	existingConfig := make(map[string]interface{})
+	for key, value := range existingBackend.Config {
+		existingConfig[key] = value
+		stringValue, isString := value.(string)
+		if !isString {
+			continue
+		}
+
+		switch util.KindOf(config[key]) {
+		case reflect.Bool:
+			if convertedValue, err := strconv.ParseBool(stringValue); err == nil {
+				existingConfig[key] = convertedValue
+			}
+		case reflect.Float64:
+			if convertedValue, err := strconv.ParseFloat(stringValue, 64); err == nil {
+				existingConfig[key] = convertedValue
+			}
+		}
+	}
+
+	if !terraformStateConfigEqual(existingConfig, config) {
+		terragruntOptions.Logger.Debugf("Backend config changed from %s to %s", existingBackend.Config, config)
+		return false
+	}
+
+	return true
+}
+
+// Validate the HTTP remote state config. There are no resources to create for this backend.
+func (httpInitializer HTTPInitializer) Initialize(remoteState *RemoteState, terragruntOptions *options.TerragruntOptions) error {
+	httpConfig, err := parseHTTPConfig(remoteState.Config)
+	if err != nil {
+		return err
+	}
+
+	if err := validateHTTPConfig(httpConfig); err != nil {
+		return err
+	}
+
+	if httpConfig.LockAddress == "" {
+		terragruntOptions.Logger.Warnf("No lock_address is configured for the remote state HTTP backend %s, so the state will not be locked while running terraform.", httpConfig.Address)
+	}
+
+	return nil
+}
+
+// Validate the HTTP remote state config
+func (httpInitializer HTTPInitializer) ValidateConfig(remoteState *RemoteState, terragruntOptions *options.TerragruntOptions) error {
+	httpConfig, err := parseHTTPConfig(remoteState.Config)
+	if err != nil {
+		return err
+	}
+
+	return validateHTTPConfig(httpConfig)
+}
+
+// Remove the terragrunt-only settings from the config, as well as the addresses of the backend if the requests are
+// signed with SigV4, so that the addresses of the signing proxy, which change on every run, aren't saved in the backend
+// config of terraform
+func (httpInitializer HTTPInitializer) GetTerraformInitArgs(config map[string]interface{}) map[string]interface{} {
+	var filteredConfig = make(map[string]interface{})
+
+	_, signsRequests := config["sigv4_service"]
+	for key, val := range config {
+		if util.ListContainsElement(terragruntHTTPOnlyConfigs, key) {
+			continue
+		}
+		if signsRequests && util.ListContainsElement(httpAddressConfigs, key) {
+			continue
+		}
+
+		filteredConfig[key] = val
+	}
+
+	return filteredConfig
+}
+
+// Parse the given map into an HTTP config
+func parseHTTPConfig(config map[string]interface{}) (*RemoteStateConfigHTTP, error) {
+	var httpConfig RemoteStateConfigHTTP
+
+	decoder, err := mapstructure.NewDecoder(&mapstructure.DecoderConfig{Result: &httpConfig, WeaklyTypedInput: true})
+	if err != nil {
+		return nil, errors.WithStackTrace(err)
+	}
+
+	if err := decoder.Decode(config); err != nil {
+		return nil, errors.WithStackTrace(err)
+	}
+
+	// Like terraform, fall back to the TF_HTTP_* env vars for the settings that aren't in the config
+	httpConfig.Address = valueOrEnv(httpConfig.Address, "TF_HTTP_ADDRESS")
+	httpConfig.LockAddress = valueOrEnv(httpConfig.LockAddress, "TF_HTTP_LOCK_ADDRESS")
+	httpConfig.UnlockAddress = valueOrEnv(httpConfig.UnlockAddress, "TF_HTTP_UNLOCK_ADDRESS")
+	httpConfig.Username = valueOrEnv(httpConfig.Username, "TF_HTTP_USERNAME")
+	httpConfig.Password = valueOrEnv(httpConfig.Password, "TF_HTTP_PASSWORD")
+
+	return &httpConfig, nil
+}
+
+// Validate all the parameters of the given HTTP remote state configuration
+func validateHTTPConfig(config *RemoteStateConfigHTTP) error {
+	if config.Address == "" {
+		return errors.WithStackTrace(MissingRequiredHTTPRemoteStateConfig("address"))
+	}
+
+	addresses := []struct {
+		name    string
+		address string
+	}{
+		{"address", config.Address},
+		{"lock_address", config.LockAddress},
+		{"unlock_address", config.UnlockAddress},
+	}
+	for _, address := range addresses {
+		if address.address == "" {
+			continue
+		}
+
+		parsedAddress, err := url.Parse(address.address)
+		if err != nil || (parsedAddress.Scheme != "http" && parsedAddress.Scheme != "https") || parsedAddress.Host == "" {
+			return errors.WithStackTrace(InvalidHTTPRemoteStateAddress{Name: address.name, Address: address.address})
+		}
+	}
+
+	if config.LockAddress != "" && config.UnlockAddress == "" {
+		return errors.WithStackTrace(MissingRequiredHTTPRemoteStateConfig("unlock_address"))
+	}
+
+	// Both the basic auth and the SigV4 signature are sent in the Authorization header
+	if config.SigV4Service != "" && (config.Username != "" || config.Password != "") {
+		return errors.WithStackTrace(ConflictingHTTPRemoteStateAuth{})
+	}
+
+	return nil
+}
+
+// Custom error types
+
+type MissingRequiredHTTPRemoteStateConfig string
+
+func (configName MissingRequiredHTTPRemoteStateConfig) Error() string {
+	return fmt.Sprintf("Missing required HTTP remote state configuration %s", string(configName))
+}
+
+type InvalidHTTPRemoteStateAddress struct {
+	Name    string
+	Address string
+}
+
+func (err InvalidHTTPRemoteStateAddress) Error() string {
+	return fmt.Sprintf("The HTTP remote state configuration %s must be an http or https URL, but got %s", err.Name, err.Address)
+}
+
+type ConflictingHTTPRemoteStateAuth struct{}
+
+func (err ConflictingHTTPRemoteStateAuth) Error() string {
+	return "The HTTP remote state configuration can't set both sigv4_service and a username or password, as the requests are authenticated with either a SigV4 signature or basic auth"
+}
@@ -0,0 +1,149 @@
+package remote
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+
+	"github.com/gruntwork-io/terragrunt/aws_helper"
+	"github.com/gruntwork-io/terragrunt/errors"
+	"github.com/gruntwork-io/terragrunt/options"
+)
+
+// The env vars terraform reads the addresses of the HTTP backend from, when they're not in the backend config
+var httpAddressEnvVars = map[string]string{
+	"address":        "TF_HTTP_ADDRESS",
+	"lock_address":   "TF_HTTP_LOCK_ADDRESS",
+	"unlock_address": "TF_HTTP_UNLOCK_ADDRESS",
+}
+
+// The headers of a request that only apply to the connection it's sent on, and so aren't forwarded by the proxy
+var hopByHopHeaders = []string{"Connection", "Keep-Alive", "Proxy-Connection", "Transfer-Encoding", "Upgrade", "Te", "Trailer"}
+
+// A proxy that signs the requests terraform sends to the HTTP backend with SigV4, and forwards them to the addresses of
+// the backend. Each address of the backend is served under its own path, which starts with a random token, so that the
+// proxy only ever forwards requests to the addresses of the backend, and only for the terraform processes of the unit.
+type httpBackendSigningProxy struct {
+	targets   map[string]string
+	transport http.RoundTripper
+}
+
+// Start, if the given remote state is an HTTP backend with sigv4_service set, a proxy on localhost that signs the
+// requests of the backend with SigV4, using the AWS credentials of the unit, and point the terraform processes of the
+// unit at it, via the TF_HTTP_ADDRESS, TF_HTTP_LOCK_ADDRESS and TF_HTTP_UNLOCK_ADDRESS env vars. Terraform has no way to
+// sign the requests itself. Returns a function that stops the proxy, which should be called once the unit finishes.
+func StartHTTPBackendSigningProxy(remoteState *RemoteState, terragruntOptions *options.TerragruntOptions) (func(), error) {
+	noop := func() {}
+	if remoteState == nil || remoteState.Backend != "http" {
+		return noop, nil
+	}
+
+	httpConfig, err := parseHTTPConfig(remoteState.Config)
+	if err != nil {
+		return noop, err
+	}
+	if httpConfig.SigV4Service == "" {
+		return noop, nil
+	}
+	if err := validateHTTPConfig(httpConfig); err != nil {
+		return noop, err
+	}
+
+	transport, err := aws_helper.NewSigV4Transport(httpConfig.SigV4Service, httpConfig.SigV4Region, terragruntOptions)
+	if err != nil {
+		return noop, err
+	}
+
+	token := make([]byte, 16)
+	if _, err := rand.Read(token); err != nil {
+		return noop, errors.WithStackTrace(err)
+	}
+	addresses := map[string]string{
+		"address":        httpConfig.Address,
+		"lock_address":   httpConfig.LockAddress,
+		"unlock_address": httpConfig.UnlockAddress,
+	}
+	proxy := &httpBackendSigningProxy{targets: map[string]string{}, transport: transport}
+	paths := map[string]string{}
+	for name, address := range addresses {
+		if address == "" {
+			continue
+		}
+		path := fmt.Sprintf("/%s/%s", hex.EncodeToString(token), name)
+		proxy.targets[path] = address
+		paths[name] = path
+	}
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return noop, errors.WithStackTrace(err)
+	}
+	server := &http.Server{Handler: proxy}
+	go server.Serve(listener)
+
+	for name, path := range paths {
+		terragruntOptions.Env[httpAddressEnvVars[name]] = fmt.Sprintf("http://%s%s", listener.Addr(), path)
+	}
+	terragruntOptions.Logger.Debugf("Signing the requests of the HTTP backend %s with SigV4 for %s via a proxy on %s", httpConfig.Address, httpConfig.SigV4Service, listener.Addr())
+
+	return func() {
+		server.Close()
+	}, nil
+}
+
+// Sign the given request of terraform, forward it to the address of the backend its path is for, and copy the response
+// back
+func (proxy *httpBackendSigningProxy) ServeHTTP(writer http.ResponseWriter, request *http.Request) {
+	target, isKnown := proxy.targets[request.URL.Path]
+	if !isKnown {
+		http.NotFound(writer, request)
+		return
+	}
+
+	targetURL, err := url.Parse(target)
+	if err != nil {
+		http.Error(writer, err.Error(), http.StatusBadGateway)
+		return
+	}
+	if request.URL.RawQuery != "" {
+		query := targetURL.Query()
+		for key, values := range request.URL.Query() {
+			query[key] = append(query[key], values...)
+		}
+		targetURL.RawQuery = query.Encode()
+	}
+
+	forwardedRequest, err := http.NewRequest(request.Method, targetURL.String(), request.Body)
+	if err != nil {
+		http.Error(writer, err.Error(), http.StatusBadGateway)
+		return
+	}
+	forwardedRequest = forwardedRequest.WithContext(request.Context())
+	forwardedRequest.ContentLength = request.ContentLength
+	for name, values := range request.Header {
+		forwardedRequest.Header[name] = values
+	}
+	for _, name := range hopByHopHeaders {
+		forwardedRequest.Header.Del(name)
+	}
+
+	response, err := proxy.transport.RoundTrip(forwardedRequest)
+	if err != nil {
+		http.Error(writer, err.Error(), http.StatusBadGateway)
+		return
+	}
+	defer response.Body.Close()
+
+	for name, values := range response.Header {
+		writer.Header()[name] = values
+	}
+	for _, name := range hopByHopHeaders {
+		writer.Header().Del(name)
+	}
+	writer.WriteHeader(response.StatusCode)
+	io.Copy(writer, response.Body)
+}
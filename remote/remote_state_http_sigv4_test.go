@@ -0,0 +1,60 @@
+package remote
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHTTPBackendSigningProxy(t *testing.T) {
+	t.Parallel()
+
+	var received *http.Request
+	var receivedBody string
+	backend := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		body, _ := ioutil.ReadAll(request.Body)
+		received = request
+		receivedBody = string(body)
+		writer.Header().Set("Content-Type", "application/json")
+		writer.WriteHeader(http.StatusConflict)
+		writer.Write([]byte(`{"ID":"lock"}`))
+	}))
+	defer backend.Close()
+
+	proxy := httptest.NewServer(&httpBackendSigningProxy{
+		targets:   map[string]string{"/token/lock_address": backend.URL + "/states/vpc?kind=lock"},
+		transport: http.DefaultTransport,
+	})
+	defer proxy.Close()
+
+	request, err := http.NewRequest("LOCK", proxy.URL+"/token/lock_address", strings.NewReader(`{"ID":"new"}`))
+	require.NoError(t, err)
+	request.Header.Set("Content-Type", "application/json")
+	response, err := http.DefaultClient.Do(request)
+	require.NoError(t, err)
+	defer response.Body.Close()
+
+	body, err := ioutil.ReadAll(response.Body)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusConflict, response.StatusCode)
+	assert.Equal(t, `{"ID":"lock"}`, string(body))
+	assert.Equal(t, "application/json", response.Header.Get("Content-Type"))
+
+	require.NotNil(t, received)
+	assert.Equal(t, "LOCK", received.Method)
+	assert.Equal(t, "/states/vpc", received.URL.Path)
+	assert.Equal(t, "lock", received.URL.Query().Get("kind"))
+	assert.Equal(t, "application/json", received.Header.Get("Content-Type"))
+	assert.Equal(t, `{"ID":"new"}`, receivedBody)
+
+	// Only the addresses of the backend are forwarded
+	response, err = http.Get(proxy.URL + "/other/address")
+	require.NoError(t, err)
+	response.Body.Close()
+	assert.Equal(t, http.StatusNotFound, response.StatusCode)
+}
@@ -0,0 +1,117 @@
+package remote
+
+import (
+	"testing"
+
+	"github.com/gruntwork-io/terragrunt/options"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHTTPConfigValuesEqual(t *testing.T) {
+	t.Parallel()
+
+	terragruntOptions, err := options.NewTerragruntOptionsForTest("remote_state_test")
+	require.Nil(t, err, "Unexpected error creating NewTerragruntOptionsForTest: %v", err)
+
+	testCases := []struct {
+		name          string
+		config        map[string]interface{}
+		backend       *TerraformBackend
+		shouldBeEqual bool
+	}{
+		{
+			"equal-with-backend-config-strings",
+			map[string]interface{}{"address": "https://state.example.com/foo", "skip_cert_verification": true, "retry_max": float64(5)},
+			&TerraformBackend{Type: "http", Config: map[string]interface{}{"address": "https://state.example.com/foo", "skip_cert_verification": "true", "retry_max": "5", "lock_address": nil}},
+			true,
+		},
+		{
+			"unequal-address",
+			map[string]interface{}{"address": "https://state.example.com/foo"},
+			&TerraformBackend{Type: "http", Config: map[string]interface{}{"address": "https://state.example.com/bar"}},
+			false,
+		},
+		{
+			"unequal-backend-type",
+			map[string]interface{}{"address": "https://state.example.com/foo"},
+			&TerraformBackend{Type: "s3", Config: map[string]interface{}{"address": "https://state.example.com/foo"}},
+			false,
+		},
+	}
+
+	for _, testCase := range testCases {
+		// Save the testCase in local scope so all the t.Run calls don't end up with the last item in the list
+		testCase := testCase
+
+		t.Run(testCase.name, func(t *testing.T) {
+			t.Parallel()
+
+			actual := httpConfigValuesEqual(testCase.config, testCase.backend, terragruntOptions)
+			assert.Equal(t, testCase.shouldBeEqual, actual)
+		})
+	}
+}
+
+func TestValidateHTTPConfig(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name          string
+		config        map[string]interface{}
+		expectedError string
+	}{
+		{
+			"valid",
+			map[string]interface{}{"address": "https://state.example.com/foo", "lock_address": "https://state.example.com/foo/lock", "unlock_address": "https://state.example.com/foo/lock", "retry_max": "5"},
+			"",
+		},
+		{
+			"invalid-address",
+			map[string]interface{}{"address": "state.example.com/foo"},
+			"address",
+		},
+		{
+			"lock-without-unlock",
+			map[string]interface{}{"address": "https://state.example.com/foo", "lock_address": "https://state.example.com/foo/lock"},
+			"unlock_address",
+		},
+		{
+			"sigv4-with-basic-auth",
+			map[string]interface{}{"address": "https://state.example.com/foo", "sigv4_service": "execute-api", "username": "terraform"},
+			"sigv4_service",
+		},
+	}
+
+	for _, testCase := range testCases {
+		// Save the testCase in local scope so all the t.Run calls don't end up with the last item in the list
+		testCase := testCase
+
+		t.Run(testCase.name, func(t *testing.T) {
+			t.Parallel()
+
+			httpConfig, err := parseHTTPConfig(testCase.config)
+			require.NoError(t, err)
+
+			err = validateHTTPConfig(httpConfig)
+			if testCase.expectedError == "" {
+				assert.NoError(t, err)
+			} else {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), testCase.expectedError)
+			}
+		})
+	}
+}
+
+func TestHTTPGetTerraformInitArgs(t *testing.T) {
+	t.Parallel()
+
+	config := map[string]interface{}{"address": "https://state.example.com/foo", "lock_address": "https://state.example.com/foo/lock", "unlock_address": "https://state.example.com/foo/lock", "retry_max": 5}
+	assert.Equal(t, config, HTTPInitializer{}.GetTerraformInitArgs(config))
+
+	// The addresses of the signing proxy are passed via the env vars instead
+	config["sigv4_service"] = "execute-api"
+	config["sigv4_region"] = "eu-west-1"
+	assert.Equal(t, map[string]interface{}{"retry_max": 5}, HTTPInitializer{}.GetTerraformInitArgs(config))
+}
@@ -0,0 +1,187 @@
+package remote
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/gruntwork-io/terragrunt/errors"
+	"github.com/gruntwork-io/terragrunt/options"
+	"github.com/gruntwork-io/terragrunt/shell"
+	"github.com/lib/pq"
+	"github.com/mitchellh/mapstructure"
+)
+
+// A representation of the configuration options available for Postgres remote state
+type RemoteStateConfigPostgres struct {
+	ConnStr            string `mapstructure:"conn_str"`
+	SchemaName         string `mapstructure:"schema_name"`
+	SkipSchemaCreation bool   `mapstructure:"skip_schema_creation"`
+}
+
+// The schema the pg backend stores the state in if schema_name is not set
+const DEFAULT_POSTGRES_SCHEMA_NAME = "terraform_remote_state"
+
+type PostgresInitializer struct{}
+
+// Returns true if:
+//
+// 1. Any of the existing backend settings are different than the current config
+// 2. The configured schema does not exist
+func (postgresInitializer PostgresInitializer) NeedsInitialization(remoteState *RemoteState, existingBackend *TerraformBackend, terragruntOptions *options.TerragruntOptions) (bool, error) {
+	if remoteState.DisableInit {
+		return false, nil
+	}
+
+	if !postgresConfigValuesEqual(remoteState.Config, existingBackend, terragruntOptions) {
+		return true, nil
+	}
+
+	postgresConfig, err := parsePostgresConfig(remoteState.Config)
+	if err != nil {
+		return false, err
+	}
+
+	if postgresConfig.SkipSchemaCreation {
+		return false, nil
+	}
+
+	db, err := openPostgresDB(postgresConfig)
+	if err != nil {
+		return false, err
+	}
+	defer db.Close()
+
+	exists, err := doesPostgresSchemaExist(db, postgresConfig.SchemaName)
+	return !exists, err
+}
+
+// Return true if the given config is in any way different than what is configured for the backend
+func postgresConfigValuesEqual(config map[string]interface{}, existingBackend *TerraformBackend, terragruntOptions *options.TerragruntOptions) bool {
+	if existingBackend == nil {
+		return len(config) == 0
+	}
+
+	if existingBackend.Type != "pg" {
+		terragruntOptions.Logger.Debugf("Backend type has changed from pg to %s", existingBackend.Type)
+		return false
+	}
+
+	if !terraformStateConfigEqual(existingBackend.Config, config) {
+		// Don't log the config, as conn_str usually holds the database password
+		terragruntOptions.Logger.Debugf("Backend config of the pg backend has changed")
+		return false
+	}
+
+	return true
+}
+
+// Initialize the schema specified in the given config. Unless skip_schema_creation is set, if the schema doesn't
+// already exist, prompt the user to create it, and if the user confirms, create it.
+func (postgresInitializer PostgresInitializer) Initialize(remoteState *RemoteState, terragruntOptions *options.TerragruntOptions) error {
+	postgresConfig, err := parsePostgresConfig(remoteState.Config)
+	if err != nil {
+		return err
+	}
+
+	if err := validatePostgresConfig(postgresConfig); err != nil {
+		return err
+	}
+
+	if postgresConfig.SkipSchemaCreation {
+		terragruntOptions.Logger.Debugf("Skipping the creation of the remote state schema %s using 'skip_schema_creation' config.", postgresConfig.SchemaName)
+		return nil
+	}
+
+	db, err := openPostgresDB(postgresConfig)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	exists, err := doesPostgresSchemaExist(db, postgresConfig.SchemaName)
+	if err != nil || exists {
+		return err
+	}
+
+	prompt := fmt.Sprintf("Remote state Postgres schema %s does not exist or you don't have permissions to access it. Would you like Terragrunt to create it?", postgresConfig.SchemaName)
+	shouldCreateSchema, err := shell.PromptUserForYesNo(prompt, terragruntOptions)
+	if err != nil || !shouldCreateSchema {
+		return err
+	}
+
+	terragruntOptions.Logger.Debugf("Creating Postgres schema %s", postgresConfig.SchemaName)
+	_, err = db.Exec(createPostgresSchemaStatement(postgresConfig.SchemaName))
+	return errors.WithStackTrace(err)
+}
+
+// Validate the Postgres remote state config, without connecting to the database
+func (postgresInitializer PostgresInitializer) ValidateConfig(remoteState *RemoteState, terragruntOptions *options.TerragruntOptions) error {
+	postgresConfig, err := parsePostgresConfig(remoteState.Config)
+	if err != nil {
+		return err
+	}
+
+	return validatePostgresConfig(postgresConfig)
+}
+
+// The pg backend has no terragrunt-only settings, so the config is passed on to terraform as is
+func (postgresInitializer PostgresInitializer) GetTerraformInitArgs(config map[string]interface{}) map[string]interface{} {
+	return config
+}
+
+// Parse the given map into a Postgres config
+func parsePostgresConfig(config map[string]interface{}) (*RemoteStateConfigPostgres, error) {
+	var postgresConfig RemoteStateConfigPostgres
+	if err := mapstructure.Decode(config, &postgresConfig); err != nil {
+		return nil, errors.WithStackTrace(err)
+	}
+
+	// Like terraform, fall back to the PG_CONN_STR env var if conn_str isn't in the config
+	postgresConfig.ConnStr = valueOrEnv(postgresConfig.ConnStr, "PG_CONN_STR")
+
+	if postgresConfig.SchemaName == "" {
+		postgresConfig.SchemaName = DEFAULT_POSTGRES_SCHEMA_NAME
+	}
+
+	return &postgresConfig, nil
+}
+
+// Validate all the parameters of the given Postgres remote state configuration
+func validatePostgresConfig(config *RemoteStateConfigPostgres) error {
+	if config.ConnStr == "" {
+		return errors.WithStackTrace(MissingRequiredPostgresRemoteStateConfig("conn_str"))
+	}
+	return nil
+}
+
+// Open a connection to the database in the given config
+func openPostgresDB(config *RemoteStateConfigPostgres) (*sql.DB, error) {
+	db, err := sql.Open("postgres", config.ConnStr)
+	if err != nil {
+		return nil, errors.WithStackTrace(err)
+	}
+	return db, nil
+}
+
+// Returns true if the given schema exists in the database
+func doesPostgresSchemaExist(db *sql.DB, schemaName string) (bool, error) {
+	var exists bool
+	query := "SELECT EXISTS (SELECT 1 FROM information_schema.schemata WHERE schema_name = $1)"
+	if err := db.QueryRow(query, schemaName).Scan(&exists); err != nil {
+		return false, errors.WithStackTrace(err)
+	}
+	return exists, nil
+}
+
+// Return the SQL statement to create the given schema
+func createPostgresSchemaStatement(schemaName string) string {
+	return fmt.Sprintf("CREATE SCHEMA IF NOT EXISTS %s", pq.QuoteIdentifier(schemaName))
+}
+
+// Custom error types
+
+type MissingRequiredPostgresRemoteStateConfig string
+
+func (configName MissingRequiredPostgresRemoteStateConfig) Error() string {
+	return fmt.Sprintf("Missing required Postgres remote state configuration %s", string(configName))
+}
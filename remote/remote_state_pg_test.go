@@ -0,0 +1,44 @@
+package remote
+
+import (
+	"testing"
+
+	"github.com/gruntwork-io/terragrunt/options"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParsePostgresConfig(t *testing.T) {
+	t.Parallel()
+
+	postgresConfig, err := parsePostgresConfig(map[string]interface{}{"conn_str": "postgres://localhost/terraform_backend"})
+	require.NoError(t, err)
+	assert.Equal(t, "postgres://localhost/terraform_backend", postgresConfig.ConnStr)
+	assert.Equal(t, DEFAULT_POSTGRES_SCHEMA_NAME, postgresConfig.SchemaName)
+	assert.False(t, postgresConfig.SkipSchemaCreation)
+
+	postgresConfig, err = parsePostgresConfig(map[string]interface{}{"conn_str": "postgres://localhost/terraform_backend", "schema_name": "prod", "skip_schema_creation": true})
+	require.NoError(t, err)
+	assert.Equal(t, "prod", postgresConfig.SchemaName)
+	assert.True(t, postgresConfig.SkipSchemaCreation)
+}
+
+func TestCreatePostgresSchemaStatement(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, `CREATE SCHEMA IF NOT EXISTS "terraform_remote_state"`, createPostgresSchemaStatement("terraform_remote_state"))
+	assert.Equal(t, `CREATE SCHEMA IF NOT EXISTS "prod""; DROP SCHEMA public; --"`, createPostgresSchemaStatement(`prod"; DROP SCHEMA public; --`))
+}
+
+func TestPostgresConfigValuesEqual(t *testing.T) {
+	t.Parallel()
+
+	terragruntOptions, err := options.NewTerragruntOptionsForTest("remote_state_test")
+	require.Nil(t, err, "Unexpected error creating NewTerragruntOptionsForTest: %v", err)
+
+	config := map[string]interface{}{"conn_str": "postgres://localhost/terraform_backend", "schema_name": "prod"}
+
+	assert.True(t, postgresConfigValuesEqual(config, &TerraformBackend{Type: "pg", Config: map[string]interface{}{"conn_str": "postgres://localhost/terraform_backend", "schema_name": "prod", "skip_schema_creation": nil}}, terragruntOptions))
+	assert.False(t, postgresConfigValuesEqual(config, &TerraformBackend{Type: "pg", Config: map[string]interface{}{"conn_str": "postgres://localhost/terraform_backend", "schema_name": "stage"}}, terragruntOptions))
+	assert.False(t, postgresConfigValuesEqual(config, &TerraformBackend{Type: "s3", Config: config}, terragruntOptions))
+}
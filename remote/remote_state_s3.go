@@ -3,6 +3,9 @@ package remote
 import (
 	"encoding/json"
 	"fmt"
+	"io/ioutil"
+	"net/url"
+	"path"
 	"reflect"
 	"strconv"
 	"time"
@@ -16,6 +19,7 @@ import (
 	"github.com/gruntwork-io/terragrunt/options"
 	"github.com/gruntwork-io/terragrunt/shell"
 	"github.com/gruntwork-io/terragrunt/util"
+	"github.com/hashicorp/go-version"
 	"github.com/mitchellh/mapstructure"
 	"github.com/sirupsen/logrus"
 )
@@ -25,6 +29,10 @@ const (
 	DefaultS3BucketAccessLoggingTargetPrefix = "TFStateLogs/"
 )
 
+// The S3 backend supports use_lockfile, which locks the state with a lock file next to it in the bucket, from terraform
+// 1.10
+var useLockfileMinVersion = version.Must(version.NewVersion("1.10.0"))
+
 /*
  * We use this construct to separate the two config keys 's3_bucket_tags' and 'dynamodb_table_tags'
  * from the others, as they are specific to the s3 backend, but only used by terragrunt to tag
@@ -33,17 +41,31 @@ const (
 type ExtendedRemoteStateConfigS3 struct {
 	remoteStateConfigS3 RemoteStateConfigS3
 
-	S3BucketTags                map[string]string `mapstructure:"s3_bucket_tags"`
-	DynamotableTags             map[string]string `mapstructure:"dynamodb_table_tags"`
-	SkipBucketVersioning        bool              `mapstructure:"skip_bucket_versioning"`
-	SkipBucketSSEncryption      bool              `mapstructure:"skip_bucket_ssencryption"`
-	SkipBucketAccessLogging     bool              `mapstructure:"skip_bucket_accesslogging"`
-	SkipBucketRootAccess        bool              `mapstructure:"skip_bucket_root_access"`
-	SkipBucketEnforcedTLS       bool              `mapstructure:"skip_bucket_enforced_tls"`
-	EnableLockTableSSEncryption bool              `mapstructure:"enable_lock_table_ssencryption"`
-	DisableAWSClientChecksums   bool              `mapstructure:"disable_aws_client_checksums"`
-	AccessLoggingBucketName     string            `mapstructure:"accesslogging_bucket_name"`
-	AccessLoggingTargetPrefix   string            `mapstructure:"accesslogging_target_prefix"`
+	S3BucketTags                 map[string]string `mapstructure:"s3_bucket_tags"`
+	DynamotableTags              map[string]string `mapstructure:"dynamodb_table_tags"`
+	SkipBucketVersioning         bool              `mapstructure:"skip_bucket_versioning"`
+	SkipBucketSSEncryption       bool              `mapstructure:"skip_bucket_ssencryption"`
+	SkipBucketAccessLogging      bool              `mapstructure:"skip_bucket_accesslogging"`
+	SkipBucketRootAccess         bool              `mapstructure:"skip_bucket_root_access"`
+	SkipBucketEnforcedTLS        bool              `mapstructure:"skip_bucket_enforced_tls"`
+	SkipBucketPublicAccessBlock  bool              `mapstructure:"skip_bucket_public_access_blocking"`
+	EnableLockTableSSEncryption  bool              `mapstructure:"enable_lock_table_ssencryption"`
+	DisableAWSClientChecksums    bool              `mapstructure:"disable_aws_client_checksums"`
+	AccessLoggingBucketName      string            `mapstructure:"accesslogging_bucket_name"`
+	AccessLoggingTargetPrefix    string            `mapstructure:"accesslogging_target_prefix"`
+	BucketSSEAlgorithm           string            `mapstructure:"bucket_sse_algorithm"`
+	BucketSSEKMSKeyID            string            `mapstructure:"bucket_sse_kms_key_id"`
+	BucketKeyEnabled             bool              `mapstructure:"bucket_key_enabled"`
+	LockTableBillingMode         string            `mapstructure:"dynamodb_table_billing_mode"`
+	LockTableReadCapacity        int64             `mapstructure:"dynamodb_table_read_capacity"`
+	LockTableWriteCapacity       int64             `mapstructure:"dynamodb_table_write_capacity"`
+	LockTableKMSKeyID            string            `mapstructure:"dynamodb_table_kms_key_id"`
+	LockTablePointInTimeRecovery bool              `mapstructure:"dynamodb_table_point_in_time_recovery"`
+	ReplicaBucket                string            `mapstructure:"replica_bucket"`
+	ReplicaRegion                string            `mapstructure:"replica_region"`
+	ReplicaBucketSSEKMSKeyID     string            `mapstructure:"replica_bucket_sse_kms_key_id"`
+	ReplicationRoleName          string            `mapstructure:"replication_role_name"`
+	BucketPolicy                 interface{}       `mapstructure:"bucket_policy"`
 }
 
 // These are settings that can appear in the remote_state config that are ONLY used by Terragrunt and NOT forwarded
@@ -56,10 +78,24 @@ var terragruntOnlyConfigs = []string{
 	"skip_bucket_accesslogging",
 	"skip_bucket_root_access",
 	"skip_bucket_enforced_tls",
+	"skip_bucket_public_access_blocking",
 	"enable_lock_table_ssencryption",
 	"disable_aws_client_checksums",
 	"accesslogging_bucket_name",
 	"accesslogging_target_prefix",
+	"bucket_sse_algorithm",
+	"bucket_sse_kms_key_id",
+	"bucket_key_enabled",
+	"dynamodb_table_billing_mode",
+	"dynamodb_table_read_capacity",
+	"dynamodb_table_write_capacity",
+	"dynamodb_table_kms_key_id",
+	"dynamodb_table_point_in_time_recovery",
+	"replica_bucket",
+	"replica_region",
+	"replica_bucket_sse_kms_key_id",
+	"replication_role_name",
+	"bucket_policy",
 }
 
 // A representation of the configuration options available for S3 remote state
@@ -70,32 +106,108 @@ type RemoteStateConfigS3 struct {
 	Region           string `mapstructure:"region"`
 	Endpoint         string `mapstructure:"endpoint"`
 	DynamoDBEndpoint string `mapstructure:"dynamodb_endpoint"`
+	StsEndpoint      string `mapstructure:"sts_endpoint"`
+	IamEndpoint      string `mapstructure:"iam_endpoint"`
 	Profile          string `mapstructure:"profile"`
 	RoleArn          string `mapstructure:"role_arn"`
 	ExternalID       string `mapstructure:"external_id"`
 	SessionName      string `mapstructure:"session_name"`
 	LockTable        string `mapstructure:"lock_table"` // Deprecated in Terraform version 0.13 or newer.
 	DynamoDBTable    string `mapstructure:"dynamodb_table"`
+	UseLockfile      bool   `mapstructure:"use_lockfile"`
 	CredsFilename    string `mapstructure:"shared_credentials_file"`
 	S3ForcePathStyle bool   `mapstructure:"force_path_style"`
+
+	SkipRegionValidation bool `mapstructure:"skip_region_validation"`
+
+	AssumeRole RemoteStateConfigS3AssumeRole `mapstructure:"assume_role"`
+	Endpoints  RemoteStateConfigS3Endpoints  `mapstructure:"endpoints"`
 }
 
-// Builds a session config for AWS related requests from the RemoteStateConfigS3 configuration
+// The endpoints block of the S3 remote state config, which replaces the endpoint, dynamodb_endpoint, sts_endpoint and
+// iam_endpoint attributes in terraform 1.6 and newer
+type RemoteStateConfigS3Endpoints struct {
+	S3       string `mapstructure:"s3"`
+	DynamoDB string `mapstructure:"dynamodb"`
+	STS      string `mapstructure:"sts"`
+	IAM      string `mapstructure:"iam"`
+}
+
+// The assume_role block of the S3 remote state config, which replaces the role_arn, external_id and session_name
+// attributes in terraform 1.6 and newer
+type RemoteStateConfigS3AssumeRole struct {
+	RoleArn     string `mapstructure:"role_arn"`
+	ExternalID  string `mapstructure:"external_id"`
+	SessionName string `mapstructure:"session_name"`
+	Duration    string `mapstructure:"duration"`
+}
+
+// Builds a session config for AWS related requests from the RemoteStateConfigS3 configuration. The credentials and
+// role configured in the remote state take precedence over the iam_role of the module, so that the state can be kept
+// in an account that the role of the module has no access to.
 func (c *ExtendedRemoteStateConfigS3) GetAwsSessionConfig() *aws_helper.AwsSessionConfig {
+	assumeRole := c.remoteStateConfigS3.GetAssumeRole()
+	endpoints := c.remoteStateConfigS3.GetEndpoints()
+
+	// An invalid duration is reported by validateS3Config, here it just falls back to the default duration
+	assumeRoleDuration, _ := time.ParseDuration(assumeRole.Duration)
+
 	return &aws_helper.AwsSessionConfig{
 		Region:                  c.remoteStateConfigS3.Region,
-		CustomS3Endpoint:        c.remoteStateConfigS3.Endpoint,
-		CustomDynamoDBEndpoint:  c.remoteStateConfigS3.DynamoDBEndpoint,
+		CustomS3Endpoint:        endpoints.S3,
+		CustomDynamoDBEndpoint:  endpoints.DynamoDB,
+		CustomSTSEndpoint:       endpoints.STS,
+		CustomIAMEndpoint:       endpoints.IAM,
 		Profile:                 c.remoteStateConfigS3.Profile,
-		RoleArn:                 c.remoteStateConfigS3.RoleArn,
-		ExternalID:              c.remoteStateConfigS3.ExternalID,
-		SessionName:             c.remoteStateConfigS3.SessionName,
+		RoleArn:                 assumeRole.RoleArn,
+		ExternalID:              assumeRole.ExternalID,
+		SessionName:             assumeRole.SessionName,
+		AssumeRoleDuration:      assumeRoleDuration,
 		CredsFilename:           c.remoteStateConfigS3.CredsFilename,
 		S3ForcePathStyle:        c.remoteStateConfigS3.S3ForcePathStyle,
 		DisableComputeChecksums: c.DisableAWSClientChecksums,
 	}
 }
 
+// Returns the role to assume to access the state: the one in the assume_role block if it's set, or else the one in the
+// role_arn, external_id and session_name attributes.
+func (s3Config *RemoteStateConfigS3) GetAssumeRole() RemoteStateConfigS3AssumeRole {
+	if s3Config.AssumeRole.RoleArn != "" {
+		return s3Config.AssumeRole
+	}
+
+	return RemoteStateConfigS3AssumeRole{
+		RoleArn:     s3Config.RoleArn,
+		ExternalID:  s3Config.ExternalID,
+		SessionName: s3Config.SessionName,
+	}
+}
+
+// Returns the endpoints of the AWS services of the state: the ones in the endpoints block, or else the ones in the
+// endpoint, dynamodb_endpoint, sts_endpoint and iam_endpoint attributes. Empty endpoints are the default ones.
+func (s3Config *RemoteStateConfigS3) GetEndpoints() RemoteStateConfigS3Endpoints {
+	endpoints := s3Config.Endpoints
+	if endpoints.S3 == "" {
+		endpoints.S3 = s3Config.Endpoint
+	}
+	if endpoints.DynamoDB == "" {
+		endpoints.DynamoDB = s3Config.DynamoDBEndpoint
+	}
+	if endpoints.STS == "" {
+		endpoints.STS = s3Config.StsEndpoint
+	}
+	if endpoints.IAM == "" {
+		endpoints.IAM = s3Config.IamEndpoint
+	}
+	return endpoints
+}
+
+// Returns true if terraform locks the state with a DynamoDB table. This is the case whenever a table is set, even along
+// with use_lockfile, as terraform then locks the state with both while migrating to lock files.
+func (s3Config *RemoteStateConfigS3) UsesLockTable() bool {
+	return s3Config.GetLockTableName() != ""
+}
+
 // The DynamoDB lock table attribute used to be called "lock_table", but has since been renamed to "dynamodb_table", and
 // the old attribute name deprecated. The old attribute name has been eventually removed from Terraform starting with
 // release 0.13. To maintain backwards compatibility, we support both names.
@@ -148,6 +260,21 @@ func (s3Initializer S3Initializer) NeedsInitialization(remoteState *RemoteState,
 
 	sessionConfig := s3ConfigExtended.GetAwsSessionConfig()
 
+	// The bucket and lock table are in different services, so they're checked concurrently
+	return anyCheckTrue(
+		func() (bool, error) { return s3BucketNeedsInitialization(&s3Config, sessionConfig, terragruntOptions) },
+		func() (bool, error) { return lockTableNeedsInitialization(&s3Config, sessionConfig, terragruntOptions) },
+	)
+}
+
+// Returns true if the S3 bucket of the given config doesn't exist. The bucket is usually shared by many modules, so it's
+// only checked once per run.
+func s3BucketNeedsInitialization(s3Config *RemoteStateConfigS3, sessionConfig *aws_helper.AwsSessionConfig, terragruntOptions *options.TerragruntOptions) (bool, error) {
+	bucketCheckKey := s3BucketCheckKey(s3Config)
+	if remoteStateResourceExists(bucketCheckKey) {
+		return false, nil
+	}
+
 	s3Client, err := CreateS3Client(sessionConfig, terragruntOptions)
 	if err != nil {
 		return false, err
@@ -156,25 +283,59 @@ func (s3Initializer S3Initializer) NeedsInitialization(remoteState *RemoteState,
 	if !DoesS3BucketExist(s3Client, &s3Config.Bucket) {
 		return true, nil
 	}
+	markRemoteStateResourceExists(bucketCheckKey)
+	return false, nil
+}
 
-	if s3Config.GetLockTableName() != "" {
-		dynamodbClient, err := dynamodb.CreateDynamoDbClient(sessionConfig, terragruntOptions)
-		if err != nil {
-			return false, err
-		}
+// Returns true if the given config uses a DynamoDB lock table that doesn't exist or isn't active yet. Like the bucket,
+// the lock table is usually shared by many modules, so it's only checked once per run.
+func lockTableNeedsInitialization(s3Config *RemoteStateConfigS3, sessionConfig *aws_helper.AwsSessionConfig, terragruntOptions *options.TerragruntOptions) (bool, error) {
+	if !s3Config.UsesLockTable() {
+		return false, nil
+	}
 
-		tableExists, err := dynamodb.LockTableExistsAndIsActive(s3Config.GetLockTableName(), dynamodbClient)
-		if err != nil {
-			return false, err
-		}
-		if !tableExists {
-			return true, nil
-		}
+	lockTableCheckKey := lockTableCheckKey(s3Config, terragruntOptions)
+	if remoteStateResourceExists(lockTableCheckKey) {
+		return false, nil
 	}
 
+	dynamodbClient, err := dynamodb.CreateDynamoDbClient(sessionConfig, terragruntOptions)
+	if err != nil {
+		return false, err
+	}
+
+	tableExists, err := dynamodb.LockTableExistsAndIsActive(s3Config.GetLockTableName(), dynamodbClient)
+	if err != nil {
+		return false, err
+	}
+	if !tableExists {
+		return true, nil
+	}
+	markRemoteStateResourceExists(lockTableCheckKey)
 	return false, nil
 }
 
+// Return the key under which the existence of the S3 bucket of the given config is recorded. Bucket names are global,
+// so only the endpoint is needed to tell S3 compatible stores apart.
+func s3BucketCheckKey(config *RemoteStateConfigS3) string {
+	return fmt.Sprintf("s3-bucket:%s:%s", config.Endpoint, config.Bucket)
+}
+
+// Return the key under which the existence of the DynamoDB lock table of the given config is recorded. Table names are
+// only unique within an account and region, so the settings that select those are part of the key.
+func lockTableCheckKey(config *RemoteStateConfigS3, terragruntOptions *options.TerragruntOptions) string {
+	return fmt.Sprintf(
+		"dynamodb-table:%s:%s:%s:%s:%s:%s:%s",
+		config.DynamoDBEndpoint,
+		config.Region,
+		config.Profile,
+		config.GetAssumeRole().RoleArn,
+		terragruntOptions.IamRole,
+		config.CredsFilename,
+		config.GetLockTableName(),
+	)
+}
+
 // Return true if the given config is in any way different than what is configured for the backend
 func configValuesEqual(config map[string]interface{}, existingBackend *TerraformBackend, terragruntOptions *options.TerragruntOptions) bool {
 	if existingBackend == nil {
@@ -243,12 +404,39 @@ func (s3Initializer S3Initializer) Initialize(remoteState *RemoteState, terragru
 
 	var s3Config = s3ConfigExtended.remoteStateConfigS3
 
+	if !s3Config.Encrypt {
+		terragruntOptions.Logger.Warnf("Encryption is not enabled on the S3 remote state bucket %s. Terraform state files may contain secrets, so we STRONGLY recommend enabling encryption!", s3Config.Bucket)
+	}
+
 	// Display a deprecation warning when the "lock_table" attribute is being used
 	// during initialization.
 	if s3Config.LockTable != "" {
 		terragruntOptions.Logger.Warnf("%s\n", lockTableDeprecationMessage)
 	}
 
+	if s3Config.UseLockfile && s3Config.UsesLockTable() {
+		terragruntOptions.Logger.Warnf("Both use_lockfile and dynamodb_table are set for the S3 remote state bucket %s, so terraform will lock the state with both the lock file and the DynamoDB table %s. Remove dynamodb_table once you have migrated to lock files.", s3Config.Bucket, s3Config.GetLockTableName())
+	}
+
+	// The bucket and the lock table don't depend on each other, so they're set up concurrently. Only setting up the
+	// bucket may prompt the user, so the prompts don't get mixed up.
+	return runAllConcurrently(
+		func() error {
+			return initializeS3Bucket(s3ConfigExtended, terragruntOptions)
+		},
+		func() error {
+			if err := createLockTableIfNecessary(s3ConfigExtended, terragruntOptions); err != nil {
+				return err
+			}
+			return UpdateLockTableSetSSEncryptionOnIfNecessary(&s3Config, s3ConfigExtended, terragruntOptions)
+		},
+	)
+}
+
+// Create the S3 bucket specified in the given config if it doesn't already exist, and check its settings
+func initializeS3Bucket(s3ConfigExtended *ExtendedRemoteStateConfigS3, terragruntOptions *options.TerragruntOptions) error {
+	s3Config := s3ConfigExtended.remoteStateConfigS3
+
 	s3Client, err := CreateS3Client(s3ConfigExtended.GetAwsSessionConfig(), terragruntOptions)
 	if err != nil {
 		return err
@@ -258,21 +446,26 @@ func (s3Initializer S3Initializer) Initialize(remoteState *RemoteState, terragru
 		return err
 	}
 
-	if !s3ConfigExtended.SkipBucketVersioning {
-		if err := checkIfVersioningEnabled(s3Client, &s3Config, terragruntOptions); err != nil {
-			return err
-		}
-	}
-
-	if err := createLockTableIfNecessary(s3ConfigExtended, s3ConfigExtended.DynamotableTags, terragruntOptions); err != nil {
-		return err
-	}
-
-	if err := UpdateLockTableSetSSEncryptionOnIfNecessary(&s3Config, s3ConfigExtended, terragruntOptions); err != nil {
+	// Checking the versioning and encryption of the bucket only reads its settings, so both are checked concurrently
+	err = runAllConcurrently(
+		func() error {
+			if s3ConfigExtended.SkipBucketVersioning {
+				return nil
+			}
+			return checkIfVersioningEnabled(s3Client, &s3Config, terragruntOptions)
+		},
+		func() error {
+			if s3ConfigExtended.SkipBucketSSEncryption {
+				return nil
+			}
+			return checkIfSSEForS3MatchesConfig(s3Client, s3ConfigExtended, terragruntOptions)
+		},
+	)
+	if err != nil {
 		return err
 	}
 
-	return nil
+	return configureS3BucketReplicationIfNecessary(s3Client, s3ConfigExtended, terragruntOptions)
 }
 
 func (s3Initializer S3Initializer) GetTerraformInitArgs(config map[string]interface{}) map[string]interface{} {
@@ -323,6 +516,14 @@ func parseExtendedS3Config(config map[string]interface{}) (*ExtendedRemoteStateC
 		extendedConfig.AccessLoggingTargetPrefix = DefaultS3BucketAccessLoggingTargetPrefix
 	}
 
+	if extendedConfig.BucketSSEAlgorithm == "" {
+		extendedConfig.BucketSSEAlgorithm = s3.ServerSideEncryptionAwsKms
+	}
+
+	if extendedConfig.LockTableBillingMode == "" {
+		extendedConfig.LockTableBillingMode = dynamodb.DYNAMODB_PAY_PER_REQUEST_BILLING_MODE
+	}
+
 	extendedConfig.remoteStateConfigS3 = s3Config
 
 	return &extendedConfig, nil
@@ -330,12 +531,68 @@ func parseExtendedS3Config(config map[string]interface{}) (*ExtendedRemoteStateC
 
 // Validate all the parameters of the given S3 remote state configuration
 func validateS3Config(extendedConfig *ExtendedRemoteStateConfigS3, terragruntOptions *options.TerragruntOptions) error {
-	var config = extendedConfig.remoteStateConfigS3
+	if err := validateS3BackendConfig(&extendedConfig.remoteStateConfigS3); err != nil {
+		return err
+	}
+
+	if extendedConfig.remoteStateConfigS3.UseLockfile && terragruntOptions.TerraformVersion != nil && terragruntOptions.TerraformVersion.LessThan(useLockfileMinVersion) {
+		return errors.WithStackTrace(UseLockfileNotSupported{TerraformVersion: terragruntOptions.TerraformVersion})
+	}
 
+	if !extendedConfig.remoteStateConfigS3.SkipRegionValidation && extendedConfig.ReplicaRegion != "" {
+		if err := validateAWSRegion("replica_region", extendedConfig.ReplicaRegion); err != nil {
+			return err
+		}
+	}
+
+	if extendedConfig.BucketSSEAlgorithm != s3.ServerSideEncryptionAwsKms && extendedConfig.BucketSSEAlgorithm != s3.ServerSideEncryptionAes256 {
+		return errors.WithStackTrace(InvalidS3BucketSSEAlgorithm(extendedConfig.BucketSSEAlgorithm))
+	}
+
+	if extendedConfig.BucketSSEKMSKeyID != "" && extendedConfig.BucketSSEAlgorithm != s3.ServerSideEncryptionAwsKms {
+		return errors.WithStackTrace(S3BucketSSEKMSKeyWithoutKMSAlgorithm(extendedConfig.BucketSSEAlgorithm))
+	}
+
+	switch extendedConfig.LockTableBillingMode {
+	case dynamodb.DYNAMODB_PAY_PER_REQUEST_BILLING_MODE:
+		if extendedConfig.LockTableReadCapacity != 0 || extendedConfig.LockTableWriteCapacity != 0 {
+			return errors.WithStackTrace(LockTableCapacityWithoutProvisionedBillingMode(extendedConfig.LockTableBillingMode))
+		}
+	case dynamodb.DYNAMODB_PROVISIONED_BILLING_MODE:
+		if extendedConfig.LockTableReadCapacity <= 0 {
+			return errors.WithStackTrace(MissingRequiredS3RemoteStateConfig("dynamodb_table_read_capacity"))
+		}
+		if extendedConfig.LockTableWriteCapacity <= 0 {
+			return errors.WithStackTrace(MissingRequiredS3RemoteStateConfig("dynamodb_table_write_capacity"))
+		}
+	default:
+		return errors.WithStackTrace(InvalidLockTableBillingMode(extendedConfig.LockTableBillingMode))
+	}
+
+	if err := validateS3ReplicationConfig(extendedConfig); err != nil {
+		return err
+	}
+
+	if _, err := customS3BucketPolicyStatements(extendedConfig); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// Validate the settings the S3 backend itself requires. The settings only Terragrunt uses, to create and configure
+// the bucket and lock table, are validated by validateS3Config when they're used.
+func validateS3BackendConfig(config *RemoteStateConfigS3) error {
 	if config.Region == "" {
 		return errors.WithStackTrace(MissingRequiredS3RemoteStateConfig("region"))
 	}
 
+	if !config.SkipRegionValidation {
+		if err := validateAWSRegion("region", config.Region); err != nil {
+			return err
+		}
+	}
+
 	if config.Bucket == "" {
 		return errors.WithStackTrace(MissingRequiredS3RemoteStateConfig("bucket"))
 	}
@@ -344,13 +601,35 @@ func validateS3Config(extendedConfig *ExtendedRemoteStateConfigS3, terragruntOpt
 		return errors.WithStackTrace(MissingRequiredS3RemoteStateConfig("key"))
 	}
 
-	if !config.Encrypt {
-		terragruntOptions.Logger.Warnf("Encryption is not enabled on the S3 remote state bucket %s. Terraform state files may contain secrets, so we STRONGLY recommend enabling encryption!", config.Bucket)
+	if config.AssumeRole.Duration != "" {
+		if _, err := time.ParseDuration(config.AssumeRole.Duration); err != nil {
+			return errors.WithStackTrace(InvalidS3AssumeRoleDuration(config.AssumeRole.Duration))
+		}
 	}
 
 	return nil
 }
 
+// Validate the settings the S3 backend requires, without making any calls to AWS, so that mistakes in the config are
+// reported before running terraform init
+func (s3Initializer S3Initializer) ValidateConfig(remoteState *RemoteState, terragruntOptions *options.TerragruntOptions) error {
+	s3ConfigExtended, err := parseExtendedS3Config(remoteState.Config)
+	if err != nil {
+		return err
+	}
+
+	return validateS3BackendConfig(&s3ConfigExtended.remoteStateConfigS3)
+}
+
+// Validate that the given region, set in the config setting of the given name, is an AWS region, e.g. us-east-1.
+// Regions that aren't known to the AWS SDK yet are allowed, as long as they have the same format.
+func validateAWSRegion(name string, region string) error {
+	if !aws_helper.IsRegion(region) {
+		return errors.WithStackTrace(InvalidS3RemoteStateRegion{Name: name, Region: region})
+	}
+	return nil
+}
+
 // If the bucket specified in the given config doesn't already exist, prompt the user to create it, and if the user
 // confirms, create the bucket and enable versioning for it.
 func createS3BucketIfNecessary(s3Client *s3.S3, config *ExtendedRemoteStateConfigS3, terragruntOptions *options.TerragruntOptions) error {
@@ -414,19 +693,13 @@ func CreateS3BucketWithVersioningSSEncryptionAndAccessLogging(s3Client *s3.S3, c
 		return err
 	}
 
-	if config.SkipBucketRootAccess {
-		terragruntOptions.Logger.Debugf("Root access is disabled for the remote state S3 bucket %s using 'skip_bucket_root_access' config.", config.remoteStateConfigS3.Bucket)
-	} else if err := EnableRootAccesstoS3Bucket(s3Client, config, terragruntOptions); err != nil {
-		return err
-	}
-
-	if config.SkipBucketEnforcedTLS {
-		terragruntOptions.Logger.Debugf("TLS enforcement is disabled for the remote state S3 bucket %s using 'skip_bucket_enforced_tls' config.", config.remoteStateConfigS3.Bucket)
-	} else if err := EnableEnforcedTLSAccesstoS3Bucket(s3Client, &config.remoteStateConfigS3, terragruntOptions); err != nil {
+	if err := PutS3BucketPolicy(s3Client, config, terragruntOptions); err != nil {
 		return err
 	}
 
-	if err := EnablePublicAccessBlockingForS3Bucket(s3Client, &config.remoteStateConfigS3, terragruntOptions); err != nil {
+	if config.SkipBucketPublicAccessBlock {
+		terragruntOptions.Logger.Debugf("Public access blocking is disabled for the remote state S3 bucket %s using 'skip_bucket_public_access_blocking' config.", config.remoteStateConfigS3.Bucket)
+	} else if err := EnablePublicAccessBlockingForS3Bucket(s3Client, &config.remoteStateConfigS3, terragruntOptions); err != nil {
 		return err
 	}
 
@@ -442,7 +715,7 @@ func CreateS3BucketWithVersioningSSEncryptionAndAccessLogging(s3Client *s3.S3, c
 
 	if config.SkipBucketSSEncryption {
 		terragruntOptions.Logger.Debugf("Server-Side Encryption is disabled for the remote state AWS S3 bucket %s using 'skip_bucket_ssencryption' config.", config.remoteStateConfigS3.Bucket)
-	} else if err := EnableSSEForS3BucketWide(s3Client, &config.remoteStateConfigS3, terragruntOptions); err != nil {
+	} else if err := EnableSSEForS3BucketWide(s3Client, config, terragruntOptions); err != nil {
 		return err
 	}
 
@@ -559,41 +832,41 @@ func isBucketAlreadyOwnedByYouError(err error) bool {
 	return isAwsErr && (awsErr.Code() == "BucketAlreadyOwnedByYou" || awsErr.Code() == "OperationAborted")
 }
 
-// Add a policy to allow root access to the bucket
-func EnableRootAccesstoS3Bucket(s3Client *s3.S3, config *ExtendedRemoteStateConfigS3, terragruntOptions *options.TerragruntOptions) error {
+// Put the bucket policy of the AWS S3 bucket specified in the given config. A bucket has a single policy, so the
+// statements that give access to the root user and that enforce TLS are put together, unless they are disabled with
+// 'skip_bucket_root_access' and 'skip_bucket_enforced_tls', along with the statements of 'bucket_policy'.
+func PutS3BucketPolicy(s3Client *s3.S3, config *ExtendedRemoteStateConfigS3, terragruntOptions *options.TerragruntOptions) error {
 	bucket := config.remoteStateConfigS3.Bucket
-	terragruntOptions.Logger.Debugf("Enabling root access to S3 bucket %s", bucket)
 
-	accountID, err := aws_helper.GetAWSAccountID(config.GetAwsSessionConfig(), terragruntOptions)
-	if err != nil {
-		return errors.WithStackTrace(err)
+	accountID := ""
+	if config.SkipBucketRootAccess {
+		terragruntOptions.Logger.Debugf("Root access is disabled for the remote state S3 bucket %s using 'skip_bucket_root_access' config.", bucket)
+	} else {
+		var err error
+		accountID, err = aws_helper.GetAWSAccountID(config.GetAwsSessionConfig(), terragruntOptions)
+		if err != nil {
+			return errors.WithStackTrace(err)
+		}
 	}
 
-	rootS3Policy := map[string]interface{}{
-		"Version": "2012-10-17",
-		"Statement": []map[string]interface{}{
-			{
-				"Sid":    "RootAccess",
-				"Effect": "Allow",
-				"Action": "s3:*",
-				"Resource": []string{
-					"arn:aws:s3:::" + bucket,
-					"arn:aws:s3:::" + bucket + "/*",
-				},
-				"Principal": map[string][]string{
-					"AWS": []string{
-						"arn:aws:iam::" + accountID + ":root",
-					},
-				},
-			},
-		},
+	if config.SkipBucketEnforcedTLS {
+		terragruntOptions.Logger.Debugf("TLS enforcement is disabled for the remote state S3 bucket %s using 'skip_bucket_enforced_tls' config.", bucket)
+	}
+
+	bucketPolicy, err := s3BucketPolicy(config, accountID)
+	if err != nil {
+		return err
+	}
+	if bucketPolicy == nil {
+		return nil
 	}
 
-	policy, err := json.Marshal(rootS3Policy)
+	policy, err := json.Marshal(bucketPolicy)
 	if err != nil {
 		return errors.WithStackTrace(err)
 	}
 
+	terragruntOptions.Logger.Debugf("Putting the bucket policy of S3 bucket %s", bucket)
 	_, err = s3Client.PutBucketPolicy(&s3.PutBucketPolicyInput{
 		Bucket: aws.String(bucket),
 		Policy: aws.String(string(policy)),
@@ -602,50 +875,125 @@ func EnableRootAccesstoS3Bucket(s3Client *s3.S3, config *ExtendedRemoteStateConf
 		return errors.WithStackTrace(err)
 	}
 
-	terragruntOptions.Logger.Debugf("Enabled root access to bucket %s", bucket)
+	terragruntOptions.Logger.Debugf("Put the bucket policy of S3 bucket %s", bucket)
 	return nil
 }
 
-// Add a policy to enforce TLS based access to the bucket
-func EnableEnforcedTLSAccesstoS3Bucket(s3Client *s3.S3, config *RemoteStateConfigS3, terragruntOptions *options.TerragruntOptions) error {
-	terragruntOptions.Logger.Debugf("Enabling enforced TLS access for S3 bucket %s", config.Bucket)
-
-	tlsS3Policy := map[string]interface{}{
-		"Version": "2012-10-17",
-		"Statement": []map[string]interface{}{
-			{
-				"Sid":    "AllowTLSRequestsOnly",
-				"Action": "s3:*",
-				"Effect": "Deny",
-				"Resource": []string{
-					"arn:aws:s3:::" + config.Bucket,
-					"arn:aws:s3:::" + config.Bucket + "/*",
+// Return the bucket policy for the AWS S3 bucket specified in the given config, or nil if it has no statements. The
+// root user of the given account is given access to the bucket unless the account ID is empty.
+func s3BucketPolicy(config *ExtendedRemoteStateConfigS3, accountID string) (map[string]interface{}, error) {
+	bucketArn := s3BucketArn(config.remoteStateConfigS3.Bucket, config.remoteStateConfigS3.Region)
+	resources := []string{
+		bucketArn,
+		bucketArn + "/*",
+	}
+
+	statements := []map[string]interface{}{}
+
+	if !config.SkipBucketRootAccess && accountID != "" {
+		statements = append(statements, map[string]interface{}{
+			"Sid":      "RootAccess",
+			"Effect":   "Allow",
+			"Action":   "s3:*",
+			"Resource": resources,
+			"Principal": map[string][]string{
+				"AWS": []string{
+					fmt.Sprintf("arn:%s:iam::%s:root", aws_helper.PartitionOfRegion(config.remoteStateConfigS3.Region), accountID),
 				},
-				"Condition": map[string]interface{}{
-					"Bool": map[string]interface{}{
-						"aws:SecureTransport": "false",
-					},
+			},
+		})
+	}
+
+	if !config.SkipBucketEnforcedTLS {
+		statements = append(statements, map[string]interface{}{
+			"Sid":      "AllowTLSRequestsOnly",
+			"Action":   "s3:*",
+			"Effect":   "Deny",
+			"Resource": resources,
+			"Condition": map[string]interface{}{
+				"Bool": map[string]interface{}{
+					"aws:SecureTransport": "false",
 				},
-				"Principal": "*",
 			},
-		},
+			"Principal": "*",
+		})
 	}
 
-	policy, err := json.Marshal(tlsS3Policy)
+	customStatements, err := customS3BucketPolicyStatements(config)
 	if err != nil {
-		return errors.WithStackTrace(err)
+		return nil, err
+	}
+	for _, statement := range customStatements {
+		// Statements without resources apply to the bucket and its objects, so that they don't have to repeat its name
+		if _, hasResource := statement["Resource"]; !hasResource {
+			if _, hasNotResource := statement["NotResource"]; !hasNotResource {
+				statement["Resource"] = resources
+			}
+		}
+		statements = append(statements, statement)
 	}
 
-	_, err = s3Client.PutBucketPolicy(&s3.PutBucketPolicyInput{
-		Bucket: aws.String(config.Bucket),
-		Policy: aws.String(string(policy)),
-	})
-	if err != nil {
-		return errors.WithStackTrace(err)
+	if len(statements) == 0 {
+		return nil, nil
 	}
 
-	terragruntOptions.Logger.Debugf("Enabled enforced TLS access for bucket %s", config.Bucket)
-	return nil
+	return map[string]interface{}{
+		"Version":   "2012-10-17",
+		"Statement": statements,
+	}, nil
+}
+
+// Return the statements of the bucket_policy setting of the given config. The policy can be given as a map or as a
+// JSON string, and its Statement can be a single statement or a list of statements.
+func customS3BucketPolicyStatements(config *ExtendedRemoteStateConfigS3) ([]map[string]interface{}, error) {
+	if config.BucketPolicy == nil {
+		return nil, nil
+	}
+
+	var policy map[string]interface{}
+	switch bucketPolicy := config.BucketPolicy.(type) {
+	case string:
+		if err := json.Unmarshal([]byte(bucketPolicy), &policy); err != nil {
+			return nil, errors.WithStackTrace(InvalidS3BucketPolicy(fmt.Sprintf("it is not valid JSON: %v", err)))
+		}
+	case map[string]interface{}:
+		policy = bucketPolicy
+	default:
+		return nil, errors.WithStackTrace(InvalidS3BucketPolicy("it must be a map or a JSON string"))
+	}
+
+	var rawStatements []interface{}
+	switch statement := policy["Statement"].(type) {
+	case []interface{}:
+		rawStatements = statement
+	case map[string]interface{}:
+		rawStatements = []interface{}{statement}
+	default:
+		return nil, errors.WithStackTrace(InvalidS3BucketPolicy("it must have a Statement"))
+	}
+
+	var statements []map[string]interface{}
+	for _, rawStatement := range rawStatements {
+		statement, isMap := rawStatement.(map[string]interface{})
+		if !isMap {
+			return nil, errors.WithStackTrace(InvalidS3BucketPolicy("each of its statements must be a map"))
+		}
+
+		// Copy the statement, so that filling in its resources doesn't change the config
+		statementCopy := make(map[string]interface{}, len(statement))
+		for key, value := range statement {
+			statementCopy[key] = value
+		}
+
+		sid, _ := statementCopy["Sid"].(string)
+		if sid == "RootAccess" || sid == "AllowTLSRequestsOnly" {
+			return nil, errors.WithStackTrace(InvalidS3BucketPolicy(fmt.Sprintf("the Sid %s is used by the statements Terragrunt adds", sid)))
+		}
+
+		statements = append(statements, statementCopy)
+	}
+
+	return statements, nil
 }
 
 // Enable versioning for the S3 bucket specified in the given config
@@ -666,24 +1014,84 @@ func EnableVersioningForS3Bucket(s3Client *s3.S3, config *RemoteStateConfigS3, t
 }
 
 // Enable bucket-wide Server-Side Encryption for the AWS S3 bucket specified in the given config
-func EnableSSEForS3BucketWide(s3Client *s3.S3, config *RemoteStateConfigS3, terragruntOptions *options.TerragruntOptions) error {
-	terragruntOptions.Logger.Debugf("Enabling bucket-wide SSE on AWS S3 bucket %s", config.Bucket)
-	// Encrypt with KMS by default
-	defEnc := &s3.ServerSideEncryptionByDefault{SSEAlgorithm: aws.String(s3.ServerSideEncryptionAwsKms)}
-	rule := &s3.ServerSideEncryptionRule{ApplyServerSideEncryptionByDefault: defEnc}
-	rules := []*s3.ServerSideEncryptionRule{rule}
+func EnableSSEForS3BucketWide(s3Client *s3.S3, config *ExtendedRemoteStateConfigS3, terragruntOptions *options.TerragruntOptions) error {
+	bucket := config.remoteStateConfigS3.Bucket
+	terragruntOptions.Logger.Debugf("Enabling bucket-wide SSE on AWS S3 bucket %s", bucket)
+
+	rules := []*s3.ServerSideEncryptionRule{s3BucketSSERule(config)}
 	serverConfig := &s3.ServerSideEncryptionConfiguration{Rules: rules}
-	input := &s3.PutBucketEncryptionInput{Bucket: aws.String(config.Bucket), ServerSideEncryptionConfiguration: serverConfig}
+	input := &s3.PutBucketEncryptionInput{Bucket: aws.String(bucket), ServerSideEncryptionConfiguration: serverConfig}
 
 	_, err := s3Client.PutBucketEncryption(input)
 	if err != nil {
 		return errors.WithStackTrace(err)
 	}
 
-	terragruntOptions.Logger.Debugf("Enabled bucket-wide SSE on AWS S3 bucket %s", config.Bucket)
+	terragruntOptions.Logger.Debugf("Enabled bucket-wide SSE on AWS S3 bucket %s", bucket)
 	return nil
 }
 
+// Return the default encryption rule for the S3 bucket specified in the given config. Unless configured otherwise,
+// objects are encrypted with the AWS managed KMS key.
+func s3BucketSSERule(config *ExtendedRemoteStateConfigS3) *s3.ServerSideEncryptionRule {
+	defEnc := &s3.ServerSideEncryptionByDefault{SSEAlgorithm: aws.String(config.BucketSSEAlgorithm)}
+	if config.BucketSSEKMSKeyID != "" {
+		defEnc.KMSMasterKeyID = aws.String(config.BucketSSEKMSKeyID)
+	}
+
+	rule := &s3.ServerSideEncryptionRule{ApplyServerSideEncryptionByDefault: defEnc}
+	if config.BucketKeyEnabled {
+		rule.BucketKeyEnabled = aws.Bool(true)
+	}
+
+	return rule
+}
+
+// Check if the default encryption of the S3 bucket specified in the given config matches the config and warn the user
+// if it does not, e.g., because the bucket was created before the KMS key was configured.
+func checkIfSSEForS3MatchesConfig(s3Client *s3.S3, config *ExtendedRemoteStateConfigS3, terragruntOptions *options.TerragruntOptions) error {
+	bucket := config.remoteStateConfigS3.Bucket
+
+	out, err := s3Client.GetBucketEncryption(&s3.GetBucketEncryptionInput{Bucket: aws.String(bucket)})
+	if err != nil {
+		if awsErr, isAwsErr := errors.Unwrap(err).(awserr.Error); isAwsErr && awsErr.Code() == "ServerSideEncryptionConfigurationNotFoundError" {
+			terragruntOptions.Logger.Warnf("Server-Side Encryption is not enabled for the remote state S3 bucket %s. Terraform state files may contain secrets, so we STRONGLY recommend enabling encryption!", bucket)
+			return nil
+		}
+		// Checking the encryption is only advisory, so roles without s3:GetEncryptionConfiguration keep working
+		terragruntOptions.Logger.Warnf("Could not check the default encryption of the remote state S3 bucket %s: %v", bucket, err)
+		return nil
+	}
+
+	if !s3BucketSSEMatchesConfig(out.ServerSideEncryptionConfiguration, config) {
+		expected := s3BucketSSERule(config)
+		terragruntOptions.Logger.Warnf("The default encryption of the remote state S3 bucket %s does not match the config: expected algorithm %s, KMS key %s and bucket key enabled %t. Terragrunt does not update the encryption of existing buckets, so you may want to update it yourself.", bucket, config.BucketSSEAlgorithm, aws.StringValue(expected.ApplyServerSideEncryptionByDefault.KMSMasterKeyID), config.BucketKeyEnabled)
+	}
+
+	return nil
+}
+
+// Returns true if the given server-side encryption configuration of an S3 bucket matches the given config
+func s3BucketSSEMatchesConfig(sseConfig *s3.ServerSideEncryptionConfiguration, config *ExtendedRemoteStateConfigS3) bool {
+	if sseConfig == nil || len(sseConfig.Rules) == 0 || sseConfig.Rules[0].ApplyServerSideEncryptionByDefault == nil {
+		return false
+	}
+
+	rule := sseConfig.Rules[0]
+	defEnc := rule.ApplyServerSideEncryptionByDefault
+
+	if aws.StringValue(defEnc.SSEAlgorithm) != config.BucketSSEAlgorithm {
+		return false
+	}
+
+	// An empty key ID means the AWS managed key, so there's nothing to compare then
+	if config.BucketSSEKMSKeyID != "" && aws.StringValue(defEnc.KMSMasterKeyID) != config.BucketSSEKMSKeyID {
+		return false
+	}
+
+	return aws.BoolValue(rule.BucketKeyEnabled) == config.BucketKeyEnabled
+}
+
 // Enable bucket-wide Access Logging for the AWS S3 bucket specified in the given config
 func EnableAccessLoggingForS3BucketWide(s3Client *s3.S3, config *RemoteStateConfigS3, terragruntOptions *options.TerragruntOptions, logsBucket string, logsBucketPrefix string) error {
 	if err := configureBucketAccessLoggingAcl(s3Client, aws.String(logsBucket), terragruntOptions); err != nil {
@@ -801,9 +1209,9 @@ func DoesS3BucketExist(s3Client *s3.S3, bucket *string) bool {
 }
 
 // Create a table for locks in DynamoDB if the user has configured a lock table and the table doesn't already exist
-func createLockTableIfNecessary(extendedS3Config *ExtendedRemoteStateConfigS3, tags map[string]string, terragruntOptions *options.TerragruntOptions) error {
+func createLockTableIfNecessary(extendedS3Config *ExtendedRemoteStateConfigS3, terragruntOptions *options.TerragruntOptions) error {
 
-	if extendedS3Config.remoteStateConfigS3.GetLockTableName() == "" {
+	if !extendedS3Config.remoteStateConfigS3.UsesLockTable() {
 		return nil
 	}
 
@@ -812,16 +1220,30 @@ func createLockTableIfNecessary(extendedS3Config *ExtendedRemoteStateConfigS3, t
 		return err
 	}
 
-	return dynamodb.CreateLockTableIfNecessary(extendedS3Config.remoteStateConfigS3.GetLockTableName(), tags, dynamodbClient, terragruntOptions)
+	tableName := extendedS3Config.remoteStateConfigS3.GetLockTableName()
+	return dynamodb.CreateLockTableIfNecessary(tableName, lockTableSettings(extendedS3Config), dynamodbClient, terragruntOptions)
+}
+
+// Return the settings to create the lock table specified in the given config with
+func lockTableSettings(config *ExtendedRemoteStateConfigS3) dynamodb.LockTableSettings {
+	return dynamodb.LockTableSettings{
+		Tags:                      config.DynamotableTags,
+		BillingMode:               config.LockTableBillingMode,
+		ReadCapacity:              config.LockTableReadCapacity,
+		WriteCapacity:             config.LockTableWriteCapacity,
+		EnableSSEncryption:        config.EnableLockTableSSEncryption,
+		SSEKMSKeyID:               config.LockTableKMSKeyID,
+		EnablePointInTimeRecovery: config.LockTablePointInTimeRecovery,
+	}
 }
 
 // Update a table for locks in DynamoDB if the user has configured a lock table and the table's server-side encryption isn't turned on
 func UpdateLockTableSetSSEncryptionOnIfNecessary(s3Config *RemoteStateConfigS3, config *ExtendedRemoteStateConfigS3, terragruntOptions *options.TerragruntOptions) error {
-	if !config.EnableLockTableSSEncryption {
+	if !config.EnableLockTableSSEncryption && config.LockTableKMSKeyID == "" {
 		return nil
 	}
 
-	if s3Config.GetLockTableName() == "" {
+	if !s3Config.UsesLockTable() {
 		return nil
 	}
 
@@ -830,7 +1252,184 @@ func UpdateLockTableSetSSEncryptionOnIfNecessary(s3Config *RemoteStateConfigS3,
 		return err
 	}
 
-	return dynamodb.UpdateLockTableSetSSEncryptionOnIfNecessary(s3Config.GetLockTableName(), dynamodbClient, terragruntOptions)
+	return dynamodb.UpdateLockTableSetSSEncryptionOnIfNecessary(s3Config.GetLockTableName(), config.LockTableKMSKeyID, dynamodbClient, terragruntOptions)
+}
+
+// Delete the state object in the S3 bucket, along with its digest in the lock table, if there is one. If versioning is
+// enabled on the bucket, the previous versions of the state are kept, so the state can still be recovered.
+func (s3Initializer S3Initializer) DeleteState(remoteState *RemoteState, terragruntOptions *options.TerragruntOptions) error {
+	s3ConfigExtended, err := parseExtendedS3Config(remoteState.Config)
+	if err != nil {
+		return err
+	}
+
+	if err := validateS3Config(s3ConfigExtended, terragruntOptions); err != nil {
+		return err
+	}
+
+	s3Config := s3ConfigExtended.remoteStateConfigS3
+
+	s3Client, err := CreateS3Client(s3ConfigExtended.GetAwsSessionConfig(), terragruntOptions)
+	if err != nil {
+		return err
+	}
+
+	terragruntOptions.Logger.Debugf("Deleting the state object %s in the S3 bucket %s", s3Config.Key, s3Config.Bucket)
+	if _, err := s3Client.DeleteObject(&s3.DeleteObjectInput{Bucket: aws.String(s3Config.Bucket), Key: aws.String(s3Config.Key)}); err != nil {
+		return errors.WithStackTrace(err)
+	}
+
+	if !s3Config.UsesLockTable() {
+		return nil
+	}
+
+	dynamodbClient, err := dynamodb.CreateDynamoDbClient(s3ConfigExtended.GetAwsSessionConfig(), terragruntOptions)
+	if err != nil {
+		return err
+	}
+
+	// Terraform stores the MD5 digest of the state in the lock table to detect stale reads, and fails if the digest
+	// doesn't match the state, so it has to go with the state
+	return dynamodb.DeleteStateDigest(s3Config.GetLockTableName(), s3StateDigestLockID(&s3Config), dynamodbClient)
+}
+
+// Read the state object in the S3 bucket. For workspaces other than the default one, the s3 backend stores the state
+// under the workspace_key_prefix, which defaults to env:.
+func (s3Initializer S3Initializer) ReadState(remoteState *RemoteState, workspace string, terragruntOptions *options.TerragruntOptions) ([]byte, error) {
+	s3ConfigExtended, err := parseExtendedS3Config(remoteState.Config)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := validateS3Config(s3ConfigExtended, terragruntOptions); err != nil {
+		return nil, err
+	}
+
+	s3Config := s3ConfigExtended.remoteStateConfigS3
+
+	s3Client, err := CreateS3Client(s3ConfigExtended.GetAwsSessionConfig(), terragruntOptions)
+	if err != nil {
+		return nil, err
+	}
+
+	key := s3StateKeyForWorkspace(remoteState.Config, s3Config.Key, workspace)
+	terragruntOptions.Logger.Debugf("Reading the state object %s in the S3 bucket %s", key, s3Config.Bucket)
+
+	output, err := s3Client.GetObject(&s3.GetObjectInput{Bucket: aws.String(s3Config.Bucket), Key: aws.String(key)})
+	if err != nil {
+		if awsErr, isAwsErr := err.(awserr.Error); isAwsErr && awsErr.Code() == s3.ErrCodeNoSuchKey {
+			return nil, nil
+		}
+		return nil, errors.WithStackTrace(err)
+	}
+	defer output.Body.Close()
+
+	stateData, err := ioutil.ReadAll(output.Body)
+	return stateData, errors.WithStackTrace(err)
+}
+
+// Return the key of the state of the given workspace in the s3 backend with the given config and key
+func s3StateKeyForWorkspace(config map[string]interface{}, key string, workspace string) string {
+	if workspace == "" || workspace == "default" {
+		return key
+	}
+
+	workspaceKeyPrefix, hasPrefix := config["workspace_key_prefix"].(string)
+	if !hasPrefix {
+		workspaceKeyPrefix = "env:"
+	}
+	return path.Join(workspaceKeyPrefix, workspace, key)
+}
+
+// Copy the state object of the given workspace from its key in the existing backend to its key in the config, if the
+// key has changed within the same bucket, there is state at the old key, and there is none at the new key. The user is
+// prompted before copying, and the object at the old key is left in place as a backup.
+func (s3Initializer S3Initializer) CopyMovedState(remoteState *RemoteState, existingBackend *TerraformBackend, workspace string, terragruntOptions *options.TerragruntOptions) (bool, error) {
+	s3ConfigExtended, err := parseExtendedS3Config(remoteState.Config)
+	if err != nil {
+		return false, err
+	}
+	s3Config := s3ConfigExtended.remoteStateConfigS3
+
+	oldKey, newKey, hasMoved := movedS3StateKey(remoteState.Config, &s3Config, existingBackend, workspace)
+	if !hasMoved {
+		return false, nil
+	}
+
+	s3Client, err := CreateS3Client(s3ConfigExtended.GetAwsSessionConfig(), terragruntOptions)
+	if err != nil {
+		return false, err
+	}
+
+	hasOldState, err := doesS3ObjectExist(s3Client, s3Config.Bucket, oldKey)
+	if err != nil || !hasOldState {
+		return false, err
+	}
+	hasNewState, err := doesS3ObjectExist(s3Client, s3Config.Bucket, newKey)
+	if err != nil {
+		return false, err
+	}
+	if hasNewState {
+		terragruntOptions.Logger.Debugf("The key of the remote state changed from %s to %s, but there is already state at %s, so not copying it", oldKey, newKey, newKey)
+		return false, nil
+	}
+
+	prompt := fmt.Sprintf("The key of the remote state in the S3 bucket %s has changed from %s to %s, and there is no state at %s. Would you like Terragrunt to copy the state from %s? The state at %s will be left in place as a backup.", s3Config.Bucket, oldKey, newKey, newKey, oldKey, oldKey)
+	shouldCopy, err := shell.PromptUserForYesNo(prompt, terragruntOptions)
+	if err != nil || !shouldCopy {
+		return false, err
+	}
+
+	terragruntOptions.Logger.Infof("Copying the remote state in the S3 bucket %s from %s to %s", s3Config.Bucket, oldKey, newKey)
+	_, err = s3Client.CopyObject(&s3.CopyObjectInput{
+		Bucket:     aws.String(s3Config.Bucket),
+		Key:        aws.String(newKey),
+		CopySource: aws.String((&url.URL{Path: s3Config.Bucket + "/" + oldKey}).EscapedPath()),
+	})
+	if err != nil {
+		return false, errors.WithStackTrace(err)
+	}
+
+	return true, nil
+}
+
+// Returns the keys of the state of the given workspace in the given existing backend and in the given config, which
+// are prefixed with the workspace_key_prefix of each for the workspaces other than the default one, and true if the
+// state is in the same bucket but under a different key
+func movedS3StateKey(rawConfig map[string]interface{}, config *RemoteStateConfigS3, existingBackend *TerraformBackend, workspace string) (string, string, bool) {
+	if existingBackend == nil || existingBackend.Type != "s3" {
+		return "", "", false
+	}
+
+	oldBucket, _ := existingBackend.Config["bucket"].(string)
+	oldKey, _ := existingBackend.Config["key"].(string)
+	if oldBucket != config.Bucket || oldKey == "" {
+		return "", "", false
+	}
+
+	oldKey = s3StateKeyForWorkspace(existingBackend.Config, oldKey, workspace)
+	newKey := s3StateKeyForWorkspace(rawConfig, config.Key, workspace)
+	if oldKey == newKey {
+		return "", "", false
+	}
+	return oldKey, newKey, true
+}
+
+// Returns true if the given object exists in the given S3 bucket
+func doesS3ObjectExist(s3Client *s3.S3, bucket string, key string) (bool, error) {
+	_, err := s3Client.HeadObject(&s3.HeadObjectInput{Bucket: aws.String(bucket), Key: aws.String(key)})
+	if err == nil {
+		return true, nil
+	}
+	if awsErr, isAwsErr := err.(awserr.Error); isAwsErr && awsErr.Code() == "NotFound" {
+		return false, nil
+	}
+	return false, errors.WithStackTrace(err)
+}
+
+// Return the ID of the item in the lock table where terraform stores the MD5 digest of the state in the given config
+func s3StateDigestLockID(config *RemoteStateConfigS3) string {
+	return fmt.Sprintf("%s/%s-md5", config.Bucket, config.Key)
 }
 
 // Create an authenticated client for DynamoDB
@@ -851,6 +1450,23 @@ func (configName MissingRequiredS3RemoteStateConfig) Error() string {
 	return fmt.Sprintf("Missing required S3 remote state configuration %s", string(configName))
 }
 
+type InvalidS3RemoteStateRegion struct {
+	Name   string
+	Region string
+}
+
+func (err InvalidS3RemoteStateRegion) Error() string {
+	return fmt.Sprintf("The %s %q of the S3 remote state configuration is not a valid AWS region, e.g. us-east-1. Set skip_region_validation = true to use a region that doesn't follow this format.", err.Name, err.Region)
+}
+
+type UseLockfileNotSupported struct {
+	TerraformVersion *version.Version
+}
+
+func (err UseLockfileNotSupported) Error() string {
+	return fmt.Sprintf("The S3 remote state configuration sets use_lockfile, which requires terraform %s or newer, but terraform %s is installed. Upgrade terraform, or lock the state with dynamodb_table instead.", useLockfileMinVersion, err.TerraformVersion)
+}
+
 type MultipleTagsDeclarations string
 
 func (target MultipleTagsDeclarations) Error() string {
@@ -868,3 +1484,39 @@ type MaxRetriesWaitingForS3ACLExceeded string
 func (err MaxRetriesWaitingForS3ACLExceeded) Error() string {
 	return fmt.Sprintf("Exceeded max retries waiting for bucket S3 bucket %s to have proper ACL for access logging", string(err))
 }
+
+type InvalidS3BucketSSEAlgorithm string
+
+func (algorithm InvalidS3BucketSSEAlgorithm) Error() string {
+	return fmt.Sprintf("Invalid S3 remote state configuration bucket_sse_algorithm %s: must be one of %s or %s", string(algorithm), s3.ServerSideEncryptionAwsKms, s3.ServerSideEncryptionAes256)
+}
+
+type S3BucketSSEKMSKeyWithoutKMSAlgorithm string
+
+func (algorithm S3BucketSSEKMSKeyWithoutKMSAlgorithm) Error() string {
+	return fmt.Sprintf("The S3 remote state configuration bucket_sse_kms_key_id can only be set when bucket_sse_algorithm is %s, but it is %s", s3.ServerSideEncryptionAwsKms, string(algorithm))
+}
+
+type InvalidLockTableBillingMode string
+
+func (billingMode InvalidLockTableBillingMode) Error() string {
+	return fmt.Sprintf("Invalid S3 remote state configuration dynamodb_table_billing_mode %s: must be one of %s or %s", string(billingMode), dynamodb.DYNAMODB_PAY_PER_REQUEST_BILLING_MODE, dynamodb.DYNAMODB_PROVISIONED_BILLING_MODE)
+}
+
+type LockTableCapacityWithoutProvisionedBillingMode string
+
+func (billingMode LockTableCapacityWithoutProvisionedBillingMode) Error() string {
+	return fmt.Sprintf("The S3 remote state configurations dynamodb_table_read_capacity and dynamodb_table_write_capacity can only be set when dynamodb_table_billing_mode is %s, but it is %s", dynamodb.DYNAMODB_PROVISIONED_BILLING_MODE, string(billingMode))
+}
+
+type InvalidS3AssumeRoleDuration string
+
+func (duration InvalidS3AssumeRoleDuration) Error() string {
+	return fmt.Sprintf("Invalid duration %s in the assume_role block of the S3 remote state configuration. Use a duration like 1h or 15m.", string(duration))
+}
+
+type InvalidS3BucketPolicy string
+
+func (reason InvalidS3BucketPolicy) Error() string {
+	return fmt.Sprintf("The S3 remote state bucket_policy is invalid: %s", string(reason))
+}
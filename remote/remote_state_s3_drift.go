@@ -0,0 +1,389 @@
+package remote
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/gruntwork-io/terragrunt/aws_helper"
+	"github.com/gruntwork-io/terragrunt/dynamodb"
+	"github.com/gruntwork-io/terragrunt/errors"
+	"github.com/gruntwork-io/terragrunt/options"
+)
+
+// Check the S3 bucket and DynamoDB lock table of the given remote state against the settings Terragrunt creates them
+// with: the versioning, default encryption, bucket policy, public access block and tags of the bucket, and the
+// encryption and point-in-time recovery of the lock table. The settings disabled with the skip_* configs aren't
+// checked.
+func (s3Initializer S3Initializer) CheckDrift(remoteState *RemoteState, terragruntOptions *options.TerragruntOptions) ([]RemoteStateDrift, error) {
+	s3ConfigExtended, err := parseExtendedS3Config(remoteState.Config)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := validateS3Config(s3ConfigExtended, terragruntOptions); err != nil {
+		return nil, err
+	}
+
+	s3Client, err := CreateS3Client(s3ConfigExtended.GetAwsSessionConfig(), terragruntOptions)
+	if err != nil {
+		return nil, err
+	}
+
+	drifts, err := checkS3BucketDrift(s3Client, s3ConfigExtended, terragruntOptions)
+	if err != nil {
+		return nil, err
+	}
+
+	lockTableDrifts, err := checkLockTableDrift(s3ConfigExtended, terragruntOptions)
+	if err != nil {
+		return nil, err
+	}
+
+	return append(drifts, lockTableDrifts...), nil
+}
+
+// Return the settings of the S3 bucket in the given config that differ from the config
+func checkS3BucketDrift(s3Client *s3.S3, config *ExtendedRemoteStateConfigS3, terragruntOptions *options.TerragruntOptions) ([]RemoteStateDrift, error) {
+	s3Config := &config.remoteStateConfigS3
+	resource := fmt.Sprintf("S3 bucket %s", s3Config.Bucket)
+
+	if !DoesS3BucketExist(s3Client, aws.String(s3Config.Bucket)) {
+		return []RemoteStateDrift{{Resource: resource, Setting: "bucket", Expected: "to exist", Actual: "missing"}}, nil
+	}
+
+	var drifts []RemoteStateDrift
+
+	if !config.SkipBucketVersioning {
+		out, err := s3Client.GetBucketVersioning(&s3.GetBucketVersioningInput{Bucket: aws.String(s3Config.Bucket)})
+		if err != nil {
+			return nil, errors.WithStackTrace(err)
+		}
+		if aws.StringValue(out.Status) != s3.BucketVersioningStatusEnabled {
+			drifts = append(drifts, RemoteStateDrift{
+				Resource: resource,
+				Setting:  "versioning",
+				Expected: s3.BucketVersioningStatusEnabled,
+				Actual:   valueOrNone(aws.StringValue(out.Status)),
+				repair:   func() error { return EnableVersioningForS3Bucket(s3Client, s3Config, terragruntOptions) },
+			})
+		}
+	}
+
+	if !config.SkipBucketSSEncryption {
+		out, err := s3Client.GetBucketEncryption(&s3.GetBucketEncryptionInput{Bucket: aws.String(s3Config.Bucket)})
+		var sseConfig *s3.ServerSideEncryptionConfiguration
+		if err == nil {
+			sseConfig = out.ServerSideEncryptionConfiguration
+		} else if awsErr, isAwsErr := err.(awserr.Error); !isAwsErr || awsErr.Code() != "ServerSideEncryptionConfigurationNotFoundError" {
+			return nil, errors.WithStackTrace(err)
+		}
+
+		if !s3BucketSSEMatchesConfig(sseConfig, config) {
+			drifts = append(drifts, RemoteStateDrift{
+				Resource: resource,
+				Setting:  "default encryption",
+				Expected: describeS3BucketSSERule(s3BucketSSERule(config)),
+				Actual:   describeS3BucketSSEConfig(sseConfig),
+				repair:   func() error { return EnableSSEForS3BucketWide(s3Client, config, terragruntOptions) },
+			})
+		}
+	}
+
+	policyDrift, err := checkS3BucketPolicyDrift(s3Client, config, resource, terragruntOptions)
+	if err != nil {
+		return nil, err
+	}
+	drifts = append(drifts, policyDrift...)
+
+	if !config.SkipBucketPublicAccessBlock {
+		out, err := s3Client.GetPublicAccessBlock(&s3.GetPublicAccessBlockInput{Bucket: aws.String(s3Config.Bucket)})
+		var publicAccessBlock *s3.PublicAccessBlockConfiguration
+		if err == nil {
+			publicAccessBlock = out.PublicAccessBlockConfiguration
+		} else if awsErr, isAwsErr := err.(awserr.Error); !isAwsErr || awsErr.Code() != "NoSuchPublicAccessBlockConfiguration" {
+			return nil, errors.WithStackTrace(err)
+		}
+
+		if !isS3PublicAccessBlocked(publicAccessBlock) {
+			drifts = append(drifts, RemoteStateDrift{
+				Resource: resource,
+				Setting:  "public access block",
+				Expected: "all public access blocked",
+				Actual:   "public access not fully blocked",
+				repair:   func() error { return EnablePublicAccessBlockingForS3Bucket(s3Client, s3Config, terragruntOptions) },
+			})
+		}
+	}
+
+	if len(config.S3BucketTags) > 0 {
+		out, err := s3Client.GetBucketTagging(&s3.GetBucketTaggingInput{Bucket: aws.String(s3Config.Bucket)})
+		existingTags := map[string]string{}
+		if err == nil {
+			for _, tag := range out.TagSet {
+				existingTags[aws.StringValue(tag.Key)] = aws.StringValue(tag.Value)
+			}
+		} else if awsErr, isAwsErr := err.(awserr.Error); !isAwsErr || awsErr.Code() != "NoSuchTagSet" {
+			return nil, errors.WithStackTrace(err)
+		}
+
+		if len(missingTags(existingTags, config.S3BucketTags)) > 0 {
+			drifts = append(drifts, RemoteStateDrift{
+				Resource: resource,
+				Setting:  "tags",
+				Expected: describeTags(config.S3BucketTags),
+				Actual:   valueOrNone(describeTags(existingTags)),
+				repair: func() error {
+					// Keep the tags that were added to the bucket outside of Terragrunt
+					mergedConfig := *config
+					mergedConfig.S3BucketTags = mergeTags(existingTags, config.S3BucketTags)
+					return TagS3Bucket(s3Client, &mergedConfig, terragruntOptions)
+				},
+			})
+		}
+	}
+
+	return drifts, nil
+}
+
+// Return the drift of the bucket policy of the S3 bucket in the given config, which has drifted if it's missing any of
+// the statements Terragrunt puts in it. The statements that were added to the policy outside of Terragrunt are kept
+// when repairing it.
+func checkS3BucketPolicyDrift(s3Client *s3.S3, config *ExtendedRemoteStateConfigS3, resource string, terragruntOptions *options.TerragruntOptions) ([]RemoteStateDrift, error) {
+	bucket := config.remoteStateConfigS3.Bucket
+
+	accountID := ""
+	if !config.SkipBucketRootAccess {
+		var err error
+		accountID, err = aws_helper.GetAWSAccountID(config.GetAwsSessionConfig(), terragruntOptions)
+		if err != nil {
+			return nil, errors.WithStackTrace(err)
+		}
+	}
+
+	expectedPolicy, err := s3BucketPolicy(config, accountID)
+	if err != nil || expectedPolicy == nil {
+		return nil, err
+	}
+
+	existingPolicy := map[string]interface{}{}
+	out, err := s3Client.GetBucketPolicy(&s3.GetBucketPolicyInput{Bucket: aws.String(bucket)})
+	if err == nil {
+		if err := json.Unmarshal([]byte(aws.StringValue(out.Policy)), &existingPolicy); err != nil {
+			return nil, errors.WithStackTrace(err)
+		}
+	} else if awsErr, isAwsErr := err.(awserr.Error); !isAwsErr || awsErr.Code() != "NoSuchBucketPolicy" {
+		return nil, errors.WithStackTrace(err)
+	}
+
+	mergedPolicy, missingSids := mergeS3BucketPolicy(existingPolicy, expectedPolicy)
+	if len(missingSids) == 0 {
+		return nil, nil
+	}
+
+	return []RemoteStateDrift{{
+		Resource: resource,
+		Setting:  "bucket policy",
+		Expected: fmt.Sprintf("statements %s", strings.Join(s3BucketPolicySids(expectedPolicy), ", ")),
+		Actual:   fmt.Sprintf("missing statements %s", strings.Join(missingSids, ", ")),
+		repair: func() error {
+			policy, err := json.Marshal(mergedPolicy)
+			if err != nil {
+				return errors.WithStackTrace(err)
+			}
+			terragruntOptions.Logger.Debugf("Putting the bucket policy of S3 bucket %s", bucket)
+			_, err = s3Client.PutBucketPolicy(&s3.PutBucketPolicyInput{Bucket: aws.String(bucket), Policy: aws.String(string(policy))})
+			return errors.WithStackTrace(err)
+		},
+	}}, nil
+}
+
+// Add the statements of the expected policy that are missing from the existing policy, matching them by Sid. Returns
+// the merged policy and the Sids of the missing statements.
+func mergeS3BucketPolicy(existingPolicy map[string]interface{}, expectedPolicy map[string]interface{}) (map[string]interface{}, []string) {
+	var existingStatements []interface{}
+	switch statement := existingPolicy["Statement"].(type) {
+	case []interface{}:
+		existingStatements = statement
+	case map[string]interface{}:
+		existingStatements = []interface{}{statement}
+	}
+
+	existingSids := map[string]bool{}
+	for _, statement := range existingStatements {
+		if statementMap, isMap := statement.(map[string]interface{}); isMap {
+			if sid, isString := statementMap["Sid"].(string); isString {
+				existingSids[sid] = true
+			}
+		}
+	}
+
+	mergedStatements := append([]interface{}{}, existingStatements...)
+	var missingSids []string
+	for _, statement := range expectedPolicy["Statement"].([]map[string]interface{}) {
+		// Statements without a Sid, such as the ones of bucket_policy, can't be matched, so they aren't checked
+		sid, _ := statement["Sid"].(string)
+		if sid == "" || existingSids[sid] {
+			continue
+		}
+		missingSids = append(missingSids, sid)
+		mergedStatements = append(mergedStatements, statement)
+	}
+
+	return map[string]interface{}{
+		"Version":   "2012-10-17",
+		"Statement": mergedStatements,
+	}, missingSids
+}
+
+// Return the Sids of the statements of the given policy created by s3BucketPolicy that have one
+func s3BucketPolicySids(policy map[string]interface{}) []string {
+	var sids []string
+	for _, statement := range policy["Statement"].([]map[string]interface{}) {
+		if sid, _ := statement["Sid"].(string); sid != "" {
+			sids = append(sids, sid)
+		}
+	}
+	return sids
+}
+
+// Returns true if all the settings of the given public access block are enabled
+func isS3PublicAccessBlocked(config *s3.PublicAccessBlockConfiguration) bool {
+	return config != nil &&
+		aws.BoolValue(config.BlockPublicAcls) &&
+		aws.BoolValue(config.BlockPublicPolicy) &&
+		aws.BoolValue(config.IgnorePublicAcls) &&
+		aws.BoolValue(config.RestrictPublicBuckets)
+}
+
+// Return a description of the given server-side encryption rule
+func describeS3BucketSSERule(rule *s3.ServerSideEncryptionRule) string {
+	if rule == nil || rule.ApplyServerSideEncryptionByDefault == nil {
+		return "none"
+	}
+
+	defEnc := rule.ApplyServerSideEncryptionByDefault
+	description := fmt.Sprintf("algorithm %s", aws.StringValue(defEnc.SSEAlgorithm))
+	if keyID := aws.StringValue(defEnc.KMSMasterKeyID); keyID != "" {
+		description += fmt.Sprintf(" with KMS key %s", keyID)
+	}
+	return fmt.Sprintf("%s and bucket key enabled %t", description, aws.BoolValue(rule.BucketKeyEnabled))
+}
+
+// Return a description of the given server-side encryption configuration
+func describeS3BucketSSEConfig(config *s3.ServerSideEncryptionConfiguration) string {
+	if config == nil || len(config.Rules) == 0 {
+		return "none"
+	}
+	return describeS3BucketSSERule(config.Rules[0])
+}
+
+// Return the settings of the DynamoDB lock table in the given config that differ from the config
+func checkLockTableDrift(config *ExtendedRemoteStateConfigS3, terragruntOptions *options.TerragruntOptions) ([]RemoteStateDrift, error) {
+	s3Config := &config.remoteStateConfigS3
+	if !s3Config.UsesLockTable() {
+		return nil, nil
+	}
+
+	dynamodbClient, err := dynamodb.CreateDynamoDbClient(config.GetAwsSessionConfig(), terragruntOptions)
+	if err != nil {
+		return nil, err
+	}
+
+	tableName := s3Config.GetLockTableName()
+	resource := fmt.Sprintf("DynamoDB table %s", tableName)
+
+	exists, err := dynamodb.LockTableExistsAndIsActive(tableName, dynamodbClient)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return []RemoteStateDrift{{Resource: resource, Setting: "table", Expected: "to exist", Actual: "missing"}}, nil
+	}
+
+	var drifts []RemoteStateDrift
+
+	if config.EnableLockTableSSEncryption || config.LockTableKMSKeyID != "" {
+		isEncrypted, err := dynamodb.LockTableCheckSSEncryptionIsOn(tableName, dynamodbClient)
+		if err != nil {
+			return nil, err
+		}
+		if !isEncrypted {
+			drifts = append(drifts, RemoteStateDrift{
+				Resource: resource,
+				Setting:  "server-side encryption",
+				Expected: "enabled",
+				Actual:   "disabled",
+				repair: func() error {
+					return dynamodb.UpdateLockTableSetSSEncryptionOnIfNecessary(tableName, config.LockTableKMSKeyID, dynamodbClient, terragruntOptions)
+				},
+			})
+		}
+	}
+
+	if config.LockTablePointInTimeRecovery {
+		isEnabled, err := dynamodb.LockTableCheckPointInTimeRecoveryIsOn(tableName, dynamodbClient)
+		if err != nil {
+			return nil, err
+		}
+		if !isEnabled {
+			drifts = append(drifts, RemoteStateDrift{
+				Resource: resource,
+				Setting:  "point-in-time recovery",
+				Expected: "enabled",
+				Actual:   "disabled",
+				repair: func() error {
+					return dynamodb.EnablePointInTimeRecoveryIfNecessary(tableName, dynamodbClient, terragruntOptions)
+				},
+			})
+		}
+	}
+
+	return drifts, nil
+}
+
+// Return the keys of the expected tags that are missing from the existing tags or have a different value
+func missingTags(existingTags map[string]string, expectedTags map[string]string) []string {
+	var missing []string
+	for key, value := range expectedTags {
+		if existingValue, hasTag := existingTags[key]; !hasTag || existingValue != value {
+			missing = append(missing, key)
+		}
+	}
+	sort.Strings(missing)
+	return missing
+}
+
+// Return the existing tags, updated with the expected tags
+func mergeTags(existingTags map[string]string, expectedTags map[string]string) map[string]string {
+	merged := map[string]string{}
+	for key, value := range existingTags {
+		merged[key] = value
+	}
+	for key, value := range expectedTags {
+		merged[key] = value
+	}
+	return merged
+}
+
+// Return the given tags as a sorted list of key=value pairs
+func describeTags(tags map[string]string) string {
+	var pairs []string
+	for key, value := range tags {
+		pairs = append(pairs, fmt.Sprintf("%s=%s", key, value))
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, ", ")
+}
+
+// Return the given value, or none if it's empty
+func valueOrNone(value string) string {
+	if value == "" {
+		return "none"
+	}
+	return value
+}
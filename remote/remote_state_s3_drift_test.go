@@ -0,0 +1,109 @@
+package remote
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/gruntwork-io/terragrunt/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMergeS3BucketPolicy(t *testing.T) {
+	t.Parallel()
+
+	existingPolicy := map[string]interface{}{
+		"Version": "2012-10-17",
+		"Statement": []interface{}{
+			map[string]interface{}{"Sid": "AllowReadFromCI", "Effect": "Allow"},
+			map[string]interface{}{"Sid": "RootAccess", "Effect": "Allow"},
+		},
+	}
+	expectedPolicy := map[string]interface{}{
+		"Version": "2012-10-17",
+		"Statement": []map[string]interface{}{
+			{"Sid": "RootAccess", "Effect": "Allow"},
+			{"Sid": "AllowTLSRequestsOnly", "Effect": "Deny"},
+			{"Effect": "Allow"},
+		},
+	}
+
+	merged, missingSids := mergeS3BucketPolicy(existingPolicy, expectedPolicy)
+	assert.Equal(t, []string{"AllowTLSRequestsOnly"}, missingSids)
+	assert.Equal(t, []interface{}{
+		map[string]interface{}{"Sid": "AllowReadFromCI", "Effect": "Allow"},
+		map[string]interface{}{"Sid": "RootAccess", "Effect": "Allow"},
+		map[string]interface{}{"Sid": "AllowTLSRequestsOnly", "Effect": "Deny"},
+	}, merged["Statement"])
+
+	_, missingSids = mergeS3BucketPolicy(map[string]interface{}{}, expectedPolicy)
+	assert.Equal(t, []string{"RootAccess", "AllowTLSRequestsOnly"}, missingSids)
+}
+
+func TestMissingTags(t *testing.T) {
+	t.Parallel()
+
+	existingTags := map[string]string{"team": "platform", "env": "dev", "owner": "ops"}
+	expectedTags := map[string]string{"team": "platform", "env": "prod", "cost-center": "123"}
+
+	assert.Equal(t, []string{"cost-center", "env"}, missingTags(existingTags, expectedTags))
+	assert.Empty(t, missingTags(existingTags, map[string]string{"team": "platform"}))
+	assert.Equal(t, map[string]string{"team": "platform", "env": "prod", "owner": "ops", "cost-center": "123"}, mergeTags(existingTags, expectedTags))
+	assert.Equal(t, "env=dev, owner=ops, team=platform", describeTags(existingTags))
+}
+
+func TestIsS3PublicAccessBlocked(t *testing.T) {
+	t.Parallel()
+
+	assert.False(t, isS3PublicAccessBlocked(nil))
+	assert.True(t, isS3PublicAccessBlocked(&s3.PublicAccessBlockConfiguration{
+		BlockPublicAcls:       aws.Bool(true),
+		BlockPublicPolicy:     aws.Bool(true),
+		IgnorePublicAcls:      aws.Bool(true),
+		RestrictPublicBuckets: aws.Bool(true),
+	}))
+	assert.False(t, isS3PublicAccessBlocked(&s3.PublicAccessBlockConfiguration{
+		BlockPublicAcls:       aws.Bool(true),
+		BlockPublicPolicy:     aws.Bool(false),
+		IgnorePublicAcls:      aws.Bool(true),
+		RestrictPublicBuckets: aws.Bool(true),
+	}))
+}
+
+func TestDescribeS3BucketSSEConfig(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, "none", describeS3BucketSSEConfig(nil))
+	assert.Equal(t, "algorithm AES256 and bucket key enabled false", describeS3BucketSSEConfig(&s3.ServerSideEncryptionConfiguration{
+		Rules: []*s3.ServerSideEncryptionRule{{
+			ApplyServerSideEncryptionByDefault: &s3.ServerSideEncryptionByDefault{SSEAlgorithm: aws.String(s3.ServerSideEncryptionAes256)},
+		}},
+	}))
+	assert.Equal(t, "algorithm aws:kms with KMS key my-key and bucket key enabled true", describeS3BucketSSEConfig(&s3.ServerSideEncryptionConfiguration{
+		Rules: []*s3.ServerSideEncryptionRule{{
+			ApplyServerSideEncryptionByDefault: &s3.ServerSideEncryptionByDefault{SSEAlgorithm: aws.String(s3.ServerSideEncryptionAwsKms), KMSMasterKeyID: aws.String("my-key")},
+			BucketKeyEnabled:                   aws.Bool(true),
+		}},
+	}))
+}
+
+func TestRemoteStateDriftRepair(t *testing.T) {
+	t.Parallel()
+
+	repaired := false
+	drift := RemoteStateDrift{Resource: "S3 bucket my-bucket", Setting: "versioning", Expected: "Enabled", Actual: "Suspended", repair: func() error {
+		repaired = true
+		return nil
+	}}
+	assert.Equal(t, "S3 bucket my-bucket: versioning is Suspended, expected Enabled", drift.String())
+	require.True(t, drift.CanRepair())
+	require.NoError(t, drift.Repair())
+	assert.True(t, repaired)
+
+	missingBucket := RemoteStateDrift{Resource: "S3 bucket my-bucket", Setting: "existence", Expected: "exists", Actual: "missing"}
+	assert.False(t, missingBucket.CanRepair())
+	err := missingBucket.Repair()
+	require.Error(t, err)
+	assert.Equal(t, DriftNotRepairable(missingBucket.String()), errors.Unwrap(err))
+}
@@ -0,0 +1,348 @@
+package remote
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/arn"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/iam"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/gruntwork-io/terragrunt/aws_helper"
+	"github.com/gruntwork-io/terragrunt/errors"
+	"github.com/gruntwork-io/terragrunt/options"
+)
+
+// A permission that terraform, or terragrunt while initializing the backend, needs on a resource of an s3 backend
+type s3BackendPermission struct {
+	Action   string
+	Resource string
+	Purpose  string
+}
+
+func (permission s3BackendPermission) String() string {
+	return fmt.Sprintf("%s on %s, to %s", permission.Action, permission.Resource, permission.Purpose)
+}
+
+// Check, before terraform runs, that the credentials the given remote state is accessed with can assume the role of
+// the backend, if any, and have the permissions terraform needs on the bucket, the state object and the lock table, as
+// well as the ones terragrunt needs to create the bucket and lock table when they don't exist yet. The permissions are
+// checked by simulating the IAM policies of the identity. When they can't be simulated, e.g. without the
+// iam:SimulatePrincipalPolicy permission, the read access is checked with read-only calls instead. Either way, the
+// calls terragrunt makes to find out whether the bucket and lock table exist are checked too, as they're subject to
+// the bucket policy and to service control policies, which the simulation doesn't cover.
+func (s3Initializer S3Initializer) CheckPermissions(remoteState *RemoteState, terragruntOptions *options.TerragruntOptions) error {
+	s3ConfigExtended, err := parseExtendedS3Config(remoteState.Config)
+	if err != nil {
+		return err
+	}
+	if err := validateS3Config(s3ConfigExtended, terragruntOptions); err != nil {
+		return err
+	}
+	s3Config := &s3ConfigExtended.remoteStateConfigS3
+	sessionConfig := s3ConfigExtended.GetAwsSessionConfig()
+
+	sess, err := aws_helper.CreateAwsSession(sessionConfig, terragruntOptions)
+	if err != nil {
+		if sessionConfig.RoleArn != "" {
+			return errors.WithStackTrace(S3BackendRoleNotAssumable{RoleArn: sessionConfig.RoleArn, Underlying: errors.Unwrap(err)})
+		}
+		return err
+	}
+	identity, err := aws_helper.GetAWSCallerIdentity(sessionConfig, terragruntOptions)
+	if err != nil {
+		return err
+	}
+	identityArn, err := arn.Parse(aws.StringValue(identity.Arn))
+	if err != nil {
+		return errors.WithStackTrace(err)
+	}
+
+	permissions := newS3BackendPermissions(s3Config, identityArn.Partition, aws.StringValue(identity.Account))
+	s3Client := s3.New(sess)
+	dynamodbClient := dynamodb.New(sess)
+
+	var denied []s3BackendPermission
+	bucketExists, err := s3BucketExistsForPreflight(s3Client, s3Config.Bucket)
+	if err != nil {
+		if !isAccessDeniedError(err) {
+			return errors.WithStackTrace(err)
+		}
+		denied = append(denied, permissions.listBucket)
+	}
+	lockTableExists := true
+	if s3Config.UsesLockTable() {
+		if lockTableExists, err = lockTableExistsForPreflight(dynamodbClient, s3Config.GetLockTableName()); err != nil {
+			if !isAccessDeniedError(err) {
+				return errors.WithStackTrace(err)
+			}
+			lockTableExists = true
+			denied = append(denied, permissions.describeLockTable)
+		}
+	}
+
+	// Terragrunt creates the bucket and lock table when they don't exist, unless the initialization is disabled
+	required := permissions.requiredFor(s3Config, !bucketExists && !remoteState.DisableInit, !lockTableExists && !remoteState.DisableInit)
+
+	var otherDenied []s3BackendPermission
+	simulated := false
+	if principalArn, canSimulate := simulationPrincipalArn(identityArn); !canSimulate {
+		terragruntOptions.Logger.Debugf("Checking the access of %s to the s3 backend with read-only calls, as its IAM policies can't be simulated", identityArn)
+	} else if otherDenied, err = simulateS3BackendPermissions(iam.New(sess), principalArn, required); err != nil {
+		terragruntOptions.Logger.Debugf("Could not simulate the IAM policies of %s, checking the access to the s3 backend with read-only calls instead: %v", identityArn, err)
+	} else {
+		simulated = true
+	}
+	if !simulated {
+		otherDenied, err = dryRunS3BackendPermissions(s3Client, dynamodbClient, s3Config, permissions, bucketExists && len(denied) == 0, lockTableExists)
+		if err != nil {
+			return err
+		}
+	}
+
+	denied = mergeS3BackendPermissions(denied, otherDenied)
+	if len(denied) > 0 {
+		return errors.WithStackTrace(MissingS3BackendPermissions{Identity: identityArn.String(), Denied: denied})
+	}
+	terragruntOptions.Logger.Debugf("Preflight check passed: %s has the permissions to use the s3 backend in bucket %s", identityArn, s3Config.Bucket)
+	return nil
+}
+
+// The permissions on the resources of an s3 backend
+type s3BackendPermissions struct {
+	listBucket        s3BackendPermission
+	readState         s3BackendPermission
+	writeState        s3BackendPermission
+	lockfile          []s3BackendPermission
+	createBucket      s3BackendPermission
+	describeLockTable s3BackendPermission
+	lockTable         []s3BackendPermission
+	createLockTable   s3BackendPermission
+}
+
+// Return the permissions on the resources of the given s3 backend config, whose ARNs are in the given partition and,
+// for the lock table, account
+func newS3BackendPermissions(s3Config *RemoteStateConfigS3, partition string, account string) s3BackendPermissions {
+	bucketArn := fmt.Sprintf("arn:%s:s3:::%s", partition, s3Config.Bucket)
+	stateArn := fmt.Sprintf("%s/%s", bucketArn, s3Config.Key)
+	lockfileArn := stateArn + ".tflock"
+	lockTableArn := fmt.Sprintf("arn:%s:dynamodb:%s:%s:table/%s", partition, s3Config.Region, account, s3Config.GetLockTableName())
+
+	return s3BackendPermissions{
+		listBucket: s3BackendPermission{Action: "s3:ListBucket", Resource: bucketArn, Purpose: "list the states in the bucket"},
+		readState:  s3BackendPermission{Action: "s3:GetObject", Resource: stateArn, Purpose: "read the state"},
+		writeState: s3BackendPermission{Action: "s3:PutObject", Resource: stateArn, Purpose: "write the state"},
+		lockfile: []s3BackendPermission{
+			{Action: "s3:GetObject", Resource: lockfileArn, Purpose: "lock the state"},
+			{Action: "s3:PutObject", Resource: lockfileArn, Purpose: "lock the state"},
+			{Action: "s3:DeleteObject", Resource: lockfileArn, Purpose: "unlock the state"},
+		},
+		createBucket:      s3BackendPermission{Action: "s3:CreateBucket", Resource: bucketArn, Purpose: "create the bucket, which doesn't exist yet"},
+		describeLockTable: s3BackendPermission{Action: "dynamodb:DescribeTable", Resource: lockTableArn, Purpose: "check the lock table"},
+		lockTable: []s3BackendPermission{
+			{Action: "dynamodb:GetItem", Resource: lockTableArn, Purpose: "lock the state"},
+			{Action: "dynamodb:PutItem", Resource: lockTableArn, Purpose: "lock the state"},
+			{Action: "dynamodb:DeleteItem", Resource: lockTableArn, Purpose: "unlock the state"},
+		},
+		createLockTable: s3BackendPermission{Action: "dynamodb:CreateTable", Resource: lockTableArn, Purpose: "create the lock table, which doesn't exist yet"},
+	}
+}
+
+// Return the permissions the given s3 backend config requires, including the ones to create the bucket and lock table
+// if they're to be created
+func (permissions s3BackendPermissions) requiredFor(s3Config *RemoteStateConfigS3, createBucket bool, createLockTable bool) []s3BackendPermission {
+	required := []s3BackendPermission{permissions.listBucket, permissions.readState, permissions.writeState}
+	if createBucket {
+		required = append(required, permissions.createBucket)
+	}
+	if s3Config.UseLockfile {
+		required = append(required, permissions.lockfile...)
+	}
+	if s3Config.UsesLockTable() {
+		required = append(required, permissions.describeLockTable)
+		required = append(required, permissions.lockTable...)
+		if createLockTable {
+			required = append(required, permissions.createLockTable)
+		}
+	}
+	return required
+}
+
+// Return the ARN of the IAM principal whose policies are simulated for the given caller identity: the user itself, or
+// the role of an assumed role session. The ARN of the role is built from the session, which doesn't include the path
+// of the role, so the simulation of a role with a path fails and falls back to the read-only calls. Other identities,
+// such as the root user and federated users, can't be simulated.
+func simulationPrincipalArn(identityArn arn.ARN) (string, bool) {
+	switch {
+	case identityArn.Service == "iam" && strings.HasPrefix(identityArn.Resource, "user/"):
+		return identityArn.String(), true
+	case identityArn.Service == "sts" && strings.HasPrefix(identityArn.Resource, "assumed-role/"):
+		parts := strings.Split(identityArn.Resource, "/")
+		if len(parts) < 3 {
+			return "", false
+		}
+		roleArn := identityArn
+		roleArn.Service = "iam"
+		roleArn.Region = ""
+		roleArn.Resource = "role/" + parts[1]
+		return roleArn.String(), true
+	}
+	return "", false
+}
+
+// Simulate the IAM policies of the given principal, and return the given permissions they don't grant. The actions on
+// each resource are simulated in one call.
+func simulateS3BackendPermissions(client *iam.IAM, principalArn string, permissions []s3BackendPermission) ([]s3BackendPermission, error) {
+	var resources []string
+	actionsOfResource := map[string][]string{}
+	for _, permission := range permissions {
+		if _, isKnown := actionsOfResource[permission.Resource]; !isKnown {
+			resources = append(resources, permission.Resource)
+		}
+		actionsOfResource[permission.Resource] = append(actionsOfResource[permission.Resource], permission.Action)
+	}
+
+	var results []*iam.EvaluationResult
+	for _, resource := range resources {
+		output, err := client.SimulatePrincipalPolicy(&iam.SimulatePrincipalPolicyInput{
+			PolicySourceArn: aws.String(principalArn),
+			ActionNames:     aws.StringSlice(actionsOfResource[resource]),
+			ResourceArns:    aws.StringSlice([]string{resource}),
+		})
+		if err != nil {
+			return nil, errors.WithStackTrace(err)
+		}
+		results = append(results, output.EvaluationResults...)
+	}
+	return deniedS3BackendPermissions(permissions, results), nil
+}
+
+// Return the given permissions that the given results of a simulation don't allow
+func deniedS3BackendPermissions(permissions []s3BackendPermission, results []*iam.EvaluationResult) []s3BackendPermission {
+	denied := []s3BackendPermission{}
+	for _, permission := range permissions {
+		allowed := false
+		for _, result := range results {
+			if aws.StringValue(result.EvalActionName) != permission.Action {
+				continue
+			}
+			if resource := aws.StringValue(result.EvalResourceName); resource != "" && resource != permission.Resource {
+				continue
+			}
+			allowed = aws.StringValue(result.EvalDecision) == iam.PolicyEvaluationDecisionTypeAllowed
+			break
+		}
+		if !allowed {
+			denied = append(denied, permission)
+		}
+	}
+	return denied
+}
+
+// Check the read access to the state object and lock table of the given config with read-only calls, and return the
+// given permissions that are denied. The write access can't be checked this way. The state object is only checked if
+// the bucket exists and can be listed, as otherwise the call fails regardless of the permission to read the object.
+func dryRunS3BackendPermissions(s3Client *s3.S3, dynamodbClient *dynamodb.DynamoDB, s3Config *RemoteStateConfigS3, permissions s3BackendPermissions, checkState bool, lockTableExists bool) ([]s3BackendPermission, error) {
+	denied := []s3BackendPermission{}
+
+	if checkState {
+		_, err := s3Client.HeadObject(&s3.HeadObjectInput{Bucket: aws.String(s3Config.Bucket), Key: aws.String(s3Config.Key)})
+		if isAccessDeniedError(err) {
+			denied = append(denied, permissions.readState)
+		} else if err != nil && !isNotFoundError(err) {
+			return nil, errors.WithStackTrace(err)
+		}
+	}
+
+	if s3Config.UsesLockTable() && lockTableExists {
+		_, err := dynamodbClient.GetItem(&dynamodb.GetItemInput{
+			TableName: aws.String(s3Config.GetLockTableName()),
+			Key: map[string]*dynamodb.AttributeValue{
+				"LockID": {S: aws.String(fmt.Sprintf("%s/%s", s3Config.Bucket, s3Config.Key))},
+			},
+		})
+		if isAccessDeniedError(err) {
+			denied = append(denied, permissions.lockTable[0])
+		} else if err != nil {
+			return nil, errors.WithStackTrace(err)
+		}
+	}
+
+	return denied, nil
+}
+
+// Returns true if the given S3 bucket exists, and an access denied error if the caller can't list it
+func s3BucketExistsForPreflight(client *s3.S3, bucket string) (bool, error) {
+	_, err := client.HeadBucket(&s3.HeadBucketInput{Bucket: aws.String(bucket)})
+	if isNotFoundError(err) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// Returns true if the given DynamoDB table exists, and an access denied error if the caller can't describe it
+func lockTableExistsForPreflight(client *dynamodb.DynamoDB, tableName string) (bool, error) {
+	_, err := client.DescribeTable(&dynamodb.DescribeTableInput{TableName: aws.String(tableName)})
+	if awsErr, isAwsErr := err.(awserr.Error); isAwsErr && awsErr.Code() == dynamodb.ErrCodeResourceNotFoundException {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// Return the given permissions, without duplicates
+func mergeS3BackendPermissions(permissions ...[]s3BackendPermission) []s3BackendPermission {
+	merged := []s3BackendPermission{}
+	seen := map[s3BackendPermission]bool{}
+	for _, list := range permissions {
+		for _, permission := range list {
+			if !seen[permission] {
+				seen[permission] = true
+				merged = append(merged, permission)
+			}
+		}
+	}
+	return merged
+}
+
+// Returns true if the given error of an AWS API call means the caller lacks the permission to make it
+func isAccessDeniedError(err error) bool {
+	if requestFailure, isRequestFailure := err.(awserr.RequestFailure); isRequestFailure && requestFailure.StatusCode() == 403 {
+		return true
+	}
+	if awsErr, isAwsErr := err.(awserr.Error); isAwsErr {
+		return awsErr.Code() == "AccessDenied" || awsErr.Code() == "AccessDeniedException"
+	}
+	return false
+}
+
+// Returns true if the given error of an AWS API call means the resource doesn't exist
+func isNotFoundError(err error) bool {
+	requestFailure, isRequestFailure := err.(awserr.RequestFailure)
+	return isRequestFailure && requestFailure.StatusCode() == 404
+}
+
+// Custom error types
+
+type MissingS3BackendPermissions struct {
+	Identity string
+	Denied   []s3BackendPermission
+}
+
+func (err MissingS3BackendPermissions) Error() string {
+	lines := []string{}
+	for _, permission := range err.Denied {
+		lines = append(lines, "  - "+permission.String())
+	}
+	return fmt.Sprintf("Preflight check failed: %s is missing permissions to use the s3 backend:\n%s", err.Identity, strings.Join(lines, "\n"))
+}
+
+type S3BackendRoleNotAssumable struct {
+	RoleArn    string
+	Underlying error
+}
+
+func (err S3BackendRoleNotAssumable) Error() string {
+	return fmt.Sprintf("Preflight check failed: could not assume the IAM role %s of the s3 backend: %v", err.RoleArn, err.Underlying)
+}
@@ -0,0 +1,165 @@
+package remote
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/arn"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/iam"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/gruntwork-io/terragrunt/options"
+)
+
+func TestSimulationPrincipalArn(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		identityArn    string
+		expectedArn    string
+		expectedCanSim bool
+	}{
+		{"arn:aws:iam::123456789012:user/deploy", "arn:aws:iam::123456789012:user/deploy", true},
+		{"arn:aws:sts::123456789012:assumed-role/terraform/session", "arn:aws:iam::123456789012:role/terraform", true},
+		{"arn:aws-us-gov:sts::123456789012:assumed-role/terraform/session", "arn:aws-us-gov:iam::123456789012:role/terraform", true},
+		{"arn:aws:iam::123456789012:root", "", false},
+		{"arn:aws:sts::123456789012:federated-user/deploy", "", false},
+	}
+
+	for _, testCase := range testCases {
+		testCase := testCase
+		t.Run(testCase.identityArn, func(t *testing.T) {
+			t.Parallel()
+
+			identityArn, err := arn.Parse(testCase.identityArn)
+			require.NoError(t, err)
+			actualArn, canSimulate := simulationPrincipalArn(identityArn)
+			assert.Equal(t, testCase.expectedCanSim, canSimulate)
+			assert.Equal(t, testCase.expectedArn, actualArn)
+		})
+	}
+}
+
+func TestS3BackendPermissionsRequiredFor(t *testing.T) {
+	t.Parallel()
+
+	s3Config := &RemoteStateConfigS3{Bucket: "states", Key: "vpc/terraform.tfstate", Region: "eu-west-1", DynamoDBTable: "locks"}
+	permissions := newS3BackendPermissions(s3Config, "aws", "123456789012")
+
+	actions := func(required []s3BackendPermission) []string {
+		result := []string{}
+		for _, permission := range required {
+			result = append(result, permission.Action+" "+permission.Resource)
+		}
+		return result
+	}
+
+	assert.Equal(t, []string{
+		"s3:ListBucket arn:aws:s3:::states",
+		"s3:GetObject arn:aws:s3:::states/vpc/terraform.tfstate",
+		"s3:PutObject arn:aws:s3:::states/vpc/terraform.tfstate",
+		"dynamodb:DescribeTable arn:aws:dynamodb:eu-west-1:123456789012:table/locks",
+		"dynamodb:GetItem arn:aws:dynamodb:eu-west-1:123456789012:table/locks",
+		"dynamodb:PutItem arn:aws:dynamodb:eu-west-1:123456789012:table/locks",
+		"dynamodb:DeleteItem arn:aws:dynamodb:eu-west-1:123456789012:table/locks",
+	}, actions(permissions.requiredFor(s3Config, false, false)))
+
+	assert.Equal(t, []string{
+		"s3:ListBucket arn:aws:s3:::states",
+		"s3:GetObject arn:aws:s3:::states/vpc/terraform.tfstate",
+		"s3:PutObject arn:aws:s3:::states/vpc/terraform.tfstate",
+		"s3:CreateBucket arn:aws:s3:::states",
+		"dynamodb:DescribeTable arn:aws:dynamodb:eu-west-1:123456789012:table/locks",
+		"dynamodb:GetItem arn:aws:dynamodb:eu-west-1:123456789012:table/locks",
+		"dynamodb:PutItem arn:aws:dynamodb:eu-west-1:123456789012:table/locks",
+		"dynamodb:DeleteItem arn:aws:dynamodb:eu-west-1:123456789012:table/locks",
+		"dynamodb:CreateTable arn:aws:dynamodb:eu-west-1:123456789012:table/locks",
+	}, actions(permissions.requiredFor(s3Config, true, true)))
+
+	// While migrating to lock files, terraform locks the state with both
+	s3Config.UseLockfile = true
+	assert.Equal(t, []string{
+		"s3:ListBucket arn:aws:s3:::states",
+		"s3:GetObject arn:aws:s3:::states/vpc/terraform.tfstate",
+		"s3:PutObject arn:aws:s3:::states/vpc/terraform.tfstate",
+		"s3:GetObject arn:aws:s3:::states/vpc/terraform.tfstate.tflock",
+		"s3:PutObject arn:aws:s3:::states/vpc/terraform.tfstate.tflock",
+		"s3:DeleteObject arn:aws:s3:::states/vpc/terraform.tfstate.tflock",
+		"dynamodb:DescribeTable arn:aws:dynamodb:eu-west-1:123456789012:table/locks",
+		"dynamodb:GetItem arn:aws:dynamodb:eu-west-1:123456789012:table/locks",
+		"dynamodb:PutItem arn:aws:dynamodb:eu-west-1:123456789012:table/locks",
+		"dynamodb:DeleteItem arn:aws:dynamodb:eu-west-1:123456789012:table/locks",
+	}, actions(permissions.requiredFor(s3Config, false, false)))
+
+	// The lock file replaces the lock table
+	s3Config.DynamoDBTable = ""
+	assert.Equal(t, []string{
+		"s3:ListBucket arn:aws:s3:::states",
+		"s3:GetObject arn:aws:s3:::states/vpc/terraform.tfstate",
+		"s3:PutObject arn:aws:s3:::states/vpc/terraform.tfstate",
+		"s3:GetObject arn:aws:s3:::states/vpc/terraform.tfstate.tflock",
+		"s3:PutObject arn:aws:s3:::states/vpc/terraform.tfstate.tflock",
+		"s3:DeleteObject arn:aws:s3:::states/vpc/terraform.tfstate.tflock",
+	}, actions(permissions.requiredFor(s3Config, false, true)))
+}
+
+func TestDeniedS3BackendPermissions(t *testing.T) {
+	t.Parallel()
+
+	permissions := []s3BackendPermission{
+		{Action: "s3:ListBucket", Resource: "arn:aws:s3:::states"},
+		{Action: "s3:GetObject", Resource: "arn:aws:s3:::states/terraform.tfstate"},
+		{Action: "s3:PutObject", Resource: "arn:aws:s3:::states/terraform.tfstate"},
+	}
+	results := []*iam.EvaluationResult{
+		{EvalActionName: aws.String("s3:ListBucket"), EvalResourceName: aws.String("arn:aws:s3:::states"), EvalDecision: aws.String("allowed")},
+		{EvalActionName: aws.String("s3:GetObject"), EvalResourceName: aws.String("arn:aws:s3:::states/terraform.tfstate"), EvalDecision: aws.String("implicitDeny")},
+	}
+
+	assert.Equal(t, permissions[1:], deniedS3BackendPermissions(permissions, results))
+}
+
+func TestMergeS3BackendPermissions(t *testing.T) {
+	t.Parallel()
+
+	listBucket := s3BackendPermission{Action: "s3:ListBucket", Resource: "arn:aws:s3:::states"}
+	getObject := s3BackendPermission{Action: "s3:GetObject", Resource: "arn:aws:s3:::states/terraform.tfstate"}
+	assert.Equal(t, []s3BackendPermission{listBucket, getObject}, mergeS3BackendPermissions([]s3BackendPermission{listBucket}, []s3BackendPermission{listBucket, getObject}))
+}
+
+func TestIsAccessDeniedError(t *testing.T) {
+	t.Parallel()
+
+	assert.True(t, isAccessDeniedError(awserr.NewRequestFailure(awserr.New("Forbidden", "Forbidden", nil), 403, "")))
+	assert.True(t, isAccessDeniedError(awserr.New("AccessDeniedException", "not authorized", nil)))
+	assert.False(t, isAccessDeniedError(awserr.NewRequestFailure(awserr.New("NotFound", "Not Found", nil), 404, "")))
+	assert.False(t, isAccessDeniedError(nil))
+	assert.True(t, isNotFoundError(awserr.NewRequestFailure(awserr.New("NotFound", "Not Found", nil), 404, "")))
+}
+
+func TestMissingS3BackendPermissionsError(t *testing.T) {
+	t.Parallel()
+
+	err := MissingS3BackendPermissions{
+		Identity: "arn:aws:sts::123456789012:assumed-role/terraform/session",
+		Denied: []s3BackendPermission{
+			{Action: "s3:PutObject", Resource: "arn:aws:s3:::states/terraform.tfstate", Purpose: "write the state"},
+			{Action: "dynamodb:PutItem", Resource: "arn:aws:dynamodb:eu-west-1:123456789012:table/locks", Purpose: "lock the state"},
+		},
+	}
+	assert.Equal(t, `Preflight check failed: arn:aws:sts::123456789012:assumed-role/terraform/session is missing permissions to use the s3 backend:
+  - s3:PutObject on arn:aws:s3:::states/terraform.tfstate, to write the state
+  - dynamodb:PutItem on arn:aws:dynamodb:eu-west-1:123456789012:table/locks, to lock the state`, err.Error())
+}
+
+func TestCheckPermissionsSkipsUnsupportedBackends(t *testing.T) {
+	t.Parallel()
+
+	terragruntOptions, err := options.NewTerragruntOptionsForTest("terragrunt.hcl")
+	require.NoError(t, err)
+
+	remoteState := &RemoteState{Backend: "local", Config: map[string]interface{}{"path": "terraform.tfstate"}}
+	assert.NoError(t, remoteState.CheckPermissions(terragruntOptions))
+}
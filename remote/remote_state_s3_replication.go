@@ -0,0 +1,376 @@
+package remote
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/iam"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/gruntwork-io/terragrunt/aws_helper"
+	"github.com/gruntwork-io/terragrunt/errors"
+	"github.com/gruntwork-io/terragrunt/options"
+	"github.com/gruntwork-io/terragrunt/shell"
+)
+
+// The ID of the replication rule, and the name of the inline policy of the replication role, created by Terragrunt
+const s3ReplicationRuleID = "terragrunt-state-replication"
+
+// IAM role names can be at most 64 characters long
+const maxIAMRoleNameLength = 64
+
+// A newly created IAM role can take a while to be usable by S3, so putting the replication configuration is retried
+const MAX_RETRIES_WAITING_FOR_S3_REPLICATION_ROLE = 12
+const SLEEP_BETWEEN_RETRIES_WAITING_FOR_S3_REPLICATION_ROLE = 5 * time.Second
+
+// If replica_bucket is set and the state bucket doesn't replicate to it yet, prompt the user to set up the replication,
+// and if the user confirms, create the replica bucket in replica_region with the same settings as the state bucket,
+// create the IAM role that S3 uses to replicate the objects, and configure the replication of the state bucket.
+func configureS3BucketReplicationIfNecessary(s3Client *s3.S3, config *ExtendedRemoteStateConfigS3, terragruntOptions *options.TerragruntOptions) error {
+	if config.ReplicaBucket == "" {
+		return nil
+	}
+
+	bucket := config.remoteStateConfigS3.Bucket
+
+	isReplicated, err := doesS3BucketReplicateTo(s3Client, bucket, s3BucketArn(config.ReplicaBucket, config.ReplicaRegion))
+	if err != nil {
+		return err
+	}
+	if isReplicated {
+		terragruntOptions.Logger.Debugf("Remote state S3 bucket %s is already replicated to %s", bucket, config.ReplicaBucket)
+		return nil
+	}
+
+	prompt := fmt.Sprintf("Remote state S3 bucket %s is not replicated to the S3 bucket %s in %s. Would you like Terragrunt to create the replica bucket and configure the replication?", bucket, config.ReplicaBucket, config.ReplicaRegion)
+	shouldReplicate, err := shell.PromptUserForYesNo(prompt, terragruntOptions)
+	if err != nil || !shouldReplicate {
+		return err
+	}
+
+	replicaConfig := s3ReplicaBucketConfig(config)
+	replicaClient, err := CreateS3Client(replicaConfig.GetAwsSessionConfig(), terragruntOptions)
+	if err != nil {
+		return err
+	}
+
+	if DoesS3BucketExist(replicaClient, aws.String(config.ReplicaBucket)) {
+		// Replication requires versioning on both buckets, so enable it in case the replica bucket was created elsewhere
+		if err := EnableVersioningForS3Bucket(replicaClient, &replicaConfig.remoteStateConfigS3, terragruntOptions); err != nil {
+			return err
+		}
+	} else if err := CreateS3BucketWithVersioningSSEncryptionAndAccessLogging(replicaClient, replicaConfig, terragruntOptions); err != nil {
+		return err
+	}
+
+	accountID, err := aws_helper.GetAWSAccountID(config.GetAwsSessionConfig(), terragruntOptions)
+	if err != nil {
+		return errors.WithStackTrace(err)
+	}
+
+	roleArn, err := createS3ReplicationRoleIfNecessary(config, terragruntOptions)
+	if err != nil {
+		return err
+	}
+
+	return putS3BucketReplication(s3Client, bucket, s3ReplicationConfiguration(config, roleArn, accountID), terragruntOptions)
+}
+
+// Returns true if the given bucket has a replication rule whose destination is the replica bucket of the given ARN
+func doesS3BucketReplicateTo(s3Client *s3.S3, bucket string, replicaBucketArn string) (bool, error) {
+	output, err := s3Client.GetBucketReplication(&s3.GetBucketReplicationInput{Bucket: aws.String(bucket)})
+	if err != nil {
+		if awsErr, isAwsErr := err.(awserr.Error); isAwsErr && awsErr.Code() == "ReplicationConfigurationNotFoundError" {
+			return false, nil
+		}
+		return false, errors.WithStackTrace(err)
+	}
+
+	for _, rule := range output.ReplicationConfiguration.Rules {
+		if rule.Destination != nil && aws.StringValue(rule.Destination.Bucket) == replicaBucketArn {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// Return the config of the replica bucket: the settings of the state bucket, in the replica region, encrypted with the
+// replica KMS key. Access logs are only written for the state bucket.
+func s3ReplicaBucketConfig(config *ExtendedRemoteStateConfigS3) *ExtendedRemoteStateConfigS3 {
+	replicaConfig := *config
+	replicaConfig.remoteStateConfigS3.Bucket = config.ReplicaBucket
+	replicaConfig.remoteStateConfigS3.Region = config.ReplicaRegion
+	replicaConfig.BucketSSEKMSKeyID = config.ReplicaBucketSSEKMSKeyID
+	replicaConfig.AccessLoggingBucketName = ""
+	return &replicaConfig
+}
+
+// Create the IAM role that S3 assumes to replicate the state bucket if it doesn't exist yet, and put its policy. Returns
+// the ARN of the role.
+func createS3ReplicationRoleIfNecessary(config *ExtendedRemoteStateConfigS3, terragruntOptions *options.TerragruntOptions) (string, error) {
+	session, err := aws_helper.CreateAwsSession(config.GetAwsSessionConfig(), terragruntOptions)
+	if err != nil {
+		return "", err
+	}
+	iamClient := iam.New(session)
+
+	roleName := s3ReplicationRoleName(config)
+
+	assumeRolePolicy, err := json.Marshal(s3ReplicationAssumeRolePolicy())
+	if err != nil {
+		return "", errors.WithStackTrace(err)
+	}
+
+	var roleArn string
+	terragruntOptions.Logger.Debugf("Creating IAM role %s for the replication of S3 bucket %s", roleName, config.remoteStateConfigS3.Bucket)
+	createRoleOutput, err := iamClient.CreateRole(&iam.CreateRoleInput{
+		RoleName:                 aws.String(roleName),
+		AssumeRolePolicyDocument: aws.String(string(assumeRolePolicy)),
+		Description:              aws.String(fmt.Sprintf("Used by S3 to replicate the Terraform state bucket %s to %s", config.remoteStateConfigS3.Bucket, config.ReplicaBucket)),
+	})
+	if err == nil {
+		roleArn = aws.StringValue(createRoleOutput.Role.Arn)
+	} else if awsErr, isAwsErr := err.(awserr.Error); isAwsErr && awsErr.Code() == iam.ErrCodeEntityAlreadyExistsException {
+		terragruntOptions.Logger.Debugf("IAM role %s already exists, updating its policy", roleName)
+		getRoleOutput, err := iamClient.GetRole(&iam.GetRoleInput{RoleName: aws.String(roleName)})
+		if err != nil {
+			return "", errors.WithStackTrace(err)
+		}
+		roleArn = aws.StringValue(getRoleOutput.Role.Arn)
+	} else {
+		return "", errors.WithStackTrace(err)
+	}
+
+	rolePolicy, err := json.Marshal(s3ReplicationRolePolicy(config))
+	if err != nil {
+		return "", errors.WithStackTrace(err)
+	}
+
+	_, err = iamClient.PutRolePolicy(&iam.PutRolePolicyInput{
+		RoleName:       aws.String(roleName),
+		PolicyName:     aws.String(s3ReplicationRuleID),
+		PolicyDocument: aws.String(string(rolePolicy)),
+	})
+	if err != nil {
+		return "", errors.WithStackTrace(err)
+	}
+
+	return roleArn, nil
+}
+
+// Put the given replication configuration on the given bucket, waiting for the replication role to be usable by S3
+func putS3BucketReplication(s3Client *s3.S3, bucket string, replicationConfig *s3.ReplicationConfiguration, terragruntOptions *options.TerragruntOptions) error {
+	input := &s3.PutBucketReplicationInput{
+		Bucket:                   aws.String(bucket),
+		ReplicationConfiguration: replicationConfig,
+	}
+
+	for retries := 0; retries < MAX_RETRIES_WAITING_FOR_S3_REPLICATION_ROLE; retries++ {
+		_, err := s3Client.PutBucketReplication(input)
+		if err == nil {
+			terragruntOptions.Logger.Debugf("Configured the replication of S3 bucket %s", bucket)
+			return nil
+		}
+
+		awsErr, isAwsErr := err.(awserr.Error)
+		if !isAwsErr || (awsErr.Code() != "InvalidRequest" && awsErr.Code() != "AccessDenied") {
+			return errors.WithStackTrace(err)
+		}
+
+		if retries < MAX_RETRIES_WAITING_FOR_S3_REPLICATION_ROLE-1 {
+			terragruntOptions.Logger.Debugf("The replication role of S3 bucket %s is not usable yet. Sleeping for %s and will try again.", bucket, SLEEP_BETWEEN_RETRIES_WAITING_FOR_S3_REPLICATION_ROLE)
+			time.Sleep(SLEEP_BETWEEN_RETRIES_WAITING_FOR_S3_REPLICATION_ROLE)
+		}
+	}
+
+	return errors.WithStackTrace(MaxRetriesWaitingForS3ReplicationRoleExceeded(bucket))
+}
+
+// Return the name of the replication role: replication_role_name if it's set, or else the name of the state bucket
+// with a -replication suffix
+func s3ReplicationRoleName(config *ExtendedRemoteStateConfigS3) string {
+	if config.ReplicationRoleName != "" {
+		return config.ReplicationRoleName
+	}
+	return config.remoteStateConfigS3.Bucket + "-replication"
+}
+
+// Return the trust policy that allows S3 to assume the replication role
+func s3ReplicationAssumeRolePolicy() map[string]interface{} {
+	return map[string]interface{}{
+		"Version": "2012-10-17",
+		"Statement": []map[string]interface{}{
+			{
+				"Effect":    "Allow",
+				"Principal": map[string]string{"Service": "s3.amazonaws.com"},
+				"Action":    "sts:AssumeRole",
+			},
+		},
+	}
+}
+
+// Return the policy of the replication role, which allows reading the objects of the state bucket and writing them to
+// the replica bucket. When the buckets are encrypted with KMS, it also allows decrypting the objects in the region of
+// the state bucket and encrypting them in the region of the replica bucket.
+func s3ReplicationRolePolicy(config *ExtendedRemoteStateConfigS3) map[string]interface{} {
+	bucketArn := s3BucketArn(config.remoteStateConfigS3.Bucket, config.remoteStateConfigS3.Region)
+	replicaBucketArn := s3BucketArn(config.ReplicaBucket, config.ReplicaRegion)
+
+	statements := []map[string]interface{}{
+		{
+			"Effect":   "Allow",
+			"Action":   []string{"s3:GetReplicationConfiguration", "s3:ListBucket"},
+			"Resource": bucketArn,
+		},
+		{
+			"Effect":   "Allow",
+			"Action":   []string{"s3:GetObjectVersionForReplication", "s3:GetObjectVersionAcl", "s3:GetObjectVersionTagging"},
+			"Resource": bucketArn + "/*",
+		},
+		{
+			"Effect":   "Allow",
+			"Action":   []string{"s3:ReplicateObject", "s3:ReplicateDelete", "s3:ReplicateTags"},
+			"Resource": replicaBucketArn + "/*",
+		},
+	}
+
+	if s3ReplicationUsesKMS(config) {
+		statements = append(statements,
+			map[string]interface{}{
+				"Effect":    "Allow",
+				"Action":    "kms:Decrypt",
+				"Resource":  "*",
+				"Condition": map[string]interface{}{"StringLike": map[string]string{"kms:ViaService": fmt.Sprintf("s3.%s.%s", config.remoteStateConfigS3.Region, aws_helper.DNSSuffixOfRegion(config.remoteStateConfigS3.Region))}},
+			},
+			map[string]interface{}{
+				"Effect":    "Allow",
+				"Action":    "kms:Encrypt",
+				"Resource":  "*",
+				"Condition": map[string]interface{}{"StringLike": map[string]string{"kms:ViaService": fmt.Sprintf("s3.%s.%s", config.ReplicaRegion, aws_helper.DNSSuffixOfRegion(config.ReplicaRegion))}},
+			},
+		)
+	}
+
+	return map[string]interface{}{
+		"Version":   "2012-10-17",
+		"Statement": statements,
+	}
+}
+
+// Return the replication configuration that replicates all the objects of the state bucket to the replica bucket
+// using the given role. Objects encrypted with KMS are re-encrypted with the replica KMS key, which defaults to the AWS
+// managed aws/s3 key of the given account in the replica region.
+func s3ReplicationConfiguration(config *ExtendedRemoteStateConfigS3, roleArn string, accountID string) *s3.ReplicationConfiguration {
+	rule := &s3.ReplicationRule{
+		ID:                      aws.String(s3ReplicationRuleID),
+		Status:                  aws.String(s3.ReplicationRuleStatusEnabled),
+		Priority:                aws.Int64(1),
+		Filter:                  &s3.ReplicationRuleFilter{Prefix: aws.String("")},
+		DeleteMarkerReplication: &s3.DeleteMarkerReplication{Status: aws.String(s3.DeleteMarkerReplicationStatusDisabled)},
+		Destination:             &s3.Destination{Bucket: aws.String(s3BucketArn(config.ReplicaBucket, config.ReplicaRegion))},
+	}
+
+	if s3ReplicationUsesKMS(config) {
+		replicaKMSKeyID := config.ReplicaBucketSSEKMSKeyID
+		if replicaKMSKeyID == "" {
+			replicaKMSKeyID = fmt.Sprintf("arn:%s:kms:%s:%s:alias/aws/s3", aws_helper.PartitionOfRegion(config.ReplicaRegion), config.ReplicaRegion, accountID)
+		}
+		rule.SourceSelectionCriteria = &s3.SourceSelectionCriteria{
+			SseKmsEncryptedObjects: &s3.SseKmsEncryptedObjects{Status: aws.String(s3.SseKmsEncryptedObjectsStatusEnabled)},
+		}
+		rule.Destination.EncryptionConfiguration = &s3.EncryptionConfiguration{ReplicaKmsKeyID: aws.String(replicaKMSKeyID)}
+	}
+
+	return &s3.ReplicationConfiguration{
+		Role:  aws.String(roleArn),
+		Rules: []*s3.ReplicationRule{rule},
+	}
+}
+
+// Returns true if the objects of the state bucket are encrypted with KMS, which has to be configured explicitly in the
+// replication
+func s3ReplicationUsesKMS(config *ExtendedRemoteStateConfigS3) bool {
+	return !config.SkipBucketSSEncryption && config.BucketSSEAlgorithm == s3.ServerSideEncryptionAwsKms
+}
+
+// Validate the replication settings of the given S3 remote state configuration
+func validateS3ReplicationConfig(config *ExtendedRemoteStateConfigS3) error {
+	if config.ReplicaBucket == "" {
+		replicationConfigs := []struct {
+			name  string
+			value string
+		}{
+			{"replica_region", config.ReplicaRegion},
+			{"replica_bucket_sse_kms_key_id", config.ReplicaBucketSSEKMSKeyID},
+			{"replication_role_name", config.ReplicationRoleName},
+		}
+		for _, replicationConfig := range replicationConfigs {
+			if replicationConfig.value != "" {
+				return errors.WithStackTrace(S3ReplicationConfigWithoutReplicaBucket(replicationConfig.name))
+			}
+		}
+		return nil
+	}
+
+	if config.ReplicaRegion == "" {
+		return errors.WithStackTrace(MissingRequiredS3RemoteStateConfig("replica_region"))
+	}
+
+	if config.ReplicaBucket == config.remoteStateConfigS3.Bucket {
+		return errors.WithStackTrace(S3ReplicaBucketSameAsBucket(config.ReplicaBucket))
+	}
+
+	// S3 can only replicate versioned buckets
+	if config.SkipBucketVersioning {
+		return errors.WithStackTrace(S3ReplicationWithoutVersioning(config.remoteStateConfigS3.Bucket))
+	}
+
+	if config.ReplicaBucketSSEKMSKeyID != "" && !s3ReplicationUsesKMS(config) {
+		return errors.WithStackTrace(S3BucketSSEKMSKeyWithoutKMSAlgorithm(config.BucketSSEAlgorithm))
+	}
+
+	if roleName := s3ReplicationRoleName(config); len(roleName) > maxIAMRoleNameLength {
+		return errors.WithStackTrace(S3ReplicationRoleNameTooLong(roleName))
+	}
+
+	return nil
+}
+
+// Return the ARN of the given S3 bucket in the given region, whose partition the ARN is in
+func s3BucketArn(bucket string, region string) string {
+	return fmt.Sprintf("arn:%s:s3:::%s", aws_helper.PartitionOfRegion(region), bucket)
+}
+
+// Custom error types
+
+type S3ReplicationConfigWithoutReplicaBucket string
+
+func (configName S3ReplicationConfigWithoutReplicaBucket) Error() string {
+	return fmt.Sprintf("The S3 remote state configuration %s is only valid with replica_bucket", string(configName))
+}
+
+type S3ReplicaBucketSameAsBucket string
+
+func (bucket S3ReplicaBucketSameAsBucket) Error() string {
+	return fmt.Sprintf("The S3 remote state replica_bucket must be different from the state bucket %s", string(bucket))
+}
+
+type S3ReplicationWithoutVersioning string
+
+func (bucket S3ReplicationWithoutVersioning) Error() string {
+	return fmt.Sprintf("The S3 remote state bucket %s can't be replicated with skip_bucket_versioning, as S3 only replicates versioned buckets", string(bucket))
+}
+
+type S3ReplicationRoleNameTooLong string
+
+func (roleName S3ReplicationRoleNameTooLong) Error() string {
+	return fmt.Sprintf("The name of the S3 replication role %s is longer than %d characters. Set replication_role_name to a shorter name.", string(roleName), maxIAMRoleNameLength)
+}
+
+type MaxRetriesWaitingForS3ReplicationRoleExceeded string
+
+func (bucket MaxRetriesWaitingForS3ReplicationRoleExceeded) Error() string {
+	return fmt.Sprintf("Exceeded max retries waiting for the replication role of S3 bucket %s to be usable", string(bucket))
+}
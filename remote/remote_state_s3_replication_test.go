@@ -0,0 +1,158 @@
+package remote
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/gruntwork-io/terragrunt/options"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateS3ReplicationConfig(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name             string
+		config           map[string]interface{}
+		expectedErrorMsg string
+	}{
+		{"no-replication", map[string]interface{}{}, ""},
+		{"replica", map[string]interface{}{"replica_bucket": "foo-replica", "replica_region": "us-west-2"}, ""},
+		{"missing-replica-region", map[string]interface{}{"replica_bucket": "foo-replica"}, "replica_region"},
+		{"invalid-replica-region", map[string]interface{}{"replica_bucket": "foo-replica", "replica_region": "Frankfurt"}, "not a valid AWS region"},
+		{"replica-region-without-replica-bucket", map[string]interface{}{"replica_region": "us-west-2"}, "only valid with replica_bucket"},
+		{"same-bucket", map[string]interface{}{"replica_bucket": "foo", "replica_region": "us-west-2"}, "must be different"},
+		{"without-versioning", map[string]interface{}{"replica_bucket": "foo-replica", "replica_region": "us-west-2", "skip_bucket_versioning": true}, "skip_bucket_versioning"},
+		{"replica-kms-key-with-aes256", map[string]interface{}{"replica_bucket": "foo-replica", "replica_region": "us-west-2", "bucket_sse_algorithm": "AES256", "replica_bucket_sse_kms_key_id": "arn:aws:kms:us-west-2:123456789012:key/foo"}, "AES256"},
+		{"role-name-too-long", map[string]interface{}{"replica_bucket": "foo-replica", "replica_region": "us-west-2", "replication_role_name": "a-very-long-role-name-for-the-replication-of-the-terraform-state-bucket"}, "longer than 64 characters"},
+	}
+
+	terragruntOptions, err := options.NewTerragruntOptionsForTest("remote_state_test")
+	require.Nil(t, err, "Unexpected error creating NewTerragruntOptionsForTest: %v", err)
+
+	for _, testCase := range testCases {
+		// Save the testCase in local scope so all the t.Run calls don't end up with the last item in the list
+		testCase := testCase
+
+		t.Run(testCase.name, func(t *testing.T) {
+			t.Parallel()
+
+			config := map[string]interface{}{"bucket": "foo", "key": "bar", "region": "us-east-1", "encrypt": true}
+			for key, value := range testCase.config {
+				config[key] = value
+			}
+
+			extendedConfig, err := parseExtendedS3Config(config)
+			require.NoError(t, err)
+
+			err = validateS3Config(extendedConfig, terragruntOptions)
+			if testCase.expectedErrorMsg == "" {
+				assert.NoError(t, err)
+			} else {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), testCase.expectedErrorMsg)
+			}
+		})
+	}
+}
+
+func TestS3ReplicationConfiguration(t *testing.T) {
+	t.Parallel()
+
+	roleArn := "arn:aws:iam::123456789012:role/foo-replication"
+
+	testCases := []struct {
+		name                     string
+		config                   map[string]interface{}
+		expectedReplicaKMSKeyID  string
+		expectedKMSPolicyActions bool
+	}{
+		{
+			"aws-managed-kms-key",
+			map[string]interface{}{},
+			"arn:aws:kms:us-west-2:123456789012:alias/aws/s3",
+			true,
+		},
+		{
+			"customer-managed-kms-key",
+			map[string]interface{}{"replica_bucket_sse_kms_key_id": "arn:aws:kms:us-west-2:123456789012:key/foo"},
+			"arn:aws:kms:us-west-2:123456789012:key/foo",
+			true,
+		},
+		{
+			"aes256",
+			map[string]interface{}{"bucket_sse_algorithm": "AES256"},
+			"",
+			false,
+		},
+	}
+
+	for _, testCase := range testCases {
+		// Save the testCase in local scope so all the t.Run calls don't end up with the last item in the list
+		testCase := testCase
+
+		t.Run(testCase.name, func(t *testing.T) {
+			t.Parallel()
+
+			config := map[string]interface{}{"bucket": "foo", "key": "bar", "region": "us-east-1", "replica_bucket": "foo-replica", "replica_region": "us-west-2"}
+			for key, value := range testCase.config {
+				config[key] = value
+			}
+
+			extendedConfig, err := parseExtendedS3Config(config)
+			require.NoError(t, err)
+
+			replicationConfig := s3ReplicationConfiguration(extendedConfig, roleArn, "123456789012")
+			assert.Equal(t, roleArn, aws.StringValue(replicationConfig.Role))
+			require.Len(t, replicationConfig.Rules, 1)
+
+			rule := replicationConfig.Rules[0]
+			assert.Equal(t, s3.ReplicationRuleStatusEnabled, aws.StringValue(rule.Status))
+			assert.Equal(t, "arn:aws:s3:::foo-replica", aws.StringValue(rule.Destination.Bucket))
+			if testCase.expectedReplicaKMSKeyID == "" {
+				assert.Nil(t, rule.Destination.EncryptionConfiguration)
+				assert.Nil(t, rule.SourceSelectionCriteria)
+			} else {
+				assert.Equal(t, testCase.expectedReplicaKMSKeyID, aws.StringValue(rule.Destination.EncryptionConfiguration.ReplicaKmsKeyID))
+				assert.Equal(t, s3.SseKmsEncryptedObjectsStatusEnabled, aws.StringValue(rule.SourceSelectionCriteria.SseKmsEncryptedObjects.Status))
+			}
+
+			statements := s3ReplicationRolePolicy(extendedConfig)["Statement"].([]map[string]interface{})
+			if testCase.expectedKMSPolicyActions {
+				assert.Len(t, statements, 5)
+			} else {
+				assert.Len(t, statements, 3)
+			}
+			assert.Equal(t, "arn:aws:s3:::foo-replica/*", statements[2]["Resource"])
+		})
+	}
+}
+
+func TestS3ReplicaBucketConfig(t *testing.T) {
+	t.Parallel()
+
+	extendedConfig, err := parseExtendedS3Config(map[string]interface{}{
+		"bucket":                        "foo",
+		"key":                           "bar",
+		"region":                        "us-east-1",
+		"bucket_sse_kms_key_id":         "arn:aws:kms:us-east-1:123456789012:key/foo",
+		"accesslogging_bucket_name":     "foo-logs",
+		"replica_bucket":                "foo-replica",
+		"replica_region":                "us-west-2",
+		"replica_bucket_sse_kms_key_id": "arn:aws:kms:us-west-2:123456789012:key/bar",
+	})
+	require.NoError(t, err)
+
+	replicaConfig := s3ReplicaBucketConfig(extendedConfig)
+	assert.Equal(t, "foo-replica", replicaConfig.remoteStateConfigS3.Bucket)
+	assert.Equal(t, "us-west-2", replicaConfig.GetAwsSessionConfig().Region)
+	assert.Equal(t, "arn:aws:kms:us-west-2:123456789012:key/bar", replicaConfig.BucketSSEKMSKeyID)
+	assert.Equal(t, "", replicaConfig.AccessLoggingBucketName)
+	assert.Equal(t, "foo-replication", s3ReplicationRoleName(extendedConfig))
+
+	// The config of the state bucket is left as is
+	assert.Equal(t, "foo", extendedConfig.remoteStateConfigS3.Bucket)
+	assert.Equal(t, "arn:aws:kms:us-east-1:123456789012:key/foo", extendedConfig.BucketSSEKMSKeyID)
+}
@@ -1,11 +1,20 @@
 package remote
 
 import (
+	"net/http"
+	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/s3"
 	"github.com/gruntwork-io/terragrunt/aws_helper"
+	"github.com/gruntwork-io/terragrunt/dynamodb"
+	"github.com/gruntwork-io/terragrunt/errors"
 	"github.com/gruntwork-io/terragrunt/options"
+	"github.com/hashicorp/go-version"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -52,6 +61,12 @@ func TestConfigValuesEqual(t *testing.T) {
 			&TerraformBackend{Type: "s3", Config: map[string]interface{}{"foo": "bar", "baz": []string{"a", "b", "c"}, "blah": 123, "bool": true}},
 			true,
 		},
+		{
+			"equal-use-lockfile-bool-handling",
+			map[string]interface{}{"use_lockfile": true, "dynamodb_table": nil},
+			&TerraformBackend{Type: "s3", Config: map[string]interface{}{"use_lockfile": "true", "dynamodb_table": nil}},
+			true,
+		},
 		{
 			"equal-encrypt-bool-handling",
 			map[string]interface{}{"encrypt": true},
@@ -187,6 +202,14 @@ func TestGetAwsSessionConfig(t *testing.T) {
 			"extra-values",
 			map[string]interface{}{"something": "unexpected", "region": "foo", "endpoint": "bar", "dynamodb_endpoint": "foobar", "profile": "baz", "role_arn": "arn::it", "shared_credentials_file": "my-file", "force_path_style": false},
 		},
+		{
+			"assume-role-block",
+			map[string]interface{}{"region": "foo", "role_arn": "arn::old", "assume_role": map[string]interface{}{"role_arn": "arn::it", "external_id": "my-id", "session_name": "my-session", "duration": "1h"}},
+		},
+		{
+			"endpoints-block",
+			map[string]interface{}{"region": "foo", "sts_endpoint": "http://old", "endpoints": map[string]interface{}{"s3": "http://s3", "dynamodb": "http://dynamodb", "sts": "http://sts", "iam": "http://iam"}},
+		},
 	}
 
 	for _, testCase := range testCases {
@@ -200,12 +223,21 @@ func TestGetAwsSessionConfig(t *testing.T) {
 			s3ConfigExtended, err := parseExtendedS3Config(testCase.config)
 			require.Nil(t, err, "Unexpected error parsing config for test: %v", err)
 
+			assumeRole := s3ConfigExtended.remoteStateConfigS3.GetAssumeRole()
+			assumeRoleDuration, _ := time.ParseDuration(assumeRole.Duration)
+			endpoints := s3ConfigExtended.remoteStateConfigS3.GetEndpoints()
+
 			expected := &aws_helper.AwsSessionConfig{
 				Region:                  s3ConfigExtended.remoteStateConfigS3.Region,
-				CustomS3Endpoint:        s3ConfigExtended.remoteStateConfigS3.Endpoint,
-				CustomDynamoDBEndpoint:  s3ConfigExtended.remoteStateConfigS3.DynamoDBEndpoint,
+				CustomS3Endpoint:        endpoints.S3,
+				CustomDynamoDBEndpoint:  endpoints.DynamoDB,
+				CustomSTSEndpoint:       endpoints.STS,
+				CustomIAMEndpoint:       endpoints.IAM,
 				Profile:                 s3ConfigExtended.remoteStateConfigS3.Profile,
-				RoleArn:                 s3ConfigExtended.remoteStateConfigS3.RoleArn,
+				RoleArn:                 assumeRole.RoleArn,
+				ExternalID:              assumeRole.ExternalID,
+				SessionName:             assumeRole.SessionName,
+				AssumeRoleDuration:      assumeRoleDuration,
 				CredsFilename:           s3ConfigExtended.remoteStateConfigS3.CredsFilename,
 				S3ForcePathStyle:        s3ConfigExtended.remoteStateConfigS3.S3ForcePathStyle,
 				DisableComputeChecksums: s3ConfigExtended.DisableAWSClientChecksums,
@@ -270,16 +302,25 @@ func TestGetTerraformInitArgs(t *testing.T) {
 		{
 			"empty-no-values-all-terragrunt-keys-filtered",
 			map[string]interface{}{
-				"s3_bucket_tags":                 map[string]string{},
-				"dynamodb_table_tags":            map[string]string{},
-				"skip_bucket_versioning":         true,
-				"skip_bucket_ssencryption":       false,
-				"skip_bucket_root_access":        false,
-				"skip_bucket_enforced_tls":       false,
-				"enable_lock_table_ssencryption": true,
-				"disable_aws_client_checksums":   false,
-				"accesslogging_bucket_name":      "test",
-				"accesslogging_target_prefix":    "test",
+				"s3_bucket_tags":                        map[string]string{},
+				"dynamodb_table_tags":                   map[string]string{},
+				"skip_bucket_versioning":                true,
+				"skip_bucket_ssencryption":              false,
+				"skip_bucket_root_access":               false,
+				"skip_bucket_enforced_tls":              false,
+				"skip_bucket_public_access_blocking":    false,
+				"enable_lock_table_ssencryption":        true,
+				"disable_aws_client_checksums":          false,
+				"accesslogging_bucket_name":             "test",
+				"accesslogging_target_prefix":           "test",
+				"bucket_sse_algorithm":                  "aws:kms",
+				"bucket_sse_kms_key_id":                 "arn:aws:kms:us-east-1:123456789012:key/foo",
+				"bucket_key_enabled":                    true,
+				"dynamodb_table_billing_mode":           "PROVISIONED",
+				"dynamodb_table_read_capacity":          1,
+				"dynamodb_table_write_capacity":         1,
+				"dynamodb_table_kms_key_id":             "arn:aws:kms:us-east-1:123456789012:key/foo",
+				"dynamodb_table_point_in_time_recovery": true,
 			},
 			map[string]interface{}{},
 			true,
@@ -338,3 +379,526 @@ func TestGetTerraformInitArgs(t *testing.T) {
 		})
 	}
 }
+
+func TestS3BucketSSERule(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name             string
+		config           map[string]interface{}
+		expectedRule     *s3.ServerSideEncryptionRule
+		expectedErrorMsg string
+	}{
+		{
+			"default-aws-managed-kms-key",
+			map[string]interface{}{},
+			&s3.ServerSideEncryptionRule{ApplyServerSideEncryptionByDefault: &s3.ServerSideEncryptionByDefault{SSEAlgorithm: aws.String("aws:kms")}},
+			"",
+		},
+		{
+			"customer-managed-kms-key-with-bucket-key",
+			map[string]interface{}{"bucket_sse_kms_key_id": "arn:aws:kms:us-east-1:123456789012:key/foo", "bucket_key_enabled": true},
+			&s3.ServerSideEncryptionRule{
+				ApplyServerSideEncryptionByDefault: &s3.ServerSideEncryptionByDefault{SSEAlgorithm: aws.String("aws:kms"), KMSMasterKeyID: aws.String("arn:aws:kms:us-east-1:123456789012:key/foo")},
+				BucketKeyEnabled:                   aws.Bool(true),
+			},
+			"",
+		},
+		{
+			"aes256",
+			map[string]interface{}{"bucket_sse_algorithm": "AES256"},
+			&s3.ServerSideEncryptionRule{ApplyServerSideEncryptionByDefault: &s3.ServerSideEncryptionByDefault{SSEAlgorithm: aws.String("AES256")}},
+			"",
+		},
+		{
+			"invalid-algorithm",
+			map[string]interface{}{"bucket_sse_algorithm": "aws:kms:dsse"},
+			nil,
+			"bucket_sse_algorithm",
+		},
+		{
+			"kms-key-with-aes256",
+			map[string]interface{}{"bucket_sse_algorithm": "AES256", "bucket_sse_kms_key_id": "arn:aws:kms:us-east-1:123456789012:key/foo"},
+			nil,
+			"bucket_sse_kms_key_id",
+		},
+	}
+
+	terragruntOptions, err := options.NewTerragruntOptionsForTest("remote_state_test")
+	require.Nil(t, err, "Unexpected error creating NewTerragruntOptionsForTest: %v", err)
+
+	for _, testCase := range testCases {
+		// Save the testCase in local scope so all the t.Run calls don't end up with the last item in the list
+		testCase := testCase
+
+		t.Run(testCase.name, func(t *testing.T) {
+			t.Parallel()
+
+			config := map[string]interface{}{"bucket": "foo", "key": "bar", "region": "us-east-1", "encrypt": true}
+			for key, value := range testCase.config {
+				config[key] = value
+			}
+
+			extendedConfig, err := parseExtendedS3Config(config)
+			require.NoError(t, err)
+
+			err = validateS3Config(extendedConfig, terragruntOptions)
+			if testCase.expectedErrorMsg != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), testCase.expectedErrorMsg)
+				return
+			}
+			require.NoError(t, err)
+
+			rule := s3BucketSSERule(extendedConfig)
+			assert.Equal(t, testCase.expectedRule, rule)
+			assert.True(t, s3BucketSSEMatchesConfig(&s3.ServerSideEncryptionConfiguration{Rules: []*s3.ServerSideEncryptionRule{rule}}, extendedConfig))
+		})
+	}
+}
+
+func TestS3BucketSSEMatchesConfig(t *testing.T) {
+	t.Parallel()
+
+	extendedConfig, err := parseExtendedS3Config(map[string]interface{}{"bucket_sse_kms_key_id": "arn:aws:kms:us-east-1:123456789012:key/foo"})
+	require.NoError(t, err)
+
+	awsManagedKey := &s3.ServerSideEncryptionConfiguration{Rules: []*s3.ServerSideEncryptionRule{
+		{ApplyServerSideEncryptionByDefault: &s3.ServerSideEncryptionByDefault{SSEAlgorithm: aws.String("aws:kms")}},
+	}}
+	aes256 := &s3.ServerSideEncryptionConfiguration{Rules: []*s3.ServerSideEncryptionRule{
+		{ApplyServerSideEncryptionByDefault: &s3.ServerSideEncryptionByDefault{SSEAlgorithm: aws.String("AES256")}},
+	}}
+
+	assert.False(t, s3BucketSSEMatchesConfig(awsManagedKey, extendedConfig))
+	assert.False(t, s3BucketSSEMatchesConfig(aes256, extendedConfig))
+	assert.False(t, s3BucketSSEMatchesConfig(nil, extendedConfig))
+}
+
+func TestS3BucketPolicy(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name         string
+		config       map[string]interface{}
+		accountID    string
+		expectedSids []string
+	}{
+		{"root-access-and-enforced-tls", map[string]interface{}{}, "123456789012", []string{"RootAccess", "AllowTLSRequestsOnly"}},
+		{"skip-root-access", map[string]interface{}{"skip_bucket_root_access": true}, "", []string{"AllowTLSRequestsOnly"}},
+		{"skip-enforced-tls", map[string]interface{}{"skip_bucket_enforced_tls": true}, "123456789012", []string{"RootAccess"}},
+		{"skip-all", map[string]interface{}{"skip_bucket_root_access": true, "skip_bucket_enforced_tls": true}, "", nil},
+	}
+
+	for _, testCase := range testCases {
+		// Save the testCase in local scope so all the t.Run calls don't end up with the last item in the list
+		testCase := testCase
+
+		t.Run(testCase.name, func(t *testing.T) {
+			t.Parallel()
+
+			config := map[string]interface{}{"bucket": "foo"}
+			for key, value := range testCase.config {
+				config[key] = value
+			}
+
+			extendedConfig, err := parseExtendedS3Config(config)
+			require.NoError(t, err)
+
+			policy, err := s3BucketPolicy(extendedConfig, testCase.accountID)
+			require.NoError(t, err)
+			if testCase.expectedSids == nil {
+				assert.Nil(t, policy)
+				return
+			}
+
+			var sids []string
+			for _, statement := range policy["Statement"].([]map[string]interface{}) {
+				sids = append(sids, statement["Sid"].(string))
+				assert.Equal(t, []string{"arn:aws:s3:::foo", "arn:aws:s3:::foo/*"}, statement["Resource"])
+			}
+			assert.Equal(t, testCase.expectedSids, sids)
+		})
+	}
+}
+
+func TestS3BucketPolicyCustomStatements(t *testing.T) {
+	t.Parallel()
+
+	denyUnencryptedUploads := map[string]interface{}{
+		"Sid":       "DenyUnencryptedUploads",
+		"Effect":    "Deny",
+		"Action":    "s3:PutObject",
+		"Principal": "*",
+		"Condition": map[string]interface{}{"Null": map[string]interface{}{"s3:x-amz-server-side-encryption": "true"}},
+	}
+
+	testCases := []struct {
+		name             string
+		bucketPolicy     interface{}
+		expectedSids     []string
+		expectedResource interface{}
+		expectedErrorMsg string
+	}{
+		{
+			"map-with-statement-list",
+			map[string]interface{}{"Statement": []interface{}{denyUnencryptedUploads}},
+			[]string{"AllowTLSRequestsOnly", "DenyUnencryptedUploads"},
+			[]string{"arn:aws:s3:::foo", "arn:aws:s3:::foo/*"},
+			"",
+		},
+		{
+			"json-with-single-statement",
+			`{"Version": "2012-10-17", "Statement": {"Sid": "AllowAuditRole", "Effect": "Allow", "Action": "s3:GetObject", "Principal": {"AWS": "arn:aws:iam::123456789012:role/audit"}, "Resource": "arn:aws:s3:::foo/*"}}`,
+			[]string{"AllowTLSRequestsOnly", "AllowAuditRole"},
+			"arn:aws:s3:::foo/*",
+			"",
+		},
+		{"invalid-json", `{"Statement": [`, nil, nil, "not valid JSON"},
+		{"missing-statement", map[string]interface{}{"Version": "2012-10-17"}, nil, nil, "must have a Statement"},
+		{"reserved-sid", map[string]interface{}{"Statement": map[string]interface{}{"Sid": "RootAccess"}}, nil, nil, "RootAccess"},
+		{"invalid-type", []interface{}{"foo"}, nil, nil, "map or a JSON string"},
+	}
+
+	terragruntOptions, err := options.NewTerragruntOptionsForTest("remote_state_test")
+	require.Nil(t, err, "Unexpected error creating NewTerragruntOptionsForTest: %v", err)
+
+	for _, testCase := range testCases {
+		// Save the testCase in local scope so all the t.Run calls don't end up with the last item in the list
+		testCase := testCase
+
+		t.Run(testCase.name, func(t *testing.T) {
+			t.Parallel()
+
+			extendedConfig, err := parseExtendedS3Config(map[string]interface{}{
+				"bucket":                  "foo",
+				"key":                     "bar",
+				"region":                  "us-east-1",
+				"encrypt":                 true,
+				"skip_bucket_root_access": true,
+				"bucket_policy":           testCase.bucketPolicy,
+			})
+			require.NoError(t, err)
+
+			err = validateS3Config(extendedConfig, terragruntOptions)
+			if testCase.expectedErrorMsg != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), testCase.expectedErrorMsg)
+				return
+			}
+			require.NoError(t, err)
+
+			policy, err := s3BucketPolicy(extendedConfig, "")
+			require.NoError(t, err)
+
+			statements := policy["Statement"].([]map[string]interface{})
+			var sids []string
+			for _, statement := range statements {
+				sids = append(sids, statement["Sid"].(string))
+			}
+			assert.Equal(t, testCase.expectedSids, sids)
+			assert.Equal(t, testCase.expectedResource, statements[1]["Resource"])
+		})
+	}
+}
+
+func TestValidateS3ConfigLockTableSettings(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name             string
+		config           map[string]interface{}
+		expectedSettings dynamodb.LockTableSettings
+		expectedErrorMsg string
+	}{
+		{
+			"defaults",
+			map[string]interface{}{},
+			dynamodb.LockTableSettings{BillingMode: "PAY_PER_REQUEST"},
+			"",
+		},
+		{
+			"provisioned-with-cmk-and-pitr",
+			map[string]interface{}{
+				"dynamodb_table_billing_mode":           "PROVISIONED",
+				"dynamodb_table_read_capacity":          float64(5),
+				"dynamodb_table_write_capacity":         float64(2),
+				"dynamodb_table_kms_key_id":             "arn:aws:kms:us-east-1:123456789012:key/foo",
+				"dynamodb_table_point_in_time_recovery": true,
+				"dynamodb_table_tags":                   map[string]string{"team": "platform"},
+			},
+			dynamodb.LockTableSettings{
+				Tags:                      map[string]string{"team": "platform"},
+				BillingMode:               "PROVISIONED",
+				ReadCapacity:              5,
+				WriteCapacity:             2,
+				SSEKMSKeyID:               "arn:aws:kms:us-east-1:123456789012:key/foo",
+				EnablePointInTimeRecovery: true,
+			},
+			"",
+		},
+		{
+			"provisioned-without-capacity",
+			map[string]interface{}{"dynamodb_table_billing_mode": "PROVISIONED"},
+			dynamodb.LockTableSettings{},
+			"dynamodb_table_read_capacity",
+		},
+		{
+			"capacity-with-pay-per-request",
+			map[string]interface{}{"dynamodb_table_read_capacity": float64(5)},
+			dynamodb.LockTableSettings{},
+			"PROVISIONED",
+		},
+		{
+			"invalid-billing-mode",
+			map[string]interface{}{"dynamodb_table_billing_mode": "ON_DEMAND"},
+			dynamodb.LockTableSettings{},
+			"ON_DEMAND",
+		},
+	}
+
+	terragruntOptions, err := options.NewTerragruntOptionsForTest("remote_state_test")
+	require.Nil(t, err, "Unexpected error creating NewTerragruntOptionsForTest: %v", err)
+
+	for _, testCase := range testCases {
+		// Save the testCase in local scope so all the t.Run calls don't end up with the last item in the list
+		testCase := testCase
+
+		t.Run(testCase.name, func(t *testing.T) {
+			t.Parallel()
+
+			config := map[string]interface{}{"bucket": "foo", "key": "bar", "region": "us-east-1", "encrypt": true, "dynamodb_table": "locks"}
+			for key, value := range testCase.config {
+				config[key] = value
+			}
+
+			extendedConfig, err := parseExtendedS3Config(config)
+			require.NoError(t, err)
+
+			err = validateS3Config(extendedConfig, terragruntOptions)
+			if testCase.expectedErrorMsg != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), testCase.expectedErrorMsg)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, testCase.expectedSettings, lockTableSettings(extendedConfig))
+		})
+	}
+}
+
+func TestUsesLockTable(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		config   map[string]interface{}
+		expected bool
+	}{
+		{map[string]interface{}{}, false},
+		{map[string]interface{}{"dynamodb_table": "locks"}, true},
+		{map[string]interface{}{"lock_table": "locks"}, true},
+		{map[string]interface{}{"use_lockfile": true}, false},
+		{map[string]interface{}{"use_lockfile": true, "dynamodb_table": "locks"}, true},
+	}
+
+	for _, testCase := range testCases {
+		extendedConfig, err := parseExtendedS3Config(testCase.config)
+		require.NoError(t, err)
+		assert.Equal(t, testCase.expected, extendedConfig.remoteStateConfigS3.UsesLockTable(), "config %v", testCase.config)
+	}
+}
+
+func TestValidateS3ConfigUseLockfileTerraformVersion(t *testing.T) {
+	t.Parallel()
+
+	extendedConfig, err := parseExtendedS3Config(map[string]interface{}{"bucket": "foo", "key": "bar", "region": "us-east-1", "encrypt": true, "use_lockfile": true})
+	require.NoError(t, err)
+
+	terragruntOptions, err := options.NewTerragruntOptionsForTest("remote_state_test")
+	require.NoError(t, err)
+
+	terragruntOptions.TerraformVersion = version.Must(version.NewVersion("1.9.8"))
+	err = validateS3Config(extendedConfig, terragruntOptions)
+	require.Error(t, err)
+	assert.IsType(t, UseLockfileNotSupported{}, errors.Unwrap(err))
+
+	terragruntOptions.TerraformVersion = version.Must(version.NewVersion("1.10.0"))
+	assert.NoError(t, validateS3Config(extendedConfig, terragruntOptions))
+}
+
+func TestS3StateKeyForWorkspace(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name      string
+		config    map[string]interface{}
+		workspace string
+		expected  string
+	}{
+		{"default-workspace", map[string]interface{}{}, "", "vpc/terraform.tfstate"},
+		{"explicit-default-workspace", map[string]interface{}{}, "default", "vpc/terraform.tfstate"},
+		{"other-workspace", map[string]interface{}{}, "blue", "env:/blue/vpc/terraform.tfstate"},
+		{"custom-prefix", map[string]interface{}{"workspace_key_prefix": "workspaces"}, "blue", "workspaces/blue/vpc/terraform.tfstate"},
+	}
+
+	for _, testCase := range testCases {
+		// Save the testCase in local scope so all the t.Run calls don't end up with the last item in the list
+		testCase := testCase
+
+		t.Run(testCase.name, func(t *testing.T) {
+			t.Parallel()
+
+			assert.Equal(t, testCase.expected, s3StateKeyForWorkspace(testCase.config, "vpc/terraform.tfstate", testCase.workspace))
+		})
+	}
+}
+
+func TestMovedS3StateKey(t *testing.T) {
+	t.Parallel()
+
+	rawConfig := map[string]interface{}{"bucket": "my-bucket", "key": "prod/networking/vpc/terraform.tfstate"}
+	config := &RemoteStateConfigS3{Bucket: "my-bucket", Key: "prod/networking/vpc/terraform.tfstate"}
+
+	testCases := []struct {
+		name            string
+		existingBackend *TerraformBackend
+		workspace       string
+		expectedOldKey  string
+		expectedNewKey  string
+		expectedMoved   bool
+	}{
+		{"moved", &TerraformBackend{Type: "s3", Config: map[string]interface{}{"bucket": "my-bucket", "key": "prod/vpc/terraform.tfstate"}}, "", "prod/vpc/terraform.tfstate", "prod/networking/vpc/terraform.tfstate", true},
+		{"moved-default-workspace", &TerraformBackend{Type: "s3", Config: map[string]interface{}{"bucket": "my-bucket", "key": "prod/vpc/terraform.tfstate"}}, "default", "prod/vpc/terraform.tfstate", "prod/networking/vpc/terraform.tfstate", true},
+		{"moved-other-workspace", &TerraformBackend{Type: "s3", Config: map[string]interface{}{"bucket": "my-bucket", "key": "prod/vpc/terraform.tfstate"}}, "blue", "env:/blue/prod/vpc/terraform.tfstate", "env:/blue/prod/networking/vpc/terraform.tfstate", true},
+		{"moved-workspace-key-prefix", &TerraformBackend{Type: "s3", Config: map[string]interface{}{"bucket": "my-bucket", "key": "prod/networking/vpc/terraform.tfstate", "workspace_key_prefix": "workspaces"}}, "blue", "workspaces/blue/prod/networking/vpc/terraform.tfstate", "env:/blue/prod/networking/vpc/terraform.tfstate", true},
+		{"same-key", &TerraformBackend{Type: "s3", Config: map[string]interface{}{"bucket": "my-bucket", "key": "prod/networking/vpc/terraform.tfstate"}}, "blue", "", "", false},
+		{"other-bucket", &TerraformBackend{Type: "s3", Config: map[string]interface{}{"bucket": "other-bucket", "key": "prod/vpc/terraform.tfstate"}}, "", "", "", false},
+		{"other-backend", &TerraformBackend{Type: "gcs", Config: map[string]interface{}{"bucket": "my-bucket", "prefix": "prod/vpc"}}, "", "", "", false},
+		{"not-initialized", nil, "", "", "", false},
+	}
+
+	for _, testCase := range testCases {
+		// Save the testCase in local scope so all the t.Run calls don't end up with the last item in the list
+		testCase := testCase
+
+		t.Run(testCase.name, func(t *testing.T) {
+			t.Parallel()
+
+			oldKey, newKey, moved := movedS3StateKey(rawConfig, config, testCase.existingBackend, testCase.workspace)
+			assert.Equal(t, testCase.expectedOldKey, oldKey)
+			assert.Equal(t, testCase.expectedNewKey, newKey)
+			assert.Equal(t, testCase.expectedMoved, moved)
+		})
+	}
+}
+
+func TestS3StateDigestLockID(t *testing.T) {
+	t.Parallel()
+
+	config := RemoteStateConfigS3{Bucket: "my-bucket", Key: "prod/vpc/terraform.tfstate"}
+	assert.Equal(t, "my-bucket/prod/vpc/terraform.tfstate-md5", s3StateDigestLockID(&config))
+}
+
+func TestGetAssumeRole(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name     string
+		config   map[string]interface{}
+		expected RemoteStateConfigS3AssumeRole
+	}{
+		{
+			"no-role",
+			map[string]interface{}{"bucket": "foo"},
+			RemoteStateConfigS3AssumeRole{},
+		},
+		{
+			"top-level-attributes",
+			map[string]interface{}{"role_arn": "arn::it", "external_id": "my-id", "session_name": "my-session"},
+			RemoteStateConfigS3AssumeRole{RoleArn: "arn::it", ExternalID: "my-id", SessionName: "my-session"},
+		},
+		{
+			"assume-role-block-takes-precedence",
+			map[string]interface{}{"role_arn": "arn::old", "external_id": "old-id", "assume_role": map[string]interface{}{"role_arn": "arn::it", "duration": "15m"}},
+			RemoteStateConfigS3AssumeRole{RoleArn: "arn::it", Duration: "15m"},
+		},
+	}
+
+	for _, testCase := range testCases {
+		// Save the testCase in local scope so all the t.Run calls don't end up with the last item in the list
+		testCase := testCase
+
+		t.Run(testCase.name, func(t *testing.T) {
+			t.Parallel()
+
+			s3ConfigExtended, err := parseExtendedS3Config(testCase.config)
+			require.NoError(t, err)
+			assert.Equal(t, testCase.expected, s3ConfigExtended.remoteStateConfigS3.GetAssumeRole())
+		})
+	}
+}
+
+func TestGetEndpoints(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name     string
+		config   map[string]interface{}
+		expected RemoteStateConfigS3Endpoints
+	}{
+		{
+			"no-endpoints",
+			map[string]interface{}{"bucket": "foo"},
+			RemoteStateConfigS3Endpoints{},
+		},
+		{
+			"top-level-attributes",
+			map[string]interface{}{"endpoint": "http://s3", "dynamodb_endpoint": "http://dynamodb", "sts_endpoint": "http://sts", "iam_endpoint": "http://iam"},
+			RemoteStateConfigS3Endpoints{S3: "http://s3", DynamoDB: "http://dynamodb", STS: "http://sts", IAM: "http://iam"},
+		},
+		{
+			"endpoints-block-takes-precedence",
+			map[string]interface{}{"endpoint": "http://old-s3", "sts_endpoint": "http://sts", "endpoints": map[string]interface{}{"s3": "http://s3"}},
+			RemoteStateConfigS3Endpoints{S3: "http://s3", STS: "http://sts"},
+		},
+	}
+
+	for _, testCase := range testCases {
+		// Save the testCase in local scope so all the t.Run calls don't end up with the last item in the list
+		testCase := testCase
+
+		t.Run(testCase.name, func(t *testing.T) {
+			t.Parallel()
+
+			s3ConfigExtended, err := parseExtendedS3Config(testCase.config)
+			require.NoError(t, err)
+			assert.Equal(t, testCase.expected, s3ConfigExtended.remoteStateConfigS3.GetEndpoints())
+		})
+	}
+}
+
+func TestCheckIfSSEForS3MatchesConfigWithoutPermission(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		writer.WriteHeader(http.StatusForbidden)
+		writer.Write([]byte(`<Error><Code>AccessDenied</Code><Message>Access Denied</Message></Error>`))
+	}))
+	defer server.Close()
+
+	sess, err := session.NewSession(&aws.Config{
+		Region:           aws.String("us-east-1"),
+		Endpoint:         aws.String(server.URL),
+		S3ForcePathStyle: aws.Bool(true),
+		Credentials:      credentials.NewStaticCredentials("AKIAEXAMPLE", "secret", ""),
+		MaxRetries:       aws.Int(0),
+	})
+	require.NoError(t, err)
+	extendedConfig, err := parseExtendedS3Config(map[string]interface{}{"bucket": "state"})
+	require.NoError(t, err)
+	terragruntOptions, err := options.NewTerragruntOptionsForTest("remote_state_test")
+	require.NoError(t, err)
+
+	// Roles that can't read the encryption of the bucket only get a warning
+	assert.NoError(t, checkIfSSEForS3MatchesConfig(s3.New(sess), extendedConfig, terragruntOptions))
+}
@@ -1,9 +1,12 @@
 package remote
 
 import (
+	"io/ioutil"
+	"os"
 	"strings"
 	"testing"
 
+	"github.com/gruntwork-io/terragrunt/errors"
 	"github.com/gruntwork-io/terragrunt/options"
 	"github.com/stretchr/testify/assert"
 )
@@ -44,6 +47,27 @@ func TestToTerraformInitArgs(t *testing.T) {
 	assertTerraformInitArgsEqual(t, args, "-backend-config=encrypt=true -backend-config=bucket=my-bucket -backend-config=key=terraform.tfstate -backend-config=region=us-east-1 -backend-config=force_path_style=true -backend-config=shared_credentials_file=my-file")
 }
 
+func TestToTerraformInitArgsAssumeRole(t *testing.T) {
+	t.Parallel()
+
+	remoteState := RemoteState{
+		Backend: "s3",
+		Config: map[string]interface{}{
+			"bucket": "my-bucket",
+			"assume_role": map[string]interface{}{
+				"role_arn":     "arn:aws:iam::123456789012:role/state",
+				"external_id":  "my-id",
+				"duration":     "1h",
+				"transitive":   true,
+				"session_name": "my-session",
+			},
+		},
+	}
+	args := remoteState.ToTerraformInitArgs()
+
+	assertTerraformInitArgsEqual(t, args, `-backend-config=bucket=my-bucket -backend-config=assume_role={duration="1h",external_id="my-id",role_arn="arn:aws:iam::123456789012:role/state",session_name="my-session",transitive=true}`)
+}
+
 func TestToTerraformInitArgsForGCS(t *testing.T) {
 	t.Parallel()
 
@@ -271,6 +295,30 @@ func TestDiffersFrom(t *testing.T) {
 			},
 			false,
 		},
+		{
+			"nested null values ignored",
+			TerraformBackend{
+				Type:   "s3",
+				Config: map[string]interface{}{"bucket": "foo", "assume_role": map[string]interface{}{"role_arn": "arn::it", "duration": nil}},
+			},
+			RemoteState{
+				Backend: "s3",
+				Config:  map[string]interface{}{"bucket": "foo", "assume_role": map[string]interface{}{"role_arn": "arn::it"}},
+			},
+			false,
+		},
+		{
+			"different nested values",
+			TerraformBackend{
+				Type:   "s3",
+				Config: map[string]interface{}{"bucket": "foo", "assume_role": map[string]interface{}{"role_arn": "arn::it", "duration": nil}},
+			},
+			RemoteState{
+				Backend: "s3",
+				Config:  map[string]interface{}{"bucket": "foo", "assume_role": map[string]interface{}{"role_arn": "arn::other"}},
+			},
+			true,
+		},
 	}
 
 	for _, testCase := range testCases {
@@ -292,3 +340,190 @@ func assertTerraformInitArgsEqual(t *testing.T, actualArgs []string, expectedArg
 		assert.Contains(t, actualArgs, expectedArg)
 	}
 }
+
+func TestDeleteStateUnsupportedBackend(t *testing.T) {
+	t.Parallel()
+
+	terragruntOptions, err := options.NewTerragruntOptionsForTest("remote_state_test")
+	assert.Nil(t, err, "Unexpected error creating NewTerragruntOptionsForTest: %v", err)
+
+	for _, backend := range []string{"http", "consul"} {
+		remoteState := RemoteState{Backend: backend, Config: map[string]interface{}{"address": "https://example.com/state"}}
+		err := remoteState.DeleteState(terragruntOptions)
+		assert.Error(t, err)
+		_, isDeleteStateNotSupported := errors.Unwrap(err).(DeleteStateNotSupported)
+		assert.True(t, isDeleteStateNotSupported, "Unexpected error for backend %s: %v", backend, err)
+	}
+}
+
+func TestReadStateUnsupportedBackend(t *testing.T) {
+	t.Parallel()
+
+	terragruntOptions, err := options.NewTerragruntOptionsForTest("remote_state_test")
+	assert.Nil(t, err, "Unexpected error creating NewTerragruntOptionsForTest: %v", err)
+
+	for _, backend := range []string{"http", "azurerm"} {
+		remoteState := RemoteState{Backend: backend, Config: map[string]interface{}{"address": "https://example.com/state"}}
+		_, err := remoteState.ReadState("", terragruntOptions)
+		assert.Error(t, err)
+		_, isReadStateNotSupported := errors.Unwrap(err).(ReadStateNotSupported)
+		assert.True(t, isReadStateNotSupported, "Unexpected error for backend %s: %v", backend, err)
+	}
+}
+
+func TestValidateRemoteStateConfig(t *testing.T) {
+	t.Parallel()
+
+	s3Config := func(overrides map[string]interface{}) map[string]interface{} {
+		config := map[string]interface{}{"bucket": "my-bucket", "key": "terraform.tfstate", "region": "us-east-1", "encrypt": true}
+		for key, value := range overrides {
+			config[key] = value
+		}
+		return config
+	}
+
+	testCases := []struct {
+		name          string
+		remoteState   RemoteState
+		expectedError error
+	}{
+		{"s3-valid", RemoteState{Backend: "s3", Config: s3Config(nil)}, nil},
+		{"s3-govcloud-region", RemoteState{Backend: "s3", Config: s3Config(map[string]interface{}{"region": "us-gov-west-1"})}, nil},
+		{"s3-sovereign-cloud-region", RemoteState{Backend: "s3", Config: s3Config(map[string]interface{}{"region": "eusc-de-east-1"})}, nil},
+		{"s3-missing-bucket", RemoteState{Backend: "s3", Config: s3Config(map[string]interface{}{"bucket": ""})}, MissingRequiredS3RemoteStateConfig("bucket")},
+		{"s3-invalid-region", RemoteState{Backend: "s3", Config: s3Config(map[string]interface{}{"region": "us-east"})}, InvalidS3RemoteStateRegion{Name: "region", Region: "us-east"}},
+		{"s3-terragrunt-only-settings-not-validated", RemoteState{Backend: "s3", Config: s3Config(map[string]interface{}{"replica_bucket": "my-replica", "replica_region": "Frankfurt"})}, nil},
+		{"s3-skip-region-validation", RemoteState{Backend: "s3", Config: s3Config(map[string]interface{}{"region": "my-local-region", "skip_region_validation": true})}, nil},
+		{"s3-disable-init", RemoteState{Backend: "s3", DisableInit: true, Config: s3Config(map[string]interface{}{"bucket": ""})}, nil},
+		{"gcs-without-prefix", RemoteState{Backend: "gcs", Config: map[string]interface{}{"bucket": "my-bucket"}}, nil},
+		{"azurerm-valid", RemoteState{Backend: "azurerm", Config: map[string]interface{}{"storage_account_name": "mystorage", "container_name": "tfstate", "key": "terraform.tfstate"}}, nil},
+		{"azurerm-missing-container", RemoteState{Backend: "azurerm", Config: map[string]interface{}{"storage_account_name": "mystorage", "key": "terraform.tfstate"}}, MissingRequiredAzureRMRemoteStateConfig("container_name")},
+		{"azurerm-bootstrap-without-subscription", RemoteState{Backend: "azurerm", Config: map[string]interface{}{"storage_account_name": "mystorage", "container_name": "tfstate", "key": "terraform.tfstate", "bootstrap_storage_account": true}}, nil},
+		{"unknown-backend", RemoteState{Backend: "consul", Config: map[string]interface{}{}}, nil},
+	}
+
+	terragruntOptions, err := options.NewTerragruntOptionsForTest("remote_state_test")
+	assert.Nil(t, err, "Unexpected error creating NewTerragruntOptionsForTest: %v", err)
+
+	for _, testCase := range testCases {
+		// Save the testCase in local scope so all the t.Run calls don't end up with the last item in the list
+		testCase := testCase
+
+		t.Run(testCase.name, func(t *testing.T) {
+			t.Parallel()
+
+			err := testCase.remoteState.ValidateConfig(terragruntOptions)
+			if testCase.expectedError == nil {
+				assert.NoError(t, err)
+			} else {
+				assert.Equal(t, testCase.expectedError, errors.Unwrap(err))
+			}
+		})
+	}
+}
+
+func TestValidateRemoteStateConfigWrongType(t *testing.T) {
+	t.Parallel()
+
+	terragruntOptions, err := options.NewTerragruntOptionsForTest("remote_state_test")
+	assert.Nil(t, err, "Unexpected error creating NewTerragruntOptionsForTest: %v", err)
+
+	remoteState := RemoteState{Backend: "s3", Config: map[string]interface{}{"bucket": "my-bucket", "key": "terraform.tfstate", "region": "us-east-1", "encrypt": "yes"}}
+	err = remoteState.ValidateConfig(terragruntOptions)
+	assert.Error(t, err)
+	typeErr, isTypeErr := errors.Unwrap(err).(InvalidRemoteStateConfigType)
+	assert.True(t, isTypeErr, "Unexpected error: %v", err)
+	assert.Equal(t, "s3", typeErr.Backend)
+	assert.Contains(t, err.Error(), "encrypt")
+}
+
+func TestValidateStateEncryption(t *testing.T) {
+	t.Parallel()
+
+	generate := &RemoteStateGenerate{Path: "backend.tf", IfExists: "overwrite_terragrunt"}
+
+	testCases := []struct {
+		name          string
+		encryption    map[string]interface{}
+		generate      *RemoteStateGenerate
+		expectedError error
+	}{
+		{"valid", map[string]interface{}{"key_provider": "pbkdf2", "passphrase": "secret"}, generate, nil},
+		{"no-generate", map[string]interface{}{"key_provider": "pbkdf2", "passphrase": "secret"}, nil, EncryptionWithoutGenerate},
+		{"missing-key-provider", map[string]interface{}{"passphrase": "secret"}, generate, InvalidStateEncryptionSetting{Name: "key_provider", Expected: "a non-empty string"}},
+		{"invalid-enforced", map[string]interface{}{"key_provider": "pbkdf2", "enforced": "yes"}, generate, InvalidStateEncryptionSetting{Name: "enforced", Expected: "a bool"}},
+	}
+
+	for _, testCase := range testCases {
+		// Save the testCase in local scope so all the t.Run calls don't end up with the last item in the list
+		testCase := testCase
+
+		t.Run(testCase.name, func(t *testing.T) {
+			t.Parallel()
+
+			remoteState := RemoteState{Backend: "s3", Generate: testCase.generate, Encryption: testCase.encryption}
+			err := remoteState.Validate()
+			if testCase.expectedError == nil {
+				assert.NoError(t, err)
+			} else {
+				assert.Equal(t, testCase.expectedError, errors.Unwrap(err))
+			}
+		})
+	}
+}
+
+func TestParseStateEncryption(t *testing.T) {
+	t.Parallel()
+
+	encryption, err := parseStateEncryption(map[string]interface{}{
+		"key_provider": "aws_kms",
+		"kms_key_id":   "alias/terraform-state",
+		"region":       "us-east-1",
+		"key_spec":     "AES_256",
+		"enforced":     true,
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "aws_kms", encryption.KeyProvider)
+	assert.Equal(t, DEFAULT_STATE_ENCRYPTION_METHOD, encryption.Method)
+	assert.True(t, encryption.Enforced)
+	assert.Equal(t, map[string]interface{}{"kms_key_id": "alias/terraform-state", "region": "us-east-1", "key_spec": "AES_256"}, encryption.KeyProviderConfig)
+}
+
+func TestGenerateStateEncryptionRequiresOpenTofu(t *testing.T) {
+	t.Parallel()
+
+	workingDir, err := ioutil.TempDir("", "state-encryption")
+	assert.NoError(t, err)
+	defer os.RemoveAll(workingDir)
+
+	remoteState := RemoteState{
+		Backend:    "local",
+		Generate:   &RemoteStateGenerate{Path: "backend.tf", IfExists: "overwrite_terragrunt"},
+		Config:     map[string]interface{}{"path": "terraform.tfstate"},
+		Encryption: map[string]interface{}{"key_provider": "pbkdf2", "passphrase": "correct-horse-battery-staple"},
+	}
+
+	testCases := []struct {
+		implementation options.TerraformImplementationType
+		expectError    bool
+	}{
+		{options.TerraformImpl, true},
+		{options.OpenTofuImpl, false},
+		{options.UnknownImpl, false},
+	}
+
+	for _, testCase := range testCases {
+		terragruntOptions, err := options.NewTerragruntOptionsForTest("mock-path-for-test.hcl")
+		assert.NoError(t, err)
+		terragruntOptions.WorkingDir = workingDir
+		terragruntOptions.TerraformImplementation = testCase.implementation
+
+		err = remoteState.GenerateTerraformCode(terragruntOptions)
+		if testCase.expectError {
+			_, isNotSupported := errors.Unwrap(err).(StateEncryptionNotSupported)
+			assert.True(t, isNotSupported, "Unexpected error for %s: %v", testCase.implementation, err)
+		} else {
+			assert.NoError(t, err, "Unexpected error for %s", testCase.implementation)
+		}
+	}
+}
@@ -0,0 +1,313 @@
+package remote
+
+import (
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"strings"
+
+	"github.com/gruntwork-io/terragrunt/errors"
+	"github.com/gruntwork-io/terragrunt/options"
+	"github.com/gruntwork-io/terragrunt/tfc"
+	"github.com/gruntwork-io/terragrunt/util"
+	"github.com/mitchellh/mapstructure"
+)
+
+// The backend types of Terraform Cloud / Terraform Enterprise: the remote backend, and the cloud block that replaces it
+// in terraform 1.1 and above. The cloud block is rendered as a top level block of the terraform block, rather than as a
+// backend block.
+const (
+	TFC_REMOTE_BACKEND = "remote"
+	TFC_CLOUD_BACKEND  = "cloud"
+)
+
+// The hostname of Terraform Cloud, which is used when no hostname is set
+const DEFAULT_TFC_HOSTNAME = "app.terraform.io"
+
+// Terraform Cloud workspace names can only contain letters, numbers, dashes and underscores
+var tfcWorkspaceNameRegexp = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+// The characters of a path that can't be in a Terraform Cloud workspace name
+var tfcWorkspaceNameInvalidCharsRegexp = regexp.MustCompile(`[^A-Za-z0-9_-]`)
+
+// These are settings that can appear in the remote_state config of the remote backend and the cloud block that are ONLY
+// used by Terragrunt, to derive the workspace name of each unit, and NOT forwarded to terraform.
+var terragruntTFCOnlyConfigs = []string{
+	"derive_workspace_name",
+	"derived_workspace_name_prefix",
+}
+
+// A representation of the configuration options available for the remote backend and the cloud block
+type RemoteStateConfigTFC struct {
+	Hostname     string                         `mapstructure:"hostname"`
+	Organization string                         `mapstructure:"organization"`
+	Token        string                         `mapstructure:"token"`
+	Workspaces   RemoteStateConfigTFCWorkspaces `mapstructure:"workspaces"`
+}
+
+// The workspaces block of the remote backend and the cloud block. The remote backend selects the workspaces with either
+// name or prefix, while the cloud block selects them with either name or tags.
+type RemoteStateConfigTFCWorkspaces struct {
+	Name    string   `mapstructure:"name"`
+	Prefix  string   `mapstructure:"prefix"`
+	Tags    []string `mapstructure:"tags"`
+	Project string   `mapstructure:"project"`
+}
+
+// Terraform Cloud creates the workspaces when terraform init is run, so there is nothing to bootstrap: this
+// initializer only validates the config and detects when it has changed. As the cloud block doesn't support
+// -backend-config, it can only be configured with generate. The remote backend can also be configured with
+// -backend-config, e.g. with the organization and token, in which case its workspaces block is in the terraform code.
+type TFCInitializer struct {
+	backend string
+}
+
+// Returns true if the backend type, the hostname, the organization or the workspaces have changed
+func (tfcInitializer TFCInitializer) NeedsInitialization(remoteState *RemoteState, existingBackend *TerraformBackend, terragruntOptions *options.TerragruntOptions) (bool, error) {
+	if remoteState.DisableInit {
+		return false, nil
+	}
+
+	if existingBackend == nil {
+		return true, nil
+	}
+
+	if existingBackend.Type != tfcInitializer.backend {
+		terragruntOptions.Logger.Debugf("Backend type has changed from %s to %s", existingBackend.Type, tfcInitializer.backend)
+		return true, nil
+	}
+
+	// With -backend-config, the other settings, such as the workspaces block, are in the terraform code, so only the
+	// settings in the config are compared
+	if remoteState.Generate == nil {
+		for key, value := range tfcInitializer.GetTerraformInitArgs(remoteState.Config) {
+			if !reflect.DeepEqual(existingBackend.Config[key], value) {
+				terragruntOptions.Logger.Debugf("Backend config %s of the %s backend has changed", key, tfcInitializer.backend)
+				return true, nil
+			}
+		}
+		return false, nil
+	}
+
+	tfcConfig, err := parseTFCConfig(tfcInitializer.backend, remoteState.Config)
+	if err != nil {
+		return false, err
+	}
+	existingTFCConfig, err := parseTFCConfig(tfcInitializer.backend, existingBackend.Config)
+	if err != nil {
+		return false, err
+	}
+
+	// The token is left out of the comparison, as it is usually read from the credentials file or env vars instead
+	tfcConfig.Token = ""
+	existingTFCConfig.Token = ""
+	if !reflect.DeepEqual(tfcConfig, existingTFCConfig) {
+		terragruntOptions.Logger.Debugf("Backend config of the %s backend has changed from %v to %v", tfcInitializer.backend, *existingTFCConfig, *tfcConfig)
+		return true, nil
+	}
+
+	return false, nil
+}
+
+// Validate the Terraform Cloud config. There are no resources to create for these backends.
+func (tfcInitializer TFCInitializer) Initialize(remoteState *RemoteState, terragruntOptions *options.TerragruntOptions) error {
+	tfcConfig, err := parseTFCConfig(tfcInitializer.backend, remoteState.Config)
+	if err != nil {
+		return err
+	}
+
+	if err := validateTFCConfig(tfcInitializer.backend, tfcConfig, remoteState.Generate != nil); err != nil {
+		return err
+	}
+
+	if tfcConfig.Token != "" {
+		terragruntOptions.Logger.Warnf("The token of the %s backend is set in the remote_state config, so it will be written to the generated backend file. Consider setting the %s env var instead.", tfcInitializer.backend, tfc.HostTokenEnvVar(tfcConfig.Hostname))
+	}
+
+	return nil
+}
+
+// Validate the Terraform Cloud config
+func (tfcInitializer TFCInitializer) ValidateConfig(remoteState *RemoteState, terragruntOptions *options.TerragruntOptions) error {
+	tfcConfig, err := parseTFCConfig(tfcInitializer.backend, remoteState.Config)
+	if err != nil {
+		return err
+	}
+
+	return validateTFCConfig(tfcInitializer.backend, tfcConfig, remoteState.Generate != nil)
+}
+
+func (tfcInitializer TFCInitializer) GetTerraformInitArgs(config map[string]interface{}) map[string]interface{} {
+	var filteredConfig = make(map[string]interface{})
+
+	for key, val := range config {
+		if util.ListContainsElement(terragruntTFCOnlyConfigs, key) {
+			continue
+		}
+
+		filteredConfig[key] = val
+	}
+
+	return filteredConfig
+}
+
+// Parse the given map into a Terraform Cloud config
+func parseTFCConfig(backend string, config map[string]interface{}) (*RemoteStateConfigTFC, error) {
+	// terraform stores the workspaces block of the remote backend as a list with a single element in the state file
+	normalizedConfig := make(map[string]interface{}, len(config))
+	for key, value := range config {
+		if workspaces, isList := value.([]interface{}); isList && key == "workspaces" && len(workspaces) == 1 {
+			value = workspaces[0]
+		}
+		normalizedConfig[key] = value
+	}
+
+	var tfcConfig RemoteStateConfigTFC
+	if err := mapstructure.Decode(normalizedConfig, &tfcConfig); err != nil {
+		return nil, errors.WithStackTrace(err)
+	}
+
+	// Like terraform, fall back to the TF_CLOUD_* env vars for the settings of the cloud block that aren't in the config
+	if backend == TFC_CLOUD_BACKEND {
+		tfcConfig.Hostname = valueOrEnv(tfcConfig.Hostname, "TF_CLOUD_HOSTNAME")
+		tfcConfig.Organization = valueOrEnv(tfcConfig.Organization, "TF_CLOUD_ORGANIZATION")
+		tfcConfig.Workspaces.Project = valueOrEnv(tfcConfig.Workspaces.Project, "TF_CLOUD_PROJECT")
+	}
+
+	if tfcConfig.Hostname == "" {
+		tfcConfig.Hostname = DEFAULT_TFC_HOSTNAME
+	}
+	// terraform stores unset tags as null, so treat an empty list the same way when comparing the configs
+	if len(tfcConfig.Workspaces.Tags) == 0 {
+		tfcConfig.Workspaces.Tags = nil
+	}
+
+	return &tfcConfig, nil
+}
+
+// Validate all the parameters of the given Terraform Cloud configuration. When the config isn't generated, i.e. the
+// remote backend is configured with -backend-config, the organization and workspaces may be in the terraform code, so
+// they're not required.
+func validateTFCConfig(backend string, config *RemoteStateConfigTFC, generated bool) error {
+	if !generated {
+		return nil
+	}
+
+	if config.Organization == "" {
+		return errors.WithStackTrace(MissingRequiredTFCRemoteStateConfig{Backend: backend, Name: "organization"})
+	}
+
+	workspaces := config.Workspaces
+	switch backend {
+	case TFC_REMOTE_BACKEND:
+		if (workspaces.Name == "") == (workspaces.Prefix == "") {
+			return errors.WithStackTrace(InvalidTFCWorkspacesConfig{Backend: backend, Expected: "exactly one of workspaces.name or workspaces.prefix"})
+		}
+	case TFC_CLOUD_BACKEND:
+		if workspaces.Prefix != "" {
+			return errors.WithStackTrace(InvalidTFCWorkspacesConfig{Backend: backend, Expected: "workspaces.name or workspaces.tags, as workspaces.prefix is only supported by the remote backend"})
+		}
+		if workspaces.Name != "" && len(workspaces.Tags) > 0 {
+			return errors.WithStackTrace(InvalidTFCWorkspacesConfig{Backend: backend, Expected: "only one of workspaces.name or workspaces.tags"})
+		}
+	}
+
+	if workspaces.Name != "" && !tfcWorkspaceNameRegexp.MatchString(workspaces.Name) {
+		return errors.WithStackTrace(InvalidTFCWorkspaceName(workspaces.Name))
+	}
+
+	return nil
+}
+
+// ParseTFCWorkspace returns the Terraform Cloud config of the given remote state, which must use the remote backend or
+// the cloud block, and select a single workspace by name, so that runs can be created in the workspace through the API
+func ParseTFCWorkspace(remoteState *RemoteState) (*RemoteStateConfigTFC, error) {
+	if remoteState == nil || (remoteState.Backend != TFC_REMOTE_BACKEND && remoteState.Backend != TFC_CLOUD_BACKEND) {
+		return nil, errors.WithStackTrace(NotTFCBackend{})
+	}
+
+	tfcConfig, err := parseTFCConfig(remoteState.Backend, remoteState.Config)
+	if err != nil {
+		return nil, err
+	}
+	if err := validateTFCConfig(remoteState.Backend, tfcConfig, true); err != nil {
+		return nil, err
+	}
+	if tfcConfig.Workspaces.Name == "" {
+		return nil, errors.WithStackTrace(InvalidTFCWorkspacesConfig{Backend: remoteState.Backend, Expected: "workspaces.name to run in Terraform Cloud, as runs are created in a single workspace"})
+	}
+	return tfcConfig, nil
+}
+
+// DeriveTFCWorkspaceName sets workspaces.name in the config of the given remote state, if it uses the remote backend or
+// the cloud block with derive_workspace_name set, to the given path of the unit relative to the included config, e.g.
+// prod/vpc, with the slashes replaced with dashes and the other characters workspace names can't contain with
+// underscores, after derived_workspace_name_prefix, if any. E.g. prod/vpc becomes prod-vpc, so that each unit gets its
+// own workspace without setting its name.
+func DeriveTFCWorkspaceName(remoteState *RemoteState, unitPath string) error {
+	if remoteState == nil || (remoteState.Backend != TFC_REMOTE_BACKEND && remoteState.Backend != TFC_CLOUD_BACKEND) {
+		return nil
+	}
+	if derive, _ := remoteState.Config["derive_workspace_name"].(bool); !derive {
+		return nil
+	}
+
+	workspaces := map[string]interface{}{}
+	if existingWorkspaces, isMap := remoteState.Config["workspaces"].(map[string]interface{}); isMap {
+		for key, value := range existingWorkspaces {
+			workspaces[key] = value
+		}
+	}
+	for _, key := range []string{"name", "prefix", "tags"} {
+		if value, isSet := workspaces[key]; isSet && value != nil {
+			return errors.WithStackTrace(InvalidTFCWorkspacesConfig{Backend: remoteState.Backend, Expected: "neither workspaces.name, workspaces.prefix nor workspaces.tags with derive_workspace_name, which sets workspaces.name"})
+		}
+	}
+
+	prefix, _ := remoteState.Config["derived_workspace_name_prefix"].(string)
+	unitPath = strings.ReplaceAll(filepath.ToSlash(unitPath), "/", "-")
+	workspaces["name"] = prefix + tfcWorkspaceNameInvalidCharsRegexp.ReplaceAllString(unitPath, "_")
+
+	// The config may be shared with the other units that include the same config, so it's copied rather than updated
+	config := make(map[string]interface{}, len(remoteState.Config))
+	for key, value := range remoteState.Config {
+		config[key] = value
+	}
+	config["workspaces"] = workspaces
+	remoteState.Config = config
+
+	return nil
+}
+
+// Custom error types
+
+type MissingRequiredTFCRemoteStateConfig struct {
+	Backend string
+	Name    string
+}
+
+func (err MissingRequiredTFCRemoteStateConfig) Error() string {
+	return fmt.Sprintf("Missing required %s remote state configuration %s", err.Backend, err.Name)
+}
+
+type InvalidTFCWorkspacesConfig struct {
+	Backend  string
+	Expected string
+}
+
+func (err InvalidTFCWorkspacesConfig) Error() string {
+	return fmt.Sprintf("The workspaces of the %s remote state configuration must set %s", err.Backend, err.Expected)
+}
+
+type NotTFCBackend struct{}
+
+func (err NotTFCBackend) Error() string {
+	return fmt.Sprintf("Running in Terraform Cloud requires a remote_state block with the %s backend or the %s block, to select the workspace to run in", TFC_REMOTE_BACKEND, TFC_CLOUD_BACKEND)
+}
+
+type InvalidTFCWorkspaceName string
+
+func (name InvalidTFCWorkspaceName) Error() string {
+	return fmt.Sprintf("%s is not a valid Terraform Cloud workspace name, as it can only contain letters, numbers, dashes and underscores. To derive the name from the path of the module, use replace(path_relative_to_include(), \"/\", \"-\").", string(name))
+}
@@ -0,0 +1,212 @@
+package remote
+
+import (
+	"testing"
+
+	"github.com/gruntwork-io/terragrunt/errors"
+	"github.com/gruntwork-io/terragrunt/options"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTFCNeedsInitialization(t *testing.T) {
+	t.Parallel()
+
+	terragruntOptions, err := options.NewTerragruntOptionsForTest("remote_state_test")
+	require.NoError(t, err)
+
+	config := map[string]interface{}{"organization": "my-org", "workspaces": map[string]interface{}{"name": "prod-vpc"}}
+
+	testCases := []struct {
+		name             string
+		backend          string
+		existingBackend  *TerraformBackend
+		shouldInitialize bool
+	}{
+		{
+			"remote-unchanged",
+			TFC_REMOTE_BACKEND,
+			&TerraformBackend{Type: "remote", Config: map[string]interface{}{"hostname": "app.terraform.io", "organization": "my-org", "token": nil, "workspaces": []interface{}{map[string]interface{}{"name": "prod-vpc", "prefix": nil}}}},
+			false,
+		},
+		{
+			"cloud-unchanged",
+			TFC_CLOUD_BACKEND,
+			&TerraformBackend{Type: "cloud", Config: map[string]interface{}{"hostname": nil, "organization": "my-org", "token": nil, "workspaces": map[string]interface{}{"name": "prod-vpc", "tags": nil, "project": nil}}},
+			false,
+		},
+		{
+			"workspace-changed",
+			TFC_CLOUD_BACKEND,
+			&TerraformBackend{Type: "cloud", Config: map[string]interface{}{"organization": "my-org", "workspaces": map[string]interface{}{"name": "stage-vpc"}}},
+			true,
+		},
+		{
+			"backend-type-changed",
+			TFC_CLOUD_BACKEND,
+			&TerraformBackend{Type: "s3", Config: map[string]interface{}{"bucket": "my-bucket"}},
+			true,
+		},
+		{
+			"not-initialized",
+			TFC_CLOUD_BACKEND,
+			nil,
+			true,
+		},
+	}
+
+	for _, testCase := range testCases {
+		// Save the testCase in local scope so all the t.Run calls don't end up with the last item in the list
+		testCase := testCase
+
+		t.Run(testCase.name, func(t *testing.T) {
+			t.Parallel()
+
+			remoteState := &RemoteState{Backend: testCase.backend, Config: config, Generate: &RemoteStateGenerate{Path: "backend.tf", IfExists: "overwrite_terragrunt"}}
+			actual, err := TFCInitializer{backend: testCase.backend}.NeedsInitialization(remoteState, testCase.existingBackend, terragruntOptions)
+			require.NoError(t, err)
+			assert.Equal(t, testCase.shouldInitialize, actual)
+		})
+	}
+}
+
+func TestValidateTFCConfig(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name          string
+		backend       string
+		config        map[string]interface{}
+		expectedError error
+	}{
+		{"cloud-name", TFC_CLOUD_BACKEND, map[string]interface{}{"organization": "my-org", "workspaces": map[string]interface{}{"name": "prod-vpc"}}, nil},
+		{"cloud-tags", TFC_CLOUD_BACKEND, map[string]interface{}{"organization": "my-org", "workspaces": map[string]interface{}{"tags": []interface{}{"vpc"}}}, nil},
+		{"remote-prefix", TFC_REMOTE_BACKEND, map[string]interface{}{"organization": "my-org", "workspaces": map[string]interface{}{"prefix": "vpc-"}}, nil},
+		{"remote-no-workspaces", TFC_REMOTE_BACKEND, map[string]interface{}{"organization": "my-org"}, InvalidTFCWorkspacesConfig{Backend: TFC_REMOTE_BACKEND, Expected: "exactly one of workspaces.name or workspaces.prefix"}},
+		{"cloud-prefix", TFC_CLOUD_BACKEND, map[string]interface{}{"organization": "my-org", "workspaces": map[string]interface{}{"prefix": "vpc-"}}, InvalidTFCWorkspacesConfig{Backend: TFC_CLOUD_BACKEND, Expected: "workspaces.name or workspaces.tags, as workspaces.prefix is only supported by the remote backend"}},
+		{"invalid-name", TFC_CLOUD_BACKEND, map[string]interface{}{"organization": "my-org", "workspaces": map[string]interface{}{"name": "prod/vpc"}}, InvalidTFCWorkspaceName("prod/vpc")},
+		{"missing-organization", TFC_REMOTE_BACKEND, map[string]interface{}{"workspaces": map[string]interface{}{"name": "prod-vpc"}}, MissingRequiredTFCRemoteStateConfig{Backend: TFC_REMOTE_BACKEND, Name: "organization"}},
+	}
+
+	for _, testCase := range testCases {
+		// Save the testCase in local scope so all the t.Run calls don't end up with the last item in the list
+		testCase := testCase
+
+		t.Run(testCase.name, func(t *testing.T) {
+			t.Parallel()
+
+			tfcConfig, err := parseTFCConfig(testCase.backend, testCase.config)
+			require.NoError(t, err)
+
+			err = validateTFCConfig(testCase.backend, tfcConfig, true)
+			if testCase.expectedError == nil {
+				assert.NoError(t, err)
+			} else {
+				assert.Equal(t, testCase.expectedError, errors.Unwrap(err))
+			}
+		})
+	}
+}
+
+func TestTFCRemoteBackendWithBackendConfigNeedsInitialization(t *testing.T) {
+	t.Parallel()
+
+	terragruntOptions, err := options.NewTerragruntOptionsForTest("remote_state_test")
+	require.NoError(t, err)
+
+	// The workspaces block is in the terraform code, so it's only in the existing config
+	remoteState := &RemoteState{Backend: TFC_REMOTE_BACKEND, Config: map[string]interface{}{"organization": "my-org"}}
+	existingBackend := &TerraformBackend{Type: "remote", Config: map[string]interface{}{"hostname": nil, "organization": "my-org", "token": nil, "workspaces": []interface{}{map[string]interface{}{"name": "prod-vpc", "prefix": nil}}}}
+
+	actual, err := TFCInitializer{backend: TFC_REMOTE_BACKEND}.NeedsInitialization(remoteState, existingBackend, terragruntOptions)
+	require.NoError(t, err)
+	assert.False(t, actual)
+
+	existingBackend.Config["organization"] = "other-org"
+	actual, err = TFCInitializer{backend: TFC_REMOTE_BACKEND}.NeedsInitialization(remoteState, existingBackend, terragruntOptions)
+	require.NoError(t, err)
+	assert.True(t, actual)
+}
+
+func TestTFCCloudBackendRequiresGenerate(t *testing.T) {
+	t.Parallel()
+
+	remoteState := RemoteState{Backend: TFC_CLOUD_BACKEND, Config: map[string]interface{}{"organization": "my-org"}}
+	assert.Equal(t, TFCBackendWithoutGenerate(TFC_CLOUD_BACKEND), errors.Unwrap(remoteState.Validate()))
+
+	remoteState.Generate = &RemoteStateGenerate{Path: "backend.tf", IfExists: "overwrite_terragrunt"}
+	assert.NoError(t, remoteState.Validate())
+}
+
+func TestTFCRemoteBackendWithoutGenerate(t *testing.T) {
+	t.Parallel()
+
+	remoteState := RemoteState{Backend: TFC_REMOTE_BACKEND, Config: map[string]interface{}{"organization": "my-org"}}
+	assert.NoError(t, remoteState.Validate())
+
+	remoteState.Config["workspaces"] = map[string]interface{}{"name": "prod-vpc"}
+	assert.Equal(t, TFCWorkspacesWithoutGenerate("workspaces"), errors.Unwrap(remoteState.Validate()))
+
+	remoteState.Generate = &RemoteStateGenerate{Path: "backend.tf", IfExists: "overwrite_terragrunt"}
+	assert.NoError(t, remoteState.Validate())
+}
+
+func TestDeriveTFCWorkspaceName(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name     string
+		config   map[string]interface{}
+		unitPath string
+		expected interface{}
+	}{
+		{"not-enabled", map[string]interface{}{"organization": "my-org"}, "prod/vpc", nil},
+		{"derived", map[string]interface{}{"derive_workspace_name": true}, "prod/vpc", map[string]interface{}{"name": "prod-vpc"}},
+		{"prefixed", map[string]interface{}{"derive_workspace_name": true, "derived_workspace_name_prefix": "app-"}, "prod/us-east-1/vpc", map[string]interface{}{"name": "app-prod-us-east-1-vpc"}},
+		{"invalid-chars", map[string]interface{}{"derive_workspace_name": true}, "prod/my.vpc", map[string]interface{}{"name": "prod-my_vpc"}},
+		{"with-project", map[string]interface{}{"derive_workspace_name": true, "workspaces": map[string]interface{}{"project": "networking"}}, "vpc", map[string]interface{}{"name": "vpc", "project": "networking"}},
+	}
+
+	for _, testCase := range testCases {
+		// Save the testCase in local scope so all the t.Run calls don't end up with the last item in the list
+		testCase := testCase
+
+		t.Run(testCase.name, func(t *testing.T) {
+			t.Parallel()
+
+			remoteState := &RemoteState{Backend: TFC_CLOUD_BACKEND, Config: testCase.config}
+			require.NoError(t, DeriveTFCWorkspaceName(remoteState, testCase.unitPath))
+			assert.Equal(t, testCase.expected, remoteState.Config["workspaces"])
+			// The original config, which may be shared by the units including it, isn't updated
+			if originalWorkspaces, isMap := testCase.config["workspaces"].(map[string]interface{}); isMap {
+				assert.NotContains(t, originalWorkspaces, "name")
+			}
+		})
+	}
+}
+
+func TestDeriveTFCWorkspaceNameWithWorkspaceName(t *testing.T) {
+	t.Parallel()
+
+	remoteState := &RemoteState{Backend: TFC_CLOUD_BACKEND, Config: map[string]interface{}{"derive_workspace_name": true, "workspaces": map[string]interface{}{"name": "prod-vpc"}}}
+	err := DeriveTFCWorkspaceName(remoteState, "prod/vpc")
+	assert.IsType(t, InvalidTFCWorkspacesConfig{}, errors.Unwrap(err))
+}
+
+func TestParseTFCWorkspace(t *testing.T) {
+	t.Parallel()
+
+	tfcConfig, err := ParseTFCWorkspace(&RemoteState{Backend: TFC_CLOUD_BACKEND, Config: map[string]interface{}{"organization": "my-org", "workspaces": map[string]interface{}{"name": "prod-vpc"}}})
+	require.NoError(t, err)
+	assert.Equal(t, DEFAULT_TFC_HOSTNAME, tfcConfig.Hostname)
+	assert.Equal(t, "my-org", tfcConfig.Organization)
+	assert.Equal(t, "prod-vpc", tfcConfig.Workspaces.Name)
+
+	_, err = ParseTFCWorkspace(&RemoteState{Backend: TFC_CLOUD_BACKEND, Config: map[string]interface{}{"organization": "my-org", "workspaces": map[string]interface{}{"tags": []interface{}{"vpc"}}}})
+	assert.IsType(t, InvalidTFCWorkspacesConfig{}, errors.Unwrap(err))
+
+	for _, remoteState := range []*RemoteState{nil, {Backend: "s3", Config: map[string]interface{}{"bucket": "my-bucket"}}} {
+		_, err = ParseTFCWorkspace(remoteState)
+		assert.Equal(t, NotTFCBackend{}, errors.Unwrap(err))
+	}
+}
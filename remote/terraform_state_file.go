@@ -83,6 +83,39 @@ func parseTerraformState(terraformStateData []byte) (*TerraformState, error) {
 	return terraformState, nil
 }
 
+// The outputs of the root module in the given Terraform state data, in the format of `terraform output -json`. Both the
+// format of Terraform 0.12 and newer, where the outputs are at the top level, and the older format, where they are in
+// the root module, are supported. Returns an empty JSON object if there is no state.
+func TerraformStateOutputsJson(terraformStateData []byte) ([]byte, error) {
+	if len(terraformStateData) == 0 {
+		return []byte("{}"), nil
+	}
+
+	var state struct {
+		Outputs map[string]json.RawMessage
+		Modules []struct {
+			Path    []string
+			Outputs map[string]json.RawMessage
+		}
+	}
+	if err := json.Unmarshal(terraformStateData, &state); err != nil {
+		return nil, errors.WithStackTrace(err)
+	}
+
+	outputs := state.Outputs
+	for _, module := range state.Modules {
+		if outputs == nil && len(module.Path) == 1 && module.Path[0] == "root" {
+			outputs = module.Outputs
+		}
+	}
+	if outputs == nil {
+		outputs = map[string]json.RawMessage{}
+	}
+
+	outputsJson, err := json.Marshal(outputs)
+	return outputsJson, errors.WithStackTrace(err)
+}
+
 type CantParseTerraformStateFile struct {
 	Path          string
 	UnderlyingErr error
@@ -319,3 +319,47 @@ func TestParseTerraformStateInvalid(t *testing.T) {
 	_, isSyntaxErr := underlyingErr.(*json.SyntaxError)
 	assert.True(t, isSyntaxErr)
 }
+
+func TestTerraformStateOutputsJson(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name      string
+		stateData string
+		expected  string
+	}{
+		{
+			"terraform-0.12-and-newer",
+			`{"version": 4, "outputs": {"vpc_id": {"value": "vpc-123", "type": "string"}, "password": {"value": "hunter2", "type": "string", "sensitive": true}}, "resources": []}`,
+			`{"password": {"value": "hunter2", "type": "string", "sensitive": true}, "vpc_id": {"value": "vpc-123", "type": "string"}}`,
+		},
+		{
+			"terraform-0.11-and-older",
+			`{"version": 3, "modules": [{"path": ["root", "vpc"], "outputs": {"id": {"value": "nested"}}}, {"path": ["root"], "outputs": {"vpc_id": {"sensitive": false, "type": "string", "value": "vpc-123"}}}]}`,
+			`{"vpc_id": {"sensitive": false, "type": "string", "value": "vpc-123"}}`,
+		},
+		{
+			"no-outputs",
+			`{"version": 4, "resources": []}`,
+			`{}`,
+		},
+		{
+			"no-state",
+			``,
+			`{}`,
+		},
+	}
+
+	for _, testCase := range testCases {
+		// Save the testCase in local scope so all the t.Run calls don't end up with the last item in the list
+		testCase := testCase
+
+		t.Run(testCase.name, func(t *testing.T) {
+			t.Parallel()
+
+			actual, err := TerraformStateOutputsJson([]byte(testCase.stateData))
+			assert.NoError(t, err)
+			assert.JSONEq(t, testCase.expected, string(actual))
+		})
+	}
+}
@@ -0,0 +1,174 @@
+// Package sarif writes the findings of the commands that validate the Terragrunt configuration, such as
+// validate-inputs, as a SARIF log, the format code scanning tools such as GitHub code scanning and the security
+// dashboards of GitLab read, so that the findings show up as annotations on the files of pull requests. See
+// https://docs.oasis-open.org/sarif/sarif/v2.1.0/sarif-v2.1.0.html
+package sarif
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+
+	"github.com/gruntwork-io/terragrunt/errors"
+	"github.com/gruntwork-io/terragrunt/util"
+)
+
+// The version of SARIF the log is written in, and its JSON schema
+const (
+	sarifVersion = "2.1.0"
+	sarifSchema  = "https://json.schemastore.org/sarif-2.1.0.json"
+)
+
+// The tool the findings are reported by
+const (
+	toolName           = "terragrunt"
+	toolInformationUri = "https://terragrunt.gruntwork.io"
+)
+
+// The URI base the paths of the files are relative to, which code scanning tools map to the root of the repo
+const srcRootUriBaseId = "%SRCROOT%"
+
+// The severities of the findings
+type Level string
+
+const (
+	LevelError   Level = "error"
+	LevelWarning Level = "warning"
+	LevelNote    Level = "note"
+)
+
+// Rule is a kind of finding, such as an unused input
+type Rule struct {
+	ID          string
+	Description string
+	HelpUri     string
+}
+
+// Result is a finding about a file. The lines and columns start at 1, and are left out of the log if they're 0.
+type Result struct {
+	Rule    Rule
+	Level   Level
+	Message string
+
+	// The path of the file the finding is about
+	File        string
+	StartLine   int
+	StartColumn int
+	EndLine     int
+	EndColumn   int
+}
+
+// Report collects the results of the commands run in all the units, and writes them as a SARIF log once the command
+// finishes. All the methods of Report can be called on a nil report, which is what is used when SARIF output is
+// disabled, so that the validation commands don't need to check whether it is.
+type Report struct {
+	rootDir     string
+	toolVersion string
+
+	mutex   sync.Mutex
+	results []Result
+}
+
+// Create a report whose files are identified by their path relative to the given root dir, usually the root of the
+// repo, with the given version of Terragrunt as the version of the tool
+func NewReport(rootDir string, toolVersion string) *Report {
+	return &Report{rootDir: rootDir, toolVersion: toolVersion}
+}
+
+// Add the given result to the report
+func (report *Report) Add(result Result) {
+	if report == nil {
+		return
+	}
+	report.mutex.Lock()
+	defer report.mutex.Unlock()
+	report.results = append(report.results, result)
+}
+
+// Write the results added so far as a SARIF log to the given file. The results are sorted by file, position and message,
+// so that the log is the same no matter the order the units ran in.
+func (report *Report) Write(path string) error {
+	if report == nil {
+		return nil
+	}
+
+	report.mutex.Lock()
+	results := append([]Result{}, report.results...)
+	report.mutex.Unlock()
+
+	sort.SliceStable(results, func(i, j int) bool {
+		if results[i].File != results[j].File {
+			return results[i].File < results[j].File
+		}
+		if results[i].StartLine != results[j].StartLine {
+			return results[i].StartLine < results[j].StartLine
+		}
+		return results[i].Message < results[j].Message
+	})
+
+	contents, err := json.MarshalIndent(report.log(results), "", "  ")
+	if err != nil {
+		return errors.WithStackTrace(err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+		return errors.WithStackTrace(err)
+	}
+	return errors.WithStackTrace(ioutil.WriteFile(path, append(contents, '\n'), 0644))
+}
+
+// Convert the given results to a SARIF log with a single run, whose tool lists the rules of the results
+func (report *Report) log(results []Result) sarifLog {
+	driver := sarifDriver{Name: toolName, Version: report.toolVersion, InformationUri: toolInformationUri, Rules: []sarifRule{}}
+	ruleIndexes := map[string]int{}
+	sarifResults := []sarifResult{}
+
+	for _, result := range results {
+		ruleIndex, hasRule := ruleIndexes[result.Rule.ID]
+		if !hasRule {
+			ruleIndex = len(driver.Rules)
+			ruleIndexes[result.Rule.ID] = ruleIndex
+			driver.Rules = append(driver.Rules, sarifRule{
+				ID:               result.Rule.ID,
+				ShortDescription: sarifMessage{Text: result.Rule.Description},
+				HelpUri:          result.Rule.HelpUri,
+			})
+		}
+
+		var region *sarifRegion
+		if result.StartLine > 0 {
+			region = &sarifRegion{StartLine: result.StartLine, StartColumn: result.StartColumn, EndLine: result.EndLine, EndColumn: result.EndColumn}
+		}
+		sarifResults = append(sarifResults, sarifResult{
+			RuleID:    result.Rule.ID,
+			RuleIndex: ruleIndex,
+			Level:     string(result.Level),
+			Message:   sarifMessage{Text: result.Message},
+			Locations: []sarifLocation{{
+				PhysicalLocation: sarifPhysicalLocation{
+					ArtifactLocation: report.artifactLocation(result.File),
+					Region:           region,
+				},
+			}},
+		})
+	}
+
+	return sarifLog{
+		Schema:  sarifSchema,
+		Version: sarifVersion,
+		Runs:    []sarifRun{{Tool: sarifTool{Driver: driver}, Results: sarifResults}},
+	}
+}
+
+// The files in the root dir are identified by their path relative to it, so that code scanning tools can match them to
+// the files of the repo. The other files are identified by their absolute path.
+func (report *Report) artifactLocation(path string) sarifArtifactLocation {
+	if util.HasPathPrefix(path, report.rootDir) {
+		if relPath, err := util.GetPathRelativeTo(path, report.rootDir); err == nil {
+			return sarifArtifactLocation{Uri: relPath, UriBaseId: srcRootUriBaseId}
+		}
+	}
+	return sarifArtifactLocation{Uri: "file://" + filepath.ToSlash(path)}
+}
@@ -0,0 +1,85 @@
+package sarif
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReportWrite(t *testing.T) {
+	t.Parallel()
+
+	tmpDir, err := ioutil.TempDir("", "sarif-report")
+	require.NoError(t, err)
+	defer os.RemoveAll(tmpDir)
+
+	unusedRule := Rule{ID: "test/unused", Description: "Unused"}
+	missingRule := Rule{ID: "test/missing", Description: "Missing", HelpUri: "https://example.com"}
+
+	report := NewReport(tmpDir, "v1.2.3")
+	report.Add(Result{Rule: missingRule, Level: LevelError, Message: "missing", File: filepath.Join(tmpDir, "b", "terragrunt.hcl")})
+	report.Add(Result{Rule: unusedRule, Level: LevelWarning, Message: "unused", File: filepath.Join(tmpDir, "a", "terragrunt.hcl"), StartLine: 3, StartColumn: 3, EndLine: 3, EndColumn: 6})
+	report.Add(Result{Rule: unusedRule, Level: LevelWarning, Message: "outside", File: "/other/terragrunt.hcl"})
+
+	path := filepath.Join(tmpDir, "reports", "terragrunt.sarif")
+	require.NoError(t, report.Write(path))
+
+	contents, err := ioutil.ReadFile(path)
+	require.NoError(t, err)
+	var log sarifLog
+	require.NoError(t, json.Unmarshal(contents, &log))
+
+	assert.Equal(t, sarifVersion, log.Version)
+	require.Len(t, log.Runs, 1)
+	run := log.Runs[0]
+	assert.Equal(t, "v1.2.3", run.Tool.Driver.Version)
+	assert.Equal(t, []sarifRule{
+		{ID: "test/unused", ShortDescription: sarifMessage{Text: "Unused"}},
+		{ID: "test/missing", ShortDescription: sarifMessage{Text: "Missing"}, HelpUri: "https://example.com"},
+	}, run.Tool.Driver.Rules)
+
+	expected := []sarifResult{
+		{
+			RuleID:    "test/unused",
+			RuleIndex: 0,
+			Level:     "warning",
+			Message:   sarifMessage{Text: "outside"},
+			Locations: []sarifLocation{{PhysicalLocation: sarifPhysicalLocation{
+				ArtifactLocation: sarifArtifactLocation{Uri: "file:///other/terragrunt.hcl"},
+			}}},
+		},
+		{
+			RuleID:    "test/unused",
+			RuleIndex: 0,
+			Level:     "warning",
+			Message:   sarifMessage{Text: "unused"},
+			Locations: []sarifLocation{{PhysicalLocation: sarifPhysicalLocation{
+				ArtifactLocation: sarifArtifactLocation{Uri: "a/terragrunt.hcl", UriBaseId: srcRootUriBaseId},
+				Region:           &sarifRegion{StartLine: 3, StartColumn: 3, EndLine: 3, EndColumn: 6},
+			}}},
+		},
+		{
+			RuleID:    "test/missing",
+			RuleIndex: 1,
+			Level:     "error",
+			Message:   sarifMessage{Text: "missing"},
+			Locations: []sarifLocation{{PhysicalLocation: sarifPhysicalLocation{
+				ArtifactLocation: sarifArtifactLocation{Uri: "b/terragrunt.hcl", UriBaseId: srcRootUriBaseId},
+			}}},
+		},
+	}
+	assert.Equal(t, expected, run.Results)
+}
+
+func TestNilReport(t *testing.T) {
+	t.Parallel()
+
+	var report *Report
+	report.Add(Result{Message: "ignored"})
+	assert.NoError(t, report.Write(filepath.Join(os.TempDir(), "never-written.sarif")))
+}
@@ -1,7 +1,6 @@
 package shell
 
 import (
-	"bytes"
 	"fmt"
 	"io"
 	"os"
@@ -13,9 +12,11 @@ import (
 
 	"github.com/gruntwork-io/terragrunt/errors"
 	"github.com/gruntwork-io/terragrunt/options"
+	"github.com/gruntwork-io/terragrunt/telemetry"
 	"github.com/gruntwork-io/terragrunt/util"
 	"github.com/hashicorp/go-multierror"
 	"github.com/sirupsen/logrus"
+	"golang.org/x/crypto/ssh/terminal"
 )
 
 // Commands that implement a REPL need a pseudo TTY when run as a subprocess in order for the readline properties to be
@@ -25,26 +26,84 @@ var terraformCommandsThatNeedPty = []string{
 	"console",
 }
 
+// The number of bytes of the stdout and stderr of a command that are kept in memory, to be returned to the caller.
+// Anything past that is spilled into a temp file, so that commands with verbose output, such as the plans of many
+// modules run concurrently by run-all, don't exhaust the memory.
+const maxCommandOutputInMemory = 1024 * 1024
+
+// Backend settings that hold credentials, whose values are masked when logging the -backend-config args of a command
+var secretBackendConfigKeys = []string{
+	"password",
+	"access_key",
+	"secret_key",
+	"client_secret",
+	"token",
+	"conn_str",
+}
+
 // Run the given Terraform command
 func RunTerraformCommand(terragruntOptions *options.TerragruntOptions, args ...string) error {
-	_, err := RunShellCommandWithOutput(terragruntOptions, "", false, isTerraformCommandThatNeedsPty(args[0]), terragruntOptions.TerraformPath, args...)
-	return err
+	return RunTerraformCommandWithOutputReaders(terragruntOptions, nil, args...)
 }
 
 // Run the given shell command
 func RunShellCommand(terragruntOptions *options.TerragruntOptions, command string, args ...string) error {
-	_, err := RunShellCommandWithOutput(terragruntOptions, "", false, false, command, args...)
+	stdoutBuf, stderrBuf, err := runCommand(terragruntOptions, terragruntOptions.TelemetrySpan.TraceParent(), "", false, false, command, args...)
+	closeOutputBuffers(terragruntOptions, stdoutBuf, stderrBuf)
+	return err
+}
+
+// Run the given Terraform command, writing its stdout/stderr to the terminal, and pass readers of its stdout and stderr
+// to processOutput, if not nil, once it's done. Unlike RunTerraformCommandWithOutput, the output isn't read into
+// memory: the readers stream it from the temp files it was spilled into, if it was too large to keep in memory, such
+// as the output of a plan of many resources.
+func RunTerraformCommandWithOutputReaders(terragruntOptions *options.TerragruntOptions, processOutput func(stdout io.Reader, stderr io.Reader), args ...string) error {
+	stdoutBuf, stderrBuf, err := runTerraformCommand(terragruntOptions, args...)
+	defer closeOutputBuffers(terragruntOptions, stdoutBuf, stderrBuf)
+
+	if processOutput == nil || stdoutBuf == nil {
+		return err
+	}
+
+	stdout, readErr := stdoutBuf.Reader()
+	if readErr != nil {
+		terragruntOptions.Logger.Warnf("Could not read the output of terraform %s: %v", util.FirstArg(args), readErr)
+		return err
+	}
+	defer stdout.Close()
+	stderr, readErr := stderrBuf.Reader()
+	if readErr != nil {
+		terragruntOptions.Logger.Warnf("Could not read the output of terraform %s: %v", util.FirstArg(args), readErr)
+		return err
+	}
+	defer stderr.Close()
+
+	processOutput(stdout, stderr)
 	return err
 }
 
 // Run the given Terraform command, writing its stdout/stderr to the terminal AND returning stdout/stderr to this
 // method's caller
 func RunTerraformCommandWithOutput(terragruntOptions *options.TerragruntOptions, args ...string) (*CmdOutput, error) {
-	needPty := false
-	if len(args) > 0 {
-		needPty = isTerraformCommandThatNeedsPty(args[0])
-	}
-	return RunShellCommandWithOutput(terragruntOptions, "", false, needPty, terragruntOptions.TerraformPath, args...)
+	return runTerraformCommandWithOutput(terragruntOptions, args...)
+}
+
+func runTerraformCommandWithOutput(terragruntOptions *options.TerragruntOptions, args ...string) (*CmdOutput, error) {
+	stdoutBuf, stderrBuf, err := runTerraformCommand(terragruntOptions, args...)
+	defer closeOutputBuffers(terragruntOptions, stdoutBuf, stderrBuf)
+	return toCmdOutput(stdoutBuf, stderrBuf), err
+}
+
+// Run the given Terraform command in a telemetry span of its own, whose trace context is passed on to terraform, so
+// that OpenTofu can add its own spans to the trace
+func runTerraformCommand(terragruntOptions *options.TerragruntOptions, args ...string) (*util.SpillBuffer, *util.SpillBuffer, error) {
+	span := terragruntOptions.TelemetrySpan.StartChild("terraform "+util.FirstArg(args), map[string]string{
+		"terraform.args":         strings.Join(maskSecretBackendConfigArgs(args), " "),
+		"terragrunt.working_dir": terragruntOptions.WorkingDir,
+	})
+	stdoutBuf, stderrBuf, err := runCommand(terragruntOptions, span.TraceParent(), "", false, terraformCommandNeedsPty(terragruntOptions, args), terragruntOptions.TerraformPath, args...)
+	span.End(err)
+	return stdoutBuf, stderrBuf, err
 }
 
 // Run the specified shell command with the specified arguments. Connect the command's stdin, stdout, and stderr to
@@ -58,18 +117,62 @@ func RunShellCommandWithOutput(
 	command string,
 	args ...string,
 ) (*CmdOutput, error) {
-	terragruntOptions.Logger.Debugf("Running command: %s %s", command, strings.Join(args, " "))
+	stdoutBuf, stderrBuf, err := runCommand(terragruntOptions, terragruntOptions.TelemetrySpan.TraceParent(), workingDir, suppressStdout, allocatePseudoTty, command, args...)
+	defer closeOutputBuffers(terragruntOptions, stdoutBuf, stderrBuf)
+	return toCmdOutput(stdoutBuf, stderrBuf), err
+}
+
+// Return the contents of the given buffers of the stdout and stderr of a command, or nil if the command didn't start
+func toCmdOutput(stdoutBuf *util.SpillBuffer, stderrBuf *util.SpillBuffer) *CmdOutput {
+	if stdoutBuf == nil {
+		return nil
+	}
+	return &CmdOutput{
+		Stdout: stdoutBuf.String(),
+		Stderr: stderrBuf.String(),
+	}
+}
+
+// Close the given buffers of the stdout and stderr of a command, if it started, which removes the temp files they
+// spilled into
+func closeOutputBuffers(terragruntOptions *options.TerragruntOptions, buffers ...*util.SpillBuffer) {
+	for _, buffer := range buffers {
+		if buffer == nil {
+			continue
+		}
+		if err := buffer.Close(); err != nil {
+			terragruntOptions.Logger.Debugf("Could not remove the buffered output of a command: %v", err)
+		}
+	}
+}
+
+// Run the specified shell command as in RunShellCommandWithOutput, and return the buffers its stdout and stderr were
+// captured into, which the caller must close, or nil if it couldn't be started. If traceParent is not empty, it's
+// passed on to the command in the TRACEPARENT env var.
+func runCommand(
+	terragruntOptions *options.TerragruntOptions,
+	traceParent string,
+	workingDir string,
+	suppressStdout bool,
+	allocatePseudoTty bool,
+	command string,
+	args ...string,
+) (*util.SpillBuffer, *util.SpillBuffer, error) {
+	terragruntOptions.Logger.Debugf("Running command: %s %s", command, strings.Join(maskSecretBackendConfigArgs(args), " "))
 	if suppressStdout {
 		terragruntOptions.Logger.Debugf("Command output will be suppressed.")
 	}
 
-	var stdoutBuf bytes.Buffer
-	var stderrBuf bytes.Buffer
+	stdoutBuf := util.NewSpillBuffer(maxCommandOutputInMemory)
+	stderrBuf := util.NewSpillBuffer(maxCommandOutputInMemory)
 
 	cmd := exec.Command(command, args...)
 
 	// TODO: consider adding prefix from terragruntOptions logger to stdout and stderr
 	cmd.Env = toEnvVarsList(terragruntOptions.Env)
+	if traceParent != "" {
+		cmd.Env = append(cmd.Env, fmt.Sprintf("%s=%s", telemetry.TraceParentEnvVar, traceParent))
+	}
 
 	var errWriter = terragruntOptions.ErrWriter
 	var outWriter = terragruntOptions.Writer
@@ -87,27 +190,38 @@ func RunShellCommandWithOutput(
 	}
 
 	// Inspired by https://blog.kowalczyk.info/article/wOYk/advanced-command-execution-in-go-with-osexec.html
-	cmdStderr := io.MultiWriter(errWriter, &stderrBuf)
+	cmdStderr := io.MultiWriter(errWriter, stderrBuf)
 	var cmdStdout io.Writer
 	if !suppressStdout {
-		cmdStdout = io.MultiWriter(outWriter, &stdoutBuf)
+		cmdStdout = io.MultiWriter(outWriter, stdoutBuf)
 	} else {
-		cmdStdout = io.MultiWriter(&stdoutBuf)
+		cmdStdout = io.MultiWriter(stdoutBuf)
+	}
+
+	// A ptty can only be allocated when stdin is a terminal, e.g. not when running in CI or with stdin redirected
+	if allocatePseudoTty && !isStdinTerminal() {
+		terragruntOptions.Logger.Debugf("Stdin is not a terminal, so not allocating a pseudo-tty for command %s", command)
+		allocatePseudoTty = false
 	}
 
 	// If we need to allocate a ptty for the command, route through the ptty routine. Otherwise, directly call the
 	// command.
 	if allocatePseudoTty {
 		if err := runCommandWithPTTY(terragruntOptions, cmd, cmdStdout, cmdStderr); err != nil {
-			return nil, err
+			closeOutputBuffers(terragruntOptions, stdoutBuf, stderrBuf)
+			return nil, nil, err
 		}
 	} else {
-		cmd.Stdin = os.Stdin
+		// With no stdin attached, a command that prompts for input reads EOF and fails instead of waiting forever
+		if terragruntOptions.InputMode != options.INPUT_MODE_NONE {
+			cmd.Stdin = os.Stdin
+		}
 		cmd.Stdout = cmdStdout
 		cmd.Stderr = cmdStderr
 		if err := cmd.Start(); err != nil {
 			// bad path, binary not executable, &c
-			return nil, errors.WithStackTrace(err)
+			closeOutputBuffers(terragruntOptions, stdoutBuf, stderrBuf)
+			return nil, nil, errors.WithStackTrace(err)
 		}
 	}
 
@@ -119,12 +233,13 @@ func RunShellCommandWithOutput(
 	err := cmd.Wait()
 	cmdChannel <- err
 
-	cmdOutput := CmdOutput{
-		Stdout: stdoutBuf.String(),
-		Stderr: stderrBuf.String(),
+	for _, buffer := range []*util.SpillBuffer{stdoutBuf, stderrBuf} {
+		if bufferErr := buffer.Err(); bufferErr != nil {
+			terragruntOptions.Logger.Warnf("Could not capture all the output of command %s: %v", command, bufferErr)
+		}
 	}
 
-	return &cmdOutput, errors.WithStackTrace(err)
+	return stdoutBuf, stderrBuf, errors.WithStackTrace(err)
 }
 
 func toEnvVarsList(envVarsAsMap map[string]string) []string {
@@ -135,6 +250,45 @@ func toEnvVarsList(envVarsAsMap map[string]string) []string {
 	return envVarsAsList
 }
 
+// terraformCommandNeedsPty returns true if a pseudo-tty should be allocated to run terraform with the given args,
+// based on the configured input mode. In pty mode, one is allocated for the terraform command requested by the user,
+// but not for the commands terragrunt runs on its own (e.g., output -json), as a pty mangles their output.
+func terraformCommandNeedsPty(terragruntOptions *options.TerragruntOptions, args []string) bool {
+	switch terragruntOptions.InputMode {
+	case options.INPUT_MODE_STDIN, options.INPUT_MODE_NONE:
+		return false
+	case options.INPUT_MODE_PTY:
+		if reflect.DeepEqual(terragruntOptions.TerraformCliArgs, args) {
+			return true
+		}
+	}
+	return len(args) > 0 && isTerraformCommandThatNeedsPty(args[0])
+}
+
+// Return a copy of the given args where the values of the -backend-config args that hold credentials are masked, so
+// that they don't end up in the logs.
+func maskSecretBackendConfigArgs(args []string) []string {
+	maskedArgs := make([]string, len(args))
+	for i, arg := range args {
+		maskedArgs[i] = arg
+
+		if !strings.HasPrefix(arg, "-backend-config=") {
+			continue
+		}
+
+		keyAndValue := strings.SplitN(strings.TrimPrefix(arg, "-backend-config="), "=", 2)
+		if len(keyAndValue) == 2 && util.ListContainsElement(secretBackendConfigKeys, keyAndValue[0]) {
+			maskedArgs[i] = fmt.Sprintf("-backend-config=%s=****", keyAndValue[0])
+		}
+	}
+	return maskedArgs
+}
+
+// isStdinTerminal returns true if the stdin of terragrunt is a terminal
+func isStdinTerminal() bool {
+	return terminal.IsTerminal(int(os.Stdin.Fd()))
+}
+
 // isTerraformCommandThatNeedsPty returns true if the sub command of terraform we are running requires a pty.
 func isTerraformCommandThatNeedsPty(command string) bool {
 	return util.ListContainsElement(terraformCommandsThatNeedPty, command)
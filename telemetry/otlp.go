@@ -0,0 +1,124 @@
+package telemetry
+
+import (
+	"sort"
+	"strconv"
+	"time"
+)
+
+// The types below are the JSON encoding of an OTLP ExportTraceServiceRequest. Only the fields Terragrunt sets are
+// included. See https://opentelemetry.io/docs/specs/otlp/#json-protobuf-encoding
+
+type otlpExportRequest struct {
+	ResourceSpans []otlpResourceSpans `json:"resourceSpans"`
+}
+
+type otlpResourceSpans struct {
+	Resource   otlpResource     `json:"resource"`
+	ScopeSpans []otlpScopeSpans `json:"scopeSpans"`
+}
+
+type otlpResource struct {
+	Attributes []otlpAttribute `json:"attributes"`
+}
+
+type otlpScopeSpans struct {
+	Scope otlpScope  `json:"scope"`
+	Spans []otlpSpan `json:"spans"`
+}
+
+type otlpScope struct {
+	Name string `json:"name"`
+}
+
+type otlpSpan struct {
+	TraceID           string          `json:"traceId"`
+	SpanID            string          `json:"spanId"`
+	ParentSpanID      string          `json:"parentSpanId,omitempty"`
+	Name              string          `json:"name"`
+	Kind              int             `json:"kind"`
+	StartTimeUnixNano string          `json:"startTimeUnixNano"`
+	EndTimeUnixNano   string          `json:"endTimeUnixNano"`
+	Attributes        []otlpAttribute `json:"attributes,omitempty"`
+	Status            otlpStatus      `json:"status"`
+}
+
+type otlpAttribute struct {
+	Key   string    `json:"key"`
+	Value otlpValue `json:"value"`
+}
+
+type otlpValue struct {
+	StringValue string `json:"stringValue"`
+}
+
+type otlpStatus struct {
+	Code    int    `json:"code,omitempty"`
+	Message string `json:"message,omitempty"`
+}
+
+// The values of the OTLP enums Terragrunt uses
+const (
+	otlpSpanKindInternal = 1
+	otlpStatusCodeOk     = 1
+	otlpStatusCodeError  = 2
+)
+
+// The name of the instrumentation scope of the spans
+const scopeName = "github.com/gruntwork-io/terragrunt"
+
+func (tracer *Tracer) toOtlp(batch []*Span) otlpExportRequest {
+	spans := []otlpSpan{}
+	for _, span := range batch {
+		spans = append(spans, span.toOtlp())
+	}
+
+	return otlpExportRequest{
+		ResourceSpans: []otlpResourceSpans{{
+			Resource:   otlpResource{Attributes: toOtlpAttributes(map[string]string{"service.name": tracer.serviceName})},
+			ScopeSpans: []otlpScopeSpans{{Scope: otlpScope{Name: scopeName}, Spans: spans}},
+		}},
+	}
+}
+
+func (span *Span) toOtlp() otlpSpan {
+	span.mutex.Lock()
+	defer span.mutex.Unlock()
+
+	status := otlpStatus{Code: otlpStatusCodeOk}
+	if span.err != nil {
+		status = otlpStatus{Code: otlpStatusCodeError, Message: span.err.Error()}
+	}
+
+	return otlpSpan{
+		TraceID:           span.traceID,
+		SpanID:            span.spanID,
+		ParentSpanID:      span.parentSpanID,
+		Name:              span.name,
+		Kind:              otlpSpanKindInternal,
+		StartTimeUnixNano: unixNano(span.start),
+		EndTimeUnixNano:   unixNano(span.end),
+		Attributes:        toOtlpAttributes(span.attributes),
+		Status:            status,
+	}
+}
+
+// Convert the given attributes, sorted by key so that the exported spans are deterministic
+func toOtlpAttributes(attributes map[string]string) []otlpAttribute {
+	keys := []string{}
+	for key := range attributes {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	otlpAttributes := []otlpAttribute{}
+	for _, key := range keys {
+		otlpAttributes = append(otlpAttributes, otlpAttribute{Key: key, Value: otlpValue{StringValue: attributes[key]}})
+	}
+	return otlpAttributes
+}
+
+// The OTLP JSON encoding represents 64 bit integers as strings
+func unixNano(t time.Time) string {
+	return strconv.FormatInt(t.UnixNano(), 10)
+}
@@ -0,0 +1,309 @@
+// Package telemetry implements OpenTelemetry tracing of Terragrunt itself. The spans of the main operations, such as
+// parsing the config, fetching the outputs of dependencies, initializing the remote state and running terraform, are
+// exported to an OpenTelemetry collector with the OTLP/HTTP protocol, using its JSON encoding, so that it's possible
+// to see where the time of a long run-all goes.
+package telemetry
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gruntwork-io/terragrunt/errors"
+)
+
+// The environment variables of the OpenTelemetry SDKs Terragrunt understands. See
+// https://opentelemetry.io/docs/specs/otel/protocol/exporter/
+const (
+	EndpointEnvVar       = "OTEL_EXPORTER_OTLP_ENDPOINT"
+	TracesEndpointEnvVar = "OTEL_EXPORTER_OTLP_TRACES_ENDPOINT"
+	HeadersEnvVar        = "OTEL_EXPORTER_OTLP_HEADERS"
+	ServiceNameEnvVar    = "OTEL_SERVICE_NAME"
+)
+
+// The environment variable holding the W3C trace context of the parent span, e.g. of the CI job that runs Terragrunt.
+// Terragrunt passes the trace context of its own spans on to terraform in the same variable.
+const TraceParentEnvVar = "TRACEPARENT"
+
+// The service name of the spans when OTEL_SERVICE_NAME is not set
+const DefaultServiceName = "terragrunt"
+
+// The path the traces are sent to when the endpoint is given for all signals, rather than for traces only
+const tracesPath = "/v1/traces"
+
+// The number of finished spans that are exported together. A long run-all exports its spans as it goes, rather than
+// all at the end.
+const exportBatchSize = 256
+
+// How long to wait for the collector to accept a batch of spans
+const exportTimeout = 10 * time.Second
+
+// Tracer collects the spans of a Terragrunt command and exports them to an OTLP/HTTP endpoint
+type Tracer struct {
+	endpoint    string
+	headers     map[string]string
+	serviceName string
+	client      *http.Client
+
+	mutex     sync.Mutex
+	finished  []*Span
+	exports   sync.WaitGroup
+	exportErr error
+}
+
+// Span is a timed operation of Terragrunt. All the methods of Span can be called on a nil span, which is what is used
+// when tracing is disabled, so that the code being traced doesn't need to check whether it is.
+type Span struct {
+	tracer       *Tracer
+	traceID      string
+	spanID       string
+	parentSpanID string
+	name         string
+	attributes   map[string]string
+	start        time.Time
+
+	mutex sync.Mutex
+	end   time.Time
+	err   error
+}
+
+// Return the endpoint the traces should be sent to: the given endpoint, as set via --terragrunt-telemetry-endpoint,
+// or else the one set via the standard OpenTelemetry environment variables. Returns an empty string if none is set,
+// in which case tracing is disabled.
+func TracesEndpoint(endpoint string) string {
+	if endpoint == "" {
+		if tracesEndpoint := os.Getenv(TracesEndpointEnvVar); tracesEndpoint != "" {
+			return tracesEndpoint
+		}
+		endpoint = os.Getenv(EndpointEnvVar)
+	}
+	if endpoint == "" {
+		return ""
+	}
+	return strings.TrimSuffix(endpoint, "/") + tracesPath
+}
+
+// Parse the headers to send to the collector, e.g. for authentication, from a list of comma separated key=value pairs,
+// as in OTEL_EXPORTER_OTLP_HEADERS
+func ParseHeaders(headers string) (map[string]string, error) {
+	parsedHeaders := map[string]string{}
+	for _, header := range strings.Split(headers, ",") {
+		if strings.TrimSpace(header) == "" {
+			continue
+		}
+		keyAndValue := strings.SplitN(header, "=", 2)
+		if len(keyAndValue) != 2 || strings.TrimSpace(keyAndValue[0]) == "" {
+			return nil, errors.WithStackTrace(InvalidHeader(header))
+		}
+		parsedHeaders[strings.TrimSpace(keyAndValue[0])] = strings.TrimSpace(keyAndValue[1])
+	}
+	return parsedHeaders, nil
+}
+
+// Create a tracer that exports its spans to the given OTLP/HTTP traces endpoint
+func NewTracer(endpoint string, headers map[string]string, serviceName string) *Tracer {
+	if serviceName == "" {
+		serviceName = DefaultServiceName
+	}
+	return &Tracer{
+		endpoint:    endpoint,
+		headers:     headers,
+		serviceName: serviceName,
+		client:      &http.Client{Timeout: exportTimeout},
+	}
+}
+
+// Start the root span of a Terragrunt command. If traceParent is the W3C trace context of another span, as found in
+// the TRACEPARENT env var, the root span is part of its trace. Otherwise, it starts a new trace.
+func (tracer *Tracer) StartRootSpan(name string, traceParent string, attributes map[string]string) *Span {
+	traceID, parentSpanID, isValid := parseTraceParent(traceParent)
+	if !isValid {
+		traceID, parentSpanID = randomID(16), ""
+	}
+	return tracer.startSpan(traceID, parentSpanID, name, attributes)
+}
+
+// Start a span that is a child of this span. Returns nil if this span is nil, i.e., tracing is disabled.
+func (span *Span) StartChild(name string, attributes map[string]string) *Span {
+	if span == nil {
+		return nil
+	}
+	return span.tracer.startSpan(span.traceID, span.spanID, name, attributes)
+}
+
+// End the span, with an error status if the given error is not nil. The span is exported with the next batch.
+func (span *Span) End(err error) {
+	if span == nil {
+		return
+	}
+
+	span.mutex.Lock()
+	if !span.end.IsZero() {
+		span.mutex.Unlock()
+		return
+	}
+	span.end = time.Now()
+	span.err = err
+	span.mutex.Unlock()
+
+	span.tracer.spanFinished(span)
+}
+
+// Return the W3C trace context of the span, to pass it on to other processes via the TRACEPARENT env var. Returns an
+// empty string if the span is nil.
+func (span *Span) TraceParent() string {
+	if span == nil {
+		return ""
+	}
+	return fmt.Sprintf("00-%s-%s-01", span.traceID, span.spanID)
+}
+
+// Export the spans that are not exported yet, and wait for all the exports to finish. Returns the first error
+// exporting the spans of the command, if any.
+func (tracer *Tracer) Shutdown() error {
+	tracer.mutex.Lock()
+	batch := tracer.finished
+	tracer.finished = nil
+	tracer.mutex.Unlock()
+
+	if len(batch) > 0 {
+		tracer.export(batch)
+	}
+	tracer.exports.Wait()
+
+	tracer.mutex.Lock()
+	defer tracer.mutex.Unlock()
+	return tracer.exportErr
+}
+
+func (tracer *Tracer) startSpan(traceID string, parentSpanID string, name string, attributes map[string]string) *Span {
+	return &Span{
+		tracer:       tracer,
+		traceID:      traceID,
+		spanID:       randomID(8),
+		parentSpanID: parentSpanID,
+		name:         name,
+		attributes:   attributes,
+		start:        time.Now(),
+	}
+}
+
+// Record the given finished span, and export a batch of spans in the background once there are enough of them
+func (tracer *Tracer) spanFinished(span *Span) {
+	tracer.mutex.Lock()
+	tracer.finished = append(tracer.finished, span)
+	if len(tracer.finished) < exportBatchSize {
+		tracer.mutex.Unlock()
+		return
+	}
+	batch := tracer.finished
+	tracer.finished = nil
+	tracer.exports.Add(1)
+	tracer.mutex.Unlock()
+
+	go func() {
+		defer tracer.exports.Done()
+		tracer.export(batch)
+	}()
+}
+
+// Send the given spans to the collector, recording the first error, if any, to be returned by Shutdown
+func (tracer *Tracer) export(batch []*Span) {
+	if err := tracer.send(batch); err != nil {
+		tracer.mutex.Lock()
+		if tracer.exportErr == nil {
+			tracer.exportErr = err
+		}
+		tracer.mutex.Unlock()
+	}
+}
+
+func (tracer *Tracer) send(batch []*Span) error {
+	body, err := json.Marshal(tracer.toOtlp(batch))
+	if err != nil {
+		return errors.WithStackTrace(err)
+	}
+
+	request, err := http.NewRequest(http.MethodPost, tracer.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return errors.WithStackTrace(err)
+	}
+	request.Header.Set("Content-Type", "application/json")
+	for key, value := range tracer.headers {
+		request.Header.Set(key, value)
+	}
+
+	response, err := tracer.client.Do(request)
+	if err != nil {
+		return errors.WithStackTrace(err)
+	}
+	defer response.Body.Close()
+	// Read the rest of the response so the connection can be reused for the next batch
+	responseBody, _ := ioutil.ReadAll(io.LimitReader(response.Body, 4096))
+
+	if response.StatusCode < 200 || response.StatusCode >= 300 {
+		return errors.WithStackTrace(ExportFailed{Endpoint: tracer.endpoint, StatusCode: response.StatusCode, Body: string(responseBody)})
+	}
+	return nil
+}
+
+// Return true if the given string is a valid W3C trace context, along with the trace ID and the span ID it contains.
+// See https://www.w3.org/TR/trace-context/#traceparent-header
+func parseTraceParent(traceParent string) (string, string, bool) {
+	parts := strings.Split(strings.TrimSpace(traceParent), "-")
+	if len(parts) != 4 || len(parts[0]) != 2 || parts[0] == "ff" {
+		return "", "", false
+	}
+	traceID, spanID := strings.ToLower(parts[1]), strings.ToLower(parts[2])
+	if !isHexID(traceID, 16) || !isHexID(spanID, 8) {
+		return "", "", false
+	}
+	return traceID, spanID, true
+}
+
+// Return true if the given string is the hex encoding of an ID of the given number of bytes that isn't all zeroes, as
+// required of trace and span IDs
+func isHexID(id string, numBytes int) bool {
+	decoded, err := hex.DecodeString(id)
+	if err != nil || len(decoded) != numBytes {
+		return false
+	}
+	return strings.Trim(id, "0") != ""
+}
+
+// Return a random ID of the given number of bytes, hex encoded
+func randomID(numBytes int) string {
+	id := make([]byte, numBytes)
+	if _, err := rand.Read(id); err != nil {
+		// crypto/rand doesn't fail on the supported platforms, but make sure the ID is still valid if it does
+		id[0] = 1
+	}
+	return hex.EncodeToString(id)
+}
+
+// Custom error types
+
+type InvalidHeader string
+
+func (header InvalidHeader) Error() string {
+	return fmt.Sprintf("Invalid OTLP header %q: headers must be given as comma separated key=value pairs", string(header))
+}
+
+type ExportFailed struct {
+	Endpoint   string
+	StatusCode int
+	Body       string
+}
+
+func (err ExportFailed) Error() string {
+	return fmt.Sprintf("Exporting the Terragrunt traces to %s failed with status %d: %s", err.Endpoint, err.StatusCode, err.Body)
+}
@@ -0,0 +1,166 @@
+package telemetry
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/gruntwork-io/terragrunt/errors"
+)
+
+func TestTracerExportsSpans(t *testing.T) {
+	t.Parallel()
+
+	collector := newTestCollector()
+	server := httptest.NewServer(collector)
+	defer server.Close()
+
+	tracer := NewTracer(TracesEndpoint(server.URL), map[string]string{"Authorization": "Bearer secret"}, "")
+	rootSpan := tracer.StartRootSpan("terragrunt run-all", "", map[string]string{"terragrunt.command": "run-all"})
+	childSpan := rootSpan.StartChild("terraform plan", nil)
+	childSpan.End(fmt.Errorf("plan failed"))
+	rootSpan.End(nil)
+	require.NoError(t, tracer.Shutdown())
+
+	assert.Equal(t, []string{tracesPath}, collector.paths)
+	assert.Equal(t, "Bearer secret", collector.authorization)
+	require.Len(t, collector.spans, 2)
+
+	child, root := collector.spans[0], collector.spans[1]
+	assert.Equal(t, "terraform plan", child.Name)
+	assert.Equal(t, root.TraceID, child.TraceID)
+	assert.Equal(t, root.SpanID, child.ParentSpanID)
+	assert.Equal(t, otlpStatus{Code: otlpStatusCodeError, Message: "plan failed"}, child.Status)
+
+	assert.Equal(t, "terragrunt run-all", root.Name)
+	assert.Empty(t, root.ParentSpanID)
+	assert.Equal(t, otlpStatus{Code: otlpStatusCodeOk}, root.Status)
+	assert.Equal(t, []otlpAttribute{{Key: "terragrunt.command", Value: otlpValue{StringValue: "run-all"}}}, root.Attributes)
+	assert.Equal(t, "terragrunt", collector.serviceName)
+}
+
+func TestTracerExportsSpansInBatches(t *testing.T) {
+	t.Parallel()
+
+	collector := newTestCollector()
+	server := httptest.NewServer(collector)
+	defer server.Close()
+
+	tracer := NewTracer(TracesEndpoint(server.URL), nil, "platform")
+	rootSpan := tracer.StartRootSpan("terragrunt run-all", "", nil)
+	for i := 0; i < exportBatchSize+1; i++ {
+		rootSpan.StartChild("parse_config", nil).End(nil)
+	}
+	rootSpan.End(nil)
+	require.NoError(t, tracer.Shutdown())
+
+	assert.Len(t, collector.paths, 2)
+	assert.Len(t, collector.spans, exportBatchSize+2)
+	assert.Equal(t, "platform", collector.serviceName)
+}
+
+func TestTracerReportsExportErrors(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		http.Error(writer, "unauthorized", http.StatusUnauthorized)
+	}))
+	defer server.Close()
+
+	tracer := NewTracer(TracesEndpoint(server.URL), nil, "")
+	tracer.StartRootSpan("terragrunt plan", "", nil).End(nil)
+
+	err := tracer.Shutdown()
+	exportErr, isExportErr := errors.Unwrap(err).(ExportFailed)
+	require.True(t, isExportErr, "Unexpected error: %v", err)
+	assert.Equal(t, http.StatusUnauthorized, exportErr.StatusCode)
+}
+
+func TestRootSpanContinuesTraceParent(t *testing.T) {
+	t.Parallel()
+
+	tracer := NewTracer("http://localhost:4318/v1/traces", nil, "")
+	span := tracer.StartRootSpan("terragrunt plan", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", nil)
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", span.traceID)
+	assert.Equal(t, "00f067aa0ba902b7", span.parentSpanID)
+	assert.Regexp(t, "^00-4bf92f3577b34da6a3ce929d0e0e4736-[0-9a-f]{16}-01$", span.TraceParent())
+
+	for _, invalid := range []string{"", "garbage", "00-00000000000000000000000000000000-00f067aa0ba902b7-01", "00-4bf92f3577b34da6-00f067aa0ba902b7-01"} {
+		span := tracer.StartRootSpan("terragrunt plan", invalid, nil)
+		assert.Len(t, span.traceID, 32, "For trace parent %q", invalid)
+		assert.NotEqual(t, "4bf92f3577b34da6a3ce929d0e0e4736", span.traceID, "For trace parent %q", invalid)
+		assert.Empty(t, span.parentSpanID, "For trace parent %q", invalid)
+	}
+}
+
+func TestNilSpan(t *testing.T) {
+	t.Parallel()
+
+	var span *Span
+	assert.Nil(t, span.StartChild("terraform plan", nil))
+	assert.Empty(t, span.TraceParent())
+	span.End(nil)
+}
+
+func TestParseHeaders(t *testing.T) {
+	t.Parallel()
+
+	headers, err := ParseHeaders("Authorization=Bearer secret, x-tenant = platform,")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"Authorization": "Bearer secret", "x-tenant": "platform"}, headers)
+
+	_, err = ParseHeaders("no-value")
+	assert.True(t, errors.IsError(err, InvalidHeader("no-value")))
+}
+
+func TestTracesEndpoint(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, "http://collector:4318/v1/traces", TracesEndpoint("http://collector:4318"))
+	assert.Equal(t, "http://collector:4318/v1/traces", TracesEndpoint("http://collector:4318/"))
+}
+
+// An OTLP/HTTP collector that records the spans it receives
+type testCollector struct {
+	mutex         sync.Mutex
+	paths         []string
+	authorization string
+	serviceName   string
+	spans         []otlpSpan
+}
+
+func newTestCollector() *testCollector {
+	return &testCollector{}
+}
+
+func (collector *testCollector) ServeHTTP(writer http.ResponseWriter, request *http.Request) {
+	body, err := ioutil.ReadAll(request.Body)
+	if err != nil {
+		http.Error(writer, err.Error(), http.StatusBadRequest)
+		return
+	}
+	var exportRequest otlpExportRequest
+	if err := json.Unmarshal(body, &exportRequest); err != nil {
+		http.Error(writer, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	collector.mutex.Lock()
+	defer collector.mutex.Unlock()
+	collector.paths = append(collector.paths, request.URL.Path)
+	collector.authorization = request.Header.Get("Authorization")
+	for _, resourceSpans := range exportRequest.ResourceSpans {
+		collector.serviceName = resourceSpans.Resource.Attributes[0].Value.StringValue
+		for _, scopeSpans := range resourceSpans.ScopeSpans {
+			collector.spans = append(collector.spans, scopeSpans.Spans...)
+		}
+	}
+	writer.WriteHeader(http.StatusOK)
+}
@@ -0,0 +1,7 @@
+locals {
+  common = read_terragrunt_config("../common.hcl")
+}
+
+terraform {
+  source = local.common.locals.source
+}
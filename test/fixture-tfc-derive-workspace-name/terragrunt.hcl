@@ -0,0 +1,11 @@
+remote_state {
+  backend = "cloud"
+  generate = {
+    path      = "backend.tf"
+    if_exists = "overwrite_terragrunt"
+  }
+  config = {
+    organization          = "my-org"
+    derive_workspace_name = true
+  }
+}
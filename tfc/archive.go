@@ -0,0 +1,99 @@
+package tfc
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/gruntwork-io/terragrunt/errors"
+)
+
+// The folders that are left out of the uploaded configuration: the providers and modules terraform init downloads,
+// which Terraform Cloud downloads itself, the Terragrunt cache, and the git metadata
+var excludedFolders = map[string]bool{
+	".terraform":        true,
+	".terragrunt-cache": true,
+	".git":              true,
+}
+
+// Write the files of the given dir, along with the given extra files, keyed by their path relative to the dir, as a
+// gzipped tarball, the format of the uploads of configuration versions, to the given writer. The extra files replace
+// the files of the dir with the same path.
+func archiveConfiguration(dir string, extraFiles map[string][]byte, writer io.Writer) error {
+	gzipWriter := gzip.NewWriter(writer)
+	tarWriter := tar.NewWriter(gzipWriter)
+
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		relPath, err := filepath.Rel(dir, path)
+		if err != nil || relPath == "." {
+			return err
+		}
+		relPath = filepath.ToSlash(relPath)
+
+		if info.IsDir() {
+			if excludedFolders[info.Name()] {
+				return filepath.SkipDir
+			}
+			return tarWriter.WriteHeader(&tar.Header{Name: relPath + "/", Mode: 0755, Typeflag: tar.TypeDir, ModTime: info.ModTime()})
+		}
+		if _, isExtraFile := extraFiles[relPath]; isExtraFile {
+			return nil
+		}
+
+		// Upload the files symlinks point at, as they might point outside of the dir. Symlinks to folders are left out.
+		if info.Mode()&os.ModeSymlink != 0 {
+			info, err = os.Stat(path)
+			if err != nil || info.IsDir() {
+				return err
+			}
+		}
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+		return archiveFile(tarWriter, relPath, path, info)
+	})
+	if err != nil {
+		return errors.WithStackTrace(err)
+	}
+
+	extraPaths := []string{}
+	for path := range extraFiles {
+		extraPaths = append(extraPaths, path)
+	}
+	sort.Strings(extraPaths)
+	for _, path := range extraPaths {
+		contents := extraFiles[path]
+		if err := tarWriter.WriteHeader(&tar.Header{Name: path, Mode: 0644, Size: int64(len(contents))}); err != nil {
+			return errors.WithStackTrace(err)
+		}
+		if _, err := tarWriter.Write(contents); err != nil {
+			return errors.WithStackTrace(err)
+		}
+	}
+
+	if err := tarWriter.Close(); err != nil {
+		return errors.WithStackTrace(err)
+	}
+	return errors.WithStackTrace(gzipWriter.Close())
+}
+
+func archiveFile(tarWriter *tar.Writer, relPath string, path string, info os.FileInfo) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	header := &tar.Header{Name: relPath, Mode: int64(info.Mode().Perm()), Size: info.Size(), ModTime: info.ModTime()}
+	if err := tarWriter.WriteHeader(header); err != nil {
+		return err
+	}
+	_, err = io.Copy(tarWriter, file)
+	return err
+}
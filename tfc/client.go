@@ -0,0 +1,312 @@
+// Package tfc runs Terraform in Terraform Cloud / Terraform Enterprise through its API: it uploads a prepared working
+// directory as a configuration version of a workspace, creates a run of it, and follows the run, printing its logs, so
+// that Terraform Cloud executes the plans and applies of the units Terragrunt configures.
+package tfc
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/gruntwork-io/terragrunt/errors"
+)
+
+// The content type of the requests and responses of the API, which follows the JSON:API spec
+const apiContentType = "application/vnd.api+json"
+
+// How long to wait for a response of the API, or for an upload to complete
+const requestTimeout = 2 * time.Minute
+
+// How often to poll the status of configuration versions and runs
+const defaultPollInterval = 2 * time.Second
+
+// How long to wait for Terraform Cloud to process an upload, for a run to leave the queue of its workspace, and for a run
+// to end once it has
+const (
+	defaultUploadTimeout = 10 * time.Minute
+	defaultQueueTimeout  = time.Hour
+	defaultRunTimeout    = 6 * time.Hour
+)
+
+// Client calls the API of a Terraform Cloud or Terraform Enterprise host
+type Client struct {
+	// The address of the host, e.g. https://app.terraform.io
+	Address string
+	// How often to poll the status of configuration versions and runs
+	PollInterval time.Duration
+	// How long to wait for Terraform Cloud to process an upload
+	UploadTimeout time.Duration
+	// How long a run may wait in the queue of its workspace, e.g. behind the runs of other units, before it's given up on
+	QueueTimeout time.Duration
+	// How long a run may take, not counting the time waiting for a confirmation, before it's given up on
+	RunTimeout time.Duration
+
+	token  string
+	client *http.Client
+}
+
+// Create a client of the API of the host at the given address, e.g. https://app.terraform.io, that authenticates with
+// the given token
+func NewClient(address string, token string) *Client {
+	return &Client{
+		Address:       strings.TrimSuffix(address, "/"),
+		PollInterval:  defaultPollInterval,
+		UploadTimeout: defaultUploadTimeout,
+		QueueTimeout:  defaultQueueTimeout,
+		RunTimeout:    defaultRunTimeout,
+		token:         token,
+		client:        &http.Client{Timeout: requestTimeout},
+	}
+}
+
+// Workspace is a Terraform Cloud workspace, which the runs of a unit are created in
+type Workspace struct {
+	ID           string
+	Name         string
+	Organization string
+	// The folder of the uploaded configuration versions that Terraform runs in, relative to their root
+	WorkingDirectory string
+}
+
+// ConfigurationVersion is an upload of the Terraform code of a unit, which runs are created from
+type ConfigurationVersion struct {
+	ID        string
+	Status    string
+	UploadUrl string
+}
+
+// Find the workspace with the given name in the given organization
+func (client *Client) Workspace(organization string, name string) (*Workspace, error) {
+	var response struct {
+		Data struct {
+			ID         string `json:"id"`
+			Attributes struct {
+				WorkingDirectory string `json:"working-directory"`
+			} `json:"attributes"`
+		} `json:"data"`
+	}
+	path := fmt.Sprintf("/organizations/%s/workspaces/%s", url.PathEscape(organization), url.PathEscape(name))
+	if err := client.do(http.MethodGet, path, nil, &response); err != nil {
+		return nil, err
+	}
+	return &Workspace{ID: response.Data.ID, Name: name, Organization: organization, WorkingDirectory: response.Data.Attributes.WorkingDirectory}, nil
+}
+
+// Set the folder of the uploaded configuration versions that Terraform runs in in the given workspace, if it's not the
+// given one already. An empty folder is the root of the configuration versions.
+func (client *Client) SetWorkingDirectory(workspace *Workspace, workingDirectory string) error {
+	if workspace.WorkingDirectory == workingDirectory {
+		return nil
+	}
+	request := map[string]interface{}{
+		"data": map[string]interface{}{
+			"type": "workspaces",
+			"attributes": map[string]interface{}{
+				"working-directory": workingDirectory,
+			},
+		},
+	}
+	if err := client.do(http.MethodPatch, "/workspaces/"+url.PathEscape(workspace.ID), request, nil); err != nil {
+		return err
+	}
+	workspace.WorkingDirectory = workingDirectory
+	return nil
+}
+
+// Create a configuration version of the given workspace, which the Terraform code is then uploaded to. Speculative
+// configuration versions can only be planned.
+func (client *Client) CreateConfigurationVersion(workspaceID string, speculative bool) (*ConfigurationVersion, error) {
+	request := map[string]interface{}{
+		"data": map[string]interface{}{
+			"type": "configuration-versions",
+			"attributes": map[string]interface{}{
+				// The run is created once the code is uploaded, with the options of the command
+				"auto-queue-runs": false,
+				"speculative":     speculative,
+			},
+		},
+	}
+	var response configurationVersionResponse
+	if err := client.do(http.MethodPost, fmt.Sprintf("/workspaces/%s/configuration-versions", url.PathEscape(workspaceID)), request, &response); err != nil {
+		return nil, err
+	}
+	return response.configurationVersion(), nil
+}
+
+// Upload the given dir, along with the given extra files, keyed by their path relative to the dir, to the given
+// configuration version, and wait for Terraform Cloud to process the upload, for at most the upload timeout of the client
+func (client *Client) UploadConfiguration(configurationVersion *ConfigurationVersion, dir string, extraFiles map[string][]byte) error {
+	var archive bytes.Buffer
+	if err := archiveConfiguration(dir, extraFiles, &archive); err != nil {
+		return err
+	}
+
+	// The upload URL is signed, so the token isn't sent along
+	request, err := http.NewRequest(http.MethodPut, configurationVersion.UploadUrl, &archive)
+	if err != nil {
+		return errors.WithStackTrace(err)
+	}
+	request.Header.Set("Content-Type", "application/octet-stream")
+	response, err := client.client.Do(request)
+	if err != nil {
+		return errors.WithStackTrace(err)
+	}
+	defer response.Body.Close()
+	if response.StatusCode >= 300 {
+		return errors.WithStackTrace(UploadFailed{ID: configurationVersion.ID, StatusCode: response.StatusCode})
+	}
+
+	deadline := time.Now().Add(client.UploadTimeout)
+	for {
+		var response configurationVersionResponse
+		if err := client.do(http.MethodGet, "/configuration-versions/"+url.PathEscape(configurationVersion.ID), nil, &response); err != nil {
+			return err
+		}
+		switch response.Data.Attributes.Status {
+		case "uploaded":
+			return nil
+		case "errored":
+			return errors.WithStackTrace(ConfigurationVersionErrored{ID: configurationVersion.ID, Message: response.Data.Attributes.ErrorMessage})
+		}
+		if time.Now().After(deadline) {
+			return errors.WithStackTrace(ConfigurationVersionTimedOut{ID: configurationVersion.ID, Status: response.Data.Attributes.Status, Timeout: client.UploadTimeout})
+		}
+		time.Sleep(client.PollInterval)
+	}
+}
+
+type configurationVersionResponse struct {
+	Data struct {
+		ID         string `json:"id"`
+		Attributes struct {
+			Status       string `json:"status"`
+			ErrorMessage string `json:"error-message"`
+			UploadUrl    string `json:"upload-url"`
+		} `json:"attributes"`
+	} `json:"data"`
+}
+
+func (response configurationVersionResponse) configurationVersion() *ConfigurationVersion {
+	return &ConfigurationVersion{
+		ID:        response.Data.ID,
+		Status:    response.Data.Attributes.Status,
+		UploadUrl: response.Data.Attributes.UploadUrl,
+	}
+}
+
+// Send a request with the given body, if any, as JSON to the given path of the API, and decode the JSON of the
+// response, if any, into the given value
+func (client *Client) do(method string, path string, body interface{}, value interface{}) error {
+	var requestBody io.Reader
+	if body != nil {
+		encoded, err := json.Marshal(body)
+		if err != nil {
+			return errors.WithStackTrace(err)
+		}
+		requestBody = bytes.NewReader(encoded)
+	}
+
+	request, err := http.NewRequest(method, client.Address+"/api/v2"+path, requestBody)
+	if err != nil {
+		return errors.WithStackTrace(err)
+	}
+	request.Header.Set("Authorization", "Bearer "+client.token)
+	request.Header.Set("Content-Type", apiContentType)
+	request.Header.Set("Accept", apiContentType)
+
+	response, err := client.client.Do(request)
+	if err != nil {
+		return errors.WithStackTrace(err)
+	}
+	defer response.Body.Close()
+
+	responseBody, err := ioutil.ReadAll(response.Body)
+	if err != nil {
+		return errors.WithStackTrace(err)
+	}
+	if response.StatusCode >= 300 {
+		return errors.WithStackTrace(APIError{Method: method, Path: path, StatusCode: response.StatusCode, Message: apiErrorMessage(responseBody)})
+	}
+
+	if value == nil || len(responseBody) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(responseBody, value); err != nil {
+		return errors.WithStackTrace(err)
+	}
+	return nil
+}
+
+// Return the details of the errors in the given JSON:API error response, or an empty string if there are none
+func apiErrorMessage(body []byte) string {
+	var response struct {
+		Errors []struct {
+			Title  string `json:"title"`
+			Detail string `json:"detail"`
+		} `json:"errors"`
+	}
+	if err := json.Unmarshal(body, &response); err != nil {
+		return ""
+	}
+
+	messages := []string{}
+	for _, apiError := range response.Errors {
+		message := apiError.Title
+		if apiError.Detail != "" {
+			message = apiError.Detail
+		}
+		messages = append(messages, message)
+	}
+	return strings.Join(messages, "; ")
+}
+
+// Custom error types
+
+type APIError struct {
+	Method     string
+	Path       string
+	StatusCode int
+	Message    string
+}
+
+func (err APIError) Error() string {
+	message := fmt.Sprintf("%s %s failed with status %d", err.Method, err.Path, err.StatusCode)
+	if err.Message != "" {
+		message = fmt.Sprintf("%s: %s", message, err.Message)
+	}
+	return message
+}
+
+type UploadFailed struct {
+	ID         string
+	StatusCode int
+}
+
+func (err UploadFailed) Error() string {
+	return fmt.Sprintf("Could not upload the configuration version %s: status %d", err.ID, err.StatusCode)
+}
+
+type ConfigurationVersionErrored struct {
+	ID      string
+	Message string
+}
+
+func (err ConfigurationVersionErrored) Error() string {
+	return fmt.Sprintf("Terraform Cloud failed to process the configuration version %s: %s", err.ID, err.Message)
+}
+
+type ConfigurationVersionTimedOut struct {
+	ID      string
+	Status  string
+	Timeout time.Duration
+}
+
+func (err ConfigurationVersionTimedOut) Error() string {
+	return fmt.Sprintf("Terraform Cloud did not process the configuration version %s within %v: it's still %s", err.ID, err.Timeout, err.Status)
+}
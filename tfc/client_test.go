@@ -0,0 +1,314 @@
+package tfc
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/gruntwork-io/terragrunt/errors"
+)
+
+// A fake Terraform Cloud API, with a single workspace, that plans the runs created in it, and applies them once they're
+// confirmed
+type fakeAPI struct {
+	mutex       sync.Mutex
+	server      *httptest.Server
+	uploaded    map[string]string
+	workspace   map[string]interface{}
+	run         map[string]interface{}
+	runStatuses []string
+	actions     []string
+}
+
+func newFakeAPI(t *testing.T) *fakeAPI {
+	api := &fakeAPI{}
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("/api/v2/organizations/my-org/workspaces/prod-vpc", func(writer http.ResponseWriter, request *http.Request) {
+		if request.Header.Get("Authorization") != "Bearer my-token" {
+			writer.WriteHeader(http.StatusUnauthorized)
+			fmt.Fprint(writer, `{"errors": [{"status": "401", "title": "unauthorized"}]}`)
+			return
+		}
+		fmt.Fprint(writer, `{"data": {"id": "ws-1", "type": "workspaces", "attributes": {"working-directory": ""}}}`)
+	})
+	mux.HandleFunc("/api/v2/workspaces/ws-1", func(writer http.ResponseWriter, request *http.Request) {
+		assert.Equal(t, http.MethodPatch, request.Method)
+		var body struct {
+			Data map[string]interface{} `json:"data"`
+		}
+		require.NoError(t, json.NewDecoder(request.Body).Decode(&body))
+		api.workspace = body.Data
+		fmt.Fprint(writer, `{"data": {"id": "ws-1", "type": "workspaces"}}`)
+	})
+	mux.HandleFunc("/api/v2/workspaces/ws-1/configuration-versions", func(writer http.ResponseWriter, request *http.Request) {
+		fmt.Fprintf(writer, `{"data": {"id": "cv-1", "attributes": {"status": "pending", "upload-url": "%s/upload/cv-1"}}}`, api.server.URL)
+	})
+	mux.HandleFunc("/upload/cv-1", func(writer http.ResponseWriter, request *http.Request) {
+		assert.Empty(t, request.Header.Get("Authorization"))
+		api.uploaded = readArchive(t, request.Body)
+	})
+	mux.HandleFunc("/api/v2/configuration-versions/cv-1", func(writer http.ResponseWriter, request *http.Request) {
+		fmt.Fprint(writer, `{"data": {"id": "cv-1", "attributes": {"status": "uploaded"}}}`)
+	})
+	mux.HandleFunc("/api/v2/runs", func(writer http.ResponseWriter, request *http.Request) {
+		var body struct {
+			Data map[string]interface{} `json:"data"`
+		}
+		require.NoError(t, json.NewDecoder(request.Body).Decode(&body))
+		api.run = body.Data
+		api.writeRun(writer)
+	})
+	mux.HandleFunc("/api/v2/runs/run-1", func(writer http.ResponseWriter, request *http.Request) {
+		api.writeRun(writer)
+	})
+	mux.HandleFunc("/api/v2/runs/run-1/actions/apply", func(writer http.ResponseWriter, request *http.Request) {
+		api.mutex.Lock()
+		defer api.mutex.Unlock()
+		api.actions = append(api.actions, "apply")
+		api.runStatuses = []string{"applying", "applied"}
+	})
+	mux.HandleFunc("/api/v2/plans/plan-1", func(writer http.ResponseWriter, request *http.Request) {
+		fmt.Fprintf(writer, `{"data": {"attributes": {"status": "finished", "log-read-url": "%s/logs/plan-1"}}}`, api.server.URL)
+	})
+	mux.HandleFunc("/logs/plan-1", func(writer http.ResponseWriter, request *http.Request) {
+		fmt.Fprint(writer, "\x02Plan: 1 to add, 0 to change, 0 to destroy.\n\x03")
+	})
+	mux.HandleFunc("/api/v2/applies/apply-1", func(writer http.ResponseWriter, request *http.Request) {
+		status := "pending"
+		if api.currentStatus() == "applied" {
+			status = "finished"
+		}
+		fmt.Fprintf(writer, `{"data": {"attributes": {"status": "%s", "log-read-url": "%s/logs/apply-1"}}}`, status, api.server.URL)
+	})
+	mux.HandleFunc("/logs/apply-1", func(writer http.ResponseWriter, request *http.Request) {
+		fmt.Fprint(writer, "\x02Apply complete! Resources: 1 added, 0 changed, 0 destroyed.\n\x03")
+	})
+
+	api.server = httptest.NewServer(mux)
+	return api
+}
+
+func (api *fakeAPI) currentStatus() string {
+	api.mutex.Lock()
+	defer api.mutex.Unlock()
+	return api.runStatuses[0]
+}
+
+// Write the run, moving it on to its next status
+func (api *fakeAPI) writeRun(writer http.ResponseWriter) {
+	api.mutex.Lock()
+	defer api.mutex.Unlock()
+	status := api.runStatuses[0]
+	if len(api.runStatuses) > 1 {
+		api.runStatuses = api.runStatuses[1:]
+	}
+	fmt.Fprintf(writer, `{"data": {"id": "run-1", "attributes": {"status": "%s", "has-changes": true, "actions": {"is-confirmable": %t}}, "relationships": {"plan": {"data": {"id": "plan-1"}}, "apply": {"data": {"id": "apply-1"}}}}}`, status, status == "planned")
+}
+
+// Return the contents of the files of the given gzipped tarball, keyed by their path
+func readArchive(t *testing.T, reader io.Reader) map[string]string {
+	gzipReader, err := gzip.NewReader(reader)
+	require.NoError(t, err)
+	tarReader := tar.NewReader(gzipReader)
+
+	files := map[string]string{}
+	for {
+		header, err := tarReader.Next()
+		if err == io.EOF {
+			return files
+		}
+		require.NoError(t, err)
+		if header.Typeflag == tar.TypeDir {
+			continue
+		}
+		contents, err := ioutil.ReadAll(tarReader)
+		require.NoError(t, err)
+		files[header.Name] = string(contents)
+	}
+}
+
+func TestApplyRun(t *testing.T) {
+	t.Parallel()
+
+	api := newFakeAPI(t)
+	defer api.server.Close()
+	api.runStatuses = []string{"pending", "planning", "planned"}
+
+	dir, err := ioutil.TempDir("", "tfc-apply")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "main.tf"), []byte(`variable "cidr" {}`), 0644))
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, ".terraform", "providers"), 0755))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, ".terraform", "providers", "aws"), []byte("binary"), 0644))
+
+	client := NewClient(api.server.URL, "my-token")
+	client.PollInterval = time.Millisecond
+
+	workspace, err := client.Workspace("my-org", "prod-vpc")
+	require.NoError(t, err)
+	configurationVersion, err := client.CreateConfigurationVersion(workspace.ID, false)
+	require.NoError(t, err)
+	require.NoError(t, client.UploadConfiguration(configurationVersion, dir, map[string][]byte{"terragrunt.auto.tfvars.json": []byte(`{"cidr": "10.0.0.0/16"}`)}))
+	assert.Equal(t, map[string]string{"main.tf": `variable "cidr" {}`, "terragrunt.auto.tfvars.json": `{"cidr": "10.0.0.0/16"}`}, api.uploaded)
+
+	run, err := client.CreateRun(workspace.ID, configurationVersion.ID, RunOptions{Message: "apply", TargetAddrs: []string{"aws_vpc.main"}})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{"message": "apply", "is-destroy": false, "plan-only": false, "auto-apply": false, "target-addrs": []interface{}{"aws_vpc.main"}}, api.run["attributes"])
+
+	var logs bytes.Buffer
+	confirmations := 0
+	run, err = client.WatchRun(run.ID, &logs, func(run *Run) (bool, error) {
+		confirmations++
+		return true, nil
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, "applied", run.Status)
+	assert.Equal(t, 1, confirmations)
+	assert.Equal(t, []string{"apply"}, api.actions)
+	assert.Equal(t, "Plan: 1 to add, 0 to change, 0 to destroy.\nApply complete! Resources: 1 added, 0 changed, 0 destroyed.\n", logs.String())
+	assert.Equal(t, 0, ExitCode(run, false))
+}
+
+func TestSetWorkingDirectory(t *testing.T) {
+	t.Parallel()
+
+	api := newFakeAPI(t)
+	defer api.server.Close()
+	client := NewClient(api.server.URL, "my-token")
+
+	workspace, err := client.Workspace("my-org", "prod-vpc")
+	require.NoError(t, err)
+	assert.Equal(t, "", workspace.WorkingDirectory)
+
+	// The workspace is only updated when its working directory changes
+	require.NoError(t, client.SetWorkingDirectory(workspace, ""))
+	assert.Nil(t, api.workspace)
+
+	require.NoError(t, client.SetWorkingDirectory(workspace, "modules/vpc"))
+	assert.Equal(t, map[string]interface{}{"working-directory": "modules/vpc"}, api.workspace["attributes"])
+	assert.Equal(t, "modules/vpc", workspace.WorkingDirectory)
+}
+
+func TestWorkspaceUnauthorized(t *testing.T) {
+	t.Parallel()
+
+	api := newFakeAPI(t)
+	defer api.server.Close()
+
+	_, err := NewClient(api.server.URL, "wrong-token").Workspace("my-org", "prod-vpc")
+	assert.Equal(t, APIError{Method: http.MethodGet, Path: "/organizations/my-org/workspaces/prod-vpc", StatusCode: http.StatusUnauthorized, Message: "unauthorized"}, errors.Unwrap(err))
+}
+
+func TestExitCode(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		status           string
+		hasChanges       bool
+		detailedExitCode bool
+		expected         int
+	}{
+		{"applied", true, false, 0},
+		{"planned_and_finished", true, false, 0},
+		{"planned_and_finished", false, true, 0},
+		{"planned_and_finished", true, true, 2},
+		{"errored", false, false, 1},
+		{"discarded", true, false, 1},
+		{"policy_soft_failed", true, true, 1},
+	}
+
+	for _, testCase := range testCases {
+		run := &Run{Status: testCase.status, HasChanges: testCase.hasChanges}
+		assert.Equal(t, testCase.expected, ExitCode(run, testCase.detailedExitCode), "%+v", testCase)
+	}
+}
+
+func TestWatchRunAwaitingPolicyOverride(t *testing.T) {
+	t.Parallel()
+
+	api := newFakeAPI(t)
+	defer api.server.Close()
+	api.runStatuses = []string{"planning", "policy_checking", "policy_override"}
+
+	client := NewClient(api.server.URL, "my-token")
+	client.PollInterval = time.Millisecond
+
+	// Only someone in the UI can override the policy checks, so the run is not waited on
+	run, err := client.WatchRun("run-1", ioutil.Discard, func(run *Run) (bool, error) {
+		t.Fatal("Expected the run not to be confirmed")
+		return false, nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "policy_override", run.Status)
+	assert.Equal(t, "its soft failed policy checks to be overridden", RunAwaitsDecision(run))
+	assert.Equal(t, 1, ExitCode(run, false))
+}
+
+func TestWatchRunQueueTimeout(t *testing.T) {
+	t.Parallel()
+
+	api := newFakeAPI(t)
+	defer api.server.Close()
+	api.runStatuses = []string{"pending"}
+
+	client := NewClient(api.server.URL, "my-token")
+	client.PollInterval = time.Millisecond
+	client.QueueTimeout = 10 * time.Millisecond
+
+	_, err := client.WatchRun("run-1", ioutil.Discard, nil)
+	assert.Equal(t, RunTimedOut{ID: "run-1", Status: "pending", Timeout: client.QueueTimeout}, errors.Unwrap(err))
+}
+
+func TestWatchRunTimeout(t *testing.T) {
+	t.Parallel()
+
+	api := newFakeAPI(t)
+	defer api.server.Close()
+	api.runStatuses = []string{"planning"}
+
+	client := NewClient(api.server.URL, "my-token")
+	client.PollInterval = time.Millisecond
+	client.RunTimeout = 10 * time.Millisecond
+
+	_, err := client.WatchRun("run-1", ioutil.Discard, nil)
+	assert.Equal(t, RunTimedOut{ID: "run-1", Status: "planning", Timeout: client.RunTimeout}, errors.Unwrap(err))
+}
+
+func TestUploadConfigurationTimeout(t *testing.T) {
+	t.Parallel()
+
+	mux := http.NewServeMux()
+	server := httptest.NewServer(mux)
+	defer server.Close()
+	mux.HandleFunc("/upload/cv-1", func(writer http.ResponseWriter, request *http.Request) {})
+	mux.HandleFunc("/api/v2/configuration-versions/cv-1", func(writer http.ResponseWriter, request *http.Request) {
+		fmt.Fprint(writer, `{"data": {"id": "cv-1", "attributes": {"status": "pending"}}}`)
+	})
+
+	dir, err := ioutil.TempDir("", "tfc-upload")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	client := NewClient(server.URL, "my-token")
+	client.PollInterval = time.Millisecond
+	client.UploadTimeout = 10 * time.Millisecond
+
+	err = client.UploadConfiguration(&ConfigurationVersion{ID: "cv-1", UploadUrl: server.URL + "/upload/cv-1"}, dir, nil)
+	assert.Equal(t, ConfigurationVersionTimedOut{ID: "cv-1", Status: "pending", Timeout: client.UploadTimeout}, errors.Unwrap(err))
+}
@@ -0,0 +1,230 @@
+package tfc
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclparse"
+	"github.com/hashicorp/hcl/v2/hclwrite"
+	"github.com/zclconf/go-cty/cty"
+
+	"github.com/gruntwork-io/terragrunt/errors"
+)
+
+// Return the token to authenticate with the given host: like terraform, from the TF_TOKEN_<host> env var, or else from
+// the credentials file terraform login writes, or else from the credentials blocks of the CLI config. The TFE_TOKEN env
+// var is also supported, for the CI systems that set it.
+func FindToken(hostname string, env map[string]string) (string, error) {
+	if token := env[HostTokenEnvVar(hostname)]; token != "" {
+		return token, nil
+	}
+	if token := env["TFE_TOKEN"]; token != "" {
+		return token, nil
+	}
+
+	token, err := CLIConfigToken(hostname, env)
+	if err != nil {
+		return "", err
+	}
+	if token == "" {
+		return "", errors.WithStackTrace(MissingToken(hostname))
+	}
+	return token, nil
+}
+
+// Return the token terraform itself would authenticate with the given host, e.g. a private module registry, with: from
+// the TF_TOKEN_<host> env var, or else from the credentials.tfrc.json file terraform login writes, or else from the
+// credentials "<host>" { token = "..." } blocks of the CLI config, i.e. the file of the TF_CLI_CONFIG_FILE env var, or
+// ~/.terraformrc. Returns an empty string if there is none.
+func CLIConfigToken(hostname string, env map[string]string) (string, error) {
+	if token := env[HostTokenEnvVar(hostname)]; token != "" {
+		return token, nil
+	}
+
+	credentialsDir := env["TF_CLI_CONFIG_DIR"]
+	homeDir, err := os.UserHomeDir()
+	if credentialsDir == "" {
+		if err != nil {
+			return "", errors.WithStackTrace(err)
+		}
+		credentialsDir = filepath.Join(homeDir, ".terraform.d")
+	}
+	contents, err := ioutil.ReadFile(filepath.Join(credentialsDir, "credentials.tfrc.json"))
+	if err != nil && !os.IsNotExist(err) {
+		return "", errors.WithStackTrace(err)
+	}
+	if err == nil {
+		var credentials struct {
+			Credentials map[string]struct {
+				Token string `json:"token"`
+			} `json:"credentials"`
+		}
+		if err := json.Unmarshal(contents, &credentials); err != nil {
+			return "", errors.WithStackTrace(err)
+		}
+		if token := credentials.Credentials[hostname].Token; token != "" {
+			return token, nil
+		}
+	}
+
+	cliConfigPath := env["TF_CLI_CONFIG_FILE"]
+	if cliConfigPath == "" {
+		if homeDir == "" {
+			return "", nil
+		}
+		cliConfigPath = filepath.Join(homeDir, ".terraformrc")
+	}
+	return cliConfigFileToken(cliConfigPath, hostname)
+}
+
+// The credentials blocks and the credentials helper of the CLI config, which has other blocks and attributes that are of
+// no interest here
+var cliConfigSchema = &hcl.BodySchema{
+	Blocks: []hcl.BlockHeaderSchema{
+		{Type: "credentials", LabelNames: []string{"hostname"}},
+		{Type: "credentials_helper", LabelNames: []string{"name"}},
+	},
+}
+
+var cliConfigCredentialsSchema = &hcl.BodySchema{
+	Attributes: []hcl.AttributeSchema{{Name: "token"}},
+}
+
+// Return the token of the credentials block of the given host in the given CLI config file, or an empty string if the
+// file or the block don't exist
+func cliConfigFileToken(path string, hostname string) (string, error) {
+	contents, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return "", nil
+	}
+	if err != nil {
+		return "", errors.WithStackTrace(err)
+	}
+
+	file, diags := hclparse.NewParser().ParseHCL(contents, path)
+	if diags.HasErrors() {
+		return "", errors.WithStackTrace(InvalidCLIConfig{Path: path, Err: diags})
+	}
+	content, _, diags := file.Body.PartialContent(cliConfigSchema)
+	if diags.HasErrors() {
+		return "", errors.WithStackTrace(InvalidCLIConfig{Path: path, Err: diags})
+	}
+
+	for _, block := range content.Blocks {
+		if block.Type != "credentials" || block.Labels[0] != hostname {
+			continue
+		}
+		credentials, _, diags := block.Body.PartialContent(cliConfigCredentialsSchema)
+		if diags.HasErrors() {
+			return "", errors.WithStackTrace(InvalidCLIConfig{Path: path, Err: diags})
+		}
+		tokenAttr, hasToken := credentials.Attributes["token"]
+		if !hasToken {
+			return "", nil
+		}
+		token, diags := tokenAttr.Expr.Value(nil)
+		if diags.HasErrors() || token.IsNull() || !token.Type().Equals(cty.String) {
+			return "", errors.WithStackTrace(InvalidCLIConfig{Path: path, Err: fmt.Errorf("the token of %s must be a string", hostname)})
+		}
+		return token.AsString(), nil
+	}
+	return "", nil
+}
+
+// CredentialsHelper is the credentials_helper block of a CLI config: the terraform-credentials-<name> plugin terraform
+// gets the tokens of the hosts from, and the args it's run with
+type CredentialsHelper struct {
+	Name string
+	Args []string
+}
+
+// AddCredentialsToCLIConfig returns the given contents of the CLI config file at the given path, with a credentials
+// block for each of the given hosts and tokens, and the given credentials helper, if any, appended. The hosts the CLI
+// config already has a credentials block for keep it, and so does its credentials helper, as terraform only supports
+// one. The hosts, and the name of the credentials helper, that are already configured, and so are skipped, are returned.
+func AddCredentialsToCLIConfig(contents string, path string, tokens map[string]string, helper *CredentialsHelper) (string, []string, error) {
+	configuredHosts := map[string]bool{}
+	hasHelper := false
+	if strings.TrimSpace(contents) != "" {
+		file, diags := hclparse.NewParser().ParseHCL([]byte(contents), path)
+		if diags.HasErrors() {
+			return "", nil, errors.WithStackTrace(InvalidCLIConfig{Path: path, Err: diags})
+		}
+		content, _, diags := file.Body.PartialContent(cliConfigSchema)
+		if diags.HasErrors() {
+			return "", nil, errors.WithStackTrace(InvalidCLIConfig{Path: path, Err: diags})
+		}
+		for _, block := range content.Blocks {
+			if block.Type == "credentials" {
+				configuredHosts[block.Labels[0]] = true
+			} else {
+				hasHelper = true
+			}
+		}
+	}
+
+	hosts := []string{}
+	for host := range tokens {
+		hosts = append(hosts, host)
+	}
+	sort.Strings(hosts)
+
+	skipped := []string{}
+	generated := hclwrite.NewEmptyFile()
+	for _, host := range hosts {
+		if configuredHosts[host] {
+			skipped = append(skipped, host)
+			continue
+		}
+		block := generated.Body().AppendNewBlock("credentials", []string{host})
+		block.Body().SetAttributeValue("token", cty.StringVal(tokens[host]))
+	}
+	if helper != nil && hasHelper {
+		skipped = append(skipped, helper.Name)
+	} else if helper != nil {
+		args := []cty.Value{}
+		for _, arg := range helper.Args {
+			args = append(args, cty.StringVal(arg))
+		}
+		argsValue := cty.ListValEmpty(cty.String)
+		if len(args) > 0 {
+			argsValue = cty.ListVal(args)
+		}
+		block := generated.Body().AppendNewBlock("credentials_helper", []string{helper.Name})
+		block.Body().SetAttributeValue("args", argsValue)
+	}
+
+	if contents != "" && !strings.HasSuffix(contents, "\n") {
+		contents += "\n"
+	}
+	return contents + string(generated.Bytes()), skipped, nil
+}
+
+// HostTokenEnvVar returns the env var terraform reads the token of the given host from: TF_TOKEN_ followed by the host,
+// with its dots replaced with underscores, and its dashes with double underscores
+func HostTokenEnvVar(hostname string) string {
+	return "TF_TOKEN_" + strings.ReplaceAll(strings.ReplaceAll(hostname, "-", "__"), ".", "_")
+}
+
+// Custom error types
+
+type MissingToken string
+
+func (hostname MissingToken) Error() string {
+	return fmt.Sprintf("Found no token for %s. Run terraform login %s, or set the %s env var.", string(hostname), string(hostname), HostTokenEnvVar(string(hostname)))
+}
+
+type InvalidCLIConfig struct {
+	Path string
+	Err  error
+}
+
+func (err InvalidCLIConfig) Error() string {
+	return fmt.Sprintf("Could not read the credentials of the terraform CLI config %s: %v", err.Path, err.Err)
+}
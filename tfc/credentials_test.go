@@ -0,0 +1,118 @@
+package tfc
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/gruntwork-io/terragrunt/errors"
+)
+
+func TestFindToken(t *testing.T) {
+	t.Parallel()
+
+	configDir, err := ioutil.TempDir("", "tfc-credentials")
+	require.NoError(t, err)
+	defer os.RemoveAll(configDir)
+	require.NoError(t, ioutil.WriteFile(filepath.Join(configDir, "credentials.tfrc.json"), []byte(`{"credentials": {"tfe.example.com": {"token": "file-token"}}}`), 0600))
+
+	token, err := FindToken("app.terraform.io", map[string]string{"TF_TOKEN_app_terraform_io": "env-token", "TFE_TOKEN": "tfe-token"})
+	require.NoError(t, err)
+	assert.Equal(t, "env-token", token)
+
+	token, err = FindToken("my-tfe.example.com", map[string]string{"TF_TOKEN_my__tfe_example_com": "dashed-token"})
+	require.NoError(t, err)
+	assert.Equal(t, "dashed-token", token)
+
+	token, err = FindToken("tfe.example.com", map[string]string{"TF_CLI_CONFIG_DIR": configDir})
+	require.NoError(t, err)
+	assert.Equal(t, "file-token", token)
+
+	_, err = FindToken("app.terraform.io", map[string]string{"TF_CLI_CONFIG_DIR": configDir})
+	assert.Equal(t, MissingToken("app.terraform.io"), errors.Unwrap(err))
+}
+
+func TestCLIConfigToken(t *testing.T) {
+	t.Parallel()
+
+	configDir, err := ioutil.TempDir("", "terraform-cli-config")
+	require.NoError(t, err)
+	defer os.RemoveAll(configDir)
+	cliConfigPath := filepath.Join(configDir, ".terraformrc")
+	cliConfig := `
+plugin_cache_dir = "/tmp/plugins"
+
+credentials "artifactory.example.com" {
+  token = "rc-token"
+}
+
+credentials "nexus.example.com" {}
+`
+	require.NoError(t, ioutil.WriteFile(cliConfigPath, []byte(cliConfig), 0600))
+	env := map[string]string{"TF_CLI_CONFIG_DIR": configDir, "TF_CLI_CONFIG_FILE": cliConfigPath, "TFE_TOKEN": "tfe-token"}
+
+	token, err := CLIConfigToken("artifactory.example.com", env)
+	require.NoError(t, err)
+	assert.Equal(t, "rc-token", token)
+
+	token, err = CLIConfigToken("my-registry.example.com", map[string]string{"TF_TOKEN_my__registry_example_com": "env-token"})
+	require.NoError(t, err)
+	assert.Equal(t, "env-token", token)
+
+	// Unlike FindToken, the TFE_TOKEN env var is only for Terraform Cloud
+	for _, hostname := range []string{"nexus.example.com", "registry.terraform.io"} {
+		token, err = CLIConfigToken(hostname, env)
+		require.NoError(t, err)
+		assert.Equal(t, "", token, hostname)
+	}
+
+	require.NoError(t, ioutil.WriteFile(cliConfigPath, []byte(`credentials "artifactory.example.com" {`), 0600))
+	_, err = CLIConfigToken("artifactory.example.com", env)
+	assert.IsType(t, InvalidCLIConfig{}, errors.Unwrap(err))
+}
+
+func TestAddCredentialsToCLIConfig(t *testing.T) {
+	t.Parallel()
+
+	existing := `plugin_cache_dir = "/tmp/plugins"
+
+credentials "registry.acme.com" {
+  token = "user-token"
+}`
+	tokens := map[string]string{"registry.acme.com": "config-token", "app.terraform.io": "tfc-token"}
+	contents, skipped, err := AddCredentialsToCLIConfig(existing, ".terraformrc", tokens, &CredentialsHelper{Name: "credstore", Args: []string{"--host=credstore.acme.com"}})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"registry.acme.com"}, skipped)
+	assert.Equal(t, existing+`
+credentials "app.terraform.io" {
+  token = "tfc-token"
+}
+credentials_helper "credstore" {
+  args = ["--host=credstore.acme.com"]
+}
+`, contents)
+
+	configDir, err := ioutil.TempDir("", "terraform-cli-config")
+	require.NoError(t, err)
+	defer os.RemoveAll(configDir)
+	configPath := filepath.Join(configDir, "terragrunt.tfrc")
+	require.NoError(t, ioutil.WriteFile(configPath, []byte(contents), 0600))
+	for host, expectedToken := range map[string]string{"registry.acme.com": "user-token", "app.terraform.io": "tfc-token"} {
+		token, err := cliConfigFileToken(configPath, host)
+		require.NoError(t, err)
+		assert.Equal(t, expectedToken, token)
+	}
+
+	contents, skipped, err = AddCredentialsToCLIConfig(`credentials_helper "vault" {}`, ".terraformrc", nil, &CredentialsHelper{Name: "credstore"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"credstore"}, skipped)
+	assert.Equal(t, "credentials_helper \"vault\" {}\n", contents)
+
+	_, _, err = AddCredentialsToCLIConfig(`credentials "registry.acme.com" {`, ".terraformrc", tokens, nil)
+	_, isInvalidCLIConfig := errors.Unwrap(err).(InvalidCLIConfig)
+	assert.True(t, isInvalidCLIConfig, "Unexpected error: %v", err)
+}
@@ -0,0 +1,316 @@
+package tfc
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/gruntwork-io/terragrunt/errors"
+)
+
+// The statuses runs end in
+var finishedRunStatuses = map[string]bool{
+	"applied":              true,
+	"planned_and_finished": true,
+	"planned_and_saved":    true,
+	"errored":              true,
+	"discarded":            true,
+	"canceled":             true,
+	"force_canceled":       true,
+	"policy_soft_failed":   true,
+}
+
+// The statuses of the runs that wait for someone to decide in the UI of Terraform Cloud whether they go on, by what they
+// wait for. Terragrunt can't make that decision, so these runs are treated as having ended.
+var awaitingDecisionRunStatuses = map[string]string{
+	"policy_override":             "its soft failed policy checks to be overridden",
+	"post_plan_awaiting_decision": "a decision on its failed run tasks",
+	"pre_apply_awaiting_decision": "a decision on its failed run tasks",
+}
+
+// The status of the runs that wait in the queue of their workspace for the runs before them to end
+const pendingRunStatus = "pending"
+
+// The statuses of plans and applies that have no logs yet
+var statusesWithoutLogs = map[string]bool{
+	"pending":     true,
+	"unreachable": true,
+}
+
+// The chars the logs of plans and applies start and end with
+const (
+	logStartChar = "\x02"
+	logEndChar   = "\x03"
+)
+
+// RunOptions are the options of a run, as set by the arguments of the terraform command it replaces
+type RunOptions struct {
+	Message string
+	// Whether the run destroys the resources of the workspace, like terraform destroy
+	IsDestroy bool
+	// Whether the run only plans, like terraform plan. Runs of speculative configuration versions only plan anyway.
+	PlanOnly bool
+	// Whether the run is applied without waiting for a confirmation, like terraform apply -auto-approve
+	AutoApply    bool
+	RefreshOnly  bool
+	TargetAddrs  []string
+	ReplaceAddrs []string
+}
+
+// Run is a run of a workspace
+type Run struct {
+	ID     string
+	Status string
+	// Whether the plan of the run has changes
+	HasChanges bool
+	// Whether the run waits for a confirmation to be applied
+	IsConfirmable bool
+	PlanID        string
+	ApplyID       string
+}
+
+// Create a run of the given configuration version of the given workspace
+func (client *Client) CreateRun(workspaceID string, configurationVersionID string, options RunOptions) (*Run, error) {
+	attributes := map[string]interface{}{
+		"message":    options.Message,
+		"is-destroy": options.IsDestroy,
+		"plan-only":  options.PlanOnly,
+		"auto-apply": options.AutoApply,
+	}
+	if options.RefreshOnly {
+		attributes["refresh-only"] = true
+	}
+	if len(options.TargetAddrs) > 0 {
+		attributes["target-addrs"] = options.TargetAddrs
+	}
+	if len(options.ReplaceAddrs) > 0 {
+		attributes["replace-addrs"] = options.ReplaceAddrs
+	}
+	request := map[string]interface{}{
+		"data": map[string]interface{}{
+			"type":       "runs",
+			"attributes": attributes,
+			"relationships": map[string]interface{}{
+				"workspace":             relationship("workspaces", workspaceID),
+				"configuration-version": relationship("configuration-versions", configurationVersionID),
+			},
+		},
+	}
+
+	var response runResponse
+	if err := client.do(http.MethodPost, "/runs", request, &response); err != nil {
+		return nil, err
+	}
+	return response.run(), nil
+}
+
+// Return the run with the given ID
+func (client *Client) Run(runID string) (*Run, error) {
+	var response runResponse
+	if err := client.do(http.MethodGet, "/runs/"+url.PathEscape(runID), nil, &response); err != nil {
+		return nil, err
+	}
+	return response.run(), nil
+}
+
+// Follow the run with the given ID until it ends, or waits for a decision in the UI, writing the logs of its plan and
+// apply to the given writer as they come in, and return it once it has. When the run waits for a confirmation, the
+// given function is called to decide whether to apply or discard it. A run that stays in the queue of its workspace for
+// longer than the queue timeout of the client, or takes longer than its run timeout, is given up on.
+func (client *Client) WatchRun(runID string, writer io.Writer, confirm func(run *Run) (bool, error)) (*Run, error) {
+	planLog := &logStream{kind: "plans", writer: writer}
+	applyLog := &logStream{kind: "applies", writer: writer}
+	confirmed := false
+	started := time.Now()
+	deadline := started.Add(client.RunTimeout)
+
+	for {
+		// The run is read before the logs, so that once it has ended, the logs are complete
+		run, err := client.Run(runID)
+		if err != nil {
+			return nil, err
+		}
+		if err := client.streamLog(planLog, run.PlanID); err != nil {
+			return nil, err
+		}
+		if err := client.streamLog(applyLog, run.ApplyID); err != nil {
+			return nil, err
+		}
+
+		if finishedRunStatuses[run.Status] || RunAwaitsDecision(run) != "" {
+			return run, nil
+		}
+
+		if run.IsConfirmable && !confirmed {
+			confirmed = true
+			apply, err := confirm(run)
+			if err != nil {
+				return nil, err
+			}
+			action := "discard"
+			if apply {
+				action = "apply"
+			}
+			body := map[string]string{"comment": fmt.Sprintf("%s by terragrunt", action)}
+			if err := client.do(http.MethodPost, fmt.Sprintf("/runs/%s/actions/%s", url.PathEscape(runID), action), body, nil); err != nil {
+				return nil, err
+			}
+			// The time waiting for the confirmation doesn't count
+			deadline = time.Now().Add(client.RunTimeout)
+			continue
+		}
+
+		now := time.Now()
+		if run.Status == pendingRunStatus && now.After(started.Add(client.QueueTimeout)) {
+			return nil, errors.WithStackTrace(RunTimedOut{ID: runID, Status: run.Status, Timeout: client.QueueTimeout})
+		}
+		if now.After(deadline) {
+			return nil, errors.WithStackTrace(RunTimedOut{ID: runID, Status: run.Status, Timeout: client.RunTimeout})
+		}
+
+		time.Sleep(client.PollInterval)
+	}
+}
+
+// Return what the given run waits for someone to decide in the UI of Terraform Cloud, e.g. to override its soft failed
+// policy checks, or an empty string if it doesn't wait for a decision
+func RunAwaitsDecision(run *Run) string {
+	return awaitingDecisionRunStatuses[run.Status]
+}
+
+// Return the exit code of the terraform command the given run replaces: 0 if the run was planned or applied, and 1 if
+// it failed, or was discarded or canceled. With detailedExitCode, like with terraform plan -detailed-exitcode, a plan
+// with changes has the exit code 2. A run that waits for a decision in the UI has the exit code 1 too.
+func ExitCode(run *Run, detailedExitCode bool) int {
+	switch run.Status {
+	case "applied":
+		return 0
+	case "planned_and_finished", "planned_and_saved":
+		if detailedExitCode && run.HasChanges {
+			return 2
+		}
+		return 0
+	default:
+		return 1
+	}
+}
+
+// The log of a plan or an apply, which is written to the writer as it comes in
+type logStream struct {
+	kind    string
+	writer  io.Writer
+	written int
+}
+
+// Write the part of the log of the given plan or apply that hasn't been written yet to the writer of the given stream
+func (client *Client) streamLog(stream *logStream, id string) error {
+	if id == "" {
+		return nil
+	}
+
+	var response struct {
+		Data struct {
+			Attributes struct {
+				Status     string `json:"status"`
+				LogReadUrl string `json:"log-read-url"`
+			} `json:"attributes"`
+		} `json:"data"`
+	}
+	if err := client.do(http.MethodGet, fmt.Sprintf("/%s/%s", stream.kind, url.PathEscape(id)), nil, &response); err != nil {
+		return err
+	}
+	if statusesWithoutLogs[response.Data.Attributes.Status] || response.Data.Attributes.LogReadUrl == "" {
+		return nil
+	}
+
+	// The log read URL is signed, so the token isn't sent along
+	logResponse, err := client.client.Get(response.Data.Attributes.LogReadUrl)
+	if err != nil {
+		return errors.WithStackTrace(err)
+	}
+	defer logResponse.Body.Close()
+	if logResponse.StatusCode >= 300 {
+		return errors.WithStackTrace(LogUnreadable{Kind: stream.kind, ID: id, StatusCode: logResponse.StatusCode})
+	}
+	log, err := ioutil.ReadAll(logResponse.Body)
+	if err != nil {
+		return errors.WithStackTrace(err)
+	}
+
+	log = bytes.TrimSuffix(bytes.TrimPrefix(log, []byte(logStartChar)), []byte(logEndChar))
+	if len(log) <= stream.written {
+		return nil
+	}
+	if _, err := stream.writer.Write(log[stream.written:]); err != nil {
+		return errors.WithStackTrace(err)
+	}
+	stream.written = len(log)
+	return nil
+}
+
+type runResponse struct {
+	Data struct {
+		ID         string `json:"id"`
+		Attributes struct {
+			Status     string `json:"status"`
+			HasChanges bool   `json:"has-changes"`
+			Actions    struct {
+				IsConfirmable bool `json:"is-confirmable"`
+			} `json:"actions"`
+		} `json:"attributes"`
+		Relationships struct {
+			Plan  relationshipData `json:"plan"`
+			Apply relationshipData `json:"apply"`
+		} `json:"relationships"`
+	} `json:"data"`
+}
+
+type relationshipData struct {
+	Data struct {
+		ID string `json:"id"`
+	} `json:"data"`
+}
+
+func (response runResponse) run() *Run {
+	return &Run{
+		ID:            response.Data.ID,
+		Status:        response.Data.Attributes.Status,
+		HasChanges:    response.Data.Attributes.HasChanges,
+		IsConfirmable: response.Data.Attributes.Actions.IsConfirmable,
+		PlanID:        response.Data.Relationships.Plan.Data.ID,
+		ApplyID:       response.Data.Relationships.Apply.Data.ID,
+	}
+}
+
+func relationship(resourceType string, id string) map[string]interface{} {
+	return map[string]interface{}{"data": map[string]string{"type": resourceType, "id": id}}
+}
+
+// Custom error types
+
+type LogUnreadable struct {
+	Kind       string
+	ID         string
+	StatusCode int
+}
+
+func (err LogUnreadable) Error() string {
+	return fmt.Sprintf("Could not read the log of %s/%s: status %d", err.Kind, err.ID, err.StatusCode)
+}
+
+type RunTimedOut struct {
+	ID      string
+	Status  string
+	Timeout time.Duration
+}
+
+func (err RunTimedOut) Error() string {
+	if err.Status == pendingRunStatus {
+		return fmt.Sprintf("The run %s waited in the queue of its workspace for more than %v, behind the other runs of the workspace", err.ID, err.Timeout)
+	}
+	return fmt.Sprintf("The run %s did not end within %v: it's still %s", err.ID, err.Timeout, err.Status)
+}
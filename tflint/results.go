@@ -0,0 +1,170 @@
+// Package tflint parses the issues tflint finds in the Terraform code of the units, and adds them up across the units of
+// a run, so that run-all tflint ends with a summary of the whole stack. See https://github.com/terraform-linters/tflint
+package tflint
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/gruntwork-io/terragrunt/errors"
+	"github.com/gruntwork-io/terragrunt/util"
+)
+
+// The severities of the issues, from the most to the least severe
+var severities = []string{"error", "warning", "notice"}
+
+// The severities tflint calls differently with --format json than everywhere else, e.g. in --minimum-failure-severity
+var jsonSeverities = map[string]string{"info": "notice"}
+
+// Issue is an issue tflint found in the Terraform code of a unit
+type Issue struct {
+	Rule     string
+	Severity string
+	Message  string
+	// The file the issue is in, relative to the working dir tflint ran in
+	File   string
+	Line   int
+	Column int
+}
+
+// String renders the issue like the compact format of tflint, e.g.
+// "main.tf:3:1: warning - variable "name" is declared but not used (terraform_unused_declarations)"
+func (issue Issue) String() string {
+	return fmt.Sprintf("%s:%d:%d: %s - %s (%s)", issue.File, issue.Line, issue.Column, issue.Severity, issue.Message, issue.Rule)
+}
+
+// Output is the outcome of running tflint in a unit: the issues it found, and the errors that kept it from linting the
+// code, such as an invalid .tflint.hcl
+type Output struct {
+	Issues []Issue
+	Errors []string
+}
+
+// The output of tflint --format json
+type tflintOutput struct {
+	Issues []struct {
+		Rule struct {
+			Name     string `json:"name"`
+			Severity string `json:"severity"`
+		} `json:"rule"`
+		Message string `json:"message"`
+		Range   struct {
+			Filename string `json:"filename"`
+			Start    struct {
+				Line   int `json:"line"`
+				Column int `json:"column"`
+			} `json:"start"`
+		} `json:"range"`
+	} `json:"issues"`
+	Errors []struct {
+		Message string `json:"message"`
+	} `json:"errors"`
+}
+
+// ParseOutput parses the output of tflint --format json
+func ParseOutput(output []byte) (*Output, error) {
+	var parsed tflintOutput
+	if err := json.Unmarshal(output, &parsed); err != nil {
+		return nil, errors.WithStackTrace(err)
+	}
+
+	result := &Output{Issues: []Issue{}, Errors: []string{}}
+	for _, issue := range parsed.Issues {
+		severity := strings.ToLower(issue.Rule.Severity)
+		if renamedSeverity, isRenamed := jsonSeverities[severity]; isRenamed {
+			severity = renamedSeverity
+		}
+		result.Issues = append(result.Issues, Issue{
+			Rule:     issue.Rule.Name,
+			Severity: severity,
+			Message:  issue.Message,
+			File:     issue.Range.Filename,
+			Line:     issue.Range.Start.Line,
+			Column:   issue.Range.Start.Column,
+		})
+	}
+	for _, tflintError := range parsed.Errors {
+		result.Errors = append(result.Errors, tflintError.Message)
+	}
+	return result, nil
+}
+
+// Results adds up the issues tflint found in the units of a run. All the methods of Results can be called on nil
+// results, which is what is used when the command isn't tflint.
+type Results struct {
+	mutex           sync.Mutex
+	units           int
+	unitsWithIssues int
+	counts          map[string]int
+}
+
+// Create results without any units
+func NewResults() *Results {
+	return &Results{counts: map[string]int{}}
+}
+
+// Add the issues tflint found in a unit to the results
+func (results *Results) Add(issues []Issue) {
+	if results == nil {
+		return
+	}
+
+	results.mutex.Lock()
+	defer results.mutex.Unlock()
+	results.units++
+	if len(issues) > 0 {
+		results.unitsWithIssues++
+	}
+	for _, issue := range issues {
+		results.counts[issue.Severity]++
+	}
+}
+
+// Summary returns a one line summary of the issues found in the units so far, e.g.
+// "tflint found 3 issues in 2 of 5 units: 1 error, 2 warnings". Returns an empty string if no units were linted.
+func (results *Results) Summary() string {
+	if results == nil {
+		return ""
+	}
+
+	results.mutex.Lock()
+	defer results.mutex.Unlock()
+	if results.units == 0 {
+		return ""
+	}
+
+	total := 0
+	for _, count := range results.counts {
+		total += count
+	}
+	summary := fmt.Sprintf("tflint found %s in %d of %s", plural(total, "issue"), results.unitsWithIssues, plural(results.units, "unit"))
+	if total == 0 {
+		return summary
+	}
+
+	otherSeverities := []string{}
+	for severity := range results.counts {
+		if !util.ListContainsElement(severities, severity) {
+			otherSeverities = append(otherSeverities, severity)
+		}
+	}
+	sort.Strings(otherSeverities)
+
+	counts := []string{}
+	for _, severity := range append(append([]string{}, severities...), otherSeverities...) {
+		if count := results.counts[severity]; count > 0 {
+			counts = append(counts, plural(count, severity))
+		}
+	}
+	return summary + ": " + strings.Join(counts, ", ")
+}
+
+func plural(count int, noun string) string {
+	if count == 1 {
+		return fmt.Sprintf("%d %s", count, noun)
+	}
+	return fmt.Sprintf("%d %ss", count, noun)
+}
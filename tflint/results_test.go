@@ -0,0 +1,62 @@
+package tflint
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseOutput(t *testing.T) {
+	t.Parallel()
+
+	output, err := ParseOutput([]byte(`{
+  "issues": [
+    {
+      "rule": {"name": "terraform_unused_declarations", "severity": "warning", "link": "https://github.com/terraform-linters/tflint-ruleset-terraform"},
+      "message": "variable \"name\" is declared but not used",
+      "range": {"filename": "variables.tf", "start": {"line": 3, "column": 1}, "end": {"line": 3, "column": 16}},
+      "callers": []
+    },
+    {
+      "rule": {"name": "terraform_deprecated_index", "severity": "info", "link": ""},
+      "message": "List items should be accessed using square brackets",
+      "range": {"filename": "main.tf", "start": {"line": 10, "column": 5}, "end": {"line": 10, "column": 20}},
+      "callers": []
+    }
+  ],
+  "errors": []
+}`))
+	require.NoError(t, err)
+	assert.Equal(t, []string{}, output.Errors)
+	assert.Equal(t, []Issue{
+		{Rule: "terraform_unused_declarations", Severity: "warning", Message: `variable "name" is declared but not used`, File: "variables.tf", Line: 3, Column: 1},
+		{Rule: "terraform_deprecated_index", Severity: "notice", Message: "List items should be accessed using square brackets", File: "main.tf", Line: 10, Column: 5},
+	}, output.Issues)
+	assert.Equal(t, `variables.tf:3:1: warning - variable "name" is declared but not used (terraform_unused_declarations)`, output.Issues[0].String())
+
+	output, err = ParseOutput([]byte(`{"issues": [], "errors": [{"message": "Failed to load configurations", "severity": "error"}]}`))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Failed to load configurations"}, output.Errors)
+
+	_, err = ParseOutput([]byte("Failed to initialize plugins"))
+	assert.Error(t, err)
+}
+
+func TestResultsSummary(t *testing.T) {
+	t.Parallel()
+
+	results := NewResults()
+	assert.Equal(t, "", results.Summary())
+
+	results.Add([]Issue{})
+	assert.Equal(t, "tflint found 0 issues in 0 of 1 unit", results.Summary())
+
+	results.Add([]Issue{{Severity: "warning"}, {Severity: "error"}, {Severity: "warning"}})
+	results.Add([]Issue{{Severity: "notice"}})
+	assert.Equal(t, "tflint found 4 issues in 2 of 3 units: 1 error, 2 warnings, 1 notice", results.Summary())
+
+	var nilResults *Results
+	nilResults.Add([]Issue{{Severity: "error"}})
+	assert.Equal(t, "", nilResults.Summary())
+}
@@ -0,0 +1,137 @@
+// Package tfr downloads Terraform modules from registries that implement the module registry protocol, e.g. the public
+// Terraform Registry, the private registry of Terraform Cloud, Artifactory or Nexus, so that terraform.source can point
+// at them with the tfr:// scheme. Like terraform, the registries are authenticated with the tokens of the terraform CLI
+// config.
+package tfr
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/go-getter"
+
+	"github.com/gruntwork-io/terragrunt/errors"
+	"github.com/gruntwork-io/terragrunt/tfc"
+)
+
+// The scheme of the sources that point at modules in registries
+const SCHEME = "tfr"
+
+// How long to wait for a response of the registry
+const requestTimeout = time.Minute
+
+// Getter is a go-getter getter that asks the registry of the tfr:// URL where the version of the module it refers to is
+// downloaded from, and downloads it from there with the getters of the client, e.g. the git getter for the public
+// registry, which points at GitHub, or the http getter for the archives Artifactory and Nexus host themselves.
+type Getter struct {
+	// The env vars the tokens of the registries are looked up in. Defaults to the env vars of the process.
+	Env map[string]string
+
+	client *getter.Client
+}
+
+func (tfrGetter *Getter) ClientMode(sourceUrl *url.URL) (getter.ClientMode, error) {
+	return getter.ClientModeDir, nil
+}
+
+func (tfrGetter *Getter) SetClient(client *getter.Client) {
+	tfrGetter.client = client
+}
+
+// Download the module the given URL refers to into the given dir
+func (tfrGetter *Getter) Get(dst string, sourceUrl *url.URL) error {
+	address, constraint, err := ParseModuleUrl(sourceUrl)
+	if err != nil {
+		return err
+	}
+
+	env := tfrGetter.Env
+	if env == nil {
+		env = processEnv()
+	}
+	token, err := tfc.CLIConfigToken(address.Host, env)
+	if err != nil {
+		return err
+	}
+
+	client := &registryClient{address: address, token: token, httpClient: &http.Client{Timeout: requestTimeout}}
+	modulesUrl, err := client.modulesUrl()
+	if err != nil {
+		return err
+	}
+	moduleVersion, err := client.resolveVersion(modulesUrl, constraint)
+	if err != nil {
+		return err
+	}
+	location, err := client.downloadLocation(modulesUrl, moduleVersion)
+	if err != nil {
+		return err
+	}
+
+	return tfrGetter.download(dst, location, address.Host, token)
+}
+
+// Modules are folders, so downloading a single file isn't supported
+func (tfrGetter *Getter) GetFile(dst string, sourceUrl *url.URL) error {
+	return errors.WithStackTrace(SingleFileNotSupported(sourceUrl.String()))
+}
+
+// Download the given location of a module into the given dir, with the options of the client of the getter. The
+// archives the registry hosts itself are downloaded with the token of the registry, as Artifactory and Nexus require it.
+func (tfrGetter *Getter) download(dst string, location string, registryHost string, token string) error {
+	ctx := context.Background()
+	pwd := ""
+	options := []getter.ClientOption{}
+	if tfrGetter.client != nil {
+		ctx = tfrGetter.client.Ctx
+		pwd = tfrGetter.client.Pwd
+		options = append(options, tfrGetter.client.Options...)
+	}
+
+	if token != "" {
+		options = append(options, func(client *getter.Client) error {
+			locationUrl, err := url.Parse(location)
+			if err != nil || locationUrl.Host != registryHost || !strings.HasPrefix(locationUrl.Scheme, "http") {
+				return nil
+			}
+			if client.Getters == nil {
+				client.Getters = map[string]getter.Getter{}
+				for name, value := range getter.Getters {
+					client.Getters[name] = value
+				}
+			}
+			httpGetter := &getter.HttpGetter{Netrc: true, Header: http.Header{"Authorization": []string{"Bearer " + token}}}
+			client.Getters["http"] = httpGetter
+			client.Getters["https"] = httpGetter
+			return nil
+		})
+	}
+
+	client := &getter.Client{Ctx: ctx, Src: location, Dst: dst, Pwd: pwd, Mode: getter.ClientModeDir, Options: options}
+	return errors.WithStackTrace(client.Get())
+}
+
+// Return the env vars of the process as a map
+func processEnv() map[string]string {
+	env := map[string]string{}
+	for _, keyValue := range os.Environ() {
+		keyAndValue := strings.SplitN(keyValue, "=", 2)
+		if len(keyAndValue) == 2 {
+			env[keyAndValue[0]] = keyAndValue[1]
+		}
+	}
+	return env
+}
+
+// Custom error types
+
+type SingleFileNotSupported string
+
+func (source SingleFileNotSupported) Error() string {
+	return fmt.Sprintf("Could not download %s: registry modules can only be downloaded as a module folder", string(source))
+}
@@ -0,0 +1,159 @@
+package tfr
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/gruntwork-io/terragrunt/errors"
+)
+
+func TestParseModuleUrl(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		source             string
+		expectedAddress    ModuleAddress
+		expectedConstraint string
+	}{
+		{"tfr:///terraform-aws-modules/vpc/aws?version=3.3.0", ModuleAddress{Host: DEFAULT_REGISTRY_HOST, Namespace: "terraform-aws-modules", Name: "vpc", Provider: "aws"}, "3.3.0"},
+		{"tfr://artifactory.example.com/acme__modules/vpc/aws?version=~>%201.0", ModuleAddress{Host: "artifactory.example.com", Namespace: "acme__modules", Name: "vpc", Provider: "aws"}, "~> 1.0"},
+		{"tfr://app.terraform.io/acme/vpc/aws", ModuleAddress{Host: "app.terraform.io", Namespace: "acme", Name: "vpc", Provider: "aws"}, ""},
+	}
+
+	for _, testCase := range testCases {
+		sourceUrl, err := url.Parse(testCase.source)
+		require.NoError(t, err)
+		address, constraint, err := ParseModuleUrl(sourceUrl)
+		require.NoError(t, err, testCase.source)
+		assert.Equal(t, testCase.expectedAddress, *address, testCase.source)
+		assert.Equal(t, testCase.expectedConstraint, constraint, testCase.source)
+	}
+
+	sourceUrl, err := url.Parse("tfr://registry.example.com/acme/vpc")
+	require.NoError(t, err)
+	_, _, err = ParseModuleUrl(sourceUrl)
+	assert.IsType(t, InvalidModuleUrl(""), errors.Unwrap(err))
+}
+
+// A fake private registry, like Artifactory, with the versions 1.0.0, 1.1.0 and 2.0.0-beta of the module acme/vpc/aws,
+// whose archives it hosts itself. Every request requires the token s3cr3t.
+func newFakeRegistry(t *testing.T) *httptest.Server {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/terraform.json", func(writer http.ResponseWriter, request *http.Request) {
+		fmt.Fprint(writer, `{"modules.v1": "/api/modules/v1/", "providers.v1": "/api/providers/v1/"}`)
+	})
+	mux.HandleFunc("/api/modules/v1/acme/vpc/aws/versions", func(writer http.ResponseWriter, request *http.Request) {
+		fmt.Fprint(writer, `{"modules": [{"versions": [{"version": "1.0.0"}, {"version": "1.1.0"}, {"version": "2.0.0-beta"}]}]}`)
+	})
+	mux.HandleFunc("/api/modules/v1/acme/vpc/aws/", func(writer http.ResponseWriter, request *http.Request) {
+		moduleVersion := filepath.Base(filepath.Dir(request.URL.Path))
+		writer.Header().Set("X-Terraform-Get", fmt.Sprintf("/archives/vpc-%s.tar.gz", moduleVersion))
+		writer.WriteHeader(http.StatusNoContent)
+	})
+	mux.HandleFunc("/archives/", func(writer http.ResponseWriter, request *http.Request) {
+		writer.Write(createTarball(t, map[string]string{"main.tf": "# " + request.URL.Path, "modules/subnet/main.tf": `variable "cidr" {}`}))
+	})
+
+	return httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		if request.Header.Get("Authorization") != "Bearer s3cr3t" {
+			writer.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		mux.ServeHTTP(writer, request)
+	}))
+}
+
+func createTarball(t *testing.T, files map[string]string) []byte {
+	var buffer bytes.Buffer
+	gzipWriter := gzip.NewWriter(&buffer)
+	tarWriter := tar.NewWriter(gzipWriter)
+	for path, contents := range files {
+		require.NoError(t, tarWriter.WriteHeader(&tar.Header{Name: path, Mode: 0644, Size: int64(len(contents)), Typeflag: tar.TypeReg}))
+		_, err := tarWriter.Write([]byte(contents))
+		require.NoError(t, err)
+	}
+	require.NoError(t, tarWriter.Close())
+	require.NoError(t, gzipWriter.Close())
+	return buffer.Bytes()
+}
+
+func TestGetterDownloadsModuleFromPrivateRegistry(t *testing.T) {
+	t.Parallel()
+
+	server := newFakeRegistry(t)
+	defer server.Close()
+	registryHost := server.Listener.Addr().String()
+
+	configDir, err := ioutil.TempDir("", "terraform-cli-config")
+	require.NoError(t, err)
+	defer os.RemoveAll(configDir)
+	cliConfigPath := filepath.Join(configDir, ".terraformrc")
+	require.NoError(t, ioutil.WriteFile(cliConfigPath, []byte(fmt.Sprintf("credentials %q {\n  token = \"s3cr3t\"\n}\n", registryHost)), 0600))
+	env := map[string]string{"TF_CLI_CONFIG_DIR": configDir, "TF_CLI_CONFIG_FILE": cliConfigPath}
+
+	testCases := []struct {
+		constraint      string
+		expectedVersion string
+	}{
+		{"", "1.1.0"},
+		{"~>%201.0.0", "1.0.0"},
+		{"2.0.0-beta", "2.0.0-beta"},
+	}
+
+	for _, testCase := range testCases {
+		dst, err := ioutil.TempDir("", "tfr-module")
+		require.NoError(t, err)
+		defer os.RemoveAll(dst)
+
+		sourceUrl, err := url.Parse(fmt.Sprintf("tfr://%s/acme/vpc/aws?version=%s", registryHost, testCase.constraint))
+		require.NoError(t, err)
+		require.NoError(t, (&Getter{Env: env}).Get(dst, sourceUrl), testCase.constraint)
+
+		contents, err := ioutil.ReadFile(filepath.Join(dst, "main.tf"))
+		require.NoError(t, err)
+		assert.Equal(t, fmt.Sprintf("# /archives/vpc-%s.tar.gz", testCase.expectedVersion), string(contents), testCase.constraint)
+		assert.FileExists(t, filepath.Join(dst, "modules", "subnet", "main.tf"))
+	}
+}
+
+func TestGetterRegistryErrors(t *testing.T) {
+	t.Parallel()
+
+	server := newFakeRegistry(t)
+	defer server.Close()
+	registryHost := server.Listener.Addr().String()
+
+	configDir, err := ioutil.TempDir("", "terraform-cli-config")
+	require.NoError(t, err)
+	defer os.RemoveAll(configDir)
+	dst, err := ioutil.TempDir("", "tfr-module")
+	require.NoError(t, err)
+	defer os.RemoveAll(dst)
+
+	sourceUrl, err := url.Parse(fmt.Sprintf("tfr://%s/acme/vpc/aws", registryHost))
+	require.NoError(t, err)
+	err = (&Getter{Env: map[string]string{"TF_CLI_CONFIG_DIR": configDir, "TF_CLI_CONFIG_FILE": filepath.Join(configDir, ".terraformrc")}}).Get(dst, sourceUrl)
+	registryErr, isRegistryErr := errors.Unwrap(err).(RegistryError)
+	require.True(t, isRegistryErr, "%v", err)
+	assert.Equal(t, http.StatusUnauthorized, registryErr.StatusCode)
+
+	// The version constraint matches none of the versions
+	env := map[string]string{"TF_CLI_CONFIG_DIR": configDir, "TF_TOKEN_" + strings.Replace(registryHost, ".", "_", -1): "s3cr3t"}
+	sourceUrl, err = url.Parse(fmt.Sprintf("tfr://%s/acme/vpc/aws?version=>%%3D3.0", registryHost))
+	require.NoError(t, err)
+	err = (&Getter{Env: env}).Get(dst, sourceUrl)
+	assert.Equal(t, NoMatchingVersion{Module: registryHost + "/acme/vpc/aws", Constraint: ">=3.0"}, errors.Unwrap(err))
+}
@@ -0,0 +1,293 @@
+package tfr
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/go-version"
+
+	"github.com/gruntwork-io/terragrunt/errors"
+)
+
+// The host of the public Terraform Registry, which the tfr:/// sources, without a host, refer to
+const DEFAULT_REGISTRY_HOST = "registry.terraform.io"
+
+// The path of the service discovery document of a registry, and the key of the module registry protocol in it
+const (
+	serviceDiscoveryPath = "/.well-known/terraform.json"
+	modulesServiceKey    = "modules.v1"
+)
+
+// ModuleAddress is the address of a module in a registry, e.g. registry.terraform.io/terraform-aws-modules/vpc/aws
+type ModuleAddress struct {
+	Host      string
+	Namespace string
+	Name      string
+	Provider  string
+}
+
+// Parse the given tfr:// URL, e.g. tfr://artifactory.example.com/acme/vpc/aws?version=1.2.0, into the address of the
+// module, and the version constraint of its version query param, if any. The host defaults to the public registry, as
+// in tfr:///terraform-aws-modules/vpc/aws.
+func ParseModuleUrl(sourceUrl *url.URL) (*ModuleAddress, string, error) {
+	parts := strings.Split(strings.Trim(sourceUrl.Path, "/"), "/")
+	if len(parts) != 3 || parts[0] == "" || parts[1] == "" || parts[2] == "" {
+		return nil, "", errors.WithStackTrace(InvalidModuleUrl(sourceUrl.String()))
+	}
+
+	host := sourceUrl.Host
+	if host == "" {
+		host = DEFAULT_REGISTRY_HOST
+	}
+	address := &ModuleAddress{Host: host, Namespace: parts[0], Name: parts[1], Provider: parts[2]}
+	return address, sourceUrl.Query().Get("version"), nil
+}
+
+func (address *ModuleAddress) String() string {
+	return strings.Join([]string{address.Host, address.Namespace, address.Name, address.Provider}, "/")
+}
+
+// A client of the module registry protocol of a registry, e.g. the public registry, the private registry of Terraform
+// Cloud, Artifactory or Nexus
+type registryClient struct {
+	address    *ModuleAddress
+	token      string
+	httpClient *http.Client
+}
+
+// The base URL of the registry. Like terraform does in its tests, the registries on localhost are accessed over plain
+// HTTP.
+func (client *registryClient) baseUrl() *url.URL {
+	scheme := "https"
+	hostname := strings.Split(client.address.Host, ":")[0]
+	if hostname == "localhost" || hostname == "127.0.0.1" {
+		scheme = "http"
+	}
+	return &url.URL{Scheme: scheme, Host: client.address.Host, Path: "/"}
+}
+
+// Return the URL of the module registry protocol of the registry, from its service discovery document, with a trailing
+// slash
+func (client *registryClient) modulesUrl() (*url.URL, error) {
+	discoveryUrl := client.baseUrl().ResolveReference(&url.URL{Path: serviceDiscoveryPath})
+	body, err := client.get(discoveryUrl)
+	if err != nil {
+		return nil, err
+	}
+
+	var services map[string]interface{}
+	if err := json.Unmarshal(body, &services); err != nil {
+		return nil, errors.WithStackTrace(err)
+	}
+	modulesPath, isString := services[modulesServiceKey].(string)
+	if !isString || modulesPath == "" {
+		return nil, errors.WithStackTrace(ModulesNotSupported(client.address.Host))
+	}
+	if !strings.HasSuffix(modulesPath, "/") {
+		modulesPath += "/"
+	}
+	modulesUrl, err := discoveryUrl.Parse(modulesPath)
+	if err != nil {
+		return nil, errors.WithStackTrace(err)
+	}
+	return modulesUrl, nil
+}
+
+// Return the version of the module to download: the version the given constraint pins, or else the latest of the
+// versions of the module in the registry that match it
+func (client *registryClient) resolveVersion(modulesUrl *url.URL, constraint string) (string, error) {
+	if exactVersion, err := version.NewVersion(constraint); err == nil && !strings.ContainsAny(constraint, "<>=~!,") {
+		return exactVersion.Original(), nil
+	}
+
+	constraints := version.Constraints{}
+	if constraint != "" {
+		var err error
+		if constraints, err = version.NewConstraint(constraint); err != nil {
+			return "", errors.WithStackTrace(InvalidVersionConstraint{Module: client.address.String(), Constraint: constraint})
+		}
+	}
+
+	body, err := client.get(client.moduleUrl(modulesUrl, "versions"))
+	if err != nil {
+		return "", err
+	}
+	var response struct {
+		Modules []struct {
+			Versions []struct {
+				Version string `json:"version"`
+			} `json:"versions"`
+		} `json:"modules"`
+	}
+	if err := json.Unmarshal(body, &response); err != nil {
+		return "", errors.WithStackTrace(err)
+	}
+
+	matchingVersions := []*version.Version{}
+	for _, module := range response.Modules {
+		for _, moduleVersion := range module.Versions {
+			parsedVersion, err := version.NewVersion(moduleVersion.Version)
+			if err != nil {
+				continue
+			}
+			// Like terraform, the prereleases are only used when the constraint pins them
+			if parsedVersion.Prerelease() != "" || !constraints.Check(parsedVersion) {
+				continue
+			}
+			matchingVersions = append(matchingVersions, parsedVersion)
+		}
+	}
+	if len(matchingVersions) == 0 {
+		return "", errors.WithStackTrace(NoMatchingVersion{Module: client.address.String(), Constraint: constraint})
+	}
+	sort.Sort(version.Collection(matchingVersions))
+	return matchingVersions[len(matchingVersions)-1].Original(), nil
+}
+
+// Return the source URL the registry says the given version of the module is downloaded from, e.g. a git repo, or an
+// archive in the registry itself. The URLs relative to the registry are resolved.
+func (client *registryClient) downloadLocation(modulesUrl *url.URL, moduleVersion string) (string, error) {
+	downloadUrl := client.moduleUrl(modulesUrl, moduleVersion, "download")
+	response, err := client.send(downloadUrl)
+	if err != nil {
+		return "", err
+	}
+	defer response.Body.Close()
+	body, err := ioutil.ReadAll(response.Body)
+	if err != nil {
+		return "", errors.WithStackTrace(err)
+	}
+
+	// The protocol returns the location in the X-Terraform-Get header, but some registries return it in the body
+	location := response.Header.Get("X-Terraform-Get")
+	if location == "" && len(body) > 0 {
+		var locationBody struct {
+			Location string `json:"location"`
+		}
+		if err := json.Unmarshal(body, &locationBody); err == nil {
+			location = locationBody.Location
+		}
+	}
+	if location == "" {
+		return "", errors.WithStackTrace(MissingDownloadLocation{Module: client.address.String(), Version: moduleVersion})
+	}
+
+	if strings.HasPrefix(location, "/") || strings.HasPrefix(location, "./") || strings.HasPrefix(location, "../") {
+		locationUrl, err := downloadUrl.Parse(location)
+		if err != nil {
+			return "", errors.WithStackTrace(err)
+		}
+		location = locationUrl.String()
+	}
+	return location, nil
+}
+
+// Return the URL of the given path of the module, e.g. <modules url>/<namespace>/<name>/<provider>/versions
+func (client *registryClient) moduleUrl(modulesUrl *url.URL, pathParts ...string) *url.URL {
+	parts := []string{client.address.Namespace, client.address.Name, client.address.Provider}
+	for _, part := range append(parts, pathParts...) {
+		modulesUrl = modulesUrl.ResolveReference(&url.URL{Path: url.PathEscape(part) + "/"})
+	}
+	modulesUrl.Path = strings.TrimSuffix(modulesUrl.Path, "/")
+	return modulesUrl
+}
+
+func (client *registryClient) get(requestUrl *url.URL) ([]byte, error) {
+	response, err := client.send(requestUrl)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+	body, err := ioutil.ReadAll(response.Body)
+	if err != nil {
+		return nil, errors.WithStackTrace(err)
+	}
+	return body, nil
+}
+
+// Send a GET request to the given URL of the registry, with the token of the registry, if any
+func (client *registryClient) send(requestUrl *url.URL) (*http.Response, error) {
+	request, err := http.NewRequest(http.MethodGet, requestUrl.String(), nil)
+	if err != nil {
+		return nil, errors.WithStackTrace(err)
+	}
+	if client.token != "" {
+		request.Header.Set("Authorization", "Bearer "+client.token)
+	}
+
+	response, err := client.httpClient.Do(request)
+	if err != nil {
+		return nil, errors.WithStackTrace(err)
+	}
+	if response.StatusCode < 200 || response.StatusCode >= 300 {
+		body, _ := ioutil.ReadAll(response.Body)
+		response.Body.Close()
+		return nil, errors.WithStackTrace(RegistryError{Module: client.address.String(), Url: requestUrl.String(), StatusCode: response.StatusCode, Message: strings.TrimSpace(string(body)), HasToken: client.token != ""})
+	}
+	return response, nil
+}
+
+// Custom error types
+
+type InvalidModuleUrl string
+
+func (source InvalidModuleUrl) Error() string {
+	return fmt.Sprintf("%s is not a valid registry module source. Expected tfr://<host>/<namespace>/<name>/<provider>[?version=<constraint>], or tfr:///<namespace>/<name>/<provider> for the public registry.", string(source))
+}
+
+type ModulesNotSupported string
+
+func (host ModulesNotSupported) Error() string {
+	return fmt.Sprintf("The registry %s does not support the module registry protocol: its service discovery document has no %s service", string(host), modulesServiceKey)
+}
+
+type InvalidVersionConstraint struct {
+	Module     string
+	Constraint string
+}
+
+func (err InvalidVersionConstraint) Error() string {
+	return fmt.Sprintf("The version of the module %s is not a valid version constraint: %s", err.Module, err.Constraint)
+}
+
+type NoMatchingVersion struct {
+	Module     string
+	Constraint string
+}
+
+func (err NoMatchingVersion) Error() string {
+	if err.Constraint == "" {
+		return fmt.Sprintf("The registry has no versions of the module %s", err.Module)
+	}
+	return fmt.Sprintf("The registry has no versions of the module %s that match %s", err.Module, err.Constraint)
+}
+
+type MissingDownloadLocation struct {
+	Module  string
+	Version string
+}
+
+func (err MissingDownloadLocation) Error() string {
+	return fmt.Sprintf("The registry returned no download location for the version %s of the module %s", err.Version, err.Module)
+}
+
+type RegistryError struct {
+	Module     string
+	Url        string
+	StatusCode int
+	Message    string
+	HasToken   bool
+}
+
+func (err RegistryError) Error() string {
+	message := fmt.Sprintf("Could not download the module %s: GET %s failed with status %d: %s", err.Module, err.Url, err.StatusCode, err.Message)
+	if (err.StatusCode == http.StatusUnauthorized || err.StatusCode == http.StatusForbidden) && !err.HasToken {
+		message += ". Found no token for the registry: run terraform login, add a credentials block for it to the terraform CLI config, or set the TF_TOKEN_<host> env var."
+	}
+	return message
+}
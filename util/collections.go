@@ -173,7 +173,8 @@ func StringListInsert(list []string, element string, index int) []string {
 func KeyValuePairStringListToMap(asList []string) (map[string]string, error) {
 	asMap := map[string]string{}
 	for _, arg := range asList {
-		parts := strings.Split(arg, "=")
+		// Only the first = separates the key from the value, so that the value can contain =, e.g. in a URL query
+		parts := strings.SplitN(arg, "=", 2)
 		if len(parts) != 2 {
 			return nil, errors.WithStackTrace(InvalidKeyValue(arg))
 		}
@@ -257,6 +257,11 @@ func TestKeyValuePairStringListToMap(t *testing.T) {
 			[]string{"ssh://git@github.com=/path/to/local"},
 			map[string]string{"ssh://git@github.com": "/path/to/local"},
 		},
+		{
+			"equals_in_value",
+			[]string{"github.com/acme=https://mirror.example.com/{path}.tar.gz?token=abc=="},
+			map[string]string{"github.com/acme": "https://mirror.example.com/{path}.tar.gz?token=abc=="},
+		},
 		{
 			"empty",
 			[]string{},
@@ -0,0 +1,83 @@
+package util
+
+import (
+	"bytes"
+	"io"
+	"sync"
+)
+
+// The maximum number of bytes of an unterminated line a PrefixWriter holds on to. Longer lines, such as a progress bar
+// that's redrawn without ever writing a newline, are written out in chunks of this size rather than growing the buffer.
+const maxPrefixWriterLineLength = 64 * 1024
+
+// Serializes the writes of all PrefixWriters, as the writers of different modules usually wrap the same stdout or
+// stderr, and the lines of each module should not be interleaved with those of the others.
+var prefixWriterLock sync.Mutex
+
+// PrefixWriter is a writer that frames the output written to it by prefixing each line with a fixed string, e.g. the
+// path of the module, before writing it to the wrapped writer. This makes the output of the modules that run-all runs
+// concurrently attributable, while still streaming it, rather than buffering the whole output of each module. Only an
+// unterminated line is held on to, so call Flush once done writing to write out the last line.
+type PrefixWriter struct {
+	writer io.Writer
+	prefix []byte
+	line   []byte
+	mutex  sync.Mutex
+}
+
+// NewPrefixWriter creates a PrefixWriter that prefixes each line written to the given writer with the given prefix.
+func NewPrefixWriter(writer io.Writer, prefix string) *PrefixWriter {
+	return &PrefixWriter{writer: writer, prefix: []byte(prefix)}
+}
+
+// Write writes each complete line in the given bytes to the wrapped writer, prefixed, and holds on to the remaining
+// unterminated line until the rest of it is written.
+func (prefixWriter *PrefixWriter) Write(p []byte) (int, error) {
+	prefixWriter.mutex.Lock()
+	defer prefixWriter.mutex.Unlock()
+
+	remaining := p
+	for len(remaining) > 0 {
+		newline := bytes.IndexByte(remaining, '\n')
+		if newline < 0 {
+			prefixWriter.line = append(prefixWriter.line, remaining...)
+			if len(prefixWriter.line) >= maxPrefixWriterLineLength {
+				if err := prefixWriter.writeLine(); err != nil {
+					return len(p) - len(remaining), err
+				}
+			}
+			break
+		}
+
+		prefixWriter.line = append(prefixWriter.line, remaining[:newline+1]...)
+		remaining = remaining[newline+1:]
+		if err := prefixWriter.writeLine(); err != nil {
+			return len(p) - len(remaining), err
+		}
+	}
+	return len(p), nil
+}
+
+// Flush writes out the unterminated line held on to, if any.
+func (prefixWriter *PrefixWriter) Flush() error {
+	prefixWriter.mutex.Lock()
+	defer prefixWriter.mutex.Unlock()
+
+	if len(prefixWriter.line) == 0 {
+		return nil
+	}
+	return prefixWriter.writeLine()
+}
+
+// Write the prefix and the line held on to in a single write, so that the line is not split up by the writes of others
+func (prefixWriter *PrefixWriter) writeLine() error {
+	framedLine := make([]byte, 0, len(prefixWriter.prefix)+len(prefixWriter.line))
+	framedLine = append(framedLine, prefixWriter.prefix...)
+	framedLine = append(framedLine, prefixWriter.line...)
+	prefixWriter.line = prefixWriter.line[:0]
+
+	prefixWriterLock.Lock()
+	defer prefixWriterLock.Unlock()
+	_, err := prefixWriter.writer.Write(framedLine)
+	return err
+}
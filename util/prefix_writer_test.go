@@ -0,0 +1,60 @@
+package util
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPrefixWriter(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name     string
+		writes   []string
+		expected string
+	}{
+		{"no output", []string{}, ""},
+		{"single line", []string{"Plan: 1 to add\n"}, "[vpc] Plan: 1 to add\n"},
+		{"many lines in one write", []string{"a\nb\n\nc\n"}, "[vpc] a\n[vpc] b\n[vpc] \n[vpc] c\n"},
+		{"line split across writes", []string{"Pl", "an: 1 to", " add\nne", "xt\n"}, "[vpc] Plan: 1 to add\n[vpc] next\n"},
+		{"unterminated last line", []string{"a\nb"}, "[vpc] a\n[vpc] b"},
+	}
+
+	for _, testCase := range testCases {
+		// Save the testCase in local scope so all the t.Run calls don't end up with the last item in the list
+		testCase := testCase
+
+		t.Run(testCase.name, func(t *testing.T) {
+			t.Parallel()
+
+			var output bytes.Buffer
+			writer := NewPrefixWriter(&output, "[vpc] ")
+			for _, write := range testCase.writes {
+				n, err := writer.Write([]byte(write))
+				require.NoError(t, err)
+				assert.Equal(t, len(write), n)
+			}
+			require.NoError(t, writer.Flush())
+			assert.Equal(t, testCase.expected, output.String())
+		})
+	}
+}
+
+func TestPrefixWriterLongLine(t *testing.T) {
+	t.Parallel()
+
+	var output bytes.Buffer
+	writer := NewPrefixWriter(&output, "[vpc] ")
+
+	// A line longer than the limit is written out in chunks rather than held on to
+	_, err := writer.Write([]byte(strings.Repeat("x", maxPrefixWriterLineLength+10)))
+	require.NoError(t, err)
+	assert.Equal(t, "[vpc] "+strings.Repeat("x", maxPrefixWriterLineLength+10), output.String())
+
+	require.NoError(t, writer.Flush())
+	assert.Equal(t, "[vpc] "+strings.Repeat("x", maxPrefixWriterLineLength+10), output.String())
+}
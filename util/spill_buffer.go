@@ -0,0 +1,148 @@
+package util
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"os"
+	"sync"
+
+	"github.com/gruntwork-io/terragrunt/errors"
+)
+
+// SpillBuffer is a goroutine-safe buffer for the output of commands that keeps at most maxMemory bytes in memory. Once
+// the output grows past that, it's moved into a temp file, and everything written afterwards is appended to the file,
+// so that the verbose output of many commands running concurrently, e.g. the plans of run-all, doesn't exhaust the
+// memory. Call Close to remove the temp file once the contents are no longer needed.
+type SpillBuffer struct {
+	maxMemory int
+	memory    bytes.Buffer
+	file      *os.File
+	err       error
+	mutex     sync.Mutex
+}
+
+// NewSpillBuffer creates a SpillBuffer that keeps at most maxMemory bytes in memory.
+func NewSpillBuffer(maxMemory int) *SpillBuffer {
+	return &SpillBuffer{maxMemory: maxMemory}
+}
+
+// Write appends the given bytes to the buffer, moving the contents into a temp file if they'd grow past the memory
+// limit. If the temp file can't be written, the rest of the output is dropped, and the error is returned by Err rather
+// than here: the buffer is usually written to along with the terminal through an io.MultiWriter, which would stop
+// writing to the terminal as well on an error.
+func (buffer *SpillBuffer) Write(p []byte) (int, error) {
+	buffer.mutex.Lock()
+	defer buffer.mutex.Unlock()
+
+	if buffer.err != nil {
+		return len(p), nil
+	}
+
+	if buffer.file == nil && buffer.memory.Len()+len(p) > buffer.maxMemory {
+		if err := buffer.spill(); err != nil {
+			buffer.err = err
+			return len(p), nil
+		}
+	}
+
+	if buffer.file == nil {
+		return buffer.memory.Write(p)
+	}
+
+	if _, err := buffer.file.Write(p); err != nil {
+		buffer.err = errors.WithStackTrace(err)
+	}
+	return len(p), nil
+}
+
+// Err returns the error the temp file couldn't be written with, if any, in which case the contents are incomplete.
+func (buffer *SpillBuffer) Err() error {
+	buffer.mutex.Lock()
+	defer buffer.mutex.Unlock()
+
+	return buffer.err
+}
+
+// Move the contents of the buffer from memory into a temp file
+func (buffer *SpillBuffer) spill() error {
+	file, err := ioutil.TempFile("", "terragrunt-output-*")
+	if err != nil {
+		return errors.WithStackTrace(err)
+	}
+	if _, err := buffer.memory.WriteTo(file); err != nil {
+		file.Close()
+		os.Remove(file.Name())
+		return errors.WithStackTrace(err)
+	}
+
+	buffer.file = file
+	buffer.memory = bytes.Buffer{}
+	return nil
+}
+
+// Spilled returns true if the contents of the buffer were moved into a temp file.
+func (buffer *SpillBuffer) Spilled() bool {
+	buffer.mutex.Lock()
+	defer buffer.mutex.Unlock()
+
+	return buffer.file != nil
+}
+
+// Reader returns a reader of the contents of the buffer, which streams them from the temp file if they were spilled into
+// one, so that they don't have to be read back into memory. The reader must be closed before the buffer.
+func (buffer *SpillBuffer) Reader() (io.ReadCloser, error) {
+	buffer.mutex.Lock()
+	defer buffer.mutex.Unlock()
+
+	if buffer.file == nil {
+		return ioutil.NopCloser(bytes.NewReader(append([]byte{}, buffer.memory.Bytes()...))), nil
+	}
+
+	file, err := os.Open(buffer.file.Name())
+	if err != nil {
+		return nil, errors.WithStackTrace(err)
+	}
+	return file, nil
+}
+
+// String returns the contents of the buffer, reading them from the temp file if they were spilled into one. If the
+// temp file can't be read, only what's left in memory, if anything, is returned. Use Reader for contents that may be
+// too large to hold in memory.
+func (buffer *SpillBuffer) String() string {
+	buffer.mutex.Lock()
+	defer buffer.mutex.Unlock()
+
+	if buffer.file == nil {
+		return buffer.memory.String()
+	}
+
+	contents, err := ioutil.ReadFile(buffer.file.Name())
+	if err != nil {
+		GlobalFallbackLogEntry.Warnf("Could not read the output buffered in %s: %v", buffer.file.Name(), err)
+		return buffer.memory.String()
+	}
+	return string(contents)
+}
+
+// Close removes the temp file the contents of the buffer were spilled into, if any. The buffer is empty afterwards.
+func (buffer *SpillBuffer) Close() error {
+	buffer.mutex.Lock()
+	defer buffer.mutex.Unlock()
+
+	buffer.memory = bytes.Buffer{}
+	if buffer.file == nil {
+		return nil
+	}
+
+	fileName := buffer.file.Name()
+	closeErr := buffer.file.Close()
+	buffer.file = nil
+	if err := os.Remove(fileName); err != nil {
+		return errors.WithStackTrace(err)
+	}
+	if closeErr != nil {
+		return errors.WithStackTrace(closeErr)
+	}
+	return nil
+}
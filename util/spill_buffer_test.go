@@ -0,0 +1,85 @@
+package util
+
+import (
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSpillBuffer(t *testing.T) {
+	t.Parallel()
+
+	buffer := NewSpillBuffer(10)
+
+	_, err := buffer.Write([]byte("12345"))
+	require.NoError(t, err)
+	_, err = buffer.Write([]byte("67890"))
+	require.NoError(t, err)
+	assert.False(t, buffer.Spilled())
+	assert.Equal(t, "1234567890", buffer.String())
+
+	// Going past the memory limit moves the contents into a temp file
+	_, err = buffer.Write([]byte("abc"))
+	require.NoError(t, err)
+	require.True(t, buffer.Spilled())
+	_, err = buffer.Write([]byte(strings.Repeat("x", 100)))
+	require.NoError(t, err)
+	assert.Equal(t, "1234567890abc"+strings.Repeat("x", 100), buffer.String())
+
+	// Closing the buffer removes the temp file
+	fileName := buffer.file.Name()
+	require.NoError(t, buffer.Close())
+	assert.False(t, FileExists(fileName))
+	assert.Equal(t, "", buffer.String())
+}
+
+func TestSpillBufferReader(t *testing.T) {
+	t.Parallel()
+
+	buffer := NewSpillBuffer(10)
+	defer buffer.Close()
+
+	_, err := buffer.Write([]byte("12345"))
+	require.NoError(t, err)
+	reader, err := buffer.Reader()
+	require.NoError(t, err)
+	contents, err := ioutil.ReadAll(reader)
+	require.NoError(t, err)
+	require.NoError(t, reader.Close())
+	assert.Equal(t, "12345", string(contents))
+
+	// Once spilled, the contents are streamed from the temp file
+	_, err = buffer.Write([]byte(strings.Repeat("x", 100)))
+	require.NoError(t, err)
+	reader, err = buffer.Reader()
+	require.NoError(t, err)
+	_, isFile := reader.(*os.File)
+	assert.True(t, isFile)
+	contents, err = ioutil.ReadAll(reader)
+	require.NoError(t, err)
+	require.NoError(t, reader.Close())
+	assert.Equal(t, "12345"+strings.Repeat("x", 100), string(contents))
+}
+
+func TestSpillBufferWriteErrorDoesNotFailWrites(t *testing.T) {
+	t.Parallel()
+
+	buffer := NewSpillBuffer(10)
+	defer buffer.Close()
+
+	_, err := buffer.Write([]byte(strings.Repeat("x", 20)))
+	require.NoError(t, err)
+	require.True(t, buffer.Spilled())
+
+	// Closing the temp file makes the following writes to it fail, which must not fail the writes to the buffer, as it's
+	// written to along with the terminal
+	require.NoError(t, buffer.file.Close())
+	written, err := buffer.Write([]byte("abc"))
+	assert.NoError(t, err)
+	assert.Equal(t, 3, written)
+	assert.Error(t, buffer.Err())
+}
@@ -0,0 +1,267 @@
+// Package vault requests short-lived cloud credentials from the AWS and GCP secrets engines of HashiCorp Vault, so that
+// the terraform processes of a unit run with credentials issued for that run only, and keeps their leases renewed while
+// the unit runs.
+package vault
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/gruntwork-io/terragrunt/errors"
+)
+
+// The env vars the address, token and namespace of Vault are read from, like the vault CLI does
+const (
+	AddressEnvVar   = "VAULT_ADDR"
+	TokenEnvVar     = "VAULT_TOKEN"
+	NamespaceEnvVar = "VAULT_NAMESPACE"
+)
+
+// The file vault login writes the token to, in the home dir
+const tokenFile = ".vault-token"
+
+// The kinds of accounts of the GCP secrets engine OAuth2 access tokens can be requested for
+const (
+	GcpRoleset             = "roleset"
+	GcpStaticAccount       = "static-account"
+	GcpImpersonatedAccount = "impersonated-account"
+)
+
+var GcpAccountTypes = []string{GcpRoleset, GcpStaticAccount, GcpImpersonatedAccount}
+
+// Client calls the HTTP API of a Vault server with a token
+type Client struct {
+	Address   string
+	Token     string
+	Namespace string
+
+	httpClient *http.Client
+}
+
+// Create a client of the Vault server at the given address, or else at the one of the VAULT_ADDR env var, that
+// authenticates with the token of the VAULT_TOKEN env var, or else the one vault login wrote to ~/.vault-token. The
+// env vars are read from the given env.
+func NewClient(address string, env map[string]string) (*Client, error) {
+	if address == "" {
+		address = env[AddressEnvVar]
+	}
+	if address == "" {
+		return nil, errors.WithStackTrace(MissingAddress{})
+	}
+
+	token := env[TokenEnvVar]
+	if token == "" {
+		homeDir, err := os.UserHomeDir()
+		if err == nil {
+			contents, err := ioutil.ReadFile(filepath.Join(homeDir, tokenFile))
+			if err != nil && !os.IsNotExist(err) {
+				return nil, errors.WithStackTrace(err)
+			}
+			token = strings.TrimSpace(string(contents))
+		}
+	}
+	if token == "" {
+		return nil, errors.WithStackTrace(MissingToken(address))
+	}
+
+	return &Client{
+		Address:    strings.TrimSuffix(address, "/"),
+		Token:      token,
+		Namespace:  env[NamespaceEnvVar],
+		httpClient: &http.Client{Timeout: time.Minute},
+	}, nil
+}
+
+// Lease is the lease of a secret Vault issued, which Vault revokes the secret at the end of, unless it's renewed
+type Lease struct {
+	ID        string
+	Duration  time.Duration
+	Renewable bool
+}
+
+// The envelope of the responses of the Vault API
+type secretJson struct {
+	LeaseID       string          `json:"lease_id"`
+	LeaseDuration int             `json:"lease_duration"`
+	Renewable     bool            `json:"renewable"`
+	Data          json.RawMessage `json:"data"`
+}
+
+func (secret *secretJson) lease() Lease {
+	return Lease{ID: secret.LeaseID, Duration: time.Duration(secret.LeaseDuration) * time.Second, Renewable: secret.Renewable}
+}
+
+// AwsCredentials are the credentials of AWS the AWS secrets engine issued
+type AwsCredentials struct {
+	AccessKeyId     string
+	SecretAccessKey string
+	// The session token of STS credentials, i.e. of the assumed_role and federation_token credential types. Empty for
+	// the iam_user credential type.
+	SessionToken string
+	Lease        Lease
+}
+
+// AwsCredentials requests credentials of the given role of the AWS secrets engine mounted at the given path. The ARN
+// of the role to assume and the TTL of the credentials are optional, and only apply to the STS credential types.
+func (client *Client) AwsCredentials(mount string, role string, roleArn string, ttl string) (*AwsCredentials, error) {
+	query := url.Values{}
+	if roleArn != "" {
+		query.Set("role_arn", roleArn)
+	}
+	if ttl != "" {
+		query.Set("ttl", ttl)
+	}
+	secret, err := client.request(http.MethodGet, secretPath(mount, "creds", role), query, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var data struct {
+		AccessKey     string `json:"access_key"`
+		SecretKey     string `json:"secret_key"`
+		SecurityToken string `json:"security_token"`
+	}
+	if err := json.Unmarshal(secret.Data, &data); err != nil {
+		return nil, errors.WithStackTrace(err)
+	}
+	return &AwsCredentials{AccessKeyId: data.AccessKey, SecretAccessKey: data.SecretKey, SessionToken: data.SecurityToken, Lease: secret.lease()}, nil
+}
+
+// GcpToken is an OAuth2 access token the GCP secrets engine issued. Access tokens have no lease, and expire after an
+// hour at most.
+type GcpToken struct {
+	Token     string
+	ExpiresAt time.Time
+}
+
+// GcpAccessToken requests an OAuth2 access token of the account of the given type and name of the GCP secrets engine
+// mounted at the given path
+func (client *Client) GcpAccessToken(mount string, accountType string, name string) (*GcpToken, error) {
+	secret, err := client.request(http.MethodGet, secretPath(mount, accountType, name, "token"), nil, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var data struct {
+		Token            string `json:"token"`
+		ExpiresAtSeconds int64  `json:"expires_at_seconds"`
+	}
+	if err := json.Unmarshal(secret.Data, &data); err != nil {
+		return nil, errors.WithStackTrace(err)
+	}
+	return &GcpToken{Token: data.Token, ExpiresAt: time.Unix(data.ExpiresAtSeconds, 0)}, nil
+}
+
+// RenewLease extends the given lease by the given increment, and returns the renewed lease. Vault may cap the increment,
+// e.g. to the max TTL of the role.
+func (client *Client) RenewLease(lease Lease, increment time.Duration) (Lease, error) {
+	secret, err := client.request(http.MethodPut, "sys/leases/renew", nil, map[string]interface{}{"lease_id": lease.ID, "increment": int(increment.Seconds())})
+	if err != nil {
+		return lease, err
+	}
+	return secret.lease(), nil
+}
+
+// RevokeLease revokes the given lease, and with it the secret it was issued for
+func (client *Client) RevokeLease(lease Lease) error {
+	_, err := client.request(http.MethodPut, "sys/leases/revoke", nil, map[string]interface{}{"lease_id": lease.ID})
+	return err
+}
+
+// Call the given path of the Vault API with the given query and JSON body, and return the secret it responds with
+func (client *Client) request(method string, path string, query url.Values, body map[string]interface{}) (*secretJson, error) {
+	requestUrl := client.Address + "/v1/" + path
+	if len(query) > 0 {
+		requestUrl += "?" + query.Encode()
+	}
+	var requestBody io.Reader
+	if body != nil {
+		contents, err := json.Marshal(body)
+		if err != nil {
+			return nil, errors.WithStackTrace(err)
+		}
+		requestBody = bytes.NewReader(contents)
+	}
+
+	request, err := http.NewRequest(method, requestUrl, requestBody)
+	if err != nil {
+		return nil, errors.WithStackTrace(err)
+	}
+	request.Header.Set("X-Vault-Token", client.Token)
+	if client.Namespace != "" {
+		request.Header.Set("X-Vault-Namespace", client.Namespace)
+	}
+	if body != nil {
+		request.Header.Set("Content-Type", "application/json")
+	}
+
+	response, err := client.httpClient.Do(request)
+	if err != nil {
+		return nil, errors.WithStackTrace(err)
+	}
+	defer response.Body.Close()
+	contents, err := ioutil.ReadAll(response.Body)
+	if err != nil {
+		return nil, errors.WithStackTrace(err)
+	}
+	if response.StatusCode < 200 || response.StatusCode >= 300 {
+		var vaultErrors struct {
+			Errors []string `json:"errors"`
+		}
+		message := strings.TrimSpace(string(contents))
+		if json.Unmarshal(contents, &vaultErrors) == nil && len(vaultErrors.Errors) > 0 {
+			message = strings.Join(vaultErrors.Errors, "; ")
+		}
+		return nil, errors.WithStackTrace(RequestFailed{Path: path, StatusCode: response.StatusCode, Message: message})
+	}
+
+	secret := &secretJson{}
+	if len(contents) > 0 {
+		if err := json.Unmarshal(contents, secret); err != nil {
+			return nil, errors.WithStackTrace(err)
+		}
+	}
+	return secret, nil
+}
+
+// Return the path of the API of the given secrets engine mount, with the given segments appended, e.g. aws/creds/deploy
+func secretPath(mount string, segments ...string) string {
+	escaped := []string{strings.Trim(mount, "/")}
+	for _, segment := range segments {
+		escaped = append(escaped, url.PathEscape(segment))
+	}
+	return strings.Join(escaped, "/")
+}
+
+// Custom error types
+
+type MissingAddress struct{}
+
+func (err MissingAddress) Error() string {
+	return fmt.Sprintf("The address of Vault is unknown. Set it in the vault_credentials block, or via the %s env var.", AddressEnvVar)
+}
+
+type MissingToken string
+
+func (address MissingToken) Error() string {
+	return fmt.Sprintf("Found no token for Vault at %s. Run vault login, or set the %s env var.", string(address), TokenEnvVar)
+}
+
+type RequestFailed struct {
+	Path       string
+	StatusCode int
+	Message    string
+}
+
+func (err RequestFailed) Error() string {
+	return fmt.Sprintf("Vault responded to %s with status %d: %s", err.Path, err.StatusCode, err.Message)
+}
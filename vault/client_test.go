@@ -0,0 +1,133 @@
+package vault
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/gruntwork-io/terragrunt/errors"
+)
+
+// A Vault server with the aws and gcp secrets engines mounted at aws-prod and gcp, which records the requests it gets
+func newTestVaultServer(t *testing.T, requests *[]string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		body, err := ioutil.ReadAll(request.Body)
+		require.NoError(t, err)
+		*requests = append(*requests, request.Method+" "+request.URL.RequestURI()+" "+string(body))
+
+		if request.Header.Get("X-Vault-Token") != "s.test" {
+			writer.WriteHeader(http.StatusForbidden)
+			writer.Write([]byte(`{"errors": ["permission denied"]}`))
+			return
+		}
+		switch request.URL.Path {
+		case "/v1/aws-prod/creds/deploy":
+			json.NewEncoder(writer).Encode(map[string]interface{}{
+				"lease_id":       "aws-prod/creds/deploy/abc",
+				"lease_duration": 3600,
+				"renewable":      true,
+				"data":           map[string]interface{}{"access_key": "AKIA123", "secret_key": "secret", "security_token": nil},
+			})
+		case "/v1/gcp/impersonated-account/terraform/token":
+			json.NewEncoder(writer).Encode(map[string]interface{}{
+				"data": map[string]interface{}{"token": "ya29.token", "expires_at_seconds": 1700000000, "token_ttl": 3599},
+			})
+		case "/v1/sys/leases/renew":
+			json.NewEncoder(writer).Encode(map[string]interface{}{"lease_id": "aws-prod/creds/deploy/abc", "lease_duration": 1800, "renewable": true})
+		case "/v1/sys/leases/revoke":
+			writer.WriteHeader(http.StatusNoContent)
+		default:
+			writer.WriteHeader(http.StatusNotFound)
+			writer.Write([]byte(`{"errors": []}`))
+		}
+	}))
+}
+
+func TestAwsCredentials(t *testing.T) {
+	t.Parallel()
+
+	requests := []string{}
+	server := newTestVaultServer(t, &requests)
+	defer server.Close()
+
+	client, err := NewClient(server.URL, map[string]string{TokenEnvVar: "s.test", NamespaceEnvVar: "team"})
+	require.NoError(t, err)
+	credentials, err := client.AwsCredentials("aws-prod/", "deploy", "", "15m")
+	require.NoError(t, err)
+	assert.Equal(t, &AwsCredentials{AccessKeyId: "AKIA123", SecretAccessKey: "secret", Lease: Lease{ID: "aws-prod/creds/deploy/abc", Duration: time.Hour, Renewable: true}}, credentials)
+
+	renewed, err := client.RenewLease(credentials.Lease, time.Hour)
+	require.NoError(t, err)
+	assert.Equal(t, 30*time.Minute, renewed.Duration)
+	require.NoError(t, client.RevokeLease(credentials.Lease))
+
+	assert.Equal(t, []string{
+		"GET /v1/aws-prod/creds/deploy?ttl=15m ",
+		`PUT /v1/sys/leases/renew {"increment":3600,"lease_id":"aws-prod/creds/deploy/abc"}`,
+		`PUT /v1/sys/leases/revoke {"lease_id":"aws-prod/creds/deploy/abc"}`,
+	}, requests)
+}
+
+func TestGcpAccessToken(t *testing.T) {
+	t.Parallel()
+
+	requests := []string{}
+	server := newTestVaultServer(t, &requests)
+	defer server.Close()
+
+	client, err := NewClient("", map[string]string{AddressEnvVar: server.URL + "/", TokenEnvVar: "s.test"})
+	require.NoError(t, err)
+	token, err := client.GcpAccessToken("gcp", GcpImpersonatedAccount, "terraform")
+	require.NoError(t, err)
+	assert.Equal(t, &GcpToken{Token: "ya29.token", ExpiresAt: time.Unix(1700000000, 0)}, token)
+}
+
+func TestRequestFailed(t *testing.T) {
+	t.Parallel()
+
+	requests := []string{}
+	server := newTestVaultServer(t, &requests)
+	defer server.Close()
+
+	client, err := NewClient(server.URL, map[string]string{TokenEnvVar: "s.wrong"})
+	require.NoError(t, err)
+	_, err = client.AwsCredentials("aws", "deploy", "", "")
+	assert.Equal(t, RequestFailed{Path: "aws/creds/deploy", StatusCode: http.StatusForbidden, Message: "permission denied"}, errors.Unwrap(err))
+}
+
+func TestNewClientWithoutAddress(t *testing.T) {
+	t.Parallel()
+
+	_, err := NewClient("", map[string]string{TokenEnvVar: "s.test"})
+	assert.Equal(t, MissingAddress{}, errors.Unwrap(err))
+}
+
+func TestRenewInterval(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, 40*time.Minute, renewInterval(Lease{Duration: time.Hour}))
+	assert.Equal(t, minRenewInterval, renewInterval(Lease{Duration: time.Second}))
+}
+
+func TestKeepRenewedStops(t *testing.T) {
+	t.Parallel()
+
+	requests := []string{}
+	server := newTestVaultServer(t, &requests)
+	defer server.Close()
+
+	client, err := NewClient(server.URL, map[string]string{TokenEnvVar: "s.test"})
+	require.NoError(t, err)
+	onRenew := func(Lease, error) { t.Error("Unexpected renewal") }
+	client.KeepRenewed(Lease{ID: "static", Duration: time.Hour}, time.Hour, onRenew)()
+	stop := client.KeepRenewed(Lease{ID: "renewable", Duration: time.Hour, Renewable: true}, time.Hour, onRenew)
+	stop()
+	stop()
+	assert.Empty(t, requests)
+}
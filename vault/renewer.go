@@ -0,0 +1,66 @@
+package vault
+
+import (
+	"sync"
+	"time"
+)
+
+// The shortest time between two renewals of a lease, so that a lease Vault can't extend any further isn't renewed in a
+// loop
+const minRenewInterval = 5 * time.Second
+
+// KeepRenewed renews the given lease by the given increment whenever two thirds of it have passed, until the returned
+// function is called, so that Vault doesn't revoke the secret while e.g. a long apply uses it. The given function is
+// called with the result of each renewal, e.g. to log it. A failed renewal is retried at the same pace, until the lease
+// ends. Leases that aren't renewable are left alone.
+func (client *Client) KeepRenewed(lease Lease, increment time.Duration, onRenew func(Lease, error)) func() {
+	if !lease.Renewable || lease.Duration <= 0 {
+		return func() {}
+	}
+
+	done := make(chan struct{})
+	stopped := sync.WaitGroup{}
+	stopped.Add(1)
+	go func() {
+		defer stopped.Done()
+		expiresAt := time.Now().Add(lease.Duration)
+		for {
+			timer := time.NewTimer(renewInterval(lease))
+			select {
+			case <-done:
+				timer.Stop()
+				return
+			case <-timer.C:
+			}
+
+			renewed, err := client.RenewLease(lease, increment)
+			onRenew(renewed, err)
+			if err == nil {
+				lease = renewed
+				expiresAt = time.Now().Add(lease.Duration)
+			} else {
+				lease.Duration = time.Until(expiresAt)
+			}
+			if lease.Duration <= 0 {
+				return
+			}
+		}
+	}()
+
+	var stopOnce sync.Once
+	return func() {
+		stopOnce.Do(func() {
+			close(done)
+			stopped.Wait()
+		})
+	}
+}
+
+// Return how long to wait before renewing the given lease: two thirds of it, but no less than minRenewInterval
+func renewInterval(lease Lease) time.Duration {
+	interval := lease.Duration * 2 / 3
+	if interval < minRenewInterval {
+		return minRenewInterval
+	}
+	return interval
+}